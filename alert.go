@@ -17,6 +17,7 @@ import (
 	"github.com/influxdata/kapacitor/models"
 	"github.com/influxdata/kapacitor/pipeline"
 	alertservice "github.com/influxdata/kapacitor/services/alert"
+	"github.com/influxdata/kapacitor/services/amqp"
 	"github.com/influxdata/kapacitor/services/hipchat"
 	"github.com/influxdata/kapacitor/services/httppost"
 	"github.com/influxdata/kapacitor/services/kafka"
@@ -59,7 +60,6 @@ type AlertNode struct {
 	topic       string
 	anonTopic   string
 	handlers    []alert.Handler
-	levels      []stateful.Expression
 	scopePools  []stateful.ScopePool
 	idTmpl      *text.Template
 	messageTmpl *text.Template
@@ -75,19 +75,50 @@ type AlertNode struct {
 
 	bufPool sync.Pool
 
-	levelResets  []stateful.Expression
 	lrScopePools []stateful.ScopePool
+
+	alertLevels
+	// Level expressions of each shard of the node
+	shardLevels []alertLevels
+
+	groups *groupStates
 }
 
 // Create a new  AlertNode which caches the most recent item and exposes it over the HTTP API.
+// Cached templates, shared by the alert nodes of all tasks
+var (
+	idTemplates      = alert.NewTextTemplates("id", nil)
+	messageTemplates = alert.NewTextTemplates("message", nil)
+	detailsTemplates = alert.NewHTMLTemplates("details", html.FuncMap{
+		"json": func(v interface{}) html.JS {
+
+			tmpBuffer := detailsBufPool.Get().(*bytes.Buffer)
+			defer func() {
+				tmpBuffer.Reset()
+				detailsBufPool.Put(tmpBuffer)
+			}()
+
+			_ = json.NewEncoder(tmpBuffer).Encode(v)
+
+			return html.JS(tmpBuffer.String())
+		},
+	})
+	detailsBufPool = sync.Pool{
+		New: func() interface{} {
+			return new(bytes.Buffer)
+		},
+	}
+)
+
 func newAlertNode(et *ExecutingTask, n *pipeline.AlertNode, d NodeDiagnostic) (an *AlertNode, err error) {
 	ctx := []keyvalue.T{
 		keyvalue.KV("task", et.Task.ID),
 	}
 
 	an = &AlertNode{
-		node: node{Node: n, et: et, diag: d},
-		a:    n,
+		node:   node{Node: n, et: et, diag: d},
+		a:      n,
+		groups: newGroupStates(),
 	}
 	an.node.runF = an.runAlert
 
@@ -103,30 +134,17 @@ func newAlertNode(et *ExecutingTask, n *pipeline.AlertNode, d NodeDiagnostic) (a
 	}
 
 	// Parse templates
-	an.idTmpl, err = text.New("id").Parse(n.Id)
+	an.idTmpl, err = idTemplates.Parse(n.Id)
 	if err != nil {
 		return nil, err
 	}
 
-	an.messageTmpl, err = text.New("message").Parse(n.Message)
+	an.messageTmpl, err = messageTemplates.Parse(n.Message)
 	if err != nil {
 		return nil, err
 	}
 
-	an.detailsTmpl, err = html.New("details").Funcs(html.FuncMap{
-		"json": func(v interface{}) html.JS {
-
-			tmpBuffer := an.bufPool.Get().(*bytes.Buffer)
-			defer func() {
-				tmpBuffer.Reset()
-				an.bufPool.Put(tmpBuffer)
-			}()
-
-			_ = json.NewEncoder(tmpBuffer).Encode(v)
-
-			return html.JS(tmpBuffer.String())
-		},
-	}).Parse(n.Details)
+	an.detailsTmpl, err = detailsTemplates.Parse(n.Details)
 	if err != nil {
 		return nil, err
 	}
@@ -329,6 +347,21 @@ func newAlertNode(et *ExecutingTask, n *pipeline.AlertNode, d NodeDiagnostic) (a
 		an.handlers = append(an.handlers, h)
 	}
 
+	for _, a := range n.AMQPHandlers {
+		c := amqp.HandlerConfig{
+			Broker:      a.Broker,
+			Exchange:    a.Exchange,
+			RoutingKey:  a.RoutingKey,
+			Template:    a.Template,
+			ContentType: a.ContentType,
+		}
+		h, err := et.tm.AMQPService.Handler(c, ctx...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create amqp handler")
+		}
+		an.handlers = append(an.handlers, h)
+	}
+
 	for _, a := range n.AlertaHandlers {
 		c := et.tm.AlertaService.DefaultHandlerConfig()
 		if a.Token != "" {
@@ -499,6 +532,13 @@ func newAlertNode(et *ExecutingTask, n *pipeline.AlertNode, d NodeDiagnostic) (a
 		}
 	}
 
+	// Each shard evaluates its own copy of the level expressions,
+	// as the state of their stateful functions is not safe for concurrent use.
+	an.shardLevels = []alertLevels{an.alertLevels}
+	for i := int64(1); i < n.Shards; i++ {
+		an.shardLevels = append(an.shardLevels, an.alertLevels.copyReset())
+	}
+
 	// Setup states
 	if n.History < 2 {
 		n.History = 2
@@ -514,7 +554,11 @@ func newAlertNode(et *ExecutingTask, n *pipeline.AlertNode, d NodeDiagnostic) (a
 	return
 }
 
-func (n *AlertNode) runAlert([]byte) error {
+func (n *AlertNode) runAlert(snapshot []byte) error {
+	if err := n.groups.restore(snapshot); err != nil {
+		n.diag.Error("failed to restore snapshot", err)
+	}
+
 	// Register delete hook
 	if n.hasAnonTopic() {
 		n.et.tm.registerDeleteHookForTask(n.et.Task.ID, deleteAlertHook(n.anonTopic))
@@ -550,10 +594,7 @@ func (n *AlertNode) runAlert([]byte) error {
 	n.statMap.Set(statsCritsTriggered, n.critsTriggered)
 
 	// Setup consumer
-	consumer := edge.NewGroupedConsumer(
-		n.ins[0],
-		n,
-	)
+	consumer := n.newGroupedConsumer(n.a.Shards, n.newShardGroup)
 	n.statMap.Set(statCardinalityGauge, consumer.CardinalityVar())
 
 	if err := consumer.Consume(); err != nil {
@@ -571,28 +612,34 @@ func (n *AlertNode) runAlert([]byte) error {
 	return nil
 }
 
-func (n *AlertNode) NewGroup(group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error) {
+func (n *AlertNode) newShardGroup(s nodeShard, group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error) {
 	id, err := n.renderID(first.Name(), first.GroupID(), first.Tags())
 	if err != nil {
 		return nil, err
 	}
 	t := first.Time()
 
-	state := n.restoreEventState(id, t, group.Tags)
+	state := n.restoreEventState(id, t, group, n.shardLevels[s.index])
 
 	return edge.NewReceiverFromForwardReceiverWithStats(
-		n.outs,
+		s.outs,
 		edge.NewTimedForwardReceiver(
-			n.timer,
+			s.timer,
 			state,
 		),
 	), nil
 }
 
-func (n *AlertNode) restoreEventState(id string, t time.Time, tags models.Tags) *alertState {
-	state := n.newAlertState(tags)
+func (n *AlertNode) restoreEventState(id string, t time.Time, group edge.GroupInfo, levels alertLevels) *alertState {
+	state := n.newAlertState(group.ID, group.Tags, levels)
+	// Restore the history of the levels from the snapshot of the node.
+	if err := n.groups.add(group.ID, state); err != nil {
+		n.diag.Error("failed to restore snapshot", err)
+	}
 	currentLevel, triggered := n.restoreEvent(id)
-	if currentLevel != alert.OK {
+	// The event state of the topics is more recent than the snapshot,
+	// e.g. the alert recovered after the snapshot was saved.
+	if currentLevel != state.currentLevel() && (currentLevel != alert.OK || !triggered.IsZero()) {
 		// Add initial event
 		state.addEvent(t, currentLevel)
 		// Record triggered time
@@ -601,7 +648,36 @@ func (n *AlertNode) restoreEventState(id string, t time.Time, tags models.Tags)
 	return state
 }
 
-func (n *AlertNode) newAlertState(tags models.Tags) *alertState {
+// alertLevels are the expressions that determine the level of the alerts.
+type alertLevels struct {
+	levels      []stateful.Expression
+	levelResets []stateful.Expression
+}
+
+// copyReset returns copies of the expressions without the state of their stateful functions.
+func (l alertLevels) copyReset() alertLevels {
+	c := alertLevels{
+		levels:      make([]stateful.Expression, len(l.levels)),
+		levelResets: make([]stateful.Expression, len(l.levelResets)),
+	}
+	for i, e := range l.levels {
+		if e != nil {
+			c.levels[i] = e.CopyReset()
+		}
+	}
+	for i, e := range l.levelResets {
+		if e != nil {
+			c.levelResets[i] = e.CopyReset()
+		}
+	}
+	return c
+}
+
+func (n *AlertNode) snapshot() ([]byte, error) {
+	return n.groups.snapshot()
+}
+
+func (n *AlertNode) newAlertState(group models.GroupID, tags models.Tags, levels alertLevels) *alertState {
 	inhibitors := make([]*alert.Inhibitor, len(n.a.Inhibitors))
 	for i, in := range n.a.Inhibitors {
 		tagset := make(models.Tags, len(in.EqualTags))
@@ -616,6 +692,8 @@ func (n *AlertNode) newAlertState(tags models.Tags) *alertState {
 	return &alertState{
 		history:    make([]alert.Level, n.a.History),
 		n:          n,
+		group:      group,
+		levels:     levels,
 		buffer:     new(edge.BatchBuffer),
 		inhibitors: inhibitors,
 	}
@@ -717,29 +795,29 @@ func (n *AlertNode) handleEvent(event alert.Event) {
 	}
 }
 
-func (n *AlertNode) determineLevel(p edge.FieldsTagsTimeGetter, currentLevel alert.Level) alert.Level {
-	if higherLevel, found := n.findFirstMatchLevel(alert.Critical, currentLevel-1, p); found {
+func (n *AlertNode) determineLevel(l alertLevels, p edge.FieldsTagsTimeGetter, currentLevel alert.Level) alert.Level {
+	if higherLevel, found := n.findFirstMatchLevel(l, alert.Critical, currentLevel-1, p); found {
 		return higherLevel
 	}
-	if rse := n.levelResets[currentLevel]; rse != nil {
+	if rse := l.levelResets[currentLevel]; rse != nil {
 		if pass, err := EvalPredicate(rse, n.lrScopePools[currentLevel], p); err != nil {
 			n.diag.Error("error evaluating reset expression for current level", err, keyvalue.KV("level", currentLevel.String()))
 		} else if !pass {
 			return currentLevel
 		}
 	}
-	if newLevel, found := n.findFirstMatchLevel(currentLevel, alert.OK, p); found {
+	if newLevel, found := n.findFirstMatchLevel(l, currentLevel, alert.OK, p); found {
 		return newLevel
 	}
 	return alert.OK
 }
 
-func (n *AlertNode) findFirstMatchLevel(start alert.Level, stop alert.Level, p edge.FieldsTagsTimeGetter) (alert.Level, bool) {
+func (n *AlertNode) findFirstMatchLevel(levels alertLevels, start alert.Level, stop alert.Level, p edge.FieldsTagsTimeGetter) (alert.Level, bool) {
 	if stop < alert.OK {
 		stop = alert.OK
 	}
 	for l := start; l > stop; l-- {
-		se := n.levels[l]
+		se := levels.levels[l]
 		if se == nil {
 			continue
 		}
@@ -792,7 +870,10 @@ func (n *AlertNode) event(
 }
 
 type alertState struct {
-	n *AlertNode
+	n     *AlertNode
+	group models.GroupID
+	// Level expressions of the shard of the group
+	levels alertLevels
 
 	buffer *edge.BatchBuffer
 
@@ -825,6 +906,8 @@ func (a *alertState) EndBatch(end edge.EndBatchMessage) (edge.Message, error) {
 }
 
 func (a *alertState) BufferedBatch(b edge.BufferedBatchMessage) (edge.Message, error) {
+	a.n.groups.mu.RLock()
+	defer a.n.groups.mu.RUnlock()
 	begin := b.Begin()
 	id, err := a.n.renderID(begin.Name(), begin.GroupID(), begin.Tags())
 	if err != nil {
@@ -841,7 +924,7 @@ func (a *alertState) BufferedBatch(b edge.BufferedBatchMessage) (edge.Message, e
 
 	currentLevel := a.currentLevel()
 	for _, bp := range b.Points() {
-		l := a.n.determineLevel(bp, currentLevel)
+		l := a.n.determineLevel(a.levels, bp, currentLevel)
 		if l < lowestLevel {
 			lowestLevel = l
 		}
@@ -888,6 +971,7 @@ func (a *alertState) BufferedBatch(b edge.BufferedBatchMessage) (edge.Message, e
 	if err != nil {
 		return nil, err
 	}
+	event.Trace = edge.TraceContext(b)
 
 	a.n.handleEvent(event)
 
@@ -917,11 +1001,13 @@ func (a *alertState) BufferedBatch(b edge.BufferedBatchMessage) (edge.Message, e
 }
 
 func (a *alertState) Point(p edge.PointMessage) (edge.Message, error) {
+	a.n.groups.mu.RLock()
+	defer a.n.groups.mu.RUnlock()
 	id, err := a.n.renderID(p.Name(), p.GroupID(), p.Tags())
 	if err != nil {
 		return nil, err
 	}
-	l := a.n.determineLevel(p, a.currentLevel())
+	l := a.n.determineLevel(a.levels, p, a.currentLevel())
 
 	a.addEvent(p.Time(), l)
 
@@ -951,6 +1037,7 @@ func (a *alertState) Point(p edge.PointMessage) (edge.Message, error) {
 		if err != nil {
 			return nil, err
 		}
+		event.Trace = edge.TraceContext(p)
 
 		a.n.handleEvent(event)
 
@@ -1000,6 +1087,7 @@ func (a *alertState) Barrier(b edge.BarrierMessage) (edge.Message, error) {
 }
 
 func (a *alertState) DeleteGroup(d edge.DeleteGroupMessage) (edge.Message, error) {
+	a.n.groups.delete(a.group)
 	return d, nil
 }
 func (a *alertState) Done() {
@@ -1008,6 +1096,54 @@ func (a *alertState) Done() {
 	}
 }
 
+// alertSnapshot is the state of an alert kept in the snapshots of the node.
+type alertSnapshot struct {
+	History        []alert.Level
+	Index          int
+	Flapping       bool
+	Changed        bool
+	FirstTriggered time.Time
+	LastTriggered  time.Time
+	Expired        bool
+}
+
+func (a *alertState) snapshot() ([]byte, error) {
+	return encodeState(alertSnapshot{
+		History:        a.history,
+		Index:          a.idx,
+		Flapping:       a.flapping,
+		Changed:        a.changed,
+		FirstTriggered: a.firstTriggered,
+		LastTriggered:  a.lastTriggered,
+		Expired:        a.expired,
+	})
+}
+
+func (a *alertState) restore(snapshot []byte) error {
+	var s alertSnapshot
+	if err := decodeState(snapshot, &s); err != nil {
+		return err
+	}
+	if len(s.History) == len(a.history) {
+		copy(a.history, s.History)
+		a.idx = s.Index
+	} else if len(s.History) > 0 {
+		// The size of the history changed, only keep the current level.
+		a.history[a.idx] = s.History[s.Index]
+	}
+	a.flapping = s.Flapping
+	a.changed = s.Changed
+	a.firstTriggered = s.FirstTriggered
+	a.lastTriggered = s.LastTriggered
+	a.expired = s.Expired
+
+	inhibited := a.history[a.idx] != alert.OK
+	for _, in := range a.inhibitors {
+		in.Set(inhibited)
+	}
+	return nil
+}
+
 // Return the duration of the current alert state.
 func (a *alertState) duration() time.Duration {
 	return a.lastTriggered.Sub(a.firstTriggered)
@@ -0,0 +1,86 @@
+package alert
+
+import (
+	html "html/template"
+	"sync"
+	text "text/template"
+)
+
+// maxTemplates is the maximum number of templates kept by a template cache.
+const maxTemplates = 1000
+
+// TextTemplates caches the text templates parsed with the same name and functions, keyed by their text.
+// The templates are shared by all callers, so they must only be executed and never modified.
+type TextTemplates struct {
+	name  string
+	funcs text.FuncMap
+	cache templateCache
+}
+
+func NewTextTemplates(name string, funcs text.FuncMap) *TextTemplates {
+	return &TextTemplates{
+		name:  name,
+		funcs: funcs,
+	}
+}
+
+// Parse returns the template of s, it is parsed only if it is not already cached.
+func (t *TextTemplates) Parse(s string) (*text.Template, error) {
+	tmpl, err := t.cache.get(s, func() (interface{}, error) {
+		return text.New(t.name).Funcs(t.funcs).Parse(s)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.(*text.Template), nil
+}
+
+// HTMLTemplates caches the html templates parsed with the same name and functions, keyed by their text.
+// The templates are shared by all callers, so they must only be executed and never modified.
+type HTMLTemplates struct {
+	name  string
+	funcs html.FuncMap
+	cache templateCache
+}
+
+func NewHTMLTemplates(name string, funcs html.FuncMap) *HTMLTemplates {
+	return &HTMLTemplates{
+		name:  name,
+		funcs: funcs,
+	}
+}
+
+// Parse returns the template of s, it is parsed only if it is not already cached.
+func (t *HTMLTemplates) Parse(s string) (*html.Template, error) {
+	tmpl, err := t.cache.get(s, func() (interface{}, error) {
+		return html.New(t.name).Funcs(t.funcs).Parse(s)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.(*html.Template), nil
+}
+
+type templateCache struct {
+	mu        sync.Mutex
+	templates map[string]interface{}
+}
+
+// get returns the cached template of s, or caches the template returned by parse.
+// Templates that fail to parse are not cached.
+func (c *templateCache) get(s string, parse func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if tmpl, ok := c.templates[s]; ok {
+		return tmpl, nil
+	}
+	tmpl, err := parse()
+	if err != nil {
+		return nil, err
+	}
+	if c.templates == nil || len(c.templates) >= maxTemplates {
+		c.templates = make(map[string]interface{})
+	}
+	c.templates[s] = tmpl
+	return tmpl, nil
+}
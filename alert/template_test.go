@@ -0,0 +1,84 @@
+package alert_test
+
+import (
+	"bytes"
+	html "html/template"
+	"strings"
+	"testing"
+	text "text/template"
+
+	"github.com/influxdata/kapacitor/alert"
+)
+
+func TestTextTemplates_Parse(t *testing.T) {
+	templates := alert.NewTextTemplates("message", text.FuncMap{
+		"upper": strings.ToUpper,
+	})
+	t1, err := templates.Parse("{{ .ID | upper }} is {{ .Level }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, err := templates.Parse("{{ .ID | upper }} is {{ .Level }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if t1 != t2 {
+		t.Error("expected the template to be parsed once and cached")
+	}
+	var buf bytes.Buffer
+	if err := t1.Execute(&buf, alert.Data{ID: "cpu", Level: alert.Critical}); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := buf.String(), "CPU is CRITICAL"; got != exp {
+		t.Errorf("unexpected message got %q exp %q", got, exp)
+	}
+
+	t3, err := templates.Parse("{{ .ID }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if t3 == t1 {
+		t.Error("expected a different template for a different text")
+	}
+
+	if _, err := templates.Parse("{{ .ID "); err == nil {
+		t.Error("expected error parsing invalid template")
+	}
+}
+
+func TestHTMLTemplates_Parse(t *testing.T) {
+	templates := alert.NewHTMLTemplates("details", html.FuncMap{})
+	t1, err := templates.Parse("<b>{{ .ID }}</b>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, err := templates.Parse("<b>{{ .ID }}</b>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if t1 != t2 {
+		t.Error("expected the template to be parsed once and cached")
+	}
+	var buf bytes.Buffer
+	if err := t1.Execute(&buf, alert.Data{ID: "<cpu>"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := buf.String(), "<b>&lt;cpu&gt;</b>"; got != exp {
+		t.Errorf("unexpected details got %q exp %q", got, exp)
+	}
+
+	if _, err := templates.Parse("{{ if }}"); err == nil {
+		t.Error("expected error parsing invalid template")
+	}
+}
+
+func BenchmarkTextTemplates_Parse(b *testing.B) {
+	templates := alert.NewTextTemplates("message", nil)
+	const tmpl = "{{ .ID }} is {{ .Level }} value: {{ index .Fields \"value\" }}"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := templates.Parse(tmpl); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/influxdata/kapacitor/expvar"
 	"github.com/influxdata/kapacitor/server/vars"
+	"github.com/influxdata/kapacitor/tracing"
 )
 
 const (
@@ -437,13 +438,26 @@ func (h *bufHandler) run() {
 			if !ok {
 				return
 			}
-			h.h.Handle(event)
+			h.handle(event)
 		case <-h.aborting:
 			return
 		}
 	}
 }
 
+// handle delivers the event to the handler, in a span of the trace of the event if it is sampled.
+func (h *bufHandler) handle(event Event) {
+	span := tracing.Start("alert handler", tracing.KindClient, event.Trace)
+	if span != nil {
+		span.SetAttribute("kapacitor.topic", event.Topic)
+		span.SetAttribute("kapacitor.alert.id", event.State.ID)
+		span.SetAttribute("kapacitor.alert.level", event.State.Level.String())
+		event.Trace = span.Context
+	}
+	h.h.Handle(event)
+	span.Finish()
+}
+
 // multiError is a list of errors.
 type multiError []error
 
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/tracing"
 )
 
 type Event struct {
@@ -15,6 +16,9 @@ type Event struct {
 	Data          EventData
 	NoExternal    bool
 	previousState EventState
+	// Context of the trace of the point or batch that triggered the event, if it is sampled.
+	// Handlers receive the context of the span of their delivery of the event.
+	Trace tracing.SpanContext
 }
 
 func (e Event) AlertData() Data {
@@ -35,6 +39,13 @@ func (e Event) PreviousState() EventState {
 	return e.previousState
 }
 
+// WithPreviousState returns a copy of the event with its previous state set,
+// it is used when events are replayed outside of a topic.
+func (e Event) WithPreviousState(s EventState) Event {
+	e.previousState = s
+	return e
+}
+
 func (e Event) TemplateData() TemplateData {
 	return TemplateData{
 		ID:       e.State.ID,
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"path"
 	"strings"
@@ -11,10 +13,25 @@ type Interface interface {
 	Authenticate(username, password string) (User, error)
 	User(username string) (User, error)
 	SubscriptionUser(token string) (User, error)
-	GrantSubscriptionAccess(token, db, rp string) error
-	ListSubscriptionTokens() ([]string, error)
-	RevokeSubscriptionAccess(token string) error
+	GrantSubscriptionAccess(cluster, token, db, rp string) error
+	ListSubscriptionTokens() ([]SubscriptionToken, error)
+	RevokeSubscriptionAccess(hash string) error
 }
+
+// SubscriptionToken is a token granted to the subscriptions of an InfluxDB cluster.
+// The token itself is not stored, only its hash.
+type SubscriptionToken struct {
+	Cluster string
+	Hash    string
+}
+
+// HashSubscriptionToken returns the hash of a subscription token.
+// The tokens are random, so unlike passwords they do not need a slow hash.
+func HashSubscriptionToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
 type Privilege uint
 
 const (
@@ -54,6 +71,51 @@ func (p Privilege) String() string {
 	}
 }
 
+// Scope is the permission to take a class of actions through the HTTP API.
+// Scopes are granted to users through their roles.
+type Scope string
+
+const (
+	// Read tasks and templates.
+	TasksReadScope Scope = "tasks:read"
+	// Create, update and delete tasks and templates.
+	TasksWriteScope Scope = "tasks:write"
+	// Read recordings, replays, shadow tasks and debug sessions.
+	ReplaysReadScope Scope = "replays:read"
+	// Create, update and delete recordings, replays, shadow tasks and debug sessions.
+	ReplaysWriteScope Scope = "replays:write"
+	// Read the configuration and the service tests.
+	ConfigReadScope Scope = "config:read"
+	// Override the configuration and run service tests.
+	ConfigWriteScope Scope = "config:write"
+	// Read alert topics, their events and handlers.
+	AlertsReadScope Scope = "alerts:read"
+	// Acknowledge alerts by deleting topics, which resets their state, and manage topic handlers.
+	AlertsAckScope Scope = "alerts:ack"
+)
+
+// ScopeList is the list of all scopes.
+var ScopeList = []Scope{
+	TasksReadScope,
+	TasksWriteScope,
+	ReplaysReadScope,
+	ReplaysWriteScope,
+	ConfigReadScope,
+	ConfigWriteScope,
+	AlertsReadScope,
+	AlertsAckScope,
+}
+
+// ValidScope reports whether s is a known scope.
+func ValidScope(s Scope) bool {
+	for _, scope := range ScopeList {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 type Action struct {
 	Resource  string
 	Privilege Privilege
@@ -68,6 +130,8 @@ type User struct {
 	hash  []byte
 	// Map of resource -> Bitmask of Privileges
 	privileges map[string]Privilege
+	// Set of scopes granted to the user
+	scopes map[Scope]bool
 }
 
 // Create a user with the given privileges.
@@ -93,6 +157,19 @@ func NewUser(name string, hash []byte, admin bool, privileges map[string][]Privi
 	}
 }
 
+// Return a copy of the user which is also granted the given scopes.
+func (u User) WithScopes(scopes ...Scope) User {
+	s := make(map[Scope]bool, len(u.scopes)+len(scopes))
+	for scope := range u.scopes {
+		s[scope] = true
+	}
+	for _, scope := range scopes {
+		s[scope] = true
+	}
+	u.scopes = s
+	return u
+}
+
 // This user has all privileges for all resources.
 var AdminUser = NewUser("ADMIN_USER", nil, true, nil)
 
@@ -126,6 +203,29 @@ func (u User) Privileges() map[string][]Privilege {
 	return privileges
 }
 
+// Return the scopes granted to the user, in the order of ScopeList.
+func (u User) Scopes() []Scope {
+	scopes := make([]Scope, 0, len(u.scopes))
+	for _, scope := range ScopeList {
+		if u.scopes[scope] {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// Determine wether the user is granted the scope.
+// Returns nil if the scope is granted, otherwise returns an error describing the needed scope.
+func (u User) AuthorizeScope(scope Scope) error {
+	if u.admin || u.scopes[scope] {
+		return nil
+	}
+	return scopeError{
+		username: u.name,
+		scope:    scope,
+	}
+}
+
 // Determine wether the user is authorized to take the action.
 // Returns nil if the action is authorized, otherwise returns an error describing the needed permissions.
 func (u User) AuthorizeAction(action Action) error {
@@ -178,6 +278,20 @@ func (e authError) MissingPrivlege() Privilege {
 	return e.action.Privilege
 }
 
+// Error returned when a user is not granted a scope.
+type scopeError struct {
+	username string
+	scope    Scope
+}
+
+func (e scopeError) Error() string {
+	return fmt.Sprintf("user %s does not have the %q scope", e.username, e.scope)
+}
+
+func (e scopeError) MissingScope() Scope {
+	return e.scope
+}
+
 const (
 	databaseRootResource = "/database"
 	apiRootResource      = "/api"
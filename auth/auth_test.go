@@ -2,6 +2,7 @@ package auth_test
 
 import (
 	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/influxdata/kapacitor/auth"
@@ -477,3 +478,61 @@ func Test_User_AuthorizeAction(t *testing.T) {
 		}
 	}
 }
+
+func Test_User_AuthorizeScope(t *testing.T) {
+	testCases := []struct {
+		username string
+		admin    bool
+		scopes   []auth.Scope
+		scope    auth.Scope
+		err      error
+	}{
+		{
+			username: "bob",
+			scopes:   []auth.Scope{auth.TasksReadScope, auth.TasksWriteScope},
+			scope:    auth.TasksWriteScope,
+		},
+		{
+			username: "jim",
+			scopes:   []auth.Scope{auth.TasksReadScope},
+			scope:    auth.TasksWriteScope,
+			err:      errors.New(`user jim does not have the "tasks:write" scope`),
+		},
+		{
+			username: "sue",
+			scope:    auth.AlertsAckScope,
+			err:      errors.New(`user sue does not have the "alerts:ack" scope`),
+		},
+		{
+			username: "alex",
+			admin:    true,
+			scope:    auth.ConfigWriteScope,
+		},
+	}
+	for _, tc := range testCases {
+		u := auth.NewUser(tc.username, nil, tc.admin, nil).WithScopes(tc.scopes...)
+		err := u.AuthorizeScope(tc.scope)
+		if err != nil {
+			if tc.err == nil {
+				t.Errorf("%s: unexpected error authorizing scope: got %q", tc.username, err.Error())
+			} else if err.Error() != tc.err.Error() {
+				t.Errorf("%s: unexpected error message: got %q exp %q", tc.username, err.Error(), tc.err.Error())
+			}
+		} else if tc.err != nil {
+			t.Errorf("%s: AUTH BREACH: expected error authorizing scope: %q", tc.username, tc.err.Error())
+		}
+	}
+}
+
+func Test_User_WithScopes(t *testing.T) {
+	u := auth.NewUser("bob", nil, false, nil).WithScopes(auth.AlertsAckScope, auth.TasksReadScope)
+	v := u.WithScopes(auth.TasksWriteScope)
+
+	// The original user must not be modified
+	if exp, got := []auth.Scope{auth.TasksReadScope, auth.AlertsAckScope}, u.Scopes(); !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected scopes: got %v exp %v", got, exp)
+	}
+	if exp, got := []auth.Scope{auth.TasksReadScope, auth.TasksWriteScope, auth.AlertsAckScope}, v.Scopes(); !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected scopes: got %v exp %v", got, exp)
+	}
+}
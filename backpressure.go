@@ -0,0 +1,166 @@
+package kapacitor
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/server/vars"
+)
+
+const (
+	statQueuedPoints   = "queued_points"
+	statRejectedWrites = "rejected_writes"
+	statRejectedPoints = "rejected_points"
+)
+
+// BackpressureError is returned when writing points to a database and retention policy
+// whose stream tasks have more points queued than the queue threshold.
+type BackpressureError struct {
+	Database        string
+	RetentionPolicy string
+	Queued          int64
+	retryAfter      time.Duration
+}
+
+func (e BackpressureError) Error() string {
+	return fmt.Sprintf("%d points queued for a task of %q.%q, retry after %v", e.Queued, e.Database, e.RetentionPolicy, e.retryAfter)
+}
+
+// RetryAfter returns the time after which the write should be retried.
+func (e BackpressureError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+type dbrpKey struct {
+	Database        string
+	RetentionPolicy string
+}
+
+type queueStats struct {
+	rejectedWrites *expvar.Int
+	rejectedPoints *expvar.Int
+
+	// Points accepted and the time of the last accepted write, in Unix nanoseconds.
+	receivedPoints int64
+	lastWrite      int64
+}
+
+// queued returns the most points queued for a stream task of the database and retention policy.
+func (tm *TaskMaster) queued(database, retentionPolicy string) int64 {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	var max int64
+	for key, edges := range tm.forks {
+		if key.Database != database || key.RetentionPolicy != retentionPolicy {
+			continue
+		}
+		for _, e := range edges {
+			if q := e.Collected() - e.Emitted(); q > max {
+				max = q
+			}
+		}
+	}
+	return max
+}
+
+// getQueueStats returns the queue stats of the database and retention policy,
+// creating them on the first write.
+func (tm *TaskMaster) getQueueStats(database, retentionPolicy string) *queueStats {
+	key := dbrpKey{Database: database, RetentionPolicy: retentionPolicy}
+	tm.queueStatsMu.Lock()
+	defer tm.queueStatsMu.Unlock()
+	if s, ok := tm.queueStats[key]; ok {
+		return s
+	}
+	s := &queueStats{
+		rejectedWrites: &expvar.Int{},
+		rejectedPoints: &expvar.Int{},
+	}
+	tm.queueStats[key] = s
+	tags := map[string]string{
+		"task_master":      tm.id,
+		"database":         database,
+		"retention_policy": retentionPolicy,
+	}
+	_, statMap := vars.NewStatistic("queue", tags)
+	statMap.Set(statQueuedPoints, expvar.NewIntFuncGauge(func() int64 {
+		return tm.queued(database, retentionPolicy)
+	}))
+	statMap.Set(statRejectedWrites, s.rejectedWrites)
+	statMap.Set(statRejectedPoints, s.rejectedPoints)
+	return s
+}
+
+// checkBackpressure returns a BackpressureError if a stream task of the database
+// and retention policy has more points queued than the queue threshold.
+func (tm *TaskMaster) checkBackpressure(database, retentionPolicy string, points int) error {
+	s := tm.getQueueStats(database, retentionPolicy)
+	if tm.QueueThreshold <= 0 {
+		return nil
+	}
+	queued := tm.queued(database, retentionPolicy)
+	if queued < tm.QueueThreshold {
+		return nil
+	}
+	s.rejectedWrites.Add(1)
+	s.rejectedPoints.Add(int64(points))
+	return BackpressureError{
+		Database:        database,
+		RetentionPolicy: retentionPolicy,
+		Queued:          queued,
+		retryAfter:      tm.BackpressureRetryAfter,
+	}
+}
+
+// recordWrite records the points accepted for the database and retention policy.
+func (tm *TaskMaster) recordWrite(database, retentionPolicy string, points int) {
+	s := tm.getQueueStats(database, retentionPolicy)
+	atomic.AddInt64(&s.receivedPoints, int64(points))
+	atomic.StoreInt64(&s.lastWrite, time.Now().UnixNano())
+}
+
+// DBRPUsage is the data written to a database and retention policy, and the stream tasks consuming it.
+type DBRPUsage struct {
+	PointsReceived int64
+	// LastWrite is zero if no points were written.
+	LastWrite time.Time
+	// IDs of the executing stream tasks of the database and retention policy.
+	Tasks []string
+}
+
+// DBRPUsage returns the usage of the databases and retention policies
+// points were written to, or executing stream tasks consume, since the task master opened.
+func (tm *TaskMaster) DBRPUsage() map[DBRP]DBRPUsage {
+	usage := make(map[DBRP]DBRPUsage)
+	tm.queueStatsMu.Lock()
+	for key, s := range tm.queueStats {
+		u := DBRPUsage{PointsReceived: atomic.LoadInt64(&s.receivedPoints)}
+		if last := atomic.LoadInt64(&s.lastWrite); last > 0 {
+			u.LastWrite = time.Unix(0, last).UTC()
+		}
+		usage[DBRP{Database: key.Database, RetentionPolicy: key.RetentionPolicy}] = u
+	}
+	tm.queueStatsMu.Unlock()
+
+	tm.mu.RLock()
+	for id, et := range tm.tasks {
+		if et.Task.Type != StreamTask {
+			continue
+		}
+		for _, dbrp := range et.Task.DBRPs {
+			u := usage[dbrp]
+			u.Tasks = append(u.Tasks, id)
+			usage[dbrp] = u
+		}
+	}
+	tm.mu.RUnlock()
+
+	for dbrp, u := range usage {
+		sort.Strings(u.Tasks)
+		usage[dbrp] = u
+	}
+	return usage
+}
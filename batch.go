@@ -3,6 +3,8 @@ package kapacitor
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
+	"sort"
 	"sync"
 	"time"
 
@@ -11,15 +13,30 @@ import (
 	"github.com/influxdata/kapacitor/edge"
 	"github.com/influxdata/kapacitor/expvar"
 	"github.com/influxdata/kapacitor/influxdb"
+	"github.com/influxdata/kapacitor/models"
 	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/tracing"
 	"github.com/pkg/errors"
 )
 
 const (
-	statsBatchesQueried = "batches_queried"
-	statsPointsQueried  = "points_queried"
+	statsBatchesQueried   = "batches_queried"
+	statsPointsQueried    = "points_queried"
+	statsBatchesCorrected = "batches_corrected"
 )
 
+// batchSource is a child of the BatchNode producing batches,
+// either a QueryNode or a FileNode.
+type batchSource interface {
+	Node
+	DBRPs() ([]DBRP, error)
+	Start()
+	Abort()
+	Cluster() string
+	GroupByMeasurement() bool
+	Queries(start, stop time.Time) ([]*Query, error)
+}
+
 type BatchNode struct {
 	node
 	s   *pipeline.BatchNode
@@ -66,7 +83,7 @@ func (n *BatchNode) Wait() error {
 func (n *BatchNode) DBRPs() ([]DBRP, error) {
 	var dbrps []DBRP
 	for _, b := range n.children {
-		d, err := b.(*QueryNode).DBRPs()
+		d, err := b.(batchSource).DBRPs()
 		if err != nil {
 			return nil, err
 		}
@@ -81,13 +98,13 @@ func (n *BatchNode) Count() int {
 
 func (n *BatchNode) Start() {
 	for _, b := range n.children {
-		b.(*QueryNode).Start()
+		b.(batchSource).Start()
 	}
 }
 
 func (n *BatchNode) Abort() {
 	for _, b := range n.children {
-		b.(*QueryNode).Abort()
+		b.(batchSource).Abort()
 	}
 }
 
@@ -100,7 +117,7 @@ type BatchQueries struct {
 func (n *BatchNode) Queries(start, stop time.Time) ([]BatchQueries, error) {
 	queries := make([]BatchQueries, len(n.children))
 	for i, b := range n.children {
-		qn := b.(*QueryNode)
+		qn := b.(batchSource)
 		qs, err := qn.Queries(start, stop)
 		if err != nil {
 			return nil, err
@@ -116,7 +133,7 @@ func (n *BatchNode) Queries(start, stop time.Time) ([]BatchQueries, error) {
 
 // Do not add the source batch node to the dot output
 // since its not really an edge.
-func (n *BatchNode) edot(*bytes.Buffer, bool) {}
+func (n *BatchNode) edot(*bytes.Buffer, bool, edgeRates) {}
 
 func (n *BatchNode) collectedCount() (count int64) {
 	for _, child := range n.children {
@@ -138,6 +155,10 @@ type QueryNode struct {
 	batchesQueried *expvar.Int
 	pointsQueried  *expvar.Int
 	byName         bool
+
+	// The intervals within the latency watermark, queried again on every tick.
+	intervals        []*queriedInterval
+	batchesCorrected *expvar.Int
 }
 
 func newQueryNode(et *ExecutingTask, n *pipeline.QueryNode, d NodeDiagnostic) (*QueryNode, error) {
@@ -147,6 +168,10 @@ func newQueryNode(et *ExecutingTask, n *pipeline.QueryNode, d NodeDiagnostic) (*
 		closing:  make(chan struct{}),
 		aborting: make(chan struct{}),
 		byName:   n.GroupByMeasurementFlag,
+
+		batchesQueried:   &expvar.Int{},
+		pointsQueried:    &expvar.Int{},
+		batchesCorrected: &expvar.Int{},
 	}
 	bn.node.runF = bn.runBatch
 	bn.node.stopF = bn.stopBatch
@@ -185,6 +210,10 @@ func newQueryNode(et *ExecutingTask, n *pipeline.QueryNode, d NodeDiagnostic) (*
 		bn.query.Fill(influxql.NumberFill, fill)
 	}
 
+	if n.Latency < 0 {
+		return nil, errors.New("latency must not be negative")
+	}
+
 	// Determine schedule
 	if n.Every != 0 && n.Cron != "" {
 		return nil, errors.New("must not set both 'every' and 'cron' properties")
@@ -265,11 +294,11 @@ func (n *QueryNode) Queries(start, stop time.Time) ([]*Query, error) {
 // Query InfluxDB and collect batches on batch collector.
 func (n *QueryNode) doQuery(in edge.Edge) error {
 	defer in.Close()
-	n.batchesQueried = &expvar.Int{}
-	n.pointsQueried = &expvar.Int{}
-
 	n.statMap.Set(statsBatchesQueried, n.batchesQueried)
 	n.statMap.Set(statsPointsQueried, n.pointsQueried)
+	if n.b.Latency > 0 {
+		n.statMap.Set(statsBatchesCorrected, n.batchesCorrected)
+	}
 
 	if n.et.tm.InfluxDBService == nil {
 		return errors.New("InfluxDB not configured, cannot query InfluxDB for batch query")
@@ -279,6 +308,8 @@ func (n *QueryNode) doQuery(in edge.Edge) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to get InfluxDB client")
 	}
+	taskID := n.et.Task.ID
+	n.et.tm.progress.set(taskID, n.Name(), n.ticker.Next(time.Now()))
 	tickC := n.ticker.Start()
 	for {
 		select {
@@ -287,52 +318,210 @@ func (n *QueryNode) doQuery(in edge.Edge) error {
 		case <-n.aborting:
 			return errors.New("batch doQuery aborted")
 		case now := <-tickC:
-			n.timer.Start()
-			// Update times for query
-			stop := now.Add(-1 * n.b.Offset)
-			n.query.SetStartTime(stop.Add(-1 * n.b.Period))
-			n.query.SetStopTime(stop)
-
-			qStr := n.query.String()
-			n.diag.StartingBatchQuery(qStr)
-
-			// Execute query
-			q := influxdb.Query{
-				Command: qStr,
+			if len(n.et.Task.DependsOn) > 0 {
+				// Wait for the tasks this task depends on, at most until the next query.
+				ok, err := n.et.tm.progress.waitForIntervals(n.et.Task.DependsOn, now, n.ticker.Next(now), n.closing, n.aborting)
+				if !ok {
+					continue
+				}
+				if err != nil {
+					n.diag.Error("querying before the tasks it depends on completed", err)
+				}
+			}
+			// Spread the queries of the tasks scheduled at the same time.
+			if delay := n.queryDelay(); delay > 0 {
+				select {
+				case <-time.After(delay):
+				case <-n.closing:
+					return nil
+				case <-n.aborting:
+					return errors.New("batch doQuery aborted")
+				}
 			}
-			resp, err := con.Query(q)
+			queried, err := n.queryTick(con, in, now)
 			if err != nil {
-				n.diag.Error("error executing query", err)
-				n.timer.Stop()
+				return err
+			}
+			if !queried {
 				break
 			}
+			if n.et.tm.progress.hasDependents(taskID) && n.et.waitIdle(n.closing) {
+				n.et.tm.progress.set(taskID, n.Name(), n.ticker.Next(now))
+			}
+		}
+	}
+}
 
-			// Collect batches
-			for _, res := range resp.Results {
-				batches, err := edge.ResultToBufferedBatches(res, n.byName)
-				if err != nil {
-					n.diag.Error("failed to understand query result", err)
+// queryDelay returns the delay of the queries of the node within the batch query jitter,
+// less than the time between the queries.
+func (n *QueryNode) queryDelay() time.Duration {
+	delay := n.et.tm.queries.delay(n.et.Task.ID, n.Name())
+	if n.b.Every > 0 {
+		delay %= n.b.Every
+	}
+	return delay
+}
+
+// queryTick queries the interval of the tick, and the previous intervals within the latency watermark.
+// It returns whether the query of the interval of the tick succeeded.
+func (n *QueryNode) queryTick(con influxdb.Client, in edge.Edge, now time.Time) (bool, error) {
+	n.timer.Start()
+	defer n.timer.Stop()
+	// Update times for query
+	stop := now.Add(-1 * n.b.Offset)
+	n.query.SetStartTime(stop.Add(-1 * n.b.Period))
+	n.query.SetStopTime(stop)
+
+	var interval *queriedInterval
+	if n.b.Latency > 0 && stop.After(now.Add(-1*n.b.Latency)) {
+		interval = &queriedInterval{
+			stop:         stop,
+			fingerprints: make(map[models.GroupID]uint64),
+		}
+	}
+	queried, err := n.queryInterval(con, in, n.query, stop, interval)
+	if err != nil {
+		return queried, err
+	}
+	if queried && n.b.Latency > 0 {
+		// Query the previous intervals again for late data.
+		if err := n.requery(con, in, now); err != nil {
+			return queried, err
+		}
+	}
+	if interval != nil {
+		// If the query failed the interval is queried again with the previous intervals.
+		n.intervals = append(n.intervals, interval)
+	}
+	return queried, nil
+}
+
+// queryInterval queries InfluxDB for the interval ending at stop and collects the batches of the result.
+// If the interval is within the latency watermark only the groups whose results changed are collected,
+// and they are corrections if the interval was queried before.
+// It returns whether the query succeeded, query errors are logged and only failing to collect a batch is returned.
+func (n *QueryNode) queryInterval(con influxdb.Client, in edge.Edge, q *Query, stop time.Time, interval *queriedInterval) (bool, error) {
+	qStr := q.String()
+	n.diag.StartingBatchQuery(qStr)
+
+	// Execute query, the batches of the query are part of its trace if it is sampled.
+	span := tracing.Start("query", tracing.KindClient, tracing.SpanContext{})
+	defer span.Finish()
+	span.SetAttribute("kapacitor.task", n.et.Task.ID)
+	span.SetAttribute("kapacitor.node", n.Name())
+	span.SetAttribute("db.statement", qStr)
+	n.timer.Pause()
+	if !n.et.tm.queries.acquire(n.b.Cluster, n.closing, n.aborting) {
+		return false, nil
+	}
+	n.timer.Resume()
+	resp, err := con.Query(influxdb.Query{
+		Command: qStr,
+	})
+	n.et.tm.queries.release(n.b.Cluster)
+	if err != nil {
+		n.diag.Error("error executing query", err)
+		span.SetError(err)
+		return false, nil
+	}
+
+	correction := false
+	if interval != nil {
+		correction = interval.queried
+		interval.queried = true
+	}
+	// Collect batches
+	for _, res := range resp.Results {
+		batches, err := edge.ResultToBufferedBatches(res, n.byName)
+		if err != nil {
+			n.diag.Error("failed to understand query result", err)
+			continue
+		}
+		for _, bch := range batches {
+			// Set stop time based off query bounds
+			if bch.Begin().Time().IsZero() || !q.IsGroupedByTime() {
+				bch.Begin().SetTime(stop)
+			}
+
+			if interval != nil {
+				f := batchFingerprint(bch)
+				if last, ok := interval.fingerprints[bch.GroupID()]; ok && last == f {
 					continue
 				}
-				for _, bch := range batches {
-					// Set stop time based off query bounds
-					if bch.Begin().Time().IsZero() || !n.query.IsGroupedByTime() {
-						bch.Begin().SetTime(stop)
-					}
-
-					n.batchesQueried.Add(1)
-					n.pointsQueried.Add(int64(len(bch.Points())))
-
-					n.timer.Pause()
-					if err := in.Collect(bch); err != nil {
-						return err
-					}
-					n.timer.Resume()
+				interval.fingerprints[bch.GroupID()] = f
+				if correction {
+					n.batchesCorrected.Add(1)
 				}
 			}
-			n.timer.Stop()
+
+			edge.SetTraceContext(bch, span.SpanContext())
+
+			n.batchesQueried.Add(1)
+			n.pointsQueried.Add(int64(bch.Len()))
+
+			n.timer.Pause()
+			if err := in.Collect(bch); err != nil {
+				return true, err
+			}
+			n.timer.Resume()
+		}
+	}
+	return true, nil
+}
+
+// requery queries the intervals within the latency watermark again,
+// and stops tracking the intervals that are older than the watermark after their last query.
+func (n *QueryNode) requery(con influxdb.Client, in edge.Edge, now time.Time) error {
+	watermark := now.Add(-1 * n.b.Latency)
+	intervals := n.intervals[:0]
+	for _, interval := range n.intervals {
+		q, err := n.query.Clone()
+		if err != nil {
+			return err
+		}
+		q.SetStartTime(interval.stop.Add(-1 * n.b.Period))
+		q.SetStopTime(interval.stop)
+		if _, err := n.queryInterval(con, in, q, interval.stop, interval); err != nil {
+			return err
+		}
+		if interval.stop.After(watermark) {
+			intervals = append(intervals, interval)
+		}
+	}
+	n.intervals = intervals
+	return nil
+}
+
+// queriedInterval is an interval within the latency watermark,
+// with the fingerprints of the results of its groups last collected.
+type queriedInterval struct {
+	stop time.Time
+	// queried reports whether a query of the interval succeeded.
+	queried      bool
+	fingerprints map[models.GroupID]uint64
+}
+
+// batchFingerprint hashes the times, tags and fields of the points of the batch.
+func batchFingerprint(b edge.BufferedBatchMessage) uint64 {
+	h := fnv.New64a()
+	for _, p := range b.Points() {
+		fmt.Fprint(h, p.Time().UnixNano())
+		tags := p.Tags()
+		for _, k := range models.SortedKeys(tags) {
+			fmt.Fprintf(h, ",%s=%s", k, tags[k])
+		}
+		fields := p.Fields()
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(h, " %s=%v", k, fields[k])
 		}
+		h.Write([]byte{'\n'})
 	}
+	return h.Sum64()
 }
 
 func (n *QueryNode) runBatch([]byte) error {
@@ -0,0 +1,125 @@
+package kapacitor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	imodels "github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/influxdb"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/timer"
+)
+
+// intervalClient is an InfluxDB client answering the queries of an interval with the values of the hosts,
+// by the stop time of the interval.
+type intervalClient struct {
+	values  map[time.Time]map[string]float64
+	queries int
+}
+
+func (c *intervalClient) Ping(ctx context.Context) (time.Duration, string, error) {
+	return 0, "", nil
+}
+
+func (c *intervalClient) Write(bp influxdb.BatchPoints) error {
+	return nil
+}
+
+func (c *intervalClient) Query(q influxdb.Query) (*influxdb.Response, error) {
+	c.queries++
+	res := influxdb.Result{}
+	for stop, hosts := range c.values {
+		if !strings.Contains(q.Command, "time < '"+stop.Format(time.RFC3339Nano)+"'") {
+			continue
+		}
+		for host, v := range hosts {
+			res.Series = append(res.Series, imodels.Row{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": host},
+				Columns: []string{"time", "mean"},
+				Values:  [][]interface{}{{stop.Add(-time.Minute).Format(time.RFC3339Nano), v}},
+			})
+		}
+	}
+	return &influxdb.Response{Results: []influxdb.Result{res}}, nil
+}
+
+func TestQueryNode_Latency(t *testing.T) {
+	batch := &pipeline.BatchNode{}
+	pipeline.CreatePipelineSources(batch)
+	p := batch.Query(`SELECT mean("value") FROM "telegraf"."autogen"."cpu"`)
+	p.Period = time.Minute
+	p.Every = time.Minute
+	p.Latency = 2 * time.Minute
+	p.GroupBy("host")
+	et := &ExecutingTask{
+		tm:   NewTaskMaster("testQueryNodeLatency", nil, taskMasterDiagnostic{}),
+		Task: &Task{ID: "test"},
+	}
+	n, err := newQueryNode(et, p, &windowNodeDiagnostic{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.timer = timer.NewNoOp()
+	in := &batchRecorder{}
+
+	t0 := time.Date(2017, 1, 1, 0, 1, 0, 0, time.UTC)
+	t1 := t0.Add(time.Minute)
+	cli := &intervalClient{values: map[time.Time]map[string]float64{
+		t0: {"serverA": 1},
+	}}
+	// tick queries the interval ending at now and returns the batches collected.
+	tick := func(now time.Time) []string {
+		if _, err := n.queryTick(cli, in, now); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, b := range in.batches {
+			got = append(got, b.Time().Format("15:04")+" "+b.Tags()["host"])
+		}
+		in.batches = nil
+		return got
+	}
+
+	if got := tick(t0); len(got) != 1 || got[0] != "00:01 serverA" {
+		t.Fatalf("unexpected batches of the first interval %v", got)
+	}
+
+	// Late data of serverA and serverB arrives for the first interval.
+	cli.values[t0] = map[string]float64{"serverA": 2, "serverB": 1}
+	cli.values[t1] = map[string]float64{"serverA": 3}
+	if got, exp := tick(t1), 3; len(got) != exp {
+		t.Fatalf("unexpected batches with the corrections %v", got)
+	}
+	if got, exp := n.batchesCorrected.IntValue(), int64(2); got != exp {
+		t.Errorf("unexpected corrected batches got %d exp %d", got, exp)
+	}
+
+	// Unchanged results are not emitted again.
+	if got := tick(t1.Add(time.Minute)); len(got) != 0 {
+		t.Errorf("unexpected batches without late data %v", got)
+	}
+	// The first interval is past the watermark and no longer queried.
+	if got, exp := len(n.intervals), 2; got != exp {
+		t.Errorf("unexpected queried intervals got %d exp %d", got, exp)
+	}
+	queries := cli.queries
+	tick(t1.Add(2 * time.Minute))
+	if got, exp := cli.queries-queries, 3; got != exp {
+		t.Errorf("unexpected queries got %d exp %d", got, exp)
+	}
+}
+
+// batchRecorder is an edge recording the batches collected.
+type batchRecorder struct {
+	edge.Edge
+	batches []edge.BufferedBatchMessage
+}
+
+func (e *batchRecorder) Collect(m edge.Message) error {
+	e.batches = append(e.batches, m.(edge.BufferedBatchMessage))
+	return nil
+}
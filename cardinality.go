@@ -0,0 +1,229 @@
+package kapacitor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/models"
+)
+
+// Actions for the points of new series above the cardinality limit of their measurement.
+const (
+	// Drop the points.
+	CardinalityDrop = "drop"
+	// Pass on one of every SampleEvery points.
+	CardinalitySample = "sample"
+	// Fail the task.
+	CardinalityError = "error"
+)
+
+// CardinalityLimits bounds the number of live series of each measurement read by a stream task,
+// so that a tag explosion does not exhaust the memory of the server.
+type CardinalityLimits struct {
+	// Whether the series of the stream tasks are tracked.
+	Enabled bool
+	// Maximum number of live series of a measurement, 0 means no limit.
+	MaxSeries int64
+	// Maximum number of live series of specific measurements, overriding MaxSeries.
+	Measurements map[string]int64
+	// Action for the points of new series above the limit.
+	Action string
+	// One of every SampleEvery points above the limit is passed on with the sample action.
+	SampleEvery int64
+	// Time after which a series that has not received points is no longer live.
+	SeriesTTL time.Duration
+}
+
+func (l CardinalityLimits) maxSeries(measurement string) int64 {
+	if max, ok := l.Measurements[measurement]; ok {
+		return max
+	}
+	return l.MaxSeries
+}
+
+// MeasurementCardinality is the live cardinality of a measurement read by a task.
+type MeasurementCardinality struct {
+	Measurement string
+	Series      int64
+	// Limit of the number of live series, 0 means no limit.
+	MaxSeries int64
+	// Points of series above the limit that were dropped or passed on by sampling.
+	Dropped int64
+	Sampled int64
+	// Tags with the most distinct values among the live series.
+	TopTags []TagCardinality
+	// Tag values with the most live series.
+	TopValues []TagValueCardinality
+}
+
+type TagCardinality struct {
+	Tag    string
+	Values int64
+}
+
+type TagValueCardinality struct {
+	Tag    string
+	Value  string
+	Series int64
+}
+
+type liveSeries struct {
+	tags     models.Tags
+	lastSeen time.Time
+}
+
+type measurementSeries struct {
+	series  map[models.GroupID]*liveSeries
+	limited int64
+	dropped int64
+	sampled int64
+}
+
+// cardinalityGovernor tracks the live series of each measurement read by a task and enforces their limits.
+// It is safe for concurrent use.
+type cardinalityGovernor struct {
+	limits CardinalityLimits
+	now    func() time.Time
+
+	mu           sync.Mutex
+	measurements map[string]*measurementSeries
+	lastExpire   time.Time
+}
+
+func newCardinalityGovernor(l CardinalityLimits) *cardinalityGovernor {
+	return &cardinalityGovernor{
+		limits:       l,
+		now:          time.Now,
+		measurements: make(map[string]*measurementSeries),
+	}
+}
+
+// admit tracks the series of the point and reports whether the point should be passed on,
+// an error is returned if the point exceeds the limit with the error action.
+func (g *cardinalityGovernor) admit(p edge.PointMessage) (bool, error) {
+	if g == nil {
+		return true, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	g.expire(now)
+
+	name := p.Name()
+	m, ok := g.measurements[name]
+	if !ok {
+		m = &measurementSeries{series: make(map[models.GroupID]*liveSeries)}
+		g.measurements[name] = m
+	}
+	tags := p.Tags()
+	key := models.ToGroupID(name, tags, models.Dimensions{TagNames: models.SortedKeys(tags)})
+	if s, ok := m.series[key]; ok {
+		s.lastSeen = now
+		return true, nil
+	}
+	max := g.limits.maxSeries(name)
+	if max <= 0 || int64(len(m.series)) < max {
+		m.series[key] = &liveSeries{tags: tags.Copy(), lastSeen: now}
+		return true, nil
+	}
+
+	// New series above the limit are not tracked.
+	switch g.limits.Action {
+	case CardinalityError:
+		return false, fmt.Errorf("task exceeded its limit of %d series of measurement %q", max, name)
+	case CardinalitySample:
+		m.limited++
+		if g.limits.SampleEvery > 0 && (m.limited-1)%g.limits.SampleEvery == 0 {
+			m.sampled++
+			return true, nil
+		}
+	}
+	m.dropped++
+	return false, nil
+}
+
+// expire deletes the series that are no longer live. Caller must have lock.
+func (g *cardinalityGovernor) expire(now time.Time) {
+	ttl := g.limits.SeriesTTL
+	// Expire at most every half TTL so that not every point scans the series.
+	if ttl <= 0 || now.Sub(g.lastExpire) < ttl/2 {
+		return
+	}
+	g.lastExpire = now
+	for _, m := range g.measurements {
+		for key, s := range m.series {
+			if now.Sub(s.lastSeen) >= ttl {
+				delete(m.series, key)
+			}
+		}
+	}
+}
+
+// cardinality returns the live cardinality of each measurement, ordered by descending number of series,
+// with at most k top tags and tag values.
+func (g *cardinalityGovernor) cardinality(k int) []MeasurementCardinality {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.expire(g.now())
+
+	cs := make([]MeasurementCardinality, 0, len(g.measurements))
+	for name, m := range g.measurements {
+		c := MeasurementCardinality{
+			Measurement: name,
+			Series:      int64(len(m.series)),
+			MaxSeries:   g.limits.maxSeries(name),
+			Dropped:     m.dropped,
+			Sampled:     m.sampled,
+		}
+		// Number of series of each value of each tag
+		values := make(map[string]map[string]int64)
+		for _, s := range m.series {
+			for tag, value := range s.tags {
+				if values[tag] == nil {
+					values[tag] = make(map[string]int64)
+				}
+				values[tag][value]++
+			}
+		}
+		for tag, vs := range values {
+			c.TopTags = append(c.TopTags, TagCardinality{Tag: tag, Values: int64(len(vs))})
+			for value, series := range vs {
+				c.TopValues = append(c.TopValues, TagValueCardinality{Tag: tag, Value: value, Series: series})
+			}
+		}
+		sort.Slice(c.TopTags, func(i, j int) bool {
+			a, b := c.TopTags[i], c.TopTags[j]
+			if a.Values != b.Values {
+				return a.Values > b.Values
+			}
+			return a.Tag < b.Tag
+		})
+		sort.Slice(c.TopValues, func(i, j int) bool {
+			a, b := c.TopValues[i], c.TopValues[j]
+			if a.Series != b.Series {
+				return a.Series > b.Series
+			}
+			if a.Tag != b.Tag {
+				return a.Tag < b.Tag
+			}
+			return a.Value < b.Value
+		})
+		if len(c.TopTags) > k {
+			c.TopTags = c.TopTags[:k]
+		}
+		if len(c.TopValues) > k {
+			c.TopValues = c.TopValues[:k]
+		}
+		cs = append(cs, c)
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].Series != cs[j].Series {
+			return cs[i].Series > cs[j].Series
+		}
+		return cs[i].Measurement < cs[j].Measurement
+	})
+	return cs
+}
@@ -0,0 +1,122 @@
+package kapacitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/models"
+)
+
+func cardinalityPoint(name string, tags models.Tags) edge.PointMessage {
+	return edge.NewPointMessage(name, "db", "rp", models.Dimensions{}, models.Fields{"value": 1.0}, tags, time.Time{})
+}
+
+func TestCardinalityGovernor_Drop(t *testing.T) {
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := newCardinalityGovernor(CardinalityLimits{
+		Enabled:      true,
+		MaxSeries:    2,
+		Measurements: map[string]int64{"mem": 1},
+		Action:       CardinalityDrop,
+		SeriesTTL:    time.Minute,
+	})
+	g.now = func() time.Time { return now }
+
+	points := []struct {
+		p   edge.PointMessage
+		exp bool
+	}{
+		{p: cardinalityPoint("cpu", models.Tags{"host": "a"}), exp: true},
+		{p: cardinalityPoint("cpu", models.Tags{"host": "b"}), exp: true},
+		{p: cardinalityPoint("cpu", models.Tags{"host": "c"}), exp: false},
+		// Known series are still admitted.
+		{p: cardinalityPoint("cpu", models.Tags{"host": "a"}), exp: true},
+		{p: cardinalityPoint("mem", models.Tags{"host": "a"}), exp: true},
+		{p: cardinalityPoint("mem", models.Tags{"host": "b"}), exp: false},
+	}
+	for i, tc := range points {
+		got, err := g.admit(tc.p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.exp {
+			t.Errorf("%d: unexpected admit got %v exp %v", i, got, tc.exp)
+		}
+	}
+
+	// Series a is seen again, b expires.
+	now = now.Add(40 * time.Second)
+	g.admit(cardinalityPoint("cpu", models.Tags{"host": "a"}))
+	now = now.Add(40 * time.Second)
+	if ok, _ := g.admit(cardinalityPoint("cpu", models.Tags{"host": "c"})); !ok {
+		t.Error("expected new series to be admitted after a series expired")
+	}
+
+	exp := []MeasurementCardinality{
+		{
+			Measurement: "cpu",
+			Series:      2,
+			MaxSeries:   2,
+			Dropped:     1,
+			TopTags:     []TagCardinality{{Tag: "host", Values: 2}},
+			TopValues:   []TagValueCardinality{{Tag: "host", Value: "a", Series: 1}, {Tag: "host", Value: "c", Series: 1}},
+		},
+		{
+			Measurement: "mem",
+			Series:      0,
+			MaxSeries:   1,
+			Dropped:     1,
+		},
+	}
+	if got := g.cardinality(10); !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected cardinality:\ngot %+v\nexp %+v", got, exp)
+	}
+}
+
+func TestCardinalityGovernor_Sample(t *testing.T) {
+	g := newCardinalityGovernor(CardinalityLimits{
+		Enabled:     true,
+		MaxSeries:   1,
+		Action:      CardinalitySample,
+		SampleEvery: 3,
+		SeriesTTL:   time.Minute,
+	})
+	g.admit(cardinalityPoint("cpu", models.Tags{"host": "a"}))
+	var admitted []bool
+	for i := 0; i < 6; i++ {
+		ok, err := g.admit(cardinalityPoint("cpu", models.Tags{"id": string(rune('a' + i))}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		admitted = append(admitted, ok)
+	}
+	if exp := []bool{true, false, false, true, false, false}; !reflect.DeepEqual(admitted, exp) {
+		t.Errorf("unexpected sampling got %v exp %v", admitted, exp)
+	}
+	c := g.cardinality(10)
+	if c[0].Series != 1 || c[0].Sampled != 2 || c[0].Dropped != 4 {
+		t.Errorf("unexpected cardinality %+v", c[0])
+	}
+}
+
+func TestCardinalityGovernor_Error(t *testing.T) {
+	g := newCardinalityGovernor(CardinalityLimits{
+		Enabled:   true,
+		MaxSeries: 1,
+		Action:    CardinalityError,
+		SeriesTTL: time.Minute,
+	})
+	if _, err := g.admit(cardinalityPoint("cpu", models.Tags{"host": "a"})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.admit(cardinalityPoint("cpu", models.Tags{"host": "b"})); err == nil {
+		t.Error("expected error when exceeding the limit")
+	}
+
+	var nilGovernor *cardinalityGovernor
+	if ok, err := nilGovernor.admit(cardinalityPoint("cpu", nil)); !ok || err != nil {
+		t.Errorf("unexpected admit from nil governor: %v %v", ok, err)
+	}
+}
@@ -13,7 +13,9 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"path"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/influxdata/influxdb/influxql"
@@ -32,19 +34,37 @@ const (
 	basePath          = "/kapacitor/v1"
 	basePreviewPath   = "/kapacitor/v1preview"
 	pingPath          = basePath + "/ping"
+	healthPath        = basePath + "/health"
+	readyPath         = basePath + "/ready"
+	influxdbPath      = basePath + "/influxdb"
+	dbrpsPath         = basePath + "/dbrps"
+	sideloadPath      = basePath + "/sideload"
 	logLevelPath      = basePath + "/loglevel"
 	logsPath          = basePreviewPath + "/logs"
 	debugVarsPath     = basePath + "/debug/vars"
 	tasksPath         = basePath + "/tasks"
+	tasksBulkPath     = basePath + "/tasks/bulk"
+	tasksValidatePath = basePath + "/tasks/validate"
+	tasksLintPath     = basePath + "/tasks/lint"
 	templatesPath     = basePath + "/templates"
 	recordingsPath    = basePath + "/recordings"
 	recordStreamPath  = basePath + "/recordings/stream"
 	recordBatchPath   = basePath + "/recordings/batch"
 	recordQueryPath   = basePath + "/recordings/query"
+	ringBufferPath    = basePath + "/recordings/ring-buffer"
+	recordImportPath  = basePath + "/recordings/import"
+	recordAlertsPath  = basePath + "/recordings/alerts"
 	replaysPath       = basePath + "/replays"
 	replayBatchPath   = basePath + "/replays/batch"
 	replayQueryPath   = basePath + "/replays/query"
+	replayDiffPath    = basePath + "/replays/diff"
+	replayAlertsPath  = basePath + "/replays/alerts"
+	evaluatePath      = basePath + "/replays/evaluate"
+	taskTestPath      = basePath + "/replays/test"
+	debugPath         = basePath + "/debug"
+	shadowsPath       = basePath + "/shadows"
 	configPath        = basePath + "/config"
+	configDiffPath    = "diff"
 	serviceTestsPath  = basePath + "/service-tests"
 	alertsPath        = basePath + "/alerts"
 	topicsPath        = alertsPath + "/topics"
@@ -53,6 +73,20 @@ const (
 	storagePath       = basePath + "/storage"
 	storesPath        = storagePath + "/stores"
 	backupPath        = storagePath + "/backup"
+	storageRekeyPath  = storagePath + "/rekey"
+	loadStatusPath    = basePath + "/load/status"
+	bundlePath        = basePath + "/bundle"
+	udfsPath          = basePath + "/udfs"
+	udfUpgradePath    = "upgrade"
+	usersPath         = basePath + "/users"
+	rolesPath         = basePath + "/roles"
+	oidcPath          = basePath + "/oidc"
+	apiTokensPath     = basePath + "/tokens"
+	auditPath         = basePath + "/audit"
+	haPath            = basePath + "/ha"
+	haSnapshotPath    = haPath + "/snapshot"
+	clusterPath       = basePath + "/cluster"
+	clusterWritePath  = clusterPath + "/write"
 )
 
 // HTTP configuration for connecting to Kapacitor
@@ -237,6 +271,9 @@ const (
 	InvalidTask TaskType = 0
 	StreamTask  TaskType = 1
 	BatchTask   TaskType = 2
+	// AlertEvents is the type of recordings of the alert events of a topic,
+	// it is never the type of a task.
+	AlertEvents TaskType = 3
 )
 
 func (tt TaskType) MarshalText() ([]byte, error) {
@@ -245,6 +282,8 @@ func (tt TaskType) MarshalText() ([]byte, error) {
 		return []byte("stream"), nil
 	case BatchTask:
 		return []byte("batch"), nil
+	case AlertEvents:
+		return []byte("alert"), nil
 	case InvalidTask:
 		return []byte("invalid"), nil
 	default:
@@ -258,6 +297,8 @@ func (tt *TaskType) UnmarshalText(text []byte) error {
 		*tt = StreamTask
 	case "batch":
 		*tt = BatchTask
+	case "alert":
+		*tt = AlertEvents
 	case "invalid":
 		*tt = InvalidTask
 	default:
@@ -554,23 +595,120 @@ type Var struct {
 	Description string      `json:"description" yaml:"description"`
 }
 
+// VarSchema constrains the values of a template var.
+type VarSchema struct {
+	// Bounds of int and float vars, unbounded if nil.
+	Min *float64 `json:"min,omitempty" yaml:"min"`
+	Max *float64 `json:"max,omitempty" yaml:"max"`
+	// Allowed values of int, float and string vars, any value if empty.
+	Enum []interface{} `json:"enum,omitempty" yaml:"enum"`
+}
+
+// Labels are arbitrary key/value pairs attached to a task.
+//
+// Tasks can be filtered by their labels using a selector,
+// a comma separated list of requirements that must all be met:
+//
+//	key=value   the label is set to value
+//	key!=value  the label is not set to value
+//	key         the label is set
+//	!key        the label is not set
+type Labels map[string]string
+
+// String returns the labels as comma separated key=value pairs sorted by key.
+func (l Labels) String() string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + l[k]
+	}
+	return strings.Join(pairs, ",")
+}
+
+// TaskSchedule restricts when an enabled task runs.
+// The task is active while the time matches the cron expression or is within any of the windows.
+// Outside of its schedule the task is paused, it processes no data and its deadman checks do not fire.
+type TaskSchedule struct {
+	// Cron expression matching the minutes during which the task is active,
+	// e.g. "* 0-5 * * 1-5" for weekday nights.
+	Cron string `json:"cron,omitempty" yaml:"cron"`
+	// Daily time windows during which the task is active.
+	Windows []TimeWindow `json:"windows,omitempty" yaml:"windows"`
+	// Name of the IANA time zone of the schedule, defaults to UTC.
+	Timezone string `json:"timezone,omitempty" yaml:"timezone"`
+}
+
+// TimeWindow is a daily window of time.
+// The window crosses midnight if Stop is not after Start.
+type TimeWindow struct {
+	// Days of the week the window starts on, e.g. "mon", every day if empty.
+	Days []string `json:"days,omitempty" yaml:"days"`
+	// Time of day of the form "15:04".
+	Start string `json:"start" yaml:"start"`
+	Stop  string `json:"stop" yaml:"stop"`
+}
+
+func (w TimeWindow) String() string {
+	if len(w.Days) == 0 {
+		return w.Start + "-" + w.Stop
+	}
+	return strings.Join(w.Days, ",") + " " + w.Start + "-" + w.Stop
+}
+
+// TaskLimits bounds the resources used by a task.
+// Zero values use the default limits of the server, which may be no limit.
+type TaskLimits struct {
+	// Points read by a stream task above the rate are dropped.
+	MaxPointsPerSecond int64 `json:"max-points-per-second,omitempty" yaml:"max-points-per-second"`
+	// The task fails if any of its nodes has more groups.
+	MaxGroups int64 `json:"max-groups,omitempty" yaml:"max-groups"`
+	// The task fails if its windows buffer more points.
+	MaxBufferedPoints int64 `json:"max-buffered-points,omitempty" yaml:"max-buffered-points"`
+}
+
+// TaskRestartPolicy restarts an enabled task after it stops with an error.
+// The delay before a restart doubles with each consecutive restart, up to MaxBackoff.
+type TaskRestartPolicy struct {
+	// Number of consecutive restarts before giving up.
+	MaxRestarts int `json:"max-restarts" yaml:"max-restarts"`
+	// Delay before the first restart, defaults to 1s.
+	Backoff Duration `json:"backoff,omitempty" yaml:"backoff"`
+	// Maximum delay before a restart, defaults to 5m.
+	// Restarts are no longer consecutive once the task runs this long without error.
+	MaxBackoff Duration `json:"max-backoff,omitempty" yaml:"max-backoff"`
+	// Topic of the critical alert sent when giving up, no alert is sent if empty.
+	Topic string `json:"topic,omitempty" yaml:"topic"`
+}
+
 // A Task plus its read-only attributes.
 type Task struct {
-	Link           Link           `json:"link"`
-	ID             string         `json:"id"`
-	TemplateID     string         `json:"template-id"`
-	Type           TaskType       `json:"type"`
-	DBRPs          []DBRP         `json:"dbrps"`
-	TICKscript     string         `json:"script"`
-	Vars           Vars           `json:"vars"`
-	Dot            string         `json:"dot"`
-	Status         TaskStatus     `json:"status"`
-	Executing      bool           `json:"executing"`
-	Error          string         `json:"error"`
-	ExecutionStats ExecutionStats `json:"stats"`
-	Created        time.Time      `json:"created"`
-	Modified       time.Time      `json:"modified"`
-	LastEnabled    time.Time      `json:"last-enabled,omitempty"`
+	Link           Link               `json:"link"`
+	ID             string             `json:"id"`
+	TemplateID     string             `json:"template-id"`
+	Type           TaskType           `json:"type"`
+	DBRPs          []DBRP             `json:"dbrps"`
+	TICKscript     string             `json:"script"`
+	Vars           Vars               `json:"vars"`
+	Labels         Labels             `json:"labels"`
+	Schedule       *TaskSchedule      `json:"schedule,omitempty"`
+	Limits         TaskLimits         `json:"limits"`
+	Priority       int                `json:"priority"`
+	Restart        *TaskRestartPolicy `json:"restart,omitempty"`
+	Restarts       int                `json:"restarts"`
+	DependsOn      []string           `json:"depends-on,omitempty"`
+	Dot            string             `json:"dot"`
+	Status         TaskStatus         `json:"status"`
+	Executing      bool               `json:"executing"`
+	Paused         bool               `json:"paused"`
+	Error          string             `json:"error"`
+	ExecutionStats ExecutionStats     `json:"stats"`
+	Created        time.Time          `json:"created"`
+	Modified       time.Time          `json:"modified"`
+	LastEnabled    time.Time          `json:"last-enabled,omitempty"`
 }
 
 // A Template plus its read-only attributes.
@@ -584,6 +722,8 @@ type Template struct {
 	Error      string    `json:"error"`
 	Created    time.Time `json:"created"`
 	Modified   time.Time `json:"modified"`
+	// Constraints on the values of the vars, by var name.
+	Schema map[string]VarSchema `json:"schema,omitempty"`
 }
 
 // Information about a recording.
@@ -606,6 +746,7 @@ type Replay struct {
 	Recording      string         `json:"recording"`
 	RecordingTime  bool           `json:"recording-time"`
 	Clock          Clock          `json:"clock"`
+	Speed          float64        `json:"speed,omitempty"`
 	Date           time.Time      `json:"date"`
 	Error          string         `json:"error"`
 	Status         Status         `json:"status"`
@@ -761,6 +902,277 @@ func (c *Client) Ping() (time.Duration, string, error) {
 	return time.Since(now), version, nil
 }
 
+// Status of a health check.
+const (
+	HealthPass = "pass"
+	HealthWarn = "warn"
+	HealthFail = "fail"
+)
+
+// Health is the health of the server and of its subsystems.
+type Health struct {
+	Link Link `json:"link"`
+	// Status is the worst status of the checks.
+	Status string `json:"status"`
+	// Ready reports whether the server is open and its critical checks do not fail.
+	Ready  bool                   `json:"ready"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
+
+// HealthCheck is the health of a subsystem, and of its components.
+type HealthCheck struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	// Critical checks must not fail for the server to be ready.
+	Critical   bool                   `json:"critical,omitempty"`
+	Components map[string]HealthCheck `json:"components,omitempty"`
+}
+
+// Health returns the health of the server and of its subsystems.
+func (c *Client) Health() (Health, error) {
+	u := *c.url
+	u.Path = healthPath
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return Health{}, err
+	}
+
+	h := Health{}
+	// The health is returned with an unavailable status when a check fails.
+	_, err = c.Do(req, &h, http.StatusOK, http.StatusServiceUnavailable)
+	return h, err
+}
+
+// Ready returns an error if the server is not ready to serve requests.
+func (c *Client) Ready() error {
+	u := *c.url
+	u.Path = readyPath
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
+// InfluxDBStatus is the status of the connections to the InfluxDB clusters.
+type InfluxDBStatus struct {
+	Link     Link                             `json:"link"`
+	Clusters map[string]InfluxDBClusterStatus `json:"clusters"`
+}
+
+// InfluxDBClusterStatus is the status of the URLs of an InfluxDB cluster.
+type InfluxDBClusterStatus struct {
+	Default bool `json:"default"`
+	// Healthy reports whether any URL of the cluster is healthy.
+	Healthy bool                `json:"healthy"`
+	URLs    []InfluxDBURLStatus `json:"urls"`
+}
+
+// InfluxDBURLStatus is the result of the last health check of a URL.
+type InfluxDBURLStatus struct {
+	URL       string    `json:"url"`
+	Healthy   bool      `json:"healthy"`
+	LastCheck time.Time `json:"last-check"`
+	Version   string    `json:"version,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// InfluxDBStatus returns the status of the connections to the InfluxDB clusters.
+func (c *Client) InfluxDBStatus() (InfluxDBStatus, error) {
+	u := *c.url
+	u.Path = influxdbPath
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return InfluxDBStatus{}, err
+	}
+
+	s := InfluxDBStatus{}
+	_, err = c.Do(req, &s, http.StatusOK)
+	return s, err
+}
+
+// DBRPsStatus are the databases and retention policies with data written to Kapacitor,
+// consumed by its stream tasks or subscribed to, and the orphaned subscriptions of the InfluxDB clusters.
+type DBRPsStatus struct {
+	Link                  Link                   `json:"link"`
+	DBRPs                 []DBRPUsage            `json:"dbrps"`
+	OrphanedSubscriptions []OrphanedSubscription `json:"orphaned-subscriptions"`
+}
+
+// DBRPUsage is the data flowing to a database and retention policy and its consumers.
+type DBRPUsage struct {
+	Database        string `json:"db"`
+	RetentionPolicy string `json:"rp"`
+	// Live reports whether points were written within the live window.
+	Live           bool      `json:"live"`
+	PointsReceived int64     `json:"points-received"`
+	LastWrite      time.Time `json:"last-write"`
+	// IDs of the executing stream tasks consuming the points.
+	Tasks []string `json:"tasks"`
+	// Names of the InfluxDB clusters subscribed to.
+	Subscriptions []string `json:"subscriptions"`
+}
+
+// OrphanedSubscription is a subscription on an InfluxDB cluster that Kapacitor no longer uses.
+type OrphanedSubscription struct {
+	Cluster         string   `json:"cluster"`
+	Database        string   `json:"db"`
+	RetentionPolicy string   `json:"rp"`
+	Name            string   `json:"name"`
+	Destinations    []string `json:"destinations"`
+	Reason          string   `json:"reason"`
+}
+
+// DBRPsStatusOptions are the options of the DBRPsStatus request.
+type DBRPsStatusOptions struct {
+	// LiveWindow is the time since the last write within which a database and retention policy is live.
+	// The server default is used if zero.
+	LiveWindow time.Duration
+}
+
+func (o *DBRPsStatusOptions) Values() *url.Values {
+	v := &url.Values{}
+	if o.LiveWindow != 0 {
+		v.Set("live-window", o.LiveWindow.String())
+	}
+	return v
+}
+
+// DBRPsStatus returns the usage of the databases and retention policies and the orphaned subscriptions.
+func (c *Client) DBRPsStatus(opt *DBRPsStatusOptions) (DBRPsStatus, error) {
+	if opt == nil {
+		opt = new(DBRPsStatusOptions)
+	}
+	u := *c.url
+	u.Path = dbrpsPath
+	u.RawQuery = opt.Values().Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return DBRPsStatus{}, err
+	}
+
+	d := DBRPsStatus{}
+	_, err = c.Do(req, &d, http.StatusOK)
+	return d, err
+}
+
+// ReconcileResult lists the orphaned subscriptions removed from the InfluxDB clusters.
+type ReconcileResult struct {
+	Removed []OrphanedSubscription `json:"removed"`
+}
+
+// ReconcileDBRPs removes the orphaned subscriptions from the InfluxDB clusters.
+func (c *Client) ReconcileDBRPs() (ReconcileResult, error) {
+	u := *c.url
+	u.Path = dbrpsPath + "/reconcile"
+
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return ReconcileResult{}, err
+	}
+
+	r := ReconcileResult{}
+	_, err = c.Do(req, &r, http.StatusOK)
+	return r, err
+}
+
+// SideloadSources are the sideload sources used by the executing tasks.
+type SideloadSources struct {
+	Link    Link             `json:"link"`
+	Sources []SideloadSource `json:"sources"`
+}
+
+// SideloadSource is a sideload source, the sideload nodes using it and its files as they are now,
+// read again without being applied.
+type SideloadSource struct {
+	Source     string              `json:"source"`
+	References []SideloadReference `json:"references"`
+	Files      []SideloadFile      `json:"files"`
+	// Error of reading the source, its files are empty.
+	Error string `json:"error,omitempty"`
+}
+
+// SideloadReference is a sideload node of a task using a sideload source.
+type SideloadReference struct {
+	Task string `json:"task"`
+	Node string `json:"node"`
+	// Order templates of the node, omitted for the references of a file.
+	Order []string `json:"order,omitempty"`
+	// Keys of the fields and tags loaded by the node,
+	// only the keys present in the file for the references of a file.
+	Keys []string `json:"keys"`
+}
+
+// SideloadFile is a values file of a sideload source.
+type SideloadFile struct {
+	Path string   `json:"path"`
+	Keys []string `json:"keys"`
+	// The sideload nodes with an order template matching the path of the file,
+	// affected by an edit of the file.
+	References []SideloadReference `json:"references"`
+	// Error of reading the values of the file, it would fail a reload.
+	Error string `json:"error,omitempty"`
+}
+
+// SideloadSources returns the sideload sources, the tasks using them and the validation errors of their files.
+func (c *Client) SideloadSources() (SideloadSources, error) {
+	u := *c.url
+	u.Path = sideloadPath + "/sources"
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return SideloadSources{}, err
+	}
+
+	s := SideloadSources{}
+	_, err = c.Do(req, &s, http.StatusOK)
+	return s, err
+}
+
+// ReloadSideload reloads the files of the sideload sources.
+func (c *Client) ReloadSideload() error {
+	u := *c.url
+	u.Path = sideloadPath + "/reload"
+
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
+// OIDCConfig is the OpenID Connect provider used to log in to Kapacitor.
+type OIDCConfig struct {
+	Issuer   string `json:"issuer"`
+	ClientID string `json:"client-id"`
+	// Scopes to request when logging in.
+	Scopes []string `json:"scopes"`
+}
+
+// Get the OpenID Connect provider of the server.
+// It does not require authentication, so that a token can be obtained from the provider.
+func (c *Client) OIDCConfig() (OIDCConfig, error) {
+	o := OIDCConfig{}
+	u := *c.url
+	u.Path = oidcPath
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return o, err
+	}
+
+	_, err = c.Do(req, &o, http.StatusOK)
+	return o, err
+}
+
 func (c *Client) TaskLink(id string) Link {
 	return Link{Relation: Self, Href: path.Join(tasksPath, id)}
 }
@@ -814,6 +1226,17 @@ type CreateTaskOptions struct {
 	TICKscript string     `json:"script,omitempty"`
 	Status     TaskStatus `json:"status,omitempty"`
 	Vars       Vars       `json:"vars,omitempty" yaml:"vars"`
+	Labels     Labels     `json:"labels,omitempty" yaml:"labels"`
+	// Schedule of the task, the task is always active if nil.
+	Schedule *TaskSchedule `json:"schedule,omitempty" yaml:"schedule"`
+	Limits   *TaskLimits   `json:"limits,omitempty" yaml:"limits"`
+	// Priority of a stream task, points are sent to tasks with a higher priority first.
+	Priority *int `json:"priority,omitempty" yaml:"priority"`
+	// Restart policy of the task, the task is not restarted after errors if nil.
+	Restart *TaskRestartPolicy `json:"restart,omitempty" yaml:"restart"`
+	// IDs of the batch tasks whose output this batch task queries,
+	// each query of the task waits until they completed their intervals up to the query time.
+	DependsOn []string `json:"depends-on,omitempty" yaml:"depends-on"`
 }
 
 // Create a new task.
@@ -848,6 +1271,22 @@ type UpdateTaskOptions struct {
 	TICKscript string     `json:"script,omitempty"`
 	Status     TaskStatus `json:"status,omitempty"`
 	Vars       Vars       `json:"vars,omitempty" yaml:"vars"`
+	// Labels replace all existing labels of the task when not nil,
+	// use an empty set of labels to remove them.
+	Labels Labels `json:"labels" yaml:"labels"`
+	// Schedule replaces the schedule of the task when not nil,
+	// use an empty schedule to remove it.
+	Schedule *TaskSchedule `json:"schedule,omitempty" yaml:"schedule"`
+	// Limits replace all limits of the task when not nil.
+	Limits *TaskLimits `json:"limits,omitempty" yaml:"limits"`
+	// Priority replaces the priority of the task when not nil.
+	Priority *int `json:"priority,omitempty" yaml:"priority"`
+	// Restart replaces the restart policy of the task when not nil,
+	// use an empty policy to remove it.
+	Restart *TaskRestartPolicy `json:"restart,omitempty" yaml:"restart"`
+	// DependsOn replaces the dependencies of the task when not nil,
+	// use an empty list to remove them.
+	DependsOn []string `json:"depends-on" yaml:"depends-on"`
 }
 
 // Update an existing task.
@@ -953,197 +1392,1769 @@ func (c *Client) DeleteTask(link Link) error {
 	return err
 }
 
-type ListTasksOptions struct {
-	TaskOptions
-	Pattern string
-	Fields  []string
-	Offset  int
-	Limit   int
-}
-
-func (o *ListTasksOptions) Default() {
-	o.TaskOptions.Default()
-	if o.Limit == 0 {
-		o.Limit = 100
-	}
-}
-
-func (o *ListTasksOptions) Values() *url.Values {
-	v := o.TaskOptions.Values()
-	v.Set("pattern", o.Pattern)
-	for _, field := range o.Fields {
-		v.Add("fields", field)
-	}
-	v.Set("offset", strconv.FormatInt(int64(o.Offset), 10))
-	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
-	return v
+// A version of the definition of a task.
+type TaskVersion struct {
+	Link       Link      `json:"link"`
+	Version    int       `json:"version"`
+	TemplateID string    `json:"template-id"`
+	Type       TaskType  `json:"type"`
+	DBRPs      []DBRP    `json:"dbrps"`
+	TICKscript string    `json:"script"`
+	Vars       Vars      `json:"vars"`
+	Created    time.Time `json:"created"`
 }
 
-// Get tasks.
-func (c *Client) ListTasks(opt *ListTasksOptions) ([]Task, error) {
-	if opt == nil {
-		opt = new(ListTasksOptions)
+// Execution statistics of a task and each of its nodes.
+type TaskStats struct {
+	Link      Link                   `json:"link"`
+	ID        string                 `json:"id"`
+	Executing bool                   `json:"executing"`
+	TaskStats map[string]interface{} `json:"task-stats,omitempty"`
+	NodeStats map[string]NodeStats   `json:"node-stats"`
+}
+
+type NodeStats struct {
+	Collected int64 `json:"collected"`
+	Emitted   int64 `json:"emitted"`
+	Errors    int64 `json:"errors"`
+	// Number of points or batches received by the node that it has not yet processed.
+	QueueDepth int64 `json:"queue-depth"`
+	// Number of points held by the node, such as the points of its windows.
+	Buffered int64 `json:"buffered"`
+	// Estimated bytes of memory held by the node and the points or batches queued for it.
+	Memory  int64        `json:"memory"`
+	Latency LatencyStats `json:"latency"`
+}
+
+// Percentiles of the time a node spent processing points or batches.
+// Only a sample of the points and batches are timed.
+type LatencyStats struct {
+	Count int64    `json:"count"`
+	P50   Duration `json:"p50"`
+	P90   Duration `json:"p90"`
+	P99   Duration `json:"p99"`
+	Max   Duration `json:"max"`
+}
+
+// Get the execution statistics of a task.
+func (c *Client) TaskStats(link Link) (TaskStats, error) {
+	s := TaskStats{}
+	if link.Href == "" {
+		return s, fmt.Errorf("invalid link %v", link)
 	}
-	opt.Default()
 
 	u := *c.url
-	u.Path = tasksPath
-	u.RawQuery = opt.Values().Encode()
+	u.Path = path.Join(link.Href, "stats")
 
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return nil, err
+		return s, err
 	}
 
-	// Response type
-	type response struct {
-		Tasks []Task `json:"tasks"`
-	}
-
-	r := &response{}
+	_, err = c.Do(req, &s, http.StatusOK)
+	return s, err
+}
 
-	_, err = c.Do(req, r, http.StatusOK)
-	if err != nil {
-		return nil, err
-	}
-	return r.Tasks, nil
+// The DOT graph of an executing task with the rates of its edges.
+type TaskDot struct {
+	Link      Link   `json:"link"`
+	ID        string `json:"id"`
+	Executing bool   `json:"executing"`
+	// Interval over which the rates were measured.
+	Interval Duration `json:"interval"`
+	// Each edge is annotated with the number of points or batches it processed,
+	// its rate per second and the number of errors of the node it leads to.
+	Dot string `json:"dot"`
 }
 
-func (c *Client) TaskOutput(link Link, name string) (*influxql.Result, error) {
-	u := *c.url
-	u.Path = path.Join(link.Href, name)
+type TaskDotOptions struct {
+	// Interval over which the rates are measured, defaults to one second.
+	// The request takes at least the interval to complete.
+	Interval time.Duration
+	DotView  string
+}
 
-	req, err := http.NewRequest("GET", u.String(), nil)
-	if err != nil {
-		return nil, err
+func (o *TaskDotOptions) Default() {
+	if o.Interval == 0 {
+		o.Interval = time.Second
 	}
-	r := &influxql.Result{}
-	_, err = c.Do(req, r, http.StatusOK)
-	if err != nil {
-		return nil, err
+	if o.DotView == "" {
+		o.DotView = "attributes"
 	}
-	return r, nil
 }
 
-type CreateTemplateOptions struct {
-	ID         string   `json:"id,omitempty"`
-	Type       TaskType `json:"type,omitempty"`
-	TICKscript string   `json:"script,omitempty"`
+func (o *TaskDotOptions) Values() *url.Values {
+	v := &url.Values{}
+	v.Set("interval", o.Interval.String())
+	v.Set("dot-view", o.DotView)
+	return v
 }
 
-// Create a new template.
-// Errors if the template already exists.
-func (c *Client) CreateTemplate(opt CreateTemplateOptions) (Template, error) {
-	var buf bytes.Buffer
-	enc := json.NewEncoder(&buf)
-	err := enc.Encode(opt)
-	if err != nil {
-		return Template{}, err
+// Get the DOT graph of a task annotated with the current rates of its edges.
+// The DOT graph is empty if the task is not executing.
+func (c *Client) TaskDot(link Link, opt *TaskDotOptions) (TaskDot, error) {
+	d := TaskDot{}
+	if link.Href == "" {
+		return d, fmt.Errorf("invalid link %v", link)
+	}
+	if opt == nil {
+		opt = new(TaskDotOptions)
 	}
+	opt.Default()
 
 	u := *c.url
-	u.Path = templatesPath
+	u.Path = path.Join(link.Href, "dot")
+	u.RawQuery = opt.Values().Encode()
 
-	req, err := http.NewRequest("POST", u.String(), &buf)
+	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return Template{}, err
+		return d, err
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	t := Template{}
-	_, err = c.Do(req, &t, http.StatusOK)
-	return t, err
+	_, err = c.Do(req, &d, http.StatusOK)
+	return d, err
 }
 
-type UpdateTemplateOptions struct {
-	ID         string   `json:"id,omitempty"`
-	Type       TaskType `json:"type,omitempty"`
-	TICKscript string   `json:"script,omitempty"`
+// The live cardinality of the measurements read by a stream task.
+type TaskCardinality struct {
+	Link Link   `json:"link"`
+	ID   string `json:"id"`
+	// Whether the series of the task are tracked,
+	// i.e. the task is an executing stream task and cardinality tracking is enabled.
+	Tracked bool `json:"tracked"`
+	// Measurements ordered by descending number of series.
+	Measurements []MeasurementCardinality `json:"measurements"`
 }
 
-// Update an existing template.
-// Only fields that are not their default value will be updated.
-func (c *Client) UpdateTemplate(link Link, opt UpdateTemplateOptions) (Template, error) {
-	t := Template{}
-	if link.Href == "" {
-		return t, fmt.Errorf("invalid link %v", link)
-	}
-
-	var buf bytes.Buffer
-	enc := json.NewEncoder(&buf)
-	err := enc.Encode(opt)
-	if err != nil {
-		return t, err
-	}
-
-	u := *c.url
-	u.Path = link.Href
+type MeasurementCardinality struct {
+	Measurement string `json:"measurement"`
+	// Number of live series.
+	Series int64 `json:"series"`
+	// Limit of the number of live series, 0 means no limit.
+	MaxSeries int64 `json:"max-series"`
+	// Points of new series above the limit that were dropped or passed on by sampling.
+	Dropped int64 `json:"dropped"`
+	Sampled int64 `json:"sampled"`
+	// Tags with the most distinct values among the live series.
+	TopTags []TagCardinality `json:"top-tags"`
+	// Tag values with the most live series.
+	TopValues []TagValueCardinality `json:"top-values"`
+}
 
-	req, err := http.NewRequest("PATCH", u.String(), &buf)
-	if err != nil {
-		return t, err
-	}
-	req.Header.Set("Content-Type", "application/json")
+type TagCardinality struct {
+	Tag    string `json:"tag"`
+	Values int64  `json:"values"`
+}
 
-	_, err = c.Do(req, &t, http.StatusOK)
-	if err != nil {
-		return t, err
-	}
-	return t, nil
+type TagValueCardinality struct {
+	Tag    string `json:"tag"`
+	Value  string `json:"value"`
+	Series int64  `json:"series"`
 }
 
-type TemplateOptions struct {
-	ScriptFormat string
+type TaskCardinalityOptions struct {
+	// Number of top tags and tag values of each measurement, defaults to 10.
+	K int
 }
 
-func (o *TemplateOptions) Default() {
-	if o.ScriptFormat == "" {
-		o.ScriptFormat = "formatted"
+func (o *TaskCardinalityOptions) Default() {
+	if o.K == 0 {
+		o.K = 10
 	}
 }
 
-func (o *TemplateOptions) Values() *url.Values {
+func (o *TaskCardinalityOptions) Values() *url.Values {
 	v := &url.Values{}
-	v.Set("script-format", o.ScriptFormat)
+	v.Set("k", strconv.Itoa(o.K))
 	return v
 }
 
-// Get information about a template.
-// Options can be nil and the default options will be used.
-// By default the TICKscript contents are formatted, use ScriptFormat="raw" to return the TICKscript unmodified.
-func (c *Client) Template(link Link, opt *TemplateOptions) (Template, error) {
-	template := Template{}
+// Get the live cardinality of the measurements read by a stream task.
+func (c *Client) TaskCardinality(link Link, opt *TaskCardinalityOptions) (TaskCardinality, error) {
+	tc := TaskCardinality{}
 	if link.Href == "" {
-		return template, fmt.Errorf("invalid link %v", link)
+		return tc, fmt.Errorf("invalid link %v", link)
 	}
-
 	if opt == nil {
-		opt = new(TemplateOptions)
+		opt = new(TaskCardinalityOptions)
 	}
 	opt.Default()
 
 	u := *c.url
-	u.Path = link.Href
+	u.Path = path.Join(link.Href, "cardinality")
 	u.RawQuery = opt.Values().Encode()
 
 	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return tc, err
+	}
+
+	_, err = c.Do(req, &tc, http.StatusOK)
+	return tc, err
+}
+
+// The CPU profile of an executing task, attributing the CPU time of the task to its nodes.
+type TaskProfile struct {
+	Link      Link   `json:"link"`
+	ID        string `json:"id"`
+	Executing bool   `json:"executing"`
+	// Duration over which the profile was collected.
+	Duration Duration `json:"duration"`
+	// CPU time of the task.
+	CPU Duration `json:"cpu"`
+	// CPU time of each node by node name.
+	Nodes map[string]NodeProfile `json:"nodes"`
+}
+
+type NodeProfile struct {
+	CPU Duration `json:"cpu"`
+	// Percentage of the CPU time of the task.
+	Percent float64 `json:"percent"`
+}
+
+type TaskProfileOptions struct {
+	// Duration over which the profile is collected, defaults to ten seconds.
+	// The request takes at least the duration to complete.
+	Duration time.Duration
+	// Format of the profile, one of "json", "pprof" or "folded", defaults to "json".
+	// The pprof format is read by go tool pprof,
+	// the folded format has a line per stack starting with the node name and is read by flame graph tools.
+	Format string
+}
+
+func (o *TaskProfileOptions) Default() {
+	if o.Duration == 0 {
+		o.Duration = 10 * time.Second
+	}
+	if o.Format == "" {
+		o.Format = "json"
+	}
+}
+
+func (o *TaskProfileOptions) Values() *url.Values {
+	v := &url.Values{}
+	v.Set("duration", o.Duration.String())
+	v.Set("format", o.Format)
+	return v
+}
+
+// Profile the CPU of the nodes of a task.
+// Only one profile can be collected at a time.
+func (c *Client) TaskProfile(link Link, opt *TaskProfileOptions) (TaskProfile, error) {
+	p := TaskProfile{}
+	if opt == nil {
+		opt = new(TaskProfileOptions)
+	}
+	opt.Format = "json"
+	req, err := c.taskProfileRequest(link, opt)
+	if err != nil {
+		return p, err
+	}
+	_, err = c.Do(req, &p, http.StatusOK)
+	return p, err
+}
+
+// Profile the CPU of the nodes of a task, writing the profile in the pprof or folded format.
+func (c *Client) WriteTaskProfile(w io.Writer, link Link, opt *TaskProfileOptions) error {
+	req, err := c.taskProfileRequest(link, opt)
+	if err != nil {
+		return err
+	}
+	if err := c.prepRequest(req); err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return c.decodeError(resp)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (c *Client) taskProfileRequest(link Link, opt *TaskProfileOptions) (*http.Request, error) {
+	if link.Href == "" {
+		return nil, fmt.Errorf("invalid link %v", link)
+	}
+	if opt == nil {
+		opt = new(TaskProfileOptions)
+	}
+	opt.Default()
+
+	u := *c.url
+	u.Path = path.Join(link.Href, "profile")
+	u.RawQuery = opt.Values().Encode()
+
+	return http.NewRequest("GET", u.String(), nil)
+}
+
+// A series of points, or a batch, emitted by a node of a task.
+type Row struct {
+	Name    string            `json:"name,omitempty"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	Columns []string          `json:"columns,omitempty"`
+	Values  [][]interface{}   `json:"values,omitempty"`
+}
+
+type TapOptions struct {
+	// Number of points or batches to receive, defaults to 10.
+	Count int
+	// Maximum time to wait for the points or batches, defaults to one minute.
+	Timeout time.Duration
+}
+
+func (o *TapOptions) Default() {
+	if o.Count == 0 {
+		o.Count = 10
+	}
+	if o.Timeout == 0 {
+		o.Timeout = time.Minute
+	}
+}
+
+func (o *TapOptions) Values() *url.Values {
+	v := &url.Values{}
+	v.Set("count", strconv.Itoa(o.Count))
+	v.Set("timeout", o.Timeout.String())
+	return v
+}
+
+// Tap the output of a node of an executing task.
+// The function f is called with a row for each point or batch the node emits,
+// until the count of the options is reached, the timeout elapses, the task stops or the context is done.
+func (c *Client) TapTask(ctx context.Context, link Link, node string, opt *TapOptions, f func(Row) error) error {
+	if link.Href == "" {
+		return fmt.Errorf("invalid link %v", link)
+	}
+	if opt == nil {
+		opt = new(TapOptions)
+	}
+	opt.Default()
+
+	u := *c.url
+	u.Path = path.Join(link.Href, "tap", node)
+	u.RawQuery = opt.Values().Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	err = c.prepRequest(req)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.decodeError(resp)
+	}
+
+	d := json.NewDecoder(resp.Body)
+	for {
+		var row Row
+		if err := d.Decode(&row); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to decode JSON: %v", err)
+		}
+		if err := f(row); err != nil {
+			return err
+		}
+	}
+}
+
+// Types of the entries of the live feed of a task.
+const (
+	TaskWatchError = "error"
+	TaskWatchAlert = "alert"
+	TaskWatchStats = "stats"
+)
+
+// TaskWatchEvent is an entry of the live feed of a task,
+// an error of one of its nodes, an alert event it emitted or its execution statistics.
+type TaskWatchEvent struct {
+	// Type is one of TaskWatchError, TaskWatchAlert or TaskWatchStats,
+	// only the field of the type is set.
+	Type  string     `json:"type"`
+	Time  time.Time  `json:"time"`
+	Error *NodeError `json:"error,omitempty"`
+	Alert *TaskAlert `json:"alert,omitempty"`
+	Stats *TaskStats `json:"stats,omitempty"`
+}
+
+// NodeError is an error logged by a node of a task.
+type NodeError struct {
+	Node    string `json:"node,omitempty"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+	// Fields are the other keys and values of the log message.
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+// TaskAlert is an alert event emitted by a task.
+type TaskAlert struct {
+	Topic string     `json:"topic"`
+	ID    string     `json:"id"`
+	State EventState `json:"state"`
+}
+
+type WatchTaskOptions struct {
+	// Interval between the execution statistics of the task, defaults to 10s.
+	Interval time.Duration
+}
+
+func (o *WatchTaskOptions) Default() {
+	if o.Interval == 0 {
+		o.Interval = 10 * time.Second
+	}
+}
+
+func (o *WatchTaskOptions) Values() *url.Values {
+	v := &url.Values{}
+	v.Set("interval", o.Interval.String())
+	return v
+}
+
+// Watch the live feed of a task.
+// The function f is called with the errors of the nodes of the task, the alert events it emits
+// and its execution statistics at each interval, until it returns an error or the context is done.
+func (c *Client) WatchTask(ctx context.Context, link Link, opt *WatchTaskOptions, f func(TaskWatchEvent) error) error {
+	if link.Href == "" {
+		return fmt.Errorf("invalid link %v", link)
+	}
+	if opt == nil {
+		opt = new(WatchTaskOptions)
+	}
+	opt.Default()
+
+	u := *c.url
+	u.Path = path.Join(link.Href, "watch")
+	u.RawQuery = opt.Values().Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	err = c.prepRequest(req)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.decodeError(resp)
+	}
+
+	d := json.NewDecoder(resp.Body)
+	for {
+		var e TaskWatchEvent
+		if err := d.Decode(&e); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to decode JSON: %v", err)
+		}
+		if err := f(e); err != nil {
+			return err
+		}
+	}
+}
+
+// Get all saved versions of a task, oldest first.
+func (c *Client) ListTaskVersions(link Link) ([]TaskVersion, error) {
+	if link.Href == "" {
+		return nil, fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = path.Join(link.Href, "versions")
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	type response struct {
+		Versions []TaskVersion `json:"versions"`
+	}
+	r := &response{}
+
+	_, err = c.Do(req, r, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return r.Versions, nil
+}
+
+// Get a version of a task.
+func (c *Client) TaskVersion(link Link) (TaskVersion, error) {
+	v := TaskVersion{}
+	if link.Href == "" {
+		return v, fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return v, err
+	}
+
+	_, err = c.Do(req, &v, http.StatusOK)
+	return v, err
+}
+
+type RollbackTaskOptions struct {
+	Version int `json:"version"`
+}
+
+// Restore the definition of a task to a saved version.
+// The restored definition is saved as a new version and the task is reloaded if it is enabled.
+func (c *Client) RollbackTask(link Link, opt RollbackTaskOptions) (Task, error) {
+	t := Task{}
+	if link.Href == "" {
+		return t, fmt.Errorf("invalid link %v", link)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return t, err
+	}
+
+	u := *c.url
+	u.Path = path.Join(link.Href, "rollback")
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return t, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &t, http.StatusOK)
+	return t, err
+}
+
+type BulkTaskAction string
+
+const (
+	BulkEnable  BulkTaskAction = "enable"
+	BulkDisable BulkTaskAction = "disable"
+	BulkReload  BulkTaskAction = "reload"
+	BulkDelete  BulkTaskAction = "delete"
+)
+
+type BulkTasksOptions struct {
+	Action BulkTaskAction `json:"action"`
+	// Glob patterns of the task IDs to act on.
+	Patterns []string `json:"patterns,omitempty"`
+	// Selector of the labels of the tasks to act on.
+	// When both are set, only tasks matching a pattern and the selector are acted on.
+	Selector string `json:"selector,omitempty"`
+}
+
+type BulkTasksResult struct {
+	Action BulkTaskAction `json:"action"`
+	// IDs of the tasks matching the patterns.
+	Tasks  []string        `json:"tasks"`
+	Errors []BulkTaskError `json:"errors,omitempty"`
+}
+
+type BulkTaskError struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// Perform an action on all tasks matching the patterns.
+// Either all tasks are changed or none are,
+// in which case the result contains the errors that prevented the change.
+func (c *Client) BulkTasks(opt BulkTasksOptions) (BulkTasksResult, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return BulkTasksResult{}, err
+	}
+
+	u := *c.url
+	u.Path = tasksBulkPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return BulkTasksResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	r := BulkTasksResult{}
+	resp, err := c.Do(req, &r, http.StatusOK, http.StatusConflict)
+	if err != nil {
+		return BulkTasksResult{}, err
+	}
+	if resp.StatusCode == http.StatusConflict {
+		return r, fmt.Errorf("failed to %s %d task(s), no tasks were changed", r.Action, len(r.Errors))
+	}
+	return r, nil
+}
+
+type ValidateTaskOptions struct {
+	// ID is optional and only used to name the DOT graph.
+	ID    string   `json:"id,omitempty"`
+	Type  TaskType `json:"type,omitempty"`
+	DBRPs []DBRP   `json:"dbrps,omitempty"`
+	// TICKscript to validate.
+	TICKscript string `json:"script"`
+	Vars       Vars   `json:"vars,omitempty"`
+}
+
+type TaskValidation struct {
+	Valid bool     `json:"valid"`
+	Type  TaskType `json:"type"`
+	DBRPs []DBRP   `json:"dbrps"`
+	// Errors that prevent the task from being created.
+	Errors []ValidationMessage `json:"errors,omitempty"`
+	// Warnings about likely mistakes, the task can still be created.
+	Warnings []ValidationMessage `json:"warnings,omitempty"`
+	// DOT graph of the compiled pipeline, empty if the task is not valid.
+	Dot string `json:"dot,omitempty"`
+}
+
+// ValidationMessage describes a problem found in a TICKscript.
+// Line and Char are zero when the position is not known.
+type ValidationMessage struct {
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+	Char    int    `json:"char,omitempty"`
+	// Node is the name of the pipeline node the message applies to, if any.
+	Node string `json:"node,omitempty"`
+}
+
+// Validate a task without creating it.
+// The TICKscript is fully compiled against the type and dbrps,
+// the returned error is only non nil if the validation could not be performed.
+func (c *Client) ValidateTask(opt ValidateTaskOptions) (TaskValidation, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return TaskValidation{}, err
+	}
+
+	u := *c.url
+	u.Path = tasksValidatePath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return TaskValidation{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	v := TaskValidation{}
+	_, err = c.Do(req, &v, http.StatusOK)
+	return v, err
+}
+
+type LintOptions struct {
+	// TICKscript to format and lint.
+	TICKscript string `json:"script"`
+}
+
+type TICKscriptLint struct {
+	// Formatted is the TICKscript in its canonical form, empty if it could not be parsed.
+	Formatted string `json:"formatted"`
+	// Changed reports whether formatting changed the TICKscript.
+	Changed bool `json:"changed"`
+	// Errors that prevent the TICKscript from being parsed.
+	Errors []ValidationMessage `json:"errors,omitempty"`
+	// Findings of the lint rules, ordered by position.
+	Findings []LintFinding `json:"findings,omitempty"`
+}
+
+// LintFinding describes a likely mistake or bad practice found in a TICKscript.
+type LintFinding struct {
+	// Rule is the name of the rule, e.g. unused-var, deprecated or missing-quiet.
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Line    int    `json:"line"`
+	Char    int    `json:"char"`
+}
+
+// Format a TICKscript in its canonical form and report lint findings.
+// The TICKscript is only parsed, the returned error is only non nil if the request failed.
+func (c *Client) LintTICKscript(opt LintOptions) (TICKscriptLint, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return TICKscriptLint{}, err
+	}
+
+	u := *c.url
+	u.Path = tasksLintPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return TICKscriptLint{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	l := TICKscriptLint{}
+	_, err = c.Do(req, &l, http.StatusOK)
+	return l, err
+}
+
+type ListTasksOptions struct {
+	TaskOptions
+	Pattern string
+	// Selector filters tasks by their labels, see Labels.
+	Selector string
+	Fields   []string
+	Offset   int
+	Limit    int
+}
+
+func (o *ListTasksOptions) Default() {
+	o.TaskOptions.Default()
+	if o.Limit == 0 {
+		o.Limit = 100
+	}
+}
+
+func (o *ListTasksOptions) Values() *url.Values {
+	v := o.TaskOptions.Values()
+	v.Set("pattern", o.Pattern)
+	if o.Selector != "" {
+		v.Set("selector", o.Selector)
+	}
+	for _, field := range o.Fields {
+		v.Add("fields", field)
+	}
+	v.Set("offset", strconv.FormatInt(int64(o.Offset), 10))
+	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
+	return v
+}
+
+// Get tasks.
+func (c *Client) ListTasks(opt *ListTasksOptions) ([]Task, error) {
+	if opt == nil {
+		opt = new(ListTasksOptions)
+	}
+	opt.Default()
+
+	u := *c.url
+	u.Path = tasksPath
+	u.RawQuery = opt.Values().Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Response type
+	type response struct {
+		Tasks []Task `json:"tasks"`
+	}
+
+	r := &response{}
+
+	_, err = c.Do(req, r, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return r.Tasks, nil
+}
+
+func (c *Client) TaskOutput(link Link, name string) (*influxql.Result, error) {
+	u := *c.url
+	u.Path = path.Join(link.Href, name)
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	r := &influxql.Result{}
+	_, err = c.Do(req, r, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+type CreateTemplateOptions struct {
+	ID         string               `json:"id,omitempty"`
+	Type       TaskType             `json:"type,omitempty"`
+	TICKscript string               `json:"script,omitempty"`
+	Schema     map[string]VarSchema `json:"schema,omitempty"`
+}
+
+// Create a new template.
+// Errors if the template already exists.
+func (c *Client) CreateTemplate(opt CreateTemplateOptions) (Template, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return Template{}, err
+	}
+
+	u := *c.url
+	u.Path = templatesPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return Template{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	t := Template{}
+	_, err = c.Do(req, &t, http.StatusOK)
+	return t, err
+}
+
+type UpdateTemplateOptions struct {
+	ID         string   `json:"id,omitempty"`
+	Type       TaskType `json:"type,omitempty"`
+	TICKscript string   `json:"script,omitempty"`
+	// Schema replaces the schema of the template when not nil,
+	// an empty schema removes it.
+	Schema map[string]VarSchema `json:"schema"`
+}
+
+// Update an existing template.
+// Only fields that are not their default value will be updated.
+func (c *Client) UpdateTemplate(link Link, opt UpdateTemplateOptions) (Template, error) {
+	t := Template{}
+	if link.Href == "" {
+		return t, fmt.Errorf("invalid link %v", link)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return t, err
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("PATCH", u.String(), &buf)
+	if err != nil {
+		return t, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &t, http.StatusOK)
+	if err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+type TemplateOptions struct {
+	ScriptFormat string
+}
+
+func (o *TemplateOptions) Default() {
+	if o.ScriptFormat == "" {
+		o.ScriptFormat = "formatted"
+	}
+}
+
+func (o *TemplateOptions) Values() *url.Values {
+	v := &url.Values{}
+	v.Set("script-format", o.ScriptFormat)
+	return v
+}
+
+// Get information about a template.
+// Options can be nil and the default options will be used.
+// By default the TICKscript contents are formatted, use ScriptFormat="raw" to return the TICKscript unmodified.
+func (c *Client) Template(link Link, opt *TemplateOptions) (Template, error) {
+	template := Template{}
+	if link.Href == "" {
+		return template, fmt.Errorf("invalid link %v", link)
+	}
+
+	if opt == nil {
+		opt = new(TemplateOptions)
+	}
+	opt.Default()
+
+	u := *c.url
+	u.Path = link.Href
+	u.RawQuery = opt.Values().Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return template, err
+	}
+
+	_, err = c.Do(req, &template, http.StatusOK)
 	if err != nil {
 		return template, err
 	}
+	return template, nil
+}
+
+// Delete a template.
+func (c *Client) DeleteTemplate(link Link) error {
+	if link.Href == "" {
+		return fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("DELETE", u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
+type ListTemplatesOptions struct {
+	TemplateOptions
+	Pattern string
+	Fields  []string
+	Offset  int
+	Limit   int
+}
+
+func (o *ListTemplatesOptions) Default() {
+	o.TemplateOptions.Default()
+	if o.Limit == 0 {
+		o.Limit = 100
+	}
+}
+
+func (o *ListTemplatesOptions) Values() *url.Values {
+	v := o.TemplateOptions.Values()
+	v.Set("pattern", o.Pattern)
+	for _, field := range o.Fields {
+		v.Add("fields", field)
+	}
+	v.Set("offset", strconv.FormatInt(int64(o.Offset), 10))
+	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
+	return v
+}
+
+// Get templates.
+func (c *Client) ListTemplates(opt *ListTemplatesOptions) ([]Template, error) {
+	if opt == nil {
+		opt = new(ListTemplatesOptions)
+	}
+	opt.Default()
+
+	u := *c.url
+	u.Path = templatesPath
+	u.RawQuery = opt.Values().Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Response type
+	type response struct {
+		Templates []Template `json:"templates"`
+	}
+
+	r := &response{}
+
+	_, err = c.Do(req, r, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return r.Templates, nil
+}
+
+// Get information about a recording.
+func (c *Client) Recording(link Link) (Recording, error) {
+	r := Recording{}
+	if link.Href == "" {
+		return r, fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return r, err
+	}
+
+	_, err = c.Do(req, &r, http.StatusOK, http.StatusAccepted)
+	if err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+func (c *Client) RecordingLink(id string) Link {
+	return Link{Relation: Self, Href: path.Join(recordingsPath, id)}
+}
+
+type RecordStreamOptions struct {
+	ID   string    `json:"id,omitempty"`
+	Task string    `json:"task"`
+	Stop time.Time `json:"stop"`
+}
+
+// Record the stream for a task.
+// Returns once the recording is started.
+func (c *Client) RecordStream(opt RecordStreamOptions) (Recording, error) {
+	r := Recording{}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return r, err
+	}
+
+	u := *c.url
+	u.Path = recordStreamPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &r, http.StatusCreated)
+	if err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+type RecordRingBufferOptions struct {
+	ID              string `json:"id,omitempty"`
+	Database        string `json:"db"`
+	RetentionPolicy string `json:"rp"`
+	// How far back to include data, defaults to everything retained by the ring buffer.
+	Duration Duration `json:"duration,omitempty"`
+}
+
+// Save the data retained by the ring buffer of a database and retention policy as a stream recording.
+// Returns once the recording is finished.
+func (c *Client) RecordRingBuffer(opt RecordRingBufferOptions) (Recording, error) {
+	r := Recording{}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return r, err
+	}
+
+	u := *c.url
+	u.Path = ringBufferPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &r, http.StatusCreated)
+	if err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+type RecordImportOptions struct {
+	ID   string   `json:"id,omitempty"`
+	Type TaskType `json:"type"`
+	// Format of the data, either "csv" or "annotated-csv".
+	// Detected from the data if empty.
+	Format string `json:"format,omitempty"`
+	// Database and retention policy of the points of a stream recording.
+	Database        string `json:"db,omitempty"`
+	RetentionPolicy string `json:"rp,omitempty"`
+	// Measurement of all points, overriding the measurement column.
+	Measurement string `json:"measurement,omitempty"`
+	// CSV column with the measurement of each point, defaults to "name".
+	MeasurementColumn string `json:"measurement-column,omitempty"`
+	// CSV column with the time of each point, defaults to "time".
+	TimeColumn string `json:"time-column,omitempty"`
+	// Go layout of the CSV times. By default times are RFC3339 or integer nanoseconds.
+	TimeFormat string `json:"time-format,omitempty"`
+	// CSV columns that are tags, all other columns are fields.
+	TagColumns []string `json:"tag-columns,omitempty"`
+	// The CSV data to import.
+	Data string `json:"data"`
+}
+
+// Import the points of a CSV or InfluxDB 2.x annotated CSV export as a recording.
+// Returns once the recording is finished.
+func (c *Client) RecordImport(opt RecordImportOptions) (Recording, error) {
+	r := Recording{}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return r, err
+	}
+
+	u := *c.url
+	u.Path = recordImportPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &r, http.StatusCreated)
+	if err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+type RecordAlertsOptions struct {
+	ID    string    `json:"id,omitempty"`
+	Topic string    `json:"topic"`
+	Stop  time.Time `json:"stop"`
+}
+
+// Record the alert events of a topic until the stop time.
+// Returns once the recording is started.
+func (c *Client) RecordAlerts(opt RecordAlertsOptions) (Recording, error) {
+	r := Recording{}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return r, err
+	}
+
+	u := *c.url
+	u.Path = recordAlertsPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &r, http.StatusCreated)
+	if err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+type RecordBatchOptions struct {
+	ID    string    `json:"id,omitempty"`
+	Task  string    `json:"task"`
+	Start time.Time `json:"start"`
+	Stop  time.Time `json:"stop"`
+}
+
+// Record the batch queries for a task.
+// Returns once the recording is started.
+func (c *Client) RecordBatch(opt RecordBatchOptions) (Recording, error) {
+	r := Recording{}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return r, err
+	}
+
+	u := *c.url
+	u.Path = recordBatchPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &r, http.StatusCreated)
+	if err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+type RecordQueryOptions struct {
+	ID      string   `json:"id,omitempty"`
+	Query   string   `json:"query"`
+	Type    TaskType `json:"type"`
+	Cluster string   `json:"cluster,omitempty"`
+}
+
+// Record the results of a query.
+// The recordingType must be one of "stream", or "batch".
+// Returns once the recording is started.
+func (c *Client) RecordQuery(opt RecordQueryOptions) (Recording, error) {
+	r := Recording{}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return r, err
+	}
+
+	u := *c.url
+	u.Path = recordQueryPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &r, http.StatusCreated)
+	if err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// Delete a recording.
+func (c *Client) DeleteRecording(link Link) error {
+	if link.Href == "" {
+		return fmt.Errorf("invalid link %v", link)
+	}
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("DELETE", u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
+type ListRecordingsOptions struct {
+	Pattern string
+	Fields  []string
+	Offset  int
+	Limit   int
+}
+
+func (o *ListRecordingsOptions) Default() {
+	if o.Limit == 0 {
+		o.Limit = 100
+	}
+}
+
+func (o *ListRecordingsOptions) Values() *url.Values {
+	v := &url.Values{}
+	v.Set("pattern", o.Pattern)
+	for _, field := range o.Fields {
+		v.Add("fields", field)
+	}
+	v.Set("offset", strconv.FormatInt(int64(o.Offset), 10))
+	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
+	return v
+}
+
+// Get information about recordings.
+// If rids is empty than all recordings are returned.
+func (c *Client) ListRecordings(opt *ListRecordingsOptions) ([]Recording, error) {
+	if opt == nil {
+		opt = new(ListRecordingsOptions)
+	}
+	opt.Default()
+	u := *c.url
+	u.Path = recordingsPath
+	u.RawQuery = opt.Values().Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Decode valid response
+	type response struct {
+		Recordings []Recording `json:"recordings"`
+	}
+
+	r := &response{}
+
+	_, err = c.Do(req, r, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return r.Recordings, nil
+}
+
+func (c *Client) ReplayLink(id string) Link {
+	return Link{Relation: Self, Href: path.Join(replaysPath, id)}
+}
+
+type CreateReplayOptions struct {
+	ID            string `json:"id"`
+	Recording     string `json:"recording"`
+	Task          string `json:"task"`
+	RecordingTime bool   `json:"recording-time"`
+	Clock         Clock  `json:"clock"`
+	// Speed of the Real clock as a multiple of realtime, e.g. 10 or 0.5.
+	// Defaults to realtime.
+	Speed float64 `json:"speed,omitempty"`
+}
+
+func (o *CreateReplayOptions) Default() {
+}
+
+// Replay a recording for a task.
+func (c *Client) CreateReplay(opt CreateReplayOptions) (Replay, error) {
+	r := Replay{}
+
+	opt.Default()
+
+	u := *c.url
+	u.Path = replaysPath
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return r, err
+	}
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &r, http.StatusCreated)
+	if err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+type ReplayBatchOptions struct {
+	ID            string    `json:"id,omitempty"`
+	Task          string    `json:"task"`
+	Start         time.Time `json:"start"`
+	Stop          time.Time `json:"stop"`
+	RecordingTime bool      `json:"recording-time"`
+	Clock         Clock     `json:"clock"`
+	// Speed of the Real clock as a multiple of realtime, e.g. 10 or 0.5.
+	// Defaults to realtime.
+	Speed float64 `json:"speed,omitempty"`
+}
+
+// Replay a query against a task.
+func (c *Client) ReplayBatch(opt ReplayBatchOptions) (Replay, error) {
+	r := Replay{}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return r, err
+	}
+
+	u := *c.url
+	u.Path = replayBatchPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &r, http.StatusCreated)
+	if err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+type ReplayQueryOptions struct {
+	ID            string `json:"id,omitempty"`
+	Task          string `json:"task"`
+	Query         string `json:"query"`
+	Cluster       string `json:"cluster,omitempty"`
+	RecordingTime bool   `json:"recording-time"`
+	Clock         Clock  `json:"clock"`
+	// Speed of the Real clock as a multiple of realtime, e.g. 10 or 0.5.
+	// Defaults to realtime.
+	Speed float64 `json:"speed,omitempty"`
+}
+
+type ReplayDiffOptions struct {
+	Recording string `json:"recording"`
+	// The task to compare against, i.e. the current version.
+	Base string `json:"base"`
+	// The task being compared, i.e. the new version.
+	Task          string `json:"task"`
+	RecordingTime bool   `json:"recording-time"`
+}
+
+// ReplayDiff is the result of replaying a recording to two tasks.
+// Only differences between the alert events and the points written to InfluxDB are listed.
+type ReplayDiff struct {
+	Recording   string       `json:"recording"`
+	Base        string       `json:"base"`
+	Task        string       `json:"task"`
+	Equal       bool         `json:"equal"`
+	BaseEvents  int          `json:"base-events"`
+	TaskEvents  int          `json:"task-events"`
+	BaseOutputs int          `json:"base-outputs"`
+	TaskOutputs int          `json:"task-outputs"`
+	Events      []EventDiff  `json:"events"`
+	Outputs     []OutputDiff `json:"outputs"`
+}
+
+// EventDiff is an alert event that is missing from one of the tasks or has a different level.
+// Events are matched by their source, alert ID and time.
+type EventDiff struct {
+	// Source is the name of the alert node, or "topic:<topic>" for events sent to a topic.
+	Source string      `json:"source"`
+	ID     string      `json:"id"`
+	Time   time.Time   `json:"time"`
+	Base   *EventState `json:"base"`
+	Task   *EventState `json:"task"`
+}
+
+// OutputDiff is a point that was only written by one of the tasks or has different fields.
+// Points are matched by their database, retention policy, measurement, tags and time.
+type OutputDiff struct {
+	Database        string                 `json:"db"`
+	RetentionPolicy string                 `json:"rp"`
+	Measurement     string                 `json:"measurement"`
+	Tags            map[string]string      `json:"tags"`
+	Time            time.Time              `json:"time"`
+	Base            map[string]interface{} `json:"base"`
+	Task            map[string]interface{} `json:"task"`
+}
+
+// Replay a recording to two tasks and compare their alert events and the points they write to InfluxDB.
+// Only alert nodes with handlers or a topic produce events.
+// The replays do not trigger alert handlers or write to InfluxDB.
+// Returns once both replays have finished.
+func (c *Client) ReplayDiff(opt ReplayDiffOptions) (ReplayDiff, error) {
+	d := ReplayDiff{}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return d, err
+	}
+
+	u := *c.url
+	u.Path = replayDiffPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return d, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &d, http.StatusOK)
+	if err != nil {
+		return d, err
+	}
+	return d, nil
+}
+
+type ReplayAlertsOptions struct {
+	Recording string `json:"recording"`
+	// Handlers the events are replayed to, their topics are ignored.
+	Handlers []TopicHandlerOptions `json:"handlers"`
+}
+
+// AlertsReplay is the result of replaying a recording of alert events to handlers.
+type AlertsReplay struct {
+	Recording string `json:"recording"`
+	// Number of events replayed to each handler.
+	Events   int      `json:"events"`
+	Handlers []string `json:"handlers"`
+}
+
+// Replay a recording of alert events to alert handlers.
+// The handlers are only created for the replay and are not added to any topic.
+// Returns once all events have been handled.
+func (c *Client) ReplayAlerts(opt ReplayAlertsOptions) (AlertsReplay, error) {
+	r := AlertsReplay{}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return r, err
+	}
+
+	u := *c.url
+	u.Path = replayAlertsPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &r, http.StatusOK)
+	if err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+type EvaluateOptions struct {
+	Recording string `json:"recording"`
+	// TICKscript to evaluate, its type is the type of the recording.
+	Script string `json:"script"`
+	// Databases and retention policies of the script, unless it has dbrp statements.
+	DBRPs         []DBRP `json:"dbrps,omitempty"`
+	RecordingTime bool   `json:"recording-time"`
+	// Maximum number of rows of each node, alert events and written points returned, defaults to 100.
+	// The first ones are returned.
+	Limit int `json:"limit,omitempty"`
+}
+
+// Evaluation is the result of replaying a recording to a TICKscript that is not defined as a task.
+type Evaluation struct {
+	Recording string `json:"recording"`
+	// The points and batches emitted by the nodes without children.
+	Outputs []NodeOutput `json:"outputs"`
+	// The alert events of the alert nodes with handlers or a topic, the handlers are not triggered.
+	Events []EvaluatedEvent `json:"events"`
+	// The points written by the InfluxDBOut nodes, they are not written to InfluxDB.
+	Writes []WrittenPoint `json:"writes"`
+	// Total number of events and written points, including those past the limit.
+	EventCount     int64          `json:"event-count"`
+	WriteCount     int64          `json:"write-count"`
+	ExecutionStats ExecutionStats `json:"stats"`
+}
+
+// NodeOutput is the output of a node, a row for each point or batch.
+type NodeOutput struct {
+	Node string `json:"node"`
+	Rows []Row  `json:"rows"`
+}
+
+// EvaluatedEvent is an alert event of an evaluation.
+type EvaluatedEvent struct {
+	// Source is the name of the alert node, or "topic:<topic>" for events sent to a topic.
+	Source string     `json:"source"`
+	ID     string     `json:"id"`
+	State  EventState `json:"state"`
+}
+
+// WrittenPoint is a point written to InfluxDB.
+type WrittenPoint struct {
+	Database        string                 `json:"db"`
+	RetentionPolicy string                 `json:"rp"`
+	Measurement     string                 `json:"measurement"`
+	Tags            map[string]string      `json:"tags"`
+	Time            time.Time              `json:"time"`
+	Fields          map[string]interface{} `json:"fields"`
+}
+
+// Evaluate replays a recording to a TICKscript without defining a task,
+// and returns the output of its nodes, its alert events and the points it writes to InfluxDB.
+// The evaluation does not trigger alert handlers or write to InfluxDB.
+// Returns once the replay has finished.
+func (c *Client) Evaluate(opt EvaluateOptions) (Evaluation, error) {
+	e := Evaluation{}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return e, err
+	}
+
+	u := *c.url
+	u.Path = evaluatePath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return e, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &e, http.StatusOK)
+	if err != nil {
+		return e, err
+	}
+	return e, nil
+}
+
+// TaskTest is a test case of a stream task, template or TICKscript,
+// which runs it against input points at a mocked time and checks its alert events
+// and the points it writes to InfluxDB.
+type TaskTest struct {
+	Name string `json:"name"`
+	// Exactly one of the ID of a defined task, the ID of a template or a TICKscript.
+	Task       string `json:"task,omitempty"`
+	Template   string `json:"template,omitempty"`
+	TICKscript string `json:"script,omitempty"`
+	// Vars of the template or TICKscript.
+	Vars Vars `json:"vars,omitempty"`
+	// Databases and retention policies of the template or TICKscript, unless it has dbrp statements.
+	// The input points are written to the first one.
+	DBRPs []DBRP `json:"dbrps,omitempty"`
+	// Time is the mocked time at which the test starts, defaults to the Unix epoch.
+	Time time.Time `json:"time"`
+	// Points are the input points, replayed in time order.
+	Points []TestPoint      `json:"points"`
+	Expect TestExpectations `json:"expect"`
+}
+
+// TestPoint is an input point of a test.
+type TestPoint struct {
+	// At is the offset of the time of the point from the start of the test,
+	// unless the line has a timestamp.
+	At   Duration `json:"at"`
+	Line string   `json:"line"`
+}
+
+// TestExpectations are the alert events and points written to InfluxDB expected in order.
+// Nil lists are not checked, empty lists expect none.
+type TestExpectations struct {
+	Alerts []ExpectedAlert `json:"alerts"`
+	Points []ExpectedPoint `json:"points"`
+}
+
+// ExpectedAlert is an expected alert event, the empty properties match any value.
+type ExpectedAlert struct {
+	ID      string `json:"id,omitempty"`
+	Level   string `json:"level,omitempty"`
+	Message string `json:"message,omitempty"`
+	// At is the offset of the time of the event from the start of the test.
+	At *Duration `json:"at,omitempty"`
+}
+
+// ExpectedPoint is an expected point written to InfluxDB, the empty properties match any value.
+// Tags and fields match exactly if set.
+type ExpectedPoint struct {
+	Database        string                 `json:"db,omitempty"`
+	RetentionPolicy string                 `json:"rp,omitempty"`
+	Measurement     string                 `json:"measurement,omitempty"`
+	Tags            map[string]string      `json:"tags,omitempty"`
+	Fields          map[string]interface{} `json:"fields,omitempty"`
+	// At is the offset of the time of the point from the start of the test.
+	At *Duration `json:"at,omitempty"`
+}
+
+// TaskTestResult is the result of a test case.
+type TaskTestResult struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	// Failures describe each difference between the expected and the actual results.
+	Failures []string `json:"failures,omitempty"`
+	// The actual alert events and points written to InfluxDB.
+	Alerts []EvaluatedEvent `json:"alerts"`
+	Points []WrittenPoint   `json:"points"`
+}
+
+// TestTask runs a test case of a task, template or TICKscript.
+// The test does not trigger alert handlers or write to InfluxDB,
+// the returned error is only non nil if the test could not be run.
+func (c *Client) TestTask(test TaskTest) (TaskTestResult, error) {
+	r := TaskTestResult{}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(test)
+	if err != nil {
+		return r, err
+	}
+
+	u := *c.url
+	u.Path = taskTestPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &r, http.StatusOK)
+	return r, err
+}
+
+// Replay a query against a task.
+func (c *Client) ReplayQuery(opt ReplayQueryOptions) (Replay, error) {
+	r := Replay{}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return r, err
+	}
+
+	u := *c.url
+	u.Path = replayQueryPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &r, http.StatusCreated)
+	if err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// Return the replay information
+func (c *Client) Replay(link Link) (Replay, error) {
+	r := Replay{}
+	if link.Href == "" {
+		return r, fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return r, err
+	}
 
-	_, err = c.Do(req, &template, http.StatusOK)
+	_, err = c.Do(req, &r, http.StatusOK, http.StatusAccepted)
 	if err != nil {
-		return template, err
+		return r, err
 	}
-	return template, nil
+	return r, nil
 }
 
-// Delete a template.
-func (c *Client) DeleteTemplate(link Link) error {
+// Delete a replay. This will cancel a running replay.
+func (c *Client) DeleteReplay(link Link) error {
 	if link.Href == "" {
 		return fmt.Errorf("invalid link %v", link)
 	}
-
 	u := *c.url
 	u.Path = link.Href
 
@@ -1153,26 +3164,27 @@ func (c *Client) DeleteTemplate(link Link) error {
 	}
 
 	_, err = c.Do(req, nil, http.StatusNoContent)
-	return err
+	if err != nil {
+		return err
+	}
+	return nil
 }
 
-type ListTemplatesOptions struct {
-	TemplateOptions
+type ListReplaysOptions struct {
 	Pattern string
 	Fields  []string
 	Offset  int
 	Limit   int
 }
 
-func (o *ListTemplatesOptions) Default() {
-	o.TemplateOptions.Default()
+func (o *ListReplaysOptions) Default() {
 	if o.Limit == 0 {
 		o.Limit = 100
 	}
 }
 
-func (o *ListTemplatesOptions) Values() *url.Values {
-	v := o.TemplateOptions.Values()
+func (o *ListReplaysOptions) Values() *url.Values {
+	v := &url.Values{}
 	v.Set("pattern", o.Pattern)
 	for _, field := range o.Fields {
 		v.Add("fields", field)
@@ -1182,25 +3194,24 @@ func (o *ListTemplatesOptions) Values() *url.Values {
 	return v
 }
 
-// Get templates.
-func (c *Client) ListTemplates(opt *ListTemplatesOptions) ([]Template, error) {
+// Get information about replays.
+// If rids is empty than all replays are returned.
+func (c *Client) ListReplays(opt *ListReplaysOptions) ([]Replay, error) {
 	if opt == nil {
-		opt = new(ListTemplatesOptions)
+		opt = new(ListReplaysOptions)
 	}
 	opt.Default()
-
 	u := *c.url
-	u.Path = templatesPath
+	u.Path = replaysPath
 	u.RawQuery = opt.Values().Encode()
 
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
-
-	// Response type
+	// Decode valid response
 	type response struct {
-		Templates []Template `json:"templates"`
+		Replays []Replay `json:"replays"`
 	}
 
 	r := &response{}
@@ -1209,14 +3220,88 @@ func (c *Client) ListTemplates(opt *ListTemplatesOptions) ([]Template, error) {
 	if err != nil {
 		return nil, err
 	}
-	return r.Templates, nil
+	return r.Replays, nil
 }
 
-// Get information about a recording.
-func (c *Client) Recording(link Link) (Recording, error) {
-	r := Recording{}
+// A debug session replays a recording to a task one point or batch at a time.
+type DebugSession struct {
+	Link      Link   `json:"link"`
+	ID        string `json:"id"`
+	Task      string `json:"task"`
+	Recording string `json:"recording"`
+	// Nodes that pause the session when they emit data.
+	Breakpoints []string `json:"breakpoints"`
+	// Breakpoint that paused the session, if any.
+	Breakpoint string `json:"breakpoint,omitempty"`
+	Status     Status `json:"status"`
+	Paused     bool   `json:"paused"`
+	Error      string `json:"error"`
+	// Number of points or batches of the recording replayed.
+	Steps int64 `json:"steps"`
+	// Time between the first and the last point or batch replayed.
+	Elapsed Duration `json:"elapsed"`
+	// Statistics of each node after the last step.
+	NodeStats map[string]NodeStats `json:"node-stats"`
+}
+
+// Actions of a debug session.
+const (
+	// Replay the next points or batches.
+	DebugStep = "step"
+	// Replay the rest of the recording, until paused or a breakpoint is reached.
+	DebugContinue = "continue"
+	// Pause a continuing session.
+	DebugPause = "pause"
+)
+
+type CreateDebugSessionOptions struct {
+	ID          string   `json:"id"`
+	Task        string   `json:"task"`
+	Recording   string   `json:"recording"`
+	Breakpoints []string `json:"breakpoints,omitempty"`
+}
+
+type UpdateDebugSessionOptions struct {
+	Action string `json:"action,omitempty"`
+	// Number of points or batches to replay with the step action, defaults to 1.
+	Count int64 `json:"count,omitempty"`
+	// Breakpoints of the session, nil leaves them unchanged.
+	Breakpoints []string `json:"breakpoints,omitempty"`
+}
+
+func (c *Client) DebugSessionLink(id string) Link {
+	return Link{Relation: Self, Href: path.Join(debugPath, id)}
+}
+
+// Start a debug session, it is paused before the first point or batch of the recording.
+func (c *Client) CreateDebugSession(opt CreateDebugSessionOptions) (DebugSession, error) {
+	s := DebugSession{}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return s, err
+	}
+
+	u := *c.url
+	u.Path = debugPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return s, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &s, http.StatusCreated)
+	return s, err
+}
+
+// Get the state of a debug session.
+func (c *Client) DebugSession(link Link) (DebugSession, error) {
+	s := DebugSession{}
 	if link.Href == "" {
-		return r, fmt.Errorf("invalid link %v", link)
+		return s, fmt.Errorf("invalid link %v", link)
 	}
 
 	u := *c.url
@@ -1224,127 +3309,405 @@ func (c *Client) Recording(link Link) (Recording, error) {
 
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return r, err
+		return s, err
 	}
 
-	_, err = c.Do(req, &r, http.StatusOK, http.StatusAccepted)
+	_, err = c.Do(req, &s, http.StatusOK)
+	return s, err
+}
+
+// Step, continue or pause a debug session, or change its breakpoints.
+// Stepping returns once the task has processed the replayed points or batches.
+func (c *Client) UpdateDebugSession(link Link, opt UpdateDebugSessionOptions) (DebugSession, error) {
+	s := DebugSession{}
+	if link.Href == "" {
+		return s, fmt.Errorf("invalid link %v", link)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
 	if err != nil {
-		return r, err
+		return s, err
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("PATCH", u.String(), &buf)
+	if err != nil {
+		return s, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &s, http.StatusOK)
+	return s, err
+}
+
+// Delete a debug session, the rest of its recording is replayed so that its task stops.
+func (c *Client) DeleteDebugSession(link Link) error {
+	if link.Href == "" {
+		return fmt.Errorf("invalid link %v", link)
+	}
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("DELETE", u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
+// Get all debug sessions.
+func (c *Client) ListDebugSessions() ([]DebugSession, error) {
+	u := *c.url
+	u.Path = debugPath
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	type response struct {
+		Sessions []DebugSession `json:"sessions"`
+	}
+	r := &response{}
+
+	_, err = c.Do(req, r, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return r.Sessions, nil
+}
+
+// A shadow runs a task against a mirrored copy of the live stream for a limited time.
+// The alert events and InfluxDB writes of the task are reported but not delivered.
+type Shadow struct {
+	Link   Link      `json:"link"`
+	ID     string    `json:"id"`
+	Task   string    `json:"task"`
+	Status Status    `json:"status"`
+	Error  string    `json:"error"`
+	Start  time.Time `json:"start"`
+	// Time the shadow stops, unless stopped early.
+	Stop time.Time `json:"stop"`
+	// Number of points mirrored to the task.
+	Points int64 `json:"points"`
+	// Number of alert events triggered by the task.
+	EventCount int64 `json:"event-count"`
+	// Number of points the task would have written to InfluxDB.
+	OutputCount int64 `json:"output-count"`
+	// The most recent alert events triggered by the task.
+	Events []ShadowEvent `json:"events"`
+}
+
+// ShadowEvent is an alert event triggered by a shadowed task.
+type ShadowEvent struct {
+	// Source is the name of the alert node, or "topic:<topic>" for events sent to a topic.
+	Source string `json:"source"`
+	ID     string `json:"id"`
+	EventState
+}
+
+// Actions of a shadow.
+const (
+	// Stop mirroring the stream before the end of the shadow, keeping its report.
+	ShadowStop = "stop"
+)
+
+type CreateShadowOptions struct {
+	ID   string `json:"id"`
+	Task string `json:"task"`
+	// How long to mirror the live stream to the task.
+	Duration Duration `json:"duration"`
+}
+
+type UpdateShadowOptions struct {
+	Action string `json:"action,omitempty"`
+}
+
+func (c *Client) ShadowLink(id string) Link {
+	return Link{Relation: Self, Href: path.Join(shadowsPath, id)}
+}
+
+// Start shadowing a task with the live stream of its databases and retention policies.
+// The task does not need to be enabled.
+func (c *Client) CreateShadow(opt CreateShadowOptions) (Shadow, error) {
+	s := Shadow{}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return s, err
+	}
+
+	u := *c.url
+	u.Path = shadowsPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return s, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &s, http.StatusCreated)
+	return s, err
+}
+
+// Get the report of a shadow.
+func (c *Client) Shadow(link Link) (Shadow, error) {
+	s := Shadow{}
+	if link.Href == "" {
+		return s, fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return s, err
+	}
+
+	_, err = c.Do(req, &s, http.StatusOK)
+	return s, err
+}
+
+// Stop a shadow early.
+// Returns once its task has stopped.
+func (c *Client) UpdateShadow(link Link, opt UpdateShadowOptions) (Shadow, error) {
+	s := Shadow{}
+	if link.Href == "" {
+		return s, fmt.Errorf("invalid link %v", link)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return s, err
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("PATCH", u.String(), &buf)
+	if err != nil {
+		return s, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &s, http.StatusOK)
+	return s, err
+}
+
+// Delete a shadow and its report, stopping it if it is running.
+func (c *Client) DeleteShadow(link Link) error {
+	if link.Href == "" {
+		return fmt.Errorf("invalid link %v", link)
+	}
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("DELETE", u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
+// Get all shadows.
+func (c *Client) ListShadows() ([]Shadow, error) {
+	u := *c.url
+	u.Path = shadowsPath
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	type response struct {
+		Shadows []Shadow `json:"shadows"`
+	}
+	r := &response{}
+
+	_, err = c.Do(req, r, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return r.Shadows, nil
+}
+
+// A user of the HTTP API.
+type User struct {
+	Link Link   `json:"link"`
+	Name string `json:"name"`
+	// Admin users have every privilege, including managing users and roles.
+	Admin bool     `json:"admin"`
+	Roles []string `json:"roles"`
+	// Scopes granted to the user by its roles.
+	Scopes []string `json:"scopes"`
+}
+
+// A role grants scopes to the users it is assigned to.
+type Role struct {
+	Link   Link     `json:"link"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type CreateUserOptions struct {
+	Name     string   `json:"name"`
+	Password string   `json:"password"`
+	Admin    bool     `json:"admin"`
+	Roles    []string `json:"roles,omitempty"`
+}
+
+type UpdateUserOptions struct {
+	// Password replaces the password of the user when not empty.
+	Password string `json:"password,omitempty"`
+	// Admin changes whether the user is an admin when not nil.
+	Admin *bool `json:"admin,omitempty"`
+	// Roles replaces the roles of the user when not nil,
+	// an empty list removes all roles.
+	Roles []string `json:"roles"`
+}
+
+type CreateRoleOptions struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type UpdateRoleOptions struct {
+	// Scopes replaces the scopes of the role when not nil.
+	Scopes []string `json:"scopes"`
+}
+
+type ListUsersOptions struct {
+	Pattern string
+	Offset  int
+	Limit   int
+}
+
+func (o *ListUsersOptions) Default() {
+	if o.Limit == 0 {
+		o.Limit = 100
+	}
+}
+
+func (o *ListUsersOptions) Values() *url.Values {
+	v := &url.Values{}
+	v.Set("pattern", o.Pattern)
+	v.Set("offset", strconv.FormatInt(int64(o.Offset), 10))
+	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
+	return v
+}
+
+type ListRolesOptions struct {
+	Pattern string
+	Offset  int
+	Limit   int
+}
+
+func (o *ListRolesOptions) Default() {
+	if o.Limit == 0 {
+		o.Limit = 100
 	}
-	return r, nil
 }
 
-func (c *Client) RecordingLink(id string) Link {
-	return Link{Relation: Self, Href: path.Join(recordingsPath, id)}
+func (o *ListRolesOptions) Values() *url.Values {
+	v := &url.Values{}
+	v.Set("pattern", o.Pattern)
+	v.Set("offset", strconv.FormatInt(int64(o.Offset), 10))
+	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
+	return v
 }
 
-type RecordStreamOptions struct {
-	ID   string    `json:"id,omitempty"`
-	Task string    `json:"task"`
-	Stop time.Time `json:"stop"`
+func (c *Client) UserLink(name string) Link {
+	return Link{Relation: Self, Href: path.Join(usersPath, name)}
 }
 
-// Record the stream for a task.
-// Returns once the recording is started.
-func (c *Client) RecordStream(opt RecordStreamOptions) (Recording, error) {
-	r := Recording{}
+func (c *Client) RoleLink(name string) Link {
+	return Link{Relation: Self, Href: path.Join(rolesPath, name)}
+}
+
+// Create a new user.
+// Errors if the user already exists.
+func (c *Client) CreateUser(opt CreateUserOptions) (User, error) {
+	usr := User{}
 
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
 	err := enc.Encode(opt)
 	if err != nil {
-		return r, err
+		return usr, err
 	}
 
 	u := *c.url
-	u.Path = recordStreamPath
+	u.Path = usersPath
 
 	req, err := http.NewRequest("POST", u.String(), &buf)
 	if err != nil {
-		return r, err
+		return usr, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	_, err = c.Do(req, &r, http.StatusCreated)
-	if err != nil {
-		return r, err
-	}
-	return r, nil
-}
-
-type RecordBatchOptions struct {
-	ID    string    `json:"id,omitempty"`
-	Task  string    `json:"task"`
-	Start time.Time `json:"start"`
-	Stop  time.Time `json:"stop"`
+	_, err = c.Do(req, &usr, http.StatusOK)
+	return usr, err
 }
 
-// Record the batch queries for a task.
-// Returns once the recording is started.
-func (c *Client) RecordBatch(opt RecordBatchOptions) (Recording, error) {
-	r := Recording{}
+// Update an existing user.
+func (c *Client) UpdateUser(link Link, opt UpdateUserOptions) (User, error) {
+	usr := User{}
+	if link.Href == "" {
+		return usr, fmt.Errorf("invalid link %v", link)
+	}
 
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
 	err := enc.Encode(opt)
 	if err != nil {
-		return r, err
+		return usr, err
 	}
 
 	u := *c.url
-	u.Path = recordBatchPath
+	u.Path = link.Href
 
-	req, err := http.NewRequest("POST", u.String(), &buf)
+	req, err := http.NewRequest("PATCH", u.String(), &buf)
 	if err != nil {
-		return r, err
+		return usr, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	_, err = c.Do(req, &r, http.StatusCreated)
-	if err != nil {
-		return r, err
-	}
-	return r, nil
-}
-
-type RecordQueryOptions struct {
-	ID      string   `json:"id,omitempty"`
-	Query   string   `json:"query"`
-	Type    TaskType `json:"type"`
-	Cluster string   `json:"cluster,omitempty"`
+	_, err = c.Do(req, &usr, http.StatusOK)
+	return usr, err
 }
 
-// Record the results of a query.
-// The recordingType must be one of "stream", or "batch".
-// Returns once the recording is started.
-func (c *Client) RecordQuery(opt RecordQueryOptions) (Recording, error) {
-	r := Recording{}
-
-	var buf bytes.Buffer
-	enc := json.NewEncoder(&buf)
-	err := enc.Encode(opt)
-	if err != nil {
-		return r, err
+// Get information about a user.
+func (c *Client) User(link Link) (User, error) {
+	usr := User{}
+	if link.Href == "" {
+		return usr, fmt.Errorf("invalid link %v", link)
 	}
 
 	u := *c.url
-	u.Path = recordQueryPath
+	u.Path = link.Href
 
-	req, err := http.NewRequest("POST", u.String(), &buf)
+	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return r, err
+		return usr, err
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	_, err = c.Do(req, &r, http.StatusCreated)
-	if err != nil {
-		return r, err
-	}
-	return r, nil
+	_, err = c.Do(req, &usr, http.StatusOK)
+	return usr, err
 }
 
-// Delete a recording.
-func (c *Client) DeleteRecording(link Link) error {
+// Delete a user.
+func (c *Client) DeleteUser(link Link) error {
 	if link.Href == "" {
 		return fmt.Errorf("invalid link %v", link)
 	}
@@ -1360,48 +3723,25 @@ func (c *Client) DeleteRecording(link Link) error {
 	return err
 }
 
-type ListRecordingsOptions struct {
-	Pattern string
-	Fields  []string
-	Offset  int
-	Limit   int
-}
-
-func (o *ListRecordingsOptions) Default() {
-	if o.Limit == 0 {
-		o.Limit = 100
-	}
-}
-
-func (o *ListRecordingsOptions) Values() *url.Values {
-	v := &url.Values{}
-	v.Set("pattern", o.Pattern)
-	for _, field := range o.Fields {
-		v.Add("fields", field)
-	}
-	v.Set("offset", strconv.FormatInt(int64(o.Offset), 10))
-	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
-	return v
-}
-
-// Get information about recordings.
-// If rids is empty than all recordings are returned.
-func (c *Client) ListRecordings(opt *ListRecordingsOptions) ([]Recording, error) {
+// Get users.
+func (c *Client) ListUsers(opt *ListUsersOptions) ([]User, error) {
 	if opt == nil {
-		opt = new(ListRecordingsOptions)
+		opt = new(ListUsersOptions)
 	}
 	opt.Default()
+
 	u := *c.url
-	u.Path = recordingsPath
+	u.Path = usersPath
 	u.RawQuery = opt.Values().Encode()
 
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
-	// Decode valid response
+
+	// Response type
 	type response struct {
-		Recordings []Recording `json:"recordings"`
+		Users []User `json:"users"`
 	}
 
 	r := &response{}
@@ -1410,130 +3750,185 @@ func (c *Client) ListRecordings(opt *ListRecordingsOptions) ([]Recording, error)
 	if err != nil {
 		return nil, err
 	}
-	return r.Recordings, nil
+	return r.Users, nil
 }
 
-func (c *Client) ReplayLink(id string) Link {
-	return Link{Relation: Self, Href: path.Join(replaysPath, id)}
-}
+// Create a new role.
+// Errors if the role already exists.
+func (c *Client) CreateRole(opt CreateRoleOptions) (Role, error) {
+	role := Role{}
 
-type CreateReplayOptions struct {
-	ID            string `json:"id"`
-	Recording     string `json:"recording"`
-	Task          string `json:"task"`
-	RecordingTime bool   `json:"recording-time"`
-	Clock         Clock  `json:"clock"`
-}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return role, err
+	}
 
-func (o *CreateReplayOptions) Default() {
-}
+	u := *c.url
+	u.Path = rolesPath
 
-// Replay a recording for a task.
-func (c *Client) CreateReplay(opt CreateReplayOptions) (Replay, error) {
-	r := Replay{}
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return role, err
+	}
+	req.Header.Set("Content-Type", "application/json")
 
-	opt.Default()
+	_, err = c.Do(req, &role, http.StatusOK)
+	return role, err
+}
 
-	u := *c.url
-	u.Path = replaysPath
+// Update an existing role.
+func (c *Client) UpdateRole(link Link, opt UpdateRoleOptions) (Role, error) {
+	role := Role{}
+	if link.Href == "" {
+		return role, fmt.Errorf("invalid link %v", link)
+	}
 
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
 	err := enc.Encode(opt)
 	if err != nil {
-		return r, err
+		return role, err
 	}
 
-	req, err := http.NewRequest("POST", u.String(), &buf)
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("PATCH", u.String(), &buf)
 	if err != nil {
-		return r, err
+		return role, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	_, err = c.Do(req, &r, http.StatusCreated)
+	_, err = c.Do(req, &role, http.StatusOK)
+	return role, err
+}
+
+// Get information about a role.
+func (c *Client) Role(link Link) (Role, error) {
+	role := Role{}
+	if link.Href == "" {
+		return role, fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return r, err
+		return role, err
 	}
-	return r, nil
-}
 
-type ReplayBatchOptions struct {
-	ID            string    `json:"id,omitempty"`
-	Task          string    `json:"task"`
-	Start         time.Time `json:"start"`
-	Stop          time.Time `json:"stop"`
-	RecordingTime bool      `json:"recording-time"`
-	Clock         Clock     `json:"clock"`
+	_, err = c.Do(req, &role, http.StatusOK)
+	return role, err
 }
 
-// Replay a query against a task.
-func (c *Client) ReplayBatch(opt ReplayBatchOptions) (Replay, error) {
-	r := Replay{}
+// Delete a role.
+// Users assigned the role are no longer granted its scopes.
+func (c *Client) DeleteRole(link Link) error {
+	if link.Href == "" {
+		return fmt.Errorf("invalid link %v", link)
+	}
+	u := *c.url
+	u.Path = link.Href
 
-	var buf bytes.Buffer
-	enc := json.NewEncoder(&buf)
-	err := enc.Encode(opt)
+	req, err := http.NewRequest("DELETE", u.String(), nil)
 	if err != nil {
-		return r, err
+		return err
+	}
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
+// Get roles.
+func (c *Client) ListRoles(opt *ListRolesOptions) ([]Role, error) {
+	if opt == nil {
+		opt = new(ListRolesOptions)
 	}
+	opt.Default()
 
 	u := *c.url
-	u.Path = replayBatchPath
+	u.Path = rolesPath
+	u.RawQuery = opt.Values().Encode()
 
-	req, err := http.NewRequest("POST", u.String(), &buf)
+	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return r, err
+		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	_, err = c.Do(req, &r, http.StatusCreated)
+	// Response type
+	type response struct {
+		Roles []Role `json:"roles"`
+	}
+
+	r := &response{}
+
+	_, err = c.Do(req, r, http.StatusOK)
 	if err != nil {
-		return r, err
+		return nil, err
 	}
-	return r, nil
+	return r.Roles, nil
 }
 
-type ReplayQueryOptions struct {
-	ID            string `json:"id,omitempty"`
-	Task          string `json:"task"`
-	Query         string `json:"query"`
-	Cluster       string `json:"cluster,omitempty"`
-	RecordingTime bool   `json:"recording-time"`
-	Clock         Clock  `json:"clock"`
+// An API token authenticates automation with the scopes it was granted,
+// it is sent as a bearer token.
+type APIToken struct {
+	Link        Link   `json:"link"`
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	// Name of the user who created the token.
+	Owner   string    `json:"owner"`
+	Scopes  []string  `json:"scopes"`
+	Created time.Time `json:"created"`
+	// Expires is zero if the token never expires.
+	Expires time.Time `json:"expires"`
+	// Token is the secret token, it is only returned when the token is created.
+	Token string `json:"token,omitempty"`
 }
 
-// Replay a query against a task.
-func (c *Client) ReplayQuery(opt ReplayQueryOptions) (Replay, error) {
-	r := Replay{}
+type CreateAPITokenOptions struct {
+	Description string   `json:"description"`
+	Scopes      []string `json:"scopes"`
+	// Duration after which the token expires, the token never expires if zero.
+	Duration Duration `json:"duration"`
+}
+
+func (c *Client) APITokenLink(id string) Link {
+	return Link{Relation: Self, Href: path.Join(apiTokensPath, id)}
+}
+
+// Create a new API token.
+// The returned token is the only copy of the secret token.
+func (c *Client) CreateAPIToken(opt CreateAPITokenOptions) (APIToken, error) {
+	t := APIToken{}
 
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
 	err := enc.Encode(opt)
 	if err != nil {
-		return r, err
+		return t, err
 	}
 
 	u := *c.url
-	u.Path = replayQueryPath
+	u.Path = apiTokensPath
 
 	req, err := http.NewRequest("POST", u.String(), &buf)
 	if err != nil {
-		return r, err
+		return t, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	_, err = c.Do(req, &r, http.StatusCreated)
-	if err != nil {
-		return r, err
-	}
-	return r, nil
+	_, err = c.Do(req, &t, http.StatusOK)
+	return t, err
 }
 
-// Return the replay information
-func (c *Client) Replay(link Link) (Replay, error) {
-	r := Replay{}
+// Get information about an API token.
+func (c *Client) APIToken(link Link) (APIToken, error) {
+	t := APIToken{}
 	if link.Href == "" {
-		return r, fmt.Errorf("invalid link %v", link)
+		return t, fmt.Errorf("invalid link %v", link)
 	}
 
 	u := *c.url
@@ -1541,18 +3936,15 @@ func (c *Client) Replay(link Link) (Replay, error) {
 
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return r, err
+		return t, err
 	}
 
-	_, err = c.Do(req, &r, http.StatusOK, http.StatusAccepted)
-	if err != nil {
-		return r, err
-	}
-	return r, nil
+	_, err = c.Do(req, &t, http.StatusOK)
+	return t, err
 }
 
-// Delete a replay. This will cancel a running replay.
-func (c *Client) DeleteReplay(link Link) error {
+// Revoke an API token.
+func (c *Client) RevokeAPIToken(link Link) error {
 	if link.Href == "" {
 		return fmt.Errorf("invalid link %v", link)
 	}
@@ -1565,54 +3957,111 @@ func (c *Client) DeleteReplay(link Link) error {
 	}
 
 	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
+// Get all API tokens.
+func (c *Client) ListAPITokens() ([]APIToken, error) {
+	u := *c.url
+	u.Path = apiTokensPath
+
+	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
-}
 
-type ListReplaysOptions struct {
-	Pattern string
-	Fields  []string
-	Offset  int
-	Limit   int
+	// Response type
+	type response struct {
+		Tokens []APIToken `json:"tokens"`
+	}
+
+	r := &response{}
+
+	_, err = c.Do(req, r, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return r.Tokens, nil
 }
 
-func (o *ListReplaysOptions) Default() {
+// An AuditEvent records a request which mutated a resource.
+type AuditEvent struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	User       string    `json:"user"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	RemoteAddr string    `json:"remote-addr"`
+	// Changes of the fields of the resource.
+	Changes []AuditChange `json:"changes"`
+}
+
+// AuditChange is the change of a field of a resource, nested fields are separated by dots.
+// Old is nil if the field did not exist and New is nil if the field was removed.
+type AuditChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+type ListAuditEventsOptions struct {
+	// Only events of the user
+	User string
+	// Only events of requests with a path with the prefix
+	Path string
+	// Only events since and until the times, if not zero.
+	Since time.Time
+	Until time.Time
+
+	Offset int
+	Limit  int
+}
+
+func (o *ListAuditEventsOptions) Default() {
 	if o.Limit == 0 {
 		o.Limit = 100
 	}
 }
 
-func (o *ListReplaysOptions) Values() *url.Values {
+func (o *ListAuditEventsOptions) Values() *url.Values {
 	v := &url.Values{}
-	v.Set("pattern", o.Pattern)
-	for _, field := range o.Fields {
-		v.Add("fields", field)
+	if o.User != "" {
+		v.Set("user", o.User)
+	}
+	if o.Path != "" {
+		v.Set("path", o.Path)
+	}
+	if !o.Since.IsZero() {
+		v.Set("since", o.Since.Format(time.RFC3339Nano))
+	}
+	if !o.Until.IsZero() {
+		v.Set("until", o.Until.Format(time.RFC3339Nano))
 	}
 	v.Set("offset", strconv.FormatInt(int64(o.Offset), 10))
 	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
 	return v
 }
 
-// Get information about replays.
-// If rids is empty than all replays are returned.
-func (c *Client) ListReplays(opt *ListReplaysOptions) ([]Replay, error) {
+// Get audit events, most recent first.
+func (c *Client) ListAuditEvents(opt *ListAuditEventsOptions) ([]AuditEvent, error) {
 	if opt == nil {
-		opt = new(ListReplaysOptions)
+		opt = new(ListAuditEventsOptions)
 	}
 	opt.Default()
+
 	u := *c.url
-	u.Path = replaysPath
+	u.Path = auditPath
 	u.RawQuery = opt.Values().Encode()
 
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
-	// Decode valid response
+
+	// Response type
 	type response struct {
-		Replays []Replay `json:"replays"`
+		Events []AuditEvent `json:"events"`
 	}
 
 	r := &response{}
@@ -1621,7 +4070,7 @@ func (c *Client) ListReplays(opt *ListReplaysOptions) ([]Replay, error) {
 	if err != nil {
 		return nil, err
 	}
-	return r.Replays, nil
+	return r.Events, nil
 }
 
 type ConfigUpdateAction struct {
@@ -1734,6 +4183,122 @@ func (c *Client) ConfigElement(link Link) (ConfigElement, error) {
 	return element, nil
 }
 
+// ConfigValidate validates a ConfigUpdateAction against a given section or element without applying it.
+func (c *Client) ConfigValidate(link Link, action ConfigUpdateAction) error {
+	if link.Href == "" {
+		return fmt.Errorf("invalid link %v", link)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(action)
+	if err != nil {
+		return err
+	}
+
+	u := *c.url
+	u.Path = link.Href
+	u.RawQuery = url.Values{"validate": []string{"true"}}.Encode()
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
+// ConfigStagedUpdate is a ConfigUpdateAction against a section or element, as part of ConfigApplyOptions.
+type ConfigStagedUpdate struct {
+	Section string `json:"section"`
+	// Element of the section, empty for sections that are not lists or to add and remove elements.
+	Element string `json:"element,omitempty"`
+	ConfigUpdateAction
+}
+
+type ConfigApplyOptions struct {
+	Updates []ConfigStagedUpdate `json:"updates"`
+	// Validate the updates without applying them.
+	Validate bool `json:"-"`
+}
+
+// ConfigApply applies the updates of several sections together.
+// The updates are validated before any of them is applied, and once a section fails to update
+// the sections already updated are rolled back to their previous configuration.
+// Each section may be updated only once.
+func (c *Client) ConfigApply(opt ConfigApplyOptions) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return err
+	}
+
+	u := *c.url
+	u.Path = configPath
+	if opt.Validate {
+		u.RawQuery = url.Values{"validate": []string{"true"}}.Encode()
+	}
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
+// ConfigDiff is the difference between the running configuration, with its overrides,
+// and the configuration of the file.
+type ConfigDiff struct {
+	Link     Link                         `json:"link"`
+	Sections map[string]ConfigSectionDiff `json:"sections"`
+}
+
+// ConfigSectionDiff lists the elements of a section that differ from the file.
+type ConfigSectionDiff struct {
+	Link     Link                `json:"link"`
+	Elements []ConfigElementDiff `json:"elements"`
+}
+
+// ConfigElementDiff lists the options of an element that differ from the file.
+type ConfigElementDiff struct {
+	Link Link `json:"link"`
+	// Added is true if the element was added by an override, all its options are listed.
+	Added   bool                        `json:"added"`
+	Options map[string]ConfigOptionDiff `json:"options"`
+}
+
+// ConfigOptionDiff is the value of an option in the file and in the running configuration.
+// The values of redacted options are whether they are set.
+type ConfigOptionDiff struct {
+	File     interface{} `json:"file"`
+	Running  interface{} `json:"running"`
+	Redacted bool        `json:"redacted,omitempty"`
+}
+
+// ConfigDiff returns the difference between the running configuration and the configuration of the file,
+// of a section or of all sections if the section is empty.
+func (c *Client) ConfigDiff(section string) (ConfigDiff, error) {
+	u := *c.url
+	u.Path = path.Join(configPath, configDiffPath, section)
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return ConfigDiff{}, err
+	}
+
+	diff := ConfigDiff{}
+	_, err = c.Do(req, &diff, http.StatusOK)
+	if err != nil {
+		return ConfigDiff{}, err
+	}
+	return diff, nil
+}
+
 type ServiceTests struct {
 	Link     Link          `json:"link"`
 	Services []ServiceTest `json:"services"`
@@ -2268,6 +4833,161 @@ func (c *Client) DoStorageAction(l Link, opt StorageActionOptions) error {
 	return nil
 }
 
+// StorageRekeyResult is the result of the rotation of the data key encrypting the sensitive values.
+type StorageRekeyResult struct {
+	// ID of the new data key.
+	KeyID uint32 `json:"key-id"`
+	// Number of values encrypted with the new data key.
+	Values int `json:"values"`
+}
+
+// RekeyStorage replaces the data key encrypting the sensitive values of the storage,
+// and encrypts them again with the new key.
+func (c *Client) RekeyStorage() (StorageRekeyResult, error) {
+	result := StorageRekeyResult{}
+	u := *c.url
+	u.Path = storageRekeyPath
+
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return result, err
+	}
+
+	_, err = c.Do(req, &result, http.StatusOK)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// HAStatus is the status of a server of a high availability pair.
+type HAStatus struct {
+	// ID of the server.
+	ID string `json:"id"`
+	// Role of the server, either leader or standby.
+	Role string `json:"role"`
+	// Leader of the pair, if it is known.
+	Leader *HALeader `json:"leader,omitempty"`
+	// Time of the last replication of the state of the leader, zero on the leader.
+	LastSync time.Time `json:"last-sync"`
+}
+
+// HALeader identifies the leader of a high availability pair.
+type HALeader struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// HASnapshot is the replicated state of the leader of a high availability pair.
+type HASnapshot struct {
+	// Values of each replicated storage namespace.
+	Namespaces map[string][]HAKeyValue `json:"namespaces"`
+}
+
+type HAKeyValue struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// HAStatus returns the high availability status of the server.
+func (c *Client) HAStatus() (HAStatus, error) {
+	status := HAStatus{}
+	u := *c.url
+	u.Path = haPath
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return status, err
+	}
+
+	_, err = c.Do(req, &status, http.StatusOK)
+	if err != nil {
+		return status, err
+	}
+	return status, nil
+}
+
+// HASnapshot returns the replicated state of the leader of a high availability pair.
+func (c *Client) HASnapshot() (HASnapshot, error) {
+	snapshot := HASnapshot{}
+	u := *c.url
+	u.Path = haSnapshotPath
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return snapshot, err
+	}
+
+	_, err = c.Do(req, &snapshot, http.StatusOK)
+	if err != nil {
+		return snapshot, err
+	}
+	return snapshot, nil
+}
+
+// ClusterStatus is the status of a node of a cluster.
+type ClusterStatus struct {
+	// Name of the node.
+	Node string `json:"node"`
+	// Fingerprint of the configuration of the cluster, it must be the same on every node.
+	Fingerprint string        `json:"fingerprint"`
+	Shards      int           `json:"shards"`
+	Nodes       []ClusterNode `json:"nodes"`
+}
+
+// ClusterNode is a node of a cluster and the shards it owns.
+type ClusterNode struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Shards []int  `json:"shards"`
+}
+
+// ClusterWriteOptions are the options of writing points forwarded by another node of a cluster.
+type ClusterWriteOptions struct {
+	Database        string
+	RetentionPolicy string
+	// Fingerprint of the configuration of the cluster of the forwarding node.
+	Fingerprint string
+}
+
+// ClusterStatus returns the cluster status of the node.
+func (c *Client) ClusterStatus() (ClusterStatus, error) {
+	status := ClusterStatus{}
+	u := *c.url
+	u.Path = clusterPath
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return status, err
+	}
+
+	_, err = c.Do(req, &status, http.StatusOK)
+	if err != nil {
+		return status, err
+	}
+	return status, nil
+}
+
+// ClusterWrite writes points in line protocol with nanosecond precision to the node,
+// the node processes them without forwarding them again.
+func (c *Client) ClusterWrite(opt ClusterWriteOptions, points []byte) error {
+	u := *c.url
+	u.Path = clusterWritePath
+	v := url.Values{}
+	v.Set("db", opt.Database)
+	v.Set("rp", opt.RetentionPolicy)
+	v.Set("fingerprint", opt.Fingerprint)
+	u.RawQuery = v.Encode()
+
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(points))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
 // Backup requests a backup of all storage from Kapacitor.
 // A short read is possible, to verify that the backup was successful
 // check that the number of bytes read matches the returned size.
@@ -2290,17 +5010,168 @@ func (c *Client) Backup() (int64, io.ReadCloser, error) {
 	return resp.ContentLength, resp.Body, nil
 }
 
+// LoadStatus is the result of the last sync of the tasks, templates and handlers
+// with the files of the load directory.
+type LoadStatus struct {
+	Dir string `json:"dir"`
+	// Whether the directory is watched for changes,
+	// otherwise it is only synced on startup and reload.
+	Watching bool      `json:"watching"`
+	LastSync time.Time `json:"last-sync"`
+	// Error that prevented the sync from completing, if any.
+	Error string `json:"error,omitempty"`
+	// Errors loading individual files, by file path.
+	FileErrors map[string]string `json:"file-errors,omitempty"`
+}
+
+// LoadStatus returns the status of the load directory.
+func (c *Client) LoadStatus() (LoadStatus, error) {
+	status := LoadStatus{}
+	u := *c.url
+	u.Path = loadStatusPath
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return status, err
+	}
+
+	_, err = c.Do(req, &status, http.StatusOK)
+	if err != nil {
+		return status, err
+	}
+	return status, nil
+}
+
+// Bundle is a portable definition of the templates, tasks, topic handlers
+// and configuration overrides of a server.
+type Bundle struct {
+	Version         int                     `json:"version"`
+	Created         time.Time               `json:"created"`
+	Templates       []CreateTemplateOptions `json:"templates"`
+	Tasks           []CreateTaskOptions     `json:"tasks"`
+	TopicHandlers   []TopicHandlerOptions   `json:"topic-handlers"`
+	ConfigOverrides []ConfigOverride        `json:"config-overrides"`
+}
+
+// ConfigOverride is the set of options overridden for a configuration section element.
+type ConfigOverride struct {
+	Section string `json:"section"`
+	Element string `json:"element,omitempty"`
+	// Whether the element was added by the override instead of being part of the configuration file.
+	Create  bool                   `json:"create,omitempty"`
+	Options map[string]interface{} `json:"options"`
+}
+
+// ExportBundle returns the definitions of the server as a bundle.
+// The configuration overrides of the bundle include the values of redacted options.
+func (c *Client) ExportBundle() (Bundle, error) {
+	b := Bundle{}
+	u := *c.url
+	u.Path = bundlePath
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return b, err
+	}
+
+	_, err = c.Do(req, &b, http.StatusOK)
+	return b, err
+}
+
+// ImportBundle creates or replaces the definitions of the bundle, preserving their IDs and task statuses.
+// Definitions that are not part of the bundle are left as they are.
+func (c *Client) ImportBundle(b Bundle) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(b)
+	if err != nil {
+		return err
+	}
+
+	u := *c.url
+	u.Path = bundlePath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
+// UpgradeUDFOptions is the new config of an upgraded UDF.
+// If none of Prog, Socket, GRPC and Python is set the UDF is restarted with its current config.
+type UpgradeUDFOptions struct {
+	Prog   string            `json:"prog,omitempty"`
+	Args   []string          `json:"args,omitempty"`
+	Env    map[string]string `json:"env,omitempty"`
+	Socket string            `json:"socket,omitempty"`
+	GRPC   string            `json:"grpc,omitempty"`
+	Python *PythonUDFOptions `json:"python,omitempty"`
+}
+
+// PythonUDFOptions is the config of a UDF implemented as a Python handler run by the bundled agent.
+type PythonUDFOptions struct {
+	Script       string `json:"script"`
+	Handler      string `json:"handler,omitempty"`
+	Interpreter  string `json:"interpreter,omitempty"`
+	Virtualenv   string `json:"virtualenv,omitempty"`
+	Requirements string `json:"requirements,omitempty"`
+}
+
+// UpgradeUDF replaces how the UDF is run or connected to.
+// Running tasks using the UDF snapshot its state and restore it into the upgraded UDF.
+func (c *Client) UpgradeUDF(name string, opt UpgradeUDFOptions) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return err
+	}
+
+	u := *c.url
+	u.Path = path.Join(udfsPath, name, udfUpgradePath)
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
 type LogLevelOptions struct {
 	Level string `json:"level"`
+	// Component whose level is set instead of the level of the server.
+	// An empty level removes the level of the component.
+	Component string `json:"component,omitempty"`
+	// Task whose level is set instead of the level of the server, it applies to the logs of its nodes.
+	// An empty level removes the level of the task.
+	Task string `json:"task,omitempty"`
+}
+
+// LogLevels are the log level of the server, and the levels of the components and tasks that override it.
+type LogLevels struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components"`
+	Tasks      map[string]string `json:"tasks"`
 }
 
 // Set the logging level.
 // Level must be one of DEBUG, INFO, WARN, ERROR, or OFF
 func (c *Client) LogLevel(level string) error {
+	return c.SetLogLevel(LogLevelOptions{Level: level})
+}
+
+// SetLogLevel sets the logging level of the server, or of a component or task.
+func (c *Client) SetLogLevel(opt LogLevelOptions) error {
 	u := *c.url
 	u.Path = logLevelPath
 
-	opt := LogLevelOptions{Level: level}
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
 	err := enc.Encode(opt)
@@ -2318,6 +5189,21 @@ func (c *Client) LogLevel(level string) error {
 	return err
 }
 
+// LogLevels returns the logging levels of the server, and of the components and tasks that override it.
+func (c *Client) LogLevels() (LogLevels, error) {
+	u := *c.url
+	u.Path = logLevelPath
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return LogLevels{}, err
+	}
+
+	levels := LogLevels{}
+	_, err = c.Do(req, &levels, http.StatusOK)
+	return levels, err
+}
+
 type DebugVars struct {
 	ClusterID        string                 `json:"cluster_id"`
 	ServerID         string                 `json:"server_id"`
@@ -2378,6 +5264,7 @@ type TaskVars struct {
 	TemplateID string `json:"template-id,omitempty" yaml:"template-id"`
 	DBRPs      []DBRP `json:"dbrps,omitempty" yaml:"dbrps"`
 	Vars       Vars   `json:"vars,omitempty" yaml:"vars"`
+	Labels     Labels `json:"labels,omitempty" yaml:"labels"`
 }
 
 func (t TaskVars) CreateTaskOptions() (CreateTaskOptions, error) {
@@ -2386,6 +5273,7 @@ func (t TaskVars) CreateTaskOptions() (CreateTaskOptions, error) {
 		TemplateID: t.TemplateID,
 		Vars:       t.Vars,
 		DBRPs:      t.DBRPs,
+		Labels:     t.Labels,
 	}
 
 	return o, nil
@@ -2397,6 +5285,7 @@ func (t TaskVars) UpdateTaskOptions() (UpdateTaskOptions, error) {
 		TemplateID: t.TemplateID,
 		Vars:       t.Vars,
 		DBRPs:      t.DBRPs,
+		Labels:     t.Labels,
 	}
 	return o, nil
 }
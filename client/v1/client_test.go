@@ -1,6 +1,7 @@
 package client_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -229,6 +230,27 @@ func Test_ReportsErrors(t *testing.T) {
 				return err
 			},
 		},
+		{
+			name: "ConfigValidate",
+			fnc: func(c *client.Client) error {
+				err := c.ConfigValidate(c.ConfigSectionLink(""), client.ConfigUpdateAction{})
+				return err
+			},
+		},
+		{
+			name: "ConfigApply",
+			fnc: func(c *client.Client) error {
+				err := c.ConfigApply(client.ConfigApplyOptions{})
+				return err
+			},
+		},
+		{
+			name: "ConfigDiff",
+			fnc: func(c *client.Client) error {
+				_, err := c.ConfigDiff("")
+				return err
+			},
+		},
 		{
 			name: "ServiceTests",
 			fnc: func(c *client.Client) error {
@@ -334,6 +356,60 @@ func Test_ReportsErrors(t *testing.T) {
 				return err
 			},
 		},
+		{
+			name: "LogLevels",
+			fnc: func(c *client.Client) error {
+				_, err := c.LogLevels()
+				return err
+			},
+		},
+		{
+			name: "InfluxDBStatus",
+			fnc: func(c *client.Client) error {
+				_, err := c.InfluxDBStatus()
+				return err
+			},
+		},
+		{
+			name: "DBRPsStatus",
+			fnc: func(c *client.Client) error {
+				_, err := c.DBRPsStatus(nil)
+				return err
+			},
+		},
+		{
+			name: "ReconcileDBRPs",
+			fnc: func(c *client.Client) error {
+				_, err := c.ReconcileDBRPs()
+				return err
+			},
+		},
+		{
+			name: "Evaluate",
+			fnc: func(c *client.Client) error {
+				_, err := c.Evaluate(client.EvaluateOptions{})
+				return err
+			},
+		},
+		{
+			name: "SideloadSources",
+			fnc: func(c *client.Client) error {
+				_, err := c.SideloadSources()
+				return err
+			},
+		},
+		{
+			name: "ReloadSideload",
+			fnc: func(c *client.Client) error {
+				return c.ReloadSideload()
+			},
+		},
+		{
+			name: "WatchTask",
+			fnc: func(c *client.Client) error {
+				return c.WatchTask(context.Background(), c.TaskLink("taskname"), nil, func(client.TaskWatchEvent) error { return nil })
+			},
+		},
 	}
 	for _, tc := range testCases {
 		s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -787,6 +863,289 @@ func Test_DeleteTask(t *testing.T) {
 	}
 }
 
+func Test_BulkTasks(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var opts client.BulkTasksOptions
+		body, _ := ioutil.ReadAll(r.Body)
+		err := json.Unmarshal(body, &opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if r.URL.Path == "/kapacitor/v1/tasks/bulk" && r.Method == "POST" {
+			exp := client.BulkTasksOptions{
+				Action:   client.BulkDisable,
+				Patterns: []string{"cpu_*", "mem_*"},
+			}
+			if !reflect.DeepEqual(exp, opts) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "unexpected BulkTasks body: got:\n%v\nexp:\n%v\n", opts, exp)
+			} else {
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, `{"action":"disable","tasks":["cpu_alert","mem_alert"]}`)
+			}
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	r, err := c.BulkTasks(client.BulkTasksOptions{
+		Action:   client.BulkDisable,
+		Patterns: []string{"cpu_*", "mem_*"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.BulkTasksResult{
+		Action: client.BulkDisable,
+		Tasks:  []string{"cpu_alert", "mem_alert"},
+	}
+	if !reflect.DeepEqual(exp, r) {
+		t.Errorf("unexpected result: got:\n%v\nexp:\n%v", r, exp)
+	}
+}
+
+func Test_BulkTasks_Failed(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/tasks/bulk" && r.Method == "POST" {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"action":"enable","tasks":["cpu_alert","mem_alert"],"errors":[{"id":"mem_alert","error":"invalid TICKscript"}]}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	r, err := c.BulkTasks(client.BulkTasksOptions{
+		Action:   client.BulkEnable,
+		Patterns: []string{"*_alert"},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	exp := []client.BulkTaskError{{ID: "mem_alert", Error: "invalid TICKscript"}}
+	if !reflect.DeepEqual(exp, r.Errors) {
+		t.Errorf("unexpected errors: got:\n%v\nexp:\n%v", r.Errors, exp)
+	}
+}
+
+func Test_RollbackTask(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var opts client.RollbackTaskOptions
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &opts)
+		if r.URL.Path == "/kapacitor/v1/tasks/taskname/rollback" && r.Method == "POST" && opts.Version == 2 {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, `{"link": {"rel":"self", "href":"/kapacitor/v1/tasks/taskname"}, "id":"taskname", "script":"stream|from()"}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	task, err := c.RollbackTask(c.TaskLink("taskname"), client.RollbackTaskOptions{Version: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.ID != "taskname" || task.TICKscript != "stream|from()" {
+		t.Errorf("unexpected task: %v", task)
+	}
+}
+
+func Test_ValidateTask(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var opts client.ValidateTaskOptions
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &opts)
+		if r.URL.Path == "/kapacitor/v1/tasks/validate" && r.Method == "POST" &&
+			opts.Type == client.StreamTask &&
+			opts.TICKscript == "stream|from()|foo()" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, `{"valid":false,"type":"stream","dbrps":[{"db":"db","rp":"rp"}],"errors":[{"message":"line 1 char 15: no method or property \"foo\" on *pipeline.FromNode","line":1,"char":15}]}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	v, err := c.ValidateTask(client.ValidateTaskOptions{
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "db", RetentionPolicy: "rp"}},
+		TICKscript: "stream|from()|foo()",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.TaskValidation{
+		Type:  client.StreamTask,
+		DBRPs: []client.DBRP{{Database: "db", RetentionPolicy: "rp"}},
+		Errors: []client.ValidationMessage{{
+			Message: `line 1 char 15: no method or property "foo" on *pipeline.FromNode`,
+			Line:    1,
+			Char:    15,
+		}},
+	}
+	if !reflect.DeepEqual(v, exp) {
+		t.Errorf("unexpected validation:\ngot\n%v\nexp\n%v", v, exp)
+	}
+}
+
+func Test_LintTICKscript(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var opts client.LintOptions
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &opts)
+		if r.URL.Path == "/kapacitor/v1/tasks/lint" && r.Method == "POST" &&
+			opts.TICKscript == "var x = 1\nstream|from()" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, `{"formatted":"var x = 1\n\nstream\n    |from()\n","changed":true,"findings":[{"rule":"unused-var","message":"var x is declared but never used","line":1,"char":5}]}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	l, err := c.LintTICKscript(client.LintOptions{
+		TICKscript: "var x = 1\nstream|from()",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.TICKscriptLint{
+		Formatted: "var x = 1\n\nstream\n    |from()\n",
+		Changed:   true,
+		Findings: []client.LintFinding{{
+			Rule:    "unused-var",
+			Message: "var x is declared but never used",
+			Line:    1,
+			Char:    5,
+		}},
+	}
+	if !reflect.DeepEqual(l, exp) {
+		t.Errorf("unexpected lint:\ngot\n%v\nexp\n%v", l, exp)
+	}
+}
+
+func Test_ListTaskVersions(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/tasks/taskname/versions" && r.Method == "GET" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, `{"versions":[{"link":{"rel":"self","href":"/kapacitor/v1/tasks/taskname/versions/1"},"version":1,"type":"stream","script":"stream|from()","created":"2017-01-01T00:00:00Z"}]}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	versions, err := c.ListTaskVersions(c.TaskLink("taskname"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []client.TaskVersion{{
+		Link:       client.Link{Relation: client.Self, Href: "/kapacitor/v1/tasks/taskname/versions/1"},
+		Version:    1,
+		Type:       client.StreamTask,
+		TICKscript: "stream|from()",
+		Created:    time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC),
+	}}
+	if !reflect.DeepEqual(exp, versions) {
+		t.Errorf("unexpected versions: got:\n%v\nexp:\n%v", versions, exp)
+	}
+}
+
+func Test_WatchTask(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/tasks/taskname/watch" && r.Method == "GET" &&
+			r.URL.Query().Get("interval") == "5s" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintln(w, `{"type":"stats","time":"2026-10-16T10:00:00Z","stats":{"link":{"rel":"self","href":"/kapacitor/v1/tasks/taskname/stats"},"id":"taskname","executing":true,"node-stats":{"from1":{"collected":2,"emitted":2,"errors":0,"queue-depth":0,"buffered":0,"memory":0,"latency":{"count":0,"p50":"0s","p90":"0s","p99":"0s","max":"0s"}}}}}`)
+			fmt.Fprintln(w, `{"type":"error","time":"2026-10-16T10:00:01Z","error":{"node":"eval2","message":"error evaluating expression","error":"divide by zero"}}`)
+			fmt.Fprintln(w, `{"type":"alert","time":"2026-10-16T10:00:02Z","alert":{"topic":"main:taskname:alert3","id":"cpu","state":{"message":"cpu is CRITICAL","details":"","time":"2026-10-16T10:00:02Z","duration":"0s","level":"CRITICAL"}}}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var events []client.TaskWatchEvent
+	err = c.WatchTask(context.Background(), c.TaskLink("taskname"), &client.WatchTaskOptions{Interval: 5 * time.Second}, func(e client.TaskWatchEvent) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	exp := []client.TaskWatchEvent{
+		{
+			Type: client.TaskWatchStats,
+			Time: tm,
+			Stats: &client.TaskStats{
+				Link:      client.Link{Relation: client.Self, Href: "/kapacitor/v1/tasks/taskname/stats"},
+				ID:        "taskname",
+				Executing: true,
+				NodeStats: map[string]client.NodeStats{
+					"from1": {Collected: 2, Emitted: 2},
+				},
+			},
+		},
+		{
+			Type: client.TaskWatchError,
+			Time: tm.Add(time.Second),
+			Error: &client.NodeError{
+				Node:    "eval2",
+				Message: "error evaluating expression",
+				Error:   "divide by zero",
+			},
+		},
+		{
+			Type: client.TaskWatchAlert,
+			Time: tm.Add(2 * time.Second),
+			Alert: &client.TaskAlert{
+				Topic: "main:taskname:alert3",
+				ID:    "cpu",
+				State: client.EventState{
+					Message: "cpu is CRITICAL",
+					Time:    tm.Add(2 * time.Second),
+					Level:   "CRITICAL",
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(exp, events) {
+		t.Errorf("unexpected events: got:\n%v\nexp:\n%v", events, exp)
+	}
+}
+
 func Test_ListTasks(t *testing.T) {
 	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/kapacitor/v1/tasks" && r.Method == "GET" &&
@@ -2115,41 +2474,167 @@ func Test_ConfigUpdate_Element(t *testing.T) {
 	}
 }
 
-func Test_ConfigSections(t *testing.T) {
-	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/kapacitor/v1/config" && r.Method == "GET" {
-			w.WriteHeader(http.StatusOK)
-			fmt.Fprintf(w, `{
-	"link": {"rel":"self", "href":"/kapacitor/v1/config"},
-	"sections":{
-		"sectionA": {
-			"link": {"rel":"self", "href":"/kapacitor/v1/config/sectionA"},
-			"elements": [
-				{
-					"link": {"rel":"self", "href":"/kapacitor/v1/config/sectionA/A"},
-					"options" :{
-						"name": "A",
-						"optionA": "o1",
-						"optionB": "o2",
-						"optionC": "o3",
-						"optionD": "o4"
-					}
-				},
-				{
-					"link": {"rel":"self", "href":"/kapacitor/v1/config/sectionA/B"},
-					"options" :{
-						"name": "B",
-						"optionA": "o5",
-						"optionB": "o6",
-						"optionC": "o7",
-						"optionD": "o8"
-					}
-				}
-			]
+func Test_ConfigValidate(t *testing.T) {
+	expUpdate := client.ConfigUpdateAction{
+		Set: map[string]interface{}{
+			"option": "new value",
 		},
-		"sectionB": {
-			"link": {"rel":"self", "href":"/kapacitor/v1/config/sectionB"},
-			"elements" :[
+	}
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var update client.ConfigUpdateAction
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &update)
+		if r.URL.Path == "/kapacitor/v1/config/section/" && r.Method == "POST" &&
+			r.URL.Query().Get("validate") == "true" &&
+			reflect.DeepEqual(update, expUpdate) {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := c.ConfigValidate(c.ConfigElementLink("section", ""), expUpdate); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_ConfigApply(t *testing.T) {
+	expApply := client.ConfigApplyOptions{
+		Updates: []client.ConfigStagedUpdate{
+			{
+				Section: "sectionA",
+				ConfigUpdateAction: client.ConfigUpdateAction{
+					Set: map[string]interface{}{"option": "new value"},
+				},
+			},
+			{
+				Section: "sectionB",
+				Element: "X",
+				ConfigUpdateAction: client.ConfigUpdateAction{
+					Delete: []string{"option"},
+				},
+			},
+		},
+	}
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var apply client.ConfigApplyOptions
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &apply)
+		if r.URL.Path == "/kapacitor/v1/config" && r.Method == "POST" &&
+			r.URL.Query().Get("validate") == "" &&
+			reflect.DeepEqual(apply, expApply) {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := c.ConfigApply(expApply); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_ConfigDiff(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/config/diff/sectionA" && r.Method == "GET" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{
+	"link": {"rel":"self", "href":"/kapacitor/v1/config/diff/sectionA"},
+	"sections":{
+		"sectionA": {
+			"link": {"rel":"self", "href":"/kapacitor/v1/config/sectionA"},
+			"elements": [
+				{
+					"link": {"rel":"self", "href":"/kapacitor/v1/config/sectionA/A"},
+					"added": false,
+					"options": {
+						"optionA": {"file": "o1", "running": "new-o1"},
+						"password": {"file": false, "running": true, "redacted": true}
+					}
+				}
+			]
+		}
+	}
+}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	diff, err := c.ConfigDiff("sectionA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.ConfigDiff{
+		Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/diff/sectionA"},
+		Sections: map[string]client.ConfigSectionDiff{
+			"sectionA": {
+				Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/sectionA"},
+				Elements: []client.ConfigElementDiff{{
+					Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/sectionA/A"},
+					Options: map[string]client.ConfigOptionDiff{
+						"optionA":  {File: "o1", Running: "new-o1"},
+						"password": {File: false, Running: true, Redacted: true},
+					},
+				}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(diff, exp) {
+		t.Errorf("unexpected config diff:\ngot\n%v\nexp\n%v", diff, exp)
+	}
+}
+
+func Test_ConfigSections(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/config" && r.Method == "GET" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{
+	"link": {"rel":"self", "href":"/kapacitor/v1/config"},
+	"sections":{
+		"sectionA": {
+			"link": {"rel":"self", "href":"/kapacitor/v1/config/sectionA"},
+			"elements": [
+				{
+					"link": {"rel":"self", "href":"/kapacitor/v1/config/sectionA/A"},
+					"options" :{
+						"name": "A",
+						"optionA": "o1",
+						"optionB": "o2",
+						"optionC": "o3",
+						"optionD": "o4"
+					}
+				},
+				{
+					"link": {"rel":"self", "href":"/kapacitor/v1/config/sectionA/B"},
+					"options" :{
+						"name": "B",
+						"optionA": "o5",
+						"optionB": "o6",
+						"optionC": "o7",
+						"optionD": "o8"
+					}
+				}
+			]
+		},
+		"sectionB": {
+			"link": {"rel":"self", "href":"/kapacitor/v1/config/sectionB"},
+			"elements" :[
 				{
 					"link": {"rel":"self", "href":"/kapacitor/v1/config/sectionB/X"},
 					"options" :{
@@ -3082,6 +3567,407 @@ func Test_LogLevel(t *testing.T) {
 	}
 }
 
+func Test_SetLogLevel(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var opts client.LogLevelOptions
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &opts)
+
+		if r.URL.Path == "/kapacitor/v1/loglevel" && r.Method == "POST" &&
+			opts.Level == "DEBUG" && opts.Task == "cpu" && opts.Component == "" {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	err = c.SetLogLevel(client.LogLevelOptions{Level: "DEBUG", Task: "cpu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_LogLevels(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/loglevel" && r.Method == "GET" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{"level":"INFO","components":{"http":"ERROR"},"tasks":{"cpu":"DEBUG"}}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	levels, err := c.LogLevels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.LogLevels{
+		Level:      "INFO",
+		Components: map[string]string{"http": "ERROR"},
+		Tasks:      map[string]string{"cpu": "DEBUG"},
+	}
+	if !reflect.DeepEqual(exp, levels) {
+		t.Errorf("unexpected log levels:\ngot\n%v\nexp\n%v", levels, exp)
+	}
+}
+
+func Test_Health(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/kapacitor/v1/health" && r.Method == "GET":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, `{"link":{"rel":"self","href":"/kapacitor/v1/health"},"status":"fail","ready":true,"checks":{"influxdb":{"status":"fail","components":{"default":{"status":"fail","message":"connection refused"}}}}}`)
+		case r.URL.Path == "/kapacitor/v1/ready" && r.Method == "GET":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, `{"error":"server is not open"}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	h, err := c.Health()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.Health{
+		Link:   client.Link{Relation: client.Self, Href: "/kapacitor/v1/health"},
+		Status: client.HealthFail,
+		Ready:  true,
+		Checks: map[string]client.HealthCheck{
+			"influxdb": {
+				Status: client.HealthFail,
+				Components: map[string]client.HealthCheck{
+					"default": {Status: client.HealthFail, Message: "connection refused"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(exp, h) {
+		t.Errorf("unexpected health:\ngot\n%v\nexp\n%v", h, exp)
+	}
+
+	if err := c.Ready(); err == nil || err.Error() != "server is not open" {
+		t.Errorf("unexpected ready error %v", err)
+	}
+}
+
+func Test_InfluxDBStatus(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/influxdb" && r.Method == "GET" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{"link":{"rel":"self","href":"/kapacitor/v1/influxdb"},"clusters":{"default":{"default":true,"healthy":true,"urls":[{"url":"http://a:8086","healthy":false,"last-check":"2026-10-16T10:00:00Z","error":"connection refused"},{"url":"http://b:8086","healthy":true,"last-check":"2026-10-16T10:00:00Z","version":"1.8.10"}]}}}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	status, err := c.InfluxDBStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastCheck := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	exp := client.InfluxDBStatus{
+		Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/influxdb"},
+		Clusters: map[string]client.InfluxDBClusterStatus{
+			"default": {
+				Default: true,
+				Healthy: true,
+				URLs: []client.InfluxDBURLStatus{
+					{URL: "http://a:8086", LastCheck: lastCheck, Error: "connection refused"},
+					{URL: "http://b:8086", Healthy: true, LastCheck: lastCheck, Version: "1.8.10"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(exp, status) {
+		t.Errorf("unexpected status:\ngot\n%v\nexp\n%v", status, exp)
+	}
+}
+
+func Test_DBRPsStatus(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/dbrps" && r.Method == "GET" &&
+			r.URL.Query().Get("live-window") == "1m0s" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{"link":{"rel":"self","href":"/kapacitor/v1/dbrps"},"dbrps":[{"db":"telegraf","rp":"autogen","live":true,"points-received":42,"last-write":"2026-10-16T10:00:00Z","tasks":["cpu_alert"],"subscriptions":["default"]}],"orphaned-subscriptions":[{"cluster":"default","db":"old","rp":"autogen","name":"kapacitor-f0a1","destinations":["http://kapacitor:9092"],"reason":"the subscription has a previous name of this Kapacitor"}]}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	status, err := c.DBRPsStatus(&client.DBRPsStatusOptions{LiveWindow: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.DBRPsStatus{
+		Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/dbrps"},
+		DBRPs: []client.DBRPUsage{{
+			Database:        "telegraf",
+			RetentionPolicy: "autogen",
+			Live:            true,
+			PointsReceived:  42,
+			LastWrite:       time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC),
+			Tasks:           []string{"cpu_alert"},
+			Subscriptions:   []string{"default"},
+		}},
+		OrphanedSubscriptions: []client.OrphanedSubscription{{
+			Cluster:         "default",
+			Database:        "old",
+			RetentionPolicy: "autogen",
+			Name:            "kapacitor-f0a1",
+			Destinations:    []string{"http://kapacitor:9092"},
+			Reason:          "the subscription has a previous name of this Kapacitor",
+		}},
+	}
+	if !reflect.DeepEqual(exp, status) {
+		t.Errorf("unexpected status:\ngot\n%v\nexp\n%v", status, exp)
+	}
+}
+
+func Test_ReconcileDBRPs(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/dbrps/reconcile" && r.Method == "POST" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{"removed":[{"cluster":"default","db":"old","rp":"autogen","name":"kapacitor-f0a1","destinations":["http://kapacitor:9092"],"reason":"the subscriptions are disabled"}]}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	result, err := c.ReconcileDBRPs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.ReconcileResult{
+		Removed: []client.OrphanedSubscription{{
+			Cluster:         "default",
+			Database:        "old",
+			RetentionPolicy: "autogen",
+			Name:            "kapacitor-f0a1",
+			Destinations:    []string{"http://kapacitor:9092"},
+			Reason:          "the subscriptions are disabled",
+		}},
+	}
+	if !reflect.DeepEqual(exp, result) {
+		t.Errorf("unexpected result:\ngot\n%v\nexp\n%v", result, exp)
+	}
+}
+
+func Test_Evaluate(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var opt client.EvaluateOptions
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &opt)
+		if r.URL.Path == "/kapacitor/v1/replays/evaluate" && r.Method == "POST" &&
+			opt.Recording == "rec" && opt.Script == "stream|from()" && opt.Limit == 5 &&
+			len(opt.DBRPs) == 1 && opt.DBRPs[0].Database == "telegraf" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{"recording":"rec","outputs":[{"node":"from1","rows":[{"name":"cpu","columns":["time","value"],"values":[["2026-10-16T10:00:00Z",1]]}]}],"events":[{"source":"alert2","id":"cpu","state":{"message":"cpu is CRITICAL","details":"","time":"2026-10-16T10:00:00Z","duration":"0s","level":"CRITICAL"}}],"writes":[{"db":"out","rp":"autogen","measurement":"cpu","tags":{"host":"serverA"},"time":"2026-10-16T10:00:00Z","fields":{"value":1}}],"event-count":1,"write-count":1,"stats":{}}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	e, err := c.Evaluate(client.EvaluateOptions{
+		Recording: "rec",
+		Script:    "stream|from()",
+		DBRPs:     []client.DBRP{{Database: "telegraf", RetentionPolicy: "autogen"}},
+		Limit:     5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	exp := client.Evaluation{
+		Recording: "rec",
+		Outputs: []client.NodeOutput{{
+			Node: "from1",
+			Rows: []client.Row{{
+				Name:    "cpu",
+				Columns: []string{"time", "value"},
+				Values:  [][]interface{}{{"2026-10-16T10:00:00Z", 1.0}},
+			}},
+		}},
+		Events: []client.EvaluatedEvent{{
+			Source: "alert2",
+			ID:     "cpu",
+			State: client.EventState{
+				Message: "cpu is CRITICAL",
+				Time:    tm,
+				Level:   "CRITICAL",
+			},
+		}},
+		Writes: []client.WrittenPoint{{
+			Database:        "out",
+			RetentionPolicy: "autogen",
+			Measurement:     "cpu",
+			Tags:            map[string]string{"host": "serverA"},
+			Time:            tm,
+			Fields:          map[string]interface{}{"value": 1.0},
+		}},
+		EventCount: 1,
+		WriteCount: 1,
+	}
+	if !reflect.DeepEqual(exp, e) {
+		t.Errorf("unexpected evaluation:\ngot\n%v\nexp\n%v", e, exp)
+	}
+}
+
+func Test_TestTask(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var test client.TaskTest
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &test)
+		if r.URL.Path == "/kapacitor/v1/replays/test" && r.Method == "POST" &&
+			test.Name == "cpu" && test.Task == "cpu_alert" &&
+			len(test.Points) == 1 && test.Points[0].At == client.Duration(10*time.Second) &&
+			test.Expect.Alerts != nil && len(test.Expect.Alerts) == 0 && test.Expect.Points == nil {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{"name":"cpu","passed":false,"failures":["expected 0 alert events, got 1","alert event 0: unexpected id=cpu level=CRITICAL at=10s"],"alerts":[{"source":"alert2","id":"cpu","state":{"message":"cpu is CRITICAL","details":"","time":"1970-01-01T00:00:10Z","duration":"0s","level":"CRITICAL"}}],"points":[]}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	r, err := c.TestTask(client.TaskTest{
+		Name:   "cpu",
+		Task:   "cpu_alert",
+		Points: []client.TestPoint{{At: client.Duration(10 * time.Second), Line: "cpu usage=95"}},
+		Expect: client.TestExpectations{Alerts: []client.ExpectedAlert{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.TaskTestResult{
+		Name: "cpu",
+		Failures: []string{
+			"expected 0 alert events, got 1",
+			"alert event 0: unexpected id=cpu level=CRITICAL at=10s",
+		},
+		Alerts: []client.EvaluatedEvent{{
+			Source: "alert2",
+			ID:     "cpu",
+			State: client.EventState{
+				Message: "cpu is CRITICAL",
+				Time:    time.Date(1970, 1, 1, 0, 0, 10, 0, time.UTC),
+				Level:   "CRITICAL",
+			},
+		}},
+		Points: []client.WrittenPoint{},
+	}
+	if !reflect.DeepEqual(exp, r) {
+		t.Errorf("unexpected result:\ngot\n%v\nexp\n%v", r, exp)
+	}
+}
+
+func Test_SideloadSources(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/sideload/sources" && r.Method == "GET" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{"link":{"rel":"self","href":"/kapacitor/v1/sideload/sources"},"sources":[{"source":"file:///etc/kapacitor/sideload","references":[{"task":"cpu_alert","node":"sideload2","order":["host/{{.host}}.yml","default.yml"],"keys":["cpu_threshold"]}],"files":[{"path":"default.yml","keys":["cpu_threshold"],"references":[{"task":"cpu_alert","node":"sideload2","keys":["cpu_threshold"]}]},{"path":"host/serverA.yml","keys":[],"references":[{"task":"cpu_alert","node":"sideload2","keys":[]}],"error":"failed to unmarshal yaml values"}]}]}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	sources, err := c.SideloadSources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.SideloadSources{
+		Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/sideload/sources"},
+		Sources: []client.SideloadSource{{
+			Source: "file:///etc/kapacitor/sideload",
+			References: []client.SideloadReference{{
+				Task:  "cpu_alert",
+				Node:  "sideload2",
+				Order: []string{"host/{{.host}}.yml", "default.yml"},
+				Keys:  []string{"cpu_threshold"},
+			}},
+			Files: []client.SideloadFile{
+				{
+					Path:       "default.yml",
+					Keys:       []string{"cpu_threshold"},
+					References: []client.SideloadReference{{Task: "cpu_alert", Node: "sideload2", Keys: []string{"cpu_threshold"}}},
+				},
+				{
+					Path:       "host/serverA.yml",
+					Keys:       []string{},
+					References: []client.SideloadReference{{Task: "cpu_alert", Node: "sideload2", Keys: []string{}}},
+					Error:      "failed to unmarshal yaml values",
+				},
+			},
+		}},
+	}
+	if !reflect.DeepEqual(exp, sources) {
+		t.Errorf("unexpected sources:\ngot\n%v\nexp\n%v", sources, exp)
+	}
+}
+
+func Test_ReloadSideload(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/sideload/reload" && r.Method == "POST" {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := c.ReloadSideload(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func Test_Bad_Creds(t *testing.T) {
 	testCases := []struct {
 		creds *client.Credentials
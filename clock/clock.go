@@ -9,7 +9,7 @@ import (
 )
 
 // A clock interface to read time and wait until an absolute time arrives.
-// Three implementations are available: A 'wall' clock that is based on realtime, a 'fast' clock that is always ahead and a 'set' clock that can be controlled via a setting time explicitly.
+// The implementations are: A 'wall' clock that is based on realtime, a 'scaled' clock that runs at a multiple of realtime, a 'fast' clock that is always ahead and a 'set' clock that can be controlled via a setting time explicitly.
 type Clock interface {
 	Setter
 	// Wait until time t has arrived. If t is in the past it immediately returns.
@@ -43,6 +43,32 @@ func (w *wallclock) Until(t time.Time) {
 
 func (w *wallclock) Set(t time.Time) {}
 
+// realtime implementation of clock that runs at a multiple of wall time
+type scaledclock struct {
+	zero  time.Time
+	speed float64
+}
+
+// Get a clock that runs speed times faster than a realtime wall clock.
+// A speed of 10 waits a tenth of the time a wall clock would, 0.5 waits twice as long.
+func Scaled(speed float64) Clock {
+	if speed <= 0 {
+		panic("clock speed must be positive")
+	}
+	return &scaledclock{zero: time.Now(), speed: speed}
+}
+
+func (s *scaledclock) Zero() time.Time {
+	return s.zero
+}
+
+func (s *scaledclock) Until(t time.Time) {
+	wait := time.Duration(float64(t.Sub(s.zero)) / s.speed)
+	time.Sleep(s.zero.Add(wait).Sub(time.Now()))
+}
+
+func (s *scaledclock) Set(t time.Time) {}
+
 // implementation of clock that is always in the future
 type fastclock struct {
 	zero time.Time
@@ -100,3 +126,96 @@ func (c *setclock) Set(t time.Time) {
 	c.cond.Broadcast()
 	c.cond.L.Unlock()
 }
+
+// A StepClock lets calls to Until return one at a time as steps are granted,
+// so that a replay can be executed step by step.
+type StepClock struct {
+	zero time.Time
+	cond *sync.Cond
+
+	// Number of calls to Until that may return
+	granted int64
+	// Number of calls to Until that returned
+	steps   int64
+	running bool
+	closed  bool
+	// Time of the last call to Until that returned
+	now time.Time
+}
+
+// Get a clock that is controlled by granting steps.
+// No call to Until returns until steps are granted.
+func Step(start time.Time) *StepClock {
+	return &StepClock{
+		zero: start,
+		cond: sync.NewCond(&sync.Mutex{}),
+	}
+}
+
+func (c *StepClock) Zero() time.Time {
+	return c.zero
+}
+
+func (c *StepClock) Set(t time.Time) {}
+
+func (c *StepClock) Until(t time.Time) {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	for !c.closed && !c.running && c.steps >= c.granted {
+		c.cond.Wait()
+	}
+	c.steps++
+	c.now = t
+	c.cond.Broadcast()
+}
+
+// Step lets the next n calls to Until return.
+func (c *StepClock) Step(n int64) {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	if c.granted < c.steps {
+		c.granted = c.steps
+	}
+	c.granted += n
+	c.cond.Broadcast()
+}
+
+// Run lets all calls to Until return until the clock is paused.
+func (c *StepClock) Run() {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	c.running = true
+	c.cond.Broadcast()
+}
+
+// Pause revokes the steps not yet taken, so that calls to Until block again.
+func (c *StepClock) Pause() {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	c.running = false
+	c.granted = c.steps
+}
+
+// Close lets all calls to Until return from now on.
+func (c *StepClock) Close() {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	c.closed = true
+	c.cond.Broadcast()
+}
+
+// Steps returns the number of calls to Until that returned and the time of the last one.
+func (c *StepClock) Steps() (int64, time.Time) {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	return c.steps, c.now
+}
+
+// Wait blocks until the given number of calls to Until returned or the clock is closed.
+func (c *StepClock) Wait(steps int64) {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	for !c.closed && c.steps < steps {
+		c.cond.Wait()
+	}
+}
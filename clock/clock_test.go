@@ -163,3 +163,69 @@ func TestWallClock(t *testing.T) {
 		t.Fatal("expected return from c.Until")
 	}
 }
+
+func TestScaledClock(t *testing.T) {
+	for _, speed := range []float64{10, 0.5} {
+		c := clock.Scaled(speed)
+		zero := c.Zero()
+		start := time.Now()
+		c.Until(zero.Add(50 * time.Millisecond))
+		exp := time.Duration(float64(50*time.Millisecond) / speed)
+		if got := time.Since(start); got < exp-time.Millisecond || got > exp+50*time.Millisecond {
+			t.Errorf("unexpected wait at speed %v: got %v exp %v", speed, got, exp)
+		}
+	}
+}
+
+func TestStepClock(t *testing.T) {
+	c := clock.Step(time.Time{})
+	zero := c.Zero()
+
+	returned := make(chan time.Time)
+	go func() {
+		for i := 1; i <= 4; i++ {
+			til := zero.Add(time.Duration(i) * time.Second)
+			c.Until(til)
+			returned <- til
+		}
+	}()
+
+	select {
+	case <-returned:
+		t.Fatal("unexpected return from c.Until before a step")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	c.Step(2)
+	for i := 1; i <= 2; i++ {
+		select {
+		case <-returned:
+		case <-time.After(20 * time.Millisecond):
+			t.Fatalf("expected return %d from c.Until", i)
+		}
+	}
+	c.Wait(2)
+	if steps, now := c.Steps(); steps != 2 || !now.Equal(zero.Add(2*time.Second)) {
+		t.Errorf("unexpected steps got %d %v exp 2 %v", steps, now, zero.Add(2*time.Second))
+	}
+	select {
+	case <-returned:
+		t.Fatal("unexpected return from c.Until after steps were taken")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	c.Run()
+	select {
+	case <-returned:
+	case <-time.After(20 * time.Millisecond):
+		t.Fatal("expected return from c.Until while running")
+	}
+	c.Pause()
+	c.Wait(3)
+	c.Close()
+	select {
+	case <-returned:
+	case <-time.After(20 * time.Millisecond):
+		t.Fatal("expected return from c.Until once closed")
+	}
+}
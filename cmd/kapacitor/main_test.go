@@ -0,0 +1,458 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	client "github.com/influxdata/kapacitor/client/v1"
+)
+
+func TestVarFlags(t *testing.T) {
+	testCases := []struct {
+		flags []string
+		types map[string]client.VarType
+		exp   client.Vars
+	}{
+		{
+			flags: []string{"crit:float=80", "field=value", "every=1m", "enabled:bool=true"},
+			types: map[string]client.VarType{"every": client.VarDuration},
+			exp: client.Vars{
+				"crit":    {Type: client.VarFloat, Value: 80.0},
+				"field":   {Type: client.VarString, Value: "value"},
+				"every":   {Type: client.VarDuration, Value: time.Minute},
+				"enabled": {Type: client.VarBool, Value: true},
+			},
+		},
+		{
+			flags: []string{"tags:list=host,cpu", "ids:list:int=1,2", "windows:list:duration=1m", "empty:list="},
+			exp: client.Vars{
+				"tags": {Type: client.VarList, Value: []client.Var{
+					{Type: client.VarString, Value: "host"},
+					{Type: client.VarString, Value: "cpu"},
+				}},
+				"ids": {Type: client.VarList, Value: []client.Var{
+					{Type: client.VarInt, Value: int64(1)},
+					{Type: client.VarInt, Value: int64(2)},
+				}},
+				"windows": {Type: client.VarList, Value: []client.Var{
+					{Type: client.VarDuration, Value: time.Minute},
+				}},
+				"empty": {Type: client.VarList, Value: []client.Var{}},
+			},
+		},
+		{
+			// The type of the template or task is used for untyped lists too.
+			flags: []string{"tags=host"},
+			types: map[string]client.VarType{"tags": client.VarList},
+			exp: client.Vars{
+				"tags": {Type: client.VarList, Value: []client.Var{{Type: client.VarString, Value: "host"}}},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		var flags varFlags
+		for _, f := range tc.flags {
+			if err := flags.Set(f); err != nil {
+				t.Fatalf("%s: %v", f, err)
+			}
+		}
+		vars, err := flags.Vars(tc.types)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(vars, tc.exp) {
+			t.Errorf("unexpected vars of %v:\ngot %v\nexp %v", tc.flags, vars, tc.exp)
+		}
+	}
+}
+
+func TestVarFlags_Errors(t *testing.T) {
+	testCases := []struct {
+		flag string
+		err  string
+	}{
+		{flag: "crit", err: "var must be in the form name[:type]=value"},
+		{flag: "crit:number=1", err: "unknown VarType number"},
+		{flag: "crit:float:int=1", err: "var crit: only a list has an element type"},
+		{flag: "ids:list:list=1", err: "var ids: the elements of a list cannot be lists"},
+		{flag: "ids:list:number=1", err: "unknown VarType number"},
+	}
+	for _, tc := range testCases {
+		var flags varFlags
+		if err := flags.Set(tc.flag); err == nil || err.Error() != tc.err {
+			t.Errorf("%s: unexpected error: got %v exp %s", tc.flag, err, tc.err)
+		}
+	}
+
+	var flags varFlags
+	flags.Set("crit:float=high")
+	flags.Set("ids:list:int=1,x")
+	for _, f := range flags {
+		_, err := varFlags{f}.Vars(nil)
+		if err == nil {
+			t.Errorf("%s: expected error parsing the value %q", f.name, f.value)
+		}
+	}
+	if _, err := (varFlags{flags[1]}).Vars(nil); err == nil || !strings.Contains(err.Error(), `invalid int element "x"`) {
+		t.Errorf("unexpected error of the list element: %v", err)
+	}
+}
+
+func TestDefine_FileAndVarFlags(t *testing.T) {
+	var created client.CreateTaskOptions
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/kapacitor/v1/tasks":
+			if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+				t.Error(err)
+			}
+			w.Write([]byte(`{"id":"my_task"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not found"}`))
+		}
+	}))
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "kapacitor-define")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "task.yaml")
+	if err := ioutil.WriteFile(file, []byte(`
+template-id: my_template
+dbrps:
+  - db: telegraf
+    rp: autogen
+vars:
+  crit:
+    type: float
+    value: 80
+  warn:
+    type: float
+    value: 70
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cli, err = connect(s.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { cli = nil; dvarFlags = nil }()
+	if err := doDefine([]string{"my_task", "-file", file, "-var", "crit=90", "-var", "ids:list:int=1,2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := client.CreateTaskOptions{
+		ID:         "my_task",
+		TemplateID: "my_template",
+		DBRPs:      []client.DBRP{{Database: "telegraf", RetentionPolicy: "autogen"}},
+		Vars: client.Vars{
+			// The flag overrides the var of the file, with the type of the var in the file.
+			"crit": {Type: client.VarFloat, Value: 90.0},
+			"warn": {Type: client.VarFloat, Value: 70.0},
+			"ids": {Type: client.VarList, Value: []client.Var{
+				{Type: client.VarInt, Value: json.Number("1")},
+				{Type: client.VarInt, Value: json.Number("2")},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(created, exp) {
+		t.Errorf("unexpected created task:\ngot %+v\nexp %+v", created, exp)
+	}
+}
+
+// taskServer serves the tasks with the IDs and records the bulk actions.
+func taskServer(t *testing.T, ids ...string) (*httptest.Server, *[]client.BulkTasksOptions) {
+	var bulk []client.BulkTasksOptions
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/kapacitor/v1/tasks":
+			prefix := strings.TrimSuffix(r.URL.Query().Get("pattern"), "*")
+			tasks := []map[string]string{}
+			for _, id := range ids {
+				if strings.HasPrefix(id, prefix) {
+					tasks = append(tasks, map[string]string{"id": id})
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"tasks": tasks})
+		case r.Method == "POST" && r.URL.Path == "/kapacitor/v1/tasks/bulk":
+			var o client.BulkTasksOptions
+			if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
+				t.Error(err)
+			}
+			bulk = append(bulk, o)
+			json.NewEncoder(w).Encode(client.BulkTasksResult{Action: o.Action, Tasks: o.Patterns})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":"unavailable"}`))
+		}
+	}))
+	return s, &bulk
+}
+
+func TestBulkTasks_AbbreviatedIDs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		tasks    []string
+		patterns []string
+		answer   string
+		exp      []string
+		err      string
+	}{
+		{
+			name:     "exact ID",
+			tasks:    []string{"foo", "foobar"},
+			patterns: []string{"foo"},
+			exp:      []string{"foo"},
+		},
+		{
+			name:     "prefix not confirmed",
+			tasks:    []string{"foobar"},
+			patterns: []string{"foo"},
+			err:      `task ID "foo" does not exist, use the full task ID foobar`,
+		},
+		{
+			name:     "prefix refused",
+			tasks:    []string{"foobar"},
+			patterns: []string{"foo"},
+			answer:   "n\n",
+			err:      `task ID "foo" does not exist, use the full task ID foobar`,
+		},
+		{
+			name:     "prefix confirmed",
+			tasks:    []string{"foobar", "other"},
+			patterns: []string{"foo", "oth*"},
+			answer:   "y\n",
+			exp:      []string{"foobar", "oth*"},
+		},
+		{
+			name:     "ambiguous prefix",
+			tasks:    []string{"foobar", "foobaz"},
+			patterns: []string{"foo"},
+			answer:   "y\n",
+			err:      `ambiguous task ID "foo" matches foobar, foobaz`,
+		},
+		{
+			name:     "unknown ID",
+			tasks:    []string{"foobar"},
+			patterns: []string{"bar"},
+			exp:      []string{"bar"},
+		},
+	}
+	defer func() { cli = nil; confirmIn = bufio.NewReader(os.Stdin) }()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, bulk := taskServer(t, tc.tasks...)
+			defer s.Close()
+			var err error
+			cli, err = connect(s.URL, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			confirmIn = bufio.NewReader(strings.NewReader(tc.answer))
+
+			err = bulkTasks(client.BulkDelete, tc.patterns, "")
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("unexpected error: got %v exp %s", err, tc.err)
+				}
+				if len(*bulk) != 0 {
+					t.Errorf("unexpected bulk actions: %v", *bulk)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			exp := []client.BulkTasksOptions{{Action: client.BulkDelete, Patterns: tc.exp}}
+			if !reflect.DeepEqual(*bulk, exp) {
+				t.Errorf("unexpected bulk actions:\ngot %v\nexp %v", *bulk, exp)
+			}
+		})
+	}
+}
+
+func TestResolveTaskID_Errors(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"unavailable"}`))
+	}))
+	defer s.Close()
+	var err error
+	cli, err = connect(s.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { cli = nil }()
+
+	if _, err := resolveTaskID("foo"); err == nil || !strings.HasPrefix(err.Error(), `failed to look up task ID "foo": `) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := bulkTasks(client.BulkDisable, []string{"foo"}, ""); err == nil || !strings.HasPrefix(err.Error(), `failed to look up task ID "foo": `) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// listServer serves a few tasks and topics to list.
+func listServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/kapacitor/v1/tasks":
+			json.NewEncoder(w).Encode(map[string]interface{}{"tasks": []client.Task{
+				{
+					ID:        "cpu_alert",
+					Type:      client.StreamTask,
+					Status:    client.Enabled,
+					Executing: true,
+					DBRPs:     []client.DBRP{{Database: "telegraf", RetentionPolicy: "autogen"}},
+					Labels:    client.Labels{"team": "db"},
+				},
+				{
+					ID:     "downsample_all_the_measurements",
+					Type:   client.BatchTask,
+					Status: client.Disabled,
+					DBRPs: []client.DBRP{
+						{Database: "telegraf", RetentionPolicy: "autogen"},
+						{Database: "telegraf", RetentionPolicy: "downsampled"},
+					},
+					Labels: client.Labels{"env": "prod"},
+				},
+			}})
+		case "/kapacitor/v1/alerts/topics":
+			json.NewEncoder(w).Encode(map[string]interface{}{"topics": []client.Topic{
+				{ID: "main:cpu_alert:alert2", Level: "CRITICAL", Collected: 12},
+				{ID: "system", Level: "OK"},
+			}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not found"}`))
+		}
+	}))
+}
+
+func TestList_Output(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+		exp  string
+		err  string
+	}{
+		{
+			name: "tasks table",
+			args: []string{"tasks"},
+			exp: `ID                              Type      Status    Executing Databases and Retention Policies                Labels
+cpu_alert                       stream    enabled   true      ["telegraf"."autogen"]                          team=db
+downsample_all_the_measurements batch     disabled  false     ["telegraf"."autogen" "telegraf"."downsampled"] env=prod
+`,
+		},
+		{
+			name: "topics table",
+			args: []string{"topics"},
+			exp: `ID                    Level     Collected
+main:cpu_alert:alert2 CRITICAL         12
+system                OK                0
+`,
+		},
+		{
+			name: "table columns",
+			args: []string{"tasks", "-columns", "status, id"},
+			exp: `Status    ID
+enabled   cpu_alert
+disabled  downsample_all_the_measurements
+`,
+		},
+		{
+			name: "json",
+			args: []string{"-format", "json", "-columns", "id,executing,dbrps", "tasks"},
+			exp: `[
+    {
+        "dbrps": [
+            {
+                "db": "telegraf",
+                "rp": "autogen"
+            }
+        ],
+        "executing": true,
+        "id": "cpu_alert"
+    },
+    {
+        "dbrps": [
+            {
+                "db": "telegraf",
+                "rp": "autogen"
+            },
+            {
+                "db": "telegraf",
+                "rp": "downsampled"
+            }
+        ],
+        "executing": false,
+        "id": "downsample_all_the_measurements"
+    }
+]
+`,
+		},
+		{
+			name: "yaml",
+			args: []string{"topics", "-format", "yaml"},
+			exp: `- collected: 12
+  id: main:cpu_alert:alert2
+  level: CRITICAL
+- collected: 0
+  id: system
+  level: OK
+`,
+		},
+		{
+			name: "unknown column",
+			args: []string{"tasks", "-columns", "id,vars"},
+			err:  `unknown column "vars", must be one of id,type,status,executing,dbrps,labels`,
+		},
+		{
+			name: "unknown format",
+			args: []string{"-format", "xml", "topics"},
+			err:  `invalid format "xml", must be one of table, json or yaml`,
+		},
+	}
+	s := listServer(t)
+	defer s.Close()
+	var err error
+	cli, err = connect(s.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { cli = nil; listOut = os.Stdout }()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			*lsFormat, *lsColumns = "table", ""
+			var out strings.Builder
+			listOut = &out
+
+			err := doList(tc.args)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("unexpected error: got %v exp %s", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tc.exp {
+				t.Errorf("unexpected output:\ngot\n%s\nexp\n%s", got, tc.exp)
+			}
+		})
+	}
+}
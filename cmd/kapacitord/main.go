@@ -88,6 +88,9 @@ func (m *Main) Run(args ...string) error {
 			m.Diag.Error("encountered error", err)
 			return fmt.Errorf("run: %s", err)
 		}
+		if cmd.Once {
+			return nil
+		}
 
 		signalCh := make(chan os.Signal, 1)
 		signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
@@ -104,7 +107,10 @@ func (m *Main) Run(args ...string) error {
 				break Loop
 
 			case syscall.SIGHUP.String():
-				m.Diag.Info("SIGHUP received, reloading tasks/templates/handlers directory...")
+				m.Diag.Info("SIGHUP received, reloading configuration and tasks/templates/handlers directory...")
+				if err := cmd.ReloadConfig(); err != nil {
+					m.Diag.Error("failed to reload configuration", err)
+				}
 				cmd.Server.Reload()
 
 			default:
@@ -10,7 +10,6 @@ import (
 	"path/filepath"
 	"strconv"
 
-	"github.com/BurntSushi/toml"
 	"github.com/influxdata/kapacitor/server"
 	"github.com/influxdata/kapacitor/services/diagnostic"
 )
@@ -44,6 +43,11 @@ type Command struct {
 	closing chan struct{}
 	pidfile string
 	Closed  chan struct{}
+	// Options of the command, to load the config again.
+	options Options
+	// Once reports whether the command ran a task to completion with the -once option,
+	// instead of starting the server.
+	Once bool
 
 	Stdin  io.Reader
 	Stdout io.Writer
@@ -73,35 +77,20 @@ func (cmd *Command) Run(args ...string) error {
 	if err != nil {
 		return err
 	}
+	if options.Once {
+		cmd.Once = true
+		return cmd.runOnce(options)
+	}
 
 	// Print sweet Kapacitor logo.
 	fmt.Print(logo)
 
-	// Parse config
-	config, err := cmd.ParseConfig(FindConfigPath(options.ConfigPath))
+	// Load config
+	config, err := cmd.loadConfig(options)
 	if err != nil {
-		return fmt.Errorf("parse config: %s", err)
-	}
-
-	// Apply any environment variables on top of the parsed config
-	if err := config.ApplyEnvOverrides(); err != nil {
-		return fmt.Errorf("apply env config: %v", err)
-	}
-
-	// Override config hostname if specified in the command line args.
-	if options.Hostname != "" {
-		config.Hostname = options.Hostname
-	}
-
-	// Override config logging file if specified in the command line args.
-	if options.LogFile != "" {
-		config.Logging.File = options.LogFile
-	}
-
-	// Override config logging level if specified in the command line args.
-	if options.LogLevel != "" {
-		config.Logging.Level = options.LogLevel
+		return err
 	}
+	cmd.options = options
 
 	// Initialize Logging Services
 	cmd.diagService = diagnostic.NewService(config.Logging, cmd.Stdout, cmd.Stderr)
@@ -142,6 +131,53 @@ func (cmd *Command) Run(args ...string) error {
 	return nil
 }
 
+// loadConfig parses the config and applies the environment variables and the command line options.
+func (cmd *Command) loadConfig(options Options) (*server.Config, error) {
+	// Parse config
+	config, err := cmd.ParseConfig(FindConfigPath(options.ConfigPath), options.ConfigDir)
+	if err != nil {
+		return nil, fmt.Errorf("parse config: %s", err)
+	}
+
+	// Apply any environment variables on top of the parsed config
+	if err := config.ApplyEnvOverrides(); err != nil {
+		return nil, fmt.Errorf("apply env config: %v", err)
+	}
+
+	// Replace the $ENV{} and $FILE{} references
+	if err := config.Interpolate(); err != nil {
+		return nil, fmt.Errorf("interpolate config: %v", err)
+	}
+
+	// Override config hostname if specified in the command line args.
+	if options.Hostname != "" {
+		config.Hostname = options.Hostname
+	}
+
+	// Override config logging file if specified in the command line args.
+	if options.LogFile != "" {
+		config.Logging.File = options.LogFile
+	}
+
+	// Override config logging level if specified in the command line args.
+	if options.LogLevel != "" {
+		config.Logging.Level = options.LogLevel
+	}
+	return config, nil
+}
+
+// ReloadConfig loads the config again and updates the services whose configuration changed.
+func (cmd *Command) ReloadConfig() error {
+	config, err := cmd.loadConfig(cmd.options)
+	if err != nil {
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+	return cmd.Server.ReloadConfig(config)
+}
+
 // Close shuts down the server.
 func (cmd *Command) Close() error {
 	defer close(cmd.Closed)
@@ -182,12 +218,19 @@ func (cmd *Command) ParseFlags(args ...string) (Options, error) {
 	var options Options
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
 	fs.StringVar(&options.ConfigPath, "config", "", "")
+	fs.StringVar(&options.ConfigDir, "config-dir", "", "")
 	fs.StringVar(&options.PIDFile, "pidfile", "", "")
 	fs.StringVar(&options.Hostname, "hostname", "", "")
 	fs.StringVar(&options.CPUProfile, "cpuprofile", "", "")
 	fs.StringVar(&options.MemProfile, "memprofile", "", "")
 	fs.StringVar(&options.LogFile, "log-file", "", "")
 	fs.StringVar(&options.LogLevel, "log-level", "", "")
+	fs.BoolVar(&options.Once, "once", false, "")
+	fs.StringVar(&options.Task, "task", "", "")
+	fs.StringVar(&options.Input, "input", "-", "")
+	fs.StringVar(&options.Output, "output", "-", "")
+	fs.StringVar(&options.DBRP, "dbrp", "", "")
+	fs.StringVar(&options.Precision, "precision", "n", "")
 	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, usage) }
 	if err := fs.Parse(args); err != nil {
 		return Options{}, err
@@ -216,22 +259,37 @@ func (cmd *Command) writePIDFile(path string) error {
 	return nil
 }
 
-// ParseConfig parses the config at path.
+// ParseConfig parses the config at path, merged with the fragments of the config directory dir if it is not blank.
 // Returns a demo configuration if path is blank.
-func (cmd *Command) ParseConfig(path string) (*server.Config, error) {
-	// Use demo configuration if no config path is specified.
+func (cmd *Command) ParseConfig(path, dir string) (*server.Config, error) {
+	fragments, err := ConfigDirFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var config *server.Config
 	if path == "" {
+		// Use demo configuration if no config path is specified.
 		log.Println("No configuration provided, using default settings")
-		return server.NewDemoConfig()
+		config, err = server.NewDemoConfig()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		log.Println("Using configuration at:", path)
+		config = server.NewConfig()
+	}
+	for _, f := range fragments {
+		log.Println("Merging configuration fragment at:", f)
 	}
 
-	log.Println("Using configuration at:", path)
-
-	config := server.NewConfig()
-	if _, err := toml.DecodeFile(path, &config); err != nil {
+	files := fragments
+	if path != "" {
+		files = append([]string{path}, fragments...)
+	}
+	if err := config.DecodeFiles(files...); err != nil {
 		return nil, err
 	}
-
 	return config, nil
 }
 
@@ -242,6 +300,11 @@ run starts the Kapacitor server.
         -config <path>
                           Set the path to the configuration file.
 
+        -config-dir <path>
+                          Set the path to a directory of configuration fragments,
+                          the *.conf files merged in lexical order over the
+                          configuration file.
+
         -hostname <name>
                           Override the hostname, the 'hostname' configuration
                           option will be overridden.
@@ -254,15 +317,51 @@ run starts the Kapacitor server.
 
         -log-level <level>
                           Sets the log level. One of debug,info,error.
+
+        -once
+                          Run a single stream task against line protocol and exit,
+                          instead of starting the server. The task runs with a server
+                          isolated in a temporary directory, which neither listens
+                          for data nor writes to InfluxDB. The alert events of the
+                          alert nodes with handlers or a topic, which are not sent,
+                          the points written to InfluxDB and the output of the nodes
+                          without children are written as JSON, one per line.
+                          Logs are written to stderr at the error level by default.
+
+        -task <path>
+                          The TICKscript of the task to run with -once.
+
+        -input <path>
+                          The line protocol to run the task against with -once,
+                          defaults to stdin.
+
+        -output <path>
+                          The file to write the results of -once to, defaults to stdout.
+
+        -dbrp <db.rp>
+                          The database and retention policy of the input of -once,
+                          if the TICKscript has no dbrp statement.
+
+        -precision <precision>
+                          The precision of the timestamps of the input of -once,
+                          one of n,u,ms,s,m,h. Defaults to n.
 `
 
 // Options represents the command line options that can be parsed.
 type Options struct {
 	ConfigPath string
+	ConfigDir  string
 	PIDFile    string
 	Hostname   string
 	CPUProfile string
 	MemProfile string
 	LogFile    string
 	LogLevel   string
+
+	Once      bool
+	Task      string
+	Input     string
+	Output    string
+	DBRP      string
+	Precision string
 }
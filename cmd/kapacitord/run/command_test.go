@@ -5,6 +5,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"text/template"
 	"time"
@@ -67,3 +69,55 @@ func TestCommand_PIDFile(t *testing.T) {
 		t.Fatal("expected pid file to be removed")
 	}
 }
+
+func TestCommand_Once(t *testing.T) {
+	tmpdir, err := ioutil.TempDir(os.TempDir(), "kapacitord-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	taskFile := filepath.Join(tmpdir, "double.tick")
+	if err := ioutil.WriteFile(taskFile, []byte(`dbrp "telegraf"."autogen"
+
+stream
+    |from()
+        .measurement('cpu')
+    |alert()
+        .id('{{ index .Tags "host" }}')
+        .message('{{ .ID }} is {{ .Level }}')
+        .details('')
+        .crit(lambda: "usage" > 90)
+        .topic('cpu')
+    |eval(lambda: "usage" * 2.0)
+        .as('double')
+    |influxDBOut()
+        .database('out')
+        .measurement('doubled')
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := run.NewCommand()
+	cmd.Stdin = bytes.NewBufferString("cpu,host=a usage=95 1\ncpu,host=a usage=10 2\n")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run("-once", "-task", taskFile, "-precision", "s"); err != nil {
+		t.Fatalf("unexpected error: %s\n%s", err, stderr.String())
+	}
+	if !cmd.Once {
+		t.Error("expected the command to run once")
+	}
+
+	exp := []string{
+		`{"type":"alert","alert":{"source":"topic:cpu","id":"a","state":{"message":"a is CRITICAL","details":"","time":"1970-01-01T00:00:01Z","duration":"0s","level":"CRITICAL"}}}`,
+		`{"type":"alert","alert":{"source":"topic:cpu","id":"a","state":{"message":"a is OK","details":"","time":"1970-01-01T00:00:02Z","duration":"1s","level":"OK"}}}`,
+		`{"type":"point","point":{"db":"out","rp":"autogen","measurement":"doubled","tags":{"host":"a"},"time":"1970-01-01T00:00:01Z","fields":{"double":190}}}`,
+		`{"type":"point","point":{"db":"out","rp":"autogen","measurement":"doubled","tags":{"host":"a"},"time":"1970-01-01T00:00:02Z","fields":{"double":20}}}`,
+	}
+	got := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected output:\ngot\n%s\nexp\n%s", strings.Join(got, "\n"), strings.Join(exp, "\n"))
+	}
+}
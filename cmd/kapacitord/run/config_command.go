@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 
 	"github.com/BurntSushi/toml"
 	"github.com/influxdata/kapacitor/server"
@@ -32,6 +33,7 @@ func (cmd *PrintConfigCommand) Run(args ...string) error {
 	// Parse command flags.
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
 	configPath := fs.String("config", "", "")
+	configDir := fs.String("config-dir", "", "")
 	hostname := fs.String("hostname", "", "")
 	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, printConfigUsage) }
 	if err := fs.Parse(args); err != nil {
@@ -39,7 +41,7 @@ func (cmd *PrintConfigCommand) Run(args ...string) error {
 	}
 
 	// Parse config from path.
-	config, err := cmd.parseConfig(FindConfigPath(*configPath))
+	config, err := cmd.parseConfig(FindConfigPath(*configPath), *configDir)
 	if err != nil {
 		return fmt.Errorf("parse config: %s", err)
 	}
@@ -49,6 +51,11 @@ func (cmd *PrintConfigCommand) Run(args ...string) error {
 		return fmt.Errorf("apply env config: %v", err)
 	}
 
+	// Replace the $ENV{} and $FILE{} references
+	if err := config.Interpolate(); err != nil {
+		return fmt.Errorf("interpolate config: %v", err)
+	}
+
 	// Override config properties.
 	if *hostname != "" {
 		config.Hostname = *hostname
@@ -93,20 +100,39 @@ func FindConfigPath(configPath string) string {
 	return ""
 }
 
-// ParseConfig parses the config at path.
-// Returns a demo configuration if path is blank.
-func (cmd *PrintConfigCommand) parseConfig(path string) (*server.Config, error) {
+// ConfigDirFiles returns the paths of the configuration fragments of the directory, its *.conf files in lexical order.
+// It returns no paths if dir is blank.
+func ConfigDirFiles(dir string) ([]string, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	return filepath.Glob(filepath.Join(dir, "*.conf"))
+}
+
+// ParseConfig parses the config at path, merged with the fragments of the config directory dir.
+// Returns a demo configuration if path and dir are blank.
+func (cmd *PrintConfigCommand) parseConfig(path, dir string) (*server.Config, error) {
 	config, err := server.NewDemoConfig()
 	if err != nil {
 		config = server.NewConfig()
 	}
 
-	if path == "" {
-		return config, nil
+	fragments, err := ConfigDirFiles(dir)
+	if err != nil {
+		return nil, err
 	}
-
-	log.Println("Merging with configuration at:", path)
-	if _, err := toml.DecodeFile(path, &config); err != nil {
+	files := fragments
+	if path != "" {
+		log.Println("Merging with configuration at:", path)
+		files = append([]string{path}, fragments...)
+	}
+	for _, f := range fragments {
+		log.Println("Merging with configuration fragment at:", f)
+	}
+	if err := config.DecodeFiles(files...); err != nil {
 		return nil, err
 	}
 	return config, nil
@@ -0,0 +1,171 @@
+package run
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/server"
+	"github.com/influxdata/kapacitor/services/diagnostic"
+)
+
+// onceResult is a line of the output of the -once option.
+type onceResult struct {
+	Type   string                 `json:"type"`
+	Alert  *client.EvaluatedEvent `json:"alert,omitempty"`
+	Point  *client.WrittenPoint   `json:"point,omitempty"`
+	Output *client.NodeOutput     `json:"output,omitempty"`
+}
+
+// runOnce runs the stream task of the -task option against the line protocol of the -input option
+// with a server isolated in a temporary directory, and writes the results to the -output option.
+func (cmd *Command) runOnce(options Options) error {
+	if options.Task == "" {
+		return errors.New("-once requires the path of a TICKscript with -task")
+	}
+	script, err := ioutil.ReadFile(options.Task)
+	if err != nil {
+		return err
+	}
+	var dbrps []client.DBRP
+	if options.DBRP != "" {
+		i := strings.IndexByte(options.DBRP, '.')
+		if i <= 0 || i == len(options.DBRP)-1 {
+			return fmt.Errorf("invalid dbrp %q, it must be of the form db.rp", options.DBRP)
+		}
+		dbrps = append(dbrps, client.DBRP{
+			Database:        options.DBRP[:i],
+			RetentionPolicy: options.DBRP[i+1:],
+		})
+	}
+	in := cmd.Stdin
+	if options.Input != "-" {
+		f, err := os.Open(options.Input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	config, err := cmd.loadConfig(options)
+	if err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "kapacitord-once-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	isolateConfig(config, dir)
+	// Only the results are written to the output.
+	if options.LogFile == "" {
+		config.Logging.File = "STDERR"
+	}
+	if options.LogLevel == "" {
+		config.Logging.Level = "ERROR"
+	}
+
+	cmd.diagService = diagnostic.NewService(config.Logging, cmd.Stdout, cmd.Stderr)
+	if err := cmd.diagService.Open(); err != nil {
+		return fmt.Errorf("failed to open diagnostic service: %v", err)
+	}
+	defer cmd.diagService.Close()
+	cmd.Diag = cmd.diagService.NewCmdHandler()
+
+	buildInfo := server.BuildInfo{Version: cmd.Version, Commit: cmd.Commit, Branch: cmd.Branch, Platform: cmd.Platform}
+	s, err := server.New(config, buildInfo, cmd.diagService)
+	if err != nil {
+		return fmt.Errorf("create server: %s", err)
+	}
+	if err := s.Open(); err != nil {
+		return fmt.Errorf("open server: %s", err)
+	}
+	defer s.Close()
+
+	// The task is named after its file, so the results do not change between runs.
+	id := strings.TrimSuffix(filepath.Base(options.Task), filepath.Ext(options.Task))
+	e, err := s.ReplayService.RunOnce(id, string(script), dbrps, in, options.Precision)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.Stdout
+	if options.Output != "-" {
+		f, err := os.Create(options.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := writeOnceResults(out, e); err != nil {
+		return err
+	}
+	if int64(len(e.Events)) < e.EventCount {
+		fmt.Fprintf(cmd.Stderr, "only the first %d of %d alert events were written\n", len(e.Events), e.EventCount)
+	}
+	if int64(len(e.Writes)) < e.WriteCount {
+		fmt.Fprintf(cmd.Stderr, "only the first %d of %d points were written\n", len(e.Writes), e.WriteCount)
+	}
+	return nil
+}
+
+// isolateConfig changes the config so the server shares nothing with other servers of the config,
+// storing its data in dir and neither listening for data nor writing to InfluxDB.
+func isolateConfig(c *server.Config, dir string) {
+	c.DataDir = dir
+	c.Storage.BoltDBPath = filepath.Join(dir, "kapacitor.db")
+	c.Replay.Dir = filepath.Join(dir, "replay")
+	c.Task.Dir = filepath.Join(dir, "tasks")
+	c.HTTP.BindAddress = "127.0.0.1:0"
+	c.GRPC.Enabled = false
+	c.Load.Enabled = false
+	c.WAL.Enabled = false
+	c.HA.Enabled = false
+	c.Cluster.Enabled = false
+	c.Stats.Enabled = false
+	c.MetaMonitoring.Enabled = false
+	c.Reporting.Enabled = false
+	for i := range c.InfluxDB {
+		c.InfluxDB[i].Enabled = false
+	}
+	c.Graphite = nil
+	c.GraphitePickle = nil
+	c.Collectd = nil
+	c.OpenTSDB = nil
+	c.UDP = nil
+	c.NATS = nil
+	c.SNMP = nil
+	c.Statsd = nil
+	c.Tail = nil
+	c.Scraper = nil
+}
+
+// writeOnceResults writes the alert events, the points written to InfluxDB
+// and the output of the nodes without children as JSON, one per line.
+func writeOnceResults(w io.Writer, e client.Evaluation) error {
+	enc := json.NewEncoder(w)
+	for i := range e.Events {
+		if err := enc.Encode(onceResult{Type: "alert", Alert: &e.Events[i]}); err != nil {
+			return err
+		}
+	}
+	for i := range e.Writes {
+		if err := enc.Encode(onceResult{Type: "point", Point: &e.Writes[i]}); err != nil {
+			return err
+		}
+	}
+	for i := range e.Outputs {
+		if err := enc.Encode(onceResult{Type: "output", Output: &e.Outputs[i]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
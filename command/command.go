@@ -24,6 +24,10 @@ type Spec struct {
 	Prog string
 	Args []string
 	Env  []string
+
+	// Limits of the resources of the process, only supported on linux.
+	// The limits are applied right after the process has started.
+	Limits Limits
 }
 
 // Commander creates new commands.
@@ -41,22 +45,49 @@ var ExecCommander = execCommander{}
 func (execCommander) NewCommand(s Spec) Command {
 	c := exec.Command(s.Prog, s.Args...)
 	c.Env = s.Env
-	return execCmd{c}
+	return &execCmd{Cmd: c, limits: s.Limits}
 }
 
 // ExecCmd implements Command using the stdlib os/exec package.
 type execCmd struct {
 	*exec.Cmd
+	limits  Limits
+	release func()
+}
+
+func (c *execCmd) Start() error {
+	if err := c.Cmd.Start(); err != nil {
+		return err
+	}
+	if c.limits.IsZero() {
+		return nil
+	}
+	release, err := applyLimits(c.Cmd.Process.Pid, c.limits)
+	if err != nil {
+		c.Cmd.Process.Kill()
+		c.Cmd.Wait()
+		return err
+	}
+	c.release = release
+	return nil
+}
+
+func (c *execCmd) Wait() error {
+	err := c.Cmd.Wait()
+	if c.release != nil {
+		c.release()
+	}
+	return err
 }
 
-func (c execCmd) Stdin(in io.Reader)   { c.Cmd.Stdin = in }
-func (c execCmd) Stdout(out io.Writer) { c.Cmd.Stdout = out }
-func (c execCmd) Stderr(err io.Writer) { c.Cmd.Stderr = err }
+func (c *execCmd) Stdin(in io.Reader)   { c.Cmd.Stdin = in }
+func (c *execCmd) Stdout(out io.Writer) { c.Cmd.Stdout = out }
+func (c *execCmd) Stderr(err io.Writer) { c.Cmd.Stderr = err }
 
-func (c execCmd) StdoutPipe() (io.Reader, error) { return c.Cmd.StdoutPipe() }
-func (c execCmd) StderrPipe() (io.Reader, error) { return c.Cmd.StderrPipe() }
+func (c *execCmd) StdoutPipe() (io.Reader, error) { return c.Cmd.StdoutPipe() }
+func (c *execCmd) StderrPipe() (io.Reader, error) { return c.Cmd.StderrPipe() }
 
-func (c execCmd) Kill() {
+func (c *execCmd) Kill() {
 	if c.Cmd.Process != nil {
 		c.Cmd.Process.Kill()
 	}
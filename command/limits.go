@@ -0,0 +1,40 @@
+package command
+
+import (
+	"errors"
+	"time"
+)
+
+// Limits of the resources a command may use.
+// Zero values are not limited.
+type Limits struct {
+	// Maximum size of the virtual memory of the process in bytes.
+	Memory int64
+	// Maximum CPU time the process may consume, it is killed once it is exceeded.
+	CPUTime time.Duration
+	// Maximum number of file descriptors the process may open.
+	OpenFiles int64
+
+	// Directory of a cgroup v2 under which a child cgroup is created for the process.
+	// If set the memory limit is enforced by the cgroup
+	// and the CPU quota limits the share of CPU time available to the process.
+	Cgroup string
+	// Number of CPUs the process may use, e.g. 0.5 for half of one CPU, requires Cgroup.
+	CPUQuota float64
+}
+
+// IsZero reports whether no limit is set.
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// Validate the limits.
+func (l Limits) Validate() error {
+	if l.Memory < 0 || l.CPUTime < 0 || l.OpenFiles < 0 || l.CPUQuota < 0 {
+		return errors.New("limits must not be negative")
+	}
+	if l.CPUQuota > 0 && l.Cgroup == "" {
+		return errors.New("a CPU quota requires a cgroup")
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Period of the CPU quota of cgroups.
+const cpuPeriod = 100 * time.Millisecond
+
+// applyLimits limits the resources of the running process pid.
+// It returns a function that releases the resources used to apply the limits once the process has exited.
+func applyLimits(pid int, l Limits) (func(), error) {
+	if l.CPUTime > 0 {
+		// Round up to whole seconds, the resolution of the limit.
+		secs := uint64((l.CPUTime + time.Second - 1) / time.Second)
+		if err := prlimit(pid, syscall.RLIMIT_CPU, secs); err != nil {
+			return nil, err
+		}
+	}
+	if l.OpenFiles > 0 {
+		if err := prlimit(pid, syscall.RLIMIT_NOFILE, uint64(l.OpenFiles)); err != nil {
+			return nil, err
+		}
+	}
+	if l.Cgroup == "" {
+		if l.Memory > 0 {
+			if err := prlimit(pid, syscall.RLIMIT_AS, uint64(l.Memory)); err != nil {
+				return nil, err
+			}
+		}
+		return func() {}, nil
+	}
+	return joinCgroup(pid, l)
+}
+
+func prlimit(pid int, resource int, limit uint64) error {
+	rlimit := syscall.Rlimit{Cur: limit, Max: limit}
+	_, _, errno := syscall.RawSyscall6(
+		syscall.SYS_PRLIMIT64,
+		uintptr(pid),
+		uintptr(resource),
+		uintptr(unsafe.Pointer(&rlimit)),
+		0, 0, 0,
+	)
+	if errno != 0 {
+		return fmt.Errorf("failed to set resource limit %d: %v", resource, errno)
+	}
+	return nil
+}
+
+// joinCgroup moves the process into a new child cgroup of l.Cgroup configured with the limits.
+func joinCgroup(pid int, l Limits) (func(), error) {
+	dir := filepath.Join(l.Cgroup, "kapacitor-"+strconv.Itoa(pid))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %v", err)
+	}
+	remove := func() {
+		// The cgroup can only be removed once its processes have exited.
+		os.Remove(dir)
+	}
+	files := map[string]string{}
+	if l.Memory > 0 {
+		files["memory.max"] = strconv.FormatInt(l.Memory, 10)
+		files["memory.swap.max"] = "0"
+	}
+	if l.CPUQuota > 0 {
+		quota := time.Duration(l.CPUQuota * float64(cpuPeriod))
+		files["cpu.max"] = fmt.Sprintf("%d %d", quota/time.Microsecond, cpuPeriod/time.Microsecond)
+	}
+	for name, value := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(value), 0644); err != nil {
+			// Swap accounting is optional.
+			if name == "memory.swap.max" && os.IsNotExist(err) {
+				continue
+			}
+			remove()
+			return nil, fmt.Errorf("failed to set cgroup limit %s: %v", name, err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+		remove()
+		return nil, fmt.Errorf("failed to move process into cgroup: %v", err)
+	}
+	return remove, nil
+}
@@ -0,0 +1,40 @@
+package command_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/command"
+)
+
+func TestExecCommander_Limits(t *testing.T) {
+	cmd := command.ExecCommander.NewCommand(command.Spec{
+		Prog: "/bin/sh",
+		// Wait for the limits to be applied before printing them.
+		Args: []string{"-c", "read x; ulimit -n; ulimit -t"},
+		Limits: command.Limits{
+			OpenFiles: 17,
+			CPUTime:   1500 * time.Millisecond,
+		},
+	})
+	var out bytes.Buffer
+	cmd.Stdout(&out)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(stdin, "\n")
+	stdin.Close()
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := strings.Fields(out.String()), []string{"17", "2"}; strings.Join(got, " ") != strings.Join(exp, " ") {
+		t.Errorf("unexpected limits: got %v exp %v", got, exp)
+	}
+}
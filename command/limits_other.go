@@ -0,0 +1,9 @@
+// +build !linux
+
+package command
+
+import "errors"
+
+func applyLimits(pid int, l Limits) (func(), error) {
+	return nil, errors.New("resource limits are only supported on linux")
+}
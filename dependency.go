@@ -0,0 +1,156 @@
+package kapacitor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// How often a producer task is checked for data still being processed.
+const idlePollInterval = 10 * time.Millisecond
+
+// intervalProgress tracks the batch intervals completed by the tasks of a TaskMaster,
+// so that tasks consuming the output of other tasks query it only once it is complete.
+type intervalProgress struct {
+	mu sync.Mutex
+	// Next interval each query node of a task has not yet completed, by task id and node name.
+	next map[string]map[string]time.Time
+	// Tasks each executing task depends on, by task id.
+	dependsOn map[string][]string
+	// Closed and replaced whenever the progress changes.
+	changed chan struct{}
+}
+
+func newIntervalProgress() *intervalProgress {
+	return &intervalProgress{
+		next:      make(map[string]map[string]time.Time),
+		dependsOn: make(map[string][]string),
+		changed:   make(chan struct{}),
+	}
+}
+
+// set records the next interval that the query node of the task has not completed.
+func (p *intervalProgress) set(task, node string, next time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	nodes, ok := p.next[task]
+	if !ok {
+		nodes = make(map[string]time.Time)
+		p.next[task] = nodes
+	}
+	nodes[node] = next
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+func (p *intervalProgress) setDependsOn(task string, tasks []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dependsOn[task] = tasks
+}
+
+// hasDependents reports whether any executing task depends on the task.
+func (p *intervalProgress) hasDependents(task string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, tasks := range p.dependsOn {
+		for _, t := range tasks {
+			if t == task {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *intervalProgress) delete(task string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.next, task)
+	delete(p.dependsOn, task)
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// pending returns the first of the tasks with an interval up to t that is not complete.
+// Tasks that are not running are never pending.
+func (p *intervalProgress) pending(tasks []string, t time.Time) (string, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, task := range tasks {
+		for _, next := range p.next[task] {
+			if !next.After(t) {
+				return task, p.changed
+			}
+		}
+	}
+	return "", p.changed
+}
+
+// waitForIntervals waits until the tasks completed all their intervals up to t.
+// It returns an error if the deadline passes first and false if closing or aborting is closed first.
+func (p *intervalProgress) waitForIntervals(tasks []string, t, deadline time.Time, closing, aborting <-chan struct{}) (bool, error) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	for {
+		task, changed := p.pending(tasks, t)
+		if task == "" {
+			return true, nil
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return true, fmt.Errorf("task %s did not complete its interval ending before %v in time", task, t)
+		case <-closing:
+			return false, nil
+		case <-aborting:
+			return false, nil
+		}
+	}
+}
+
+// waitIdle waits until all data received by the task has been processed
+// and its writes to InfluxDB have been flushed.
+// It returns false if done is closed first.
+func (et *ExecutingTask) waitIdle(done <-chan struct{}) bool {
+	last := int64(-1)
+	for {
+		pending, total := et.pendingMessages()
+		// Messages may be in between edges while a node processes them,
+		// so the task is only idle when nothing changed since the last check.
+		if pending == 0 && total == last {
+			break
+		}
+		last = total
+		select {
+		case <-done:
+			return false
+		case <-time.After(idlePollInterval):
+		}
+	}
+	for _, n := range et.nodes {
+		if out, ok := n.(*InfluxDBOutNode); ok {
+			out.wb.flush()
+		}
+	}
+	return true
+}
+
+// pendingMessages returns the number of messages collected on the edges of the task
+// that have not been emitted to their child nodes, and the total number of collected messages.
+func (et *ExecutingTask) pendingMessages() (pending, total int64) {
+	for _, n := range et.nodes {
+		switch n := n.(type) {
+		case *BatchNode:
+			// The batch node has no edges of its own.
+			continue
+		case *QueryNode:
+			// The edge into a query node is collected by its query.
+			total += n.batchesQueried.IntValue()
+		}
+		total += n.emittedCount()
+		pending -= n.collectedCount()
+	}
+	pending += total
+	return
+}
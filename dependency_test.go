@@ -0,0 +1,60 @@
+package kapacitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIntervalProgress_WaitForIntervals(t *testing.T) {
+	p := newIntervalProgress()
+	now := time.Date(2017, 1, 1, 0, 1, 0, 0, time.UTC)
+	never := make(chan struct{})
+
+	// Tasks that are not running are never waited for
+	if ok, err := p.waitForIntervals([]string{"producer"}, now, now.Add(time.Hour), never, never); !ok || err != nil {
+		t.Fatalf("unexpected wait result %v %v", ok, err)
+	}
+
+	p.set("producer", "query1", now)
+	if task, _ := p.pending([]string{"producer"}, now); task != "producer" {
+		t.Fatalf("expected producer to be pending got %q", task)
+	}
+
+	// Completes once the producer moves on to its next interval
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.waitForIntervals([]string{"producer"}, now, time.Now().Add(time.Minute), never, never)
+		done <- err
+	}()
+	p.set("producer", "query1", now.Add(time.Minute))
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for interval")
+	}
+
+	// Errors once the deadline passes
+	later := now.Add(time.Minute)
+	if ok, err := p.waitForIntervals([]string{"producer"}, later, time.Now().Add(10*time.Millisecond), never, never); !ok || err == nil {
+		t.Errorf("expected deadline error got %v %v", ok, err)
+	}
+
+	// Stops when closing
+	closing := make(chan struct{})
+	close(closing)
+	if ok, _ := p.waitForIntervals([]string{"producer"}, later, time.Now().Add(time.Minute), closing, never); ok {
+		t.Error("expected wait to stop when closing")
+	}
+
+	p.setDependsOn("consumer", []string{"producer"})
+	if !p.hasDependents("producer") {
+		t.Error("expected producer to have dependents")
+	}
+	p.delete("consumer")
+	if p.hasDependents("producer") {
+		t.Error("expected producer to have no dependents")
+	}
+}
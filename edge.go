@@ -3,11 +3,13 @@ package kapacitor
 import (
 	"errors"
 	"sync"
+	"sync/atomic"
 
 	"github.com/influxdata/kapacitor/edge"
 	"github.com/influxdata/kapacitor/expvar"
 	"github.com/influxdata/kapacitor/pipeline"
 	"github.com/influxdata/kapacitor/server/vars"
+	"github.com/influxdata/kapacitor/tracing"
 )
 
 const (
@@ -29,9 +31,21 @@ type Edge struct {
 	mu     sync.Mutex
 	closed bool
 
+	// Taps of the edge, copied on write so that collecting needs no lock.
+	taps atomic.Value // []*Tap
+
+	// Sizes of the collected messages, estimating the memory of the queued messages.
+	sizes sizeSampler
+
 	statsKey string
 	statMap  *expvar.Map
 	diag     EdgeDiagnostic
+
+	task  string
+	child string
+	// Span of the child node processing the last traced message it emitted,
+	// only used by the goroutine of the child.
+	span *tracing.Span
 }
 
 func newEdge(taskName, parentName, childName string, t pipeline.EdgeType, size int, d EdgeDiagnostic) edge.StatsEdge {
@@ -50,7 +64,58 @@ func newEdge(taskName, parentName, childName string, t pipeline.EdgeType, size i
 		statsKey:  key,
 		statMap:   sm,
 		diag:      d,
+		task:      taskName,
+		child:     childName,
+	}
+}
+
+// Emit emits the next message to the child node.
+// The child node is done processing the previous message when it asks for the next,
+// so the span of a traced message lasts until then.
+// The child receives a copy of a traced message carrying the context of its span.
+func (e *Edge) Emit() (edge.Message, bool) {
+	if e.span != nil {
+		e.span.Finish()
+		e.span = nil
+	}
+	m, ok := e.StatsEdge.Emit()
+	if ok {
+		if tc := edge.TraceContext(m); tc.IsValid() {
+			e.span = tracing.Start(e.child, tracing.KindInternal, tc)
+			e.span.SetAttribute("kapacitor.task", e.task)
+			e.span.SetAttribute("kapacitor.node", e.child)
+			// The messages the child emits for the message are part of its span.
+			m = edge.WithTraceContext(m, e.span.SpanContext())
+		}
+	}
+	return m, ok
+}
+
+func (e *Edge) Collect(m edge.Message) error {
+	e.observe(m)
+	return e.StatsEdge.Collect(m)
+}
+
+func (e *Edge) TryCollect(m edge.Message) (bool, error) {
+	tc, ok := e.StatsEdge.(edge.TryCollector)
+	if !ok {
+		return false, nil
+	}
+	ok, err := tc.TryCollect(m)
+	if ok {
+		e.observe(m)
+	}
+	return ok, err
+}
+
+// observe passes the collected message to the taps and samples its size.
+func (e *Edge) observe(m edge.Message) {
+	if taps, _ := e.taps.Load().([]*Tap); len(taps) > 0 {
+		for _, t := range taps {
+			t.collect(m)
+		}
 	}
+	e.sizes.observe(m)
 }
 
 func (e *Edge) Close() error {
@@ -60,6 +125,7 @@ func (e *Edge) Close() error {
 		return nil
 	}
 	e.closed = true
+	e.closeTaps()
 	vars.DeleteStatistic(e.statsKey)
 	e.diag.ClosingEdge(e.Collected(), e.Emitted())
 	return e.StatsEdge.Close()
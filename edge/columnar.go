@@ -0,0 +1,496 @@
+package edge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor/influxdb"
+	"github.com/influxdata/kapacitor/models"
+)
+
+// ColumnType is the type of the values of a column.
+type ColumnType int
+
+const (
+	FloatColumn ColumnType = iota
+	IntegerColumn
+	StringColumn
+	BooleanColumn
+	// MixedColumn holds values of more than one type.
+	MixedColumn
+)
+
+func (t ColumnType) String() string {
+	switch t {
+	case FloatColumn:
+		return "float"
+	case IntegerColumn:
+		return "integer"
+	case StringColumn:
+		return "string"
+	case BooleanColumn:
+		return "boolean"
+	case MixedColumn:
+		return "mixed"
+	default:
+		return fmt.Sprintf("unknown column type %d", int(t))
+	}
+}
+
+// Column holds the values of a field for each point of a columnar batch.
+// Only the slice of the type of the column is set.
+type Column struct {
+	Name string
+	Type ColumnType
+
+	Floats   []float64
+	Integers []int64
+	Strings  []string
+	Booleans []bool
+	Values   []interface{}
+
+	// Nulls marks the points without a value for the field, nil if every point has a value.
+	Nulls []bool
+}
+
+// Len returns the number of points of the column.
+func (c *Column) Len() int {
+	switch c.Type {
+	case FloatColumn:
+		return len(c.Floats)
+	case IntegerColumn:
+		return len(c.Integers)
+	case StringColumn:
+		return len(c.Strings)
+	case BooleanColumn:
+		return len(c.Booleans)
+	default:
+		return len(c.Values)
+	}
+}
+
+// IsNull reports whether the point i has no value for the field.
+func (c *Column) IsNull(i int) bool {
+	return c.Nulls != nil && c.Nulls[i]
+}
+
+// Value returns the value of the field of the point i, or false if it is null.
+func (c *Column) Value(i int) (interface{}, bool) {
+	if c.IsNull(i) {
+		return nil, false
+	}
+	switch c.Type {
+	case FloatColumn:
+		return c.Floats[i], true
+	case IntegerColumn:
+		return c.Integers[i], true
+	case StringColumn:
+		return c.Strings[i], true
+	case BooleanColumn:
+		return c.Booleans[i], true
+	default:
+		return c.Values[i], true
+	}
+}
+
+// value is Value of a column that may be nil.
+func (c *Column) value(i int) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	return c.Value(i)
+}
+
+// newColumn creates a column from the values of a field, nil values are null.
+func newColumn(name string, values []interface{}) *Column {
+	c := &Column{
+		Name: name,
+		Type: columnType(values),
+	}
+	n := len(values)
+	switch c.Type {
+	case FloatColumn:
+		c.Floats = make([]float64, n)
+	case IntegerColumn:
+		c.Integers = make([]int64, n)
+	case StringColumn:
+		c.Strings = make([]string, n)
+	case BooleanColumn:
+		c.Booleans = make([]bool, n)
+	default:
+		c.Values = make([]interface{}, n)
+	}
+	for i, v := range values {
+		if v == nil {
+			if c.Nulls == nil {
+				c.Nulls = make([]bool, n)
+			}
+			c.Nulls[i] = true
+			continue
+		}
+		switch c.Type {
+		case FloatColumn:
+			c.Floats[i] = v.(float64)
+		case IntegerColumn:
+			c.Integers[i] = v.(int64)
+		case StringColumn:
+			c.Strings[i] = v.(string)
+		case BooleanColumn:
+			c.Booleans[i] = v.(bool)
+		default:
+			c.Values[i] = v
+		}
+	}
+	return c
+}
+
+// columnType returns the type shared by the non nil values, or MixedColumn.
+func columnType(values []interface{}) ColumnType {
+	typ := MixedColumn
+	for _, v := range values {
+		var t ColumnType
+		switch v.(type) {
+		case nil:
+			continue
+		case float64:
+			t = FloatColumn
+		case int64:
+			t = IntegerColumn
+		case string:
+			t = StringColumn
+		case bool:
+			t = BooleanColumn
+		default:
+			return MixedColumn
+		}
+		if typ == MixedColumn {
+			typ = t
+		} else if typ != t {
+			return MixedColumn
+		}
+	}
+	return typ
+}
+
+// ColumnarBatchMessage is a buffered batch whose points are stored by column,
+// instead of as a map of fields per point.
+// Points materializes the points on first use, so nodes that only read columns avoid allocating them.
+type ColumnarBatchMessage interface {
+	BufferedBatchMessage
+
+	// Columnar reports whether the points are stored by column.
+	// SetPoints replaces the columns with the new points.
+	Columnar() bool
+	// Times returns the time of each point.
+	Times() []time.Time
+	// Columns returns a column for each field, ordered by name.
+	Columns() []*Column
+	// Column returns the column of a field, or nil if no point has the field.
+	Column(name string) *Column
+}
+
+type columnarBatchMessage struct {
+	begin BeginBatchMessage
+	end   EndBatchMessage
+
+	// Tags of every point
+	tags     models.Tags
+	columnar bool
+	times    []time.Time
+	columns  []*Column
+
+	// Points materialized from the columns, shared by shallow copies.
+	points *materializedPoints
+}
+
+type materializedPoints struct {
+	once   sync.Once
+	points []BatchPointMessage
+}
+
+// NewColumnarBatchMessage creates a batch of len(times) points with the given tags.
+// Each column must have a value or null for each point.
+func NewColumnarBatchMessage(
+	begin BeginBatchMessage,
+	tags models.Tags,
+	times []time.Time,
+	columns []*Column,
+	end EndBatchMessage,
+) ColumnarBatchMessage {
+	sort.Slice(columns, func(i, j int) bool { return columns[i].Name < columns[j].Name })
+	return &columnarBatchMessage{
+		begin:    begin,
+		end:      end,
+		tags:     tags,
+		columnar: true,
+		times:    times,
+		columns:  columns,
+		points:   new(materializedPoints),
+	}
+}
+
+func (*columnarBatchMessage) Type() MessageType {
+	return BufferedBatch
+}
+func (bb *columnarBatchMessage) ShallowCopy() BufferedBatchMessage {
+	c := new(columnarBatchMessage)
+	*c = *bb
+	return c
+}
+func (bb *columnarBatchMessage) Begin() BeginBatchMessage {
+	return bb.begin
+}
+func (bb *columnarBatchMessage) SetBegin(begin BeginBatchMessage) {
+	bb.begin = begin
+}
+
+func (bb *columnarBatchMessage) Name() string {
+	return bb.begin.Name()
+}
+func (bb *columnarBatchMessage) GroupID() models.GroupID {
+	return bb.begin.GroupID()
+}
+func (bb *columnarBatchMessage) GroupInfo() GroupInfo {
+	return bb.begin.GroupInfo()
+}
+func (bb *columnarBatchMessage) Dimensions() models.Dimensions {
+	return bb.begin.Dimensions()
+}
+func (bb *columnarBatchMessage) Tags() models.Tags {
+	return bb.begin.Tags()
+}
+func (bb *columnarBatchMessage) Time() time.Time {
+	return bb.begin.Time()
+}
+
+func (bb *columnarBatchMessage) Len() int {
+	if bb.Columnar() {
+		return len(bb.times)
+	}
+	return len(bb.Points())
+}
+
+func (bb *columnarBatchMessage) Columnar() bool {
+	return bb.columnar
+}
+func (bb *columnarBatchMessage) Times() []time.Time {
+	return bb.times
+}
+func (bb *columnarBatchMessage) Columns() []*Column {
+	return bb.columns
+}
+func (bb *columnarBatchMessage) Column(name string) *Column {
+	i := sort.Search(len(bb.columns), func(i int) bool { return bb.columns[i].Name >= name })
+	if i < len(bb.columns) && bb.columns[i].Name == name {
+		return bb.columns[i]
+	}
+	return nil
+}
+
+// Points returns the points of the batch, materializing them from the columns on first use.
+func (bb *columnarBatchMessage) Points() []BatchPointMessage {
+	bb.points.once.Do(func() {
+		points := make([]BatchPointMessage, len(bb.times))
+		for i, t := range bb.times {
+			fields := make(models.Fields, len(bb.columns))
+			for _, c := range bb.columns {
+				if v, ok := c.Value(i); ok {
+					fields[c.Name] = v
+				}
+			}
+			points[i] = NewBatchPointMessage(fields, bb.tags, t)
+		}
+		bb.points.points = points
+	})
+	return bb.points.points
+}
+
+// SetPoints replaces the columns of the batch with the points.
+func (bb *columnarBatchMessage) SetPoints(points []BatchPointMessage) {
+	bb.columnar = false
+	bb.times = nil
+	bb.columns = nil
+	bb.points = new(materializedPoints)
+	bb.points.once.Do(func() {
+		bb.points.points = points
+	})
+}
+func (bb *columnarBatchMessage) End() EndBatchMessage {
+	return bb.end
+}
+func (bb *columnarBatchMessage) SetEnd(end EndBatchMessage) {
+	bb.end = end
+}
+
+// buffered returns the batch with its points materialized.
+func (bb *columnarBatchMessage) buffered() *bufferedBatchMessage {
+	return &bufferedBatchMessage{
+		begin:  bb.begin,
+		points: bb.Points(),
+		end:    bb.end,
+	}
+}
+
+func (bb *columnarBatchMessage) ToResult() models.Result {
+	return models.Result{
+		Series: models.Rows{bb.ToRow()},
+	}
+}
+
+// ToRow creates the row from the columns without materializing the points.
+func (bb *columnarBatchMessage) ToRow() (row *models.Row) {
+	if !bb.Columnar() {
+		return bb.buffered().ToRow()
+	}
+	row = &models.Row{
+		Name: bb.begin.Name(),
+		Tags: bb.begin.Tags(),
+	}
+	if len(bb.times) == 0 {
+		return
+	}
+	// Like the buffered batch, the row has the fields of the first point.
+	columns := make(map[string]*Column, len(bb.columns))
+	row.Columns = []string{"time"}
+	for _, c := range bb.columns {
+		if !c.IsNull(0) {
+			columns[c.Name] = c
+			row.Columns = append(row.Columns, c.Name)
+		}
+	}
+	// Append tags that are not on the batch
+	for t := range bb.tags {
+		if _, ok := bb.begin.Tags()[t]; !ok {
+			row.Columns = append(row.Columns, t)
+		}
+	}
+	// Sort all columns but leave time as first
+	sort.Strings(row.Columns[1:])
+	row.Values = make([][]interface{}, len(bb.times))
+	for i, t := range bb.times {
+		row.Values[i] = make([]interface{}, len(row.Columns))
+		row.Values[i][0] = t
+		for j, name := range row.Columns[1:] {
+			if v, ok := columns[name].value(i); ok {
+				row.Values[i][j+1] = v
+			} else if v, ok := bb.tags[name]; ok {
+				row.Values[i][j+1] = v
+			}
+		}
+	}
+	return
+}
+
+func (bb *columnarBatchMessage) MarshalJSON() ([]byte, error) {
+	return bb.buffered().MarshalJSON()
+}
+
+// ResultToBufferedBatches converts the series of a query result to columnar batches.
+// Points without any field values are skipped.
+func ResultToBufferedBatches(res influxdb.Result, groupByName bool) ([]BufferedBatchMessage, error) {
+	if res.Err != "" {
+		return nil, errors.New(res.Err)
+	}
+	batches := make([]BufferedBatchMessage, 0, len(res.Series))
+	for _, series := range res.Series {
+		timeIndex := -1
+		for i, c := range series.Columns {
+			if c == "time" {
+				timeIndex = i
+				break
+			}
+		}
+
+		// Find the points with field values
+		var tmax time.Time
+		times := make([]time.Time, 0, len(series.Values))
+		rows := make([][]interface{}, 0, len(series.Values))
+		for _, v := range series.Values {
+			var t time.Time
+			if timeIndex >= 0 {
+				var err error
+				if t, err = resultTime(v[timeIndex]); err != nil {
+					return nil, err
+				}
+			}
+			hasFields := false
+			for i := range series.Columns {
+				if i != timeIndex && v[i] != nil {
+					hasFields = true
+					break
+				}
+			}
+			if !hasFields {
+				continue
+			}
+			if t.After(tmax) {
+				tmax = t
+			}
+			times = append(times, t)
+			rows = append(rows, v)
+		}
+
+		columns := make([]*Column, 0, len(series.Columns))
+		values := make([]interface{}, len(rows))
+		for i, name := range series.Columns {
+			if i == timeIndex {
+				continue
+			}
+			null := true
+			for j, v := range rows {
+				values[j] = resultValue(v[i])
+				if values[j] != nil {
+					null = false
+				}
+			}
+			if null {
+				// No point has the field
+				continue
+			}
+			columns = append(columns, newColumn(name, values))
+		}
+
+		batches = append(batches, NewColumnarBatchMessage(
+			NewBeginBatchMessage(
+				series.Name,
+				series.Tags,
+				groupByName,
+				tmax,
+				len(times),
+			),
+			series.Tags,
+			times,
+			columns,
+			NewEndBatchMessage(),
+		))
+	}
+	return batches, nil
+}
+
+func resultTime(v interface{}) (time.Time, error) {
+	tStr, ok := v.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("unexpected time value: %v", v)
+	}
+	t, err := time.Parse(time.RFC3339Nano, tStr)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, tStr)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unexpected time format: %v", err)
+		}
+	}
+	return t.UTC(), nil
+}
+
+func resultValue(v interface{}) interface{} {
+	if n, ok := v.(json.Number); ok {
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+	}
+	return v
+}
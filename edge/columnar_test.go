@@ -0,0 +1,106 @@
+package edge_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	imodels "github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/influxdb"
+	"github.com/influxdata/kapacitor/models"
+)
+
+var columnarResult = influxdb.Result{
+	Series: []imodels.Row{{
+		Name:    "cpu",
+		Tags:    map[string]string{"host": "serverA"},
+		Columns: []string{"time", "value", "state", "count"},
+		Values: [][]interface{}{
+			{"1970-01-01T00:00:01Z", json.Number("1.5"), "ok", nil},
+			{"1970-01-01T00:00:02Z", nil, nil, nil},
+			{"1970-01-01T00:00:03Z", json.Number("2"), nil, int64(4)},
+		},
+	}},
+}
+
+func TestResultToBufferedBatches_Columnar(t *testing.T) {
+	batches, err := edge.ResultToBufferedBatches(columnarResult, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 1 {
+		t.Fatalf("unexpected batches got %d exp 1", len(batches))
+	}
+	b, ok := batches[0].(edge.ColumnarBatchMessage)
+	if !ok || !b.Columnar() {
+		t.Fatalf("expected columnar batch, got %T", batches[0])
+	}
+	// The point without fields is skipped.
+	if got := b.Len(); got != 2 {
+		t.Fatalf("unexpected len got %d exp 2", got)
+	}
+	if got, exp := b.Time(), time.Unix(3, 0).UTC(); !got.Equal(exp) {
+		t.Errorf("unexpected tmax got %v exp %v", got, exp)
+	}
+	if got, exp := b.Begin().SizeHint(), 2; got != exp {
+		t.Errorf("unexpected size hint got %d exp %d", got, exp)
+	}
+
+	value := b.Column("value")
+	if value == nil || value.Type != edge.FloatColumn || !reflect.DeepEqual(value.Floats, []float64{1.5, 2}) {
+		t.Errorf("unexpected value column %+v", value)
+	}
+	state := b.Column("state")
+	if state == nil || state.Type != edge.StringColumn || state.IsNull(0) || !state.IsNull(1) {
+		t.Errorf("unexpected state column %+v", state)
+	}
+	count := b.Column("count")
+	if count == nil || count.Type != edge.IntegerColumn {
+		t.Errorf("unexpected count column %+v", count)
+	}
+	if b.Column("missing") != nil {
+		t.Error("expected no column for missing field")
+	}
+
+	tags := models.Tags{"host": "serverA"}
+	exp := []edge.BatchPointMessage{
+		edge.NewBatchPointMessage(models.Fields{"value": 1.5, "state": "ok"}, tags, time.Unix(1, 0).UTC()),
+		edge.NewBatchPointMessage(models.Fields{"value": 2.0, "count": int64(4)}, tags, time.Unix(3, 0).UTC()),
+	}
+	if got := b.Points(); !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected points\ngot %v\nexp %v", got, exp)
+	}
+}
+
+func TestColumnarBatchMessage_ToRow(t *testing.T) {
+	batches, err := edge.ResultToBufferedBatches(columnarResult, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := batches[0]
+	buffered := edge.NewBufferedBatchMessage(b.Begin(), b.Points(), b.End())
+	if got, exp := b.ToRow(), buffered.ToRow(); !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected row\ngot %v\nexp %v", got, exp)
+	}
+}
+
+func TestColumnarBatchMessage_SetPoints(t *testing.T) {
+	batches, err := edge.ResultToBufferedBatches(columnarResult, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := batches[0].(edge.ColumnarBatchMessage)
+	c := b.ShallowCopy().(edge.ColumnarBatchMessage)
+	points := []edge.BatchPointMessage{
+		edge.NewBatchPointMessage(models.Fields{"value": 3.0}, nil, time.Unix(5, 0).UTC()),
+	}
+	c.SetPoints(points)
+	if c.Columnar() || c.Len() != 1 || !reflect.DeepEqual(c.Points(), points) {
+		t.Errorf("expected copy to hold the new points, got %v", c.Points())
+	}
+	if !b.Columnar() || b.Len() != 2 {
+		t.Error("expected original batch to keep its columns")
+	}
+}
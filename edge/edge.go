@@ -25,6 +25,13 @@ type Edge interface {
 	Type() pipeline.EdgeType
 }
 
+// TryCollector is implemented by edges that can collect a message only if they have room for it,
+// without waiting for the receiver.
+type TryCollector interface {
+	// TryCollect collects the message if the edge has room for it and reports whether it did.
+	TryCollect(Message) (bool, error)
+}
+
 type edgeState int
 
 const (
@@ -63,6 +70,17 @@ func (e *channelEdge) Collect(m Message) error {
 	}
 }
 
+func (e *channelEdge) TryCollect(m Message) (bool, error) {
+	select {
+	case e.messages <- m:
+		return true, nil
+	case <-e.aborting:
+		return false, ErrAborted
+	default:
+		return false, nil
+	}
+}
+
 func (e *channelEdge) Emit() (m Message, ok bool) {
 	select {
 	case m, ok = <-e.messages:
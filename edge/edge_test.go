@@ -110,6 +110,40 @@ func TestEdge_CollectBatch(t *testing.T) {
 	}
 }
 
+func TestForward_FullEdge(t *testing.T) {
+	slow := edge.NewStatsEdge(edge.NewChannelEdge(pipeline.StreamEdge, 1))
+	fast := edge.NewStatsEdge(edge.NewChannelEdge(pipeline.StreamEdge, 1))
+	// The slow branch has not read its previous point yet.
+	if err := slow.Collect(point); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- edge.Forward([]edge.StatsEdge{slow, fast}, point)
+	}()
+	// The fast branch gets the point while the forward waits for the slow one.
+	if _, ok := fast.Emit(); !ok {
+		t.Fatal("did not get point out of fast edge")
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("forward returned before the slow edge had room: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	slow.Emit()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := slow.Collected(); got != 2 {
+		t.Errorf("unexpected collected points of slow edge got %d exp 2", got)
+	}
+	if got := fast.Collected(); got != 1 {
+		t.Errorf("unexpected collected points of fast edge got %d exp 1", got)
+	}
+}
+
 var emittedMsg edge.Message
 var emittedOK bool
 
@@ -164,6 +198,31 @@ func (r noopReceiver) DeleteGroup(d edge.DeleteGroupMessage) error {
 func (r noopReceiver) Done() {
 }
 
+func BenchmarkForward(b *testing.B) {
+	outs := make([]edge.StatsEdge, 3)
+	for i := range outs {
+		outs[i] = edge.NewStatsEdge(edge.NewChannelEdge(pipeline.StreamEdge, defaultEdgeBufferSize))
+		go func(e edge.Edge) {
+			for {
+				if _, ok := e.Emit(); !ok {
+					return
+				}
+			}
+		}(outs[i])
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := edge.Forward(outs, point); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	for _, e := range outs {
+		e.Close()
+	}
+}
+
 func BenchmarkConsumer(b *testing.B) {
 	var msg edge.Message
 	msg = batch
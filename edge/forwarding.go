@@ -90,20 +90,67 @@ func (fr *forwardingReceiver) forward(msg Message, err error) error {
 		return err
 	}
 	if msg != nil {
-		for _, out := range fr.outs {
-			if err := out.Collect(msg); err != nil {
-				return err
-			}
-		}
+		return forward(fr.outs, msg)
 	}
 	return nil
 }
 
+// Forward collects the message into each of the edges, see forward.
 func Forward(outs []StatsEdge, msg Message) error {
+	if len(outs) == 1 {
+		return outs[0].Collect(msg)
+	}
+	var full []StatsEdge
+	for _, out := range outs {
+		ok, err := tryCollect(out, msg)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			full = append(full, out)
+		}
+	}
+	for _, out := range full {
+		if err := out.Collect(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forward collects the message into each of the edges.
+//
+// The children of the edges run concurrently, each reading from its own bounded edge.
+// When the edge of a child that is behind is full, the message is first collected
+// into the edges that have room for it, and only then forward waits for the full edges,
+// so that the other branches are not held back by the slow one.
+func forward(outs []Edge, msg Message) error {
+	if len(outs) == 1 {
+		return outs[0].Collect(msg)
+	}
+	var full []Edge
 	for _, out := range outs {
+		ok, err := tryCollect(out, msg)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			full = append(full, out)
+		}
+	}
+	for _, out := range full {
 		if err := out.Collect(msg); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// tryCollect collects the message into the edge if it has room for it.
+// It reports false for edges that cannot tell, so that the caller waits on them.
+func tryCollect(e Edge, m Message) (bool, error) {
+	if tc, ok := e.(TryCollector); ok {
+		return tc.TryCollect(m)
+	}
+	return false, nil
+}
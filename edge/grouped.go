@@ -2,6 +2,7 @@ package edge
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/influxdata/kapacitor/expvar"
 	"github.com/influxdata/kapacitor/models"
@@ -21,6 +22,12 @@ type GroupedReceiver interface {
 	NewGroup(group GroupInfo, first PointMeta) (Receiver, error)
 }
 
+// GroupLimiter is implemented by grouped receivers that limit the number of groups.
+type GroupLimiter interface {
+	// MaxGroups returns the maximum number of groups, zero means no limit.
+	MaxGroups() int64
+}
+
 // GroupInfo identifies and contians information about a specific group.
 type GroupInfo struct {
 	ID         models.GroupID
@@ -34,14 +41,24 @@ type groupedConsumer struct {
 	groups      map[models.GroupID]Receiver
 	current     Receiver
 	cardinality *expvar.Int
+	maxGroups   int64
 }
 
 // NewGroupedConsumer creates a new grouped consumer for edge e and grouped receiver r.
 func NewGroupedConsumer(e Edge, r GroupedReceiver) GroupedConsumer {
+	return newGroupedConsumer(e, r, new(expvar.Int))
+}
+
+// newGroupedConsumer creates a new grouped consumer counting its groups in cardinality,
+// which is shared by the shards of a sharded consumer.
+func newGroupedConsumer(e Edge, r GroupedReceiver, cardinality *expvar.Int) *groupedConsumer {
 	gc := &groupedConsumer{
 		gr:          r,
 		groups:      make(map[models.GroupID]Receiver),
-		cardinality: new(expvar.Int),
+		cardinality: cardinality,
+	}
+	if l, ok := r.(GroupLimiter); ok {
+		gc.maxGroups = l.MaxGroups()
 	}
 	gc.consumer = NewConsumerWithReceiver(e, gc)
 	return gc
@@ -57,6 +74,9 @@ func (c *groupedConsumer) CardinalityVar() expvar.IntVar {
 func (c *groupedConsumer) getOrCreateGroup(group GroupInfo, first PointMeta) (Receiver, error) {
 	r, ok := c.groups[group.ID]
 	if !ok {
+		if c.maxGroups > 0 && c.cardinality.IntValue() >= c.maxGroups {
+			return nil, fmt.Errorf("task exceeded its limit of %d groups", c.maxGroups)
+		}
 		c.cardinality.Add(1)
 		recv, err := c.gr.NewGroup(group, first)
 		if err != nil {
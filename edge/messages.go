@@ -2,7 +2,6 @@ package edge
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"sort"
@@ -10,8 +9,8 @@ import (
 	"time"
 
 	imodels "github.com/influxdata/influxdb/models"
-	"github.com/influxdata/kapacitor/influxdb"
 	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/tracing"
 )
 
 // Message represents data to be passed along an edge.
@@ -176,6 +175,9 @@ type pointMessage struct {
 	fields models.Fields
 
 	time time.Time
+
+	// Context of the trace of the point if it is sampled.
+	trace *tracing.SpanContext
 }
 
 func NewPointMessage(
@@ -392,6 +394,9 @@ type beginBatchMessage struct {
 	// If non-zero expect a batch with SizeHint points,
 	// otherwise an unknown number of points are coming.
 	sizeHint int
+
+	// Context of the trace of the batch if it is sampled.
+	trace *tracing.SpanContext
 }
 
 func NewBeginBatchMessage(
@@ -592,6 +597,8 @@ type BufferedBatchMessage interface {
 	// Expose common read interfaces of begin and point messages.
 	PointMeta
 
+	// Len returns the number of points.
+	Len() int
 	Points() []BatchPointMessage
 	SetPoints([]BatchPointMessage)
 
@@ -654,6 +661,9 @@ func (bb *bufferedBatchMessage) Time() time.Time {
 	return bb.begin.Time()
 }
 
+func (bb *bufferedBatchMessage) Len() int {
+	return len(bb.points)
+}
 func (bb *bufferedBatchMessage) Points() []BatchPointMessage {
 	return bb.points
 }
@@ -795,77 +805,6 @@ func (bb *bufferedBatchMessage) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-func ResultToBufferedBatches(res influxdb.Result, groupByName bool) ([]BufferedBatchMessage, error) {
-	if res.Err != "" {
-		return nil, errors.New(res.Err)
-	}
-	batches := make([]BufferedBatchMessage, 0, len(res.Series))
-	for _, series := range res.Series {
-		b := NewBufferedBatchMessage(
-			NewBeginBatchMessage(
-				series.Name,
-				series.Tags,
-				groupByName,
-				time.Time{},
-				len(series.Values),
-			),
-			make([]BatchPointMessage, 0, len(series.Values)),
-			NewEndBatchMessage(),
-		)
-		points := b.Points()
-
-		for _, v := range series.Values {
-			fields := make(models.Fields)
-			var t time.Time
-			for i, c := range series.Columns {
-				if c == "time" {
-					tStr, ok := v[i].(string)
-					if !ok {
-						return nil, fmt.Errorf("unexpected time value: %v", v[i])
-					}
-					var err error
-					t, err = time.Parse(time.RFC3339Nano, tStr)
-					if err != nil {
-						t, err = time.Parse(time.RFC3339, tStr)
-						if err != nil {
-							return nil, fmt.Errorf("unexpected time format: %v", err)
-						}
-					}
-				} else {
-					value := v[i]
-					if n, ok := value.(json.Number); ok {
-						f, err := n.Float64()
-						if err == nil {
-							value = f
-						}
-					}
-					if value == nil {
-						continue
-					}
-					fields[c] = value
-				}
-			}
-			if len(fields) > 0 {
-				if t.After(b.Begin().Time()) {
-					b.Begin().SetTime(t.UTC())
-				}
-				points = append(
-					points,
-					NewBatchPointMessage(
-						fields,
-						series.Tags,
-						t.UTC(),
-					),
-				)
-			}
-		}
-		b.Begin().SetSizeHint(len(points))
-		b.SetPoints(points)
-		batches = append(batches, b)
-	}
-	return batches, nil
-}
-
 type BatchPointMessages []BatchPointMessage
 
 func (l BatchPointMessages) Len() int               { return len(l) }
@@ -0,0 +1,162 @@
+package edge
+
+import (
+	"errors"
+
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/models"
+)
+
+// shardedConsumer passes the messages of an edge by group to shards,
+// each consuming its own edge on a separate goroutine.
+type shardedConsumer struct {
+	in       Edge
+	consumer Consumer
+	edges    []Edge
+	shards   []*groupedConsumer
+	// Edge of the shard of the current batch
+	current     Edge
+	cardinality *expvar.Int
+}
+
+// NewShardedGroupedConsumer creates a grouped consumer for edge e that processes the groups
+// in parallel, on a goroutine per grouped receiver of shards.
+// A group is always processed by the same shard, so that its messages are processed in order.
+// The shards read their messages from edges of the given size.
+//
+// The receivers of the groups of different shards are called concurrently.
+func NewShardedGroupedConsumer(e Edge, shards []GroupedReceiver, size int) GroupedConsumer {
+	sc := &shardedConsumer{
+		in:          e,
+		edges:       make([]Edge, len(shards)),
+		shards:      make([]*groupedConsumer, len(shards)),
+		cardinality: new(expvar.Int),
+	}
+	for i, r := range shards {
+		sc.edges[i] = NewChannelEdge(e.Type(), size)
+		sc.shards[i] = newGroupedConsumer(sc.edges[i], r, sc.cardinality)
+	}
+	sc.consumer = NewConsumerWithReceiver(e, sc)
+	return sc
+}
+
+// Consume passes the messages to the shards until the edge is closed or aborted,
+// and waits for the shards to process them.
+func (c *shardedConsumer) Consume() error {
+	errs := make(chan error, len(c.shards))
+	for i := range c.shards {
+		go func(e Edge, gc *groupedConsumer) {
+			err := gc.Consume()
+			if err != nil {
+				// Stop reading messages, the consumer fails with the error of the shard.
+				e.Abort()
+				c.in.Abort()
+			}
+			errs <- err
+		}(c.edges[i], c.shards[i])
+	}
+	err := c.consumer.Consume()
+	for range c.shards {
+		if shardErr := <-errs; shardErr != nil && (err == nil || err == ErrAborted) {
+			err = shardErr
+		}
+	}
+	return err
+}
+
+func (c *shardedConsumer) CardinalityVar() expvar.IntVar {
+	return c.cardinality
+}
+
+// shard returns the edge of the shard of the group.
+func (c *shardedConsumer) shard(id models.GroupID) Edge {
+	// FNV-1a
+	h := uint32(2166136261)
+	for i := 0; i < len(id); i++ {
+		h ^= uint32(id[i])
+		h *= 16777619
+	}
+	return c.edges[h%uint32(len(c.edges))]
+}
+
+func (c *shardedConsumer) BeginBatch(begin BeginBatchMessage) error {
+	c.current = c.shard(begin.GroupID())
+	return c.current.Collect(begin)
+}
+
+func (c *shardedConsumer) BatchPoint(bp BatchPointMessage) error {
+	if c.current == nil {
+		return errors.New("received batch point without batch")
+	}
+	return c.current.Collect(bp)
+}
+
+func (c *shardedConsumer) EndBatch(end EndBatchMessage) error {
+	if c.current == nil {
+		return errors.New("received end of batch without batch")
+	}
+	err := c.current.Collect(end)
+	c.current = nil
+	return err
+}
+
+func (c *shardedConsumer) BufferedBatch(batch BufferedBatchMessage) error {
+	return c.shard(batch.Begin().GroupID()).Collect(batch)
+}
+
+func (c *shardedConsumer) Point(p PointMessage) error {
+	return c.shard(p.GroupID()).Collect(p)
+}
+
+func (c *shardedConsumer) Barrier(b BarrierMessage) error {
+	return c.shard(b.GroupID()).Collect(b)
+}
+
+func (c *shardedConsumer) DeleteGroup(d DeleteGroupMessage) error {
+	return c.shard(d.GroupID()).Collect(d)
+}
+
+// Done closes the edges of the shards, which process their remaining messages.
+func (c *shardedConsumer) Done() {
+	for _, e := range c.edges {
+		// The edge of a failed shard is already aborted.
+		e.Close()
+	}
+}
+
+// shardEdge collects the messages forwarded by a shard into an edge shared with the other shards.
+type shardEdge struct {
+	StatsEdge
+
+	buffer  BatchBuffer
+	inBatch bool
+}
+
+// NewShardEdges returns edges through which a shard of a grouped consumer forwards its messages into outs.
+// The batches of the shard are collected whole, so that they do not interleave
+// with the batches forwarded concurrently by the other shards.
+func NewShardEdges(outs []StatsEdge) []StatsEdge {
+	edges := make([]StatsEdge, len(outs))
+	for i, out := range outs {
+		edges[i] = &shardEdge{StatsEdge: out}
+	}
+	return edges
+}
+
+func (e *shardEdge) Collect(m Message) error {
+	switch m := m.(type) {
+	case BeginBatchMessage:
+		e.inBatch = true
+		return e.buffer.BeginBatch(m)
+	case BatchPointMessage:
+		if e.inBatch {
+			return e.buffer.BatchPoint(m)
+		}
+	case EndBatchMessage:
+		if e.inBatch {
+			e.inBatch = false
+			return e.StatsEdge.Collect(e.buffer.BufferedBatchMessage(m))
+		}
+	}
+	return e.StatsEdge.Collect(m)
+}
@@ -0,0 +1,168 @@
+package edge_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
+)
+
+// shardRecorder records the points of its groups.
+type shardRecorder struct {
+	mu     *sync.Mutex
+	points map[models.GroupID][]int64
+	shards map[models.GroupID]int
+	shard  int
+	err    error
+}
+
+func (r *shardRecorder) NewGroup(group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.shards[group.ID]; ok {
+		return nil, fmt.Errorf("group %s created twice", group.ID)
+	}
+	r.shards[group.ID] = r.shard
+	return &groupRecorder{r: r, id: group.ID}, nil
+}
+
+type groupRecorder struct {
+	noopReceiver
+	r  *shardRecorder
+	id models.GroupID
+}
+
+func (g *groupRecorder) Point(p edge.PointMessage) error {
+	if g.r.err != nil {
+		return g.r.err
+	}
+	g.r.mu.Lock()
+	defer g.r.mu.Unlock()
+	g.r.points[g.id] = append(g.r.points[g.id], p.Time().UnixNano())
+	return nil
+}
+
+func newShardRecorders(n int) []*shardRecorder {
+	mu := new(sync.Mutex)
+	points := make(map[models.GroupID][]int64)
+	shards := make(map[models.GroupID]int)
+	recorders := make([]*shardRecorder, n)
+	for i := range recorders {
+		recorders[i] = &shardRecorder{mu: mu, points: points, shards: shards, shard: i}
+	}
+	return recorders
+}
+
+func groupPoint(group string, t int64) edge.PointMessage {
+	return edge.NewPointMessage(
+		"cpu", "db", "rp",
+		models.Dimensions{TagNames: []string{"host"}},
+		models.Fields{"value": 1.0},
+		models.Tags{"host": group},
+		time.Unix(0, t),
+	)
+}
+
+func TestShardedGroupedConsumer(t *testing.T) {
+	in := edge.NewChannelEdge(pipeline.StreamEdge, defaultEdgeBufferSize)
+	recorders := newShardRecorders(4)
+	receivers := make([]edge.GroupedReceiver, len(recorders))
+	for i, r := range recorders {
+		receivers[i] = r
+	}
+	consumer := edge.NewShardedGroupedConsumer(in, receivers, 10)
+
+	const groups, points = 20, 100
+	go func() {
+		for i := int64(0); i < points; i++ {
+			for g := 0; g < groups; g++ {
+				in.Collect(groupPoint(fmt.Sprintf("server%d", g), i))
+			}
+		}
+		in.Close()
+	}()
+	if err := consumer.Consume(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := recorders[0]
+	if got := consumer.CardinalityVar().IntValue(); got != groups {
+		t.Errorf("unexpected cardinality got %d exp %d", got, groups)
+	}
+	exp := make([]int64, points)
+	for i := range exp {
+		exp[i] = int64(i)
+	}
+	used := make(map[int]bool)
+	for id, got := range r.points {
+		if !reflect.DeepEqual(got, exp) {
+			t.Errorf("unexpected points of group %s, got %v", id, got)
+		}
+		used[r.shards[id]] = true
+	}
+	if len(r.points) != groups {
+		t.Errorf("unexpected number of groups got %d exp %d", len(r.points), groups)
+	}
+	if len(used) < 2 {
+		t.Errorf("expected groups to be processed by several shards, got %v", used)
+	}
+}
+
+func TestShardedGroupedConsumer_Error(t *testing.T) {
+	in := edge.NewChannelEdge(pipeline.StreamEdge, defaultEdgeBufferSize)
+	recorders := newShardRecorders(2)
+	receivers := make([]edge.GroupedReceiver, len(recorders))
+	for i, r := range recorders {
+		r.err = errors.New("failed")
+		receivers[i] = r
+	}
+	consumer := edge.NewShardedGroupedConsumer(in, receivers, 10)
+	go func() {
+		for i := int64(0); ; i++ {
+			if err := in.Collect(groupPoint("serverA", i)); err != nil {
+				return
+			}
+		}
+	}()
+	if err := consumer.Consume(); err == nil || err.Error() != "failed" {
+		t.Errorf("unexpected error got %v exp failed", err)
+	}
+}
+
+func TestShardEdges(t *testing.T) {
+	out := edge.NewStatsEdge(edge.NewChannelEdge(pipeline.BatchEdge, defaultEdgeBufferSize))
+	shard := edge.NewShardEdges([]edge.StatsEdge{out})[0]
+
+	begin := edge.NewBeginBatchMessage("cpu", groupTags, groupDims.ByName, now, 2)
+	points := []edge.BatchPointMessage{
+		edge.NewBatchPointMessage(models.Fields{"value": 1.0}, groupTags, now),
+		edge.NewBatchPointMessage(models.Fields{"value": 2.0}, groupTags, now),
+	}
+	for _, m := range []edge.Message{begin, points[0], points[1], edge.NewEndBatchMessage()} {
+		if err := shard.Collect(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out.Close()
+
+	m, ok := out.Emit()
+	if !ok {
+		t.Fatal("expected batch")
+	}
+	b, ok := m.(edge.BufferedBatchMessage)
+	if !ok {
+		t.Fatalf("expected buffered batch got %T", m)
+	}
+	if !reflect.DeepEqual(b.Points(), points) {
+		t.Errorf("unexpected points got %v exp %v", b.Points(), points)
+	}
+	if _, ok := out.Emit(); ok {
+		t.Error("expected a single message")
+	}
+}
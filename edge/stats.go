@@ -141,6 +141,20 @@ func (e *batchStatsEdge) Collect(m Message) error {
 	if err := e.edge.Collect(m); err != nil {
 		return err
 	}
+	e.collect(m)
+	return nil
+}
+
+func (e *batchStatsEdge) TryCollect(m Message) (bool, error) {
+	ok, err := tryCollect(e.edge, m)
+	if ok {
+		e.collect(m)
+	}
+	return ok, err
+}
+
+// collect counts the collected message.
+func (e *batchStatsEdge) collect(m Message) {
 	switch b := m.(type) {
 	case BeginBatchMessage:
 		g := b.GroupInfo()
@@ -158,12 +172,11 @@ func (e *batchStatsEdge) Collect(m Message) error {
 	case BufferedBatchMessage:
 		e.collected.Add(1)
 		begin := b.Begin()
-		e.incCollected(begin.GroupID(), begin.GroupInfo, int64(len(b.Points())))
+		e.incCollected(begin.GroupID(), begin.GroupInfo, int64(b.Len()))
 	default:
 		// Do not count other messages
 		// TODO(nathanielc): How should we count other messages?
 	}
-	return nil
 }
 
 func (e *batchStatsEdge) Emit() (m Message, ok bool) {
@@ -186,7 +199,7 @@ func (e *batchStatsEdge) Emit() (m Message, ok bool) {
 		case BufferedBatchMessage:
 			e.emitted.Add(1)
 			begin := b.Begin()
-			e.incEmitted(begin.GroupID(), begin.GroupInfo, int64(len(b.Points())))
+			e.incEmitted(begin.GroupID(), begin.GroupInfo, int64(b.Len()))
 		default:
 			// Do not count other messages
 			// TODO(nathanielc): How should we count other messages?
@@ -207,12 +220,25 @@ func (e *streamStatsEdge) Collect(m Message) error {
 	if err := e.edge.Collect(m); err != nil {
 		return err
 	}
+	e.collect(m)
+	return nil
+}
+
+func (e *streamStatsEdge) TryCollect(m Message) (bool, error) {
+	ok, err := tryCollect(e.edge, m)
+	if ok {
+		e.collect(m)
+	}
+	return ok, err
+}
+
+// collect counts the collected message.
+func (e *streamStatsEdge) collect(m Message) {
 	if m.Type() == Point {
 		e.collected.Add(1)
 		p := m.(GroupInfoer)
 		e.incCollected(p.GroupID(), p.GroupInfo, 1)
 	}
-	return nil
 }
 
 func (e *streamStatsEdge) Emit() (m Message, ok bool) {
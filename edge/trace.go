@@ -0,0 +1,85 @@
+package edge
+
+import "github.com/influxdata/kapacitor/tracing"
+
+// Traced is a message that carries the context of its trace if it is sampled.
+// Points and batches are traced, the copies of a message are part of the same trace.
+type Traced interface {
+	TraceContext() tracing.SpanContext
+	// SetTraceContext sets the context of the trace of the message.
+	// It must be set before the message is collected by an edge, since messages are shared.
+	SetTraceContext(tracing.SpanContext)
+}
+
+// TraceContext returns the context of the trace of the message,
+// or an invalid context if the message is not traced.
+func TraceContext(m Message) tracing.SpanContext {
+	if t, ok := m.(Traced); ok {
+		return t.TraceContext()
+	}
+	return tracing.SpanContext{}
+}
+
+// SetTraceContext sets the context of the trace of the message,
+// if the message is traced and the context is valid.
+func SetTraceContext(m Message, c tracing.SpanContext) {
+	if !c.IsValid() {
+		return
+	}
+	if t, ok := m.(Traced); ok {
+		t.SetTraceContext(c)
+	}
+}
+
+func (pm *pointMessage) TraceContext() tracing.SpanContext {
+	if pm.trace == nil {
+		return tracing.SpanContext{}
+	}
+	return *pm.trace
+}
+func (pm *pointMessage) SetTraceContext(c tracing.SpanContext) {
+	pm.trace = &c
+}
+
+func (bb *beginBatchMessage) TraceContext() tracing.SpanContext {
+	if bb.trace == nil {
+		return tracing.SpanContext{}
+	}
+	return *bb.trace
+}
+func (bb *beginBatchMessage) SetTraceContext(c tracing.SpanContext) {
+	bb.trace = &c
+}
+
+func (bb *bufferedBatchMessage) TraceContext() tracing.SpanContext {
+	return TraceContext(bb.begin)
+}
+func (bb *bufferedBatchMessage) SetTraceContext(c tracing.SpanContext) {
+	begin := bb.begin.ShallowCopy()
+	if t, ok := begin.(Traced); ok {
+		t.SetTraceContext(c)
+		bb.begin = begin
+	}
+}
+
+// WithTraceContext returns a shallow copy of the traced message with the context,
+// so that the context of a message shared by several edges can be changed for one of them.
+// Other messages are returned as is.
+func WithTraceContext(m Message, c tracing.SpanContext) Message {
+	var t Traced
+	switch msg := m.(type) {
+	case PointMessage:
+		cp := msg.ShallowCopy()
+		t, m = cp.(Traced), cp
+	case BeginBatchMessage:
+		cp := msg.ShallowCopy()
+		t, m = cp.(Traced), cp
+	case BufferedBatchMessage:
+		cp := msg.ShallowCopy()
+		t, m = cp.(Traced), cp
+	}
+	if t != nil && c.IsValid() {
+		t.SetTraceContext(c)
+	}
+	return m
+}
@@ -19,6 +19,7 @@ type EvalNode struct {
 	refVarList  [][]string
 	scopePool   stateful.ScopePool
 	tags        map[string]bool
+	groups      *groupStates
 
 	evalErrors *expvar.Int
 }
@@ -29,8 +30,9 @@ func newEvalNode(et *ExecutingTask, n *pipeline.EvalNode, d NodeDiagnostic) (*Ev
 		return nil, errors.New("must provide one name per expression via the 'As' property")
 	}
 	en := &EvalNode{
-		node: node{Node: n, et: et, diag: d},
-		e:    n,
+		node:   node{Node: n, et: et, diag: d},
+		e:      n,
+		groups: newGroupStates(),
 	}
 
 	// Create stateful expressions
@@ -63,32 +65,41 @@ func newEvalNode(et *ExecutingTask, n *pipeline.EvalNode, d NodeDiagnostic) (*Ev
 }
 
 func (n *EvalNode) runEval(snapshot []byte) error {
-	consumer := edge.NewGroupedConsumer(
-		n.ins[0],
-		n,
-	)
+	if err := n.groups.restore(snapshot); err != nil {
+		n.diag.Error("failed to restore snapshot", err)
+	}
+	consumer := n.newGroupedConsumer(n.e.Shards, n.newShardGroup)
 	n.statMap.Set(statCardinalityGauge, consumer.CardinalityVar())
 
 	return consumer.Consume()
 
 }
 
-func (n *EvalNode) NewGroup(group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error) {
+func (n *EvalNode) newShardGroup(s nodeShard, group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error) {
 	return edge.NewReceiverFromForwardReceiverWithStats(
-		n.outs,
-		edge.NewTimedForwardReceiver(n.timer, n.newGroup()),
+		s.outs,
+		edge.NewTimedForwardReceiver(s.timer, n.newGroup(group.ID)),
 	), nil
 }
 
-func (n *EvalNode) newGroup() *evalGroup {
+func (n *EvalNode) newGroup(id models.GroupID) *evalGroup {
 	expressions := make([]stateful.Expression, len(n.expressions))
 	for i, exp := range n.expressions {
 		expressions[i] = exp.CopyReset()
 	}
-	return &evalGroup{
+	g := &evalGroup{
 		n:           n,
+		id:          id,
 		expressions: expressions,
 	}
+	if err := n.groups.add(id, g); err != nil {
+		n.diag.Error("failed to restore snapshot", err)
+	}
+	return g
+}
+
+func (n *EvalNode) snapshot() ([]byte, error) {
+	return n.groups.snapshot()
 }
 
 func (n *EvalNode) eval(expressions []stateful.Expression, p edge.FieldsTagsTimeSetter) error {
@@ -177,6 +188,7 @@ func (n *EvalNode) eval(expressions []stateful.Expression, p edge.FieldsTagsTime
 
 type evalGroup struct {
 	n           *EvalNode
+	id          models.GroupID
 	expressions []stateful.Expression
 }
 
@@ -207,7 +219,9 @@ func (g *evalGroup) Point(p edge.PointMessage) (edge.Message, error) {
 }
 
 func (g *evalGroup) doEval(p edge.FieldsTagsTimeSetter) bool {
+	g.n.groups.mu.RLock()
 	err := g.n.eval(g.expressions, p)
+	g.n.groups.mu.RUnlock()
 	if err != nil {
 		if !g.n.e.QuietFlag {
 			g.n.diag.Error("error evaluating expression", err)
@@ -218,10 +232,19 @@ func (g *evalGroup) doEval(p edge.FieldsTagsTimeSetter) bool {
 	return true
 }
 
+func (g *evalGroup) snapshot() ([]byte, error) {
+	return snapshotExpressions(g.expressions)
+}
+
+func (g *evalGroup) restore(snapshot []byte) error {
+	return restoreExpressions(g.expressions, snapshot)
+}
+
 func (g *evalGroup) Barrier(b edge.BarrierMessage) (edge.Message, error) {
 	return b, nil
 }
 func (g *evalGroup) DeleteGroup(d edge.DeleteGroupMessage) (edge.Message, error) {
+	g.n.groups.delete(g.id)
 	return d, nil
 }
 func (g *evalGroup) Done() {}
@@ -0,0 +1,342 @@
+package kapacitor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	imodels "github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/command"
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/pkg/errors"
+)
+
+const (
+	statsPointsSent     = "points_sent"
+	statsPointsReceived = "points_received"
+)
+
+type ExecNode struct {
+	node
+	e   *pipeline.ExecNode
+	cmd command.Command
+
+	stdin   io.WriteCloser
+	replies chan []string
+	closing chan struct{}
+
+	pointsSent     *expvar.Int
+	pointsReceived *expvar.Int
+}
+
+// Create a new ExecNode which streams data to a command.
+func newExecNode(et *ExecutingTask, n *pipeline.ExecNode, d NodeDiagnostic) (*ExecNode, error) {
+	if et.tm.Commander == nil {
+		return nil, errors.New("no commander configured, cannot execute commands")
+	}
+	en := &ExecNode{
+		node: node{Node: n, et: et, diag: d},
+		e:    n,
+		cmd: et.tm.Commander.NewCommand(command.Spec{
+			Prog: n.Command[0],
+			Args: n.Command[1:],
+		}),
+	}
+	en.node.runF = en.runExec
+	return en, nil
+}
+
+func (n *ExecNode) runExec([]byte) error {
+	n.pointsSent = &expvar.Int{}
+	n.pointsReceived = &expvar.Int{}
+	n.statMap.Set(statsPointsSent, n.pointsSent)
+	n.statMap.Set(statsPointsReceived, n.pointsReceived)
+
+	stdin, err := n.cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := n.cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := n.cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := n.cmd.Start(); err != nil {
+		return errors.Wrap(err, "failed to start command")
+	}
+	n.stdin = stdin
+	n.replies = make(chan []string)
+	n.closing = make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		n.readStderr(stderr)
+	}()
+	go func() {
+		defer wg.Done()
+		n.readStdout(stdout)
+	}()
+
+	consumer := edge.NewGroupedConsumer(
+		n.ins[0],
+		n,
+	)
+	n.statMap.Set(statCardinalityGauge, consumer.CardinalityVar())
+
+	err = consumer.Consume()
+	close(n.closing)
+	n.stdin.Close()
+	if err != nil {
+		// Stop the node, the output of the command is not needed anymore.
+		// Waiting for the command closes its output even if its children still hold it open.
+		n.cmd.Kill()
+		n.cmd.Wait()
+		wg.Wait()
+		return err
+	}
+	wg.Wait()
+	if err := n.cmd.Wait(); err != nil {
+		return errors.Wrap(err, "command failed")
+	}
+	return nil
+}
+
+// readStderr logs each line the command writes to STDERR.
+func (n *ExecNode) readStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		n.diag.Error("exec command error output", errors.New(scanner.Text()))
+	}
+}
+
+// readStdout collects the replies of the command if capture is set,
+// otherwise the output is discarded.
+func (n *ExecNode) readStdout(r io.Reader) {
+	defer close(n.replies)
+	if !n.e.CaptureFlag {
+		io.Copy(ioutil.Discard, r)
+		return
+	}
+	scanner := bufio.NewScanner(r)
+	var lines []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			lines = append(lines, line)
+			continue
+		}
+		select {
+		case n.replies <- lines:
+		case <-n.closing:
+			// Nobody is waiting for replies anymore.
+			io.Copy(ioutil.Discard, r)
+			return
+		}
+		lines = nil
+	}
+}
+
+func (n *ExecNode) NewGroup(group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error) {
+	g := &execGroup{
+		n:      n,
+		buffer: new(edge.BatchBuffer),
+	}
+	return edge.NewReceiverFromForwardReceiverWithStats(
+		n.outs,
+		edge.NewTimedForwardReceiver(n.timer, g),
+	), nil
+}
+
+type execGroup struct {
+	n      *ExecNode
+	buffer *edge.BatchBuffer
+}
+
+func (g *execGroup) BeginBatch(begin edge.BeginBatchMessage) (edge.Message, error) {
+	return nil, g.buffer.BeginBatch(begin)
+}
+
+func (g *execGroup) BatchPoint(bp edge.BatchPointMessage) (edge.Message, error) {
+	return nil, g.buffer.BatchPoint(bp)
+}
+
+func (g *execGroup) EndBatch(end edge.EndBatchMessage) (edge.Message, error) {
+	return g.BufferedBatch(g.buffer.BufferedBatchMessage(end))
+}
+
+func (g *execGroup) BufferedBatch(batch edge.BufferedBatchMessage) (edge.Message, error) {
+	var buf bytes.Buffer
+	switch g.n.e.Format {
+	case pipeline.ExecFormatJSON:
+		if err := json.NewEncoder(&buf).Encode(batch); err != nil {
+			return nil, errors.Wrap(err, "failed to encode batch")
+		}
+	default:
+		for _, bp := range batch.Points() {
+			if err := writeLine(&buf, batch.Name(), bp); err != nil {
+				return nil, err
+			}
+		}
+	}
+	replies, err := g.n.exchange(buf.Bytes(), len(batch.Points()))
+	if err != nil || !g.n.e.CaptureFlag {
+		return batch, err
+	}
+
+	batch = batch.ShallowCopy()
+	points := make([]edge.BatchPointMessage, len(replies))
+	for i, r := range replies {
+		points[i] = edge.NewBatchPointMessage(r.Fields, withGroupTags(r.Tags, batch.Tags()), r.Time)
+	}
+	batch.SetPoints(points)
+	return batch, nil
+}
+
+func (g *execGroup) Point(p edge.PointMessage) (edge.Message, error) {
+	var buf bytes.Buffer
+	switch g.n.e.Format {
+	case pipeline.ExecFormatJSON:
+		if err := json.NewEncoder(&buf).Encode(p); err != nil {
+			return nil, errors.Wrap(err, "failed to encode point")
+		}
+	default:
+		if err := writeLine(&buf, p.Name(), p); err != nil {
+			return nil, err
+		}
+	}
+	replies, err := g.n.exchange(buf.Bytes(), 1)
+	if err != nil || !g.n.e.CaptureFlag {
+		return p, err
+	}
+
+	switch len(replies) {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("command replied with %d points to a single point", len(replies))
+	}
+	p = p.ShallowCopy()
+	groupTags := make(models.Tags, len(p.Dimensions().TagNames))
+	for _, d := range p.Dimensions().TagNames {
+		groupTags[d] = p.Tags()[d]
+	}
+	p.SetTagsAndDimensions(withGroupTags(replies[0].Tags, groupTags), p.Dimensions())
+	p.SetFields(replies[0].Fields)
+	p.SetTime(replies[0].Time)
+	return p, nil
+}
+
+func (g *execGroup) Barrier(b edge.BarrierMessage) (edge.Message, error) {
+	return b, nil
+}
+func (g *execGroup) DeleteGroup(d edge.DeleteGroupMessage) (edge.Message, error) {
+	return d, nil
+}
+func (g *execGroup) Done() {}
+
+// execPoint is a point replied by the command.
+type execPoint struct {
+	Tags   models.Tags   `json:"tags"`
+	Fields models.Fields `json:"fields"`
+	Time   time.Time     `json:"time"`
+}
+
+// exchange writes the data followed by an empty line to the command
+// and reads the reply if capture is set.
+func (n *ExecNode) exchange(data []byte, points int) ([]execPoint, error) {
+	if _, err := n.stdin.Write(append(data, '\n')); err != nil {
+		return nil, errors.Wrap(err, "failed to write to command")
+	}
+	n.pointsSent.Add(int64(points))
+	if !n.e.CaptureFlag {
+		return nil, nil
+	}
+
+	var timeout <-chan time.Time
+	if n.e.Timeout > 0 {
+		timer := time.NewTimer(n.e.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var lines []string
+	select {
+	case l, ok := <-n.replies:
+		if !ok {
+			return nil, errors.New("command closed its output")
+		}
+		lines = l
+	case <-timeout:
+		// The reply is late or lost, the later replies could not be matched to their data.
+		return nil, fmt.Errorf("command did not reply within %v, stopping the node", n.e.Timeout)
+	}
+
+	replies, err := n.parseReply(lines)
+	if err != nil {
+		return nil, err
+	}
+	n.pointsReceived.Add(int64(len(replies)))
+	return replies, nil
+}
+
+func (n *ExecNode) parseReply(lines []string) ([]execPoint, error) {
+	replies := make([]execPoint, 0, len(lines))
+	if n.e.Format == pipeline.ExecFormatJSON {
+		for _, l := range lines {
+			var p execPoint
+			if err := json.Unmarshal([]byte(l), &p); err != nil {
+				return nil, errors.Wrap(err, "invalid reply from command")
+			}
+			p.Time = p.Time.UTC()
+			replies = append(replies, p)
+		}
+		return replies, nil
+	}
+	points, err := imodels.ParsePointsString(strings.Join(lines, "\n"))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid reply from command")
+	}
+	for _, p := range points {
+		replies = append(replies, execPoint{
+			Tags:   p.Tags().Map(),
+			Fields: models.Fields(p.Fields()),
+			Time:   p.Time().UTC(),
+		})
+	}
+	return replies, nil
+}
+
+// writeLine writes the point as a line of line protocol.
+func writeLine(w io.Writer, name string, p edge.FieldsTagsTimeGetter) error {
+	point, err := imodels.NewPoint(name, imodels.NewTags(p.Tags()), imodels.Fields(p.Fields()), p.Time())
+	if err != nil {
+		return errors.Wrap(err, "failed to encode point")
+	}
+	_, err = fmt.Fprintln(w, point.String())
+	return err
+}
+
+// withGroupTags returns the tags with the group tags restored.
+func withGroupTags(tags, group models.Tags) models.Tags {
+	if tags == nil {
+		tags = make(models.Tags, len(group))
+	}
+	for k, v := range group {
+		tags[k] = v
+	}
+	return tags
+}
@@ -0,0 +1,317 @@
+package kapacitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/objectstore"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/pkg/errors"
+)
+
+const (
+	statsReadErrors  = "read_errors"
+	statsBatchesRead = "batches_read"
+	statsPointsRead  = "points_read"
+)
+
+// FileNode reads batches from a file on a schedule.
+type FileNode struct {
+	node
+	f           *pipeline.FileNode
+	measurement string
+	ticker      ticker
+	readMu      sync.Mutex
+	readErr     chan error
+	closing     chan struct{}
+	aborting    chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	readErrors   *expvar.Int
+	batchesRead  *expvar.Int
+	pointsRead   *expvar.Int
+	tagColumns   map[string]bool
+	groupColumns []string
+}
+
+func newFileNode(et *ExecutingTask, n *pipeline.FileNode, d NodeDiagnostic) (*FileNode, error) {
+	if err := objectstore.Validate(n.Path); err != nil {
+		return nil, err
+	}
+	fn := &FileNode{
+		node:         node{Node: n, et: et, diag: d},
+		f:            n,
+		measurement:  n.Measurement,
+		closing:      make(chan struct{}),
+		aborting:     make(chan struct{}),
+		tagColumns:   make(map[string]bool, len(n.TagColumns)),
+		groupColumns: n.Dimensions,
+	}
+	fn.node.runF = fn.runBatch
+	fn.node.stopF = fn.stopBatch
+	fn.ctx, fn.cancel = context.WithCancel(context.Background())
+
+	for _, t := range n.TagColumns {
+		fn.tagColumns[t] = true
+	}
+	if fn.measurement == "" {
+		fn.measurement = fileMeasurement(n.Path)
+	}
+
+	switch {
+	case n.Every != 0:
+		fn.ticker = newTimeTicker(n.Every, false)
+	case n.Cron != "":
+		var err error
+		fn.ticker, err = newCronTicker(n.Cron)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("must define one of 'every' or 'cron'")
+	}
+	return fn, nil
+}
+
+// fileMeasurement returns the file name without its extension.
+func fileMeasurement(p string) string {
+	if u, err := url.Parse(p); err == nil && u.Scheme != "" {
+		p = u.Path
+	}
+	base := path.Base(p)
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// Files are not read from InfluxDB.
+func (n *FileNode) DBRPs() ([]DBRP, error) {
+	return nil, nil
+}
+
+func (n *FileNode) Cluster() string {
+	return ""
+}
+
+func (n *FileNode) GroupByMeasurement() bool {
+	return false
+}
+
+// Files are not recorded, so there are no queries to replay.
+func (n *FileNode) Queries(start, stop time.Time) ([]*Query, error) {
+	return []*Query{}, nil
+}
+
+func (n *FileNode) Start() {
+	n.readMu.Lock()
+	defer n.readMu.Unlock()
+	n.readErr = make(chan error, 1)
+	go func() {
+		n.readErr <- n.doRead(n.ins[0])
+	}()
+}
+
+func (n *FileNode) Abort() {
+	n.cancel()
+	close(n.aborting)
+}
+
+// Read the file and collect batches on batch collector.
+func (n *FileNode) doRead(in edge.Edge) error {
+	defer in.Close()
+	n.readErrors = &expvar.Int{}
+	n.batchesRead = &expvar.Int{}
+	n.pointsRead = &expvar.Int{}
+
+	n.statMap.Set(statsReadErrors, n.readErrors)
+	n.statMap.Set(statsBatchesRead, n.batchesRead)
+	n.statMap.Set(statsPointsRead, n.pointsRead)
+
+	tickC := n.ticker.Start()
+	for {
+		select {
+		case <-n.closing:
+			return nil
+		case <-n.aborting:
+			return errors.New("batch doRead aborted")
+		case now := <-tickC:
+			n.timer.Start()
+			batches, err := n.read(now)
+			if err != nil {
+				n.readErrors.Add(1)
+				n.diag.Error("failed to read file", err)
+				n.timer.Stop()
+				break
+			}
+			for _, b := range batches {
+				n.batchesRead.Add(1)
+				n.pointsRead.Add(int64(len(b.Points())))
+
+				n.timer.Pause()
+				if err := in.Collect(b); err != nil {
+					return err
+				}
+				n.timer.Resume()
+			}
+			n.timer.Stop()
+		}
+	}
+}
+
+func (n *FileNode) read(now time.Time) ([]edge.BufferedBatchMessage, error) {
+	data, err := objectstore.Get(n.ctx, n.f.Path)
+	if err != nil {
+		return nil, err
+	}
+	return n.parseCSV(data, now.UTC())
+}
+
+// parseCSV converts the rows of a CSV file into batches grouped by the group columns.
+// Rows without fields are skipped.
+func (n *FileNode) parseCSV(data []byte, now time.Time) ([]edge.BufferedBatchMessage, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = 0
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid csv")
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	header := rows[0]
+	timeFormat := n.f.TimeFormat
+	if timeFormat == "" {
+		timeFormat = time.RFC3339Nano
+	}
+
+	var batches []edge.BufferedBatchMessage
+	groups := make(map[models.GroupID]int)
+	for i, row := range rows[1:] {
+		t := now
+		tags := make(models.Tags, len(n.tagColumns))
+		fields := make(models.Fields, len(row))
+		for j, value := range row {
+			column := header[j]
+			switch {
+			case value == "":
+				continue
+			case column == n.f.TimeColumn:
+				t, err = time.Parse(timeFormat, value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid time on line %d: %v", i+2, err)
+				}
+				t = t.UTC()
+			case n.tagColumns[column]:
+				tags[column] = value
+			default:
+				fields[column] = fieldValue(value)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		groupTags := make(models.Tags, len(n.groupColumns))
+		for _, d := range n.groupColumns {
+			groupTags[d] = tags[d]
+		}
+		groupID := models.ToGroupID(n.measurement, groupTags, models.Dimensions{TagNames: n.groupColumns})
+		idx, ok := groups[groupID]
+		if !ok {
+			idx = len(batches)
+			groups[groupID] = idx
+			batches = append(batches, edge.NewBufferedBatchMessage(
+				edge.NewBeginBatchMessage(n.measurement, groupTags, false, now, 0),
+				nil,
+				edge.NewEndBatchMessage(),
+			))
+		}
+		b := batches[idx]
+		b.SetPoints(append(b.Points(), edge.NewBatchPointMessage(fields, tags, t)))
+	}
+	for _, b := range batches {
+		b.Begin().SetSizeHint(len(b.Points()))
+	}
+	return batches, nil
+}
+
+// fieldValue converts a CSV value into an integer, float, boolean or string field.
+func fieldValue(v string) interface{} {
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	switch strings.ToLower(v) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return v
+}
+
+func (n *FileNode) runBatch([]byte) error {
+	errC := make(chan error, 1)
+	go func() {
+		defer func() {
+			err := recover()
+			if err != nil {
+				errC <- fmt.Errorf("%v", err)
+			}
+		}()
+		for bt, ok := n.ins[0].Emit(); ok; bt, ok = n.ins[0].Emit() {
+			for _, child := range n.outs {
+				err := child.Collect(bt)
+				if err != nil {
+					errC <- err
+					return
+				}
+			}
+		}
+		errC <- nil
+	}()
+	var readErr error
+	n.readMu.Lock()
+	if n.readErr != nil {
+		n.readMu.Unlock()
+		select {
+		case readErr = <-n.readErr:
+		case <-n.aborting:
+			readErr = errors.New("batch readErr aborted")
+		}
+	} else {
+		n.readMu.Unlock()
+	}
+
+	var err error
+	select {
+	case err = <-errC:
+	case <-n.aborting:
+		err = errors.New("batch run aborted")
+	}
+	if readErr != nil {
+		return readErr
+	}
+	return err
+}
+
+func (n *FileNode) stopBatch() {
+	if n.ticker != nil {
+		n.ticker.Stop()
+	}
+	n.cancel()
+	close(n.closing)
+}
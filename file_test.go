@@ -0,0 +1,121 @@
+package kapacitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
+)
+
+func TestFileNode_ParseCSV(t *testing.T) {
+	p := &pipeline.FileNode{
+		Path:       "s3://reference/hosts.csv?region=eu-west-1",
+		Format:     pipeline.FileFormatCSV,
+		Every:      time.Hour,
+		TimeColumn: "updated",
+		TagColumns: []string{"host", "dc"},
+		Dimensions: []string{"dc"},
+	}
+	n, err := newFileNode(&ExecutingTask{}, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := n.measurement, "hosts"; got != exp {
+		t.Fatalf("unexpected measurement: got %s exp %s", got, exp)
+	}
+
+	data := []byte(`host,dc,updated,cores,threshold,owner,active
+serverA,east,2018-03-01T00:00:00Z,8,0.75,ops,true
+serverB,west,,16,0.9,,false
+serverC,east,2018-03-02T12:00:00Z,4,,dev,
+serverD,west,,,,,
+`)
+	now := time.Date(2018, 3, 3, 0, 0, 0, 0, time.UTC)
+	batches, err := n.parseCSV(data, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 {
+		t.Fatalf("unexpected number of batches: %d", len(batches))
+	}
+
+	type point struct {
+		Time   time.Time
+		Tags   models.Tags
+		Fields models.Fields
+	}
+	exp := []struct {
+		tags   models.Tags
+		points []point
+	}{
+		{
+			tags: models.Tags{"dc": "east"},
+			points: []point{
+				{
+					Time:   time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC),
+					Tags:   models.Tags{"host": "serverA", "dc": "east"},
+					Fields: models.Fields{"cores": int64(8), "threshold": 0.75, "owner": "ops", "active": true},
+				},
+				{
+					Time:   time.Date(2018, 3, 2, 12, 0, 0, 0, time.UTC),
+					Tags:   models.Tags{"host": "serverC", "dc": "east"},
+					Fields: models.Fields{"cores": int64(4), "owner": "dev"},
+				},
+			},
+		},
+		{
+			tags: models.Tags{"dc": "west"},
+			points: []point{
+				{
+					Time:   now,
+					Tags:   models.Tags{"host": "serverB", "dc": "west"},
+					Fields: models.Fields{"cores": int64(16), "threshold": 0.9, "active": false},
+				},
+			},
+		},
+	}
+	for i, b := range batches {
+		if got := b.Name(); got != "hosts" {
+			t.Errorf("batch %d: unexpected name %s", i, got)
+		}
+		if got := b.Tags(); !reflect.DeepEqual(got, exp[i].tags) {
+			t.Errorf("batch %d: unexpected tags: got %v exp %v", i, got, exp[i].tags)
+		}
+		if got := b.Time(); !got.Equal(now) {
+			t.Errorf("batch %d: unexpected time: got %v exp %v", i, got, now)
+		}
+		if len(b.Points()) != len(exp[i].points) {
+			t.Fatalf("batch %d: unexpected number of points: %d", i, len(b.Points()))
+		}
+		for j, p := range b.Points() {
+			e := exp[i].points[j]
+			if !p.Time().Equal(e.Time) || !reflect.DeepEqual(p.Tags(), e.Tags) || !reflect.DeepEqual(p.Fields(), e.Fields) {
+				t.Errorf("batch %d point %d: unexpected point:\ngot %v %v %v\nexp %v %v %v", i, j, p.Time(), p.Tags(), p.Fields(), e.Time, e.Tags, e.Fields)
+			}
+		}
+	}
+}
+
+func TestFileNode_ParseCSV_Errors(t *testing.T) {
+	p := &pipeline.FileNode{
+		Path:       "/etc/kapacitor/hosts.csv",
+		Format:     pipeline.FileFormatCSV,
+		Every:      time.Hour,
+		TimeColumn: "updated",
+	}
+	n, err := newFileNode(&ExecutingTask{}, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := map[string]string{
+		"time":    "host,updated,cores\nserverA,yesterday,8\n",
+		"columns": "host,cores\nserverA,8,extra\n",
+	}
+	for name, data := range testCases {
+		if _, err := n.parseCSV([]byte(data), time.Now()); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -23,14 +23,17 @@ type HTTPOutNode struct {
 	routes  []httpd.Route
 	result  *models.Result
 	indexes []*httpOutGroup
+
+	broadcaster *httpd.Broadcaster
 }
 
 // Create a new  HTTPOutNode which caches the most recent item and exposes it over the HTTP API.
 func newHTTPOutNode(et *ExecutingTask, n *pipeline.HTTPOutNode, d NodeDiagnostic) (*HTTPOutNode, error) {
 	hn := &HTTPOutNode{
-		node:   node{Node: n, et: et, diag: d},
-		c:      n,
-		result: new(models.Result),
+		node:        node{Node: n, et: et, diag: d},
+		c:           n,
+		result:      new(models.Result),
+		broadcaster: httpd.NewBroadcaster(),
 	}
 	et.registerOutput(hn.c.Endpoint, hn)
 	hn.node.runF = hn.runOut
@@ -44,6 +47,10 @@ func (n *HTTPOutNode) Endpoint() string {
 
 func (n *HTTPOutNode) runOut([]byte) error {
 	hndl := func(w http.ResponseWriter, req *http.Request) {
+		if httpd.IsWebSocket(req) {
+			n.broadcaster.Serve(w, req, n.currentResult)
+			return
+		}
 		n.mu.RLock()
 		defer n.mu.RUnlock()
 
@@ -86,22 +93,43 @@ func (n *HTTPOutNode) runOut([]byte) error {
 	return consumer.Consume()
 }
 
+// currentResult returns a copy of the result to send to new WebSocket clients.
+func (n *HTTPOutNode) currentResult() interface{} {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	result := *n.result
+	result.Series = make(models.Rows, 0, len(n.result.Series))
+	for _, row := range n.result.Series {
+		if row != nil {
+			result.Series = append(result.Series, row)
+		}
+	}
+	return result
+}
+
 // Update the result structure with a row.
+// The row is pushed to the WebSocket clients as a result with a single series.
 func (n *HTTPOutNode) updateResultWithRow(idx int, row *models.Row) {
 	n.mu.Lock()
-	defer n.mu.Unlock()
 	if idx >= len(n.result.Series) {
+		n.mu.Unlock()
 		n.diag.Error("index out of range for row update",
 			fmt.Errorf("index %v is larger than number of series %v", idx, len(n.result.Series)))
 		return
 	}
 	n.result.Series[idx] = row
+	n.mu.Unlock()
+
+	if err := n.broadcaster.Broadcast(models.Result{Series: models.Rows{row}}); err != nil {
+		n.diag.Error("failed to push row to websocket clients", err)
+	}
 }
 
 func (n *HTTPOutNode) stopOut() {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 	n.et.tm.HTTPDService.DelRoutes(n.routes)
+	n.broadcaster.Close()
 }
 
 func (n *HTTPOutNode) NewGroup(group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error) {
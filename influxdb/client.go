@@ -2,9 +2,11 @@ package influxdb
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -51,6 +53,9 @@ type BatchPointsConfig struct {
 
 	// Write consistency is the number of servers required to confirm write
 	WriteConsistency string
+
+	// Gzip compresses the points written to the server
+	Gzip bool
 }
 
 // Query defines a query to send to the server
@@ -104,14 +109,75 @@ type Credentials struct {
 }
 
 // HTTPClient is safe for concurrent use.
+// Requests fail over to the next healthy URL when a URL cannot be reached,
+// a URL is unhealthy until it answers a health check or a request.
 type HTTPClient struct {
 	mu     sync.RWMutex
 	config Config
 	urls   []url.URL
+	health []*urlHealth
 	client *http.Client
 	index  int32
 }
 
+// URLStatus is the health of a URL of a client.
+type URLStatus struct {
+	URL     string
+	Healthy bool
+	// LastCheck is the time of the last health check of the URL,
+	// or of the last request that changed its health.
+	LastCheck time.Time
+	// Version of the server that answered the last health check.
+	Version string
+	// Err is the error of the last health check or request, if it failed.
+	Err error
+}
+
+// urlHealth is the health of a URL, updated by the health checks and requests of the URL.
+type urlHealth struct {
+	mu        sync.Mutex
+	unhealthy bool
+	lastCheck time.Time
+	version   string
+	err       error
+}
+
+func (h *urlHealth) healthy() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return !h.unhealthy
+}
+
+// set records the result of a health check or request, it returns whether the health of the URL changed.
+func (h *urlHealth) set(version string, err error) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	changed := h.unhealthy != (err != nil)
+	h.unhealthy = err != nil
+	h.lastCheck = time.Now()
+	if version != "" {
+		h.version = version
+	}
+	h.err = err
+	return changed
+}
+
+func (h *urlHealth) status(u url.URL) URLStatus {
+	// Do not expose the password of the URL.
+	if u.User != nil {
+		u.User = url.User(u.User.Username())
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return URLStatus{
+		URL:       u.String(),
+		Healthy:   !h.unhealthy,
+		LastCheck: h.lastCheck,
+		Version:   h.version,
+		Err:       h.err,
+	}
+}
+
 // NewHTTPClient returns a new Client from the provided config.
 // Client is safe for concurrent use by multiple goroutines.
 func NewHTTPClient(conf Config) (*HTTPClient, error) {
@@ -130,6 +196,7 @@ func NewHTTPClient(conf Config) (*HTTPClient, error) {
 	c := &HTTPClient{
 		config: conf,
 		urls:   urls,
+		health: newURLHealth(urls, nil, nil),
 		client: &http.Client{
 			Timeout:   conf.Timeout,
 			Transport: conf.Transport,
@@ -155,6 +222,22 @@ func parseURLs(urlStrs []string) ([]url.URL, error) {
 	return urls, nil
 }
 
+// newURLHealth returns the health of the URLs, keeping the health of the previous URLs.
+func newURLHealth(urls, prevURLs []url.URL, prevHealth []*urlHealth) []*urlHealth {
+	health := make([]*urlHealth, len(urls))
+	for i, u := range urls {
+		for j, prev := range prevURLs {
+			if prev == u {
+				health[i] = prevHealth[j]
+			}
+		}
+		if health[i] == nil {
+			health[i] = &urlHealth{}
+		}
+	}
+	return health
+}
+
 func (c *HTTPClient) loadConfig() Config {
 	c.mu.RLock()
 	config := c.config
@@ -162,11 +245,11 @@ func (c *HTTPClient) loadConfig() Config {
 	return config
 }
 
-func (c *HTTPClient) loadURLs() []url.URL {
+func (c *HTTPClient) loadURLs() ([]url.URL, []*urlHealth) {
 	c.mu.RLock()
-	urls := c.urls
+	urls, health := c.urls, c.health
 	c.mu.RUnlock()
-	return urls
+	return urls, health
 }
 
 func (c *HTTPClient) loadHTTPClient() *http.Client {
@@ -194,6 +277,7 @@ func (c *HTTPClient) Update(new Config) error {
 	if err != nil {
 		return err
 	}
+	c.health = newURLHealth(urls, c.urls, c.health)
 	c.urls = urls
 	if old.Credentials != new.Credentials ||
 		old.Timeout != new.Timeout ||
@@ -211,15 +295,77 @@ func (c *HTTPClient) Update(new Config) error {
 	return nil
 }
 
+// url returns the next healthy URL in round robin order, or the next URL if they are all unhealthy.
 func (c *HTTPClient) url() url.URL {
-	urls := c.loadURLs()
-	i := atomic.LoadInt32(&c.index)
-	i = (i + 1) % int32(len(urls))
-	atomic.StoreInt32(&c.index, i)
-	return urls[i]
+	urls, health := c.loadURLs()
+	first := -1
+	for range urls {
+		i := atomic.LoadInt32(&c.index)
+		i = (i + 1) % int32(len(urls))
+		atomic.StoreInt32(&c.index, i)
+		if first < 0 {
+			first = int(i)
+		}
+		if health[i].healthy() {
+			return urls[i]
+		}
+	}
+	return urls[first]
+}
+
+// healthOf returns the health of the URL of the request, nil if it is not a URL of the client.
+func (c *HTTPClient) healthOf(u *url.URL) *urlHealth {
+	urls, health := c.loadURLs()
+	for i := range urls {
+		if urls[i].Scheme == u.Scheme && urls[i].Host == u.Host {
+			return health[i]
+		}
+	}
+	return nil
 }
 
+// do sends the request, failing over to the next healthy URL if the URL of the request cannot be reached.
 func (c *HTTPClient) do(req *http.Request, result interface{}, codes ...int) (*http.Response, error) {
+	urls, _ := c.loadURLs()
+	tried := make(map[string]bool, len(urls))
+	for {
+		resp, err := c.doOnce(req, result, codes...)
+		if req.Context().Err() != nil {
+			return resp, err
+		}
+		h := c.healthOf(req.URL)
+		if _, ok := err.(*url.Error); !ok {
+			// The URL answered.
+			if h != nil && !h.healthy() {
+				h.set("", nil)
+			}
+			return resp, err
+		}
+		tried[req.URL.Host] = true
+		if h != nil {
+			h.set("", err)
+		}
+		next := c.url()
+		if tried[next.Host] || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+		retry := req.WithContext(req.Context())
+		u := *req.URL
+		u.Scheme, u.Host, u.User = next.Scheme, next.Host, next.User
+		retry.URL = &u
+		retry.Host = ""
+		if req.GetBody != nil {
+			body, berr := req.GetBody()
+			if berr != nil {
+				return resp, err
+			}
+			retry.Body = body
+		}
+		req = retry
+	}
+}
+
+func (c *HTTPClient) doOnce(req *http.Request, result interface{}, codes ...int) (*http.Response, error) {
 	// Get current config
 	config := c.loadConfig()
 	// Set auth credentials
@@ -285,42 +431,85 @@ func (c *HTTPClient) do(req *http.Request, result interface{}, codes ...int) (*h
 // Ping returns how long the request took, the version of the server it connected to, and an error if one occurred.
 func (c *HTTPClient) Ping(ctx context.Context) (time.Duration, string, error) {
 	now := time.Now()
-	u := c.url()
+	req, err := pingRequest(ctx, c.url())
+	if err != nil {
+		return 0, "", err
+	}
+	resp, err := c.do(req, nil, http.StatusNoContent)
+	if err != nil {
+		return 0, "", err
+	}
+	version := resp.Header.Get("X-Influxdb-Version")
+	return time.Since(now), version, nil
+}
+
+func pingRequest(ctx context.Context, u url.URL) (*http.Request, error) {
 	u.Path = "ping"
 	if ctx != nil {
 		if dl, ok := ctx.Deadline(); ok {
 			v := url.Values{}
-			v.Set("wait_for_leader", fmt.Sprintf("%.0fs", time.Now().Sub(dl).Seconds()))
+			v.Set("wait_for_leader", fmt.Sprintf("%.0fs", dl.Sub(time.Now()).Seconds()))
 			u.RawQuery = v.Encode()
 		}
 	}
 
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return 0, "", err
+		return nil, err
 	}
 	if ctx != nil {
 		req = req.WithContext(ctx)
 	}
-	resp, err := c.do(req, nil, http.StatusNoContent)
-	if err != nil {
-		return 0, "", err
+	return req, nil
+}
+
+// CheckHealth pings each URL and returns their status.
+func (c *HTTPClient) CheckHealth(ctx context.Context) []URLStatus {
+	urls, health := c.loadURLs()
+	var wg sync.WaitGroup
+	for i := range urls {
+		wg.Add(1)
+		go func(u url.URL, h *urlHealth) {
+			defer wg.Done()
+			req, err := pingRequest(ctx, u)
+			if err != nil {
+				h.set("", err)
+				return
+			}
+			resp, err := c.doOnce(req, nil, http.StatusNoContent)
+			if err != nil {
+				h.set("", err)
+				return
+			}
+			h.set(resp.Header.Get("X-Influxdb-Version"), nil)
+		}(urls[i], health[i])
 	}
-	version := resp.Header.Get("X-Influxdb-Version")
-	return time.Since(now), version, nil
+	wg.Wait()
+	return c.Status()
 }
 
-func (c *HTTPClient) Write(bp BatchPoints) error {
-	var b bytes.Buffer
-	precision := bp.Precision()
-	for _, p := range bp.Points() {
-		if _, err := b.Write(p.Bytes(precision)); err != nil {
-			return err
-		}
+// Status returns the status of each URL, as of their last health check or request.
+func (c *HTTPClient) Status() []URLStatus {
+	urls, health := c.loadURLs()
+	statuses := make([]URLStatus, len(urls))
+	for i := range urls {
+		statuses[i] = health[i].status(urls[i])
+	}
+	return statuses
+}
 
-		if err := b.WriteByte('\n'); err != nil {
-			return err
-		}
+// gzipWriters are the reused writers compressing the bodies of write requests.
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+func (c *HTTPClient) Write(bp BatchPoints) error {
+	// The body is not reused, since the transport may read it after the request returns.
+	b := new(bytes.Buffer)
+	if err := writeBody(b, bp); err != nil {
+		return err
 	}
 
 	u := c.url()
@@ -331,16 +520,44 @@ func (c *HTTPClient) Write(bp BatchPoints) error {
 	v.Set("precision", bp.Precision())
 	v.Set("consistency", bp.WriteConsistency())
 	u.RawQuery = v.Encode()
-	req, err := http.NewRequest("POST", u.String(), &b)
+	req, err := http.NewRequest("POST", u.String(), b)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/octet-stream")
+	if bp.Gzip() {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
 
 	_, err = c.do(req, nil, http.StatusNoContent, http.StatusOK)
 	return err
 }
 
+// writeBody writes the points of bp to b in line protocol, compressed if gzip is set.
+func writeBody(b *bytes.Buffer, bp BatchPoints) error {
+	w := io.Writer(b)
+	var gw *gzip.Writer
+	if bp.Gzip() {
+		gw = gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(gw)
+		gw.Reset(b)
+		w = gw
+	}
+	precision := bp.Precision()
+	var line []byte
+	for _, p := range bp.Points() {
+		line = p.AppendBytes(line[:0], precision)
+		line = append(line, '\n')
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+	if gw != nil {
+		return gw.Close()
+	}
+	return nil
+}
+
 // Response represents a list of statement results.
 type Response struct {
 	Results []Result
@@ -432,6 +649,11 @@ type BatchPoints interface {
 	RetentionPolicy() string
 	// SetRetentionPolicy sets the retention policy of this Batch
 	SetRetentionPolicy(s string)
+
+	// Gzip returns whether the points of this Batch are compressed when written
+	Gzip() bool
+	// SetGzip sets whether the points of this Batch are compressed when written
+	SetGzip(b bool)
 }
 
 // NewBatchPoints returns a BatchPoints interface based on the given config.
@@ -447,6 +669,7 @@ func NewBatchPoints(conf BatchPointsConfig) (BatchPoints, error) {
 		precision:        conf.Precision,
 		retentionPolicy:  conf.RetentionPolicy,
 		writeConsistency: conf.WriteConsistency,
+		gzip:             conf.Gzip,
 	}
 	return bp, nil
 }
@@ -457,6 +680,7 @@ type batchpoints struct {
 	precision        string
 	retentionPolicy  string
 	writeConsistency string
+	gzip             bool
 }
 
 func (bp *batchpoints) AddPoint(p Point) {
@@ -507,6 +731,14 @@ func (bp *batchpoints) SetRetentionPolicy(rp string) {
 	bp.retentionPolicy = rp
 }
 
+func (bp *batchpoints) Gzip() bool {
+	return bp.gzip
+}
+
+func (bp *batchpoints) SetGzip(b bool) {
+	bp.gzip = b
+}
+
 type Point struct {
 	Name   string
 	Tags   map[string]string
@@ -516,29 +748,19 @@ type Point struct {
 
 // Returns byte array of a line protocol representation of the point
 func (p Point) Bytes(precision string) []byte {
-	key := imodels.MakeKey([]byte(p.Name), imodels.NewTags(p.Tags))
-	fields := imodels.Fields(p.Fields).MarshalBinary()
-	kl := len(key)
-	fl := len(fields)
-	var bytes []byte
-
-	if p.Time.IsZero() {
-		bytes = make([]byte, fl+kl+1)
-		copy(bytes, key)
-		bytes[kl] = ' '
-		copy(bytes[kl+1:], fields)
-	} else {
-		timeStr := strconv.FormatInt(p.Time.UnixNano()/imodels.GetPrecisionMultiplier(precision), 10)
-		tl := len(timeStr)
-		bytes = make([]byte, fl+kl+tl+2)
-		copy(bytes, key)
-		bytes[kl] = ' '
-		copy(bytes[kl+1:], fields)
-		bytes[kl+fl+1] = ' '
-		copy(bytes[kl+fl+2:], []byte(timeStr))
-	}
-
-	return bytes
+	return p.AppendBytes(nil, precision)
+}
+
+// AppendBytes appends the line protocol representation of the point to b.
+func (p Point) AppendBytes(b []byte, precision string) []byte {
+	b = append(b, imodels.MakeKey([]byte(p.Name), imodels.NewTags(p.Tags))...)
+	b = append(b, ' ')
+	b = append(b, imodels.Fields(p.Fields).MarshalBinary()...)
+	if !p.Time.IsZero() {
+		b = append(b, ' ')
+		b = strconv.AppendInt(b, p.Time.UnixNano()/imodels.GetPrecisionMultiplier(precision), 10)
+	}
+	return b
 }
 
 // Simple type to create github.com/influxdata/kapacitor/influxdb clients.
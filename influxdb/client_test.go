@@ -1,12 +1,19 @@
 package influxdb
 
 import (
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestClient_Query(t *testing.T) {
@@ -172,6 +179,97 @@ func TestClient_Write(t *testing.T) {
 	}
 }
 
+func TestClient_Write_Gzip(t *testing.T) {
+	var encoding, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		var rd io.Reader = r.Body
+		if encoding == "gzip" {
+			gr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			rd = gr
+		}
+		b, err := ioutil.ReadAll(rd)
+		if err != nil {
+			t.Error(err)
+		}
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	config := Config{URLs: []string{ts.URL}}
+	c, _ := NewHTTPClient(config)
+
+	points := []Point{
+		{
+			Name:   "cpu",
+			Tags:   map[string]string{"host": "serverA"},
+			Fields: map[string]interface{}{"value": 1.5},
+			Time:   time.Unix(1, 0),
+		},
+		{
+			Name:   "cpu",
+			Tags:   map[string]string{"host": "serverB"},
+			Fields: map[string]interface{}{"value": int64(2)},
+			Time:   time.Unix(2, 0),
+		},
+	}
+	exp := "cpu,host=serverA value=1.5 1\ncpu,host=serverB value=2i 2\n"
+	for _, gz := range []bool{false, true} {
+		bp, err := NewBatchPoints(BatchPointsConfig{Precision: "s", Gzip: gz})
+		if err != nil {
+			t.Fatal(err)
+		}
+		bp.AddPoints(points)
+		if err := c.Write(bp); err != nil {
+			t.Fatal(err)
+		}
+		if got := encoding == "gzip"; got != gz {
+			t.Errorf("unexpected gzip encoding got %t exp %t", got, gz)
+		}
+		if body != exp {
+			t.Errorf("unexpected body gzip %t\ngot %q\nexp %q", gz, body, exp)
+		}
+	}
+}
+
+func BenchmarkClient_Write(b *testing.B) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(ioutil.Discard, r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	c, _ := NewHTTPClient(Config{URLs: []string{ts.URL}})
+
+	const n = 1000
+	points := make([]Point, n)
+	for i := range points {
+		points[i] = Point{
+			Name:   "cpu",
+			Tags:   map[string]string{"host": fmt.Sprintf("server%02d", i%20), "region": "us-west"},
+			Fields: map[string]interface{}{"mean": float64(i) + 0.5, "count": int64(i)},
+			Time:   time.Unix(0, 1500000000000000000+int64(i)),
+		}
+	}
+	for _, gz := range []bool{false, true} {
+		b.Run(fmt.Sprintf("gzip=%t", gz), func(b *testing.B) {
+			bp, _ := NewBatchPoints(BatchPointsConfig{Gzip: gz})
+			bp.AddPoints(points)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := c.Write(bp); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*n)/b.Elapsed().Seconds(), "points/s")
+		})
+	}
+}
+
 func TestClient_UserAgent(t *testing.T) {
 	receivedUserAgent := ""
 	var code int
@@ -241,6 +339,64 @@ func TestClient_UserAgent(t *testing.T) {
 	}
 }
 
+func TestClient_Failover(t *testing.T) {
+	var writes int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/write" {
+			body, _ := ioutil.ReadAll(r.Body)
+			if string(body) != "cpu value=1 0\n" {
+				t.Errorf("unexpected body %q", body)
+			}
+			atomic.AddInt32(&writes, 1)
+		}
+		w.Header().Set("X-Influxdb-Version", "1.8.0")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	downURL := down.URL
+	down.Close()
+
+	c, err := NewHTTPClient(Config{URLs: []string{downURL, up.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests to the unreachable URL fail over to the healthy URL.
+	bp, _ := NewBatchPoints(BatchPointsConfig{Database: "db"})
+	bp.AddPoint(Point{Name: "cpu", Fields: map[string]interface{}{"value": 1.0}, Time: time.Unix(0, 0)})
+	for i := 0; i < 4; i++ {
+		if err := c.Write(bp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(&writes); got != 4 {
+		t.Errorf("unexpected writes: got %d exp 4", got)
+	}
+	statuses := c.Status()
+	if statuses[0].Healthy || statuses[0].Err == nil {
+		t.Errorf("expected %s to be unhealthy: %+v", downURL, statuses[0])
+	}
+	if !statuses[1].Healthy {
+		t.Errorf("expected %s to be healthy: %+v", up.URL, statuses[1])
+	}
+
+	// The health checks ping every URL.
+	statuses = c.CheckHealth(context.Background())
+	if statuses[0].Healthy || statuses[0].LastCheck.IsZero() {
+		t.Errorf("expected %s to be unhealthy: %+v", downURL, statuses[0])
+	}
+	if !statuses[1].Healthy || statuses[1].Version != "1.8.0" {
+		t.Errorf("expected %s to be healthy: %+v", up.URL, statuses[1])
+	}
+
+	// Without a healthy URL the requests fail.
+	up.Close()
+	if _, _, err := c.Ping(nil); err == nil {
+		t.Error("expected error without a healthy URL")
+	}
+}
+
 func TestBatchPoints_SettersGetters(t *testing.T) {
 	bp, _ := NewBatchPoints(BatchPointsConfig{
 		Precision:        "ns",
@@ -10,6 +10,7 @@ import (
 	"github.com/influxdata/kapacitor/expvar"
 	"github.com/influxdata/kapacitor/influxdb"
 	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/models"
 	"github.com/influxdata/kapacitor/pipeline"
 	"github.com/pkg/errors"
 )
@@ -41,7 +42,7 @@ func newInfluxDBOutNode(et *ExecutingTask, n *pipeline.InfluxDBOutNode, d NodeDi
 	in := &InfluxDBOutNode{
 		node:        node{Node: n, et: et, diag: d},
 		i:           n,
-		wb:          newWriteBuffer(int(n.Buffer), n.FlushInterval, cli),
+		wb:          newWriteBuffer(int(n.Buffer), n.FlushInterval, int(n.Writers), cli),
 		batchBuffer: new(edge.BatchBuffer),
 	}
 	in.node.runF = in.runOut
@@ -109,29 +110,18 @@ func (n *InfluxDBOutNode) EndBatch(end edge.EndBatchMessage) (edge.Message, erro
 }
 
 func (n *InfluxDBOutNode) BufferedBatch(batch edge.BufferedBatchMessage) (edge.Message, error) {
-	n.write("", "", batch)
+	points := getPoints()
+	for _, p := range batch.Points() {
+		*points = append(*points, n.point(batch.Name(), p.Tags(), p.Fields(), p.Time()))
+	}
+	n.write("", "", points)
 	return batch, nil
 }
 
 func (n *InfluxDBOutNode) Point(p edge.PointMessage) (edge.Message, error) {
-	batch := edge.NewBufferedBatchMessage(
-		edge.NewBeginBatchMessage(
-			p.Name(),
-			p.Tags(),
-			p.Dimensions().ByName,
-			p.Time(),
-			1,
-		),
-		[]edge.BatchPointMessage{
-			edge.NewBatchPointMessage(
-				p.Fields(),
-				p.Tags(),
-				p.Time(),
-			),
-		},
-		edge.NewEndBatchMessage(),
-	)
-	n.write(p.Database(), p.RetentionPolicy(), batch)
+	points := getPoints()
+	*points = append(*points, n.point(p.Name(), p.Tags(), p.Fields(), p.Time()))
+	n.write(p.Database(), p.RetentionPolicy(), points)
 	return p, nil
 }
 
@@ -148,55 +138,82 @@ func (n *InfluxDBOutNode) stopOut() {
 	n.wb.abort()
 }
 
-func (n *InfluxDBOutNode) write(db, rp string, batch edge.BufferedBatchMessage) error {
+// point returns the point written for the fields and tags,
+// with the measurement and static tags of the node.
+func (n *InfluxDBOutNode) point(name string, tags models.Tags, fields models.Fields, t time.Time) influxdb.Point {
+	if n.i.Measurement != "" {
+		name = n.i.Measurement
+	}
+	if len(n.i.Tags) > 0 {
+		merged := make(map[string]string, len(tags)+len(n.i.Tags))
+		for k, v := range tags {
+			merged[k] = v
+		}
+		for k, v := range n.i.Tags {
+			merged[k] = v
+		}
+		tags = merged
+	}
+	return influxdb.Point{
+		Name:   name,
+		Tags:   tags,
+		Fields: fields,
+		Time:   t,
+	}
+}
+
+// write buffers the points, which are owned by the write buffer afterwards.
+func (n *InfluxDBOutNode) write(db, rp string, points *[]influxdb.Point) {
 	if n.i.Database != "" {
 		db = n.i.Database
 	}
 	if n.i.RetentionPolicy != "" {
 		rp = n.i.RetentionPolicy
 	}
-	name := n.i.Measurement
-	if name == "" {
-		name = batch.Name()
-	}
-
-	points := make([]influxdb.Point, len(batch.Points()))
-	for j, p := range batch.Points() {
-		var tags map[string]string
-		if len(n.i.Tags) > 0 {
-			tags = make(map[string]string, len(p.Tags())+len(n.i.Tags))
-			for k, v := range p.Tags() {
-				tags[k] = v
-			}
-			for k, v := range n.i.Tags {
-				tags[k] = v
-			}
-		} else {
-			tags = p.Tags()
-		}
-		points[j] = influxdb.Point{
-			Name:   name,
-			Tags:   tags,
-			Fields: p.Fields(),
-			Time:   p.Time(),
-		}
-	}
 	bpc := influxdb.BatchPointsConfig{
 		Database:         db,
 		RetentionPolicy:  rp,
 		WriteConsistency: n.i.WriteConsistency,
 		Precision:        n.i.Precision,
+		Gzip:             n.i.GzipFlag,
 	}
 	n.wb.enqueue(bpc, points)
-	return nil
 }
 
+// pointsPool holds the slices of points passed from the node to the write buffer and its writers.
+var pointsPool = sync.Pool{
+	New: func() interface{} {
+		return new([]influxdb.Point)
+	},
+}
+
+func getPoints() *[]influxdb.Point {
+	return pointsPool.Get().(*[]influxdb.Point)
+}
+
+// putPoints returns the slice to the pool, releasing the tags and fields of its points.
+func putPoints(points *[]influxdb.Point) {
+	for i := range *points {
+		(*points)[i] = influxdb.Point{}
+	}
+	*points = (*points)[:0]
+	pointsPool.Put(points)
+}
+
+// writeBuffer buffers the points by their batch config, until the buffer size
+// or flush interval is reached, and passes the buffered batches to the writers of their database.
 type writeBuffer struct {
 	size          int
 	flushInterval time.Duration
-	errC          chan error
 	queue         chan queueEntry
-	buffer        map[influxdb.BatchPointsConfig]influxdb.BatchPoints
+	buffer        map[influxdb.BatchPointsConfig]*[]influxdb.Point
+
+	// Writers by database
+	writers    map[string]chan queueEntry
+	numWriters int
+	writersWG  sync.WaitGroup
+	// Batches passed to the writers and not yet written
+	pending sync.WaitGroup
 
 	flushing chan struct{}
 	flushed  chan struct{}
@@ -210,10 +227,13 @@ type writeBuffer struct {
 
 type queueEntry struct {
 	bpc    influxdb.BatchPointsConfig
-	points []influxdb.Point
+	points *[]influxdb.Point
 }
 
-func newWriteBuffer(size int, flushInterval time.Duration, cli influxdb.Client) *writeBuffer {
+func newWriteBuffer(size int, flushInterval time.Duration, writers int, cli influxdb.Client) *writeBuffer {
+	if writers < 1 {
+		writers = 1
+	}
 	return &writeBuffer{
 		cli:           cli,
 		size:          size,
@@ -221,12 +241,14 @@ func newWriteBuffer(size int, flushInterval time.Duration, cli influxdb.Client)
 		flushing:      make(chan struct{}),
 		flushed:       make(chan struct{}),
 		queue:         make(chan queueEntry),
-		buffer:        make(map[influxdb.BatchPointsConfig]influxdb.BatchPoints),
+		buffer:        make(map[influxdb.BatchPointsConfig]*[]influxdb.Point),
+		writers:       make(map[string]chan queueEntry),
+		numWriters:    writers,
 		stopping:      make(chan struct{}),
 	}
 }
 
-func (w *writeBuffer) enqueue(bpc influxdb.BatchPointsConfig, points []influxdb.Point) {
+func (w *writeBuffer) enqueue(bpc influxdb.BatchPointsConfig, points *[]influxdb.Point) {
 	qe := queueEntry{
 		bpc:    bpc,
 		points: points,
@@ -234,6 +256,7 @@ func (w *writeBuffer) enqueue(bpc influxdb.BatchPointsConfig, points []influxdb.
 	select {
 	case w.queue <- qe:
 	case <-w.stopping:
+		putPoints(points)
 	}
 }
 
@@ -242,9 +265,13 @@ func (w *writeBuffer) start() {
 	go w.run()
 }
 
+// flush writes all buffered points and waits for the writes to complete.
 func (w *writeBuffer) flush() {
-	w.flushing <- struct{}{}
-	<-w.flushed
+	select {
+	case w.flushing <- struct{}{}:
+		<-w.flushed
+	case <-w.stopping:
+	}
 }
 
 func (w *writeBuffer) abort() {
@@ -254,34 +281,30 @@ func (w *writeBuffer) abort() {
 
 func (w *writeBuffer) run() {
 	defer w.wg.Done()
+	defer w.stopWriters()
 	flushTick := time.NewTicker(w.flushInterval)
 	defer flushTick.Stop()
-	var err error
 	for {
 		select {
 		case qe := <-w.queue:
 			// Read incoming points off queue
-			bp, ok := w.buffer[qe.bpc]
-			if !ok {
-				bp, err = influxdb.NewBatchPoints(qe.bpc)
-				if err != nil {
-					w.i.diag.Error("failed to write points to InfluxDB", err)
-					break
-				}
-				w.buffer[qe.bpc] = bp
+			points, ok := w.buffer[qe.bpc]
+			if ok {
+				*points = append(*points, *qe.points...)
+				putPoints(qe.points)
+			} else {
+				points = qe.points
+				w.buffer[qe.bpc] = points
 			}
-			bp.AddPoints(qe.points)
 			// Check if we hit buffer size
-			if len(bp.Points()) >= w.size {
-				err = w.write(bp)
-				if err != nil {
-					w.i.diag.Error("failed to write points to InfluxDB", err)
-				}
+			if len(*points) >= w.size {
+				w.send(qe.bpc, points)
 				delete(w.buffer, qe.bpc)
 			}
 		case <-w.flushing:
 			// Explicit flush called
 			w.writeAll()
+			w.pending.Wait()
 			w.flushed <- struct{}{}
 		case <-flushTick.C:
 			// Flush all points after flush interval timeout
@@ -293,21 +316,61 @@ func (w *writeBuffer) run() {
 }
 
 func (w *writeBuffer) writeAll() {
-	for bpc, bp := range w.buffer {
-		err := w.write(bp)
-		if err != nil {
+	for bpc, points := range w.buffer {
+		w.send(bpc, points)
+		delete(w.buffer, bpc)
+	}
+}
+
+// send passes the points to a writer of their database,
+// waiting for one if all of them are writing.
+func (w *writeBuffer) send(bpc influxdb.BatchPointsConfig, points *[]influxdb.Point) {
+	writer, ok := w.writers[bpc.Database]
+	if !ok {
+		writer = make(chan queueEntry)
+		w.writers[bpc.Database] = writer
+		w.writersWG.Add(w.numWriters)
+		for i := 0; i < w.numWriters; i++ {
+			go w.runWriter(writer)
+		}
+	}
+	w.pending.Add(1)
+	writer <- queueEntry{
+		bpc:    bpc,
+		points: points,
+	}
+}
+
+func (w *writeBuffer) stopWriters() {
+	for db, writer := range w.writers {
+		close(writer)
+		delete(w.writers, db)
+	}
+	w.writersWG.Wait()
+}
+
+func (w *writeBuffer) runWriter(writer <-chan queueEntry) {
+	defer w.writersWG.Done()
+	for qe := range writer {
+		if err := w.write(qe.bpc, *qe.points); err != nil {
 			w.i.diag.Error("failed to write points to InfluxDB", err)
 		}
-		delete(w.buffer, bpc)
+		putPoints(qe.points)
+		w.pending.Done()
 	}
 }
 
-func (w *writeBuffer) write(bp influxdb.BatchPoints) error {
-	err := w.cli.Write(bp)
+func (w *writeBuffer) write(bpc influxdb.BatchPointsConfig, points []influxdb.Point) error {
+	bp, err := influxdb.NewBatchPoints(bpc)
+	if err != nil {
+		return err
+	}
+	bp.AddPoints(points)
+	err = w.cli.Write(bp)
 	if err != nil {
 		w.i.writeErrors.Add(1)
 		return err
 	}
-	w.i.pointsWritten.Add(int64(len(bp.Points())))
+	w.i.pointsWritten.Add(int64(len(points)))
 	return nil
 }
@@ -0,0 +1,170 @@
+package kapacitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/influxdb"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
+)
+
+// writeRecorder is an InfluxDB client recording the written batches.
+type writeRecorder struct {
+	mu      sync.Mutex
+	latency time.Duration
+	batches []influxdb.BatchPoints
+	points  int
+}
+
+func (c *writeRecorder) Ping(ctx context.Context) (time.Duration, string, error) {
+	return 0, "", nil
+}
+
+func (c *writeRecorder) Write(bp influxdb.BatchPoints) error {
+	time.Sleep(c.latency)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.batches != nil {
+		c.batches = append(c.batches, bp)
+	}
+	c.points += len(bp.Points())
+	return nil
+}
+
+func (c *writeRecorder) Query(q influxdb.Query) (*influxdb.Response, error) {
+	return &influxdb.Response{}, nil
+}
+
+func newTestInfluxDBOutNode(p *pipeline.InfluxDBOutNode, cli influxdb.Client) *InfluxDBOutNode {
+	n := &InfluxDBOutNode{
+		i:             p,
+		wb:            newWriteBuffer(int(p.Buffer), p.FlushInterval, int(p.Writers), cli),
+		batchBuffer:   new(edge.BatchBuffer),
+		pointsWritten: new(expvar.Int),
+		writeErrors:   new(expvar.Int),
+	}
+	n.wb.i = n
+	n.wb.start()
+	return n
+}
+
+func TestInfluxDBOutNode_Write(t *testing.T) {
+	cli := &writeRecorder{batches: []influxdb.BatchPoints{}}
+	p := &pipeline.InfluxDBOutNode{
+		Measurement:   "cpu_1m",
+		Buffer:        3,
+		FlushInterval: time.Hour,
+		Writers:       2,
+		GzipFlag:      true,
+		Tags:          map[string]string{"downsampled": "true"},
+	}
+	n := newTestInfluxDBOutNode(p, cli)
+
+	tm := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		tags := models.Tags{"host": fmt.Sprintf("server%d", i)}
+		batch := edge.NewBufferedBatchMessage(
+			edge.NewBeginBatchMessage("cpu", tags, false, tm, 2),
+			[]edge.BatchPointMessage{
+				edge.NewBatchPointMessage(models.Fields{"mean": float64(i)}, tags, tm),
+				edge.NewBatchPointMessage(models.Fields{"mean": float64(i) + 0.5}, tags, tm.Add(time.Minute)),
+			},
+			edge.NewEndBatchMessage(),
+		)
+		if _, err := n.BufferedBatch(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, db := range []string{"db0", "db1"} {
+		point := edge.NewPointMessage("cpu", db, "autogen", models.Dimensions{}, models.Fields{"mean": 1.0}, models.Tags{"host": "serverA"}, tm)
+		if _, err := n.Point(point); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n.stopOut()
+
+	if got, exp := n.pointsWritten.IntValue(), int64(10); got != exp {
+		t.Errorf("unexpected points written got %d exp %d", got, exp)
+	}
+	written := make(map[string][]string)
+	for _, bp := range cli.batches {
+		if !bp.Gzip() {
+			t.Error("expected gzip to be set on the written batch")
+		}
+		if len(bp.Points()) > 4 {
+			t.Errorf("unexpected batch size %d, expected batches of the buffer size", len(bp.Points()))
+		}
+		for _, point := range bp.Points() {
+			written[bp.Database()] = append(written[bp.Database()], fmt.Sprintf("%s %s %s %v", point.Name, point.Tags["host"], point.Tags["downsampled"], point.Fields["mean"]))
+		}
+	}
+	exp := map[string][]string{
+		"": {
+			"cpu_1m server0 true 0", "cpu_1m server0 true 0.5",
+			"cpu_1m server1 true 1", "cpu_1m server1 true 1.5",
+			"cpu_1m server2 true 2", "cpu_1m server2 true 2.5",
+			"cpu_1m server3 true 3", "cpu_1m server3 true 3.5",
+		},
+		"db0": {"cpu_1m serverA true 1"},
+		"db1": {"cpu_1m serverA true 1"},
+	}
+	if len(written) != len(exp) {
+		t.Fatalf("unexpected databases got %v exp %v", written, exp)
+	}
+	for db, points := range exp {
+		got := written[db]
+		// Concurrent writers may write the batches of a database in any order.
+		sort.Strings(got)
+		if fmt.Sprint(got) != fmt.Sprint(points) {
+			t.Errorf("unexpected points for database %q\ngot %v\nexp %v", db, got, points)
+		}
+	}
+}
+
+// BenchmarkInfluxDBOutNode_Write measures the throughput of downsampled batches
+// written to a server that takes a millisecond per write.
+func BenchmarkInfluxDBOutNode_Write(b *testing.B) {
+	const pointsPerBatch = 100
+	tm := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+	batches := make([]edge.BufferedBatchMessage, 100)
+	for i := range batches {
+		tags := models.Tags{"host": fmt.Sprintf("server%02d", i)}
+		points := make([]edge.BatchPointMessage, pointsPerBatch)
+		for j := range points {
+			points[j] = edge.NewBatchPointMessage(models.Fields{"mean": float64(j)}, tags, tm.Add(time.Duration(j)*time.Minute))
+		}
+		batches[i] = edge.NewBufferedBatchMessage(
+			edge.NewBeginBatchMessage("cpu", tags, false, tm, pointsPerBatch),
+			points,
+			edge.NewEndBatchMessage(),
+		)
+	}
+	for _, writers := range []int64{1, 4} {
+		b.Run(fmt.Sprintf("writers=%d", writers), func(b *testing.B) {
+			cli := &writeRecorder{latency: time.Millisecond}
+			p := &pipeline.InfluxDBOutNode{
+				Database:      "db",
+				Buffer:        1000,
+				FlushInterval: time.Hour,
+				Writers:       writers,
+			}
+			n := newTestInfluxDBOutNode(p, cli)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := n.BufferedBatch(batches[i%len(batches)]); err != nil {
+					b.Fatal(err)
+				}
+			}
+			n.stopOut()
+			b.ReportMetric(float64(b.N*pointsPerBatch)/b.Elapsed().Seconds(), "points/s")
+		})
+	}
+}
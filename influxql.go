@@ -3,6 +3,7 @@ package kapacitor
 import (
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/influxdata/kapacitor/edge"
@@ -22,6 +23,8 @@ type InfluxQLNode struct {
 	createFn               createReduceContextFunc
 	isStreamTransformation bool
 
+	// mu guards the create function, which is shared by the shards of the node.
+	mu          sync.Mutex
 	currentKind reflect.Kind
 }
 
@@ -52,18 +55,15 @@ type baseReduceContext struct {
 }
 
 func (n *InfluxQLNode) runInfluxQL([]byte) error {
-	consumer := edge.NewGroupedConsumer(
-		n.ins[0],
-		n,
-	)
+	consumer := n.newGroupedConsumer(n.n.Shards, n.newShardGroup)
 	n.statMap.Set(statCardinalityGauge, consumer.CardinalityVar())
 	return consumer.Consume()
 }
 
-func (n *InfluxQLNode) NewGroup(group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error) {
+func (n *InfluxQLNode) newShardGroup(s nodeShard, group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error) {
 	return edge.NewReceiverFromForwardReceiverWithStats(
-		n.outs,
-		edge.NewTimedForwardReceiver(n.timer, n.newGroup(first)),
+		s.outs,
+		edge.NewTimedForwardReceiver(s.timer, n.newGroup(first)),
 	), nil
 }
 
@@ -277,6 +277,8 @@ func (g *influxqlStreamingTransformGroup) Barrier(b edge.BarrierMessage) (edge.M
 }
 
 func (n *InfluxQLNode) getCreateFn(kind reflect.Kind) (createReduceContextFunc, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	changed := n.currentKind != kind
 	if !changed && n.createFn != nil {
 		return n.createFn, nil
@@ -0,0 +1,321 @@
+package kapacitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/lua"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/pkg/errors"
+	glua "github.com/yuin/gopher-lua"
+)
+
+const (
+	statsInlineErrors = "inline_errors"
+)
+
+type InlineNode struct {
+	node
+	i      *pipeline.InlineNode
+	chunk  *lua.Chunk
+	state  *lua.State
+	errors *expvar.Int
+
+	batchBuffer *edge.BatchBuffer
+}
+
+// Create a new InlineNode which transforms the data with a Lua script.
+func newInlineNode(et *ExecutingTask, n *pipeline.InlineNode, d NodeDiagnostic) (*InlineNode, error) {
+	chunk, err := lua.Compile(n.Script)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid inline script")
+	}
+	in := &InlineNode{
+		node:        node{Node: n, et: et, diag: d},
+		i:           n,
+		chunk:       chunk,
+		state:       lua.NewState(),
+		batchBuffer: new(edge.BatchBuffer),
+	}
+	in.node.runF = in.runInline
+	return in, nil
+}
+
+func (n *InlineNode) runInline([]byte) error {
+	n.errors = &expvar.Int{}
+	n.statMap.Set(statsInlineErrors, n.errors)
+
+	consumer := edge.NewConsumerWithReceiver(
+		n.ins[0],
+		edge.NewReceiverFromForwardReceiverWithStats(
+			n.outs,
+			edge.NewTimedForwardReceiver(n.timer, n),
+		),
+	)
+	return consumer.Consume()
+}
+
+// run runs the script and reports whether the data is kept.
+func (n *InlineNode) run() (bool, error) {
+	results, err := n.state.Run(n.chunk, n.i.MaxSteps)
+	if err != nil {
+		return false, err
+	}
+	return len(results) == 0 || results[0] != glua.LFalse, nil
+}
+
+func (n *InlineNode) handleError(err error) {
+	n.errors.Add(1)
+	if !n.i.QuietFlag {
+		n.diag.Error("error running inline script", err)
+	}
+}
+
+func (n *InlineNode) BeginBatch(begin edge.BeginBatchMessage) (edge.Message, error) {
+	return nil, n.batchBuffer.BeginBatch(begin)
+}
+
+func (n *InlineNode) BatchPoint(bp edge.BatchPointMessage) (edge.Message, error) {
+	return nil, n.batchBuffer.BatchPoint(bp)
+}
+
+func (n *InlineNode) EndBatch(end edge.EndBatchMessage) (edge.Message, error) {
+	return n.BufferedBatch(n.batchBuffer.BufferedBatchMessage(end))
+}
+
+func (n *InlineNode) BufferedBatch(batch edge.BufferedBatchMessage) (edge.Message, error) {
+	b, err := n.transformBatch(batch)
+	if err != nil {
+		n.handleError(err)
+		// Skip bad batch
+		return nil, nil
+	}
+	return b, nil
+}
+
+func (n *InlineNode) transformBatch(batch edge.BufferedBatchMessage) (edge.Message, error) {
+	original := batch.Points()
+	points := n.state.NewTable()
+	for _, bp := range original {
+		p := n.state.NewTable()
+		p.RawSetString("time", timeToValue(bp.Time()))
+		p.RawSetString("tags", n.tagsToTable(bp.Tags()))
+		p.RawSetString("fields", n.fieldsToTable(bp.Fields()))
+		points.Append(p)
+	}
+	n.state.SetGlobal("name", glua.LString(batch.Name()))
+	n.state.SetGlobal("tags", n.tagsToTable(batch.Tags()))
+	n.state.SetGlobal("points", points)
+	keep, err := n.run()
+	if err != nil || !keep {
+		return nil, err
+	}
+
+	points, ok := n.state.Global("points").(*glua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("points must be a table, got %s", n.state.Global("points").Type())
+	}
+	bps := make([]edge.BatchPointMessage, points.Len())
+	for i := range bps {
+		p, ok := points.RawGetInt(i + 1).(*glua.LTable)
+		if !ok {
+			return nil, fmt.Errorf("point %d must be a table", i+1)
+		}
+		// Points at the same position keep the exact time and integer fields they had,
+		// unless the script changed them.
+		var orig edge.FieldsTagsTimeGetter
+		if i < len(original) {
+			orig = original[i]
+		}
+		t, err := valueToTime(p.RawGetString("time"), orig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "point %d", i+1)
+		}
+		tags, err := tableToTags(p.RawGetString("tags"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "point %d", i+1)
+		}
+		fields, err := tableToFields(p.RawGetString("fields"), orig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "point %d", i+1)
+		}
+		bps[i] = edge.NewBatchPointMessage(fields, tags, t)
+	}
+	batch = batch.ShallowCopy()
+	begin := batch.Begin().ShallowCopy()
+	begin.SetSizeHint(len(bps))
+	batch.SetBegin(begin)
+	batch.SetPoints(bps)
+	return batch, nil
+}
+
+func (n *InlineNode) Point(p edge.PointMessage) (edge.Message, error) {
+	p, err := n.transformPoint(p)
+	if err != nil {
+		n.handleError(err)
+		// Skip bad point
+		return nil, nil
+	}
+	return p, nil
+}
+
+func (n *InlineNode) transformPoint(p edge.PointMessage) (edge.PointMessage, error) {
+	n.state.SetGlobal("name", glua.LString(p.Name()))
+	n.state.SetGlobal("time", timeToValue(p.Time()))
+	n.state.SetGlobal("tags", n.tagsToTable(p.Tags()))
+	n.state.SetGlobal("fields", n.fieldsToTable(p.Fields()))
+	keep, err := n.run()
+	if err != nil || !keep {
+		return nil, err
+	}
+
+	t, err := valueToTime(n.state.Global("time"), p)
+	if err != nil {
+		return nil, err
+	}
+	newTags, err := tableToTags(n.state.Global("tags"))
+	if err != nil {
+		return nil, err
+	}
+	for _, dim := range p.Dimensions().TagNames {
+		if newTags[dim] != p.Tags()[dim] {
+			return nil, fmt.Errorf("cannot change tag %q the data is grouped by", dim)
+		}
+	}
+	newFields, err := tableToFields(n.state.Global("fields"), p)
+	if err != nil {
+		return nil, err
+	}
+	p = p.ShallowCopy()
+	p.SetTime(t)
+	p.SetTags(newTags)
+	p.SetFields(newFields)
+	return p, nil
+}
+
+func (n *InlineNode) Barrier(b edge.BarrierMessage) (edge.Message, error) {
+	return b, nil
+}
+func (n *InlineNode) DeleteGroup(d edge.DeleteGroupMessage) (edge.Message, error) {
+	return d, nil
+}
+func (n *InlineNode) Done() {}
+
+func (n *InlineNode) tagsToTable(tags models.Tags) *glua.LTable {
+	t := n.state.NewTable()
+	for k, v := range tags {
+		t.RawSetString(k, glua.LString(v))
+	}
+	return t
+}
+
+func (n *InlineNode) fieldsToTable(fields models.Fields) *glua.LTable {
+	t := n.state.NewTable()
+	for k, v := range fields {
+		switch v := v.(type) {
+		case int64:
+			t.RawSetString(k, glua.LNumber(v))
+		case float64:
+			t.RawSetString(k, glua.LNumber(v))
+		case string:
+			t.RawSetString(k, glua.LString(v))
+		case bool:
+			t.RawSetString(k, glua.LBool(v))
+		default:
+			t.RawSetString(k, glua.LString(fmt.Sprint(v)))
+		}
+	}
+	return t
+}
+
+// timeToValue converts the time to a number of nanoseconds.
+// Lua numbers are floats, so times are only exact to about a microsecond.
+func timeToValue(t time.Time) glua.LValue {
+	return glua.LNumber(t.UnixNano())
+}
+
+// valueToTime converts the number of nanoseconds to a time.
+// The exact original time is kept if the script did not change it.
+func valueToTime(v glua.LValue, orig edge.FieldsTagsTimeGetter) (time.Time, error) {
+	if v, ok := v.(glua.LNumber); ok {
+		if orig != nil && v == timeToValue(orig.Time()) {
+			return orig.Time(), nil
+		}
+		if ns := int64(v); glua.LNumber(ns) == v {
+			return time.Unix(0, ns).UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("time must be an integer number of nanoseconds, got %s", v)
+}
+
+func tableToTags(v glua.LValue) (models.Tags, error) {
+	t, ok := v.(*glua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("tags must be a table, got %s", v.Type())
+	}
+	tags := make(models.Tags)
+	var err error
+	t.ForEach(func(k, v glua.LValue) {
+		if err != nil {
+			return
+		}
+		name, ok := k.(glua.LString)
+		if !ok {
+			err = fmt.Errorf("tag names must be strings, got %s", k.Type())
+			return
+		}
+		switch v.(type) {
+		case glua.LString, glua.LNumber:
+			tags[string(name)] = v.String()
+		default:
+			err = fmt.Errorf("tag %q must be a string, got %s", name, v.Type())
+		}
+	})
+	return tags, err
+}
+
+// tableToFields converts the table to fields.
+// Numbers are floats unless the field was an integer and the number is integral.
+func tableToFields(v glua.LValue, orig edge.FieldsTagsTimeGetter) (models.Fields, error) {
+	t, ok := v.(*glua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("fields must be a table, got %s", v.Type())
+	}
+	var origFields models.Fields
+	if orig != nil {
+		origFields = orig.Fields()
+	}
+	fields := make(models.Fields)
+	var err error
+	t.ForEach(func(k, v glua.LValue) {
+		if err != nil {
+			return
+		}
+		name, ok := k.(glua.LString)
+		if !ok {
+			err = fmt.Errorf("field names must be strings, got %s", k.Type())
+			return
+		}
+		switch v := v.(type) {
+		case glua.LNumber:
+			if i, ok := origFields[string(name)].(int64); ok && glua.LNumber(i) == v {
+				// Keep the exact value of large integers.
+				fields[string(name)] = i
+			} else if ok && glua.LNumber(int64(v)) == v {
+				fields[string(name)] = int64(v)
+			} else {
+				fields[string(name)] = float64(v)
+			}
+		case glua.LString:
+			fields[string(name)] = string(v)
+		case glua.LBool:
+			fields[string(name)] = bool(v)
+		default:
+			err = fmt.Errorf("field %q has unsupported type %s", name, v.Type())
+		}
+	})
+	return fields, err
+}
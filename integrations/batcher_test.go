@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path"
+	"reflect"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -61,6 +63,88 @@ func TestBatch_InvalidQuery(t *testing.T) {
 	}
 }
 
+func TestBatch_File(t *testing.T) {
+	f, err := ioutil.TempFile("", "hosts.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("host,dc,cores\nserverA,east,8\nserverB,west,16\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	var script = fmt.Sprintf(`
+batch
+	|file('%s')
+		.every(10ms)
+		.measurement('hosts')
+		.tags('host', 'dc')
+		.groupBy('host')
+	|where(lambda: "cores" > 10)
+	|httpOut('TestBatch_File')
+`, f.Name())
+
+	d := diagService.NewKapacitorHandler()
+	tm := kapacitor.NewTaskMaster("TestBatch_File", newServerInfo(), d)
+	httpdService := newHTTPDService()
+	tm.HTTPDService = httpdService
+	tm.TaskStore = taskStore{}
+	tm.DeadmanService = deadman{}
+	tm.Open()
+	defer tm.Close()
+
+	task, err := tm.NewTask("TestBatch_File", script, kapacitor.BatchTask, dbrps, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	et, err := tm.StartTask(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := et.StartBatching(); err != nil {
+		t.Fatal(err)
+	}
+	// The route of the output is registered once the node is running.
+	endpoint := httpdService.URL() + "/tasks/TestBatch_File/TestBatch_File"
+
+	timeout := time.After(5 * time.Second)
+	for {
+		resp, err := http.Get(endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := models.Result{}
+		if resp.StatusCode == http.StatusOK {
+			err = json.NewDecoder(resp.Body).Decode(&result)
+		}
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range result.Series {
+			if len(row.Values) == 0 {
+				continue
+			}
+			if got, exp := row.Name, "hosts"; got != exp {
+				t.Errorf("unexpected name: got %s exp %s", got, exp)
+			}
+			if got, exp := row.Tags, map[string]string{"host": "serverB"}; !reflect.DeepEqual(got, exp) {
+				t.Errorf("unexpected tags: got %v exp %v", got, exp)
+			}
+			if got, exp := row.Columns, []string{"time", "cores", "dc"}; !reflect.DeepEqual(got, exp) {
+				t.Errorf("unexpected columns: got %v exp %v", got, exp)
+			}
+			return
+		}
+		select {
+		case <-timeout:
+			t.Fatal("timed out waiting for file batches")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
 //{"name":"packets","points":[
 // {"fields":{"value":"bad"},"time":"2015-10-18T00:00:00Z"},
 // {"fields":{"value":"good"},"time":"2015-10-18T00:00:02Z"},
@@ -136,6 +136,9 @@ type UDFService struct {
 	ListFunc   func() []string
 	InfoFunc   func(name string) (udf.Info, bool)
 	CreateFunc func(name, taskID, nodeID string, d udf.Diagnostic, abortCallback func()) (udf.Interface, error)
+
+	RestartPolicyFunc func(name string) udf.RestartPolicy
+	UpgradesFunc      func(name string) <-chan struct{}
 }
 
 func (u UDFService) List() []string {
@@ -150,6 +153,20 @@ func (u UDFService) Create(name, taskID, nodeID string, d udf.Diagnostic, abortC
 	return u.CreateFunc(name, taskID, nodeID, d, abortCallback)
 }
 
+func (u UDFService) RestartPolicy(name string) udf.RestartPolicy {
+	if u.RestartPolicyFunc == nil {
+		return udf.RestartPolicy{}
+	}
+	return u.RestartPolicyFunc(name)
+}
+
+func (u UDFService) Upgrades(name string) <-chan struct{} {
+	if u.UpgradesFunc == nil {
+		return nil
+	}
+	return u.UpgradesFunc(name)
+}
+
 type taskStore struct{}
 
 func (ts taskStore) SaveSnapshot(name string, snapshot *kapacitor.TaskSnapshot) error { return nil }
@@ -3,6 +3,7 @@ package integrations
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -15,6 +16,8 @@ import (
 	"path"
 	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"text/template"
@@ -2728,6 +2731,198 @@ stream
 	testStreamerWithOutput(t, "TestStream_Eval_Keep", script, 2*time.Second, er, false, nil)
 }
 
+func TestStream_Exec(t *testing.T) {
+	var script = `
+stream
+	|from()
+		.measurement('cpu')
+		.groupBy('host')
+	|exec('sed', '-u', 's/ value=/ usage=/')
+		.capture()
+		.timeout(5s)
+	|window()
+		.period(10s)
+		.every(10s)
+	|httpOut('TestStream_Exec')
+`
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "serverA"},
+				Columns: []string{"time", "usage"},
+				Values: [][]interface{}{
+					{time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC), 10.0},
+					{time.Date(1971, 1, 1, 0, 0, 2, 0, time.UTC), 30.0},
+				},
+			},
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "serverB"},
+				Columns: []string{"time", "usage"},
+				Values: [][]interface{}{
+					{time.Date(1971, 1, 1, 0, 0, 1, 0, time.UTC), 20.0},
+				},
+			},
+		},
+	}
+
+	testStreamerWithOutput(t, "TestStream_Exec", script, 15*time.Second, er, true, func(tm *kapacitor.TaskMaster) {
+		tm.Commander = command.ExecCommander
+	})
+}
+
+func TestStream_ExecTimeout(t *testing.T) {
+	// The command never replies and its child keeps its output open.
+	var script = `
+stream
+	|from()
+		.measurement('cpu')
+	|exec('sh', '-c', 'sleep 60; true')
+		.capture()
+		.timeout(100ms)
+	|httpOut('TestStream_ExecTimeout')
+`
+	clock, et, replayErr, tm := testStreamer(t, "TestStream_ExecTimeout", script, func(tm *kapacitor.TaskMaster) {
+		tm.Commander = command.ExecCommander
+	})
+	defer tm.Close()
+
+	start := time.Now()
+	err := fastForwardTask(clock, et, replayErr, tm, 5*time.Second)
+	if exp := "command did not reply within 100ms, stopping the node"; err == nil || !strings.Contains(err.Error(), exp) {
+		t.Errorf("unexpected error: got %v exp %s", err, exp)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("node took %v to stop", d)
+	}
+}
+
+func TestStream_Wasm(t *testing.T) {
+	module, err := ioutil.ReadFile("testdata/TestStream_Wasm.wasm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var script = fmt.Sprintf(`
+stream
+	|from()
+		.measurement('cpu')
+		.groupBy('host')
+	|wasm('%s', 'scale')
+		.fields('value')
+		.as('double')
+		.maxInstructions(100)
+	|window()
+		.period(10s)
+		.every(10s)
+	|httpOut('TestStream_Wasm')
+`, base64.StdEncoding.EncodeToString(module))
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "serverA"},
+				Columns: []string{"time", "double", "value"},
+				Values: [][]interface{}{
+					{time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC), 20.0, 10.0},
+					{time.Date(1971, 1, 1, 0, 0, 2, 0, time.UTC), 60.0, 30.0},
+				},
+			},
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "serverB"},
+				Columns: []string{"time", "double", "value"},
+				Values: [][]interface{}{
+					{time.Date(1971, 1, 1, 0, 0, 1, 0, time.UTC), 40.0, 20.0},
+				},
+			},
+		},
+	}
+
+	testStreamerWithOutput(t, "TestStream_Wasm", script, 15*time.Second, er, true, nil)
+}
+
+func TestStream_Onnx(t *testing.T) {
+	var script = `
+stream
+	|from()
+		.measurement('cpu')
+		.groupBy('host')
+	|onnx('testdata/TestStream_Onnx.onnx')
+		.fields('value')
+		.as('double', 'large')
+	|window()
+		.period(10s)
+		.every(10s)
+	|httpOut('TestStream_Onnx')
+`
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "serverA"},
+				Columns: []string{"time", "double", "large", "value"},
+				Values: [][]interface{}{
+					{time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC), 20.0, 0.0, 10.0},
+					{time.Date(1971, 1, 1, 0, 0, 2, 0, time.UTC), 60.0, 1.0, 30.0},
+				},
+			},
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "serverB"},
+				Columns: []string{"time", "double", "large", "value"},
+				Values: [][]interface{}{
+					{time.Date(1971, 1, 1, 0, 0, 1, 0, time.UTC), 40.0, 1.0, 20.0},
+				},
+			},
+		},
+	}
+
+	testStreamerWithOutput(t, "TestStream_Onnx", script, 15*time.Second, er, true, nil)
+}
+
+func TestStream_Inline(t *testing.T) {
+	var script = `
+stream
+	|from()
+		.measurement('cpu')
+		.groupBy('host')
+	|inline('''
+		count = (count or 0) + 1
+		fields.double = fields.value * 2
+		fields.count = count
+		tags.size = fields.value > 15 and 'large' or 'small'
+		return fields.value ~= 30
+	''')
+	|window()
+		.period(10s)
+		.every(10s)
+	|httpOut('TestStream_Inline')
+`
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "serverA"},
+				Columns: []string{"time", "count", "double", "size", "value"},
+				Values: [][]interface{}{
+					{time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC), 1.0, 20.0, "small", 10.0},
+				},
+			},
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "serverB"},
+				Columns: []string{"time", "count", "double", "size", "value"},
+				Values: [][]interface{}{
+					{time.Date(1971, 1, 1, 0, 0, 1, 0, time.UTC), 2.0, 40.0, "large", 20.0},
+				},
+			},
+		},
+	}
+
+	testStreamerWithOutput(t, "TestStream_Inline", script, 15*time.Second, er, true, nil)
+}
+
 func TestStream_Eval_Tags(t *testing.T) {
 	var script = `
 stream
@@ -2883,6 +3078,98 @@ stream
 	testStreamerWithOutput(t, "TestStream_EvalGroups", script, 3*time.Second, er, false, nil)
 }
 
+func TestStream_EvalGroups_Shards(t *testing.T) {
+	var script = `
+stream
+	|from()
+		.measurement('types')
+		.groupBy('group')
+	|eval(lambda: count())
+		.as('count')
+		.shards(2)
+	|httpOut('TestStream_EvalGroups')
+`
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "types",
+				Tags:    map[string]string{"group": "A"},
+				Columns: []string{"time", "count"},
+				Values: [][]interface{}{
+					{
+						time.Date(1971, 1, 1, 0, 0, 1, 0, time.UTC),
+						2.0,
+					},
+				},
+			},
+			{
+				Name:    "types",
+				Tags:    map[string]string{"group": "B"},
+				Columns: []string{"time", "count"},
+				Values: [][]interface{}{
+					{
+						time.Date(1971, 1, 1, 0, 0, 1, 0, time.UTC),
+						2.0,
+					},
+				},
+			},
+		},
+	}
+
+	// The groups are processed by different shards, so their order is not deterministic.
+	testStreamerWithOutput(t, "TestStream_EvalGroups", script, 3*time.Second, er, true, nil)
+}
+
+func TestStream_EvalGroups_OptimizedPipeline(t *testing.T) {
+	var script = `
+stream
+	|from()
+		.measurement('types')
+		.groupBy('group')
+	|eval(lambda: count())
+		.as('count')
+	|httpOut('TestStream_EvalGroups')
+
+stream
+	|from()
+		.measurement('types')
+		.groupBy('group')
+	|where(lambda: "group" == 'A')
+	|httpOut('A')
+`
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "types",
+				Tags:    map[string]string{"group": "A"},
+				Columns: []string{"time", "count"},
+				Values: [][]interface{}{
+					{
+						time.Date(1971, 1, 1, 0, 0, 1, 0, time.UTC),
+						2.0,
+					},
+				},
+			},
+			{
+				Name:    "types",
+				Tags:    map[string]string{"group": "B"},
+				Columns: []string{"time", "count"},
+				Values: [][]interface{}{
+					{
+						time.Date(1971, 1, 1, 0, 0, 1, 0, time.UTC),
+						2.0,
+					},
+				},
+			},
+		},
+	}
+
+	// The branches share a single from node.
+	testStreamerWithOutput(t, "TestStream_EvalGroups", script, 3*time.Second, er, false, func(tm *kapacitor.TaskMaster) {
+		tm.OptimizePipelines = true
+	})
+}
+
 func TestStream_Eval_Time(t *testing.T) {
 	var script = `
 stream
@@ -7132,6 +7419,244 @@ stream
 	<-done
 }
 
+// failingUDF passes the data through and fails after forwarding failAfter messages if failAfter is positive.
+type failingUDF struct {
+	in            chan edge.Message
+	out           chan edge.Message
+	failAfter     int
+	abortCallback func()
+	snapshot      []byte
+
+	mu        sync.Mutex
+	err       error
+	restored  []byte
+	abortOnce sync.Once
+	aborting  chan struct{}
+	done      chan struct{}
+}
+
+func newFailingUDF(failAfter int, abortCallback func()) *failingUDF {
+	return &failingUDF{
+		in:            make(chan edge.Message),
+		out:           make(chan edge.Message),
+		failAfter:     failAfter,
+		abortCallback: abortCallback,
+		aborting:      make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+func (u *failingUDF) Open() error {
+	go u.run()
+	return nil
+}
+
+func (u *failingUDF) run() {
+	defer close(u.done)
+	defer close(u.out)
+	count := 0
+	for {
+		select {
+		case m, ok := <-u.in:
+			if !ok {
+				return
+			}
+			select {
+			case u.out <- m:
+			case <-u.aborting:
+				return
+			}
+			count++
+			if count == u.failAfter {
+				u.Abort(fmt.Errorf("udf crashed after %d messages", count))
+				return
+			}
+		case <-u.aborting:
+			return
+		}
+	}
+}
+
+func (u *failingUDF) Abort(err error) {
+	u.abortOnce.Do(func() {
+		u.mu.Lock()
+		u.err = err
+		u.mu.Unlock()
+		close(u.aborting)
+		if u.abortCallback != nil {
+			u.abortCallback()
+		}
+	})
+}
+
+func (u *failingUDF) Close() error {
+	close(u.in)
+	<-u.done
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.err
+}
+
+func (u *failingUDF) Info() (udf.Info, error)            { return udf.Info{}, nil }
+func (u *failingUDF) Init(options []*agent.Option) error { return nil }
+func (u *failingUDF) In() chan<- edge.Message            { return u.in }
+func (u *failingUDF) Out() <-chan edge.Message           { return u.out }
+func (u *failingUDF) Snapshot() ([]byte, error)          { return u.snapshot, nil }
+func (u *failingUDF) Restore(snapshot []byte) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.restored = snapshot
+	return nil
+}
+
+func TestStream_UDFRestart(t *testing.T) {
+	var script = `
+stream
+	|from()
+		.measurement('cpu')
+		.groupBy('host')
+	@passthrough()
+	|window()
+		.period(10s)
+		.every(10s)
+	|httpOut('TestStream_UDFRestart')
+`
+	udfService := UDFService{}
+	udfService.ListFunc = func() []string {
+		return []string{"passthrough"}
+	}
+	udfService.InfoFunc = func(name string) (info udf.Info, ok bool) {
+		info.Wants = agent.EdgeType_STREAM
+		info.Provides = agent.EdgeType_STREAM
+		return info, name == "passthrough"
+	}
+	var creates int32
+	udfService.CreateFunc = func(name, taskID, nodeID string, d udf.Diagnostic, abortCallback func()) (udf.Interface, error) {
+		// The first UDF fails after the first point, the restarted one does not fail.
+		failAfter := 0
+		if atomic.AddInt32(&creates, 1) == 1 {
+			failAfter = 1
+		}
+		return newFailingUDF(failAfter, abortCallback), nil
+	}
+	udfService.RestartPolicyFunc = func(name string) udf.RestartPolicy {
+		return udf.RestartPolicy{Attempts: 1}
+	}
+
+	tmInit := func(tm *kapacitor.TaskMaster) {
+		tm.UDFService = udfService
+	}
+
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "serverA"},
+				Columns: []string{"time", "value"},
+				Values: [][]interface{}{
+					{time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC), 10.0},
+					{time.Date(1971, 1, 1, 0, 0, 2, 0, time.UTC), 30.0},
+				},
+			},
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "serverB"},
+				Columns: []string{"time", "value"},
+				Values: [][]interface{}{
+					{time.Date(1971, 1, 1, 0, 0, 1, 0, time.UTC), 20.0},
+				},
+			},
+		},
+	}
+
+	testStreamerWithOutput(t, "TestStream_UDFRestart", script, 15*time.Second, er, true, tmInit)
+	if got, exp := atomic.LoadInt32(&creates), int32(2); got != exp {
+		t.Errorf("unexpected number of UDFs created, got %d exp %d", got, exp)
+	}
+}
+
+func TestStream_UDFUpgrade(t *testing.T) {
+	var script = `
+stream
+	|from()
+		.measurement('cpu')
+		.groupBy('host')
+	@passthrough()
+	|window()
+		.period(10s)
+		.every(10s)
+	|httpOut('TestStream_UDFUpgrade')
+`
+	udfService := UDFService{}
+	udfService.ListFunc = func() []string {
+		return []string{"passthrough"}
+	}
+	udfService.InfoFunc = func(name string) (info udf.Info, ok bool) {
+		info.Wants = agent.EdgeType_STREAM
+		info.Provides = agent.EdgeType_STREAM
+		return info, name == "passthrough"
+	}
+	var mu sync.Mutex
+	var udfs []*failingUDF
+	udfService.CreateFunc = func(name, taskID, nodeID string, d udf.Diagnostic, abortCallback func()) (udf.Interface, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		u := newFailingUDF(0, abortCallback)
+		u.snapshot = []byte(fmt.Sprintf("udf%d", len(udfs)))
+		udfs = append(udfs, u)
+		return u, nil
+	}
+	// The UDF is upgraded once, before the task receives any data.
+	upgraded := make(chan struct{})
+	close(upgraded)
+	var upgrades int32
+	udfService.UpgradesFunc = func(name string) <-chan struct{} {
+		if atomic.AddInt32(&upgrades, 1) == 1 {
+			return upgraded
+		}
+		return make(chan struct{})
+	}
+
+	tmInit := func(tm *kapacitor.TaskMaster) {
+		tm.UDFService = udfService
+	}
+
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "serverA"},
+				Columns: []string{"time", "value"},
+				Values: [][]interface{}{
+					{time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC), 10.0},
+					{time.Date(1971, 1, 1, 0, 0, 2, 0, time.UTC), 30.0},
+				},
+			},
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "serverB"},
+				Columns: []string{"time", "value"},
+				Values: [][]interface{}{
+					{time.Date(1971, 1, 1, 0, 0, 1, 0, time.UTC), 20.0},
+				},
+			},
+		},
+	}
+
+	testStreamerWithOutput(t, "TestStream_UDFUpgrade", script, 15*time.Second, er, true, tmInit)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got, exp := len(udfs), 2; got != exp {
+		t.Fatalf("unexpected number of UDFs created, got %d exp %d", got, exp)
+	}
+	udfs[1].mu.Lock()
+	defer udfs[1].mu.Unlock()
+	if got, exp := string(udfs[1].restored), "udf0"; got != exp {
+		t.Errorf("unexpected snapshot restored by the upgraded UDF, got %q exp %q", got, exp)
+	}
+}
+
 func TestStream_Alert(t *testing.T) {
 	requestCount := int32(0)
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -11084,7 +11609,7 @@ stream
 		},
 	}
 	tmInit := func(tm *kapacitor.TaskMaster) {
-		tm.SideloadService = sideload.NewService(diagService.NewSideloadHandler())
+		tm.SideloadService = sideload.NewService(sideload.NewConfig(), diagService.NewSideloadHandler())
 	}
 
 	testStreamerWithOutput(t, "TestStream_Sideload", script, 1*time.Second, er, true, tmInit)
@@ -11156,7 +11681,7 @@ stream
 		},
 	}
 	tmInit := func(tm *kapacitor.TaskMaster) {
-		tm.SideloadService = sideload.NewService(diagService.NewSideloadHandler())
+		tm.SideloadService = sideload.NewService(sideload.NewConfig(), diagService.NewSideloadHandler())
 	}
 
 	testStreamerWithOutput(t, "TestStream_Sideload", script, 1*time.Second, er, true, tmInit)
@@ -11235,7 +11760,7 @@ stream
 		},
 	}
 	tmInit := func(tm *kapacitor.TaskMaster) {
-		tm.SideloadService = sideload.NewService(diagService.NewSideloadHandler())
+		tm.SideloadService = sideload.NewService(sideload.NewConfig(), diagService.NewSideloadHandler())
 	}
 
 	testStreamerWithOutput(t, "TestStream_Sideload", script, 1*time.Second, er, true, tmInit)
@@ -11740,6 +12265,7 @@ stream
 			"avg_exec_time_ns":    int64(0),
 			"errors":              int64(0),
 			"collected":           int64(90),
+			"memory_bytes":        int64(4276),
 		},
 	}
 
@@ -11821,6 +12347,7 @@ stream
 			"avg_exec_time_ns":    int64(0),
 			"errors":              int64(0),
 			"collected":           int64(90),
+			"memory_bytes":        int64(4276),
 		},
 		"max3": map[string]interface{}{
 			"emitted":             int64(0),
@@ -11946,6 +12473,7 @@ stream
 			"avg_exec_time_ns":    int64(0),
 			"errors":              int64(0),
 			"collected":           int64(90),
+			"memory_bytes":        int64(4266),
 		},
 		"groupby3": map[string]interface{}{
 			"emitted":             int64(0),
@@ -12158,6 +12686,7 @@ s2|join(s1)
 			"avg_exec_time_ns":    int64(0),
 			"errors":              int64(0),
 			"collected":           int64(180),
+			"memory_bytes":        int64(0),
 		},
 	}
 
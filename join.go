@@ -75,6 +75,7 @@ func (n *JoinNode) runJoin([]byte) error {
 		return int64(l)
 	}
 	n.statMap.Set(statCardinalityGauge, expvar.NewIntFuncGauge(valueF))
+	n.statMap.Set(statMemoryBytes, expvar.NewIntFuncGauge(n.memoryBytes))
 
 	return consumer.Consume()
 }
@@ -178,6 +179,7 @@ func (n *JoinNode) matchPoints(p srcPoint) {
 			}
 		}
 		// Remove all sent points.
+		n.releasePoints(buf[:i])
 		n.specificGroupsBuffer[groupId] = buf[i:]
 	}
 
@@ -208,6 +210,7 @@ func (n *JoinNode) matchPoints(p srcPoint) {
 		if n.allReported {
 			// Can't trust lowMark until all parents have reported.
 			// Remove any unneeded match points.
+			n.releasePoints(matches[:i])
 			n.matchGroupsBuffer[groupId] = matches[i:]
 		}
 
@@ -220,11 +223,13 @@ func (n *JoinNode) matchPoints(p srcPoint) {
 			} else {
 				// Option 2
 				// Cache this point for when its match arrives.
+				n.memory.add(messageSize(p.Msg))
 				n.specificGroupsBuffer[groupId] = append(n.specificGroupsBuffer[groupId], p)
 			}
 		}
 	} else {
 		// Cache match point.
+		n.memory.add(messageSize(p.Msg))
 		n.matchGroupsBuffer[groupId] = append(n.matchGroupsBuffer[groupId], p)
 
 		// Send all specific points that match, to the group.
@@ -240,10 +245,18 @@ func (n *JoinNode) matchPoints(p srcPoint) {
 			}
 		}
 		// Remove all sent points
+		n.releasePoints(buf[:i])
 		n.specificGroupsBuffer[groupId] = buf[i:]
 	}
 }
 
+// releasePoints releases the memory of points removed from the match or specific buffers.
+func (n *JoinNode) releasePoints(ps []srcPoint) {
+	for _, p := range ps {
+		n.memory.add(-messageSize(p.Msg))
+	}
+}
+
 // Add the specific tags from the specific point to the matched point
 // and then send both on to the group.
 func (n *JoinNode) sendMatchPoint(specific, matched srcPoint) {
@@ -372,6 +385,7 @@ func (g *joinGroup) emit(onlyReadySets bool) error {
 	for ; i < len(sets); i++ {
 		if sets[i].Ready() || !onlyReadySets {
 			err := g.emitJoinedSet(sets[i])
+			g.n.memory.add(-sets[i].bytes)
 			if err != nil {
 				return err
 			}
@@ -458,6 +472,8 @@ type joinset struct {
 	expected int
 	size     int
 	finished int
+	// Estimated bytes of memory of the values
+	bytes int64
 
 	first int
 
@@ -507,6 +523,9 @@ func (js *joinset) Set(i int, v edge.Message) {
 	}
 	js.values[i] = v
 	js.size++
+	size := messageSize(v)
+	js.bytes += size
+	js.j.memory.add(size)
 }
 
 // a valid point in the set
@@ -0,0 +1,139 @@
+package kapacitor
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Number of buckets of a latency histogram.
+// Bucket i counts durations in [2^(i-1), 2^i) microseconds, the last bucket counts all longer durations.
+const latencyBuckets = 32
+
+// latencyHistogram counts the durations a node spent processing messages in exponential buckets,
+// so that percentiles can be estimated without keeping every duration.
+type latencyHistogram struct {
+	counts [latencyBuckets]int64
+	max    int64
+}
+
+func latencyBucket(d time.Duration) int {
+	us := int64(d / time.Microsecond)
+	b := 0
+	for us > 0 && b < latencyBuckets-1 {
+		us >>= 1
+		b++
+	}
+	return b
+}
+
+// latencyBucketBounds returns the range of durations counted by a bucket.
+func latencyBucketBounds(b int) (lower, upper time.Duration) {
+	if b > 0 {
+		lower = time.Duration(int64(1)<<uint(b-1)) * time.Microsecond
+	}
+	upper = time.Duration(int64(1)<<uint(b)) * time.Microsecond
+	return
+}
+
+// Observe records the duration of processing a message.
+func (h *latencyHistogram) Observe(d time.Duration) {
+	atomic.AddInt64(&h.counts[latencyBucket(d)], 1)
+	for {
+		max := atomic.LoadInt64(&h.max)
+		if int64(d) <= max || atomic.CompareAndSwapInt64(&h.max, max, int64(d)) {
+			return
+		}
+	}
+}
+
+// LatencyStats summarizes the durations a node spent processing messages.
+// Only a sample of the messages is timed, see the timing-sample-rate of the stats service.
+type LatencyStats struct {
+	// Number of timed messages
+	Count int64
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+func (h *latencyHistogram) stats() LatencyStats {
+	var counts [latencyBuckets]int64
+	var s LatencyStats
+	for i := range counts {
+		counts[i] = atomic.LoadInt64(&h.counts[i])
+		s.Count += counts[i]
+	}
+	s.Max = time.Duration(atomic.LoadInt64(&h.max))
+	if s.Count == 0 {
+		return s
+	}
+	percentile := func(p float64) time.Duration {
+		rank := p * float64(s.Count)
+		var seen int64
+		for b, c := range counts {
+			if c == 0 || float64(seen+c) < rank {
+				seen += c
+				continue
+			}
+			// Interpolate within the bucket
+			lower, upper := latencyBucketBounds(b)
+			d := lower + time.Duration(float64(upper-lower)*(rank-float64(seen))/float64(c))
+			if d > s.Max {
+				d = s.Max
+			}
+			return d
+		}
+		return s.Max
+	}
+	s.P50 = percentile(0.5)
+	s.P90 = percentile(0.9)
+	s.P99 = percentile(0.99)
+	return s
+}
+
+// latencySetter sets the average execution time of a node
+// and records the duration of each timed message in its latency histogram.
+type latencySetter struct {
+	*MaxDuration
+	*latencyHistogram
+}
+
+// NodeExecutionStats are the statistics of a node for diagnosing slow pipelines.
+type NodeExecutionStats struct {
+	Collected int64
+	Emitted   int64
+	Errors    int64
+	// Number of messages received by the node it has not yet started processing
+	QueueDepth int64
+	// Number of points held by the node, such as the points of its windows
+	Buffered int64
+	// Estimated bytes of memory held by the node and the messages queued for it
+	Memory  int64
+	Latency LatencyStats
+}
+
+// NodeExecutionStats returns the statistics of each node of the task by node name.
+func (et *ExecutingTask) NodeExecutionStats() map[string]NodeExecutionStats {
+	stats := make(map[string]NodeExecutionStats, len(et.nodes))
+	for _, n := range et.nodes {
+		stats[n.Name()] = n.executionStats()
+	}
+	return stats
+}
+
+func (n *node) executionStats() NodeExecutionStats {
+	var queued int64
+	for _, in := range n.ins {
+		queued += in.Collected() - in.Emitted()
+	}
+	return NodeExecutionStats{
+		Collected:  n.collectedCount(),
+		Emitted:    n.emittedCount(),
+		Errors:     n.nodeErrors.IntValue(),
+		QueueDepth: queued,
+		Buffered:   n.buffered.buffered(),
+		Memory:     n.memoryBytes(),
+		Latency:    n.latency.stats(),
+	}
+}
@@ -0,0 +1,61 @@
+package kapacitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	h := &latencyHistogram{}
+	if got, exp := h.stats(), (LatencyStats{}); got != exp {
+		t.Fatalf("unexpected stats of empty histogram got %+v exp %+v", got, exp)
+	}
+
+	for i := 1; i <= 100; i++ {
+		h.Observe(time.Duration(i) * time.Millisecond)
+	}
+	s := h.stats()
+	if got, exp := s.Count, int64(100); got != exp {
+		t.Errorf("unexpected count got %d exp %d", got, exp)
+	}
+	if got, exp := s.Max, 100*time.Millisecond; got != exp {
+		t.Errorf("unexpected max got %v exp %v", got, exp)
+	}
+	// Percentiles are only accurate to the bucket containing them.
+	for _, tc := range []struct {
+		name string
+		got  time.Duration
+		exp  time.Duration
+	}{
+		{name: "p50", got: s.P50, exp: 50 * time.Millisecond},
+		{name: "p90", got: s.P90, exp: 90 * time.Millisecond},
+		{name: "p99", got: s.P99, exp: 99 * time.Millisecond},
+	} {
+		lower, upper := latencyBucketBounds(latencyBucket(tc.exp))
+		if tc.got < lower || tc.got >= upper {
+			t.Errorf("unexpected %s got %v exp between %v and %v", tc.name, tc.got, lower, upper)
+		}
+	}
+	if !(s.P50 <= s.P90 && s.P90 <= s.P99 && s.P99 <= s.Max) {
+		t.Errorf("percentiles are not ordered: %+v", s)
+	}
+}
+
+func TestLatencyBucket(t *testing.T) {
+	for _, d := range []time.Duration{
+		0,
+		500 * time.Nanosecond,
+		time.Microsecond,
+		3 * time.Microsecond,
+		time.Millisecond,
+		time.Second,
+	} {
+		lower, upper := latencyBucketBounds(latencyBucket(d))
+		if d < lower || d >= upper {
+			t.Errorf("duration %v not in bucket [%v, %v)", d, lower, upper)
+		}
+	}
+	if got, exp := latencyBucket(time.Hour), latencyBuckets-1; got != exp {
+		t.Errorf("unexpected bucket for long duration got %d exp %d", got, exp)
+	}
+}
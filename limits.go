@@ -0,0 +1,82 @@
+package kapacitor
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// TaskLimits bounds the resources used by a task, a zero value means no limit.
+type TaskLimits struct {
+	// Maximum number of points per second read by a stream task.
+	// Points above the limit are dropped.
+	MaxPointsPerSecond int64
+	// Maximum number of groups of any node of the task.
+	// The task fails if the limit is exceeded.
+	MaxGroups int64
+	// Maximum number of points buffered by the windows of the task.
+	// The task fails if the limit is exceeded.
+	MaxBufferedPoints int64
+}
+
+// bufferLimit counts the points buffered by all nodes of a task.
+// It is shared by the nodes, so it is safe for concurrent use.
+// Each node counts its own points with a bufferLimit whose parent is the limit of the task.
+type bufferLimit struct {
+	max    int64
+	count  int64
+	parent *bufferLimit
+}
+
+// add changes the number of buffered points by n,
+// returning an error if more than the maximum number of points are buffered.
+func (l *bufferLimit) add(n int) error {
+	if l == nil {
+		return nil
+	}
+	c := atomic.AddInt64(&l.count, int64(n))
+	if n > 0 && l.max > 0 && c > l.max {
+		return fmt.Errorf("task exceeded its limit of %d buffered points", l.max)
+	}
+	return l.parent.add(n)
+}
+
+func (l *bufferLimit) buffered() int64 {
+	return atomic.LoadInt64(&l.count)
+}
+
+// rateLimit limits the number of points per second.
+// It is not safe for concurrent use.
+type rateLimit struct {
+	max int64
+	now func() time.Time
+
+	second  time.Time
+	count   int64
+	dropped int64
+}
+
+func newRateLimit(max int64) *rateLimit {
+	return &rateLimit{
+		max: max,
+		now: time.Now,
+	}
+}
+
+// allow reports whether another point may be read in the current second.
+func (l *rateLimit) allow() bool {
+	if l.max <= 0 {
+		return true
+	}
+	now := l.now().Truncate(time.Second)
+	if !now.Equal(l.second) {
+		l.second = now
+		l.count = 0
+	}
+	if l.count >= l.max {
+		l.dropped++
+		return false
+	}
+	l.count++
+	return true
+}
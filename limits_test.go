@@ -0,0 +1,47 @@
+package kapacitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newRateLimit(2)
+	l.now = func() time.Time { return now }
+
+	for i, exp := range []bool{true, true, false, false} {
+		if got := l.allow(); got != exp {
+			t.Errorf("%d: unexpected allow got %v exp %v", i, got, exp)
+		}
+	}
+	if l.dropped != 2 {
+		t.Errorf("unexpected dropped got %d exp 2", l.dropped)
+	}
+
+	now = now.Add(time.Second)
+	if !l.allow() {
+		t.Error("expected point to be allowed in the next second")
+	}
+}
+
+func TestBufferLimit(t *testing.T) {
+	l := &bufferLimit{max: 2}
+	if err := l.add(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.add(1); err == nil {
+		t.Error("expected error when exceeding the limit")
+	}
+	if err := l.add(-2); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.buffered(); got != 1 {
+		t.Errorf("unexpected buffered got %d exp 1", got)
+	}
+
+	var nilLimit *bufferLimit
+	if err := nilLimit.add(10); err != nil {
+		t.Errorf("unexpected error from nil limit: %v", err)
+	}
+}
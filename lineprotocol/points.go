@@ -0,0 +1,382 @@
+// Package lineprotocol parses points written in the InfluxDB line protocol.
+//
+// Parsed points refer to the bytes they were parsed from instead of copying them,
+// and are kept in pooled structures that are reused by later writes,
+// so that parsing allocates only the values the caller keeps.
+package lineprotocol
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxStrings is the maximum number of distinct strings interned by a Points.
+const maxStrings = 10000
+
+// FieldType is the type of the value of a field.
+type FieldType int
+
+const (
+	Float FieldType = iota
+	Integer
+	String
+	Boolean
+)
+
+type Tag struct {
+	Key   []byte
+	Value []byte
+}
+
+// Field is a field of a point, only the value of its type is set.
+type Field struct {
+	Key     []byte
+	Type    FieldType
+	Float   float64
+	Integer int64
+	String  []byte
+	Boolean bool
+}
+
+type Point struct {
+	Name   []byte
+	Tags   []Tag
+	Fields []Field
+	Time   time.Time
+}
+
+// Points is a reusable set of parsed points.
+// It is not safe for concurrent use.
+type Points struct {
+	points []Point
+	n      int
+
+	// Unescaped names, keys and values, allocated with the capacity of
+	// the parsed bytes so that appending never moves them.
+	scratch []byte
+	// Interned strings, kept across parses
+	strings map[string]string
+}
+
+var pool = sync.Pool{
+	New: func() interface{} {
+		return &Points{strings: make(map[string]string)}
+	},
+}
+
+// Get returns an empty Points from the pool.
+func Get() *Points {
+	return pool.Get().(*Points)
+}
+
+// Put returns the Points to the pool, it must not be used afterwards.
+func Put(ps *Points) {
+	ps.Reset()
+	pool.Put(ps)
+}
+
+// Len returns the number of parsed points.
+func (ps *Points) Len() int {
+	return ps.n
+}
+
+// Point returns the point i, it is valid until the next Parse or Reset.
+func (ps *Points) Point(i int) *Point {
+	return &ps.points[i]
+}
+
+// Reset removes the points, releasing the bytes they refer to.
+func (ps *Points) Reset() {
+	for i := range ps.points[:ps.n] {
+		p := &ps.points[i]
+		p.Name = nil
+		for j := range p.Tags {
+			p.Tags[j] = Tag{}
+		}
+		p.Tags = p.Tags[:0]
+		for j := range p.Fields {
+			p.Fields[j] = Field{}
+		}
+		p.Fields = p.Fields[:0]
+	}
+	ps.n = 0
+	ps.scratch = ps.scratch[:0]
+}
+
+// String returns b as a string, reusing the strings of previous calls
+// so that repeated names, keys and values are allocated once.
+func (ps *Points) String(b []byte) string {
+	if s, ok := ps.strings[string(b)]; ok {
+		return s
+	}
+	s := string(b)
+	if len(ps.strings) >= maxStrings {
+		ps.strings = make(map[string]string)
+	}
+	ps.strings[s] = s
+	return s
+}
+
+// Parse parses the points of buf, replacing the previous points.
+// Points without a timestamp have the defaultTime, truncated to the precision.
+// The points refer to buf, so it must not be modified while they are used.
+//
+// Like the InfluxDB parser, all lines are parsed and the error lists each line that failed to parse,
+// the points of the other lines are kept.
+func (ps *Points) Parse(buf []byte, defaultTime time.Time, precision string) error {
+	ps.Reset()
+	if cap(ps.scratch) < len(buf) {
+		ps.scratch = make([]byte, 0, len(buf))
+	}
+	var (
+		pos    int
+		block  []byte
+		failed []string
+	)
+	for pos < len(buf) {
+		pos, block = scanLine(buf, pos)
+		pos++
+
+		if len(block) == 0 {
+			continue
+		}
+
+		start := skipWhitespace(block, 0)
+
+		// If line is all whitespace, just skip it
+		if start >= len(block) {
+			continue
+		}
+
+		// lines which start with '#' are comments
+		if block[start] == '#' {
+			continue
+		}
+
+		// strip the newline if one is present
+		if block[len(block)-1] == '\n' {
+			block = block[:len(block)-1]
+		}
+
+		if err := ps.parsePoint(block[start:], defaultTime, precision); err != nil {
+			failed = append(failed, fmt.Sprintf("unable to parse '%s': %v", string(block[start:]), err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// parsePoint parses a line into the next point.
+func (ps *Points) parsePoint(buf []byte, defaultTime time.Time, precision string) error {
+	pos, key, err := scanKey(buf, 0)
+	if err != nil {
+		return err
+	}
+	// measurement name is required
+	if len(key) == 0 {
+		return fmt.Errorf("missing measurement")
+	}
+	if len(key) > MaxKeyLength {
+		return fmt.Errorf("max key length exceeded: %v > %v", len(key), MaxKeyLength)
+	}
+
+	if ps.n == len(ps.points) {
+		ps.points = append(ps.points, Point{})
+	}
+	p := &ps.points[ps.n]
+	if err := ps.parseKey(p, key); err != nil {
+		p.Tags = p.Tags[:0]
+		return err
+	}
+
+	pos, fields, err := scanFields(buf, pos)
+	if err == nil && len(fields) == 0 {
+		// at least one field is required
+		err = fmt.Errorf("missing fields")
+	}
+	var ts []byte
+	if err == nil {
+		pos, ts, err = scanTime(buf, pos)
+	}
+	if err == nil {
+		err = ps.parseFields(p, fields)
+	}
+	if err != nil {
+		p.Tags = p.Tags[:0]
+		p.Fields = p.Fields[:0]
+		return err
+	}
+
+	if len(ts) == 0 {
+		p.Time = truncate(defaultTime, precision)
+	} else {
+		t, err := parseIntBytes(ts)
+		if err == nil {
+			p.Time, err = safeCalcTime(t, precision)
+		}
+		if err == nil {
+			// Determine if there are illegal non-whitespace characters after the timestamp block.
+			for ; pos < len(buf); pos++ {
+				if buf[pos] != ' ' {
+					err = ErrInvalidPoint
+					break
+				}
+			}
+		}
+		if err != nil {
+			p.Tags = p.Tags[:0]
+			p.Fields = p.Fields[:0]
+			return err
+		}
+	}
+	ps.n++
+	return nil
+}
+
+// parseKey sets the measurement and tags of the point from its validated key.
+func (ps *Points) parseKey(p *Point, key []byte) error {
+	i, name := scanTo(key, 0, ',')
+	p.Name = ps.unescape(name, `," =`)
+	p.Tags = p.Tags[:0]
+	for i++; i < len(key); i++ {
+		var k, v []byte
+		i, k = scanTo(key, i, '=')
+		i, v = scanTo(key, i+1, ',')
+		p.Tags = append(p.Tags, Tag{
+			Key:   ps.unescape(k, `, =`),
+			Value: ps.unescape(v, `, =`),
+		})
+	}
+	// Tags must be unique
+	for i := range p.Tags {
+		for j := i + 1; j < len(p.Tags); j++ {
+			if bytes.Equal(p.Tags[i].Key, p.Tags[j].Key) {
+				return fmt.Errorf("duplicate tags")
+			}
+		}
+	}
+	return nil
+}
+
+// parseFields sets the fields of the point from its validated fields.
+func (ps *Points) parseFields(p *Point, fields []byte) error {
+	p.Fields = p.Fields[:0]
+	for i := 0; i < len(fields); i++ {
+		var k, v []byte
+		i, k = scanTo(fields, i, '=')
+		i, v = scanFieldValue(fields, i+1)
+		if len(k) == 0 || len(v) == 0 {
+			continue
+		}
+		f := Field{Key: ps.unescape(k, `," =`)}
+		switch c := v[0]; {
+		case c == '"':
+			if len(v) < 2 {
+				return fmt.Errorf("unbalanced quotes")
+			}
+			f.Type = String
+			f.String = ps.unescape(v[1:len(v)-1], `"\`)
+		case strings.IndexByte(`0123456789-.nNiI`, c) >= 0:
+			var err error
+			if v[len(v)-1] == 'i' {
+				f.Type = Integer
+				f.Integer, err = parseIntBytes(v[:len(v)-1])
+			} else {
+				f.Type = Float
+				f.Float, err = parseFloatBytes(v)
+			}
+			if err != nil {
+				return ErrInvalidNumber
+			}
+		default:
+			f.Type = Boolean
+			f.Boolean = c == 't' || c == 'T'
+		}
+		p.Fields = append(p.Fields, f)
+	}
+	return nil
+}
+
+// unescape returns b without the backslashes before the escaped bytes.
+// b is returned as is if it has no backslash, otherwise it is unescaped into the scratch bytes.
+func (ps *Points) unescape(b []byte, escaped string) []byte {
+	if bytes.IndexByte(b, '\\') < 0 {
+		return b
+	}
+	start := len(ps.scratch)
+	ps.scratch = appendUnescaped(ps.scratch, b, escaped)
+	return ps.scratch[start:len(ps.scratch):len(ps.scratch)]
+}
+
+// Value returns the value of the field, copying string values.
+func (f *Field) Value() interface{} {
+	switch f.Type {
+	case Integer:
+		return f.Integer
+	case String:
+		return string(f.String)
+	case Boolean:
+		return f.Boolean
+	default:
+		return f.Float
+	}
+}
+
+func precisionMultiplier(precision string) int64 {
+	d := time.Nanosecond
+	switch precision {
+	case "u":
+		d = time.Microsecond
+	case "ms":
+		d = time.Millisecond
+	case "s":
+		d = time.Second
+	case "m":
+		d = time.Minute
+	case "h":
+		d = time.Hour
+	}
+	return int64(d)
+}
+
+func truncate(t time.Time, precision string) time.Time {
+	switch precision {
+	case "u":
+		return t.Truncate(time.Microsecond)
+	case "ms":
+		return t.Truncate(time.Millisecond)
+	case "s":
+		return t.Truncate(time.Second)
+	case "m":
+		return t.Truncate(time.Minute)
+	case "h":
+		return t.Truncate(time.Hour)
+	}
+	return t
+}
+
+const (
+	minNanoTime = int64(-1<<63) + 2
+	maxNanoTime = int64(1<<63-1) - 1
+)
+
+var errTimeOutOfRange = fmt.Errorf("time outside range %d - %d", minNanoTime, maxNanoTime)
+
+// safeCalcTime returns the time of the timestamp in the precision,
+// or an error if it is outside the range of nanosecond timestamps.
+func safeCalcTime(timestamp int64, precision string) (time.Time, error) {
+	mult := precisionMultiplier(precision)
+	ns := timestamp * mult
+	if mult != 1 && (timestamp == minNanoTime || ns/mult != timestamp) {
+		return time.Time{}, errTimeOutOfRange
+	}
+	if ns < minNanoTime || ns > maxNanoTime {
+		return time.Time{}, errTimeOutOfRange
+	}
+	return time.Unix(0, ns).UTC(), nil
+}
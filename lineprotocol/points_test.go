@@ -0,0 +1,206 @@
+package lineprotocol_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	imodels "github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/lineprotocol"
+)
+
+var lines = []string{
+	`cpu value=1`,
+	`cpu value=1 10`,
+	`cpu,host=serverA,region=east value=1.5,count=3i,state="ok",up=true 1000000000`,
+	`cpu,host=serverA value=-1.5e3,count=-3i,up=F,down=false,t=T`,
+	`cpu value=1e-3,big=9223372036854775807i`,
+	`cpu\,1,ho\ st=ser\=verA val\=ue=1`,
+	`c\ p\"u,host=a value=1`,
+	`cpu value="a \"quoted\" string with \\ and \n"`,
+	`cpu value="with spaces, commas=and equals"`,
+	`cpu    value=1    5`,
+	`  cpu value=1`,
+	`# comment`,
+	``,
+	`   `,
+	`cpu value=.5`,
+	`cpu value=5.`,
+	`cpu value=1 -1`,
+	`cpu value=1 9223372036854775806`,
+	`cpu,host=a,host=b value=1`,
+	`cpu value=1,value=2`,
+	// invalid lines
+	`cpu`,
+	`cpu value`,
+	`cpu value=`,
+	`cpu value="unbalanced`,
+	`cpu value=1 abc`,
+	`cpu value=1 10 11`,
+	`cpu value=1i2`,
+	`cpu value=1.2.3`,
+	`cpu value=tru`,
+	`cpu value=nan`,
+	`cpu,host value=1`,
+	`cpu,host= value=1`,
+	`cpu,=a value=1`,
+	`,host=a value=1`,
+	`cpu value=1 9223372036854775807`,
+	`cpu value=9223372036854775808i`,
+	`cpu value=1,`,
+	`cpu =1`,
+	`cpu value=--1`,
+}
+
+func TestParse(t *testing.T) {
+	ps := lineprotocol.Get()
+	defer lineprotocol.Put(ps)
+	now := time.Unix(0, 1234567891).UTC()
+	for _, precision := range []string{"", "n", "u", "ms", "s", "m", "h"} {
+		for _, line := range lines {
+			checkParse(t, ps, line, now, precision)
+		}
+	}
+}
+
+func TestParse_MultipleLines(t *testing.T) {
+	ps := lineprotocol.Get()
+	defer lineprotocol.Put(ps)
+	checkParse(t, ps, strings.Join(lines, "\n"), time.Unix(0, 1234567891).UTC(), "")
+	checkParse(t, ps, strings.Join(lines, "\r\n")+"\n", time.Unix(0, 1234567891).UTC(), "s")
+}
+
+func checkParse(t *testing.T, ps *lineprotocol.Points, line string, now time.Time, precision string) {
+	t.Helper()
+	exp, expErr := imodels.ParsePointsWithPrecision([]byte(line), now, precision)
+	gotErr := ps.Parse([]byte(line), now, precision)
+	if (gotErr == nil) != (expErr == nil) {
+		t.Errorf("%q precision %q: unexpected error got %v exp %v", line, precision, gotErr, expErr)
+		return
+	}
+	if gotErr != nil && gotErr.Error() != expErr.Error() {
+		t.Errorf("%q precision %q: unexpected error message\ngot %v\nexp %v", line, precision, gotErr, expErr)
+	}
+	if ps.Len() != len(exp) {
+		t.Errorf("%q precision %q: unexpected number of points got %d exp %d", line, precision, ps.Len(), len(exp))
+		return
+	}
+	for i, e := range exp {
+		p := ps.Point(i)
+		if got := string(p.Name); got != e.Name() {
+			t.Errorf("%q: unexpected name got %q exp %q", line, got, e.Name())
+		}
+		tags := make(map[string]string, len(p.Tags))
+		for _, tag := range p.Tags {
+			tags[string(tag.Key)] = string(tag.Value)
+		}
+		if exp := e.Tags().Map(); !reflect.DeepEqual(tags, exp) {
+			t.Errorf("%q: unexpected tags got %v exp %v", line, tags, exp)
+		}
+		fields := make(map[string]interface{}, len(p.Fields))
+		for j := range p.Fields {
+			fields[string(p.Fields[j].Key)] = p.Fields[j].Value()
+		}
+		if exp := map[string]interface{}(e.Fields()); !reflect.DeepEqual(fields, exp) {
+			t.Errorf("%q: unexpected fields got %v exp %v", line, fields, exp)
+		}
+		if !p.Time.Equal(e.Time()) {
+			t.Errorf("%q precision %q: unexpected time got %v exp %v", line, precision, p.Time, e.Time())
+		}
+	}
+}
+
+func TestParse_Reuse(t *testing.T) {
+	ps := lineprotocol.Get()
+	defer lineprotocol.Put(ps)
+	if err := ps.Parse([]byte("cpu,host=a\\ b value=1\nmem value=2"), time.Now(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.Parse([]byte("disk value=3"), time.Now(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if ps.Len() != 1 {
+		t.Fatalf("unexpected number of points got %d exp 1", ps.Len())
+	}
+	p := ps.Point(0)
+	if string(p.Name) != "disk" || len(p.Tags) != 0 || len(p.Fields) != 1 || p.Fields[0].Float != 3 {
+		t.Errorf("unexpected point %+v", p)
+	}
+}
+
+func TestPoints_String(t *testing.T) {
+	ps := lineprotocol.Get()
+	defer lineprotocol.Put(ps)
+	b := []byte("serverA")
+	s := ps.String(b)
+	b[0] = 'S'
+	if s != "serverA" {
+		t.Errorf("unexpected string got %q, expected a copy of the bytes", s)
+	}
+	if allocs := testing.AllocsPerRun(10, func() { ps.String([]byte("serverA")) }); allocs != 0 {
+		t.Errorf("unexpected allocations for interned string got %v exp 0", allocs)
+	}
+}
+
+// benchmarkLines returns a write of n points with a few series and fields.
+func benchmarkLines(n int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, "cpu,host=server%02d,region=us-west,cpu=cpu%d usage_user=%d.5,usage_idle=87.25,count=%di,state=\"ok\" %d\n", i%20, i%4, i, i, 1500000000000000000+int64(i))
+	}
+	return buf.Bytes()
+}
+
+const benchmarkPoints = 5000
+
+func BenchmarkParse(b *testing.B) {
+	buf := benchmarkLines(benchmarkPoints)
+	now := time.Now()
+	b.SetBytes(int64(len(buf)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ps := lineprotocol.Get()
+		if err := ps.Parse(buf, now, ""); err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < ps.Len(); j++ {
+			p := ps.Point(j)
+			ps.String(p.Name)
+			for _, tag := range p.Tags {
+				ps.String(tag.Key)
+				ps.String(tag.Value)
+			}
+			for k := range p.Fields {
+				ps.String(p.Fields[k].Key)
+				p.Fields[k].Value()
+			}
+		}
+		lineprotocol.Put(ps)
+	}
+	b.ReportMetric(float64(b.N*benchmarkPoints)/b.Elapsed().Seconds(), "points/s")
+}
+
+// BenchmarkParse_InfluxDB measures the parser of the InfluxDB models package, for comparison.
+func BenchmarkParse_InfluxDB(b *testing.B) {
+	buf := benchmarkLines(benchmarkPoints)
+	now := time.Now()
+	b.SetBytes(int64(len(buf)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		points, err := imodels.ParsePointsWithPrecision(buf, now, "")
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, p := range points {
+			p.Name()
+			p.Tags().Map()
+			p.Fields()
+		}
+	}
+	b.ReportMetric(float64(b.N*benchmarkPoints)/b.Elapsed().Seconds(), "points/s")
+}
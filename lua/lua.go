@@ -0,0 +1,220 @@
+// Package lua runs small user defined Lua scripts inside kapacitord.
+//
+// Scripts are run by gopher-lua, which implements Lua 5.1, in a sandbox:
+// only the base functions and the math, string and table libraries are available,
+// and the number of executed instructions, the depth of calls and the length of strings are limited.
+package lua
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	glua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+const (
+	// Maximum depth of nested function calls.
+	maxCallDepth = 200
+	// Maximum length of strings created by a script.
+	MaxStringLength = 1 << 20
+
+	chunkName = "script"
+)
+
+// ErrStepLimit is returned when a run executes more steps than allowed.
+// Once the limit is exceeded every following instruction fails, so the error cannot be caught by pcall.
+var ErrStepLimit = errors.New("lua: step limit exceeded")
+
+// Functions of the base library which are removed since they give access to the host.
+var unsafeBaseFuncs = []string{
+	"collectgarbage",
+	"dofile",
+	"loadfile",
+	"module",
+	"newproxy",
+	"print",
+	"require",
+	"_printregs",
+}
+
+// Chunk is a compiled script.
+type Chunk struct {
+	proto *glua.FunctionProto
+}
+
+// Compile parses and compiles the source of a script.
+func Compile(src string) (*Chunk, error) {
+	stmts, err := parse.Parse(strings.NewReader(src), chunkName)
+	if err != nil {
+		return nil, fmt.Errorf("syntax error: %v", err)
+	}
+	proto, err := glua.Compile(stmts, chunkName)
+	if err != nil {
+		return nil, fmt.Errorf("syntax error: %v", err)
+	}
+	return &Chunk{proto: proto}, nil
+}
+
+// State holds the global variables of the scripts run by it.
+// A State must not be used concurrently.
+type State struct {
+	l      *glua.LState
+	limits *limits
+}
+
+// NewState returns a state with the sandboxed standard library loaded.
+func NewState() *State {
+	l := glua.NewState(glua.Options{
+		CallStackSize: maxCallDepth,
+		SkipOpenLibs:  true,
+	})
+	for _, lib := range []struct {
+		name string
+		open glua.LGFunction
+	}{
+		{glua.BaseLibName, glua.OpenBase},
+		{glua.TabLibName, glua.OpenTable},
+		{glua.StringLibName, glua.OpenString},
+		{glua.MathLibName, glua.OpenMath},
+	} {
+		l.Push(l.NewFunction(lib.open))
+		l.Push(glua.LString(lib.name))
+		l.Call(1, 0)
+	}
+	for _, name := range unsafeBaseFuncs {
+		l.SetGlobal(name, glua.LNil)
+	}
+	s := &State{
+		l:      l,
+		limits: &limits{Context: context.Background(), l: l},
+	}
+	str := l.GetGlobal(glua.StringLibName).(*glua.LTable)
+	str.RawSetString("dump", glua.LNil)
+	str.RawSetString("rep", l.NewFunction(s.strRep))
+	l.SetContext(s.limits)
+	return s
+}
+
+// NewTable returns a new empty table.
+func (s *State) NewTable() *glua.LTable {
+	return s.l.NewTable()
+}
+
+// SetGlobal sets the global variable name.
+func (s *State) SetGlobal(name string, v glua.LValue) {
+	s.l.SetGlobal(name, v)
+}
+
+// Global returns the value of the global variable name.
+func (s *State) Global(name string) glua.LValue {
+	return s.l.GetGlobal(name)
+}
+
+// Run runs the chunk and returns its results.
+// If maxSteps is positive the run fails with ErrStepLimit once it executed more instructions.
+func (s *State) Run(c *Chunk, maxSteps int64) ([]glua.LValue, error) {
+	s.limits.reset(maxSteps)
+	s.l.SetTop(0)
+	defer s.l.SetTop(0)
+	err := s.l.CallByParam(glua.P{
+		Fn:      s.l.NewFunctionFromProto(c.proto),
+		NRet:    glua.MultRet,
+		Protect: true,
+	})
+	if s.limits.err != nil {
+		return nil, s.limits.err
+	}
+	if err != nil {
+		if apiErr, ok := err.(*glua.ApiError); ok {
+			// Drop the stack trace, the message has the line of the error.
+			return nil, errors.New("lua: " + apiErr.Object.String())
+		}
+		return nil, err
+	}
+	results := make([]glua.LValue, s.l.GetTop())
+	for i := range results {
+		results[i] = s.l.Get(i + 1)
+	}
+	return results, nil
+}
+
+// strRep implements string.rep, bounding the length of the result and charging a step per repetition.
+func (s *State) strRep(l *glua.LState) int {
+	str := l.CheckString(1)
+	n := l.CheckInt(2)
+	if n > MaxStringLength || n > 0 && len(str) > MaxStringLength/n {
+		l.RaiseError("string length exceeds %d bytes", MaxStringLength)
+		return 0
+	}
+	if n <= 0 || str == "" {
+		l.Push(glua.LString(""))
+		return 1
+	}
+	var b strings.Builder
+	b.Grow(len(str) * n)
+	for i := 0; i < n; i++ {
+		if !s.limits.step() {
+			l.RaiseError("%s", ErrStepLimit)
+			return 0
+		}
+		b.WriteString(str)
+	}
+	l.Push(glua.LString(b.String()))
+	return 1
+}
+
+// limits enforces the limits of a run.
+// It is the context of the state, gopher-lua consults its Done channel before executing each instruction.
+type limits struct {
+	context.Context
+	l *glua.LState
+
+	steps    int64
+	maxSteps int64
+	err      error
+}
+
+var closed = make(chan struct{})
+
+func init() {
+	close(closed)
+}
+
+func (c *limits) reset(maxSteps int64) {
+	c.steps = 0
+	c.maxSteps = maxSteps
+	c.err = nil
+}
+
+// step counts a step and reports whether the step limit still holds.
+func (c *limits) step() bool {
+	c.steps++
+	if c.maxSteps > 0 && c.steps > c.maxSteps {
+		c.err = ErrStepLimit
+		return false
+	}
+	return true
+}
+
+// Done returns a closed channel if the run must stop.
+// Strings are only created into registers,
+// so checking the registers of the current function bounds the length of every string before it is used.
+func (c *limits) Done() <-chan struct{} {
+	if c.err != nil || !c.step() {
+		return closed
+	}
+	for i := c.l.GetTop(); i > 0; i-- {
+		if s, ok := c.l.Get(i).(glua.LString); ok && len(s) > MaxStringLength {
+			c.err = fmt.Errorf("lua: string length exceeds %d bytes", MaxStringLength)
+			return closed
+		}
+	}
+	return nil
+}
+
+func (c *limits) Err() error {
+	return c.err
+}
@@ -0,0 +1,148 @@
+package lua_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/kapacitor/lua"
+	glua "github.com/yuin/gopher-lua"
+)
+
+func run(t *testing.T, src string) []glua.LValue {
+	t.Helper()
+	c, err := lua.Compile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := lua.NewState().Run(c, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestRun(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+		exp  []glua.LValue
+	}{
+		{
+			name: "arithmetic",
+			src:  `return 1 + 2 * 3, 7 % -3, 7 / 2, 2 ^ 10, 1 + 1.5`,
+			exp:  []glua.LValue{glua.LNumber(7), glua.LNumber(-2), glua.LNumber(3.5), glua.LNumber(1024), glua.LNumber(2.5)},
+		},
+		{
+			name: "comparison and logic",
+			src:  `return 1 < 2, 1 == 1.0, "a" < "b", nil or "x", false and 1, not nil, 2 >= 3`,
+			exp:  []glua.LValue{glua.LTrue, glua.LTrue, glua.LTrue, glua.LString("x"), glua.LFalse, glua.LTrue, glua.LFalse},
+		},
+		{
+			name: "generic for",
+			src: `
+local t = {10, 20}
+local sum = 0
+for _, v in ipairs(t) do sum = sum + v end
+return sum, #t`,
+			exp: []glua.LValue{glua.LNumber(30), glua.LNumber(2)},
+		},
+		{
+			name: "libraries",
+			src: `
+return math.floor(3.7), string.format("%5.2f|%d", 3.14159, 42), string.sub("hello", 2, -2),
+  ("abc"):upper(), string.rep("ab", 3), string.rep("ab", 0), unpack({1, 2})`,
+			exp: []glua.LValue{glua.LNumber(3), glua.LString(" 3.14|42"), glua.LString("ell"), glua.LString("ABC"),
+				glua.LString("ababab"), glua.LString(""), glua.LNumber(1), glua.LNumber(2)},
+		},
+		{
+			name: "sandbox",
+			src:  `return os, io, require, dofile, loadfile, print, string.dump`,
+			exp:  []glua.LValue{glua.LNil, glua.LNil, glua.LNil, glua.LNil, glua.LNil, glua.LNil, glua.LNil},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := run(t, tc.src); !reflect.DeepEqual(got, tc.exp) {
+				t.Errorf("unexpected results:\ngot %#v\nexp %#v", got, tc.exp)
+			}
+		})
+	}
+}
+
+func TestRun_Globals(t *testing.T) {
+	c, err := lua.Compile(`
+count = (count or 0) + 1
+fields.doubled = fields.value * 2
+fields.value = nil
+return count`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := lua.NewState()
+	for i := 1; i <= 3; i++ {
+		fields := s.NewTable()
+		fields.RawSetString("value", glua.LNumber(1.5))
+		s.SetGlobal("fields", fields)
+		results, err := s.Run(c, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, exp := results, []glua.LValue{glua.LNumber(i)}; !reflect.DeepEqual(got, exp) {
+			t.Errorf("unexpected results of run %d: got %v exp %v", i, got, exp)
+		}
+		var keys []string
+		fields.ForEach(func(k, _ glua.LValue) { keys = append(keys, k.String()) })
+		if got, exp := keys, []string{"doubled"}; !reflect.DeepEqual(got, exp) {
+			t.Errorf("unexpected fields of run %d: got %v exp %v", i, got, exp)
+		}
+		if got, exp := fields.RawGetString("doubled"), glua.LNumber(3); got != exp {
+			t.Errorf("unexpected doubled field of run %d: got %v exp %v", i, got, exp)
+		}
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	testCases := []struct {
+		src string
+		err string
+	}{
+		{src: "\nreturn 1 + {}", err: "lua: script:2: cannot perform add operation between number and table"},
+		{src: `error("custom")`, err: "lua: script:1: custom"},
+		{src: `local function f() return 1 + f() end return f()`, err: "lua: script:1: stack overflow"},
+		{src: `local s = "x" while true do s = s .. s end`, err: "lua: string length exceeds 1048576 bytes"},
+		{src: `local s = "x" pcall(function() while true do s = s .. s end end) return s`, err: "lua: string length exceeds 1048576 bytes"},
+		{src: `return string.rep("x", 1048577)`, err: "lua: script:1: string length exceeds 1048576 bytes"},
+		{src: `return string.rep("", 1e15)`, err: "lua: script:1: string length exceeds 1048576 bytes"},
+		{src: `return string.rep("abc", 400000)`, err: "lua: script:1: string length exceeds 1048576 bytes"},
+		{src: `return string.rep("x", 20000)`, err: lua.ErrStepLimit.Error()},
+		{src: `while true do end`, err: lua.ErrStepLimit.Error()},
+		{src: `pcall(function() while true do end end) return 1`, err: lua.ErrStepLimit.Error()},
+	}
+	for _, tc := range testCases {
+		c, err := lua.Compile(tc.src)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.src, err)
+		}
+		_, err = lua.NewState().Run(c, 10000)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+			t.Errorf("%s: unexpected error: got %v exp %s", tc.src, err, tc.err)
+		}
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	testCases := []string{
+		`x = `,
+		"if x then\nreturn 1",
+		`x = "abc`,
+		`return 1 return 2`,
+		`1 + 1`,
+	}
+	for _, src := range testCases {
+		_, err := lua.Compile(src)
+		if err == nil || !strings.HasPrefix(err.Error(), "syntax error: ") {
+			t.Errorf("%q: unexpected error: got %v", src, err)
+		}
+	}
+}
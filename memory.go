@@ -0,0 +1,142 @@
+package kapacitor
+
+import (
+	"sync/atomic"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/models"
+)
+
+const (
+	statMemoryBytes = "memory_bytes"
+
+	// Estimated overhead of a message, its header, time and maps.
+	messageOverhead = 128
+	// Estimated overhead of a tag or field in its map.
+	entryOverhead = 16
+	// Estimated size of a field value that is not a string.
+	valueSize = 16
+
+	// One of every sizeSampleEvery messages collected by an edge is measured.
+	sizeSampleEvery = 64
+)
+
+// memoryAccount counts the estimated bytes of memory held by a node, such as the points of its windows.
+// Each node counts its own bytes with an account whose parent is the account of the task.
+// It is safe for concurrent use.
+type memoryAccount struct {
+	bytes  int64
+	parent *memoryAccount
+}
+
+// add changes the number of bytes held by n.
+func (a *memoryAccount) add(n int64) {
+	if a == nil {
+		return
+	}
+	atomic.AddInt64(&a.bytes, n)
+	a.parent.add(n)
+}
+
+func (a *memoryAccount) used() int64 {
+	if a == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&a.bytes)
+}
+
+// sizeSampler estimates the average size of the messages collected by an edge
+// by measuring a sample of them. It is safe for concurrent use.
+type sizeSampler struct {
+	seen    int64
+	sampled int64
+	total   int64
+}
+
+func (s *sizeSampler) observe(m edge.Message) {
+	if atomic.AddInt64(&s.seen, 1)%sizeSampleEvery != 1 {
+		return
+	}
+	atomic.AddInt64(&s.total, messageSize(m))
+	atomic.AddInt64(&s.sampled, 1)
+}
+
+func (s *sizeSampler) average() int64 {
+	sampled := atomic.LoadInt64(&s.sampled)
+	if sampled == 0 {
+		return 0
+	}
+	return atomic.LoadInt64(&s.total) / sampled
+}
+
+// bufferMemory estimates the memory of a buffer of points from the average size of a sample of them,
+// so that the buffer need not measure every point it holds.
+// It is not safe for concurrent use.
+type bufferMemory struct {
+	account *memoryAccount
+	sizes   sizeSampler
+	bytes   int64
+}
+
+// observe samples the size of a point added to the buffer.
+func (b *bufferMemory) observe(m edge.Message) {
+	b.sizes.observe(m)
+}
+
+// set updates the memory of the buffer holding count points.
+func (b *bufferMemory) set(count int) {
+	bytes := int64(count) * b.sizes.average()
+	b.account.add(bytes - b.bytes)
+	b.bytes = bytes
+}
+
+// messageSize estimates the bytes of memory held by a message.
+func messageSize(m edge.Message) int64 {
+	switch m := m.(type) {
+	case edge.PointMessage:
+		return messageOverhead +
+			int64(len(m.Name())+len(m.Database())+len(m.RetentionPolicy())) +
+			tagsSize(m.Tags()) +
+			fieldsSize(m.Fields())
+	case edge.BatchPointMessage:
+		return messageOverhead + tagsSize(m.Tags()) + fieldsSize(m.Fields())
+	case edge.BufferedBatchMessage:
+		size := messageOverhead + int64(len(m.Name())) + tagsSize(m.Tags())
+		for _, p := range m.Points() {
+			size += messageSize(p)
+		}
+		return size
+	default:
+		return messageOverhead
+	}
+}
+
+func tagsSize(tags models.Tags) int64 {
+	var size int64
+	for k, v := range tags {
+		size += entryOverhead + int64(len(k)+len(v))
+	}
+	return size
+}
+
+func fieldsSize(fields models.Fields) int64 {
+	var size int64
+	for k, v := range fields {
+		size += entryOverhead + int64(len(k))
+		if s, ok := v.(string); ok {
+			size += int64(len(s))
+		} else {
+			size += valueSize
+		}
+	}
+	return size
+}
+
+// queuedBytes estimates the bytes of the messages queued on an edge.
+func queuedBytes(e edge.StatsEdge) int64 {
+	ke, ok := e.(*Edge)
+	if !ok {
+		return 0
+	}
+	return (e.Collected() - e.Emitted()) * ke.sizes.average()
+}
@@ -0,0 +1,104 @@
+package kapacitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/models"
+)
+
+func TestMessageSize(t *testing.T) {
+	p := edge.NewPointMessage(
+		"cpu", "db", "rp",
+		models.Dimensions{},
+		models.Fields{"value": 1.0, "host": "serverA"},
+		models.Tags{"dc": "east"},
+		time.Unix(0, 0),
+	)
+	// overhead + name, db, rp + tag + float field + string field
+	exp := int64(messageOverhead + 7 + (entryOverhead + 6) + (entryOverhead + 5 + valueSize) + (entryOverhead + 4 + 7))
+	if got := messageSize(p); got != exp {
+		t.Errorf("unexpected point size got %d exp %d", got, exp)
+	}
+
+	b := edge.NewBufferedBatchMessage(
+		edge.NewBeginBatchMessage("cpu", models.Tags{}, false, time.Unix(0, 0), 2),
+		[]edge.BatchPointMessage{
+			edge.BatchPointFromPoint(p),
+			edge.BatchPointFromPoint(p),
+		},
+		edge.NewEndBatchMessage(),
+	)
+	bp := messageSize(edge.BatchPointFromPoint(p))
+	if got, exp := messageSize(b), messageOverhead+3+2*bp; got != exp {
+		t.Errorf("unexpected batch size got %d exp %d", got, exp)
+	}
+}
+
+func TestMemoryAccount(t *testing.T) {
+	task := &memoryAccount{}
+	a := &memoryAccount{parent: task}
+	b := &memoryAccount{parent: task}
+	a.add(100)
+	b.add(50)
+	a.add(-30)
+	if got := a.used(); got != 70 {
+		t.Errorf("unexpected node bytes got %d exp 70", got)
+	}
+	if got := task.used(); got != 120 {
+		t.Errorf("unexpected task bytes got %d exp 120", got)
+	}
+
+	var nilAccount *memoryAccount
+	nilAccount.add(10)
+	if got := nilAccount.used(); got != 0 {
+		t.Errorf("unexpected bytes of nil account got %d exp 0", got)
+	}
+}
+
+func TestBufferMemory(t *testing.T) {
+	p := edge.NewPointMessage("cpu", "", "", models.Dimensions{}, models.Fields{"value": 1.0}, nil, time.Unix(0, 0))
+	size := messageSize(p)
+
+	task := &memoryAccount{}
+	m := &bufferMemory{account: task}
+	for i := 1; i <= 10; i++ {
+		m.observe(p)
+		m.set(i)
+	}
+	if got, exp := task.used(), 10*size; got != exp {
+		t.Errorf("unexpected bytes got %d exp %d", got, exp)
+	}
+	m.set(4)
+	if got, exp := task.used(), 4*size; got != exp {
+		t.Errorf("unexpected bytes after purge got %d exp %d", got, exp)
+	}
+	m.set(0)
+	if got := task.used(); got != 0 {
+		t.Errorf("unexpected bytes after delete got %d exp 0", got)
+	}
+}
+
+func TestWindowByCount_Memory(t *testing.T) {
+	task := &memoryAccount{}
+	w := newWindowByCount("test", edge.GroupInfo{}, 3, 1, false, nil, task, newWindowNodeDiagnostic())
+	var size int64
+	for i := 0; i < 5; i++ {
+		p := edge.NewPointMessage("test", "", "", models.Dimensions{}, models.Fields{"value": float64(i)}, nil, time.Unix(int64(i), 0))
+		size = messageSize(edge.BatchPointFromPoint(p))
+		if _, err := w.Point(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The window holds at most its period of points.
+	if got, exp := task.used(), 3*size; got != exp {
+		t.Errorf("unexpected bytes got %d exp %d", got, exp)
+	}
+	if _, err := w.DeleteGroup(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := task.used(); got != 0 {
+		t.Errorf("unexpected bytes after delete got %d exp 0", got)
+	}
+}
@@ -2,9 +2,11 @@ package kapacitor
 
 import (
 	"bytes"
+	"context"
 	"expvar"
 	"fmt"
 	"runtime"
+	"runtime/pprof"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -42,6 +44,10 @@ type NodeDiagnostic interface {
 	LogPointData(key, prefix string, data edge.PointMessage)
 	LogBatchData(key, prefix string, data edge.BufferedBatchMessage)
 
+	// StreamNode
+	PointsThrottled(limit, dropped int64)
+	PointsCardinalityLimited(dropped int64)
+
 	//UDF
 	UDFLog(s string)
 }
@@ -93,8 +99,10 @@ type Node interface {
 	// abort parent edges
 	abortParentEdges()
 
-	// executing dot
-	edot(buf *bytes.Buffer, labels bool)
+	// executing dot, edges are annotated with their rates if rates is not nil
+	edot(buf *bytes.Buffer, labels bool, rates edgeRates)
+	// number of messages collected by the edge to each child by child name
+	childCounts() map[string]int64
 
 	nodeStatsByGroup() map[models.GroupID]nodeStats
 
@@ -105,6 +113,13 @@ type Node interface {
 	incrementErrorCount()
 
 	stats() map[string]interface{}
+
+	executionStats() NodeExecutionStats
+
+	// estimated bytes of memory held by the node and the messages queued for it
+	memoryBytes() int64
+
+	tap(count int) (*Tap, error)
 }
 
 //implementation of Node
@@ -129,6 +144,27 @@ type node struct {
 	quiet bool
 
 	nodeErrors *kexpvar.Int
+	latency    *latencyHistogram
+	// Points buffered by the node, counted towards the buffer limit of the task.
+	buffered *bufferLimit
+	// Memory held by the node, counted towards the memory of the task.
+	memory *memoryAccount
+	// Setter of the timer of the node, shared by the timers of its shards.
+	timerSetter latencySetter
+}
+
+// MaxGroups returns the limit of the number of groups of the task.
+func (n *node) MaxGroups() int64 {
+	return n.et.Task.Limits.MaxGroups
+}
+
+// memoryBytes estimates the memory held by the node and the messages queued for it.
+func (n *node) memoryBytes() int64 {
+	bytes := n.memory.used()
+	for _, in := range n.ins {
+		bytes += queuedBytes(in)
+	}
+	return bytes
 }
 
 func (n *node) addParentEdge(e edge.StatsEdge) {
@@ -148,6 +184,11 @@ func (n *node) init(quiet bool) {
 		"type": n.et.Task.Type.String(),
 		"kind": n.Desc(),
 	}
+	for k, v := range n.et.Task.Labels {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
 	n.statsKey, n.statMap = vars.NewStatistic("nodes", tags)
 	avgExecVar := &MaxDuration{}
 	n.statMap.Set(statAverageExecTime, avgExecVar)
@@ -155,7 +196,14 @@ func (n *node) init(quiet bool) {
 	n.statMap.Set(statErrorCount, n.nodeErrors)
 	n.diag = newNodeDiagnostic(n, n.diag)
 	n.statMap.Set(statCardinalityGauge, kexpvar.NewIntFuncGauge(nil))
-	n.timer = n.et.tm.TimingService.NewTimer(avgExecVar)
+	n.latency = &latencyHistogram{}
+	n.buffered = &bufferLimit{parent: n.et.buffered}
+	n.memory = &memoryAccount{parent: n.et.memory}
+	n.timerSetter = latencySetter{
+		MaxDuration:      avgExecVar,
+		latencyHistogram: n.latency,
+	}
+	n.timer = n.et.tm.TimingService.NewTimer(n.timerSetter)
 	n.errCh = make(chan error, 1)
 	n.quiet = quiet
 }
@@ -182,8 +230,11 @@ func (n *node) start(snapshot []byte) {
 			}
 			n.errCh <- err
 		}()
-		// Run node
-		err = n.runF(snapshot)
+		// Run node, labeled so that its CPU can be profiled.
+		// The goroutines the node starts have the same labels.
+		pprof.Do(context.Background(), n.profileLabels(), func(context.Context) {
+			err = n.runF(snapshot)
+		})
 	}()
 }
 
@@ -253,7 +304,7 @@ func (n *node) closeChildEdges() {
 	}
 }
 
-func (n *node) edot(buf *bytes.Buffer, labels bool) {
+func (n *node) edot(buf *bytes.Buffer, labels bool, rates edgeRates) {
 	if labels {
 		// Print all stats on node.
 		buf.WriteString(
@@ -284,6 +335,18 @@ func (n *node) edot(buf *bytes.Buffer, labels bool) {
 		buf.Write([]byte("\"];\n"))
 
 		for i, c := range n.children {
+			if rates != nil {
+				buf.WriteString(
+					fmt.Sprintf("%s -> %s [label=\"processed=%d rate=%0.2f/s errors=%d\"];\n",
+						n.Name(),
+						c.Name(),
+						n.outs[i].Collected(),
+						rates[n.Name()][c.Name()],
+						c.executionStats().Errors,
+					),
+				)
+				continue
+			}
 			buf.Write([]byte(
 				fmt.Sprintf("%s -> %s [label=\"processed=%d\"];\n",
 					n.Name(),
@@ -316,6 +379,18 @@ func (n *node) edot(buf *bytes.Buffer, labels bool) {
 		})
 		buf.Write([]byte("];\n"))
 		for i, c := range n.children {
+			if rates != nil {
+				buf.WriteString(
+					fmt.Sprintf("%s -> %s [processed=\"%d\" rate=\"%0.2f\" errors=\"%d\"];\n",
+						n.Name(),
+						c.Name(),
+						n.outs[i].Collected(),
+						rates[n.Name()][c.Name()],
+						c.executionStats().Errors,
+					),
+				)
+				continue
+			}
 			buf.Write([]byte(
 				fmt.Sprintf("%s -> %s [processed=\"%d\"];\n",
 					n.Name(),
@@ -327,6 +402,14 @@ func (n *node) edot(buf *bytes.Buffer, labels bool) {
 	}
 }
 
+func (n *node) childCounts() map[string]int64 {
+	counts := make(map[string]int64, len(n.children))
+	for i, c := range n.children {
+		counts[c.Name()] = n.outs[i].Collected()
+	}
+	return counts
+}
+
 // node collected count is the sum of emitted counts of parent edges
 func (n *node) collectedCount() (count int64) {
 	for _, in := range n.ins {
@@ -0,0 +1,241 @@
+package kapacitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"text/template"
+	"time"
+
+	imodels "github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/objectstore"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/pkg/errors"
+)
+
+const (
+	statsObjectsWritten = "objects_written"
+	statsWriteErrors    = "write_errors"
+)
+
+type ObjectStoreOutNode struct {
+	node
+	c   *pipeline.ObjectStoreOutNode
+	url *template.Template
+
+	objectsWritten *expvar.Int
+	writeErrors    *expvar.Int
+}
+
+// Create a new ObjectStoreOutNode which writes each batch as an object.
+func newObjectStoreOutNode(et *ExecutingTask, n *pipeline.ObjectStoreOutNode, d NodeDiagnostic) (*ObjectStoreOutNode, error) {
+	url, err := template.New("url").Parse(n.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid object url template")
+	}
+	on := &ObjectStoreOutNode{
+		node: node{Node: n, et: et, diag: d},
+		c:    n,
+		url:  url,
+	}
+	on.node.runF = on.runOut
+	return on, nil
+}
+
+func (n *ObjectStoreOutNode) runOut([]byte) error {
+	n.objectsWritten = &expvar.Int{}
+	n.writeErrors = &expvar.Int{}
+	n.statMap.Set(statsObjectsWritten, n.objectsWritten)
+	n.statMap.Set(statsWriteErrors, n.writeErrors)
+
+	consumer := edge.NewGroupedConsumer(
+		n.ins[0],
+		n,
+	)
+	n.statMap.Set(statCardinalityGauge, consumer.CardinalityVar())
+
+	return consumer.Consume()
+}
+
+func (n *ObjectStoreOutNode) NewGroup(group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error) {
+	g := &objectStoreOutGroup{
+		n:      n,
+		buffer: new(edge.BatchBuffer),
+	}
+	return edge.NewReceiverFromForwardReceiverWithStats(
+		n.outs,
+		edge.NewTimedForwardReceiver(n.timer, g),
+	), nil
+}
+
+type objectStoreOutGroup struct {
+	n      *ObjectStoreOutNode
+	buffer *edge.BatchBuffer
+}
+
+func (g *objectStoreOutGroup) BeginBatch(begin edge.BeginBatchMessage) (edge.Message, error) {
+	return nil, g.buffer.BeginBatch(begin)
+}
+
+func (g *objectStoreOutGroup) BatchPoint(bp edge.BatchPointMessage) (edge.Message, error) {
+	return nil, g.buffer.BatchPoint(bp)
+}
+
+func (g *objectStoreOutGroup) EndBatch(end edge.EndBatchMessage) (edge.Message, error) {
+	return g.BufferedBatch(g.buffer.BufferedBatchMessage(end))
+}
+
+func (g *objectStoreOutGroup) BufferedBatch(batch edge.BufferedBatchMessage) (edge.Message, error) {
+	if len(batch.Points()) > 0 {
+		if err := g.n.write(batch); err != nil {
+			g.n.writeErrors.Add(1)
+			g.n.diag.Error("failed to write object", err)
+		} else {
+			g.n.objectsWritten.Add(1)
+		}
+	}
+	return batch, nil
+}
+
+func (g *objectStoreOutGroup) Point(p edge.PointMessage) (edge.Message, error) {
+	return p, nil
+}
+
+func (g *objectStoreOutGroup) Barrier(b edge.BarrierMessage) (edge.Message, error) {
+	return b, nil
+}
+func (g *objectStoreOutGroup) DeleteGroup(d edge.DeleteGroupMessage) (edge.Message, error) {
+	return d, nil
+}
+func (g *objectStoreOutGroup) Done() {}
+
+// objectURLData is the data available to the object URL template.
+type objectURLData struct {
+	TaskName string
+	Name     string
+	Group    string
+	Tags     map[string]string
+	Time     time.Time
+}
+
+func (n *ObjectStoreOutNode) write(batch edge.BufferedBatchMessage) error {
+	var url bytes.Buffer
+	err := n.url.Execute(&url, objectURLData{
+		TaskName: n.et.Task.ID,
+		Name:     batch.Name(),
+		Group:    string(batch.GroupID()),
+		Tags:     batch.Tags(),
+		Time:     batch.Time(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to execute url template")
+	}
+
+	var data []byte
+	var contentType string
+	switch n.c.Format {
+	case pipeline.ObjectFormatCSV:
+		data, err = batchToCSV(batch)
+		contentType = "text/csv"
+	default:
+		data, err = batchToLineProtocol(batch)
+		contentType = "text/plain; charset=utf-8"
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if n.c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.c.Timeout)
+		defer cancel()
+	}
+	if err := objectstore.Put(ctx, url.String(), data, contentType); err != nil {
+		return errors.Wrapf(err, "failed to write object %s", url.String())
+	}
+	return nil
+}
+
+// batchToLineProtocol encodes each point of the batch as a line.
+func batchToLineProtocol(batch edge.BufferedBatchMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, bp := range batch.Points() {
+		p, err := imodels.NewPoint(batch.Name(), imodels.NewTags(bp.Tags()), imodels.Fields(bp.Fields()), bp.Time())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to encode point")
+		}
+		buf.WriteString(p.String())
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// batchToCSV encodes the batch with a header row of the time, tag and field columns.
+func batchToCSV(batch edge.BufferedBatchMessage) ([]byte, error) {
+	tagSet := make(map[string]bool)
+	fieldSet := make(map[string]bool)
+	for _, bp := range batch.Points() {
+		for k := range bp.Tags() {
+			tagSet[k] = true
+		}
+		for k := range bp.Fields() {
+			fieldSet[k] = true
+		}
+	}
+	tags := sortedKeys(tagSet)
+	fields := sortedKeys(fieldSet)
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := append(append([]string{"time"}, tags...), fields...)
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	row := make([]string, len(header))
+	for _, bp := range batch.Points() {
+		row[0] = bp.Time().UTC().Format(time.RFC3339Nano)
+		for i, k := range tags {
+			row[1+i] = bp.Tags()[k]
+		}
+		for i, k := range fields {
+			row[1+len(tags)+i] = csvValue(bp.Fields()[k])
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,71 @@
+package kapacitor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
+)
+
+func TestObjectStoreOutNode_Write(t *testing.T) {
+	dir, err := ioutil.TempDir("", "object_store_out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tm := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+	tags := models.Tags{"host": "serverA"}
+	batch := edge.NewBufferedBatchMessage(
+		edge.NewBeginBatchMessage("cpu", tags, false, tm, 2),
+		[]edge.BatchPointMessage{
+			edge.NewBatchPointMessage(models.Fields{"mean": 91.5, "count": int64(6)}, models.Tags{"host": "serverA", "cpu": "cpu0"}, tm.Add(-time.Minute)),
+			edge.NewBatchPointMessage(models.Fields{"mean": 88.0}, models.Tags{"host": "serverA"}, tm),
+		},
+		edge.NewEndBatchMessage(),
+	)
+
+	testCases := []struct {
+		format string
+		exp    string
+	}{
+		{
+			format: pipeline.ObjectFormatCSV,
+			exp: `time,cpu,host,count,mean
+2018-03-01T09:59:00Z,cpu0,serverA,6,91.5
+2018-03-01T10:00:00Z,,serverA,,88
+`,
+		},
+		{
+			format: pipeline.ObjectFormatLine,
+			exp: `cpu,cpu=cpu0,host=serverA count=6i,mean=91.5 1519898340000000000
+cpu,host=serverA mean=88 1519898400000000000
+`,
+		},
+	}
+	for _, tc := range testCases {
+		p := &pipeline.ObjectStoreOutNode{
+			URL:    filepath.Join(dir, `{{ .TaskName }}/{{ index .Tags "host" }}/{{ .Time.Format "2006-01-02T15" }}.`+tc.format),
+			Format: tc.format,
+		}
+		n, err := newObjectStoreOutNode(&ExecutingTask{Task: &Task{ID: "archive"}}, p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := n.write(batch); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, "archive", "serverA", "2018-03-01T10."+tc.format))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(data); got != tc.exp {
+			t.Errorf("unexpected %s object:\ngot\n%s\nexp\n%s", tc.format, got, tc.exp)
+		}
+	}
+}
@@ -0,0 +1,442 @@
+// Package objectstore reads and writes objects addressed by URL on the local file system,
+// Amazon S3, Google Cloud Storage or Azure Blob Storage.
+//
+// Supported URLs are:
+//
+//    /path/to/file or file:///path/to/file
+//    s3://bucket/key?region=us-east-1&endpoint=http://localhost:9000
+//    gs://bucket/object
+//    az://container/blob?account=myaccount
+//
+// S3 credentials are read from the environment or the shared credentials file.
+// GCS credentials are the Google application default credentials.
+// Azure requests are authorized with the shared access signature in AZURE_STORAGE_SAS_TOKEN,
+// the account defaults to AZURE_STORAGE_ACCOUNT.
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	defaultS3Region = "us-east-1"
+	gcsScope        = "https://www.googleapis.com/auth/devstorage.read_write"
+	gcsEndpoint     = "https://storage.googleapis.com"
+	azAPIVersion    = "2019-12-12"
+)
+
+// Validate returns an error if the URL is not a supported object URL.
+func Validate(rawurl string) error {
+	_, err := parse(rawurl)
+	return err
+}
+
+// Get returns the content of the object at the URL.
+func Get(ctx context.Context, rawurl string) ([]byte, error) {
+	o, err := parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	switch o.scheme {
+	case "s3":
+		return o.s3Get(ctx)
+	case "gs":
+		return o.gcsGet(ctx)
+	case "az":
+		return o.azDo(ctx, "GET", nil, "")
+	default:
+		return ioutil.ReadFile(o.key)
+	}
+}
+
+// ErrNotModified is returned by GetIfNoneMatch if the object has not changed.
+var ErrNotModified = errors.New("object not modified")
+
+// GetIfNoneMatch returns the content and entity tag of the object at the URL,
+// or ErrNotModified if the entity tag of the object is still etag.
+// The entity tag of a local file is derived from its modification time and size.
+func GetIfNoneMatch(ctx context.Context, rawurl, etag string) ([]byte, string, error) {
+	o, err := parse(rawurl)
+	if err != nil {
+		return nil, "", err
+	}
+	header := http.Header{}
+	if etag != "" {
+		header.Set("If-None-Match", etag)
+	}
+	var req *http.Request
+	cli := http.DefaultClient
+	switch o.scheme {
+	case "s3":
+		req, err = o.s3Request("GET", nil, "", header)
+	case "gs":
+		cli, err = o.gcsClient(ctx)
+		if err == nil {
+			req, err = o.gcsGetRequest(header)
+		}
+	case "az":
+		req, err = o.azRequest("GET", nil, "", header)
+	default:
+		info, err := os.Stat(o.key)
+		if err != nil {
+			return nil, "", err
+		}
+		tag := fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+		if tag == etag {
+			return nil, "", ErrNotModified
+		}
+		data, err := ioutil.ReadFile(o.key)
+		return data, tag, err
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return doETag(ctx, cli, req)
+}
+
+// Put writes the data to the object at the URL, replacing any existing object.
+// Missing parent directories of local files are created.
+func Put(ctx context.Context, rawurl string, data []byte, contentType string) error {
+	o, err := parse(rawurl)
+	if err != nil {
+		return err
+	}
+	switch o.scheme {
+	case "s3":
+		return o.s3Put(ctx, data, contentType)
+	case "gs":
+		return o.gcsPut(ctx, data, contentType)
+	case "az":
+		_, err := o.azDo(ctx, "PUT", data, contentType)
+		return err
+	default:
+		if err := os.MkdirAll(filepath.Dir(o.key), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(o.key, data, 0644)
+	}
+}
+
+// Delete removes the object at the URL.
+// It is not an error to delete an object that does not exist.
+func Delete(ctx context.Context, rawurl string) error {
+	o, err := parse(rawurl)
+	if err != nil {
+		return err
+	}
+	switch o.scheme {
+	case "s3":
+		_, err = o.s3Do(ctx, "DELETE", nil, "")
+	case "gs":
+		err = o.gcsDelete(ctx)
+	case "az":
+		_, err = o.azDo(ctx, "DELETE", nil, "")
+	default:
+		err = os.Remove(o.key)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if e, ok := err.(statusError); ok && e.code == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// object is a parsed object URL.
+type object struct {
+	scheme string
+	bucket string
+	// key is the object key or the local path.
+	key      string
+	region   string
+	endpoint string
+	// account is the Azure storage account.
+	account string
+}
+
+func parse(rawurl string) (object, error) {
+	if rawurl == "" {
+		return object{}, fmt.Errorf("object url must not be empty")
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return object{}, fmt.Errorf("invalid object url %q: %v", rawurl, err)
+	}
+	switch u.Scheme {
+	case "":
+		return object{key: rawurl}, nil
+	case "file":
+		if u.Path == "" {
+			return object{}, fmt.Errorf("invalid object url %q: missing path", rawurl)
+		}
+		return object{scheme: "file", key: u.Path}, nil
+	case "s3", "gs", "az":
+		key := strings.TrimPrefix(u.Path, "/")
+		if u.Host == "" || key == "" {
+			return object{}, fmt.Errorf("invalid object url %q: must be %s://bucket/key", rawurl, u.Scheme)
+		}
+		o := object{
+			scheme:   u.Scheme,
+			bucket:   u.Host,
+			key:      key,
+			region:   u.Query().Get("region"),
+			endpoint: u.Query().Get("endpoint"),
+			account:  u.Query().Get("account"),
+		}
+		if o.scheme == "az" {
+			if o.account == "" {
+				o.account = os.Getenv("AZURE_STORAGE_ACCOUNT")
+			}
+			if o.account == "" && o.endpoint == "" {
+				return object{}, fmt.Errorf("invalid object url %q: must set the account or endpoint", rawurl)
+			}
+		}
+		if o.endpoint != "" {
+			if e, err := url.Parse(o.endpoint); err != nil || e.Host == "" {
+				return object{}, fmt.Errorf("invalid object url %q: invalid endpoint %q", rawurl, o.endpoint)
+			}
+		}
+		return o, nil
+	default:
+		return object{}, fmt.Errorf("invalid object url %q: unsupported scheme %q", rawurl, u.Scheme)
+	}
+}
+
+// s3URL returns the path style URL of the object.
+func (o object) s3URL() string {
+	region := o.s3Region()
+	endpoint := o.endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+	return strings.TrimSuffix(endpoint, "/") + "/" + o.bucket + "/" + escapePath(o.key)
+}
+
+func (o object) s3Region() string {
+	if o.region != "" {
+		return o.region
+	}
+	if r := os.Getenv("AWS_REGION"); r != "" {
+		return r
+	}
+	return defaultS3Region
+}
+
+// s3Request returns the signed request of the object, the headers are signed with the request.
+func (o object) s3Request(method string, body []byte, contentType string, header http.Header) (*http.Request, error) {
+	req, err := http.NewRequest(method, o.s3URL(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	creds := credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvProvider{},
+		&credentials.SharedCredentialsProvider{},
+	})
+	if _, err := v4.NewSigner(creds).Sign(req, bytes.NewReader(body), "s3", o.s3Region(), time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign s3 request: %v", err)
+	}
+	return req, nil
+}
+
+func (o object) s3Do(ctx context.Context, method string, body []byte, contentType string) ([]byte, error) {
+	req, err := o.s3Request(method, body, contentType, nil)
+	if err != nil {
+		return nil, err
+	}
+	return do(ctx, http.DefaultClient, req)
+}
+
+func (o object) s3Get(ctx context.Context) ([]byte, error) {
+	return o.s3Do(ctx, "GET", nil, "")
+}
+
+func (o object) s3Put(ctx context.Context, data []byte, contentType string) error {
+	_, err := o.s3Do(ctx, "PUT", data, contentType)
+	return err
+}
+
+func (o object) gcsClient(ctx context.Context) (*http.Client, error) {
+	if o.endpoint != "" {
+		// Custom endpoints, i.e. emulators, are not authenticated.
+		return http.DefaultClient, nil
+	}
+	cli, err := google.DefaultClient(ctx, gcsScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get google credentials: %v", err)
+	}
+	return cli, nil
+}
+
+func (o object) gcsEndpoint() string {
+	if o.endpoint != "" {
+		return strings.TrimSuffix(o.endpoint, "/")
+	}
+	return gcsEndpoint
+}
+
+func (o object) gcsGetRequest(header http.Header) (*http.Request, error) {
+	u := fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media", o.gcsEndpoint(), url.PathEscape(o.bucket), url.PathEscape(o.key))
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return req, nil
+}
+
+func (o object) gcsGet(ctx context.Context) ([]byte, error) {
+	cli, err := o.gcsClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := o.gcsGetRequest(nil)
+	if err != nil {
+		return nil, err
+	}
+	return do(ctx, cli, req)
+}
+
+func (o object) gcsPut(ctx context.Context, data []byte, contentType string) error {
+	cli, err := o.gcsClient(ctx)
+	if err != nil {
+		return err
+	}
+	u := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?%s", o.gcsEndpoint(), url.PathEscape(o.bucket), url.Values{
+		"uploadType": []string{"media"},
+		"name":       []string{o.key},
+	}.Encode())
+	req, err := http.NewRequest("POST", u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	req.Header.Set("Content-Type", contentType)
+	_, err = do(ctx, cli, req)
+	return err
+}
+
+func (o object) gcsDelete(ctx context.Context) error {
+	cli, err := o.gcsClient(ctx)
+	if err != nil {
+		return err
+	}
+	u := fmt.Sprintf("%s/storage/v1/b/%s/o/%s", o.gcsEndpoint(), url.PathEscape(o.bucket), url.PathEscape(o.key))
+	req, err := http.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return err
+	}
+	_, err = do(ctx, cli, req)
+	return err
+}
+
+// azURL returns the URL of the blob, authorized with the shared access signature if set.
+func (o object) azURL() string {
+	endpoint := o.endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", o.account)
+	}
+	u := strings.TrimSuffix(endpoint, "/") + "/" + o.bucket + "/" + escapePath(o.key)
+	if sas := strings.TrimPrefix(os.Getenv("AZURE_STORAGE_SAS_TOKEN"), "?"); sas != "" {
+		u += "?" + sas
+	}
+	return u
+}
+
+func (o object) azDo(ctx context.Context, method string, body []byte, contentType string) ([]byte, error) {
+	req, err := o.azRequest(method, body, contentType, nil)
+	if err != nil {
+		return nil, err
+	}
+	return do(ctx, http.DefaultClient, req)
+}
+
+func (o object) azRequest(method string, body []byte, contentType string, header http.Header) (*http.Request, error) {
+	req, err := http.NewRequest(method, o.azURL(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("x-ms-version", azAPIVersion)
+	if method == "PUT" {
+		req.Header.Set("x-ms-blob-type", "BlockBlob")
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+// statusError is returned for non 2xx responses.
+type statusError struct {
+	code int
+	host string
+	body []byte
+}
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("unexpected response code %d from %s: %s", e.code, e.host, e.body)
+}
+
+func do(ctx context.Context, cli *http.Client, req *http.Request) ([]byte, error) {
+	body, _, err := doETag(ctx, cli, req)
+	return body, err
+}
+
+// doETag returns the body and entity tag of the response,
+// or ErrNotModified if the response is not modified.
+func doETag(ctx context.Context, cli *http.Client, req *http.Request) ([]byte, string, error) {
+	resp, err := cli.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, "", ErrNotModified
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, "", statusError{code: resp.StatusCode, host: req.URL.Host, body: bytes.TrimSpace(body)}
+	}
+	return body, resp.Header.Get("ETag"), nil
+}
+
+// escapePath escapes each segment of an object key.
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,337 @@
+package objectstore_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/kapacitor/objectstore"
+)
+
+func TestGet_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "objectstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hosts.csv")
+	if err := ioutil.WriteFile(path, []byte("host,dc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []string{path, "file://" + path} {
+		data, err := objectstore.Get(context.Background(), u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, exp := string(data), "host,dc\n"; got != exp {
+			t.Errorf("unexpected content for %s: got %q exp %q", u, got, exp)
+		}
+	}
+}
+
+func TestGet_S3(t *testing.T) {
+	os.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, exp := r.URL.Path, "/reference/data/hosts.csv"; got != exp {
+			t.Errorf("unexpected path: got %s exp %s", got, exp)
+		}
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/s3/") {
+			t.Errorf("unexpected authorization header %q", auth)
+		}
+		w.Write([]byte("host,dc\n"))
+	}))
+	defer ts.Close()
+
+	data, err := objectstore.Get(context.Background(), "s3://reference/data/hosts.csv?region=eu-west-1&endpoint="+ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := string(data), "host,dc\n"; got != exp {
+		t.Errorf("unexpected content: got %q exp %q", got, exp)
+	}
+}
+
+func TestGet_GCS(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, exp := r.URL.EscapedPath(), "/storage/v1/b/reference/o/data%2Fhosts.csv"; got != exp {
+			t.Errorf("unexpected path: got %s exp %s", got, exp)
+		}
+		if got, exp := r.URL.Query().Get("alt"), "media"; got != exp {
+			t.Errorf("unexpected alt: got %s exp %s", got, exp)
+		}
+		w.Write([]byte("host,dc\n"))
+	}))
+	defer ts.Close()
+
+	data, err := objectstore.Get(context.Background(), "gs://reference/data/hosts.csv?endpoint="+ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := string(data), "host,dc\n"; got != exp {
+		t.Errorf("unexpected content: got %q exp %q", got, exp)
+	}
+}
+
+func TestGetIfNoneMatch_S3(t *testing.T) {
+	os.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "if-none-match") && r.Header.Get("If-None-Match") != "" {
+			t.Error("expected the if-none-match header to be signed")
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("host,dc\n"))
+	}))
+	defer ts.Close()
+
+	u := "s3://reference/hosts.csv?endpoint=" + ts.URL
+	data, etag, err := objectstore.GetIfNoneMatch(context.Background(), u, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "host,dc\n" || etag != `"v1"` {
+		t.Errorf("unexpected object %q with etag %s", data, etag)
+	}
+	if _, _, err := objectstore.GetIfNoneMatch(context.Background(), u, etag); err != objectstore.ErrNotModified {
+		t.Errorf("expected not modified error, got %v", err)
+	}
+}
+
+func TestGetIfNoneMatch_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "objectstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hosts.csv")
+	if err := ioutil.WriteFile(path, []byte("host,dc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, etag, err := objectstore.GetIfNoneMatch(context.Background(), path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := objectstore.GetIfNoneMatch(context.Background(), path, etag); err != objectstore.ErrNotModified {
+		t.Errorf("expected not modified error, got %v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte("host,dc,rack\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, _, err := objectstore.GetIfNoneMatch(context.Background(), path, etag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := string(data), "host,dc,rack\n"; got != exp {
+		t.Errorf("unexpected content: got %q exp %q", got, exp)
+	}
+}
+
+func TestGet_Error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "NoSuchKey", http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	_, err := objectstore.Get(context.Background(), "gs://reference/missing.csv?endpoint="+ts.URL)
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected 404 error, got %v", err)
+	}
+}
+
+func TestPut_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "objectstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "archive", "cpu.csv")
+	if err := objectstore.Put(context.Background(), path, []byte("time,value\n"), "text/csv"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := string(data), "time,value\n"; got != exp {
+		t.Errorf("unexpected content: got %q exp %q", got, exp)
+	}
+}
+
+func TestPut_S3(t *testing.T) {
+	os.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, exp := r.Method, "PUT"; got != exp {
+			t.Errorf("unexpected method: got %s exp %s", got, exp)
+		}
+		if got, exp := r.URL.Path, "/archive/cpu/2018.csv"; got != exp {
+			t.Errorf("unexpected path: got %s exp %s", got, exp)
+		}
+		if got, exp := r.Header.Get("Content-Type"), "text/csv"; got != exp {
+			t.Errorf("unexpected content type: got %s exp %s", got, exp)
+		}
+		if r.Header.Get("X-Amz-Content-Sha256") == "" {
+			t.Error("expected content sha256 header")
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if got, exp := string(body), "time,value\n"; got != exp {
+			t.Errorf("unexpected body: got %q exp %q", got, exp)
+		}
+	}))
+	defer ts.Close()
+
+	err := objectstore.Put(context.Background(), "s3://archive/cpu/2018.csv?endpoint="+ts.URL, []byte("time,value\n"), "text/csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPut_GCS(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, exp := r.Method, "POST"; got != exp {
+			t.Errorf("unexpected method: got %s exp %s", got, exp)
+		}
+		if got, exp := r.URL.Path, "/upload/storage/v1/b/archive/o"; got != exp {
+			t.Errorf("unexpected path: got %s exp %s", got, exp)
+		}
+		if got, exp := r.URL.Query().Get("name"), "cpu/2018.csv"; got != exp {
+			t.Errorf("unexpected name: got %s exp %s", got, exp)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if got, exp := string(body), "time,value\n"; got != exp {
+			t.Errorf("unexpected body: got %q exp %q", got, exp)
+		}
+	}))
+	defer ts.Close()
+
+	err := objectstore.Put(context.Background(), "gs://archive/cpu/2018.csv?endpoint="+ts.URL, []byte("time,value\n"), "text/csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPut_Azure(t *testing.T) {
+	os.Setenv("AZURE_STORAGE_SAS_TOKEN", "?sv=2019-12-12&sig=abc")
+	defer os.Unsetenv("AZURE_STORAGE_SAS_TOKEN")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, exp := r.Method, "PUT"; got != exp {
+			t.Errorf("unexpected method: got %s exp %s", got, exp)
+		}
+		if got, exp := r.URL.Path, "/archive/cpu/2018.csv"; got != exp {
+			t.Errorf("unexpected path: got %s exp %s", got, exp)
+		}
+		if got, exp := r.URL.Query().Get("sig"), "abc"; got != exp {
+			t.Errorf("unexpected signature: got %s exp %s", got, exp)
+		}
+		if got, exp := r.Header.Get("x-ms-blob-type"), "BlockBlob"; got != exp {
+			t.Errorf("unexpected blob type: got %s exp %s", got, exp)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if got, exp := string(body), "time,value\n"; got != exp {
+			t.Errorf("unexpected body: got %q exp %q", got, exp)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	err := objectstore.Put(context.Background(), "az://archive/cpu/2018.csv?endpoint="+ts.URL, []byte("time,value\n"), "text/csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	var deleted []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, exp := r.Method, "DELETE"; got != exp {
+			t.Errorf("unexpected method: got %s exp %s", got, exp)
+		}
+		deleted = append(deleted, r.URL.EscapedPath())
+		if strings.Contains(r.URL.Path, "missing") {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	for _, u := range []string{
+		"gs://archive/cpu.csv?endpoint=" + ts.URL,
+		"az://archive/cpu.csv?endpoint=" + ts.URL,
+		"gs://archive/missing.csv?endpoint=" + ts.URL,
+	} {
+		if err := objectstore.Delete(context.Background(), u); err != nil {
+			t.Errorf("%s: unexpected error: %v", u, err)
+		}
+	}
+	exp := []string{"/storage/v1/b/archive/o/cpu.csv", "/archive/cpu.csv", "/storage/v1/b/archive/o/missing.csv"}
+	if !reflect.DeepEqual(deleted, exp) {
+		t.Errorf("unexpected deletes: got %v exp %v", deleted, exp)
+	}
+
+	dir, err := ioutil.TempDir("", "objectstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cpu.csv")
+	if err := ioutil.WriteFile(path, []byte("time,value\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := objectstore.Delete(context.Background(), path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected file to be deleted, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		url string
+		err bool
+	}{
+		{url: "/tmp/hosts.csv"},
+		{url: "file:///tmp/hosts.csv"},
+		{url: "s3://bucket/hosts.csv"},
+		{url: "gs://bucket/dir/hosts.csv"},
+		{url: "az://container/hosts.csv?account=myaccount"},
+		{url: "az://container/hosts.csv", err: true},
+		{url: "", err: true},
+		{url: "s3://bucket", err: true},
+		{url: "ftp://host/hosts.csv", err: true},
+		{url: "s3://bucket/key?endpoint=invalid", err: true},
+	}
+	for _, tc := range testCases {
+		err := objectstore.Validate(tc.url)
+		if tc.err && err == nil {
+			t.Errorf("%q: expected error", tc.url)
+		} else if !tc.err && err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.url, err)
+		}
+	}
+}
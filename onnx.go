@@ -0,0 +1,221 @@
+package kapacitor
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/onnx"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/pkg/errors"
+)
+
+const (
+	statsOnnxErrors = "onnx_errors"
+)
+
+type OnnxNode struct {
+	node
+	o       *pipeline.OnnxNode
+	model   *onnx.Model
+	input   onnx.ValueInfo
+	shape   []int
+	outputs []string
+
+	errors *expvar.Int
+}
+
+// Create a new OnnxNode which scores each point with an ONNX model.
+func newOnnxNode(et *ExecutingTask, n *pipeline.OnnxNode, d NodeDiagnostic) (*OnnxNode, error) {
+	b, err := ioutil.ReadFile(n.Model)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read onnx model")
+	}
+	m, err := onnx.Load(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid onnx model %s", n.Model)
+	}
+	if len(m.Inputs()) != 1 {
+		return nil, fmt.Errorf("onnx model %s must have a single input, got %d", n.Model, len(m.Inputs()))
+	}
+	input := m.Inputs()[0]
+	if input.Shape == nil {
+		return nil, fmt.Errorf("onnx model %s: the shape of input %q is unknown", n.Model, input.Name)
+	}
+	if input.Size() != len(n.FieldsList) {
+		return nil, fmt.Errorf("onnx model %s: input %q has %d values, got %d fields", n.Model, input.Name, input.Size(), len(n.FieldsList))
+	}
+	// Dimensions which are not fixed have a size of 1.
+	shape := make([]int, len(input.Shape))
+	for i, d := range input.Shape {
+		shape[i] = d
+		if d < 0 {
+			shape[i] = 1
+		}
+	}
+
+	modelOutputs := make(map[string]onnx.ValueInfo, len(m.Outputs()))
+	var outputs []string
+	for _, out := range m.Outputs() {
+		modelOutputs[out.Name] = out
+		outputs = append(outputs, out.Name)
+	}
+	if len(n.OutputsList) > 0 {
+		outputs = n.OutputsList
+	}
+	size := 0
+	for _, name := range outputs {
+		out, ok := modelOutputs[name]
+		if !ok {
+			return nil, fmt.Errorf("onnx model %s does not have an output named %q", n.Model, name)
+		}
+		if s := out.Size(); s >= 0 && size >= 0 {
+			size += s
+		} else {
+			// The number of values is only known when the model runs.
+			size = -1
+		}
+	}
+	if size >= 0 && size != len(n.AsList) {
+		return nil, fmt.Errorf("onnx model %s: outputs have %d values, got %d as names", n.Model, size, len(n.AsList))
+	}
+
+	on := &OnnxNode{
+		node:    node{Node: n, et: et, diag: d},
+		o:       n,
+		model:   m,
+		input:   input,
+		shape:   shape,
+		outputs: outputs,
+	}
+	on.node.runF = on.runOnnx
+	return on, nil
+}
+
+func (n *OnnxNode) runOnnx([]byte) error {
+	n.errors = &expvar.Int{}
+	n.statMap.Set(statsOnnxErrors, n.errors)
+
+	consumer := edge.NewGroupedConsumer(
+		n.ins[0],
+		n,
+	)
+	n.statMap.Set(statCardinalityGauge, consumer.CardinalityVar())
+
+	return consumer.Consume()
+}
+
+func (n *OnnxNode) NewGroup(group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error) {
+	return edge.NewReceiverFromForwardReceiverWithStats(
+		n.outs,
+		edge.NewTimedForwardReceiver(n.timer, n),
+	), nil
+}
+
+// score runs the model with the fields of p and sets the values of its outputs as fields of p.
+func (n *OnnxNode) score(p edge.FieldsTagsTimeSetter) error {
+	fields := p.Fields()
+	in := onnx.NewTensor(n.input.Type, n.shape...)
+	for i, name := range n.o.FieldsList {
+		v, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("field %q does not exist", name)
+		}
+		switch v := v.(type) {
+		case float64:
+			in.Data[i] = v
+		case int64:
+			in.Data[i] = float64(v)
+		case bool:
+			if v {
+				in.Data[i] = 1
+			}
+		default:
+			return fmt.Errorf("field %q has unsupported type %T", name, v)
+		}
+	}
+	results, err := n.model.Run(map[string]*onnx.Tensor{n.input.Name: in})
+	if err != nil {
+		return err
+	}
+	newFields := make(models.Fields, len(fields)+len(n.o.AsList))
+	for f, v := range fields {
+		newFields[f] = v
+	}
+	i := 0
+	for _, name := range n.outputs {
+		out := results[name]
+		if i+len(out.Data) > len(n.o.AsList) {
+			return fmt.Errorf("outputs have more than %d values", len(n.o.AsList))
+		}
+		for _, v := range out.Data {
+			newFields[n.o.AsList[i]] = onnxValue(out.Type, v)
+			i++
+		}
+	}
+	if i != len(n.o.AsList) {
+		return fmt.Errorf("outputs have %d values, expected %d", i, len(n.o.AsList))
+	}
+	p.SetFields(newFields)
+	return nil
+}
+
+// onnxValue converts a value of a tensor of type t into a field value.
+func onnxValue(t onnx.DataType, v float64) interface{} {
+	switch t {
+	case onnx.Bool:
+		return v != 0
+	case onnx.Int32, onnx.Int64:
+		return int64(v)
+	default:
+		return v
+	}
+}
+
+func (n *OnnxNode) doScore(p edge.FieldsTagsTimeSetter) bool {
+	if err := n.score(p); err != nil {
+		n.errors.Add(1)
+		if !n.o.QuietFlag {
+			n.diag.Error("error scoring point with onnx model", err)
+		}
+		// Skip bad point
+		return false
+	}
+	return true
+}
+
+func (n *OnnxNode) BeginBatch(begin edge.BeginBatchMessage) (edge.Message, error) {
+	begin = begin.ShallowCopy()
+	begin.SetSizeHint(0)
+	return begin, nil
+}
+
+func (n *OnnxNode) BatchPoint(bp edge.BatchPointMessage) (edge.Message, error) {
+	bp = bp.ShallowCopy()
+	if n.doScore(bp) {
+		return bp, nil
+	}
+	return nil, nil
+}
+
+func (n *OnnxNode) EndBatch(end edge.EndBatchMessage) (edge.Message, error) {
+	return end, nil
+}
+
+func (n *OnnxNode) Point(p edge.PointMessage) (edge.Message, error) {
+	p = p.ShallowCopy()
+	if n.doScore(p) {
+		return p, nil
+	}
+	return nil, nil
+}
+
+func (n *OnnxNode) Barrier(b edge.BarrierMessage) (edge.Message, error) {
+	return b, nil
+}
+func (n *OnnxNode) DeleteGroup(d edge.DeleteGroupMessage) (edge.Message, error) {
+	return d, nil
+}
+func (n *OnnxNode) Done() {}
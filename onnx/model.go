@@ -0,0 +1,625 @@
+// Package onnx implements the inference of ONNX models.
+//
+// It is used to score data with pre-trained models inside kapacitord.
+// Models are loaded from the ONNX protocol buffer format and evaluated by an interpreter of their graph,
+// the operators commonly used by linear models and feed forward neural networks are supported.
+// All computations are made with double precision and tensors of numbers,
+// whatever their element type, are stored as float64 values.
+package onnx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DataType is the element type of a tensor.
+type DataType int32
+
+const (
+	Float  DataType = 1
+	Int32  DataType = 6
+	Int64  DataType = 7
+	Bool   DataType = 9
+	Double DataType = 11
+)
+
+func (t DataType) String() string {
+	switch t {
+	case Float:
+		return "float"
+	case Int32:
+		return "int32"
+	case Int64:
+		return "int64"
+	case Bool:
+		return "bool"
+	case Double:
+		return "double"
+	default:
+		return fmt.Sprintf("unknown(%d)", int32(t))
+	}
+}
+
+// IsInteger reports whether the values of the type are integers.
+func (t DataType) IsInteger() bool {
+	return t == Int32 || t == Int64 || t == Bool
+}
+
+func (t DataType) supported() bool {
+	switch t {
+	case Float, Int32, Int64, Bool, Double:
+		return true
+	}
+	return false
+}
+
+// ValueInfo describes an input or output of a model.
+type ValueInfo struct {
+	Name string
+	Type DataType
+	// The dimensions of the value, dimensions which are not fixed are -1.
+	// Shape is nil if it is unknown.
+	Shape []int
+}
+
+// Size returns the number of elements of the value, treating dimensions which are not fixed as 1.
+// It returns -1 if the shape is unknown.
+func (v ValueInfo) Size() int {
+	if v.Shape == nil {
+		return -1
+	}
+	size := 1
+	for _, d := range v.Shape {
+		if d > 0 {
+			size *= d
+		}
+	}
+	return size
+}
+
+// Model is a loaded ONNX model.
+type Model struct {
+	opset        int64
+	inputs       []ValueInfo
+	outputs      []ValueInfo
+	initializers map[string]*Tensor
+	nodes        []*node
+}
+
+type node struct {
+	name    string
+	op      string
+	inputs  []string
+	outputs []string
+	attrs   map[string]*attribute
+	f       opFunc
+}
+
+// String returns a description of the node for errors.
+func (n *node) String() string {
+	if n.name != "" {
+		return fmt.Sprintf("%s node %q", n.op, n.name)
+	}
+	return n.op + " node"
+}
+
+type attribute struct {
+	f      float64
+	i      int64
+	s      string
+	t      *Tensor
+	floats []float64
+	ints   []int64
+}
+
+// Load loads a model from its protocol buffer encoding.
+func Load(b []byte) (*Model, error) {
+	m := &Model{
+		initializers: make(map[string]*Tensor),
+	}
+	var graph *decoder
+	d := &decoder{b: b}
+	for !d.done() {
+		f, err := d.key()
+		if err != nil {
+			return nil, err
+		}
+		switch f {
+		case 7:
+			graph, err = d.message()
+		case 8:
+			err = m.decodeOpset(d)
+		default:
+			err = d.skip()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid model: %v", err)
+		}
+	}
+	if graph == nil {
+		return nil, fmt.Errorf("invalid model: missing graph")
+	}
+	if err := m.decodeGraph(graph); err != nil {
+		return nil, fmt.Errorf("invalid graph: %v", err)
+	}
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Model) decodeOpset(d *decoder) error {
+	o, err := d.message()
+	if err != nil {
+		return err
+	}
+	var domain string
+	var version int64
+	for !o.done() {
+		f, err := o.key()
+		if err != nil {
+			return err
+		}
+		switch f {
+		case 1:
+			domain, err = o.string()
+		case 2:
+			version, err = o.int()
+		default:
+			err = o.skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if domain == "" || domain == "ai.onnx" {
+		m.opset = version
+	}
+	return nil
+}
+
+func (m *Model) decodeGraph(d *decoder) error {
+	var inputs []ValueInfo
+	for !d.done() {
+		f, err := d.key()
+		if err != nil {
+			return err
+		}
+		switch f {
+		case 1:
+			var n *node
+			n, err = decodeNode(d)
+			if err == nil {
+				m.nodes = append(m.nodes, n)
+			}
+		case 5:
+			var t *Tensor
+			var name string
+			t, name, err = decodeTensor(d)
+			if err == nil {
+				m.initializers[name] = t
+			}
+		case 11, 12:
+			var v ValueInfo
+			v, err = decodeValueInfo(d)
+			if f == 11 {
+				inputs = append(inputs, v)
+			} else {
+				m.outputs = append(m.outputs, v)
+			}
+		default:
+			err = d.skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// Graph inputs may also list initializers, which are not inputs of the model.
+	for _, in := range inputs {
+		if _, ok := m.initializers[in.Name]; !ok {
+			m.inputs = append(m.inputs, in)
+		}
+	}
+	return nil
+}
+
+func decodeNode(d *decoder) (*node, error) {
+	nd, err := d.message()
+	if err != nil {
+		return nil, err
+	}
+	n := &node{attrs: make(map[string]*attribute)}
+	var domain string
+	for !nd.done() {
+		f, err := nd.key()
+		if err != nil {
+			return nil, err
+		}
+		var s string
+		switch f {
+		case 1:
+			s, err = nd.string()
+			n.inputs = append(n.inputs, s)
+		case 2:
+			s, err = nd.string()
+			n.outputs = append(n.outputs, s)
+		case 3:
+			n.name, err = nd.string()
+		case 4:
+			n.op, err = nd.string()
+		case 5:
+			var name string
+			var a *attribute
+			name, a, err = decodeAttribute(nd)
+			n.attrs[name] = a
+		case 7:
+			domain, err = nd.string()
+		default:
+			err = nd.skip()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if domain != "" && domain != "ai.onnx" {
+		return nil, fmt.Errorf("%v: unsupported operator domain %q", n, domain)
+	}
+	return n, nil
+}
+
+func decodeAttribute(d *decoder) (string, *attribute, error) {
+	ad, err := d.message()
+	if err != nil {
+		return "", nil, err
+	}
+	var name string
+	a := new(attribute)
+	for !ad.done() {
+		f, err := ad.key()
+		if err != nil {
+			return "", nil, err
+		}
+		switch f {
+		case 1:
+			name, err = ad.string()
+		case 2:
+			a.f, err = ad.float32()
+		case 3:
+			a.i, err = ad.int()
+		case 4:
+			a.s, err = ad.string()
+		case 5:
+			a.t, _, err = decodeTensor(ad)
+		case 7:
+			a.floats, err = ad.floats(a.floats, false)
+		case 8:
+			a.ints, err = ad.ints(a.ints)
+		default:
+			err = ad.skip()
+		}
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	return name, a, nil
+}
+
+func decodeTensor(d *decoder) (*Tensor, string, error) {
+	td, err := d.message()
+	if err != nil {
+		return nil, "", err
+	}
+	var name string
+	var dims []int64
+	var raw []byte
+	t := new(Tensor)
+	for !td.done() {
+		f, err := td.key()
+		if err != nil {
+			return nil, "", err
+		}
+		var ints []int64
+		switch f {
+		case 1:
+			dims, err = td.ints(dims)
+		case 2:
+			var v int64
+			v, err = td.int()
+			t.Type = DataType(v)
+		case 4:
+			t.Data, err = td.floats(t.Data, false)
+		case 5, 7:
+			ints, err = td.ints(nil)
+			for _, v := range ints {
+				if f == 5 {
+					v = int64(int32(v))
+				}
+				t.Data = append(t.Data, float64(v))
+			}
+		case 8:
+			name, err = td.string()
+		case 9:
+			raw, err = td.bytes()
+		case 10:
+			t.Data, err = td.floats(t.Data, true)
+		case 14:
+			var loc int64
+			if loc, err = td.int(); err == nil && loc != 0 {
+				err = fmt.Errorf("tensor %q: external data is not supported", name)
+			}
+		default:
+			err = td.skip()
+		}
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	if !t.Type.supported() {
+		return nil, "", fmt.Errorf("tensor %q: unsupported data type %v", name, t.Type)
+	}
+	// Check the shape before allocating its values.
+	t.Shape = make([]int, len(dims))
+	for i, dim := range dims {
+		if dim < 0 || dim > MaxTensorSize {
+			return nil, "", fmt.Errorf("tensor %q: invalid dimension %d", name, dim)
+		}
+		t.Shape[i] = int(dim)
+	}
+	size, err := validSize(t.Shape)
+	if err != nil {
+		return nil, "", fmt.Errorf("tensor %q: %v", name, err)
+	}
+	if raw != nil {
+		if t.Data, err = decodeRaw(raw, t.Type, size); err != nil {
+			return nil, "", fmt.Errorf("tensor %q: %v", name, err)
+		}
+	}
+	if size != len(t.Data) {
+		return nil, "", fmt.Errorf("tensor %q: shape %v does not match its %d values", name, t.Shape, len(t.Data))
+	}
+	return t, name, nil
+}
+
+func decodeValueInfo(d *decoder) (ValueInfo, error) {
+	var v ValueInfo
+	vd, err := d.message()
+	if err != nil {
+		return v, err
+	}
+	for !vd.done() {
+		f, err := vd.key()
+		if err != nil {
+			return v, err
+		}
+		switch f {
+		case 1:
+			v.Name, err = vd.string()
+		case 2:
+			err = decodeType(vd, &v)
+		default:
+			err = vd.skip()
+		}
+		if err != nil {
+			return v, err
+		}
+	}
+	if v.Shape != nil {
+		if _, err := validSize(fixedShape(v.Shape)); err != nil {
+			return v, fmt.Errorf("value %q: %v", v.Name, err)
+		}
+	}
+	return v, nil
+}
+
+// fixedShape returns the shape with dimensions which are not fixed set to 1.
+func fixedShape(shape []int) []int {
+	fixed := make([]int, len(shape))
+	for i, d := range shape {
+		fixed[i] = d
+		if d < 0 {
+			fixed[i] = 1
+		}
+	}
+	return fixed
+}
+
+// decodeType decodes a TypeProto, only tensor types are supported.
+func decodeType(d *decoder, v *ValueInfo) error {
+	td, err := d.message()
+	if err != nil {
+		return err
+	}
+	for !td.done() {
+		f, err := td.key()
+		if err != nil {
+			return err
+		}
+		if f != 1 {
+			return fmt.Errorf("value %q is not a tensor", v.Name)
+		}
+		tt, err := td.message()
+		if err != nil {
+			return err
+		}
+		for !tt.done() {
+			f, err := tt.key()
+			if err != nil {
+				return err
+			}
+			switch f {
+			case 1:
+				var typ int64
+				typ, err = tt.int()
+				v.Type = DataType(typ)
+			case 2:
+				v.Shape, err = decodeShape(tt)
+			default:
+				err = tt.skip()
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func decodeShape(d *decoder) ([]int, error) {
+	sd, err := d.message()
+	if err != nil {
+		return nil, err
+	}
+	shape := []int{}
+	for !sd.done() {
+		f, err := sd.key()
+		if err != nil {
+			return nil, err
+		}
+		if f != 1 {
+			if err := sd.skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		dd, err := sd.message()
+		if err != nil {
+			return nil, err
+		}
+		dim := -1
+		for !dd.done() {
+			f, err := dd.key()
+			if err != nil {
+				return nil, err
+			}
+			if f == 1 {
+				var v int64
+				if v, err = dd.int(); err == nil && v > MaxTensorSize {
+					err = fmt.Errorf("invalid dimension %d", v)
+				} else if v >= 0 {
+					dim = int(v)
+				}
+			} else {
+				err = dd.skip()
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		shape = append(shape, dim)
+	}
+	return shape, nil
+}
+
+// check checks that the operators of the graph are supported and
+// that the nodes are sorted so that values are defined before they are used.
+func (m *Model) check() error {
+	defined := make(map[string]bool)
+	for name := range m.initializers {
+		defined[name] = true
+	}
+	for _, in := range m.inputs {
+		if !in.Type.supported() {
+			return fmt.Errorf("input %q has unsupported type %v", in.Name, in.Type)
+		}
+		defined[in.Name] = true
+	}
+	for _, n := range m.nodes {
+		op, ok := ops[n.op]
+		if !ok {
+			return fmt.Errorf("unsupported operator %s", n.op)
+		}
+		n.f = op
+		for _, in := range n.inputs {
+			if in != "" && !defined[in] {
+				return fmt.Errorf("%v: input %q is not defined by a previous node", n, in)
+			}
+		}
+		for _, out := range n.outputs {
+			defined[out] = true
+		}
+	}
+	var missing []string
+	for _, out := range m.outputs {
+		if !defined[out.Name] {
+			missing = append(missing, out.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("outputs are not defined by any node: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Inputs returns the inputs of the model.
+func (m *Model) Inputs() []ValueInfo {
+	return m.inputs
+}
+
+// Outputs returns the outputs of the model.
+func (m *Model) Outputs() []ValueInfo {
+	return m.outputs
+}
+
+// Run evaluates the model with the given input tensors and returns its output tensors by name.
+func (m *Model) Run(inputs map[string]*Tensor) (map[string]*Tensor, error) {
+	values := make(map[string]*Tensor, len(m.initializers)+len(inputs))
+	for name, t := range m.initializers {
+		values[name] = t
+	}
+	for _, in := range m.inputs {
+		t, ok := inputs[in.Name]
+		if !ok {
+			return nil, fmt.Errorf("missing input %q", in.Name)
+		}
+		if size, err := validSize(t.Shape); err != nil {
+			return nil, fmt.Errorf("input %q: %v", in.Name, err)
+		} else if size != len(t.Data) {
+			return nil, fmt.Errorf("input %q has shape %v but %d values", in.Name, t.Shape, len(t.Data))
+		}
+		if err := checkShape(in, t); err != nil {
+			return nil, err
+		}
+		values[in.Name] = t
+	}
+	for _, n := range m.nodes {
+		args := make([]*Tensor, len(n.inputs))
+		for i, in := range n.inputs {
+			if in != "" {
+				args[i] = values[in]
+			}
+		}
+		results, err := n.f(&opContext{node: n, opset: m.opset}, args)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", n, err)
+		}
+		for i, out := range n.outputs {
+			if i < len(results) && out != "" {
+				values[out] = results[i]
+			}
+		}
+	}
+	outputs := make(map[string]*Tensor, len(m.outputs))
+	for _, out := range m.outputs {
+		t, ok := values[out.Name]
+		if !ok {
+			return nil, fmt.Errorf("output %q was not computed", out.Name)
+		}
+		outputs[out.Name] = t
+	}
+	return outputs, nil
+}
+
+func checkShape(v ValueInfo, t *Tensor) error {
+	if v.Shape == nil {
+		return nil
+	}
+	if len(v.Shape) != len(t.Shape) {
+		return fmt.Errorf("input %q must have %d dimensions, got %d", v.Name, len(v.Shape), len(t.Shape))
+	}
+	for i, d := range v.Shape {
+		if d >= 0 && d != t.Shape[i] {
+			return fmt.Errorf("input %q must have shape %v, got %v", v.Name, v.Shape, t.Shape)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,509 @@
+package onnx
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Protocol buffer encoding helpers used to build test models.
+
+func pbKey(field, wire int) []byte {
+	return pbVarint(uint64(field<<3 | wire))
+}
+
+func pbVarint(v uint64) []byte {
+	b := make([]byte, binary.MaxVarintLen64)
+	return b[:binary.PutUvarint(b, v)]
+}
+
+func pbBytes(field int, b []byte) []byte {
+	return cat(pbKey(field, wireBytes), pbVarint(uint64(len(b))), b)
+}
+
+func pbString(field int, s string) []byte {
+	return pbBytes(field, []byte(s))
+}
+
+func pbInt(field int, v int64) []byte {
+	return cat(pbKey(field, wireVarint), pbVarint(uint64(v)))
+}
+
+func pbFloat(field int, v float32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, math.Float32bits(v))
+	return cat(pbKey(field, wireFixed32), b)
+}
+
+func pbPackedInts(field int, vs ...int64) []byte {
+	var b []byte
+	for _, v := range vs {
+		b = append(b, pbVarint(uint64(v))...)
+	}
+	return pbBytes(field, b)
+}
+
+func cat(bs ...[]byte) []byte {
+	var out []byte
+	for _, b := range bs {
+		out = append(out, b...)
+	}
+	return out
+}
+
+// floatTensor encodes a float tensor with its values as raw data.
+func floatTensor(field int, name string, dims []int64, vs ...float32) []byte {
+	raw := make([]byte, 4*len(vs))
+	for i, v := range vs {
+		binary.LittleEndian.PutUint32(raw[4*i:], math.Float32bits(v))
+	}
+	return pbBytes(field, cat(pbPackedInts(1, dims...), pbInt(2, int64(Float)), pbString(8, name), pbBytes(9, raw)))
+}
+
+// int64Tensor encodes an int64 tensor with its values as unpacked int64_data.
+func int64Tensor(field int, name string, dims []int64, vs ...int64) []byte {
+	b := cat(pbPackedInts(1, dims...), pbInt(2, int64(Int64)), pbString(8, name))
+	for _, v := range vs {
+		b = append(b, pbInt(7, v)...)
+	}
+	return pbBytes(field, b)
+}
+
+// valueInfo encodes a tensor value, negative dimensions are encoded as parameters.
+func valueInfo(field int, name string, typ DataType, dims ...int64) []byte {
+	var shape []byte
+	for _, d := range dims {
+		if d < 0 {
+			shape = append(shape, pbBytes(1, pbString(2, "N"))...)
+		} else {
+			shape = append(shape, pbBytes(1, pbInt(1, d))...)
+		}
+	}
+	tensorType := cat(pbInt(1, int64(typ)), pbBytes(2, shape))
+	return pbBytes(field, cat(pbString(1, name), pbBytes(2, pbBytes(1, tensorType))))
+}
+
+func nodeProto(op string, inputs, outputs []string, attrs ...[]byte) []byte {
+	var b []byte
+	for _, in := range inputs {
+		b = append(b, pbString(1, in)...)
+	}
+	for _, out := range outputs {
+		b = append(b, pbString(2, out)...)
+	}
+	b = append(b, pbString(4, op)...)
+	for _, a := range attrs {
+		b = append(b, pbBytes(5, a)...)
+	}
+	return pbBytes(1, b)
+}
+
+func attrInt(name string, v int64) []byte {
+	return cat(pbString(1, name), pbInt(3, v), pbInt(20, 2))
+}
+
+func attrFloat(name string, v float32) []byte {
+	return cat(pbString(1, name), pbFloat(2, v), pbInt(20, 1))
+}
+
+func modelProto(opset int64, graph ...[]byte) []byte {
+	return cat(pbInt(1, 7), pbBytes(7, cat(graph...)), pbBytes(8, pbInt(2, opset)))
+}
+
+// testModel is a small classifier of two features:
+// hidden = relu(x * W1' + b1), probabilities = softmax(hidden * W2 + b2), label = argmax(probabilities).
+func testModel() []byte {
+	return modelProto(13,
+		nodeProto("Gemm", []string{"x", "W1", "b1"}, []string{"h"}, attrInt("transB", 1)),
+		nodeProto("Relu", []string{"h"}, []string{"hr"}),
+		nodeProto("MatMul", []string{"hr", "W2"}, []string{"m"}),
+		nodeProto("Add", []string{"m", "b2"}, []string{"logits"}),
+		nodeProto("Softmax", []string{"logits"}, []string{"probabilities"}),
+		nodeProto("ArgMax", []string{"probabilities"}, []string{"label"}, attrInt("axis", 1), attrInt("keepdims", 0)),
+		floatTensor(5, "W1", []int64{3, 2}, 1, 0, 0, 1, 1, -1),
+		floatTensor(5, "b1", []int64{3}, 0, 0, -1),
+		floatTensor(5, "W2", []int64{3, 2}, 1, 0, 0, 1, 0.5, 0.5),
+		floatTensor(5, "b2", []int64{2}, 0, 0.25),
+		valueInfo(11, "x", Float, -1, 2),
+		// Initializers may also be listed as inputs.
+		valueInfo(11, "W1", Float, 3, 2),
+		valueInfo(12, "probabilities", Float, -1, 2),
+		valueInfo(12, "label", Int64, -1),
+	)
+}
+
+func TestModel(t *testing.T) {
+	m, err := Load(testModel())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := m.Inputs(), []ValueInfo{{Name: "x", Type: Float, Shape: []int{-1, 2}}}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected inputs: got %v exp %v", got, exp)
+	}
+	expOutputs := []ValueInfo{
+		{Name: "probabilities", Type: Float, Shape: []int{-1, 2}},
+		{Name: "label", Type: Int64, Shape: []int{-1}},
+	}
+	if got := m.Outputs(); !reflect.DeepEqual(got, expOutputs) {
+		t.Errorf("unexpected outputs: got %v exp %v", got, expOutputs)
+	}
+	if got, exp := m.Inputs()[0].Size(), 2; got != exp {
+		t.Errorf("unexpected input size: got %d exp %d", got, exp)
+	}
+
+	x := &Tensor{Type: Float, Shape: []int{2, 2}, Data: []float64{3, 1, 0, 2}}
+	outputs, err := m.Run(map[string]*Tensor{"x": x})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Row 0: h = [3 1 1], logits = [3.5 1.75]; row 1: h = [0 2 0], logits = [0 2.25].
+	softmax := func(a, b float64) []float64 {
+		ea, eb := math.Exp(a), math.Exp(b)
+		return []float64{float64(float32(ea / (ea + eb))), float64(float32(eb / (ea + eb)))}
+	}
+	expProbabilities := append(softmax(3.5, 1.75), softmax(0, 2.25)...)
+	if got := outputs["probabilities"]; !reflect.DeepEqual(got.Shape, []int{2, 2}) || !reflect.DeepEqual(got.Data, expProbabilities) {
+		t.Errorf("unexpected probabilities: got %v %v exp %v", got.Shape, got.Data, expProbabilities)
+	}
+	if got, exp := outputs["label"], (&Tensor{Type: Int64, Shape: []int{2}, Data: []float64{0, 1}}); !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected label: got %v exp %v", got, exp)
+	}
+
+	if _, err := m.Run(map[string]*Tensor{"x": NewTensor(Float, 1, 3)}); err == nil || err.Error() != `input "x" must have shape [-1 2], got [1 3]` {
+		t.Errorf("unexpected error for invalid input shape: %v", err)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		model []byte
+		err   string
+	}{
+		{
+			name:  "missing graph",
+			model: pbInt(1, 7),
+			err:   "invalid model: missing graph",
+		},
+		{
+			name:  "truncated",
+			model: testModel()[:40],
+			err:   "invalid model: unexpected end of data",
+		},
+		{
+			name: "unsupported operator",
+			model: modelProto(13,
+				nodeProto("Conv", []string{"x"}, []string{"y"}),
+				valueInfo(11, "x", Float, 1),
+				valueInfo(12, "y", Float, 1),
+			),
+			err: "unsupported operator Conv",
+		},
+		{
+			name: "unsorted nodes",
+			model: modelProto(13,
+				nodeProto("Relu", []string{"h"}, []string{"y"}),
+				nodeProto("Relu", []string{"x"}, []string{"h"}),
+				valueInfo(11, "x", Float, 1),
+				valueInfo(12, "y", Float, 1),
+			),
+			err: `Relu node: input "h" is not defined by a previous node`,
+		},
+		{
+			name: "undefined output",
+			model: modelProto(13,
+				valueInfo(11, "x", Float, 1),
+				valueInfo(12, "y", Float, 1),
+			),
+			err: "outputs are not defined by any node: y",
+		},
+		{
+			name: "invalid initializer",
+			model: modelProto(13,
+				floatTensor(5, "w", []int64{2, 2}, 1, 2, 3),
+			),
+			err: `invalid graph: tensor "w": raw data of 12 bytes does not match 4 values`,
+		},
+		{
+			name: "invalid initializer values",
+			model: modelProto(13,
+				int64Tensor(5, "w", []int64{2, 2}, 1, 2, 3),
+			),
+			err: `invalid graph: tensor "w": shape [2 2] does not match its 3 values`,
+		},
+		{
+			name: "huge dimension",
+			model: modelProto(13,
+				floatTensor(5, "w", []int64{1 << 40}),
+			),
+			err: `invalid graph: tensor "w": invalid dimension 1099511627776`,
+		},
+		{
+			name: "negative dimension",
+			model: modelProto(13,
+				floatTensor(5, "w", []int64{-1}),
+			),
+			err: `invalid graph: tensor "w": invalid dimension -1`,
+		},
+		{
+			name: "overflowing shape",
+			model: modelProto(13,
+				floatTensor(5, "w", []int64{1 << 16, 1 << 16, 1 << 16, 1 << 16}),
+			),
+			err: `invalid graph: tensor "w": shape [65536 65536 65536 65536] has more than 16777216 elements`,
+		},
+		{
+			name: "huge input",
+			model: modelProto(13,
+				nodeProto("Relu", []string{"x"}, []string{"y"}),
+				valueInfo(11, "x", Float, 1<<20, -1, 1<<20),
+				valueInfo(12, "y", Float, 1),
+			),
+			err: `invalid graph: value "x": shape [1048576 1 1048576] has more than 16777216 elements`,
+		},
+		{
+			name: "huge input dimension",
+			model: modelProto(13,
+				valueInfo(11, "x", Float, 1<<32),
+			),
+			err: `invalid graph: invalid dimension 4294967296`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load(tc.model)
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("unexpected error: got %v exp %s", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestLoad_Malformed(t *testing.T) {
+	model := testModel()
+	// Truncated or corrupted models must fail or load, but never panic.
+	for i := range model {
+		Load(model[:i])
+		corrupted := append([]byte{}, model...)
+		corrupted[i] ^= 0xff
+		if m, err := Load(corrupted); err == nil {
+			m.Run(map[string]*Tensor{"x": {Type: Float, Shape: []int{1, 2}, Data: []float64{1, 2}}})
+		}
+	}
+}
+
+func tensor(shape []int, data ...float64) *Tensor {
+	return &Tensor{Type: Double, Shape: shape, Data: data}
+}
+
+func TestOps(t *testing.T) {
+	testCases := []struct {
+		name  string
+		op    string
+		opset int64
+		attrs map[string]*attribute
+		args  []*Tensor
+		exp   *Tensor
+		err   string
+	}{
+		{
+			name: "broadcast add",
+			op:   "Add",
+			args: []*Tensor{tensor([]int{2, 3}, 1, 2, 3, 4, 5, 6), tensor([]int{3}, 10, 20, 30)},
+			exp:  tensor([]int{2, 3}, 11, 22, 33, 14, 25, 36),
+		},
+		{
+			name: "broadcast sub column",
+			op:   "Sub",
+			args: []*Tensor{tensor([]int{2, 1}, 10, 20), tensor([]int{1, 3}, 1, 2, 3)},
+			exp:  tensor([]int{2, 3}, 9, 8, 7, 19, 18, 17),
+		},
+		{
+			name: "incompatible shapes",
+			op:   "Mul",
+			args: []*Tensor{tensor([]int{2}, 1, 2), tensor([]int{3}, 1, 2, 3)},
+			err:  "cannot broadcast shapes [[2] [3]]",
+		},
+		{
+			name: "integer division",
+			op:   "Div",
+			args: []*Tensor{{Type: Int64, Shape: []int{2}, Data: []float64{7, -7}}, {Type: Int64, Shape: []int{}, Data: []float64{2}}},
+			exp:  &Tensor{Type: Int64, Shape: []int{2}, Data: []float64{3, -3}},
+		},
+		{
+			name: "integer division by zero",
+			op:   "Div",
+			args: []*Tensor{{Type: Int64, Shape: []int{1}, Data: []float64{1}}, {Type: Int64, Shape: []int{1}, Data: []float64{0}}},
+			err:  "integer division by zero",
+		},
+		{
+			name: "matmul vector",
+			op:   "MatMul",
+			args: []*Tensor{tensor([]int{2}, 1, 2), tensor([]int{2, 3}, 1, 2, 3, 4, 5, 6)},
+			exp:  tensor([]int{3}, 9, 12, 15),
+		},
+		{
+			name: "matmul batch",
+			op:   "MatMul",
+			args: []*Tensor{tensor([]int{2, 1, 2}, 1, 2, 3, 4), tensor([]int{2, 1}, 10, 1)},
+			exp:  tensor([]int{2, 1, 1}, 12, 34),
+		},
+		{
+			name:  "gemm",
+			op:    "Gemm",
+			attrs: map[string]*attribute{"transA": {i: 1}, "alpha": {f: 2}, "beta": {f: 0.5}},
+			args:  []*Tensor{tensor([]int{2, 1}, 1, 2), tensor([]int{2, 2}, 1, 2, 3, 4), tensor([]int{}, 4)},
+			exp:   tensor([]int{1, 2}, 16, 22),
+		},
+		{
+			name:  "softmax axis",
+			op:    "Softmax",
+			attrs: map[string]*attribute{"axis": {i: 0}},
+			args:  []*Tensor{tensor([]int{2, 2}, 0, 5, 0, 5)},
+			exp:   tensor([]int{2, 2}, 0.5, 0.5, 0.5, 0.5),
+		},
+		{
+			name:  "softmax coerced",
+			op:    "Softmax",
+			opset: 11,
+			args:  []*Tensor{tensor([]int{1, 2, 2}, 0, 0, 0, 0)},
+			exp:   tensor([]int{1, 2, 2}, 0.25, 0.25, 0.25, 0.25),
+		},
+		{
+			name:  "clip inputs",
+			op:    "Clip",
+			opset: 11,
+			args:  []*Tensor{tensor([]int{3}, -5, 0.5, 5), tensor([]int{}, 0), nil},
+			exp:   tensor([]int{3}, 0, 0.5, 5),
+		},
+		{
+			name:  "clip attributes",
+			op:    "Clip",
+			opset: 6,
+			attrs: map[string]*attribute{"min": {f: -1}, "max": {f: 1}},
+			args:  []*Tensor{tensor([]int{3}, -5, 0.5, 5)},
+			exp:   tensor([]int{3}, -1, 0.5, 1),
+		},
+		{
+			name:  "cast",
+			op:    "Cast",
+			attrs: map[string]*attribute{"to": {i: int64(Int64)}},
+			args:  []*Tensor{tensor([]int{3}, 1.7, -1.7, math.NaN())},
+			exp:   &Tensor{Type: Int64, Shape: []int{3}, Data: []float64{1, -1, 0}},
+		},
+		{
+			name:  "argmax last index",
+			op:    "ArgMax",
+			attrs: map[string]*attribute{"axis": {i: -1}, "select_last_index": {i: 1}},
+			args:  []*Tensor{tensor([]int{2, 3}, 1, 3, 3, 2, 1, 0)},
+			exp:   &Tensor{Type: Int64, Shape: []int{2, 1}, Data: []float64{2, 0}},
+		},
+		{
+			name: "reshape",
+			op:   "Reshape",
+			args: []*Tensor{tensor([]int{2, 3}, 1, 2, 3, 4, 5, 6), {Type: Int64, Shape: []int{3}, Data: []float64{0, -1, 1}}},
+			exp:  tensor([]int{2, 3, 1}, 1, 2, 3, 4, 5, 6),
+		},
+		{
+			name: "invalid reshape",
+			op:   "Reshape",
+			args: []*Tensor{tensor([]int{2, 3}, 1, 2, 3, 4, 5, 6), {Type: Int64, Shape: []int{2}, Data: []float64{4, -1}}},
+			err:  "cannot reshape [2 3] to [4 -1]",
+		},
+		{
+			name:  "flatten",
+			op:    "Flatten",
+			attrs: map[string]*attribute{"axis": {i: 0}},
+			args:  []*Tensor{tensor([]int{2, 2}, 1, 2, 3, 4)},
+			exp:   tensor([]int{1, 4}, 1, 2, 3, 4),
+		},
+		{
+			name: "squeeze all",
+			op:   "Squeeze",
+			args: []*Tensor{tensor([]int{1, 2, 1}, 1, 2)},
+			exp:  tensor([]int{2}, 1, 2),
+		},
+		{
+			name: "unsqueeze input",
+			op:   "Unsqueeze",
+			args: []*Tensor{tensor([]int{2}, 1, 2), {Type: Int64, Shape: []int{2}, Data: []float64{0, -1}}},
+			exp:  tensor([]int{1, 2, 1}, 1, 2),
+		},
+		{
+			name:  "transpose",
+			op:    "Transpose",
+			attrs: map[string]*attribute{"perm": {ints: []int64{1, 0}}},
+			args:  []*Tensor{tensor([]int{2, 3}, 1, 2, 3, 4, 5, 6)},
+			exp:   tensor([]int{3, 2}, 1, 4, 2, 5, 3, 6),
+		},
+		{
+			name:  "concat",
+			op:    "Concat",
+			attrs: map[string]*attribute{"axis": {i: 1}},
+			args:  []*Tensor{tensor([]int{2, 1}, 1, 2), tensor([]int{2, 2}, 3, 4, 5, 6)},
+			exp:   tensor([]int{2, 3}, 1, 3, 4, 2, 5, 6),
+		},
+		{
+			name:  "leaky relu",
+			op:    "LeakyRelu",
+			attrs: map[string]*attribute{"alpha": {f: 0.5}},
+			args:  []*Tensor{tensor([]int{2}, -2, 2)},
+			exp:   tensor([]int{2}, -1, 2),
+		},
+		{
+			name: "float precision",
+			op:   "Add",
+			args: []*Tensor{{Type: Float, Shape: []int{1}, Data: []float64{0.1}}, {Type: Float, Shape: []int{1}, Data: []float64{0.2}}},
+			exp:  &Tensor{Type: Float, Shape: []int{1}, Data: []float64{float64(float32(0.1 + 0.2))}},
+		},
+		{
+			name: "broadcast too large",
+			op:   "Add",
+			args: []*Tensor{tensor([]int{8192, 1}, make([]float64, 8192)...), tensor([]int{1, 8192}, make([]float64, 8192)...)},
+			err:  "shape [8192 8192] has more than 16777216 elements",
+		},
+		{
+			name: "matmul too large",
+			op:   "MatMul",
+			args: []*Tensor{tensor([]int{8192, 1}, make([]float64, 8192)...), tensor([]int{1, 8192}, make([]float64, 8192)...)},
+			err:  "shape [8192 8192] has more than 16777216 elements",
+		},
+		{
+			name:  "reshape overflow",
+			op:    "Reshape",
+			attrs: map[string]*attribute{"shape": {ints: []int64{1 << 16, 1 << 16, 1 << 16, 1 << 16}}},
+			args:  []*Tensor{tensor([]int{0})},
+			err:   "shape [65536 65536 65536 65536] has more than 16777216 elements",
+		},
+		{
+			name: "missing input",
+			op:   "Relu",
+			args: []*Tensor{nil},
+			err:  "missing input 0",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attrs := tc.attrs
+			if attrs == nil {
+				attrs = make(map[string]*attribute)
+			}
+			c := &opContext{node: &node{op: tc.op, attrs: attrs}, opset: tc.opset}
+			results, err := ops[tc.op](c, tc.args)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("unexpected error: got %v exp %s", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := results[0]; !reflect.DeepEqual(got, tc.exp) {
+				t.Errorf("unexpected result: got %v exp %v", got, tc.exp)
+			}
+		})
+	}
+}
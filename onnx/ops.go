@@ -0,0 +1,637 @@
+package onnx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// opFunc computes the outputs of an operator from its inputs, missing optional inputs are nil.
+type opFunc func(c *opContext, args []*Tensor) ([]*Tensor, error)
+
+type opContext struct {
+	node  *node
+	opset int64
+}
+
+// since reports whether the operator set of the model is at least version.
+// Models which do not import the default operator set use the latest one.
+func (c *opContext) since(version int64) bool {
+	return c.opset == 0 || c.opset >= version
+}
+
+func (c *opContext) attr(name string) (*attribute, bool) {
+	a, ok := c.node.attrs[name]
+	return a, ok
+}
+
+func (c *opContext) attrInt(name string, def int64) int64 {
+	if a, ok := c.attr(name); ok {
+		return a.i
+	}
+	return def
+}
+
+func (c *opContext) attrFloat(name string, def float64) float64 {
+	if a, ok := c.attr(name); ok {
+		return a.f
+	}
+	return def
+}
+
+// axes returns the axes given either by the attribute name or by the input i of the node.
+func (c *opContext) axes(name string, args []*Tensor, i int) ([]int64, bool) {
+	if a, ok := c.attr(name); ok {
+		return a.ints, true
+	}
+	if i < len(args) && args[i] != nil {
+		return ints(args[i]), true
+	}
+	return nil, false
+}
+
+var ops = map[string]opFunc{
+	"Abs":       unaryOp(math.Abs),
+	"Add":       binaryOp(func(x, y float64) float64 { return x + y }),
+	"ArgMax":    argMax,
+	"Cast":      cast,
+	"Clip":      clip,
+	"Concat":    concat,
+	"Constant":  constant,
+	"Div":       div,
+	"Dropout":   identity,
+	"Exp":       unaryOp(math.Exp),
+	"Flatten":   flatten,
+	"Gemm":      gemm,
+	"Identity":  identity,
+	"LeakyRelu": leakyRelu,
+	"Log":       unaryOp(math.Log),
+	"MatMul":    matMul,
+	"Mul":       binaryOp(func(x, y float64) float64 { return x * y }),
+	"Neg":       unaryOp(func(x float64) float64 { return -x }),
+	"Pow":       binaryOp(math.Pow),
+	"Relu":      unaryOp(func(x float64) float64 { return math.Max(x, 0) }),
+	"Reshape":   reshapeOp,
+	"Sigmoid":   unaryOp(func(x float64) float64 { return 1 / (1 + math.Exp(-x)) }),
+	"Softmax":   softmax,
+	"Sqrt":      unaryOp(math.Sqrt),
+	"Squeeze":   squeeze,
+	"Sub":       binaryOp(func(x, y float64) float64 { return x - y }),
+	"Tanh":      unaryOp(math.Tanh),
+	"Transpose": transpose,
+	"Unsqueeze": unsqueeze,
+}
+
+// requireInputs checks that the first n inputs of an operator are given.
+func requireInputs(args []*Tensor, n int) error {
+	if len(args) < n {
+		return fmt.Errorf("expected %d inputs, got %d", n, len(args))
+	}
+	for i := 0; i < n; i++ {
+		if args[i] == nil {
+			return fmt.Errorf("missing input %d", i)
+		}
+	}
+	return nil
+}
+
+// ints returns the values of an integer tensor.
+func ints(t *Tensor) []int64 {
+	vs := make([]int64, len(t.Data))
+	for i, v := range t.Data {
+		vs[i] = int64(v)
+	}
+	return vs
+}
+
+func unaryOp(f func(x float64) float64) opFunc {
+	return func(c *opContext, args []*Tensor) ([]*Tensor, error) {
+		if err := requireInputs(args, 1); err != nil {
+			return nil, err
+		}
+		return []*Tensor{unaryMap(args[0], f)}, nil
+	}
+}
+
+func binaryOp(f func(x, y float64) float64) opFunc {
+	return func(c *opContext, args []*Tensor) ([]*Tensor, error) {
+		if err := requireInputs(args, 2); err != nil {
+			return nil, err
+		}
+		out, err := binaryMap(args[0], args[1], args[0].Type, f)
+		return []*Tensor{out}, err
+	}
+}
+
+func div(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	if err := requireInputs(args, 2); err != nil {
+		return nil, err
+	}
+	if args[0].Type.IsInteger() {
+		for _, v := range args[1].Data {
+			if v == 0 {
+				return nil, errors.New("integer division by zero")
+			}
+		}
+	}
+	out, err := binaryMap(args[0], args[1], args[0].Type, func(x, y float64) float64 { return x / y })
+	return []*Tensor{out}, err
+}
+
+func identity(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	if err := requireInputs(args, 1); err != nil {
+		return nil, err
+	}
+	return []*Tensor{args[0]}, nil
+}
+
+func leakyRelu(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	alpha := c.attrFloat("alpha", 0.01)
+	return unaryOp(func(x float64) float64 {
+		if x < 0 {
+			return alpha * x
+		}
+		return x
+	})(c, args)
+}
+
+func clip(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	if err := requireInputs(args, 1); err != nil {
+		return nil, err
+	}
+	min, max := math.Inf(-1), math.Inf(1)
+	if c.since(11) {
+		for i, bound := range []*float64{&min, &max} {
+			if i+1 < len(args) && args[i+1] != nil {
+				if args[i+1].Size() != 1 {
+					return nil, errors.New("min and max must be scalars")
+				}
+				*bound = args[i+1].Data[0]
+			}
+		}
+	} else {
+		min = c.attrFloat("min", min)
+		max = c.attrFloat("max", max)
+	}
+	return []*Tensor{unaryMap(args[0], func(x float64) float64 {
+		return math.Min(math.Max(x, min), max)
+	})}, nil
+}
+
+func cast(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	if err := requireInputs(args, 1); err != nil {
+		return nil, err
+	}
+	to := DataType(c.attrInt("to", 0))
+	if !to.supported() {
+		return nil, fmt.Errorf("unsupported type %v", to)
+	}
+	out := NewTensor(to, args[0].Shape...)
+	for i, v := range args[0].Data {
+		out.Data[i] = convert(to, v)
+	}
+	return []*Tensor{out}, nil
+}
+
+func constant(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	if a, ok := c.attr("value"); ok && a.t != nil {
+		return []*Tensor{a.t}, nil
+	}
+	if a, ok := c.attr("value_float"); ok {
+		return []*Tensor{{Type: Float, Shape: []int{}, Data: []float64{a.f}}}, nil
+	}
+	if a, ok := c.attr("value_floats"); ok {
+		return []*Tensor{{Type: Float, Shape: []int{len(a.floats)}, Data: a.floats}}, nil
+	}
+	if a, ok := c.attr("value_int"); ok {
+		return []*Tensor{{Type: Int64, Shape: []int{}, Data: []float64{float64(a.i)}}}, nil
+	}
+	if a, ok := c.attr("value_ints"); ok {
+		t := NewTensor(Int64, len(a.ints))
+		for i, v := range a.ints {
+			t.Data[i] = float64(v)
+		}
+		return []*Tensor{t}, nil
+	}
+	return nil, errors.New("missing or unsupported value attribute")
+}
+
+// matMul multiplies matrices like numpy.matmul.
+func matMul(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	if err := requireInputs(args, 2); err != nil {
+		return nil, err
+	}
+	a, b := args[0], args[1]
+	as, bs := a.Shape, b.Shape
+	if len(as) == 0 || len(bs) == 0 {
+		return nil, errors.New("inputs must not be scalars")
+	}
+	vectorA, vectorB := len(as) == 1, len(bs) == 1
+	if vectorA {
+		as = []int{1, as[0]}
+	}
+	if vectorB {
+		bs = []int{bs[0], 1}
+	}
+	m, k := as[len(as)-2], as[len(as)-1]
+	n := bs[len(bs)-1]
+	if bs[len(bs)-2] != k {
+		return nil, fmt.Errorf("cannot multiply shapes %v and %v", a.Shape, b.Shape)
+	}
+	aBatch, bBatch := as[:len(as)-2], bs[:len(bs)-2]
+	batch, err := broadcastShape(aBatch, bBatch)
+	if err != nil {
+		return nil, err
+	}
+	shape := append(append([]int{}, batch...), m, n)
+	out, err := newTensor(a.Type, shape...)
+	if err != nil {
+		return nil, err
+	}
+	aStrides, bStrides := broadcastStrides(aBatch, batch), broadcastStrides(bBatch, batch)
+	index := make([]int, len(batch))
+	ai, bi := 0, 0
+	for o := 0; o < len(out.Data); o += m * n {
+		am, bm, om := a.Data[ai*m*k:], b.Data[bi*k*n:], out.Data[o:]
+		for i := 0; i < m; i++ {
+			for j := 0; j < n; j++ {
+				var sum float64
+				for p := 0; p < k; p++ {
+					sum += am[i*k+p] * bm[p*n+j]
+				}
+				om[i*n+j] = convert(a.Type, sum)
+			}
+		}
+		for d := len(batch) - 1; d >= 0; d-- {
+			index[d]++
+			ai += aStrides[d]
+			bi += bStrides[d]
+			if index[d] < batch[d] {
+				break
+			}
+			ai -= aStrides[d] * batch[d]
+			bi -= bStrides[d] * batch[d]
+			index[d] = 0
+		}
+	}
+	switch {
+	case vectorA && vectorB:
+		shape = shape[:len(shape)-2]
+	case vectorA:
+		shape = append(shape[:len(shape)-2], n)
+	case vectorB:
+		shape = shape[:len(shape)-1]
+	}
+	return []*Tensor{reshape(out, shape)}, nil
+}
+
+// gemm computes alpha * A' * B' + beta * C.
+func gemm(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	if err := requireInputs(args, 2); err != nil {
+		return nil, err
+	}
+	a, b := args[0], args[1]
+	if len(a.Shape) != 2 || len(b.Shape) != 2 {
+		return nil, errors.New("A and B must be matrices")
+	}
+	alpha, beta := c.attrFloat("alpha", 1), c.attrFloat("beta", 1)
+	transA, transB := c.attrInt("transA", 0) != 0, c.attrInt("transB", 0) != 0
+	m, k := a.Shape[0], a.Shape[1]
+	aRow, aCol := k, 1
+	if transA {
+		m, k = k, m
+		aRow, aCol = 1, m
+	}
+	kb, n := b.Shape[0], b.Shape[1]
+	bRow, bCol := n, 1
+	if transB {
+		kb, n = n, kb
+		bRow, bCol = 1, kb
+	}
+	if k != kb {
+		return nil, fmt.Errorf("cannot multiply shapes %v and %v", a.Shape, b.Shape)
+	}
+	out, err := newTensor(a.Type, m, n)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < m; i++ {
+		for j := 0; j < n; j++ {
+			var sum float64
+			for p := 0; p < k; p++ {
+				sum += a.Data[i*aRow+p*aCol] * b.Data[p*bRow+j*bCol]
+			}
+			out.Data[i*n+j] = convert(a.Type, alpha*sum)
+		}
+	}
+	if len(args) > 2 && args[2] != nil {
+		if out, err = binaryMap(out, args[2], a.Type, func(x, y float64) float64 { return x + beta*y }); err != nil {
+			return nil, err
+		}
+		if out.Shape[0] != m || out.Shape[1] != n {
+			return nil, fmt.Errorf("cannot broadcast C of shape %v to [%d %d]", args[2].Shape, m, n)
+		}
+	}
+	return []*Tensor{out}, nil
+}
+
+func softmax(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	if err := requireInputs(args, 1); err != nil {
+		return nil, err
+	}
+	t := args[0]
+	rank := len(t.Shape)
+	var outer, size, inner int
+	if c.since(13) {
+		axis, err := normalizeAxis(c.attrInt("axis", -1), rank)
+		if err != nil {
+			return nil, err
+		}
+		outer, size, inner = sizeOf(t.Shape[:axis]), t.Shape[axis], sizeOf(t.Shape[axis+1:])
+	} else {
+		// Older versions coerce the input into a matrix.
+		axis, err := normalizeAxis(c.attrInt("axis", 1), rank+1)
+		if err != nil {
+			return nil, err
+		}
+		outer, size, inner = sizeOf(t.Shape[:axis]), sizeOf(t.Shape[axis:]), 1
+	}
+	out := NewTensor(t.Type, t.Shape...)
+	for o := 0; o < outer; o++ {
+		for i := 0; i < inner; i++ {
+			base := o*size*inner + i
+			max := math.Inf(-1)
+			for j := 0; j < size; j++ {
+				max = math.Max(max, t.Data[base+j*inner])
+			}
+			var sum float64
+			for j := 0; j < size; j++ {
+				e := math.Exp(t.Data[base+j*inner] - max)
+				out.Data[base+j*inner] = e
+				sum += e
+			}
+			for j := 0; j < size; j++ {
+				out.Data[base+j*inner] = convert(t.Type, out.Data[base+j*inner]/sum)
+			}
+		}
+	}
+	return []*Tensor{out}, nil
+}
+
+func argMax(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	if err := requireInputs(args, 1); err != nil {
+		return nil, err
+	}
+	t := args[0]
+	axis, err := normalizeAxis(c.attrInt("axis", 0), len(t.Shape))
+	if err != nil {
+		return nil, err
+	}
+	last := c.attrInt("select_last_index", 0) != 0
+	outer, size, inner := sizeOf(t.Shape[:axis]), t.Shape[axis], sizeOf(t.Shape[axis+1:])
+	if size == 0 {
+		return nil, errors.New("cannot reduce an empty axis")
+	}
+	shape := append([]int{}, t.Shape...)
+	shape[axis] = 1
+	out := NewTensor(Int64, shape...)
+	for o := 0; o < outer; o++ {
+		for i := 0; i < inner; i++ {
+			base := o*size*inner + i
+			best := 0
+			for j := 1; j < size; j++ {
+				v, max := t.Data[base+j*inner], t.Data[base+best*inner]
+				if v > max || (last && v == max) {
+					best = j
+				}
+			}
+			out.Data[o*inner+i] = float64(best)
+		}
+	}
+	if c.attrInt("keepdims", 1) == 0 {
+		shape = append(shape[:axis], shape[axis+1:]...)
+	}
+	return []*Tensor{reshape(out, shape)}, nil
+}
+
+func flatten(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	if err := requireInputs(args, 1); err != nil {
+		return nil, err
+	}
+	t := args[0]
+	axis, err := normalizeAxis(c.attrInt("axis", 1), len(t.Shape)+1)
+	if err != nil {
+		return nil, err
+	}
+	return []*Tensor{reshape(t, []int{sizeOf(t.Shape[:axis]), sizeOf(t.Shape[axis:])})}, nil
+}
+
+func reshapeOp(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	if err := requireInputs(args, 1); err != nil {
+		return nil, err
+	}
+	t := args[0]
+	var dims []int64
+	if len(args) > 1 && args[1] != nil {
+		dims = ints(args[1])
+	} else if a, ok := c.attr("shape"); ok {
+		dims = a.ints
+	} else {
+		return nil, errors.New("missing shape")
+	}
+	allowZero := c.attrInt("allowzero", 0) != 0
+	shape := make([]int, len(dims))
+	inferred := -1
+	for i, d := range dims {
+		switch {
+		case d == -1:
+			if inferred >= 0 {
+				return nil, errors.New("shape must have at most one dimension of -1")
+			}
+			inferred = i
+			shape[i] = 1
+		case d == 0 && !allowZero:
+			if i >= len(t.Shape) {
+				return nil, fmt.Errorf("cannot copy dimension %d of shape %v", i, t.Shape)
+			}
+			shape[i] = t.Shape[i]
+		case d < 0 || d > MaxTensorSize:
+			return nil, fmt.Errorf("invalid dimension %d", d)
+		default:
+			shape[i] = int(d)
+		}
+	}
+	size, err := validSize(shape)
+	if err != nil {
+		return nil, err
+	}
+	if inferred >= 0 {
+		if size == 0 || t.Size()%size != 0 {
+			return nil, fmt.Errorf("cannot reshape %v to %v", t.Shape, dims)
+		}
+		shape[inferred] = t.Size() / size
+		size *= shape[inferred]
+	}
+	if size != t.Size() {
+		return nil, fmt.Errorf("cannot reshape %v to %v", t.Shape, dims)
+	}
+	return []*Tensor{reshape(t, shape)}, nil
+}
+
+func squeeze(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	if err := requireInputs(args, 1); err != nil {
+		return nil, err
+	}
+	t := args[0]
+	remove := make([]bool, len(t.Shape))
+	if axes, ok := c.axes("axes", args, 1); ok {
+		for _, a := range axes {
+			axis, err := normalizeAxis(a, len(t.Shape))
+			if err != nil {
+				return nil, err
+			}
+			if t.Shape[axis] != 1 {
+				return nil, fmt.Errorf("cannot squeeze dimension %d of shape %v", axis, t.Shape)
+			}
+			remove[axis] = true
+		}
+	} else {
+		for i, d := range t.Shape {
+			remove[i] = d == 1
+		}
+	}
+	shape := []int{}
+	for i, d := range t.Shape {
+		if !remove[i] {
+			shape = append(shape, d)
+		}
+	}
+	return []*Tensor{reshape(t, shape)}, nil
+}
+
+func unsqueeze(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	if err := requireInputs(args, 1); err != nil {
+		return nil, err
+	}
+	t := args[0]
+	axes, ok := c.axes("axes", args, 1)
+	if !ok {
+		return nil, errors.New("missing axes")
+	}
+	rank := len(t.Shape) + len(axes)
+	insert := make([]bool, rank)
+	for _, a := range axes {
+		axis, err := normalizeAxis(a, rank)
+		if err != nil {
+			return nil, err
+		}
+		if insert[axis] {
+			return nil, fmt.Errorf("duplicate axis %d", a)
+		}
+		insert[axis] = true
+	}
+	shape := make([]int, rank)
+	j := 0
+	for i := range shape {
+		if insert[i] {
+			shape[i] = 1
+		} else {
+			shape[i] = t.Shape[j]
+			j++
+		}
+	}
+	return []*Tensor{reshape(t, shape)}, nil
+}
+
+func transpose(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	if err := requireInputs(args, 1); err != nil {
+		return nil, err
+	}
+	t := args[0]
+	rank := len(t.Shape)
+	perm := make([]int, rank)
+	if a, ok := c.attr("perm"); ok {
+		if len(a.ints) != rank {
+			return nil, fmt.Errorf("perm %v does not match rank %d", a.ints, rank)
+		}
+		seen := make([]bool, rank)
+		for i, p := range a.ints {
+			if p < 0 || p >= int64(rank) || seen[p] {
+				return nil, fmt.Errorf("invalid perm %v", a.ints)
+			}
+			seen[p] = true
+			perm[i] = int(p)
+		}
+	} else {
+		for i := range perm {
+			perm[i] = rank - 1 - i
+		}
+	}
+	shape := make([]int, rank)
+	for i, p := range perm {
+		shape[i] = t.Shape[p]
+	}
+	out := NewTensor(t.Type, shape...)
+	in := strides(t.Shape)
+	index := make([]int, rank)
+	for o := range out.Data {
+		src := 0
+		for i, p := range perm {
+			src += index[i] * in[p]
+		}
+		out.Data[o] = t.Data[src]
+		for d := rank - 1; d >= 0; d-- {
+			index[d]++
+			if index[d] < shape[d] {
+				break
+			}
+			index[d] = 0
+		}
+	}
+	return []*Tensor{out}, nil
+}
+
+func concat(c *opContext, args []*Tensor) ([]*Tensor, error) {
+	if len(args) == 0 {
+		return nil, errors.New("expected at least one input")
+	}
+	if err := requireInputs(args, len(args)); err != nil {
+		return nil, err
+	}
+	a, ok := c.attr("axis")
+	if !ok {
+		return nil, errors.New("missing axis")
+	}
+	first := args[0]
+	axis, err := normalizeAxis(a.i, len(first.Shape))
+	if err != nil {
+		return nil, err
+	}
+	shape := append([]int{}, first.Shape...)
+	shape[axis] = 0
+	for _, t := range args {
+		if len(t.Shape) != len(shape) {
+			return nil, errors.New("inputs must have the same rank")
+		}
+		for i, d := range t.Shape {
+			if i != axis && d != shape[i] {
+				return nil, fmt.Errorf("cannot concatenate shapes %v and %v", first.Shape, t.Shape)
+			}
+		}
+		shape[axis] += t.Shape[axis]
+	}
+	out, err := newTensor(first.Type, shape...)
+	if err != nil {
+		return nil, err
+	}
+	outer, inner := sizeOf(shape[:axis]), sizeOf(shape[axis+1:])
+	o := 0
+	for i := 0; i < outer; i++ {
+		for _, t := range args {
+			n := t.Shape[axis] * inner
+			copy(out.Data[o:o+n], t.Data[i*n:(i+1)*n])
+			o += n
+		}
+	}
+	return []*Tensor{out}, nil
+}
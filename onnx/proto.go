@@ -0,0 +1,184 @@
+package onnx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Protocol buffer wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("unexpected end of data")
+
+// decoder decodes the protocol buffer wire format of a message.
+type decoder struct {
+	b []byte
+	// Wire type of the last decoded key.
+	wire int
+}
+
+func (d *decoder) done() bool {
+	return len(d.b) == 0
+}
+
+// key decodes the key of the next field and returns its number.
+func (d *decoder) key() (int, error) {
+	k, err := d.varint()
+	if err != nil {
+		return 0, err
+	}
+	d.wire = int(k & 7)
+	n := k >> 3
+	if n == 0 || n > math.MaxInt32 {
+		return 0, fmt.Errorf("invalid field number %d", n)
+	}
+	return int(n), nil
+}
+
+func (d *decoder) varint() (uint64, error) {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	d.b = d.b[n:]
+	return v, nil
+}
+
+func (d *decoder) bytes() ([]byte, error) {
+	if d.wire != wireBytes {
+		return nil, fmt.Errorf("unexpected wire type %d for length delimited field", d.wire)
+	}
+	l, err := d.varint()
+	if err != nil {
+		return nil, err
+	}
+	if l > uint64(len(d.b)) {
+		return nil, errTruncated
+	}
+	b := d.b[:l]
+	d.b = d.b[l:]
+	return b, nil
+}
+
+func (d *decoder) message() (*decoder, error) {
+	b, err := d.bytes()
+	if err != nil {
+		return nil, err
+	}
+	return &decoder{b: b}, nil
+}
+
+func (d *decoder) string() (string, error) {
+	b, err := d.bytes()
+	return string(b), err
+}
+
+func (d *decoder) int() (int64, error) {
+	if d.wire != wireVarint {
+		return 0, fmt.Errorf("unexpected wire type %d for varint field", d.wire)
+	}
+	v, err := d.varint()
+	return int64(v), err
+}
+
+func (d *decoder) float32() (float64, error) {
+	if d.wire != wireFixed32 {
+		return 0, fmt.Errorf("unexpected wire type %d for float field", d.wire)
+	}
+	if len(d.b) < 4 {
+		return 0, errTruncated
+	}
+	v := math.Float32frombits(binary.LittleEndian.Uint32(d.b))
+	d.b = d.b[4:]
+	return float64(v), nil
+}
+
+func (d *decoder) float64() (float64, error) {
+	if d.wire != wireFixed64 {
+		return 0, fmt.Errorf("unexpected wire type %d for double field", d.wire)
+	}
+	if len(d.b) < 8 {
+		return 0, errTruncated
+	}
+	v := math.Float64frombits(binary.LittleEndian.Uint64(d.b))
+	d.b = d.b[8:]
+	return v, nil
+}
+
+// ints appends the values of a repeated varint field, which may be packed, to vs.
+func (d *decoder) ints(vs []int64) ([]int64, error) {
+	if d.wire != wireBytes {
+		v, err := d.int()
+		return append(vs, v), err
+	}
+	p, err := d.message()
+	if err != nil {
+		return nil, err
+	}
+	p.wire = wireVarint
+	for !p.done() {
+		v, err := p.int()
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+// floats appends the values of a repeated float or double field, which may be packed, to vs.
+func (d *decoder) floats(vs []float64, double bool) ([]float64, error) {
+	wire, decode := wireFixed32, (*decoder).float32
+	if double {
+		wire, decode = wireFixed64, (*decoder).float64
+	}
+	if d.wire != wireBytes {
+		v, err := decode(d)
+		return append(vs, v), err
+	}
+	p, err := d.message()
+	if err != nil {
+		return nil, err
+	}
+	p.wire = wire
+	for !p.done() {
+		v, err := decode(p)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+// skip skips the value of a field that is not decoded.
+func (d *decoder) skip() error {
+	switch d.wire {
+	case wireVarint:
+		_, err := d.varint()
+		return err
+	case wireFixed64:
+		if len(d.b) < 8 {
+			return errTruncated
+		}
+		d.b = d.b[8:]
+	case wireBytes:
+		_, err := d.bytes()
+		return err
+	case wireFixed32:
+		if len(d.b) < 4 {
+			return errTruncated
+		}
+		d.b = d.b[4:]
+	default:
+		return fmt.Errorf("unsupported wire type %d", d.wire)
+	}
+	return nil
+}
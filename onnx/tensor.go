@@ -0,0 +1,232 @@
+package onnx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// MaxTensorSize is the maximum number of elements of a tensor.
+// It bounds the memory used by a model, since the shapes of its tensors are read from the model.
+const MaxTensorSize = 1 << 24
+
+// Tensor is a multi-dimensional array of numbers stored in row-major order.
+type Tensor struct {
+	Type  DataType
+	Shape []int
+	Data  []float64
+}
+
+// NewTensor returns a tensor of the given type and shape with zero values.
+func NewTensor(typ DataType, shape ...int) *Tensor {
+	t := &Tensor{Type: typ, Shape: shape}
+	t.Data = make([]float64, t.Size())
+	return t
+}
+
+// Size returns the number of elements of the tensor.
+func (t *Tensor) Size() int {
+	return sizeOf(t.Shape)
+}
+
+// newTensor is like NewTensor but returns an error if the shape is invalid or too large.
+func newTensor(typ DataType, shape ...int) (*Tensor, error) {
+	if _, err := validSize(shape); err != nil {
+		return nil, err
+	}
+	return NewTensor(typ, shape...), nil
+}
+
+// validSize returns the number of elements of a tensor of the given shape,
+// or an error if a dimension is negative or the tensor has more than MaxTensorSize elements.
+func validSize(shape []int) (int, error) {
+	size := 1
+	for _, d := range shape {
+		if d < 0 || d > MaxTensorSize {
+			return 0, fmt.Errorf("invalid dimension %d", d)
+		}
+		if size *= d; size > MaxTensorSize {
+			return 0, fmt.Errorf("shape %v has more than %d elements", shape, MaxTensorSize)
+		}
+	}
+	return size, nil
+}
+
+func sizeOf(shape []int) int {
+	size := 1
+	for _, d := range shape {
+		size *= d
+	}
+	return size
+}
+
+// strides returns the number of elements between consecutive indexes of each dimension.
+func strides(shape []int) []int {
+	s := make([]int, len(shape))
+	n := 1
+	for i := len(shape) - 1; i >= 0; i-- {
+		s[i] = n
+		n *= shape[i]
+	}
+	return s
+}
+
+// convert rounds v to a value of type t.
+func convert(t DataType, v float64) float64 {
+	switch t {
+	case Float:
+		return float64(float32(v))
+	case Int32:
+		if math.IsNaN(v) {
+			return 0
+		}
+		return float64(int32(v))
+	case Int64:
+		if math.IsNaN(v) {
+			return 0
+		}
+		return float64(int64(v))
+	case Bool:
+		if v != 0 {
+			return 1
+		}
+		return 0
+	}
+	return v
+}
+
+// rawWidth returns the number of bytes of a value of type t in raw data.
+func rawWidth(t DataType) int {
+	switch t {
+	case Float, Int32:
+		return 4
+	case Int64, Double:
+		return 8
+	}
+	return 1
+}
+
+// decodeRaw decodes size values of type typ from raw data.
+func decodeRaw(raw []byte, typ DataType, size int) ([]float64, error) {
+	width := rawWidth(typ)
+	if len(raw) != size*width {
+		return nil, fmt.Errorf("raw data of %d bytes does not match %d values", len(raw), size)
+	}
+	data := make([]float64, size)
+	for i := range data {
+		b := raw[i*width:]
+		switch typ {
+		case Float:
+			data[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		case Int32:
+			data[i] = float64(int32(binary.LittleEndian.Uint32(b)))
+		case Int64:
+			data[i] = float64(int64(binary.LittleEndian.Uint64(b)))
+		case Double:
+			data[i] = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		case Bool:
+			if b[0] != 0 {
+				data[i] = 1
+			}
+		}
+	}
+	return data, nil
+}
+
+// broadcastShape returns the shape of the result of an operation on tensors of the given shapes
+// using multidirectional broadcasting.
+func broadcastShape(shapes ...[]int) ([]int, error) {
+	rank := 0
+	for _, s := range shapes {
+		if len(s) > rank {
+			rank = len(s)
+		}
+	}
+	out := make([]int, rank)
+	for i := range out {
+		out[i] = 1
+	}
+	for _, s := range shapes {
+		off := rank - len(s)
+		for i, d := range s {
+			switch {
+			case d == out[off+i] || d == 1:
+			case out[off+i] == 1:
+				out[off+i] = d
+			default:
+				return nil, fmt.Errorf("cannot broadcast shapes %v", shapes)
+			}
+		}
+	}
+	return out, nil
+}
+
+// broadcastStrides returns the strides of a tensor of shape s broadcast to the shape out,
+// broadcast dimensions have a stride of 0.
+func broadcastStrides(s, out []int) []int {
+	st := strides(s)
+	bs := make([]int, len(out))
+	off := len(out) - len(s)
+	for i, d := range s {
+		if d != 1 {
+			bs[off+i] = st[i]
+		}
+	}
+	return bs
+}
+
+// binaryMap applies f element-wise to the broadcast values of a and b.
+func binaryMap(a, b *Tensor, typ DataType, f func(x, y float64) float64) (*Tensor, error) {
+	shape, err := broadcastShape(a.Shape, b.Shape)
+	if err != nil {
+		return nil, err
+	}
+	out, err := newTensor(typ, shape...)
+	if err != nil {
+		return nil, err
+	}
+	as, bs := broadcastStrides(a.Shape, shape), broadcastStrides(b.Shape, shape)
+	index := make([]int, len(shape))
+	ai, bi := 0, 0
+	for i := range out.Data {
+		out.Data[i] = convert(typ, f(a.Data[ai], b.Data[bi]))
+		// Increment the index, from the last dimension.
+		for d := len(shape) - 1; d >= 0; d-- {
+			index[d]++
+			ai += as[d]
+			bi += bs[d]
+			if index[d] < shape[d] {
+				break
+			}
+			ai -= as[d] * shape[d]
+			bi -= bs[d] * shape[d]
+			index[d] = 0
+		}
+	}
+	return out, nil
+}
+
+// unaryMap applies f to each value of t.
+func unaryMap(t *Tensor, f func(x float64) float64) *Tensor {
+	out := NewTensor(t.Type, t.Shape...)
+	for i, v := range t.Data {
+		out.Data[i] = convert(t.Type, f(v))
+	}
+	return out
+}
+
+// reshape returns a tensor sharing the values of t with another shape.
+func reshape(t *Tensor, shape []int) *Tensor {
+	return &Tensor{Type: t.Type, Shape: shape, Data: t.Data}
+}
+
+// normalizeAxis converts a possibly negative axis of a tensor of the given rank to an index.
+func normalizeAxis(axis int64, rank int) (int, error) {
+	if axis < -int64(rank) || axis >= int64(rank) {
+		return 0, fmt.Errorf("axis %d is out of range for rank %d", axis, rank)
+	}
+	if axis < 0 {
+		axis += int64(rank)
+	}
+	return int(axis), nil
+}
@@ -269,6 +269,13 @@ type AlertNodeData struct {
 	// Default: 21
 	History int64 `json:"history"`
 
+	// Number of shards processing the groups of the node in parallel.
+	// The groups are distributed to the shards by their ID,
+	// so that the points of each group are still processed in order.
+	// Zero or one processes all groups on a single goroutine.
+	// Stateful functions of the level expressions keep their state per shard.
+	Shards int64 `json:"shards,omitempty"`
+
 	// Optional tag key to use when tagging the data with the alert level.
 	LevelTag string `json:"levelTag"`
 	// Optional field key to add to the data, containing the alert level as a string.
@@ -385,6 +392,10 @@ type AlertNodeData struct {
 	// Send alert to Kafka topic
 	// tick:ignore
 	KafkaHandlers []*KafkaHandler `tick:"Kafka" json:"kafka"`
+
+	// Send alert to an AMQP exchange
+	// tick:ignore
+	AMQPHandlers []*AMQPHandler `tick:"Amqp" json:"amqp"`
 }
 
 func newAlertNode(wants EdgeType) *AlertNode {
@@ -442,11 +453,15 @@ func (n *AlertNode) UnmarshalJSON(data []byte) error {
 //tick:ignore
 func (n *AlertNodeData) ChainMethods() map[string]reflect.Value {
 	return map[string]reflect.Value{
-		"Log": reflect.ValueOf(n.chainnode.Log),
+		"Log":  reflect.ValueOf(n.chainnode.Log),
+		"Exec": reflect.ValueOf(n.chainnode.Exec),
 	}
 }
 
 func (n *AlertNodeData) validate() error {
+	if n.Shards < 0 {
+		return fmt.Errorf("shards must be >= 0, got %d", n.Shards)
+	}
 	for _, snmp := range n.SNMPTrapHandlers {
 		if err := snmp.validate(); err != nil {
 			return errors.Wrapf(err, "invalid SNMP trap %q", snmp.TrapOid)
@@ -1878,3 +1893,58 @@ type KafkaHandler struct {
 	// If empty the alert data in JSON is sent as the message body.
 	Template string `json:"template"`
 }
+
+// Publish the alert to an AMQP exchange.
+//
+// Example:
+//    [[amqp]]
+//      enabled = true
+//      name = "default"
+//      url = "amqp://localhost:5672/"
+//      exchange = "alerts"
+//      exchange-type = "topic"
+//
+// Example:
+//    stream
+//         |alert()
+//             .amqp()
+//                 .broker('default')
+//                 .exchange('alerts')
+//                 .routingKey('alerts.{{ .Level }}.{{ index .Tags "host" }}')
+//
+// The routing key is a template executed against the alert data,
+// so events can be routed based on their tags.
+// If empty, the routing key configured for the broker is used, falling back to the alert ID.
+//
+// tick:property
+func (n *AlertNodeData) Amqp() *AMQPHandler {
+	a := &AMQPHandler{
+		AlertNodeData: n,
+	}
+	n.AMQPHandlers = append(n.AMQPHandlers, a)
+	return a
+}
+
+// AMQP alert Handler
+// tick:embedded:AlertNode.Amqp
+type AMQPHandler struct {
+	*AlertNodeData `json:"-"`
+
+	// Broker is the name of the configured AMQP broker.
+	Broker string `json:"broker"`
+
+	// Exchange to publish to.
+	// If empty the exchange configured for the broker is used.
+	Exchange string `json:"exchange"`
+
+	// Template for the routing key of the published message.
+	RoutingKey string `json:"routingKey"`
+
+	// Template used to construct the message body
+	// If empty the alert data in JSON is sent as the message body.
+	Template string `json:"template"`
+
+	// Content type of the published message.
+	// If empty the content type configured for the broker is used.
+	ContentType string `json:"contentType"`
+}
@@ -13,6 +13,8 @@ import (
 // A node that handles creating several child QueryNodes.
 // Each call to `query` creates a child batch node that
 // can further be configured. See QueryNode
+// Each call to `file` creates a child batch node reading
+// a file instead of querying InfluxDB. See FileNode
 // The `batch` variable in batch tasks is an instance of
 // a BatchNode.
 //
@@ -94,6 +96,16 @@ func (b *BatchNode) Query(q string) *QueryNode {
 	return n
 }
 
+// Read batches of reference data from a CSV file on a schedule.
+// The path is either a local path or an s3:// or gs:// object URL.
+// See FileNode
+func (b *BatchNode) File(path string) *FileNode {
+	n := newFileNode()
+	n.Path = path
+	b.linkChild(n)
+	return n
+}
+
 // Do not add the source batch node to the dot output
 // since its not really an edge.
 // tick:ignore
@@ -155,6 +167,17 @@ type QueryNode struct {
 	// 1 AM and the Offset is 1 hour. Then at 1 AM on Sunday the data from 12 AM will be queried.
 	Offset time.Duration `json:"offset"`
 
+	// The latency watermark, how late data may arrive in InfluxDB.
+	//
+	// Instead of waiting a static Offset for late data, each interval is queried when it ends
+	// and queried again on every following schedule until it is older than the Latency.
+	// The groups whose results changed since they were last emitted are emitted again as corrections,
+	// with the same time, so the downstream nodes see the late data.
+	//
+	// For example with an Every of 1m and a Latency of 5m,
+	// each interval is queried once when it ends and up to 5 more times while its data may still be late.
+	Latency time.Duration `json:"latency"`
+
 	// Align the group by time intervals with the start time of the query
 	// tick:ignore
 	AlignGroupFlag bool `tick:"AlignGroup" json:"alignGroup"`
@@ -196,18 +219,20 @@ func (n *QueryNode) MarshalJSON() ([]byte, error) {
 	var raw = &struct {
 		TypeOf
 		*Alias
-		Period string `json:"period"`
-		Every  string `json:"every"`
-		Offset string `json:"offset"`
+		Period  string `json:"period"`
+		Every   string `json:"every"`
+		Offset  string `json:"offset"`
+		Latency string `json:"latency"`
 	}{
 		TypeOf: TypeOf{
 			Type: "query",
 			ID:   n.ID(),
 		},
-		Alias:  (*Alias)(n),
-		Period: influxql.FormatDuration(n.Period),
-		Every:  influxql.FormatDuration(n.Every),
-		Offset: influxql.FormatDuration(n.Offset),
+		Alias:   (*Alias)(n),
+		Period:  influxql.FormatDuration(n.Period),
+		Every:   influxql.FormatDuration(n.Every),
+		Offset:  influxql.FormatDuration(n.Offset),
+		Latency: influxql.FormatDuration(n.Latency),
 	}
 	return json.Marshal(raw)
 }
@@ -219,9 +244,10 @@ func (n *QueryNode) UnmarshalJSON(data []byte) error {
 	var raw = &struct {
 		TypeOf
 		*Alias
-		Period string `json:"period"`
-		Every  string `json:"every"`
-		Offset string `json:"offset"`
+		Period  string `json:"period"`
+		Every   string `json:"every"`
+		Offset  string `json:"offset"`
+		Latency string `json:"latency"`
 	}{
 		Alias: (*Alias)(n),
 	}
@@ -248,6 +274,14 @@ func (n *QueryNode) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	// The latency is absent from the JSON of tasks created before it existed.
+	if raw.Latency != "" {
+		n.Latency, err = influxql.ParseDuration(raw.Latency)
+		if err != nil {
+			return err
+		}
+	}
+
 	n.setID(raw.ID)
 	return nil
 }
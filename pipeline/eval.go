@@ -46,6 +46,12 @@ type EvalNode struct {
 	// keep all fields.
 	// tick:ignore
 	KeepList []string `json:"keepList"`
+
+	// Number of shards processing the groups of the node in parallel.
+	// The groups are distributed to the shards by their ID,
+	// so that the points of each group are still processed in order.
+	// Zero or one processes all groups on a single goroutine.
+	Shards int64 `json:"shards,omitempty"`
 }
 
 func newEvalNode(e EdgeType, exprs []*ast.LambdaNode) *EvalNode {
@@ -94,6 +100,9 @@ func (n *EvalNode) UnmarshalJSON(data []byte) error {
 	return nil
 }
 func (e *EvalNode) validate() error {
+	if e.Shards < 0 {
+		return fmt.Errorf("shards must be >= 0, got %d", e.Shards)
+	}
 	if asLen, lambdaLen := len(e.AsList), len(e.Lambdas); asLen != lambdaLen {
 		return fmt.Errorf("must specify same number of expressions and .as() names: got %d as names, and %d expressions.", asLen, lambdaLen)
 	}
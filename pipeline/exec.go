@@ -0,0 +1,148 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+)
+
+const (
+	// ExecFormatLine exchanges data with the command as InfluxDB line protocol.
+	ExecFormatLine = "line"
+	// ExecFormatJSON exchanges data with the command as one JSON object per line.
+	ExecFormatJSON = "json"
+)
+
+// An ExecNode streams the data to the STDIN of a long running command.
+// It is a lightweight alternative to a UDF for simple inline transformations.
+//
+// Each point or batch is written to the command followed by an empty line.
+// In the 'line' format each point is written as a line of line protocol.
+// In the 'json' format a point is written as a JSON object with the
+// name, tags, fields and time keys and a batch as a JSON object with the
+// name, tmax, tags and points keys.
+//
+// By default the data is passed on unchanged and the output of the command is discarded.
+// If capture is set the command must reply to each point or batch with
+// zero or more points in the same format followed by an empty line.
+// The tags, fields and time of the replied points replace the points of the batch,
+// or the point of a stream. The name and group tags of the data are kept.
+// A reply to a stream point must not contain more than one point.
+// JSON numbers are read as floats.
+//
+// Example:
+//    stream
+//        |from()
+//            .measurement('requests')
+//        |exec('/usr/bin/python3', '/etc/kapacitor/enrich.py')
+//            .format('json')
+//            .capture()
+//        |influxDBOut()
+//            .database('enriched')
+//
+// The command is started with the task and stopped when the task is stopped.
+// Anything the command writes to STDERR is logged.
+//
+// Available Statistics:
+//
+//    * points_sent -- number of points written to the command
+//    * points_received -- number of points read from the command
+//
+type ExecNode struct {
+	chainnode
+
+	// The command to execute
+	// tick:ignore
+	Command []string `json:"command"`
+
+	// The format of the data, either 'line' or 'json'.
+	// Defaults to 'line'.
+	Format string `json:"format"`
+
+	// Whether to replace the data with the points read from the command.
+	// tick:ignore
+	CaptureFlag bool `tick:"Capture" json:"capture"`
+
+	// How long to wait for the reply of the command when capture is set.
+	// If the command does not reply in time it is killed and the task fails.
+	// If zero no timeout is used.
+	Timeout time.Duration `json:"timeout"`
+}
+
+func newExecNode(wants EdgeType, command ...string) *ExecNode {
+	return &ExecNode{
+		chainnode: newBasicChainNode("exec", wants, wants),
+		Command:   command,
+		Format:    ExecFormatLine,
+	}
+}
+
+// MarshalJSON converts ExecNode to JSON
+// tick:ignore
+func (n *ExecNode) MarshalJSON() ([]byte, error) {
+	type Alias ExecNode
+	var raw = &struct {
+		TypeOf
+		*Alias
+		Timeout string `json:"timeout"`
+	}{
+		TypeOf: TypeOf{
+			Type: "exec",
+			ID:   n.ID(),
+		},
+		Alias:   (*Alias)(n),
+		Timeout: influxql.FormatDuration(n.Timeout),
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON converts JSON to an ExecNode
+// tick:ignore
+func (n *ExecNode) UnmarshalJSON(data []byte) error {
+	type Alias ExecNode
+	var raw = &struct {
+		TypeOf
+		*Alias
+		Timeout string `json:"timeout"`
+	}{
+		Alias: (*Alias)(n),
+	}
+	err := json.Unmarshal(data, raw)
+	if err != nil {
+		return err
+	}
+	if raw.Type != "exec" {
+		return fmt.Errorf("error unmarshaling node %d of type %s as ExecNode", raw.ID, raw.Type)
+	}
+	n.Timeout, err = influxql.ParseDuration(raw.Timeout)
+	if err != nil {
+		return err
+	}
+	n.setID(raw.ID)
+	return nil
+}
+
+func (n *ExecNode) validate() error {
+	if len(n.Command) == 0 || n.Command[0] == "" {
+		return errors.New("must specify a command")
+	}
+	switch n.Format {
+	case ExecFormatLine, ExecFormatJSON:
+	default:
+		return fmt.Errorf("invalid exec format %q, must be %q or %q", n.Format, ExecFormatLine, ExecFormatJSON)
+	}
+	if n.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+	return nil
+}
+
+// Replace the data with the points read from the STDOUT of the command.
+// tick:property
+func (n *ExecNode) Capture() *ExecNode {
+	n.CaptureFlag = true
+	return n
+}
@@ -0,0 +1,221 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+)
+
+const (
+	// FileFormatCSV reads files with a header row followed by one point per row.
+	FileFormatCSV = "csv"
+	// FileFormatParquet is recognized but not yet supported.
+	FileFormatParquet = "parquet"
+)
+
+// A FileNode defines a file source and a schedule for
+// processing batch data. The file is read completely on each
+// tick of the schedule and passed into the data pipeline as batches.
+// This is useful to join reference data, such as inventories or thresholds,
+// against metrics.
+//
+// The path is either a local path, a file:// URL, an
+// s3://bucket/key URL or a gs://bucket/object URL.
+// The region and endpoint of S3 objects can be set with the `region` and
+// `endpoint` query parameters.
+//
+// CSV files must have a header row naming the columns.
+// The columns listed with the tags property become tags,
+// the time column is parsed as the point time
+// and all other columns become fields.
+// Numeric and boolean values are converted, all other values are strings.
+// Empty values are ignored and rows without fields are skipped.
+//
+// Example:
+// var hosts = batch
+//     |file('s3://reference/hosts.csv?region=eu-west-1')
+//         .every(1h)
+//         .measurement('hosts')
+//         .tags('host', 'dc')
+//         .groupBy('host')
+//
+// In the above example the hosts file is read every hour
+// and a batch is emitted per host.
+//
+// Parquet files are not supported.
+//
+// Available Statistics:
+//
+//    * read_errors -- number of errors reading or parsing the file
+//    * batches_read -- number of batches read from the file
+//    * points_read -- total number of points in batches
+//
+type FileNode struct {
+	chainnode `json:"-"`
+
+	// The path or URL of the file.
+	// tick:ignore
+	Path string `json:"path"`
+
+	// The format of the file.
+	// Defaults to 'csv'.
+	Format string `json:"format"`
+
+	// How often to read the file.
+	//
+	// The Every property is mutually exclusive with the Cron property.
+	Every time.Duration `json:"every"`
+
+	// Define a schedule using a cron syntax.
+	//
+	// The specific cron implementation is documented here:
+	// https://github.com/gorhill/cronexpr#implementation
+	//
+	// The Cron property is mutually exclusive with the Every property.
+	Cron string `json:"cron"`
+
+	// The measurement name of the batches.
+	// Defaults to the file name without extension.
+	Measurement string `json:"measurement"`
+
+	// The column holding the point time.
+	// If empty or missing all points have the time the file was read.
+	TimeColumn string `json:"timeColumn"`
+
+	// The layout of the time column as described by https://golang.org/pkg/time/#Parse.
+	// Defaults to RFC3339.
+	TimeFormat string `json:"timeFormat"`
+
+	// The columns read as tags.
+	// tick:ignore
+	TagColumns []string `tick:"Tags" json:"tags"`
+
+	// The list of tags to group the batches by.
+	// tick:ignore
+	Dimensions []string `tick:"GroupBy" json:"groupBy"`
+}
+
+func newFileNode() *FileNode {
+	return &FileNode{
+		chainnode: newBasicChainNode("file", BatchEdge, BatchEdge),
+		Format:    FileFormatCSV,
+	}
+}
+
+// MarshalJSON converts FileNode to JSON
+// tick:ignore
+func (n *FileNode) MarshalJSON() ([]byte, error) {
+	type Alias FileNode
+	var raw = &struct {
+		TypeOf
+		*Alias
+		Every string `json:"every"`
+	}{
+		TypeOf: TypeOf{
+			Type: "file",
+			ID:   n.ID(),
+		},
+		Alias: (*Alias)(n),
+		Every: influxql.FormatDuration(n.Every),
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON converts JSON to an FileNode
+// tick:ignore
+func (n *FileNode) UnmarshalJSON(data []byte) error {
+	type Alias FileNode
+	var raw = &struct {
+		TypeOf
+		*Alias
+		Every string `json:"every"`
+	}{
+		Alias: (*Alias)(n),
+	}
+	err := json.Unmarshal(data, raw)
+	if err != nil {
+		return err
+	}
+	if raw.Type != "file" {
+		return fmt.Errorf("error unmarshaling node %d of type %s as FileNode", raw.ID, raw.Type)
+	}
+
+	n.Every, err = influxql.ParseDuration(raw.Every)
+	if err != nil {
+		return err
+	}
+
+	n.setID(raw.ID)
+	return nil
+}
+
+//tick:ignore
+func (n *FileNode) ChainMethods() map[string]reflect.Value {
+	return map[string]reflect.Value{
+		"GroupBy": reflect.ValueOf(n.chainnode.GroupBy),
+	}
+}
+
+func (n *FileNode) validate() error {
+	if n.Path == "" {
+		return errors.New("must specify a file path")
+	}
+	switch n.Format {
+	case FileFormatCSV:
+	case FileFormatParquet:
+		return errors.New("parquet files are not supported")
+	default:
+		return fmt.Errorf("invalid file format %q, must be %q", n.Format, FileFormatCSV)
+	}
+	if n.Every != 0 && n.Cron != "" {
+		return errors.New("must not set both 'every' and 'cron' properties")
+	}
+	if n.Every == 0 && n.Cron == "" {
+		return errors.New("must define one of 'every' or 'cron'")
+	}
+	if n.Every < 0 {
+		return errors.New("'every' must not be negative")
+	}
+	tags := make(map[string]bool, len(n.TagColumns))
+	for _, t := range n.TagColumns {
+		if t == "" {
+			return errors.New("tag columns must not be empty")
+		}
+		if t == n.TimeColumn {
+			return fmt.Errorf("column %q cannot be both the time column and a tag", t)
+		}
+		tags[t] = true
+	}
+	for _, d := range n.Dimensions {
+		if !tags[d] {
+			return fmt.Errorf("cannot group by %q, it is not a tag column", d)
+		}
+	}
+	return nil
+}
+
+// The columns read as tags.
+// tick:property
+func (n *FileNode) Tags(columns ...string) *FileNode {
+	n.TagColumns = columns
+	return n
+}
+
+// Group the batches by a set of tag columns.
+//
+// Example:
+//    batch
+//        |file('/etc/kapacitor/hosts.csv')
+//            .every(1h)
+//            .tags('host', 'dc')
+//            .groupBy('dc')
+//
+// tick:property
+func (n *FileNode) GroupBy(tags ...string) *FileNode {
+	n.Dimensions = tags
+	return n
+}
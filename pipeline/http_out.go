@@ -12,6 +12,10 @@ import (
 // For example if the task endpoint is at `/kapacitor/v1/tasks/<task_id>` and endpoint is
 // `top10`, then the data can be requested from `/kapacitor/v1/tasks/<task_id>/top10`.
 //
+// Clients may also open a WebSocket on the same endpoint.
+// The current data is sent once the connection is established,
+// then each updated series is pushed as a result containing only that series.
+//
 // Example:
 //    stream
 //        |window()
@@ -32,6 +32,11 @@ const DefaultFlushInterval = time.Second * 10
 //    * points_written -- number of points written to InfluxDB
 //    * write_errors -- number of errors attempting to write to InfluxDB
 //
+// Points are buffered by database, retention policy, write consistency and precision,
+// and written in batches of the buffer size, or after the flush interval.
+// Writing is slower than processing points for many tasks,
+// so that the writers and gzip properties help tasks such as downsampling write larger volumes.
+//
 type InfluxDBOutNode struct {
 	node `json:"-"`
 
@@ -54,6 +59,12 @@ type InfluxDBOutNode struct {
 	// Write points to InfluxDB after interval even if buffer is not full.
 	// Default: 10s
 	FlushInterval time.Duration `json:"flushInterval"`
+	// Number of batches of each database written to InfluxDB concurrently.
+	// Zero or one writes the batches of a database one at a time.
+	Writers int64 `json:"writers,omitempty"`
+	// Compress the points written to InfluxDB with gzip.
+	// tick:ignore
+	GzipFlag bool `tick:"Gzip" json:"gzip,omitempty"`
 	// Static set of tags to add to all data points before writing them.
 	// tick:ignore
 	Tags map[string]string `tick:"Tag" json:"tags"`
@@ -142,3 +153,19 @@ func (i *InfluxDBOutNode) Create() *InfluxDBOutNode {
 	i.CreateFlag = true
 	return i
 }
+
+// Gzip compresses the points written to InfluxDB,
+// trading CPU for network bandwidth on large writes.
+//
+// tick:property
+func (i *InfluxDBOutNode) Gzip() *InfluxDBOutNode {
+	i.GzipFlag = true
+	return i
+}
+
+func (i *InfluxDBOutNode) validate() error {
+	if i.Writers < 0 {
+		return fmt.Errorf("writers must be >= 0, got %d", i.Writers)
+	}
+	return nil
+}
@@ -50,6 +50,12 @@ type InfluxQLNode struct {
 
 	// tick:ignore
 	Args []interface{} `json:"args"`
+
+	// Number of shards processing the groups of the node in parallel.
+	// The groups are distributed to the shards by their ID,
+	// so that the points of each group are still processed in order.
+	// Zero or one processes all groups on a single goroutine.
+	Shards int64 `json:"shards,omitempty"`
 }
 
 func newInfluxQLNode(method, field string, wants, provides EdgeType, reducer ReduceCreater) *InfluxQLNode {
@@ -62,6 +68,13 @@ func newInfluxQLNode(method, field string, wants, provides EdgeType, reducer Red
 	}
 }
 
+func (n *InfluxQLNode) validate() error {
+	if n.Shards < 0 {
+		return fmt.Errorf("shards must be >= 0, got %d", n.Shards)
+	}
+	return nil
+}
+
 // MarshalJSON converts InfluxQLNode to JSON
 // tick:ignore
 func (n *InfluxQLNode) MarshalJSON() ([]byte, error) {
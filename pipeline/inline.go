@@ -0,0 +1,129 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/influxdata/kapacitor/lua"
+)
+
+// Default maximum number of steps executed by each run of an inline script.
+const DefaultInlineMaxSteps = 100000
+
+// An InlineNode transforms the data with a Lua script, for small custom transformations
+// that do not justify a UDF.
+// The script runs inside Kapacitor, it has no access to the host and the number of steps,
+// i.e. instructions of the Lua virtual machine, it executes is limited, as is the length of strings.
+// Lua 5.1 is supported, as implemented by gopher-lua, along with its base functions and math, string and table libraries.
+// Lua numbers are floats: integer fields stay integers if their values are integral,
+// and times are only exact to about a microsecond unless they are not changed.
+//
+// For stream data the script runs once per point with the globals:
+//
+//    * name -- the measurement of the point
+//    * time -- the time of the point as nanoseconds since the epoch
+//    * tags -- a table of the tags of the point
+//    * fields -- a table of the fields of the point
+//
+// The point is updated with the values of the globals after the script ran,
+// so the script modifies the point by changing the globals, e.g. setting fields.x = nil removes the field x.
+// The tags the data is grouped by cannot be changed.
+// If the script returns false the point is dropped.
+//
+// Example:
+//    stream
+//        |from()
+//            .measurement('disk')
+//            .groupBy('host')
+//        |inline('''
+//            fields.used_percent = 100 * fields.used / fields.total
+//            if fields.used_percent > 90 then
+//                tags.tier = 'full'
+//            end
+//        ''')
+//
+// For batch data the script runs once per batch with the globals name, tags, the tags of the group,
+// and points, a sequence of tables with the time, tags and fields of each point.
+// The batch is updated with the points of the sequence after the script ran,
+// so the script may add, remove or modify points.
+// If the script returns false the batch is dropped.
+//
+// Global variables set by the script persist across runs, so the script may keep state.
+// If the script fails, the point or batch is dropped and the error is logged unless the node is quiet.
+//
+// Available Statistics:
+//
+//    * inline_errors -- number of points or batches dropped because the script failed
+//
+type InlineNode struct {
+	chainnode
+
+	// The Lua script.
+	// tick:ignore
+	Script string `json:"script"`
+
+	// Maximum number of steps executed by each run of the script, the run fails if it is exceeded.
+	// If zero the number of steps is not limited.
+	// Defaults to 100000.
+	MaxSteps int64 `json:"maxSteps"`
+}
+
+func newInlineNode(wants EdgeType, script string) *InlineNode {
+	return &InlineNode{
+		chainnode: newBasicChainNode("inline", wants, wants),
+		Script:    script,
+		MaxSteps:  DefaultInlineMaxSteps,
+	}
+}
+
+// MarshalJSON converts InlineNode to JSON
+// tick:ignore
+func (n *InlineNode) MarshalJSON() ([]byte, error) {
+	type Alias InlineNode
+	var raw = &struct {
+		TypeOf
+		*Alias
+	}{
+		TypeOf: TypeOf{
+			Type: "inline",
+			ID:   n.ID(),
+		},
+		Alias: (*Alias)(n),
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON converts JSON to an InlineNode
+// tick:ignore
+func (n *InlineNode) UnmarshalJSON(data []byte) error {
+	type Alias InlineNode
+	var raw = &struct {
+		TypeOf
+		*Alias
+	}{
+		Alias: (*Alias)(n),
+	}
+	err := json.Unmarshal(data, raw)
+	if err != nil {
+		return err
+	}
+	if raw.Type != "inline" {
+		return fmt.Errorf("error unmarshaling node %d of type %s as InlineNode", raw.ID, raw.Type)
+	}
+	n.setID(raw.ID)
+	return nil
+}
+
+func (n *InlineNode) validate() error {
+	if n.Script == "" {
+		return errors.New("must specify a script")
+	}
+	if n.MaxSteps < 0 {
+		return errors.New("maxSteps must not be negative")
+	}
+	if _, err := lua.Compile(n.Script); err != nil {
+		return fmt.Errorf("invalid script: %v", err)
+	}
+	return nil
+}
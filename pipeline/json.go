@@ -174,6 +174,7 @@ func init() {
 	sourceFilters = map[string]func([]byte, Node) (Node, error){
 		"from":  unmarshalFrom,
 		"query": unmarshalQuery,
+		"file":  unmarshalFile,
 	}
 
 	// Add default construction of chain nodes
@@ -191,9 +192,14 @@ func init() {
 		"k8sAutoscale":      func(parent chainnodeAlias) Node { return parent.K8sAutoscale() },
 		"influxdbOut":       func(parent chainnodeAlias) Node { return parent.InfluxDBOut() },
 		"httpPost":          func(parent chainnodeAlias) Node { return parent.HttpPost() },
+		"objectStoreOut":    func(parent chainnodeAlias) Node { return parent.ObjectStoreOut("") },
 		"httpOut":           func(parent chainnodeAlias) Node { return parent.HttpOut("") },
 		"flatten":           func(parent chainnodeAlias) Node { return parent.Flatten() },
 		"eval":              func(parent chainnodeAlias) Node { return parent.Eval() },
+		"exec":              func(parent chainnodeAlias) Node { return parent.Exec("") },
+		"wasm":              func(parent chainnodeAlias) Node { return parent.Wasm("", "") },
+		"inline":            func(parent chainnodeAlias) Node { return parent.Inline("") },
+		"onnx":              func(parent chainnodeAlias) Node { return parent.Onnx("") },
 		"derivative":        func(parent chainnodeAlias) Node { return parent.Derivative("") },
 		"changeDetect":      func(parent chainnodeAlias) Node { return parent.ChangeDetect("") },
 		"delete":            func(parent chainnodeAlias) Node { return parent.Delete() },
@@ -408,6 +414,16 @@ func unmarshalQuery(data []byte, source Node) (Node, error) {
 	return child, err
 }
 
+func unmarshalFile(data []byte, source Node) (Node, error) {
+	batch, ok := source.(*BatchNode)
+	if !ok {
+		return nil, fmt.Errorf("parent of file node must be a BatchNode but is %T", source)
+	}
+	child := batch.File("")
+	err := json.Unmarshal(data, child)
+	return child, err
+}
+
 func unmarshalWhere(data []byte, parents []Node, typ TypeOf) (Node, error) {
 	if len(parents) != 1 {
 		return nil, fmt.Errorf("expected one parent for node %d but found %d", typ.ID, len(parents))
@@ -527,6 +543,7 @@ type chainnodeAlias interface {
 	Distinct(string) *InfluxQLNode
 	Elapsed(string, time.Duration) *InfluxQLNode
 	Eval(...*ast.LambdaNode) *EvalNode
+	Exec(string, ...string) *ExecNode
 	First(string) *InfluxQLNode
 	Flatten() *FlattenNode
 	HoltWinters(string, int64, int64, time.Duration) *InfluxQLNode
@@ -547,6 +564,7 @@ type chainnodeAlias interface {
 	Mode(string) *InfluxQLNode
 	MovingAverage(string, int64) *InfluxQLNode
 	Name() string
+	ObjectStoreOut(string) *ObjectStoreOutNode
 	Parents() []Node
 	Percentile(string, float64) *InfluxQLNode
 	Provides() EdgeType
@@ -564,6 +582,9 @@ type chainnodeAlias interface {
 	Top(int64, string, ...string) *InfluxQLNode
 	Union(...Node) *UnionNode
 	Wants() EdgeType
+	Wasm(string, string) *WasmNode
+	Inline(string) *InlineNode
+	Onnx(string) *OnnxNode
 	Window() *WindowNode
 	addParent(Node)
 	dot(*bytes.Buffer)
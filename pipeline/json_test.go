@@ -258,7 +258,8 @@ func TestPipeline_MarshalJSON(t *testing.T) {
             "talk": null,
             "mqtt": null,
             "snmpTrap": null,
-            "kafka": null
+            "kafka": null,
+            "amqp": null
         },
         {
             "typeOf": "httpOut",
@@ -962,6 +963,72 @@ func Test_unmarshalQuery(t *testing.T) {
 	}
 }
 
+func Test_unmarshalFile(t *testing.T) {
+	type args struct {
+		data   []byte
+		source Node
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    Node
+		wantErr bool
+	}{
+		{
+			name: "should error when parent isn't a BatchNode",
+			args: args{
+				source: &MockNode{},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unmarshal file",
+			args: args{
+				source: &BatchNode{},
+				data: []byte(`{
+                    "typeOf": "file",
+                    "id": "2",
+                    "path": "/etc/kapacitor/hosts.csv",
+                    "format": "csv",
+                    "every": "1h",
+                    "tags": ["host", "dc"],
+                    "groupBy": ["dc"]
+                }`),
+			},
+			want: &FileNode{
+				Path:       "/etc/kapacitor/hosts.csv",
+				Format:     "csv",
+				Every:      time.Hour,
+				TagColumns: []string{"host", "dc"},
+				Dimensions: []string{"dc"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.wantErr {
+				p := &Pipeline{}
+				p1 := tt.args.source
+				p.addSource(p1)
+			}
+			got, err := unmarshalFile(tt.args.data, tt.args.source)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unmarshalFile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			var cmpOptions = cmp.Options{
+				cmpopts.IgnoreUnexported(FileNode{}),
+			}
+			if !cmp.Equal(got, tt.want, cmpOptions...) {
+				t.Errorf("unmarshalFile() =-got/+want\n%s", cmp.Diff(got, tt.want, cmpOptions...))
+			}
+		})
+	}
+}
+
 func Test_unmarshalStats(t *testing.T) {
 	type args struct {
 		data    []byte
@@ -1302,6 +1369,8 @@ func (m *MockNode) Parents() []Node              { return nil }
 func (m *MockNode) Children() []Node             { return nil }
 func (m *MockNode) addParent(p Node)             {}
 func (m *MockNode) linkChild(c Node)             {}
+func (m *MockNode) setParents(ps []Node)         {}
+func (m *MockNode) setChildren(cs []Node)        {}
 func (m *MockNode) Desc() string                 { return "" }
 func (m *MockNode) Name() string                 { return "" }
 func (m *MockNode) SetName(string)               {}
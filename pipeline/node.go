@@ -46,6 +46,9 @@ type Node interface {
 	addParent(p Node)
 	// Links a child node by adding both the parent and child relation.
 	linkChild(c Node)
+	// Replace the parents or children, without changing the relations of the other nodes.
+	setParents(ps []Node)
+	setChildren(cs []Node)
 
 	// Short description of the node does not need to be unique
 	Desc() string
@@ -156,6 +159,14 @@ func (n *node) linkChild(c Node) {
 	c.addParent(n)
 }
 
+func (n *node) setParents(ps []Node) {
+	n.parents = ps
+}
+
+func (n *node) setChildren(cs []Node) {
+	n.children = cs
+}
+
 func (n *node) tMark() bool {
 	return n.tm
 }
@@ -352,6 +363,47 @@ func (n *chainnode) HttpPost(url ...string) *HTTPPostNode {
 	return h
 }
 
+// Create an exec node that streams the data to the STDIN of a command.
+// See ExecNode
+func (n *chainnode) Exec(executable string, args ...string) *ExecNode {
+	e := newExecNode(n.provides, append([]string{executable}, args...)...)
+	n.linkChild(e)
+	return e
+}
+
+// Create a wasm node that transforms the data with a function exported by a WebAssembly module,
+// the module is encoded in base64.
+// See WasmNode
+func (n *chainnode) Wasm(module, function string) *WasmNode {
+	w := newWasmNode(n.provides, module, function)
+	n.linkChild(w)
+	return w
+}
+
+// Create an inline node that transforms the data with a Lua script.
+// See InlineNode
+func (n *chainnode) Inline(script string) *InlineNode {
+	i := newInlineNode(n.provides, script)
+	n.linkChild(i)
+	return i
+}
+
+// Create an onnx node that scores the data with an ONNX model.
+// See OnnxNode
+func (n *chainnode) Onnx(model string) *OnnxNode {
+	o := newOnnxNode(n.provides, model)
+	n.linkChild(o)
+	return o
+}
+
+// Create an object store output node that writes each batch as an object to S3, GCS or a local file.
+// The url is a template executed for each batch.
+func (n *chainnode) ObjectStoreOut(url string) *ObjectStoreOutNode {
+	o := newObjectStoreOutNode(n.provides, url)
+	n.linkChild(o)
+	return o
+}
+
 // Create an influxdb output node that will store the incoming data into InfluxDB.
 func (n *chainnode) InfluxDBOut() *InfluxDBOutNode {
 	i := newInfluxDBOutNode(n.provides)
@@ -0,0 +1,150 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+)
+
+const (
+	// ObjectFormatLine writes batches as InfluxDB line protocol.
+	ObjectFormatLine = "line"
+	// ObjectFormatCSV writes batches as CSV files with a header row.
+	ObjectFormatCSV = "csv"
+	// ObjectFormatParquet is recognized but not yet supported.
+	ObjectFormatParquet = "parquet"
+)
+
+// An ObjectStoreOutNode writes each incoming batch as an object to S3, GCS or the local file system.
+// This is useful for cheap long-term archiving of aggregates computed in Kapacitor.
+// Batches are passed on unchanged.
+//
+// The object URL is a template executed for each batch.
+// The template data has the following fields:
+//
+//    * TaskName -- the name of the task
+//    * Name -- the measurement name of the batch
+//    * Group -- the group ID of the batch
+//    * Tags -- the group tags of the batch
+//    * Time -- the time of the batch
+//
+// Supported URLs are local paths, file:// URLs, s3://bucket/key and gs://bucket/object URLs.
+// The region and endpoint of S3 objects can be set with the `region` and
+// `endpoint` query parameters.
+//
+// Example:
+//    stream
+//        |from()
+//            .measurement('cpu')
+//            .groupBy('host')
+//        |window()
+//            .period(1h)
+//            .every(1h)
+//        |mean('usage_idle')
+//        |objectStoreOut('s3://archive/{{ .TaskName }}/{{ index .Tags "host" }}/{{ .Time.Format "2006-01-02T15" }}.csv')
+//            .format('csv')
+//
+// The above example archives the hourly mean of each host as a CSV object.
+//
+// Parquet objects are not supported.
+//
+// Available Statistics:
+//
+//    * objects_written -- number of objects written
+//    * write_errors -- number of errors writing objects
+//
+type ObjectStoreOutNode struct {
+	chainnode
+
+	// The template of the object URL.
+	// tick:ignore
+	URL string `json:"url"`
+
+	// The format of the objects, either 'line' or 'csv'.
+	// Defaults to 'line'.
+	Format string `json:"format"`
+
+	// Timeout for writing an object.
+	// If zero no timeout is used.
+	Timeout time.Duration `json:"timeout"`
+}
+
+func newObjectStoreOutNode(wants EdgeType, url string) *ObjectStoreOutNode {
+	return &ObjectStoreOutNode{
+		chainnode: newBasicChainNode("object_store_out", wants, wants),
+		URL:       url,
+		Format:    ObjectFormatLine,
+	}
+}
+
+// MarshalJSON converts ObjectStoreOutNode to JSON
+// tick:ignore
+func (n *ObjectStoreOutNode) MarshalJSON() ([]byte, error) {
+	type Alias ObjectStoreOutNode
+	var raw = &struct {
+		TypeOf
+		*Alias
+		Timeout string `json:"timeout"`
+	}{
+		TypeOf: TypeOf{
+			Type: "objectStoreOut",
+			ID:   n.ID(),
+		},
+		Alias:   (*Alias)(n),
+		Timeout: influxql.FormatDuration(n.Timeout),
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON converts JSON to an ObjectStoreOutNode
+// tick:ignore
+func (n *ObjectStoreOutNode) UnmarshalJSON(data []byte) error {
+	type Alias ObjectStoreOutNode
+	var raw = &struct {
+		TypeOf
+		*Alias
+		Timeout string `json:"timeout"`
+	}{
+		Alias: (*Alias)(n),
+	}
+	err := json.Unmarshal(data, raw)
+	if err != nil {
+		return err
+	}
+	if raw.Type != "objectStoreOut" {
+		return fmt.Errorf("error unmarshaling node %d of type %s as ObjectStoreOutNode", raw.ID, raw.Type)
+	}
+	n.Timeout, err = influxql.ParseDuration(raw.Timeout)
+	if err != nil {
+		return err
+	}
+	n.setID(raw.ID)
+	return nil
+}
+
+func (n *ObjectStoreOutNode) validate() error {
+	if n.Wants() != BatchEdge {
+		return errors.New("objectStoreOut requires batch data, use a window to create batches from a stream")
+	}
+	if n.URL == "" {
+		return errors.New("must specify an object url")
+	}
+	if _, err := template.New("url").Parse(n.URL); err != nil {
+		return fmt.Errorf("invalid object url template: %v", err)
+	}
+	switch n.Format {
+	case ObjectFormatLine, ObjectFormatCSV:
+	case ObjectFormatParquet:
+		return errors.New("parquet objects are not supported")
+	default:
+		return fmt.Errorf("invalid object format %q, must be %q or %q", n.Format, ObjectFormatLine, ObjectFormatCSV)
+	}
+	if n.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// An OnnxNode scores each point with a pre-trained ONNX model,
+// so that anomaly detection, classification or regression models exported from
+// common machine learning frameworks can run inside the pipeline.
+// The model is evaluated inside Kapacitor, the operators used by linear models
+// and feed forward neural networks are supported.
+//
+// The model must have a single input, whose values are the fields listed with the 'fields' property, in order.
+// Dimensions of the input which are not fixed, e.g. the batch dimension, have a size of 1,
+// so the input must have one element per field.
+// The values of the outputs of the model, all outputs in the order of the model unless the 'outputs' property is set,
+// are stored as the fields named with the 'as' property, one field per value.
+// Values of integer outputs, e.g. the label of a classifier, are stored as integer fields.
+//
+// Example:
+//    stream
+//        |from()
+//            .measurement('cpu')
+//            .groupBy('host')
+//        |onnx('/etc/kapacitor/models/anomaly.onnx')
+//            .fields('usage_user', 'usage_system', 'usage_iowait')
+//            .outputs('label', 'probabilities')
+//            .as('anomaly', 'p_normal', 'p_anomaly')
+//        |alert()
+//            .crit(lambda: "anomaly" == 1)
+//
+// For batch data, e.g. windows, each point of the batch is scored.
+// The model is loaded when the task starts.
+// Points which cannot be scored, e.g. because a field is missing, are dropped and the error is logged
+// unless the node is quiet.
+//
+// Available Statistics:
+//
+//    * onnx_errors -- number of points dropped because they could not be scored
+//
+type OnnxNode struct {
+	chainnode
+
+	// The path of the ONNX model.
+	// tick:ignore
+	Model string `json:"model"`
+
+	// The fields passed as the input of the model.
+	// tick:ignore
+	FieldsList []string `tick:"Fields" json:"fields"`
+
+	// The outputs of the model which are stored as fields.
+	// tick:ignore
+	OutputsList []string `tick:"Outputs" json:"outputs"`
+
+	// The fields the values of the outputs are stored as.
+	// tick:ignore
+	AsList []string `tick:"As" json:"as"`
+}
+
+func newOnnxNode(wants EdgeType, model string) *OnnxNode {
+	return &OnnxNode{
+		chainnode: newBasicChainNode("onnx", wants, wants),
+		Model:     model,
+	}
+}
+
+// MarshalJSON converts OnnxNode to JSON
+// tick:ignore
+func (n *OnnxNode) MarshalJSON() ([]byte, error) {
+	type Alias OnnxNode
+	var raw = &struct {
+		TypeOf
+		*Alias
+	}{
+		TypeOf: TypeOf{
+			Type: "onnx",
+			ID:   n.ID(),
+		},
+		Alias: (*Alias)(n),
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON converts JSON to an OnnxNode
+// tick:ignore
+func (n *OnnxNode) UnmarshalJSON(data []byte) error {
+	type Alias OnnxNode
+	var raw = &struct {
+		TypeOf
+		*Alias
+	}{
+		Alias: (*Alias)(n),
+	}
+	err := json.Unmarshal(data, raw)
+	if err != nil {
+		return err
+	}
+	if raw.Type != "onnx" {
+		return fmt.Errorf("error unmarshaling node %d of type %s as OnnxNode", raw.ID, raw.Type)
+	}
+	n.setID(raw.ID)
+	return nil
+}
+
+func (n *OnnxNode) validate() error {
+	if n.Model == "" {
+		return errors.New("must specify a model")
+	}
+	if len(n.FieldsList) == 0 {
+		return errors.New("must specify at least one field with .fields()")
+	}
+	if len(n.AsList) == 0 {
+		return errors.New("must specify at least one result name with .as()")
+	}
+	return nil
+}
+
+// The fields passed as the input of the model, in order.
+// tick:property
+func (n *OnnxNode) Fields(fields ...string) *OnnxNode {
+	n.FieldsList = fields
+	return n
+}
+
+// The names of the outputs of the model which are stored as fields, in order.
+// Defaults to all the outputs of the model.
+// tick:property
+func (n *OnnxNode) Outputs(outputs ...string) *OnnxNode {
+	n.OutputsList = outputs
+	return n
+}
+
+// The names of the fields the values of the outputs are stored as, in order.
+// tick:property
+func (n *OnnxNode) As(names ...string) *OnnxNode {
+	n.AsList = names
+	return n
+}
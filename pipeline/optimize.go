@@ -0,0 +1,228 @@
+package pipeline
+
+import (
+	"encoding/json"
+
+	"github.com/influxdata/kapacitor/tick/ast"
+	"github.com/influxdata/kapacitor/tick/stateful"
+)
+
+// Optimize rewrites the pipeline so that it does less work for the same results,
+// which helps generated tasks that repeat the same filters across many branches.
+//
+//   - Sibling from(), where() and groupBy() nodes with the same properties are merged,
+//     the merged node has the children of all of them.
+//   - A where() node is moved ahead of the eval(), default() and delete() nodes before it,
+//     when its filter does not depend on the fields and tags those nodes change.
+//
+// Nodes with stats are not rewritten, so that their stats are still collected.
+// Optimize returns the number of nodes merged or moved.
+// tick:ignore
+func Optimize(p *Pipeline) int {
+	o := &optimizer{
+		nodes:   make(map[ID]Node),
+		stats:   make(map[ID]bool),
+		visited: make(map[ID]bool),
+	}
+	for _, src := range p.sources {
+		if s, ok := src.(*StatsNode); ok {
+			o.stats[s.SourceNode.ID()] = true
+		}
+	}
+	// Collect the nodes before rewriting the pipeline, to reset their marks afterwards.
+	var nodes []Node
+	_ = p.Walk(func(n Node) error {
+		nodes = append(nodes, n)
+		o.nodes[n.ID()] = n
+		return nil
+	})
+	for _, n := range nodes {
+		if w, ok := n.(*WhereNode); ok {
+			for o.pushWhere(w) {
+			}
+		}
+	}
+	for _, src := range p.sources {
+		o.mergeChildren(src)
+	}
+	if o.rewrites > 0 {
+		for _, n := range nodes {
+			n.setPMark(false)
+		}
+		p.sorted = nil
+	}
+	return o.rewrites
+}
+
+// optimizer identifies the nodes by their ID,
+// since the parents of a node are the nodes embedded in its parents.
+type optimizer struct {
+	nodes map[ID]Node
+	// Nodes with stats
+	stats map[ID]bool
+	// Nodes whose children are merged
+	visited  map[ID]bool
+	rewrites int
+}
+
+// mergeChildren merges the identical children of n, then the children of its children.
+func (o *optimizer) mergeChildren(n Node) {
+	if o.visited[n.ID()] {
+		return
+	}
+	o.visited[n.ID()] = true
+	var (
+		merged []Node
+		keys   []string
+	)
+	for _, c := range n.Children() {
+		key := o.mergeKey(c)
+		var into Node
+		if key != "" {
+			for i, m := range merged {
+				if keys[i] == key && !sharesChild(m, c) {
+					into = m
+					break
+				}
+			}
+		}
+		if into == nil {
+			merged = append(merged, c)
+			keys = append(keys, key)
+			continue
+		}
+		for _, gc := range c.Children() {
+			replaceNode(gc.Parents(), c, into)
+			into.setChildren(append(into.Children(), gc))
+		}
+		c.setChildren(nil)
+		o.rewrites++
+	}
+	n.setChildren(merged)
+	for _, c := range merged {
+		o.mergeChildren(c)
+	}
+}
+
+// mergeKey returns the properties of a node that can be merged with its identical siblings,
+// or an empty key if the node cannot be merged.
+func (o *optimizer) mergeKey(n Node) string {
+	switch n.(type) {
+	case *FromNode, *WhereNode, *GroupByNode:
+	default:
+		return ""
+	}
+	if len(n.Parents()) != 1 || o.stats[n.ID()] {
+		return ""
+	}
+	data, err := json.Marshal(n)
+	if err != nil {
+		return ""
+	}
+	var props map[string]interface{}
+	if err := json.Unmarshal(data, &props); err != nil {
+		return ""
+	}
+	delete(props, "id")
+	// The keys of maps are marshaled in order
+	key, err := json.Marshal(props)
+	if err != nil {
+		return ""
+	}
+	return string(key)
+}
+
+// pushWhere moves the where node ahead of its parent if it is safe,
+// and reports whether it was moved.
+func (o *optimizer) pushWhere(w *WhereNode) bool {
+	if len(w.Parents()) != 1 || o.stats[w.ID()] || w.Lambda == nil || !isStateless(w.Lambda) {
+		return false
+	}
+	parent := o.nodes[w.Parents()[0].ID()]
+	if len(parent.Children()) != 1 || len(parent.Parents()) != 1 || o.stats[parent.ID()] {
+		return false
+	}
+	refs := ast.FindReferenceVariables(w.Lambda)
+	switch n := parent.(type) {
+	case *EvalNode:
+		// Only an eval keeping all fields leaves the other fields unchanged,
+		// and stateful functions must see all points.
+		if !n.KeepFlag || len(n.KeepList) > 0 || containsAny(n.AsList, refs) || containsAny(n.TagsList, refs) {
+			return false
+		}
+		for _, l := range n.Lambdas {
+			if !isStateless(l) {
+				return false
+			}
+		}
+	case *DefaultNode:
+		for _, r := range refs {
+			if _, ok := n.Fields[r]; ok {
+				return false
+			}
+			if _, ok := n.Tags[r]; ok {
+				return false
+			}
+		}
+	case *DeleteNode:
+		if containsAny(n.Fields, refs) || containsAny(n.Tags, refs) {
+			return false
+		}
+	default:
+		return false
+	}
+
+	grandparent := o.nodes[parent.Parents()[0].ID()]
+	replaceNode(grandparent.Children(), parent, w)
+	children := w.Children()
+	for _, c := range children {
+		replaceNode(c.Parents(), w, parent)
+	}
+	parent.setChildren(children)
+	parent.setParents([]Node{w})
+	w.setChildren([]Node{parent})
+	w.setParents([]Node{grandparent})
+	o.rewrites++
+	return true
+}
+
+func isStateless(l *ast.LambdaNode) bool {
+	for _, f := range ast.FindFunctionCalls(l) {
+		if !stateful.IsStateless(f) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAny(names, refs []string) bool {
+	for _, n := range names {
+		for _, r := range refs {
+			if n == r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sharesChild reports whether the nodes have a common child.
+func sharesChild(a, b Node) bool {
+	for _, ac := range a.Children() {
+		for _, bc := range b.Children() {
+			if ac.ID() == bc.ID() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// replaceNode replaces the node old of the nodes with n.
+func replaceNode(nodes []Node, old, n Node) {
+	for i := range nodes {
+		if nodes[i].ID() == old.ID() {
+			nodes[i] = n
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/influxdata/kapacitor/tick/stateful"
+)
+
+func TestOptimize(t *testing.T) {
+	testCases := []struct {
+		name     string
+		script   string
+		rewrites int
+		dot      string
+	}{
+		{
+			name: "merge identical branches",
+			script: `
+stream
+	|from()
+		.measurement('cpu')
+		.where(lambda: "host" == 'a')
+	|groupBy('cpu')
+	|where(lambda: "usage" > 90)
+	|httpOut('high')
+
+stream
+	|from()
+		.measurement('cpu')
+		.where(lambda: "host" == 'a')
+	|groupBy('cpu')
+	|where(lambda: "usage" < 10)
+	|httpOut('low')
+`,
+			rewrites: 2,
+			dot: `digraph test {
+stream0 -> from1;
+from1 -> groupby2;
+groupby2 -> where3;
+groupby2 -> where7;
+where7 -> http_out8;
+where3 -> http_out4;
+}`,
+		},
+		{
+			name: "different properties",
+			script: `
+stream
+	|from()
+		.measurement('cpu')
+	|httpOut('cpu')
+
+stream
+	|from()
+		.measurement('mem')
+	|httpOut('mem')
+`,
+			dot: `digraph test {
+stream0 -> from1;
+stream0 -> from3;
+from3 -> http_out4;
+from1 -> http_out2;
+}`,
+		},
+		{
+			name: "push where ahead of eval",
+			script: `
+stream
+	|from()
+		.measurement('cpu')
+	|eval(lambda: "usage" * 100.0)
+		.as('percent')
+		.keep()
+	|default()
+		.field('idle', 0.0)
+	|where(lambda: "host" == 'a')
+	|httpOut('a')
+`,
+			rewrites: 2,
+			dot: `digraph test {
+stream0 -> from1;
+from1 -> where4;
+where4 -> eval2;
+eval2 -> default3;
+default3 -> http_out5;
+}`,
+		},
+		{
+			name: "where depends on eval",
+			script: `
+stream
+	|from()
+		.measurement('cpu')
+	|eval(lambda: "usage" * 100.0)
+		.as('percent')
+		.keep()
+	|where(lambda: "percent" > 90)
+	|httpOut('a')
+`,
+			dot: `digraph test {
+stream0 -> from1;
+from1 -> eval2;
+eval2 -> where3;
+where3 -> http_out4;
+}`,
+		},
+		{
+			name: "eval with stateful function",
+			script: `
+stream
+	|from()
+		.measurement('cpu')
+	|eval(lambda: sigma("usage"))
+		.as('sigma')
+		.keep()
+	|where(lambda: "host" == 'a')
+	|httpOut('a')
+`,
+			dot: `digraph test {
+stream0 -> from1;
+from1 -> eval2;
+eval2 -> where3;
+where3 -> http_out4;
+}`,
+		},
+		{
+			name: "merged node with stats",
+			script: `
+var cpu = stream
+	|from()
+		.measurement('cpu')
+cpu
+	|httpOut('a')
+cpu
+	|stats(10s)
+	|httpOut('stats')
+
+stream
+	|from()
+		.measurement('cpu')
+	|httpOut('b')
+`,
+			dot: `digraph test {
+stream0 -> from1;
+stream0 -> from5;
+from5 -> http_out6;
+from1 -> http_out2;
+stats3 -> http_out4;
+}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := CreatePipeline(tc.script, StreamEdge, stateful.NewScope(), deadman{}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Optimize(p); got != tc.rewrites {
+				t.Errorf("unexpected rewrites got %d exp %d", got, tc.rewrites)
+			}
+			if got := string(p.Dot("test")); got != tc.dot {
+				t.Errorf("unexpected pipeline\ngot\n%s\nexp\n%s", got, tc.dot)
+			}
+			if err := Validate(p); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -169,6 +169,29 @@ func (p *Pipeline) Walk(f func(n Node) error) error {
 	return nil
 }
 
+// LinkLeafNoOps links a NoOpNode to each node without children that emits data,
+// so that the output of the leaves of the pipeline can be tapped.
+// Returns the leaf nodes.
+// tick:ignore
+func (p *Pipeline) LinkLeafNoOps() []Node {
+	var leaves []Node
+	_ = p.Walk(func(n Node) error {
+		if len(n.Children()) == 0 && n.Provides() != NoEdge {
+			leaves = append(leaves, n)
+		}
+		return nil
+	})
+	for _, n := range leaves {
+		n.linkChild(newNoOpNode(n.Provides()))
+	}
+	// Sort the pipeline again with the new nodes.
+	for _, n := range p.sorted {
+		n.setPMark(false)
+	}
+	p.sorted = nil
+	return leaves
+}
+
 func (p *Pipeline) sort() {
 	// Iterate the sources in reverse order
 	for i := len(p.sources) - 1; i >= 0; i-- {
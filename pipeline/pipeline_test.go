@@ -67,6 +67,40 @@ w.every(1s)
 	}
 }
 
+func TestTICK_To_Pipeline_AlertExec(t *testing.T) {
+	var tickScript = `
+stream
+	|from()
+	|alert()
+		.exec('/usr/bin/notify')
+	|exec('/usr/bin/cat')
+		.capture()
+`
+
+	scope := stateful.NewScope()
+	p, err := CreatePipeline(tickScript, StreamEdge, scope, deadman{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, ok := p.sources[0].Children()[0].Children()[0].(*AlertNode)
+	if !ok {
+		t.Fatalf("unexpected node type: exp AlertNode got %T", p.sources[0].Children()[0].Children()[0])
+	}
+	if exp, got := []string{"/usr/bin/notify"}, a.ExecHandlers[0].Command; !reflect.DeepEqual(exp, got) {
+		t.Errorf("unexpected alert exec command exp %v got %v", exp, got)
+	}
+	e, ok := a.Children()[0].(*ExecNode)
+	if !ok {
+		t.Fatalf("unexpected node type: exp ExecNode got %T", a.Children()[0])
+	}
+	if exp, got := []string{"/usr/bin/cat"}, e.Command; !reflect.DeepEqual(exp, got) {
+		t.Errorf("unexpected exec command exp %v got %v", exp, got)
+	}
+	if !e.CaptureFlag {
+		t.Error("expected capture to be set")
+	}
+}
+
 func TestPipelineSort(t *testing.T) {
 	assert := assert.New(t)
 
@@ -134,3 +168,42 @@ func UnmarshalJSONTestHelper(t *testing.T, input []byte, node interface{}, wantE
 		t.Errorf("UnmarshalJSON() =\ngot:\n%#+v\nwant:\n%#+v\n", node, want)
 	}
 }
+
+func TestPipeline_LinkLeafNoOps(t *testing.T) {
+	var tickScript = `
+var data = stream
+	|from()
+	|window()
+		.period(10s)
+		.every(10s)
+
+data
+	|mean('value')
+
+data
+	|count('value')
+	|influxDBOut()
+		.database('db')
+`
+	p, err := CreatePipeline(tickScript, StreamEdge, stateful.NewScope(), deadman{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := p.Len()
+	leaves := p.LinkLeafNoOps()
+	var names []string
+	for _, l := range leaves {
+		names = append(names, l.Name())
+	}
+	if exp := []string{"mean3"}; !reflect.DeepEqual(exp, names) {
+		t.Errorf("unexpected leaves: exp %v got %v", exp, names)
+	}
+	if exp, got := n+1, p.Len(); exp != got {
+		t.Errorf("unexpected number of nodes: exp %d got %d", exp, got)
+	}
+	for _, l := range leaves {
+		if _, ok := l.Children()[0].(*NoOpNode); !ok {
+			t.Errorf("unexpected child of %s: exp NoOpNode got %T", l.Name(), l.Children()[0])
+		}
+	}
+}
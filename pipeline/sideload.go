@@ -17,10 +17,20 @@ import (
 // Add a field `cpu_threshold` and a tag `foo` to each point based on the value loaded from the hierarchical source.
 // The list of templates in the `.order()` property are evaluated using the points tags.
 // The files paths are checked then checked in order for the specified keys and the first value that is found is used.
+//
+// Paths can have wildcards, as in `dc/{{.dc}}/*.yml`, so that large hierarchies need not have one file per tag value:
+//        |sideload()
+//             .source('file:///path/to/dir')
+//             .order('host/{{.host}}.yml', 'dc/{{.dc}}/*.yml', 'default.yml')
+//             .field('cpu_threshold', 0.0)
+//
+// The keys missing from a file fall back to the next paths of the order, so the files are merged key by key.
 type SideloadNode struct {
 	chainnode
 
-	// Source for the data, currently only `file://` based sources are supported
+	// Source for the data, either a `file://` directory or the tar archive of a directory,
+	// optionally gzipped, at an `http://` or `https://` URL or an `s3://`, `gs://` or `az://` object.
+	// Remote sources are fetched again on the refresh interval of the sideload service.
 	Source string `json:"source"`
 
 	// Order is a list of paths that indicate the hierarchical order.
@@ -49,6 +59,8 @@ func newSideloadNode(wants EdgeType) *SideloadNode {
 // The paths are relative to the source and can have template markers like `{{.tagname}}` that will be replaced with the tag value of the point.
 // The paths are then searched in order for the keys and the first value that is found is used.
 // This allows for values to be overridden based on a hierarchy of tags.
+// A path can have the wildcards `*`, `?` and `[...]`, as in Go's path.Match, that match the files of a level,
+// the files matching a path are searched in lexical order. Wildcard characters in the tag values match literally.
 // tick:property
 func (n *SideloadNode) Order(order ...string) *SideloadNode {
 	n.OrderList = order
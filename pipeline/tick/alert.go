@@ -35,6 +35,7 @@ func (n *AlertNode) Build(a *pipeline.AlertNode) (ast.Node, error) {
 		Dot("warnReset", a.WarnReset).
 		Dot("critReset", a.CritReset).
 		Dot("history", a.History).
+		Dot("shards", a.Shards).
 		Dot("levelTag", a.LevelTag).
 		Dot("levelField", a.LevelField).
 		Dot("messageField", a.MessageField).
@@ -168,6 +169,15 @@ func (n *AlertNode) Build(a *pipeline.AlertNode) (ast.Node, error) {
 			Dot("template", h.Template)
 	}
 
+	for _, h := range a.AMQPHandlers {
+		n.Dot("amqp").
+			Dot("broker", h.Broker).
+			Dot("exchange", h.Exchange).
+			Dot("routingKey", h.RoutingKey).
+			Dot("template", h.Template).
+			Dot("contentType", h.ContentType)
+	}
+
 	for _, h := range a.AlertaHandlers {
 		n.Dot("alerta").
 			Dot("token", h.Token).
@@ -465,6 +465,32 @@ func TestAlertKafka(t *testing.T) {
 	PipelineTickTestHelper(t, pipe, want)
 }
 
+func TestAlertAmqp(t *testing.T) {
+	pipe, _, from := StreamFrom()
+	handler := from.Alert().Amqp()
+	handler.Broker = "default"
+	handler.Exchange = "alerts"
+	handler.RoutingKey = "alerts.{{ .Level }}"
+	handler.Template = "tmpl"
+	handler.ContentType = "text/plain"
+
+	want := `stream
+    |from()
+    |alert()
+        .id('{{ .Name }}:{{ .Group }}')
+        .message('{{ .ID }} is {{ .Level }}')
+        .details('{{ json . }}')
+        .history(21)
+        .amqp()
+        .broker('default')
+        .exchange('alerts')
+        .routingKey('alerts.{{ .Level }}')
+        .template('tmpl')
+        .contentType('text/plain')
+`
+	PipelineTickTestHelper(t, pipe, want)
+}
+
 func TestAlertAlerta(t *testing.T) {
 	pipe, _, from := StreamFrom()
 	handler := from.Alert().Alerta()
@@ -104,6 +104,10 @@ func (a *AST) Create(n pipeline.Node, parents []ast.Node) (ast.Node, error) {
 		return NewEc2Autoscale(parents).Build(node)
 	case *pipeline.EvalNode:
 		return NewEval(parents).Build(node)
+	case *pipeline.ExecNode:
+		return NewExec(parents).Build(node)
+	case *pipeline.FileNode:
+		return NewFile(parents).Build(node)
 	case *pipeline.FlattenNode:
 		return NewFlatten(parents).Build(node)
 	case *pipeline.FromNode:
@@ -124,6 +128,8 @@ func (a *AST) Create(n pipeline.Node, parents []ast.Node) (ast.Node, error) {
 		return NewKapacitorLoopbackNode(parents).Build(node)
 	case *pipeline.LogNode:
 		return NewLog(parents).Build(node)
+	case *pipeline.ObjectStoreOutNode:
+		return NewObjectStoreOut(parents).Build(node)
 	case *pipeline.QueryNode:
 		return NewQuery(parents).Build(node)
 	case *pipeline.SampleNode:
@@ -140,6 +146,12 @@ func (a *AST) Create(n pipeline.Node, parents []ast.Node) (ast.Node, error) {
 		return NewSwarmAutoscale(parents).Build(node)
 	case *pipeline.UDFNode:
 		return NewUDF(parents).Build(node)
+	case *pipeline.WasmNode:
+		return NewWasm(parents).Build(node)
+	case *pipeline.InlineNode:
+		return NewInline(parents).Build(node)
+	case *pipeline.OnnxNode:
+		return NewOnnx(parents).Build(node)
 	case *pipeline.WhereNode:
 		return NewWhere(parents).Build(node)
 	case *pipeline.WindowNode:
@@ -24,7 +24,8 @@ func (n *EvalNode) Build(e *pipeline.EvalNode) (ast.Node, error) {
 	n.Pipe("eval", largs(e.Lambdas)...).
 		Dot("as", args(e.AsList)...).
 		Dot("tags", args(e.TagsList)...).
-		DotIf("quiet", e.QuietFlag)
+		DotIf("quiet", e.QuietFlag).
+		Dot("shards", e.Shards)
 
 	if e.KeepFlag {
 		n.Dot("keep", args(e.KeepList)...)
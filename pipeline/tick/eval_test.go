@@ -43,3 +43,23 @@ func TestEval(t *testing.T) {
 `
 	PipelineTickTestHelper(t, pipe, want)
 }
+
+func TestEvalShards(t *testing.T) {
+	pipe, _, from := StreamFrom()
+	eval := from.Eval(&ast.LambdaNode{
+		Expression: &ast.FunctionNode{
+			Func: "count",
+		},
+	})
+	eval.As("count")
+	eval.Shards = 4
+
+	want := `stream
+    |from()
+    |eval(lambda: count())
+        .as('count')
+        .tags()
+        .shards(4)
+`
+	PipelineTickTestHelper(t, pipe, want)
+}
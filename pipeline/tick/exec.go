@@ -0,0 +1,30 @@
+package tick
+
+import (
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/tick/ast"
+)
+
+// ExecNode converts the ExecNode pipeline node into the TICKScript AST
+type ExecNode struct {
+	Function
+}
+
+// NewExec creates an ExecNode function builder
+func NewExec(parents []ast.Node) *ExecNode {
+	return &ExecNode{
+		Function{
+			Parents: parents,
+		},
+	}
+}
+
+// Build creates an ExecNode ast.Node
+func (n *ExecNode) Build(e *pipeline.ExecNode) (ast.Node, error) {
+	n.Pipe("exec", args(e.Command)...).
+		Dot("format", e.Format).
+		DotIf("capture", e.CaptureFlag).
+		Dot("timeout", e.Timeout)
+
+	return n.prev, n.err
+}
@@ -0,0 +1,23 @@
+package tick_test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExec(t *testing.T) {
+	pipe, _, from := StreamFrom()
+	exec := from.Exec("/usr/bin/python3", "/etc/kapacitor/enrich.py")
+	exec.Format = "json"
+	exec.Capture()
+	exec.Timeout = 10 * time.Second
+
+	want := `stream
+    |from()
+    |exec('/usr/bin/python3', '/etc/kapacitor/enrich.py')
+        .format('json')
+        .capture()
+        .timeout(10s)
+`
+	PipelineTickTestHelper(t, pipe, want)
+}
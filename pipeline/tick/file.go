@@ -0,0 +1,35 @@
+package tick
+
+import (
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/tick/ast"
+)
+
+// FileNode converts the FileNode pipeline node into the TICKScript AST
+type FileNode struct {
+	Function
+}
+
+// NewFile creates a FileNode function builder
+func NewFile(parents []ast.Node) *FileNode {
+	return &FileNode{
+		Function{
+			Parents: parents,
+		},
+	}
+}
+
+// Build creates a FileNode ast.Node
+func (n *FileNode) Build(f *pipeline.FileNode) (ast.Node, error) {
+	n.Pipe("file", f.Path).
+		Dot("format", f.Format).
+		Dot("every", f.Every).
+		Dot("cron", f.Cron).
+		Dot("measurement", f.Measurement).
+		Dot("timeColumn", f.TimeColumn).
+		Dot("timeFormat", f.TimeFormat).
+		DotNotEmpty("tags", args(f.TagColumns)...).
+		DotNotEmpty("groupBy", args(f.Dimensions)...)
+
+	return n.prev, n.err
+}
@@ -0,0 +1,33 @@
+package tick_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/pipeline"
+)
+
+func TestFile(t *testing.T) {
+	batch := &pipeline.BatchNode{}
+	pipe := pipeline.CreatePipelineSources(batch)
+	file := batch.File("s3://reference/hosts.csv")
+
+	file.Every = time.Hour
+	file.Measurement = "hosts"
+	file.TimeColumn = "updated"
+	file.TimeFormat = "2006-01-02"
+	file.Tags("host", "dc")
+	file.GroupBy("dc")
+
+	want := `batch
+    |file('s3://reference/hosts.csv')
+        .format('csv')
+        .every(1h)
+        .measurement('hosts')
+        .timeColumn('updated')
+        .timeFormat('2006-01-02')
+        .tags('host', 'dc')
+        .groupBy('dc')
+`
+	PipelineTickTestHelper(t, pipe, want)
+}
@@ -38,6 +38,7 @@ func (n *InfluxQLNode) Build(q *pipeline.InfluxQLNode) (ast.Node, error) {
 	}
 	n.Pipe(q.Method, args...).
 		Dot("as", q.As).
-		DotIf("usePointTimes", q.PointTimes)
+		DotIf("usePointTimes", q.PointTimes).
+		Dot("shards", q.Shards)
 	return n.prev, n.err
 }
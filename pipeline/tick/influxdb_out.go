@@ -32,6 +32,8 @@ func (n *InfluxDBOutNode) Build(db *pipeline.InfluxDBOutNode) (ast.Node, error)
 		Dot("precision", db.Precision).
 		Dot("buffer", db.Buffer).
 		Dot("flushInterval", db.FlushInterval).
+		Dot("writers", db.Writers).
+		DotIf("gzip", db.GzipFlag).
 		DotIf("create", db.CreateFlag)
 
 	var tags []string
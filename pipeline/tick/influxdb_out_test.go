@@ -17,6 +17,8 @@ func TestInfluxDBOut(t *testing.T) {
 	influx.Precision = "ms"
 	influx.Buffer = 10
 	influx.FlushInterval = time.Second
+	influx.Writers = 4
+	influx.Gzip()
 	influx.Create()
 
 	want := `stream
@@ -29,6 +31,8 @@ func TestInfluxDBOut(t *testing.T) {
         .precision('ms')
         .buffer(10)
         .flushInterval(1s)
+        .writers(4)
+        .gzip()
         .create()
         .tag('kapacitor', 'true')
         .tag('version', '0.2')
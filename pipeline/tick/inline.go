@@ -0,0 +1,29 @@
+package tick
+
+import (
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/tick/ast"
+)
+
+// InlineNode converts the InlineNode pipeline node into the TICKScript AST
+type InlineNode struct {
+	Function
+}
+
+// NewInline creates an InlineNode function builder
+func NewInline(parents []ast.Node) *InlineNode {
+	return &InlineNode{
+		Function{
+			Parents: parents,
+		},
+	}
+}
+
+// Build creates an InlineNode ast.Node
+func (n *InlineNode) Build(i *pipeline.InlineNode) (ast.Node, error) {
+	n.Pipe("inline", i.Script).
+		Dot("maxSteps", i.MaxSteps).
+		DotIf("quiet", i.QuietFlag)
+
+	return n.prev, n.err
+}
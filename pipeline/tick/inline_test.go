@@ -0,0 +1,21 @@
+package tick_test
+
+import (
+	"testing"
+)
+
+func TestInline(t *testing.T) {
+	pipe, _, from := StreamFrom()
+	inline := from.Inline("fields.ratio = fields.used / fields.total\nreturn fields.ratio < 1")
+	inline.MaxSteps = 100
+	inline.Quiet()
+
+	want := `stream
+    |from()
+    |inline('fields.ratio = fields.used / fields.total
+return fields.ratio < 1')
+        .maxSteps(100)
+        .quiet()
+`
+	PipelineTickTestHelper(t, pipe, want)
+}
@@ -0,0 +1,29 @@
+package tick
+
+import (
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/tick/ast"
+)
+
+// ObjectStoreOutNode converts the ObjectStoreOutNode pipeline node into the TICKScript AST
+type ObjectStoreOutNode struct {
+	Function
+}
+
+// NewObjectStoreOut creates an ObjectStoreOutNode function builder
+func NewObjectStoreOut(parents []ast.Node) *ObjectStoreOutNode {
+	return &ObjectStoreOutNode{
+		Function{
+			Parents: parents,
+		},
+	}
+}
+
+// Build creates an ObjectStoreOutNode ast.Node
+func (n *ObjectStoreOutNode) Build(o *pipeline.ObjectStoreOutNode) (ast.Node, error) {
+	n.Pipe("objectStoreOut", o.URL).
+		Dot("format", o.Format).
+		Dot("timeout", o.Timeout)
+
+	return n.prev, n.err
+}
@@ -0,0 +1,21 @@
+package tick_test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestObjectStoreOut(t *testing.T) {
+	pipe, _, query := BatchQuery("select mean(usage_idle) from cpu")
+	out := query.ObjectStoreOut(`s3://archive/{{ .TaskName }}/{{ index .Tags "host" }}.csv`)
+	out.Format = "csv"
+	out.Timeout = 10 * time.Second
+
+	want := `batch
+    |query('select mean(usage_idle) from cpu')
+    |objectStoreOut('s3://archive/{{ .TaskName }}/{{ index .Tags "host" }}.csv')
+        .format('csv')
+        .timeout(10s)
+`
+	PipelineTickTestHelper(t, pipe, want)
+}
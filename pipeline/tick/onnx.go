@@ -0,0 +1,31 @@
+package tick
+
+import (
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/tick/ast"
+)
+
+// OnnxNode converts the OnnxNode pipeline node into the TICKScript AST
+type OnnxNode struct {
+	Function
+}
+
+// NewOnnx creates an OnnxNode function builder
+func NewOnnx(parents []ast.Node) *OnnxNode {
+	return &OnnxNode{
+		Function{
+			Parents: parents,
+		},
+	}
+}
+
+// Build creates an OnnxNode ast.Node
+func (n *OnnxNode) Build(o *pipeline.OnnxNode) (ast.Node, error) {
+	n.Pipe("onnx", o.Model).
+		Dot("fields", args(o.FieldsList)...).
+		DotNotEmpty("outputs", args(o.OutputsList)...).
+		Dot("as", args(o.AsList)...).
+		DotIf("quiet", o.QuietFlag)
+
+	return n.prev, n.err
+}
@@ -0,0 +1,21 @@
+package tick_test
+
+import (
+	"testing"
+)
+
+func TestOnnx(t *testing.T) {
+	pipe, _, from := StreamFrom()
+	onnx := from.Onnx("/etc/kapacitor/models/anomaly.onnx")
+	onnx.Fields("usage_user", "usage_system").Outputs("label").As("anomaly").Quiet()
+
+	want := `stream
+    |from()
+    |onnx('/etc/kapacitor/models/anomaly.onnx')
+        .fields('usage_user', 'usage_system')
+        .outputs('label')
+        .as('anomaly')
+        .quiet()
+`
+	PipelineTickTestHelper(t, pipe, want)
+}
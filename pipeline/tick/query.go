@@ -27,6 +27,7 @@ func (n *QueryNode) Build(q *pipeline.QueryNode) (ast.Node, error) {
 		DotIf("align", q.AlignFlag).
 		Dot("cron", q.Cron).
 		Dot("offset", q.Offset).
+		Dot("latency", q.Latency).
 		DotIf("alignGroup", q.AlignGroupFlag).
 		Dot("groupBy", q.Dimensions).
 		DotIf("groupByMeasurement", q.GroupByMeasurementFlag).
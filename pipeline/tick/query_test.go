@@ -12,6 +12,7 @@ func TestQuery(t *testing.T) {
 	query.Every = 30 * time.Second
 	query.AlignFlag = true
 	query.Offset = time.Hour
+	query.Latency = 5 * time.Minute
 	query.AlignGroupFlag = true
 	query.Dimensions = []interface{}{"host", "region"}
 	query.GroupByMeasurementFlag = true
@@ -24,6 +25,7 @@ func TestQuery(t *testing.T) {
         .every(30s)
         .align()
         .offset(1h)
+        .latency(5m)
         .alignGroup()
         .groupBy(['host', 'region'])
         .groupByMeasurement()
@@ -0,0 +1,32 @@
+package tick
+
+import (
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/tick/ast"
+)
+
+// WasmNode converts the WasmNode pipeline node into the TICKScript AST
+type WasmNode struct {
+	Function
+}
+
+// NewWasm creates a WasmNode function builder
+func NewWasm(parents []ast.Node) *WasmNode {
+	return &WasmNode{
+		Function{
+			Parents: parents,
+		},
+	}
+}
+
+// Build creates a WasmNode ast.Node
+func (n *WasmNode) Build(w *pipeline.WasmNode) (ast.Node, error) {
+	n.Pipe("wasm", w.Module, w.Function).
+		Dot("fields", args(w.FieldsList)...).
+		Dot("as", args(w.AsList)...).
+		Dot("maxInstructions", w.MaxInstructions).
+		Dot("maxMemoryPages", w.MaxMemoryPages).
+		DotIf("quiet", w.QuietFlag)
+
+	return n.prev, n.err
+}
@@ -0,0 +1,24 @@
+package tick_test
+
+import (
+	"testing"
+)
+
+func TestWasm(t *testing.T) {
+	pipe, _, from := StreamFrom()
+	// A module exporting load(f64, f64) f64.
+	wasm := from.Wasm("AGFzbQEAAAABBwFgAnx8AXwDAgEABwgBBGxvYWQAAAoJAQcAIAAgAaAL", "load")
+	wasm.Fields("usage_user", "usage_system").As("load").Quiet()
+	wasm.MaxInstructions = 10000
+
+	want := `stream
+    |from()
+    |wasm('AGFzbQEAAAABBwFgAnx8AXwDAgEABwgBBGxvYWQAAAoJAQcAIAAgAaAL', 'load')
+        .fields('usage_user', 'usage_system')
+        .as('load')
+        .maxInstructions(10000)
+        .maxMemoryPages(16)
+        .quiet()
+`
+	PipelineTickTestHelper(t, pipe, want)
+}
@@ -49,6 +49,14 @@ import (
 //
 // NOTE: The UDF process runs as the same user as the Kapacitor daemon.
 // As a result, make sure the user is properly secured, as well as the configuration file.
+//
+// If the UDF is configured with restart-attempts it is restarted when it fails
+// instead of failing the task, see the example configuration.
+//
+// Available Statistics:
+//
+//    * restarts -- number of times the UDF was restarted after it failed
+//
 type UDFNode struct {
 	chainnode
 
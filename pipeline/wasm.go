@@ -0,0 +1,186 @@
+package pipeline
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/influxdata/kapacitor/wasm"
+)
+
+const (
+	// Default maximum number of instructions executed per point.
+	DefaultWasmMaxInstructions = wasm.DefaultMaxInstructions
+	// Default maximum number of 64KiB memory pages of a module.
+	DefaultWasmMaxMemoryPages = wasm.DefaultMaxMemoryPages
+)
+
+// A WasmNode transforms each point with a function exported by a WebAssembly module.
+// The module runs sandboxed inside Kapacitor, it cannot access the host
+// and its memory, table and number of executed instructions are limited.
+// Modules may be compiled from any language targeting WebAssembly,
+// e.g. Rust, Go (TinyGo) or AssemblyScript, but must not depend on imports.
+//
+// The module is embedded in the task, encoded in base64, e.g. the output of `base64 -w0 transform.wasm`.
+// It is validated when the task is defined.
+//
+// The values of the fields are passed as the arguments of the function
+// and the results of the function are stored as the fields named with the 'as' property.
+// The function must take one argument per field and return one result per 'as' name,
+// all of which must be numbers.
+// Integer arguments accept integer and boolean fields,
+// float arguments accept float and integer fields.
+//
+// Example:
+//    stream
+//        |from()
+//            .measurement('cpu')
+//        |wasm('AGFzbQEAAAAB...', 'load')
+//            .fields('usage_user', 'usage_system')
+//            .as('load')
+//            .maxInstructions(10000)
+//
+// The module is instantiated when the task starts.
+// All points of the task node use the same instance,
+// so the function may keep state in the globals and memory of the module.
+// Points for which the function fails, or traps, are dropped and the error is logged
+// unless the node is quiet.
+//
+// Available Statistics:
+//
+//    * wasm_errors -- number of points dropped because the function failed
+//
+type WasmNode struct {
+	chainnode
+
+	// The WebAssembly module encoded in base64.
+	// tick:ignore
+	Module string `json:"module"`
+
+	// The name of the exported function.
+	// tick:ignore
+	Function string `json:"function"`
+
+	// The fields passed as the arguments of the function.
+	// tick:ignore
+	FieldsList []string `tick:"Fields" json:"fields"`
+
+	// The fields the results of the function are stored as.
+	// tick:ignore
+	AsList []string `tick:"As" json:"as"`
+
+	// Maximum number of instructions executed for each point, the function traps if it is exceeded.
+	// Defaults to 1000000.
+	MaxInstructions int64 `json:"maxInstructions"`
+
+	// Maximum number of 64KiB pages of memory the module may use.
+	// Defaults to 16, i.e. 1MiB.
+	MaxMemoryPages int64 `json:"maxMemoryPages"`
+}
+
+func newWasmNode(wants EdgeType, module, function string) *WasmNode {
+	return &WasmNode{
+		chainnode:       newBasicChainNode("wasm", wants, wants),
+		Module:          module,
+		Function:        function,
+		MaxInstructions: DefaultWasmMaxInstructions,
+		MaxMemoryPages:  DefaultWasmMaxMemoryPages,
+	}
+}
+
+// MarshalJSON converts WasmNode to JSON
+// tick:ignore
+func (n *WasmNode) MarshalJSON() ([]byte, error) {
+	type Alias WasmNode
+	var raw = &struct {
+		TypeOf
+		*Alias
+	}{
+		TypeOf: TypeOf{
+			Type: "wasm",
+			ID:   n.ID(),
+		},
+		Alias: (*Alias)(n),
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON converts JSON to a WasmNode
+// tick:ignore
+func (n *WasmNode) UnmarshalJSON(data []byte) error {
+	type Alias WasmNode
+	var raw = &struct {
+		TypeOf
+		*Alias
+	}{
+		Alias: (*Alias)(n),
+	}
+	err := json.Unmarshal(data, raw)
+	if err != nil {
+		return err
+	}
+	if raw.Type != "wasm" {
+		return fmt.Errorf("error unmarshaling node %d of type %s as WasmNode", raw.ID, raw.Type)
+	}
+	n.setID(raw.ID)
+	return nil
+}
+
+func (n *WasmNode) validate() error {
+	if n.Module == "" {
+		return errors.New("must specify a module")
+	}
+	if n.Function == "" {
+		return errors.New("must specify a function")
+	}
+	if len(n.AsList) == 0 {
+		return errors.New("must specify at least one result name with .as()")
+	}
+	if n.MaxInstructions <= 0 {
+		return errors.New("maxInstructions must be positive")
+	}
+	if n.MaxMemoryPages <= 0 || n.MaxMemoryPages > 65536 {
+		return errors.New("maxMemoryPages must be between 1 and 65536")
+	}
+	_, err := n.Compile()
+	return err
+}
+
+// Compile decodes and validates the module and checks the signature of the function.
+// tick:ignore
+func (n *WasmNode) Compile() (*wasm.Module, error) {
+	b, err := base64.StdEncoding.DecodeString(n.Module)
+	if err != nil {
+		return nil, fmt.Errorf("module must be encoded in base64: %v", err)
+	}
+	m, err := wasm.Compile(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wasm module: %v", err)
+	}
+	typ, ok := m.Exports()[n.Function]
+	if !ok {
+		return nil, fmt.Errorf("wasm module does not export a function named %q", n.Function)
+	}
+	if len(typ.Params) != len(n.FieldsList) {
+		return nil, fmt.Errorf("wasm function %s takes %d arguments, got %d fields", n.Function, len(typ.Params), len(n.FieldsList))
+	}
+	if len(typ.Results) != len(n.AsList) {
+		return nil, fmt.Errorf("wasm function %s returns %d results, got %d as names", n.Function, len(typ.Results), len(n.AsList))
+	}
+	return m, nil
+}
+
+// The fields passed as the arguments of the function, in order.
+// tick:property
+func (n *WasmNode) Fields(fields ...string) *WasmNode {
+	n.FieldsList = fields
+	return n
+}
+
+// The names of the fields the results of the function are stored as, in order.
+// tick:property
+func (n *WasmNode) As(names ...string) *WasmNode {
+	n.AsList = names
+	return n
+}
@@ -0,0 +1,66 @@
+package pipeline
+
+import (
+	"testing"
+)
+
+// A module exporting load(f64, f64) f64.
+const testWasmModule = "AGFzbQEAAAABBwFgAnx8AXwDAgEABwgBBGxvYWQAAAoJAQcAIAAgAaAL"
+
+func TestWasmNode_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(n *WasmNode)
+		err    string
+	}{
+		{
+			name:   "valid",
+			modify: func(n *WasmNode) {},
+		},
+		{
+			name:   "no instruction limit",
+			modify: func(n *WasmNode) { n.MaxInstructions = 0 },
+			err:    "maxInstructions must be positive",
+		},
+		{
+			name:   "no memory limit",
+			modify: func(n *WasmNode) { n.MaxMemoryPages = 0 },
+			err:    "maxMemoryPages must be between 1 and 65536",
+		},
+		{
+			name:   "not base64",
+			modify: func(n *WasmNode) { n.Module = "/etc/kapacitor/wasm/transform.wasm" },
+			err:    "module must be encoded in base64: illegal base64 data at input byte 29",
+		},
+		{
+			name:   "invalid module",
+			modify: func(n *WasmNode) { n.Module = "AGFzbQIAAAA=" },
+			err:    "invalid wasm module: unsupported WebAssembly version 2",
+		},
+		{
+			name:   "unknown function",
+			modify: func(n *WasmNode) { n.Function = "scale" },
+			err:    `wasm module does not export a function named "scale"`,
+		},
+		{
+			name:   "fields",
+			modify: func(n *WasmNode) { n.FieldsList = []string{"usage_user"} },
+			err:    "wasm function load takes 2 arguments, got 1 fields",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newWasmNode(StreamEdge, testWasmModule, "load")
+			n.Fields("usage_user", "usage_system").As("load")
+			tt.modify(n)
+			err := n.validate()
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			} else if err == nil || err.Error() != tt.err {
+				t.Fatalf("unexpected error: got %v exp %s", err, tt.err)
+			}
+		})
+	}
+}
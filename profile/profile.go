@@ -0,0 +1,393 @@
+// Package profile reads the CPU profiles written by runtime/pprof,
+// to attribute their samples to the labels of the goroutines that were sampled.
+//
+// Only the parts of the profile needed to filter and aggregate samples are decoded,
+// the other parts are written back as they were read.
+package profile
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Fields of the messages of profile.proto
+const (
+	profileSampleType = 1
+	profileSample     = 2
+	profileLocation   = 4
+	profileFunction   = 5
+	profileStrings    = 6
+
+	valueTypeType = 1
+
+	sampleLocation = 1
+	sampleValue    = 2
+	sampleLabel    = 3
+
+	labelKey = 1
+	labelStr = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunction = 1
+
+	functionID   = 1
+	functionName = 2
+)
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// Profile is a CPU profile.
+type Profile struct {
+	// Encoded fields of the profile other than the samples.
+	fields [][]byte
+
+	// Types of the values of the samples, i.e. "samples" and "cpu".
+	SampleTypes []string
+	Samples     []*Sample
+
+	// Function names of the frames of each location, from the innermost inlined frame.
+	locations map[uint64][]string
+}
+
+// Sample is the stack of a goroutine at the time it was sampled.
+type Sample struct {
+	// Stack of function names from the innermost frame.
+	Stack  []string
+	Values []int64
+	Labels map[string]string
+
+	encoded []byte
+}
+
+// Parse reads a gzipped CPU profile.
+func Parse(r io.Reader) (*Profile, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read profile")
+	}
+	data, err := ioutil.ReadAll(gz)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read profile")
+	}
+
+	p := &Profile{locations: make(map[uint64][]string)}
+	var (
+		strs        []string
+		sampleTypes []int64
+		samples     [][]byte
+		locations   = make(map[uint64][]uint64)
+		functions   = make(map[uint64]int64)
+	)
+	err = walk(data, func(num int, typ int, v uint64, b []byte, field []byte) error {
+		switch num {
+		case profileSample:
+			samples = append(samples, b)
+			return nil
+		case profileSampleType:
+			err := walk(b, func(num int, typ int, v uint64, b []byte, _ []byte) error {
+				if num == valueTypeType {
+					sampleTypes = append(sampleTypes, int64(v))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		case profileStrings:
+			strs = append(strs, string(b))
+		case profileLocation:
+			var (
+				id    uint64
+				funcs []uint64
+			)
+			err := walk(b, func(num int, typ int, v uint64, b []byte, _ []byte) error {
+				switch num {
+				case locationID:
+					id = v
+				case locationLine:
+					return walk(b, func(num int, typ int, v uint64, b []byte, _ []byte) error {
+						if num == lineFunction {
+							funcs = append(funcs, v)
+						}
+						return nil
+					})
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			locations[id] = funcs
+		case profileFunction:
+			var id uint64
+			var name int64
+			err := walk(b, func(num int, typ int, v uint64, b []byte, _ []byte) error {
+				switch num {
+				case functionID:
+					id = v
+				case functionName:
+					name = int64(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			functions[id] = name
+		}
+		p.fields = append(p.fields, field)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	str := func(i int64) string {
+		if i < 0 || int(i) >= len(strs) {
+			return ""
+		}
+		return strs[i]
+	}
+	for _, t := range sampleTypes {
+		p.SampleTypes = append(p.SampleTypes, str(t))
+	}
+	for id, funcs := range locations {
+		names := make([]string, len(funcs))
+		for i, f := range funcs {
+			names[i] = str(functions[f])
+		}
+		p.locations[id] = names
+	}
+	for _, b := range samples {
+		s := &Sample{encoded: b}
+		var locs []uint64
+		err := walk(b, func(num int, typ int, v uint64, b []byte, _ []byte) error {
+			switch num {
+			case sampleLocation:
+				return varints(typ, v, b, func(v uint64) { locs = append(locs, v) })
+			case sampleValue:
+				return varints(typ, v, b, func(v uint64) { s.Values = append(s.Values, int64(v)) })
+			case sampleLabel:
+				var key, value int64
+				err := walk(b, func(num int, typ int, v uint64, b []byte, _ []byte) error {
+					switch num {
+					case labelKey:
+						key = int64(v)
+					case labelStr:
+						value = int64(v)
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				if s.Labels == nil {
+					s.Labels = make(map[string]string)
+				}
+				s.Labels[str(key)] = str(value)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range locs {
+			s.Stack = append(s.Stack, p.locations[l]...)
+		}
+		p.Samples = append(p.Samples, s)
+	}
+	return p, nil
+}
+
+// Filter returns the profile of the samples with the label.
+func (p *Profile) Filter(key, value string) *Profile {
+	f := *p
+	f.Samples = nil
+	for _, s := range p.Samples {
+		if s.Labels[key] == value {
+			f.Samples = append(f.Samples, s)
+		}
+	}
+	return &f
+}
+
+// Value returns the sum of the values of the samples of the sample type.
+func (p *Profile) Value(sampleType string) int64 {
+	i := p.sampleTypeIndex(sampleType)
+	var total int64
+	for _, s := range p.Samples {
+		total += s.value(i)
+	}
+	return total
+}
+
+// ValueByLabel returns the sum of the values of the samples of the sample type,
+// by the value of their label.
+func (p *Profile) ValueByLabel(sampleType, key string) map[string]int64 {
+	i := p.sampleTypeIndex(sampleType)
+	values := make(map[string]int64)
+	for _, s := range p.Samples {
+		if l, ok := s.Labels[key]; ok {
+			values[l] += s.value(i)
+		}
+	}
+	return values
+}
+
+// Write writes the profile gzipped, in the format read by go tool pprof.
+func (p *Profile) Write(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	for _, f := range p.fields {
+		if _, err := gz.Write(f); err != nil {
+			return err
+		}
+	}
+	var buf [binary.MaxVarintLen64]byte
+	for _, s := range p.Samples {
+		buf[0] = profileSample<<3 | wireBytes
+		if _, err := gz.Write(buf[:1]); err != nil {
+			return err
+		}
+		n := binary.PutUvarint(buf[:], uint64(len(s.encoded)))
+		if _, err := gz.Write(buf[:n]); err != nil {
+			return err
+		}
+		if _, err := gz.Write(s.encoded); err != nil {
+			return err
+		}
+	}
+	return gz.Close()
+}
+
+// WriteFolded writes the stacks of the samples in the folded format read by flame graph tools,
+// one line per distinct stack from the outermost frame with the sum of the values of the sample type.
+// The stacks start with the value of the label, to group them by label.
+func (p *Profile) WriteFolded(w io.Writer, sampleType, key string) error {
+	i := p.sampleTypeIndex(sampleType)
+	values := make(map[string]int64)
+	var frames []string
+	for _, s := range p.Samples {
+		frames = frames[:0]
+		if key != "" {
+			frames = append(frames, s.Labels[key])
+		}
+		for j := len(s.Stack) - 1; j >= 0; j-- {
+			frames = append(frames, s.Stack[j])
+		}
+		values[strings.Join(frames, ";")] += s.value(i)
+	}
+	stacks := make([]string, 0, len(values))
+	for stack := range values {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+	for _, stack := range stacks {
+		if _, err := fmt.Fprintf(w, "%s %d\n", stack, values[stack]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sampleTypeIndex returns the index of the values of the sample type,
+// or the index of the last sample type, which is the default, if it is not found.
+func (p *Profile) sampleTypeIndex(sampleType string) int {
+	for i, t := range p.SampleTypes {
+		if t == sampleType {
+			return i
+		}
+	}
+	return len(p.SampleTypes) - 1
+}
+
+func (s *Sample) value(i int) int64 {
+	if i < 0 || i >= len(s.Values) {
+		return 0
+	}
+	return s.Values[i]
+}
+
+// walk calls f for each field of the encoded message,
+// with its varint value or its bytes and the encoded field.
+func walk(data []byte, f func(num int, typ int, v uint64, b []byte, field []byte) error) error {
+	for len(data) > 0 {
+		start := data
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errInvalid
+		}
+		data = data[n:]
+		var (
+			v uint64
+			b []byte
+		)
+		typ := int(key & 7)
+		switch typ {
+		case wireVarint:
+			v, n = binary.Uvarint(data)
+			if n <= 0 {
+				return errInvalid
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errInvalid
+			}
+			v = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errInvalid
+			}
+			v = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return errInvalid
+			}
+			b = data[n : n+int(l)]
+			data = data[n+int(l):]
+		default:
+			return errInvalid
+		}
+		if err := f(int(key>>3), typ, v, b, start[:len(start)-len(data)]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// varints calls f for the values of a repeated varint field, which may be packed.
+func varints(typ int, v uint64, b []byte, f func(uint64)) error {
+	if typ != wireBytes {
+		f(v)
+		return nil
+	}
+	for len(b) > 0 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errInvalid
+		}
+		f(v)
+		b = b[n:]
+	}
+	return nil
+}
+
+var errInvalid = errors.New("invalid profile")
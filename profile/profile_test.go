@@ -0,0 +1,113 @@
+package profile_test
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/profile"
+)
+
+var sink int
+
+func spin(stop chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		for i := 0; i < 1000; i++ {
+			sink += i
+		}
+	}
+}
+
+func TestProfile(t *testing.T) {
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		t.Skip("cannot profile:", err)
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, node := range []string{"eval1", "where2"} {
+		wg.Add(1)
+		go pprof.Do(context.Background(), pprof.Labels("task", "cpu", "node", node), func(context.Context) {
+			defer wg.Done()
+			spin(stop)
+		})
+	}
+	time.Sleep(500 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+	pprof.StopCPUProfile()
+
+	p, err := profile.Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := strings.Join(p.SampleTypes, ","), "samples,cpu"; got != exp {
+		t.Errorf("unexpected sample types got %s exp %s", got, exp)
+	}
+
+	task := p.Filter("task", "cpu")
+	if len(task.Samples) == 0 {
+		t.Fatal("expected samples of the task")
+	}
+	for _, s := range task.Samples {
+		if !strings.Contains(strings.Join(s.Stack, ";"), "profile_test.spin") {
+			t.Errorf("unexpected stack of sample %v", s.Stack)
+		}
+	}
+	nodes := task.ValueByLabel("cpu", "node")
+	for _, node := range []string{"eval1", "where2"} {
+		if nodes[node] <= 0 {
+			t.Errorf("expected CPU time for node %s, got %v", node, nodes)
+		}
+	}
+	if got, exp := nodes["eval1"]+nodes["where2"], task.Value("cpu"); got != exp {
+		t.Errorf("unexpected CPU time of nodes got %d exp %d", got, exp)
+	}
+	if other := p.Filter("task", "mem"); len(other.Samples) != 0 {
+		t.Errorf("unexpected samples of other task %d", len(other.Samples))
+	}
+
+	// The filtered profile is read back the same
+	var out bytes.Buffer
+	if err := task.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	read, err := profile.Parse(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := len(read.Samples), len(task.Samples); got != exp {
+		t.Fatalf("unexpected samples got %d exp %d", got, exp)
+	}
+	if got, exp := read.Value("cpu"), task.Value("cpu"); got != exp {
+		t.Errorf("unexpected CPU time got %d exp %d", got, exp)
+	}
+
+	var folded bytes.Buffer
+	if err := task.WriteFolded(&folded, "cpu", "node"); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(folded.String()), "\n") {
+		if !strings.HasPrefix(line, "eval1;") && !strings.HasPrefix(line, "where2;") {
+			t.Errorf("unexpected folded stack %q, expected the node first", line)
+		}
+		if !strings.Contains(line, "profile_test.spin") {
+			t.Errorf("unexpected folded stack %q", line)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	if _, err := profile.Parse(strings.NewReader("not a profile")); err == nil {
+		t.Error("expected error")
+	}
+}
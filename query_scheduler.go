@@ -0,0 +1,105 @@
+package kapacitor
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/server/vars"
+)
+
+const (
+	statQueriesRunning = "queries_running"
+	statQueriesWaiting = "queries_waiting"
+)
+
+// queryScheduler spreads the batch queries of the tasks scheduled at the same time over the jitter,
+// and limits the concurrent batch queries of each InfluxDB cluster.
+// It is shared by the task masters of the server.
+type queryScheduler struct {
+	jitter        time.Duration
+	maxConcurrent int
+
+	mu       sync.Mutex
+	clusters map[string]*clusterQueries
+}
+
+type clusterQueries struct {
+	slots   chan struct{}
+	waiting *expvar.Int
+}
+
+func newQueryScheduler(jitter time.Duration, maxConcurrent int) *queryScheduler {
+	return &queryScheduler{
+		jitter:        jitter,
+		maxConcurrent: maxConcurrent,
+		clusters:      make(map[string]*clusterQueries),
+	}
+}
+
+// delay returns the delay of the queries of the node of the task within the jitter.
+// The delay of a node is the same on every schedule so its queries stay evenly spaced.
+func (s *queryScheduler) delay(task, node string) time.Duration {
+	if s == nil || s.jitter <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(task))
+	h.Write([]byte{0})
+	h.Write([]byte(node))
+	return time.Duration(h.Sum64() % uint64(s.jitter))
+}
+
+// getCluster returns the queries of the cluster, creating them on the first query.
+func (s *queryScheduler) getCluster(cluster string) *clusterQueries {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.clusters[cluster]; ok {
+		return c
+	}
+	c := &clusterQueries{
+		slots:   make(chan struct{}, s.maxConcurrent),
+		waiting: &expvar.Int{},
+	}
+	s.clusters[cluster] = c
+	_, statMap := vars.NewStatistic("batch_queries", map[string]string{"cluster": cluster})
+	statMap.Set(statQueriesRunning, expvar.NewIntFuncGauge(func() int64 {
+		return int64(len(c.slots))
+	}))
+	statMap.Set(statQueriesWaiting, c.waiting)
+	return c
+}
+
+// acquire waits until a query of the cluster can run.
+// It returns false if closing or aborting is closed first.
+// Each successful acquire must be followed by a release.
+func (s *queryScheduler) acquire(cluster string, closing, aborting <-chan struct{}) bool {
+	if s == nil || s.maxConcurrent <= 0 {
+		return true
+	}
+	c := s.getCluster(cluster)
+	select {
+	case c.slots <- struct{}{}:
+		return true
+	default:
+	}
+	c.waiting.Add(1)
+	defer c.waiting.Add(-1)
+	select {
+	case c.slots <- struct{}{}:
+		return true
+	case <-closing:
+		return false
+	case <-aborting:
+		return false
+	}
+}
+
+// release frees the query of the cluster for the next waiting query.
+func (s *queryScheduler) release(cluster string) {
+	if s == nil || s.maxConcurrent <= 0 {
+		return
+	}
+	<-s.getCluster(cluster).slots
+}
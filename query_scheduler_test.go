@@ -0,0 +1,73 @@
+package kapacitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryScheduler_Delay(t *testing.T) {
+	s := newQueryScheduler(time.Minute, 0)
+	d := s.delay("task", "query1")
+	if d < 0 || d >= time.Minute {
+		t.Fatalf("unexpected delay %v outside of the jitter", d)
+	}
+	if got := s.delay("task", "query1"); got != d {
+		t.Errorf("unexpected delay of the same node got %v exp %v", got, d)
+	}
+	if s.delay("task", "query2") == d && s.delay("other", "query1") == d {
+		t.Error("expected the delays of other nodes to differ")
+	}
+	if got := newQueryScheduler(0, 0).delay("task", "query1"); got != 0 {
+		t.Errorf("unexpected delay without jitter %v", got)
+	}
+	var nilScheduler *queryScheduler
+	if got := nilScheduler.delay("task", "query1"); got != 0 {
+		t.Errorf("unexpected delay of nil scheduler %v", got)
+	}
+}
+
+func TestQueryScheduler_Acquire(t *testing.T) {
+	s := newQueryScheduler(0, 2)
+	never := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		if !s.acquire("cluster", never, never) {
+			t.Fatal("expected query to run")
+		}
+	}
+	// Other clusters have their own limit.
+	if !s.acquire("other", never, never) {
+		t.Fatal("expected query of other cluster to run")
+	}
+
+	acquired := make(chan bool)
+	go func() {
+		acquired <- s.acquire("cluster", never, never)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("expected query to wait for a running query")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if got := s.getCluster("cluster").waiting.IntValue(); got != 1 {
+		t.Errorf("unexpected waiting queries got %d exp 1", got)
+	}
+	s.release("cluster")
+	select {
+	case ok := <-acquired:
+		if !ok {
+			t.Fatal("expected query to run")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the query to run")
+	}
+
+	// Waiting stops when the node closes.
+	closing := make(chan struct{})
+	close(closing)
+	if s.acquire("cluster", closing, never) {
+		t.Error("expected query not to run when closing")
+	}
+	if got := s.getCluster("cluster").waiting.IntValue(); got != 0 {
+		t.Errorf("unexpected waiting queries got %d exp 0", got)
+	}
+}
@@ -3,26 +3,39 @@ package server
 import (
 	"encoding"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/user"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/influxdata/kapacitor/command"
+	"github.com/influxdata/kapacitor/listmap"
 	"github.com/influxdata/kapacitor/services/alerta"
+	"github.com/influxdata/kapacitor/services/amqp"
+	"github.com/influxdata/kapacitor/services/audit"
 	"github.com/influxdata/kapacitor/services/azure"
+	"github.com/influxdata/kapacitor/services/cluster"
 	"github.com/influxdata/kapacitor/services/config"
 	"github.com/influxdata/kapacitor/services/consul"
 	"github.com/influxdata/kapacitor/services/deadman"
 	"github.com/influxdata/kapacitor/services/diagnostic"
 	"github.com/influxdata/kapacitor/services/dns"
+	"github.com/influxdata/kapacitor/services/docker_discovery"
 	"github.com/influxdata/kapacitor/services/ec2"
 	"github.com/influxdata/kapacitor/services/file_discovery"
 	"github.com/influxdata/kapacitor/services/gce"
+	"github.com/influxdata/kapacitor/services/graphite_pickle"
+	"github.com/influxdata/kapacitor/services/grpcapi"
+	"github.com/influxdata/kapacitor/services/ha"
+	"github.com/influxdata/kapacitor/services/health"
 	"github.com/influxdata/kapacitor/services/hipchat"
+	"github.com/influxdata/kapacitor/services/http_discovery"
 	"github.com/influxdata/kapacitor/services/httpd"
 	"github.com/influxdata/kapacitor/services/httppost"
 	"github.com/influxdata/kapacitor/services/influxdb"
@@ -30,32 +43,42 @@ import (
 	"github.com/influxdata/kapacitor/services/kafka"
 	"github.com/influxdata/kapacitor/services/load"
 	"github.com/influxdata/kapacitor/services/marathon"
+	"github.com/influxdata/kapacitor/services/metamonitoring"
 	"github.com/influxdata/kapacitor/services/mqtt"
+	"github.com/influxdata/kapacitor/services/nats"
 	"github.com/influxdata/kapacitor/services/nerve"
+	"github.com/influxdata/kapacitor/services/oidc"
 	"github.com/influxdata/kapacitor/services/opsgenie"
 	"github.com/influxdata/kapacitor/services/opsgenie2"
 	"github.com/influxdata/kapacitor/services/pagerduty"
 	"github.com/influxdata/kapacitor/services/pagerduty2"
 	"github.com/influxdata/kapacitor/services/pushover"
+	"github.com/influxdata/kapacitor/services/rbac"
 	"github.com/influxdata/kapacitor/services/replay"
 	"github.com/influxdata/kapacitor/services/reporting"
 	"github.com/influxdata/kapacitor/services/scraper"
 	"github.com/influxdata/kapacitor/services/sensu"
 	"github.com/influxdata/kapacitor/services/serverset"
+	"github.com/influxdata/kapacitor/services/sideload"
 	"github.com/influxdata/kapacitor/services/slack"
 	"github.com/influxdata/kapacitor/services/smtp"
+	"github.com/influxdata/kapacitor/services/snmp"
 	"github.com/influxdata/kapacitor/services/snmptrap"
 	"github.com/influxdata/kapacitor/services/static_discovery"
 	"github.com/influxdata/kapacitor/services/stats"
+	"github.com/influxdata/kapacitor/services/statsd"
 	"github.com/influxdata/kapacitor/services/storage"
 	"github.com/influxdata/kapacitor/services/swarm"
+	"github.com/influxdata/kapacitor/services/tail"
 	"github.com/influxdata/kapacitor/services/talk"
 	"github.com/influxdata/kapacitor/services/task_store"
 	"github.com/influxdata/kapacitor/services/telegram"
+	"github.com/influxdata/kapacitor/services/tracing"
 	"github.com/influxdata/kapacitor/services/triton"
 	"github.com/influxdata/kapacitor/services/udf"
 	"github.com/influxdata/kapacitor/services/udp"
 	"github.com/influxdata/kapacitor/services/victorops"
+	"github.com/influxdata/kapacitor/services/wal"
 	"github.com/pkg/errors"
 
 	"github.com/influxdata/influxdb/services/collectd"
@@ -65,23 +88,40 @@ import (
 
 // Config represents the configuration format for the kapacitord binary.
 type Config struct {
-	HTTP           httpd.Config      `toml:"http"`
-	Replay         replay.Config     `toml:"replay"`
-	Storage        storage.Config    `toml:"storage"`
-	Task           task_store.Config `toml:"task"`
-	Load           load.Config       `toml:"load"`
-	InfluxDB       []influxdb.Config `toml:"influxdb" override:"influxdb,element-key=name"`
-	Logging        diagnostic.Config `toml:"logging"`
-	ConfigOverride config.Config     `toml:"config-override"`
+	HTTP           httpd.Config          `toml:"http"`
+	GRPC           grpcapi.Config        `toml:"grpc"`
+	Replay         replay.Config         `toml:"replay"`
+	Storage        storage.Config        `toml:"storage"`
+	Task           task_store.Config     `toml:"task"`
+	Load           load.Config           `toml:"load"`
+	InfluxDB       []influxdb.Config     `toml:"influxdb" override:"influxdb,element-key=name"`
+	Logging        diagnostic.Config     `toml:"logging"`
+	ConfigOverride config.Config         `toml:"config-override"`
+	RBAC           rbac.Config           `toml:"rbac"`
+	OIDC           oidc.Config           `toml:"oidc"`
+	Audit          audit.Config          `toml:"audit"`
+	Tracing        tracing.Config        `toml:"tracing"`
+	Health         health.Config         `toml:"health"`
+	MetaMonitoring metamonitoring.Config `toml:"meta-monitoring"`
+	HA             ha.Config             `toml:"ha"`
+	Cluster        cluster.Config        `toml:"cluster"`
+	WAL            wal.Config            `toml:"wal"`
+	Sideload       sideload.Config       `toml:"sideload"`
 
 	// Input services
-	Graphite []graphite.Config `toml:"graphite"`
-	Collectd collectd.Config   `toml:"collectd"`
-	OpenTSDB opentsdb.Config   `toml:"opentsdb"`
-	UDP      []udp.Config      `toml:"udp"`
+	Graphite       []graphite.Config        `toml:"graphite"`
+	GraphitePickle []graphite_pickle.Config `toml:"graphite-pickle"`
+	Collectd       CollectdConfigs          `toml:"collectd" env-config:"implicit-index"`
+	OpenTSDB       OpenTSDBConfigs          `toml:"opentsdb" env-config:"implicit-index"`
+	UDP            []udp.Config             `toml:"udp"`
+	NATS           []nats.Config            `toml:"nats"`
+	SNMP           []snmp.Config            `toml:"snmp"`
+	Statsd         []statsd.Config          `toml:"statsd"`
+	Tail           []tail.Config            `toml:"tail"`
 
 	// Alert handlers
 	Alerta     alerta.Config     `toml:"alerta" override:"alerta"`
+	AMQP       amqp.Configs      `toml:"amqp" override:"amqp,element-key=name"`
 	HipChat    hipchat.Config    `toml:"hipchat" override:"hipchat"`
 	Kafka      kafka.Configs     `toml:"kafka" override:"kafka,element-key=id"`
 	MQTT       mqtt.Configs      `toml:"mqtt" override:"mqtt,element-key=name"`
@@ -104,9 +144,11 @@ type Config struct {
 	Azure           []azure.Config            `toml:"azure" override:"azure,element-key=id"`
 	Consul          []consul.Config           `toml:"consul" override:"consul,element-key=id"`
 	DNS             []dns.Config              `toml:"dns" override:"dns,element-key=id"`
+	DockerDiscovery []docker_discovery.Config `toml:"docker-discovery" override:"docker-discovery,element-key=id"`
 	EC2             []ec2.Config              `toml:"ec2" override:"ec2,element-key=id"`
 	FileDiscovery   []file_discovery.Config   `toml:"file-discovery" override:"file-discovery,element-key=id"`
 	GCE             []gce.Config              `toml:"gce" override:"gce,element-key=id"`
+	HTTPDiscovery   []http_discovery.Config   `toml:"http-discovery" override:"http-discovery,element-key=id"`
 	Marathon        []marathon.Config         `toml:"marathon" override:"marathon,element-key=id"`
 	Nerve           []nerve.Config            `toml:"nerve" override:"nerve,element-key=id"`
 	Serverset       []serverset.Config        `toml:"serverset" override:"serverset,element-key=id"`
@@ -130,6 +172,22 @@ type Config struct {
 	Commander command.Commander `toml:"-"`
 }
 
+// CollectdConfigs is a list of collectd listeners.
+// A single [collectd] section is accepted as well as multiple [[collectd]] sections.
+type CollectdConfigs []collectd.Config
+
+func (cs *CollectdConfigs) UnmarshalTOML(data interface{}) error {
+	return listmap.DoUnmarshalTOML(cs, data)
+}
+
+// OpenTSDBConfigs is a list of OpenTSDB listeners.
+// A single [opentsdb] section is accepted as well as multiple [[opentsdb]] sections.
+type OpenTSDBConfigs []opentsdb.Config
+
+func (cs *OpenTSDBConfigs) UnmarshalTOML(data interface{}) error {
+	return listmap.DoUnmarshalTOML(cs, data)
+}
+
 // NewConfig returns an instance of Config with reasonable defaults.
 func NewConfig() *Config {
 	c := &Config{
@@ -144,11 +202,22 @@ func NewConfig() *Config {
 	c.InfluxDB = []influxdb.Config{influxdb.NewConfig()}
 	c.Logging = diagnostic.NewConfig()
 	c.ConfigOverride = config.NewConfig()
-
-	c.Collectd = collectd.NewConfig()
-	c.OpenTSDB = opentsdb.NewConfig()
+	c.RBAC = rbac.NewConfig()
+	c.OIDC = oidc.NewConfig()
+	c.Audit = audit.NewConfig()
+	c.Tracing = tracing.NewConfig()
+	c.Health = health.NewConfig()
+	c.MetaMonitoring = metamonitoring.NewConfig()
+	c.HA = ha.NewConfig()
+	c.Cluster = cluster.NewConfig()
+	c.WAL = wal.NewConfig()
+	c.Sideload = sideload.NewConfig()
+
+	c.Collectd = CollectdConfigs{collectd.NewConfig()}
+	c.OpenTSDB = OpenTSDBConfigs{opentsdb.NewConfig()}
 
 	c.Alerta = alerta.NewConfig()
+	c.AMQP = amqp.Configs{amqp.NewConfig()}
 	c.HipChat = hipchat.NewConfig()
 	c.Kafka = kafka.Configs{kafka.NewConfig()}
 	c.MQTT = mqtt.Configs{mqtt.NewConfig()}
@@ -171,6 +240,7 @@ func NewConfig() *Config {
 	c.UDF = udf.NewConfig()
 	c.Deadman = deadman.NewConfig()
 	c.Load = load.NewConfig()
+	c.GRPC = grpcapi.NewConfig()
 
 	return c
 }
@@ -195,6 +265,7 @@ func NewDemoConfig() (*Config, error) {
 	c.Storage.BoltDBPath = filepath.Join(homeDir, ".kapacitor", c.Storage.BoltDBPath)
 	c.DataDir = filepath.Join(homeDir, ".kapacitor", c.DataDir)
 	c.Load.Dir = filepath.Join(homeDir, ".kapacitor", c.Load.Dir)
+	c.WAL.Dir = filepath.Join(homeDir, ".kapacitor", c.WAL.Dir)
 
 	return c, nil
 }
@@ -207,6 +278,9 @@ func (c *Config) Validate() error {
 	if c.DataDir == "" {
 		return fmt.Errorf("must configure valid data dir")
 	}
+	if err := c.Logging.Validate(); err != nil {
+		return errors.Wrap(err, "logging")
+	}
 	if err := c.Replay.Validate(); err != nil {
 		return errors.Wrap(err, "replay")
 	}
@@ -216,12 +290,54 @@ func (c *Config) Validate() error {
 	if err := c.HTTP.Validate(); err != nil {
 		return errors.Wrap(err, "http")
 	}
+	if err := c.GRPC.Validate(); err != nil {
+		return errors.Wrap(err, "grpc")
+	}
 	if err := c.Task.Validate(); err != nil {
 		return errors.Wrap(err, "task")
 	}
 	if err := c.Load.Validate(); err != nil {
 		return err
 	}
+	if err := c.RBAC.Validate(); err != nil {
+		return errors.Wrap(err, "rbac")
+	}
+	if len(c.HTTP.HTTPSClientCertificateRoles) > 0 && !c.RBAC.Enabled {
+		return errors.New("http: https-client-certificate-roles requires rbac to be enabled")
+	}
+	if err := c.OIDC.Validate(); err != nil {
+		return errors.Wrap(err, "oidc")
+	}
+	if err := c.Audit.Validate(); err != nil {
+		return errors.Wrap(err, "audit")
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		return errors.Wrap(err, "tracing")
+	}
+	if err := c.Health.Validate(); err != nil {
+		return errors.Wrap(err, "health")
+	}
+	if err := c.MetaMonitoring.Validate(); err != nil {
+		return errors.Wrap(err, "meta-monitoring")
+	}
+	if c.MetaMonitoring.Enabled && !c.Stats.Enabled {
+		return errors.New("meta-monitoring: requires stats to be enabled")
+	}
+	if err := c.HA.Validate(); err != nil {
+		return errors.Wrap(err, "ha")
+	}
+	if err := c.Cluster.Validate(); err != nil {
+		return errors.Wrap(err, "cluster")
+	}
+	if c.Cluster.Enabled && c.HA.Enabled {
+		return errors.New("cluster: cannot be enabled together with ha")
+	}
+	if err := c.WAL.Validate(); err != nil {
+		return errors.Wrap(err, "wal")
+	}
+	if err := c.Sideload.Validate(); err != nil {
+		return errors.Wrap(err, "sideload")
+	}
 	// Validate the set of InfluxDB configs.
 	// All names should be unique.
 	names := make(map[string]bool, len(c.InfluxDB))
@@ -258,11 +374,44 @@ func (c *Config) Validate() error {
 			return errors.Wrap(err, "graphite")
 		}
 	}
+	for _, g := range c.GraphitePickle {
+		if err := g.Validate(); err != nil {
+			return errors.Wrap(err, "graphite-pickle")
+		}
+	}
+	for _, u := range c.UDP {
+		if err := u.Validate(); err != nil {
+			return errors.Wrap(err, "udp")
+		}
+	}
+	for i := range c.NATS {
+		if err := c.NATS[i].Validate(); err != nil {
+			return errors.Wrapf(err, "nats %q", c.NATS[i].Name)
+		}
+	}
+	for i := range c.SNMP {
+		if err := c.SNMP[i].Validate(); err != nil {
+			return errors.Wrapf(err, "snmp %q", c.SNMP[i].Name)
+		}
+	}
+	for i := range c.Statsd {
+		if err := c.Statsd[i].Validate(); err != nil {
+			return errors.Wrapf(err, "statsd %d", i)
+		}
+	}
+	for i := range c.Tail {
+		if err := c.Tail[i].Validate(); err != nil {
+			return errors.Wrapf(err, "tail %q", c.Tail[i].Name)
+		}
+	}
 
 	// Validate alert handlers
 	if err := c.Alerta.Validate(); err != nil {
 		return errors.Wrap(err, "alerta")
 	}
+	if err := c.AMQP.Validate(); err != nil {
+		return errors.Wrap(err, "amqp")
+	}
 	if err := c.HipChat.Validate(); err != nil {
 		return errors.Wrap(err, "hipchat")
 	}
@@ -341,6 +490,12 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	for i := range c.DockerDiscovery {
+		if err := c.DockerDiscovery[i].Validate(); err != nil {
+			return errors.Wrapf(err, "docker discovery %q", c.DockerDiscovery[i].ID)
+		}
+	}
+
 	for i := range c.EC2 {
 		if err := c.EC2[i].Validate(); err != nil {
 			return errors.Wrapf(err, "ec2 %q", c.EC2[i].ID)
@@ -359,6 +514,12 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	for i := range c.HTTPDiscovery {
+		if err := c.HTTPDiscovery[i].Validate(); err != nil {
+			return errors.Wrapf(err, "http discovery %q", c.HTTPDiscovery[i].ID)
+		}
+	}
+
 	if err := c.Kubernetes.Validate(); err != nil {
 		return errors.Wrap(err, "kubernetes")
 	}
@@ -404,6 +565,164 @@ func (c *Config) ApplyEnvOverrides() error {
 	return c.applyEnvOverrides("KAPACITOR", "", reflect.ValueOf(c))
 }
 
+// DecodeFiles decodes the TOML configuration files into the config, in order,
+// e.g. the configuration file followed by the fragments of a configuration directory.
+// The options defined by a file take precedence over the options of the previous files,
+// except for the arrays of tables, e.g. [[httppost]], whose entries are appended to the entries of the previous files.
+// The options a file does not define are left unchanged.
+func (c *Config) DecodeFiles(paths ...string) error {
+	// The arrays of tables defined by the previous files.
+	arrays := make(map[string]bool)
+	dst := reflect.ValueOf(c).Elem()
+	for _, path := range paths {
+		f := NewConfig()
+		md, err := toml.DecodeFile(path, f)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode %s", path)
+		}
+		defined := make(map[string]bool, len(md.Keys()))
+		for _, k := range md.Keys() {
+			defined[strings.ToLower(k.String())] = true
+		}
+		src := reflect.ValueOf(f).Elem()
+		for i := 0; i < dst.NumField(); i++ {
+			field := dst.Type().Field(i)
+			name := strings.ToLower(tomlName(field))
+			if field.PkgPath != "" || !defined[name] {
+				continue
+			}
+			if field.Type.Kind() == reflect.Slice {
+				if arrays[name] {
+					dst.Field(i).Set(reflect.AppendSlice(dst.Field(i), src.Field(i)))
+				} else {
+					dst.Field(i).Set(src.Field(i))
+				}
+				arrays[name] = true
+				continue
+			}
+			mergeDefined(dst.Field(i), src.Field(i), name, defined)
+		}
+	}
+	return nil
+}
+
+// mergeDefined sets the options of dst defined by the keys to their values in src.
+func mergeDefined(dst, src reflect.Value, key string, defined map[string]bool) {
+	if dst.Kind() != reflect.Struct || reflect.PtrTo(dst.Type()).Implements(textUnmarshalerType) {
+		dst.Set(src)
+		return
+	}
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		// The options of embedded structs are options of the struct.
+		if field.Anonymous && field.Tag.Get("toml") == "" {
+			mergeDefined(dst.Field(i), src.Field(i), key, defined)
+			continue
+		}
+		fieldKey := key + "." + strings.ToLower(tomlName(field))
+		if defined[fieldKey] {
+			mergeDefined(dst.Field(i), src.Field(i), fieldKey, defined)
+		}
+	}
+}
+
+// interpolationPattern matches the $ENV{VAR} and $FILE{/path} references of string values.
+var interpolationPattern = regexp.MustCompile(`\$(ENV|FILE)\{([^}]*)\}`)
+
+// Interpolate replaces the references in all string values of the configuration:
+// $ENV{VAR} with the value of the environment variable VAR, which must be set,
+// and $FILE{/path} with the content of the file without its trailing newlines,
+// so that credentials can be injected by the environment.
+func (c *Config) Interpolate() error {
+	return interpolateValue("", reflect.ValueOf(c).Elem())
+}
+
+func interpolateValue(name string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return interpolateValue(name, v.Elem())
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			f := t.Field(i)
+			// Skip unexported fields
+			if f.PkgPath != "" {
+				continue
+			}
+			fieldName := strings.Split(f.Tag.Get("toml"), ",")[0]
+			if fieldName == "" {
+				fieldName = f.Name
+			}
+			if name != "" {
+				fieldName = name + "." + fieldName
+			}
+			if err := interpolateValue(fieldName, v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := interpolateValue(fmt.Sprintf("%s[%d]", name, i), v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			// Map values are not addressable, interpolate a copy and set it back.
+			e := reflect.New(v.Type().Elem()).Elem()
+			e.Set(v.MapIndex(k))
+			if e.Kind() == reflect.Interface && e.Elem().Kind() == reflect.String {
+				value, err := interpolateString(fmt.Sprintf("%s.%v", name, k), e.Elem().String())
+				if err != nil {
+					return err
+				}
+				e.Set(reflect.ValueOf(value))
+			} else if err := interpolateValue(fmt.Sprintf("%s.%v", name, k), e); err != nil {
+				return err
+			}
+			v.SetMapIndex(k, e)
+		}
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		value, err := interpolateString(name, v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(value)
+	}
+	return nil
+}
+
+func interpolateString(name, s string) (string, error) {
+	var err error
+	value := interpolationPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		m := interpolationPattern.FindStringSubmatch(ref)
+		switch m[1] {
+		case "ENV":
+			v, ok := os.LookupEnv(m[2])
+			if !ok && err == nil {
+				err = fmt.Errorf("%s: environment variable %q is not set", name, m[2])
+			}
+			return v
+		default:
+			data, rerr := ioutil.ReadFile(m[2])
+			if rerr != nil && err == nil {
+				err = errors.Wrapf(rerr, "%s: failed to read file", name)
+			}
+			return strings.TrimRight(string(data), "\r\n")
+		}
+	})
+	return value, err
+}
+
 func (c *Config) applyEnvOverridesToMap(prefix string, fieldDesc string, mapValue, key, spec reflect.Value) error {
 	// If we have a pointer, dereference it
 	s := spec
@@ -1,7 +1,11 @@
 package server_test
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/BurntSushi/toml"
@@ -82,6 +86,149 @@ headers = { Authorization = "your-key" }
 	}
 }
 
+func TestConfig_Interpolate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kapacitor-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	secretPath := filepath.Join(dir, "secret")
+	if err := ioutil.WriteFile(secretPath, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("KAPACITOR_TEST_SMTP_HOST", "smtp.example.com"); err != nil {
+		t.Fatalf("failed to set env var: %v", err)
+	}
+	defer os.Unsetenv("KAPACITOR_TEST_SMTP_HOST")
+
+	var c server.Config
+	if _, err := toml.Decode(fmt.Sprintf(`
+[smtp]
+host = "$ENV{KAPACITOR_TEST_SMTP_HOST}"
+password = "$FILE{%s}"
+
+[[influxdb]]
+urls = ["http://$ENV{KAPACITOR_TEST_SMTP_HOST}:8086"]
+
+[[httppost]]
+headers = { Authorization = "Bearer $FILE{%s}" }
+`, secretPath, secretPath), &c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Interpolate(); err != nil {
+		t.Fatal(err)
+	}
+	if c.SMTP.Host != "smtp.example.com" {
+		t.Errorf("unexpected smtp host: %s", c.SMTP.Host)
+	}
+	if c.SMTP.Password != "s3cr3t" {
+		t.Errorf("unexpected smtp password: %s", c.SMTP.Password)
+	}
+	if c.InfluxDB[0].URLs[0] != "http://smtp.example.com:8086" {
+		t.Errorf("unexpected influxdb url: %s", c.InfluxDB[0].URLs[0])
+	}
+	if c.HTTPPost[0].Headers["Authorization"] != "Bearer s3cr3t" {
+		t.Errorf("unexpected header Authorization: %s", c.HTTPPost[0].Headers["Authorization"])
+	}
+
+	c.SMTP.Username = "$ENV{KAPACITOR_TEST_MISSING}"
+	if err := c.Interpolate(); err == nil || err.Error() != `smtp.username: environment variable "KAPACITOR_TEST_MISSING" is not set` {
+		t.Errorf("unexpected error for missing env var: %v", err)
+	}
+	c.SMTP.Username = "$FILE{" + filepath.Join(dir, "missing") + "}"
+	if err := c.Interpolate(); err == nil || !strings.HasPrefix(err.Error(), "smtp.username: failed to read file") {
+		t.Errorf("unexpected error for missing file: %v", err)
+	}
+}
+
+func TestConfig_DecodeFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kapacitor-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := []struct {
+		name    string
+		content string
+	}{
+		{
+			name: "kapacitor.conf",
+			content: `
+hostname = "main"
+[http]
+bind-address = ":9093"
+[smtp]
+enabled = true
+host = "smtp.example.com"
+port = 2525
+[[httppost]]
+endpoint = "main"
+url = "http://main.example.com"
+`,
+		},
+		{
+			name: "10-smtp.conf",
+			content: `
+[smtp]
+port = 465
+password = "secret"
+[[httppost]]
+endpoint = "fragment"
+url = "http://fragment.example.com"
+`,
+		},
+		{
+			name: "20-slack.conf",
+			content: `
+[[slack]]
+workspace = "ops"
+enabled = true
+channel = "#alerts"
+`,
+		},
+	}
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = filepath.Join(dir, f.name)
+		if err := ioutil.WriteFile(paths[i], []byte(f.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := server.NewConfig()
+	if err := c.DecodeFiles(paths...); err != nil {
+		t.Fatal(err)
+	}
+	if c.Hostname != "main" {
+		t.Errorf("unexpected hostname: %s", c.Hostname)
+	}
+	if c.HTTP.BindAddress != ":9093" {
+		t.Errorf("unexpected http bind-address: %s", c.HTTP.BindAddress)
+	}
+	// The options not defined by any file keep their default values.
+	if !c.HTTP.LogEnabled {
+		t.Error("expected http log-enabled to keep its default value")
+	}
+	if !c.SMTP.Enabled || c.SMTP.Host != "smtp.example.com" || c.SMTP.Port != 465 || c.SMTP.Password != "secret" {
+		t.Errorf("unexpected smtp config: %+v", c.SMTP)
+	}
+	if len(c.HTTPPost) != 2 || c.HTTPPost[0].Endpoint != "main" || c.HTTPPost[1].Endpoint != "fragment" {
+		t.Errorf("unexpected httppost configs: %+v", c.HTTPPost)
+	}
+	if len(c.Slack) != 1 || c.Slack[0].Workspace != "ops" || c.Slack[0].Channel != "#alerts" {
+		t.Errorf("unexpected slack configs: %+v", c.Slack)
+	}
+
+	// A file that cannot be decoded names the file.
+	badPath := filepath.Join(dir, "bad.conf")
+	if err := ioutil.WriteFile(badPath, []byte("[smtp"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DecodeFiles(badPath); err == nil || !strings.HasPrefix(err.Error(), "failed to decode "+badPath) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
 // Ensure the configuration can be parsed.
 func TestConfig_Single_Conf(t *testing.T) {
 	// Parse configuration.
@@ -198,3 +345,31 @@ channel = "#kapacitor_public"
 		t.Fatalf("Expected config to be invalid, %s", cStr)
 	}
 }
+
+// Ensure collectd and opentsdb listeners can be configured as a single section or multiple sections.
+func TestConfig_Collectd_OpenTSDB_Conf(t *testing.T) {
+	// Parse configuration.
+	var c server.Config
+	if _, err := toml.Decode(`
+[collectd]
+enabled = true
+bind-address = ":25826"
+[[opentsdb]]
+enabled = true
+bind-address = ":4242"
+[[opentsdb]]
+enabled = true
+bind-address = ":4243"
+database = "other"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if len(c.Collectd) != 1 || c.Collectd[0].BindAddress != ":25826" || !c.Collectd[0].Enabled {
+		t.Fatalf("unexpected collectd config: %v", c.Collectd)
+	}
+	if len(c.OpenTSDB) != 2 || c.OpenTSDB[0].BindAddress != ":4242" || c.OpenTSDB[1].BindAddress != ":4243" || c.OpenTSDB[1].Database != "other" {
+		t.Fatalf("unexpected opentsdb config: %v", c.OpenTSDB)
+	}
+}
@@ -6,11 +6,16 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"runtime/pprof"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/collectd"
 	"github.com/influxdata/influxdb/services/graphite"
 	"github.com/influxdata/influxdb/services/meta"
@@ -18,21 +23,33 @@ import (
 	"github.com/influxdata/kapacitor"
 	"github.com/influxdata/kapacitor/auth"
 	"github.com/influxdata/kapacitor/command"
+	"github.com/influxdata/kapacitor/edge"
 	iclient "github.com/influxdata/kapacitor/influxdb"
 	"github.com/influxdata/kapacitor/keyvalue"
 	"github.com/influxdata/kapacitor/server/vars"
 	"github.com/influxdata/kapacitor/services/alert"
 	"github.com/influxdata/kapacitor/services/alerta"
+	"github.com/influxdata/kapacitor/services/amqp"
+	"github.com/influxdata/kapacitor/services/audit"
 	"github.com/influxdata/kapacitor/services/azure"
+	"github.com/influxdata/kapacitor/services/bundle"
+	"github.com/influxdata/kapacitor/services/cluster"
 	"github.com/influxdata/kapacitor/services/config"
 	"github.com/influxdata/kapacitor/services/consul"
+	"github.com/influxdata/kapacitor/services/dbrps"
 	"github.com/influxdata/kapacitor/services/deadman"
 	"github.com/influxdata/kapacitor/services/diagnostic"
 	"github.com/influxdata/kapacitor/services/dns"
+	"github.com/influxdata/kapacitor/services/docker_discovery"
 	"github.com/influxdata/kapacitor/services/ec2"
 	"github.com/influxdata/kapacitor/services/file_discovery"
 	"github.com/influxdata/kapacitor/services/gce"
+	"github.com/influxdata/kapacitor/services/graphite_pickle"
+	"github.com/influxdata/kapacitor/services/grpcapi"
+	"github.com/influxdata/kapacitor/services/ha"
+	"github.com/influxdata/kapacitor/services/health"
 	"github.com/influxdata/kapacitor/services/hipchat"
+	"github.com/influxdata/kapacitor/services/http_discovery"
 	"github.com/influxdata/kapacitor/services/httpd"
 	"github.com/influxdata/kapacitor/services/httppost"
 	"github.com/influxdata/kapacitor/services/influxdb"
@@ -40,14 +57,18 @@ import (
 	"github.com/influxdata/kapacitor/services/kafka"
 	"github.com/influxdata/kapacitor/services/load"
 	"github.com/influxdata/kapacitor/services/marathon"
+	"github.com/influxdata/kapacitor/services/metamonitoring"
 	"github.com/influxdata/kapacitor/services/mqtt"
+	"github.com/influxdata/kapacitor/services/nats"
 	"github.com/influxdata/kapacitor/services/nerve"
 	"github.com/influxdata/kapacitor/services/noauth"
+	"github.com/influxdata/kapacitor/services/oidc"
 	"github.com/influxdata/kapacitor/services/opsgenie"
 	"github.com/influxdata/kapacitor/services/opsgenie2"
 	"github.com/influxdata/kapacitor/services/pagerduty"
 	"github.com/influxdata/kapacitor/services/pagerduty2"
 	"github.com/influxdata/kapacitor/services/pushover"
+	"github.com/influxdata/kapacitor/services/rbac"
 	"github.com/influxdata/kapacitor/services/replay"
 	"github.com/influxdata/kapacitor/services/reporting"
 	"github.com/influxdata/kapacitor/services/scraper"
@@ -57,18 +78,24 @@ import (
 	"github.com/influxdata/kapacitor/services/sideload"
 	"github.com/influxdata/kapacitor/services/slack"
 	"github.com/influxdata/kapacitor/services/smtp"
+	"github.com/influxdata/kapacitor/services/snmp"
 	"github.com/influxdata/kapacitor/services/snmptrap"
 	"github.com/influxdata/kapacitor/services/static_discovery"
 	"github.com/influxdata/kapacitor/services/stats"
+	"github.com/influxdata/kapacitor/services/statsd"
 	"github.com/influxdata/kapacitor/services/storage"
 	"github.com/influxdata/kapacitor/services/swarm"
+	"github.com/influxdata/kapacitor/services/tail"
 	"github.com/influxdata/kapacitor/services/talk"
 	"github.com/influxdata/kapacitor/services/task_store"
 	"github.com/influxdata/kapacitor/services/telegram"
+	"github.com/influxdata/kapacitor/services/tokens"
+	"github.com/influxdata/kapacitor/services/tracing"
 	"github.com/influxdata/kapacitor/services/triton"
 	"github.com/influxdata/kapacitor/services/udf"
 	"github.com/influxdata/kapacitor/services/udp"
 	"github.com/influxdata/kapacitor/services/victorops"
+	"github.com/influxdata/kapacitor/services/wal"
 	"github.com/influxdata/kapacitor/uuid"
 	"github.com/influxdata/kapacitor/waiter"
 	"github.com/pkg/errors"
@@ -110,10 +137,18 @@ type Server struct {
 	TaskMaster       *kapacitor.TaskMaster
 	TaskMasterLookup *kapacitor.TaskMasterLookup
 
+	// PointsWriter writes the points received by the input services,
+	// it is the TaskMaster unless the points are partitioned across a cluster.
+	PointsWriter interface {
+		WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+	}
+
 	LoadService           *load.Service
+	BundleService         *bundle.Service
 	SideloadService       *sideload.Service
 	AuthService           auth.Interface
 	HTTPDService          *httpd.Service
+	GRPCService           *grpcapi.Service
 	StorageService        *storage.Service
 	AlertService          *alert.Service
 	TaskStore             *task_store.Service
@@ -123,6 +158,11 @@ type Server struct {
 	ConfigOverrideService *config.Service
 	TesterService         *servicetest.Service
 	StatsService          *stats.Service
+	ClusterService        *cluster.Service
+	WALService            *wal.Service
+	HealthService         *health.Service
+	MetaMonitoringService *metamonitoring.Service
+	DBRPsService          *dbrps.Service
 
 	ScraperService *scraper.Service
 
@@ -138,6 +178,8 @@ type Server struct {
 	DynamicServices map[string]Updater
 	// Channel of incoming configuration updates.
 	configUpdates chan config.ConfigUpdate
+	// reloadMu serializes the reloads of the configuration.
+	reloadMu sync.Mutex
 
 	BuildInfo   BuildInfo
 	clusterIDMu sync.Mutex
@@ -198,16 +240,27 @@ func New(c *Config, buildInfo BuildInfo, diagService *diagnostic.Service) (*Serv
 	kd := diagService.NewKapacitorHandler()
 	s.TaskMaster = kapacitor.NewTaskMaster(kapacitor.MainTaskMaster, vars.Info, kd)
 	s.TaskMaster.DefaultRetentionPolicy = c.DefaultRetentionPolicy
+	s.TaskMaster.QueueThreshold = c.Task.QueueThreshold
+	s.TaskMaster.BackpressureRetryAfter = time.Duration(c.Task.BackpressureRetryAfter)
+	s.TaskMaster.CardinalityLimits = c.Task.Cardinality.Limits()
+	s.TaskMaster.OptimizePipelines = c.Task.OptimizePipelines
+	s.TaskMaster.BatchQueryJitter = time.Duration(c.Task.BatchQueryJitter)
+	s.TaskMaster.MaxConcurrentQueries = c.Task.MaxConcurrentQueries
 	s.TaskMaster.Commander = s.Commander
 	s.TaskMasterLookup.Set(s.TaskMaster)
 	if err := s.TaskMaster.Open(); err != nil {
 		return nil, err
 	}
+	s.PointsWriter = s.TaskMaster
 
 	// Append Kapacitor services.
 	s.initHTTPDService()
 	s.appendStorageService()
 	s.appendAuthService()
+	s.appendOIDCService()
+	s.appendAPITokenService()
+	s.appendAuditService()
+	s.appendTracingService()
 	s.appendConfigOverrideService()
 	s.appendTesterService()
 	s.appendSideloadService()
@@ -215,6 +268,13 @@ func New(c *Config, buildInfo BuildInfo, diagService *diagnostic.Service) (*Serv
 	// Init alert service
 	s.initAlertService()
 
+	// Append the log and cluster services before the services receiving points.
+	s.appendWALService()
+	s.appendClusterService()
+	// The points written to the HTTP API are partitioned and logged too.
+	s.HTTPDService.Handler.PointsWriter = s.PointsWriter
+	s.HTTPDService.LocalHandler.PointsWriter = s.PointsWriter
+
 	// Append all dynamic services after the config override and tester services.
 	s.appendUDFService()
 	s.appendDeadmanService()
@@ -222,13 +282,19 @@ func New(c *Config, buildInfo BuildInfo, diagService *diagnostic.Service) (*Serv
 	if err := s.appendInfluxDBService(); err != nil {
 		return nil, errors.Wrap(err, "influxdb service")
 	}
+	s.appendDBRPsService()
 
 	if err := s.appendLoadService(); err != nil {
 		return nil, errors.Wrap(err, "load service")
 	}
 
+	if err := s.appendBundleService(); err != nil {
+		return nil, errors.Wrap(err, "bundle service")
+	}
+
 	// Append Alert integration services
 	s.appendAlertaService()
+	s.appendAMQPService()
 	s.appendHipChatService()
 	s.appendKafkaService()
 	if err := s.appendMQTTService(); err != nil {
@@ -262,16 +328,23 @@ func New(c *Config, buildInfo BuildInfo, diagService *diagnostic.Service) (*Serv
 
 	// Append third-party integrations
 	// Append extra input services
-	if err := s.appendCollectdService(); err != nil {
+	if err := s.appendCollectdServices(); err != nil {
 		return nil, errors.Wrap(err, "collectd service")
 	}
 	s.appendUDPServices()
-	if err := s.appendOpenTSDBService(); err != nil {
+	s.appendNATSServices()
+	s.appendSNMPServices()
+	s.appendStatsdServices()
+	s.appendTailServices()
+	if err := s.appendOpenTSDBServices(); err != nil {
 		return nil, errors.Wrap(err, "opentsdb service")
 	}
 	if err := s.appendGraphiteServices(); err != nil {
 		return nil, errors.Wrap(err, "graphite service")
 	}
+	if err := s.appendGraphitePickleServices(); err != nil {
+		return nil, errors.Wrap(err, "graphite pickle service")
+	}
 
 	// Append Scraper and discovery services
 	s.appendScraperService()
@@ -289,8 +362,10 @@ func New(c *Config, buildInfo BuildInfo, diagService *diagnostic.Service) (*Serv
 	s.appendAzureService()
 	s.appendConsulService()
 	s.appendDNSService()
+	s.appendDockerDiscoveryService()
 	s.appendFileService()
 	s.appendGCEService()
+	s.appendHTTPDiscoveryService()
 	s.appendMarathonService()
 	s.appendNerveService()
 	s.appendServersetService()
@@ -302,7 +377,19 @@ func New(c *Config, buildInfo BuildInfo, diagService *diagnostic.Service) (*Serv
 	s.appendStatsService()
 	s.appendReportingService()
 
-	// Append HTTPD Service last so that the API is not listening till everything else succeeded.
+	// Append the HA service after the task store and alert services, it starts and stops the tasks.
+	s.appendHAService()
+
+	// Append the meta-monitoring service after the task store, alert and stats services, its tasks alert on the stats.
+	if err := s.appendMetaMonitoringService(); err != nil {
+		return nil, errors.Wrap(err, "meta-monitoring service")
+	}
+
+	// Append the health service after the services it checks.
+	s.appendHealthService()
+
+	// Append the API services last so that the API is not listening till everything else succeeded.
+	s.appendGRPCService()
 	s.appendHTTPDService()
 
 	return s, nil
@@ -375,7 +462,7 @@ func (s *Server) appendTesterService() {
 
 func (s *Server) appendSideloadService() {
 	d := s.DiagService.NewSideloadHandler()
-	srv := sideload.NewService(d)
+	srv := sideload.NewService(s.config.Sideload, d)
 	srv.HTTPDService = s.HTTPDService
 
 	s.SideloadService = srv
@@ -410,6 +497,7 @@ func (s *Server) appendLoadService() error {
 	}
 
 	srv.StorageService = s.StorageService
+	srv.HTTPDService = s.HTTPDService
 
 	s.LoadService = srv
 	s.AppendService("load", srv)
@@ -417,6 +505,22 @@ func (s *Server) appendLoadService() error {
 	return nil
 }
 
+func (s *Server) appendBundleService() error {
+	d := s.DiagService.NewBundleHandler()
+	srv, err := bundle.NewService(s.HTTPDService.LocalHandler, d)
+	if err != nil {
+		return err
+	}
+
+	srv.HTTPDService = s.HTTPDService
+	srv.ConfigOverrideService = s.ConfigOverrideService
+
+	s.BundleService = srv
+	s.AppendService("bundle", srv)
+
+	return nil
+}
+
 func (s *Server) appendInfluxDBService() error {
 	c := s.config.InfluxDB
 	d := s.DiagService.NewInfluxDBHandler()
@@ -435,7 +539,7 @@ func (s *Server) appendInfluxDBService() error {
 	srv.ClusterIDWaiter = w
 
 	srv.HTTPDService = s.HTTPDService
-	srv.PointsWriter = s.TaskMaster
+	srv.PointsWriter = s.PointsWriter
 	srv.AuthService = s.AuthService
 	srv.ClientCreator = iclient.ClientCreator{}
 
@@ -467,18 +571,153 @@ func (s *Server) appendHTTPDService() {
 	s.AppendService("httpd", s.HTTPDService)
 }
 
+func (s *Server) appendGRPCService() {
+	c := s.config.GRPC
+	d := s.DiagService.NewGRPCHandler()
+	srv := grpcapi.NewService(c, s.HTTPDService.Handler, d)
+	srv.AlertService = s.AlertService
+
+	s.GRPCService = srv
+	s.AppendService("grpc", srv)
+}
+
 func (s *Server) appendTaskStoreService() {
 	d := s.DiagService.NewTaskStoreHandler()
 	srv := task_store.NewService(s.config.Task, d)
 	srv.StorageService = s.StorageService
 	srv.HTTPDService = s.HTTPDService
 	srv.TaskMasterLookup = s.TaskMasterLookup
+	srv.AlertService = s.AlertService
+	srv.LogWatcher = s.DiagService.SessionService
+
+	// The tasks are started once the HA service elected this server the leader.
+	if s.config.HA.Enabled {
+		srv.Standby()
+	}
+
+	if s.ClusterService != nil {
+		srv.ClusterService = s.ClusterService
+	}
 
 	s.TaskStore = srv
 	s.TaskMaster.TaskStore = srv
 	s.AppendService("task_store", srv)
 }
 
+func (s *Server) appendHAService() {
+	if !s.config.HA.Enabled {
+		return
+	}
+	d := s.DiagService.NewHAHandler()
+	srv := ha.NewService(s.config.HA, vars.Info, d)
+	srv.StorageService = s.StorageService
+	srv.HTTPDService = s.HTTPDService
+	srv.TaskStore = s.TaskStore
+	srv.AlertService = s.AlertService
+
+	s.HTTPDService.Handler.LeaderService = srv
+	s.AppendService("ha", srv)
+}
+
+// Components of the logs of the alert handlers, whose last errors are checked by the health service.
+var alertHandlerComponents = []string{
+	"alert",
+	"alerta",
+	"amqp",
+	"hipchat",
+	"httppost",
+	"kafka",
+	"mqtt",
+	"opsgenie",
+	"opsgenie2",
+	"pagerduty",
+	"pagerduty2",
+	"pushover",
+	"sensu",
+	"slack",
+	"smtp",
+	"snmp",
+	"talk",
+	"telegram",
+	"victorops",
+}
+
+func (s *Server) appendHealthService() {
+	c := s.config.Health
+	if !c.Enabled {
+		return
+	}
+	srv := health.NewService(c)
+	srv.HTTPDService = s.HTTPDService
+	srv.AddCheck("storage", true, health.StorageCheck(s.StorageService))
+	srv.AddCheck("influxdb", false, health.InfluxDBCheck(s.InfluxDBService))
+	srv.AddCheck("scrapers", false, health.ScraperCheck(s.ScraperService))
+	srv.AddCheck("alert_handlers", false, health.LastErrorsCheck(s.DiagService.LastErrors, alertHandlerComponents, time.Duration(c.ErrorWindow)))
+
+	s.HealthService = srv
+	s.AppendService("health", srv)
+}
+
+func (s *Server) appendDBRPsService() {
+	d := s.DiagService.NewDBRPsHandler()
+	srv := dbrps.NewService(d)
+	srv.TaskMaster = s.TaskMaster
+	srv.InfluxDBService = s.InfluxDBService
+	srv.HTTPDService = s.HTTPDService
+
+	s.DBRPsService = srv
+	s.AppendService("dbrps", srv)
+}
+
+func (s *Server) appendMetaMonitoringService() error {
+	c := s.config.MetaMonitoring
+	d := s.DiagService.NewMetaMonitoringHandler()
+	srv, err := metamonitoring.NewService(c, s.HTTPDService.LocalHandler, d)
+	if err != nil {
+		return err
+	}
+	srv.Database = s.config.Stats.Database
+	srv.RetentionPolicy = s.config.Stats.RetentionPolicy
+	srv.HandlerComponents = alertHandlerComponents
+	srv.LastErrors = s.DiagService.LastErrors
+
+	s.MetaMonitoringService = srv
+	s.AppendService("meta-monitoring", srv)
+	return nil
+}
+
+func (s *Server) appendClusterService() {
+	c := s.config.Cluster
+	if !c.Enabled {
+		return
+	}
+	d := s.DiagService.NewClusterHandler()
+	srv := cluster.NewService(c, d)
+	// Only the points of this node are written to the log.
+	srv.PointsWriter = s.PointsWriter
+	srv.HTTPDService = s.HTTPDService
+
+	s.PointsWriter = srv
+	s.ClusterService = srv
+	s.AppendService("cluster", srv)
+}
+
+func (s *Server) appendWALService() {
+	c := s.config.WAL
+	if !c.Enabled {
+		return
+	}
+	d := s.DiagService.NewWALHandler()
+	srv := wal.NewService(c, d)
+	srv.PointsWriter = s.PointsWriter
+	srv.TaskMaster = s.TaskMaster
+	s.TaskMaster.WAL = srv
+
+	s.PointsWriter = srv
+	s.WALService = srv
+	s.AppendService("wal", srv)
+}
+
 func (s *Server) appendSessionService() {
 	srv := s.DiagService.SessionService
 	srv.HTTPDService = s.HTTPDService
@@ -495,6 +734,7 @@ func (s *Server) appendReplayService() {
 	srv.InfluxDBService = s.InfluxDBService
 	srv.TaskMaster = s.TaskMaster
 	srv.TaskMasterLookup = s.TaskMasterLookup
+	srv.AlertService = s.AlertService
 
 	s.ReplayService = srv
 	s.AppendService("replay", srv)
@@ -550,12 +790,26 @@ func (s *Server) appendDeadmanService() {
 func (s *Server) appendUDFService() {
 	d := s.DiagService.NewUDFServiceHandler()
 	srv := udf.NewService(s.config.UDF, d)
+	srv.HTTPDService = s.HTTPDService
+	srv.Dir = filepath.Join(s.dataDir, "udf")
 
 	s.TaskMaster.UDFService = srv
 	s.AppendService("udf", srv)
 }
 
 func (s *Server) appendAuthService() {
+	if s.config.RBAC.Enabled {
+		d := s.DiagService.NewRBACHandler()
+		srv := rbac.NewService(s.config.RBAC, d)
+		srv.HTTPDService = s.HTTPDService
+		srv.StorageService = s.StorageService
+
+		s.AuthService = srv
+		s.HTTPDService.Handler.AuthService = srv
+		s.HTTPDService.Handler.RoleService = srv
+		s.AppendService("auth", srv)
+		return
+	}
 	d := s.DiagService.NewNoAuthHandler()
 	srv := noauth.NewService(d)
 
@@ -564,6 +818,50 @@ func (s *Server) appendAuthService() {
 	s.AppendService("auth", srv)
 }
 
+func (s *Server) appendOIDCService() {
+	if !s.config.OIDC.Enabled {
+		return
+	}
+	d := s.DiagService.NewOIDCHandler()
+	srv := oidc.NewService(s.config.OIDC, d)
+	srv.HTTPDService = s.HTTPDService
+
+	s.HTTPDService.Handler.TokenVerifier = srv
+	s.AppendService("oidc", srv)
+}
+
+func (s *Server) appendAPITokenService() {
+	srv := tokens.NewService()
+	srv.HTTPDService = s.HTTPDService
+	srv.StorageService = s.StorageService
+	srv.AuthService = s.AuthService
+
+	s.HTTPDService.Handler.APITokenService = srv
+	s.AppendService("tokens", srv)
+}
+
+func (s *Server) appendAuditService() {
+	if !s.config.Audit.Enabled {
+		return
+	}
+	d := s.DiagService.NewAuditHandler()
+	srv := audit.NewService(s.config.Audit, d)
+	srv.HTTPDService = s.HTTPDService
+	srv.StorageService = s.StorageService
+
+	s.HTTPDService.Handler.AuditService = srv
+	s.AppendService("audit", srv)
+}
+
+func (s *Server) appendTracingService() {
+	if !s.config.Tracing.Enabled {
+		return
+	}
+	d := s.DiagService.NewTracingHandler()
+	srv := tracing.NewService(s.config.Tracing, d)
+	s.AppendService("tracing", srv)
+}
+
 func (s *Server) appendMQTTService() error {
 	cs := s.config.MQTT
 	d := s.DiagService.NewMQTTHandler()
@@ -757,6 +1055,19 @@ func (s *Server) appendAlertaService() {
 	s.AppendService("alerta", srv)
 }
 
+func (s *Server) appendAMQPService() {
+	c := s.config.AMQP
+	d := s.DiagService.NewAMQPHandler()
+	srv := amqp.NewService(c, d)
+	srv.PointsWriter = s.PointsWriter
+
+	s.TaskMaster.AMQPService = srv
+	s.AlertService.AMQPService = srv
+
+	s.SetDynamicService("amqp", srv)
+	s.AppendService("amqp", srv)
+}
+
 func (s *Server) appendTalkService() {
 	c := s.config.Talk
 	d := s.DiagService.NewTalkHandler()
@@ -769,43 +1080,44 @@ func (s *Server) appendTalkService() {
 	s.AppendService("talk", srv)
 }
 
-func (s *Server) appendCollectdService() error {
-	c := s.config.Collectd
-	if !c.Enabled {
-		return nil
-	}
-	srv := collectd.NewService(c)
-	w, err := s.DiagService.NewStaticLevelHandler("info", "collectd")
-	if err != nil {
-		return fmt.Errorf("failed to create static level handler for collectd: %v", err)
-	}
-	srv.SetLogOutput(w)
-
-	srv.MetaClient = s.MetaClient
-	srv.PointsWriter = s.TaskMaster
-	s.AppendService("collectd", srv)
+func (s *Server) appendCollectdServices() error {
+	for i, c := range s.config.Collectd {
+		if !c.Enabled {
+			continue
+		}
+		srv := collectd.NewService(c)
+		w, err := s.DiagService.NewStaticLevelHandler("info", "collectd")
+		if err != nil {
+			return fmt.Errorf("failed to create static level handler for collectd: %v", err)
+		}
+		srv.SetLogOutput(w)
 
+		srv.MetaClient = s.MetaClient
+		srv.PointsWriter = s.PointsWriter
+		s.AppendService(fmt.Sprintf("collectd%d", i), srv)
+	}
 	return nil
 }
 
-func (s *Server) appendOpenTSDBService() error {
-	c := s.config.OpenTSDB
-	if !c.Enabled {
-		return nil
-	}
-	srv, err := opentsdb.NewService(c)
-	if err != nil {
-		return err
-	}
-	w, err := s.DiagService.NewStaticLevelHandler("info", "opentsdb")
-	if err != nil {
-		return fmt.Errorf("failed to create static level handler for opentsdb: %v", err)
-	}
-	srv.SetLogOutput(w)
+func (s *Server) appendOpenTSDBServices() error {
+	for i, c := range s.config.OpenTSDB {
+		if !c.Enabled {
+			continue
+		}
+		srv, err := opentsdb.NewService(c)
+		if err != nil {
+			return err
+		}
+		w, err := s.DiagService.NewStaticLevelHandler("info", "opentsdb")
+		if err != nil {
+			return fmt.Errorf("failed to create static level handler for opentsdb: %v", err)
+		}
+		srv.SetLogOutput(w)
 
-	srv.PointsWriter = s.TaskMaster
-	srv.MetaClient = s.MetaClient
-	s.AppendService("opentsdb", srv)
+		srv.PointsWriter = s.PointsWriter
+		srv.MetaClient = s.MetaClient
+		s.AppendService(fmt.Sprintf("opentsdb%d", i), srv)
+	}
 	return nil
 }
 
@@ -824,13 +1136,29 @@ func (s *Server) appendGraphiteServices() error {
 		}
 		srv.SetLogOutput(w)
 
-		srv.PointsWriter = s.TaskMaster
+		srv.PointsWriter = s.PointsWriter
 		srv.MetaClient = s.MetaClient
 		s.AppendService(fmt.Sprintf("graphite%d", i), srv)
 	}
 	return nil
 }
 
+func (s *Server) appendGraphitePickleServices() error {
+	for i, c := range s.config.GraphitePickle {
+		if !c.Enabled {
+			continue
+		}
+		d := s.DiagService.NewGraphitePickleHandler()
+		srv, err := graphite_pickle.NewService(c, d)
+		if err != nil {
+			return errors.Wrap(err, "creating new graphite pickle service")
+		}
+		srv.PointsWriter = s.PointsWriter
+		s.AppendService(fmt.Sprintf("graphite-pickle%d", i), srv)
+	}
+	return nil
+}
+
 func (s *Server) appendUDPServices() {
 	for i, c := range s.config.UDP {
 		if !c.Enabled {
@@ -838,11 +1166,59 @@ func (s *Server) appendUDPServices() {
 		}
 		d := s.DiagService.NewUDPHandler()
 		srv := udp.NewService(c, d)
-		srv.PointsWriter = s.TaskMaster
+		srv.PointsWriter = s.PointsWriter
 		s.AppendService(fmt.Sprintf("udp%d", i), srv)
 	}
 }
 
+func (s *Server) appendNATSServices() {
+	for i, c := range s.config.NATS {
+		if !c.Enabled {
+			continue
+		}
+		d := s.DiagService.NewNATSHandler()
+		srv := nats.NewService(c, d)
+		srv.PointsWriter = s.PointsWriter
+		s.AppendService(fmt.Sprintf("nats%d", i), srv)
+	}
+}
+
+func (s *Server) appendSNMPServices() {
+	for i, c := range s.config.SNMP {
+		if !c.Enabled {
+			continue
+		}
+		d := s.DiagService.NewSNMPHandler()
+		srv := snmp.NewService(c, d)
+		srv.PointsWriter = s.PointsWriter
+		s.AppendService(fmt.Sprintf("snmp%d", i), srv)
+	}
+}
+
+func (s *Server) appendStatsdServices() {
+	for i, c := range s.config.Statsd {
+		if !c.Enabled {
+			continue
+		}
+		d := s.DiagService.NewStatsdHandler()
+		srv := statsd.NewService(c, d)
+		srv.PointsWriter = s.PointsWriter
+		s.AppendService(fmt.Sprintf("statsd%d", i), srv)
+	}
+}
+
+func (s *Server) appendTailServices() {
+	for i, c := range s.config.Tail {
+		if !c.Enabled {
+			continue
+		}
+		d := s.DiagService.NewTailHandler(c.Name)
+		srv := tail.NewService(c, d)
+		srv.PointsWriter = s.PointsWriter
+		s.AppendService(fmt.Sprintf("tail%d", i), srv)
+	}
+}
+
 func (s *Server) appendStatsService() {
 	c := s.config.Stats
 	if c.Enabled {
@@ -871,11 +1247,30 @@ func (s *Server) appendScraperService() {
 	d := s.DiagService.NewScraperHandler()
 	srv := scraper.NewService(c, d)
 	srv.PointsWriter = s.TaskMaster
+	if s.PointsWriter != s.TaskMaster {
+		// The scraped points are partitioned or logged too.
+		srv.PointsWriter = kapacitorPointsWriter{PointsWriter: s.PointsWriter}
+	}
 	s.ScraperService = srv
 	s.SetDynamicService("scraper", srv)
 	s.AppendService("scraper", srv)
 }
 
+// kapacitorPointsWriter writes Kapacitor points with a writer of InfluxDB points.
+type kapacitorPointsWriter struct {
+	PointsWriter interface {
+		WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+	}
+}
+
+func (w kapacitorPointsWriter) WriteKapacitorPoint(p edge.PointMessage) error {
+	point, err := models.NewPoint(p.Name(), models.NewTags(p.Tags()), models.Fields(p.Fields()), p.Time())
+	if err != nil {
+		return err
+	}
+	return w.PointsWriter.WritePoints(p.Database(), p.RetentionPolicy(), models.ConsistencyLevelAny, []models.Point{point})
+}
+
 func (s *Server) appendAzureService() {
 	c := s.config.Azure
 	d := s.DiagService.NewAzureHandler()
@@ -900,6 +1295,14 @@ func (s *Server) appendDNSService() {
 	s.AppendService("dns", srv)
 }
 
+func (s *Server) appendDockerDiscoveryService() {
+	c := s.config.DockerDiscovery
+	d := s.DiagService.NewDockerDiscoveryHandler()
+	srv := docker_discovery.NewService(c, s.ScraperService, d)
+	s.SetDynamicService("docker-discovery", srv)
+	s.AppendService("docker-discovery", srv)
+}
+
 func (s *Server) appendFileService() {
 	c := s.config.FileDiscovery
 	d := s.DiagService.NewFileDiscoveryHandler()
@@ -916,6 +1319,14 @@ func (s *Server) appendGCEService() {
 	s.AppendService("gce", srv)
 }
 
+func (s *Server) appendHTTPDiscoveryService() {
+	c := s.config.HTTPDiscovery
+	d := s.DiagService.NewHTTPDiscoveryHandler()
+	srv := http_discovery.NewService(c, s.ScraperService, d)
+	s.SetDynamicService("http-discovery", srv)
+	s.AppendService("http-discovery", srv)
+}
+
 func (s *Server) appendMarathonService() {
 	c := s.config.Marathon
 	d := s.DiagService.NewMarathonHandler()
@@ -976,9 +1387,17 @@ func (s *Server) Open() error {
 		return fmt.Errorf("failed to reload tasks/templates/handlers: %v", err)
 	}
 
+	// Replay the points logged before a crash into the loaded tasks.
+	if s.WALService != nil {
+		s.WALService.Replay()
+	}
+
 	go s.watchServices()
 	go s.watchConfigUpdates()
 
+	if s.HealthService != nil {
+		s.HealthService.SetReady(true)
+	}
 	return nil
 }
 
@@ -1034,6 +1453,9 @@ func (s *Server) watchConfigUpdates() {
 
 // Close shuts down the meta and data stores and all services.
 func (s *Server) Close() error {
+	if s.HealthService != nil {
+		s.HealthService.SetReady(false)
+	}
 	s.stopProfile()
 	s.clusterIDChanged.Stop()
 
@@ -1049,7 +1471,11 @@ func (s *Server) Close() error {
 
 	// Drain the in-flight writes and stop all tasks.
 	s.TaskMaster.Drain()
-	s.TaskMaster.StopTasks()
+	if s.config.Task.SnapshotOnShutdown {
+		s.TaskMaster.HandoffTasks()
+	} else {
+		s.TaskMaster.StopTasks()
+	}
 
 	// Close services now that all tasks are stopped.
 	for i := len(s.Services) - 1; i >= 0; i-- {
@@ -1141,6 +1567,147 @@ func (s *Server) Reload() {
 	if err := s.SideloadService.Reload(); err != nil {
 		s.Diag.Error("failed to reload sideload sources", err)
 	}
+	if err := s.HTTPDService.ReloadCertificate(); err != nil {
+		s.Diag.Error("failed to reload HTTPS certificate", err)
+	}
+}
+
+// inputServices are the sections of the input services with listeners, the names of their services
+// and the functions appending their services from the configuration of the server.
+var inputServices = []struct {
+	section string
+	name    string
+	append  func(s *Server) error
+}{
+	{section: "collectd", name: "collectd", append: (*Server).appendCollectdServices},
+	{section: "opentsdb", name: "opentsdb", append: (*Server).appendOpenTSDBServices},
+	{section: "graphite", name: "graphite", append: (*Server).appendGraphiteServices},
+	{section: "graphite-pickle", name: "graphite-pickle", append: (*Server).appendGraphitePickleServices},
+	{section: "udp", name: "udp", append: func(s *Server) error { s.appendUDPServices(); return nil }},
+	{section: "nats", name: "nats", append: func(s *Server) error { s.appendNATSServices(); return nil }},
+	{section: "snmp", name: "snmp", append: func(s *Server) error { s.appendSNMPServices(); return nil }},
+	{section: "statsd", name: "statsd", append: func(s *Server) error { s.appendStatsdServices(); return nil }},
+	{section: "tail", name: "tail", append: func(s *Server) error { s.appendTailServices(); return nil }},
+}
+
+// ReloadConfig updates the running services to the configuration c, e.g. once the configuration file is read again,
+// without interrupting the tasks. The config overrides still apply to the new configuration.
+//
+// The sections that can be overridden update their services in place,
+// the HTTP listener is moved to its new bind address, the log levels are reset to the new levels,
+// and the services of the input sections that changed are restarted.
+// Changes to the other sections require a restart, they are ignored.
+func (s *Server) ReloadConfig(c *Config) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	if err := s.ConfigOverrideService.Reload(c, !s.config.SkipConfigOverrides && s.config.ConfigOverride.Enabled); err != nil {
+		return err
+	}
+	reloaded := make(map[string]bool)
+	newConfig := reflect.ValueOf(c).Elem()
+	config := reflect.ValueOf(s.config).Elem()
+	for i := 0; i < config.NumField(); i++ {
+		if _, ok := config.Type().Field(i).Tag.Lookup("override"); ok {
+			config.Field(i).Set(newConfig.Field(i))
+			reloaded[tomlName(config.Type().Field(i))] = true
+		}
+	}
+
+	if c.HTTP.BindAddress != s.config.HTTP.BindAddress {
+		if err := s.HTTPDService.Rebind(c.HTTP.BindAddress); err != nil {
+			return errors.Wrap(err, "failed to move the HTTP listener")
+		}
+		s.Diag.Info("moved HTTP listener", keyvalue.KV("bind-address", c.HTTP.BindAddress))
+		s.config.HTTP.BindAddress = c.HTTP.BindAddress
+	}
+
+	if c.Logging.Level != s.config.Logging.Level ||
+		!reflect.DeepEqual(c.Logging.Components, s.config.Logging.Components) ||
+		!reflect.DeepEqual(c.Logging.Tasks, s.config.Logging.Tasks) {
+		if err := s.DiagService.SetLogLevels(c.Logging); err != nil {
+			return errors.Wrap(err, "failed to set log levels")
+		}
+		s.config.Logging.Level = c.Logging.Level
+		s.config.Logging.Components = c.Logging.Components
+		s.config.Logging.Tasks = c.Logging.Tasks
+	}
+
+	for _, in := range inputServices {
+		reloaded[in.section] = true
+		old, new := configSection(config, in.section), configSection(newConfig, in.section)
+		if reflect.DeepEqual(old.Interface(), new.Interface()) {
+			continue
+		}
+		if err := s.closeServices(in.name); err != nil {
+			return errors.Wrapf(err, "failed to stop %s services", in.section)
+		}
+		old.Set(new)
+		n := len(s.Services)
+		if err := in.append(s); err != nil {
+			return errors.Wrapf(err, "failed to create %s services", in.section)
+		}
+		for _, srv := range s.Services[n:] {
+			if err := srv.Open(); err != nil {
+				return fmt.Errorf("open service %T: %s", srv, err)
+			}
+		}
+		s.Diag.Info("restarted input services", keyvalue.KV("section", in.section))
+	}
+
+	// Report the sections whose changes were ignored.
+	for i := 0; i < config.NumField(); i++ {
+		name := tomlName(config.Type().Field(i))
+		if name == "-" || reloaded[name] {
+			continue
+		}
+		if !reflect.DeepEqual(config.Field(i).Interface(), newConfig.Field(i).Interface()) {
+			s.Diag.Info("configuration changes require a restart", keyvalue.KV("section", name))
+		}
+	}
+	return nil
+}
+
+// closeServices closes and removes the services with the name followed by their index, e.g. udp0.
+func (s *Server) closeServices(name string) error {
+	services := make([]Service, 0, len(s.Services))
+	byName := make(map[string]int, len(s.ServicesByName))
+	names := make(map[int]string, len(s.ServicesByName))
+	for n, i := range s.ServicesByName {
+		names[i] = n
+	}
+	for i, srv := range s.Services {
+		n := names[i]
+		if _, err := strconv.Atoi(strings.TrimPrefix(n, name)); err == nil && strings.HasPrefix(n, name) {
+			if err := srv.Close(); err != nil {
+				return err
+			}
+			continue
+		}
+		byName[n] = len(services)
+		services = append(services, srv)
+	}
+	s.Services = services
+	s.ServicesByName = byName
+	return nil
+}
+
+// configSection returns the field of the config of the section.
+func configSection(config reflect.Value, section string) reflect.Value {
+	for i := 0; i < config.NumField(); i++ {
+		if tomlName(config.Type().Field(i)) == section {
+			return config.Field(i)
+		}
+	}
+	panic("unknown configuration section " + section)
+}
+
+func tomlName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("toml"), ",")[0]
+	if name == "" {
+		return f.Name
+	}
+	return name
 }
 
 func (s *Server) SetClusterID(clusterID uuid.UUID) error {
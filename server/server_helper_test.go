@@ -229,6 +229,7 @@ func NewConfig() *server.Config {
 	c.Storage.BoltDBPath = filepath.Join(MustTempDir(), "bolt.db")
 	c.DataDir = MustTempDir()
 	c.HTTP.BindAddress = "127.0.0.1:0"
+	c.GRPC.BindAddress = "127.0.0.1:0"
 	//c.HTTP.BindAddress = "127.0.0.1:9092"
 	//c.HTTP.GZIP = false
 	c.InfluxDB[0].Enabled = false
@@ -1,13 +1,17 @@
 package server_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"math/rand"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/mail"
 	"net/url"
 	"os"
@@ -15,10 +19,12 @@ import (
 	"path"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -34,9 +40,12 @@ import (
 	"github.com/influxdata/kapacitor/command"
 	"github.com/influxdata/kapacitor/command/commandtest"
 	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/profile"
 	"github.com/influxdata/kapacitor/server"
 	"github.com/influxdata/kapacitor/services/alert/alerttest"
 	"github.com/influxdata/kapacitor/services/alerta/alertatest"
+	"github.com/influxdata/kapacitor/services/cluster"
+	"github.com/influxdata/kapacitor/services/grpcapi/api"
 	"github.com/influxdata/kapacitor/services/hipchat/hipchattest"
 	"github.com/influxdata/kapacitor/services/httppost"
 	"github.com/influxdata/kapacitor/services/httppost/httpposttest"
@@ -53,6 +62,7 @@ import (
 	"github.com/influxdata/kapacitor/services/pagerduty2"
 	"github.com/influxdata/kapacitor/services/pagerduty2/pagerduty2test"
 	"github.com/influxdata/kapacitor/services/pushover/pushovertest"
+	"github.com/influxdata/kapacitor/services/replay"
 	"github.com/influxdata/kapacitor/services/sensu/sensutest"
 	"github.com/influxdata/kapacitor/services/slack"
 	"github.com/influxdata/kapacitor/services/slack/slacktest"
@@ -63,10 +73,14 @@ import (
 	"github.com/influxdata/kapacitor/services/telegram"
 	"github.com/influxdata/kapacitor/services/telegram/telegramtest"
 	"github.com/influxdata/kapacitor/services/udf"
+	"github.com/influxdata/kapacitor/services/udp"
 	"github.com/influxdata/kapacitor/services/victorops"
 	"github.com/influxdata/kapacitor/services/victorops/victoropstest"
 	"github.com/k-sone/snmpgo"
 	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 )
 
 var udfDir string
@@ -297,381 +311,556 @@ func TestServer_Authenticate_Bearer(t *testing.T) {
 	}
 }
 
-func TestServer_CreateTask(t *testing.T) {
-	s, cli := OpenDefaultServer()
+func TestServer_RBAC(t *testing.T) {
+	conf := NewConfig()
+	conf.HTTP.AuthEnabled = true
+	conf.RBAC.Enabled = true
+	conf.RBAC.AdminUsername = "admin"
+	conf.RBAC.AdminPassword = "admin password"
+	s := OpenServer(conf)
 	defer s.Close()
-
-	id := "testTaskID"
-	ttype := client.StreamTask
-	dbrps := []client.DBRP{
-		{
-			Database:        "mydb",
-			RetentionPolicy: "myrp",
-		},
-		{
-			Database:        "otherdb",
-			RetentionPolicy: "default",
-		},
+	newClient := func(username, password string) *client.Client {
+		cli, err := client.New(client.Config{
+			URL: s.URL(),
+			Credentials: &client.Credentials{
+				Method:   client.UserAuthentication,
+				Username: username,
+				Password: password,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cli
 	}
-	tick := `stream
-    |from()
-        .measurement('test')
-`
-	task, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         id,
-		Type:       ttype,
-		DBRPs:      dbrps,
-		TICKscript: tick,
-		Status:     client.Disabled,
-	})
-	if err != nil {
+	admin := newClient("admin", "admin password")
+	if _, err := admin.CreateRole(client.CreateRoleOptions{
+		Name:   "reader",
+		Scopes: []string{"tasks:read", "config:read"},
+	}); err != nil {
 		t.Fatal(err)
 	}
-
-	ti, err := cli.Task(task.Link, nil)
-	if err != nil {
+	if _, err := admin.CreateUser(client.CreateUserOptions{
+		Name:     "bob",
+		Password: "bob's password",
+		Roles:    []string{"reader"},
+	}); err != nil {
 		t.Fatal(err)
 	}
 
-	if ti.Error != "" {
-		t.Fatal(ti.Error)
+	if _, _, err := newClient("bob", "wrong").Ping(); err == nil {
+		t.Error("expected authentication error")
 	}
-	if ti.ID != id {
-		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+
+	bob := newClient("bob", "bob's password")
+	if _, err := bob.ListTasks(nil); err != nil {
+		t.Error(err)
 	}
-	if ti.Type != client.StreamTask {
-		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	if _, err := bob.ConfigSections(); err != nil {
+		t.Error(err)
 	}
-	if ti.Status != client.Disabled {
-		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+	_, err := bob.CreateTask(client.CreateTaskOptions{
+		ID:         "task",
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "db", RetentionPolicy: "rp"}},
+		TICKscript: "stream|from()",
+	})
+	if err == nil {
+		t.Error("expected error creating a task without the tasks:write scope")
+	} else if exp, got := `user bob does not have the "tasks:write" scope for API endpoint "/kapacitor/v1/tasks"`, err.Error(); got != exp {
+		t.Errorf("unexpected error message: got %q exp %q", got, exp)
 	}
-	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
-		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	if _, err := bob.ListRecordings(nil); err == nil {
+		t.Error("expected error listing recordings without the replays:read scope")
 	}
-	if ti.TICKscript != tick {
-		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+	if _, err := bob.ListUsers(nil); err == nil {
+		t.Error("expected error listing users as a user who is not an admin")
 	}
-	dot := "digraph testTaskID {\nstream0 -> from1;\n}"
-	if ti.Dot != dot {
-		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+
+	if _, err := admin.UpdateRole(admin.RoleLink("reader"), client.UpdateRoleOptions{
+		Scopes: []string{"tasks:read", "tasks:write"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.CreateTask(client.CreateTaskOptions{
+		ID:         "task",
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "db", RetentionPolicy: "rp"}},
+		TICKscript: "stream|from()",
+	}); err != nil {
+		t.Error(err)
+	}
+	if _, err := bob.ConfigSections(); err == nil {
+		t.Error("expected error reading the configuration without the config:read scope")
 	}
 }
 
-func TestServer_CreateTask_Quiet(t *testing.T) {
-	s, cli := OpenDefaultServer()
+func TestServer_APITokens(t *testing.T) {
+	conf := NewConfig()
+	conf.HTTP.AuthEnabled = true
+	conf.RBAC.Enabled = true
+	conf.RBAC.AdminUsername = "admin"
+	conf.RBAC.AdminPassword = "admin password"
+	s := OpenServer(conf)
 	defer s.Close()
-
-	id := "testTaskID"
-	ttype := client.StreamTask
-	dbrps := []client.DBRP{
-		{
-			Database:        "mydb",
-			RetentionPolicy: "myrp",
-		},
-		{
-			Database:        "otherdb",
-			RetentionPolicy: "default",
+	admin, err := client.New(client.Config{
+		URL: s.URL(),
+		Credentials: &client.Credentials{
+			Method:   client.UserAuthentication,
+			Username: "admin",
+			Password: "admin password",
 		},
-	}
-	tick := `stream
-    |from()
-        .measurement('test')
-        .quiet()
-`
-	task, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         id,
-		Type:       ttype,
-		DBRPs:      dbrps,
-		TICKscript: tick,
-		Status:     client.Disabled,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	ti, err := cli.Task(task.Link, nil)
+	token, err := admin.CreateAPIToken(client.CreateAPITokenOptions{
+		Description: "ci",
+		Scopes:      []string{"tasks:read"},
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	if ti.Error != "" {
-		t.Fatal(ti.Error)
+	if token.Owner != "admin" || token.Token == "" {
+		t.Fatalf("unexpected token %v", token)
 	}
-	if ti.ID != id {
-		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	ci, err := client.New(client.Config{
+		URL: s.URL(),
+		Credentials: &client.Credentials{
+			Method: client.BearerAuthentication,
+			Token:  token.Token,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
-	if ti.Type != client.StreamTask {
-		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	if _, err := ci.ListTasks(nil); err != nil {
+		t.Error(err)
 	}
-	if ti.Status != client.Disabled {
-		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+	if _, err := ci.CreateTask(client.CreateTaskOptions{
+		ID:         "task",
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "db", RetentionPolicy: "rp"}},
+		TICKscript: "stream|from()",
+	}); err == nil {
+		t.Error("expected error creating a task without the tasks:write scope")
 	}
-	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
-		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	if _, err := ci.ListAPITokens(); err == nil {
+		t.Error("expected error listing tokens with a token")
 	}
-	if ti.TICKscript != tick {
-		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+
+	if err := admin.RevokeAPIToken(token.Link); err != nil {
+		t.Fatal(err)
 	}
-	dot := "digraph testTaskID {\nstream0 -> from1;\n}"
-	if ti.Dot != dot {
-		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	if _, err := ci.ListTasks(nil); err == nil {
+		t.Error("expected error using a revoked token")
 	}
-}
-
-func TestServer_CreateTaskImplicitStream(t *testing.T) {
-	s, cli := OpenDefaultServer()
-	defer s.Close()
 
-	id := "testTaskID"
-	dbrps := []client.DBRP{
-		{
-			Database:        "mydb",
-			RetentionPolicy: "myrp",
-		},
-		{
-			Database:        "otherdb",
-			RetentionPolicy: "default",
-		},
+	// The tokens of an admin are disabled once it is no longer an admin or deleted.
+	if _, err := admin.CreateUser(client.CreateUserOptions{Name: "ops", Password: "ops password", Admin: true}); err != nil {
+		t.Fatal(err)
 	}
-	tick := `dbrp "mydb"."myrp"
-
-dbrp "otherdb"."default"
-
-stream
-    |from()
-        .measurement('test')
-`
-	task, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         id,
-		TICKscript: tick,
-		Status:     client.Disabled,
+	ops, err := client.New(client.Config{
+		URL: s.URL(),
+		Credentials: &client.Credentials{
+			Method:   client.UserAuthentication,
+			Username: "ops",
+			Password: "ops password",
+		},
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	ti, err := cli.Task(task.Link, nil)
+	token, err = ops.CreateAPIToken(client.CreateAPITokenOptions{Scopes: []string{"tasks:read"}})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	if ti.Error != "" {
-		t.Fatal(ti.Error)
-	}
-	if ti.ID != id {
-		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	ci, err = client.New(client.Config{
+		URL: s.URL(),
+		Credentials: &client.Credentials{
+			Method: client.BearerAuthentication,
+			Token:  token.Token,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
-	if ti.Type != client.StreamTask {
-		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	if _, err := ci.ListTasks(nil); err != nil {
+		t.Error(err)
 	}
-	if ti.Status != client.Disabled {
-		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+	notAdmin := false
+	if _, err := admin.UpdateUser(admin.UserLink("ops"), client.UpdateUserOptions{Admin: &notAdmin}); err != nil {
+		t.Fatal(err)
 	}
-	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
-		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	if _, err := ci.ListTasks(nil); err == nil {
+		t.Error("expected error using the token of a user who is no longer an admin")
 	}
-	if ti.TICKscript != tick {
-		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+	if err := admin.DeleteUser(admin.UserLink("ops")); err != nil {
+		t.Fatal(err)
 	}
-	dot := "digraph testTaskID {\nstream0 -> from1;\n}"
-	if ti.Dot != dot {
-		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	if _, err := ci.ListTasks(nil); err == nil {
+		t.Error("expected error using the token of a deleted user")
 	}
 }
 
-func TestServer_CreateTaskBatch(t *testing.T) {
-	s, cli := OpenDefaultServer()
+func TestServer_Audit(t *testing.T) {
+	conf := NewConfig()
+	conf.Audit.Enabled = true
+	s := OpenServer(conf)
 	defer s.Close()
+	cli := Client(s)
 
-	id := "testTaskID"
-	dbrps := []client.DBRP{
-		{
-			Database:        "mydb",
-			RetentionPolicy: "myrp",
-		},
-	}
-	tick := `dbrp "mydb"."myrp"
-
-batch
-    |query('SELECT * from mydb.myrp.mymeas')
-    |log()
-`
 	task, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         id,
-		TICKscript: tick,
+		ID:         "task",
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "db", RetentionPolicy: "rp"}},
+		TICKscript: "stream|from()",
 		Status:     client.Disabled,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	ti, err := cli.Task(task.Link, nil)
-	if err != nil {
+	if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{TICKscript: "stream|from().measurement('cpu')"}); err != nil {
 		t.Fatal(err)
 	}
-
-	if ti.Error != "" {
-		t.Fatal(ti.Error)
+	if err := cli.ConfigUpdate(cli.ConfigElementLink("smtp", ""), client.ConfigUpdateAction{
+		Set: map[string]interface{}{"host": "smtp.example.com"},
+	}); err != nil {
+		t.Fatal(err)
 	}
-	if ti.ID != id {
-		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+
+	events, err := cli.ListAuditEvents(nil)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if ti.Type != client.BatchTask {
-		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.BatchTask)
+	if len(events) != 3 {
+		t.Fatalf("unexpected number of events: got %d exp 3", len(events))
 	}
-	if ti.Status != client.Disabled {
-		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+	changed := func(e client.AuditEvent, field string) (client.AuditChange, bool) {
+		for _, c := range e.Changes {
+			if c.Field == field {
+				return c, true
+			}
+		}
+		return client.AuditChange{}, false
 	}
-	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
-		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	if e := events[0]; e.Method != "POST" || e.Path != "/kapacitor/v1/config/smtp/" {
+		t.Errorf("unexpected config event %v", e)
+	} else if c, ok := changed(e, "options.host"); !ok || c.Old != "localhost" || c.New != "smtp.example.com" {
+		t.Errorf("unexpected changes of config event %v", e.Changes)
 	}
-	if ti.TICKscript != tick {
-		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+	if e := events[1]; e.Method != "PATCH" || e.Path != "/kapacitor/v1/tasks/task" {
+		t.Errorf("unexpected update event %v", e)
+	} else if c, ok := changed(e, "script"); !ok || !strings.Contains(fmt.Sprint(c.New), ".measurement('cpu')") {
+		t.Errorf("unexpected changes of update event %v", e.Changes)
 	}
-	dot := "digraph testTaskID {\nquery1 -> log2;\n}"
-	if ti.Dot != dot {
-		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	if e := events[2]; e.Method != "POST" || e.Path != "/kapacitor/v1/tasks" {
+		t.Errorf("unexpected create event %v", e)
+	} else if _, ok := changed(e, "id"); !ok {
+		t.Errorf("unexpected changes of create event %v", e.Changes)
 	}
 }
 
-func TestServer_CreateTaskImplicitAndExplicit(t *testing.T) {
-	s, cli := OpenDefaultServer()
+func TestServer_HAStandby(t *testing.T) {
+	conf := NewConfig()
+	conf.HA.Enabled = true
+	conf.HA.AdvertiseURL = "http://localhost:9092"
+	// No Consul agent listens on the address, so the server cannot become the leader.
+	conf.HA.ConsulAddress = "127.0.0.1:1"
+	s := OpenServer(conf)
 	defer s.Close()
+	cli := Client(s)
 
-	id := "testTaskID"
-	dbrps := []client.DBRP{
-		{
-			Database:        "mydb",
-			RetentionPolicy: "myrp",
-		},
+	status, err := cli.HAStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Role != "standby" || status.Leader != nil {
+		t.Errorf("unexpected status %+v", status)
 	}
-	tick := `dbrp "mydb"."myrp"
 
-dbrp "otherdb"."default"
-
-stream
-    |from()
-        .measurement('test')
-`
-	_, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         id,
-		DBRPs:      dbrps,
-		TICKscript: tick,
-		Status:     client.Disabled,
+	_, err = cli.CreateTask(client.CreateTaskOptions{
+		ID:         "task",
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "db", RetentionPolicy: "rp"}},
+		TICKscript: "stream|from()",
+		Status:     client.Enabled,
 	})
-
-	// It is expected that error should be non nil
-	if err == nil {
-		t.Fatal("expected task to fail to be created")
+	if err == nil || !strings.Contains(err.Error(), "standby") {
+		t.Errorf("expected standby server to reject the task, got %v", err)
 	}
 }
 
-func TestServer_CreateTaskExplicitUpdateImplicit(t *testing.T) {
-	s, cli := OpenDefaultServer()
+func TestServer_Cluster(t *testing.T) {
+	conf := NewConfig()
+	conf.Cluster.Enabled = true
+	conf.Cluster.Node = "a"
+	conf.Cluster.Shards = 4
+	conf.Cluster.MaxQueuedPoints = 20
+	// No server listens on the URL of the other node.
+	conf.Cluster.Nodes = []cluster.NodeConfig{
+		{Name: "a", URL: "http://localhost:9092"},
+		{Name: "b", URL: "http://127.0.0.1:1"},
+	}
+	s := OpenServer(conf)
 	defer s.Close()
+	cli := Client(s)
 
-	id := "testTaskID"
-	createDBRPs := []client.DBRP{
-		{
-			Database:        "mydb",
-			RetentionPolicy: "myrp",
-		},
-		{
-			Database:        "otherdb",
-			RetentionPolicy: "default",
-		},
+	status, err := cli.ClusterStatus()
+	if err != nil {
+		t.Fatal(err)
 	}
-	createTick := `stream
-    |from()
-        .measurement('test')
-`
-	updateDBRPs := []client.DBRP{
-		{
-			Database:        "mydb",
-			RetentionPolicy: "myrp",
-		},
+	exp := []client.ClusterNode{
+		{Name: "a", URL: "http://localhost:9092", Shards: []int{0, 2}},
+		{Name: "b", URL: "http://127.0.0.1:1", Shards: []int{1, 3}},
+	}
+	if status.Node != "a" || status.Shards != 4 || !reflect.DeepEqual(status.Nodes, exp) {
+		t.Errorf("unexpected status %+v", status)
 	}
-	updateTick := `dbrp "mydb"."myrp"
 
-stream
-    |from()
-        .measurement('test')
-`
-	task, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         id,
-		DBRPs:      createDBRPs,
-		TICKscript: createTick,
-		Status:     client.Disabled,
-	})
-	if err != nil {
+	var points []string
+	for i := 0; i < 20; i++ {
+		points = append(points, fmt.Sprintf("cpu%d value=1", i))
+	}
+	// The points of the other node are queued until the queue is full.
+	if _, err := s.Write("mydb", "myrp", strings.Join(points, "\n"), nil); err != nil {
 		t.Fatal(err)
 	}
+	for i := 0; i < conf.Cluster.MaxQueuedPoints; i++ {
+		if _, err = s.Write("mydb", "myrp", strings.Join(points, "\n"), nil); err != nil {
+			break
+		}
+	}
+	if err == nil || !strings.Contains(err.Error(), `to node "b" is full`) {
+		t.Errorf("expected error queueing points to node b, got %v", err)
+	}
+}
 
-	ti, err := cli.Task(task.Link, nil)
+func TestServer_LogLevels(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	for _, opt := range []client.LogLevelOptions{
+		{Level: "info"},
+		{Level: "ERROR", Component: "http"},
+		{Level: "ERROR", Component: "alert"},
+		{Level: "", Component: "alert"},
+		{Level: "DEBUG", Task: "cpu"},
+	} {
+		if err := cli.SetLogLevel(opt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := cli.LogLevels()
 	if err != nil {
 		t.Fatal(err)
 	}
+	exp := client.LogLevels{
+		Level:      "INFO",
+		Components: map[string]string{"http": "ERROR"},
+		Tasks:      map[string]string{"cpu": "DEBUG"},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected log levels got %v exp %v", got, exp)
+	}
 
-	if ti.Error != "" {
-		t.Fatal(ti.Error)
+	for _, opt := range []client.LogLevelOptions{
+		{Level: "WARN"},
+		{Level: "", Task: ""},
+		{Level: "trace", Task: "cpu"},
+		{Level: "DEBUG", Component: "http", Task: "cpu"},
+	} {
+		if err := cli.SetLogLevel(opt); err == nil {
+			t.Errorf("expected error setting log level %+v", opt)
+		}
 	}
-	if ti.ID != id {
-		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+}
+
+func TestServer_Health(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	if err := cli.Ready(); err != nil {
+		t.Fatalf("expected server to be ready: %v", err)
 	}
-	if ti.Type != client.StreamTask {
-		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	h, err := cli.Health()
+	if err != nil {
+		t.Fatal(err)
 	}
-	if ti.Status != client.Disabled {
-		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+	if h.Status != client.HealthPass || !h.Ready {
+		t.Errorf("unexpected health %+v", h)
 	}
-	if !reflect.DeepEqual(ti.DBRPs, createDBRPs) {
-		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, createDBRPs)
+	if h.Link.Href != "/kapacitor/v1/health" {
+		t.Errorf("unexpected link %v", h.Link)
 	}
-	if ti.TICKscript != createTick {
-		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, createTick)
+	for _, name := range []string{"storage", "influxdb", "scrapers", "alert_handlers"} {
+		hc, ok := h.Checks[name]
+		if !ok {
+			t.Errorf("missing check %q", name)
+			continue
+		}
+		if hc.Status != client.HealthPass {
+			t.Errorf("unexpected status of check %q: %+v", name, hc)
+		}
 	}
-	dot := "digraph testTaskID {\nstream0 -> from1;\n}"
-	if ti.Dot != dot {
-		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	if !h.Checks["storage"].Critical {
+		t.Error("expected storage check to be critical")
 	}
 
-	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-		TICKscript: updateTick,
-	})
-	if err != nil {
+	// An alert handler failing to deliver an alert makes its check warn.
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+	tick := fmt.Sprintf(`stream
+    |from()
+        .measurement('test')
+    |alert()
+        .crit(lambda: TRUE)
+        .post('%s')
+`, closed.URL)
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "testHealth",
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TICKscript: tick,
+		Status:     client.Enabled,
+	}); err != nil {
 		t.Fatal(err)
 	}
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", "test value=1 0", v)
 
-	ti, err = cli.Task(task.Link, nil)
+	timeout := time.After(5 * time.Second)
+	for {
+		h, err = cli.Health()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Checks["alert_handlers"].Status == client.HealthWarn {
+			break
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("timed out waiting for alert handler error, got %+v", h.Checks["alert_handlers"])
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if hc := h.Checks["alert_handlers"].Components["httppost"]; hc.Status != client.HealthWarn || !strings.Contains(hc.Message, "failed to POST alert data") {
+		t.Errorf("unexpected check of httppost %+v", hc)
+	}
+	// Warnings do not make the server unhealthy or not ready.
+	if h.Status != client.HealthWarn || !h.Ready {
+		t.Errorf("unexpected health %+v", h)
+	}
+	if err := cli.Ready(); err != nil {
+		t.Errorf("expected server to be ready: %v", err)
+	}
+}
+
+func TestServer_MetaMonitoring(t *testing.T) {
+	c := NewConfig()
+	c.MetaMonitoring.Enabled = true
+	c.Stats.StatsInterval = toml.Duration(10 * time.Millisecond)
+	s := OpenServer(c)
+	cli := Client(s)
+	defer s.Close()
+
+	selector := &client.ListTasksOptions{Selector: "managed-by=meta-monitoring"}
+	tasks, err := cli.ListTasks(selector)
 	if err != nil {
 		t.Fatal(err)
 	}
+	var ids []string
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+		if task.Status != client.Enabled || !task.Executing {
+			t.Errorf("expected task %s to be enabled and executing", task.ID)
+		}
+	}
+	if exp := []string{"_meta_dropped_points", "_meta_handler_errors", "_meta_queue_depth", "_meta_task_errors"}; !reflect.DeepEqual(ids, exp) {
+		t.Fatalf("unexpected meta-monitoring tasks got %v exp %v", ids, exp)
+	}
 
-	if ti.Error != "" {
-		t.Fatal(ti.Error)
+	// An alert handler failing to deliver an alert raises an alert on the topic.
+	alerts := make(chan alert.Data, 100)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ad := alert.Data{}
+		json.NewDecoder(r.Body).Decode(&ad)
+		alerts <- ad
+	}))
+	defer ts.Close()
+	if _, err := cli.CreateTopicHandler(cli.TopicHandlersLink("kapacitor"), client.TopicHandlerOptions{
+		ID:      "recorder",
+		Kind:    "post",
+		Options: map[string]interface{}{"url": ts.URL},
+	}); err != nil {
+		t.Fatal(err)
 	}
-	if ti.ID != id {
-		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+	tick := fmt.Sprintf(`stream
+    |from()
+        .measurement('test')
+    |alert()
+        .crit(lambda: TRUE)
+        .post('%s')
+`, closed.URL)
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "testMetaMonitoring",
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TICKscript: tick,
+		Status:     client.Enabled,
+	}); err != nil {
+		t.Fatal(err)
 	}
-	if ti.Type != client.StreamTask {
-		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", "test value=1 0", v)
+
+	timeout := time.After(5 * time.Second)
+	for {
+		var ad alert.Data
+		select {
+		case ad = <-alerts:
+		case <-timeout:
+			t.Fatal("timed out waiting for the handler errors alert")
+		}
+		if ad.ID != "handler_errors:httppost" {
+			continue
+		}
+		if ad.Level != alert.Warning || ad.Message != "1 errors of the httppost alert handler" {
+			t.Errorf("unexpected alert %+v", ad)
+		}
+		break
 	}
-	if ti.Status != client.Disabled {
-		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+
+	// The tasks are updated when the server restarts, and removed once meta-monitoring is disabled.
+	s.Restart()
+	tasks, err = cli.ListTasks(selector)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if !reflect.DeepEqual(ti.DBRPs, updateDBRPs) {
-		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, updateDBRPs)
+	for _, task := range tasks {
+		if task.Status != client.Enabled || !task.Executing {
+			t.Errorf("expected task %s to be enabled and executing after restart", task.ID)
+		}
 	}
-	if ti.TICKscript != updateTick {
-		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, updateTick)
+	if len(tasks) != 4 {
+		t.Errorf("unexpected meta-monitoring tasks after restart %d", len(tasks))
 	}
-	dot = "digraph testTaskID {\nstream0 -> from1;\n}"
-	if ti.Dot != dot {
-		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	s.Config.MetaMonitoring.Enabled = false
+	s.Restart()
+	tasks, err = cli.ListTasks(selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 0 {
+		t.Errorf("expected meta-monitoring tasks to be removed, got %d", len(tasks))
 	}
 }
 
-func TestServer_EnableTask(t *testing.T) {
+func TestServer_CreateTask(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
@@ -702,13 +891,6 @@ func TestServer_EnableTask(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-		Status: client.Enabled,
-	})
-	if err != nil {
-		t.Fatal(err)
-	}
-
 	ti, err := cli.Task(task.Link, nil)
 	if err != nil {
 		t.Fatal(err)
@@ -723,11 +905,8 @@ func TestServer_EnableTask(t *testing.T) {
 	if ti.Type != client.StreamTask {
 		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
 	}
-	if ti.Status != client.Enabled {
-		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Enabled)
-	}
-	if ti.Executing != true {
-		t.Fatalf("unexpected executing got %v exp %v", ti.Executing, true)
+	if ti.Status != client.Disabled {
+		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
 	}
 	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
 		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
@@ -735,20 +914,13 @@ func TestServer_EnableTask(t *testing.T) {
 	if ti.TICKscript != tick {
 		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
 	}
-	dot := `digraph testTaskID {
-graph [throughput="0.00 points/s"];
-
-stream0 [avg_exec_time_ns="0s" errors="0" working_cardinality="0" ];
-stream0 -> from1 [processed="0"];
-
-from1 [avg_exec_time_ns="0s" errors="0" working_cardinality="0" ];
-}`
+	dot := "digraph testTaskID {\nstream0 -> from1;\n}"
 	if ti.Dot != dot {
 		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
 	}
 }
 
-func TestServer_EnableTaskOnCreate(t *testing.T) {
+func TestServer_CreateTask_Quiet(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
@@ -767,13 +939,14 @@ func TestServer_EnableTaskOnCreate(t *testing.T) {
 	tick := `stream
     |from()
         .measurement('test')
+        .quiet()
 `
 	task, err := cli.CreateTask(client.CreateTaskOptions{
 		ID:         id,
 		Type:       ttype,
 		DBRPs:      dbrps,
 		TICKscript: tick,
-		Status:     client.Enabled,
+		Status:     client.Disabled,
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -793,11 +966,8 @@ func TestServer_EnableTaskOnCreate(t *testing.T) {
 	if ti.Type != client.StreamTask {
 		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
 	}
-	if ti.Status != client.Enabled {
-		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Enabled)
-	}
-	if ti.Executing != true {
-		t.Fatalf("unexpected executing got %v exp %v", ti.Executing, true)
+	if ti.Status != client.Disabled {
+		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
 	}
 	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
 		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
@@ -805,25 +975,17 @@ func TestServer_EnableTaskOnCreate(t *testing.T) {
 	if ti.TICKscript != tick {
 		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
 	}
-	dot := `digraph testTaskID {
-graph [throughput="0.00 points/s"];
-
-stream0 [avg_exec_time_ns="0s" errors="0" working_cardinality="0" ];
-stream0 -> from1 [processed="0"];
-
-from1 [avg_exec_time_ns="0s" errors="0" working_cardinality="0" ];
-}`
+	dot := "digraph testTaskID {\nstream0 -> from1;\n}"
 	if ti.Dot != dot {
 		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
 	}
 }
 
-func TestServer_DisableTask(t *testing.T) {
+func TestServer_CreateTaskImplicitStream(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
 	id := "testTaskID"
-	ttype := client.StreamTask
 	dbrps := []client.DBRP{
 		{
 			Database:        "mydb",
@@ -834,14 +996,16 @@ func TestServer_DisableTask(t *testing.T) {
 			RetentionPolicy: "default",
 		},
 	}
-	tick := `stream
+	tick := `dbrp "mydb"."myrp"
+
+dbrp "otherdb"."default"
+
+stream
     |from()
         .measurement('test')
 `
 	task, err := cli.CreateTask(client.CreateTaskOptions{
 		ID:         id,
-		Type:       ttype,
-		DBRPs:      dbrps,
 		TICKscript: tick,
 		Status:     client.Disabled,
 	})
@@ -849,20 +1013,6 @@ func TestServer_DisableTask(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-		Status: client.Enabled,
-	})
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-		Status: client.Disabled,
-	})
-	if err != nil {
-		t.Fatal(err)
-	}
-
 	ti, err := cli.Task(task.Link, nil)
 	if err != nil {
 		t.Fatal(err)
@@ -892,30 +1042,25 @@ func TestServer_DisableTask(t *testing.T) {
 	}
 }
 
-func TestServer_DeleteTask(t *testing.T) {
+func TestServer_CreateTaskBatch(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
 	id := "testTaskID"
-	ttype := client.StreamTask
 	dbrps := []client.DBRP{
 		{
 			Database:        "mydb",
 			RetentionPolicy: "myrp",
 		},
-		{
-			Database:        "otherdb",
-			RetentionPolicy: "default",
-		},
 	}
-	tick := `stream
-    |from()
-        .measurement('test')
+	tick := `dbrp "mydb"."myrp"
+
+batch
+    |query('SELECT * from mydb.myrp.mymeas')
+    |log()
 `
 	task, err := cli.CreateTask(client.CreateTaskOptions{
 		ID:         id,
-		Type:       ttype,
-		DBRPs:      dbrps,
 		TICKscript: tick,
 		Status:     client.Disabled,
 	})
@@ -923,151 +1068,178 @@ func TestServer_DeleteTask(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = cli.DeleteTask(task.Link)
+	ti, err := cli.Task(task.Link, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	ti, err := cli.Task(task.Link, nil)
-	if err == nil {
-		t.Fatal("unexpected task:", ti)
+	if ti.Error != "" {
+		t.Fatal(ti.Error)
+	}
+	if ti.ID != id {
+		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	}
+	if ti.Type != client.BatchTask {
+		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.BatchTask)
+	}
+	if ti.Status != client.Disabled {
+		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+	}
+	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
+		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	}
+	if ti.TICKscript != tick {
+		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+	}
+	dot := "digraph testTaskID {\nquery1 -> log2;\n}"
+	if ti.Dot != dot {
+		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
 	}
 }
 
-func TestServer_TaskNums(t *testing.T) {
+func TestServer_CreateTaskImplicitAndExplicit(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
 	id := "testTaskID"
-	ttype := client.StreamTask
 	dbrps := []client.DBRP{
 		{
 			Database:        "mydb",
 			RetentionPolicy: "myrp",
 		},
 	}
-	tick := `stream
+	tick := `dbrp "mydb"."myrp"
+
+dbrp "otherdb"."default"
+
+stream
     |from()
         .measurement('test')
 `
+	_, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Disabled,
+	})
 
-	// Create a bunch of tasks with every 3rd task enabled
-	count := 100
-	enabled := 0
-	tasks := make([]client.Task, count)
-	for i := 0; i < count; i++ {
-		status := client.Disabled
-		if i%3 == 0 {
-			enabled++
-			status = client.Enabled
-		}
-		task, err := cli.CreateTask(client.CreateTaskOptions{
-			ID:         fmt.Sprintf("%s-%d", id, i),
-			Type:       ttype,
-			DBRPs:      dbrps,
-			TICKscript: tick,
-			Status:     status,
-		})
-		if err != nil {
-			t.Fatal(err)
-		}
-		tasks[i] = task
-	}
-	if stats, err := s.Stats(); err != nil {
-		t.Fatal(err)
-	} else {
-		if got, exp := stats.NumTasks, count; got != exp {
-			t.Errorf("unexpected num_tasks got %d exp %d", got, exp)
-		}
-		if got, exp := stats.NumEnabledTasks, enabled; got != exp {
-			t.Errorf("unexpected num_enabled_tasks got %d exp %d", got, exp)
-		}
+	// It is expected that error should be non nil
+	if err == nil {
+		t.Fatal("expected task to fail to be created")
 	}
+}
 
-	// Enable a bunch of tasks
-	for i, task := range tasks {
-		if i%2 == 0 && task.Status != client.Enabled {
-			enabled++
-			tasks[i].Status = client.Enabled
-			if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-				Status: client.Enabled,
-			}); err != nil {
-				t.Fatal(err)
-			}
-		}
+func TestServer_CreateTaskExplicitUpdateImplicit(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testTaskID"
+	createDBRPs := []client.DBRP{
+		{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		},
+		{
+			Database:        "otherdb",
+			RetentionPolicy: "default",
+		},
+	}
+	createTick := `stream
+    |from()
+        .measurement('test')
+`
+	updateDBRPs := []client.DBRP{
+		{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		},
 	}
+	updateTick := `dbrp "mydb"."myrp"
 
-	if stats, err := s.Stats(); err != nil {
+stream
+    |from()
+        .measurement('test')
+`
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		DBRPs:      createDBRPs,
+		TICKscript: createTick,
+		Status:     client.Disabled,
+	})
+	if err != nil {
 		t.Fatal(err)
-	} else {
-		if got, exp := stats.NumTasks, count; got != exp {
-			t.Errorf("unexpected num_tasks got %d exp %d", got, exp)
-		}
-		if got, exp := stats.NumEnabledTasks, enabled; got != exp {
-			t.Errorf("unexpected num_enabled_tasks got %d exp %d", got, exp)
-		}
 	}
 
-	// Disable a bunch of tasks
-	for i, task := range tasks {
-		if i%5 == 0 && task.Status != client.Disabled {
-			enabled--
-			tasks[i].Status = client.Disabled
-			if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-				Status: client.Disabled,
-			}); err != nil {
-				t.Fatal(err)
-			}
-		}
+	ti, err := cli.Task(task.Link, nil)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	if stats, err := s.Stats(); err != nil {
-		t.Fatal(err)
-	} else {
-		if got, exp := stats.NumTasks, count; got != exp {
-			t.Errorf("unexpected num_tasks got %d exp %d", got, exp)
-		}
-		if got, exp := stats.NumEnabledTasks, enabled; got != exp {
-			t.Errorf("unexpected num_enabled_tasks got %d exp %d", got, exp)
-		}
+	if ti.Error != "" {
+		t.Fatal(ti.Error)
+	}
+	if ti.ID != id {
+		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	}
+	if ti.Type != client.StreamTask {
+		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	}
+	if ti.Status != client.Disabled {
+		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+	}
+	if !reflect.DeepEqual(ti.DBRPs, createDBRPs) {
+		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, createDBRPs)
+	}
+	if ti.TICKscript != createTick {
+		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, createTick)
+	}
+	dot := "digraph testTaskID {\nstream0 -> from1;\n}"
+	if ti.Dot != dot {
+		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
 	}
 
-	// Delete a bunch of tasks
-	for i, task := range tasks {
-		if i%6 == 0 {
-			count--
-			if task.Status == client.Enabled {
-				enabled--
-			}
-			if err := cli.DeleteTask(task.Link); err != nil {
-				t.Fatal(err)
-			}
-		}
+	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		TICKscript: updateTick,
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	if stats, err := s.Stats(); err != nil {
+	ti, err = cli.Task(task.Link, nil)
+	if err != nil {
 		t.Fatal(err)
-	} else {
-		if got, exp := stats.NumTasks, count; got != exp {
-			t.Errorf("unexpected num_tasks got %d exp %d", got, exp)
-		}
-		if got, exp := stats.NumEnabledTasks, enabled; got != exp {
-			t.Errorf("unexpected num_enabled_tasks got %d exp %d", got, exp)
-		}
 	}
 
+	if ti.Error != "" {
+		t.Fatal(ti.Error)
+	}
+	if ti.ID != id {
+		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	}
+	if ti.Type != client.StreamTask {
+		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	}
+	if ti.Status != client.Disabled {
+		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+	}
+	if !reflect.DeepEqual(ti.DBRPs, updateDBRPs) {
+		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, updateDBRPs)
+	}
+	if ti.TICKscript != updateTick {
+		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, updateTick)
+	}
+	dot = "digraph testTaskID {\nstream0 -> from1;\n}"
+	if ti.Dot != dot {
+		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	}
 }
-func TestServer_ListTasks(t *testing.T) {
+
+func TestServer_EnableTask(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
-	count := 10
 
+	id := "testTaskID"
 	ttype := client.StreamTask
-	tick := `stream
-    |from()
-        .measurement('test')
-`
-
 	dbrps := []client.DBRP{
 		{
 			Database:        "mydb",
@@ -1078,73 +1250,73 @@ func TestServer_ListTasks(t *testing.T) {
 			RetentionPolicy: "default",
 		},
 	}
-	for i := 0; i < count; i++ {
-		id := fmt.Sprintf("testTaskID%d", i)
-		status := client.Disabled
-		if i%2 == 0 {
-			status = client.Enabled
-		}
-		_, err := cli.CreateTask(client.CreateTaskOptions{
-			ID:         id,
-			Type:       ttype,
-			DBRPs:      dbrps,
-			TICKscript: tick,
-			Status:     status,
-		})
-		if err != nil {
-			t.Fatal(err)
-		}
+	tick := `stream
+    |from()
+        .measurement('test')
+`
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       ttype,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
-	tasks, err := cli.ListTasks(nil)
+
+	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Enabled,
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
-	if exp, got := count, len(tasks); exp != got {
-		t.Fatalf("unexpected number of tasks: exp:%d got:%d", exp, got)
+
+	ti, err := cli.Task(task.Link, nil)
+	if err != nil {
+		t.Fatal(err)
 	}
-	for i, task := range tasks {
-		if exp, got := fmt.Sprintf("testTaskID%d", i), task.ID; exp != got {
-			t.Errorf("unexpected task.ID i:%d exp:%s got:%s", i, exp, got)
-		}
-		if exp, got := client.StreamTask, task.Type; exp != got {
-			t.Errorf("unexpected task.Type i:%d exp:%v got:%v", i, exp, got)
-		}
-		if !reflect.DeepEqual(task.DBRPs, dbrps) {
-			t.Fatalf("unexpected dbrps i:%d exp:%s got:%s", i, dbrps, task.DBRPs)
-		}
-		exp := client.Disabled
-		if i%2 == 0 {
-			exp = client.Enabled
-		}
-		if got := task.Status; exp != got {
-			t.Errorf("unexpected task.Status i:%d exp:%v got:%v", i, exp, got)
-		}
-		if exp, got := i%2 == 0, task.Executing; exp != got {
-			t.Errorf("unexpected task.Executing i:%d exp:%v got:%v", i, exp, got)
-		}
-		if exp, got := true, len(task.Dot) != 0; exp != got {
-			t.Errorf("unexpected task.Dot i:%d exp:\n%v\ngot:\n%v\n", i, exp, got)
-		}
-		if exp, got := tick, task.TICKscript; exp != got {
-			t.Errorf("unexpected task.TICKscript i:%d exp:%v got:%v", i, exp, got)
-		}
-		if exp, got := "", task.Error; exp != got {
-			t.Errorf("unexpected task.Error i:%d exp:%v got:%v", i, exp, got)
-		}
+
+	if ti.Error != "" {
+		t.Fatal(ti.Error)
 	}
+	if ti.ID != id {
+		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	}
+	if ti.Type != client.StreamTask {
+		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	}
+	if ti.Status != client.Enabled {
+		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Enabled)
+	}
+	if ti.Executing != true {
+		t.Fatalf("unexpected executing got %v exp %v", ti.Executing, true)
+	}
+	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
+		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	}
+	if ti.TICKscript != tick {
+		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+	}
+	dot := `digraph testTaskID {
+graph [throughput="0.00 points/s"];
 
+stream0 [avg_exec_time_ns="0s" errors="0" working_cardinality="0" ];
+stream0 -> from1 [processed="0"];
+
+from1 [avg_exec_time_ns="0s" errors="0" working_cardinality="0" ];
+}`
+	if ti.Dot != dot {
+		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	}
 }
 
-func TestServer_ListTasks_Fields(t *testing.T) {
+func TestServer_EnableTaskOnCreate(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
-	count := 100
 
+	id := "testTaskID"
 	ttype := client.StreamTask
-	tick := `stream
-    |from()
-        .measurement('test')
-`
 	dbrps := []client.DBRP{
 		{
 			Database:        "mydb",
@@ -1155,82 +1327,22 @@ func TestServer_ListTasks_Fields(t *testing.T) {
 			RetentionPolicy: "default",
 		},
 	}
-	for i := 0; i < count; i++ {
-		id := fmt.Sprintf("testTaskID%d", i)
-		_, err := cli.CreateTask(client.CreateTaskOptions{
-			ID:         id,
-			Type:       ttype,
-			DBRPs:      dbrps,
-			TICKscript: tick,
-			Status:     client.Enabled,
-		})
-		if err != nil {
-			t.Fatal(err)
-		}
-	}
-	tasks, err := cli.ListTasks(&client.ListTasksOptions{
-		Pattern: "testTaskID1*",
-		Fields:  []string{"type", "status"},
-		Offset:  1,
-		Limit:   5,
-	})
-	if err != nil {
-		t.Fatal(err)
-	}
-	if exp, got := 5, len(tasks); exp != got {
-		t.Fatalf("unexpected number of tasks: exp:%d got:%d", exp, got)
-	}
-	for i, task := range tasks {
-		if exp, got := fmt.Sprintf("testTaskID1%d", i), task.ID; exp != got {
-			t.Errorf("unexpected task.ID i:%d exp:%s got:%s", i, exp, got)
-		}
-		if exp, got := client.StreamTask, task.Type; exp != got {
-			t.Errorf("unexpected task.Type i:%d exp:%v got:%v", i, exp, got)
-		}
-		if exp, got := client.Enabled, task.Status; exp != got {
-			t.Errorf("unexpected task.Status i:%d exp:%v got:%v", i, exp, got)
-		}
-		// We didn't request these fields so they should be default zero values
-		if exp, got := 0, len(task.DBRPs); exp != got {
-			t.Fatalf("unexpected dbrps i:%d exp:%d got:%d", i, exp, got)
-		}
-		if exp, got := false, task.Executing; exp != got {
-			t.Errorf("unexpected task.Executing i:%d exp:%v got:%v", i, exp, got)
-		}
-		if exp, got := "", task.Dot; exp != got {
-			t.Errorf("unexpected task.Dot i:%d exp:%v got:%v", i, exp, got)
-		}
-		if exp, got := "", task.TICKscript; exp != got {
-			t.Errorf("unexpected task.TICKscript i:%d exp:%v got:%v", i, exp, got)
-		}
-		if exp, got := "", task.Error; exp != got {
-			t.Errorf("unexpected task.Error i:%d exp:%v got:%v", i, exp, got)
-		}
-	}
-}
-
-func TestServer_CreateTemplate(t *testing.T) {
-	s, cli := OpenDefaultServer()
-	defer s.Close()
-
-	id := "testTemplateID"
-	ttype := client.StreamTask
-	tick := `var x = 5
-
-stream
+	tick := `stream
     |from()
         .measurement('test')
 `
-	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
+	task, err := cli.CreateTask(client.CreateTaskOptions{
 		ID:         id,
 		Type:       ttype,
+		DBRPs:      dbrps,
 		TICKscript: tick,
+		Status:     client.Enabled,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	ti, err := cli.Template(template.Link, nil)
+	ti, err := cli.Task(task.Link, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1244,40 +1356,77 @@ stream
 	if ti.Type != client.StreamTask {
 		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
 	}
+	if ti.Status != client.Enabled {
+		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Enabled)
+	}
+	if ti.Executing != true {
+		t.Fatalf("unexpected executing got %v exp %v", ti.Executing, true)
+	}
+	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
+		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	}
 	if ti.TICKscript != tick {
-		t.Fatalf("unexpected TICKscript got\n%s\nexp\n%s\n", ti.TICKscript, tick)
+		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
 	}
-	dot := "digraph testTemplateID {\nstream0 -> from1;\n}"
+	dot := `digraph testTaskID {
+graph [throughput="0.00 points/s"];
+
+stream0 [avg_exec_time_ns="0s" errors="0" working_cardinality="0" ];
+stream0 -> from1 [processed="0"];
+
+from1 [avg_exec_time_ns="0s" errors="0" working_cardinality="0" ];
+}`
 	if ti.Dot != dot {
 		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
 	}
-	vars := client.Vars{"x": {Value: int64(5), Type: client.VarInt}}
-	if !reflect.DeepEqual(vars, ti.Vars) {
-		t.Fatalf("unexpected vars\ngot\n%s\nexp\n%s\n", ti.Vars, vars)
-	}
 }
-func TestServer_UpdateTemplateID(t *testing.T) {
+
+func TestServer_DisableTask(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
-	id := "testTemplateID"
+	id := "testTaskID"
 	ttype := client.StreamTask
-	tick := `var x = 5
-
-stream
+	dbrps := []client.DBRP{
+		{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		},
+		{
+			Database:        "otherdb",
+			RetentionPolicy: "default",
+		},
+	}
+	tick := `stream
     |from()
         .measurement('test')
 `
-	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
+	task, err := cli.CreateTask(client.CreateTaskOptions{
 		ID:         id,
 		Type:       ttype,
+		DBRPs:      dbrps,
 		TICKscript: tick,
+		Status:     client.Disabled,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	ti, err := cli.Template(template.Link, nil)
+	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Enabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ti, err := cli.Task(task.Link, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1291,637 +1440,670 @@ stream
 	if ti.Type != client.StreamTask {
 		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
 	}
+	if ti.Status != client.Disabled {
+		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+	}
+	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
+		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	}
 	if ti.TICKscript != tick {
-		t.Fatalf("unexpected TICKscript got\n%s\nexp\n%s\n", ti.TICKscript, tick)
+		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
 	}
-	dot := "digraph testTemplateID {\nstream0 -> from1;\n}"
+	dot := "digraph testTaskID {\nstream0 -> from1;\n}"
 	if ti.Dot != dot {
 		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
 	}
-	vars := client.Vars{"x": {Value: int64(5), Type: client.VarInt}}
-	if !reflect.DeepEqual(vars, ti.Vars) {
-		t.Fatalf("unexpected vars\ngot\n%s\nexp\n%s\n", ti.Vars, vars)
-	}
-
-	newID := "newTemplateID"
-	template, err = cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
-		ID: newID,
-	})
-	if err != nil {
-		t.Fatal(err)
-	}
+}
 
-	if got, exp := template.Link.Href, "/kapacitor/v1/templates/newTemplateID"; got != exp {
-		t.Fatalf("unexpected template link got %s exp %s", got, exp)
-	}
+func TestServer_DeleteTask(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
 
-	ti, err = cli.Template(template.Link, nil)
+	id := "testTaskID"
+	ttype := client.StreamTask
+	dbrps := []client.DBRP{
+		{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		},
+		{
+			Database:        "otherdb",
+			RetentionPolicy: "default",
+		},
+	}
+	tick := `stream
+    |from()
+        .measurement('test')
+`
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       ttype,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Disabled,
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if ti.Error != "" {
-		t.Fatal(ti.Error)
-	}
-	if ti.ID != newID {
-		t.Fatalf("unexpected id got %s exp %s", ti.ID, newID)
-	}
-	if ti.Type != client.StreamTask {
-		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
-	}
-	if ti.TICKscript != tick {
-		t.Fatalf("unexpected TICKscript got\n%s\nexp\n%s\n", ti.TICKscript, tick)
-	}
-	dot = "digraph newTemplateID {\nstream0 -> from1;\n}"
-	if ti.Dot != dot {
-		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	err = cli.DeleteTask(task.Link)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if !reflect.DeepEqual(vars, ti.Vars) {
-		t.Fatalf("unexpected vars\ngot\n%s\nexp\n%s\n", ti.Vars, vars)
+
+	ti, err := cli.Task(task.Link, nil)
+	if err == nil {
+		t.Fatal("unexpected task:", ti)
 	}
 }
 
-func TestServer_CreateTemplateImplicitAndUpdateExplicitWithTasks(t *testing.T) {
+func TestServer_BulkTasks(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
-	id := "testTemplateID"
-	implicitTick := `dbrp "telegraf"."autogen"
-
-var x = 5
-
-stream
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:    "bulk_a",
+		Type:  client.StreamTask,
+		DBRPs: dbrps,
+		TICKscript: `stream
     |from()
         .measurement('test')
-`
-	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
-		ID:         id,
-		TICKscript: implicitTick,
-	})
-	if err != nil {
+`,
+		Status: client.Disabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// The query is not allowed by the dbrps, so the task cannot be started.
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:    "bulk_b",
+		Type:  client.BatchTask,
+		DBRPs: dbrps,
+		TICKscript: `batch
+    |query('SELECT value FROM "otherdb"."default".test')
+        .every(1m)
+`,
+		Status: client.Disabled,
+	}); err != nil {
 		t.Fatal(err)
 	}
 
-	ti, err := cli.Template(template.Link, nil)
+	checkStatus := func(id string, status client.TaskStatus) {
+		ti, err := cli.Task(cli.TaskLink(id), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ti.Status != status || ti.Executing != (status == client.Enabled) {
+			t.Errorf("unexpected task %s status: got %v executing %v exp %v", id, ti.Status, ti.Executing, status)
+		}
+	}
+
+	r, err := cli.BulkTasks(client.BulkTasksOptions{
+		Action:   client.BulkEnable,
+		Patterns: []string{"bulk_*"},
+	})
+	if err == nil {
+		t.Fatal("expected error enabling tasks")
+	}
+	if got, exp := r.Tasks, []string{"bulk_a", "bulk_b"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected tasks: got %v exp %v", got, exp)
+	}
+	if len(r.Errors) != 1 || r.Errors[0].ID != "bulk_b" {
+		t.Errorf("unexpected errors: %v", r.Errors)
+	}
+	// Changes to bulk_a must have been rolled back.
+	checkStatus("bulk_a", client.Disabled)
+	checkStatus("bulk_b", client.Disabled)
+
+	r, err = cli.BulkTasks(client.BulkTasksOptions{
+		Action:   client.BulkEnable,
+		Patterns: []string{"bulk_a", "*_a"},
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	if ti.Error != "" {
-		t.Fatal(ti.Error)
+	if got, exp := r.Tasks, []string{"bulk_a"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected tasks: got %v exp %v", got, exp)
 	}
-	if ti.ID != id {
-		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	checkStatus("bulk_a", client.Enabled)
+
+	if _, err := cli.BulkTasks(client.BulkTasksOptions{
+		Action:   client.BulkReload,
+		Patterns: []string{"bulk_a"},
+	}); err != nil {
+		t.Fatal(err)
 	}
-	if ti.Type != client.StreamTask {
-		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	checkStatus("bulk_a", client.Enabled)
+
+	if _, err := cli.BulkTasks(client.BulkTasksOptions{
+		Action:   client.BulkDisable,
+		Patterns: []string{"bulk_*"},
+	}); err != nil {
+		t.Fatal(err)
 	}
-	if ti.TICKscript != implicitTick {
-		t.Fatalf("unexpected TICKscript got\n%s\nexp\n%s\n", ti.TICKscript, implicitTick)
+	checkStatus("bulk_a", client.Disabled)
+	checkStatus("bulk_b", client.Disabled)
+
+	if _, err := cli.BulkTasks(client.BulkTasksOptions{
+		Action:   client.BulkDelete,
+		Patterns: []string{"bulk_*"},
+	}); err != nil {
+		t.Fatal(err)
 	}
-	dot := "digraph testTemplateID {\nstream0 -> from1;\n}"
-	if ti.Dot != dot {
-		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	tasks, err := cli.ListTasks(nil)
+	if err != nil {
+		t.Fatal(err)
 	}
-	vars := client.Vars{"x": {Value: int64(5), Type: client.VarInt}}
-	if !reflect.DeepEqual(vars, ti.Vars) {
-		t.Fatalf("unexpected vars\ngot\n%s\nexp\n%s\n", ti.Vars, vars)
+	if len(tasks) != 0 {
+		t.Errorf("expected all tasks to be deleted, got %v", tasks)
 	}
 
-	implicitDBRPs := []client.DBRP{
-		{
-			Database:        "telegraf",
-			RetentionPolicy: "autogen",
-		},
+	if _, err := cli.BulkTasks(client.BulkTasksOptions{
+		Action:   "restart",
+		Patterns: []string{"bulk_*"},
+	}); err == nil {
+		t.Error("expected error for invalid action")
 	}
+}
 
-	count := 1
-	tasks := make([]client.Task, count)
-	for i := 0; i < count; i++ {
+func TestServer_TaskLabels(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	tick := `stream
+    |from()
+        .measurement('test')
+`
+	for id, labels := range map[string]client.Labels{
+		"db_cpu":  {"team": "db", "env": "prod"},
+		"db_mem":  {"team": "db", "env": "dev"},
+		"web_cpu": {"team": "web"},
+		"no_team": nil,
+	} {
 		task, err := cli.CreateTask(client.CreateTaskOptions{
-			TemplateID: template.ID,
-			Status:     client.Enabled,
+			ID:         id,
+			Type:       client.StreamTask,
+			DBRPs:      dbrps,
+			TICKscript: tick,
+			Labels:     labels,
+			Status:     client.Disabled,
 		})
 		if err != nil {
 			t.Fatal(err)
 		}
-		tasks[i] = task
+		if !reflect.DeepEqual(task.Labels, labels) {
+			t.Errorf("unexpected labels for %s: got %v exp %v", id, task.Labels, labels)
+		}
+	}
 
-		ti, err := cli.Task(task.Link, nil)
+	listIDs := func(selector string) []string {
+		tasks, err := cli.ListTasks(&client.ListTasksOptions{
+			Selector: selector,
+			Fields:   []string{"labels"},
+		})
 		if err != nil {
 			t.Fatal(err)
 		}
-
-		if !reflect.DeepEqual(ti.DBRPs, implicitDBRPs) {
-			t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, implicitDBRPs)
+		ids := make([]string, len(tasks))
+		for i, task := range tasks {
+			ids[i] = task.ID
+		}
+		return ids
+	}
+	testCases := []struct {
+		selector string
+		exp      []string
+	}{
+		{selector: "team=db", exp: []string{"db_cpu", "db_mem"}},
+		{selector: "team=db,env!=dev", exp: []string{"db_cpu"}},
+		{selector: "!team", exp: []string{"no_team"}},
+		{selector: "team", exp: []string{"db_cpu", "db_mem", "web_cpu"}},
+	}
+	for _, tc := range testCases {
+		if got := listIDs(tc.selector); !reflect.DeepEqual(got, tc.exp) {
+			t.Errorf("unexpected tasks for selector %q: got %v exp %v", tc.selector, got, tc.exp)
 		}
 	}
 
-	updateTick := `var x = 5
-
-	stream
-	    |from()
-	        .measurement('test')
-	`
+	if _, err := cli.ListTasks(&client.ListTasksOptions{Selector: "team=="}); err == nil {
+		t.Error("expected error for invalid selector")
+	}
+	if _, err := cli.UpdateTask(cli.TaskLink("no_team"), client.UpdateTaskOptions{
+		Labels: client.Labels{"task": "x"},
+	}); err == nil {
+		t.Error("expected error for reserved label key")
+	}
 
-	_, err = cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
-		ID:         id,
-		TICKscript: updateTick,
+	// Update and remove labels
+	task, err := cli.UpdateTask(cli.TaskLink("no_team"), client.UpdateTaskOptions{
+		Labels: client.Labels{"team": "ops"},
 	})
-	// Expects error
-	if err == nil {
+	if err != nil {
 		t.Fatal(err)
 	}
+	if exp := (client.Labels{"team": "ops"}); !reflect.DeepEqual(task.Labels, exp) {
+		t.Errorf("unexpected labels: got %v exp %v", task.Labels, exp)
+	}
+	task, err = cli.UpdateTask(cli.TaskLink("web_cpu"), client.UpdateTaskOptions{
+		Labels: client.Labels{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(task.Labels) != 0 {
+		t.Errorf("expected labels to be removed, got %v", task.Labels)
+	}
+	// Labels are kept when not updated
+	task, err = cli.UpdateTask(cli.TaskLink("db_cpu"), client.UpdateTaskOptions{
+		TICKscript: tick,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := (client.Labels{"team": "db", "env": "prod"}); !reflect.DeepEqual(task.Labels, exp) {
+		t.Errorf("unexpected labels: got %v exp %v", task.Labels, exp)
+	}
 
-	finalTick := `dbrp "telegraf"."autogen"
-
-	dbrp "telegraf"."not_autogen"
-
-	var x = 5
-
-	stream
-	    |from()
-	        .measurement('test')
-	`
-
-	finalDBRPs := []client.DBRP{
-		{
-			Database:        "telegraf",
-			RetentionPolicy: "autogen",
-		},
-		{
-			Database:        "telegraf",
-			RetentionPolicy: "not_autogen",
-		},
+	// Enable by selector
+	r, err := cli.BulkTasks(client.BulkTasksOptions{
+		Action:   client.BulkEnable,
+		Selector: "team=db",
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
-	template, err = cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
-		ID:         id,
-		TICKscript: finalTick,
+	if exp := []string{"db_cpu", "db_mem"}; !reflect.DeepEqual(r.Tasks, exp) {
+		t.Errorf("unexpected enabled tasks: got %v exp %v", r.Tasks, exp)
+	}
+	r, err = cli.BulkTasks(client.BulkTasksOptions{
+		Action:   client.BulkDisable,
+		Patterns: []string{"*_cpu"},
+		Selector: "team=db",
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
+	if exp := []string{"db_cpu"}; !reflect.DeepEqual(r.Tasks, exp) {
+		t.Errorf("unexpected disabled tasks: got %v exp %v", r.Tasks, exp)
+	}
 
-	for _, task := range tasks {
-		ti, err := cli.Task(task.Link, nil)
-		if err != nil {
-			t.Fatal(err)
+	// Labels are tags of the node statistics
+	dv, err := cli.DebugVars()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, stat := range dv.Stats {
+		if stat.Name != "nodes" || stat.Tags["task"] != "db_mem" {
+			continue
 		}
-
-		if !reflect.DeepEqual(ti.DBRPs, finalDBRPs) {
-			t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, finalDBRPs)
+		found = true
+		if stat.Tags["team"] != "db" || stat.Tags["env"] != "dev" {
+			t.Errorf("unexpected node stat tags: %v", stat.Tags)
 		}
 	}
+	if !found {
+		t.Error("no node stats found for task db_mem")
+	}
 }
-func TestServer_UpdateTemplateID_WithTasks(t *testing.T) {
+
+func TestServer_TaskVersions(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
-	id := "testTemplateID"
-	ttype := client.StreamTask
-	tick := `var x = 5
-
-stream
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	tick1 := `stream
     |from()
         .measurement('test')
 `
-	dbrps := []client.DBRP{
-		{
-			Database:        "mydb",
-			RetentionPolicy: "myrp",
-		},
-		{
-			Database:        "otherdb",
-			RetentionPolicy: "default",
-		},
-	}
-
-	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
-		ID:         id,
-		Type:       ttype,
-		TICKscript: tick,
+	tick2 := `stream
+    |from()
+        .measurement('other')
+`
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "testTaskID",
+		Type:       client.StreamTask,
+		DBRPs:      dbrps,
+		TICKscript: tick1,
+		Status:     client.Enabled,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	count := 100
-	tasks := make([]client.Task, count)
-	for i := 0; i < count; i++ {
-		task, err := cli.CreateTask(client.CreateTaskOptions{
-			TemplateID: template.ID,
-			DBRPs:      dbrps,
-			Status:     client.Enabled,
-		})
-		if err != nil {
-			t.Fatal(err)
-		}
-		tasks[i] = task
+	// Changing only the status does not create a version.
+	if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{Status: client.Disabled}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{Status: client.Enabled}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{TICKscript: tick2}); err != nil {
+		t.Fatal(err)
 	}
 
-	newID := "newTemplateID"
-	template, err = cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
-		ID: newID,
-	})
+	versions, err := cli.ListTaskVersions(task.Link)
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	for _, task := range tasks {
-		got, err := cli.Task(task.Link, nil)
-		if err != nil {
-			t.Fatal(err)
-		}
-		if got.TemplateID != newID {
-			t.Errorf("unexpected task TemplateID got %s exp %s", got.TemplateID, newID)
+	if len(versions) != 2 {
+		t.Fatalf("unexpected number of versions: got %d exp 2", len(versions))
+	}
+	for i, script := range []string{tick1, tick2} {
+		v := versions[i]
+		if v.Version != i+1 || v.TICKscript != script || v.Type != client.StreamTask || !reflect.DeepEqual(v.DBRPs, dbrps) {
+			t.Errorf("unexpected version %d: %v", i+1, v)
 		}
-		if got.TICKscript != tick {
-			t.Errorf("unexpected task TICKscript got %s exp %s", got.TICKscript, tick)
+		if exp := "/kapacitor/v1/tasks/testTaskID/versions/" + strconv.Itoa(i+1); v.Link.Href != exp {
+			t.Errorf("unexpected version link: got %s exp %s", v.Link.Href, exp)
 		}
 	}
-}
-func TestServer_UpdateTemplateID_Fail(t *testing.T) {
-	s, cli := OpenDefaultServer()
-	defer s.Close()
-
-	id := "testTemplateID"
-	newID := "anotherTemplateID"
-	ttype := client.StreamTask
-	tick := `var x = 5
-
-stream
-    |from()
-        .measurement('test')
-`
-	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
-		ID:         id,
-		Type:       ttype,
-		TICKscript: tick,
-	})
+	v, err := cli.TaskVersion(versions[0].Link)
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	ti, err := cli.Template(template.Link, nil)
-	if err != nil {
-		t.Fatal(err)
+	if v.TICKscript != tick1 {
+		t.Errorf("unexpected version script: got %s exp %s", v.TICKscript, tick1)
 	}
 
-	if ti.Error != "" {
-		t.Fatal(ti.Error)
-	}
-	if ti.ID != id {
-		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
-	}
-	if ti.Type != client.StreamTask {
-		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	if _, err := cli.RollbackTask(task.Link, client.RollbackTaskOptions{Version: 5}); err == nil {
+		t.Error("expected error rolling back to unknown version")
 	}
-	if ti.TICKscript != tick {
-		t.Fatalf("unexpected TICKscript got\n%s\nexp\n%s\n", ti.TICKscript, tick)
+	task, err = cli.RollbackTask(task.Link, client.RollbackTaskOptions{Version: 1})
+	if err != nil {
+		t.Fatal(err)
 	}
-	dot := "digraph testTemplateID {\nstream0 -> from1;\n}"
-	if ti.Dot != dot {
-		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	if task.TICKscript != tick1 {
+		t.Errorf("unexpected script after rollback: got %s exp %s", task.TICKscript, tick1)
 	}
-	vars := client.Vars{"x": {Value: int64(5), Type: client.VarInt}}
-	if !reflect.DeepEqual(vars, ti.Vars) {
-		t.Fatalf("unexpected vars\ngot\n%s\nexp\n%s\n", ti.Vars, vars)
+	if task.Status != client.Enabled || !task.Executing {
+		t.Errorf("expected task to be enabled and executing after rollback, got %v %v", task.Status, task.Executing)
 	}
 
-	// Create conflicting template
-	if _, err := cli.CreateTemplate(client.CreateTemplateOptions{
-		ID:         newID,
-		Type:       ttype,
-		TICKscript: tick,
-	}); err != nil {
+	versions, err = cli.ListTaskVersions(task.Link)
+	if err != nil {
 		t.Fatal(err)
 	}
-	if _, err = cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
-		ID: newID,
-	}); err == nil {
-		t.Fatal("expected update template to fail on name conflict")
+	if len(versions) != 3 || versions[2].Version != 3 || versions[2].TICKscript != tick1 {
+		t.Errorf("expected rollback to be saved as version 3, got %v", versions)
 	}
 
-	// Can still get old template
-	ti, err = cli.Template(template.Link, nil)
+	// Versions follow the task when its ID changes and are deleted with it.
+	task, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{ID: "newTaskID"})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	if ti.Error != "" {
-		t.Fatal(ti.Error)
+	versions, err = cli.ListTaskVersions(task.Link)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if ti.ID != id {
-		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	if len(versions) != 3 {
+		t.Errorf("unexpected number of versions after ID change: got %d exp 3", len(versions))
 	}
-	if ti.Type != client.StreamTask {
-		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	if err := cli.DeleteTask(task.Link); err != nil {
+		t.Fatal(err)
 	}
-	if ti.TICKscript != tick {
-		t.Fatalf("unexpected TICKscript got\n%s\nexp\n%s\n", ti.TICKscript, tick)
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "newTaskID",
+		Type:       client.StreamTask,
+		DBRPs:      dbrps,
+		TICKscript: tick2,
+	}); err != nil {
+		t.Fatal(err)
 	}
-	if ti.Dot != dot {
-		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	versions, err = cli.ListTaskVersions(task.Link)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if !reflect.DeepEqual(vars, ti.Vars) {
-		t.Fatalf("unexpected vars\ngot\n%s\nexp\n%s\n", ti.Vars, vars)
+	if len(versions) != 1 || versions[0].TICKscript != tick2 {
+		t.Errorf("expected only the version of the new task, got %v", versions)
 	}
 }
-func TestServer_UpdateTemplateID_WithTasks_Fail(t *testing.T) {
+
+func TestServer_TaskDependsOn(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
-	id := "testTemplateID"
-	ttype := client.StreamTask
-	tick := `var x = 5
-
-stream
-    |from()
-        .measurement('test')
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	batch := `batch
+    |query('SELECT mean("value") FROM "telegraf"."default".cpu')
+        .period(1m)
+        .every(1m)
 `
-	dbrps := []client.DBRP{
-		{
-			Database:        "mydb",
-			RetentionPolicy: "myrp",
-		},
-		{
-			Database:        "otherdb",
-			RetentionPolicy: "default",
-		},
+	producer, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "producer",
+		Type:       client.BatchTask,
+		DBRPs:      dbrps,
+		TICKscript: batch,
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
-
-	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
-		ID:         id,
-		Type:       ttype,
-		TICKscript: tick,
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "stream",
+		Type:       client.StreamTask,
+		DBRPs:      dbrps,
+		TICKscript: "stream|from().measurement('test')",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	consumer, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "consumer",
+		Type:       client.BatchTask,
+		DBRPs:      dbrps,
+		TICKscript: batch,
+		DependsOn:  []string{"producer"},
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
+	if exp := []string{"producer"}; !reflect.DeepEqual(consumer.DependsOn, exp) {
+		t.Errorf("unexpected depends on got %v exp %v", consumer.DependsOn, exp)
+	}
 
-	count := 100
-	tasks := make([]client.Task, count)
-	for i := 0; i < count; i++ {
-		task, err := cli.CreateTask(client.CreateTaskOptions{
-			TemplateID: template.ID,
-			DBRPs:      dbrps,
-			Status:     client.Enabled,
-		})
-		if err != nil {
-			t.Fatal(err)
+	for _, tc := range []struct {
+		link      client.Link
+		dependsOn []string
+		exp       string
+	}{
+		{link: consumer.Link, dependsOn: []string{"unknown"}, exp: `task depends on unknown task "unknown"`},
+		{link: consumer.Link, dependsOn: []string{"stream"}, exp: `task depends on "stream" which is not a batch task`},
+		{link: consumer.Link, dependsOn: []string{"consumer"}, exp: "task cannot depend on itself"},
+		{link: producer.Link, dependsOn: []string{"consumer"}, exp: `task "consumer" depends on task "producer", which would create a cycle`},
+	} {
+		_, err := cli.UpdateTask(tc.link, client.UpdateTaskOptions{DependsOn: tc.dependsOn})
+		if err == nil || err.Error() != tc.exp {
+			t.Errorf("%v: unexpected error got %v exp %q", tc.dependsOn, err, tc.exp)
 		}
-		tasks[i] = task
 	}
 
-	// Create conflicting template
-	newID := "newTemplateID"
-	if _, err := cli.CreateTemplate(client.CreateTemplateOptions{
-		ID:         newID,
-		Type:       ttype,
-		TICKscript: tick,
-	}); err != nil {
+	// An empty list removes the dependencies
+	consumer, err = cli.UpdateTask(consumer.Link, client.UpdateTaskOptions{DependsOn: []string{}})
+	if err != nil {
 		t.Fatal(err)
 	}
-	if _, err = cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
-		ID:         newID,
-		TICKscript: "stream",
-	}); err == nil {
-		t.Fatal("expected update template to fail on conflicting name")
-	}
-
-	for _, task := range tasks {
-		got, err := cli.Task(task.Link, nil)
-		if err != nil {
-			t.Fatal(err)
-		}
-		if got.TemplateID != id {
-			t.Errorf("unexpected task TemplateID got %s exp %s", got.TemplateID, id)
-		}
-		if got.TICKscript != tick {
-			t.Errorf("unexpected task TICKscript got %s exp %s", got.TICKscript, tick)
-		}
+	if len(consumer.DependsOn) != 0 {
+		t.Errorf("expected dependencies to be removed got %v", consumer.DependsOn)
 	}
 }
 
-func TestServer_DeleteTemplate(t *testing.T) {
+func TestServer_TaskRestart(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
-	id := "testTemplateID"
-	ttype := client.StreamTask
-	tick := `stream
+	// The group limit makes the task fail whenever it sees a second host.
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   "restarted",
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `stream
     |from()
         .measurement('test')
-`
-	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
-		ID:         id,
-		Type:       ttype,
-		TICKscript: tick,
+        .groupBy('host')
+    |window()
+        .period(10s)
+        .every(10s)
+    |count('value')
+`,
+		Status: client.Enabled,
+		Limits: &client.TaskLimits{MaxGroups: 1},
+		Restart: &client.TaskRestartPolicy{
+			MaxRestarts: 2,
+			Backoff:     client.Duration(time.Millisecond),
+			MaxBackoff:  client.Duration(time.Hour),
+			Topic:       "tasks",
+		},
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
+	if exp := (client.TaskRestartPolicy{
+		MaxRestarts: 2,
+		Backoff:     client.Duration(time.Millisecond),
+		MaxBackoff:  client.Duration(time.Hour),
+		Topic:       "tasks",
+	}); task.Restart == nil || *task.Restart != exp {
+		t.Fatalf("unexpected restart policy got %+v exp %+v", task.Restart, exp)
+	}
 
-	err = cli.DeleteTemplate(template.Link)
+	points := `test,host=serverA value=1 0000000001
+test,host=serverB value=1 0000000002
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	// waitFor polls the task until the condition is true
+	waitFor := func(cond func(client.Task) bool) client.Task {
+		var ti client.Task
+		for i := 0; i < 100; i++ {
+			ti, err = cli.Task(task.Link, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cond(ti) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return ti
+	}
+
+	for restarts := 1; restarts <= 2; restarts++ {
+		s.MustWrite("mydb", "myrp", points, v)
+		ti := waitFor(func(ti client.Task) bool {
+			return ti.Restarts == restarts && ti.Executing
+		})
+		if ti.Restarts != restarts || !ti.Executing {
+			t.Fatalf("expected task to be restarted %d times, got %d restarts executing %v", restarts, ti.Restarts, ti.Executing)
+		}
+	}
+
+	// The third failure gives up
+	s.MustWrite("mydb", "myrp", points, v)
+	ti := waitFor(func(ti client.Task) bool {
+		return !ti.Executing && ti.Error != ""
+	})
+	if ti.Executing {
+		t.Fatal("expected task to stop after its last restart")
+	}
+	if exp := "task exceeded its limit of 1 groups"; !strings.Contains(ti.Error, exp) {
+		t.Errorf("unexpected error got %q exp %q", ti.Error, exp)
+	}
+
+	var events client.TopicEvents
+	for i := 0; i < 100; i++ {
+		events, err = cli.ListTopicEvents(cli.TopicEventsLink("tasks"), nil)
+		if err == nil && len(events.Events) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(events.Events) != 1 {
+		t.Fatalf("expected one alert event got %+v err %v", events, err)
+	}
+	if got := events.Events[0]; got.ID != "restarted" || got.State.Level != "CRITICAL" {
+		t.Errorf("unexpected alert event %+v", got)
+	}
+
+	// Disabling the task forgets its restarts
+	ti, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Disabled,
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	ti, err := cli.Template(template.Link, nil)
-	if err == nil {
-		t.Fatal("unexpected template:", ti)
+	if ti.Restarts != 0 {
+		t.Errorf("unexpected restarts after disabling got %d exp 0", ti.Restarts)
 	}
 }
 
-func TestServer_CreateTaskFromTemplate(t *testing.T) {
+func TestServer_TaskPriority(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
-	id := "testTemplateID"
-	ttype := client.StreamTask
-	tick := `// Configurable measurement
-var measurement = 'test'
-
-stream
-    |from()
-        .measurement(measurement)
-`
-	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
-		ID:         id,
-		Type:       ttype,
-		TICKscript: tick,
+	priority := 10
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   "alerting",
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: "stream|from().measurement('test')",
+		Status:     client.Enabled,
+		Priority:   &priority,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
+	if task.Priority != priority {
+		t.Errorf("unexpected priority got %d exp %d", task.Priority, priority)
+	}
 
-	templateInfo, err := cli.Template(template.Link, nil)
+	priority = -1
+	task, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Priority: &priority,
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	if templateInfo.Error != "" {
-		t.Fatal(templateInfo.Error)
-	}
-	if templateInfo.ID != id {
-		t.Fatalf("unexpected template.id got %s exp %s", templateInfo.ID, id)
-	}
-	if templateInfo.Type != client.StreamTask {
-		t.Fatalf("unexpected template.type got %v exp %v", templateInfo.Type, client.StreamTask)
-	}
-	if templateInfo.TICKscript != tick {
-		t.Fatalf("unexpected template.TICKscript got %s exp %s", templateInfo.TICKscript, tick)
-	}
-	dot := "digraph testTemplateID {\nstream0 -> from1;\n}"
-	if templateInfo.Dot != dot {
-		t.Fatalf("unexpected template.dot\ngot\n%s\nexp\n%s\n", templateInfo.Dot, dot)
-	}
-	expVars := client.Vars{
-		"measurement": {
-			Value:       "test",
-			Type:        client.VarString,
-			Description: "Configurable measurement",
-		},
-	}
-	if got, exp := templateInfo.Vars, expVars; !reflect.DeepEqual(exp, got) {
-		t.Errorf("unexpected template vars: got %v exp %v", got, exp)
-	}
-
-	dbrps := []client.DBRP{
-		{
-			Database:        "mydb",
-			RetentionPolicy: "myrp",
-		},
-		{
-			Database:        "otherdb",
-			RetentionPolicy: "default",
-		},
+	if task.Priority != priority {
+		t.Errorf("unexpected priority after update got %d exp %d", task.Priority, priority)
 	}
-	vars := client.Vars{
-		"measurement": {
-			Value: "another_measurement",
-			Type:  client.VarString,
-		},
+	if !task.Executing {
+		t.Error("expected task to keep executing after changing its priority")
 	}
 
-	task, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         "taskid",
-		TemplateID: id,
-		DBRPs:      dbrps,
-		Vars:       vars,
+	// Other updates keep the priority
+	task, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Labels: client.Labels{"team": "ops"},
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	taskInfo, err := cli.Task(task.Link, nil)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	if taskInfo.Error != "" {
-		t.Fatal(taskInfo.Error)
-	}
-	if taskInfo.ID != "taskid" {
-		t.Fatalf("unexpected task.id got %s exp %s", taskInfo.ID, "taskid")
-	}
-	if taskInfo.Type != client.StreamTask {
-		t.Fatalf("unexpected task.type got %v exp %v", taskInfo.Type, client.StreamTask)
-	}
-	if taskInfo.TICKscript != tick {
-		t.Fatalf("unexpected task.TICKscript got %s exp %s", taskInfo.TICKscript, tick)
-	}
-	dot = "digraph taskid {\nstream0 -> from1;\n}"
-	if taskInfo.Dot != dot {
-		t.Fatalf("unexpected task.dot\ngot\n%s\nexp\n%s\n", taskInfo.Dot, dot)
-	}
-	if taskInfo.Status != client.Disabled {
-		t.Fatalf("unexpected task.status got %v exp %v", taskInfo.Status, client.Disabled)
-	}
-	if !reflect.DeepEqual(taskInfo.DBRPs, dbrps) {
-		t.Fatalf("unexpected task.dbrps got %s exp %s", taskInfo.DBRPs, dbrps)
-	}
-	if !reflect.DeepEqual(taskInfo.Vars, vars) {
-		t.Fatalf("unexpected task.vars got %s exp %s", taskInfo.Vars, vars)
-	}
-}
-
-func TestServer_DynamicStreamTask(t *testing.T) {
-	s, cli := OpenDefaultServer()
-	defer s.Close()
-
-	testCases := []struct {
-		name string
-		tick string
-		want client.TaskType
-	}{
-		{
-			name: "stream",
-			tick: `
-dbrp "db"."rp"
-stream
-    |from()
-         .measurement('test')
-`,
-			want: client.StreamTask,
-		},
-		{
-			name: "stream_through_var",
-			tick: `
-dbrp "db"."rp"
-var s = stream
-s
-    |from()
-         .measurement('test')
-`,
-			want: client.StreamTask,
-		},
-		{
-			name: "batch",
-			tick: `
-dbrp "db"."rp"
-batch
-    |query('select * from db.rp.m')
-`,
-			want: client.BatchTask,
-		},
-		{
-			name: "batch_through_var",
-			tick: `
-dbrp "db"."rp"
-var b = batch
-b
-    |query('select * from db.rp.m')
-`,
-			want: client.BatchTask,
-		},
-	}
-
-	for _, tc := range testCases {
-		tc := tc
-		t.Run(tc.name, func(t *testing.T) {
-			task, err := cli.CreateTask(client.CreateTaskOptions{
-				ID:         tc.name,
-				TICKscript: tc.tick,
-				Status:     client.Disabled,
-			})
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			if task.Type != tc.want {
-				t.Fatalf("unexpected task type: got: %v want: %v", task.Type, tc.want)
-			}
-		})
+	if task.Priority != priority {
+		t.Errorf("unexpected priority after other update got %d exp %d", task.Priority, priority)
 	}
 }
 
-func TestServer_StreamTask(t *testing.T) {
+func TestServer_TaskLimits(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
-	id := "testStreamTask"
-	ttype := client.StreamTask
 	dbrps := []client.DBRP{{
 		Database:        "mydb",
 		RetentionPolicy: "myrp",
@@ -1929,754 +2111,821 @@ func TestServer_StreamTask(t *testing.T) {
 	tick := `stream
     |from()
         .measurement('test')
+        .groupBy('host')
     |window()
         .period(10s)
         .every(10s)
     |count('value')
-    |httpOut('count')
 `
-
-	task, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         id,
-		Type:       ttype,
+	groupsTask, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "groups",
+		Type:       client.StreamTask,
 		DBRPs:      dbrps,
 		TICKscript: tick,
-		Status:     client.Disabled,
+		Status:     client.Enabled,
+		Limits:     &client.TaskLimits{MaxGroups: 1},
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-		Status: client.Enabled,
+	if exp := (client.TaskLimits{MaxGroups: 1}); groupsTask.Limits != exp {
+		t.Errorf("unexpected limits got %+v exp %+v", groupsTask.Limits, exp)
+	}
+	bufferTask, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "buffer",
+		Type:       client.StreamTask,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Enabled,
+		Limits:     &client.TaskLimits{MaxBufferedPoints: 2},
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), id)
-
-	// Request data before any writes and expect null responses
-	nullResponse := `{"series":null}`
-	err = s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5)
-	if err != nil {
-		t.Error(err)
-	}
-
-	points := `test value=1 0000000000
-test value=1 0000000001
-test value=1 0000000001
-test value=1 0000000002
-test value=1 0000000002
-test value=1 0000000003
-test value=1 0000000003
-test value=1 0000000004
-test value=1 0000000005
-test value=1 0000000005
-test value=1 0000000005
-test value=1 0000000006
-test value=1 0000000007
-test value=1 0000000008
-test value=1 0000000009
-test value=1 0000000010
-test value=1 0000000011
+	points := `test,host=serverA value=1 0000000001
+test,host=serverB value=1 0000000002
+test,host=serverA value=1 0000000003
 `
 	v := url.Values{}
 	v.Add("precision", "s")
 	s.MustWrite("mydb", "myrp", points, v)
 
-	exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
-	err = s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5)
-	if err != nil {
-		t.Error(err)
+	for _, tc := range []struct {
+		link client.Link
+		exp  string
+	}{
+		{link: groupsTask.Link, exp: "task exceeded its limit of 1 groups"},
+		{link: bufferTask.Link, exp: "task exceeded its limit of 2 buffered points"},
+	} {
+		var ti client.Task
+		for i := 0; i < 100; i++ {
+			ti, err = cli.Task(tc.link, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ti.Error != "" {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if !strings.Contains(ti.Error, tc.exp) {
+			t.Errorf("%s: unexpected error got %q exp %q", ti.ID, ti.Error, tc.exp)
+		}
+		if ti.Executing {
+			t.Errorf("%s: expected task to have stopped", ti.ID)
+		}
+	}
+
+	if _, err := cli.UpdateTask(groupsTask.Link, client.UpdateTaskOptions{
+		Limits: &client.TaskLimits{MaxGroups: -1},
+	}); err == nil {
+		t.Error("expected error for negative limit")
 	}
 }
 
-func TestServer_StreamTask_NoRP(t *testing.T) {
-	conf := NewConfig()
-	conf.DefaultRetentionPolicy = "myrp"
-	s := OpenServer(conf)
+func TestServer_TaskStats(t *testing.T) {
+	s, cli := OpenDefaultServer()
 	defer s.Close()
-	cli := Client(s)
 
-	id := "testStreamTask"
-	ttype := client.StreamTask
-	dbrps := []client.DBRP{{
-		Database:        "mydb",
-		RetentionPolicy: "myrp",
-	}}
-	tick := `stream
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   "stats",
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `stream
     |from()
         .measurement('test')
     |window()
         .period(10s)
         .every(10s)
     |count('value')
-    |httpOut('count')
-`
+`,
+		Status: client.Enabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	task, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         id,
-		Type:       ttype,
-		DBRPs:      dbrps,
-		TICKscript: tick,
-		Status:     client.Disabled,
-	})
+	points := `test value=1 0000000001
+test value=1 0000000002
+test value=1 0000000003
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+
+	var stats client.TaskStats
+	for i := 0; i < 100; i++ {
+		stats, err = cli.TaskStats(task.Link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.NodeStats["window2"].Collected == 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !stats.Executing {
+		t.Error("expected task to be executing")
+	}
+	if got, exp := stats.ID, task.ID; got != exp {
+		t.Errorf("unexpected ID got %s exp %s", got, exp)
+	}
+	for _, name := range []string{"stream0", "from1", "window2", "count3"} {
+		if _, ok := stats.NodeStats[name]; !ok {
+			t.Errorf("missing stats for node %s", name)
+		}
+	}
+	from := stats.NodeStats["from1"]
+	if from.Collected != 3 || from.Emitted != 3 || from.Errors != 0 || from.QueueDepth != 0 {
+		t.Errorf("unexpected from stats %+v", from)
+	}
+	window := stats.NodeStats["window2"]
+	if window.Collected != 3 || window.Emitted != 0 || window.Buffered != 3 {
+		t.Errorf("unexpected window stats %+v", window)
+	}
+	if window.Memory <= 0 {
+		t.Errorf("expected window memory to be estimated, got %d", window.Memory)
+	}
+	ti, err := cli.Task(task.Link, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if memory, ok := ti.ExecutionStats.TaskStats["memory_bytes"].(float64); !ok || memory < float64(window.Memory) {
+		t.Errorf("unexpected task memory got %v, window memory %d", ti.ExecutionStats.TaskStats["memory_bytes"], window.Memory)
+	}
+	if l := window.Latency; l.P50 > l.P90 || l.P90 > l.P99 || l.P99 > l.Max {
+		t.Errorf("unexpected window latency %+v", l)
+	}
 
-	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-		Status: client.Enabled,
-	})
+	if _, err := cli.TaskStats(cli.TaskLink("unknown")); err == nil {
+		t.Error("expected error for unknown task")
+	}
+
+	if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{Status: client.Disabled}); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = cli.TaskStats(task.Link)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if stats.Executing || len(stats.NodeStats) != 0 {
+		t.Errorf("expected no stats for disabled task, got %+v", stats)
+	}
+}
 
-	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), id)
+func TestServer_TaskTap(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
 
-	// Request data before any writes and expect null responses
-	nullResponse := `{"series":null}`
-	err = s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5)
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   "tapped",
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `stream
+    |from()
+        .measurement('test')
+    |eval(lambda: "value" * 2.0)
+        .as('double')
+    |log()
+`,
+		Status: client.Enabled,
+	})
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	points := `test value=1 0000000000
-test value=1 0000000001
-test value=1 0000000001
-test value=1 0000000002
-test value=1 0000000002
-test value=1 0000000003
-test value=1 0000000003
-test value=1 0000000004
-test value=1 0000000005
-test value=1 0000000005
-test value=1 0000000005
-test value=1 0000000006
-test value=1 0000000007
-test value=1 0000000008
-test value=1 0000000009
-test value=1 0000000010
-test value=1 0000000011
-`
+	var rows []client.Row
+	done := make(chan error, 1)
+	go func() {
+		done <- cli.TapTask(context.Background(), task.Link, "eval2", &client.TapOptions{Count: 2, Timeout: 10 * time.Second}, func(r client.Row) error {
+			rows = append(rows, r)
+			return nil
+		})
+	}()
+
+	// Write points until the tap has received its count, since it only receives points written after it is added.
 	v := url.Values{}
 	v.Add("precision", "s")
-	s.MustWrite("mydb", "", points, v)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+tapping:
+	for i := 1; ; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 2 {
+				t.Fatalf("unexpected number of rows got %d exp 2", len(rows))
+			}
+			for _, r := range rows {
+				if r.Name != "test" || !reflect.DeepEqual(r.Columns, []string{"time", "double"}) || len(r.Values) != 1 {
+					t.Errorf("unexpected row %+v", r)
+				}
+			}
+			break tapping
+		case <-ticker.C:
+			s.MustWrite("mydb", "myrp", fmt.Sprintf("test value=%d %010d\n", i, i), v)
+		}
+	}
 
-	exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
-	err = s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5)
-	if err != nil {
-		t.Error(err)
+	for _, tc := range []struct {
+		node string
+		opt  *client.TapOptions
+		exp  string
+	}{
+		{node: "unknown", exp: "unknown node unknown"},
+		{node: "log3", exp: "node has no output, tap one of its parents instead"},
+		{node: "eval2", opt: &client.TapOptions{Count: -1}, exp: "invalid count"},
+	} {
+		err := cli.TapTask(context.Background(), task.Link, tc.node, tc.opt, func(client.Row) error { return nil })
+		if err == nil || !strings.Contains(err.Error(), tc.exp) {
+			t.Errorf("%s: unexpected error got %v exp %q", tc.node, err, tc.exp)
+		}
 	}
 }
 
-func TestServer_StreamTemplateTask(t *testing.T) {
+func TestServer_TaskWatch(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
-	templateId := "testStreamTemplate"
-	taskId := "testStreamTask"
-	ttype := client.StreamTask
-	dbrps := []client.DBRP{{
-		Database:        "mydb",
-		RetentionPolicy: "myrp",
-	}}
-	tick := `
-var field = 'nonexistent'
-stream
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   "watched",
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `var data = stream
     |from()
         .measurement('test')
-    |window()
-        .period(10s)
-        .every(10s)
-    |count(field)
-    |httpOut('count')
-`
-	if _, err := cli.CreateTemplate(client.CreateTemplateOptions{
-		ID:         templateId,
-		Type:       ttype,
-		TICKscript: tick,
-	}); err != nil {
-		t.Fatal(err)
-	}
 
-	if _, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         taskId,
-		TemplateID: templateId,
-		DBRPs:      dbrps,
-		Status:     client.Enabled,
-		Vars: client.Vars{
-			"field": {
-				Value: "value",
-				Type:  client.VarString,
-			},
-		},
-	}); err != nil {
+data
+    |eval(lambda: "missing" * 2.0)
+        .as('double')
+
+data
+    |alert()
+        .id('test')
+        .crit(lambda: "value" > 0)
+        .topic('watched')
+`,
+		Status: client.Enabled,
+	})
+	if err != nil {
 		t.Fatal(err)
 	}
 
-	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), taskId)
-
-	// Request data before any writes and expect null responses
-	nullResponse := `{"series":null}`
-	if err := s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5); err != nil {
-		t.Error(err)
-	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan client.TaskWatchEvent, 100)
+	done := make(chan error, 1)
+	go func() {
+		done <- cli.WatchTask(ctx, task.Link, &client.WatchTaskOptions{Interval: time.Second}, func(e client.TaskWatchEvent) error {
+			select {
+			case received <- e:
+			case <-ctx.Done():
+			}
+			return nil
+		})
+	}()
 
-	points := `test value=1 0000000000
-test value=1 0000000001
-test value=1 0000000001
-test value=1 0000000002
-test value=1 0000000002
-test value=1 0000000003
-test value=1 0000000003
-test value=1 0000000004
-test value=1 0000000005
-test value=1 0000000005
-test value=1 0000000005
-test value=1 0000000006
-test value=1 0000000007
-test value=1 0000000008
-test value=1 0000000009
-test value=1 0000000010
-test value=1 0000000011
-`
+	// Write points until the feed has received each type of entry, since it only receives the ones after it started.
 	v := url.Values{}
 	v.Add("precision", "s")
-	s.MustWrite("mydb", "myrp", points, v)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(10 * time.Second)
+	got := make(map[string]client.TaskWatchEvent)
+	for i := 1; len(got) < 3; i++ {
+		select {
+		case e := <-received:
+			got[e.Type] = e
+		case err := <-done:
+			t.Fatalf("watch stopped: %v", err)
+		case <-timeout:
+			t.Fatalf("timed out waiting for the entries, got %v", got)
+		case <-ticker.C:
+			s.MustWrite("mydb", "myrp", fmt.Sprintf("test value=%d %010d\n", i, i), v)
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
 
-	exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
-	if err := s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5); err != nil {
-		t.Error(err)
+	if stats := got[client.TaskWatchStats].Stats; stats == nil || stats.ID != "watched" || !stats.Executing {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if e := got[client.TaskWatchError].Error; e == nil || e.Node != "eval2" || e.Message != "error evaluating expression" || e.Error == "" {
+		t.Errorf("unexpected error %+v", e)
+	}
+	if a := got[client.TaskWatchAlert].Alert; a == nil || a.Topic != "watched" || a.ID != "test" || a.State.Level != "CRITICAL" {
+		t.Errorf("unexpected alert %+v", a)
+	}
+
+	err = cli.WatchTask(context.Background(), task.Link, &client.WatchTaskOptions{Interval: time.Millisecond}, func(client.TaskWatchEvent) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "invalid interval") {
+		t.Errorf("unexpected error got %v", err)
+	}
+	err = cli.WatchTask(context.Background(), cli.TaskLink("unknown"), nil, func(client.TaskWatchEvent) error { return nil })
+	if err == nil {
+		t.Error("expected error watching unknown task")
 	}
 }
-func TestServer_StreamTemplateTask_MissingVar(t *testing.T) {
+
+func TestServer_TaskDot(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
-	templateId := "testStreamTemplate"
-	taskId := "testStreamTask"
-	ttype := client.StreamTask
-	dbrps := []client.DBRP{{
-		Database:        "mydb",
-		RetentionPolicy: "myrp",
-	}}
-	tick := `
-var field string
-stream
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   "live",
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `stream
     |from()
         .measurement('test')
-    |window()
-        .period(10s)
-        .every(10s)
-    |count(field)
-    |httpOut('count')
-`
-	if _, err := cli.CreateTemplate(client.CreateTemplateOptions{
-		ID:         templateId,
-		Type:       ttype,
-		TICKscript: tick,
-	}); err != nil {
+    |log()
+`,
+		Status: client.Enabled,
+	})
+	if err != nil {
 		t.Fatal(err)
 	}
 
-	if _, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         taskId,
-		TemplateID: templateId,
-		DBRPs:      dbrps,
-		Status:     client.Enabled,
-	}); err == nil {
-		t.Error("expected error for missing task vars")
-	} else if exp, got := "invalid TICKscript: missing value for var \"field\".", err.Error(); got != exp {
-		t.Errorf("unexpected error message: got %s exp %s", got, exp)
+	// Write points while the rates are measured.
+	done := make(chan struct{})
+	writing := make(chan struct{})
+	go func() {
+		defer close(writing)
+		v := url.Values{}
+		v.Add("precision", "s")
+		for i := 1; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				s.MustWrite("mydb", "myrp", fmt.Sprintf("test value=%d %010d\n", i, i), v)
+			}
+		}
+	}()
+	dot, err := cli.TaskDot(task.Link, &client.TaskDotOptions{Interval: 500 * time.Millisecond})
+	close(done)
+	<-writing
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dot.Executing {
+		t.Error("expected task to be executing")
+	}
+	if got, exp := time.Duration(dot.Interval), 500*time.Millisecond; got != exp {
+		t.Errorf("unexpected interval got %v exp %v", got, exp)
+	}
+	m := regexp.MustCompile(`from1 -> log2 \[processed="\d+" rate="([0-9.]+)" errors="0"\];`).FindStringSubmatch(dot.Dot)
+	if m == nil {
+		t.Fatalf("missing edge rate in DOT:\n%s", dot.Dot)
+	}
+	if rate, _ := strconv.ParseFloat(m[1], 64); rate <= 0 {
+		t.Errorf("unexpected edge rate %v in DOT:\n%s", rate, dot.Dot)
+	}
+
+	labels, err := cli.TaskDot(task.Link, &client.TaskDotOptions{Interval: 10 * time.Millisecond, DotView: "labels"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(labels.Dot, `from1 -> log2 [label="processed=`) || !strings.Contains(labels.Dot, "/s errors=0") {
+		t.Errorf("missing edge label in DOT:\n%s", labels.Dot)
+	}
+
+	if _, err := cli.TaskDot(task.Link, &client.TaskDotOptions{Interval: time.Hour}); err == nil {
+		t.Error("expected error for too long interval")
+	}
+	if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{Status: client.Disabled}); err != nil {
+		t.Fatal(err)
+	}
+	dot, err = cli.TaskDot(task.Link, &client.TaskDotOptions{Interval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dot.Executing || dot.Dot != "" {
+		t.Errorf("expected no DOT for disabled task, got %+v", dot)
 	}
 }
-func TestServer_StreamTemplateTask_AllTypes(t *testing.T) {
+
+func TestServer_TaskSchedule(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
-	templateId := "testStreamTemplate"
-	taskId := "testStreamTask"
-	ttype := client.StreamTask
-	dbrps := []client.DBRP{{
-		Database:        "mydb",
-		RetentionPolicy: "myrp",
-	}}
-	tick := `
-var bool bool
-var count_threshold int
-var value_threshold float
-var window duration
-var field string
-var tagMatch regex
-var match lambda
-var eval lambda
-var groups list
-var secondGroup list
-stream
+	// A window that does not contain the current time
+	now := time.Now().UTC()
+	schedule := &client.TaskSchedule{
+		Windows: []client.TimeWindow{{
+			Start: now.Add(2 * time.Hour).Format("15:04"),
+			Stop:  now.Add(3 * time.Hour).Format("15:04"),
+		}},
+	}
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   "testTaskID",
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `stream
     |from()
         .measurement('test')
-        .where(lambda: match AND "tag" =~ tagMatch AND bool AND "value" >= value_threshold)
-        .groupBy(groups)
-        |log().prefix('FROM')
-    |window()
-        .period(window)
-        .every(window)
-        |log().prefix('WINDOW')
-    |count(field)
-        |log().prefix('COUNT')
-    |groupBy(secondGroup)
-    |sum('count')
-        .as('count')
-        |log().prefix('SUM')
-    |where(lambda: "count" >= count_threshold)
-        |log().prefix('WHERE')
-    |eval(eval)
-        .as('count')
-    |httpOut('count')
-`
-	if _, err := cli.CreateTemplate(client.CreateTemplateOptions{
-		ID:         templateId,
-		Type:       ttype,
-		TICKscript: tick,
-	}); err != nil {
+`,
+		Status:   client.Enabled,
+		Schedule: schedule,
+	})
+	if err != nil {
 		t.Fatal(err)
 	}
+	if !reflect.DeepEqual(task.Schedule, schedule) {
+		t.Errorf("unexpected schedule got %v exp %v", task.Schedule, schedule)
+	}
+	if !task.Paused || task.Executing {
+		t.Fatalf("expected task to be paused, got paused %v executing %v", task.Paused, task.Executing)
+	}
 
-	if _, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         taskId,
-		TemplateID: templateId,
-		DBRPs:      dbrps,
-		Status:     client.Enabled,
-		Vars: client.Vars{
-			"bool": {
-				Value: true,
-				Type:  client.VarBool,
-			},
-			"count_threshold": {
-				Value: int64(1),
-				Type:  client.VarInt,
-			},
-			"value_threshold": {
-				Value: float64(1.0),
-				Type:  client.VarFloat,
-			},
-			"window": {
-				Value: 10 * time.Second,
-				Type:  client.VarDuration,
-			},
-			"field": {
-				Value: "value",
-				Type:  client.VarString,
-			},
-			"tagMatch": {
-				Value: "^a.*",
-				Type:  client.VarRegex,
-			},
-			"match": {
-				Value: `"value" == 1.0`,
-				Type:  client.VarLambda,
-			},
-			"eval": {
-				Value: `"count" * 2`,
-				Type:  client.VarLambda,
-			},
-			"groups": {
-				Value: []client.Var{client.Var{Type: client.VarStar}},
-				Type:  client.VarList,
-			},
-			"secondGroup": {
-				Value: []client.Var{client.Var{Value: "tag", Type: client.VarString}},
-				Type:  client.VarList,
-			},
-		},
-	}); err != nil {
+	// Removing the schedule resumes the task
+	task, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{Schedule: &client.TaskSchedule{}})
+	if err != nil {
 		t.Fatal(err)
 	}
+	if task.Schedule != nil {
+		t.Errorf("unexpected schedule %v", task.Schedule)
+	}
+	if task.Paused || !task.Executing {
+		t.Fatalf("expected task to be executing, got paused %v executing %v", task.Paused, task.Executing)
+	}
 
-	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), taskId)
-
-	// Request data before any writes and expect null responses
-	nullResponse := `{"series":null}`
-	if err := s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5); err != nil {
-		t.Error(err)
+	// Setting a schedule pauses the task
+	task, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{Schedule: schedule})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !task.Paused || task.Executing {
+		t.Fatalf("expected task to be paused, got paused %v executing %v", task.Paused, task.Executing)
 	}
 
-	points := `test,tag=abc,other=a value=1 0000000000
-test,tag=abc,other=b value=1 0000000000
-test,tag=abc,other=a value=1 0000000001
-test,tag=bbc,other=b value=1 0000000001
-test,tag=abc,other=a value=1 0000000002
-test,tag=abc,other=a value=0 0000000002
-test,tag=abc,other=b value=1 0000000003
-test,tag=abc,other=a value=1 0000000003
-test,tag=abc,other=a value=1 0000000004
-test,tag=abc,other=b value=1 0000000005
-test,tag=abc,other=a value=1 0000000005
-test,tag=bbc,other=a value=1 0000000005
-test,tag=abc,other=b value=1 0000000006
-test,tag=abc,other=a value=1 0000000007
-test,tag=abc,other=b value=0 0000000008
-test,tag=abc,other=a value=1 0000000009
-test,tag=abc,other=a value=1 0000000010
-test,tag=abc,other=a value=1 0000000011
-test,tag=abc,other=b value=1 0000000011
-test,tag=bbc,other=a value=1 0000000011
-test,tag=bbc,other=b value=1 0000000011
-test,tag=abc,other=a value=1 0000000021
-`
-	v := url.Values{}
-	v.Add("precision", "s")
-	s.MustWrite("mydb", "myrp", points, v)
+	// Disabled tasks are not paused
+	task, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{Status: client.Disabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Paused || task.Executing {
+		t.Fatalf("expected task to be stopped, got paused %v executing %v", task.Paused, task.Executing)
+	}
 
-	exp := `{"series":[{"name":"test","tags":{"tag":"abc"},"columns":["time","count"],"values":[["1970-01-01T00:00:10Z",24]]}]}`
-	if err := s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5); err != nil {
-		t.Error(err)
+	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Schedule: &client.TaskSchedule{Cron: "* * *"},
+	})
+	if err == nil {
+		t.Error("expected error for invalid cron expression")
 	}
 }
 
-func TestServer_StreamTemplateTaskFromUpdate(t *testing.T) {
+func TestServer_ValidateTask(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
-	templateId := "testStreamTemplate"
-	taskId := "testStreamTask"
-	ttype := client.StreamTask
 	dbrps := []client.DBRP{{
 		Database:        "mydb",
 		RetentionPolicy: "myrp",
 	}}
-	tick := `
-var field = 'nonexistent'
-stream
+
+	// Valid task
+	v, err := cli.ValidateTask(client.ValidateTaskOptions{
+		ID:    "testTaskID",
+		Type:  client.StreamTask,
+		DBRPs: dbrps,
+		TICKscript: `stream
     |from()
         .measurement('test')
-    |window()
-        .period(10s)
-        .every(10s)
-    |count(field)
-    |httpOut('count')
-`
-	if _, err := cli.CreateTemplate(client.CreateTemplateOptions{
-		ID:         templateId,
-		Type:       ttype,
-		TICKscript: tick,
-	}); err != nil {
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Valid || len(v.Errors) != 0 || len(v.Warnings) != 0 {
+		t.Fatalf("expected valid task without warnings, got %+v", v)
+	}
+	if v.Type != client.StreamTask {
+		t.Errorf("unexpected type got %v exp %v", v.Type, client.StreamTask)
+	}
+	if !reflect.DeepEqual(v.DBRPs, dbrps) {
+		t.Errorf("unexpected dbrps got %s exp %s", v.DBRPs, dbrps)
+	}
+	dot := "digraph testTaskID {\nstream0 -> from1;\n}"
+	if v.Dot != dot {
+		t.Errorf("unexpected dot\ngot\n%s\nexp\n%s\n", v.Dot, dot)
+	}
+	// Nothing was created
+	if tasks, err := cli.ListTasks(nil); err != nil {
 		t.Fatal(err)
+	} else if len(tasks) != 0 {
+		t.Fatalf("unexpected tasks %v", tasks)
 	}
 
-	task, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         taskId,
-		TemplateID: templateId,
-		DBRPs:      dbrps,
-		Status:     client.Disabled,
-		Vars: client.Vars{
-			"field": {
-				Value: "value",
-				Type:  client.VarString,
-			},
-		},
+	// Errors with a position
+	v, err = cli.ValidateTask(client.ValidateTaskOptions{
+		DBRPs: dbrps,
+		TICKscript: `stream
+    |from()
+        .measurement('test')
+    |window()
+        .foo(10s)
+`,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
+	if v.Valid || len(v.Errors) != 1 {
+		t.Fatalf("expected a single error, got %+v", v)
+	}
+	if e := v.Errors[0]; e.Line != 5 || e.Char != 10 {
+		t.Errorf("unexpected error position got %d:%d exp 5:10: %s", e.Line, e.Char, e.Message)
+	}
+	if v.Dot != "" {
+		t.Errorf("unexpected dot for invalid task %s", v.Dot)
+	}
 
-	if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-		Status: client.Enabled,
-	}); err != nil {
+	// Type mismatch
+	v, err = cli.ValidateTask(client.ValidateTaskOptions{
+		Type:       client.BatchTask,
+		DBRPs:      dbrps,
+		TICKscript: "stream|from().measurement('test')",
+	})
+	if err != nil {
 		t.Fatal(err)
 	}
-
-	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), taskId)
-
-	// Request data before any writes and expect null responses
-	nullResponse := `{"series":null}`
-	if err := s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5); err != nil {
-		t.Error(err)
+	if v.Valid || len(v.Errors) != 1 {
+		t.Fatalf("expected a single error, got %+v", v)
 	}
-
-	points := `test value=1 0000000000
-test value=1 0000000001
-test value=1 0000000001
-test value=1 0000000002
-test value=1 0000000002
-test value=1 0000000003
-test value=1 0000000003
-test value=1 0000000004
-test value=1 0000000005
-test value=1 0000000005
-test value=1 0000000005
-test value=1 0000000006
-test value=1 0000000007
-test value=1 0000000008
-test value=1 0000000009
-test value=1 0000000010
-test value=1 0000000011
-`
-	v := url.Values{}
-	v.Add("precision", "s")
-	s.MustWrite("mydb", "myrp", points, v)
-
-	exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
-	if err := s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5); err != nil {
-		t.Error(err)
+	if exp, got := "task type batch does not match the TICKscript, which defines a stream task", v.Errors[0].Message; got != exp {
+		t.Errorf("unexpected error got %q exp %q", got, exp)
 	}
-}
-func TestServer_StreamTemplateTask_UpdateTemplate(t *testing.T) {
-	s, cli := OpenDefaultServer()
-	defer s.Close()
 
-	templateId := "testStreamTemplate"
-	taskId := "testStreamTask"
-	ttype := client.StreamTask
-	dbrps := []client.DBRP{{
-		Database:        "mydb",
-		RetentionPolicy: "myrp",
-	}}
-	tickWrong := `
+	// Unknown fields
+	v, err = cli.ValidateTask(client.ValidateTaskOptions{
+		TICKscript: `dbrp "mydb"."myrp"
+
 stream
     |from()
         .measurement('test')
     |window()
         .period(10s)
         .every(10s)
-    |count('wrong')
-    |httpOut('count')
-`
-	tickCorrect := `
-var field string
-stream
-    |from()
-        .measurement('test')
-    |window()
-        .period(10s)
-        .every(10s)
-    |count(field)
-    |httpOut('count')
-`
-	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
-		ID:         templateId,
-		Type:       ttype,
-		TICKscript: tickWrong,
+    |mean('value')
+    |eval(lambda: "mean" * 2.0)
+        .as('double')
+        .keep()
+    |alert()
+        .crit(lambda: "value" > 1.0 AND "double" > 1.0)
+`,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	if _, err = cli.CreateTask(client.CreateTaskOptions{
-		ID:         taskId,
-		TemplateID: templateId,
-		DBRPs:      dbrps,
-		Status:     client.Enabled,
-		Vars: client.Vars{
-			"field": {
-				Value: "value",
-				Type:  client.VarString,
-			},
-		},
-	}); err != nil {
-		t.Fatal(err)
-	}
-
-	if _, err := cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
-		TICKscript: tickCorrect,
-	}); err != nil {
-		t.Fatal(err)
+	if !v.Valid {
+		t.Fatalf("expected valid task, got %+v", v)
 	}
-
-	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), taskId)
-
-	// Request data before any writes and expect null responses
-	nullResponse := `{"series":null}`
-	if err := s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5); err != nil {
-		t.Error(err)
+	if !reflect.DeepEqual(v.DBRPs, dbrps) {
+		t.Errorf("unexpected dbrps got %s exp %s", v.DBRPs, dbrps)
 	}
-
-	points := `test value=1 0000000000
-test value=1 0000000001
-test value=1 0000000001
-test value=1 0000000002
-test value=1 0000000002
-test value=1 0000000003
-test value=1 0000000003
-test value=1 0000000004
-test value=1 0000000005
-test value=1 0000000005
-test value=1 0000000005
-test value=1 0000000006
-test value=1 0000000007
-test value=1 0000000008
-test value=1 0000000009
-test value=1 0000000010
-test value=1 0000000011
-`
-	v := url.Values{}
-	v.Add("precision", "s")
-	s.MustWrite("mydb", "myrp", points, v)
-
-	exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
-	if err := s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5); err != nil {
-		t.Error(err)
+	expWarnings := []client.ValidationMessage{{
+		Message: `unknown field "value", it is not produced by the parent nodes unless it is a tag`,
+		Line:    14,
+		Char:    15,
+		Node:    "alert5",
+	}}
+	if !reflect.DeepEqual(v.Warnings, expWarnings) {
+		t.Errorf("unexpected warnings\ngot\n%+v\nexp\n%+v", v.Warnings, expWarnings)
 	}
 }
-func TestServer_StreamTemplateTask_UpdateTemplate_Rollback(t *testing.T) {
+
+func TestServer_TaskNums(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
-	templateId := "testStreamTemplate"
-	taskId := "testStreamTask"
+	id := "testTaskID"
 	ttype := client.StreamTask
-	dbrps := []client.DBRP{{
-		Database:        "mydb",
-		RetentionPolicy: "myrp",
-	}}
-	tickCorrect := `
-var field string
-stream
-    |from()
-        .measurement('test')
-    |window()
-        .period(10s)
-        .every(10s)
-    |count(field)
-    |httpOut('count')
-`
-	tickNewVar := `
-var field string
-var period duration
-stream
+	dbrps := []client.DBRP{
+		{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		},
+	}
+	tick := `stream
     |from()
         .measurement('test')
-    |window()
-        .period(period)
-        .every(period)
-    |count(field)
-    |httpOut('count')
 `
-	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
-		ID:         templateId,
-		Type:       ttype,
-		TICKscript: tickCorrect,
-	})
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	// Create several tasks
-	count := 5
+	// Create a bunch of tasks with every 3rd task enabled
+	count := 100
+	enabled := 0
 	tasks := make([]client.Task, count)
 	for i := 0; i < count; i++ {
-		if task, err := cli.CreateTask(client.CreateTaskOptions{
-			ID:         fmt.Sprintf("%s-%d", taskId, i),
-			TemplateID: templateId,
+		status := client.Disabled
+		if i%3 == 0 {
+			enabled++
+			status = client.Enabled
+		}
+		task, err := cli.CreateTask(client.CreateTaskOptions{
+			ID:         fmt.Sprintf("%s-%d", id, i),
+			Type:       ttype,
 			DBRPs:      dbrps,
-			Status:     client.Enabled,
-			Vars: client.Vars{
-				"field": {
-					Value: "value",
-					Type:  client.VarString,
-				},
-			},
-		}); err != nil {
+			TICKscript: tick,
+			Status:     status,
+		})
+		if err != nil {
 			t.Fatal(err)
-		} else {
-			tasks[i] = task
+		}
+		tasks[i] = task
+	}
+	if stats, err := s.Stats(); err != nil {
+		t.Fatal(err)
+	} else {
+		if got, exp := stats.NumTasks, count; got != exp {
+			t.Errorf("unexpected num_tasks got %d exp %d", got, exp)
+		}
+		if got, exp := stats.NumEnabledTasks, enabled; got != exp {
+			t.Errorf("unexpected num_enabled_tasks got %d exp %d", got, exp)
 		}
 	}
 
-	if _, err := cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
-		TICKscript: tickNewVar,
-	}); err == nil {
-		t.Error("expected error for breaking template update, got nil")
-	} else if got, exp := err.Error(), `error reloading associated task testStreamTask-0: missing value for var "period".`; exp != got {
-		t.Errorf("unexpected error for breaking template update, got %s exp %s", got, exp)
+	// Enable a bunch of tasks
+	for i, task := range tasks {
+		if i%2 == 0 && task.Status != client.Enabled {
+			enabled++
+			tasks[i].Status = client.Enabled
+			if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+				Status: client.Enabled,
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
 	}
 
-	// Get all tasks and make sure their TICKscript has the original value
-	for _, task := range tasks {
-		if gotTask, err := cli.Task(task.Link, &client.TaskOptions{ScriptFormat: "raw"}); err != nil {
-			t.Fatal(err)
-		} else if got, exp := gotTask.TICKscript, tickCorrect; got != exp {
-			t.Errorf("unexpected task TICKscript:\ngot\n%s\nexp\n%s\n", got, exp)
+	if stats, err := s.Stats(); err != nil {
+		t.Fatal(err)
+	} else {
+		if got, exp := stats.NumTasks, count; got != exp {
+			t.Errorf("unexpected num_tasks got %d exp %d", got, exp)
+		}
+		if got, exp := stats.NumEnabledTasks, enabled; got != exp {
+			t.Errorf("unexpected num_enabled_tasks got %d exp %d", got, exp)
 		}
 	}
 
-	// Update all tasks with new var
-	for _, task := range tasks {
-		if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-			Vars: client.Vars{
-				"field": {
-					Value: "value",
-					Type:  client.VarString,
-				},
-				"period": {
-					Value: 10 * time.Second,
-					Type:  client.VarDuration,
-				},
-			},
-		}); err != nil {
-			t.Fatal(err)
+	// Disable a bunch of tasks
+	for i, task := range tasks {
+		if i%5 == 0 && task.Status != client.Disabled {
+			enabled--
+			tasks[i].Status = client.Disabled
+			if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+				Status: client.Disabled,
+			}); err != nil {
+				t.Fatal(err)
+			}
 		}
 	}
 
-	// Now update template should succeed since the tasks are updated too.
-	if _, err := cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
-		TICKscript: tickNewVar,
-	}); err != nil {
+	if stats, err := s.Stats(); err != nil {
 		t.Fatal(err)
+	} else {
+		if got, exp := stats.NumTasks, count; got != exp {
+			t.Errorf("unexpected num_tasks got %d exp %d", got, exp)
+		}
+		if got, exp := stats.NumEnabledTasks, enabled; got != exp {
+			t.Errorf("unexpected num_enabled_tasks got %d exp %d", got, exp)
+		}
 	}
 
-	for _, task := range tasks {
-		taskId := task.ID
-		endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), taskId)
+	// Delete a bunch of tasks
+	for i, task := range tasks {
+		if i%6 == 0 {
+			count--
+			if task.Status == client.Enabled {
+				enabled--
+			}
+			if err := cli.DeleteTask(task.Link); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
 
-		// Request data before any writes and expect null responses
-		nullResponse := `{"series":null}`
-		if err := s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5); err != nil {
-			t.Error(err)
+	if stats, err := s.Stats(); err != nil {
+		t.Fatal(err)
+	} else {
+		if got, exp := stats.NumTasks, count; got != exp {
+			t.Errorf("unexpected num_tasks got %d exp %d", got, exp)
+		}
+		if got, exp := stats.NumEnabledTasks, enabled; got != exp {
+			t.Errorf("unexpected num_enabled_tasks got %d exp %d", got, exp)
 		}
 	}
 
-	points := `test value=1 0000000000
-test value=1 0000000001
-test value=1 0000000001
-test value=1 0000000002
-test value=1 0000000002
-test value=1 0000000003
-test value=1 0000000003
-test value=1 0000000004
-test value=1 0000000005
-test value=1 0000000005
-test value=1 0000000005
-test value=1 0000000006
-test value=1 0000000007
-test value=1 0000000008
-test value=1 0000000009
-test value=1 0000000010
-test value=1 0000000011
-`
-	v := url.Values{}
-	v.Add("precision", "s")
-	s.MustWrite("mydb", "myrp", points, v)
+}
+func TestServer_ListTasks(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+	count := 10
 
-	for _, task := range tasks {
-		taskId := task.ID
-		endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), taskId)
+	ttype := client.StreamTask
+	tick := `stream
+    |from()
+        .measurement('test')
+`
 
-		exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
-		if err := s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5); err != nil {
-			t.Error(err)
+	dbrps := []client.DBRP{
+		{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		},
+		{
+			Database:        "otherdb",
+			RetentionPolicy: "default",
+		},
+	}
+	for i := 0; i < count; i++ {
+		id := fmt.Sprintf("testTaskID%d", i)
+		status := client.Disabled
+		if i%2 == 0 {
+			status = client.Enabled
+		}
+		_, err := cli.CreateTask(client.CreateTaskOptions{
+			ID:         id,
+			Type:       ttype,
+			DBRPs:      dbrps,
+			TICKscript: tick,
+			Status:     status,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tasks, err := cli.ListTasks(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := count, len(tasks); exp != got {
+		t.Fatalf("unexpected number of tasks: exp:%d got:%d", exp, got)
+	}
+	for i, task := range tasks {
+		if exp, got := fmt.Sprintf("testTaskID%d", i), task.ID; exp != got {
+			t.Errorf("unexpected task.ID i:%d exp:%s got:%s", i, exp, got)
+		}
+		if exp, got := client.StreamTask, task.Type; exp != got {
+			t.Errorf("unexpected task.Type i:%d exp:%v got:%v", i, exp, got)
+		}
+		if !reflect.DeepEqual(task.DBRPs, dbrps) {
+			t.Fatalf("unexpected dbrps i:%d exp:%s got:%s", i, dbrps, task.DBRPs)
+		}
+		exp := client.Disabled
+		if i%2 == 0 {
+			exp = client.Enabled
+		}
+		if got := task.Status; exp != got {
+			t.Errorf("unexpected task.Status i:%d exp:%v got:%v", i, exp, got)
+		}
+		if exp, got := i%2 == 0, task.Executing; exp != got {
+			t.Errorf("unexpected task.Executing i:%d exp:%v got:%v", i, exp, got)
+		}
+		if exp, got := true, len(task.Dot) != 0; exp != got {
+			t.Errorf("unexpected task.Dot i:%d exp:\n%v\ngot:\n%v\n", i, exp, got)
+		}
+		if exp, got := tick, task.TICKscript; exp != got {
+			t.Errorf("unexpected task.TICKscript i:%d exp:%v got:%v", i, exp, got)
+		}
+		if exp, got := "", task.Error; exp != got {
+			t.Errorf("unexpected task.Error i:%d exp:%v got:%v", i, exp, got)
 		}
 	}
+
 }
 
-func TestServer_UpdateTaskID(t *testing.T) {
+func TestServer_ListTasks_Fields(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
+	count := 100
 
-	id := "testTaskID"
 	ttype := client.StreamTask
+	tick := `stream
+    |from()
+        .measurement('test')
+`
 	dbrps := []client.DBRP{
 		{
 			Database:        "mydb",
@@ -2687,122 +2936,210 @@ func TestServer_UpdateTaskID(t *testing.T) {
 			RetentionPolicy: "default",
 		},
 	}
-	tick := `stream
-    |from()
-        .measurement('test')
-`
-	task, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         id,
-		Type:       ttype,
-		DBRPs:      dbrps,
-		TICKscript: tick,
-		Status:     client.Disabled,
+	for i := 0; i < count; i++ {
+		id := fmt.Sprintf("testTaskID%d", i)
+		_, err := cli.CreateTask(client.CreateTaskOptions{
+			ID:         id,
+			Type:       ttype,
+			DBRPs:      dbrps,
+			TICKscript: tick,
+			Status:     client.Enabled,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tasks, err := cli.ListTasks(&client.ListTasksOptions{
+		Pattern: "testTaskID1*",
+		Fields:  []string{"type", "status"},
+		Offset:  1,
+		Limit:   5,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	ti, err := cli.Task(task.Link, nil)
-	if err != nil {
-		t.Fatal(err)
+	if exp, got := 5, len(tasks); exp != got {
+		t.Fatalf("unexpected number of tasks: exp:%d got:%d", exp, got)
+	}
+	for i, task := range tasks {
+		if exp, got := fmt.Sprintf("testTaskID1%d", i), task.ID; exp != got {
+			t.Errorf("unexpected task.ID i:%d exp:%s got:%s", i, exp, got)
+		}
+		if exp, got := client.StreamTask, task.Type; exp != got {
+			t.Errorf("unexpected task.Type i:%d exp:%v got:%v", i, exp, got)
+		}
+		if exp, got := client.Enabled, task.Status; exp != got {
+			t.Errorf("unexpected task.Status i:%d exp:%v got:%v", i, exp, got)
+		}
+		// We didn't request these fields so they should be default zero values
+		if exp, got := 0, len(task.DBRPs); exp != got {
+			t.Fatalf("unexpected dbrps i:%d exp:%d got:%d", i, exp, got)
+		}
+		if exp, got := false, task.Executing; exp != got {
+			t.Errorf("unexpected task.Executing i:%d exp:%v got:%v", i, exp, got)
+		}
+		if exp, got := "", task.Dot; exp != got {
+			t.Errorf("unexpected task.Dot i:%d exp:%v got:%v", i, exp, got)
+		}
+		if exp, got := "", task.TICKscript; exp != got {
+			t.Errorf("unexpected task.TICKscript i:%d exp:%v got:%v", i, exp, got)
+		}
+		if exp, got := "", task.Error; exp != got {
+			t.Errorf("unexpected task.Error i:%d exp:%v got:%v", i, exp, got)
+		}
 	}
+}
 
-	if ti.Error != "" {
-		t.Fatal(ti.Error)
+func TestServer_Bundle(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	if err := cli.ConfigUpdate(cli.ConfigElementLink("smtp", ""), client.ConfigUpdateAction{
+		Set: map[string]interface{}{"from": "kapacitor@example.com", "password": "secret"},
+	}); err != nil {
+		t.Fatal(err)
 	}
-	if ti.ID != id {
-		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	if err := cli.ConfigUpdate(cli.ConfigSectionLink("kubernetes"), client.ConfigUpdateAction{
+		Add: map[string]interface{}{"id": "k8s", "enabled": false},
+	}); err != nil {
+		t.Fatal(err)
 	}
-	if ti.Type != client.StreamTask {
-		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	if _, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:         "template",
+		TICKscript: "var warn = 80.0\n\nstream\n    |from()\n        .measurement('cpu')\n    |where(lambda: \"value\" > warn)\n",
+	}); err != nil {
+		t.Fatal(err)
 	}
-	if ti.Status != client.Disabled {
-		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+	dbrps := []client.DBRP{{Database: "db", RetentionPolicy: "rp"}}
+	priority := 5
+	tasks := []client.CreateTaskOptions{
+		{
+			ID:         "enabled",
+			Type:       client.StreamTask,
+			DBRPs:      dbrps,
+			TICKscript: "stream\n    |from()\n        .measurement('mem')\n",
+			Status:     client.Enabled,
+			Labels:     client.Labels{"team": "ops"},
+			Priority:   &priority,
+		},
+		{
+			ID:         "templated",
+			TemplateID: "template",
+			DBRPs:      dbrps,
+			Status:     client.Disabled,
+			Vars:       client.Vars{"warn": {Type: client.VarFloat, Value: 90.0}},
+		},
 	}
-	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
-		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	for _, o := range tasks {
+		if _, err := cli.CreateTask(o); err != nil {
+			t.Fatal(err)
+		}
 	}
-	if ti.TICKscript != tick {
-		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+	handler := client.TopicHandlerOptions{
+		Topic: "cpu",
+		ID:    "log",
+		Kind:  "log",
+		Options: map[string]interface{}{
+			"path": "/tmp/alerts.log",
+		},
 	}
-	dot := "digraph testTaskID {\nstream0 -> from1;\n}"
-	if ti.Dot != dot {
-		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	if _, err := cli.CreateTopicHandler(cli.TopicHandlersLink(handler.Topic), handler); err != nil {
+		t.Fatal(err)
 	}
 
-	newID := "newTaskID"
-	task, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-		ID: newID,
-	})
+	bundle, err := cli.ExportBundle()
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	if got, exp := task.Link.Href, "/kapacitor/v1/tasks/newTaskID"; got != exp {
-		t.Fatalf("unexpected task link got %s exp %s", got, exp)
+	exp := []client.ConfigOverride{
+		{
+			Section: "kubernetes",
+			Element: "k8s",
+			Create:  true,
+			Options: map[string]interface{}{"id": "k8s", "enabled": false},
+		},
+		{
+			Section: "smtp",
+			Options: map[string]interface{}{"from": "kapacitor@example.com", "password": "secret"},
+		},
+	}
+	if !reflect.DeepEqual(bundle.ConfigOverrides, exp) {
+		t.Errorf("unexpected config overrides got %+v exp %+v", bundle.ConfigOverrides, exp)
+	}
+	if len(bundle.Templates) != 1 || len(bundle.Tasks) != 2 || len(bundle.TopicHandlers) != 1 {
+		t.Fatalf("unexpected bundle %+v", bundle)
 	}
 
-	ti, err = cli.Task(task.Link, nil)
-	if err != nil {
+	// Import the bundle into another server
+	other, otherCli := OpenDefaultServer()
+	defer other.Close()
+	if _, err := otherCli.CreateTask(client.CreateTaskOptions{
+		ID:         "enabled",
+		Type:       client.StreamTask,
+		DBRPs:      dbrps,
+		TICKscript: "stream\n    |from()\n        .measurement('other')\n",
+		Status:     client.Disabled,
+	}); err != nil {
 		t.Fatal(err)
 	}
+	// Importing again replaces the definitions
+	for i := 0; i < 2; i++ {
+		if err := otherCli.ImportBundle(bundle); err != nil {
+			t.Fatal(err)
+		}
+	}
 
-	if ti.Error != "" {
-		t.Fatal(ti.Error)
+	imported, err := otherCli.ExportBundle()
+	if err != nil {
+		t.Fatal(err)
 	}
-	if ti.ID != newID {
-		t.Fatalf("unexpected id got %s exp %s", ti.ID, newID)
+	imported.Created = bundle.Created
+	if !reflect.DeepEqual(imported, bundle) {
+		t.Errorf("unexpected imported definitions\ngot\n%+v\nexp\n%+v", imported, bundle)
 	}
-	if ti.Type != client.StreamTask {
-		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	task, err := otherCli.Task(otherCli.TaskLink("enabled"), nil)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if ti.Status != client.Disabled {
-		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+	if !task.Executing {
+		t.Error("expected imported enabled task to be executing")
 	}
-	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
-		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	smtp, err := otherCli.ConfigElement(otherCli.ConfigElementLink("smtp", ""))
+	if err != nil {
+		t.Fatal(err)
 	}
-	if ti.TICKscript != tick {
-		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+	if got := smtp.Options["from"]; got != "kapacitor@example.com" {
+		t.Errorf("unexpected smtp from got %v", got)
 	}
-	dot = "digraph newTaskID {\nstream0 -> from1;\n}"
-	if ti.Dot != dot {
-		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+
+	bundle.Version = 2
+	if err := otherCli.ImportBundle(bundle); err == nil || err.Error() != "unsupported bundle version 2, expected 1" {
+		t.Errorf("unexpected error %v", err)
 	}
 }
-func TestServer_UpdateTaskID_Fail(t *testing.T) {
+
+func TestServer_CreateTemplate(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
-	id := "testTaskID"
-	newID := "anotherTaskID"
+	id := "testTemplateID"
 	ttype := client.StreamTask
-	dbrps := []client.DBRP{
-		{
-			Database:        "mydb",
-			RetentionPolicy: "myrp",
-		},
-		{
-			Database:        "otherdb",
-			RetentionPolicy: "default",
-		},
-	}
-	tick := `stream
+	tick := `var x = 5
+
+stream
     |from()
         .measurement('test')
 `
-	task, err := cli.CreateTask(client.CreateTaskOptions{
+	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
 		ID:         id,
 		Type:       ttype,
-		DBRPs:      dbrps,
 		TICKscript: tick,
-		Status:     client.Disabled,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	ti, err := cli.Task(task.Link, nil)
+	ti, err := cli.Template(template.Link, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2816,97 +3153,151 @@ func TestServer_UpdateTaskID_Fail(t *testing.T) {
 	if ti.Type != client.StreamTask {
 		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
 	}
-	if ti.Status != client.Disabled {
-		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
-	}
-	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
-		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
-	}
 	if ti.TICKscript != tick {
-		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+		t.Fatalf("unexpected TICKscript got\n%s\nexp\n%s\n", ti.TICKscript, tick)
 	}
-	dot := "digraph testTaskID {\nstream0 -> from1;\n}"
+	dot := "digraph testTemplateID {\nstream0 -> from1;\n}"
 	if ti.Dot != dot {
 		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
 	}
+	vars := client.Vars{"x": {Value: int64(5), Type: client.VarInt}}
+	if !reflect.DeepEqual(vars, ti.Vars) {
+		t.Fatalf("unexpected vars\ngot\n%s\nexp\n%s\n", ti.Vars, vars)
+	}
+}
 
-	// Create conflicting task
-	if _, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         newID,
-		Type:       ttype,
-		DBRPs:      dbrps,
-		TICKscript: tick,
-		Status:     client.Disabled,
-	}); err != nil {
-		t.Fatal(err)
+func TestServer_TemplateSchema(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	tick := `var warn = 80.0
+var field = 'usage_idle'
+
+stream
+    |from()
+        .measurement('cpu')
+    |where(lambda: field > warn)
+`
+	max := 100.0
+	min := 0.0
+	schema := map[string]client.VarSchema{
+		"warn":  {Min: &min, Max: &max},
+		"field": {Enum: []interface{}{"usage_idle", "usage_user"}},
 	}
 
-	if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-		ID: newID,
-	}); err == nil {
-		t.Fatal("expected error on name conflict")
+	// The default value must match the schema
+	low := 50.0
+	_, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:         "template",
+		TICKscript: tick,
+		Schema:     map[string]client.VarSchema{"warn": {Max: &low}},
+	})
+	if exp := `invalid schema: default value does not match the schema: invalid value for var "warn": 80 is greater than the maximum 50`; err == nil || err.Error() != exp {
+		t.Fatalf("unexpected error got %v exp %q", err, exp)
 	}
 
-	// Can still get old task
-	ti, err = cli.Task(task.Link, nil)
+	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:         "template",
+		TICKscript: tick,
+		Schema:     schema,
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
+	if !reflect.DeepEqual(template.Schema, schema) {
+		t.Fatalf("unexpected schema got %v exp %v", template.Schema, schema)
+	}
 
-	if ti.Error != "" {
-		t.Fatal(ti.Error)
+	for _, tc := range []struct {
+		vars client.Vars
+		exp  string
+	}{
+		{
+			vars: client.Vars{"warn": {Type: client.VarFloat, Value: 120.0}},
+			exp:  `invalid value for var "warn": 120 is greater than the maximum 100`,
+		},
+		{
+			vars: client.Vars{"field": {Type: client.VarString, Value: "usage_system"}},
+			exp:  `invalid value for var "field": "usage_system" is not one of "usage_idle", "usage_user"`,
+		},
+	} {
+		_, err := cli.CreateTask(client.CreateTaskOptions{
+			ID:         "task",
+			TemplateID: template.ID,
+			DBRPs:      []client.DBRP{{Database: "db", RetentionPolicy: "rp"}},
+			Vars:       tc.vars,
+		})
+		if err == nil || err.Error() != tc.exp {
+			t.Errorf("%v: unexpected error got %v exp %q", tc.vars, err, tc.exp)
+		}
 	}
-	if ti.ID != id {
-		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "task",
+		TemplateID: template.ID,
+		DBRPs:      []client.DBRP{{Database: "db", RetentionPolicy: "rp"}},
+		Vars:       client.Vars{"warn": {Type: client.VarFloat, Value: 90.0}},
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
-	if ti.Type != client.StreamTask {
-		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+
+	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Vars: client.Vars{"warn": {Type: client.VarFloat, Value: -1.0}},
+	})
+	if exp := `invalid value for var "warn": -1 is less than the minimum 0`; err == nil || err.Error() != exp {
+		t.Errorf("unexpected error got %v exp %q", err, exp)
 	}
-	if ti.Status != client.Disabled {
-		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+
+	// A schema that does not match the vars of an associated task is rejected
+	_, err = cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
+		Schema: map[string]client.VarSchema{"warn": {Max: &max, Min: &low}, "field": schema["field"]},
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
-	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
-		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	high := 85.0
+	_, err = cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
+		Schema: map[string]client.VarSchema{"warn": {Max: &high}},
+	})
+	if exp := `associated task task does not match the schema: invalid value for var "warn": 90 is greater than the maximum 85`; err == nil || err.Error() != exp {
+		t.Errorf("unexpected error got %v exp %q", err, exp)
 	}
-	if ti.TICKscript != tick {
-		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+
+	// An empty schema removes it
+	template, err = cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
+		Schema: map[string]client.VarSchema{},
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
-	if ti.Dot != dot {
-		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	if template.Schema != nil {
+		t.Errorf("unexpected schema %v", template.Schema)
 	}
 }
-func TestServer_UpdateTaskID_Enabled(t *testing.T) {
+
+func TestServer_UpdateTemplateID(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
-	id := "testTaskID"
+	id := "testTemplateID"
 	ttype := client.StreamTask
-	dbrps := []client.DBRP{
-		{
-			Database:        "mydb",
-			RetentionPolicy: "myrp",
-		},
-		{
-			Database:        "otherdb",
-			RetentionPolicy: "default",
-		},
-	}
-	tick := `stream
+	tick := `var x = 5
+
+stream
     |from()
         .measurement('test')
 `
-	task, err := cli.CreateTask(client.CreateTaskOptions{
+	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
 		ID:         id,
 		Type:       ttype,
-		DBRPs:      dbrps,
 		TICKscript: tick,
-		Status:     client.Enabled,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	ti, err := cli.Task(task.Link, nil)
+	ti, err := cli.Template(template.Link, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2920,32 +3311,31 @@ func TestServer_UpdateTaskID_Enabled(t *testing.T) {
 	if ti.Type != client.StreamTask {
 		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
 	}
-	if ti.Status != client.Enabled {
-		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Enabled)
-	}
-	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
-		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
-	}
 	if ti.TICKscript != tick {
-		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+		t.Fatalf("unexpected TICKscript got\n%s\nexp\n%s\n", ti.TICKscript, tick)
 	}
-	if !ti.Executing {
-		t.Fatal("expected task to be executing")
+	dot := "digraph testTemplateID {\nstream0 -> from1;\n}"
+	if ti.Dot != dot {
+		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	}
+	vars := client.Vars{"x": {Value: int64(5), Type: client.VarInt}}
+	if !reflect.DeepEqual(vars, ti.Vars) {
+		t.Fatalf("unexpected vars\ngot\n%s\nexp\n%s\n", ti.Vars, vars)
 	}
 
-	newID := "newTaskID"
-	task, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+	newID := "newTemplateID"
+	template, err = cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
 		ID: newID,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if got, exp := task.Link.Href, "/kapacitor/v1/tasks/newTaskID"; got != exp {
-		t.Fatalf("unexpected task link got %s exp %s", got, exp)
+	if got, exp := template.Link.Href, "/kapacitor/v1/templates/newTemplateID"; got != exp {
+		t.Fatalf("unexpected template link got %s exp %s", got, exp)
 	}
 
-	ti, err = cli.Task(task.Link, nil)
+	ti, err = cli.Template(template.Link, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2959,433 +3349,3921 @@ func TestServer_UpdateTaskID_Enabled(t *testing.T) {
 	if ti.Type != client.StreamTask {
 		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
 	}
-	if ti.Status != client.Enabled {
-		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Enabled)
-	}
-	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
-		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
-	}
 	if ti.TICKscript != tick {
-		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+		t.Fatalf("unexpected TICKscript got\n%s\nexp\n%s\n", ti.TICKscript, tick)
 	}
-	if !ti.Executing {
-		t.Fatal("expected task to be executing")
+	dot = "digraph newTemplateID {\nstream0 -> from1;\n}"
+	if ti.Dot != dot {
+		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	}
+	if !reflect.DeepEqual(vars, ti.Vars) {
+		t.Fatalf("unexpected vars\ngot\n%s\nexp\n%s\n", ti.Vars, vars)
 	}
 }
 
-func TestServer_StreamTask_AllMeasurements(t *testing.T) {
+func TestServer_CreateTemplateImplicitAndUpdateExplicitWithTasks(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
 
-	id := "testStreamTask"
-	ttype := client.StreamTask
-	dbrps := []client.DBRP{{
-		Database:        "mydb",
-		RetentionPolicy: "myrp",
-	}}
-	tick := `stream
+	id := "testTemplateID"
+	implicitTick := `dbrp "telegraf"."autogen"
+
+var x = 5
+
+stream
     |from()
-    |window()
-        .period(10s)
-        .every(10s)
-    |count('value')
-    |httpOut('count')
+        .measurement('test')
 `
-
-	task, err := cli.CreateTask(client.CreateTaskOptions{
+	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
 		ID:         id,
-		Type:       ttype,
-		DBRPs:      dbrps,
-		TICKscript: tick,
-		Status:     client.Disabled,
+		TICKscript: implicitTick,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-		Status: client.Enabled,
-	})
+	ti, err := cli.Template(template.Link, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), id)
-
-	// Request data before any writes and expect null responses
-	nullResponse := `{"series":null}`
-	err = s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5)
-	if err != nil {
-		t.Error(err)
+	if ti.Error != "" {
+		t.Fatal(ti.Error)
 	}
-
-	points := `test0 value=1 0000000000
-test1 value=1 0000000001
-test0 value=1 0000000001
-test1 value=1 0000000002
-test0 value=1 0000000002
-test1 value=1 0000000003
-test0 value=1 0000000003
-test1 value=1 0000000004
-test0 value=1 0000000005
-test1 value=1 0000000005
-test0 value=1 0000000005
-test1 value=1 0000000006
-test0 value=1 0000000007
-test1 value=1 0000000008
-test0 value=1 0000000009
-test1 value=1 0000000010
-test0 value=1 0000000011
-`
-	v := url.Values{}
-	v.Add("precision", "s")
-	s.MustWrite("mydb", "myrp", points, v)
-
-	exp := `{"series":[{"name":"test0","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
-	err = s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5)
-	if err != nil {
-		t.Error(err)
+	if ti.ID != id {
+		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	}
+	if ti.Type != client.StreamTask {
+		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	}
+	if ti.TICKscript != implicitTick {
+		t.Fatalf("unexpected TICKscript got\n%s\nexp\n%s\n", ti.TICKscript, implicitTick)
+	}
+	dot := "digraph testTemplateID {\nstream0 -> from1;\n}"
+	if ti.Dot != dot {
+		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	}
+	vars := client.Vars{"x": {Value: int64(5), Type: client.VarInt}}
+	if !reflect.DeepEqual(vars, ti.Vars) {
+		t.Fatalf("unexpected vars\ngot\n%s\nexp\n%s\n", ti.Vars, vars)
 	}
-}
 
-func TestServer_BatchTask(t *testing.T) {
-	c := NewConfig()
-	c.InfluxDB[0].Enabled = true
-	count := 0
-	stopTimeC := make(chan time.Time, 1)
+	implicitDBRPs := []client.DBRP{
+		{
+			Database:        "telegraf",
+			RetentionPolicy: "autogen",
+		},
+	}
 
-	db := NewInfluxDB(func(q string) *iclient.Response {
-		stmt, err := influxql.ParseStatement(q)
+	count := 1
+	tasks := make([]client.Task, count)
+	for i := 0; i < count; i++ {
+		task, err := cli.CreateTask(client.CreateTaskOptions{
+			TemplateID: template.ID,
+			Status:     client.Enabled,
+		})
 		if err != nil {
-			return &iclient.Response{Err: err.Error()}
-		}
-		slct, ok := stmt.(*influxql.SelectStatement)
-		if !ok {
-			return nil
+			t.Fatal(err)
 		}
-		cond, ok := slct.Condition.(*influxql.BinaryExpr)
-		if !ok {
-			return &iclient.Response{Err: "expected select condition to be binary expression"}
+		tasks[i] = task
+
+		ti, err := cli.Task(task.Link, nil)
+		if err != nil {
+			t.Fatal(err)
 		}
-		stopTimeExpr, ok := cond.RHS.(*influxql.BinaryExpr)
-		if !ok {
-			return &iclient.Response{Err: "expected select condition rhs to be binary expression"}
+
+		if !reflect.DeepEqual(ti.DBRPs, implicitDBRPs) {
+			t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, implicitDBRPs)
 		}
-		stopTL, ok := stopTimeExpr.RHS.(*influxql.StringLiteral)
-		if !ok {
-			return &iclient.Response{Err: "expected select condition rhs to be string literal"}
+	}
+
+	updateTick := `var x = 5
+
+	stream
+	    |from()
+	        .measurement('test')
+	`
+
+	_, err = cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
+		ID:         id,
+		TICKscript: updateTick,
+	})
+	// Expects error
+	if err == nil {
+		t.Fatal(err)
+	}
+
+	finalTick := `dbrp "telegraf"."autogen"
+
+	dbrp "telegraf"."not_autogen"
+
+	var x = 5
+
+	stream
+	    |from()
+	        .measurement('test')
+	`
+
+	finalDBRPs := []client.DBRP{
+		{
+			Database:        "telegraf",
+			RetentionPolicy: "autogen",
+		},
+		{
+			Database:        "telegraf",
+			RetentionPolicy: "not_autogen",
+		},
+	}
+	template, err = cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
+		ID:         id,
+		TICKscript: finalTick,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, task := range tasks {
+		ti, err := cli.Task(task.Link, nil)
+		if err != nil {
+			t.Fatal(err)
 		}
-		count++
-		switch count {
-		case 1:
-			stopTime, err := time.Parse(time.RFC3339Nano, stopTL.Val)
-			if err != nil {
-				return &iclient.Response{Err: err.Error()}
-			}
-			stopTimeC <- stopTime
-			return &iclient.Response{
-				Results: []iclient.Result{{
-					Series: []imodels.Row{{
-						Name:    "cpu",
-						Columns: []string{"time", "value"},
-						Values: [][]interface{}{
-							{
-								stopTime.Add(-2 * time.Millisecond).Format(time.RFC3339Nano),
-								1.0,
-							},
-							{
-								stopTime.Add(-1 * time.Millisecond).Format(time.RFC3339Nano),
-								1.0,
-							},
-						},
-					}},
-				}},
-			}
-		default:
-			return &iclient.Response{
-				Results: []iclient.Result{{
-					Series: []imodels.Row{{
-						Name:    "cpu",
-						Columns: []string{"time", "value"},
-						Values:  [][]interface{}{},
-					}},
-				}},
-			}
+
+		if !reflect.DeepEqual(ti.DBRPs, finalDBRPs) {
+			t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, finalDBRPs)
 		}
-	})
-	c.InfluxDB[0].URLs = []string{db.URL()}
-	s := OpenServer(c)
+	}
+}
+func TestServer_UpdateTemplateID_WithTasks(t *testing.T) {
+	s, cli := OpenDefaultServer()
 	defer s.Close()
-	cli := Client(s)
 
-	id := "testBatchTask"
-	ttype := client.BatchTask
-	dbrps := []client.DBRP{{
-		Database:        "mydb",
-		RetentionPolicy: "myrp",
-	}}
-	tick := `batch
-    |query('SELECT value from mydb.myrp.cpu')
-        .period(5ms)
-        .every(5ms)
-        .align()
-    |count('value')
-    |where(lambda: "count" == 2)
-    |httpOut('count')
+	id := "testTemplateID"
+	ttype := client.StreamTask
+	tick := `var x = 5
+
+stream
+    |from()
+        .measurement('test')
 `
+	dbrps := []client.DBRP{
+		{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		},
+		{
+			Database:        "otherdb",
+			RetentionPolicy: "default",
+		},
+	}
 
-	task, err := cli.CreateTask(client.CreateTaskOptions{
+	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
 		ID:         id,
 		Type:       ttype,
-		DBRPs:      dbrps,
 		TICKscript: tick,
-		Status:     client.Disabled,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-		Status: client.Enabled,
+	count := 100
+	tasks := make([]client.Task, count)
+	for i := 0; i < count; i++ {
+		task, err := cli.CreateTask(client.CreateTaskOptions{
+			TemplateID: template.ID,
+			DBRPs:      dbrps,
+			Status:     client.Enabled,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tasks[i] = task
+	}
+
+	newID := "newTemplateID"
+	template, err = cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
+		ID: newID,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), id)
-
-	timeout := time.NewTicker(100 * time.Millisecond)
-	defer timeout.Stop()
-	select {
-	case <-timeout.C:
-		t.Fatal("timedout waiting for query")
-	case stopTime := <-stopTimeC:
-		exp := fmt.Sprintf(`{"series":[{"name":"cpu","columns":["time","count"],"values":[["%s",2]]}]}`, stopTime.Local().Format(time.RFC3339Nano))
-		err = s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5)
-		if err != nil {
-			t.Error(err)
-		}
-		_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-			Status: client.Disabled,
-		})
+	for _, task := range tasks {
+		got, err := cli.Task(task.Link, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
+		if got.TemplateID != newID {
+			t.Errorf("unexpected task TemplateID got %s exp %s", got.TemplateID, newID)
+		}
+		if got.TICKscript != tick {
+			t.Errorf("unexpected task TICKscript got %s exp %s", got.TICKscript, tick)
+		}
 	}
 }
-func TestServer_BatchTask_InfluxDBConfigUpdate(t *testing.T) {
-	c := NewConfig()
-	c.InfluxDB[0].Enabled = true
-	count := 0
-	stopTimeC := make(chan time.Time, 1)
+func TestServer_UpdateTemplateID_Fail(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
 
-	badCount := 0
+	id := "testTemplateID"
+	newID := "anotherTemplateID"
+	ttype := client.StreamTask
+	tick := `var x = 5
 
-	dbBad := NewInfluxDB(func(q string) *iclient.Response {
-		badCount++
-		// Return empty results
-		return &iclient.Response{
-			Results: []iclient.Result{},
-		}
+stream
+    |from()
+        .measurement('test')
+`
+	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:         id,
+		Type:       ttype,
+		TICKscript: tick,
 	})
-	defer dbBad.Close()
-	db := NewInfluxDB(func(q string) *iclient.Response {
-		stmt, err := influxql.ParseStatement(q)
-		if err != nil {
-			return &iclient.Response{Err: err.Error()}
-		}
-		slct, ok := stmt.(*influxql.SelectStatement)
-		if !ok {
-			return nil
-		}
-		cond, ok := slct.Condition.(*influxql.BinaryExpr)
-		if !ok {
-			return &iclient.Response{Err: "expected select condition to be binary expression"}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ti, err := cli.Template(template.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ti.Error != "" {
+		t.Fatal(ti.Error)
+	}
+	if ti.ID != id {
+		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	}
+	if ti.Type != client.StreamTask {
+		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	}
+	if ti.TICKscript != tick {
+		t.Fatalf("unexpected TICKscript got\n%s\nexp\n%s\n", ti.TICKscript, tick)
+	}
+	dot := "digraph testTemplateID {\nstream0 -> from1;\n}"
+	if ti.Dot != dot {
+		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	}
+	vars := client.Vars{"x": {Value: int64(5), Type: client.VarInt}}
+	if !reflect.DeepEqual(vars, ti.Vars) {
+		t.Fatalf("unexpected vars\ngot\n%s\nexp\n%s\n", ti.Vars, vars)
+	}
+
+	// Create conflicting template
+	if _, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:         newID,
+		Type:       ttype,
+		TICKscript: tick,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
+		ID: newID,
+	}); err == nil {
+		t.Fatal("expected update template to fail on name conflict")
+	}
+
+	// Can still get old template
+	ti, err = cli.Template(template.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ti.Error != "" {
+		t.Fatal(ti.Error)
+	}
+	if ti.ID != id {
+		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	}
+	if ti.Type != client.StreamTask {
+		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	}
+	if ti.TICKscript != tick {
+		t.Fatalf("unexpected TICKscript got\n%s\nexp\n%s\n", ti.TICKscript, tick)
+	}
+	if ti.Dot != dot {
+		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	}
+	if !reflect.DeepEqual(vars, ti.Vars) {
+		t.Fatalf("unexpected vars\ngot\n%s\nexp\n%s\n", ti.Vars, vars)
+	}
+}
+func TestServer_UpdateTemplateID_WithTasks_Fail(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testTemplateID"
+	ttype := client.StreamTask
+	tick := `var x = 5
+
+stream
+    |from()
+        .measurement('test')
+`
+	dbrps := []client.DBRP{
+		{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		},
+		{
+			Database:        "otherdb",
+			RetentionPolicy: "default",
+		},
+	}
+
+	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:         id,
+		Type:       ttype,
+		TICKscript: tick,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := 100
+	tasks := make([]client.Task, count)
+	for i := 0; i < count; i++ {
+		task, err := cli.CreateTask(client.CreateTaskOptions{
+			TemplateID: template.ID,
+			DBRPs:      dbrps,
+			Status:     client.Enabled,
+		})
+		if err != nil {
+			t.Fatal(err)
 		}
-		stopTimeExpr, ok := cond.RHS.(*influxql.BinaryExpr)
-		if !ok {
-			return &iclient.Response{Err: "expected select condition rhs to be binary expression"}
+		tasks[i] = task
+	}
+
+	// Create conflicting template
+	newID := "newTemplateID"
+	if _, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:         newID,
+		Type:       ttype,
+		TICKscript: tick,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
+		ID:         newID,
+		TICKscript: "stream",
+	}); err == nil {
+		t.Fatal("expected update template to fail on conflicting name")
+	}
+
+	for _, task := range tasks {
+		got, err := cli.Task(task.Link, nil)
+		if err != nil {
+			t.Fatal(err)
 		}
-		stopTL, ok := stopTimeExpr.RHS.(*influxql.StringLiteral)
-		if !ok {
-			return &iclient.Response{Err: "expected select condition rhs to be string literal"}
+		if got.TemplateID != id {
+			t.Errorf("unexpected task TemplateID got %s exp %s", got.TemplateID, id)
 		}
-		count++
-		switch count {
-		case 1:
-			stopTime, err := time.Parse(time.RFC3339Nano, stopTL.Val)
-			if err != nil {
-				return &iclient.Response{Err: err.Error()}
-			}
-			stopTimeC <- stopTime
-			return &iclient.Response{
-				Results: []iclient.Result{{
-					Series: []imodels.Row{{
-						Name:    "cpu",
-						Columns: []string{"time", "value"},
-						Values: [][]interface{}{
-							{
-								stopTime.Add(-2 * time.Millisecond).Format(time.RFC3339Nano),
-								1.0,
-							},
-							{
-								stopTime.Add(-1 * time.Millisecond).Format(time.RFC3339Nano),
-								1.0,
-							},
-						},
-					}},
-				}},
-			}
-		default:
-			return &iclient.Response{
-				Results: []iclient.Result{{
-					Series: []imodels.Row{{
-						Name:    "cpu",
-						Columns: []string{"time", "value"},
-						Values:  [][]interface{}{},
-					}},
-				}},
-			}
+		if got.TICKscript != tick {
+			t.Errorf("unexpected task TICKscript got %s exp %s", got.TICKscript, tick)
 		}
-	})
-	defer db.Close()
+	}
+}
 
-	// Set bad URL first
-	c.InfluxDB[0].URLs = []string{dbBad.URL()}
-	s := OpenServer(c)
+func TestServer_DeleteTemplate(t *testing.T) {
+	s, cli := OpenDefaultServer()
 	defer s.Close()
-	cli := Client(s)
 
-	id := "testBatchTask"
-	ttype := client.BatchTask
-	dbrps := []client.DBRP{{
-		Database:        "mydb",
-		RetentionPolicy: "myrp",
-	}}
-	tick := `batch
-    |query('SELECT value from mydb.myrp.cpu')
-        .period(5ms)
-        .every(5ms)
-        .align()
-    |count('value')
-    |where(lambda: "count" == 2)
-    |httpOut('count')
+	id := "testTemplateID"
+	ttype := client.StreamTask
+	tick := `stream
+    |from()
+        .measurement('test')
 `
+	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:         id,
+		Type:       ttype,
+		TICKscript: tick,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	task, err := cli.CreateTask(client.CreateTaskOptions{
+	err = cli.DeleteTemplate(template.Link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ti, err := cli.Template(template.Link, nil)
+	if err == nil {
+		t.Fatal("unexpected template:", ti)
+	}
+}
+
+func TestServer_CreateTaskFromTemplate(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testTemplateID"
+	ttype := client.StreamTask
+	tick := `// Configurable measurement
+var measurement = 'test'
+
+stream
+    |from()
+        .measurement(measurement)
+`
+	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
 		ID:         id,
 		Type:       ttype,
-		DBRPs:      dbrps,
 		TICKscript: tick,
-		Status:     client.Disabled,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-		Status: client.Enabled,
+	templateInfo, err := cli.Template(template.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if templateInfo.Error != "" {
+		t.Fatal(templateInfo.Error)
+	}
+	if templateInfo.ID != id {
+		t.Fatalf("unexpected template.id got %s exp %s", templateInfo.ID, id)
+	}
+	if templateInfo.Type != client.StreamTask {
+		t.Fatalf("unexpected template.type got %v exp %v", templateInfo.Type, client.StreamTask)
+	}
+	if templateInfo.TICKscript != tick {
+		t.Fatalf("unexpected template.TICKscript got %s exp %s", templateInfo.TICKscript, tick)
+	}
+	dot := "digraph testTemplateID {\nstream0 -> from1;\n}"
+	if templateInfo.Dot != dot {
+		t.Fatalf("unexpected template.dot\ngot\n%s\nexp\n%s\n", templateInfo.Dot, dot)
+	}
+	expVars := client.Vars{
+		"measurement": {
+			Value:       "test",
+			Type:        client.VarString,
+			Description: "Configurable measurement",
+		},
+	}
+	if got, exp := templateInfo.Vars, expVars; !reflect.DeepEqual(exp, got) {
+		t.Errorf("unexpected template vars: got %v exp %v", got, exp)
+	}
+
+	dbrps := []client.DBRP{
+		{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		},
+		{
+			Database:        "otherdb",
+			RetentionPolicy: "default",
+		},
+	}
+	vars := client.Vars{
+		"measurement": {
+			Value: "another_measurement",
+			Type:  client.VarString,
+		},
+	}
+
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "taskid",
+		TemplateID: id,
+		DBRPs:      dbrps,
+		Vars:       vars,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	taskInfo, err := cli.Task(task.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if taskInfo.Error != "" {
+		t.Fatal(taskInfo.Error)
+	}
+	if taskInfo.ID != "taskid" {
+		t.Fatalf("unexpected task.id got %s exp %s", taskInfo.ID, "taskid")
+	}
+	if taskInfo.Type != client.StreamTask {
+		t.Fatalf("unexpected task.type got %v exp %v", taskInfo.Type, client.StreamTask)
+	}
+	if taskInfo.TICKscript != tick {
+		t.Fatalf("unexpected task.TICKscript got %s exp %s", taskInfo.TICKscript, tick)
+	}
+	dot = "digraph taskid {\nstream0 -> from1;\n}"
+	if taskInfo.Dot != dot {
+		t.Fatalf("unexpected task.dot\ngot\n%s\nexp\n%s\n", taskInfo.Dot, dot)
+	}
+	if taskInfo.Status != client.Disabled {
+		t.Fatalf("unexpected task.status got %v exp %v", taskInfo.Status, client.Disabled)
+	}
+	if !reflect.DeepEqual(taskInfo.DBRPs, dbrps) {
+		t.Fatalf("unexpected task.dbrps got %s exp %s", taskInfo.DBRPs, dbrps)
+	}
+	if !reflect.DeepEqual(taskInfo.Vars, vars) {
+		t.Fatalf("unexpected task.vars got %s exp %s", taskInfo.Vars, vars)
+	}
+}
+
+func TestServer_DynamicStreamTask(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	testCases := []struct {
+		name string
+		tick string
+		want client.TaskType
+	}{
+		{
+			name: "stream",
+			tick: `
+dbrp "db"."rp"
+stream
+    |from()
+         .measurement('test')
+`,
+			want: client.StreamTask,
+		},
+		{
+			name: "stream_through_var",
+			tick: `
+dbrp "db"."rp"
+var s = stream
+s
+    |from()
+         .measurement('test')
+`,
+			want: client.StreamTask,
+		},
+		{
+			name: "batch",
+			tick: `
+dbrp "db"."rp"
+batch
+    |query('select * from db.rp.m')
+`,
+			want: client.BatchTask,
+		},
+		{
+			name: "batch_through_var",
+			tick: `
+dbrp "db"."rp"
+var b = batch
+b
+    |query('select * from db.rp.m')
+`,
+			want: client.BatchTask,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			task, err := cli.CreateTask(client.CreateTaskOptions{
+				ID:         tc.name,
+				TICKscript: tc.tick,
+				Status:     client.Disabled,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if task.Type != tc.want {
+				t.Fatalf("unexpected task type: got: %v want: %v", task.Type, tc.want)
+			}
+		})
+	}
+}
+
+func TestServer_StreamTask(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testStreamTask"
+	ttype := client.StreamTask
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	tick := `stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(10s)
+        .every(10s)
+    |count('value')
+    |httpOut('count')
+`
+
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       ttype,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Enabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), id)
+
+	// Request data before any writes and expect null responses
+	nullResponse := `{"series":null}`
+	err = s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5)
+	if err != nil {
+		t.Error(err)
+	}
+
+	points := `test value=1 0000000000
+test value=1 0000000001
+test value=1 0000000001
+test value=1 0000000002
+test value=1 0000000002
+test value=1 0000000003
+test value=1 0000000003
+test value=1 0000000004
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000006
+test value=1 0000000007
+test value=1 0000000008
+test value=1 0000000009
+test value=1 0000000010
+test value=1 0000000011
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+
+	exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
+	err = s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServer_StreamTask_SnapshotOnShutdown(t *testing.T) {
+	c := NewConfig()
+	// Only the shutdown saves snapshots.
+	c.Task.SnapshotInterval = 0
+	c.Task.SnapshotOnShutdown = true
+	s := OpenServer(c)
+	cli := Client(s)
+	defer s.Close()
+
+	id := "testStreamTask"
+	tick := `stream
+    |from()
+        .measurement('test')
+    |eval(lambda: count())
+        .as('n')
+    |window()
+        .period(10s)
+        .every(10s)
+    |max('n')
+    |httpOut('max')
+`
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TICKscript: tick,
+		Status:     client.Enabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", `test value=1 0000000000
+test value=1 0000000001
+test value=1 0000000002
+test value=1 0000000003
+test value=1 0000000004
+test value=1 0000000005
+`, v)
+
+	// The window and the count resume after the restart.
+	s.Restart()
+
+	endpoint := fmt.Sprintf("%s/tasks/%s/max", s.URL(), id)
+	if err := s.HTTPGetRetry(endpoint, `{"series":null}`, 100, time.Millisecond*5); err != nil {
+		t.Fatal(err)
+	}
+	s.MustWrite("mydb", "myrp", `test value=1 0000000006
+test value=1 0000000007
+test value=1 0000000008
+test value=1 0000000009
+test value=1 0000000010
+`, v)
+
+	exp := `{"series":[{"name":"test","columns":["time","max"],"values":[["1970-01-01T00:00:10Z",10]]}]}`
+	if err := s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServer_StreamTask_WALReplay(t *testing.T) {
+	testStreamTaskWALReplay(t, false)
+}
+
+// The points of the snapshot taken at the shutdown are not replayed again.
+func TestServer_StreamTask_WALReplaySnapshot(t *testing.T) {
+	testStreamTaskWALReplay(t, true)
+}
+
+func testStreamTaskWALReplay(t *testing.T, snapshotOnShutdown bool) {
+	c := NewConfig()
+	c.Task.SnapshotInterval = 0
+	c.Task.SnapshotOnShutdown = snapshotOnShutdown
+	c.WAL.Enabled = true
+	c.WAL.Dir = MustTempDir()
+	c.WAL.FsyncInterval = 0
+	s := OpenServer(c)
+	cli := Client(s)
+	defer s.Close()
+
+	id := "testStreamTask"
+	tick := `stream
+    |from()
+        .measurement('test')
+    |eval(lambda: count())
+        .as('n')
+    |window()
+        .period(10s)
+        .every(10s)
+    |max('n')
+    |httpOut('max')
+`
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TICKscript: tick,
+		Status:     client.Enabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", `test value=1 0000000000
+test value=1 0000000001
+test value=1 0000000002
+test value=1 0000000003
+test value=1 0000000004
+test value=1 0000000005
+`, v)
+
+	// Simulate a crash by restoring the log which the clean shutdown deletes.
+	backup := MustTempDir()
+	if err := copyFiles(c.WAL.Dir, backup); err != nil {
+		t.Fatal(err)
+	}
+	s.Stop()
+	if err := copyFiles(backup, c.WAL.Dir); err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+
+	endpoint := fmt.Sprintf("%s/tasks/%s/max", s.URL(), id)
+	if err := s.HTTPGetRetry(endpoint, `{"series":null}`, 100, time.Millisecond*5); err != nil {
+		t.Fatal(err)
+	}
+	s.MustWrite("mydb", "myrp", `test value=1 0000000006
+test value=1 0000000007
+test value=1 0000000008
+test value=1 0000000009
+test value=1 0000000010
+`, v)
+
+	exp := `{"series":[{"name":"test","columns":["time","max"],"values":[["1970-01-01T00:00:10Z",10]]}]}`
+	if err := s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServer_StreamTask_Cardinality(t *testing.T) {
+	c := NewConfig()
+	c.Task.Cardinality.Enabled = true
+	c.Task.Cardinality.MaxSeries = 2
+	s := OpenServer(c)
+	cli := Client(s)
+	defer s.Close()
+
+	id := "testStreamTask"
+	tick := `stream
+    |from()
+        .measurement('test')
+        .groupBy('host')
+    |httpOut('points')
+`
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TICKscript: tick,
+		Status:     client.Enabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", `test,host=a,dc=east value=1 0000000000
+test,host=b,dc=east value=1 0000000001
+test,host=c,dc=east value=1 0000000002
+test,host=a,dc=east value=2 0000000003
+`, v)
+
+	exp := client.TaskCardinality{
+		Link:    client.Link{Relation: client.Self, Href: "/kapacitor/v1/tasks/testStreamTask/cardinality"},
+		ID:      id,
+		Tracked: true,
+		Measurements: []client.MeasurementCardinality{{
+			Measurement: "test",
+			Series:      2,
+			MaxSeries:   2,
+			Dropped:     1,
+			TopTags:     []client.TagCardinality{{Tag: "host", Values: 2}},
+			TopValues:   []client.TagValueCardinality{{Tag: "dc", Value: "east", Series: 2}},
+		}},
+	}
+	var got client.TaskCardinality
+	for i := 0; i < 100; i++ {
+		got, err = cli.TaskCardinality(task.Link, &client.TaskCardinalityOptions{K: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reflect.DeepEqual(got, exp) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected cardinality:\ngot %+v\nexp %+v", got, exp)
+	}
+
+	// The points of the series above the limit are dropped.
+	endpoint := fmt.Sprintf("%s/tasks/%s/points", s.URL(), id)
+	expPoints := `{"series":[{"name":"test","tags":{"dc":"east","host":"a"},"columns":["time","value"],"values":[["1970-01-01T00:00:03Z",2]]},{"name":"test","tags":{"dc":"east","host":"b"},"columns":["time","value"],"values":[["1970-01-01T00:00:01Z",1]]}]}`
+	if err := s.HTTPGetRetry(endpoint, expPoints, 100, time.Millisecond*5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServer_StreamTask_Profile(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testStreamTask"
+	tick := `stream
+    |from()
+        .measurement('test')
+        .groupBy('host')
+    |eval(lambda: sqrt("value") * 2.0)
+        .as('value')
+    |httpOut('points')
+`
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TICKscript: tick,
+		Status:     client.Enabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keep the task busy while it is profiled.
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		v := url.Values{}
+		v.Add("precision", "s")
+		var buf bytes.Buffer
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			buf.Reset()
+			for j := 0; j < 100; j++ {
+				fmt.Fprintf(&buf, "test,host=%c value=%d %d\n", 'a'+j%5, j, i*100+j)
+			}
+			s.MustWrite("mydb", "myrp", buf.String(), v)
+		}
+	}()
+	defer wg.Wait()
+	defer close(done)
+
+	got, err := cli.TaskProfile(task.Link, &client.TaskProfileOptions{Duration: 500 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Executing || got.ID != id || got.Duration != client.Duration(500*time.Millisecond) {
+		t.Errorf("unexpected profile %+v", got)
+	}
+	if got.Link.Href != "/kapacitor/v1/tasks/testStreamTask/profile" {
+		t.Errorf("unexpected link %v", got.Link)
+	}
+	var total time.Duration
+	for name, n := range got.Nodes {
+		switch name {
+		case "stream0", "from1", "eval2", "http_out3":
+		default:
+			t.Errorf("unexpected node %s", name)
+		}
+		total += time.Duration(n.CPU)
+	}
+	if time.Duration(got.CPU) != total {
+		t.Errorf("unexpected CPU of task got %v exp sum of nodes %v", got.CPU, total)
+	}
+
+	var pprof bytes.Buffer
+	if err := cli.WriteTaskProfile(&pprof, task.Link, &client.TaskProfileOptions{Duration: 200 * time.Millisecond, Format: "pprof"}); err != nil {
+		t.Fatal(err)
+	}
+	p, err := profile.Parse(&pprof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sample := range p.Samples {
+		if sample.Labels["task"] != id {
+			t.Errorf("unexpected sample of another task %v", sample.Labels)
+		}
+	}
+
+	var folded bytes.Buffer
+	if err := cli.WriteTaskProfile(&folded, task.Link, &client.TaskProfileOptions{Duration: 200 * time.Millisecond, Format: "folded"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(folded.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		switch node := strings.SplitN(line, ";", 2)[0]; node {
+		case "stream0", "from1", "eval2", "http_out3":
+		default:
+			t.Errorf("unexpected folded stack %q, expected a node of the task first", line)
+		}
+	}
+
+	if err := cli.WriteTaskProfile(ioutil.Discard, task.Link, &client.TaskProfileOptions{Format: "svg"}); err == nil {
+		t.Error("expected error for an invalid format")
+	}
+}
+
+func TestServer_StreamTask_Tracing(t *testing.T) {
+	type span struct {
+		TraceID      string `json:"traceId"`
+		SpanID       string `json:"spanId"`
+		ParentSpanID string `json:"parentSpanId"`
+		Name         string `json:"name"`
+	}
+	var mu sync.Mutex
+	var spans []span
+	var traceparent string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []span `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}))
+	defer collector.Close()
+	alerts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer alerts.Close()
+
+	c := NewConfig()
+	c.Tracing.Enabled = true
+	c.Tracing.URL = collector.URL
+	c.Tracing.SampleRate = 1
+	c.Tracing.FlushInterval = toml.Duration(10 * time.Millisecond)
+	s := OpenServer(c)
+	cli := Client(s)
+	defer s.Close()
+
+	tick := fmt.Sprintf(`stream
+    |from()
+        .measurement('test')
+    |alert()
+        .id('traced')
+        .crit(lambda: "value" > 10)
+        .post('%s')
+`, alerts.URL)
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "testStreamTask",
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TICKscript: tick,
+		Status:     client.Enabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", "test value=42 0", v)
+
+	// The trace of the point goes from its ingestion through the nodes of the task to the alert POST.
+	expNames := []string{"ingest", "stream", "stream0", "from1", "alert2", "alert handler", "POST"}
+	byName := make(map[string]span)
+	timeout := time.After(5 * time.Second)
+	for len(byName) < len(expNames) {
+		select {
+		case <-timeout:
+			t.Fatalf("timed out waiting for spans, got %v", byName)
+		case <-time.After(10 * time.Millisecond):
+		}
+		mu.Lock()
+		for _, sp := range spans {
+			byName[sp.Name] = sp
+		}
+		mu.Unlock()
+	}
+	parents := map[string]string{
+		"stream":        "ingest",
+		"stream0":       "stream",
+		"from1":         "stream0",
+		"alert2":        "from1",
+		"alert handler": "alert2",
+		"POST":          "alert handler",
+	}
+	root := byName["ingest"]
+	for _, name := range expNames {
+		sp, ok := byName[name]
+		if !ok {
+			t.Fatalf("missing span %q", name)
+		}
+		if sp.TraceID != root.TraceID {
+			t.Errorf("span %q not in the trace of the point got %s exp %s", name, sp.TraceID, root.TraceID)
+		}
+		if parent, ok := parents[name]; ok && sp.ParentSpanID != byName[parent].SpanID {
+			t.Errorf("unexpected parent of span %q, expected %q", name, parent)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if exp := "00-" + root.TraceID + "-" + byName["POST"].SpanID + "-01"; traceparent != exp {
+		t.Errorf("unexpected traceparent header of alert POST got %q exp %q", traceparent, exp)
+	}
+}
+
+func TestServer_StreamTask_SnapshotInterval(t *testing.T) {
+	c := NewConfig()
+	c.Task.SnapshotInterval = toml.Duration(10 * time.Millisecond)
+	s := OpenServer(c)
+	cli := Client(s)
+	defer s.Close()
+
+	id := "testStreamTask"
+	tick := `stream
+    |from()
+        .measurement('test')
+    |stateCount(lambda: "value" > 10)
+    |httpOut('count')
+`
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TICKscript: tick,
+		Status:     client.Enabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", `test value=11 0000000000
+test value=12 0000000001
+test value=13 0000000002
+`, v)
+
+	// Wait for a snapshot including all points, the shutdown does not save one.
+	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), id)
+	if err := s.HTTPGetRetry(endpoint, `{"series":[{"name":"test","columns":["time","state_count","value"],"values":[["1970-01-01T00:00:02Z",3,13]]}]}`, 100, time.Millisecond*5); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	s.Restart()
+
+	if err := s.HTTPGetRetry(endpoint, `{"series":null}`, 100, time.Millisecond*5); err != nil {
+		t.Fatal(err)
+	}
+	s.MustWrite("mydb", "myrp", `test value=14 0000000003
+`, v)
+	exp := `{"series":[{"name":"test","columns":["time","state_count","value"],"values":[["1970-01-01T00:00:03Z",4,14]]}]}`
+	if err := s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServer_StreamTask_WebSocket(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testStreamTask"
+	tick := `stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(10s)
+        .every(10s)
+    |count('value')
+    |httpOut('count')
+`
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TICKscript: tick,
+		Status:     client.Enabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), id)
+	// Wait for the endpoint to exist
+	if err := s.HTTPGetRetry(endpoint, `{"series":null}`, 100, time.Millisecond*5); err != nil {
+		t.Fatal(err)
+	}
+	ws, err := websocket.Dial(strings.Replace(endpoint, "http://", "ws://", 1), "", s.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	ws.SetDeadline(time.Now().Add(10 * time.Second))
+
+	var msg string
+	if err := websocket.Message.Receive(ws, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if exp := `{"series":[]}`; msg != exp {
+		t.Errorf("unexpected initial message:\ngot %s\nexp %s", msg, exp)
+	}
+
+	points := `test value=1 0000000000
+test value=1 0000000005
+test value=1 0000000010
+test value=1 0000000021
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+
+	exps := []string{
+		`{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",2]]}]}`,
+		`{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:20Z",1]]}]}`,
+	}
+	for _, exp := range exps {
+		if err := websocket.Message.Receive(ws, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg != exp {
+			t.Errorf("unexpected message:\ngot %s\nexp %s", msg, exp)
+		}
+	}
+}
+
+func TestServer_GRPC(t *testing.T) {
+	c := NewConfig()
+	c.GRPC.Enabled = true
+	c.GRPC.WatchInterval = toml.Duration(10 * time.Millisecond)
+	s := OpenServer(c)
+	defer s.Close()
+
+	conn, err := grpc.Dial(s.GRPCService.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	cli := api.NewKapacitorClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	watch, err := cli.WatchTasks(ctx, &api.WatchTasksRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tick := `stream
+    |from()
+        .measurement('test')
+`
+	task, err := cli.CreateTask(ctx, &api.CreateTaskRequest{
+		Id:         "testGRPCTask",
+		Type:       api.TaskType_STREAM,
+		Dbrps:      []*api.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TickScript: tick,
+		Status:     api.TaskStatus_DISABLED,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Id != "testGRPCTask" || task.Type != api.TaskType_STREAM || task.Status != api.TaskStatus_DISABLED {
+		t.Errorf("unexpected task %v", task)
+	}
+
+	event, err := watch.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Deleted || event.Task.Id != "testGRPCTask" || event.Task.Status != api.TaskStatus_DISABLED {
+		t.Errorf("unexpected event %v", event)
+	}
+
+	if _, err := cli.UpdateTask(ctx, &api.UpdateTaskRequest{
+		Id:     "testGRPCTask",
+		Status: api.TaskStatus_ENABLED,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	event, err = watch.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Deleted || event.Task.Status != api.TaskStatus_ENABLED || !event.Task.Executing {
+		t.Errorf("unexpected event %v", event)
+	}
+
+	tasks, err := cli.ListTasks(ctx, &api.ListTasksRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks.Tasks) != 1 || tasks.Tasks[0].TickScript != tick {
+		t.Errorf("unexpected tasks %v", tasks)
+	}
+
+	if _, err := cli.DeleteTask(ctx, &api.DeleteTaskRequest{Id: "testGRPCTask"}); err != nil {
+		t.Fatal(err)
+	}
+	event, err = watch.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !event.Deleted || event.Task.Id != "testGRPCTask" {
+		t.Errorf("unexpected event %v", event)
+	}
+
+	_, err = cli.GetTask(ctx, &api.GetTaskRequest{Id: "testGRPCTask"})
+	if got, exp := grpc.Code(err), codes.NotFound; got != exp {
+		t.Errorf("unexpected error code: got %v exp %v: %v", got, exp, err)
+	}
+}
+
+func TestServer_StreamTask_NoRP(t *testing.T) {
+	conf := NewConfig()
+	conf.DefaultRetentionPolicy = "myrp"
+	s := OpenServer(conf)
+	defer s.Close()
+	cli := Client(s)
+
+	id := "testStreamTask"
+	ttype := client.StreamTask
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	tick := `stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(10s)
+        .every(10s)
+    |count('value')
+    |httpOut('count')
+`
+
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       ttype,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Enabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), id)
+
+	// Request data before any writes and expect null responses
+	nullResponse := `{"series":null}`
+	err = s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5)
+	if err != nil {
+		t.Error(err)
+	}
+
+	points := `test value=1 0000000000
+test value=1 0000000001
+test value=1 0000000001
+test value=1 0000000002
+test value=1 0000000002
+test value=1 0000000003
+test value=1 0000000003
+test value=1 0000000004
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000006
+test value=1 0000000007
+test value=1 0000000008
+test value=1 0000000009
+test value=1 0000000010
+test value=1 0000000011
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "", points, v)
+
+	exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
+	err = s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServer_StreamTemplateTask(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	templateId := "testStreamTemplate"
+	taskId := "testStreamTask"
+	ttype := client.StreamTask
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	tick := `
+var field = 'nonexistent'
+stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(10s)
+        .every(10s)
+    |count(field)
+    |httpOut('count')
+`
+	if _, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:         templateId,
+		Type:       ttype,
+		TICKscript: tick,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         taskId,
+		TemplateID: templateId,
+		DBRPs:      dbrps,
+		Status:     client.Enabled,
+		Vars: client.Vars{
+			"field": {
+				Value: "value",
+				Type:  client.VarString,
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), taskId)
+
+	// Request data before any writes and expect null responses
+	nullResponse := `{"series":null}`
+	if err := s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5); err != nil {
+		t.Error(err)
+	}
+
+	points := `test value=1 0000000000
+test value=1 0000000001
+test value=1 0000000001
+test value=1 0000000002
+test value=1 0000000002
+test value=1 0000000003
+test value=1 0000000003
+test value=1 0000000004
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000006
+test value=1 0000000007
+test value=1 0000000008
+test value=1 0000000009
+test value=1 0000000010
+test value=1 0000000011
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+
+	exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
+	if err := s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5); err != nil {
+		t.Error(err)
+	}
+}
+func TestServer_StreamTemplateTask_MissingVar(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	templateId := "testStreamTemplate"
+	taskId := "testStreamTask"
+	ttype := client.StreamTask
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	tick := `
+var field string
+stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(10s)
+        .every(10s)
+    |count(field)
+    |httpOut('count')
+`
+	if _, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:         templateId,
+		Type:       ttype,
+		TICKscript: tick,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         taskId,
+		TemplateID: templateId,
+		DBRPs:      dbrps,
+		Status:     client.Enabled,
+	}); err == nil {
+		t.Error("expected error for missing task vars")
+	} else if exp, got := "invalid TICKscript: missing value for var \"field\".", err.Error(); got != exp {
+		t.Errorf("unexpected error message: got %s exp %s", got, exp)
+	}
+}
+func TestServer_StreamTemplateTask_AllTypes(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	templateId := "testStreamTemplate"
+	taskId := "testStreamTask"
+	ttype := client.StreamTask
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	tick := `
+var bool bool
+var count_threshold int
+var value_threshold float
+var window duration
+var field string
+var tagMatch regex
+var match lambda
+var eval lambda
+var groups list
+var secondGroup list
+stream
+    |from()
+        .measurement('test')
+        .where(lambda: match AND "tag" =~ tagMatch AND bool AND "value" >= value_threshold)
+        .groupBy(groups)
+        |log().prefix('FROM')
+    |window()
+        .period(window)
+        .every(window)
+        |log().prefix('WINDOW')
+    |count(field)
+        |log().prefix('COUNT')
+    |groupBy(secondGroup)
+    |sum('count')
+        .as('count')
+        |log().prefix('SUM')
+    |where(lambda: "count" >= count_threshold)
+        |log().prefix('WHERE')
+    |eval(eval)
+        .as('count')
+    |httpOut('count')
+`
+	if _, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:         templateId,
+		Type:       ttype,
+		TICKscript: tick,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         taskId,
+		TemplateID: templateId,
+		DBRPs:      dbrps,
+		Status:     client.Enabled,
+		Vars: client.Vars{
+			"bool": {
+				Value: true,
+				Type:  client.VarBool,
+			},
+			"count_threshold": {
+				Value: int64(1),
+				Type:  client.VarInt,
+			},
+			"value_threshold": {
+				Value: float64(1.0),
+				Type:  client.VarFloat,
+			},
+			"window": {
+				Value: 10 * time.Second,
+				Type:  client.VarDuration,
+			},
+			"field": {
+				Value: "value",
+				Type:  client.VarString,
+			},
+			"tagMatch": {
+				Value: "^a.*",
+				Type:  client.VarRegex,
+			},
+			"match": {
+				Value: `"value" == 1.0`,
+				Type:  client.VarLambda,
+			},
+			"eval": {
+				Value: `"count" * 2`,
+				Type:  client.VarLambda,
+			},
+			"groups": {
+				Value: []client.Var{client.Var{Type: client.VarStar}},
+				Type:  client.VarList,
+			},
+			"secondGroup": {
+				Value: []client.Var{client.Var{Value: "tag", Type: client.VarString}},
+				Type:  client.VarList,
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), taskId)
+
+	// Request data before any writes and expect null responses
+	nullResponse := `{"series":null}`
+	if err := s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5); err != nil {
+		t.Error(err)
+	}
+
+	points := `test,tag=abc,other=a value=1 0000000000
+test,tag=abc,other=b value=1 0000000000
+test,tag=abc,other=a value=1 0000000001
+test,tag=bbc,other=b value=1 0000000001
+test,tag=abc,other=a value=1 0000000002
+test,tag=abc,other=a value=0 0000000002
+test,tag=abc,other=b value=1 0000000003
+test,tag=abc,other=a value=1 0000000003
+test,tag=abc,other=a value=1 0000000004
+test,tag=abc,other=b value=1 0000000005
+test,tag=abc,other=a value=1 0000000005
+test,tag=bbc,other=a value=1 0000000005
+test,tag=abc,other=b value=1 0000000006
+test,tag=abc,other=a value=1 0000000007
+test,tag=abc,other=b value=0 0000000008
+test,tag=abc,other=a value=1 0000000009
+test,tag=abc,other=a value=1 0000000010
+test,tag=abc,other=a value=1 0000000011
+test,tag=abc,other=b value=1 0000000011
+test,tag=bbc,other=a value=1 0000000011
+test,tag=bbc,other=b value=1 0000000011
+test,tag=abc,other=a value=1 0000000021
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+
+	exp := `{"series":[{"name":"test","tags":{"tag":"abc"},"columns":["time","count"],"values":[["1970-01-01T00:00:10Z",24]]}]}`
+	if err := s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServer_StreamTemplateTaskFromUpdate(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	templateId := "testStreamTemplate"
+	taskId := "testStreamTask"
+	ttype := client.StreamTask
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	tick := `
+var field = 'nonexistent'
+stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(10s)
+        .every(10s)
+    |count(field)
+    |httpOut('count')
+`
+	if _, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:         templateId,
+		Type:       ttype,
+		TICKscript: tick,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         taskId,
+		TemplateID: templateId,
+		DBRPs:      dbrps,
+		Status:     client.Disabled,
+		Vars: client.Vars{
+			"field": {
+				Value: "value",
+				Type:  client.VarString,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Enabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), taskId)
+
+	// Request data before any writes and expect null responses
+	nullResponse := `{"series":null}`
+	if err := s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5); err != nil {
+		t.Error(err)
+	}
+
+	points := `test value=1 0000000000
+test value=1 0000000001
+test value=1 0000000001
+test value=1 0000000002
+test value=1 0000000002
+test value=1 0000000003
+test value=1 0000000003
+test value=1 0000000004
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000006
+test value=1 0000000007
+test value=1 0000000008
+test value=1 0000000009
+test value=1 0000000010
+test value=1 0000000011
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+
+	exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
+	if err := s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5); err != nil {
+		t.Error(err)
+	}
+}
+func TestServer_StreamTemplateTask_UpdateTemplate(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	templateId := "testStreamTemplate"
+	taskId := "testStreamTask"
+	ttype := client.StreamTask
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	tickWrong := `
+stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(10s)
+        .every(10s)
+    |count('wrong')
+    |httpOut('count')
+`
+	tickCorrect := `
+var field string
+stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(10s)
+        .every(10s)
+    |count(field)
+    |httpOut('count')
+`
+	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:         templateId,
+		Type:       ttype,
+		TICKscript: tickWrong,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = cli.CreateTask(client.CreateTaskOptions{
+		ID:         taskId,
+		TemplateID: templateId,
+		DBRPs:      dbrps,
+		Status:     client.Enabled,
+		Vars: client.Vars{
+			"field": {
+				Value: "value",
+				Type:  client.VarString,
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
+		TICKscript: tickCorrect,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), taskId)
+
+	// Request data before any writes and expect null responses
+	nullResponse := `{"series":null}`
+	if err := s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5); err != nil {
+		t.Error(err)
+	}
+
+	points := `test value=1 0000000000
+test value=1 0000000001
+test value=1 0000000001
+test value=1 0000000002
+test value=1 0000000002
+test value=1 0000000003
+test value=1 0000000003
+test value=1 0000000004
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000006
+test value=1 0000000007
+test value=1 0000000008
+test value=1 0000000009
+test value=1 0000000010
+test value=1 0000000011
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+
+	exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
+	if err := s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5); err != nil {
+		t.Error(err)
+	}
+}
+func TestServer_StreamTemplateTask_UpdateTemplate_Rollback(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	templateId := "testStreamTemplate"
+	taskId := "testStreamTask"
+	ttype := client.StreamTask
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	tickCorrect := `
+var field string
+stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(10s)
+        .every(10s)
+    |count(field)
+    |httpOut('count')
+`
+	tickNewVar := `
+var field string
+var period duration
+stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(period)
+        .every(period)
+    |count(field)
+    |httpOut('count')
+`
+	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:         templateId,
+		Type:       ttype,
+		TICKscript: tickCorrect,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create several tasks
+	count := 5
+	tasks := make([]client.Task, count)
+	for i := 0; i < count; i++ {
+		if task, err := cli.CreateTask(client.CreateTaskOptions{
+			ID:         fmt.Sprintf("%s-%d", taskId, i),
+			TemplateID: templateId,
+			DBRPs:      dbrps,
+			Status:     client.Enabled,
+			Vars: client.Vars{
+				"field": {
+					Value: "value",
+					Type:  client.VarString,
+				},
+			},
+		}); err != nil {
+			t.Fatal(err)
+		} else {
+			tasks[i] = task
+		}
+	}
+
+	if _, err := cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
+		TICKscript: tickNewVar,
+	}); err == nil {
+		t.Error("expected error for breaking template update, got nil")
+	} else if got, exp := err.Error(), `error reloading associated task testStreamTask-0: missing value for var "period".`; exp != got {
+		t.Errorf("unexpected error for breaking template update, got %s exp %s", got, exp)
+	}
+
+	// Get all tasks and make sure their TICKscript has the original value
+	for _, task := range tasks {
+		if gotTask, err := cli.Task(task.Link, &client.TaskOptions{ScriptFormat: "raw"}); err != nil {
+			t.Fatal(err)
+		} else if got, exp := gotTask.TICKscript, tickCorrect; got != exp {
+			t.Errorf("unexpected task TICKscript:\ngot\n%s\nexp\n%s\n", got, exp)
+		}
+	}
+
+	// Update all tasks with new var
+	for _, task := range tasks {
+		if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+			Vars: client.Vars{
+				"field": {
+					Value: "value",
+					Type:  client.VarString,
+				},
+				"period": {
+					Value: 10 * time.Second,
+					Type:  client.VarDuration,
+				},
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Now update template should succeed since the tasks are updated too.
+	if _, err := cli.UpdateTemplate(template.Link, client.UpdateTemplateOptions{
+		TICKscript: tickNewVar,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, task := range tasks {
+		taskId := task.ID
+		endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), taskId)
+
+		// Request data before any writes and expect null responses
+		nullResponse := `{"series":null}`
+		if err := s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5); err != nil {
+			t.Error(err)
+		}
+	}
+
+	points := `test value=1 0000000000
+test value=1 0000000001
+test value=1 0000000001
+test value=1 0000000002
+test value=1 0000000002
+test value=1 0000000003
+test value=1 0000000003
+test value=1 0000000004
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000006
+test value=1 0000000007
+test value=1 0000000008
+test value=1 0000000009
+test value=1 0000000010
+test value=1 0000000011
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+
+	for _, task := range tasks {
+		taskId := task.ID
+		endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), taskId)
+
+		exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
+		if err := s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestServer_UpdateTaskID(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testTaskID"
+	ttype := client.StreamTask
+	dbrps := []client.DBRP{
+		{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		},
+		{
+			Database:        "otherdb",
+			RetentionPolicy: "default",
+		},
+	}
+	tick := `stream
+    |from()
+        .measurement('test')
+`
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       ttype,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ti, err := cli.Task(task.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ti.Error != "" {
+		t.Fatal(ti.Error)
+	}
+	if ti.ID != id {
+		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	}
+	if ti.Type != client.StreamTask {
+		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	}
+	if ti.Status != client.Disabled {
+		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+	}
+	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
+		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	}
+	if ti.TICKscript != tick {
+		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+	}
+	dot := "digraph testTaskID {\nstream0 -> from1;\n}"
+	if ti.Dot != dot {
+		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	}
+
+	newID := "newTaskID"
+	task, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		ID: newID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := task.Link.Href, "/kapacitor/v1/tasks/newTaskID"; got != exp {
+		t.Fatalf("unexpected task link got %s exp %s", got, exp)
+	}
+
+	ti, err = cli.Task(task.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ti.Error != "" {
+		t.Fatal(ti.Error)
+	}
+	if ti.ID != newID {
+		t.Fatalf("unexpected id got %s exp %s", ti.ID, newID)
+	}
+	if ti.Type != client.StreamTask {
+		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	}
+	if ti.Status != client.Disabled {
+		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+	}
+	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
+		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	}
+	if ti.TICKscript != tick {
+		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+	}
+	dot = "digraph newTaskID {\nstream0 -> from1;\n}"
+	if ti.Dot != dot {
+		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	}
+}
+func TestServer_UpdateTaskID_Fail(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testTaskID"
+	newID := "anotherTaskID"
+	ttype := client.StreamTask
+	dbrps := []client.DBRP{
+		{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		},
+		{
+			Database:        "otherdb",
+			RetentionPolicy: "default",
+		},
+	}
+	tick := `stream
+    |from()
+        .measurement('test')
+`
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       ttype,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ti, err := cli.Task(task.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ti.Error != "" {
+		t.Fatal(ti.Error)
+	}
+	if ti.ID != id {
+		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	}
+	if ti.Type != client.StreamTask {
+		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	}
+	if ti.Status != client.Disabled {
+		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+	}
+	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
+		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	}
+	if ti.TICKscript != tick {
+		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+	}
+	dot := "digraph testTaskID {\nstream0 -> from1;\n}"
+	if ti.Dot != dot {
+		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	}
+
+	// Create conflicting task
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         newID,
+		Type:       ttype,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Disabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		ID: newID,
+	}); err == nil {
+		t.Fatal("expected error on name conflict")
+	}
+
+	// Can still get old task
+	ti, err = cli.Task(task.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ti.Error != "" {
+		t.Fatal(ti.Error)
+	}
+	if ti.ID != id {
+		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	}
+	if ti.Type != client.StreamTask {
+		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	}
+	if ti.Status != client.Disabled {
+		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Disabled)
+	}
+	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
+		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	}
+	if ti.TICKscript != tick {
+		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+	}
+	if ti.Dot != dot {
+		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", ti.Dot, dot)
+	}
+}
+func TestServer_UpdateTaskID_Enabled(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testTaskID"
+	ttype := client.StreamTask
+	dbrps := []client.DBRP{
+		{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		},
+		{
+			Database:        "otherdb",
+			RetentionPolicy: "default",
+		},
+	}
+	tick := `stream
+    |from()
+        .measurement('test')
+`
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       ttype,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Enabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ti, err := cli.Task(task.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ti.Error != "" {
+		t.Fatal(ti.Error)
+	}
+	if ti.ID != id {
+		t.Fatalf("unexpected id got %s exp %s", ti.ID, id)
+	}
+	if ti.Type != client.StreamTask {
+		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	}
+	if ti.Status != client.Enabled {
+		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Enabled)
+	}
+	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
+		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	}
+	if ti.TICKscript != tick {
+		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+	}
+	if !ti.Executing {
+		t.Fatal("expected task to be executing")
+	}
+
+	newID := "newTaskID"
+	task, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		ID: newID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := task.Link.Href, "/kapacitor/v1/tasks/newTaskID"; got != exp {
+		t.Fatalf("unexpected task link got %s exp %s", got, exp)
+	}
+
+	ti, err = cli.Task(task.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ti.Error != "" {
+		t.Fatal(ti.Error)
+	}
+	if ti.ID != newID {
+		t.Fatalf("unexpected id got %s exp %s", ti.ID, newID)
+	}
+	if ti.Type != client.StreamTask {
+		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.StreamTask)
+	}
+	if ti.Status != client.Enabled {
+		t.Fatalf("unexpected status got %v exp %v", ti.Status, client.Enabled)
+	}
+	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
+		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	}
+	if ti.TICKscript != tick {
+		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+	}
+	if !ti.Executing {
+		t.Fatal("expected task to be executing")
+	}
+}
+
+func TestServer_StreamTask_AllMeasurements(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testStreamTask"
+	ttype := client.StreamTask
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	tick := `stream
+    |from()
+    |window()
+        .period(10s)
+        .every(10s)
+    |count('value')
+    |httpOut('count')
+`
+
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       ttype,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Enabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), id)
+
+	// Request data before any writes and expect null responses
+	nullResponse := `{"series":null}`
+	err = s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5)
+	if err != nil {
+		t.Error(err)
+	}
+
+	points := `test0 value=1 0000000000
+test1 value=1 0000000001
+test0 value=1 0000000001
+test1 value=1 0000000002
+test0 value=1 0000000002
+test1 value=1 0000000003
+test0 value=1 0000000003
+test1 value=1 0000000004
+test0 value=1 0000000005
+test1 value=1 0000000005
+test0 value=1 0000000005
+test1 value=1 0000000006
+test0 value=1 0000000007
+test1 value=1 0000000008
+test0 value=1 0000000009
+test1 value=1 0000000010
+test0 value=1 0000000011
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+
+	exp := `{"series":[{"name":"test0","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
+	err = s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServer_BatchTask(t *testing.T) {
+	c := NewConfig()
+	c.InfluxDB[0].Enabled = true
+	count := 0
+	stopTimeC := make(chan time.Time, 1)
+
+	db := NewInfluxDB(func(q string) *iclient.Response {
+		stmt, err := influxql.ParseStatement(q)
+		if err != nil {
+			return &iclient.Response{Err: err.Error()}
+		}
+		slct, ok := stmt.(*influxql.SelectStatement)
+		if !ok {
+			return nil
+		}
+		cond, ok := slct.Condition.(*influxql.BinaryExpr)
+		if !ok {
+			return &iclient.Response{Err: "expected select condition to be binary expression"}
+		}
+		stopTimeExpr, ok := cond.RHS.(*influxql.BinaryExpr)
+		if !ok {
+			return &iclient.Response{Err: "expected select condition rhs to be binary expression"}
+		}
+		stopTL, ok := stopTimeExpr.RHS.(*influxql.StringLiteral)
+		if !ok {
+			return &iclient.Response{Err: "expected select condition rhs to be string literal"}
+		}
+		count++
+		switch count {
+		case 1:
+			stopTime, err := time.Parse(time.RFC3339Nano, stopTL.Val)
+			if err != nil {
+				return &iclient.Response{Err: err.Error()}
+			}
+			stopTimeC <- stopTime
+			return &iclient.Response{
+				Results: []iclient.Result{{
+					Series: []imodels.Row{{
+						Name:    "cpu",
+						Columns: []string{"time", "value"},
+						Values: [][]interface{}{
+							{
+								stopTime.Add(-2 * time.Millisecond).Format(time.RFC3339Nano),
+								1.0,
+							},
+							{
+								stopTime.Add(-1 * time.Millisecond).Format(time.RFC3339Nano),
+								1.0,
+							},
+						},
+					}},
+				}},
+			}
+		default:
+			return &iclient.Response{
+				Results: []iclient.Result{{
+					Series: []imodels.Row{{
+						Name:    "cpu",
+						Columns: []string{"time", "value"},
+						Values:  [][]interface{}{},
+					}},
+				}},
+			}
+		}
+	})
+	c.InfluxDB[0].URLs = []string{db.URL()}
+	s := OpenServer(c)
+	defer s.Close()
+	cli := Client(s)
+
+	id := "testBatchTask"
+	ttype := client.BatchTask
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	tick := `batch
+    |query('SELECT value from mydb.myrp.cpu')
+        .period(5ms)
+        .every(5ms)
+        .align()
+    |count('value')
+    |where(lambda: "count" == 2)
+    |httpOut('count')
+`
+
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       ttype,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Enabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), id)
+
+	timeout := time.NewTicker(100 * time.Millisecond)
+	defer timeout.Stop()
+	select {
+	case <-timeout.C:
+		t.Fatal("timedout waiting for query")
+	case stopTime := <-stopTimeC:
+		exp := fmt.Sprintf(`{"series":[{"name":"cpu","columns":["time","count"],"values":[["%s",2]]}]}`, stopTime.Local().Format(time.RFC3339Nano))
+		err = s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5)
+		if err != nil {
+			t.Error(err)
+		}
+		_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+			Status: client.Disabled,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+func TestServer_BatchTask_InfluxDBConfigUpdate(t *testing.T) {
+	c := NewConfig()
+	c.InfluxDB[0].Enabled = true
+	count := 0
+	stopTimeC := make(chan time.Time, 1)
+
+	badCount := 0
+
+	dbBad := NewInfluxDB(func(q string) *iclient.Response {
+		badCount++
+		// Return empty results
+		return &iclient.Response{
+			Results: []iclient.Result{},
+		}
+	})
+	defer dbBad.Close()
+	db := NewInfluxDB(func(q string) *iclient.Response {
+		stmt, err := influxql.ParseStatement(q)
+		if err != nil {
+			return &iclient.Response{Err: err.Error()}
+		}
+		slct, ok := stmt.(*influxql.SelectStatement)
+		if !ok {
+			return nil
+		}
+		cond, ok := slct.Condition.(*influxql.BinaryExpr)
+		if !ok {
+			return &iclient.Response{Err: "expected select condition to be binary expression"}
+		}
+		stopTimeExpr, ok := cond.RHS.(*influxql.BinaryExpr)
+		if !ok {
+			return &iclient.Response{Err: "expected select condition rhs to be binary expression"}
+		}
+		stopTL, ok := stopTimeExpr.RHS.(*influxql.StringLiteral)
+		if !ok {
+			return &iclient.Response{Err: "expected select condition rhs to be string literal"}
+		}
+		count++
+		switch count {
+		case 1:
+			stopTime, err := time.Parse(time.RFC3339Nano, stopTL.Val)
+			if err != nil {
+				return &iclient.Response{Err: err.Error()}
+			}
+			stopTimeC <- stopTime
+			return &iclient.Response{
+				Results: []iclient.Result{{
+					Series: []imodels.Row{{
+						Name:    "cpu",
+						Columns: []string{"time", "value"},
+						Values: [][]interface{}{
+							{
+								stopTime.Add(-2 * time.Millisecond).Format(time.RFC3339Nano),
+								1.0,
+							},
+							{
+								stopTime.Add(-1 * time.Millisecond).Format(time.RFC3339Nano),
+								1.0,
+							},
+						},
+					}},
+				}},
+			}
+		default:
+			return &iclient.Response{
+				Results: []iclient.Result{{
+					Series: []imodels.Row{{
+						Name:    "cpu",
+						Columns: []string{"time", "value"},
+						Values:  [][]interface{}{},
+					}},
+				}},
+			}
+		}
+	})
+	defer db.Close()
+
+	// Set bad URL first
+	c.InfluxDB[0].URLs = []string{dbBad.URL()}
+	s := OpenServer(c)
+	defer s.Close()
+	cli := Client(s)
+
+	id := "testBatchTask"
+	ttype := client.BatchTask
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	tick := `batch
+    |query('SELECT value from mydb.myrp.cpu')
+        .period(5ms)
+        .every(5ms)
+        .align()
+    |count('value')
+    |where(lambda: "count" == 2)
+    |httpOut('count')
+`
+
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       ttype,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Enabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Update InfluxDB config, while task is running
+	influxdbDefault := cli.ConfigElementLink("influxdb", "default")
+	if err := cli.ConfigUpdate(influxdbDefault, client.ConfigUpdateAction{
+		Set: map[string]interface{}{
+			"urls": []string{db.URL()},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), id)
+	timeout := time.NewTicker(100 * time.Millisecond)
+	defer timeout.Stop()
+	select {
+	case <-timeout.C:
+		t.Fatal("timedout waiting for query")
+	case stopTime := <-stopTimeC:
+		exp := fmt.Sprintf(`{"series":[{"name":"cpu","columns":["time","count"],"values":[["%s",2]]}]}`, stopTime.Local().Format(time.RFC3339Nano))
+		err = s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5)
+		if err != nil {
+			t.Error(err)
+		}
+		_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+			Status: client.Disabled,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if badCount == 0 {
+		t.Error("expected bad influxdb to be queried at least once")
+	}
+}
+
+func TestServer_InvalidBatchTask(t *testing.T) {
+	c := NewConfig()
+	c.InfluxDB[0].Enabled = true
+	db := NewInfluxDB(func(q string) *iclient.Response {
+		return nil
+	})
+	c.InfluxDB[0].URLs = []string{db.URL()}
+	s := OpenServer(c)
+	defer s.Close()
+	cli := Client(s)
+
+	id := "testInvalidBatchTask"
+	ttype := client.BatchTask
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	tick := `batch
+    |query(' SELECT value from unknowndb.unknownrp.cpu ')
+        .period(5ms)
+        .every(5ms)
+    |count('value')
+    |httpOut('count')
+`
+
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       ttype,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Enabled,
+	})
+	expErr := `batch query is not allowed to request data from "unknowndb"."unknownrp"`
+	if err != nil && err.Error() != expErr {
+		t.Fatalf("unexpected err: got %v exp %s", err, expErr)
+	}
+
+	err = cli.DeleteTask(task.Link)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServer_RecordReplayStream(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testStreamTask"
+	ttype := client.StreamTask
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+
+	tmpDir, err := ioutil.TempDir("", "testStreamTaskRecording")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	tick := `stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(10s)
+        .every(10s)
+    |count('value')
+    |alert()
+        .id('test-count')
+        .message('{{ .ID }} got: {{ index .Fields "count" }}')
+        .crit(lambda: TRUE)
+        .log('` + tmpDir + `/alert.log')
+`
+
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       ttype,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recording, err := cli.RecordStream(client.RecordStreamOptions{
+		ID:   "recordingid",
+		Task: task.ID,
+		Stop: time.Date(1970, 1, 1, 0, 0, 10, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := "/kapacitor/v1/recordings/recordingid", recording.Link.Href; exp != got {
+		t.Errorf("unexpected recording.Link.Href got %s exp %s", got, exp)
+	}
+
+	points := `test value=1 0000000000
+test value=1 0000000001
+test value=1 0000000001
+test value=1 0000000002
+test value=1 0000000002
+test value=1 0000000003
+test value=1 0000000003
+test value=1 0000000004
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000006
+test value=1 0000000007
+test value=1 0000000008
+test value=1 0000000009
+test value=1 0000000010
+test value=1 0000000011
+test value=1 0000000012
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+
+	retry := 0
+	for recording.Status == client.Running {
+		time.Sleep(100 * time.Millisecond)
+		recording, err = cli.Recording(recording.Link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		retry++
+		if retry > 100 {
+			t.Fatal("failed to finish recording")
+		}
+	}
+	if recording.Status != client.Finished || recording.Error != "" {
+		t.Errorf("recording failed: %s", recording.Error)
+	}
+
+	replay, err := cli.CreateReplay(client.CreateReplayOptions{
+		ID:            "replayid",
+		Task:          id,
+		Recording:     recording.ID,
+		Clock:         client.Fast,
+		RecordingTime: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := "/kapacitor/v1/replays/replayid", replay.Link.Href; exp != got {
+		t.Errorf("unexpected replay.Link.Href got %s exp %s", got, exp)
+	}
+	if exp, got := id, replay.Task; exp != got {
+		t.Errorf("unexpected replay.Task got %s exp %s", got, exp)
+	}
+
+	retry = 0
+	for replay.Status == client.Running {
+		time.Sleep(100 * time.Millisecond)
+		replay, err = cli.Replay(replay.Link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		retry++
+		if retry > 10 {
+			t.Fatal("failed to finish replay")
+		}
+	}
+	if replay.Status != client.Finished || replay.Error != "" {
+		t.Errorf("replay failed: %s", replay.Error)
+	}
+
+	f, err := os.Open(filepath.Join(tmpDir, "alert.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	type response struct {
+		ID      string          `json:"id"`
+		Message string          `json:"message"`
+		Time    time.Time       `json:"time"`
+		Level   string          `json:"level"`
+		Data    influxql.Result `json:"data"`
+	}
+	exp := response{
+		ID:      "test-count",
+		Message: "test-count got: 15",
+		Time:    time.Date(1970, 1, 1, 0, 0, 10, 0, time.UTC),
+		Level:   "CRITICAL",
+		Data: influxql.Result{
+			Series: imodels.Rows{
+				{
+					Name:    "test",
+					Columns: []string{"time", "count"},
+					Values: [][]interface{}{
+						{
+							time.Date(1970, 1, 1, 0, 0, 10, 0, time.UTC).Format(time.RFC3339Nano),
+							15.0,
+						},
+					},
+				},
+			},
+		},
+	}
+	got := response{}
+	d := json.NewDecoder(f)
+	d.Decode(&got)
+	if !reflect.DeepEqual(exp, got) {
+		t.Errorf("unexpected alert log:\ngot %v\nexp %v", got, exp)
+	}
+
+	recordings, err := cli.ListRecordings(nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if exp, got := 1, len(recordings); exp != got {
+		t.Fatalf("unexpected recordings list:\ngot %v\nexp %v\nrecordings %v", got, exp, recordings)
+	}
+
+	err = cli.DeleteRecording(recordings[0].Link)
+	if err != nil {
+		t.Error(err)
+	}
+
+	recordings, err = cli.ListRecordings(nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if exp, got := 0, len(recordings); exp != got {
+		t.Errorf("unexpected recordings list after delete:\ngot %v\nexp %v\nrecordings %v", got, exp, recordings)
+	}
+
+	replays, err := cli.ListReplays(nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if exp, got := 1, len(replays); exp != got {
+		t.Fatalf("unexpected replays list:\ngot %v\nexp %v\nreplays %v", got, exp, replays)
+	}
+
+	err = cli.DeleteReplay(replays[0].Link)
+	if err != nil {
+		t.Error(err)
+	}
+
+	replays, err = cli.ListReplays(nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if exp, got := 0, len(replays); exp != got {
+		t.Errorf("unexpected replays list after delete:\ngot %v\nexp %v\nreplays %v", got, exp, replays)
+	}
+}
+
+func TestServer_ReplaySpeed(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testReplaySpeed"
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   id,
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(10s)
+        .every(10s)
+    |count('value')
+`,
+		Status: client.Disabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	recording, err := cli.RecordStream(client.RecordStreamOptions{
+		Task: id,
+		Stop: time.Date(1970, 1, 1, 0, 0, 10, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := `test value=1 0000000000
+test value=1 0000000005
+test value=1 0000000010
+test value=1 0000000011
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+	for retry := 0; recording.Status == client.Running; retry++ {
+		if retry > 100 {
+			t.Fatal("failed to finish recording")
+		}
+		time.Sleep(100 * time.Millisecond)
+		recording, err = cli.Recording(recording.Link)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := cli.CreateReplay(client.CreateReplayOptions{
+		Task:      id,
+		Recording: recording.ID,
+		Clock:     client.Fast,
+		Speed:     10,
+	}); err == nil {
+		t.Error("expected error setting speed for the fast clock")
+	}
+	if _, err := cli.CreateReplay(client.CreateReplayOptions{
+		Task:      id,
+		Recording: recording.ID,
+		Clock:     client.Real,
+		Speed:     -1,
+	}); err == nil {
+		t.Error("expected error setting a negative speed")
+	}
+
+	// The recording spans 10s, at 20x it replays in 500ms.
+	start := time.Now()
+	replay, err := cli.CreateReplay(client.CreateReplayOptions{
+		Task:          id,
+		Recording:     recording.ID,
+		Clock:         client.Real,
+		Speed:         20,
+		RecordingTime: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := 20.0, replay.Speed; exp != got {
+		t.Errorf("unexpected replay speed got %v exp %v", got, exp)
+	}
+	for retry := 0; replay.Status == client.Running; retry++ {
+		if retry > 100 {
+			t.Fatal("failed to finish replay")
+		}
+		time.Sleep(10 * time.Millisecond)
+		replay, err = cli.Replay(replay.Link)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	elapsed := time.Since(start)
+	if replay.Status != client.Finished || replay.Error != "" {
+		t.Errorf("replay failed: %s", replay.Error)
+	}
+	if elapsed < 500*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("unexpected replay duration at 20x speed: %v", elapsed)
+	}
+	if exp, got := 20.0, replay.Speed; exp != got {
+		t.Errorf("unexpected replay speed got %v exp %v", got, exp)
+	}
+}
+
+func TestServer_ReplayDiff(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	tick := `stream
+    |from()
+        .measurement('test')
+    |alert()
+        .crit(lambda: "value" > %d)
+        .topic('diff')
+    |influxDBOut()
+        .database('out')
+        .retentionPolicy('autogen')
+        .measurement('alerts')
+`
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	for _, task := range []struct {
+		id        string
+		threshold int
+	}{
+		{id: "base", threshold: 5},
+		{id: "same", threshold: 5},
+		{id: "lower", threshold: 3},
+	} {
+		if _, err := cli.CreateTask(client.CreateTaskOptions{
+			ID:         task.id,
+			Type:       client.StreamTask,
+			DBRPs:      dbrps,
+			TICKscript: fmt.Sprintf(tick, task.threshold),
+			Status:     client.Disabled,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recording, err := cli.RecordStream(client.RecordStreamOptions{
+		Task: "base",
+		Stop: time.Date(1970, 1, 1, 0, 0, 10, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := `test value=1 0000000000
+test value=4 0000000004
+test value=7 0000000007
+test value=1 0000000011
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+	for retry := 0; recording.Status == client.Running; retry++ {
+		if retry > 100 {
+			t.Fatal("failed to finish recording")
+		}
+		time.Sleep(100 * time.Millisecond)
+		recording, err = cli.Recording(recording.Link)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := cli.ReplayDiff(client.ReplayDiffOptions{
+		Recording: recording.ID,
+		Base:      "base",
+		Task:      "base",
+	}); err == nil {
+		t.Error("expected error diffing a task against itself")
+	}
+
+	diff, err := cli.ReplayDiff(client.ReplayDiffOptions{
+		Recording:     recording.ID,
+		Base:          "base",
+		Task:          "same",
+		RecordingTime: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.Equal || len(diff.Events) != 0 || len(diff.Outputs) != 0 {
+		t.Errorf("expected identical tasks to be equal, got %+v", diff)
+	}
+	if diff.BaseEvents == 0 || diff.BaseEvents != diff.TaskEvents {
+		t.Errorf("unexpected event counts base %d task %d", diff.BaseEvents, diff.TaskEvents)
+	}
+
+	diff, err = cli.ReplayDiff(client.ReplayDiffOptions{
+		Recording:     recording.ID,
+		Base:          "base",
+		Task:          "lower",
+		RecordingTime: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Equal {
+		t.Fatal("expected tasks to differ")
+	}
+	if got, exp := len(diff.Events), 1; got != exp {
+		t.Fatalf("unexpected number of event diffs got %d exp %d: %+v", got, exp, diff.Events)
+	}
+	e := diff.Events[0]
+	if exp := time.Date(1970, 1, 1, 0, 0, 4, 0, time.UTC); !e.Time.Equal(exp) {
+		t.Errorf("unexpected event time got %v exp %v", e.Time, exp)
+	}
+	if e.Source != "topic:diff" {
+		t.Errorf("unexpected event source got %q exp %q", e.Source, "topic:diff")
+	}
+	if e.Base != nil {
+		t.Errorf("unexpected base event %+v", e.Base)
+	}
+	if e.Task == nil || e.Task.Level != "CRITICAL" {
+		t.Errorf("unexpected task event %+v", e.Task)
+	}
+	if got, exp := len(diff.Outputs), 1; got != exp {
+		t.Fatalf("unexpected number of output diffs got %d exp %d: %+v", got, exp, diff.Outputs)
+	}
+	if o := diff.Outputs[0]; o.Database != "out" || o.Measurement != "alerts" || o.Base != nil || o.Task == nil {
+		t.Errorf("unexpected output diff %+v", o)
+	}
+}
+
+func TestServer_RecordReplayObjectStorage(t *testing.T) {
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case "PUT":
+			data, _ := ioutil.ReadAll(r.Body)
+			objects[r.URL.Path] = data
+			w.WriteHeader(http.StatusCreated)
+		case "GET":
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				http.Error(w, "BlobNotFound", http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case "DELETE":
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer ts.Close()
+	object := func(name string) ([]byte, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		data, ok := objects[name]
+		return data, ok
+	}
+
+	c := NewConfig()
+	c.Replay.StorageURL = "az://recordings/kapacitor?account=test&endpoint=" + ts.URL
+	s := OpenServer(c)
+	defer s.Close()
+	cli := Client(s)
+
+	id := "testObjectStorageTask"
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   id,
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(10s)
+        .every(10s)
+    |count('value')
+`,
+		Status: client.Disabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	recording, err := cli.RecordStream(client.RecordStreamOptions{
+		ID:   "objectRecording",
+		Task: id,
+		Stop: time.Date(1970, 1, 1, 0, 0, 10, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := `test value=1 0000000000
+test value=1 0000000005
+test value=1 0000000011
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+	for retry := 0; recording.Status == client.Running; retry++ {
+		if retry > 100 {
+			t.Fatal("failed to finish recording")
+		}
+		time.Sleep(100 * time.Millisecond)
+		recording, err = cli.Recording(recording.Link)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if recording.Status != client.Finished || recording.Error != "" {
+		t.Fatalf("recording failed: %s", recording.Error)
+	}
+	data, ok := object("/recordings/kapacitor/objectRecording.srpl")
+	if !ok {
+		t.Fatal("expected recording to be uploaded")
+	}
+	if got, exp := int64(len(data)), recording.Size; got != exp {
+		t.Errorf("unexpected uploaded size got %d exp %d", got, exp)
+	}
+
+	// Remove the cached copy so the replay has to download it.
+	cached := filepath.Join(c.Replay.Dir, "objectRecording.srpl")
+	if err := os.Remove(cached); err != nil {
+		t.Fatal(err)
+	}
+	replay, err := cli.CreateReplay(client.CreateReplayOptions{
+		Task:          id,
+		Recording:     recording.ID,
+		Clock:         client.Fast,
+		RecordingTime: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for retry := 0; replay.Status == client.Running; retry++ {
+		if retry > 100 {
+			t.Fatal("failed to finish replay")
+		}
+		time.Sleep(100 * time.Millisecond)
+		replay, err = cli.Replay(replay.Link)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if replay.Status != client.Finished || replay.Error != "" {
+		t.Errorf("replay failed: %s", replay.Error)
+	}
+	if _, err := os.Stat(cached); err != nil {
+		t.Errorf("expected recording to be cached: %v", err)
+	}
+
+	if err := cli.DeleteRecording(recording.Link); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := object("/recordings/kapacitor/objectRecording.srpl"); ok {
+		t.Error("expected recording to be deleted from object storage")
+	}
+}
+
+func TestServer_RecordingRetention(t *testing.T) {
+	c := NewConfig()
+	c.Replay.Retention = toml.Duration(500 * time.Millisecond)
+	c.Replay.RetentionCheckInterval = toml.Duration(100 * time.Millisecond)
+	s := OpenServer(c)
+	defer s.Close()
+	cli := Client(s)
+
+	id := "testRetentionTask"
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   id,
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `stream
+    |from()
+        .measurement('test')
+`,
+		Status: client.Disabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	recording, err := cli.RecordStream(client.RecordStreamOptions{
+		Task: id,
+		Stop: time.Date(1970, 1, 1, 0, 0, 10, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", "test value=1 0000000011\n", v)
+
+	for retry := 0; ; retry++ {
+		if retry > 50 {
+			t.Fatal("expected recording to expire")
+		}
+		time.Sleep(100 * time.Millisecond)
+		recordings, err := cli.ListRecordings(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recordings) == 0 {
+			break
+		}
+	}
+	if _, err := os.Stat(filepath.Join(c.Replay.Dir, recording.ID+".srpl")); !os.IsNotExist(err) {
+		t.Errorf("expected recording data to be deleted, got %v", err)
+	}
+}
+
+func TestServer_RecordImport(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	if _, err := cli.RecordImport(client.RecordImportOptions{
+		Type: client.StreamTask,
+		Data: "time,value\n1970-01-01T00:00:00Z,1\n",
+	}); err == nil {
+		t.Error("expected error importing a stream recording without db and rp")
+	}
+
+	recording, err := cli.RecordImport(client.RecordImportOptions{
+		ID:              "csvImport",
+		Type:            client.StreamTask,
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+		Measurement:     "cpu",
+		TagColumns:      []string{"host"},
+		Data: `time,host,value
+1970-01-01T00:00:02Z,serverB,2.5
+1970-01-01T00:00:01Z,serverA,1
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recording.Status != client.Finished || recording.Error != "" {
+		t.Fatalf("recording failed: %s", recording.Error)
+	}
+	if exp, got := client.StreamTask, recording.Type; exp != got {
+		t.Errorf("unexpected recording.Type got %v exp %v", got, exp)
+	}
+	f, err := os.Open(filepath.Join(s.Config.Replay.Dir, "csvImport.srpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `mydb
+myrp
+cpu,host=serverA value=1i 1000000000
+mydb
+myrp
+cpu,host=serverB value=2.5 2000000000
+`
+	if got := string(data); got != exp {
+		t.Errorf("unexpected recording got:\n%s\nexp:\n%s", got, exp)
+	}
+
+	tmpDir, err := ioutil.TempDir("", "testRecordImport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	id := "testRecordImportTask"
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   id,
+		Type: client.BatchTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `batch
+    |query('SELECT usage_idle FROM "mydb"."myrp"."cpu"')
+        .period(10s)
+        .every(10s)
+        .groupBy('host')
+    |sum('usage_idle')
+    |alert()
+        .id('{{ index .Tags "host" }}')
+        .message('{{ .ID }} got: {{ index .Fields "sum" }}')
+        .crit(lambda: TRUE)
+        .log('` + tmpDir + `/alert.log')
+`,
+		Status: client.Disabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	recording, err = cli.RecordImport(client.RecordImportOptions{
+		Type: client.BatchTask,
+		Data: `#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string,string
+#group,false,false,true,true,false,false,true,true,true
+#default,_result,,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement,host
+,,0,1970-01-01T00:00:00Z,1970-01-01T00:00:10Z,1970-01-01T00:00:01Z,10,usage_idle,cpu,serverA
+,,0,1970-01-01T00:00:00Z,1970-01-01T00:00:10Z,1970-01-01T00:00:02Z,20,usage_idle,cpu,serverA
+
+#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string,string
+#group,false,false,true,true,false,false,true,true,true
+#default,_result,,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement,host
+,,1,1970-01-01T00:00:00Z,1970-01-01T00:00:10Z,1970-01-01T00:00:01Z,5,usage_idle,cpu,serverB
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recording.Status != client.Finished || recording.Error != "" {
+		t.Fatalf("recording failed: %s", recording.Error)
+	}
+	if exp, got := client.BatchTask, recording.Type; exp != got {
+		t.Errorf("unexpected recording.Type got %v exp %v", got, exp)
+	}
+
+	replay, err := cli.CreateReplay(client.CreateReplayOptions{
+		Task:          id,
+		Recording:     recording.ID,
+		Clock:         client.Fast,
+		RecordingTime: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for retry := 0; replay.Status == client.Running; retry++ {
+		if retry > 10 {
+			t.Fatal("failed to finish replay")
+		}
+		time.Sleep(100 * time.Millisecond)
+		replay, err = cli.Replay(replay.Link)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if replay.Status != client.Finished || replay.Error != "" {
+		t.Errorf("replay failed: %s", replay.Error)
+	}
+	alerts, err := ioutil.ReadFile(filepath.Join(tmpDir, "alert.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{"serverA got: 30", "serverB got: 5"} {
+		if got := string(alerts); !strings.Contains(got, exp) {
+			t.Errorf("unexpected alert log, expected it to contain %q:\n%s", exp, got)
+		}
+	}
+}
+
+func TestServer_Evaluate(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	recording, err := cli.RecordImport(client.RecordImportOptions{
+		Type:            client.StreamTask,
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+		Measurement:     "cpu",
+		TagColumns:      []string{"host"},
+		Data: `time,host,value
+1970-01-01T00:00:01Z,serverA,1
+1970-01-01T00:00:02Z,serverA,7
+1970-01-01T00:00:03Z,serverB,9
+1970-01-01T00:00:11Z,serverA,1
+1970-01-01T00:00:11Z,serverB,1
+1970-01-01T00:00:12Z,serverC,1
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbrps := []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}}
+
+	if _, err := cli.Evaluate(client.EvaluateOptions{
+		Recording: recording.ID,
+		Script:    "stream|from().measurement('cpu')|bad()",
+		DBRPs:     dbrps,
+	}); err == nil || !strings.Contains(err.Error(), "invalid TICKscript") {
+		t.Errorf("expected invalid TICKscript error, got %v", err)
+	}
+	if _, err := cli.Evaluate(client.EvaluateOptions{
+		Recording: recording.ID,
+		Script:    "stream|from().measurement('cpu')",
+	}); err == nil {
+		t.Error("expected error evaluating without dbrp")
+	}
+
+	e, err := cli.Evaluate(client.EvaluateOptions{
+		Recording: recording.ID,
+		Script: `stream
+    |from()
+        .measurement('cpu')
+    |where(lambda: "value" > 5)
+    |alert()
+        .crit(lambda: TRUE)
+        .topic('evaluate')
+    |influxDBOut()
+        .database('out')
+        .retentionPolicy('autogen')
+        .measurement('alerts')
+`,
+		DBRPs:         dbrps,
+		RecordingTime: true,
+		Limit:         1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Outputs) != 0 {
+		t.Errorf("unexpected outputs %+v", e.Outputs)
+	}
+	if got, exp := e.EventCount, int64(2); got != exp {
+		t.Errorf("unexpected event count got %d exp %d", got, exp)
+	}
+	if len(e.Events) != 1 || e.Events[0].Source != "topic:evaluate" || e.Events[0].State.Level != "CRITICAL" ||
+		!e.Events[0].State.Time.Equal(time.Date(1970, 1, 1, 0, 0, 2, 0, time.UTC)) {
+		t.Errorf("unexpected events %+v", e.Events)
+	}
+	if got, exp := e.WriteCount, int64(2); got != exp {
+		t.Errorf("unexpected write count got %d exp %d", got, exp)
+	}
+	if len(e.Writes) != 1 || e.Writes[0].Database != "out" || e.Writes[0].Tags["host"] != "serverA" {
+		t.Errorf("unexpected writes %+v", e.Writes)
+	}
+
+	// The output of the nodes without children is returned.
+	e, err = cli.Evaluate(client.EvaluateOptions{
+		Recording: recording.ID,
+		Script: `dbrp "mydb"."myrp"
+
+stream
+    |from()
+        .measurement('cpu')
+        .groupBy('host')
+    |window()
+        .period(10s)
+        .every(10s)
+        .align()
+    |max('value')
+`,
+		RecordingTime: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []client.NodeOutput{{
+		Node: "max3",
+		Rows: []client.Row{
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "serverA"},
+				Columns: []string{"time", "max"},
+				Values:  [][]interface{}{{"1970-01-01T00:00:10Z", 7.0}},
+			},
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "serverB"},
+				Columns: []string{"time", "max"},
+				Values:  [][]interface{}{{"1970-01-01T00:00:10Z", 9.0}},
+			},
+		},
+	}}
+	if len(e.Outputs) == 1 {
+		sort.Slice(e.Outputs[0].Rows, func(i, j int) bool {
+			return e.Outputs[0].Rows[i].Tags["host"] < e.Outputs[0].Rows[j].Tags["host"]
+		})
+	}
+	if !reflect.DeepEqual(exp, e.Outputs) {
+		t.Errorf("unexpected outputs:\ngot\n%+v\nexp\n%+v", e.Outputs, exp)
+	}
+}
+
+func TestServer_TaskTest(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	if _, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:   "threshold",
+		Type: client.StreamTask,
+		TICKscript: `var crit float
+
+stream
+    |from()
+        .measurement('cpu')
+    |alert()
+        .id('{{ index .Tags "host" }}')
+        .message('{{ .ID }} is {{ .Level }}')
+        .crit(lambda: "usage" > crit)
+        .topic('cpu')
+    |influxDBOut()
+        .database('out')
+        .measurement('alerts')
+`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	sec := func(n int) *client.Duration {
+		d := client.Duration(time.Duration(n) * time.Second)
+		return &d
+	}
+	test := client.TaskTest{
+		Name:     "threshold",
+		Template: "threshold",
+		Vars:     client.Vars{"crit": {Type: client.VarFloat, Value: 90.0}},
+		DBRPs:    []client.DBRP{{Database: "telegraf", RetentionPolicy: "autogen"}},
+		Time:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Points: []client.TestPoint{
+			{At: client.Duration(20 * time.Second), Line: "cpu,host=a usage=10"},
+			{At: 0, Line: "cpu,host=a usage=50"},
+			{At: client.Duration(10 * time.Second), Line: "cpu,host=a usage=95"},
+		},
+		Expect: client.TestExpectations{
+			Alerts: []client.ExpectedAlert{
+				{ID: "a", Level: "CRITICAL", Message: "a is CRITICAL", At: sec(10)},
+				{ID: "a", Level: "ok", At: sec(20)},
+			},
+			Points: []client.ExpectedPoint{
+				{Measurement: "alerts", Tags: map[string]string{"host": "a"}, Fields: map[string]interface{}{"usage": 95}, At: sec(10)},
+				{Measurement: "alerts", Fields: map[string]interface{}{"usage": 10}},
+			},
+		},
+	}
+	r, err := cli.TestTask(test)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Passed || len(r.Failures) != 0 || len(r.Alerts) != 2 || len(r.Points) != 2 {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	test.Expect.Alerts = test.Expect.Alerts[:1]
+	test.Expect.Points = []client.ExpectedPoint{}
+	test.Expect.Alerts[0].Level = "WARNING"
+	r, err = cli.TestTask(test)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{
+		"expected 1 alert events, got 2",
+		"alert event 0: expected level WARNING, got CRITICAL",
+		"alert event 1: unexpected id=a level=OK at=20s",
+		"expected 0 points, got 2",
+		"point 0: unexpected measurement=alerts tags=map[host:a] fields=map[usage:95] at=10s",
+		"point 1: unexpected measurement=alerts tags=map[host:a] fields=map[usage:10] at=20s",
+	}
+	if r.Passed || !reflect.DeepEqual(r.Failures, exp) {
+		t.Errorf("unexpected failures:\ngot\n%s\nexp\n%s", strings.Join(r.Failures, "\n"), strings.Join(exp, "\n"))
+	}
+
+	// Nil expectations are not checked.
+	r, err = cli.TestTask(client.TaskTest{
+		TICKscript: "dbrp \"telegraf\".\"autogen\"\nstream|from()|log()",
+		Points:     []client.TestPoint{{Line: "cpu usage=1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Passed {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	if _, err := cli.TestTask(client.TaskTest{
+		Template:   "threshold",
+		TICKscript: "stream|from()",
+	}); err == nil || !strings.Contains(err.Error(), "exactly one of") {
+		t.Errorf("expected error testing both a template and a script, got %v", err)
+	}
+	if _, err := cli.TestTask(client.TaskTest{
+		TICKscript: "dbrp \"telegraf\".\"autogen\"\nbatch|query('SELECT * FROM cpu')",
+	}); err == nil || !strings.Contains(err.Error(), "only stream tasks") {
+		t.Errorf("expected error testing a batch task, got %v", err)
+	}
+}
+
+func TestServer_Shadow(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testShadowCanary"
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   id,
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `stream
+    |from()
+        .measurement('test')
+    |alert()
+        .id('canary')
+        .message('{{ .ID }} is {{ .Level }}')
+        .crit(lambda: "value" > 5)
+        .topic('canary')
+`,
+		Status: client.Disabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   "testShadowBatch",
+		Type: client.BatchTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `batch
+    |query('SELECT value FROM "mydb"."myrp"."test"')
+        .period(10s)
+        .every(10s)
+`,
+		Status: client.Disabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.CreateShadow(client.CreateShadowOptions{
+		Task:     "testShadowBatch",
+		Duration: client.Duration(time.Hour),
+	}); err == nil {
+		t.Error("expected error shadowing a batch task")
+	}
+
+	shadow, err := cli.CreateShadow(client.CreateShadowOptions{
+		ID:       "canary",
+		Task:     id,
+		Duration: client.Duration(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := "/kapacitor/v1/shadows/canary", shadow.Link.Href; exp != got {
+		t.Errorf("unexpected shadow.Link.Href got %s exp %s", got, exp)
+	}
+	if exp, got := client.Running, shadow.Status; exp != got {
+		t.Errorf("unexpected shadow.Status got %v exp %v", got, exp)
+	}
+
+	points := `test value=1 0000000000
+test value=7 0000000001
+test value=3 0000000002
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+	for retry := 0; shadow.Points < 3 || shadow.EventCount < 2; retry++ {
+		if retry > 100 {
+			t.Fatalf("shadow did not process all points: %+v", shadow)
+		}
+		time.Sleep(10 * time.Millisecond)
+		shadow, err = cli.Shadow(shadow.Link)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	shadow, err = cli.UpdateShadow(shadow.Link, client.UpdateShadowOptions{Action: client.ShadowStop})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shadow.Status != client.Finished || shadow.Error != "" {
+		t.Errorf("unexpected shadow status %v: %s", shadow.Status, shadow.Error)
+	}
+	if exp, got := int64(3), shadow.Points; exp != got {
+		t.Errorf("unexpected shadow.Points got %d exp %d", got, exp)
+	}
+	// The critical event and its recovery.
+	if got, exp := len(shadow.Events), 2; got != exp {
+		t.Fatalf("unexpected number of events got %d exp %d: %+v", got, exp, shadow.Events)
+	}
+	for i, level := range []string{"CRITICAL", "OK"} {
+		e := shadow.Events[i]
+		if e.Source != "topic:canary" || e.ID != "canary" || e.Level != level {
+			t.Errorf("unexpected event %d: %+v", i, e)
+		}
+	}
+
+	// No events were delivered to the topic.
+	if _, err := cli.Topic(cli.TopicLink("canary")); err == nil {
+		t.Error("expected shadow events not to be delivered to the topic")
+	}
+
+	shadows, err := cli.ListShadows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shadows) != 1 || shadows[0].ID != "canary" {
+		t.Errorf("unexpected shadows %+v", shadows)
+	}
+	if err := cli.DeleteShadow(shadow.Link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.Shadow(shadow.Link); err == nil {
+		t.Error("expected error getting deleted shadow")
+	}
+}
+
+func TestServer_RecordReplayAlerts(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   "alerts",
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `stream
+    |from()
+        .measurement('test')
+    |alert()
+        .id('test')
+        .crit(lambda: "value" > 5)
+        .topic('recorded')
+`,
+		Status: client.Enabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	recording, err := cli.RecordAlerts(client.RecordAlertsOptions{
+		ID:    "events",
+		Topic: "recorded",
+		Stop:  time.Now().Add(time.Second),
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	// Update InfluxDB config, while task is running
-	influxdbDefault := cli.ConfigElementLink("influxdb", "default")
-	if err := cli.ConfigUpdate(influxdbDefault, client.ConfigUpdateAction{
-		Set: map[string]interface{}{
-			"urls": []string{db.URL()},
-		},
-	}); err != nil {
-		t.Fatal(err)
+	if recording.Type != client.AlertEvents {
+		t.Errorf("unexpected recording type got %v exp %v", recording.Type, client.AlertEvents)
 	}
-
-	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), id)
-	timeout := time.NewTicker(100 * time.Millisecond)
-	defer timeout.Stop()
-	select {
-	case <-timeout.C:
-		t.Fatal("timedout waiting for query")
-	case stopTime := <-stopTimeC:
-		exp := fmt.Sprintf(`{"series":[{"name":"cpu","columns":["time","count"],"values":[["%s",2]]}]}`, stopTime.Local().Format(time.RFC3339Nano))
-		err = s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5)
-		if err != nil {
-			t.Error(err)
+	points := `test value=1 0000000000
+test value=7 0000000001
+test value=1 0000000002
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+	for retry := 0; recording.Status == client.Running; retry++ {
+		if retry > 100 {
+			t.Fatal("failed to finish recording")
 		}
-		_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-			Status: client.Disabled,
-		})
+		time.Sleep(100 * time.Millisecond)
+		recording, err = cli.Recording(recording.Link)
 		if err != nil {
 			t.Fatal(err)
 		}
 	}
-	if badCount == 0 {
-		t.Error("expected bad influxdb to be queried at least once")
+	if recording.Status != client.Finished || recording.Error != "" {
+		t.Fatalf("recording failed: %s", recording.Error)
 	}
-}
-
-func TestServer_InvalidBatchTask(t *testing.T) {
-	c := NewConfig()
-	c.InfluxDB[0].Enabled = true
-	db := NewInfluxDB(func(q string) *iclient.Response {
-		return nil
-	})
-	c.InfluxDB[0].URLs = []string{db.URL()}
-	s := OpenServer(c)
-	defer s.Close()
-	cli := Client(s)
 
-	id := "testInvalidBatchTask"
-	ttype := client.BatchTask
-	dbrps := []client.DBRP{{
-		Database:        "mydb",
-		RetentionPolicy: "myrp",
-	}}
-	tick := `batch
-    |query(' SELECT value from unknowndb.unknownrp.cpu ')
-        .period(5ms)
-        .every(5ms)
-    |count('value')
-    |httpOut('count')
-`
+	if _, err := cli.CreateReplay(client.CreateReplayOptions{
+		Task:      "alerts",
+		Recording: recording.ID,
+	}); err == nil {
+		t.Error("expected error replaying alert events to a task")
+	}
 
-	task, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         id,
-		Type:       ttype,
-		DBRPs:      dbrps,
-		TICKscript: tick,
-		Status:     client.Disabled,
+	all := alerttest.NewPostServer()
+	defer all.Close()
+	crit := alerttest.NewPostServer()
+	defer crit.Close()
+	replay, err := cli.ReplayAlerts(client.ReplayAlertsOptions{
+		Recording: recording.ID,
+		Handlers: []client.TopicHandlerOptions{
+			{
+				ID:      "all",
+				Kind:    "post",
+				Options: map[string]interface{}{"url": all.URL},
+			},
+			{
+				Kind:    "post",
+				Options: map[string]interface{}{"url": crit.URL},
+				Match:   "level() == CRITICAL",
+			},
+		},
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
-		Status: client.Enabled,
-	})
-	expErr := `batch query is not allowed to request data from "unknowndb"."unknownrp"`
-	if err != nil && err.Error() != expErr {
-		t.Fatalf("unexpected err: got %v exp %s", err, expErr)
+	exp := client.AlertsReplay{
+		Recording: "events",
+		Events:    2,
+		Handlers:  []string{"all", "handler1"},
 	}
+	if !reflect.DeepEqual(replay, exp) {
+		t.Errorf("unexpected replay got %+v exp %+v", replay, exp)
+	}
+	all.Close()
+	crit.Close()
 
-	err = cli.DeleteTask(task.Link)
-	if err != nil {
-		t.Fatal(err)
+	got := all.Data()
+	if len(got) != 2 {
+		t.Fatalf("unexpected number of events posted got %d exp 2", len(got))
+	}
+	if got[0].Level != alert.Critical || got[0].PreviousLevel != alert.OK {
+		t.Errorf("unexpected first event %+v", got[0])
+	}
+	if got[1].Level != alert.OK || got[1].PreviousLevel != alert.Critical {
+		t.Errorf("unexpected second event %+v", got[1])
+	}
+	if got, exp := got[0].Time, time.Unix(1, 0).UTC(); !got.Equal(exp) {
+		t.Errorf("unexpected event time got %v exp %v", got, exp)
+	}
+	if got := crit.Data(); len(got) != 1 || got[0].Level != alert.Critical {
+		t.Errorf("unexpected events posted to matching handler %+v", got)
 	}
 }
 
-func TestServer_RecordReplayStream(t *testing.T) {
-	s, cli := OpenDefaultServer()
-	defer s.Close()
-
-	id := "testStreamTask"
-	ttype := client.StreamTask
-	dbrps := []client.DBRP{{
+func TestServer_RecordReplayRingBuffer(t *testing.T) {
+	c := NewConfig()
+	c.Replay.RingBuffers = []replay.RingBufferConfig{{
 		Database:        "mydb",
 		RetentionPolicy: "myrp",
+		Retention:       toml.Duration(time.Hour),
 	}}
+	s := OpenServer(c)
+	defer s.Close()
+	cli := Client(s)
 
-	tmpDir, err := ioutil.TempDir("", "testStreamTaskRecording")
+	id := "testRingBufferTask"
+	tmpDir, err := ioutil.TempDir("", "testRingBufferRecording")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -3403,70 +7281,92 @@ func TestServer_RecordReplayStream(t *testing.T) {
         .crit(lambda: TRUE)
         .log('` + tmpDir + `/alert.log')
 `
-
-	task, err := cli.CreateTask(client.CreateTaskOptions{
-		ID:         id,
-		Type:       ttype,
-		DBRPs:      dbrps,
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   id,
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
 		TICKscript: tick,
 		Status:     client.Disabled,
-	})
-	if err != nil {
-		t.Fatal(err)
-	}
-	recording, err := cli.RecordStream(client.RecordStreamOptions{
-		ID:   "recordingid",
-		Task: task.ID,
-		Stop: time.Date(1970, 1, 1, 0, 0, 10, 0, time.UTC),
-	})
-	if err != nil {
+	}); err != nil {
 		t.Fatal(err)
 	}
-	if exp, got := "/kapacitor/v1/recordings/recordingid", recording.Link.Href; exp != got {
-		t.Errorf("unexpected recording.Link.Href got %s exp %s", got, exp)
+
+	if _, err := cli.RecordRingBuffer(client.RecordRingBufferOptions{
+		Database:        "mydb",
+		RetentionPolicy: "otherrp",
+	}); err == nil {
+		t.Error("expected error recording unconfigured ring buffer")
 	}
 
 	points := `test value=1 0000000000
 test value=1 0000000001
-test value=1 0000000001
 test value=1 0000000002
-test value=1 0000000002
-test value=1 0000000003
 test value=1 0000000003
 test value=1 0000000004
 test value=1 0000000005
-test value=1 0000000005
-test value=1 0000000005
 test value=1 0000000006
 test value=1 0000000007
 test value=1 0000000008
 test value=1 0000000009
 test value=1 0000000010
 test value=1 0000000011
-test value=1 0000000012
 `
 	v := url.Values{}
 	v.Add("precision", "s")
 	s.MustWrite("mydb", "myrp", points, v)
-
-	retry := 0
-	for recording.Status == client.Running {
-		time.Sleep(100 * time.Millisecond)
-		recording, err = cli.Recording(recording.Link)
+	// Not part of the ring buffer
+	s.MustWrite("mydb", "otherrp", points, url.Values{"precision": []string{"s"}})
+
+	// The ring buffer consumes the stream asynchronously,
+	// snapshot until all points have been recorded.
+	var recording client.Recording
+	for retry := 0; ; retry++ {
+		recording, err = cli.RecordRingBuffer(client.RecordRingBufferOptions{
+			ID:              "ringbuffer",
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		})
 		if err != nil {
 			t.Fatal(err)
 		}
-		retry++
-		if retry > 100 {
-			t.Fatal("failed to finish recording")
+		if recording.Status != client.Finished || recording.Error != "" {
+			t.Fatalf("recording failed: %s", recording.Error)
+		}
+		f, err := os.Open(filepath.Join(s.Config.Replay.Dir, recording.ID+".srpl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(gz)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Each point is recorded on three lines: database, retention policy and line protocol.
+		if exp, got := 12*3, strings.Count(string(data), "\n"); got == exp {
+			break
+		} else if got > exp || retry > 100 {
+			t.Fatalf("unexpected number of recorded lines got %d exp %d:\n%s", got, exp, data)
+		}
+		if err := cli.DeleteRecording(recording.Link); err != nil {
+			t.Fatal(err)
 		}
+		time.Sleep(10 * time.Millisecond)
 	}
-	if recording.Status != client.Finished || recording.Error != "" {
-		t.Errorf("recording failed: %s", recording.Error)
+	if exp, got := "/kapacitor/v1/recordings/ringbuffer", recording.Link.Href; exp != got {
+		t.Errorf("unexpected recording.Link.Href got %s exp %s", got, exp)
+	}
+	if exp, got := client.StreamTask, recording.Type; exp != got {
+		t.Errorf("unexpected recording.Type got %v exp %v", got, exp)
 	}
 
 	replay, err := cli.CreateReplay(client.CreateReplayOptions{
-		ID:            "replayid",
 		Task:          id,
 		Recording:     recording.ID,
 		Clock:         client.Fast,
@@ -3475,108 +7375,186 @@ test value=1 0000000012
 	if err != nil {
 		t.Fatal(err)
 	}
-	if exp, got := "/kapacitor/v1/replays/replayid", replay.Link.Href; exp != got {
-		t.Errorf("unexpected replay.Link.Href got %s exp %s", got, exp)
-	}
-	if exp, got := id, replay.Task; exp != got {
-		t.Errorf("unexpected replay.Task got %s exp %s", got, exp)
-	}
-
-	retry = 0
-	for replay.Status == client.Running {
+	for retry := 0; replay.Status == client.Running; retry++ {
+		if retry > 10 {
+			t.Fatal("failed to finish replay")
+		}
 		time.Sleep(100 * time.Millisecond)
 		replay, err = cli.Replay(replay.Link)
 		if err != nil {
 			t.Fatal(err)
 		}
-		retry++
-		if retry > 10 {
-			t.Fatal("failed to finish replay")
-		}
 	}
 	if replay.Status != client.Finished || replay.Error != "" {
 		t.Errorf("replay failed: %s", replay.Error)
 	}
 
-	f, err := os.Open(filepath.Join(tmpDir, "alert.log"))
+	data, err := ioutil.ReadFile(filepath.Join(tmpDir, "alert.log"))
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer f.Close()
-	type response struct {
-		ID      string          `json:"id"`
-		Message string          `json:"message"`
-		Time    time.Time       `json:"time"`
-		Level   string          `json:"level"`
-		Data    influxql.Result `json:"data"`
+	if exp, got := "test-count got: 10", string(data); !strings.Contains(got, exp) {
+		t.Errorf("unexpected alert log, expected it to contain %q:\n%s", exp, got)
 	}
-	exp := response{
-		ID:      "test-count",
-		Message: "test-count got: 15",
-		Time:    time.Date(1970, 1, 1, 0, 0, 10, 0, time.UTC),
-		Level:   "CRITICAL",
-		Data: influxql.Result{
-			Series: imodels.Rows{
-				{
-					Name:    "test",
-					Columns: []string{"time", "count"},
-					Values: [][]interface{}{
-						{
-							time.Date(1970, 1, 1, 0, 0, 10, 0, time.UTC).Format(time.RFC3339Nano),
-							15.0,
-						},
-					},
-				},
-			},
-		},
+}
+
+func TestServer_DebugSession(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   "debugged",
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(5s)
+        .every(5s)
+    |count('value')
+    |log()
+`,
+		Status: client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recording, err := cli.RecordStream(client.RecordStreamOptions{
+		ID:   "debugrecording",
+		Task: task.ID,
+		Stop: time.Date(1970, 1, 1, 0, 0, 10, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := `test value=1 0000000000
+test value=1 0000000001
+test value=1 0000000002
+test value=1 0000000003
+test value=1 0000000004
+test value=1 0000000005
+test value=1 0000000006
+test value=1 0000000007
+test value=1 0000000011
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+	for retry := 0; recording.Status == client.Running; retry++ {
+		if retry > 100 {
+			t.Fatal("failed to finish recording")
+		}
+		time.Sleep(100 * time.Millisecond)
+		recording, err = cli.Recording(recording.Link)
+		if err != nil {
+			t.Fatal(err)
+		}
 	}
-	got := response{}
-	d := json.NewDecoder(f)
-	d.Decode(&got)
-	if !reflect.DeepEqual(exp, got) {
-		t.Errorf("unexpected alert log:\ngot %v\nexp %v", got, exp)
+
+	if _, err := cli.CreateDebugSession(client.CreateDebugSessionOptions{
+		Task:        task.ID,
+		Recording:   recording.ID,
+		Breakpoints: []string{"unknown"},
+	}); err == nil {
+		t.Error("expected error for unknown breakpoint")
 	}
 
-	recordings, err := cli.ListRecordings(nil)
+	session, err := cli.CreateDebugSession(client.CreateDebugSessionOptions{
+		ID:          "dbg",
+		Task:        task.ID,
+		Recording:   recording.ID,
+		Breakpoints: []string{"count3"},
+	})
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-	if exp, got := 1, len(recordings); exp != got {
-		t.Fatalf("unexpected recordings list:\ngot %v\nexp %v\nrecordings %v", got, exp, recordings)
+	if session.Status != client.Running || !session.Paused || session.Steps != 0 {
+		t.Errorf("unexpected new session %+v", session)
 	}
 
-	err = cli.DeleteRecording(recordings[0].Link)
+	session, err = cli.UpdateDebugSession(session.Link, client.UpdateDebugSessionOptions{Action: client.DebugStep, Count: 2})
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-
-	recordings, err = cli.ListRecordings(nil)
-	if err != nil {
-		t.Error(err)
+	if session.Steps != 2 || !session.Paused {
+		t.Errorf("unexpected session after step %+v", session)
 	}
-	if exp, got := 0, len(recordings); exp != got {
-		t.Errorf("unexpected recordings list after delete:\ngot %v\nexp %v\nrecordings %v", got, exp, recordings)
+	if got, exp := time.Duration(session.Elapsed), time.Second; got != exp {
+		t.Errorf("unexpected elapsed time got %v exp %v", got, exp)
+	}
+	if n := session.NodeStats["window2"]; n.Collected != 2 || n.Buffered != 2 || n.Emitted != 0 {
+		t.Errorf("unexpected window stats after step %+v", n)
 	}
 
-	replays, err := cli.ListReplays(nil)
+	// Continue until the window emits, once the point at 5s arrives.
+	session, err = cli.UpdateDebugSession(session.Link, client.UpdateDebugSessionOptions{Action: client.DebugContinue})
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-	if exp, got := 1, len(replays); exp != got {
-		t.Fatalf("unexpected replays list:\ngot %v\nexp %v\nreplays %v", got, exp, replays)
+	for retry := 0; !session.Paused; retry++ {
+		if retry > 100 {
+			t.Fatal("debug session did not reach breakpoint")
+		}
+		time.Sleep(10 * time.Millisecond)
+		session, err = cli.DebugSession(session.Link)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if session.Breakpoint != "count3" || session.Steps != 6 {
+		t.Errorf("unexpected session at breakpoint %+v", session)
+	}
+	if n := session.NodeStats["count3"]; n.Emitted != 1 {
+		t.Errorf("unexpected count stats at breakpoint %+v", n)
+	}
+	// The window keeps the emitted points until it purges them at its next emit.
+	if n := session.NodeStats["window2"]; n.Buffered != 6 {
+		t.Errorf("unexpected window stats at breakpoint %+v", n)
 	}
 
-	err = cli.DeleteReplay(replays[0].Link)
-	if err != nil {
-		t.Error(err)
+	// Remove the breakpoints and replay the rest of the recording.
+	if _, err := cli.UpdateDebugSession(session.Link, client.UpdateDebugSessionOptions{Breakpoints: []string{}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.UpdateDebugSession(session.Link, client.UpdateDebugSessionOptions{Action: client.DebugContinue}); err != nil {
+		t.Fatal(err)
+	}
+	for retry := 0; session.Status == client.Running; retry++ {
+		if retry > 100 {
+			t.Fatal("debug session did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+		session, err = cli.DebugSession(session.Link)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if session.Status != client.Finished || session.Error != "" || session.Steps != 8 {
+		t.Errorf("unexpected finished session %+v", session)
+	}
+	if n := session.NodeStats["from1"]; n.Emitted != 8 {
+		t.Errorf("unexpected from stats after finishing %+v", n)
+	}
+	if _, err := cli.UpdateDebugSession(session.Link, client.UpdateDebugSessionOptions{Action: client.DebugStep}); err == nil {
+		t.Error("expected error stepping finished session")
 	}
 
-	replays, err = cli.ListReplays(nil)
+	sessions, err := cli.ListDebugSessions()
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-	if exp, got := 0, len(replays); exp != got {
-		t.Errorf("unexpected replays list after delete:\ngot %v\nexp %v\nreplays %v", got, exp, replays)
+	if len(sessions) != 1 || sessions[0].ID != "dbg" {
+		t.Errorf("unexpected sessions %+v", sessions)
+	}
+	if err := cli.DeleteDebugSession(session.Link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.DebugSession(session.Link); err == nil {
+		t.Error("expected error getting deleted session")
 	}
 }
 
@@ -5266,6 +9244,16 @@ func TestServer_RecordReplayQuery_Missing(t *testing.T) {
 	}
 }
 
+func TestServer_UpgradeUDF_Missing(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	err := cli.UpgradeUDF("missing", client.UpgradeUDFOptions{})
+	if exp := "no such UDF missing"; err == nil || err.Error() != exp {
+		t.Fatalf("unexpected error upgrading missing UDF: got %v exp %s", err, exp)
+	}
+}
+
 // If this test fails due to missing python dependencies, run 'INSTALL_PREFIX=/usr/local ./install-deps.sh' from the root directory of the
 // kapacitor project.
 func TestServer_UDFStreamAgents(t *testing.T) {
@@ -6237,6 +10225,155 @@ func TestServer_CreateReplay_ValidIDs(t *testing.T) {
 	}
 }
 
+func TestServer_ConfigApply(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	// Validating an invalid update fails, and a valid one applies nothing.
+	if err := cli.ConfigValidate(cli.ConfigElementLink("smtp", ""), client.ConfigUpdateAction{
+		Set: map[string]interface{}{"port": "bad"},
+	}); err == nil {
+		t.Error("expected error validating invalid smtp port")
+	}
+	if err := cli.ConfigValidate(cli.ConfigElementLink("smtp", ""), client.ConfigUpdateAction{
+		Set: map[string]interface{}{"from": "kapacitor@example.com"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	diff, err := cli.ConfigDiff("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Sections) != 0 {
+		t.Fatalf("unexpected diff after validation %+v", diff)
+	}
+
+	if err := cli.ConfigApply(client.ConfigApplyOptions{
+		Updates: []client.ConfigStagedUpdate{
+			{
+				Section:            "smtp",
+				ConfigUpdateAction: client.ConfigUpdateAction{Set: map[string]interface{}{"from": "kapacitor@example.com"}},
+			},
+			{
+				Section:            "alerta",
+				ConfigUpdateAction: client.ConfigUpdateAction{Set: map[string]interface{}{"environment": "production"}},
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	diff, err = cli.ConfigDiff("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]client.ConfigSectionDiff{
+		"smtp": {
+			Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/smtp"},
+			Elements: []client.ConfigElementDiff{{
+				Link:    client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/smtp/"},
+				Options: map[string]client.ConfigOptionDiff{"from": {File: "", Running: "kapacitor@example.com"}},
+			}},
+		},
+		"alerta": {
+			Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/alerta"},
+			Elements: []client.ConfigElementDiff{{
+				Link:    client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/alerta/"},
+				Options: map[string]client.ConfigOptionDiff{"environment": {File: "", Running: "production"}},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(diff.Sections, exp) {
+		t.Errorf("unexpected diff:\ngot\n%+v\nexp\n%+v", diff.Sections, exp)
+	}
+}
+
+func TestServer_ReloadConfig(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testStreamTask"
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:    id,
+		Type:  client.StreamTask,
+		DBRPs: []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TICKscript: `stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(10s)
+        .every(10s)
+    |count('value')
+    |httpOut('count')
+`,
+		Status: client.Enabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", `test value=1 0000000000
+test value=1 0000000001
+test value=1 0000000002
+test value=1 0000000003
+test value=1 0000000004
+`, v)
+
+	// Move the HTTP listener, add a UDP listener and change the log level.
+	oldURL := s.URL()
+	c := *s.Config
+	c.HTTP.BindAddress = "127.0.0.1:0"
+	c.UDP = []udp.Config{{
+		Enabled:         true,
+		BindAddress:     "127.0.0.1:0",
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	c.Logging.Level = "ERROR"
+	if err := s.ReloadConfig(&c); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.URL() == oldURL {
+		t.Fatalf("HTTP listener not moved from %s", oldURL)
+	}
+	// Open connections are kept, new connections are refused.
+	newClient := &http.Client{Transport: &http.Transport{}}
+	if _, err := newClient.Get(oldURL + "/ping"); err == nil {
+		t.Errorf("expected the previous HTTP listener %s to be closed", oldURL)
+	}
+	if level, _, _ := s.DiagService.LogLevels(); level != "ERROR" {
+		t.Errorf("unexpected log level: got %s exp ERROR", level)
+	}
+	i, ok := s.ServicesByName["udp0"]
+	if !ok {
+		t.Fatal("expected the udp0 service")
+	}
+	conn, err := net.DialUDP("udp", nil, s.Services[i].(*udp.Service).Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The task keeps its window across the reload.
+	s.MustWrite("mydb", "myrp", `test value=1 0000000005
+test value=1 0000000006
+test value=1 0000000007
+test value=1 0000000008
+`, v)
+	if _, err := conn.Write([]byte("test value=1 9000000000\n")); err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the UDP point before closing the window.
+	time.Sleep(100 * time.Millisecond)
+	s.MustWrite("mydb", "myrp", "test value=1 0000000010\n", v)
+
+	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), id)
+	exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",10]]}]}`
+	if err := s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestServer_UpdateConfig(t *testing.T) {
 	type updateAction struct {
 		element      string
@@ -6271,29 +10408,36 @@ func TestServer_UpdateConfig(t *testing.T) {
 				Elements: []client.ConfigElement{{
 					Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/influxdb/default"},
 					Options: map[string]interface{}{
-						"default":                     false,
-						"disable-subscriptions":       false,
-						"enabled":                     true,
-						"excluded-subscriptions":      map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
-						"http-port":                   float64(0),
-						"insecure-skip-verify":        false,
-						"kapacitor-hostname":          "",
-						"name":                        "default",
-						"password":                    true,
-						"ssl-ca":                      "",
-						"ssl-cert":                    "",
-						"ssl-key":                     "",
-						"startup-timeout":             "1h0m0s",
-						"subscription-protocol":       "http",
-						"subscription-mode":           "cluster",
-						"subscriptions":               nil,
-						"subscriptions-sync-interval": "1m0s",
-						"timeout":                     "0s",
-						"udp-bind":                    "",
-						"udp-buffer":                  float64(1e3),
-						"udp-read-buffer":             float64(0),
-						"urls":                        []interface{}{db.URL()},
-						"username":                    "bob",
+						"default":                            false,
+						"disable-subscriptions":              false,
+						"enabled":                            true,
+						"excluded-subscriptions":             map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
+						"http-port":                          float64(0),
+						"insecure-skip-verify":               false,
+						"kapacitor-hostname":                 "",
+						"name":                               "default",
+						"password":                           true,
+						"ssl-ca":                             "",
+						"ssl-cert":                           "",
+						"ssl-key":                            "",
+						"startup-timeout":                    "1h0m0s",
+						"subscription-protocol":              "http",
+						"subscription-mode":                  "cluster",
+						"subscriptions":                      nil,
+						"subscriptions-sync-interval":        "1m0s",
+						"health-check-interval":              "10s",
+						"subscription-http-bind":             "",
+						"subscription-ssl-cert":              "",
+						"subscription-ssl-key":               "",
+						"udp-allowed-sources":                nil,
+						"subscription-measurements":          nil,
+						"excluded-subscription-measurements": nil,
+						"timeout":                            "0s",
+						"udp-bind":                           "",
+						"udp-buffer":                         float64(1e3),
+						"udp-read-buffer":                    float64(0),
+						"urls":                               []interface{}{db.URL()},
+						"username":                           "bob",
 					},
 					Redacted: []string{
 						"password",
@@ -6303,29 +10447,36 @@ func TestServer_UpdateConfig(t *testing.T) {
 			expDefaultElement: client.ConfigElement{
 				Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/influxdb/default"},
 				Options: map[string]interface{}{
-					"default":                     false,
-					"disable-subscriptions":       false,
-					"enabled":                     true,
-					"excluded-subscriptions":      map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
-					"http-port":                   float64(0),
-					"insecure-skip-verify":        false,
-					"kapacitor-hostname":          "",
-					"name":                        "default",
-					"password":                    true,
-					"ssl-ca":                      "",
-					"ssl-cert":                    "",
-					"ssl-key":                     "",
-					"startup-timeout":             "1h0m0s",
-					"subscription-protocol":       "http",
-					"subscription-mode":           "cluster",
-					"subscriptions":               nil,
-					"subscriptions-sync-interval": "1m0s",
-					"timeout":                     "0s",
-					"udp-bind":                    "",
-					"udp-buffer":                  float64(1e3),
-					"udp-read-buffer":             float64(0),
-					"urls":                        []interface{}{db.URL()},
-					"username":                    "bob",
+					"default":                            false,
+					"disable-subscriptions":              false,
+					"enabled":                            true,
+					"excluded-subscriptions":             map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
+					"http-port":                          float64(0),
+					"insecure-skip-verify":               false,
+					"kapacitor-hostname":                 "",
+					"name":                               "default",
+					"password":                           true,
+					"ssl-ca":                             "",
+					"ssl-cert":                           "",
+					"ssl-key":                            "",
+					"startup-timeout":                    "1h0m0s",
+					"subscription-protocol":              "http",
+					"subscription-mode":                  "cluster",
+					"subscriptions":                      nil,
+					"subscriptions-sync-interval":        "1m0s",
+					"health-check-interval":              "10s",
+					"subscription-http-bind":             "",
+					"subscription-ssl-cert":              "",
+					"subscription-ssl-key":               "",
+					"udp-allowed-sources":                nil,
+					"subscription-measurements":          nil,
+					"excluded-subscription-measurements": nil,
+					"timeout":                            "0s",
+					"udp-bind":                           "",
+					"udp-buffer":                         float64(1e3),
+					"udp-read-buffer":                    float64(0),
+					"urls":                               []interface{}{db.URL()},
+					"username":                           "bob",
 				},
 				Redacted: []string{
 					"password",
@@ -6345,29 +10496,36 @@ func TestServer_UpdateConfig(t *testing.T) {
 						Elements: []client.ConfigElement{{
 							Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/influxdb/default"},
 							Options: map[string]interface{}{
-								"default":                     false,
-								"disable-subscriptions":       false,
-								"enabled":                     true,
-								"excluded-subscriptions":      map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
-								"http-port":                   float64(0),
-								"insecure-skip-verify":        false,
-								"kapacitor-hostname":          "",
-								"name":                        "default",
-								"password":                    true,
-								"ssl-ca":                      "",
-								"ssl-cert":                    "",
-								"ssl-key":                     "",
-								"startup-timeout":             "1h0m0s",
-								"subscription-protocol":       "http",
-								"subscription-mode":           "cluster",
-								"subscriptions":               nil,
-								"subscriptions-sync-interval": "1m0s",
-								"timeout":                     "0s",
-								"udp-bind":                    "",
-								"udp-buffer":                  float64(1e3),
-								"udp-read-buffer":             float64(0),
-								"urls":                        []interface{}{"http://192.0.2.0:8086"},
-								"username":                    "bob",
+								"default":                            false,
+								"disable-subscriptions":              false,
+								"enabled":                            true,
+								"excluded-subscriptions":             map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
+								"http-port":                          float64(0),
+								"insecure-skip-verify":               false,
+								"kapacitor-hostname":                 "",
+								"name":                               "default",
+								"password":                           true,
+								"ssl-ca":                             "",
+								"ssl-cert":                           "",
+								"ssl-key":                            "",
+								"startup-timeout":                    "1h0m0s",
+								"subscription-protocol":              "http",
+								"subscription-mode":                  "cluster",
+								"subscriptions":                      nil,
+								"subscriptions-sync-interval":        "1m0s",
+								"health-check-interval":              "10s",
+								"subscription-http-bind":             "",
+								"subscription-ssl-cert":              "",
+								"subscription-ssl-key":               "",
+								"udp-allowed-sources":                nil,
+								"subscription-measurements":          nil,
+								"excluded-subscription-measurements": nil,
+								"timeout":                            "0s",
+								"udp-bind":                           "",
+								"udp-buffer":                         float64(1e3),
+								"udp-read-buffer":                    float64(0),
+								"urls":                               []interface{}{"http://192.0.2.0:8086"},
+								"username":                           "bob",
 							},
 							Redacted: []string{
 								"password",
@@ -6377,29 +10535,36 @@ func TestServer_UpdateConfig(t *testing.T) {
 					expElement: client.ConfigElement{
 						Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/influxdb/default"},
 						Options: map[string]interface{}{
-							"default":                     false,
-							"disable-subscriptions":       false,
-							"enabled":                     true,
-							"excluded-subscriptions":      map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
-							"http-port":                   float64(0),
-							"insecure-skip-verify":        false,
-							"kapacitor-hostname":          "",
-							"name":                        "default",
-							"password":                    true,
-							"ssl-ca":                      "",
-							"ssl-cert":                    "",
-							"ssl-key":                     "",
-							"startup-timeout":             "1h0m0s",
-							"subscription-protocol":       "http",
-							"subscription-mode":           "cluster",
-							"subscriptions":               nil,
-							"subscriptions-sync-interval": "1m0s",
-							"timeout":                     "0s",
-							"udp-bind":                    "",
-							"udp-buffer":                  float64(1e3),
-							"udp-read-buffer":             float64(0),
-							"urls":                        []interface{}{"http://192.0.2.0:8086"},
-							"username":                    "bob",
+							"default":                            false,
+							"disable-subscriptions":              false,
+							"enabled":                            true,
+							"excluded-subscriptions":             map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
+							"http-port":                          float64(0),
+							"insecure-skip-verify":               false,
+							"kapacitor-hostname":                 "",
+							"name":                               "default",
+							"password":                           true,
+							"ssl-ca":                             "",
+							"ssl-cert":                           "",
+							"ssl-key":                            "",
+							"startup-timeout":                    "1h0m0s",
+							"subscription-protocol":              "http",
+							"subscription-mode":                  "cluster",
+							"subscriptions":                      nil,
+							"subscriptions-sync-interval":        "1m0s",
+							"health-check-interval":              "10s",
+							"subscription-http-bind":             "",
+							"subscription-ssl-cert":              "",
+							"subscription-ssl-key":               "",
+							"udp-allowed-sources":                nil,
+							"subscription-measurements":          nil,
+							"excluded-subscription-measurements": nil,
+							"timeout":                            "0s",
+							"udp-bind":                           "",
+							"udp-buffer":                         float64(1e3),
+							"udp-read-buffer":                    float64(0),
+							"urls":                               []interface{}{"http://192.0.2.0:8086"},
+							"username":                           "bob",
 						},
 						Redacted: []string{
 							"password",
@@ -6420,29 +10585,36 @@ func TestServer_UpdateConfig(t *testing.T) {
 						Elements: []client.ConfigElement{{
 							Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/influxdb/default"},
 							Options: map[string]interface{}{
-								"default":                     true,
-								"disable-subscriptions":       false,
-								"enabled":                     true,
-								"excluded-subscriptions":      map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
-								"http-port":                   float64(0),
-								"insecure-skip-verify":        false,
-								"kapacitor-hostname":          "",
-								"name":                        "default",
-								"password":                    true,
-								"ssl-ca":                      "",
-								"ssl-cert":                    "",
-								"ssl-key":                     "",
-								"startup-timeout":             "1h0m0s",
-								"subscription-protocol":       "https",
-								"subscription-mode":           "cluster",
-								"subscriptions":               map[string]interface{}{"_internal": []interface{}{"monitor"}},
-								"subscriptions-sync-interval": "1m0s",
-								"timeout":                     "0s",
-								"udp-bind":                    "",
-								"udp-buffer":                  float64(1e3),
-								"udp-read-buffer":             float64(0),
-								"urls":                        []interface{}{"http://192.0.2.0:8086"},
-								"username":                    "bob",
+								"default":                            true,
+								"disable-subscriptions":              false,
+								"enabled":                            true,
+								"excluded-subscriptions":             map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
+								"http-port":                          float64(0),
+								"insecure-skip-verify":               false,
+								"kapacitor-hostname":                 "",
+								"name":                               "default",
+								"password":                           true,
+								"ssl-ca":                             "",
+								"ssl-cert":                           "",
+								"ssl-key":                            "",
+								"startup-timeout":                    "1h0m0s",
+								"subscription-protocol":              "https",
+								"subscription-mode":                  "cluster",
+								"subscriptions":                      map[string]interface{}{"_internal": []interface{}{"monitor"}},
+								"subscriptions-sync-interval":        "1m0s",
+								"health-check-interval":              "10s",
+								"subscription-http-bind":             "",
+								"subscription-ssl-cert":              "",
+								"subscription-ssl-key":               "",
+								"udp-allowed-sources":                nil,
+								"subscription-measurements":          nil,
+								"excluded-subscription-measurements": nil,
+								"timeout":                            "0s",
+								"udp-bind":                           "",
+								"udp-buffer":                         float64(1e3),
+								"udp-read-buffer":                    float64(0),
+								"urls":                               []interface{}{"http://192.0.2.0:8086"},
+								"username":                           "bob",
 							},
 							Redacted: []string{
 								"password",
@@ -6452,29 +10624,36 @@ func TestServer_UpdateConfig(t *testing.T) {
 					expElement: client.ConfigElement{
 						Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/influxdb/default"},
 						Options: map[string]interface{}{
-							"default":                     true,
-							"disable-subscriptions":       false,
-							"enabled":                     true,
-							"excluded-subscriptions":      map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
-							"http-port":                   float64(0),
-							"insecure-skip-verify":        false,
-							"kapacitor-hostname":          "",
-							"name":                        "default",
-							"password":                    true,
-							"ssl-ca":                      "",
-							"ssl-cert":                    "",
-							"ssl-key":                     "",
-							"startup-timeout":             "1h0m0s",
-							"subscription-protocol":       "https",
-							"subscription-mode":           "cluster",
-							"subscriptions":               map[string]interface{}{"_internal": []interface{}{"monitor"}},
-							"subscriptions-sync-interval": "1m0s",
-							"timeout":                     "0s",
-							"udp-bind":                    "",
-							"udp-buffer":                  float64(1e3),
-							"udp-read-buffer":             float64(0),
-							"urls":                        []interface{}{"http://192.0.2.0:8086"},
-							"username":                    "bob",
+							"default":                            true,
+							"disable-subscriptions":              false,
+							"enabled":                            true,
+							"excluded-subscriptions":             map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
+							"http-port":                          float64(0),
+							"insecure-skip-verify":               false,
+							"kapacitor-hostname":                 "",
+							"name":                               "default",
+							"password":                           true,
+							"ssl-ca":                             "",
+							"ssl-cert":                           "",
+							"ssl-key":                            "",
+							"startup-timeout":                    "1h0m0s",
+							"subscription-protocol":              "https",
+							"subscription-mode":                  "cluster",
+							"subscriptions":                      map[string]interface{}{"_internal": []interface{}{"monitor"}},
+							"subscriptions-sync-interval":        "1m0s",
+							"health-check-interval":              "10s",
+							"subscription-http-bind":             "",
+							"subscription-ssl-cert":              "",
+							"subscription-ssl-key":               "",
+							"udp-allowed-sources":                nil,
+							"subscription-measurements":          nil,
+							"excluded-subscription-measurements": nil,
+							"timeout":                            "0s",
+							"udp-bind":                           "",
+							"udp-buffer":                         float64(1e3),
+							"udp-read-buffer":                    float64(0),
+							"urls":                               []interface{}{"http://192.0.2.0:8086"},
+							"username":                           "bob",
 						},
 						Redacted: []string{
 							"password",
@@ -6491,29 +10670,36 @@ func TestServer_UpdateConfig(t *testing.T) {
 						Elements: []client.ConfigElement{{
 							Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/influxdb/default"},
 							Options: map[string]interface{}{
-								"default":                     true,
-								"disable-subscriptions":       false,
-								"enabled":                     true,
-								"excluded-subscriptions":      map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
-								"http-port":                   float64(0),
-								"insecure-skip-verify":        false,
-								"kapacitor-hostname":          "",
-								"name":                        "default",
-								"password":                    true,
-								"ssl-ca":                      "",
-								"ssl-cert":                    "",
-								"ssl-key":                     "",
-								"startup-timeout":             "1h0m0s",
-								"subscription-protocol":       "https",
-								"subscription-mode":           "cluster",
-								"subscriptions":               map[string]interface{}{"_internal": []interface{}{"monitor"}},
-								"subscriptions-sync-interval": "1m0s",
-								"timeout":                     "0s",
-								"udp-bind":                    "",
-								"udp-buffer":                  float64(1e3),
-								"udp-read-buffer":             float64(0),
-								"urls":                        []interface{}{db.URL()},
-								"username":                    "bob",
+								"default":                            true,
+								"disable-subscriptions":              false,
+								"enabled":                            true,
+								"excluded-subscriptions":             map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
+								"http-port":                          float64(0),
+								"insecure-skip-verify":               false,
+								"kapacitor-hostname":                 "",
+								"name":                               "default",
+								"password":                           true,
+								"ssl-ca":                             "",
+								"ssl-cert":                           "",
+								"ssl-key":                            "",
+								"startup-timeout":                    "1h0m0s",
+								"subscription-protocol":              "https",
+								"subscription-mode":                  "cluster",
+								"subscriptions":                      map[string]interface{}{"_internal": []interface{}{"monitor"}},
+								"subscriptions-sync-interval":        "1m0s",
+								"health-check-interval":              "10s",
+								"subscription-http-bind":             "",
+								"subscription-ssl-cert":              "",
+								"subscription-ssl-key":               "",
+								"udp-allowed-sources":                nil,
+								"subscription-measurements":          nil,
+								"excluded-subscription-measurements": nil,
+								"timeout":                            "0s",
+								"udp-bind":                           "",
+								"udp-buffer":                         float64(1e3),
+								"udp-read-buffer":                    float64(0),
+								"urls":                               []interface{}{db.URL()},
+								"username":                           "bob",
 							},
 							Redacted: []string{
 								"password",
@@ -6523,29 +10709,36 @@ func TestServer_UpdateConfig(t *testing.T) {
 					expElement: client.ConfigElement{
 						Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/influxdb/default"},
 						Options: map[string]interface{}{
-							"default":                     true,
-							"disable-subscriptions":       false,
-							"enabled":                     true,
-							"excluded-subscriptions":      map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
-							"http-port":                   float64(0),
-							"insecure-skip-verify":        false,
-							"kapacitor-hostname":          "",
-							"name":                        "default",
-							"password":                    true,
-							"ssl-ca":                      "",
-							"ssl-cert":                    "",
-							"ssl-key":                     "",
-							"startup-timeout":             "1h0m0s",
-							"subscription-protocol":       "https",
-							"subscription-mode":           "cluster",
-							"subscriptions":               map[string]interface{}{"_internal": []interface{}{"monitor"}},
-							"subscriptions-sync-interval": "1m0s",
-							"timeout":                     "0s",
-							"udp-bind":                    "",
-							"udp-buffer":                  float64(1e3),
-							"udp-read-buffer":             float64(0),
-							"urls":                        []interface{}{db.URL()},
-							"username":                    "bob",
+							"default":                            true,
+							"disable-subscriptions":              false,
+							"enabled":                            true,
+							"excluded-subscriptions":             map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
+							"http-port":                          float64(0),
+							"insecure-skip-verify":               false,
+							"kapacitor-hostname":                 "",
+							"name":                               "default",
+							"password":                           true,
+							"ssl-ca":                             "",
+							"ssl-cert":                           "",
+							"ssl-key":                            "",
+							"startup-timeout":                    "1h0m0s",
+							"subscription-protocol":              "https",
+							"subscription-mode":                  "cluster",
+							"subscriptions":                      map[string]interface{}{"_internal": []interface{}{"monitor"}},
+							"subscriptions-sync-interval":        "1m0s",
+							"health-check-interval":              "10s",
+							"subscription-http-bind":             "",
+							"subscription-ssl-cert":              "",
+							"subscription-ssl-key":               "",
+							"udp-allowed-sources":                nil,
+							"subscription-measurements":          nil,
+							"excluded-subscription-measurements": nil,
+							"timeout":                            "0s",
+							"udp-bind":                           "",
+							"udp-buffer":                         float64(1e3),
+							"udp-read-buffer":                    float64(0),
+							"urls":                               []interface{}{db.URL()},
+							"username":                           "bob",
 						},
 						Redacted: []string{
 							"password",
@@ -6566,29 +10759,36 @@ func TestServer_UpdateConfig(t *testing.T) {
 							{
 								Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/influxdb/default"},
 								Options: map[string]interface{}{
-									"default":                     true,
-									"disable-subscriptions":       false,
-									"enabled":                     true,
-									"excluded-subscriptions":      map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
-									"http-port":                   float64(0),
-									"insecure-skip-verify":        false,
-									"kapacitor-hostname":          "",
-									"name":                        "default",
-									"password":                    true,
-									"ssl-ca":                      "",
-									"ssl-cert":                    "",
-									"ssl-key":                     "",
-									"startup-timeout":             "1h0m0s",
-									"subscription-protocol":       "https",
-									"subscription-mode":           "cluster",
-									"subscriptions":               map[string]interface{}{"_internal": []interface{}{"monitor"}},
-									"subscriptions-sync-interval": "1m0s",
-									"timeout":                     "0s",
-									"udp-bind":                    "",
-									"udp-buffer":                  float64(1e3),
-									"udp-read-buffer":             float64(0),
-									"urls":                        []interface{}{db.URL()},
-									"username":                    "bob",
+									"default":                            true,
+									"disable-subscriptions":              false,
+									"enabled":                            true,
+									"excluded-subscriptions":             map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
+									"http-port":                          float64(0),
+									"insecure-skip-verify":               false,
+									"kapacitor-hostname":                 "",
+									"name":                               "default",
+									"password":                           true,
+									"ssl-ca":                             "",
+									"ssl-cert":                           "",
+									"ssl-key":                            "",
+									"startup-timeout":                    "1h0m0s",
+									"subscription-protocol":              "https",
+									"subscription-mode":                  "cluster",
+									"subscriptions":                      map[string]interface{}{"_internal": []interface{}{"monitor"}},
+									"subscriptions-sync-interval":        "1m0s",
+									"health-check-interval":              "10s",
+									"subscription-http-bind":             "",
+									"subscription-ssl-cert":              "",
+									"subscription-ssl-key":               "",
+									"udp-allowed-sources":                nil,
+									"subscription-measurements":          nil,
+									"excluded-subscription-measurements": nil,
+									"timeout":                            "0s",
+									"udp-bind":                           "",
+									"udp-buffer":                         float64(1e3),
+									"udp-read-buffer":                    float64(0),
+									"urls":                               []interface{}{db.URL()},
+									"username":                           "bob",
 								},
 								Redacted: []string{
 									"password",
@@ -6597,29 +10797,36 @@ func TestServer_UpdateConfig(t *testing.T) {
 							{
 								Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/influxdb/new"},
 								Options: map[string]interface{}{
-									"default":                     false,
-									"disable-subscriptions":       false,
-									"enabled":                     false,
-									"excluded-subscriptions":      map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
-									"http-port":                   float64(0),
-									"insecure-skip-verify":        false,
-									"kapacitor-hostname":          "",
-									"name":                        "new",
-									"password":                    false,
-									"ssl-ca":                      "",
-									"ssl-cert":                    "",
-									"ssl-key":                     "",
-									"startup-timeout":             "5m0s",
-									"subscription-protocol":       "http",
-									"subscription-mode":           "cluster",
-									"subscriptions":               nil,
-									"subscriptions-sync-interval": "1m0s",
-									"timeout":                     "0s",
-									"udp-bind":                    "",
-									"udp-buffer":                  float64(1e3),
-									"udp-read-buffer":             float64(0),
-									"urls":                        []interface{}{db.URL()},
-									"username":                    "",
+									"default":                            false,
+									"disable-subscriptions":              false,
+									"enabled":                            false,
+									"excluded-subscriptions":             map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
+									"http-port":                          float64(0),
+									"insecure-skip-verify":               false,
+									"kapacitor-hostname":                 "",
+									"name":                               "new",
+									"password":                           false,
+									"ssl-ca":                             "",
+									"ssl-cert":                           "",
+									"ssl-key":                            "",
+									"startup-timeout":                    "5m0s",
+									"subscription-protocol":              "http",
+									"subscription-mode":                  "cluster",
+									"subscriptions":                      nil,
+									"subscriptions-sync-interval":        "1m0s",
+									"health-check-interval":              "10s",
+									"subscription-http-bind":             "",
+									"subscription-ssl-cert":              "",
+									"subscription-ssl-key":               "",
+									"udp-allowed-sources":                nil,
+									"subscription-measurements":          nil,
+									"excluded-subscription-measurements": nil,
+									"timeout":                            "0s",
+									"udp-bind":                           "",
+									"udp-buffer":                         float64(1e3),
+									"udp-read-buffer":                    float64(0),
+									"urls":                               []interface{}{db.URL()},
+									"username":                           "",
 								},
 								Redacted: []string{
 									"password",
@@ -6630,29 +10837,36 @@ func TestServer_UpdateConfig(t *testing.T) {
 					expElement: client.ConfigElement{
 						Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/influxdb/new"},
 						Options: map[string]interface{}{
-							"default":                     false,
-							"disable-subscriptions":       false,
-							"enabled":                     false,
-							"excluded-subscriptions":      map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
-							"http-port":                   float64(0),
-							"insecure-skip-verify":        false,
-							"kapacitor-hostname":          "",
-							"name":                        "new",
-							"password":                    false,
-							"ssl-ca":                      "",
-							"ssl-cert":                    "",
-							"ssl-key":                     "",
-							"startup-timeout":             "5m0s",
-							"subscription-protocol":       "http",
-							"subscriptions":               nil,
-							"subscription-mode":           "cluster",
-							"subscriptions-sync-interval": "1m0s",
-							"timeout":                     "0s",
-							"udp-bind":                    "",
-							"udp-buffer":                  float64(1e3),
-							"udp-read-buffer":             float64(0),
-							"urls":                        []interface{}{db.URL()},
-							"username":                    "",
+							"default":                            false,
+							"disable-subscriptions":              false,
+							"enabled":                            false,
+							"excluded-subscriptions":             map[string]interface{}{"_kapacitor": []interface{}{"autogen"}},
+							"http-port":                          float64(0),
+							"insecure-skip-verify":               false,
+							"kapacitor-hostname":                 "",
+							"name":                               "new",
+							"password":                           false,
+							"ssl-ca":                             "",
+							"ssl-cert":                           "",
+							"ssl-key":                            "",
+							"startup-timeout":                    "5m0s",
+							"subscription-protocol":              "http",
+							"subscriptions":                      nil,
+							"subscription-mode":                  "cluster",
+							"subscriptions-sync-interval":        "1m0s",
+							"health-check-interval":              "10s",
+							"subscription-http-bind":             "",
+							"subscription-ssl-cert":              "",
+							"subscription-ssl-key":               "",
+							"udp-allowed-sources":                nil,
+							"subscription-measurements":          nil,
+							"excluded-subscription-measurements": nil,
+							"timeout":                            "0s",
+							"udp-bind":                           "",
+							"udp-buffer":                         float64(1e3),
+							"udp-read-buffer":                    float64(0),
+							"urls":                               []interface{}{db.URL()},
+							"username":                           "",
 						},
 						Redacted: []string{
 							"password",
@@ -8634,6 +12848,16 @@ func TestServer_ListServiceTests(t *testing.T) {
 					"timeout": "24h0m0s",
 				},
 			},
+			{
+				Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/service-tests/amqp"},
+				Name: "amqp",
+				Options: client.ServiceTestOptions{
+					"broker":      "default",
+					"exchange":    "",
+					"routing-key": "kapacitor.test",
+					"message":     "test amqp message",
+				},
+			},
 			{
 				Link: client.Link{Relation: "self", Href: "/kapacitor/v1/service-tests/azure"},
 				Name: "azure",
@@ -8654,6 +12878,13 @@ func TestServer_ListServiceTests(t *testing.T) {
 				Options: client.ServiceTestOptions{
 					"id": ""},
 			},
+			{
+				Link: client.Link{Relation: "self", Href: "/kapacitor/v1/service-tests/docker-discovery"},
+				Name: "docker-discovery",
+				Options: client.ServiceTestOptions{
+					"id": "",
+				},
+			},
 			{
 				Link: client.Link{Relation: "self", Href: "/kapacitor/v1/service-tests/ec2"},
 				Name: "ec2",
@@ -8684,6 +12915,13 @@ func TestServer_ListServiceTests(t *testing.T) {
 					"level":   "CRITICAL",
 				},
 			},
+			{
+				Link: client.Link{Relation: "self", Href: "/kapacitor/v1/service-tests/http-discovery"},
+				Name: "http-discovery",
+				Options: client.ServiceTestOptions{
+					"id": "",
+				},
+			},
 			{
 				Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/service-tests/httppost"},
 				Name: "httppost",
@@ -11790,6 +16028,68 @@ func TestServer_AlertTopic(t *testing.T) {
 	}
 }
 
+func TestServer_AlertTopic_EventsWebSocket(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	u := strings.Replace(s.URL(), "http://", "ws://", 1) + "/alerts/topics/ws/events?min-level=WARNING"
+	ws, err := websocket.Dial(u, "", s.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	ws.SetDeadline(time.Now().Add(10 * time.Second))
+
+	tick := `
+stream
+	|from()
+		.measurement('alert')
+	|alert()
+		.topic('ws')
+		.id('id')
+		.message('message')
+		.details('details')
+		.info(lambda: "value" > 0)
+		.crit(lambda: "value" > 1)
+`
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "testAlertWebSocket",
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TICKscript: tick,
+		Status:     client.Enabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	points := `alert value=1 0000000000
+alert value=2 0000000001
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+
+	// The INFO event is below the minimum level.
+	var event client.TopicEvent
+	if err := websocket.JSON.Receive(ws, &event); err != nil {
+		t.Fatal(err)
+	}
+	exp := client.TopicEvent{
+		Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/alerts/topics/ws/events/id"},
+		ID:   "id",
+		State: client.EventState{
+			Message:  "message",
+			Details:  "details",
+			Time:     time.Date(1970, 1, 1, 0, 0, 1, 0, time.UTC),
+			Duration: client.Duration(time.Second),
+			Level:    "CRITICAL",
+		},
+	}
+	if !reflect.DeepEqual(event, exp) {
+		t.Errorf("unexpected event:\ngot\n%+v\nexp\n%+v\n", event, exp)
+	}
+}
+
 func TestServer_AlertListTopics(t *testing.T) {
 	// Setup test TCP server
 	ts, err := alerttest.NewTCPServer()
@@ -12303,6 +16603,89 @@ options:
 
 }
 
+func TestLoadService_Watch(t *testing.T) {
+	c := NewConfig()
+	c.Load.WatchInterval = toml.Duration(10 * time.Millisecond)
+	if err := os.MkdirAll(path.Join(c.Load.Dir, "tasks", "team"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	s := OpenServer(c)
+	defer s.Close()
+	cli := Client(s)
+
+	waitForTasks := func(exp []string) {
+		t.Helper()
+		ids := []string{}
+		for i := 0; i < 200; i++ {
+			tasks, err := cli.ListTasks(&client.ListTasksOptions{Fields: []string{"link"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = ids[:0]
+			for _, task := range tasks {
+				ids = append(ids, task.ID)
+			}
+			if reflect.DeepEqual(ids, exp) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("unexpected tasks got %v exp %v", ids, exp)
+	}
+
+	// Tasks in sub directories are loaded
+	tick := "dbrp \"db\".\"rp\"\n\nstream\n    |from()\n        .measurement('cpu')\n"
+	f := path.Join(c.Load.Dir, "tasks", "team", "cpu.tick")
+	if err := ioutil.WriteFile(f, []byte(tick), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitForTasks([]string{"cpu"})
+
+	status, err := cli.LoadStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Watching || status.Dir != c.Load.Dir || status.LastSync.IsZero() || len(status.FileErrors) != 0 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	// Errors are reported by file
+	if err := ioutil.WriteFile(path.Join(c.Load.Dir, "tasks", "bad.tick"), []byte("stream\n    |bad()\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(c.Load.Dir, "tasks", "cpu.tick"), []byte(tick), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		status, err = cli.LoadStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(status.FileErrors) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(status.FileErrors) != 2 {
+		t.Fatalf("unexpected file errors %v", status.FileErrors)
+	}
+	if got, exp := status.FileErrors[path.Join("tasks", "team", "cpu.tick")], "task cpu is already defined by file "+path.Join(c.Load.Dir, "tasks", "cpu.tick"); got != exp {
+		t.Errorf("unexpected error got %q exp %q", got, exp)
+	}
+	if got := status.FileErrors[path.Join("tasks", "bad.tick")]; !strings.HasPrefix(got, "failed to create task") {
+		t.Errorf("unexpected error %q", got)
+	}
+	waitForTasks([]string{"cpu"})
+
+	// Tasks of removed files are deleted
+	for _, name := range []string{"bad.tick", "cpu.tick", path.Join("team", "cpu.tick")} {
+		if err := os.Remove(path.Join(c.Load.Dir, "tasks", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitForTasks([]string{})
+}
+
 func TestSideloadService(t *testing.T) {
 	dir := MustTempDir()
 	defer os.RemoveAll(dir)
@@ -12,6 +12,7 @@ import (
 
 	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/influxdata/kapacitor/alert"
+	"github.com/influxdata/kapacitor/auth"
 	client "github.com/influxdata/kapacitor/client/v1"
 	"github.com/influxdata/kapacitor/services/httpd"
 )
@@ -39,8 +40,9 @@ const (
 )
 
 type apiServer struct {
-	Registrar    HandlerSpecRegistrar
-	Topics       Topics
+	Registrar     HandlerSpecRegistrar
+	Topics        Topics
+	AnonRegistrar AnonHandlerRegistrar
 	Persister    TopicPersister
 	routes       []httpd.Route
 	HTTPDService interface {
@@ -57,31 +59,41 @@ func (s *apiServer) Open() error {
 			Method:      "GET",
 			Pattern:     topicsPath,
 			HandlerFunc: s.handleListTopics,
+			Scope:       auth.AlertsReadScope,
 		},
 		{
 			Method:      "GET",
 			Pattern:     topicsPathAnchored,
 			HandlerFunc: s.handleRouteTopicGet,
+			Scope:       auth.AlertsReadScope,
 		},
 		{
 			Method:      "POST",
 			Pattern:     topicsPathAnchored,
 			HandlerFunc: s.handleRouteTopicPost,
+			Scope:       auth.AlertsAckScope,
+			Replicated:  true,
 		},
 		{
 			Method:      "PATCH",
 			Pattern:     topicsPathAnchored,
 			HandlerFunc: s.handleRouteTopicPatch,
+			Scope:       auth.AlertsAckScope,
+			Replicated:  true,
 		},
 		{
 			Method:      "PUT",
 			Pattern:     topicsPathAnchored,
 			HandlerFunc: s.handleRouteTopicPut,
+			Scope:       auth.AlertsAckScope,
+			Replicated:  true,
 		},
 		{
 			Method:      "DELETE",
 			Pattern:     topicsPathAnchored,
 			HandlerFunc: s.handleRouteTopicDelete,
+			Scope:       auth.AlertsAckScope,
+			Replicated:  true,
 		},
 		{
 			// Satisfy CORS checks.
@@ -178,6 +190,8 @@ func (s *apiServer) handleRouteTopicGet(w http.ResponseWriter, r *http.Request)
 	id := s.topicIDFromPath(p)
 
 	switch {
+	case pathMatch(eventsPattern, p) && httpd.IsWebSocket(r):
+		s.handleStreamEvents(id, w, r)
 	case pathMatch(eventsPattern, p):
 		s.handleListEvents(id, w, r)
 	case pathMatch(eventPattern, p):
@@ -315,6 +329,46 @@ func (s *apiServer) handleListEvents(topic string, w http.ResponseWriter, r *htt
 	w.Write(httpd.MarshalJSON(res, true))
 }
 
+// handleStreamEvents pushes each event collected by the topic to the WebSocket client.
+func (s *apiServer) handleStreamEvents(topic string, w http.ResponseWriter, r *http.Request) {
+	minLevelStr := r.URL.Query().Get("min-level")
+	minLevel, err := alert.ParseLevel(minLevelStr)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	h := &streamHandler{
+		s:           s,
+		minLevel:    minLevel,
+		broadcaster: httpd.NewBroadcaster(),
+	}
+	s.AnonRegistrar.RegisterAnonHandler(topic, h)
+	defer s.AnonRegistrar.DeregisterAnonHandler(topic, h)
+	defer h.broadcaster.Close()
+
+	h.broadcaster.Serve(w, r, nil)
+}
+
+// streamHandler forwards topic events to a WebSocket client.
+type streamHandler struct {
+	s           *apiServer
+	minLevel    alert.Level
+	broadcaster *httpd.Broadcaster
+}
+
+func (h *streamHandler) Handle(event alert.Event) {
+	if event.State.Level < h.minLevel {
+		return
+	}
+	if err := h.broadcaster.Broadcast(client.TopicEvent{
+		Link:  h.s.topicEventLink(event.Topic, event.State.ID),
+		ID:    event.State.ID,
+		State: h.s.convertEventStateToClient(event.State),
+	}); err != nil {
+		h.s.diag.Error("failed to push event to websocket client", err)
+	}
+}
+
 func (s *apiServer) handleGetEvent(topic, eventID string, w http.ResponseWriter, r *http.Request) {
 	state, ok, err := s.Topics.EventState(topic, eventID)
 	if err != nil {
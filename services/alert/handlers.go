@@ -211,9 +211,12 @@ type aggregateHandler struct {
 	wg sync.WaitGroup
 }
 
+// Cached message templates, shared by the aggregate handlers
+var aggregateMessageTemplates = alert.NewTextTemplates("message", nil)
+
 func NewAggregateHandler(c AggregateHandlerConfig, d HandlerDiagnostic) (alert.Handler, error) {
 	// Parse and validate message template
-	tmpl, err := text.New("message").Parse(c.Message)
+	tmpl, err := aggregateMessageTemplates.Parse(c.Message)
 	if err != nil {
 		return nil, err
 	}
@@ -14,6 +14,7 @@ import (
 	"github.com/influxdata/kapacitor/keyvalue"
 	"github.com/influxdata/kapacitor/models"
 	"github.com/influxdata/kapacitor/services/alerta"
+	"github.com/influxdata/kapacitor/services/amqp"
 	"github.com/influxdata/kapacitor/services/hipchat"
 	"github.com/influxdata/kapacitor/services/httpd"
 	"github.com/influxdata/kapacitor/services/httppost"
@@ -64,13 +65,17 @@ type Service struct {
 	topics         *alert.Topics
 	EventCollector EventCollector
 
+	watchersMu  sync.RWMutex
+	watchers    map[int]func(alert.Event)
+	nextWatcher int
+
 	HTTPDService interface {
 		AddRoutes([]httpd.Route) error
 		DelRoutes([]httpd.Route)
 	}
 
 	StorageService interface {
-		Store(namespace string) storage.Interface
+		EncryptedStore(namespace string) (storage.Interface, error)
 		Register(name string, store storage.StoreActioner)
 		Versions() storage.Versions
 	}
@@ -83,6 +88,9 @@ type Service struct {
 		DefaultHandlerConfig() alerta.HandlerConfig
 		Handler(alerta.HandlerConfig, ...keyvalue.T) (alert.Handler, error)
 	}
+	AMQPService interface {
+		Handler(amqp.HandlerConfig, ...keyvalue.T) (alert.Handler, error)
+	}
 	HipChatService interface {
 		Handler(hipchat.HandlerConfig, ...keyvalue.T) alert.Handler
 	}
@@ -140,12 +148,14 @@ func NewService(d Diagnostic) *Service {
 		topics:          alert.NewTopics(),
 		diag:            d,
 		inhibitorLookup: alert.NewInhibitorLookup(),
+		watchers:        make(map[int]func(alert.Event)),
 	}
 	s.APIServer = &apiServer{
-		Registrar: s,
-		Topics:    s,
-		Persister: s,
-		diag:      d,
+		Registrar:     s,
+		Topics:        s,
+		AnonRegistrar: s,
+		Persister:     s,
+		diag:          d,
 	}
 	s.EventCollector = s
 	return s
@@ -165,7 +175,11 @@ func (s *Service) Open() error {
 	defer s.mu.Unlock()
 
 	// Create DAO
-	store := s.StorageService.Store(alertNamespace)
+	// The stored values contain credentials, so they are encrypted at rest.
+	store, err := s.StorageService.EncryptedStore(alertNamespace)
+	if err != nil {
+		return err
+	}
 	specsDAO, err := newHandlerSpecKV(store)
 	if err != nil {
 		return err
@@ -357,6 +371,26 @@ func (s *Service) loadSavedHandlerSpecs() error {
 	return nil
 }
 
+// Reload replaces the handlers and the topic states with the ones saved in the storage,
+// after the storage was replicated from the leader of a high availability pair.
+func (s *Service) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for topic, handlers := range s.handlers {
+		for _, h := range handlers {
+			s.topics.DeregisterHandler(topic, h.Handler)
+			if ha, ok := h.Handler.(closer); ok {
+				ha.Close()
+			}
+		}
+	}
+	s.handlers = make(map[string]map[string]handler)
+	if err := s.loadSavedHandlerSpecs(); err != nil {
+		return err
+	}
+	return s.loadSavedTopicStates()
+}
+
 func (s *Service) convertEventStatesToAlert(states map[string]EventState) map[string]alert.EventState {
 	newStates := make(map[string]alert.EventState, len(states))
 	for id, state := range states {
@@ -442,9 +476,29 @@ func (s *Service) Collect(event alert.Event) error {
 	if err != nil {
 		return err
 	}
+	s.watchersMu.RLock()
+	for _, f := range s.watchers {
+		f(event)
+	}
+	s.watchersMu.RUnlock()
 	return s.persistTopicState(event.Topic)
 }
 
+// Watch calls f with each collected event, until cancel is called.
+// The function f must not block as it is called while the event is collected.
+func (s *Service) Watch(f func(alert.Event)) (cancel func()) {
+	s.watchersMu.Lock()
+	defer s.watchersMu.Unlock()
+	id := s.nextWatcher
+	s.nextWatcher++
+	s.watchers[id] = f
+	return func() {
+		s.watchersMu.Lock()
+		defer s.watchersMu.Unlock()
+		delete(s.watchers, id)
+	}
+}
+
 func (s *Service) persistTopicState(topic string) error {
 	t, ok := s.topics.Topic(topic)
 	if !ok {
@@ -573,6 +627,19 @@ func (s *Service) RegisterHandlerSpec(spec HandlerSpec) error {
 	return nil
 }
 
+// NewHandler creates the handler described by the spec without registering it on a topic.
+// Handlers implementing Close() must be closed by the caller.
+func (s *Service) NewHandler(spec HandlerSpec) (alert.Handler, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	h, err := s.createHandlerFromSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	return h.Handler, nil
+}
+
 type closer interface {
 	Close()
 }
@@ -772,6 +839,17 @@ func (s *Service) createHandlerFromSpec(spec HandlerSpec) (handler, error) {
 			return handler{}, err
 		}
 		h = newExternalHandler(h)
+	case "amqp":
+		c := amqp.HandlerConfig{}
+		err = decodeOptions(spec.Options, &c)
+		if err != nil {
+			return handler{}, err
+		}
+		h, err = s.AMQPService.Handler(c, ctx...)
+		if err != nil {
+			return handler{}, err
+		}
+		h = newExternalHandler(h)
 	case "exec":
 		c := ExecHandlerConfig{
 			Commander: s.Commander,
@@ -310,32 +310,42 @@ func (s *Service) DefaultHandlerConfig() HandlerConfig {
 	}
 }
 
+// Cached templates, shared by the handlers
+var (
+	resourceTemplates    = alert.NewTextTemplates("resource", nil)
+	eventTemplates       = alert.NewTextTemplates("event", nil)
+	environmentTemplates = alert.NewTextTemplates("environment", nil)
+	groupTemplates       = alert.NewTextTemplates("group", nil)
+	valueTemplates       = alert.NewTextTemplates("value", nil)
+	serviceTemplates     = alert.NewTextTemplates("service", nil)
+)
+
 func (s *Service) Handler(c HandlerConfig, ctx ...keyvalue.T) (alert.Handler, error) {
 	// Parse and validate alerta templates
-	rtmpl, err := text.New("resource").Parse(c.Resource)
+	rtmpl, err := resourceTemplates.Parse(c.Resource)
 	if err != nil {
 		return nil, err
 	}
-	evtmpl, err := text.New("event").Parse(c.Event)
+	evtmpl, err := eventTemplates.Parse(c.Event)
 	if err != nil {
 		return nil, err
 	}
-	etmpl, err := text.New("environment").Parse(c.Environment)
+	etmpl, err := environmentTemplates.Parse(c.Environment)
 	if err != nil {
 		return nil, err
 	}
-	gtmpl, err := text.New("group").Parse(c.Group)
+	gtmpl, err := groupTemplates.Parse(c.Group)
 	if err != nil {
 		return nil, err
 	}
-	vtmpl, err := text.New("value").Parse(c.Value)
+	vtmpl, err := valueTemplates.Parse(c.Value)
 	if err != nil {
 		return nil, err
 	}
 
 	var stmpl []*text.Template
 	for _, service := range c.Service {
-		tmpl, err := text.New("service").Parse(service)
+		tmpl, err := serviceTemplates.Parse(service)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,345 @@
+package amqp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/server/vars"
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+)
+
+// statistics gathered by the AMQP consumer.
+const (
+	statMessagesReceived  = "messages_rx"
+	statPointsParseFail   = "points_parse_fail"
+	statPointsTransmitted = "points_tx"
+	statTransmitFail      = "tx_fail"
+	statMessagesPublished = "messages_published"
+	statPublishFail       = "publish_fail"
+)
+
+type PointsWriter interface {
+	WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+}
+
+// Broker manages the connections to a single AMQP broker.
+// Publishing and consuming use separate connections so that
+// flow control on one does not stall the other.
+type Broker struct {
+	cfg  Config
+	pw   PointsWriter
+	diag Diagnostic
+
+	// dialMu serializes connecting to the broker, so that concurrent publishers share one connection.
+	dialMu sync.Mutex
+	// mu only guards the publishing state, it is never held during network I/O,
+	// so that a slow or unreachable broker does not block the other publishers.
+	mu   sync.Mutex
+	conn *amqp.Connection
+	// idle are the open publishing channels of conn that are not in use.
+	// A channel publishes one message at a time, so concurrent publishes use their own channels.
+	idle     []*amqp.Channel
+	declared map[string]bool
+	closed   bool
+
+	closing chan struct{}
+	wg      sync.WaitGroup
+
+	statMap *expvar.Map
+	statKey string
+}
+
+func NewBroker(c Config, pw PointsWriter, d Diagnostic) *Broker {
+	b := &Broker{
+		cfg:      c,
+		pw:       pw,
+		diag:     d,
+		declared: make(map[string]bool),
+		closing:  make(chan struct{}),
+	}
+	b.statKey, b.statMap = vars.NewStatistic("amqp", map[string]string{"name": c.Name})
+	if len(c.Queues) > 0 {
+		b.wg.Add(1)
+		go b.consume()
+	}
+	return b
+}
+
+// Close stops any consumers and closes the broker connections.
+func (b *Broker) Close() {
+	close(b.closing)
+	b.wg.Wait()
+	b.mu.Lock()
+	b.closed = true
+	conn := b.conn
+	b.conn = nil
+	b.idle = nil
+	b.mu.Unlock()
+	if conn != nil {
+		conn.Close()
+	}
+	vars.DeleteStatistic(b.statKey)
+}
+
+func (b *Broker) dial() (*amqp.Connection, error) {
+	ac, err := b.cfg.dialConfig()
+	if err != nil {
+		return nil, err
+	}
+	return amqp.DialConfig(b.cfg.URL, ac)
+}
+
+// channel takes an idle publishing channel, connecting or opening a new channel if there is none.
+func (b *Broker) channel() (*amqp.Connection, *amqp.Channel, error) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil, nil, errors.New("broker is closed")
+	}
+	conn := b.conn
+	if n := len(b.idle); n > 0 {
+		ch := b.idle[n-1]
+		b.idle = b.idle[:n-1]
+		b.mu.Unlock()
+		return conn, ch, nil
+	}
+	b.mu.Unlock()
+
+	if conn == nil {
+		var err error
+		if conn, err = b.connect(); err != nil {
+			return nil, nil, err
+		}
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		return conn, nil, errors.Wrap(err, "failed to open channel")
+	}
+	return conn, ch, nil
+}
+
+// connect connects to the broker, unless another publisher connected while waiting.
+func (b *Broker) connect() (*amqp.Connection, error) {
+	b.dialMu.Lock()
+	defer b.dialMu.Unlock()
+	b.mu.Lock()
+	conn, closed := b.conn, b.closed
+	b.mu.Unlock()
+	if closed {
+		return nil, errors.New("broker is closed")
+	}
+	if conn != nil {
+		return conn, nil
+	}
+
+	conn, err := b.dial()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to broker")
+	}
+	b.mu.Lock()
+	closed = b.closed
+	if !closed {
+		b.conn = conn
+	}
+	b.mu.Unlock()
+	if closed {
+		conn.Close()
+		return nil, errors.New("broker is closed")
+	}
+	return conn, nil
+}
+
+// release returns the channel to the idle channels once it has published,
+// the channel is closed instead if its connection was replaced in the meantime.
+func (b *Broker) release(conn *amqp.Connection, ch *amqp.Channel) {
+	b.mu.Lock()
+	if b.conn == conn && !b.closed {
+		b.idle = append(b.idle, ch)
+		b.mu.Unlock()
+		return
+	}
+	b.mu.Unlock()
+	ch.Close()
+}
+
+// reset closes the connection after a failed publish,
+// unless another publisher already replaced it.
+func (b *Broker) reset(conn *amqp.Connection) {
+	if conn == nil {
+		return
+	}
+	b.mu.Lock()
+	if b.conn != conn {
+		b.mu.Unlock()
+		return
+	}
+	b.conn = nil
+	b.idle = nil
+	b.declared = make(map[string]bool)
+	b.mu.Unlock()
+	conn.Close()
+}
+
+// Publish publishes the body to the exchange with the routing key.
+// The exchange is declared first if an exchange type is configured.
+// If the content type is empty, the content type configured for the broker is used.
+func (b *Broker) Publish(exchange, key, contentType string, body []byte) error {
+	if contentType == "" {
+		contentType = b.cfg.contentType()
+	}
+	conn, err := b.publish(exchange, key, contentType, body)
+	if err != nil {
+		// The connection may have gone stale, reconnect and retry once.
+		b.reset(conn)
+		conn, err = b.publish(exchange, key, contentType, body)
+	}
+	if err != nil {
+		b.reset(conn)
+		b.statMap.Add(statPublishFail, 1)
+		return err
+	}
+	b.statMap.Add(statMessagesPublished, 1)
+	return nil
+}
+
+// publish publishes the message on a channel of the returned connection.
+func (b *Broker) publish(exchange, key, contentType string, body []byte) (*amqp.Connection, error) {
+	conn, ch, err := b.channel()
+	if err != nil {
+		return conn, err
+	}
+	if err := b.declare(conn, ch, exchange); err != nil {
+		return conn, err
+	}
+	mode := amqp.Transient
+	if b.cfg.Persistent {
+		mode = amqp.Persistent
+	}
+	if err := ch.Publish(exchange, key, false, false, amqp.Publishing{
+		ContentType:  contentType,
+		DeliveryMode: mode,
+		Timestamp:    time.Now(),
+		Body:         body,
+	}); err != nil {
+		return conn, err
+	}
+	b.release(conn, ch)
+	return conn, nil
+}
+
+// declare declares the exchange once per connection, if an exchange type is configured.
+func (b *Broker) declare(conn *amqp.Connection, ch *amqp.Channel, exchange string) error {
+	if exchange == "" || b.cfg.ExchangeType == "" {
+		return nil
+	}
+	b.mu.Lock()
+	declared := b.declared[exchange]
+	b.mu.Unlock()
+	if declared {
+		return nil
+	}
+	if err := ch.ExchangeDeclare(exchange, b.cfg.ExchangeType, true, false, false, false, nil); err != nil {
+		return errors.Wrapf(err, "failed to declare exchange %q", exchange)
+	}
+	b.mu.Lock()
+	if b.conn == conn {
+		b.declared[exchange] = true
+	}
+	b.mu.Unlock()
+	return nil
+}
+
+// consume reads points from the configured queues, reconnecting as needed.
+func (b *Broker) consume() {
+	defer b.wg.Done()
+	for {
+		err := b.consumeOnce()
+		select {
+		case <-b.closing:
+			return
+		default:
+		}
+		if err != nil {
+			b.diag.Error("lost connection to broker", err)
+		}
+		select {
+		case <-b.closing:
+			return
+		case <-time.After(b.cfg.reconnectWait()):
+		}
+	}
+}
+
+func (b *Broker) consumeOnce() error {
+	conn, err := b.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	if err := ch.Qos(b.cfg.Prefetch, 0, false); err != nil {
+		return err
+	}
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	var wg sync.WaitGroup
+	for _, q := range b.cfg.Queues {
+		deliveries, err := ch.Consume(q, "kapacitor-"+b.cfg.Name, false, false, false, false, nil)
+		if err != nil {
+			return errors.Wrapf(err, "failed to consume queue %q", q)
+		}
+		b.diag.ConsumingQueue(q)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range deliveries {
+				b.handleDelivery(d)
+			}
+		}()
+	}
+
+	select {
+	case <-b.closing:
+		conn.Close()
+		wg.Wait()
+		return nil
+	case err := <-closed:
+		wg.Wait()
+		if err != nil {
+			return err
+		}
+		return errors.New("connection closed")
+	}
+}
+
+func (b *Broker) handleDelivery(d amqp.Delivery) {
+	b.statMap.Add(statMessagesReceived, 1)
+	points, err := models.ParsePointsWithPrecision(d.Body, time.Now().UTC(), b.cfg.Precision)
+	if err != nil {
+		b.statMap.Add(statPointsParseFail, 1)
+		b.diag.Error("failed to parse points", err, keyvalue.KV("queue", d.RoutingKey))
+		// Unparsable messages will never succeed, do not requeue them.
+		d.Nack(false, false)
+		return
+	}
+	if err := b.pw.WritePoints(
+		b.cfg.Database,
+		b.cfg.RetentionPolicy,
+		models.ConsistencyLevelAll,
+		points,
+	); err != nil {
+		b.statMap.Add(statTransmitFail, 1)
+		b.diag.Error("failed to write points to database", err, keyvalue.KV("database", b.cfg.Database))
+		d.Nack(false, true)
+		return
+	}
+	b.statMap.Add(statPointsTransmitted, int64(len(points)))
+	d.Ack(false)
+}
@@ -0,0 +1,176 @@
+package amqp
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/tlsconfig"
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+)
+
+const (
+	DefaultURL           = "amqp://localhost:5672/"
+	DefaultExchangeType  = "topic"
+	DefaultPrefetch      = 100
+	DefaultReconnectWait = 5 * time.Second
+	DefaultPrecision     = "ns"
+	DefaultHeartbeat     = 10 * time.Second
+	DefaultContentType   = "application/json"
+)
+
+type Config struct {
+	Enabled bool `toml:"enabled" override:"enabled"`
+	// Name is the unique name of this broker.
+	Name string `toml:"name" override:"name"`
+	// URL of the AMQP broker, i.e. amqp://localhost:5672/vhost or amqps://localhost:5671/vhost.
+	URL string `toml:"url" override:"url"`
+
+	Username string `toml:"username" override:"username"`
+	Password string `toml:"password" override:"password,redact"`
+
+	// Path to CA file
+	SSLCA string `toml:"ssl-ca" override:"ssl-ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl-cert" override:"ssl-cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl-key" override:"ssl-key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool `toml:"insecure-skip-verify" override:"insecure-skip-verify"`
+
+	// Exchange is the default exchange alert events are published to.
+	Exchange string `toml:"exchange" override:"exchange"`
+	// ExchangeType is the type used when declaring the exchange.
+	// If empty the exchange is expected to already exist.
+	ExchangeType string `toml:"exchange-type" override:"exchange-type"`
+	// RoutingKey is the default routing key template for alert events.
+	// The template is executed against the template data of the alert, i.e. alerts.{{ index .Tags "host" }}.
+	// If empty the alert ID is used as the routing key.
+	RoutingKey string `toml:"routing-key" override:"routing-key"`
+	// Persistent marks published alert events as persistent.
+	Persistent bool `toml:"persistent" override:"persistent"`
+	// ContentType is the default content type of published alert events.
+	ContentType string `toml:"content-type" override:"content-type"`
+
+	// Queues are the queues to consume line protocol points from.
+	// If empty no queues are consumed.
+	Queues []string `toml:"queues" override:"queues"`
+	// Prefetch is the number of unacknowledged messages the broker will deliver at once.
+	Prefetch int `toml:"prefetch" override:"prefetch"`
+	// Precision of the timestamps of the consumed line protocol.
+	Precision string `toml:"precision" override:"precision"`
+	// Database and retention policy consumed points are written to.
+	Database        string `toml:"database" override:"database"`
+	RetentionPolicy string `toml:"retention-policy" override:"retention-policy"`
+
+	// ReconnectWait is the time to wait between reconnect attempts.
+	ReconnectWait toml.Duration `toml:"reconnect-wait" override:"reconnect-wait"`
+}
+
+func NewConfig() Config {
+	return Config{
+		Name:          "default",
+		URL:           DefaultURL,
+		ExchangeType:  DefaultExchangeType,
+		Prefetch:      DefaultPrefetch,
+		Precision:     DefaultPrecision,
+		ContentType:   DefaultContentType,
+		ReconnectWait: toml.Duration(DefaultReconnectWait),
+	}
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Name == "" {
+		return errors.New("must specify a name for the amqp broker")
+	}
+	if c.URL == "" {
+		return errors.New("must specify a url for the amqp broker")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return errors.Wrapf(err, "invalid url %q", c.URL)
+	}
+	switch u.Scheme {
+	case "amqp", "amqps":
+	default:
+		return fmt.Errorf("invalid url scheme %q, must be one of amqp or amqps", u.Scheme)
+	}
+	switch c.ExchangeType {
+	case "", "direct", "fanout", "topic", "headers":
+	default:
+		return fmt.Errorf("invalid exchange-type %q", c.ExchangeType)
+	}
+	if len(c.Queues) > 0 && c.Database == "" {
+		return errors.New("must specify a database when consuming queues")
+	}
+	if c.Prefetch < 0 {
+		return errors.New("prefetch must not be negative")
+	}
+	switch c.Precision {
+	case "", "n", "ns", "u", "ms", "s", "m", "h":
+	default:
+		return fmt.Errorf("invalid precision %q", c.Precision)
+	}
+	return nil
+}
+
+// dialConfig returns the amqp client configuration.
+func (c Config) dialConfig() (amqp.Config, error) {
+	ac := amqp.Config{
+		Heartbeat: DefaultHeartbeat,
+		Locale:    "en_US",
+	}
+	if c.Username != "" {
+		ac.SASL = []amqp.Authentication{&amqp.PlainAuth{
+			Username: c.Username,
+			Password: c.Password,
+		}}
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return ac, err
+	}
+	if u.Scheme == "amqps" || c.SSLCA != "" || c.SSLCert != "" {
+		t, err := tlsconfig.Create(c.SSLCA, c.SSLCert, c.SSLKey, c.InsecureSkipVerify)
+		if err != nil {
+			return ac, err
+		}
+		ac.TLSClientConfig = t
+	}
+	return ac, nil
+}
+
+func (c Config) reconnectWait() time.Duration {
+	if c.ReconnectWait <= 0 {
+		return DefaultReconnectWait
+	}
+	return time.Duration(c.ReconnectWait)
+}
+
+func (c Config) contentType() string {
+	if c.ContentType == "" {
+		return DefaultContentType
+	}
+	return c.ContentType
+}
+
+type Configs []Config
+
+func (cs Configs) Validate() error {
+	names := make(map[string]bool, len(cs))
+	for _, c := range cs {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		if names[c.Name] {
+			return fmt.Errorf("duplicate name %q", c.Name)
+		}
+		names[c.Name] = true
+	}
+	return nil
+}
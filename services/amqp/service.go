@@ -0,0 +1,269 @@
+package amqp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"text/template"
+
+	"github.com/influxdata/kapacitor/alert"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/pkg/errors"
+)
+
+type Diagnostic interface {
+	WithContext(ctx ...keyvalue.T) Diagnostic
+	InsecureSkipVerify()
+	ConsumingQueue(queue string)
+	Error(msg string, err error, ctx ...keyvalue.T)
+}
+
+type Service struct {
+	PointsWriter PointsWriter
+
+	mu      sync.RWMutex
+	configs map[string]Config
+	brokers map[string]*Broker
+	opened  bool
+	diag    Diagnostic
+}
+
+func NewService(cs Configs, d Diagnostic) *Service {
+	configs := make(map[string]Config, len(cs))
+	for _, c := range cs {
+		if c.InsecureSkipVerify {
+			d.InsecureSkipVerify()
+		}
+		configs[c.Name] = c
+	}
+	return &Service{
+		configs: configs,
+		brokers: make(map[string]*Broker, len(cs)),
+		diag:    d,
+	}
+}
+
+func (s *Service) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, c := range s.configs {
+		if c.Enabled {
+			s.brokers[name] = s.newBroker(c)
+		}
+	}
+	s.opened = true
+	return nil
+}
+
+func (s *Service) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, b := range s.brokers {
+		b.Close()
+		delete(s.brokers, name)
+	}
+	s.opened = false
+	return nil
+}
+
+func (s *Service) newBroker(c Config) *Broker {
+	return NewBroker(c, s.PointsWriter, s.diag.WithContext(keyvalue.KV("broker", c.Name)))
+}
+
+// Broker returns the broker with the given name.
+func (s *Service) Broker(name string) (*Broker, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.brokers[name]
+	return b, ok
+}
+
+func (s *Service) Update(newConfigs []interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exists := make(map[string]bool, len(newConfigs))
+	for _, nc := range newConfigs {
+		c, ok := nc.(Config)
+		if !ok {
+			return fmt.Errorf("unexpected config object type, got %T exp %T", nc, c)
+		}
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		if c.InsecureSkipVerify {
+			s.diag.InsecureSkipVerify()
+		}
+		exists[c.Name] = true
+		old, hadConfig := s.configs[c.Name]
+		s.configs[c.Name] = c
+		if hadConfig && reflect.DeepEqual(old, c) {
+			continue
+		}
+		// Replace the broker so that new connection and consumer settings take effect.
+		if b, ok := s.brokers[c.Name]; ok {
+			b.Close()
+			delete(s.brokers, c.Name)
+		}
+		if c.Enabled && s.opened {
+			s.brokers[c.Name] = s.newBroker(c)
+		}
+	}
+
+	// Find any deleted brokers
+	for name := range s.configs {
+		if !exists[name] {
+			if b, ok := s.brokers[name]; ok {
+				b.Close()
+				delete(s.brokers, name)
+			}
+			delete(s.configs, name)
+		}
+	}
+	return nil
+}
+
+type testOptions struct {
+	Broker     string `json:"broker"`
+	Exchange   string `json:"exchange"`
+	RoutingKey string `json:"routing-key"`
+	Message    string `json:"message"`
+}
+
+func (s *Service) TestOptions() interface{} {
+	return &testOptions{
+		Broker:     "default",
+		Exchange:   "",
+		RoutingKey: "kapacitor.test",
+		Message:    "test amqp message",
+	}
+}
+
+func (s *Service) Test(options interface{}) error {
+	o, ok := options.(*testOptions)
+	if !ok {
+		return fmt.Errorf("unexpected options type %T", options)
+	}
+	b, ok := s.Broker(o.Broker)
+	if !ok {
+		return fmt.Errorf("unknown broker %q", o.Broker)
+	}
+	exchange := o.Exchange
+	if exchange == "" {
+		exchange = b.cfg.Exchange
+	}
+	return b.Publish(exchange, o.RoutingKey, "", []byte(o.Message))
+}
+
+type HandlerConfig struct {
+	// Broker is the name of the configured broker.
+	Broker string `mapstructure:"broker"`
+	// Exchange to publish to, defaults to the broker exchange.
+	Exchange string `mapstructure:"exchange"`
+	// RoutingKey is a template for the routing key, defaults to the broker routing key.
+	// The template is executed against the template data of the alert.
+	RoutingKey string `mapstructure:"routing-key"`
+	// Template is used to construct the message body.
+	// If empty the alert data in JSON is sent as the message body.
+	Template string `mapstructure:"template"`
+	// ContentType of the message, defaults to the broker content type.
+	ContentType string `mapstructure:"content-type"`
+}
+
+type handler struct {
+	s *Service
+
+	broker      string
+	exchange    string
+	routingKey  *template.Template
+	template    *template.Template
+	contentType string
+
+	diag Diagnostic
+}
+
+// Cached templates, shared by the handlers
+var (
+	routingKeyTemplates = alert.NewTextTemplates("amqp routing key", nil)
+	alertTemplates      = alert.NewTextTemplates("amqp alert template", nil)
+)
+
+func (s *Service) Handler(c HandlerConfig, ctx ...keyvalue.T) (alert.Handler, error) {
+	s.mu.RLock()
+	bc, ok := s.configs[c.Broker]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown broker %q", c.Broker)
+	}
+	exchange := c.Exchange
+	if exchange == "" {
+		exchange = bc.Exchange
+	}
+	rk := c.RoutingKey
+	if rk == "" {
+		rk = bc.RoutingKey
+	}
+	if rk == "" {
+		rk = "{{.ID}}"
+	}
+	routingKey, err := routingKeyTemplates.Parse(rk)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse routing key template")
+	}
+	var t *template.Template
+	if c.Template != "" {
+		t, err = alertTemplates.Parse(c.Template)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse template")
+		}
+	}
+	return &handler{
+		s:           s,
+		broker:      c.Broker,
+		exchange:    exchange,
+		routingKey:  routingKey,
+		template:    t,
+		contentType: c.ContentType,
+		diag:        s.diag.WithContext(ctx...),
+	}, nil
+}
+
+func (h *handler) Handle(event alert.Event) {
+	// Look up the broker on each event so that config updates are honored.
+	b, ok := h.s.Broker(h.broker)
+	if !ok {
+		h.diag.Error("failed to publish alert", fmt.Errorf("unknown or disabled broker %q", h.broker))
+		return
+	}
+	key := bytes.Buffer{}
+	if err := h.routingKey.Execute(&key, event.TemplateData()); err != nil {
+		h.diag.Error("failed to execute routing key template", err)
+		return
+	}
+	body, err := h.prepareBody(event.AlertData())
+	if err != nil {
+		h.diag.Error("failed to prepare amqp message body", err)
+		return
+	}
+	if err := b.Publish(h.exchange, key.String(), h.contentType, body); err != nil {
+		h.diag.Error("failed to publish alert to amqp broker", err)
+	}
+}
+
+func (h *handler) prepareBody(ad alert.Data) ([]byte, error) {
+	body := bytes.Buffer{}
+	if h.template != nil {
+		err := h.template.Execute(&body, ad)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to execute alert template")
+		}
+	} else {
+		err := json.NewEncoder(&body).Encode(ad)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal alert data json")
+		}
+	}
+	return body.Bytes(), nil
+}
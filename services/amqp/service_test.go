@@ -0,0 +1,607 @@
+package amqp_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/alert"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/services/amqp"
+)
+
+func TestService_Consume(t *testing.T) {
+	srv := newTestServer(t, func(connect int, queue string) []string {
+		return []string{"cpu,host=a value=1 1000000000", "not line protocol", "cpu,host=b value=2 2000000000"}
+	})
+	defer srv.Close()
+
+	c := newTestConfig(srv)
+	c.Queues = []string{"points"}
+	c.Database = "db"
+	c.RetentionPolicy = "rp"
+
+	pw := newPointsWriter()
+	s := amqp.NewService(amqp.Configs{c}, diag{})
+	s.PointsWriter = pw
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, exp := range []string{"cpu,host=a value=1 1000000000", "cpu,host=b value=2 2000000000"} {
+		if got := pw.wait(t); got.String() != exp {
+			t.Errorf("unexpected point: got %q exp %q", got.String(), exp)
+		}
+	}
+	if pw.database != "db" || pw.retentionPolicy != "rp" {
+		t.Errorf("unexpected destination of the points: %s.%s", pw.database, pw.retentionPolicy)
+	}
+	// The message that cannot be parsed is rejected without being requeued.
+	exp := []ack{{tag: 1}, {tag: 2, nack: true}, {tag: 3}}
+	for _, e := range exp {
+		if got := srv.waitAck(t); got != e {
+			t.Errorf("unexpected acknowledgement: got %+v exp %+v", got, e)
+		}
+	}
+}
+
+func TestService_ConsumeWriteFailure(t *testing.T) {
+	srv := newTestServer(t, func(connect int, queue string) []string {
+		return []string{"cpu value=1 1000000000"}
+	})
+	defer srv.Close()
+
+	c := newTestConfig(srv)
+	c.Queues = []string{"points"}
+	c.Database = "db"
+
+	s := amqp.NewService(amqp.Configs{c}, diag{})
+	s.PointsWriter = failingPointsWriter{}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// The message is requeued so that it is written once the writer is back.
+	if got, exp := srv.waitAck(t), (ack{tag: 1, nack: true, requeue: true}); got != exp {
+		t.Errorf("unexpected acknowledgement: got %+v exp %+v", got, exp)
+	}
+}
+
+func TestService_ConsumeReconnect(t *testing.T) {
+	srv := newTestServer(t, func(connect int, queue string) []string {
+		if connect == 1 {
+			return []string{"cpu value=1 1000000000"}
+		}
+		return []string{"cpu value=2 2000000000"}
+	})
+	defer srv.Close()
+
+	c := newTestConfig(srv)
+	c.Queues = []string{"points"}
+	c.Database = "db"
+	c.ReconnectWait = toml.Duration(10 * time.Millisecond)
+
+	pw := newPointsWriter()
+	s := amqp.NewService(amqp.Configs{c}, diag{})
+	s.PointsWriter = pw
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if got, exp := pw.wait(t).String(), "cpu value=1 1000000000"; got != exp {
+		t.Errorf("unexpected point: got %q exp %q", got, exp)
+	}
+	srv.waitAck(t)
+	srv.Drop()
+	if got, exp := pw.wait(t).String(), "cpu value=2 2000000000"; got != exp {
+		t.Errorf("unexpected point after reconnecting: got %q exp %q", got, exp)
+	}
+	if got := srv.Connects(); got != 2 {
+		t.Errorf("unexpected number of connections got %d exp 2", got)
+	}
+}
+
+func TestHandler_Publish(t *testing.T) {
+	srv := newTestServer(t, nil)
+	defer srv.Close()
+
+	c := newTestConfig(srv)
+	c.Exchange = "alerts"
+	c.ExchangeType = "topic"
+	c.RoutingKey = `alerts.{{ .Level }}.{{ index .Tags "host" }}`
+
+	s := amqp.NewService(amqp.Configs{c}, diag{})
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	event := alert.Event{
+		State: alert.EventState{
+			ID:      "cpu",
+			Message: "cpu is CRITICAL",
+			Level:   alert.Critical,
+		},
+		Data: alert.EventData{
+			Name: "cpu",
+			Tags: map[string]string{"host": "serverA"},
+		},
+	}
+
+	h, err := s.Handler(amqp.HandlerConfig{Broker: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Handle(event)
+	p := srv.waitPublish(t)
+	if exp := (publishing{exchange: "alerts", routingKey: "alerts.CRITICAL.serverA", contentType: "application/json"}); p.exchange != exp.exchange || p.routingKey != exp.routingKey || p.contentType != exp.contentType {
+		t.Errorf("unexpected publishing: got %+v exp %+v", p, exp)
+	}
+	if !bytes.Contains(p.body, []byte(`"message":"cpu is CRITICAL"`)) {
+		t.Errorf("unexpected body %s", p.body)
+	}
+
+	h, err = s.Handler(amqp.HandlerConfig{
+		Broker:      "default",
+		Exchange:    "events",
+		RoutingKey:  "{{ .Name }}.{{ .ID }}",
+		Template:    "{{ .Message }}",
+		ContentType: "text/plain",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Handle(event)
+	p = srv.waitPublish(t)
+	if exp := (publishing{exchange: "events", routingKey: "cpu.cpu", contentType: "text/plain", body: []byte("cpu is CRITICAL")}); p.exchange != exp.exchange || p.routingKey != exp.routingKey || p.contentType != exp.contentType || !bytes.Equal(p.body, exp.body) {
+		t.Errorf("unexpected publishing: got %+v exp %+v", p, exp)
+	}
+	// The exchanges are declared once on the connection.
+	if got, exp := srv.Declared(), []string{"alerts", "events"}; !equal(got, exp) {
+		t.Errorf("unexpected declared exchanges: got %v exp %v", got, exp)
+	}
+}
+
+func TestBroker_PublishReconnect(t *testing.T) {
+	srv := newTestServer(t, nil)
+	defer srv.Close()
+
+	c := newTestConfig(srv)
+	c.ContentType = "text/plain"
+	s := amqp.NewService(amqp.Configs{c}, diag{})
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	b, ok := s.Broker("default")
+	if !ok {
+		t.Fatal("missing broker")
+	}
+
+	if err := b.Publish("", "test", "", []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	if p := srv.waitPublish(t); p.contentType != "text/plain" || string(p.body) != "one" {
+		t.Errorf("unexpected publishing: %+v", p)
+	}
+	srv.Drop()
+	// Wait for the client to notice the connection is gone.
+	time.Sleep(50 * time.Millisecond)
+	if err := b.Publish("", "test", "", []byte("two")); err != nil {
+		t.Fatal(err)
+	}
+	if p := srv.waitPublish(t); string(p.body) != "two" {
+		t.Errorf("unexpected publishing after reconnecting: %+v", p)
+	}
+	if got := srv.Connects(); got != 2 {
+		t.Errorf("unexpected number of connections got %d exp 2", got)
+	}
+}
+
+func TestBroker_PublishConcurrent(t *testing.T) {
+	srv := newTestServer(t, nil)
+	defer srv.Close()
+
+	s := amqp.NewService(amqp.Configs{newTestConfig(srv)}, diag{})
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	b, _ := s.Broker("default")
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := b.Publish("", "test", "", bytes.Repeat([]byte("x"), 1000)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	for i := 0; i < n; i++ {
+		if p := srv.waitPublish(t); len(p.body) != 1000 {
+			t.Errorf("unexpected body of %d bytes", len(p.body))
+		}
+	}
+	if got := srv.Connects(); got != 1 {
+		t.Errorf("unexpected number of connections got %d exp 1", got)
+	}
+}
+
+func newTestConfig(srv *testServer) amqp.Config {
+	c := amqp.NewConfig()
+	c.Enabled = true
+	c.URL = "amqp://guest:guest@" + srv.Addr() + "/"
+	return c
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// AMQP 0-9-1 frame types and the end of frame marker.
+const (
+	frameMethod    = 1
+	frameHeader    = 2
+	frameBody      = 3
+	frameHeartbeat = 8
+	frameEnd       = 0xCE
+)
+
+type ack struct {
+	tag     uint64
+	nack    bool
+	requeue bool
+}
+
+type publishing struct {
+	exchange    string
+	routingKey  string
+	contentType string
+	size        uint64
+	body        []byte
+}
+
+// testServer is a fake AMQP broker,
+// it delivers the messages of the queues to their consumers and records the acknowledgements and publishings.
+type testServer struct {
+	t        *testing.T
+	l        net.Listener
+	messages func(connect int, queue string) []string
+
+	acks        chan ack
+	publishings chan publishing
+
+	mu       sync.Mutex
+	conns    []net.Conn
+	declared []string
+}
+
+func newTestServer(t *testing.T, messages func(connect int, queue string) []string) *testServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{
+		t:           t,
+		l:           l,
+		messages:    messages,
+		acks:        make(chan ack, 100),
+		publishings: make(chan publishing, 100),
+	}
+	go s.serve()
+	return s
+}
+
+func (s *testServer) Addr() string {
+	return s.l.Addr().String()
+}
+
+func (s *testServer) Close() {
+	s.l.Close()
+	s.Drop()
+}
+
+// Drop closes the connections of the clients.
+func (s *testServer) Drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+}
+
+func (s *testServer) Connects() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+func (s *testServer) Declared() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.declared
+}
+
+func (s *testServer) waitAck(t *testing.T) ack {
+	select {
+	case a := <-s.acks:
+		return a
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for acknowledgement")
+	}
+	return ack{}
+}
+
+func (s *testServer) waitPublish(t *testing.T) publishing {
+	select {
+	case p := <-s.publishings:
+		return p
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for publishing")
+	}
+	return publishing{}
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		connect := len(s.conns)
+		s.mu.Unlock()
+		go s.serveConn(conn, connect)
+	}
+}
+
+// serveConn speaks just enough of the protocol for the client to connect, consume and publish.
+func (s *testServer) serveConn(conn net.Conn, connect int) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return
+	}
+	// connection.start
+	s.send(conn, 0, method(10, 10).octet(0).octet(9).table().longstr("PLAIN").longstr("en_US"))
+
+	var tag uint64
+	// pending is the publishing waiting for its content.
+	pending := make(map[uint16]*publishing)
+	for {
+		typ, channel, payload, err := readFrame(r)
+		if err != nil {
+			return
+		}
+		switch typ {
+		case frameMethod:
+			args := &reader{b: payload[4:]}
+			switch id := [2]uint16{binary.BigEndian.Uint16(payload), binary.BigEndian.Uint16(payload[2:])}; id {
+			case [2]uint16{10, 11}: // connection.start-ok
+				s.send(conn, 0, method(10, 30).short(0).long(131072).short(0))
+			case [2]uint16{10, 31}: // connection.tune-ok
+			case [2]uint16{10, 40}: // connection.open
+				s.send(conn, 0, method(10, 41).shortstr(""))
+			case [2]uint16{10, 50}: // connection.close
+				s.send(conn, 0, method(10, 51))
+				return
+			case [2]uint16{20, 10}: // channel.open
+				s.send(conn, channel, method(20, 11).longstr(""))
+			case [2]uint16{20, 40}: // channel.close
+				s.send(conn, channel, method(20, 41))
+			case [2]uint16{40, 10}: // exchange.declare
+				args.short()
+				s.mu.Lock()
+				s.declared = append(s.declared, args.shortstr())
+				s.mu.Unlock()
+				s.send(conn, channel, method(40, 11))
+			case [2]uint16{60, 10}: // basic.qos
+				s.send(conn, channel, method(60, 11))
+			case [2]uint16{60, 20}: // basic.consume
+				args.short()
+				queue := args.shortstr()
+				consumer := args.shortstr()
+				s.send(conn, channel, method(60, 21).shortstr(consumer))
+				for _, m := range s.messages(connect, queue) {
+					tag++
+					s.send(conn, channel, method(60, 60).shortstr(consumer).longlong(tag).octet(0).shortstr("").shortstr(queue))
+					s.sendFrame(conn, frameHeader, channel, new(writer).short(60).short(0).longlong(uint64(len(m))).short(0).b)
+					s.sendFrame(conn, frameBody, channel, []byte(m))
+				}
+			case [2]uint16{60, 40}: // basic.publish
+				args.short()
+				pending[channel] = &publishing{exchange: args.shortstr(), routingKey: args.shortstr()}
+			case [2]uint16{60, 80}: // basic.ack
+				s.acks <- ack{tag: args.longlong()}
+			case [2]uint16{60, 120}: // basic.nack
+				s.acks <- ack{tag: args.longlong(), nack: true, requeue: args.octet()&2 != 0}
+			default:
+				s.t.Errorf("unexpected method %v", id)
+				return
+			}
+		case frameHeader:
+			p := pending[channel]
+			args := &reader{b: payload[4:]}
+			p.size = args.longlong()
+			if flags := args.short(); flags&0x8000 != 0 {
+				p.contentType = args.shortstr()
+			}
+			if p.size == 0 {
+				s.publishings <- *p
+			}
+		case frameBody:
+			p := pending[channel]
+			p.body = append(p.body, payload...)
+			if uint64(len(p.body)) == p.size {
+				s.publishings <- *p
+			}
+		case frameHeartbeat:
+		}
+	}
+}
+
+func (s *testServer) send(conn net.Conn, channel uint16, w *writer) {
+	s.sendFrame(conn, frameMethod, channel, w.b)
+}
+
+func (s *testServer) sendFrame(conn net.Conn, typ byte, channel uint16, payload []byte) {
+	frame := new(writer).octet(typ).short(channel).long(uint32(len(payload)))
+	frame.b = append(append(frame.b, payload...), frameEnd)
+	conn.Write(frame.b)
+}
+
+func readFrame(r *bufio.Reader) (typ byte, channel uint16, payload []byte, err error) {
+	header := make([]byte, 7)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return
+	}
+	payload = make([]byte, binary.BigEndian.Uint32(header[3:])+1)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return
+	}
+	if payload[len(payload)-1] != frameEnd {
+		err = errors.New("missing frame end")
+		return
+	}
+	return header[0], binary.BigEndian.Uint16(header[1:]), payload[:len(payload)-1], nil
+}
+
+// writer encodes the fields of a frame.
+type writer struct {
+	b []byte
+}
+
+func method(class, id uint16) *writer {
+	return new(writer).short(class).short(id)
+}
+
+func (w *writer) octet(v byte) *writer {
+	w.b = append(w.b, v)
+	return w
+}
+
+func (w *writer) short(v uint16) *writer {
+	w.b = append(w.b, byte(v>>8), byte(v))
+	return w
+}
+
+func (w *writer) long(v uint32) *writer {
+	w.b = append(w.b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	return w
+}
+
+func (w *writer) longlong(v uint64) *writer {
+	return w.long(uint32(v >> 32)).long(uint32(v))
+}
+
+func (w *writer) shortstr(v string) *writer {
+	w.b = append(append(w.b, byte(len(v))), v...)
+	return w
+}
+
+func (w *writer) longstr(v string) *writer {
+	w.long(uint32(len(v)))
+	w.b = append(w.b, v...)
+	return w
+}
+
+// table writes an empty table.
+func (w *writer) table() *writer {
+	return w.long(0)
+}
+
+// reader decodes the fields of a frame.
+type reader struct {
+	b []byte
+}
+
+func (r *reader) octet() byte {
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+func (r *reader) short() uint16 {
+	v := binary.BigEndian.Uint16(r.b)
+	r.b = r.b[2:]
+	return v
+}
+
+func (r *reader) longlong() uint64 {
+	v := binary.BigEndian.Uint64(r.b)
+	r.b = r.b[8:]
+	return v
+}
+
+func (r *reader) shortstr() string {
+	n := int(r.octet())
+	v := string(r.b[:n])
+	r.b = r.b[n:]
+	return v
+}
+
+type pointsWriter struct {
+	database        string
+	retentionPolicy string
+	points          chan models.Point
+}
+
+func newPointsWriter() *pointsWriter {
+	return &pointsWriter{points: make(chan models.Point, 10)}
+}
+
+func (pw *pointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	pw.database, pw.retentionPolicy = database, retentionPolicy
+	for _, p := range points {
+		pw.points <- p
+	}
+	return nil
+}
+
+func (pw *pointsWriter) wait(t *testing.T) models.Point {
+	select {
+	case p := <-pw.points:
+		return p
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for point")
+	}
+	return nil
+}
+
+type failingPointsWriter struct{}
+
+func (failingPointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	return errors.New("writer is down")
+}
+
+type diag struct{}
+
+func (d diag) WithContext(ctx ...keyvalue.T) amqp.Diagnostic { return d }
+func (diag) InsecureSkipVerify()                             {}
+func (diag) ConsumingQueue(queue string)                     {}
+func (diag) Error(msg string, err error, ctx ...keyvalue.T)  {}
@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/pkg/errors"
+)
+
+const (
+	// How long audit events are kept by default.
+	DefaultRetention = 30 * 24 * time.Hour
+)
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+	// How long audit events are kept, zero keeps them forever.
+	Retention toml.Duration `toml:"retention"`
+	// Forward audit events as syslog messages, e.g. udp://localhost:514 or tcp://localhost:514.
+	SyslogURL string `toml:"syslog-url"`
+	// Forward audit events as JSON with a POST request to the URL.
+	HTTPURL string `toml:"http-url"`
+	// Headers of the POST requests.
+	HTTPHeaders map[string]string `toml:"http-headers"`
+	// Do not verify the certificate of the HTTP URL.
+	InsecureSkipVerify bool `toml:"insecure-skip-verify"`
+}
+
+func NewConfig() Config {
+	return Config{
+		Retention: toml.Duration(DefaultRetention),
+	}
+}
+
+func (c Config) Validate() error {
+	if c.Retention < 0 {
+		return errors.New("retention must not be negative")
+	}
+	if c.SyslogURL != "" {
+		u, err := url.Parse(c.SyslogURL)
+		if err != nil {
+			return errors.Wrapf(err, "invalid syslog-url %q", c.SyslogURL)
+		}
+		if u.Scheme != "udp" && u.Scheme != "tcp" {
+			return errors.Errorf("invalid syslog-url %q, scheme must be udp or tcp", c.SyslogURL)
+		}
+		if u.Host == "" {
+			return errors.Errorf("invalid syslog-url %q, must specify a host", c.SyslogURL)
+		}
+	}
+	if c.HTTPURL != "" {
+		u, err := url.Parse(c.HTTPURL)
+		if err != nil {
+			return errors.Wrapf(err, "invalid http-url %q", c.HTTPURL)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return errors.Errorf("invalid http-url %q, must be an http(s) URL", c.HTTPURL)
+		}
+	}
+	return nil
+}
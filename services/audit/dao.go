@@ -0,0 +1,157 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/influxdata/kapacitor/services/storage"
+)
+
+// Data access object for audit events.
+type EventDAO interface {
+	// Append an event.
+	Create(e Event) error
+
+	// Delete an event.
+	Delete(id string) error
+
+	// List the events recorded in [since, until) which match, most recent first,
+	// skipping offset events and returning at most limit events, or all of them if limit <= 0.
+	// A zero since or until does not bound the time of the events.
+	List(since, until time.Time, match func(Event) bool, offset, limit int) ([]Event, error)
+
+	// Oldest returns at most limit events, oldest first.
+	Oldest(limit int) ([]Event, error)
+
+	Rebuild() error
+}
+
+//--------------------------------------------------------------------
+// The following structures are stored in a database via JSON encoding.
+// Changes to the structures could break existing data.
+
+// version is the current version of the stored structures.
+const version = 1
+
+type Event struct {
+	// IDs sort in the order the events were recorded.
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	User       string    `json:"user"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	RemoteAddr string    `json:"remote-addr"`
+	Changes    []Change  `json:"changes"`
+}
+
+// Change of a field of the JSON representation of a resource,
+// nested fields are separated by dots.
+type Change struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+func (e Event) ObjectID() string {
+	return e.ID
+}
+
+func (e Event) MarshalBinary() ([]byte, error) {
+	return storage.VersionJSONEncode(version, e)
+}
+
+func (e *Event) UnmarshalBinary(data []byte) error {
+	return storage.VersionJSONDecode(data, func(version int, dec *json.Decoder) error {
+		return dec.Decode(e)
+	})
+}
+
+// Number of events read at once when paging through the events.
+const listBatchSize = 100
+
+// Key/Value store based implementation of the EventDAO
+type eventKV struct {
+	kv    storage.Interface
+	store *storage.IndexedStore
+}
+
+func newEventKV(store storage.Interface) (*eventKV, error) {
+	c := storage.DefaultIndexedStoreConfig("events", func() storage.BinaryObject {
+		return new(Event)
+	})
+	istore, err := storage.NewIndexedStore(store, c)
+	if err != nil {
+		return nil, err
+	}
+	return &eventKV{
+		kv:    store,
+		store: istore,
+	}, nil
+}
+
+func (kv *eventKV) Create(e Event) error {
+	return kv.store.Create(&e)
+}
+
+func (kv *eventKV) Delete(id string) error {
+	return kv.store.Delete(id)
+}
+
+// List pages through the events in the order of their IDs, which is the order of their times,
+// so that only the events up to the limit or older than since are read.
+func (kv *eventKV) List(since, until time.Time, match func(Event) bool, offset, limit int) ([]Event, error) {
+	events := []Event{}
+	err := kv.kv.View(func(tx storage.ReadOnlyTx) error {
+		for i := 0; ; i += listBatchSize {
+			objects, err := kv.store.ReverseListTx(tx, storage.DefaultIDIndex, "", i, listBatchSize)
+			if err != nil {
+				return err
+			}
+			for _, object := range objects {
+				e, ok := object.(*Event)
+				if !ok {
+					return storage.ImpossibleTypeErr(e, object)
+				}
+				if !since.IsZero() && e.Time.Before(since) {
+					return nil
+				}
+				if !until.IsZero() && !e.Time.Before(until) || !match(*e) {
+					continue
+				}
+				if offset > 0 {
+					offset--
+					continue
+				}
+				events = append(events, *e)
+				if limit > 0 && len(events) == limit {
+					return nil
+				}
+			}
+			if len(objects) < listBatchSize {
+				return nil
+			}
+		}
+	})
+	return events, err
+}
+
+func (kv *eventKV) Oldest(limit int) ([]Event, error) {
+	objects, err := kv.store.List(storage.DefaultIDIndex, "", 0, limit)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]Event, len(objects))
+	for i, object := range objects {
+		e, ok := object.(*Event)
+		if !ok {
+			return nil, storage.ImpossibleTypeErr(e, object)
+		}
+		events[i] = *e
+	}
+	return events, nil
+}
+
+func (kv *eventKV) Rebuild() error {
+	return kv.store.Rebuild()
+}
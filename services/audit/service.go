@@ -0,0 +1,483 @@
+// Package audit records the mutating requests to the HTTP API.
+//
+// Each event records who made the request, when, and the changes of the fields of the resource it mutated.
+// Events are kept in the storage service and are optionally forwarded to syslog or to an HTTP endpoint.
+// Only admin users are allowed to query the audit log.
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/services/storage"
+	"github.com/pkg/errors"
+)
+
+const (
+	auditPath = "/audit"
+
+	// Public name of the store
+	eventsAPIName = "audit"
+	// The storage namespace for all audit data.
+	auditNamespace = "audit"
+
+	// Number of events buffered for forwarding, events are dropped when the buffer is full.
+	forwardBufferSize = 1000
+	// How often expired events are deleted.
+	cleanupInterval = time.Hour
+)
+
+// Fields of resources that change without being mutated by a request, they are not recorded as changes.
+var volatileFields = map[string]bool{
+	"stats":        true,
+	"dot":          true,
+	"created":      true,
+	"modified":     true,
+	"last-enabled": true,
+}
+
+// Names of fields whose values are secret, also matched as the suffix of the names of fields, e.g. api-key.
+var secretFields = []string{"password", "token", "secret", "key"}
+
+// redacted replaces the values of secret fields in the changes.
+const redacted = "[REDACTED]"
+
+type Diagnostic interface {
+	Error(msg string, err error)
+}
+
+type Service struct {
+	config Config
+	diag   Diagnostic
+	routes []httpd.Route
+	events EventDAO
+
+	mu     sync.Mutex
+	lastID int64
+
+	forward    chan client.AuditEvent
+	httpClient *http.Client
+	syslog     net.Conn
+	hostname   string
+
+	closing chan struct{}
+	wg      sync.WaitGroup
+
+	StorageService interface {
+		EncryptedStore(namespace string) (storage.Interface, error)
+		Register(name string, store storage.StoreActioner)
+	}
+	HTTPDService interface {
+		AddRoutes([]httpd.Route) error
+		DelRoutes([]httpd.Route)
+	}
+}
+
+func NewService(c Config, d Diagnostic) *Service {
+	hostname, _ := os.Hostname()
+	return &Service{
+		config:   c,
+		diag:     d,
+		hostname: hostname,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: c.InsecureSkipVerify,
+				},
+			},
+		},
+	}
+}
+
+func (s *Service) Open() error {
+	// The changes may contain the values of the options of handlers, so they are encrypted at rest.
+	store, err := s.StorageService.EncryptedStore(auditNamespace)
+	if err != nil {
+		return err
+	}
+	events, err := newEventKV(store)
+	if err != nil {
+		return err
+	}
+	s.events = events
+	s.StorageService.Register(eventsAPIName, s.events)
+
+	// The route has no scope so only admin users may query the audit log.
+	s.routes = []httpd.Route{
+		{
+			Method:      "GET",
+			Pattern:     auditPath,
+			HandlerFunc: s.handleListEvents,
+		},
+	}
+	if err := s.HTTPDService.AddRoutes(s.routes); err != nil {
+		return errors.Wrap(err, "failed to add API routes")
+	}
+
+	s.closing = make(chan struct{})
+	if s.config.SyslogURL != "" || s.config.HTTPURL != "" {
+		s.forward = make(chan client.AuditEvent, forwardBufferSize)
+		s.wg.Add(1)
+		go s.runForward()
+	}
+	if s.config.Retention > 0 {
+		s.wg.Add(1)
+		go s.runCleanup()
+	}
+	return nil
+}
+
+func (s *Service) Close() error {
+	if s.HTTPDService != nil {
+		s.HTTPDService.DelRoutes(s.routes)
+	}
+	if s.closing != nil {
+		close(s.closing)
+		s.wg.Wait()
+	}
+	if s.syslog != nil {
+		s.syslog.Close()
+	}
+	return nil
+}
+
+// Record an event of the HTTP API.
+func (s *Service) Record(he httpd.AuditEvent) {
+	changes, err := diff(he.Before, he.After)
+	if err != nil {
+		s.diag.Error("failed to compute changes of audited resource", err)
+	}
+	e := Event{
+		ID:         s.nextID(he.Time),
+		Time:       he.Time,
+		User:       he.User,
+		Method:     he.Method,
+		Path:       he.Path,
+		Status:     he.Status,
+		RemoteAddr: he.RemoteAddr,
+		Changes:    changes,
+	}
+	if err := s.events.Create(e); err != nil {
+		s.diag.Error("failed to store audit event", err)
+	}
+	if s.forward != nil {
+		select {
+		case s.forward <- convertEvent(e):
+		default:
+			s.diag.Error("dropping audit event", errors.New("forwarding buffer is full"))
+		}
+	}
+}
+
+// nextID returns an ID which sorts after all previous IDs.
+func (s *Service) nextID(t time.Time) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := t.UnixNano()
+	if id <= s.lastID {
+		id = s.lastID + 1
+	}
+	s.lastID = id
+	return fmt.Sprintf("%020d", id)
+}
+
+// diff returns the changes of the fields between two JSON representations of a resource,
+// either of which may be nil. The values of secret fields are redacted, only the fact that they changed is kept.
+func diff(before, after []byte) ([]Change, error) {
+	if before == nil && after == nil {
+		return nil, nil
+	}
+	oldFields, err := flatten(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := flatten(after)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for field, o := range oldFields {
+		if n, ok := newFields[field]; !ok || !reflect.DeepEqual(o, n) {
+			changes = append(changes, Change{Field: field, Old: o.value, New: n.value})
+		}
+	}
+	for field, n := range newFields {
+		if _, ok := oldFields[field]; !ok {
+			changes = append(changes, Change{Field: field, New: n.value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes, nil
+}
+
+// fieldValue is the value of a field, or a digest of it if the field is secret.
+type fieldValue struct {
+	value  interface{}
+	digest [sha256.Size]byte
+}
+
+// flatten returns the values of the fields of a JSON object by their dot separated path.
+// Arrays are values, they are not flattened.
+// The options of alert handlers, which hold credentials and webhook URLs, and the fields with secret names
+// are redacted, their digest is only kept to find whether they changed.
+func flatten(data []byte) (map[string]fieldValue, error) {
+	fields := make(map[string]fieldValue)
+	if data == nil {
+		return fields, nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	_, isHandler := obj["kind"]
+	for k, v := range obj {
+		if volatileFields[k] || k == "link" {
+			continue
+		}
+		flattenValue(fields, k, v, isHandler && k == "options")
+	}
+	return fields, nil
+}
+
+func flattenValue(fields map[string]fieldValue, prefix string, v interface{}, secret bool) {
+	obj, ok := v.(map[string]interface{})
+	if !ok || len(obj) == 0 {
+		if secret || isSecretField(prefix) {
+			data, _ := json.Marshal(v)
+			fields[prefix] = fieldValue{value: redacted, digest: sha256.Sum256(data)}
+			return
+		}
+		fields[prefix] = fieldValue{value: v}
+		return
+	}
+	for k, v := range obj {
+		flattenValue(fields, prefix+"."+k, v, secret)
+	}
+}
+
+// isSecretField reports whether the last name of the dot separated path of a field is secret.
+func isSecretField(field string) bool {
+	name := strings.ToLower(field[strings.LastIndex(field, ".")+1:])
+	for _, secret := range secretFields {
+		if name == secret || strings.HasSuffix(name, "-"+secret) || strings.HasSuffix(name, "_"+secret) {
+			return true
+		}
+	}
+	return false
+}
+
+func convertEvent(e Event) client.AuditEvent {
+	changes := make([]client.AuditChange, len(e.Changes))
+	for i, c := range e.Changes {
+		changes[i] = client.AuditChange{
+			Field: c.Field,
+			Old:   c.Old,
+			New:   c.New,
+		}
+	}
+	return client.AuditEvent{
+		ID:         e.ID,
+		Time:       e.Time,
+		User:       e.User,
+		Method:     e.Method,
+		Path:       e.Path,
+		Status:     e.Status,
+		RemoteAddr: e.RemoteAddr,
+		Changes:    changes,
+	}
+}
+
+func (s *Service) handleListEvents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	user := q.Get("user")
+	pathPrefix := q.Get("path")
+	var since, until time.Time
+	if str := q.Get("since"); str != "" {
+		t, err := time.Parse(time.RFC3339Nano, str)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid since parameter %q: %s", str, err), true, http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+	if str := q.Get("until"); str != "" {
+		t, err := time.Parse(time.RFC3339Nano, str)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid until parameter %q: %s", str, err), true, http.StatusBadRequest)
+			return
+		}
+		until = t
+	}
+	offset, limit := 0, 100
+	if str := q.Get("offset"); str != "" {
+		i, err := strconv.Atoi(str)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid offset parameter %q must be an integer: %s", str, err), true, http.StatusBadRequest)
+			return
+		}
+		offset = i
+	}
+	if str := q.Get("limit"); str != "" {
+		i, err := strconv.Atoi(str)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid limit parameter %q must be an integer: %s", str, err), true, http.StatusBadRequest)
+			return
+		}
+		limit = i
+	}
+
+	match := func(e Event) bool {
+		return (user == "" || e.User == user) && (pathPrefix == "" || strings.HasPrefix(e.Path, pathPrefix))
+	}
+	// Most recent events first
+	events, err := s.events.List(since, until, match, offset, limit)
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to list audit events: %s", err), true, http.StatusInternalServerError)
+		return
+	}
+	type response struct {
+		Events []client.AuditEvent `json:"events"`
+	}
+	res := response{Events: make([]client.AuditEvent, len(events))}
+	for i, e := range events {
+		res.Events[i] = convertEvent(e)
+	}
+	w.Write(httpd.MarshalJSON(res, true))
+}
+
+func (s *Service) runCleanup() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.deleteExpired(time.Now().Add(-time.Duration(s.config.Retention))); err != nil {
+			s.diag.Error("failed to delete expired audit events", err)
+		}
+		select {
+		case <-s.closing:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deleteExpired deletes the events recorded before the time, reading the oldest events in batches.
+func (s *Service) deleteExpired(before time.Time) error {
+	for {
+		events, err := s.events.Oldest(listBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if !e.Time.Before(before) {
+				// Events are sorted by time.
+				return nil
+			}
+			if err := s.events.Delete(e.ID); err != nil {
+				return err
+			}
+		}
+		if len(events) < listBatchSize {
+			return nil
+		}
+	}
+}
+
+func (s *Service) runForward() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.closing:
+			return
+		case e := <-s.forward:
+			if s.config.SyslogURL != "" {
+				if err := s.sendSyslog(e); err != nil {
+					s.diag.Error("failed to forward audit event to syslog", err)
+				}
+			}
+			if s.config.HTTPURL != "" {
+				if err := s.post(e); err != nil {
+					s.diag.Error("failed to forward audit event over HTTP", err)
+				}
+			}
+		}
+	}
+}
+
+// Priority of the syslog messages, the log audit facility (13) with the informational severity (6).
+const syslogPriority = 13*8 + 6
+
+// sendSyslog sends the event as an RFC 5424 message, using octet counting framing over TCP.
+func (s *Service) sendSyslog(e client.AuditEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("<%d>1 %s %s kapacitor - audit - %s", syslogPriority, e.Time.Format(time.RFC3339Nano), s.hostname, data)
+
+	u, err := url.Parse(s.config.SyslogURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "tcp" {
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+	// Reconnect once, in case the server closed the connection.
+	for i := 0; i < 2; i++ {
+		if s.syslog == nil {
+			s.syslog, err = net.DialTimeout(u.Scheme, u.Host, 10*time.Second)
+			if err != nil {
+				return err
+			}
+		}
+		if _, err = s.syslog.Write([]byte(msg)); err == nil {
+			return nil
+		}
+		s.syslog.Close()
+		s.syslog = nil
+	}
+	return err
+}
+
+func (s *Service) post(e client.AuditEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.config.HTTPURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.config.HTTPHeaders {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected response code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/auth"
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/audit"
+	"github.com/influxdata/kapacitor/services/diagnostic"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/services/httpd/httpdtest"
+	"github.com/influxdata/kapacitor/services/storage/storagetest"
+)
+
+var diagService *diagnostic.Service
+
+func init() {
+	diagService = diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	diagService.Open()
+}
+
+// things is a fake API resource audited by the service.
+type things struct {
+	mu     sync.Mutex
+	things map[string]map[string]interface{}
+}
+
+func (t *things) routes() []httpd.Route {
+	return []httpd.Route{
+		{
+			Method: "GET",
+			// Stats of a thing change without it being mutated.
+			Pattern: "/things/",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				t.mu.Lock()
+				defer t.mu.Unlock()
+				thing, ok := t.things[r.URL.Path]
+				if !ok {
+					httpd.HttpError(w, "no thing", true, http.StatusNotFound)
+					return
+				}
+				thing["stats"] = time.Now().UnixNano()
+				w.Write(httpd.MarshalJSON(thing, true))
+			},
+		},
+		{
+			Method:  "POST",
+			Pattern: "/things",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				t.mu.Lock()
+				defer t.mu.Unlock()
+				thing := make(map[string]interface{})
+				json.NewDecoder(r.Body).Decode(&thing)
+				href := httpd.BasePath + "/things/" + thing["id"].(string)
+				thing["link"] = client.Link{Relation: client.Self, Href: href}
+				t.things[href] = thing
+				w.Write(httpd.MarshalJSON(thing, true))
+			},
+		},
+		{
+			Method:  "PATCH",
+			Pattern: "/things/",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				t.mu.Lock()
+				defer t.mu.Unlock()
+				json.NewDecoder(r.Body).Decode(&map[string]interface{}{})
+				thing := t.things[r.URL.Path]
+				thing["options"] = map[string]interface{}{"url": "http://new", "enabled": true}
+				w.WriteHeader(http.StatusNoContent)
+			},
+		},
+		{
+			Method:  "DELETE",
+			Pattern: "/things/",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				t.mu.Lock()
+				defer t.mu.Unlock()
+				delete(t.things, r.URL.Path)
+				w.WriteHeader(http.StatusNoContent)
+			},
+		},
+	}
+}
+
+func OpenNewService(c audit.Config) (*audit.Service, *httpdtest.Server, *client.Client) {
+	service := audit.NewService(c, diagService.NewAuditHandler())
+	service.StorageService = storagetest.New()
+	server := httpdtest.NewServer(testing.Verbose())
+	service.HTTPDService = server
+	server.Handler.AuditService = service
+	if err := service.Open(); err != nil {
+		panic(err)
+	}
+	t := &things{things: make(map[string]map[string]interface{})}
+	if err := server.AddRoutes(t.routes()); err != nil {
+		panic(err)
+	}
+	cli, err := client.New(client.Config{URL: server.Server.URL})
+	if err != nil {
+		panic(err)
+	}
+	return service, server, cli
+}
+
+func do(t *testing.T, method, url, body string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestService_Record(t *testing.T) {
+	c := audit.NewConfig()
+	c.Enabled = true
+	service, server, cli := OpenNewService(c)
+	defer server.Close()
+	defer service.Close()
+
+	url := server.Server.URL + httpd.BasePath
+	do(t, "POST", url+"/things", `{"id":"a","options":{"url":"http://old"}}`)
+	do(t, "GET", url+"/things/a", "")
+	do(t, "PATCH", url+"/things/a", `{}`)
+	do(t, "DELETE", url+"/things/a", "")
+	do(t, "DELETE", url+"/unknown", "")
+
+	events, err := cli.ListAuditEvents(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type event struct {
+		Method  string
+		Path    string
+		Status  int
+		Changes []client.AuditChange
+	}
+	got := make([]event, len(events))
+	for i, e := range events {
+		if e.User != auth.AdminUser.Name() {
+			t.Errorf("unexpected user %q", e.User)
+		}
+		got[i] = event{Method: e.Method, Path: e.Path, Status: e.Status, Changes: e.Changes}
+	}
+	exp := []event{
+		{
+			Method:  "DELETE",
+			Path:    "/kapacitor/v1/things/a",
+			Status:  http.StatusNoContent,
+			Changes: []client.AuditChange{{Field: "id", Old: "a"}, {Field: "options.enabled", Old: true}, {Field: "options.url", Old: "http://new"}},
+		},
+		{
+			Method:  "PATCH",
+			Path:    "/kapacitor/v1/things/a",
+			Status:  http.StatusNoContent,
+			Changes: []client.AuditChange{{Field: "options.enabled", New: true}, {Field: "options.url", Old: "http://old", New: "http://new"}},
+		},
+		{
+			Method:  "POST",
+			Path:    "/kapacitor/v1/things",
+			Status:  http.StatusOK,
+			Changes: []client.AuditChange{{Field: "id", New: "a"}, {Field: "options.url", New: "http://old"}},
+		},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected events:\ngot\n%+v\nexp\n%+v", got, exp)
+	}
+
+	events, err = cli.ListAuditEvents(&client.ListAuditEventsOptions{Path: "/kapacitor/v1/things/", Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Method != "PATCH" {
+		t.Errorf("unexpected filtered events %v", events)
+	}
+	events, err = cli.ListAuditEvents(&client.ListAuditEventsOptions{User: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no events of bob, got %v", events)
+	}
+}
+
+func TestService_RecordRedacted(t *testing.T) {
+	c := audit.NewConfig()
+	c.Enabled = true
+	service, server, cli := OpenNewService(c)
+	defer server.Close()
+	defer service.Close()
+
+	url := server.Server.URL + httpd.BasePath
+	// Things with a kind are like alert handlers, all their options are secret.
+	do(t, "POST", url+"/things", `{"id":"h","kind":"slack","options":{"url":"http://old","channel":"#ops"},"user":{"name":"bob","api-key":"k1"}}`)
+	do(t, "PATCH", url+"/things/h", `{}`)
+
+	events, err := cli.ListAuditEvents(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("unexpected number of events: got %d exp 2", len(events))
+	}
+	exp := [][]client.AuditChange{
+		{
+			// The channel was removed and enabled was added, a changed secret is redacted too.
+			{Field: "options.channel", Old: "[REDACTED]"},
+			{Field: "options.enabled", New: "[REDACTED]"},
+			{Field: "options.url", Old: "[REDACTED]", New: "[REDACTED]"},
+		},
+		{
+			{Field: "id", New: "h"},
+			{Field: "kind", New: "slack"},
+			{Field: "options.channel", New: "[REDACTED]"},
+			{Field: "options.url", New: "[REDACTED]"},
+			{Field: "user.api-key", New: "[REDACTED]"},
+			{Field: "user.name", New: "bob"},
+		},
+	}
+	for i := range exp {
+		if !reflect.DeepEqual(events[i].Changes, exp[i]) {
+			t.Errorf("unexpected changes of %s event:\ngot %+v\nexp %+v", events[i].Method, events[i].Changes, exp[i])
+		}
+	}
+}
+
+func TestService_ListPages(t *testing.T) {
+	c := audit.NewConfig()
+	c.Enabled = true
+	// The events are older than the retention.
+	c.Retention = 0
+	service, server, cli := OpenNewService(c)
+	defer server.Close()
+	defer service.Close()
+
+	// More events than are read at once.
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 250; i++ {
+		user := "alice"
+		if i%2 == 1 {
+			user = "bob"
+		}
+		service.Record(httpd.AuditEvent{
+			Time:   start.Add(time.Duration(i) * time.Second),
+			User:   user,
+			Method: "POST",
+			Path:   fmt.Sprintf("/kapacitor/v1/things/%d", i),
+			Status: http.StatusOK,
+		})
+	}
+	testCases := []struct {
+		opts  client.ListAuditEventsOptions
+		paths []int
+	}{
+		{
+			opts:  client.ListAuditEventsOptions{Limit: 3},
+			paths: []int{249, 248, 247},
+		},
+		{
+			opts:  client.ListAuditEventsOptions{User: "alice", Offset: 100, Limit: 2},
+			paths: []int{48, 46},
+		},
+		{
+			opts: client.ListAuditEventsOptions{
+				Since: start.Add(10 * time.Second),
+				Until: start.Add(13 * time.Second),
+			},
+			paths: []int{12, 11, 10},
+		},
+		{
+			opts:  client.ListAuditEventsOptions{Path: "/kapacitor/v1/things/24", Limit: -1},
+			paths: []int{249, 248, 247, 246, 245, 244, 243, 242, 241, 240, 24},
+		},
+	}
+	for _, tc := range testCases {
+		events, err := cli.ListAuditEvents(&tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths := make([]int, len(events))
+		for i, e := range events {
+			fmt.Sscanf(e.Path, "/kapacitor/v1/things/%d", &paths[i])
+		}
+		if !reflect.DeepEqual(paths, tc.paths) {
+			t.Errorf("unexpected events of %+v: got %v exp %v", tc.opts, paths, tc.paths)
+		}
+	}
+}
+
+func TestService_Forward(t *testing.T) {
+	received := make(chan client.AuditEvent, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, exp := r.Header.Get("Authorization"), "Bearer secret"; got != exp {
+			t.Errorf("unexpected authorization header: got %q exp %q", got, exp)
+		}
+		var e client.AuditEvent
+		json.NewDecoder(r.Body).Decode(&e)
+		received <- e
+	}))
+	defer ts.Close()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	c := audit.NewConfig()
+	c.Enabled = true
+	c.HTTPURL = ts.URL
+	c.HTTPHeaders = map[string]string{"Authorization": "Bearer secret"}
+	c.SyslogURL = "udp://" + conn.LocalAddr().String()
+	service, server, _ := OpenNewService(c)
+	defer server.Close()
+	defer service.Close()
+
+	do(t, "POST", server.Server.URL+httpd.BasePath+"/things", `{"id":"a"}`)
+
+	select {
+	case e := <-received:
+		if e.Method != "POST" || e.Path != "/kapacitor/v1/things" {
+			t.Errorf("unexpected forwarded event %v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the forwarded event")
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := string(buf[:n]); !strings.HasPrefix(msg, "<110>1 ") || !strings.Contains(msg, " kapacitor - audit - ") || !strings.Contains(msg, `"path":"/kapacitor/v1/things"`) {
+		t.Errorf("unexpected syslog message %q", msg)
+	}
+}
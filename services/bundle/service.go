@@ -0,0 +1,318 @@
+// Package bundle exports and imports the definitions of a server as a single document,
+// so they can be restored or copied to another server.
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/config"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/pkg/errors"
+)
+
+const (
+	bundlePath = "/bundle"
+
+	// Version of the bundle format
+	version = 1
+
+	// Number of templates and tasks listed per request
+	pageSize = 100
+)
+
+var defaultURL = "http://localhost:9092"
+
+type Diagnostic interface {
+	Error(msg string, err error)
+	Importing(kind, id string)
+}
+
+type Service struct {
+	cli    *client.Client
+	routes []httpd.Route
+
+	HTTPDService interface {
+		AddRoutes([]httpd.Route) error
+		DelRoutes([]httpd.Route)
+	}
+	ConfigOverrideService interface {
+		Overrides() ([]config.Override, error)
+	}
+
+	diag Diagnostic
+}
+
+func NewService(h http.Handler, d Diagnostic) (*Service, error) {
+	cfg := client.Config{
+		URL:       defaultURL,
+		UserAgent: "internal-bundle-service",
+	}
+	if h != nil {
+		cfg.Transport = client.NewLocalTransport(h)
+	}
+	cli, err := client.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %v", err)
+	}
+	return &Service{
+		cli:  cli,
+		diag: d,
+	}, nil
+}
+
+func (s *Service) Open() error {
+	// Define API routes
+	s.routes = []httpd.Route{
+		{
+			Method:      "GET",
+			Pattern:     bundlePath,
+			HandlerFunc: s.handleExport,
+		},
+		{
+			Method:      "POST",
+			Pattern:     bundlePath,
+			HandlerFunc: s.handleImport,
+		},
+	}
+
+	err := s.HTTPDService.AddRoutes(s.routes)
+	return errors.Wrap(err, "failed to add API routes")
+}
+
+func (s *Service) Close() error {
+	s.HTTPDService.DelRoutes(s.routes)
+	return nil
+}
+
+func (s *Service) handleExport(w http.ResponseWriter, r *http.Request) {
+	b, err := s.Export()
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.Write(httpd.MarshalJSON(b, true))
+}
+
+func (s *Service) handleImport(w http.ResponseWriter, r *http.Request) {
+	b := client.Bundle{}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&b); err != nil {
+		httpd.HttpError(w, "invalid JSON", true, http.StatusBadRequest)
+		return
+	}
+	if err := s.Import(b); err != nil {
+		s.diag.Error("failed to import bundle", err)
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Export returns a bundle of all templates, tasks, topic handlers and configuration overrides.
+func (s *Service) Export() (client.Bundle, error) {
+	b := client.Bundle{
+		Version:         version,
+		Created:         time.Now().UTC(),
+		Templates:       []client.CreateTemplateOptions{},
+		Tasks:           []client.CreateTaskOptions{},
+		TopicHandlers:   []client.TopicHandlerOptions{},
+		ConfigOverrides: []client.ConfigOverride{},
+	}
+
+	for offset := 0; ; offset += pageSize {
+		templates, err := s.cli.ListTemplates(&client.ListTemplatesOptions{
+			TemplateOptions: client.TemplateOptions{ScriptFormat: "raw"},
+			Offset:          offset,
+			Limit:           pageSize,
+		})
+		if err != nil {
+			return b, errors.Wrap(err, "failed to list templates")
+		}
+		for _, t := range templates {
+			b.Templates = append(b.Templates, client.CreateTemplateOptions{
+				ID:         t.ID,
+				Type:       t.Type,
+				TICKscript: t.TICKscript,
+				Schema:     t.Schema,
+			})
+		}
+		if len(templates) < pageSize {
+			break
+		}
+	}
+
+	for offset := 0; ; offset += pageSize {
+		tasks, err := s.cli.ListTasks(&client.ListTasksOptions{
+			TaskOptions: client.TaskOptions{ScriptFormat: "raw"},
+			Offset:      offset,
+			Limit:       pageSize,
+		})
+		if err != nil {
+			return b, errors.Wrap(err, "failed to list tasks")
+		}
+		for _, t := range tasks {
+			b.Tasks = append(b.Tasks, createTaskOptions(t))
+		}
+		if len(tasks) < pageSize {
+			break
+		}
+	}
+
+	topics, err := s.cli.ListTopics(nil)
+	if err != nil {
+		return b, errors.Wrap(err, "failed to list topics")
+	}
+	for _, topic := range topics.Topics {
+		handlers, err := s.cli.ListTopicHandlers(topic.HandlersLink, nil)
+		if err != nil {
+			return b, errors.Wrapf(err, "failed to list handlers of topic %s", topic.ID)
+		}
+		for _, h := range handlers.Handlers {
+			b.TopicHandlers = append(b.TopicHandlers, client.TopicHandlerOptions{
+				Topic:   topic.ID,
+				ID:      h.ID,
+				Kind:    h.Kind,
+				Options: h.Options,
+				Match:   h.Match,
+			})
+		}
+	}
+
+	overrides, err := s.ConfigOverrideService.Overrides()
+	if err != nil {
+		return b, err
+	}
+	for _, o := range overrides {
+		section, element := o.SectionAndElement()
+		b.ConfigOverrides = append(b.ConfigOverrides, client.ConfigOverride{
+			Section: section,
+			Element: element,
+			Create:  o.Create,
+			Options: o.Options,
+		})
+	}
+	return b, nil
+}
+
+// createTaskOptions returns the options that create a task with the same definition.
+func createTaskOptions(t client.Task) client.CreateTaskOptions {
+	o := client.CreateTaskOptions{
+		ID:         t.ID,
+		TemplateID: t.TemplateID,
+		DBRPs:      t.DBRPs,
+		Status:     t.Status,
+		Vars:       t.Vars,
+		Labels:     t.Labels,
+		Schedule:   t.Schedule,
+		Restart:    t.Restart,
+		DependsOn:  t.DependsOn,
+	}
+	// The type and TICKscript of a templated task are defined by its template
+	if t.TemplateID == "" {
+		o.Type = t.Type
+		o.TICKscript = t.TICKscript
+	}
+	if t.Limits != (client.TaskLimits{}) {
+		limits := t.Limits
+		o.Limits = &limits
+	}
+	if t.Priority != 0 {
+		priority := t.Priority
+		o.Priority = &priority
+	}
+	return o
+}
+
+// Import creates or replaces the definitions of the bundle.
+// Definitions that are not part of the bundle are left as they are.
+func (s *Service) Import(b client.Bundle) error {
+	if b.Version != version {
+		return fmt.Errorf("unsupported bundle version %d, expected %d", b.Version, version)
+	}
+
+	// Configuration comes first since handlers and tasks may use the services it configures.
+	overrides, err := s.ConfigOverrideService.Overrides()
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(overrides))
+	for _, o := range overrides {
+		section, element := o.SectionAndElement()
+		existing[path.Join(section, element)] = true
+	}
+	for _, o := range b.ConfigOverrides {
+		id := path.Join(o.Section, o.Element)
+		s.diag.Importing("config override", id)
+		var err error
+		if o.Create && !existing[id] {
+			err = s.cli.ConfigUpdate(s.cli.ConfigSectionLink(o.Section), client.ConfigUpdateAction{Add: o.Options})
+		} else {
+			err = s.cli.ConfigUpdate(s.cli.ConfigElementLink(o.Section, o.Element), client.ConfigUpdateAction{Set: o.Options})
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to import config override %s", id)
+		}
+	}
+
+	for _, o := range b.Templates {
+		s.diag.Importing("template", o.ID)
+		l := s.cli.TemplateLink(o.ID)
+		if t, _ := s.cli.Template(l, nil); t.ID == "" {
+			_, err = s.cli.CreateTemplate(o)
+		} else {
+			schema := o.Schema
+			if schema == nil {
+				schema = map[string]client.VarSchema{}
+			}
+			_, err = s.cli.UpdateTemplate(l, client.UpdateTemplateOptions{
+				Type:       o.Type,
+				TICKscript: o.TICKscript,
+				Schema:     schema,
+			})
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to import template %s", o.ID)
+		}
+	}
+
+	// Existing tasks are replaced so that no part of their previous definition remains.
+	// Dependencies are set once all tasks exist, since tasks can only depend on existing tasks.
+	for _, o := range b.Tasks {
+		s.diag.Importing("task", o.ID)
+		l := s.cli.TaskLink(o.ID)
+		if err := s.cli.DeleteTask(l); err != nil {
+			return errors.Wrapf(err, "failed to replace task %s", o.ID)
+		}
+		o.DependsOn = nil
+		if _, err := s.cli.CreateTask(o); err != nil {
+			return errors.Wrapf(err, "failed to import task %s", o.ID)
+		}
+	}
+	for _, o := range b.Tasks {
+		if len(o.DependsOn) == 0 {
+			continue
+		}
+		if _, err := s.cli.UpdateTask(s.cli.TaskLink(o.ID), client.UpdateTaskOptions{DependsOn: o.DependsOn}); err != nil {
+			return errors.Wrapf(err, "failed to import dependencies of task %s", o.ID)
+		}
+	}
+
+	for _, o := range b.TopicHandlers {
+		s.diag.Importing("topic handler", o.Topic+"/"+o.ID)
+		l := s.cli.TopicHandlerLink(o.Topic, o.ID)
+		if h, _ := s.cli.TopicHandler(l); h.ID == "" {
+			_, err = s.cli.CreateTopicHandler(s.cli.TopicHandlersLink(o.Topic), o)
+		} else {
+			_, err = s.cli.ReplaceTopicHandler(l, o)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to import topic handler %s/%s", o.Topic, o.ID)
+		}
+	}
+	return nil
+}
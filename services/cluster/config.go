@@ -0,0 +1,128 @@
+package cluster
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultShards          = 64
+	DefaultWriteTimeout    = 10 * time.Second
+	DefaultMaxQueuedPoints = 100000
+)
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+	// Name of this node, it must be one of the nodes.
+	Node string `toml:"node"`
+	// Nodes of the cluster, every node must be configured with the same nodes.
+	Nodes []NodeConfig `toml:"nodes"`
+	// Number of shards the stream data is partitioned into.
+	Shards int `toml:"shards"`
+	// Tags partitioning the points of measurements, the points of other measurements are partitioned by measurement.
+	Partitions []PartitionConfig `toml:"partition"`
+	// Timeout for forwarding points to another node.
+	WriteTimeout toml.Duration `toml:"write-timeout"`
+	// Maximum number of points queued to be forwarded to another node, writes fail once it is reached.
+	MaxQueuedPoints int `toml:"max-queued-points"`
+
+	// Credentials of an admin user of the other nodes.
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	Token    string `toml:"token"`
+	// Path to CA file
+	SSLCA string `toml:"ssl-ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl-cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl-key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool `toml:"insecure-skip-verify"`
+}
+
+type NodeConfig struct {
+	Name string `toml:"name"`
+	// URL of the HTTP API of the node.
+	URL string `toml:"url"`
+}
+
+// PartitionConfig partitions the points of a measurement by the measurement and the values of the tags.
+type PartitionConfig struct {
+	Measurement string   `toml:"measurement"`
+	Tags        []string `toml:"tags"`
+}
+
+func NewConfig() Config {
+	return Config{
+		Shards:          DefaultShards,
+		WriteTimeout:    toml.Duration(DefaultWriteTimeout),
+		MaxQueuedPoints: DefaultMaxQueuedPoints,
+	}
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Node == "" {
+		return errors.New("must specify node")
+	}
+	if len(c.Nodes) == 0 {
+		return errors.New("must specify at least one node")
+	}
+	found := false
+	names := make(map[string]bool, len(c.Nodes))
+	for _, n := range c.Nodes {
+		if n.Name == "" {
+			return errors.New("must specify name of node")
+		}
+		if names[n.Name] {
+			return errors.Errorf("duplicate node %q", n.Name)
+		}
+		names[n.Name] = true
+		if n.Name == c.Node {
+			found = true
+		}
+		u, err := url.Parse(n.URL)
+		if err != nil {
+			return errors.Wrapf(err, "invalid url of node %q", n.Name)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.Errorf("invalid url %q of node %q, must be an http(s) URL", n.URL, n.Name)
+		}
+	}
+	if !found {
+		return errors.Errorf("node %q is not one of the nodes", c.Node)
+	}
+	if c.Shards < len(c.Nodes) {
+		return errors.New("shards must be at least the number of nodes")
+	}
+	measurements := make(map[string]bool, len(c.Partitions))
+	for _, p := range c.Partitions {
+		if p.Measurement == "" {
+			return errors.New("must specify measurement of partition")
+		}
+		if measurements[p.Measurement] {
+			return errors.Errorf("duplicate partition of measurement %q", p.Measurement)
+		}
+		measurements[p.Measurement] = true
+		for _, t := range p.Tags {
+			if t == "" {
+				return errors.Errorf("empty tag in partition of measurement %q", p.Measurement)
+			}
+		}
+	}
+	if c.WriteTimeout <= 0 {
+		return errors.New("write-timeout must be positive")
+	}
+	if c.MaxQueuedPoints <= 0 {
+		return errors.New("max-queued-points must be positive")
+	}
+	if c.Token != "" && (c.Username != "" || c.Password != "") {
+		return errors.New("cannot use both token and username/password")
+	}
+	return nil
+}
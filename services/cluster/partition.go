@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"sort"
+	"strconv"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// partitioner assigns the points to shards and the shards to nodes.
+// Every node of the cluster must compute the same assignment,
+// so it only depends on the configuration of the cluster.
+type partitioner struct {
+	shards int
+	// Sorted names of the nodes.
+	nodes []string
+	// Partition tags by measurement.
+	tags map[string][]string
+}
+
+func newPartitioner(c Config) *partitioner {
+	p := &partitioner{
+		shards: c.Shards,
+		nodes:  make([]string, len(c.Nodes)),
+		tags:   make(map[string][]string, len(c.Partitions)),
+	}
+	for i, n := range c.Nodes {
+		p.nodes[i] = n.Name
+	}
+	sort.Strings(p.nodes)
+	for _, pc := range c.Partitions {
+		p.tags[pc.Measurement] = pc.Tags
+	}
+	return p
+}
+
+// Shard returns the shard of the point, from its measurement and the values of the partition tags of the measurement.
+func (p *partitioner) Shard(pt models.Point) int {
+	h := fnv.New32a()
+	name := pt.Name()
+	h.Write([]byte(name))
+	if tags := p.tags[name]; len(tags) > 0 {
+		ptTags := pt.Tags()
+		for _, t := range tags {
+			h.Write([]byte{0})
+			h.Write(ptTags.Get([]byte(t)))
+		}
+	}
+	return int(h.Sum32() % uint32(p.shards))
+}
+
+// Owner returns the node owning the shard, the shards are assigned round robin to the sorted nodes.
+func (p *partitioner) Owner(shard int) string {
+	return p.nodes[shard%len(p.nodes)]
+}
+
+// KeyOwner returns the node owning the key, e.g. the ID of a batch task.
+func (p *partitioner) KeyOwner(key string) string {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return p.Owner(int(h.Sum32() % uint32(p.shards)))
+}
+
+// Shards returns the shards owned by the node.
+func (p *partitioner) Shards(node string) []int {
+	var shards []int
+	for i := 0; i < p.shards; i++ {
+		if p.Owner(i) == node {
+			shards = append(shards, i)
+		}
+	}
+	return shards
+}
+
+// fingerprint identifies the configuration of the cluster,
+// the nodes only accept the points forwarded by the nodes with the same configuration.
+func fingerprint(c Config) string {
+	h := sha256.New()
+	nodes := append([]NodeConfig(nil), c.Nodes...)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	for _, n := range nodes {
+		h.Write([]byte(n.Name + "\x00" + n.URL + "\x00"))
+	}
+	h.Write([]byte(strconv.Itoa(c.Shards) + "\x00"))
+	partitions := append([]PartitionConfig(nil), c.Partitions...)
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].Measurement < partitions[j].Measurement })
+	for _, pc := range partitions {
+		h.Write([]byte(pc.Measurement + "\x00"))
+		for _, t := range pc.Tags {
+			h.Write([]byte(t + "\x00"))
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
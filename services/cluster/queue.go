@@ -0,0 +1,91 @@
+package cluster
+
+import (
+	"sync"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// batch is the points of a write to forward to a node.
+type batch struct {
+	database        string
+	retentionPolicy string
+	points          []models.Point
+}
+
+// queue holds the batches to forward to a node, in the order they were written.
+type queue struct {
+	mu      sync.Mutex
+	batches []batch
+	// Number of points queued or reserved.
+	size int
+	max  int
+	// ready is signaled when a batch is pushed.
+	ready chan struct{}
+}
+
+func newQueue(max int) *queue {
+	return &queue{
+		max:   max,
+		ready: make(chan struct{}, 1),
+	}
+}
+
+// reserve reserves room for n points, it reports false if the queue is full.
+func (q *queue) reserve(n int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.size+n > q.max {
+		return false
+	}
+	q.size += n
+	return true
+}
+
+// release releases the room reserved for n points.
+func (q *queue) release(n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.size -= n
+}
+
+// push queues the batch in the room reserved for its points.
+func (q *queue) push(b batch) {
+	q.mu.Lock()
+	q.batches = append(q.batches, b)
+	q.mu.Unlock()
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// front returns the oldest batch, ok is false if the queue is empty.
+func (q *queue) front() (b batch, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.batches) == 0 {
+		return batch{}, false
+	}
+	return q.batches[0], true
+}
+
+// pop removes the oldest batch.
+func (q *queue) pop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.size -= len(q.batches[0].points)
+	q.batches[0] = batch{}
+	q.batches = q.batches[1:]
+}
+
+// Len returns the number of points queued.
+func (q *queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, b := range q.batches {
+		n += len(b.points)
+	}
+	return n
+}
@@ -0,0 +1,304 @@
+// Package cluster partitions the stream data across the nodes of a Kapacitor cluster.
+//
+// The points are assigned to shards by their measurement and the values of the partition tags of the measurement,
+// and the shards are assigned to the nodes.
+// A node receiving points writes the points of its own shards to its tasks,
+// and forwards the other points to the nodes owning their shards.
+// The points to forward are queued per node, and retried while the node is down, so a write either
+// queues all its points of other nodes and writes its own points, or fails without writing any point.
+// So every node runs the stream tasks on the points of its shards only,
+// and the partition tags of a measurement must be among the tags the tasks using it group by.
+//
+// Batch tasks query their data, each of them runs on a single node assigned from the task ID.
+// All the nodes must have the same task definitions, e.g. loaded from the same load directory.
+package cluster
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/tlsconfig"
+	"github.com/pkg/errors"
+)
+
+const (
+	clusterPath = "/cluster"
+	writePath   = clusterPath + "/write"
+
+	// Intervals between the retries of forwarding points to a node.
+	minRetryInterval = 100 * time.Millisecond
+	maxRetryInterval = 10 * time.Second
+)
+
+type Diagnostic interface {
+	Error(msg string, err error)
+	OpenedNode(node string, shards []int)
+}
+
+type Service struct {
+	config      Config
+	diag        Diagnostic
+	partitioner *partitioner
+	fingerprint string
+	routes      []httpd.Route
+	// Clients of the other nodes by name.
+	clients map[string]*client.Client
+	// Queues of the points to forward to the other nodes by name.
+	queues  map[string]*queue
+	closing chan struct{}
+	wg      sync.WaitGroup
+
+	// PointsWriter writes the points of the shards of this node.
+	PointsWriter interface {
+		WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+	}
+	HTTPDService interface {
+		AddRoutes([]httpd.Route) error
+		DelRoutes([]httpd.Route)
+	}
+}
+
+func NewService(c Config, d Diagnostic) *Service {
+	return &Service{
+		config:      c,
+		diag:        d,
+		partitioner: newPartitioner(c),
+		fingerprint: fingerprint(c),
+	}
+}
+
+func (s *Service) Open() error {
+	tlsConfig, err := tlsconfig.Create(s.config.SSLCA, s.config.SSLCert, s.config.SSLKey, s.config.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	s.clients = make(map[string]*client.Client, len(s.config.Nodes)-1)
+	s.queues = make(map[string]*queue, len(s.config.Nodes)-1)
+	for _, n := range s.config.Nodes {
+		if n.Name == s.config.Node {
+			continue
+		}
+		c := client.Config{
+			URL:       n.URL,
+			Timeout:   time.Duration(s.config.WriteTimeout),
+			TLSConfig: tlsConfig,
+		}
+		if s.config.Token != "" {
+			c.Credentials = &client.Credentials{
+				Method: client.BearerAuthentication,
+				Token:  s.config.Token,
+			}
+		} else if s.config.Username != "" {
+			c.Credentials = &client.Credentials{
+				Method:   client.UserAuthentication,
+				Username: s.config.Username,
+				Password: s.config.Password,
+			}
+		}
+		cli, err := client.New(c)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create client of node %q", n.Name)
+		}
+		s.clients[n.Name] = cli
+		s.queues[n.Name] = newQueue(s.config.MaxQueuedPoints)
+	}
+
+	// The routes have no scope so only admin users may use them.
+	s.routes = []httpd.Route{
+		{
+			Method:      "GET",
+			Pattern:     clusterPath,
+			HandlerFunc: s.handleStatus,
+		},
+		{
+			Method:      "POST",
+			Pattern:     writePath,
+			HandlerFunc: s.handleWrite,
+			NoAudit:     true,
+		},
+	}
+	if err := s.HTTPDService.AddRoutes(s.routes); err != nil {
+		return errors.Wrap(err, "failed to add API routes")
+	}
+	s.closing = make(chan struct{})
+	for node, q := range s.queues {
+		s.wg.Add(1)
+		go s.runForwarding(node, q)
+	}
+	s.diag.OpenedNode(s.config.Node, s.partitioner.Shards(s.config.Node))
+	return nil
+}
+
+func (s *Service) Close() error {
+	if s.HTTPDService != nil {
+		s.HTTPDService.DelRoutes(s.routes)
+	}
+	if s.closing != nil {
+		close(s.closing)
+		s.wg.Wait()
+		s.closing = nil
+	}
+	for node, q := range s.queues {
+		if n := q.Len(); n > 0 {
+			s.diag.Error("dropped queued points", errors.Errorf("%d points were not forwarded to node %q", n, node))
+		}
+	}
+	return nil
+}
+
+// OwnsTask reports whether the batch task runs on this node.
+func (s *Service) OwnsTask(id string) bool {
+	return s.partitioner.KeyOwner(id) == s.config.Node
+}
+
+// WritePoints writes the points of the shards of this node,
+// and queues the other points to be forwarded to the nodes owning their shards.
+// It fails without writing or queueing any point if this node fails to write its points or a queue is full.
+func (s *Service) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	var local []models.Point
+	remote := make(map[string][]models.Point)
+	for _, p := range points {
+		node := s.partitioner.Owner(s.partitioner.Shard(p))
+		if node == s.config.Node {
+			local = append(local, p)
+		} else {
+			remote[node] = append(remote[node], p)
+		}
+	}
+
+	reserved := make([]string, 0, len(remote))
+	release := func() {
+		for _, node := range reserved {
+			s.queues[node].release(len(remote[node]))
+		}
+	}
+	for node, pts := range remote {
+		if !s.queues[node].reserve(len(pts)) {
+			release()
+			return errors.Errorf("queue of the points to forward to node %q is full", node)
+		}
+		reserved = append(reserved, node)
+	}
+	if len(local) > 0 {
+		if err := s.PointsWriter.WritePoints(database, retentionPolicy, consistencyLevel, local); err != nil {
+			release()
+			return err
+		}
+	}
+	for node, pts := range remote {
+		s.queues[node].push(batch{
+			database:        database,
+			retentionPolicy: retentionPolicy,
+			points:          pts,
+		})
+	}
+	return nil
+}
+
+// runForwarding forwards the queued points to the node in order,
+// retrying with backoff while the node fails to write them.
+func (s *Service) runForwarding(node string, q *queue) {
+	defer s.wg.Done()
+	interval := minRetryInterval
+	for {
+		b, ok := q.front()
+		if !ok {
+			select {
+			case <-q.ready:
+				continue
+			case <-s.closing:
+				return
+			}
+		}
+		if err := s.forward(node, b.database, b.retentionPolicy, b.points); err != nil {
+			s.diag.Error("failed to forward points", errors.Wrapf(err, "failed to forward %d points to node %q, retrying in %v", len(b.points), node, interval))
+			select {
+			case <-time.After(interval):
+			case <-s.closing:
+				return
+			}
+			interval *= 2
+			if interval > maxRetryInterval {
+				interval = maxRetryInterval
+			}
+			continue
+		}
+		interval = minRetryInterval
+		q.pop()
+	}
+}
+
+func (s *Service) forward(node, database, retentionPolicy string, points []models.Point) error {
+	var buf bytes.Buffer
+	for _, p := range points {
+		buf.WriteString(p.String())
+		buf.WriteByte('\n')
+	}
+	return s.clients[node].ClusterWrite(client.ClusterWriteOptions{
+		Database:        database,
+		RetentionPolicy: retentionPolicy,
+		Fingerprint:     s.fingerprint,
+	}, buf.Bytes())
+}
+
+func (s *Service) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := client.ClusterStatus{
+		Node:        s.config.Node,
+		Fingerprint: s.fingerprint,
+		Shards:      s.config.Shards,
+		Nodes:       make([]client.ClusterNode, len(s.config.Nodes)),
+	}
+	for i, n := range s.config.Nodes {
+		status.Nodes[i] = client.ClusterNode{
+			Name:   n.Name,
+			URL:    n.URL,
+			Shards: s.partitioner.Shards(n.Name),
+		}
+	}
+	w.Write(httpd.MarshalJSON(status, true))
+}
+
+// handleWrite writes the points forwarded by another node, they belong to the shards of this node.
+func (s *Service) handleWrite(w http.ResponseWriter, r *http.Request) {
+	// Nodes with different configurations assign the points differently, and could forward them back and forth.
+	if fp := r.URL.Query().Get("fingerprint"); fp != s.fingerprint {
+		httpd.HttpError(w, fmt.Sprintf("cluster configuration of node %q differs from the forwarding node", s.config.Node), true, http.StatusConflict)
+		return
+	}
+	database := r.URL.Query().Get("db")
+	if database == "" {
+		httpd.HttpError(w, "database is required", true, http.StatusBadRequest)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	points, err := models.ParsePointsWithPrecision(body, time.Now().UTC(), "n")
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if err := s.PointsWriter.WritePoints(
+		database,
+		r.URL.Query().Get("rp"),
+		models.ConsistencyLevelAll,
+		points,
+	); influxdb.IsClientError(err) {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
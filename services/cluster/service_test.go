@@ -0,0 +1,357 @@
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/services/diagnostic"
+	"github.com/influxdata/kapacitor/services/httpd/httpdtest"
+	"github.com/pkg/errors"
+)
+
+type pointsWriter struct {
+	mu     sync.Mutex
+	points []models.Point
+	err    error
+}
+
+func (w *pointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	w.points = append(w.points, points...)
+	return nil
+}
+
+func (w *pointsWriter) SetError(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.err = err
+}
+
+func (w *pointsWriter) Points() []models.Point {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.points
+}
+
+type testNode struct {
+	*Service
+	writer *pointsWriter
+	httpd  *httpdtest.Server
+}
+
+func (n testNode) Close() {
+	n.Service.Close()
+	n.httpd.Close()
+}
+
+// openNodes opens a node for each config, the nodes of the configs are set to the test servers.
+func openNodes(t *testing.T, configs ...Config) []testNode {
+	ds := diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	ds.Open()
+	servers := make([]*httpdtest.Server, len(configs))
+	var nodes []NodeConfig
+	for i := range configs {
+		servers[i] = httpdtest.NewServer(false)
+		nodes = append(nodes, NodeConfig{
+			Name: fmt.Sprintf("node%d", i),
+			URL:  servers[i].Server.URL,
+		})
+	}
+	testNodes := make([]testNode, len(configs))
+	for i, c := range configs {
+		c.Enabled = true
+		c.Node = nodes[i].Name
+		c.Nodes = nodes
+		if err := c.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		s := NewService(c, ds.NewClusterHandler())
+		w := new(pointsWriter)
+		s.PointsWriter = w
+		s.HTTPDService = servers[i]
+		if err := s.Open(); err != nil {
+			t.Fatal(err)
+		}
+		testNodes[i] = testNode{Service: s, writer: w, httpd: servers[i]}
+	}
+	return testNodes
+}
+
+func testConfig() Config {
+	c := NewConfig()
+	c.Shards = 8
+	c.Partitions = []PartitionConfig{{Measurement: "cpu", Tags: []string{"host"}}}
+	return c
+}
+
+// waitPoints waits until the node has written the number of points.
+func waitPoints(t *testing.T, n testNode, count int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(n.writer.Points()) < count {
+		if time.Now().After(deadline) {
+			t.Fatalf("node %s wrote %d points exp %d", n.config.Node, len(n.writer.Points()), count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// splitPoints returns the number of the points owned by each node.
+func splitPoints(nodes []testNode, points []models.Point) map[string]int {
+	counts := make(map[string]int)
+	for _, pt := range points {
+		counts[nodes[0].partitioner.Owner(nodes[0].partitioner.Shard(pt))]++
+	}
+	return counts
+}
+
+func testPoints(t *testing.T) []models.Point {
+	var lines []string
+	for i := 0; i < 20; i++ {
+		lines = append(lines, fmt.Sprintf("cpu,host=server%d value=%d %d", i, i, i))
+	}
+	return parsePoints(t, lines...)
+}
+
+func parsePoints(t *testing.T, lines ...string) []models.Point {
+	points, err := models.ParsePointsString(strings.Join(lines, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return points
+}
+
+func TestPartitioner(t *testing.T) {
+	c := testConfig()
+	c.Nodes = []NodeConfig{{Name: "b"}, {Name: "a"}, {Name: "c"}}
+	p := newPartitioner(c)
+	// The order of the nodes in the configuration does not change the assignment.
+	c.Nodes = []NodeConfig{{Name: "c"}, {Name: "b"}, {Name: "a"}}
+	other := newPartitioner(c)
+
+	points := parsePoints(t,
+		"cpu,host=serverA,cpu=cpu0 value=1 1",
+		"cpu,host=serverA,cpu=cpu1 value=1 1",
+		"mem,host=serverA value=1 1",
+		"mem,host=serverB value=1 1",
+	)
+	if got, exp := p.Shard(points[0]), p.Shard(points[1]); got != exp {
+		t.Errorf("points with the same partition tags are in different shards got %d exp %d", got, exp)
+	}
+	if got, exp := p.Shard(points[2]), p.Shard(points[3]); got != exp {
+		t.Errorf("points of a measurement without partition tags are in different shards got %d exp %d", got, exp)
+	}
+	for i, pt := range points {
+		if got, exp := other.Owner(other.Shard(pt)), p.Owner(p.Shard(pt)); got != exp {
+			t.Errorf("%d: unexpected owner got %s exp %s", i, got, exp)
+		}
+	}
+	if got, exp := other.KeyOwner("task"), p.KeyOwner("task"); got != exp {
+		t.Errorf("unexpected task owner got %s exp %s", got, exp)
+	}
+
+	exp := map[string][]int{
+		"a": {0, 3, 6},
+		"b": {1, 4, 7},
+		"c": {2, 5},
+	}
+	for node, shards := range exp {
+		if got := p.Shards(node); !reflect.DeepEqual(got, shards) {
+			t.Errorf("unexpected shards of node %s got %v exp %v", node, got, shards)
+		}
+	}
+}
+
+func TestService_WritePoints(t *testing.T) {
+	nodes := openNodes(t, testConfig(), testConfig())
+	for _, n := range nodes {
+		defer n.Close()
+	}
+
+	points := testPoints(t)
+	if err := nodes[0].WritePoints("db", "rp", models.ConsistencyLevelAll, points); err != nil {
+		t.Fatal(err)
+	}
+	counts := splitPoints(nodes, points)
+	waitPoints(t, nodes[1], counts["node1"])
+
+	total := 0
+	for _, n := range nodes {
+		written := n.writer.Points()
+		total += len(written)
+		for _, pt := range written {
+			if owner := n.partitioner.Owner(n.partitioner.Shard(pt)); owner != n.config.Node {
+				t.Errorf("point %s written to node %s exp %s", pt, n.config.Node, owner)
+			}
+		}
+		if len(written) == 0 {
+			t.Errorf("no points written to node %s", n.config.Node)
+		}
+	}
+	if total != len(points) {
+		t.Errorf("unexpected number of points written got %d exp %d", total, len(points))
+	}
+}
+
+func TestService_WritePoints_ConfigMismatch(t *testing.T) {
+	other := testConfig()
+	other.Shards = 16
+	nodes := openNodes(t, testConfig(), other)
+	for _, n := range nodes {
+		defer n.Close()
+	}
+
+	points := testPoints(t)
+	if err := nodes[0].WritePoints("db", "rp", models.ConsistencyLevelAll, points); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := len(nodes[1].writer.Points()); got != 0 {
+		t.Errorf("unexpected points written to node with another configuration: %d", got)
+	}
+	// The points stay queued until the configurations match.
+	if got, exp := nodes[0].queues["node1"].Len(), splitPoints(nodes, points)["node1"]; got != exp {
+		t.Errorf("unexpected queued points got %d exp %d", got, exp)
+	}
+}
+
+func TestService_WritePoints_NodeDown(t *testing.T) {
+	nodes := openNodes(t, testConfig(), testConfig())
+	for _, n := range nodes {
+		defer n.Close()
+	}
+
+	// The node is down until its routes are added back.
+	nodes[1].httpd.DelRoutes(nodes[1].routes)
+	points := testPoints(t)
+	if err := nodes[0].WritePoints("db", "rp", models.ConsistencyLevelAll, points); err != nil {
+		t.Fatal(err)
+	}
+	counts := splitPoints(nodes, points)
+	if got := len(nodes[0].writer.Points()); got != counts["node0"] {
+		t.Errorf("unexpected points written to the node got %d exp %d", got, counts["node0"])
+	}
+	if got := len(nodes[1].writer.Points()); got != 0 {
+		t.Errorf("unexpected points written to the node down: %d", got)
+	}
+
+	if err := nodes[1].httpd.AddRoutes(nodes[1].routes); err != nil {
+		t.Fatal(err)
+	}
+	waitPoints(t, nodes[1], counts["node1"])
+	if got := len(nodes[1].writer.Points()); got != counts["node1"] {
+		t.Errorf("unexpected points forwarded got %d exp %d", got, counts["node1"])
+	}
+}
+
+func TestService_WritePoints_Atomic(t *testing.T) {
+	c := testConfig()
+	c.MaxQueuedPoints = 15
+	nodes := openNodes(t, c, testConfig())
+	for _, n := range nodes {
+		defer n.Close()
+	}
+	nodes[1].httpd.DelRoutes(nodes[1].routes)
+	points := testPoints(t)
+	counts := splitPoints(nodes, points)
+	if counts["node1"] > c.MaxQueuedPoints || 2*counts["node1"] <= c.MaxQueuedPoints {
+		t.Fatalf("unexpected points of node1 %d for a queue of %d points", counts["node1"], c.MaxQueuedPoints)
+	}
+
+	// A failed write of this node queues no points.
+	nodes[0].writer.SetError(errors.New("write failed"))
+	if err := nodes[0].WritePoints("db", "rp", models.ConsistencyLevelAll, points); err == nil || err.Error() != "write failed" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := nodes[0].queues["node1"].Len(); got != 0 {
+		t.Errorf("unexpected queued points after failed write: %d", got)
+	}
+	nodes[0].writer.SetError(nil)
+
+	if err := nodes[0].WritePoints("db", "rp", models.ConsistencyLevelAll, points); err != nil {
+		t.Fatal(err)
+	}
+	// A full queue fails the write before this node writes its points.
+	err := nodes[0].WritePoints("db", "rp", models.ConsistencyLevelAll, points)
+	if err == nil || !strings.Contains(err.Error(), "is full") {
+		t.Fatalf("expected queue full error, got %v", err)
+	}
+	if got := len(nodes[0].writer.Points()); got != counts["node0"] {
+		t.Errorf("unexpected points written to the node got %d exp %d", got, counts["node0"])
+	}
+	if got := nodes[0].queues["node1"].Len(); got != counts["node1"] {
+		t.Errorf("unexpected queued points got %d exp %d", got, counts["node1"])
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := func() Config {
+		c := NewConfig()
+		c.Enabled = true
+		c.Node = "a"
+		c.Nodes = []NodeConfig{
+			{Name: "a", URL: "http://a:9092"},
+			{Name: "b", URL: "http://b:9092"},
+		}
+		return c
+	}
+	testCases := []struct {
+		name   string
+		modify func(c *Config)
+		err    string
+	}{
+		{
+			name:   "valid",
+			modify: func(c *Config) {},
+		},
+		{
+			name:   "unknown node",
+			modify: func(c *Config) { c.Node = "c" },
+			err:    `node "c" is not one of the nodes`,
+		},
+		{
+			name:   "duplicate node",
+			modify: func(c *Config) { c.Nodes[1].Name = "a" },
+			err:    `duplicate node "a"`,
+		},
+		{
+			name:   "invalid url",
+			modify: func(c *Config) { c.Nodes[1].URL = "b:9092" },
+			err:    `invalid url "b:9092" of node "b", must be an http(s) URL`,
+		},
+		{
+			name:   "too few shards",
+			modify: func(c *Config) { c.Shards = 1 },
+			err:    "shards must be at least the number of nodes",
+		},
+		{
+			name: "duplicate partition",
+			modify: func(c *Config) {
+				c.Partitions = []PartitionConfig{{Measurement: "cpu"}, {Measurement: "cpu", Tags: []string{"host"}}}
+			},
+			err: `duplicate partition of measurement "cpu"`,
+		},
+	}
+	for _, tc := range testCases {
+		c := valid()
+		tc.modify(&c)
+		err := c.Validate()
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.name, err)
+			}
+		} else if err == nil || err.Error() != tc.err {
+			t.Errorf("%s: unexpected error got %v exp %s", tc.name, err, tc.err)
+		}
+	}
+}
@@ -57,6 +57,11 @@ func (o Override) ObjectID() string {
 	return o.ID
 }
 
+// SectionAndElement returns the section and element names of an override.
+func (o Override) SectionAndElement() (section, element string) {
+	return sectionAndElementFromID(o.ID)
+}
+
 func (o Override) MarshalBinary() ([]byte, error) {
 	return storage.VersionJSONEncode(version, o)
 }
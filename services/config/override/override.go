@@ -506,7 +506,7 @@ func (e Element) Value() interface{} {
 // Any fields with the `override:",redact"` tag set will be replaced
 // with a boolean value indicating whether a non-zero value was set.
 func (e Element) Redacted() (map[string]interface{}, []string, error) {
-	walker := newRedactWalker()
+	walker := newRedactWalker(true)
 	// walk the section and collect redacted options
 	if err := reflectwalk.Walk(e.value, walker); err != nil {
 		return nil, nil, errors.Wrap(err, "failed to redact section")
@@ -514,6 +514,15 @@ func (e Element) Redacted() (map[string]interface{}, []string, error) {
 	return walker.optionsMap(), walker.redactedList(), nil
 }
 
+// Options returns the options for the element in a map, including the values of redacted options.
+func (e Element) Options() (map[string]interface{}, error) {
+	walker := newRedactWalker(false)
+	if err := reflectwalk.Walk(e.value, walker); err != nil {
+		return nil, errors.Wrap(err, "failed to read section")
+	}
+	return walker.optionsMap(), nil
+}
+
 // getElementKey returns the name of the field that is used to uniquely identify elements of a list.
 func getElementKey(f reflect.StructField) string {
 	parts := strings.Split(f.Tag.Get(structTagKey), ",")
@@ -555,12 +564,14 @@ func findFieldByElementKey(v reflect.Value, elementKey string) reflect.Value {
 // redactWalker reads the the sections from the walked values and redacts and sensitive fields.
 type redactWalker struct {
 	depthWalker
+	redact   bool
 	options  map[string]interface{}
 	redacted []string
 }
 
-func newRedactWalker() *redactWalker {
+func newRedactWalker(redact bool) *redactWalker {
 	return &redactWalker{
+		redact:  redact,
 		options: make(map[string]interface{}),
 	}
 }
@@ -586,7 +597,7 @@ func (w *redactWalker) StructField(f reflect.StructField, v reflect.Value) error
 			break
 		}
 		var value interface{}
-		if isRedacted(f) {
+		if w.redact && isRedacted(f) {
 			value = !isZero(v)
 			// Add field to redacted list
 			w.redacted = append(w.redacted, name)
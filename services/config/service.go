@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"net/http"
 	"path"
+	"reflect"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/influxdata/kapacitor/auth"
 	client "github.com/influxdata/kapacitor/client/v1"
 	"github.com/influxdata/kapacitor/services/config/override"
 	"github.com/influxdata/kapacitor/services/httpd"
@@ -20,6 +24,9 @@ const (
 	configPath         = "/config"
 	configPathAnchored = "/config/"
 	configBasePath     = httpd.BasePath + configPathAnchored
+	diffPath           = "/config/diff"
+	diffPathAnchored   = "/config/diff/"
+	diffBasePath       = httpd.BasePath + diffPathAnchored
 
 	// The amount of time an update is allowed take, when sending and receiving.
 	updateTimeout = 5 * time.Second
@@ -37,18 +44,23 @@ type ConfigUpdate struct {
 
 type Service struct {
 	enabled bool
-	config  interface{}
 	diag    Diagnostic
 	updates chan<- ConfigUpdate
 	routes  []httpd.Route
 
+	// The configuration the overrides apply to, replaced when it is reloaded.
+	configMu sync.RWMutex
+	config   interface{}
+	// Serializes staging and applying updates.
+	updateMu sync.Mutex
+
 	// Cached map of section name to element key name
 	elementKeys map[string]string
 
 	overrides OverrideDAO
 
 	StorageService interface {
-		Store(namespace string) storage.Interface
+		EncryptedStore(namespace string) (storage.Interface, error)
 		Register(name string, store storage.StoreActioner)
 	}
 	HTTPDService interface {
@@ -74,7 +86,11 @@ const (
 )
 
 func (s *Service) Open() error {
-	store := s.StorageService.Store(configNamespace)
+	// The stored values contain credentials, so they are encrypted at rest.
+	store, err := s.StorageService.EncryptedStore(configNamespace)
+	if err != nil {
+		return err
+	}
 	overrides, err := newOverrideKV(store)
 	if err != nil {
 		return err
@@ -95,16 +111,37 @@ func (s *Service) Open() error {
 			Method:      "GET",
 			Pattern:     configPath,
 			HandlerFunc: s.handleGetConfig,
+			Scope:       auth.ConfigReadScope,
 		},
 		{
 			Method:      "GET",
 			Pattern:     configPathAnchored,
 			HandlerFunc: s.handleGetConfig,
+			Scope:       auth.ConfigReadScope,
+		},
+		{
+			Method:      "POST",
+			Pattern:     configPath,
+			HandlerFunc: s.handleApply,
+			Scope:       auth.ConfigWriteScope,
 		},
 		{
 			Method:      "POST",
 			Pattern:     configPathAnchored,
 			HandlerFunc: s.handleUpdateSection,
+			Scope:       auth.ConfigWriteScope,
+		},
+		{
+			Method:      "GET",
+			Pattern:     diffPath,
+			HandlerFunc: s.handleDiff,
+			Scope:       auth.ConfigReadScope,
+		},
+		{
+			Method:      "GET",
+			Pattern:     diffPathAnchored,
+			HandlerFunc: s.handleDiff,
+			Scope:       auth.ConfigReadScope,
 		},
 	}
 
@@ -228,32 +265,165 @@ func (s *Service) handleUpdateSection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+	su, err := s.stageUpdate(ua)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Get("validate") == "true" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err := s.applyUpdates([]stagedUpdate{su}); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+
+	// Success
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleApply applies the updates of several sections together, see applyUpdates.
+func (s *Service) handleApply(w http.ResponseWriter, r *http.Request) {
+	if !s.enabled {
+		httpd.HttpError(w, "config override service is not enabled", true, http.StatusForbidden)
+		return
+	}
+	apply := struct {
+		Updates []struct {
+			Section string `json:"section"`
+			Element string `json:"element"`
+			updateAction
+		} `json:"updates"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&apply); err != nil {
+		httpd.HttpError(w, fmt.Sprint("failed to decode JSON:", err), true, http.StatusBadRequest)
+		return
+	}
+	if len(apply.Updates) == 0 {
+		httpd.HttpError(w, "no updates specified", true, http.StatusBadRequest)
+		return
+	}
+
+	// Stage all updates before applying any of them.
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+	sus := make([]stagedUpdate, len(apply.Updates))
+	sections := make(map[string]bool, len(apply.Updates))
+	for i, u := range apply.Updates {
+		if sections[u.Section] {
+			httpd.HttpError(w, fmt.Sprintf("section %s must be updated only once", u.Section), true, http.StatusBadRequest)
+			return
+		}
+		sections[u.Section] = true
+		ua := u.updateAction
+		ua.section = u.Section
+		ua.element = u.Element
+		ua.hasElement = true
+		su, err := s.stageUpdate(ua)
+		if err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+			return
+		}
+		sus[i] = su
+	}
+	if r.URL.Query().Get("validate") == "true" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err := s.applyUpdates(sus); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// stagedUpdate is a validated update action, with the previous and new configuration of its section.
+type stagedUpdate struct {
+	section   string
+	element   string
+	oldConfig []interface{}
+	newConfig []interface{}
+	// save permanently stores the overrides of the update.
+	save func() error
+}
+
+// stageUpdate validates the update action and resolves the configuration of its section before and after it.
+func (s *Service) stageUpdate(ua updateAction) (stagedUpdate, error) {
+	section := ua.section
+	// Resolve the current configuration of the section
+	current, err := s.overrides.List(section)
+	if err != nil {
+		return stagedUpdate{}, errors.Wrap(err, "failed to retrieve config overrides")
+	}
+	oldConfig, err := override.OverrideConfig(s.fileConfig(), convertOverrides(current))
+	if err != nil {
+		return stagedUpdate{}, errors.Wrap(err, "failed to apply configuration overrides")
+	}
+
 	// Apply sets/deletes to stored overrides
 	overrides, saveFunc, err := s.overridesForUpdateAction(ua)
 	if err != nil {
-		httpd.HttpError(w, fmt.Sprint("failed to apply update: ", err), true, http.StatusBadRequest)
-		return
+		return stagedUpdate{}, errors.Wrap(err, "failed to apply update")
 	}
 
 	// Apply overrides to config object
-	os := convertOverrides(overrides)
-	newConfig, err := override.OverrideConfig(s.config, os)
+	newConfig, err := override.OverrideConfig(s.fileConfig(), convertOverrides(overrides))
 	if err != nil {
-		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
-		return
+		return stagedUpdate{}, err
+	}
+
+	return stagedUpdate{
+		section:   section,
+		element:   ua.element,
+		oldConfig: sectionValues(oldConfig[section]),
+		newConfig: sectionValues(newConfig[section]),
+		save:      saveFunc,
+	}, nil
+}
+
+// sectionValues collects the values of the elements of a section.
+func sectionValues(section override.Section) []interface{} {
+	values := make([]interface{}, len(section))
+	for i, e := range section {
+		values[i] = e.Value()
+	}
+	return values
+}
+
+// applyUpdates updates the services of the staged updates in order, and saves their overrides once all succeeded.
+// If a service fails to update, the services already updated and the failed one are rolled back to their previous configuration.
+func (s *Service) applyUpdates(sus []stagedUpdate) error {
+	for i, su := range sus {
+		if err := s.sendUpdate(su.section, su.newConfig); err != nil {
+			err = fmt.Errorf("failed to update configuration %s/%s: %v", su.section, su.element, err)
+			for j := i; j >= 0; j-- {
+				if rerr := s.sendUpdate(sus[j].section, sus[j].oldConfig); rerr != nil {
+					s.diag.Error("failed to roll back configuration update", rerr)
+					err = fmt.Errorf("%v; failed to roll back configuration %s: %v", err, sus[j].section, rerr)
+				}
+			}
+			return err
+		}
 	}
 
-	// collect element values
-	sectionList := make([]interface{}, len(newConfig[section]))
-	for i, s := range newConfig[section] {
-		sectionList[i] = s.Value()
+	// Save the result of the updates
+	for _, su := range sus {
+		if err := su.save(); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// Construct ConfigUpdate
+// sendUpdate sends the new configuration of a section to its service and waits for the result of its update.
+func (s *Service) sendUpdate(section string, config []interface{}) error {
 	errC := make(chan error, 1)
 	cu := ConfigUpdate{
 		Name:      section,
-		NewConfig: sectionList,
+		NewConfig: config,
 		ErrC:      errC,
 	}
 
@@ -262,8 +432,7 @@ func (s *Service) handleUpdateSection(w http.ResponseWriter, r *http.Request) {
 	defer sendTimer.Stop()
 	select {
 	case <-sendTimer.C:
-		httpd.HttpError(w, fmt.Sprintf("failed to send configuration update %s/%s: timeout", section, element), true, http.StatusInternalServerError)
-		return
+		return errors.New("timeout sending update")
 	case s.updates <- cu:
 	}
 
@@ -272,23 +441,10 @@ func (s *Service) handleUpdateSection(w http.ResponseWriter, r *http.Request) {
 	defer recvTimer.Stop()
 	select {
 	case <-recvTimer.C:
-		httpd.HttpError(w, fmt.Sprintf("failed to update configuration %s/%s: timeout", section, element), true, http.StatusInternalServerError)
-		return
+		return errors.New("timeout")
 	case err := <-errC:
-		if err != nil {
-			httpd.HttpError(w, fmt.Sprintf("failed to update configuration %s/%s: %v", section, element, err), true, http.StatusInternalServerError)
-			return
-		}
-	}
-
-	// Save the result of the update
-	if err := saveFunc(); err != nil {
-		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
-		return
+		return err
 	}
-
-	// Success
-	w.WriteHeader(http.StatusNoContent)
 }
 
 func (s *Service) handleGetConfig(w http.ResponseWriter, r *http.Request) {
@@ -347,6 +503,131 @@ func (s *Service) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleDiff serves the difference between the running configuration and the configuration of the file,
+// of all sections or of the section of the path.
+func (s *Service) handleDiff(w http.ResponseWriter, r *http.Request) {
+	if !s.enabled {
+		httpd.HttpError(w, "config override service is not enabled", true, http.StatusForbidden)
+		return
+	}
+	section := strings.TrimPrefix(r.URL.Path, diffBasePath)
+	if r.URL.Path == httpd.BasePath+diffPath {
+		section = ""
+	} else if !validSectionOrElement.MatchString(section) {
+		httpd.HttpError(w, fmt.Sprintf("invalid section name %q", section), true, http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.elementKeys[section]; section != "" && !ok {
+		httpd.HttpError(w, fmt.Sprint("unknown section: ", section), true, http.StatusNotFound)
+		return
+	}
+	diff, err := s.diff(section)
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprint("failed to resolve config diff: ", err), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(diff); err != nil {
+		s.diag.Error("failed to JSON encode config diff", err)
+	}
+}
+
+// diff returns the elements and options of the running configuration that differ from the configuration of the file.
+func (s *Service) diff(section string) (client.ConfigDiff, error) {
+	overrides, err := s.overrides.List(section)
+	if err != nil {
+		return client.ConfigDiff{}, errors.Wrap(err, "failed to retrieve config overrides")
+	}
+	running, err := override.OverrideConfig(s.fileConfig(), convertOverrides(overrides))
+	if err != nil {
+		return client.ConfigDiff{}, errors.Wrap(err, "failed to apply configuration overrides")
+	}
+	file, err := override.OverrideConfig(s.fileConfig(), nil)
+	if err != nil {
+		return client.ConfigDiff{}, errors.Wrap(err, "failed to resolve file configuration")
+	}
+	diff := client.ConfigDiff{
+		Link:     client.Link{Relation: client.Self, Href: path.Join(httpd.BasePath, diffPath, section)},
+		Sections: make(map[string]client.ConfigSectionDiff),
+	}
+	for name, elements := range running {
+		if section != "" && name != section {
+			continue
+		}
+		fileElements := make(map[string]override.Element, len(file[name]))
+		for _, e := range file[name] {
+			fileElements[e.ElementID()] = e
+		}
+		var sec client.ConfigSectionDiff
+		for _, e := range elements {
+			fe, inFile := fileElements[e.ElementID()]
+			ed, changed, err := diffElement(e, fe, inFile)
+			if err != nil {
+				return client.ConfigDiff{}, err
+			}
+			if !changed {
+				continue
+			}
+			ed.Link = s.elementLink(name, e.ElementID())
+			sec.Elements = append(sec.Elements, ed)
+		}
+		if len(sec.Elements) > 0 {
+			sec.Link = s.sectionLink(name)
+			diff.Sections[name] = sec
+		}
+	}
+	return diff, nil
+}
+
+// diffElement returns the options of the running element that differ from the element of the file,
+// and whether any differ. All options differ if the element is not in the file.
+func diffElement(running, file override.Element, inFile bool) (client.ConfigElementDiff, bool, error) {
+	ed := client.ConfigElementDiff{
+		Options: make(map[string]client.ConfigOptionDiff),
+	}
+	runningOptions, err := running.Options()
+	if err != nil {
+		return ed, false, err
+	}
+	runningRedacted, redactedList, err := running.Redacted()
+	if err != nil {
+		return ed, false, err
+	}
+	redacted := make(map[string]bool, len(redactedList))
+	for _, name := range redactedList {
+		redacted[name] = true
+	}
+	if !inFile {
+		ed.Added = true
+		for name := range runningOptions {
+			ed.Options[name] = client.ConfigOptionDiff{
+				Running:  runningRedacted[name],
+				Redacted: redacted[name],
+			}
+		}
+		return ed, true, nil
+	}
+	fileOptions, err := file.Options()
+	if err != nil {
+		return ed, false, err
+	}
+	fileRedacted, _, err := file.Redacted()
+	if err != nil {
+		return ed, false, err
+	}
+	for name, value := range runningOptions {
+		if reflect.DeepEqual(value, fileOptions[name]) {
+			continue
+		}
+		ed.Options[name] = client.ConfigOptionDiff{
+			File:     fileRedacted[name],
+			Running:  runningRedacted[name],
+			Redacted: redacted[name],
+		}
+	}
+	return ed, len(ed.Options) > 0, nil
+}
+
 // overridesForUpdateAction produces a list of overrides relevant to the update action and
 // returns  save function. Call the save function to permanently store the result of the update.
 func (s *Service) overridesForUpdateAction(ua updateAction) ([]Override, func() error, error) {
@@ -489,7 +770,7 @@ func (s *Service) getConfig(section string) (client.ConfigSections, error) {
 		return client.ConfigSections{}, errors.Wrap(err, "failed to retrieve config overrides")
 	}
 	os := convertOverrides(overrides)
-	sections, err := override.OverrideConfig(s.config, os)
+	sections, err := override.OverrideConfig(s.fileConfig(), os)
 	if err != nil {
 		return client.ConfigSections{}, errors.Wrap(err, "failed to apply configuration overrides")
 	}
@@ -520,13 +801,83 @@ func (s *Service) getConfig(section string) (client.ConfigSections, error) {
 	return config, nil
 }
 
+// fileConfig returns the configuration the overrides apply to.
+func (s *Service) fileConfig() interface{} {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.config
+}
+
+// Reload replaces the configuration the overrides apply to, e.g. once the configuration file is read again,
+// and updates the services of the sections whose resolved configuration changed.
+// The overrides are applied to the configuration only if withOverrides is true.
+// If a service fails to update, the services already updated are rolled back and the previous configuration is kept.
+func (s *Service) Reload(config interface{}, withOverrides bool) error {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+
+	var overrides []override.Override
+	if withOverrides {
+		os, err := s.overrides.List("")
+		if err != nil {
+			return errors.Wrap(err, "failed to retrieve config overrides")
+		}
+		overrides = convertOverrides(os)
+	}
+	oldSections, err := override.OverrideConfig(s.fileConfig(), overrides)
+	if err != nil {
+		return errors.Wrap(err, "failed to apply configuration overrides to the previous configuration")
+	}
+	newSections, err := override.OverrideConfig(config, overrides)
+	if err != nil {
+		return errors.Wrap(err, "failed to apply configuration overrides")
+	}
+
+	names := make([]string, 0, len(newSections))
+	for name := range newSections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sus []stagedUpdate
+	for _, name := range names {
+		oldConfig := sectionValues(oldSections[name])
+		newConfig := sectionValues(newSections[name])
+		if reflect.DeepEqual(oldConfig, newConfig) {
+			continue
+		}
+		sus = append(sus, stagedUpdate{
+			section:   name,
+			oldConfig: oldConfig,
+			newConfig: newConfig,
+			save:      func() error { return nil },
+		})
+	}
+	if err := s.applyUpdates(sus); err != nil {
+		return err
+	}
+
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	s.config = config
+	return nil
+}
+
+// Overrides returns all configuration overrides, including the values of redacted options.
+func (s *Service) Overrides() ([]Override, error) {
+	overrides, err := s.overrides.List("")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve config overrides")
+	}
+	return overrides, nil
+}
+
 func (s *Service) Config() (map[string][]interface{}, error) {
 	overrides, err := s.overrides.List("")
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to retrieve config overrides")
 	}
 	os := convertOverrides(overrides)
-	sections, err := override.OverrideConfig(s.config, os)
+	sections, err := override.OverrideConfig(s.fileConfig(), os)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to apply configuration overrides")
 	}
@@ -74,6 +74,8 @@ func TestService_UpdateSection(t *testing.T) {
 		exp        interface{}
 		updateErr  error
 		skipUpdate bool
+		// Config the section is rolled back to after the update error.
+		expRollback interface{}
 	}{
 		// NOTE: These test cases all update the same underlying service,
 		// so changes from one effect the next.
@@ -138,6 +140,9 @@ func TestService_UpdateSection(t *testing.T) {
 				},
 			},
 			updateErr: errors.New("failed to update service"),
+			expRollback: []interface{}{
+				SectionB{},
+			},
 		},
 		// Set unknown option
 		{
@@ -204,6 +209,14 @@ func TestService_UpdateSection(t *testing.T) {
 					err = fmt.Errorf("unexpected config update Name: got %s exp %s", got, exp)
 				}
 				cu.ErrC <- err
+				if tc.updateErr != nil {
+					cu := <-updates
+					var err error
+					if !reflect.DeepEqual(cu.NewConfig, tc.expRollback) {
+						err = fmt.Errorf("unexpected rollback config: got %v exp %v", cu.NewConfig, tc.expRollback)
+					}
+					cu.ErrC <- err
+				}
 			}()
 		}
 		resp, err := http.Post(basePath+tc.path, "application/json", strings.NewReader(tc.body))
@@ -1097,3 +1110,354 @@ func TestService_GetConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestService_ValidateSection(t *testing.T) {
+	testConfig := &TestConfig{
+		SectionA: SectionA{
+			Option1: "o1",
+		},
+	}
+	updates := make(chan config.ConfigUpdate, 1)
+	service, server := OpenNewSerivce(testConfig, updates)
+	defer server.Close()
+	defer service.Close()
+	basePath := server.Server.URL + httpd.BasePath + "/config"
+
+	testCases := []struct {
+		body    string
+		expCode int
+		expErr  string
+	}{
+		{
+			body:    `{"set":{"option-1":"new-o1"}}`,
+			expCode: http.StatusNoContent,
+		},
+		{
+			body:    `{"set":{"option-1":"invalid"}}`,
+			expCode: http.StatusBadRequest,
+			expErr:  "failed to override configuration section-a/: failed validation: invalid option-1",
+		},
+	}
+	for _, tc := range testCases {
+		resp, err := http.Post(basePath+"/section-a/?validate=true", "application/json", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.expCode {
+			t.Fatalf("unexpected code: got %d exp %d.\nBody:\n%s", resp.StatusCode, tc.expCode, string(body))
+		}
+		if tc.expErr != "" {
+			gotErr := struct {
+				Error string
+			}{}
+			json.Unmarshal(body, &gotErr)
+			if gotErr.Error != tc.expErr {
+				t.Errorf("unexpected error: got %q exp %q", gotErr.Error, tc.expErr)
+			}
+		}
+	}
+
+	// Validating sends no update and stores no override.
+	select {
+	case cu := <-updates:
+		t.Fatalf("unexpected config update %v", cu)
+	default:
+	}
+	if overrides, err := service.Overrides(); err != nil {
+		t.Fatal(err)
+	} else if len(overrides) != 0 {
+		t.Errorf("unexpected overrides %v", overrides)
+	}
+}
+
+func TestService_Apply(t *testing.T) {
+	testConfig := &TestConfig{
+		SectionA: SectionA{
+			Option1: "o1",
+		},
+		SectionB: SectionB{
+			Option2: "o2",
+		},
+	}
+	updates := make(chan config.ConfigUpdate, 10)
+	service, server := OpenNewSerivce(testConfig, updates)
+	defer server.Close()
+	defer service.Close()
+	basePath := server.Server.URL + httpd.BasePath + "/config"
+
+	apply := func(body string, expCode int, expErr string) {
+		t.Helper()
+		resp, err := http.Post(basePath, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != expCode {
+			t.Fatalf("unexpected code: got %d exp %d.\nBody:\n%s", resp.StatusCode, expCode, string(b))
+		}
+		if expErr != "" {
+			gotErr := struct {
+				Error string
+			}{}
+			json.Unmarshal(b, &gotErr)
+			if gotErr.Error != expErr {
+				t.Errorf("unexpected error: got %q exp %q", gotErr.Error, expErr)
+			}
+		}
+	}
+	body := `{"updates":[
+		{"section":"section-a","set":{"option-1":"new-o1"}},
+		{"section":"section-b","set":{"option-2":"new-o2"}}
+	]}`
+
+	// An invalid update fails before any section is updated.
+	apply(`{"updates":[
+		{"section":"section-a","set":{"option-1":"new-o1"}},
+		{"section":"section-b","set":{"unknown":"value"}}
+	]}`, http.StatusBadRequest, "failed to override configuration section-b/: unknown options [unknown] in section section-b")
+	apply(`{"updates":[
+		{"section":"section-a","set":{"option-1":"new-o1"}},
+		{"section":"section-a","set":{"option-1":"new-o2"}}
+	]}`, http.StatusBadRequest, "section section-a must be updated only once")
+
+	// A section failing to update rolls back the sections already updated.
+	type update struct {
+		name string
+		exp  interface{}
+		err  error
+	}
+	expUpdates := []update{
+		{name: "section-a", exp: []interface{}{SectionA{Option1: "new-o1"}}},
+		{name: "section-b", exp: []interface{}{SectionB{Option2: "new-o2"}}, err: errors.New("failed to update service")},
+		{name: "section-b", exp: []interface{}{SectionB{Option2: "o2"}}},
+		{name: "section-a", exp: []interface{}{SectionA{Option1: "o1"}}},
+	}
+	done := make(chan error, 1)
+	go func() {
+		for _, u := range expUpdates {
+			cu := <-updates
+			if cu.Name != u.name || !reflect.DeepEqual(cu.NewConfig, u.exp) {
+				done <- fmt.Errorf("unexpected update: got %s %v exp %s %v", cu.Name, cu.NewConfig, u.name, u.exp)
+				cu.ErrC <- errors.New("unexpected update")
+				return
+			}
+			cu.ErrC <- u.err
+		}
+		done <- nil
+	}()
+	apply(body, http.StatusInternalServerError, "failed to update configuration section-b/: failed to update service")
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if overrides, err := service.Overrides(); err != nil {
+		t.Fatal(err)
+	} else if len(overrides) != 0 {
+		t.Errorf("unexpected overrides after rollback %v", overrides)
+	}
+
+	// All sections are updated and saved.
+	go func() {
+		for i := 0; i < 2; i++ {
+			cu := <-updates
+			cu.ErrC <- nil
+		}
+	}()
+	apply(body, http.StatusNoContent, "")
+	if overrides, err := service.Overrides(); err != nil {
+		t.Fatal(err)
+	} else if len(overrides) != 2 {
+		t.Errorf("unexpected overrides %v", overrides)
+	}
+}
+
+func TestService_Diff(t *testing.T) {
+	testConfig := &TestConfig{
+		SectionA: SectionA{
+			Option1: "o1",
+		},
+		SectionB: SectionB{
+			Option2: "o2",
+		},
+		SectionCs: []SectionC{
+			{
+				Name:    "x",
+				Option3: 1,
+			},
+		},
+	}
+	updates := make(chan config.ConfigUpdate, 10)
+	service, server := OpenNewSerivce(testConfig, updates)
+	defer server.Close()
+	defer service.Close()
+	basePath := server.Server.URL + httpd.BasePath + "/config"
+	go func() {
+		for cu := range updates {
+			cu.ErrC <- nil
+		}
+	}()
+	for _, u := range []struct {
+		path string
+		body string
+	}{
+		{path: "/section-b/", body: `{"set":{"password":"secret"}}`},
+		{path: "/section-c/", body: `{"add":{"name":"y","option-3":2}}`},
+	} {
+		resp, err := http.Post(basePath+u.path, "application/json", strings.NewReader(u.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("update failed: %d", resp.StatusCode)
+		}
+	}
+
+	exp := client.ConfigDiff{
+		Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/diff"},
+		Sections: map[string]client.ConfigSectionDiff{
+			"section-b": {
+				Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/section-b"},
+				Elements: []client.ConfigElementDiff{{
+					Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/section-b/"},
+					Options: map[string]client.ConfigOptionDiff{
+						"password": {File: false, Running: true, Redacted: true},
+					},
+				}},
+			},
+			"section-c": {
+				Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/section-c"},
+				Elements: []client.ConfigElementDiff{{
+					Link:  client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/section-c/y"},
+					Added: true,
+					Options: map[string]client.ConfigOptionDiff{
+						"name":     {Running: "y"},
+						"option-3": {Running: float64(2)},
+					},
+				}},
+			},
+		},
+	}
+	getDiff := func(path string) client.ConfigDiff {
+		t.Helper()
+		resp, err := http.Get(basePath + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected code: %d", resp.StatusCode)
+		}
+		var got client.ConfigDiff
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	if got := getDiff("/diff"); !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected diff:\ngot\n%+v\nexp\n%+v\n", got, exp)
+	}
+
+	expSection := client.ConfigDiff{
+		Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/config/diff/section-c"},
+		Sections: map[string]client.ConfigSectionDiff{
+			"section-c": exp.Sections["section-c"],
+		},
+	}
+	if got := getDiff("/diff/section-c"); !reflect.DeepEqual(got, expSection) {
+		t.Errorf("unexpected section diff:\ngot\n%+v\nexp\n%+v\n", got, expSection)
+	}
+
+	resp, err := http.Get(basePath + "/diff/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected code for unknown section: %d", resp.StatusCode)
+	}
+}
+
+func TestService_Reload(t *testing.T) {
+	testConfig := &TestConfig{
+		SectionA: SectionA{
+			Option1: "o1",
+		},
+		SectionB: SectionB{
+			Option2: "o2",
+		},
+	}
+	updates := make(chan config.ConfigUpdate, 10)
+	service, server := OpenNewSerivce(testConfig, updates)
+	defer server.Close()
+	defer service.Close()
+	basePath := server.Server.URL + httpd.BasePath + "/config"
+
+	// Override option-2 so that its value in the file is ignored.
+	go func() {
+		cu := <-updates
+		cu.ErrC <- nil
+	}()
+	resp, err := http.Post(basePath+"/section-b/", "application/json", strings.NewReader(`{"set":{"option-2":"override-o2"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected code: got %d exp %d", resp.StatusCode, http.StatusNoContent)
+	}
+
+	type update struct {
+		name string
+		exp  interface{}
+		err  error
+	}
+	reload := func(c *TestConfig, expUpdates []update, expErr string) {
+		t.Helper()
+		done := make(chan error, 1)
+		go func() {
+			for _, u := range expUpdates {
+				cu := <-updates
+				if cu.Name != u.name || !reflect.DeepEqual(cu.NewConfig, u.exp) {
+					done <- fmt.Errorf("unexpected update: got %s %v exp %s %v", cu.Name, cu.NewConfig, u.name, u.exp)
+					cu.ErrC <- errors.New("unexpected update")
+					return
+				}
+				cu.ErrC <- u.err
+			}
+			done <- nil
+		}()
+		err := service.Reload(c, true)
+		if got := fmt.Sprint(err); expErr == "" && err != nil || expErr != "" && got != expErr {
+			t.Errorf("unexpected error: got %v exp %q", err, expErr)
+		}
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the changed section is updated, with its overrides applied.
+	reload(&TestConfig{
+		SectionA: SectionA{Option1: "new-o1"},
+		SectionB: SectionB{Option2: "new-o2"},
+	}, []update{
+		{name: "section-a", exp: []interface{}{SectionA{Option1: "new-o1"}}},
+	}, "")
+
+	// A section failing to update is rolled back and the previous configuration is kept.
+	reload(&TestConfig{
+		SectionA: SectionA{Option1: "new-o1"},
+		SectionB: SectionB{Option2: "new-o2", Password: "secret"},
+	}, []update{
+		{name: "section-b", exp: []interface{}{SectionB{Option2: "override-o2", Password: "secret"}}, err: errors.New("failed to update service")},
+		{name: "section-b", exp: []interface{}{SectionB{Option2: "override-o2"}}},
+	}, "failed to update configuration section-b/: failed to update service")
+	reload(&TestConfig{
+		SectionA: SectionA{Option1: "new-o1"},
+		SectionB: SectionB{Option2: "new-o2", Password: "secret"},
+	}, []update{
+		{name: "section-b", exp: []interface{}{SectionB{Option2: "override-o2", Password: "secret"}}},
+	}, "")
+}
@@ -2,8 +2,11 @@ package consul
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/config"
 )
 
@@ -19,6 +22,12 @@ type Config struct {
 	Username     string   `toml:"username" override:"username"`
 	Password     string   `toml:"password" override:"password,redact"`
 	Services     []string `toml:"services" override:"services"`
+	// Tags limits the targets to services having all of these tags.
+	Tags []string `toml:"tags" override:"tags"`
+	// MetaTags maps Consul meta data to tags on the scraped points.
+	// Keys are node, address, dc, service, service_id, service_address, service_port, tags
+	// or metadata_<key> for node meta data, values are the names of the tags.
+	MetaTags map[string]string `toml:"meta-tags" override:"meta-tags"`
 	// Path to CA file
 	SSLCA string `toml:"ssl-ca" override:"ssl-ca"`
 	// Path to host cert file
@@ -46,14 +55,63 @@ func (c Config) Validate() error {
 	if strings.TrimSpace(c.Address) == "" {
 		return fmt.Errorf("consul discovery requires a server address")
 	}
+	for _, t := range c.Tags {
+		if t == "" {
+			return fmt.Errorf("consul discovery tags must not be empty")
+		}
+	}
+	for k, v := range c.MetaTags {
+		if !model.LabelName(metaLabelPrefix + k).IsValid() {
+			return fmt.Errorf("invalid consul meta data name %q", k)
+		}
+		if !model.LabelName(v).IsValid() {
+			return fmt.Errorf("invalid tag name %q for consul meta data %q", v, k)
+		}
+	}
 	return nil
 }
 
+// metaLabelPrefix is the prefix of the labels holding Consul meta data.
+const metaLabelPrefix = model.MetaLabelPrefix + "consul_"
+
 // Prom writes the prometheus configuration for discoverer into ScrapeConfig
 func (c Config) Prom(conf *config.ScrapeConfig) {
 	conf.ServiceDiscoveryConfig.ConsulSDConfigs = []*config.ConsulSDConfig{
 		c.PromConfig(),
 	}
+	// Filter and map the consul meta data before any relabeling of the scraper.
+	conf.RelabelConfigs = append(c.RelabelConfigs(), conf.RelabelConfigs...)
+}
+
+// RelabelConfigs returns the relabeling rules filtering targets by tag
+// and mapping meta data to tags.
+func (c Config) RelabelConfigs() []*config.RelabelConfig {
+	var rcs []*config.RelabelConfig
+	// The tags label is surrounded by the separator so each tag can be matched as a whole.
+	for _, t := range c.Tags {
+		sep := regexp.QuoteMeta(c.TagSeparator)
+		rcs = append(rcs, &config.RelabelConfig{
+			SourceLabels: model.LabelNames{metaLabelPrefix + "tags"},
+			Regex:        config.MustNewRegexp(".*" + sep + regexp.QuoteMeta(t) + sep + ".*"),
+			Action:       config.RelabelKeep,
+		})
+	}
+	keys := make([]string, 0, len(c.MetaTags))
+	for k := range c.MetaTags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		rcs = append(rcs, &config.RelabelConfig{
+			SourceLabels: model.LabelNames{model.LabelName(metaLabelPrefix + k)},
+			Separator:    config.DefaultRelabelConfig.Separator,
+			Regex:        config.DefaultRelabelConfig.Regex,
+			TargetLabel:  c.MetaTags[k],
+			Replacement:  config.DefaultRelabelConfig.Replacement,
+			Action:       config.RelabelReplace,
+		})
+	}
+	return rcs
 }
 
 // PromConfig returns the prometheus configuration for this discoverer
@@ -0,0 +1,89 @@
+package consul_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/kapacitor/services/consul"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/relabel"
+)
+
+func TestConfig_RelabelConfigs(t *testing.T) {
+	c := consul.Config{}
+	c.Init()
+	c.ID = "mycluster"
+	c.Tags = []string{"prod", "metrics"}
+	c.MetaTags = map[string]string{
+		"node":          "host",
+		"dc":            "datacenter",
+		"metadata_rack": "rack",
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	target := func(tags string) model.LabelSet {
+		return model.LabelSet{
+			model.AddressLabel:              "10.0.0.1:9100",
+			"__meta_consul_node":            "node1",
+			"__meta_consul_dc":              "dc1",
+			"__meta_consul_tags":            model.LabelValue(tags),
+			"__meta_consul_metadata_rack":   "r12",
+			"__meta_consul_service":         "node_exporter",
+			"__meta_consul_service_id":      "node_exporter-1",
+			"__meta_consul_service_port":    "9100",
+			"__meta_consul_service_address": "",
+		}
+	}
+
+	testCases := []struct {
+		tags string
+		exp  model.LabelSet
+	}{
+		{
+			tags: ",metrics,prod,",
+			exp: model.LabelSet{
+				"host":       "node1",
+				"datacenter": "dc1",
+				"rack":       "r12",
+			},
+		},
+		{
+			tags: ",prod,",
+		},
+		{
+			tags: ",metrics,production,",
+		},
+	}
+	for _, tc := range testCases {
+		got := relabel.Process(target(tc.tags), c.RelabelConfigs()...)
+		if tc.exp == nil {
+			if got != nil {
+				t.Errorf("expected target with tags %q to be dropped, got %v", tc.tags, got)
+			}
+			continue
+		}
+		for k, v := range tc.exp {
+			if got[k] != v {
+				t.Errorf("unexpected label %s for tags %q: got %q exp %q", k, tc.tags, got[k], v)
+			}
+		}
+	}
+}
+
+func TestConfig_Validate_MetaTags(t *testing.T) {
+	c := consul.Config{}
+	c.Init()
+	c.ID = "mycluster"
+	c.MetaTags = map[string]string{"node": "invalid-tag"}
+	if err := c.Validate(); err == nil {
+		t.Error("expected error for invalid tag name")
+	}
+	c.MetaTags = map[string]string{"node": "host"}
+	if err := c.Validate(); err != nil {
+		t.Error(err)
+	}
+	if got, exp := len(c.RelabelConfigs()), 1; got != exp {
+		t.Errorf("unexpected number of relabel configs: got %d exp %d", got, exp)
+	}
+}
@@ -0,0 +1,173 @@
+// Package dbrps reports the databases and retention policies with data flowing to Kapacitor,
+// the stream tasks consuming them and the InfluxDB clusters subscribed to them,
+// and reconciles the subscriptions of the clusters by removing the orphaned ones.
+package dbrps
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/influxdata/kapacitor"
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/pkg/errors"
+)
+
+const (
+	dbrpsPath     = "/dbrps"
+	reconcilePath = "/dbrps/reconcile"
+
+	// DefaultLiveWindow is the time since the last write within which a database and retention policy is live.
+	DefaultLiveWindow = 5 * time.Minute
+)
+
+type Diagnostic interface {
+	RemovedSubscription(cluster, db, rp, name, reason string)
+}
+
+type Service struct {
+	diag   Diagnostic
+	routes []httpd.Route
+
+	TaskMaster interface {
+		DBRPUsage() map[kapacitor.DBRP]kapacitor.DBRPUsage
+	}
+	InfluxDBService interface {
+		Subscriptions() map[string][]client.DBRP
+		OrphanedSubscriptions() ([]client.OrphanedSubscription, error)
+		RemoveOrphanedSubscriptions() ([]client.OrphanedSubscription, error)
+	}
+	HTTPDService interface {
+		AddRoutes([]httpd.Route) error
+		DelRoutes([]httpd.Route)
+	}
+}
+
+func NewService(d Diagnostic) *Service {
+	return &Service{
+		diag: d,
+	}
+}
+
+func (s *Service) Open() error {
+	s.routes = []httpd.Route{
+		{
+			Method:      "GET",
+			Pattern:     dbrpsPath,
+			HandlerFunc: s.handleDBRPs,
+		},
+		{
+			Method:      "POST",
+			Pattern:     reconcilePath,
+			HandlerFunc: s.handleReconcile,
+		},
+	}
+	if err := s.HTTPDService.AddRoutes(s.routes); err != nil {
+		return errors.Wrap(err, "failed to add API routes")
+	}
+	return nil
+}
+
+func (s *Service) Close() error {
+	if s.HTTPDService != nil {
+		s.HTTPDService.DelRoutes(s.routes)
+	}
+	return nil
+}
+
+// Status returns the usage of the databases and retention policies and the orphaned subscriptions,
+// the databases and retention policies written to within the live window are live.
+func (s *Service) Status(liveWindow time.Duration) (client.DBRPsStatus, error) {
+	orphaned, err := s.InfluxDBService.OrphanedSubscriptions()
+	if err != nil {
+		return client.DBRPsStatus{}, errors.Wrap(err, "failed to list orphaned subscriptions")
+	}
+
+	usage := make(map[client.DBRP]*client.DBRPUsage)
+	get := func(db, rp string) *client.DBRPUsage {
+		key := client.DBRP{Database: db, RetentionPolicy: rp}
+		u, ok := usage[key]
+		if !ok {
+			u = &client.DBRPUsage{
+				Database:        db,
+				RetentionPolicy: rp,
+				Tasks:           []string{},
+				Subscriptions:   []string{},
+			}
+			usage[key] = u
+		}
+		return u
+	}
+	now := time.Now()
+	for dbrp, tu := range s.TaskMaster.DBRPUsage() {
+		u := get(dbrp.Database, dbrp.RetentionPolicy)
+		u.PointsReceived = tu.PointsReceived
+		u.LastWrite = tu.LastWrite
+		u.Live = !tu.LastWrite.IsZero() && now.Sub(tu.LastWrite) <= liveWindow
+		u.Tasks = append(u.Tasks, tu.Tasks...)
+	}
+	for cluster, dbrps := range s.InfluxDBService.Subscriptions() {
+		for _, dbrp := range dbrps {
+			u := get(dbrp.Database, dbrp.RetentionPolicy)
+			u.Subscriptions = append(u.Subscriptions, cluster)
+		}
+	}
+
+	d := client.DBRPsStatus{
+		Link:                  client.Link{Relation: client.Self, Href: httpd.BasePath + dbrpsPath},
+		DBRPs:                 make([]client.DBRPUsage, 0, len(usage)),
+		OrphanedSubscriptions: orphaned,
+	}
+	for _, u := range usage {
+		sort.Strings(u.Subscriptions)
+		d.DBRPs = append(d.DBRPs, *u)
+	}
+	sort.Slice(d.DBRPs, func(i, j int) bool {
+		if d.DBRPs[i].Database != d.DBRPs[j].Database {
+			return d.DBRPs[i].Database < d.DBRPs[j].Database
+		}
+		return d.DBRPs[i].RetentionPolicy < d.DBRPs[j].RetentionPolicy
+	})
+	return d, nil
+}
+
+// Reconcile removes the orphaned subscriptions from the InfluxDB clusters.
+func (s *Service) Reconcile() (client.ReconcileResult, error) {
+	removed, err := s.InfluxDBService.RemoveOrphanedSubscriptions()
+	for _, o := range removed {
+		s.diag.RemovedSubscription(o.Cluster, o.Database, o.RetentionPolicy, o.Name, o.Reason)
+	}
+	if removed == nil {
+		removed = []client.OrphanedSubscription{}
+	}
+	return client.ReconcileResult{Removed: removed}, err
+}
+
+func (s *Service) handleDBRPs(w http.ResponseWriter, r *http.Request) {
+	liveWindow := DefaultLiveWindow
+	if lw := r.URL.Query().Get("live-window"); lw != "" {
+		d, err := time.ParseDuration(lw)
+		if err != nil || d <= 0 {
+			httpd.HttpError(w, fmt.Sprintf("invalid live-window %q, must be a positive duration", lw), true, http.StatusBadRequest)
+			return
+		}
+		liveWindow = d
+	}
+	d, err := s.Status(liveWindow)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.Write(httpd.MarshalJSON(d, true))
+}
+
+func (s *Service) handleReconcile(w http.ResponseWriter, r *http.Request) {
+	result, err := s.Reconcile()
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to remove orphaned subscriptions, removed %d: %v", len(result.Removed), err), true, http.StatusInternalServerError)
+		return
+	}
+	w.Write(httpd.MarshalJSON(result, true))
+}
@@ -0,0 +1,152 @@
+package dbrps_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor"
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/dbrps"
+)
+
+type diag struct {
+	removed []string
+}
+
+func (d *diag) RemovedSubscription(cluster, db, rp, name, reason string) {
+	d.removed = append(d.removed, cluster+"/"+db+"."+rp+"/"+name)
+}
+
+type taskMaster map[kapacitor.DBRP]kapacitor.DBRPUsage
+
+func (tm taskMaster) DBRPUsage() map[kapacitor.DBRP]kapacitor.DBRPUsage {
+	return tm
+}
+
+type influxDBService struct {
+	subs     map[string][]client.DBRP
+	orphaned []client.OrphanedSubscription
+	err      error
+}
+
+func (s *influxDBService) Subscriptions() map[string][]client.DBRP {
+	return s.subs
+}
+
+func (s *influxDBService) OrphanedSubscriptions() ([]client.OrphanedSubscription, error) {
+	return s.orphaned, s.err
+}
+
+func (s *influxDBService) RemoveOrphanedSubscriptions() ([]client.OrphanedSubscription, error) {
+	return s.orphaned, s.err
+}
+
+func TestService_Status(t *testing.T) {
+	now := time.Now().UTC()
+	old := now.Add(-time.Hour)
+	orphaned := []client.OrphanedSubscription{{
+		Cluster:         "default",
+		Database:        "old",
+		RetentionPolicy: "autogen",
+		Name:            "kapacitor-0a1b",
+		Reason:          "the subscriptions are disabled",
+	}}
+
+	s := dbrps.NewService(&diag{})
+	s.TaskMaster = taskMaster{
+		{Database: "telegraf", RetentionPolicy: "autogen"}: {PointsReceived: 10, LastWrite: now, Tasks: []string{"cpu"}},
+		{Database: "app", RetentionPolicy: "autogen"}:      {PointsReceived: 2, LastWrite: old},
+		{Database: "idle", RetentionPolicy: "autogen"}:     {Tasks: []string{"idle"}},
+	}
+	s.InfluxDBService = &influxDBService{
+		subs: map[string][]client.DBRP{
+			"remote":  {{Database: "telegraf", RetentionPolicy: "autogen"}},
+			"default": {{Database: "telegraf", RetentionPolicy: "autogen"}, {Database: "app", RetentionPolicy: "weekly"}},
+		},
+		orphaned: orphaned,
+	}
+
+	status, err := s.Status(5 * time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []client.DBRPUsage{
+		{
+			Database:        "app",
+			RetentionPolicy: "autogen",
+			PointsReceived:  2,
+			LastWrite:       old,
+			Tasks:           []string{},
+			Subscriptions:   []string{},
+		},
+		{
+			Database:        "app",
+			RetentionPolicy: "weekly",
+			Tasks:           []string{},
+			Subscriptions:   []string{"default"},
+		},
+		{
+			Database:        "idle",
+			RetentionPolicy: "autogen",
+			Tasks:           []string{"idle"},
+			Subscriptions:   []string{},
+		},
+		{
+			Database:        "telegraf",
+			RetentionPolicy: "autogen",
+			Live:            true,
+			PointsReceived:  10,
+			LastWrite:       now,
+			Tasks:           []string{"cpu"},
+			Subscriptions:   []string{"default", "remote"},
+		},
+	}
+	if !reflect.DeepEqual(status.DBRPs, exp) {
+		t.Errorf("unexpected dbrps:\ngot\n%+v\nexp\n%+v", status.DBRPs, exp)
+	}
+	if !reflect.DeepEqual(status.OrphanedSubscriptions, orphaned) {
+		t.Errorf("unexpected orphaned subscriptions:\ngot\n%+v\nexp\n%+v", status.OrphanedSubscriptions, orphaned)
+	}
+
+	// A longer live window includes the older writes.
+	status, err = s.Status(2 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.DBRPs[0].Live {
+		t.Errorf("expected %s.%s to be live", status.DBRPs[0].Database, status.DBRPs[0].RetentionPolicy)
+	}
+}
+
+func TestService_Reconcile(t *testing.T) {
+	removed := []client.OrphanedSubscription{
+		{Cluster: "default", Database: "old", RetentionPolicy: "autogen", Name: "kapacitor-0a1b"},
+		{Cluster: "remote", Database: "app", RetentionPolicy: "weekly", Name: "kapacitor"},
+	}
+	d := &diag{}
+	s := dbrps.NewService(d)
+	s.InfluxDBService = &influxDBService{orphaned: removed}
+
+	result, err := s.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Removed, removed) {
+		t.Errorf("unexpected removed subscriptions:\ngot\n%+v\nexp\n%+v", result.Removed, removed)
+	}
+	if exp := []string{"default/old.autogen/kapacitor-0a1b", "remote/app.weekly/kapacitor"}; !reflect.DeepEqual(d.removed, exp) {
+		t.Errorf("unexpected logged removals got %v exp %v", d.removed, exp)
+	}
+
+	// The subscriptions removed before a failure are reported.
+	s.InfluxDBService = &influxDBService{orphaned: removed[:1], err: errors.New("connection refused")}
+	result, err = s.Reconcile()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if len(result.Removed) != 1 {
+		t.Errorf("unexpected removed subscriptions %+v", result.Removed)
+	}
+}
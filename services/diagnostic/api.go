@@ -1,6 +1,7 @@
 package diagnostic
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -83,6 +84,46 @@ func (s *SessionService) NewLogger() *sessionsLogger {
 	}
 }
 
+// Watch calls f with the keys and values of each log message with the tags, until cancel is called.
+// The function f must not block as it is called while the message is logged.
+func (s *SessionService) Watch(tags map[string]string, f func(map[string]interface{})) (cancel func()) {
+	ts := make([]tag, 0, len(tags))
+	for k, v := range tags {
+		ts = append(ts, tag{key: k, value: v})
+	}
+	session := s.SessionsStore.Create(&watchWriter{f: f}, "application/json", DebugLevel, ts)
+	return func() {
+		s.SessionsStore.Delete(session)
+	}
+}
+
+// watchWriter decodes the JSON log messages of a session.
+type watchWriter struct {
+	header http.Header
+	f      func(map[string]interface{})
+}
+
+func (w *watchWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+func (w *watchWriter) WriteHeader(int) {}
+
+// Write decodes a log message, each message is written at once.
+func (w *watchWriter) Write(buf []byte) (int, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return 0, err
+	}
+	w.f(m)
+	return len(buf), nil
+}
+
+func (w *watchWriter) Flush() {}
+
 func (s *SessionService) handleSessions(w http.ResponseWriter, r *http.Request) {
 	params := r.URL.Query()
 	tags := []tag{}
@@ -111,3 +111,26 @@ func TestHandleSessions_level(t *testing.T) {
 	}
 
 }
+
+func TestSessionService_Watch(t *testing.T) {
+	s := NewSessionService()
+	var got []map[string]interface{}
+	cancel := s.Watch(map[string]string{"task": "t1", "lvl": "error"}, func(m map[string]interface{}) {
+		got = append(got, m)
+	})
+	logger := s.NewLogger()
+	logger.With(String("task", "t1")).Error("failed", String("node", "eval2"))
+	logger.With(String("task", "t2")).Error("failed")
+	logger.With(String("task", "t1")).Info("started")
+	cancel()
+	logger.With(String("task", "t1")).Error("failed")
+
+	if len(got) != 1 {
+		t.Fatalf("unexpected number of messages got %d exp 1: %v", len(got), got)
+	}
+	for k, exp := range map[string]string{"lvl": "error", "msg": "failed", "task": "t1", "node": "eval2"} {
+		if got[0][k] != exp {
+			t.Errorf("unexpected %s got %v exp %s", k, got[0][k], exp)
+		}
+	}
+}
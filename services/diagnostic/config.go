@@ -1,13 +1,78 @@
 package diagnostic
 
+import (
+	"fmt"
+)
+
+const (
+	LogfmtFormat = "logfmt"
+	JSONFormat   = "json"
+)
+
 type Config struct {
 	File  string `toml:"file"`
 	Level string `toml:"level"`
+	// Format of the log lines, logfmt or json.
+	Format string `toml:"format"`
+	// Levels of components, overriding the level of the server.
+	// The component of a log is its service field.
+	Components map[string]string `toml:"components"`
+	// Levels of tasks and their nodes, overriding the levels of the server and components.
+	Tasks map[string]string `toml:"tasks"`
 }
 
 func NewConfig() Config {
 	return Config{
-		File:  "STDERR",
-		Level: "DEBUG",
+		File:   "STDERR",
+		Level:  "DEBUG",
+		Format: LogfmtFormat,
+	}
+}
+
+func (c Config) Validate() error {
+	if _, err := c.level(); err != nil {
+		return err
+	}
+	switch c.Format {
+	case "", LogfmtFormat, JSONFormat:
+	default:
+		return fmt.Errorf("invalid format %q, must be %s or %s", c.Format, LogfmtFormat, JSONFormat)
+	}
+	for component, level := range c.Components {
+		if _, err := ParseLevel(level); err != nil {
+			return fmt.Errorf("component %s: %v", component, err)
+		}
+	}
+	for task, level := range c.Tasks {
+		if _, err := ParseLevel(level); err != nil {
+			return fmt.Errorf("task %s: %v", task, err)
+		}
+	}
+	return nil
+}
+
+// level returns the level of the server, an empty level is the debug level.
+func (c Config) level() (Level, error) {
+	if c.Level == "" {
+		return DebugLevel, nil
+	}
+	return ParseLevel(c.Level)
+}
+
+// levels returns the levels of the configuration.
+func (c Config) levels() (*Levels, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	level, _ := c.level()
+	levels := NewLevels(level)
+	for component, name := range c.Components {
+		level, _ := ParseLevel(name)
+		levels.SetComponentLevel(component, level)
+	}
+	for task, name := range c.Tasks {
+		level, _ := ParseLevel(name)
+		levels.SetTaskLevel(task, level)
 	}
+	return levels, nil
 }
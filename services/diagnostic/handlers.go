@@ -17,6 +17,7 @@ import (
 	"github.com/influxdata/kapacitor/models"
 	alertservice "github.com/influxdata/kapacitor/services/alert"
 	"github.com/influxdata/kapacitor/services/alerta"
+	"github.com/influxdata/kapacitor/services/amqp"
 	"github.com/influxdata/kapacitor/services/ec2"
 	"github.com/influxdata/kapacitor/services/hipchat"
 	"github.com/influxdata/kapacitor/services/httppost"
@@ -231,6 +232,14 @@ func (h *KapacitorHandler) StoppedTaskWithError(task string, err error) {
 	h.l.Error("failed to stop task with out error", String("task", task), Error(err))
 }
 
+func (h *KapacitorHandler) SavedTaskSnapshot(task string) {
+	h.l.Info("saved task snapshot", String("task", task))
+}
+
+func (h *KapacitorHandler) FailedToSaveTaskSnapshot(task string, err error) {
+	h.l.Error("failed to save task snapshot", String("task", task), Error(err))
+}
+
 func (h *KapacitorHandler) TaskMasterDot(d string) {
 	h.l.Debug("listing dot", String("dot", d))
 }
@@ -264,6 +273,19 @@ func (h *KapacitorHandler) StartingBatchQuery(q string) {
 	h.l.Debug("starting next batch query", String("query", q))
 }
 
+func (h *KapacitorHandler) PointsThrottled(limit, dropped int64) {
+	h.l.Error("task exceeded its limit of points per second, dropping points",
+		Int64("limit", limit),
+		Int64("dropped", dropped),
+	)
+}
+
+func (h *KapacitorHandler) PointsCardinalityLimited(dropped int64) {
+	h.l.Error("task exceeded its limit of series of a measurement, limiting points of new series",
+		Int64("dropped", dropped),
+	)
+}
+
 func TagPairs(tags models.Tags) []Field {
 	ts := []Field{}
 	for k, v := range tags {
@@ -430,6 +452,31 @@ func (h *KafkaHandler) InsecureSkipVerify() {
 	h.l.Info("service is configured to skip ssl verification")
 }
 
+// AMQP handler
+type AMQPHandler struct {
+	l Logger
+}
+
+func (h *AMQPHandler) WithContext(ctx ...keyvalue.T) amqp.Diagnostic {
+	fields := logFieldsFromContext(ctx)
+
+	return &AMQPHandler{
+		l: h.l.With(fields...),
+	}
+}
+
+func (h *AMQPHandler) Error(msg string, err error, ctx ...keyvalue.T) {
+	Err(h.l, msg, err, ctx)
+}
+
+func (h *AMQPHandler) InsecureSkipVerify() {
+	h.l.Info("service is configured to skip ssl verification")
+}
+
+func (h *AMQPHandler) ConsumingQueue(queue string) {
+	h.l.Info("consuming queue", String("queue", queue))
+}
+
 // HTTPD handler
 
 type HTTPDHandler struct {
@@ -465,6 +512,14 @@ func (h *HTTPDHandler) ListeningOn(addr string, proto string) {
 	h.l.Info("listening on", String("addr", addr), String("protocol", proto))
 }
 
+func (h *HTTPDHandler) ReloadedCertificate(path string) {
+	h.l.Info("reloaded certificate", String("path", path))
+}
+
+func (h *HTTPDHandler) ObtainedCertificate(domains []string, notAfter time.Time) {
+	h.l.Info("obtained certificate", Strings("domains", domains), Time("expires", notAfter))
+}
+
 func (h *HTTPDHandler) WriteBodyReceived(body string) {
 	h.l.Debug("write body received by handler: %s", String("body", body))
 }
@@ -608,6 +663,14 @@ func (h *StorageHandler) Error(msg string, err error) {
 	h.l.Error(msg, Error(err))
 }
 
+func (h *StorageHandler) EncryptedValues(namespace string, count int) {
+	h.l.Info("encrypted existing values", String("namespace", namespace), Int("count", count))
+}
+
+func (h *StorageHandler) RotatedDataKey(id uint32, count int) {
+	h.l.Info("rotated data key", Int("key_id", int(id)), Int("count", count))
+}
+
 // TaskStore Handler
 
 type TaskStoreHandler struct {
@@ -626,6 +689,22 @@ func (h *TaskStoreHandler) FinishedTask(taskID string) {
 	h.l.Debug("task finished", String("task", taskID))
 }
 
+func (h *TaskStoreHandler) PausedTask(taskID string) {
+	h.l.Info("paused task outside of its schedule", String("task", taskID))
+}
+
+func (h *TaskStoreHandler) ResumedTask(taskID string) {
+	h.l.Info("resumed task within its schedule", String("task", taskID))
+}
+
+func (h *TaskStoreHandler) RestartingTask(taskID string, attempt int, delay time.Duration) {
+	h.l.Info("restarting task after error", String("task", taskID), Int("attempt", attempt), Duration("delay", delay))
+}
+
+func (h *TaskStoreHandler) GaveUpRestartingTask(taskID string, restarts int) {
+	h.l.Error("task stopped with an error and will not be restarted", String("task", taskID), Int("restarts", restarts))
+}
+
 func (h *TaskStoreHandler) Debug(msg string) {
 	h.l.Debug(msg)
 }
@@ -718,6 +797,10 @@ func (h *UDFServiceHandler) LoadedUDFInfo(udf string) {
 	h.l.Debug("loaded UDF info", String("udf", udf))
 }
 
+func (h *UDFServiceHandler) UpgradedUDF(udf string) {
+	h.l.Info("upgraded UDF", String("udf", udf))
+}
+
 func (h *UDFServiceHandler) WithUDFContext() udf.Diagnostic {
 	return &UDFServiceHandler{
 		l: h.l,
@@ -977,6 +1060,125 @@ func (h *NoAuthHandler) FakedSubscriptionUserToken() {
 	h.l.Info("using noauth auth backend. Faked authentication for subscription user token")
 }
 
+// RBAC handler
+
+type RBACHandler struct {
+	l Logger
+}
+
+func (h *RBACHandler) CreatedAdminUser(username string) {
+	h.l.Info("created admin user", String("user", username))
+}
+
+// OIDC handler
+
+type OIDCHandler struct {
+	l Logger
+}
+
+func (h *OIDCHandler) Error(msg string, err error) {
+	h.l.Error(msg, Error(err))
+}
+
+// Audit handler
+
+type AuditHandler struct {
+	l Logger
+}
+
+func (h *AuditHandler) Error(msg string, err error) {
+	h.l.Error(msg, Error(err))
+}
+
+// Tracing handler
+
+type TracingHandler struct {
+	l Logger
+}
+
+func (h *TracingHandler) Error(msg string, err error) {
+	h.l.Error(msg, Error(err))
+}
+
+// HA handler
+
+type HAHandler struct {
+	l Logger
+}
+
+func (h *HAHandler) Error(msg string, err error) {
+	h.l.Error(msg, Error(err))
+}
+
+func (h *HAHandler) BecameLeader() {
+	h.l.Info("became the leader")
+}
+
+func (h *HAHandler) BecameStandby() {
+	h.l.Info("became the standby")
+}
+
+func (h *HAHandler) NewLeader(id, url string) {
+	h.l.Info("new leader", String("id", id), String("url", url))
+}
+
+// Meta-monitoring handler
+
+type MetaMonitoringHandler struct {
+	l Logger
+}
+
+func (h *MetaMonitoringHandler) InstalledTask(id string) {
+	h.l.Info("installed meta-monitoring task", String("task", id))
+}
+
+func (h *MetaMonitoringHandler) RemovedTask(id string) {
+	h.l.Info("removed meta-monitoring task", String("task", id))
+}
+
+// DBRPs handler
+
+type DBRPsHandler struct {
+	l Logger
+}
+
+func (h *DBRPsHandler) RemovedSubscription(cluster, db, rp, name, reason string) {
+	h.l.Info("removed orphaned subscription",
+		String("cluster", cluster),
+		String("dbrp", fmt.Sprintf("%s.%s", db, rp)),
+		String("name", name),
+		String("reason", reason),
+	)
+}
+
+// Cluster handler
+
+type ClusterHandler struct {
+	l Logger
+}
+
+func (h *ClusterHandler) Error(msg string, err error) {
+	h.l.Error(msg, Error(err))
+}
+
+func (h *ClusterHandler) OpenedNode(node string, shards []int) {
+	h.l.Info("opened cluster node", String("node", node), Int("shards", len(shards)))
+}
+
+// WAL handler
+
+type WALHandler struct {
+	l Logger
+}
+
+func (h *WALHandler) Error(msg string, err error) {
+	h.l.Error(msg, Error(err))
+}
+
+func (h *WALHandler) Replayed(segments, points int) {
+	h.l.Info("replayed write-ahead log", Int("segments", segments), Int("points", points))
+}
+
 // Stats handler
 
 type StatsHandler struct {
@@ -1005,6 +1207,108 @@ func (h *UDPHandler) ClosedService() {
 	h.l.Info("closed service")
 }
 
+// NATS handler
+
+type NATSHandler struct {
+	l Logger
+}
+
+func (h *NATSHandler) Error(msg string, err error, ctx ...keyvalue.T) {
+	Err(h.l, msg, err, ctx)
+}
+
+func (h *NATSHandler) Connected(url, serverID string) {
+	h.l.Info("connected to NATS server", String("url", url), String("server_id", serverID))
+}
+
+func (h *NATSHandler) Subscribed(subject string) {
+	h.l.Debug("subscribed to subject", String("subject", subject))
+}
+
+func (h *NATSHandler) ConsumerReady(stream, durable string) {
+	h.l.Info("consuming from JetStream stream", String("stream", stream), String("durable", durable))
+}
+
+func (h *NATSHandler) ClosedService() {
+	h.l.Info("closed service")
+}
+
+// Graphite pickle handler
+
+type GraphitePickleHandler struct {
+	l Logger
+}
+
+func (h *GraphitePickleHandler) Error(msg string, err error, ctx ...keyvalue.T) {
+	Err(h.l, msg, err, ctx)
+}
+
+func (h *GraphitePickleHandler) StartedListening(addr string) {
+	h.l.Info("started listening for graphite pickle protocol", String("address", addr))
+}
+
+func (h *GraphitePickleHandler) ClosedService() {
+	h.l.Info("closed service")
+}
+
+// Statsd handler
+
+type StatsdHandler struct {
+	l Logger
+}
+
+func (h *StatsdHandler) Error(msg string, err error, ctx ...keyvalue.T) {
+	Err(h.l, msg, err, ctx)
+}
+
+func (h *StatsdHandler) StartedListening(protocol, addr string) {
+	h.l.Info("started listening for statsd metrics", String("protocol", protocol), String("address", addr))
+}
+
+func (h *StatsdHandler) ClosedService() {
+	h.l.Info("closed service")
+}
+
+// Tail handler
+
+type TailHandler struct {
+	l Logger
+}
+
+func (h *TailHandler) Error(msg string, err error, ctx ...keyvalue.T) {
+	Err(h.l, msg, err, ctx)
+}
+
+func (h *TailHandler) TailingFile(path string) {
+	h.l.Info("tailing file", String("path", path))
+}
+
+func (h *TailHandler) StoppedTailingFile(path string) {
+	h.l.Info("stopped tailing file", String("path", path))
+}
+
+func (h *TailHandler) ClosedService() {
+	h.l.Info("closed service")
+}
+
+// SNMP handler
+
+type SNMPHandler struct {
+	l Logger
+}
+
+func (h *SNMPHandler) Error(msg string, err error, ctx ...keyvalue.T) {
+	Err(h.l, msg, err, ctx)
+}
+
+func (h *SNMPHandler) StartedPolling(agents []string) {
+	h.l.Info("started polling SNMP agents", Strings("agents", agents))
+}
+
+func (h *SNMPHandler) ClosedService() {
+	h.l.Info("closed service")
+}
+
 // InfluxDB handler
 
 type InfluxDBHandler struct {
@@ -1043,6 +1347,18 @@ func (h *InfluxDBHandler) StartedUDPListener(db string, rp string) {
 	h.l.Info("started UDP listener", String("dbrp", fmt.Sprintf("%s.%s", db, rp)))
 }
 
+func (h *InfluxDBHandler) StartedSubscriptionListener(addr, protocol string) {
+	h.l.Info("started subscription listener", String("address", addr), String("protocol", protocol))
+}
+
+func (h *InfluxDBHandler) URLHealthy(url string) {
+	h.l.Info("InfluxDB URL is healthy", String("url", url))
+}
+
+func (h *InfluxDBHandler) URLUnhealthy(url string, err error) {
+	h.l.Error("InfluxDB URL is unhealthy", String("url", url), Error(err))
+}
+
 // Scraper handler
 
 type ScraperHandler struct {
@@ -1272,6 +1588,20 @@ func (h *LoadHandler) Loading(el string, file string) {
 	h.l.Debug("loading object from file", String("object", el), String("file", file))
 }
 
+// Bundle handler
+
+type BundleHandler struct {
+	l Logger
+}
+
+func (h *BundleHandler) Error(msg string, err error) {
+	h.l.Error(msg, Error(err))
+}
+
+func (h *BundleHandler) Importing(kind, id string) {
+	h.l.Debug("importing object from bundle", String("object", kind), String("id", id))
+}
+
 // Session handler
 
 type SessionHandler struct {
@@ -1311,3 +1641,17 @@ func (h *SideloadHandler) WithContext(ctx ...keyvalue.T) sideload.Diagnostic {
 		l: h.l.With(fields...),
 	}
 }
+
+// gRPC handler
+
+type GRPCHandler struct {
+	l Logger
+}
+
+func (h *GRPCHandler) Error(msg string, err error) {
+	h.l.Error(msg, Error(err))
+}
+
+func (h *GRPCHandler) StartedListening(addr string) {
+	h.l.Info("started listening on gRPC", String("address", addr))
+}
@@ -0,0 +1,93 @@
+package diagnostic
+
+import (
+	"sync"
+	"time"
+)
+
+// LastError is the last error logged by a component.
+type LastError struct {
+	Time    time.Time
+	Message string
+	// Error of the error field of the log, empty if it has none.
+	Error string
+	// Count of the errors logged by the component.
+	Count int64
+}
+
+// lastErrors records the last error logged by each component, for health checks.
+type lastErrors struct {
+	mu     sync.RWMutex
+	errors map[string]LastError
+}
+
+func newLastErrors() *lastErrors {
+	return &lastErrors{
+		errors: make(map[string]LastError),
+	}
+}
+
+func (e *lastErrors) Logger() Logger {
+	return &lastErrorsLogger{errors: e}
+}
+
+func (e *lastErrors) record(component string, now time.Time, msg string, fields []Field) {
+	le := LastError{
+		Time:    now,
+		Message: msg,
+	}
+	for _, f := range fields {
+		if ef, ok := f.(ErrorField); ok && ef.err != nil {
+			le.Error = ef.err.Error()
+			break
+		}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	le.Count = e.errors[component].Count + 1
+	e.errors[component] = le
+}
+
+// Get returns the last errors of the components.
+func (e *lastErrors) Get() map[string]LastError {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	errors := make(map[string]LastError, len(e.errors))
+	for k, v := range e.errors {
+		errors[k] = v
+	}
+	return errors
+}
+
+// lastErrorsLogger records the errors of the loggers of components, it discards all other logs.
+type lastErrorsLogger struct {
+	errors    *lastErrors
+	context   []Field
+	component string
+}
+
+func (l *lastErrorsLogger) Error(msg string, ctx ...Field) {
+	if l.component == "" {
+		return
+	}
+	fields := make([]Field, 0, len(l.context)+len(ctx))
+	fields = append(fields, ctx...)
+	fields = append(fields, l.context...)
+	l.errors.record(l.component, time.Now(), msg, fields)
+}
+
+func (l *lastErrorsLogger) Debug(msg string, ctx ...Field) {}
+
+func (l *lastErrorsLogger) Info(msg string, ctx ...Field) {}
+
+func (l *lastErrorsLogger) With(ctx ...Field) Logger {
+	context := make([]Field, len(l.context), len(l.context)+len(ctx))
+	copy(context, l.context)
+	context = append(context, ctx...)
+	component, _ := scope(context)
+	return &lastErrorsLogger{
+		errors:    l.errors,
+		context:   context,
+		component: component,
+	}
+}
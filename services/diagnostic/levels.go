@@ -0,0 +1,165 @@
+package diagnostic
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Keys of the context fields that identify the component and the task of a logger.
+const (
+	componentKey = "service"
+	taskKey      = "task"
+)
+
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "DEBUG"
+	case InfoLevel:
+		return "INFO"
+	case ErrorLevel:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+}
+
+// ParseLevel returns the level of its case insensitive name.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToUpper(name) {
+	case "DEBUG":
+		return DebugLevel, nil
+	case "INFO":
+		return InfoLevel, nil
+	case "ERROR":
+		return ErrorLevel, nil
+	default:
+		return 0, fmt.Errorf("invalid log level %q, must be one of DEBUG, INFO or ERROR", name)
+	}
+}
+
+// Levels are the log level of the server, and the levels of the components and tasks that override it.
+// The level of a task applies to the logs of the task and its nodes,
+// and takes precedence over the level of their component.
+type Levels struct {
+	mu         sync.RWMutex
+	level      Level
+	components map[string]Level
+	tasks      map[string]Level
+}
+
+func NewLevels(level Level) *Levels {
+	return &Levels{
+		level:      level,
+		components: make(map[string]Level),
+		tasks:      make(map[string]Level),
+	}
+}
+
+// Enabled reports whether a log of the level, component and task is written.
+// The component and task are empty for logs that are not part of one.
+func (l *Levels) Enabled(lvl Level, component, task string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if level, ok := l.tasks[task]; ok && task != "" {
+		return lvl >= level
+	}
+	if level, ok := l.components[component]; ok && component != "" {
+		return lvl >= level
+	}
+	return lvl >= l.level
+}
+
+// Level returns the level of the server.
+func (l *Levels) Level() Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.level
+}
+
+// SetLevel sets the level of the server.
+func (l *Levels) SetLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+}
+
+// SetComponentLevel sets the level of the component.
+func (l *Levels) SetComponentLevel(component string, level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.components[component] = level
+}
+
+// DeleteComponentLevel removes the level of the component, its logs use the level of the server.
+func (l *Levels) DeleteComponentLevel(component string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.components, component)
+}
+
+// SetTaskLevel sets the level of the task.
+func (l *Levels) SetTaskLevel(task string, level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tasks[task] = level
+}
+
+// DeleteTaskLevel removes the level of the task, its logs use the level of their component or of the server.
+func (l *Levels) DeleteTaskLevel(task string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.tasks, task)
+}
+
+// Reset replaces the levels with the levels of o.
+func (l *Levels) Reset(o *Levels) {
+	level, components, tasks := o.Level(), o.ComponentLevels(), o.TaskLevels()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+	l.components = components
+	l.tasks = tasks
+}
+
+// ComponentLevels returns the levels of the components that override the level of the server.
+func (l *Levels) ComponentLevels() map[string]Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return copyLevels(l.components)
+}
+
+// TaskLevels returns the levels of the tasks that override the level of the server.
+func (l *Levels) TaskLevels() map[string]Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return copyLevels(l.tasks)
+}
+
+func copyLevels(levels map[string]Level) map[string]Level {
+	c := make(map[string]Level, len(levels))
+	for k, v := range levels {
+		c[k] = v
+	}
+	return c
+}
+
+// scope returns the component and task of the context of a logger,
+// the last fields take precedence as they do for the children of loggers.
+func scope(context []Field) (component, task string) {
+	for _, f := range context {
+		s, ok := f.(StringField)
+		if !ok {
+			continue
+		}
+		switch {
+		case bytes.Equal(s.key, []byte(componentKey)):
+			component = s.value
+		case bytes.Equal(s.key, []byte(taskKey)):
+			task = s.value
+		}
+	}
+	return
+}
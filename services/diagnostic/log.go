@@ -81,9 +81,16 @@ type ServerLogger struct {
 	mu      *sync.Mutex
 	context []Field
 	w       *bufio.Writer
+	// Write JSON log lines instead of logfmt.
+	json bool
 
 	levelMu sync.RWMutex
 	levelF  func(lvl Level) bool
+
+	// Levels of the logs of the component and task of the context, when set they replace levelF.
+	levels    *Levels
+	component string
+	task      string
 }
 
 func NewServerLogger(w io.Writer) *ServerLogger {
@@ -95,6 +102,13 @@ func NewServerLogger(w io.Writer) *ServerLogger {
 	}
 }
 
+// NewJSONServerLogger returns a logger writing a JSON object per log line.
+func NewJSONServerLogger(w io.Writer) *ServerLogger {
+	l := NewServerLogger(w)
+	l.json = true
+	return l
+}
+
 // LevelF set on parent applies to self and any future children
 func (l *ServerLogger) SetLevelF(f func(Level) bool) {
 	l.levelMu.Lock()
@@ -102,42 +116,58 @@ func (l *ServerLogger) SetLevelF(f func(Level) bool) {
 	l.levelF = f
 }
 
+// SetLevels sets the levels of the logs of the components and tasks,
+// they apply to self and any future children.
+func (l *ServerLogger) SetLevels(levels *Levels) {
+	l.levelMu.Lock()
+	defer l.levelMu.Unlock()
+	l.levels = levels
+}
+
+func (l *ServerLogger) enabled(lvl Level) bool {
+	l.levelMu.RLock()
+	defer l.levelMu.RUnlock()
+	if l.levels != nil {
+		return l.levels.Enabled(lvl, l.component, l.task)
+	}
+	return l.levelF(lvl)
+}
+
 func (l *ServerLogger) With(ctx ...Field) Logger {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	newCtx := make([]Field, len(l.context))
 	copy(newCtx, l.context)
+	newCtx = append(newCtx, ctx...)
+	l.levelMu.RLock()
+	defer l.levelMu.RUnlock()
+	component, task := scope(newCtx)
 	return &ServerLogger{
-		mu:      l.mu,
-		context: append(newCtx, ctx...),
-		w:       l.w,
-		levelF:  l.levelF,
+		mu:        l.mu,
+		context:   newCtx,
+		w:         l.w,
+		json:      l.json,
+		levelF:    l.levelF,
+		levels:    l.levels,
+		component: component,
+		task:      task,
 	}
 }
 
 func (l *ServerLogger) Error(msg string, ctx ...Field) {
-	l.levelMu.RLock()
-	logLine := l.levelF(ErrorLevel)
-	l.levelMu.RUnlock()
-	if logLine {
+	if l.enabled(ErrorLevel) {
 		l.Log(time.Now(), "error", msg, ctx)
 	}
 }
 
 func (l *ServerLogger) Debug(msg string, ctx ...Field) {
-	l.levelMu.RLock()
-	logLine := l.levelF(DebugLevel)
-	l.levelMu.RUnlock()
-	if logLine {
+	if l.enabled(DebugLevel) {
 		l.Log(time.Now(), "debug", msg, ctx)
 	}
 }
 
 func (l *ServerLogger) Info(msg string, ctx ...Field) {
-	l.levelMu.RLock()
-	logLine := l.levelF(InfoLevel)
-	l.levelMu.RUnlock()
-	if logLine {
+	if l.enabled(InfoLevel) {
 		l.Log(time.Now(), "info", msg, ctx)
 	}
 }
@@ -146,7 +176,11 @@ func (l *ServerLogger) Info(msg string, ctx ...Field) {
 func (l *ServerLogger) Log(now time.Time, level string, msg string, ctx []Field) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	writeLogfmt(l.w, now, level, msg, l.context, ctx)
+	if l.json {
+		writeJSON(l.w, now, level, msg, l.context, ctx)
+	} else {
+		writeLogfmt(l.w, now, level, msg, l.context, ctx)
+	}
 	l.w.Flush()
 }
 
@@ -382,6 +382,108 @@ func TestLogger_SetLeveF(t *testing.T) {
 	}
 }
 
+func TestLogger_SetLevels(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	l := diagnostic.NewServerLogger(buf)
+	levels := diagnostic.NewLevels(diagnostic.InfoLevel)
+	l.SetLevels(levels)
+	levels.SetComponentLevel("http", diagnostic.ErrorLevel)
+	levels.SetTaskLevel("cpu", diagnostic.DebugLevel)
+
+	server := l.With(diagnostic.String("service", "alert"))
+	http := l.With(diagnostic.String("service", "http"))
+	task := l.With(diagnostic.String("service", "kapacitor")).With(diagnostic.String("task", "cpu"))
+	node := task.With(diagnostic.String("node", "alert2"))
+	other := l.With(diagnostic.String("service", "http"), diagnostic.String("task", "mem"))
+
+	testCases := []struct {
+		name string
+		l    diagnostic.Logger
+		log  func(l diagnostic.Logger)
+		exp  bool
+	}{
+		{name: "server info", l: server, log: func(l diagnostic.Logger) { l.Info("msg") }, exp: true},
+		{name: "server debug", l: server, log: func(l diagnostic.Logger) { l.Debug("msg") }},
+		{name: "component info", l: http, log: func(l diagnostic.Logger) { l.Info("msg") }},
+		{name: "component error", l: http, log: func(l diagnostic.Logger) { l.Error("msg") }, exp: true},
+		{name: "task debug", l: task, log: func(l diagnostic.Logger) { l.Debug("msg") }, exp: true},
+		{name: "node debug", l: node, log: func(l diagnostic.Logger) { l.Debug("msg") }, exp: true},
+		{name: "other task of component", l: other, log: func(l diagnostic.Logger) { l.Info("msg") }},
+	}
+	for _, tc := range testCases {
+		buf.Reset()
+		tc.log(tc.l)
+		if got := buf.Len() > 0; got != tc.exp {
+			t.Errorf("%s: unexpected log got %v exp %v", tc.name, got, tc.exp)
+		}
+	}
+
+	// Changes of the levels apply to existing loggers.
+	levels.DeleteTaskLevel("cpu")
+	levels.SetLevel(diagnostic.ErrorLevel)
+	buf.Reset()
+	node.Info("msg")
+	if buf.Len() > 0 {
+		t.Errorf("unexpected log after level change %q", buf.String())
+	}
+	levels.DeleteComponentLevel("http")
+	levels.SetLevel(diagnostic.DebugLevel)
+	buf.Reset()
+	http.Debug("msg")
+	if buf.Len() == 0 {
+		t.Error("expected debug log after level change")
+	}
+}
+
+func TestJSONLogger(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	l := diagnostic.NewJSONServerLogger(buf).With(diagnostic.String("service", "kapacitor"), diagnostic.String("task", "cpu"))
+	l.Error("failed \"to\" process", diagnostic.String("node", "alert2"), diagnostic.Int("n", 2), diagnostic.Error(errors.New("err")))
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON log line %q: %v", buf.String(), err)
+	}
+	delete(got, "ts")
+	exp := map[string]interface{}{
+		"lvl":     "error",
+		"msg":     "failed \"to\" process",
+		"service": "kapacitor",
+		"task":    "cpu",
+		"node":    "alert2",
+		"n":       float64(2),
+		"err":     "err",
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected log line got %v exp %v", got, exp)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := diagnostic.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	c.Format = "json"
+	c.Components = map[string]string{"http": "error"}
+	c.Tasks = map[string]string{"cpu": "DEBUG"}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []func(c *diagnostic.Config){
+		func(c *diagnostic.Config) { c.Level = "WARN" },
+		func(c *diagnostic.Config) { c.Format = "text" },
+		func(c *diagnostic.Config) { c.Components = map[string]string{"http": "all"} },
+		func(c *diagnostic.Config) { c.Tasks = map[string]string{"cpu": "trace"} },
+	} {
+		c := diagnostic.NewConfig()
+		f(&c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected invalid config %+v", c)
+		}
+	}
+}
+
 func TestSessionsLoggerWithoutContext(t *testing.T) {
 	now := time.Now()
 	nowStr := now.Format(diagnostic.RFC3339Milli)
@@ -6,8 +6,6 @@ import (
 	"io"
 	"os"
 	"path"
-	"strings"
-	"sync"
 )
 
 type nopCloser struct {
@@ -28,8 +26,8 @@ type Service struct {
 
 	SessionService *SessionService
 
-	levelMu sync.RWMutex
-	level   string
+	levels     *Levels
+	lastErrors *lastErrors
 }
 
 func NewService(c Config, stdout, stderr io.Writer) *Service {
@@ -48,20 +46,75 @@ func BootstrapMainHandler() *CmdHandler {
 	return s.NewCmdHandler()
 }
 
+// SetLogLevelFromName sets the log level of the server.
 func (s *Service) SetLogLevelFromName(lvl string) error {
-	s.levelMu.Lock()
-	defer s.levelMu.Unlock()
-	level := strings.ToUpper(lvl)
-	switch level {
-	case "INFO", "ERROR", "DEBUG":
-		s.level = level
-	default:
-		return errors.New("invalid log level")
+	level, err := ParseLevel(lvl)
+	if err != nil {
+		return err
+	}
+	s.levels.SetLevel(level)
+	return nil
+}
+
+// SetComponentLogLevel sets the log level of the component, overriding the level of the server.
+// An empty level removes the level of the component.
+func (s *Service) SetComponentLogLevel(component, lvl string) error {
+	if lvl == "" {
+		s.levels.DeleteComponentLevel(component)
+		return nil
+	}
+	level, err := ParseLevel(lvl)
+	if err != nil {
+		return err
+	}
+	s.levels.SetComponentLevel(component, level)
+	return nil
+}
+
+// SetTaskLogLevel sets the log level of the task and its nodes, overriding the levels of the server and components.
+// An empty level removes the level of the task.
+func (s *Service) SetTaskLogLevel(task, lvl string) error {
+	if lvl == "" {
+		s.levels.DeleteTaskLevel(task)
+		return nil
 	}
+	level, err := ParseLevel(lvl)
+	if err != nil {
+		return err
+	}
+	s.levels.SetTaskLevel(task, level)
+	return nil
+}
 
+// SetLogLevels sets the log levels of the server, components and tasks to the levels of the config,
+// the levels set at runtime are discarded.
+func (s *Service) SetLogLevels(c Config) error {
+	levels, err := c.levels()
+	if err != nil {
+		return err
+	}
+	s.levels.Reset(levels)
 	return nil
 }
 
+// LogLevels returns the names of the log levels of the server, and of the components and tasks that override it.
+func (s *Service) LogLevels() (level string, components, tasks map[string]string) {
+	return s.levels.Level().String(), levelNames(s.levels.ComponentLevels()), levelNames(s.levels.TaskLevels())
+}
+
+// LastErrors returns the last error logged by each component, keyed by the service field of their logs.
+func (s *Service) LastErrors() map[string]LastError {
+	return s.lastErrors.Get()
+}
+
+func levelNames(levels map[string]Level) map[string]string {
+	names := make(map[string]string, len(levels))
+	for k, v := range levels {
+		names[k] = v.String()
+	}
+	return names
+}
+
 func logLevelFromName(lvl string) Level {
 	var level Level
 	switch lvl {
@@ -77,15 +130,11 @@ func logLevelFromName(lvl string) Level {
 }
 
 func (s *Service) Open() error {
-	s.levelMu.Lock()
-	s.level = s.c.Level
-	s.levelMu.Unlock()
-
-	levelF := func(lvl Level) bool {
-		s.levelMu.RLock()
-		defer s.levelMu.RUnlock()
-		return lvl >= logLevelFromName(s.level)
+	levels, err := s.c.levels()
+	if err != nil {
+		return err
 	}
+	s.levels = levels
 
 	switch s.c.File {
 	case "STDERR":
@@ -108,14 +157,21 @@ func (s *Service) Open() error {
 		s.f = f
 	}
 
-	l := NewServerLogger(s.f)
-	l.SetLevelF(levelF)
+	var l *ServerLogger
+	if s.c.Format == JSONFormat {
+		l = NewJSONServerLogger(s.f)
+	} else {
+		l = NewServerLogger(s.f)
+	}
+	l.SetLevels(s.levels)
 
 	s.SessionService = NewSessionService()
+	s.lastErrors = newLastErrors()
 
 	s.Logger = NewMultiLogger(
 		l,
 		s.SessionService.NewLogger(),
+		s.lastErrors.Logger(),
 	)
 
 	s.SessionService.SetDiagnostic(s.NewSessionHandler())
@@ -196,6 +252,12 @@ func (s *Service) NewHipChatHandler() *HipChatHandler {
 	}
 }
 
+func (s *Service) NewAMQPHandler() *AMQPHandler {
+	return &AMQPHandler{
+		l: s.Logger.With(String("service", "amqp")),
+	}
+}
+
 func (s *Service) NewKafkaHandler() *KafkaHandler {
 	return &KafkaHandler{
 		l: s.Logger.With(String("service", "kafka")),
@@ -330,6 +392,60 @@ func (s *Service) NewNoAuthHandler() *NoAuthHandler {
 	}
 }
 
+func (s *Service) NewRBACHandler() *RBACHandler {
+	return &RBACHandler{
+		l: s.Logger.With(String("service", "rbac")),
+	}
+}
+
+func (s *Service) NewOIDCHandler() *OIDCHandler {
+	return &OIDCHandler{
+		l: s.Logger.With(String("service", "oidc")),
+	}
+}
+
+func (s *Service) NewAuditHandler() *AuditHandler {
+	return &AuditHandler{
+		l: s.Logger.With(String("service", "audit")),
+	}
+}
+
+func (s *Service) NewTracingHandler() *TracingHandler {
+	return &TracingHandler{
+		l: s.Logger.With(String("service", "tracing")),
+	}
+}
+
+func (s *Service) NewHAHandler() *HAHandler {
+	return &HAHandler{
+		l: s.Logger.With(String("service", "ha")),
+	}
+}
+
+func (s *Service) NewMetaMonitoringHandler() *MetaMonitoringHandler {
+	return &MetaMonitoringHandler{
+		l: s.Logger.With(String("service", "meta-monitoring")),
+	}
+}
+
+func (s *Service) NewDBRPsHandler() *DBRPsHandler {
+	return &DBRPsHandler{
+		l: s.Logger.With(String("service", "dbrps")),
+	}
+}
+
+func (s *Service) NewClusterHandler() *ClusterHandler {
+	return &ClusterHandler{
+		l: s.Logger.With(String("service", "cluster")),
+	}
+}
+
+func (s *Service) NewWALHandler() *WALHandler {
+	return &WALHandler{
+		l: s.Logger.With(String("service", "wal")),
+	}
+}
+
 func (s *Service) NewStatsHandler() *StatsHandler {
 	return &StatsHandler{
 		l: s.Logger.With(String("service", "stats")),
@@ -342,6 +458,36 @@ func (s *Service) NewUDPHandler() *UDPHandler {
 	}
 }
 
+func (s *Service) NewNATSHandler() *NATSHandler {
+	return &NATSHandler{
+		l: s.Logger.With(String("service", "nats")),
+	}
+}
+
+func (s *Service) NewGraphitePickleHandler() *GraphitePickleHandler {
+	return &GraphitePickleHandler{
+		l: s.Logger.With(String("service", "graphite-pickle")),
+	}
+}
+
+func (s *Service) NewStatsdHandler() *StatsdHandler {
+	return &StatsdHandler{
+		l: s.Logger.With(String("service", "statsd")),
+	}
+}
+
+func (s *Service) NewTailHandler(name string) *TailHandler {
+	return &TailHandler{
+		l: s.Logger.With(String("service", "tail"), String("name", name)),
+	}
+}
+
+func (s *Service) NewSNMPHandler() *SNMPHandler {
+	return &SNMPHandler{
+		l: s.Logger.With(String("service", "snmp")),
+	}
+}
+
 func (s *Service) NewInfluxDBHandler() *InfluxDBHandler {
 	return &InfluxDBHandler{
 		l: s.Logger.With(String("service", "influxdb")),
@@ -376,6 +522,13 @@ func (s *Service) NewDNSHandler() *ScraperHandler {
 	}
 }
 
+func (s *Service) NewDockerDiscoveryHandler() *ScraperHandler {
+	return &ScraperHandler{
+		l:   s.Logger.With(String("service", "docker-discovery")),
+		buf: bytes.NewBuffer(nil),
+	}
+}
+
 func (s *Service) NewFileDiscoveryHandler() *ScraperHandler {
 	return &ScraperHandler{
 		l:   s.Logger.With(String("service", "file-discovery")),
@@ -390,6 +543,13 @@ func (s *Service) NewGCEHandler() *ScraperHandler {
 	}
 }
 
+func (s *Service) NewHTTPDiscoveryHandler() *ScraperHandler {
+	return &ScraperHandler{
+		l:   s.Logger.With(String("service", "http-discovery")),
+		buf: bytes.NewBuffer(nil),
+	}
+}
+
 func (s *Service) NewMarathonHandler() *ScraperHandler {
 	return &ScraperHandler{
 		l:   s.Logger.With(String("service", "marathon")),
@@ -466,3 +626,15 @@ func (s *Service) NewLoadHandler() *LoadHandler {
 		l: s.Logger.With(String("service", "load")),
 	}
 }
+
+func (s *Service) NewBundleHandler() *BundleHandler {
+	return &BundleHandler{
+		l: s.Logger.With(String("service", "bundle")),
+	}
+}
+
+func (s *Service) NewGRPCHandler() *GRPCHandler {
+	return &GRPCHandler{
+		l: s.Logger.With(String("service", "grpc")),
+	}
+}
@@ -0,0 +1,105 @@
+package docker_discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/influxdata/kapacitor/tlsconfig"
+)
+
+// container is the subset of the Docker container list response used for discovery.
+type container struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	Labels map[string]string `json:"Labels"`
+	Ports  []struct {
+		PrivatePort int    `json:"PrivatePort"`
+		Type        string `json:"Type"`
+	} `json:"Ports"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress string `json:"IPAddress"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+// client lists containers using the Docker Engine API.
+type client struct {
+	url    *url.URL
+	client *http.Client
+}
+
+func newClient(c Config) (*client, error) {
+	u, err := url.Parse(c.Host)
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{}
+	base := &url.URL{Scheme: "http", Host: u.Host}
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		dialer := &net.Dialer{Timeout: 30 * time.Second}
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		// The host is ignored when dialing the socket.
+		base.Host = "docker"
+	case "tcp":
+		if c.SSLCA != "" || c.SSLCert != "" || c.SSLKey != "" {
+			t, err := tlsconfig.Create(c.SSLCA, c.SSLCert, c.SSLKey, c.InsecureSkipVerify)
+			if err != nil {
+				return nil, err
+			}
+			transport.TLSClientConfig = t
+			base.Scheme = "https"
+		}
+	default:
+		return nil, fmt.Errorf("invalid docker host scheme %q", u.Scheme)
+	}
+	return &client{
+		url: base,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Second,
+		},
+	}, nil
+}
+
+// Containers returns the running containers having all of the labels.
+func (c *client) Containers(ctx context.Context, labels []string) ([]container, error) {
+	u := *c.url
+	u.Path = "/containers/json"
+	if len(labels) > 0 {
+		filters, err := json.Marshal(map[string][]string{"label": labels})
+		if err != nil {
+			return nil, err
+		}
+		u.RawQuery = url.Values{"filters": []string{string(filters)}}.Encode()
+	}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to list docker containers, status %d: %s", resp.StatusCode, body)
+	}
+	var containers []container
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("failed to decode docker containers: %v", err)
+	}
+	return containers, nil
+}
@@ -0,0 +1,95 @@
+package docker_discovery
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+)
+
+// Config is a Docker container discovery configuration
+type Config struct {
+	Enabled bool   `toml:"enabled" override:"enabled"`
+	ID      string `toml:"id" override:"id"`
+	// Host is the address of the Docker daemon, either unix:///path/to/docker.sock or tcp://host:port.
+	Host string `toml:"host" override:"host"`
+	// Labels filters the containers by label, either key or key=value.
+	Labels []string `toml:"labels" override:"labels"`
+	// Network is the name of the container network used to reach the containers.
+	// If empty the first network with an IP address is used.
+	Network string `toml:"network" override:"network"`
+	// Port is the port scraped if the container does not have a port label.
+	// If zero the only exposed port of the container is used.
+	Port int `toml:"port" override:"port"`
+	// PortLabel is the container label overriding the scraped port.
+	PortLabel string `toml:"port-label" override:"port-label"`
+	// PathLabel is the container label overriding the metrics path of the scraper.
+	PathLabel       string        `toml:"path-label" override:"path-label"`
+	RefreshInterval toml.Duration `toml:"refresh-interval" override:"refresh-interval"`
+	// Path to CA file
+	SSLCA string `toml:"ssl-ca" override:"ssl-ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl-cert" override:"ssl-cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl-key" override:"ssl-key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool `toml:"insecure-skip-verify" override:"insecure-skip-verify"`
+}
+
+// DefaultRefreshInterval is used if the refresh interval is not set.
+const DefaultRefreshInterval = 30 * time.Second
+
+// Init adds default values to Docker configuration
+func (c *Config) Init() {
+	c.Host = "unix:///var/run/docker.sock"
+	c.PortLabel = "kapacitor.scrape.port"
+	c.PathLabel = "kapacitor.scrape.path"
+	c.RefreshInterval = toml.Duration(DefaultRefreshInterval)
+}
+
+// Validate validates Docker configuration's values
+func (c Config) Validate() error {
+	if c.ID == "" {
+		return fmt.Errorf("docker discovery must be given a ID")
+	}
+	u, err := url.Parse(c.Host)
+	if err != nil {
+		return fmt.Errorf("invalid docker host %q: %v", c.Host, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		if u.Path == "" {
+			return fmt.Errorf("docker host %q must specify a socket path", c.Host)
+		}
+	case "tcp":
+		if u.Host == "" {
+			return fmt.Errorf("docker host %q must specify an address", c.Host)
+		}
+	default:
+		return fmt.Errorf("invalid docker host scheme %q, must be unix or tcp", u.Scheme)
+	}
+	for _, l := range c.Labels {
+		if strings.TrimSpace(l) == "" || strings.HasPrefix(l, "=") {
+			return fmt.Errorf("invalid docker label filter %q", l)
+		}
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("invalid docker discovery port %d", c.Port)
+	}
+	if c.RefreshInterval < 0 {
+		return fmt.Errorf("docker discovery refresh-interval must not be negative")
+	}
+	return nil
+}
+
+// Service return discoverer type
+func (c Config) Service() string {
+	return "docker-discovery"
+}
+
+// ServiceID returns the discoverers name
+func (c Config) ServiceID() string {
+	return c.ID
+}
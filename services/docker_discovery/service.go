@@ -0,0 +1,342 @@
+package docker_discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor/services/scraper"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/util/strutil"
+)
+
+const (
+	// containerLabelPrefix is the prefix of the tags holding the container labels.
+	containerLabelPrefix = "container_label_"
+	containerNameLabel   = "container_name"
+	containerImageLabel  = "container_image"
+)
+
+type Diagnostic scraper.Diagnostic
+
+// Service is the Docker container discovery service
+type Service struct {
+	Configs []Config
+	mu      sync.Mutex
+
+	registry    scraper.Registry
+	discoverers []*discoverer
+
+	diag Diagnostic
+	open bool
+}
+
+// NewService creates a new unopened service
+func NewService(c []Config, r scraper.Registry, d Diagnostic) *Service {
+	return &Service{
+		Configs:  c,
+		registry: r,
+		diag:     d,
+	}
+}
+
+// Open starts the service
+func (s *Service) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.open {
+		return nil
+	}
+
+	s.open = true
+	if err := s.register(); err != nil {
+		return err
+	}
+
+	return s.registry.Commit()
+}
+
+// Close stops the service
+func (s *Service) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.open {
+		return nil
+	}
+
+	s.open = false
+	s.deregister()
+
+	return s.registry.Commit()
+}
+
+func (s *Service) deregister() {
+	// Stop discovering and remove all the configurations in the registry
+	for _, d := range s.discoverers {
+		d.stop()
+		s.registry.RemoveDiscoverer(d)
+	}
+	s.discoverers = nil
+}
+
+func (s *Service) register() error {
+	// Add all configurations to registry and start discovering containers
+	for _, c := range s.Configs {
+		if !c.Enabled {
+			continue
+		}
+		d, err := newDiscoverer(c, s.registry, s.diag)
+		if err != nil {
+			return fmt.Errorf("failed to create docker discoverer %q: %v", c.ID, err)
+		}
+		s.registry.AddDiscoverer(d)
+		s.discoverers = append(s.discoverers, d)
+		d.start()
+	}
+	return nil
+}
+
+// Update updates configuration while running
+func (s *Service) Update(newConfigs []interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	configs := make([]Config, len(newConfigs))
+	for i, c := range newConfigs {
+		if config, ok := c.(Config); ok {
+			configs[i] = config
+		} else {
+			return fmt.Errorf("unexpected config object type, got %T exp %T", c, config)
+		}
+	}
+
+	s.deregister()
+	s.Configs = configs
+	if s.open {
+		if err := s.register(); err != nil {
+			return err
+		}
+	}
+
+	return s.registry.Commit()
+}
+
+type testOptions struct {
+	ID string `json:"id"`
+}
+
+// TestOptions returns an object that is in turn passed to Test.
+func (s *Service) TestOptions() interface{} {
+	return &testOptions{}
+}
+
+// Test a service with the provided options.
+func (s *Service) Test(options interface{}) error {
+	o, ok := options.(*testOptions)
+	if !ok {
+		return fmt.Errorf("unexpected options type %T", options)
+	}
+
+	found := -1
+	for i := range s.Configs {
+		if s.Configs[i].ID == o.ID && s.Configs[i].Enabled {
+			found = i
+		}
+	}
+	if found < 0 {
+		return fmt.Errorf("discoverer %q is not enabled or does not exist", o.ID)
+	}
+
+	c := s.Configs[found]
+	cli, err := newClient(c)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err = cli.Containers(ctx, c.Labels)
+	return err
+}
+
+// discoverer periodically lists the containers and commits the registry
+// when the discovered targets change.
+type discoverer struct {
+	Config
+
+	client   *client
+	registry scraper.Registry
+	diag     Diagnostic
+
+	mu    sync.Mutex
+	group *config.TargetGroup
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newDiscoverer(c Config, r scraper.Registry, d Diagnostic) (*discoverer, error) {
+	cli, err := newClient(c)
+	if err != nil {
+		return nil, err
+	}
+	return &discoverer{
+		Config:   c,
+		client:   cli,
+		registry: r,
+		diag:     d,
+		group:    &config.TargetGroup{Source: c.ID},
+	}, nil
+}
+
+// Prom writes the discovered containers as static targets into ScrapeConfig
+func (d *discoverer) Prom(c *config.ScrapeConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c.ServiceDiscoveryConfig.StaticConfigs = []*config.TargetGroup{d.group}
+}
+
+func (d *discoverer) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.run(ctx)
+	}()
+}
+
+func (d *discoverer) stop() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+func (d *discoverer) run(ctx context.Context) {
+	interval := time.Duration(d.RefreshInterval)
+	if interval == 0 {
+		interval = DefaultRefreshInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		d.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *discoverer) refresh(ctx context.Context) {
+	containers, err := d.client.Containers(ctx, d.Labels)
+	if err != nil {
+		if ctx.Err() == nil {
+			d.diag.With("discoverer", d.ID).Errorf("failed to list docker containers: %v", err)
+		}
+		return
+	}
+	group := d.targetGroup(containers)
+
+	d.mu.Lock()
+	changed := !reflect.DeepEqual(d.group, group)
+	d.group = group
+	d.mu.Unlock()
+
+	if changed {
+		if err := d.registry.Commit(); err != nil {
+			d.diag.With("discoverer", d.ID).Errorf("failed to update scrapers: %v", err)
+		}
+	}
+}
+
+// targetGroup converts the containers into scrape targets.
+// Containers without a reachable address or port are skipped.
+func (d *discoverer) targetGroup(containers []container) *config.TargetGroup {
+	group := &config.TargetGroup{
+		Source:  d.ID,
+		Targets: []model.LabelSet{},
+	}
+	for _, c := range containers {
+		ip := d.address(c)
+		port := d.port(c)
+		if ip == "" || port == 0 {
+			continue
+		}
+		target := model.LabelSet{
+			model.AddressLabel:  model.LabelValue(net.JoinHostPort(ip, strconv.Itoa(port))),
+			containerImageLabel: model.LabelValue(c.Image),
+		}
+		if len(c.Names) > 0 {
+			target[containerNameLabel] = model.LabelValue(strings.TrimPrefix(c.Names[0], "/"))
+		}
+		if path := c.Labels[d.PathLabel]; d.PathLabel != "" && path != "" {
+			target[model.MetricsPathLabel] = model.LabelValue(path)
+		}
+		for k, v := range c.Labels {
+			name := containerLabelPrefix + strutil.SanitizeLabelName(k)
+			target[model.LabelName(name)] = model.LabelValue(v)
+		}
+		group.Targets = append(group.Targets, target)
+	}
+	sort.Slice(group.Targets, func(i, j int) bool {
+		return group.Targets[i][model.AddressLabel] < group.Targets[j][model.AddressLabel]
+	})
+	return group
+}
+
+// address returns the IP address of the container on the configured network
+// or the first network with an address.
+func (d *discoverer) address(c container) string {
+	networks := c.NetworkSettings.Networks
+	if d.Network != "" {
+		return networks[d.Network].IPAddress
+	}
+	names := make([]string, 0, len(networks))
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ip := networks[name].IPAddress; ip != "" {
+			return ip
+		}
+	}
+	return ""
+}
+
+// port returns the port from the container port label, the configured port
+// or the only exposed TCP port of the container.
+func (d *discoverer) port(c container) int {
+	if v, ok := c.Labels[d.PortLabel]; ok && d.PortLabel != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p <= 0 || p > 65535 {
+			d.diag.With("discoverer", d.ID).Warnf("invalid port label %q on container %s", v, c.ID)
+			return 0
+		}
+		return p
+	}
+	if d.Port != 0 {
+		return d.Port
+	}
+	port := 0
+	for _, p := range c.Ports {
+		if p.Type != "tcp" || p.PrivatePort == port {
+			continue
+		}
+		if port != 0 {
+			// Multiple ports are exposed, the port to scrape is ambiguous.
+			return 0
+		}
+		port = p.PrivatePort
+	}
+	return port
+}
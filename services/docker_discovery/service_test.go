@@ -0,0 +1,145 @@
+package docker_discovery_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/services/docker_discovery"
+	"github.com/influxdata/kapacitor/services/scraper"
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+)
+
+const containers = `[
+	{
+		"Id": "8dfafdbc3a40",
+		"Names": ["/web"],
+		"Image": "nginx:1.13",
+		"Labels": {"kapacitor.scrape.port": "9113", "com.example.team": "frontend"},
+		"Ports": [{"PrivatePort": 80, "Type": "tcp"}],
+		"NetworkSettings": {"Networks": {"bridge": {"IPAddress": "172.17.0.2"}}}
+	},
+	{
+		"Id": "9cd87474be90",
+		"Names": ["/app"],
+		"Image": "app:latest",
+		"Labels": {"kapacitor.scrape.path": "/debug/metrics"},
+		"Ports": [{"PrivatePort": 8080, "Type": "tcp"}],
+		"NetworkSettings": {"Networks": {"bridge": {"IPAddress": "172.17.0.3"}}}
+	},
+	{
+		"Id": "3176a2479c92",
+		"Names": ["/db"],
+		"Image": "postgres:10",
+		"Labels": {},
+		"Ports": [{"PrivatePort": 5432, "Type": "tcp"}, {"PrivatePort": 9187, "Type": "tcp"}],
+		"NetworkSettings": {"Networks": {"bridge": {"IPAddress": "172.17.0.4"}}}
+	}
+]`
+
+func TestService(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/json" {
+			http.NotFound(w, r)
+			return
+		}
+		if got, exp := r.URL.Query().Get("filters"), `{"label":["kapacitor.scrape"]}`; got != exp {
+			t.Errorf("unexpected filters: got %s exp %s", got, exp)
+		}
+		w.Write([]byte(containers))
+	}))
+	defer ts.Close()
+
+	c := docker_discovery.Config{}
+	c.Init()
+	c.Enabled = true
+	c.ID = "local"
+	c.Host = "tcp://" + strings.TrimPrefix(ts.URL, "http://")
+	c.Labels = []string{"kapacitor.scrape"}
+	c.RefreshInterval = toml.Duration(time.Hour)
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &registry{commits: make(chan struct{}, 10)}
+	s := docker_discovery.NewService([]docker_discovery.Config{c}, r, log.NewNopLogger())
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Wait for the commit after the containers have been discovered.
+	for i := 0; i < 2; i++ {
+		select {
+		case <-r.commits:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for commit")
+		}
+	}
+
+	sc := &config.ScrapeConfig{}
+	r.discoverer().Prom(sc)
+	groups := sc.ServiceDiscoveryConfig.StaticConfigs
+	if len(groups) != 1 {
+		t.Fatalf("unexpected number of target groups: %d", len(groups))
+	}
+	exp := []model.LabelSet{
+		{
+			model.AddressLabel:                      "172.17.0.2:9113",
+			"container_name":                        "web",
+			"container_image":                       "nginx:1.13",
+			"container_label_com_example_team":      "frontend",
+			"container_label_kapacitor_scrape_port": "9113",
+		},
+		{
+			model.AddressLabel:                      "172.17.0.3:8080",
+			model.MetricsPathLabel:                  "/debug/metrics",
+			"container_name":                        "app",
+			"container_image":                       "app:latest",
+			"container_label_kapacitor_scrape_path": "/debug/metrics",
+		},
+	}
+	got := groups[0].Targets
+	if len(got) != len(exp) {
+		t.Fatalf("unexpected targets:\ngot %v\nexp %v", got, exp)
+	}
+	for i := range exp {
+		if !got[i].Equal(exp[i]) {
+			t.Errorf("unexpected target %d:\ngot %v\nexp %v", i, got[i], exp[i])
+		}
+	}
+}
+
+type registry struct {
+	mu          sync.Mutex
+	discoverers []scraper.Discoverer
+	commits     chan struct{}
+}
+
+func (r *registry) Commit() error {
+	r.commits <- struct{}{}
+	return nil
+}
+
+func (r *registry) AddDiscoverer(d scraper.Discoverer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.discoverers = append(r.discoverers, d)
+}
+
+func (r *registry) RemoveDiscoverer(scraper.Discoverer) {}
+func (r *registry) AddScrapers([]scraper.Config)        {}
+func (r *registry) RemoveScrapers([]scraper.Config)     {}
+func (r *registry) Pairs() []scraper.Pair               { return nil }
+
+func (r *registry) discoverer() scraper.Discoverer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.discoverers[0]
+}
@@ -0,0 +1,86 @@
+package graphite_pickle
+
+import (
+	"github.com/influxdata/influxdb/services/graphite"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultBindAddress is the default address carbon clients send pickles to.
+	DefaultBindAddress = ":2004"
+
+	// DefaultSeparator is the separator used to join template matched name parts.
+	DefaultSeparator = "."
+
+	// DefaultMaxMessageSize is the largest pickle accepted, larger messages close the connection.
+	DefaultMaxMessageSize = 1 << 20
+
+	// DefaultMaxConnections is the maximum number of concurrent connections.
+	DefaultMaxConnections = 250
+)
+
+type Config struct {
+	Enabled     bool   `toml:"enabled"`
+	BindAddress string `toml:"bind-address"`
+
+	Database        string `toml:"database"`
+	RetentionPolicy string `toml:"retention-policy"`
+
+	// Templates map metric names to measurements, tags and fields
+	// using the same syntax as the graphite plaintext listener.
+	Templates []string `toml:"templates"`
+	// Tags are default tags added to all points, i.e. region=us-west.
+	Tags      []string `toml:"tags"`
+	Separator string   `toml:"separator"`
+
+	MaxMessageSize int `toml:"max-message-size"`
+	MaxConnections int `toml:"max-connections"`
+}
+
+func NewConfig() Config {
+	return Config{
+		BindAddress:    DefaultBindAddress,
+		Separator:      DefaultSeparator,
+		MaxMessageSize: DefaultMaxMessageSize,
+		MaxConnections: DefaultMaxConnections,
+	}
+}
+
+// WithDefaults takes the given config and returns a new config with any required
+// default values set.
+func (c Config) WithDefaults() Config {
+	d := c
+	if d.BindAddress == "" {
+		d.BindAddress = DefaultBindAddress
+	}
+	if d.Separator == "" {
+		d.Separator = DefaultSeparator
+	}
+	if d.MaxMessageSize <= 0 {
+		d.MaxMessageSize = DefaultMaxMessageSize
+	}
+	if d.MaxConnections <= 0 {
+		d.MaxConnections = DefaultMaxConnections
+	}
+	return d
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Database == "" {
+		return errors.New("must specify a database")
+	}
+	return c.graphiteConfig().Validate()
+}
+
+// graphiteConfig returns the plaintext listener configuration sharing the templates and tags,
+// so that both listeners parse metric names identically.
+func (c Config) graphiteConfig() *graphite.Config {
+	return &graphite.Config{
+		Templates: c.Templates,
+		Tags:      c.Tags,
+		Separator: c.Separator,
+	}
+}
@@ -0,0 +1,283 @@
+package graphite_pickle
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/graphite"
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/server/vars"
+)
+
+// statistics gathered by the graphite pickle listener.
+const (
+	statMessagesReceived  = "messages_rx"
+	statBytesReceived     = "bytes_rx"
+	statPointsParseFail   = "points_parse_fail"
+	statReadFail          = "read_fail"
+	statConnections       = "connections"
+	statRejected          = "connections_rejected"
+	statPointsTransmitted = "points_tx"
+	statTransmitFail      = "tx_fail"
+)
+
+type Diagnostic interface {
+	Error(msg string, err error, ctx ...keyvalue.T)
+	StartedListening(addr string)
+	ClosedService()
+}
+
+// Service accepts metrics sent with the carbon pickle protocol.
+// Each message is a 4 byte big endian length followed by a pickled
+// list of (path, (timestamp, value)) tuples.
+type Service struct {
+	config Config
+	parser *graphite.Parser
+
+	listener net.Listener
+	wg       sync.WaitGroup
+	done     chan struct{}
+
+	connMu sync.Mutex
+	conns  map[net.Conn]bool
+
+	PointsWriter interface {
+		WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+	}
+
+	Diag    Diagnostic
+	statMap *expvar.Map
+	statKey string
+}
+
+func NewService(c Config, diag Diagnostic) (*Service, error) {
+	d := c.WithDefaults()
+	gc := d.graphiteConfig()
+	parser, err := graphite.NewParserWithOptions(graphite.Options{
+		Separator:   gc.Separator,
+		Templates:   gc.Templates,
+		DefaultTags: gc.DefaultTags(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Service{
+		config: d,
+		parser: parser,
+		conns:  make(map[net.Conn]bool),
+		Diag:   diag,
+	}, nil
+}
+
+func (s *Service) Open() (err error) {
+	if s.config.Database == "" {
+		return errors.New("database has to be specified in config")
+	}
+	s.listener, err = net.Listen("tcp", s.config.BindAddress)
+	if err != nil {
+		s.Diag.Error("failed to set up TCP listener at address", err, keyvalue.KV("address", s.config.BindAddress))
+		return err
+	}
+
+	tags := map[string]string{"bind": s.listener.Addr().String()}
+	s.statKey, s.statMap = vars.NewStatistic("graphite_pickle", tags)
+
+	s.Diag.StartedListening(s.listener.Addr().String())
+
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go s.serve()
+	return nil
+}
+
+func (s *Service) Close() error {
+	if s.listener == nil {
+		return errors.New("Service already closed")
+	}
+	close(s.done)
+	s.listener.Close()
+	s.connMu.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.connMu.Unlock()
+	s.wg.Wait()
+
+	vars.DeleteStatistic(s.statKey)
+	s.listener = nil
+	s.done = nil
+
+	s.Diag.ClosedService()
+	return nil
+}
+
+// Addr returns the address the service is listening on.
+func (s *Service) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *Service) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			s.statMap.Add(statReadFail, 1)
+			s.Diag.Error("failed to accept TCP connection", err)
+			continue
+		}
+		s.connMu.Lock()
+		if len(s.conns) >= s.config.MaxConnections {
+			s.connMu.Unlock()
+			s.statMap.Add(statRejected, 1)
+			conn.Close()
+			continue
+		}
+		s.conns[conn] = true
+		s.connMu.Unlock()
+		s.statMap.Add(statConnections, 1)
+
+		s.wg.Add(1)
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Service) handleConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.connMu.Lock()
+		delete(s.conns, conn)
+		s.connMu.Unlock()
+		s.statMap.Add(statConnections, -1)
+		conn.Close()
+	}()
+
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(conn, header[:]); err != nil {
+			if err != io.EOF {
+				s.readFailed(err)
+			}
+			return
+		}
+		size := binary.BigEndian.Uint32(header[:])
+		if int64(size) > int64(s.config.MaxMessageSize) {
+			s.readFailed(fmt.Errorf("message size %d exceeds max-message-size %d", size, s.config.MaxMessageSize))
+			return
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(conn, data); err != nil {
+			s.readFailed(err)
+			return
+		}
+		s.statMap.Add(statBytesReceived, int64(len(data)+len(header)))
+		s.statMap.Add(statMessagesReceived, 1)
+		s.handleMessage(data)
+	}
+}
+
+func (s *Service) readFailed(err error) {
+	select {
+	case <-s.done:
+		// Connections are closed on shutdown, do not report them.
+		return
+	default:
+	}
+	s.statMap.Add(statReadFail, 1)
+	s.Diag.Error("failed to read pickle message", err)
+}
+
+func (s *Service) handleMessage(data []byte) {
+	v, err := unpickle(data)
+	if err != nil {
+		s.statMap.Add(statPointsParseFail, 1)
+		s.Diag.Error("failed to decode pickle message", err)
+		return
+	}
+	metrics, ok := v.([]interface{})
+	if !ok {
+		s.statMap.Add(statPointsParseFail, 1)
+		s.Diag.Error("failed to decode pickle message", fmt.Errorf("expected list of metrics, got %T", v))
+		return
+	}
+	points := make([]models.Point, 0, len(metrics))
+	for _, m := range metrics {
+		line, err := metricLine(m)
+		if err == nil {
+			var p models.Point
+			p, err = s.parser.Parse(line)
+			if err == nil {
+				points = append(points, p)
+				continue
+			}
+		}
+		s.statMap.Add(statPointsParseFail, 1)
+		s.Diag.Error("failed to parse metric", err)
+	}
+	if len(points) == 0 {
+		return
+	}
+	if err := s.PointsWriter.WritePoints(
+		s.config.Database,
+		s.config.RetentionPolicy,
+		models.ConsistencyLevelAll,
+		points,
+	); err != nil {
+		s.Diag.Error("failed to write points to database", err, keyvalue.KV("database", s.config.Database))
+		s.statMap.Add(statTransmitFail, 1)
+		return
+	}
+	s.statMap.Add(statPointsTransmitted, int64(len(points)))
+}
+
+// metricLine converts a (path, (timestamp, value)) tuple into a plaintext graphite line.
+func metricLine(m interface{}) (string, error) {
+	t, ok := m.([]interface{})
+	if !ok || len(t) != 2 {
+		return "", fmt.Errorf("invalid metric %v", m)
+	}
+	path, ok := t[0].(string)
+	if !ok {
+		return "", fmt.Errorf("invalid metric path %v", t[0])
+	}
+	dp, ok := t[1].([]interface{})
+	if !ok || len(dp) != 2 {
+		return "", fmt.Errorf("invalid datapoint for %q", path)
+	}
+	ts, err := number(dp[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid timestamp for %q: %v", path, err)
+	}
+	value, err := number(dp[1])
+	if err != nil {
+		return "", fmt.Errorf("invalid value for %q: %v", path, err)
+	}
+	return path + " " + value + " " + ts, nil
+}
+
+func number(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case string:
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return "", err
+		}
+		return v, nil
+	default:
+		return "", fmt.Errorf("unexpected type %T", v)
+	}
+}
@@ -0,0 +1,96 @@
+package graphite_pickle_test
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/services/graphite_pickle"
+)
+
+// Pickles of [('servers.host1.cpu.load', (1500000000, 1.5)), ('servers.host2.cpu.load', (1500000000.0, 2))]
+// as created by Python for each protocol.
+var pickles = map[string]string{
+	"protocol0": "286c70300a2856736572766572732e686f7374312e6370752e6c6f61640a70310a2849313530303030303030300a46312e350a7470320a7470330a612856736572766572732e686f7374322e6370752e6c6f61640a70340a2846313530303030303030302e300a49320a7470350a7470360a612e",
+	"protocol2": "80025d7100285816000000736572766572732e686f7374312e6370752e6c6f616471014a002f6859473ff80000000000008671028671035816000000736572766572732e686f7374322e6370752e6c6f616471044741d65a0bc00000004b02867105867106652e",
+	"protocol4": "80049558000000000000005d94288c16736572766572732e686f7374312e6370752e6c6f6164944a002f6859473ff8000000000000869486948c16736572766572732e686f7374322e6370752e6c6f6164944741d65a0bc00000004b0286948694652e",
+}
+
+func TestService(t *testing.T) {
+	for name, pickle := range pickles {
+		t.Run(name, func(t *testing.T) {
+			c := graphite_pickle.NewConfig()
+			c.Enabled = true
+			c.BindAddress = "127.0.0.1:0"
+			c.Database = "db"
+			c.Templates = []string{"servers.* .host.measurement.field"}
+			c.Tags = []string{"dc=west"}
+			if err := c.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			pw := &pointsWriter{points: make(chan []models.Point, 1)}
+			s, err := graphite_pickle.NewService(c, diag{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.PointsWriter = pw
+			if err := s.Open(); err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			data, err := hex.DecodeString(pickle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, err := net.Dial("tcp", s.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			var header [4]byte
+			binary.BigEndian.PutUint32(header[:], uint32(len(data)))
+			if _, err := conn.Write(append(header[:], data...)); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case points := <-pw.points:
+				exp := []string{
+					"cpu,dc=west,host=host1 load=1.5 1500000000000000000",
+					"cpu,dc=west,host=host2 load=2 1500000000000000000",
+				}
+				if len(points) != len(exp) {
+					t.Fatalf("unexpected number of points: got %d exp %d", len(points), len(exp))
+				}
+				for i, p := range points {
+					if got := p.String(); got != exp[i] {
+						t.Errorf("unexpected point %d:\ngot %s\nexp %s", i, got, exp[i])
+					}
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for points")
+			}
+		})
+	}
+}
+
+type pointsWriter struct {
+	points chan []models.Point
+}
+
+func (pw *pointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	pw.points <- points
+	return nil
+}
+
+type diag struct{}
+
+func (diag) Error(msg string, err error, ctx ...keyvalue.T) {}
+func (diag) StartedListening(addr string)                   {}
+func (diag) ClosedService()                                 {}
@@ -0,0 +1,365 @@
+package graphite_pickle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// mark is pushed on the stack by the MARK opcode.
+type mark struct{}
+
+// unpickle decodes a Python pickle containing only the primitive types
+// sent by carbon clients, i.e. lists and tuples of strings and numbers.
+// Protocols 0 through 4 are supported.
+func unpickle(data []byte) (interface{}, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+	var stack []interface{}
+	memo := make(map[int]interface{})
+
+	pop := func() (interface{}, error) {
+		if len(stack) == 0 {
+			return nil, errors.New("stack underflow")
+		}
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v, nil
+	}
+	// popMark returns the items pushed since the last mark.
+	popMark := func() ([]interface{}, error) {
+		for i := len(stack) - 1; i >= 0; i-- {
+			if _, ok := stack[i].(mark); ok {
+				items := append([]interface{}{}, stack[i+1:]...)
+				stack = stack[:i]
+				return items, nil
+			}
+		}
+		return nil, errors.New("mark not found")
+	}
+	top := func() (*[]interface{}, error) {
+		if len(stack) == 0 {
+			return nil, errors.New("stack underflow")
+		}
+		l, ok := stack[len(stack)-1].(*[]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected list, got %T", stack[len(stack)-1])
+		}
+		return l, nil
+	}
+	readN := func(n int) ([]byte, error) {
+		if n < 0 || n > len(data) {
+			return nil, errors.New("invalid length")
+		}
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	}
+	readUint := func(n int) (int, error) {
+		b, err := readN(n)
+		if err != nil {
+			return 0, err
+		}
+		v := 0
+		for i := n - 1; i >= 0; i-- {
+			v = v<<8 | int(b[i])
+		}
+		return v, nil
+	}
+	readLine := func() (string, error) {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(line, "\n"), nil
+	}
+
+	for {
+		op, err := r.ReadByte()
+		if err != nil {
+			return nil, errors.New("unexpected end of pickle")
+		}
+		switch op {
+		case '.': // STOP
+			v, err := pop()
+			if err != nil {
+				return nil, err
+			}
+			return resolveLists(v), nil
+		case 0x80: // PROTO
+			if _, err := r.ReadByte(); err != nil {
+				return nil, err
+			}
+		case 0x95: // FRAME
+			if _, err := readN(8); err != nil {
+				return nil, err
+			}
+		case '(': // MARK
+			stack = append(stack, mark{})
+		case ']': // EMPTY_LIST
+			stack = append(stack, &[]interface{}{})
+		case 'l': // LIST
+			items, err := popMark()
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, &items)
+		case ')': // EMPTY_TUPLE
+			stack = append(stack, []interface{}{})
+		case 't': // TUPLE
+			items, err := popMark()
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, items)
+		case 0x85, 0x86, 0x87: // TUPLE1, TUPLE2, TUPLE3
+			n := int(op - 0x84)
+			if len(stack) < n {
+				return nil, errors.New("stack underflow")
+			}
+			items := append([]interface{}{}, stack[len(stack)-n:]...)
+			stack = append(stack[:len(stack)-n], items)
+		case 'a': // APPEND
+			v, err := pop()
+			if err != nil {
+				return nil, err
+			}
+			l, err := top()
+			if err != nil {
+				return nil, err
+			}
+			*l = append(*l, v)
+		case 'e': // APPENDS
+			items, err := popMark()
+			if err != nil {
+				return nil, err
+			}
+			l, err := top()
+			if err != nil {
+				return nil, err
+			}
+			*l = append(*l, items...)
+		case 'N': // NONE
+			stack = append(stack, nil)
+		case 0x88: // NEWTRUE
+			stack = append(stack, true)
+		case 0x89: // NEWFALSE
+			stack = append(stack, false)
+		case 'I': // INT
+			line, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			switch line {
+			case "00":
+				stack = append(stack, false)
+			case "01":
+				stack = append(stack, true)
+			default:
+				i, err := strconv.ParseInt(line, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				stack = append(stack, i)
+			}
+		case 'L': // LONG
+			line, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			i, err := strconv.ParseInt(strings.TrimSuffix(line, "L"), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, i)
+		case 'J': // BININT
+			b, err := readN(4)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, int64(int32(binary.LittleEndian.Uint32(b))))
+		case 'K': // BININT1
+			v, err := readUint(1)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, int64(v))
+		case 'M': // BININT2
+			v, err := readUint(2)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, int64(v))
+		case 0x8a: // LONG1
+			n, err := readUint(1)
+			if err != nil {
+				return nil, err
+			}
+			b, err := readN(n)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, decodeLong(b))
+		case 'F': // FLOAT
+			line, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			f, err := strconv.ParseFloat(line, 64)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, f)
+		case 'G': // BINFLOAT
+			b, err := readN(8)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, math.Float64frombits(binary.BigEndian.Uint64(b)))
+		case 'S': // STRING
+			line, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			s, err := strconv.Unquote(line)
+			if err != nil {
+				// Python may quote strings with single quotes.
+				if len(line) >= 2 && line[0] == '\'' && line[len(line)-1] == '\'' {
+					s = line[1 : len(line)-1]
+				} else {
+					return nil, err
+				}
+			}
+			stack = append(stack, s)
+		case 'V': // UNICODE
+			line, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, line)
+		case 'U', 0x8c, 'T', 'X', 'B', 'C', 0x8d, 0x8e: // SHORT_BINSTRING, SHORT_BINUNICODE, BINSTRING, BINUNICODE, BINBYTES, SHORT_BINBYTES, BINUNICODE8, BINBYTES8
+			size := 4
+			switch op {
+			case 'U', 0x8c, 'C':
+				size = 1
+			case 0x8d, 0x8e:
+				size = 8
+			}
+			n, err := readUint(size)
+			if err != nil {
+				return nil, err
+			}
+			b, err := readN(n)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, string(b))
+		case 'p': // PUT
+			line, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			i, err := strconv.Atoi(line)
+			if err != nil {
+				return nil, err
+			}
+			if len(stack) == 0 {
+				return nil, errors.New("stack underflow")
+			}
+			memo[i] = stack[len(stack)-1]
+		case 'q', 'r': // BINPUT, LONG_BINPUT
+			size := 1
+			if op == 'r' {
+				size = 4
+			}
+			i, err := readUint(size)
+			if err != nil {
+				return nil, err
+			}
+			if len(stack) == 0 {
+				return nil, errors.New("stack underflow")
+			}
+			memo[i] = stack[len(stack)-1]
+		case 0x94: // MEMOIZE
+			if len(stack) == 0 {
+				return nil, errors.New("stack underflow")
+			}
+			memo[len(memo)] = stack[len(stack)-1]
+		case 'g': // GET
+			line, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			i, err := strconv.Atoi(line)
+			if err != nil {
+				return nil, err
+			}
+			v, ok := memo[i]
+			if !ok {
+				return nil, fmt.Errorf("memo %d not found", i)
+			}
+			stack = append(stack, v)
+		case 'h', 'j': // BINGET, LONG_BINGET
+			size := 1
+			if op == 'j' {
+				size = 4
+			}
+			i, err := readUint(size)
+			if err != nil {
+				return nil, err
+			}
+			v, ok := memo[i]
+			if !ok {
+				return nil, fmt.Errorf("memo %d not found", i)
+			}
+			stack = append(stack, v)
+		default:
+			return nil, fmt.Errorf("unsupported pickle opcode 0x%x", op)
+		}
+	}
+}
+
+// decodeLong decodes a little endian two's complement integer.
+func decodeLong(b []byte) interface{} {
+	if len(b) == 0 {
+		return int64(0)
+	}
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	v := new(big.Int).SetBytes(be)
+	if b[len(b)-1]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	if v.IsInt64() {
+		return v.Int64()
+	}
+	f, _ := new(big.Float).SetInt(v).Float64()
+	return f
+}
+
+// resolveLists replaces list pointers, used while building lists, with the lists themselves.
+func resolveLists(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *[]interface{}:
+		out := make([]interface{}, len(*v))
+		for i, e := range *v {
+			out[i] = resolveLists(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = resolveLists(e)
+		}
+		return out
+	}
+	return v
+}
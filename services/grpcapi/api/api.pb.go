@@ -0,0 +1,2369 @@
+// Code generated by protoc-gen-go.
+// source: api.proto
+// DO NOT EDIT!
+
+/*
+Package api is a generated protocol buffer package.
+
+It is generated from these files:
+	api.proto
+
+It has these top-level messages:
+	Empty
+	DBRP
+	Var
+	Task
+	ListTasksRequest
+	ListTasksResponse
+	GetTaskRequest
+	CreateTaskRequest
+	UpdateTaskRequest
+	DeleteTaskRequest
+	WatchTasksRequest
+	TaskEvent
+	Recording
+	ListRecordingsRequest
+	ListRecordingsResponse
+	GetRecordingRequest
+	RecordStreamRequest
+	RecordBatchRequest
+	RecordQueryRequest
+	DeleteRecordingRequest
+	Replay
+	ListReplaysRequest
+	ListReplaysResponse
+	GetReplayRequest
+	CreateReplayRequest
+	DeleteReplayRequest
+	Topic
+	ListTopicsRequest
+	ListTopicsResponse
+	GetTopicRequest
+	DeleteTopicRequest
+	EventState
+	TopicEvent
+	ListTopicEventsRequest
+	ListTopicEventsResponse
+	WatchTopicEventsRequest
+*/
+package api
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type TaskType int32
+
+const (
+	TaskType_INVALID_TASK TaskType = 0
+	TaskType_STREAM       TaskType = 1
+	TaskType_BATCH        TaskType = 2
+)
+
+var TaskType_name = map[int32]string{
+	0: "INVALID_TASK",
+	1: "STREAM",
+	2: "BATCH",
+}
+var TaskType_value = map[string]int32{
+	"INVALID_TASK": 0,
+	"STREAM":       1,
+	"BATCH":        2,
+}
+
+func (x TaskType) String() string {
+	return proto.EnumName(TaskType_name, int32(x))
+}
+func (TaskType) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+type TaskStatus int32
+
+const (
+	TaskStatus_UNKNOWN_STATUS TaskStatus = 0
+	TaskStatus_DISABLED       TaskStatus = 1
+	TaskStatus_ENABLED        TaskStatus = 2
+)
+
+var TaskStatus_name = map[int32]string{
+	0: "UNKNOWN_STATUS",
+	1: "DISABLED",
+	2: "ENABLED",
+}
+var TaskStatus_value = map[string]int32{
+	"UNKNOWN_STATUS": 0,
+	"DISABLED":       1,
+	"ENABLED":        2,
+}
+
+func (x TaskStatus) String() string {
+	return proto.EnumName(TaskStatus_name, int32(x))
+}
+func (TaskStatus) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+type VarType int32
+
+const (
+	VarType_UNKNOWN_VAR VarType = 0
+	VarType_BOOL        VarType = 1
+	VarType_INT         VarType = 2
+	VarType_FLOAT       VarType = 3
+	VarType_STRING      VarType = 4
+	VarType_REGEX       VarType = 5
+	VarType_DURATION    VarType = 6
+	VarType_LAMBDA      VarType = 7
+	VarType_LIST        VarType = 8
+	VarType_STAR        VarType = 9
+)
+
+var VarType_name = map[int32]string{
+	0: "UNKNOWN_VAR",
+	1: "BOOL",
+	2: "INT",
+	3: "FLOAT",
+	4: "STRING",
+	5: "REGEX",
+	6: "DURATION",
+	7: "LAMBDA",
+	8: "LIST",
+	9: "STAR",
+}
+var VarType_value = map[string]int32{
+	"UNKNOWN_VAR": 0,
+	"BOOL":        1,
+	"INT":         2,
+	"FLOAT":       3,
+	"STRING":      4,
+	"REGEX":       5,
+	"DURATION":    6,
+	"LAMBDA":      7,
+	"LIST":        8,
+	"STAR":        9,
+}
+
+func (x VarType) String() string {
+	return proto.EnumName(VarType_name, int32(x))
+}
+func (VarType) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+type Status int32
+
+const (
+	Status_FAILED   Status = 0
+	Status_RUNNING  Status = 1
+	Status_FINISHED Status = 2
+)
+
+var Status_name = map[int32]string{
+	0: "FAILED",
+	1: "RUNNING",
+	2: "FINISHED",
+}
+var Status_value = map[string]int32{
+	"FAILED":   0,
+	"RUNNING":  1,
+	"FINISHED": 2,
+}
+
+func (x Status) String() string {
+	return proto.EnumName(Status_name, int32(x))
+}
+func (Status) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+type Clock int32
+
+const (
+	Clock_FAST Clock = 0
+	Clock_REAL Clock = 1
+)
+
+var Clock_name = map[int32]string{
+	0: "FAST",
+	1: "REAL",
+}
+var Clock_value = map[string]int32{
+	"FAST": 0,
+	"REAL": 1,
+}
+
+func (x Clock) String() string {
+	return proto.EnumName(Clock_name, int32(x))
+}
+func (Clock) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+type Level int32
+
+const (
+	Level_OK       Level = 0
+	Level_INFO     Level = 1
+	Level_WARNING  Level = 2
+	Level_CRITICAL Level = 3
+)
+
+var Level_name = map[int32]string{
+	0: "OK",
+	1: "INFO",
+	2: "WARNING",
+	3: "CRITICAL",
+}
+var Level_value = map[string]int32{
+	"OK":       0,
+	"INFO":     1,
+	"WARNING":  2,
+	"CRITICAL": 3,
+}
+
+func (x Level) String() string {
+	return proto.EnumName(Level_name, int32(x))
+}
+func (Level) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+type Empty struct {
+}
+
+func (m *Empty) Reset()                    { *m = Empty{} }
+func (m *Empty) String() string            { return proto.CompactTextString(m) }
+func (*Empty) ProtoMessage()               {}
+func (*Empty) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+type DBRP struct {
+	Database        string `protobuf:"bytes,1,opt,name=database" json:"database,omitempty"`
+	RetentionPolicy string `protobuf:"bytes,2,opt,name=retention_policy,json=retentionPolicy" json:"retention_policy,omitempty"`
+}
+
+func (m *DBRP) Reset()                    { *m = DBRP{} }
+func (m *DBRP) String() string            { return proto.CompactTextString(m) }
+func (*DBRP) ProtoMessage()               {}
+func (*DBRP) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *DBRP) GetDatabase() string {
+	if m != nil {
+		return m.Database
+	}
+	return ""
+}
+
+func (m *DBRP) GetRetentionPolicy() string {
+	if m != nil {
+		return m.RetentionPolicy
+	}
+	return ""
+}
+
+// A template variable.
+// The value is set in the field matching the type,
+// durations are set in int_value and
+// regexes and lambdas are set in string_value.
+type Var struct {
+	Type        VarType `protobuf:"varint,1,opt,name=type,enum=api.VarType" json:"type,omitempty"`
+	BoolValue   bool    `protobuf:"varint,2,opt,name=bool_value,json=boolValue" json:"bool_value,omitempty"`
+	IntValue    int64   `protobuf:"varint,3,opt,name=int_value,json=intValue" json:"int_value,omitempty"`
+	FloatValue  float64 `protobuf:"fixed64,4,opt,name=float_value,json=floatValue" json:"float_value,omitempty"`
+	StringValue string  `protobuf:"bytes,5,opt,name=string_value,json=stringValue" json:"string_value,omitempty"`
+	ListValue   []*Var  `protobuf:"bytes,6,rep,name=list_value,json=listValue" json:"list_value,omitempty"`
+	Description string  `protobuf:"bytes,7,opt,name=description" json:"description,omitempty"`
+}
+
+func (m *Var) Reset()                    { *m = Var{} }
+func (m *Var) String() string            { return proto.CompactTextString(m) }
+func (*Var) ProtoMessage()               {}
+func (*Var) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *Var) GetType() VarType {
+	if m != nil {
+		return m.Type
+	}
+	return VarType_UNKNOWN_VAR
+}
+
+func (m *Var) GetBoolValue() bool {
+	if m != nil {
+		return m.BoolValue
+	}
+	return false
+}
+
+func (m *Var) GetIntValue() int64 {
+	if m != nil {
+		return m.IntValue
+	}
+	return 0
+}
+
+func (m *Var) GetFloatValue() float64 {
+	if m != nil {
+		return m.FloatValue
+	}
+	return 0
+}
+
+func (m *Var) GetStringValue() string {
+	if m != nil {
+		return m.StringValue
+	}
+	return ""
+}
+
+func (m *Var) GetListValue() []*Var {
+	if m != nil {
+		return m.ListValue
+	}
+	return nil
+}
+
+func (m *Var) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+type Task struct {
+	Id          string          `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	TemplateId  string          `protobuf:"bytes,2,opt,name=template_id,json=templateId" json:"template_id,omitempty"`
+	Type        TaskType        `protobuf:"varint,3,opt,name=type,enum=api.TaskType" json:"type,omitempty"`
+	Dbrps       []*DBRP         `protobuf:"bytes,4,rep,name=dbrps" json:"dbrps,omitempty"`
+	TickScript  string          `protobuf:"bytes,5,opt,name=tick_script,json=tickScript" json:"tick_script,omitempty"`
+	Vars        map[string]*Var `protobuf:"bytes,6,rep,name=vars" json:"vars,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Dot         string          `protobuf:"bytes,7,opt,name=dot" json:"dot,omitempty"`
+	Status      TaskStatus      `protobuf:"varint,8,opt,name=status,enum=api.TaskStatus" json:"status,omitempty"`
+	Executing   bool            `protobuf:"varint,9,opt,name=executing" json:"executing,omitempty"`
+	Error       string          `protobuf:"bytes,10,opt,name=error" json:"error,omitempty"`
+	Created     int64           `protobuf:"varint,11,opt,name=created" json:"created,omitempty"`
+	Modified    int64           `protobuf:"varint,12,opt,name=modified" json:"modified,omitempty"`
+	LastEnabled int64           `protobuf:"varint,13,opt,name=last_enabled,json=lastEnabled" json:"last_enabled,omitempty"`
+}
+
+func (m *Task) Reset()                    { *m = Task{} }
+func (m *Task) String() string            { return proto.CompactTextString(m) }
+func (*Task) ProtoMessage()               {}
+func (*Task) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *Task) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Task) GetTemplateId() string {
+	if m != nil {
+		return m.TemplateId
+	}
+	return ""
+}
+
+func (m *Task) GetType() TaskType {
+	if m != nil {
+		return m.Type
+	}
+	return TaskType_INVALID_TASK
+}
+
+func (m *Task) GetDbrps() []*DBRP {
+	if m != nil {
+		return m.Dbrps
+	}
+	return nil
+}
+
+func (m *Task) GetTickScript() string {
+	if m != nil {
+		return m.TickScript
+	}
+	return ""
+}
+
+func (m *Task) GetVars() map[string]*Var {
+	if m != nil {
+		return m.Vars
+	}
+	return nil
+}
+
+func (m *Task) GetDot() string {
+	if m != nil {
+		return m.Dot
+	}
+	return ""
+}
+
+func (m *Task) GetStatus() TaskStatus {
+	if m != nil {
+		return m.Status
+	}
+	return TaskStatus_UNKNOWN_STATUS
+}
+
+func (m *Task) GetExecuting() bool {
+	if m != nil {
+		return m.Executing
+	}
+	return false
+}
+
+func (m *Task) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *Task) GetCreated() int64 {
+	if m != nil {
+		return m.Created
+	}
+	return 0
+}
+
+func (m *Task) GetModified() int64 {
+	if m != nil {
+		return m.Modified
+	}
+	return 0
+}
+
+func (m *Task) GetLastEnabled() int64 {
+	if m != nil {
+		return m.LastEnabled
+	}
+	return 0
+}
+
+type ListTasksRequest struct {
+	Pattern string `protobuf:"bytes,1,opt,name=pattern" json:"pattern,omitempty"`
+	Offset  int32  `protobuf:"varint,2,opt,name=offset" json:"offset,omitempty"`
+	// Defaults to 100.
+	Limit int32 `protobuf:"varint,3,opt,name=limit" json:"limit,omitempty"`
+}
+
+func (m *ListTasksRequest) Reset()                    { *m = ListTasksRequest{} }
+func (m *ListTasksRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListTasksRequest) ProtoMessage()               {}
+func (*ListTasksRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *ListTasksRequest) GetPattern() string {
+	if m != nil {
+		return m.Pattern
+	}
+	return ""
+}
+
+func (m *ListTasksRequest) GetOffset() int32 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *ListTasksRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type ListTasksResponse struct {
+	Tasks []*Task `protobuf:"bytes,1,rep,name=tasks" json:"tasks,omitempty"`
+}
+
+func (m *ListTasksResponse) Reset()                    { *m = ListTasksResponse{} }
+func (m *ListTasksResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListTasksResponse) ProtoMessage()               {}
+func (*ListTasksResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *ListTasksResponse) GetTasks() []*Task {
+	if m != nil {
+		return m.Tasks
+	}
+	return nil
+}
+
+type GetTaskRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *GetTaskRequest) Reset()                    { *m = GetTaskRequest{} }
+func (m *GetTaskRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetTaskRequest) ProtoMessage()               {}
+func (*GetTaskRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *GetTaskRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type CreateTaskRequest struct {
+	Id         string          `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	TemplateId string          `protobuf:"bytes,2,opt,name=template_id,json=templateId" json:"template_id,omitempty"`
+	Type       TaskType        `protobuf:"varint,3,opt,name=type,enum=api.TaskType" json:"type,omitempty"`
+	Dbrps      []*DBRP         `protobuf:"bytes,4,rep,name=dbrps" json:"dbrps,omitempty"`
+	TickScript string          `protobuf:"bytes,5,opt,name=tick_script,json=tickScript" json:"tick_script,omitempty"`
+	Vars       map[string]*Var `protobuf:"bytes,6,rep,name=vars" json:"vars,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Status     TaskStatus      `protobuf:"varint,7,opt,name=status,enum=api.TaskStatus" json:"status,omitempty"`
+}
+
+func (m *CreateTaskRequest) Reset()                    { *m = CreateTaskRequest{} }
+func (m *CreateTaskRequest) String() string            { return proto.CompactTextString(m) }
+func (*CreateTaskRequest) ProtoMessage()               {}
+func (*CreateTaskRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *CreateTaskRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *CreateTaskRequest) GetTemplateId() string {
+	if m != nil {
+		return m.TemplateId
+	}
+	return ""
+}
+
+func (m *CreateTaskRequest) GetType() TaskType {
+	if m != nil {
+		return m.Type
+	}
+	return TaskType_INVALID_TASK
+}
+
+func (m *CreateTaskRequest) GetDbrps() []*DBRP {
+	if m != nil {
+		return m.Dbrps
+	}
+	return nil
+}
+
+func (m *CreateTaskRequest) GetTickScript() string {
+	if m != nil {
+		return m.TickScript
+	}
+	return ""
+}
+
+func (m *CreateTaskRequest) GetVars() map[string]*Var {
+	if m != nil {
+		return m.Vars
+	}
+	return nil
+}
+
+func (m *CreateTaskRequest) GetStatus() TaskStatus {
+	if m != nil {
+		return m.Status
+	}
+	return TaskStatus_UNKNOWN_STATUS
+}
+
+type UpdateTaskRequest struct {
+	Id         string          `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	TemplateId string          `protobuf:"bytes,2,opt,name=template_id,json=templateId" json:"template_id,omitempty"`
+	Type       TaskType        `protobuf:"varint,3,opt,name=type,enum=api.TaskType" json:"type,omitempty"`
+	Dbrps      []*DBRP         `protobuf:"bytes,4,rep,name=dbrps" json:"dbrps,omitempty"`
+	TickScript string          `protobuf:"bytes,5,opt,name=tick_script,json=tickScript" json:"tick_script,omitempty"`
+	Vars       map[string]*Var `protobuf:"bytes,6,rep,name=vars" json:"vars,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Status     TaskStatus      `protobuf:"varint,7,opt,name=status,enum=api.TaskStatus" json:"status,omitempty"`
+}
+
+func (m *UpdateTaskRequest) Reset()                    { *m = UpdateTaskRequest{} }
+func (m *UpdateTaskRequest) String() string            { return proto.CompactTextString(m) }
+func (*UpdateTaskRequest) ProtoMessage()               {}
+func (*UpdateTaskRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *UpdateTaskRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *UpdateTaskRequest) GetTemplateId() string {
+	if m != nil {
+		return m.TemplateId
+	}
+	return ""
+}
+
+func (m *UpdateTaskRequest) GetType() TaskType {
+	if m != nil {
+		return m.Type
+	}
+	return TaskType_INVALID_TASK
+}
+
+func (m *UpdateTaskRequest) GetDbrps() []*DBRP {
+	if m != nil {
+		return m.Dbrps
+	}
+	return nil
+}
+
+func (m *UpdateTaskRequest) GetTickScript() string {
+	if m != nil {
+		return m.TickScript
+	}
+	return ""
+}
+
+func (m *UpdateTaskRequest) GetVars() map[string]*Var {
+	if m != nil {
+		return m.Vars
+	}
+	return nil
+}
+
+func (m *UpdateTaskRequest) GetStatus() TaskStatus {
+	if m != nil {
+		return m.Status
+	}
+	return TaskStatus_UNKNOWN_STATUS
+}
+
+type DeleteTaskRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *DeleteTaskRequest) Reset()                    { *m = DeleteTaskRequest{} }
+func (m *DeleteTaskRequest) String() string            { return proto.CompactTextString(m) }
+func (*DeleteTaskRequest) ProtoMessage()               {}
+func (*DeleteTaskRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *DeleteTaskRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type WatchTasksRequest struct {
+	Pattern string `protobuf:"bytes,1,opt,name=pattern" json:"pattern,omitempty"`
+}
+
+func (m *WatchTasksRequest) Reset()                    { *m = WatchTasksRequest{} }
+func (m *WatchTasksRequest) String() string            { return proto.CompactTextString(m) }
+func (*WatchTasksRequest) ProtoMessage()               {}
+func (*WatchTasksRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *WatchTasksRequest) GetPattern() string {
+	if m != nil {
+		return m.Pattern
+	}
+	return ""
+}
+
+type TaskEvent struct {
+	Task *Task `protobuf:"bytes,1,opt,name=task" json:"task,omitempty"`
+	// The task was deleted, only its ID is set.
+	Deleted bool `protobuf:"varint,2,opt,name=deleted" json:"deleted,omitempty"`
+}
+
+func (m *TaskEvent) Reset()                    { *m = TaskEvent{} }
+func (m *TaskEvent) String() string            { return proto.CompactTextString(m) }
+func (*TaskEvent) ProtoMessage()               {}
+func (*TaskEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *TaskEvent) GetTask() *Task {
+	if m != nil {
+		return m.Task
+	}
+	return nil
+}
+
+func (m *TaskEvent) GetDeleted() bool {
+	if m != nil {
+		return m.Deleted
+	}
+	return false
+}
+
+type Recording struct {
+	Id       string   `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Type     TaskType `protobuf:"varint,2,opt,name=type,enum=api.TaskType" json:"type,omitempty"`
+	Size     int64    `protobuf:"varint,3,opt,name=size" json:"size,omitempty"`
+	Date     int64    `protobuf:"varint,4,opt,name=date" json:"date,omitempty"`
+	Error    string   `protobuf:"bytes,5,opt,name=error" json:"error,omitempty"`
+	Status   Status   `protobuf:"varint,6,opt,name=status,enum=api.Status" json:"status,omitempty"`
+	Progress float64  `protobuf:"fixed64,7,opt,name=progress" json:"progress,omitempty"`
+}
+
+func (m *Recording) Reset()                    { *m = Recording{} }
+func (m *Recording) String() string            { return proto.CompactTextString(m) }
+func (*Recording) ProtoMessage()               {}
+func (*Recording) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *Recording) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Recording) GetType() TaskType {
+	if m != nil {
+		return m.Type
+	}
+	return TaskType_INVALID_TASK
+}
+
+func (m *Recording) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *Recording) GetDate() int64 {
+	if m != nil {
+		return m.Date
+	}
+	return 0
+}
+
+func (m *Recording) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *Recording) GetStatus() Status {
+	if m != nil {
+		return m.Status
+	}
+	return Status_FAILED
+}
+
+func (m *Recording) GetProgress() float64 {
+	if m != nil {
+		return m.Progress
+	}
+	return 0
+}
+
+type ListRecordingsRequest struct {
+	Pattern string `protobuf:"bytes,1,opt,name=pattern" json:"pattern,omitempty"`
+	Offset  int32  `protobuf:"varint,2,opt,name=offset" json:"offset,omitempty"`
+	// Defaults to 100.
+	Limit int32 `protobuf:"varint,3,opt,name=limit" json:"limit,omitempty"`
+}
+
+func (m *ListRecordingsRequest) Reset()                    { *m = ListRecordingsRequest{} }
+func (m *ListRecordingsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListRecordingsRequest) ProtoMessage()               {}
+func (*ListRecordingsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *ListRecordingsRequest) GetPattern() string {
+	if m != nil {
+		return m.Pattern
+	}
+	return ""
+}
+
+func (m *ListRecordingsRequest) GetOffset() int32 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *ListRecordingsRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type ListRecordingsResponse struct {
+	Recordings []*Recording `protobuf:"bytes,1,rep,name=recordings" json:"recordings,omitempty"`
+}
+
+func (m *ListRecordingsResponse) Reset()                    { *m = ListRecordingsResponse{} }
+func (m *ListRecordingsResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListRecordingsResponse) ProtoMessage()               {}
+func (*ListRecordingsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *ListRecordingsResponse) GetRecordings() []*Recording {
+	if m != nil {
+		return m.Recordings
+	}
+	return nil
+}
+
+type GetRecordingRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *GetRecordingRequest) Reset()                    { *m = GetRecordingRequest{} }
+func (m *GetRecordingRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetRecordingRequest) ProtoMessage()               {}
+func (*GetRecordingRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *GetRecordingRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type RecordStreamRequest struct {
+	Id   string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Task string `protobuf:"bytes,2,opt,name=task" json:"task,omitempty"`
+	Stop int64  `protobuf:"varint,3,opt,name=stop" json:"stop,omitempty"`
+}
+
+func (m *RecordStreamRequest) Reset()                    { *m = RecordStreamRequest{} }
+func (m *RecordStreamRequest) String() string            { return proto.CompactTextString(m) }
+func (*RecordStreamRequest) ProtoMessage()               {}
+func (*RecordStreamRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *RecordStreamRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *RecordStreamRequest) GetTask() string {
+	if m != nil {
+		return m.Task
+	}
+	return ""
+}
+
+func (m *RecordStreamRequest) GetStop() int64 {
+	if m != nil {
+		return m.Stop
+	}
+	return 0
+}
+
+type RecordBatchRequest struct {
+	Id    string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Task  string `protobuf:"bytes,2,opt,name=task" json:"task,omitempty"`
+	Start int64  `protobuf:"varint,3,opt,name=start" json:"start,omitempty"`
+	Stop  int64  `protobuf:"varint,4,opt,name=stop" json:"stop,omitempty"`
+}
+
+func (m *RecordBatchRequest) Reset()                    { *m = RecordBatchRequest{} }
+func (m *RecordBatchRequest) String() string            { return proto.CompactTextString(m) }
+func (*RecordBatchRequest) ProtoMessage()               {}
+func (*RecordBatchRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *RecordBatchRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *RecordBatchRequest) GetTask() string {
+	if m != nil {
+		return m.Task
+	}
+	return ""
+}
+
+func (m *RecordBatchRequest) GetStart() int64 {
+	if m != nil {
+		return m.Start
+	}
+	return 0
+}
+
+func (m *RecordBatchRequest) GetStop() int64 {
+	if m != nil {
+		return m.Stop
+	}
+	return 0
+}
+
+type RecordQueryRequest struct {
+	Id      string   `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Query   string   `protobuf:"bytes,2,opt,name=query" json:"query,omitempty"`
+	Type    TaskType `protobuf:"varint,3,opt,name=type,enum=api.TaskType" json:"type,omitempty"`
+	Cluster string   `protobuf:"bytes,4,opt,name=cluster" json:"cluster,omitempty"`
+}
+
+func (m *RecordQueryRequest) Reset()                    { *m = RecordQueryRequest{} }
+func (m *RecordQueryRequest) String() string            { return proto.CompactTextString(m) }
+func (*RecordQueryRequest) ProtoMessage()               {}
+func (*RecordQueryRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *RecordQueryRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *RecordQueryRequest) GetQuery() string {
+	if m != nil {
+		return m.Query
+	}
+	return ""
+}
+
+func (m *RecordQueryRequest) GetType() TaskType {
+	if m != nil {
+		return m.Type
+	}
+	return TaskType_INVALID_TASK
+}
+
+func (m *RecordQueryRequest) GetCluster() string {
+	if m != nil {
+		return m.Cluster
+	}
+	return ""
+}
+
+type DeleteRecordingRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *DeleteRecordingRequest) Reset()                    { *m = DeleteRecordingRequest{} }
+func (m *DeleteRecordingRequest) String() string            { return proto.CompactTextString(m) }
+func (*DeleteRecordingRequest) ProtoMessage()               {}
+func (*DeleteRecordingRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *DeleteRecordingRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type Replay struct {
+	Id            string  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Task          string  `protobuf:"bytes,2,opt,name=task" json:"task,omitempty"`
+	Recording     string  `protobuf:"bytes,3,opt,name=recording" json:"recording,omitempty"`
+	RecordingTime bool    `protobuf:"varint,4,opt,name=recording_time,json=recordingTime" json:"recording_time,omitempty"`
+	Clock         Clock   `protobuf:"varint,5,opt,name=clock,enum=api.Clock" json:"clock,omitempty"`
+	Date          int64   `protobuf:"varint,6,opt,name=date" json:"date,omitempty"`
+	Error         string  `protobuf:"bytes,7,opt,name=error" json:"error,omitempty"`
+	Status        Status  `protobuf:"varint,8,opt,name=status,enum=api.Status" json:"status,omitempty"`
+	Progress      float64 `protobuf:"fixed64,9,opt,name=progress" json:"progress,omitempty"`
+}
+
+func (m *Replay) Reset()                    { *m = Replay{} }
+func (m *Replay) String() string            { return proto.CompactTextString(m) }
+func (*Replay) ProtoMessage()               {}
+func (*Replay) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *Replay) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Replay) GetTask() string {
+	if m != nil {
+		return m.Task
+	}
+	return ""
+}
+
+func (m *Replay) GetRecording() string {
+	if m != nil {
+		return m.Recording
+	}
+	return ""
+}
+
+func (m *Replay) GetRecordingTime() bool {
+	if m != nil {
+		return m.RecordingTime
+	}
+	return false
+}
+
+func (m *Replay) GetClock() Clock {
+	if m != nil {
+		return m.Clock
+	}
+	return Clock_FAST
+}
+
+func (m *Replay) GetDate() int64 {
+	if m != nil {
+		return m.Date
+	}
+	return 0
+}
+
+func (m *Replay) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *Replay) GetStatus() Status {
+	if m != nil {
+		return m.Status
+	}
+	return Status_FAILED
+}
+
+func (m *Replay) GetProgress() float64 {
+	if m != nil {
+		return m.Progress
+	}
+	return 0
+}
+
+type ListReplaysRequest struct {
+	Pattern string `protobuf:"bytes,1,opt,name=pattern" json:"pattern,omitempty"`
+	Offset  int32  `protobuf:"varint,2,opt,name=offset" json:"offset,omitempty"`
+	// Defaults to 100.
+	Limit int32 `protobuf:"varint,3,opt,name=limit" json:"limit,omitempty"`
+}
+
+func (m *ListReplaysRequest) Reset()                    { *m = ListReplaysRequest{} }
+func (m *ListReplaysRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListReplaysRequest) ProtoMessage()               {}
+func (*ListReplaysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+func (m *ListReplaysRequest) GetPattern() string {
+	if m != nil {
+		return m.Pattern
+	}
+	return ""
+}
+
+func (m *ListReplaysRequest) GetOffset() int32 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *ListReplaysRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type ListReplaysResponse struct {
+	Replays []*Replay `protobuf:"bytes,1,rep,name=replays" json:"replays,omitempty"`
+}
+
+func (m *ListReplaysResponse) Reset()                    { *m = ListReplaysResponse{} }
+func (m *ListReplaysResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListReplaysResponse) ProtoMessage()               {}
+func (*ListReplaysResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *ListReplaysResponse) GetReplays() []*Replay {
+	if m != nil {
+		return m.Replays
+	}
+	return nil
+}
+
+type GetReplayRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *GetReplayRequest) Reset()                    { *m = GetReplayRequest{} }
+func (m *GetReplayRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetReplayRequest) ProtoMessage()               {}
+func (*GetReplayRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+
+func (m *GetReplayRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type CreateReplayRequest struct {
+	Id            string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Task          string `protobuf:"bytes,2,opt,name=task" json:"task,omitempty"`
+	Recording     string `protobuf:"bytes,3,opt,name=recording" json:"recording,omitempty"`
+	RecordingTime bool   `protobuf:"varint,4,opt,name=recording_time,json=recordingTime" json:"recording_time,omitempty"`
+	Clock         Clock  `protobuf:"varint,5,opt,name=clock,enum=api.Clock" json:"clock,omitempty"`
+}
+
+func (m *CreateReplayRequest) Reset()                    { *m = CreateReplayRequest{} }
+func (m *CreateReplayRequest) String() string            { return proto.CompactTextString(m) }
+func (*CreateReplayRequest) ProtoMessage()               {}
+func (*CreateReplayRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+func (m *CreateReplayRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *CreateReplayRequest) GetTask() string {
+	if m != nil {
+		return m.Task
+	}
+	return ""
+}
+
+func (m *CreateReplayRequest) GetRecording() string {
+	if m != nil {
+		return m.Recording
+	}
+	return ""
+}
+
+func (m *CreateReplayRequest) GetRecordingTime() bool {
+	if m != nil {
+		return m.RecordingTime
+	}
+	return false
+}
+
+func (m *CreateReplayRequest) GetClock() Clock {
+	if m != nil {
+		return m.Clock
+	}
+	return Clock_FAST
+}
+
+type DeleteReplayRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *DeleteReplayRequest) Reset()                    { *m = DeleteReplayRequest{} }
+func (m *DeleteReplayRequest) String() string            { return proto.CompactTextString(m) }
+func (*DeleteReplayRequest) ProtoMessage()               {}
+func (*DeleteReplayRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+
+func (m *DeleteReplayRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type Topic struct {
+	Id        string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Level     Level  `protobuf:"varint,2,opt,name=level,enum=api.Level" json:"level,omitempty"`
+	Collected int64  `protobuf:"varint,3,opt,name=collected" json:"collected,omitempty"`
+}
+
+func (m *Topic) Reset()                    { *m = Topic{} }
+func (m *Topic) String() string            { return proto.CompactTextString(m) }
+func (*Topic) ProtoMessage()               {}
+func (*Topic) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+
+func (m *Topic) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Topic) GetLevel() Level {
+	if m != nil {
+		return m.Level
+	}
+	return Level_OK
+}
+
+func (m *Topic) GetCollected() int64 {
+	if m != nil {
+		return m.Collected
+	}
+	return 0
+}
+
+type ListTopicsRequest struct {
+	Pattern  string `protobuf:"bytes,1,opt,name=pattern" json:"pattern,omitempty"`
+	MinLevel Level  `protobuf:"varint,2,opt,name=min_level,json=minLevel,enum=api.Level" json:"min_level,omitempty"`
+}
+
+func (m *ListTopicsRequest) Reset()                    { *m = ListTopicsRequest{} }
+func (m *ListTopicsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListTopicsRequest) ProtoMessage()               {}
+func (*ListTopicsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
+
+func (m *ListTopicsRequest) GetPattern() string {
+	if m != nil {
+		return m.Pattern
+	}
+	return ""
+}
+
+func (m *ListTopicsRequest) GetMinLevel() Level {
+	if m != nil {
+		return m.MinLevel
+	}
+	return Level_OK
+}
+
+type ListTopicsResponse struct {
+	Topics []*Topic `protobuf:"bytes,1,rep,name=topics" json:"topics,omitempty"`
+}
+
+func (m *ListTopicsResponse) Reset()                    { *m = ListTopicsResponse{} }
+func (m *ListTopicsResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListTopicsResponse) ProtoMessage()               {}
+func (*ListTopicsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
+
+func (m *ListTopicsResponse) GetTopics() []*Topic {
+	if m != nil {
+		return m.Topics
+	}
+	return nil
+}
+
+type GetTopicRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *GetTopicRequest) Reset()                    { *m = GetTopicRequest{} }
+func (m *GetTopicRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetTopicRequest) ProtoMessage()               {}
+func (*GetTopicRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
+
+func (m *GetTopicRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type DeleteTopicRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *DeleteTopicRequest) Reset()                    { *m = DeleteTopicRequest{} }
+func (m *DeleteTopicRequest) String() string            { return proto.CompactTextString(m) }
+func (*DeleteTopicRequest) ProtoMessage()               {}
+func (*DeleteTopicRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
+
+func (m *DeleteTopicRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type EventState struct {
+	Message  string `protobuf:"bytes,1,opt,name=message" json:"message,omitempty"`
+	Details  string `protobuf:"bytes,2,opt,name=details" json:"details,omitempty"`
+	Time     int64  `protobuf:"varint,3,opt,name=time" json:"time,omitempty"`
+	Duration int64  `protobuf:"varint,4,opt,name=duration" json:"duration,omitempty"`
+	Level    Level  `protobuf:"varint,5,opt,name=level,enum=api.Level" json:"level,omitempty"`
+}
+
+func (m *EventState) Reset()                    { *m = EventState{} }
+func (m *EventState) String() string            { return proto.CompactTextString(m) }
+func (*EventState) ProtoMessage()               {}
+func (*EventState) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
+
+func (m *EventState) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *EventState) GetDetails() string {
+	if m != nil {
+		return m.Details
+	}
+	return ""
+}
+
+func (m *EventState) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *EventState) GetDuration() int64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+func (m *EventState) GetLevel() Level {
+	if m != nil {
+		return m.Level
+	}
+	return Level_OK
+}
+
+type TopicEvent struct {
+	Id    string      `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Topic string      `protobuf:"bytes,2,opt,name=topic" json:"topic,omitempty"`
+	State *EventState `protobuf:"bytes,3,opt,name=state" json:"state,omitempty"`
+}
+
+func (m *TopicEvent) Reset()                    { *m = TopicEvent{} }
+func (m *TopicEvent) String() string            { return proto.CompactTextString(m) }
+func (*TopicEvent) ProtoMessage()               {}
+func (*TopicEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
+
+func (m *TopicEvent) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *TopicEvent) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *TopicEvent) GetState() *EventState {
+	if m != nil {
+		return m.State
+	}
+	return nil
+}
+
+type ListTopicEventsRequest struct {
+	Topic    string `protobuf:"bytes,1,opt,name=topic" json:"topic,omitempty"`
+	MinLevel Level  `protobuf:"varint,2,opt,name=min_level,json=minLevel,enum=api.Level" json:"min_level,omitempty"`
+}
+
+func (m *ListTopicEventsRequest) Reset()                    { *m = ListTopicEventsRequest{} }
+func (m *ListTopicEventsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListTopicEventsRequest) ProtoMessage()               {}
+func (*ListTopicEventsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{33} }
+
+func (m *ListTopicEventsRequest) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *ListTopicEventsRequest) GetMinLevel() Level {
+	if m != nil {
+		return m.MinLevel
+	}
+	return Level_OK
+}
+
+type ListTopicEventsResponse struct {
+	Events []*TopicEvent `protobuf:"bytes,1,rep,name=events" json:"events,omitempty"`
+}
+
+func (m *ListTopicEventsResponse) Reset()                    { *m = ListTopicEventsResponse{} }
+func (m *ListTopicEventsResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListTopicEventsResponse) ProtoMessage()               {}
+func (*ListTopicEventsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{34} }
+
+func (m *ListTopicEventsResponse) GetEvents() []*TopicEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+type WatchTopicEventsRequest struct {
+	Topic    string `protobuf:"bytes,1,opt,name=topic" json:"topic,omitempty"`
+	MinLevel Level  `protobuf:"varint,2,opt,name=min_level,json=minLevel,enum=api.Level" json:"min_level,omitempty"`
+}
+
+func (m *WatchTopicEventsRequest) Reset()                    { *m = WatchTopicEventsRequest{} }
+func (m *WatchTopicEventsRequest) String() string            { return proto.CompactTextString(m) }
+func (*WatchTopicEventsRequest) ProtoMessage()               {}
+func (*WatchTopicEventsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{35} }
+
+func (m *WatchTopicEventsRequest) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *WatchTopicEventsRequest) GetMinLevel() Level {
+	if m != nil {
+		return m.MinLevel
+	}
+	return Level_OK
+}
+
+func init() {
+	proto.RegisterType((*Empty)(nil), "api.Empty")
+	proto.RegisterType((*DBRP)(nil), "api.DBRP")
+	proto.RegisterType((*Var)(nil), "api.Var")
+	proto.RegisterType((*Task)(nil), "api.Task")
+	proto.RegisterType((*ListTasksRequest)(nil), "api.ListTasksRequest")
+	proto.RegisterType((*ListTasksResponse)(nil), "api.ListTasksResponse")
+	proto.RegisterType((*GetTaskRequest)(nil), "api.GetTaskRequest")
+	proto.RegisterType((*CreateTaskRequest)(nil), "api.CreateTaskRequest")
+	proto.RegisterType((*UpdateTaskRequest)(nil), "api.UpdateTaskRequest")
+	proto.RegisterType((*DeleteTaskRequest)(nil), "api.DeleteTaskRequest")
+	proto.RegisterType((*WatchTasksRequest)(nil), "api.WatchTasksRequest")
+	proto.RegisterType((*TaskEvent)(nil), "api.TaskEvent")
+	proto.RegisterType((*Recording)(nil), "api.Recording")
+	proto.RegisterType((*ListRecordingsRequest)(nil), "api.ListRecordingsRequest")
+	proto.RegisterType((*ListRecordingsResponse)(nil), "api.ListRecordingsResponse")
+	proto.RegisterType((*GetRecordingRequest)(nil), "api.GetRecordingRequest")
+	proto.RegisterType((*RecordStreamRequest)(nil), "api.RecordStreamRequest")
+	proto.RegisterType((*RecordBatchRequest)(nil), "api.RecordBatchRequest")
+	proto.RegisterType((*RecordQueryRequest)(nil), "api.RecordQueryRequest")
+	proto.RegisterType((*DeleteRecordingRequest)(nil), "api.DeleteRecordingRequest")
+	proto.RegisterType((*Replay)(nil), "api.Replay")
+	proto.RegisterType((*ListReplaysRequest)(nil), "api.ListReplaysRequest")
+	proto.RegisterType((*ListReplaysResponse)(nil), "api.ListReplaysResponse")
+	proto.RegisterType((*GetReplayRequest)(nil), "api.GetReplayRequest")
+	proto.RegisterType((*CreateReplayRequest)(nil), "api.CreateReplayRequest")
+	proto.RegisterType((*DeleteReplayRequest)(nil), "api.DeleteReplayRequest")
+	proto.RegisterType((*Topic)(nil), "api.Topic")
+	proto.RegisterType((*ListTopicsRequest)(nil), "api.ListTopicsRequest")
+	proto.RegisterType((*ListTopicsResponse)(nil), "api.ListTopicsResponse")
+	proto.RegisterType((*GetTopicRequest)(nil), "api.GetTopicRequest")
+	proto.RegisterType((*DeleteTopicRequest)(nil), "api.DeleteTopicRequest")
+	proto.RegisterType((*EventState)(nil), "api.EventState")
+	proto.RegisterType((*TopicEvent)(nil), "api.TopicEvent")
+	proto.RegisterType((*ListTopicEventsRequest)(nil), "api.ListTopicEventsRequest")
+	proto.RegisterType((*ListTopicEventsResponse)(nil), "api.ListTopicEventsResponse")
+	proto.RegisterType((*WatchTopicEventsRequest)(nil), "api.WatchTopicEventsRequest")
+	proto.RegisterEnum("api.TaskType", TaskType_name, TaskType_value)
+	proto.RegisterEnum("api.TaskStatus", TaskStatus_name, TaskStatus_value)
+	proto.RegisterEnum("api.VarType", VarType_name, VarType_value)
+	proto.RegisterEnum("api.Status", Status_name, Status_value)
+	proto.RegisterEnum("api.Clock", Clock_name, Clock_value)
+	proto.RegisterEnum("api.Level", Level_name, Level_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for Kapacitor service
+
+type KapacitorClient interface {
+	ListTasks(ctx context.Context, in *ListTasksRequest, opts ...grpc.CallOption) (*ListTasksResponse, error)
+	GetTask(ctx context.Context, in *GetTaskRequest, opts ...grpc.CallOption) (*Task, error)
+	CreateTask(ctx context.Context, in *CreateTaskRequest, opts ...grpc.CallOption) (*Task, error)
+	// Update the task, unset fields are left unchanged.
+	UpdateTask(ctx context.Context, in *UpdateTaskRequest, opts ...grpc.CallOption) (*Task, error)
+	DeleteTask(ctx context.Context, in *DeleteTaskRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Watch the state of the tasks.
+	// The current state of each task is sent first, then each change.
+	WatchTasks(ctx context.Context, in *WatchTasksRequest, opts ...grpc.CallOption) (Kapacitor_WatchTasksClient, error)
+	ListRecordings(ctx context.Context, in *ListRecordingsRequest, opts ...grpc.CallOption) (*ListRecordingsResponse, error)
+	GetRecording(ctx context.Context, in *GetRecordingRequest, opts ...grpc.CallOption) (*Recording, error)
+	RecordStream(ctx context.Context, in *RecordStreamRequest, opts ...grpc.CallOption) (*Recording, error)
+	RecordBatch(ctx context.Context, in *RecordBatchRequest, opts ...grpc.CallOption) (*Recording, error)
+	RecordQuery(ctx context.Context, in *RecordQueryRequest, opts ...grpc.CallOption) (*Recording, error)
+	DeleteRecording(ctx context.Context, in *DeleteRecordingRequest, opts ...grpc.CallOption) (*Empty, error)
+	ListReplays(ctx context.Context, in *ListReplaysRequest, opts ...grpc.CallOption) (*ListReplaysResponse, error)
+	GetReplay(ctx context.Context, in *GetReplayRequest, opts ...grpc.CallOption) (*Replay, error)
+	CreateReplay(ctx context.Context, in *CreateReplayRequest, opts ...grpc.CallOption) (*Replay, error)
+	DeleteReplay(ctx context.Context, in *DeleteReplayRequest, opts ...grpc.CallOption) (*Empty, error)
+	ListTopics(ctx context.Context, in *ListTopicsRequest, opts ...grpc.CallOption) (*ListTopicsResponse, error)
+	GetTopic(ctx context.Context, in *GetTopicRequest, opts ...grpc.CallOption) (*Topic, error)
+	DeleteTopic(ctx context.Context, in *DeleteTopicRequest, opts ...grpc.CallOption) (*Empty, error)
+	ListTopicEvents(ctx context.Context, in *ListTopicEventsRequest, opts ...grpc.CallOption) (*ListTopicEventsResponse, error)
+	// Watch the events of a topic as they are collected.
+	WatchTopicEvents(ctx context.Context, in *WatchTopicEventsRequest, opts ...grpc.CallOption) (Kapacitor_WatchTopicEventsClient, error)
+}
+
+type kapacitorClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewKapacitorClient(cc *grpc.ClientConn) KapacitorClient {
+	return &kapacitorClient{cc}
+}
+
+func (c *kapacitorClient) ListTasks(ctx context.Context, in *ListTasksRequest, opts ...grpc.CallOption) (*ListTasksResponse, error) {
+	out := new(ListTasksResponse)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/ListTasks", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) GetTask(ctx context.Context, in *GetTaskRequest, opts ...grpc.CallOption) (*Task, error) {
+	out := new(Task)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/GetTask", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) CreateTask(ctx context.Context, in *CreateTaskRequest, opts ...grpc.CallOption) (*Task, error) {
+	out := new(Task)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/CreateTask", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) UpdateTask(ctx context.Context, in *UpdateTaskRequest, opts ...grpc.CallOption) (*Task, error) {
+	out := new(Task)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/UpdateTask", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) DeleteTask(ctx context.Context, in *DeleteTaskRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/DeleteTask", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) WatchTasks(ctx context.Context, in *WatchTasksRequest, opts ...grpc.CallOption) (Kapacitor_WatchTasksClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Kapacitor_serviceDesc.Streams[0], c.cc, "/api.Kapacitor/WatchTasks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &kapacitorWatchTasksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Kapacitor_WatchTasksClient interface {
+	Recv() (*TaskEvent, error)
+	grpc.ClientStream
+}
+
+type kapacitorWatchTasksClient struct {
+	grpc.ClientStream
+}
+
+func (x *kapacitorWatchTasksClient) Recv() (*TaskEvent, error) {
+	m := new(TaskEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *kapacitorClient) ListRecordings(ctx context.Context, in *ListRecordingsRequest, opts ...grpc.CallOption) (*ListRecordingsResponse, error) {
+	out := new(ListRecordingsResponse)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/ListRecordings", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) GetRecording(ctx context.Context, in *GetRecordingRequest, opts ...grpc.CallOption) (*Recording, error) {
+	out := new(Recording)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/GetRecording", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) RecordStream(ctx context.Context, in *RecordStreamRequest, opts ...grpc.CallOption) (*Recording, error) {
+	out := new(Recording)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/RecordStream", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) RecordBatch(ctx context.Context, in *RecordBatchRequest, opts ...grpc.CallOption) (*Recording, error) {
+	out := new(Recording)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/RecordBatch", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) RecordQuery(ctx context.Context, in *RecordQueryRequest, opts ...grpc.CallOption) (*Recording, error) {
+	out := new(Recording)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/RecordQuery", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) DeleteRecording(ctx context.Context, in *DeleteRecordingRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/DeleteRecording", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) ListReplays(ctx context.Context, in *ListReplaysRequest, opts ...grpc.CallOption) (*ListReplaysResponse, error) {
+	out := new(ListReplaysResponse)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/ListReplays", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) GetReplay(ctx context.Context, in *GetReplayRequest, opts ...grpc.CallOption) (*Replay, error) {
+	out := new(Replay)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/GetReplay", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) CreateReplay(ctx context.Context, in *CreateReplayRequest, opts ...grpc.CallOption) (*Replay, error) {
+	out := new(Replay)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/CreateReplay", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) DeleteReplay(ctx context.Context, in *DeleteReplayRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/DeleteReplay", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) ListTopics(ctx context.Context, in *ListTopicsRequest, opts ...grpc.CallOption) (*ListTopicsResponse, error) {
+	out := new(ListTopicsResponse)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/ListTopics", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) GetTopic(ctx context.Context, in *GetTopicRequest, opts ...grpc.CallOption) (*Topic, error) {
+	out := new(Topic)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/GetTopic", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) DeleteTopic(ctx context.Context, in *DeleteTopicRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/DeleteTopic", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) ListTopicEvents(ctx context.Context, in *ListTopicEventsRequest, opts ...grpc.CallOption) (*ListTopicEventsResponse, error) {
+	out := new(ListTopicEventsResponse)
+	err := grpc.Invoke(ctx, "/api.Kapacitor/ListTopicEvents", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kapacitorClient) WatchTopicEvents(ctx context.Context, in *WatchTopicEventsRequest, opts ...grpc.CallOption) (Kapacitor_WatchTopicEventsClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Kapacitor_serviceDesc.Streams[1], c.cc, "/api.Kapacitor/WatchTopicEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &kapacitorWatchTopicEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Kapacitor_WatchTopicEventsClient interface {
+	Recv() (*TopicEvent, error)
+	grpc.ClientStream
+}
+
+type kapacitorWatchTopicEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *kapacitorWatchTopicEventsClient) Recv() (*TopicEvent, error) {
+	m := new(TopicEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for Kapacitor service
+
+type KapacitorServer interface {
+	ListTasks(context.Context, *ListTasksRequest) (*ListTasksResponse, error)
+	GetTask(context.Context, *GetTaskRequest) (*Task, error)
+	CreateTask(context.Context, *CreateTaskRequest) (*Task, error)
+	// Update the task, unset fields are left unchanged.
+	UpdateTask(context.Context, *UpdateTaskRequest) (*Task, error)
+	DeleteTask(context.Context, *DeleteTaskRequest) (*Empty, error)
+	// Watch the state of the tasks.
+	// The current state of each task is sent first, then each change.
+	WatchTasks(*WatchTasksRequest, Kapacitor_WatchTasksServer) error
+	ListRecordings(context.Context, *ListRecordingsRequest) (*ListRecordingsResponse, error)
+	GetRecording(context.Context, *GetRecordingRequest) (*Recording, error)
+	RecordStream(context.Context, *RecordStreamRequest) (*Recording, error)
+	RecordBatch(context.Context, *RecordBatchRequest) (*Recording, error)
+	RecordQuery(context.Context, *RecordQueryRequest) (*Recording, error)
+	DeleteRecording(context.Context, *DeleteRecordingRequest) (*Empty, error)
+	ListReplays(context.Context, *ListReplaysRequest) (*ListReplaysResponse, error)
+	GetReplay(context.Context, *GetReplayRequest) (*Replay, error)
+	CreateReplay(context.Context, *CreateReplayRequest) (*Replay, error)
+	DeleteReplay(context.Context, *DeleteReplayRequest) (*Empty, error)
+	ListTopics(context.Context, *ListTopicsRequest) (*ListTopicsResponse, error)
+	GetTopic(context.Context, *GetTopicRequest) (*Topic, error)
+	DeleteTopic(context.Context, *DeleteTopicRequest) (*Empty, error)
+	ListTopicEvents(context.Context, *ListTopicEventsRequest) (*ListTopicEventsResponse, error)
+	// Watch the events of a topic as they are collected.
+	WatchTopicEvents(*WatchTopicEventsRequest, Kapacitor_WatchTopicEventsServer) error
+}
+
+func RegisterKapacitorServer(s *grpc.Server, srv KapacitorServer) {
+	s.RegisterService(&_Kapacitor_serviceDesc, srv)
+}
+
+func _Kapacitor_ListTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).ListTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/ListTasks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).ListTasks(ctx, req.(*ListTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_GetTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).GetTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/GetTask",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).GetTask(ctx, req.(*GetTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_CreateTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).CreateTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/CreateTask",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).CreateTask(ctx, req.(*CreateTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_UpdateTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).UpdateTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/UpdateTask",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).UpdateTask(ctx, req.(*UpdateTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_DeleteTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).DeleteTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/DeleteTask",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).DeleteTask(ctx, req.(*DeleteTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_WatchTasks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchTasksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KapacitorServer).WatchTasks(m, &kapacitorWatchTasksServer{stream})
+}
+
+type Kapacitor_WatchTasksServer interface {
+	Send(*TaskEvent) error
+	grpc.ServerStream
+}
+
+type kapacitorWatchTasksServer struct {
+	grpc.ServerStream
+}
+
+func (x *kapacitorWatchTasksServer) Send(m *TaskEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Kapacitor_ListRecordings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRecordingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).ListRecordings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/ListRecordings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).ListRecordings(ctx, req.(*ListRecordingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_GetRecording_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRecordingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).GetRecording(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/GetRecording",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).GetRecording(ctx, req.(*GetRecordingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_RecordStream_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordStreamRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).RecordStream(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/RecordStream",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).RecordStream(ctx, req.(*RecordStreamRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_RecordBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).RecordBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/RecordBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).RecordBatch(ctx, req.(*RecordBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_RecordQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordQueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).RecordQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/RecordQuery",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).RecordQuery(ctx, req.(*RecordQueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_DeleteRecording_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRecordingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).DeleteRecording(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/DeleteRecording",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).DeleteRecording(ctx, req.(*DeleteRecordingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_ListReplays_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListReplaysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).ListReplays(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/ListReplays",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).ListReplays(ctx, req.(*ListReplaysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_GetReplay_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetReplayRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).GetReplay(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/GetReplay",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).GetReplay(ctx, req.(*GetReplayRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_CreateReplay_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateReplayRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).CreateReplay(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/CreateReplay",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).CreateReplay(ctx, req.(*CreateReplayRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_DeleteReplay_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteReplayRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).DeleteReplay(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/DeleteReplay",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).DeleteReplay(ctx, req.(*DeleteReplayRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_ListTopics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTopicsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).ListTopics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/ListTopics",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).ListTopics(ctx, req.(*ListTopicsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_GetTopic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTopicRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).GetTopic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/GetTopic",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).GetTopic(ctx, req.(*GetTopicRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_DeleteTopic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteTopicRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).DeleteTopic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/DeleteTopic",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).DeleteTopic(ctx, req.(*DeleteTopicRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_ListTopicEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTopicEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KapacitorServer).ListTopicEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Kapacitor/ListTopicEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KapacitorServer).ListTopicEvents(ctx, req.(*ListTopicEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kapacitor_WatchTopicEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchTopicEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KapacitorServer).WatchTopicEvents(m, &kapacitorWatchTopicEventsServer{stream})
+}
+
+type Kapacitor_WatchTopicEventsServer interface {
+	Send(*TopicEvent) error
+	grpc.ServerStream
+}
+
+type kapacitorWatchTopicEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *kapacitorWatchTopicEventsServer) Send(m *TopicEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Kapacitor_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "api.Kapacitor",
+	HandlerType: (*KapacitorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListTasks",
+			Handler:    _Kapacitor_ListTasks_Handler,
+		},
+		{
+			MethodName: "GetTask",
+			Handler:    _Kapacitor_GetTask_Handler,
+		},
+		{
+			MethodName: "CreateTask",
+			Handler:    _Kapacitor_CreateTask_Handler,
+		},
+		{
+			MethodName: "UpdateTask",
+			Handler:    _Kapacitor_UpdateTask_Handler,
+		},
+		{
+			MethodName: "DeleteTask",
+			Handler:    _Kapacitor_DeleteTask_Handler,
+		},
+		{
+			MethodName: "ListRecordings",
+			Handler:    _Kapacitor_ListRecordings_Handler,
+		},
+		{
+			MethodName: "GetRecording",
+			Handler:    _Kapacitor_GetRecording_Handler,
+		},
+		{
+			MethodName: "RecordStream",
+			Handler:    _Kapacitor_RecordStream_Handler,
+		},
+		{
+			MethodName: "RecordBatch",
+			Handler:    _Kapacitor_RecordBatch_Handler,
+		},
+		{
+			MethodName: "RecordQuery",
+			Handler:    _Kapacitor_RecordQuery_Handler,
+		},
+		{
+			MethodName: "DeleteRecording",
+			Handler:    _Kapacitor_DeleteRecording_Handler,
+		},
+		{
+			MethodName: "ListReplays",
+			Handler:    _Kapacitor_ListReplays_Handler,
+		},
+		{
+			MethodName: "GetReplay",
+			Handler:    _Kapacitor_GetReplay_Handler,
+		},
+		{
+			MethodName: "CreateReplay",
+			Handler:    _Kapacitor_CreateReplay_Handler,
+		},
+		{
+			MethodName: "DeleteReplay",
+			Handler:    _Kapacitor_DeleteReplay_Handler,
+		},
+		{
+			MethodName: "ListTopics",
+			Handler:    _Kapacitor_ListTopics_Handler,
+		},
+		{
+			MethodName: "GetTopic",
+			Handler:    _Kapacitor_GetTopic_Handler,
+		},
+		{
+			MethodName: "DeleteTopic",
+			Handler:    _Kapacitor_DeleteTopic_Handler,
+		},
+		{
+			MethodName: "ListTopicEvents",
+			Handler:    _Kapacitor_ListTopicEvents_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchTasks",
+			Handler:       _Kapacitor_WatchTasks_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchTopicEvents",
+			Handler:       _Kapacitor_WatchTopicEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api.proto",
+}
+
+func init() { proto.RegisterFile("api.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 1795 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xd4, 0x18, 0xed, 0x4e, 0xe3, 0xd8,
+	0x75, 0x9c, 0xc4, 0x49, 0x7c, 0x92, 0x09, 0x9e, 0xcb, 0x2c, 0x13, 0x05, 0xb6, 0x9b, 0xf1, 0x16,
+	0xc1, 0xa2, 0x76, 0x66, 0xc4, 0xd2, 0xd5, 0x68, 0xda, 0x4a, 0x35, 0x10, 0x98, 0x88, 0x10, 0xb6,
+	0x37, 0x06, 0xb6, 0x55, 0xa5, 0xc8, 0xc4, 0x17, 0x6a, 0xe1, 0xc4, 0x5e, 0xfb, 0x42, 0x9b, 0x4a,
+	0x7d, 0x89, 0x3e, 0x41, 0x5f, 0x64, 0x9f, 0xa0, 0x7f, 0xfa, 0x2c, 0x7d, 0x80, 0xaa, 0xba, 0x1f,
+	0xfe, 0x48, 0x9c, 0x50, 0x2a, 0x21, 0x4d, 0xf7, 0x9f, 0xef, 0x39, 0xe7, 0x9e, 0xef, 0xaf, 0x6b,
+	0xd0, 0xec, 0xc0, 0x7d, 0x13, 0x84, 0x3e, 0xf5, 0x51, 0xd1, 0x0e, 0x5c, 0xa3, 0x02, 0x6a, 0x67,
+	0x1c, 0xd0, 0xa9, 0x71, 0x0a, 0xa5, 0xc3, 0x7d, 0xfc, 0x2d, 0x6a, 0x41, 0xd5, 0xb1, 0xa9, 0x7d,
+	0x65, 0x47, 0xa4, 0xa9, 0xb4, 0x95, 0x6d, 0x0d, 0x27, 0x67, 0xf4, 0x15, 0xe8, 0x21, 0xa1, 0x64,
+	0x42, 0x5d, 0x7f, 0x32, 0x0c, 0x7c, 0xcf, 0x1d, 0x4d, 0x9b, 0x05, 0x4e, 0xb3, 0x92, 0xc0, 0xbf,
+	0xe5, 0x60, 0xe3, 0x5f, 0x0a, 0x14, 0x2f, 0xec, 0x10, 0xb5, 0xa1, 0x44, 0xa7, 0x81, 0x60, 0xd5,
+	0xd8, 0xad, 0xbf, 0x61, 0xe2, 0x2f, 0xec, 0xd0, 0x9a, 0x06, 0x04, 0x73, 0x0c, 0xfa, 0x1c, 0xe0,
+	0xca, 0xf7, 0xbd, 0xe1, 0xbd, 0xed, 0xdd, 0x11, 0xce, 0xae, 0x8a, 0x35, 0x06, 0xb9, 0x60, 0x00,
+	0xb4, 0x0e, 0x9a, 0x3b, 0xa1, 0x12, 0x5b, 0x6c, 0x2b, 0xdb, 0x45, 0x5c, 0x75, 0x27, 0x54, 0x20,
+	0xbf, 0x80, 0xda, 0xb5, 0xe7, 0xdb, 0x31, 0xba, 0xd4, 0x56, 0xb6, 0x15, 0x0c, 0x1c, 0x24, 0x08,
+	0x5e, 0x43, 0x3d, 0xa2, 0xa1, 0x3b, 0xb9, 0x91, 0x14, 0x2a, 0xd7, 0xb6, 0x26, 0x60, 0x82, 0x64,
+	0x0b, 0xc0, 0x73, 0xa3, 0x98, 0x45, 0xb9, 0x5d, 0xdc, 0xae, 0xed, 0x56, 0x63, 0x3d, 0xb1, 0xc6,
+	0x70, 0x82, 0xb0, 0x0d, 0x35, 0x87, 0x44, 0xa3, 0xd0, 0x0d, 0x98, 0x9d, 0xcd, 0x8a, 0x60, 0x95,
+	0x01, 0x19, 0xff, 0x2c, 0x42, 0xc9, 0xb2, 0xa3, 0x5b, 0xd4, 0x80, 0x82, 0xeb, 0x48, 0xf7, 0x15,
+	0x5c, 0x87, 0xe9, 0x49, 0xc9, 0x38, 0xf0, 0x6c, 0x4a, 0x86, 0xae, 0x23, 0x7d, 0x06, 0x31, 0xa8,
+	0xeb, 0xa0, 0xd7, 0xd2, 0x4d, 0x45, 0xee, 0xa6, 0xe7, 0x5c, 0x3c, 0xe3, 0x94, 0xf1, 0xd3, 0x17,
+	0xa0, 0x3a, 0x57, 0x61, 0x10, 0x35, 0x4b, 0x5c, 0x45, 0x8d, 0xd3, 0xb0, 0x90, 0x61, 0x01, 0xe7,
+	0x42, 0xdc, 0xd1, 0xed, 0x50, 0xe8, 0x23, 0x4d, 0x05, 0x06, 0x1a, 0x70, 0x08, 0xda, 0x82, 0xd2,
+	0xbd, 0x1d, 0x46, 0xd2, 0xc6, 0xd5, 0x44, 0x08, 0x33, 0x34, 0xea, 0x4c, 0x68, 0x38, 0xc5, 0x9c,
+	0x00, 0xe9, 0x50, 0x74, 0x7c, 0x2a, 0x2d, 0x64, 0x9f, 0x68, 0x0b, 0xca, 0x11, 0xb5, 0xe9, 0x5d,
+	0xd4, 0xac, 0x72, 0x0d, 0x57, 0x92, 0xcb, 0x03, 0x0e, 0xc6, 0x12, 0x8d, 0x36, 0x40, 0x23, 0x7f,
+	0x26, 0xa3, 0x3b, 0xea, 0x4e, 0x6e, 0x9a, 0x9a, 0x08, 0x66, 0x02, 0x40, 0x2f, 0x41, 0x25, 0x61,
+	0xe8, 0x87, 0x4d, 0xe0, 0xac, 0xc5, 0x01, 0x35, 0xa1, 0x32, 0x0a, 0x89, 0x4d, 0x89, 0xd3, 0xac,
+	0xf1, 0x00, 0xc7, 0x47, 0x96, 0x8c, 0x63, 0xdf, 0x71, 0xaf, 0x5d, 0xe2, 0x34, 0xeb, 0x22, 0xf6,
+	0xf1, 0x99, 0x85, 0xd6, 0xb3, 0x23, 0x3a, 0x24, 0x13, 0xfb, 0xca, 0x23, 0x4e, 0xf3, 0x39, 0xc7,
+	0xd7, 0x18, 0xac, 0x23, 0x40, 0x2d, 0x13, 0xb4, 0xc4, 0x34, 0x66, 0xd4, 0x2d, 0x99, 0xca, 0xa0,
+	0xb0, 0x4f, 0xf4, 0x13, 0x50, 0xd3, 0xa4, 0xcb, 0x06, 0x5d, 0x80, 0x3f, 0x14, 0xde, 0x2b, 0xc6,
+	0xef, 0x41, 0xef, 0xb9, 0x11, 0x65, 0x96, 0x46, 0x98, 0x7c, 0x7f, 0x47, 0x22, 0xca, 0xf4, 0x0d,
+	0x6c, 0x4a, 0x49, 0x38, 0x91, 0xdc, 0xe2, 0x23, 0x5a, 0x83, 0xb2, 0x7f, 0x7d, 0x1d, 0x11, 0xca,
+	0x59, 0xaa, 0x58, 0x9e, 0x98, 0xdd, 0x9e, 0x3b, 0x76, 0x29, 0x8f, 0xaf, 0x8a, 0xc5, 0xc1, 0xd8,
+	0x83, 0x17, 0x19, 0xde, 0x51, 0xe0, 0x4f, 0x22, 0x1e, 0x66, 0xca, 0x00, 0x4d, 0x25, 0x13, 0x66,
+	0x46, 0x82, 0x05, 0xdc, 0x68, 0x43, 0xe3, 0x98, 0xf0, 0x4b, 0xb1, 0x3e, 0x73, 0xd9, 0x66, 0xfc,
+	0xa3, 0x00, 0x2f, 0x0e, 0xb8, 0x07, 0x1f, 0xa0, 0xfa, 0x3f, 0xc9, 0xc9, 0xbd, 0x99, 0x9c, 0x6c,
+	0x73, 0x06, 0x39, 0xdd, 0x73, 0x09, 0x9a, 0xa6, 0x63, 0xe5, 0xc1, 0x74, 0x7c, 0x8a, 0x0c, 0x60,
+	0xde, 0x3c, 0x0f, 0x9c, 0x1f, 0xad, 0x37, 0x73, 0xba, 0x7f, 0x52, 0x6f, 0x7e, 0x09, 0x2f, 0x0e,
+	0x89, 0x47, 0x1e, 0x74, 0xa6, 0xf1, 0x73, 0x78, 0x71, 0x69, 0xd3, 0xd1, 0x1f, 0x1f, 0x57, 0x75,
+	0xc6, 0x21, 0x68, 0x8c, 0xb2, 0x73, 0x4f, 0x26, 0x14, 0x7d, 0x0e, 0x25, 0x56, 0x27, 0x9c, 0x66,
+	0xa6, 0x7c, 0x38, 0x98, 0x71, 0x71, 0xb8, 0x7c, 0x47, 0x8e, 0x9a, 0xf8, 0x68, 0xfc, 0xa0, 0x80,
+	0x86, 0xc9, 0xc8, 0x0f, 0x1d, 0xd6, 0xa9, 0xe6, 0xe3, 0x1b, 0x87, 0xaf, 0xb0, 0x3c, 0x7c, 0x08,
+	0x4a, 0x91, 0xfb, 0x97, 0x78, 0x48, 0xf1, 0x6f, 0x06, 0x63, 0xde, 0xe7, 0x93, 0xa9, 0x88, 0xf9,
+	0x77, 0xda, 0x04, 0xd5, 0x6c, 0x13, 0xfc, 0x32, 0x09, 0x42, 0x99, 0x8b, 0xa8, 0x71, 0x11, 0x73,
+	0xdd, 0xb5, 0x05, 0xd5, 0x20, 0xf4, 0x6f, 0x42, 0x12, 0x89, 0x58, 0x29, 0x38, 0x39, 0x1b, 0x43,
+	0xf8, 0x8c, 0x75, 0x93, 0xc4, 0x84, 0x27, 0x6f, 0x57, 0x1f, 0x61, 0x6d, 0x5e, 0x80, 0xec, 0x59,
+	0x6f, 0x00, 0xc2, 0x04, 0x2a, 0x1b, 0x57, 0x83, 0xeb, 0x9f, 0x10, 0xe3, 0x0c, 0x85, 0xb1, 0x09,
+	0xab, 0xc7, 0x24, 0x65, 0xb4, 0x2c, 0x0d, 0x4e, 0x61, 0x55, 0xd0, 0x0c, 0x68, 0x48, 0xec, 0xf1,
+	0xb2, 0xd2, 0x43, 0x32, 0xe2, 0xa2, 0xe6, 0xf8, 0x37, 0x83, 0x45, 0xd4, 0x0f, 0x92, 0x58, 0x50,
+	0x3f, 0x30, 0xae, 0x00, 0x09, 0x76, 0xfb, 0x2c, 0xb7, 0xfe, 0x17, 0x6e, 0x2f, 0x41, 0x8d, 0xa8,
+	0x1d, 0x52, 0xc9, 0x4e, 0x1c, 0x12, 0x19, 0xa5, 0x8c, 0x8c, 0x3f, 0xc5, 0x32, 0x7e, 0x7b, 0x47,
+	0xc2, 0xe9, 0x32, 0x19, 0x2f, 0x41, 0xfd, 0x9e, 0xe1, 0xa5, 0x10, 0x71, 0x78, 0x4c, 0x87, 0x60,
+	0x93, 0xd2, 0xbb, 0x8b, 0x28, 0x09, 0xb9, 0x54, 0x0d, 0xc7, 0x47, 0x63, 0x1b, 0xd6, 0x44, 0x5d,
+	0xfd, 0x57, 0xaf, 0xfe, 0x5b, 0x81, 0x32, 0x26, 0x81, 0x67, 0x4f, 0x1f, 0x65, 0xfb, 0x06, 0x68,
+	0x49, 0xe4, 0xb8, 0x6a, 0x1a, 0x4e, 0x01, 0x68, 0x13, 0x1a, 0xc9, 0x61, 0x48, 0xdd, 0xb1, 0xc8,
+	0xf4, 0x2a, 0x7e, 0x9e, 0x40, 0x2d, 0x77, 0xcc, 0x56, 0x27, 0x75, 0xe4, 0xf9, 0xa3, 0x5b, 0x9e,
+	0xf2, 0x8d, 0x5d, 0x10, 0x6d, 0x9e, 0x41, 0xb0, 0x40, 0x24, 0x85, 0x52, 0x5e, 0x54, 0x28, 0x95,
+	0xc5, 0x85, 0x52, 0x7d, 0x5c, 0xa1, 0x68, 0x73, 0x85, 0xf2, 0x07, 0x40, 0x22, 0x8f, 0x99, 0x0f,
+	0x9e, 0xbc, 0x4a, 0x7e, 0x05, 0xab, 0x33, 0xdc, 0x65, 0x89, 0x6c, 0x42, 0x25, 0x14, 0x20, 0x59,
+	0x1f, 0x35, 0x59, 0x1f, 0x0c, 0x86, 0x63, 0x9c, 0x61, 0x80, 0xce, 0x2b, 0x83, 0x43, 0x97, 0x04,
+	0xf0, 0xef, 0x0a, 0xac, 0x8a, 0x11, 0xf9, 0x20, 0xdd, 0x27, 0x8c, 0x26, 0x2b, 0xf0, 0x38, 0x1b,
+	0x1f, 0xb2, 0xe4, 0x12, 0x54, 0xcb, 0x0f, 0xdc, 0x51, 0x4e, 0xf5, 0x36, 0xa8, 0x1e, 0xb9, 0x27,
+	0x5e, 0xb3, 0x90, 0x91, 0xd0, 0x63, 0x10, 0x2c, 0x10, 0xcc, 0x90, 0x91, 0xef, 0x79, 0x64, 0xc4,
+	0x3a, 0xb9, 0x28, 0xcb, 0x14, 0x60, 0x5c, 0xc8, 0xcd, 0x8a, 0x31, 0x7f, 0x44, 0x84, 0xb7, 0x40,
+	0x1b, 0xbb, 0x93, 0xe1, 0x32, 0x91, 0xd5, 0xb1, 0x3b, 0xe1, 0x5f, 0xc6, 0x7b, 0x40, 0x59, 0xbe,
+	0x32, 0xb6, 0x06, 0x94, 0x29, 0x87, 0xc8, 0xd0, 0x8a, 0xbb, 0x9c, 0x08, 0x4b, 0x8c, 0xf1, 0x1a,
+	0x56, 0xd8, 0xd6, 0xc6, 0x61, 0x4b, 0xbc, 0xf1, 0x53, 0x40, 0x72, 0x34, 0x3e, 0x44, 0xf5, 0x37,
+	0x05, 0x80, 0x4f, 0x3a, 0x96, 0xf1, 0xbc, 0x23, 0x8c, 0x49, 0x14, 0xd9, 0x37, 0xf1, 0x6b, 0x2d,
+	0x3e, 0x8a, 0x49, 0x47, 0x6d, 0xd7, 0x8b, 0x64, 0x06, 0xc4, 0x47, 0x9e, 0x18, 0x2c, 0xb8, 0xb2,
+	0x39, 0xb2, 0x6f, 0xfe, 0xec, 0xbb, 0x0b, 0x6d, 0xfe, 0xb2, 0x11, 0x0d, 0x2d, 0x39, 0xa7, 0xd1,
+	0x50, 0x97, 0x44, 0xc3, 0xf8, 0x1d, 0x00, 0x57, 0x5a, 0x8c, 0xe0, 0x05, 0xed, 0x8e, 0x7b, 0x21,
+	0x6e, 0x77, 0xfc, 0x80, 0x36, 0x79, 0x53, 0xa5, 0x42, 0x8d, 0x9a, 0x5c, 0x3a, 0x52, 0xcb, 0xb0,
+	0xc0, 0x1a, 0x97, 0x62, 0xea, 0xa4, 0xec, 0x93, 0x78, 0x26, 0x6c, 0x95, 0x2c, 0xdb, 0x47, 0xc7,
+	0x72, 0x1f, 0x5e, 0xe5, 0x18, 0xcb, 0x80, 0x6e, 0x41, 0x99, 0x70, 0x88, 0x0c, 0xe8, 0x4a, 0x1a,
+	0x50, 0x4e, 0x89, 0x25, 0xda, 0xf8, 0x0e, 0x5e, 0x89, 0x45, 0xe5, 0xa9, 0xb5, 0xdb, 0xf9, 0x1a,
+	0xaa, 0x71, 0xef, 0x47, 0x3a, 0xd4, 0xbb, 0xfd, 0x0b, 0xb3, 0xd7, 0x3d, 0x1c, 0x5a, 0xe6, 0xe0,
+	0x44, 0x7f, 0x86, 0x00, 0xca, 0x03, 0x0b, 0x77, 0xcc, 0x53, 0x5d, 0x41, 0x1a, 0xa8, 0xfb, 0xa6,
+	0x75, 0xf0, 0x51, 0x2f, 0xec, 0xfc, 0x12, 0x20, 0xdd, 0xda, 0x10, 0x82, 0xc6, 0x79, 0xff, 0xa4,
+	0x7f, 0x76, 0xd9, 0x1f, 0x0e, 0x2c, 0xd3, 0x3a, 0x1f, 0xe8, 0xcf, 0x50, 0x1d, 0xaa, 0x87, 0xdd,
+	0x81, 0xb9, 0xdf, 0xeb, 0x1c, 0xea, 0x0a, 0xaa, 0x41, 0xa5, 0xd3, 0x17, 0x87, 0xc2, 0xce, 0x5f,
+	0xa1, 0x22, 0x1f, 0xe6, 0x68, 0x05, 0x6a, 0xf1, 0xcd, 0x0b, 0x13, 0xeb, 0xcf, 0x50, 0x15, 0x4a,
+	0xfb, 0x67, 0x67, 0x3d, 0x5d, 0x41, 0x15, 0x28, 0x76, 0xfb, 0x96, 0x5e, 0x60, 0x62, 0x8f, 0x7a,
+	0x67, 0xa6, 0xa5, 0x17, 0xa5, 0x36, 0xdd, 0xfe, 0xb1, 0x5e, 0x62, 0x60, 0xdc, 0x39, 0xee, 0x7c,
+	0xa7, 0xab, 0x5c, 0xd6, 0x39, 0x36, 0xad, 0xee, 0x59, 0x5f, 0x2f, 0x33, 0xa2, 0x9e, 0x79, 0xba,
+	0x7f, 0x68, 0xea, 0x15, 0xc6, 0xae, 0xd7, 0x1d, 0x58, 0x7a, 0x95, 0x7d, 0x0d, 0x2c, 0x13, 0xeb,
+	0xda, 0xce, 0x5b, 0x28, 0x4b, 0xbd, 0x01, 0xca, 0x47, 0x66, 0x97, 0x29, 0xf5, 0x8c, 0x69, 0x88,
+	0xcf, 0xfb, 0x7d, 0xc6, 0x5b, 0x61, 0x0c, 0x8f, 0xba, 0xfd, 0xee, 0xe0, 0x23, 0xd7, 0x77, 0x1d,
+	0x54, 0xde, 0x73, 0x18, 0x8f, 0x23, 0x73, 0x60, 0x09, 0x35, 0x71, 0xc7, 0xec, 0xe9, 0xca, 0xce,
+	0x1e, 0xa8, 0xdc, 0x8f, 0xa8, 0x0c, 0x85, 0xb3, 0x13, 0x81, 0xea, 0xf6, 0x8f, 0xce, 0x84, 0xd1,
+	0x97, 0x26, 0xe6, 0x2c, 0x0b, 0x8c, 0xe5, 0x01, 0xee, 0x5a, 0xdd, 0x03, 0xb3, 0xa7, 0x17, 0x77,
+	0x7f, 0xd0, 0x40, 0x3b, 0xb1, 0x03, 0x7b, 0xe4, 0x52, 0x3f, 0x44, 0x1f, 0x40, 0x4b, 0x9e, 0x67,
+	0xe8, 0x33, 0x11, 0xa5, 0xb9, 0xa7, 0x60, 0x6b, 0x6d, 0x1e, 0x2c, 0x33, 0xe8, 0x2b, 0xa8, 0xc8,
+	0x47, 0x1a, 0x12, 0xef, 0xec, 0xd9, 0x27, 0x5b, 0x2b, 0xdd, 0x4b, 0xd1, 0x5b, 0x80, 0xf4, 0xc1,
+	0x83, 0xd6, 0x16, 0xbf, 0x80, 0xe6, 0x2e, 0xa4, 0x3b, 0xbd, 0xbc, 0x90, 0x5b, 0xf2, 0xb3, 0x17,
+	0xde, 0x01, 0xa4, 0x3b, 0xb7, 0xbc, 0x90, 0x5b, 0xc2, 0x5b, 0x22, 0x0f, 0xf9, 0xcf, 0x20, 0xf4,
+	0x0d, 0x40, 0xba, 0x80, 0xcb, 0x1b, 0xb9, 0x8d, 0xbc, 0xd5, 0x48, 0x44, 0xf0, 0xd4, 0x7f, 0xa7,
+	0xa0, 0x2e, 0x34, 0x66, 0x57, 0x44, 0xd4, 0x4a, 0x1c, 0x94, 0x5b, 0x4c, 0x5b, 0xeb, 0x0b, 0x71,
+	0xd2, 0x83, 0xef, 0xa1, 0x9e, 0xdd, 0x11, 0x51, 0x33, 0x76, 0xe3, 0xfc, 0x82, 0xd3, 0x9a, 0xdb,
+	0x34, 0xd9, 0xcd, 0xec, 0xda, 0x28, 0x6f, 0x2e, 0xd8, 0x24, 0x73, 0x37, 0xbf, 0x81, 0x5a, 0x66,
+	0x43, 0x44, 0xaf, 0x32, 0xe8, 0xec, 0xce, 0xb8, 0xfc, 0x1e, 0xdf, 0xfa, 0x66, 0xee, 0x65, 0xf7,
+	0xc0, 0xdc, 0xbd, 0x0f, 0xb0, 0x32, 0xb7, 0xb4, 0xa1, 0xf5, 0x4c, 0x74, 0x72, 0x96, 0x66, 0x43,
+	0xf4, 0x1b, 0xa8, 0x65, 0xf6, 0x0c, 0x29, 0x33, 0xbf, 0xd7, 0xb4, 0x9a, 0x79, 0x84, 0xf4, 0xf0,
+	0x5b, 0xd0, 0x92, 0x5d, 0x43, 0xe6, 0xf7, 0xfc, 0xee, 0xd1, 0xca, 0x6e, 0x29, 0xe8, 0x17, 0x50,
+	0xcf, 0xee, 0x1d, 0xd2, 0xb1, 0x0b, 0x56, 0x91, 0xd9, 0x6b, 0x7b, 0x50, 0xcf, 0x2e, 0x03, 0xf2,
+	0xda, 0x82, 0xfd, 0x60, 0xc6, 0xbe, 0x5f, 0x03, 0xa4, 0xa3, 0x16, 0x65, 0xea, 0x2c, 0x3b, 0xd3,
+	0x5b, 0xaf, 0x72, 0x70, 0x69, 0xdc, 0xcf, 0xa0, 0x1a, 0xcf, 0x5b, 0xf4, 0x32, 0xa9, 0xc0, 0xcc,
+	0x60, 0x6d, 0x65, 0xa6, 0x34, 0xda, 0x85, 0x5a, 0x66, 0xf4, 0x4a, 0x67, 0xe6, 0x87, 0xf1, 0x8c,
+	0x82, 0x3d, 0x58, 0x99, 0x9b, 0x1f, 0x68, 0x7d, 0x56, 0x9b, 0x99, 0x81, 0xd0, 0xda, 0x58, 0x8c,
+	0x94, 0xfa, 0x1e, 0x80, 0x3e, 0x3f, 0x49, 0xd0, 0x46, 0xa6, 0xee, 0xf2, 0xfc, 0xe6, 0x87, 0xd2,
+	0x3b, 0xe5, 0xaa, 0xcc, 0x7f, 0xec, 0x7e, 0xfd, 0x9f, 0x01, 0x00, 0x6e, 0xeb, 0x2a, 0xb6, 0xe5,
+	0x15, 0x00, 0x00,
+}
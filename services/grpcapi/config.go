@@ -0,0 +1,50 @@
+package grpcapi
+
+import (
+	"net"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultBindAddress   = ":9094"
+	DefaultWatchInterval = toml.Duration(time.Second)
+)
+
+type Config struct {
+	Enabled     bool   `toml:"enabled"`
+	BindAddress string `toml:"bind-address"`
+
+	TLSEnabled     bool   `toml:"tls-enabled"`
+	TLSCertificate string `toml:"tls-certificate"`
+	TLSPrivateKey  string `toml:"tls-private-key"`
+
+	// How often the state of tasks is polled for watch requests.
+	WatchInterval toml.Duration `toml:"watch-interval"`
+}
+
+func NewConfig() Config {
+	return Config{
+		BindAddress:    DefaultBindAddress,
+		TLSCertificate: "/etc/ssl/kapacitor.pem",
+		WatchInterval:  DefaultWatchInterval,
+	}
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(c.BindAddress); err != nil {
+		return errors.Wrapf(err, "invalid grpc bind address %s", c.BindAddress)
+	}
+	if c.TLSEnabled && c.TLSCertificate == "" {
+		return errors.New("must specify tls-certificate when tls is enabled")
+	}
+	if c.WatchInterval <= 0 {
+		return errors.New("watch-interval must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,698 @@
+package grpcapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/influxdata/kapacitor/alert"
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/grpcapi/api"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// Number of topic events buffered per watch call.
+// Calls that fall further behind are aborted.
+const watchBufferSize = 100
+
+// Fields of the tasks compared by watch calls.
+var watchTaskFields = []string{
+	"type",
+	"dbrps",
+	"script",
+	"status",
+	"executing",
+	"error",
+	"created",
+	"modified",
+	"last-enabled",
+	"vars",
+}
+
+func (s *Service) ListTasks(ctx context.Context, req *api.ListTasksRequest) (*api.ListTasksResponse, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := c.ListTasks(&client.ListTasksOptions{
+		Pattern: req.Pattern,
+		Offset:  int(req.Offset),
+		Limit:   int(req.Limit),
+	})
+	if err != nil {
+		return nil, c.error(err)
+	}
+	resp := &api.ListTasksResponse{Tasks: make([]*api.Task, len(tasks))}
+	for i, t := range tasks {
+		if resp.Tasks[i], err = toTask(t); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (s *Service) GetTask(ctx context.Context, req *api.GetTaskRequest) (*api.Task, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t, err := c.Task(c.TaskLink(req.Id), nil)
+	if err != nil {
+		return nil, c.error(err)
+	}
+	return toTask(t)
+}
+
+func (s *Service) CreateTask(ctx context.Context, req *api.CreateTaskRequest) (*api.Task, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := fromVars(req.Vars)
+	if err != nil {
+		return nil, err
+	}
+	t, err := c.CreateTask(client.CreateTaskOptions{
+		ID:         req.Id,
+		TemplateID: req.TemplateId,
+		Type:       client.TaskType(req.Type),
+		DBRPs:      fromDBRPs(req.Dbrps),
+		TICKscript: req.TickScript,
+		Status:     client.TaskStatus(req.Status),
+		Vars:       vars,
+	})
+	if err != nil {
+		return nil, c.error(err)
+	}
+	return toTask(t)
+}
+
+func (s *Service) UpdateTask(ctx context.Context, req *api.UpdateTaskRequest) (*api.Task, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := fromVars(req.Vars)
+	if err != nil {
+		return nil, err
+	}
+	t, err := c.UpdateTask(c.TaskLink(req.Id), client.UpdateTaskOptions{
+		TemplateID: req.TemplateId,
+		Type:       client.TaskType(req.Type),
+		DBRPs:      fromDBRPs(req.Dbrps),
+		TICKscript: req.TickScript,
+		Status:     client.TaskStatus(req.Status),
+		Vars:       vars,
+	})
+	if err != nil {
+		return nil, c.error(err)
+	}
+	return toTask(t)
+}
+
+func (s *Service) DeleteTask(ctx context.Context, req *api.DeleteTaskRequest) (*api.Empty, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.DeleteTask(c.TaskLink(req.Id)); err != nil {
+		return nil, c.error(err)
+	}
+	return &api.Empty{}, nil
+}
+
+// WatchTasks polls the tasks and sends an event for each new, changed or deleted task.
+func (s *Service) WatchTasks(req *api.WatchTasksRequest, stream api.Kapacitor_WatchTasksServer) error {
+	ctx := stream.Context()
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(s.watchInterval())
+	defer ticker.Stop()
+
+	known := make(map[string]*api.Task)
+	for {
+		tasks, err := c.listAllTasks(req.Pattern)
+		if err != nil {
+			return c.error(err)
+		}
+		seen := make(map[string]bool, len(tasks))
+		for _, t := range tasks {
+			seen[t.ID] = true
+			task, err := toTask(t)
+			if err != nil {
+				return err
+			}
+			if prev, ok := known[t.ID]; ok && proto.Equal(prev, task) {
+				continue
+			}
+			known[t.ID] = task
+			if err := stream.Send(&api.TaskEvent{Task: task}); err != nil {
+				return err
+			}
+		}
+		var deleted []string
+		for id := range known {
+			if !seen[id] {
+				deleted = append(deleted, id)
+			}
+		}
+		sort.Strings(deleted)
+		for _, id := range deleted {
+			delete(known, id)
+			if err := stream.Send(&api.TaskEvent{Task: &api.Task{Id: id}, Deleted: true}); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (c *call) listAllTasks(pattern string) ([]client.Task, error) {
+	const limit = 100
+	var all []client.Task
+	for {
+		tasks, err := c.ListTasks(&client.ListTasksOptions{
+			Pattern: pattern,
+			Fields:  watchTaskFields,
+			Offset:  len(all),
+			Limit:   limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, tasks...)
+		if len(tasks) < limit {
+			return all, nil
+		}
+	}
+}
+
+func (s *Service) ListRecordings(ctx context.Context, req *api.ListRecordingsRequest) (*api.ListRecordingsResponse, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recordings, err := c.ListRecordings(&client.ListRecordingsOptions{
+		Pattern: req.Pattern,
+		Offset:  int(req.Offset),
+		Limit:   int(req.Limit),
+	})
+	if err != nil {
+		return nil, c.error(err)
+	}
+	resp := &api.ListRecordingsResponse{Recordings: make([]*api.Recording, len(recordings))}
+	for i, r := range recordings {
+		resp.Recordings[i] = toRecording(r)
+	}
+	return resp, nil
+}
+
+func (s *Service) GetRecording(ctx context.Context, req *api.GetRecordingRequest) (*api.Recording, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := c.Recording(c.RecordingLink(req.Id))
+	if err != nil {
+		return nil, c.error(err)
+	}
+	return toRecording(r), nil
+}
+
+func (s *Service) RecordStream(ctx context.Context, req *api.RecordStreamRequest) (*api.Recording, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := c.RecordStream(client.RecordStreamOptions{
+		ID:   req.Id,
+		Task: req.Task,
+		Stop: fromTime(req.Stop),
+	})
+	if err != nil {
+		return nil, c.error(err)
+	}
+	return toRecording(r), nil
+}
+
+func (s *Service) RecordBatch(ctx context.Context, req *api.RecordBatchRequest) (*api.Recording, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := c.RecordBatch(client.RecordBatchOptions{
+		ID:    req.Id,
+		Task:  req.Task,
+		Start: fromTime(req.Start),
+		Stop:  fromTime(req.Stop),
+	})
+	if err != nil {
+		return nil, c.error(err)
+	}
+	return toRecording(r), nil
+}
+
+func (s *Service) RecordQuery(ctx context.Context, req *api.RecordQueryRequest) (*api.Recording, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := c.RecordQuery(client.RecordQueryOptions{
+		ID:      req.Id,
+		Query:   req.Query,
+		Type:    client.TaskType(req.Type),
+		Cluster: req.Cluster,
+	})
+	if err != nil {
+		return nil, c.error(err)
+	}
+	return toRecording(r), nil
+}
+
+func (s *Service) DeleteRecording(ctx context.Context, req *api.DeleteRecordingRequest) (*api.Empty, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.DeleteRecording(c.RecordingLink(req.Id)); err != nil {
+		return nil, c.error(err)
+	}
+	return &api.Empty{}, nil
+}
+
+func (s *Service) ListReplays(ctx context.Context, req *api.ListReplaysRequest) (*api.ListReplaysResponse, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	replays, err := c.ListReplays(&client.ListReplaysOptions{
+		Pattern: req.Pattern,
+		Offset:  int(req.Offset),
+		Limit:   int(req.Limit),
+	})
+	if err != nil {
+		return nil, c.error(err)
+	}
+	resp := &api.ListReplaysResponse{Replays: make([]*api.Replay, len(replays))}
+	for i, r := range replays {
+		resp.Replays[i] = toReplay(r)
+	}
+	return resp, nil
+}
+
+func (s *Service) GetReplay(ctx context.Context, req *api.GetReplayRequest) (*api.Replay, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := c.Replay(c.ReplayLink(req.Id))
+	if err != nil {
+		return nil, c.error(err)
+	}
+	return toReplay(r), nil
+}
+
+func (s *Service) CreateReplay(ctx context.Context, req *api.CreateReplayRequest) (*api.Replay, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := c.CreateReplay(client.CreateReplayOptions{
+		ID:            req.Id,
+		Task:          req.Task,
+		Recording:     req.Recording,
+		RecordingTime: req.RecordingTime,
+		Clock:         client.Clock(req.Clock),
+	})
+	if err != nil {
+		return nil, c.error(err)
+	}
+	return toReplay(r), nil
+}
+
+func (s *Service) DeleteReplay(ctx context.Context, req *api.DeleteReplayRequest) (*api.Empty, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.DeleteReplay(c.ReplayLink(req.Id)); err != nil {
+		return nil, c.error(err)
+	}
+	return &api.Empty{}, nil
+}
+
+func (s *Service) ListTopics(ctx context.Context, req *api.ListTopicsRequest) (*api.ListTopicsResponse, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	topics, err := c.ListTopics(&client.ListTopicsOptions{
+		Pattern:  req.Pattern,
+		MinLevel: alert.Level(req.MinLevel).String(),
+	})
+	if err != nil {
+		return nil, c.error(err)
+	}
+	resp := &api.ListTopicsResponse{Topics: make([]*api.Topic, len(topics.Topics))}
+	for i, t := range topics.Topics {
+		resp.Topics[i] = toTopic(t)
+	}
+	return resp, nil
+}
+
+func (s *Service) GetTopic(ctx context.Context, req *api.GetTopicRequest) (*api.Topic, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t, err := c.Topic(c.TopicLink(req.Id))
+	if err != nil {
+		return nil, c.error(err)
+	}
+	return toTopic(t), nil
+}
+
+func (s *Service) DeleteTopic(ctx context.Context, req *api.DeleteTopicRequest) (*api.Empty, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.DeleteTopic(c.TopicLink(req.Id)); err != nil {
+		return nil, c.error(err)
+	}
+	return &api.Empty{}, nil
+}
+
+func (s *Service) ListTopicEvents(ctx context.Context, req *api.ListTopicEventsRequest) (*api.ListTopicEventsResponse, error) {
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	events, err := c.ListTopicEvents(c.TopicEventsLink(req.Topic), &client.ListTopicEventsOptions{
+		MinLevel: alert.Level(req.MinLevel).String(),
+	})
+	if err != nil {
+		return nil, c.error(err)
+	}
+	resp := &api.ListTopicEventsResponse{Events: make([]*api.TopicEvent, len(events.Events))}
+	for i, e := range events.Events {
+		resp.Events[i] = &api.TopicEvent{
+			Id:    e.ID,
+			Topic: req.Topic,
+			State: &api.EventState{
+				Message:  e.State.Message,
+				Details:  e.State.Details,
+				Time:     toTime(e.State.Time),
+				Duration: int64(e.State.Duration),
+				Level:    toLevel(e.State.Level),
+			},
+		}
+	}
+	return resp, nil
+}
+
+// WatchTopicEvents sends the events of the topic as they are collected.
+func (s *Service) WatchTopicEvents(req *api.WatchTopicEventsRequest, stream api.Kapacitor_WatchTopicEventsServer) error {
+	if s.AlertService == nil {
+		return grpc.Errorf(codes.Unimplemented, "alert service is not available")
+	}
+	ctx := stream.Context()
+	c, err := s.newCall(ctx)
+	if err != nil {
+		return err
+	}
+	// Check that the caller may read topics, the topic itself may not exist yet.
+	if _, err := c.ListTopics(&client.ListTopicsOptions{Pattern: req.Topic}); err != nil {
+		return c.error(err)
+	}
+
+	w := &topicWatcher{
+		minLevel: alert.Level(req.MinLevel),
+		events:   make(chan alert.Event, watchBufferSize),
+		overflow: make(chan struct{}),
+	}
+	s.AlertService.RegisterAnonHandler(req.Topic, w)
+	defer s.AlertService.DeregisterAnonHandler(req.Topic, w)
+
+	for {
+		select {
+		case e := <-w.events:
+			if err := stream.Send(&api.TopicEvent{
+				Id:    e.State.ID,
+				Topic: e.Topic,
+				State: &api.EventState{
+					Message:  e.State.Message,
+					Details:  e.State.Details,
+					Time:     toTime(e.State.Time),
+					Duration: int64(e.State.Duration),
+					Level:    api.Level(e.State.Level),
+				},
+			}); err != nil {
+				return err
+			}
+		case <-w.overflow:
+			return grpc.Errorf(codes.ResourceExhausted, "too many pending events, events were dropped")
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// topicWatcher buffers the events of a topic for a watch call.
+type topicWatcher struct {
+	minLevel alert.Level
+	events   chan alert.Event
+	overflow chan struct{}
+	once     sync.Once
+}
+
+func (w *topicWatcher) Handle(event alert.Event) {
+	if event.State.Level < w.minLevel {
+		return
+	}
+	select {
+	case w.events <- event:
+	default:
+		w.once.Do(func() { close(w.overflow) })
+	}
+}
+
+func toTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromTime(t int64) time.Time {
+	if t == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, t).UTC()
+}
+
+func toLevel(l string) api.Level {
+	level, _ := alert.ParseLevel(l)
+	return api.Level(level)
+}
+
+func toTask(t client.Task) (*api.Task, error) {
+	vars, err := toVars(t.Vars)
+	if err != nil {
+		return nil, grpc.Errorf(codes.Internal, "task %s: %v", t.ID, err)
+	}
+	task := &api.Task{
+		Id:          t.ID,
+		TemplateId:  t.TemplateID,
+		Type:        api.TaskType(t.Type),
+		TickScript:  t.TICKscript,
+		Vars:        vars,
+		Dot:         t.Dot,
+		Status:      api.TaskStatus(t.Status),
+		Executing:   t.Executing,
+		Error:       t.Error,
+		Created:     toTime(t.Created),
+		Modified:    toTime(t.Modified),
+		LastEnabled: toTime(t.LastEnabled),
+	}
+	for _, d := range t.DBRPs {
+		task.Dbrps = append(task.Dbrps, &api.DBRP{
+			Database:        d.Database,
+			RetentionPolicy: d.RetentionPolicy,
+		})
+	}
+	return task, nil
+}
+
+func fromDBRPs(dbrps []*api.DBRP) []client.DBRP {
+	if len(dbrps) == 0 {
+		return nil
+	}
+	ds := make([]client.DBRP, len(dbrps))
+	for i, d := range dbrps {
+		ds[i] = client.DBRP{
+			Database:        d.Database,
+			RetentionPolicy: d.RetentionPolicy,
+		}
+	}
+	return ds
+}
+
+func toVars(vars client.Vars) (map[string]*api.Var, error) {
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	vs := make(map[string]*api.Var, len(vars))
+	for name, v := range vars {
+		av, err := toVar(v)
+		if err != nil {
+			return nil, fmt.Errorf("var %s: %v", name, err)
+		}
+		vs[name] = av
+	}
+	return vs, nil
+}
+
+func toVar(v client.Var) (*api.Var, error) {
+	av := &api.Var{
+		Type:        api.VarType(v.Type),
+		Description: v.Description,
+	}
+	// Values of list elements are not converted by the client.
+	if n, ok := v.Value.(json.Number); ok {
+		switch v.Type {
+		case client.VarFloat:
+			f, err := n.Float64()
+			if err != nil {
+				return nil, err
+			}
+			v.Value = f
+		default:
+			i, err := n.Int64()
+			if err != nil {
+				return nil, err
+			}
+			v.Value = i
+		}
+	}
+	switch value := v.Value.(type) {
+	case nil:
+	case bool:
+		av.BoolValue = value
+	case int64:
+		av.IntValue = value
+	case time.Duration:
+		av.IntValue = int64(value)
+	case float64:
+		av.FloatValue = value
+	case string:
+		av.StringValue = value
+	case []client.Var:
+		for _, e := range value {
+			ae, err := toVar(e)
+			if err != nil {
+				return nil, err
+			}
+			av.ListValue = append(av.ListValue, ae)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected value %T for var of type %v", v.Value, v.Type)
+	}
+	return av, nil
+}
+
+func fromVars(vars map[string]*api.Var) (client.Vars, error) {
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	vs := make(client.Vars, len(vars))
+	for name, av := range vars {
+		v, err := fromVar(av)
+		if err != nil {
+			return nil, grpc.Errorf(codes.InvalidArgument, "var %s: %v", name, err)
+		}
+		vs[name] = v
+	}
+	return vs, nil
+}
+
+func fromVar(av *api.Var) (client.Var, error) {
+	if av == nil {
+		return client.Var{}, fmt.Errorf("missing var")
+	}
+	v := client.Var{
+		Type:        client.VarType(av.Type),
+		Description: av.Description,
+	}
+	switch v.Type {
+	case client.VarBool:
+		v.Value = av.BoolValue
+	case client.VarInt:
+		v.Value = av.IntValue
+	case client.VarDuration:
+		v.Value = time.Duration(av.IntValue)
+	case client.VarFloat:
+		v.Value = av.FloatValue
+	case client.VarString, client.VarRegex, client.VarLambda:
+		v.Value = av.StringValue
+	case client.VarStar:
+	case client.VarList:
+		list := make([]client.Var, len(av.ListValue))
+		for i, e := range av.ListValue {
+			var err error
+			if list[i], err = fromVar(e); err != nil {
+				return client.Var{}, err
+			}
+		}
+		v.Value = list
+	default:
+		return client.Var{}, fmt.Errorf("unknown var type %v", av.Type)
+	}
+	return v, nil
+}
+
+func toRecording(r client.Recording) *api.Recording {
+	return &api.Recording{
+		Id:       r.ID,
+		Type:     api.TaskType(r.Type),
+		Size:     r.Size,
+		Date:     toTime(r.Date),
+		Error:    r.Error,
+		Status:   api.Status(r.Status),
+		Progress: r.Progress,
+	}
+}
+
+func toReplay(r client.Replay) *api.Replay {
+	return &api.Replay{
+		Id:            r.ID,
+		Task:          r.Task,
+		Recording:     r.Recording,
+		RecordingTime: r.RecordingTime,
+		Clock:         api.Clock(r.Clock),
+		Date:          toTime(r.Date),
+		Error:         r.Error,
+		Status:        api.Status(r.Status),
+		Progress:      r.Progress,
+	}
+}
+
+func toTopic(t client.Topic) *api.Topic {
+	return &api.Topic{
+		Id:        t.ID,
+		Level:     toLevel(t.Level),
+		Collected: t.Collected,
+	}
+}
@@ -0,0 +1,179 @@
+// Package grpcapi serves the gRPC API of Kapacitor.
+//
+// Each call is translated into a request to the HTTP API handler,
+// so that both APIs share authentication, authorization and validation.
+package grpcapi
+
+//go:generate protoc --go_out=plugins=grpc:api -I api api/api.proto
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor/alert"
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/grpcapi/api"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// URL of the HTTP API used by the local transport, it is never dialed.
+const localURL = "http://localhost:9092"
+
+type Diagnostic interface {
+	Error(msg string, err error)
+	StartedListening(addr string)
+}
+
+type Service struct {
+	config   Config
+	handler  http.Handler
+	server   *grpc.Server
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	AlertService interface {
+		RegisterAnonHandler(topic string, h alert.Handler)
+		DeregisterAnonHandler(topic string, h alert.Handler)
+	}
+
+	diag Diagnostic
+}
+
+// NewService creates a gRPC service that handles calls with the HTTP API handler h.
+func NewService(c Config, h http.Handler, d Diagnostic) *Service {
+	return &Service{
+		config:  c,
+		handler: h,
+		diag:    d,
+	}
+}
+
+func (s *Service) Open() error {
+	if !s.config.Enabled {
+		return nil
+	}
+	var opts []grpc.ServerOption
+	if s.config.TLSEnabled {
+		key := s.config.TLSPrivateKey
+		if key == "" {
+			key = s.config.TLSCertificate
+		}
+		creds, err := credentials.NewServerTLSFromFile(s.config.TLSCertificate, key)
+		if err != nil {
+			return errors.Wrap(err, "failed to load tls certificate")
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	l, err := net.Listen("tcp", s.config.BindAddress)
+	if err != nil {
+		return err
+	}
+	s.listener = l
+	s.server = grpc.NewServer(opts...)
+	api.RegisterKapacitorServer(s.server, s)
+	s.diag.StartedListening(l.Addr().String())
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.server.Serve(l); err != nil {
+			s.diag.Error("grpc server stopped", err)
+		}
+	}()
+	return nil
+}
+
+func (s *Service) Close() error {
+	if s.server != nil {
+		s.server.Stop()
+		s.wg.Wait()
+	}
+	return nil
+}
+
+// Addr returns the address the service listens on, nil if it is disabled.
+func (s *Service) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+func (s *Service) watchInterval() time.Duration {
+	return time.Duration(s.config.WatchInterval)
+}
+
+// authTransport forwards the credentials of the call to the HTTP API handler
+// and records the status of the last response.
+type authTransport struct {
+	authorization string
+	transport     http.RoundTripper
+	status        int
+}
+
+func (t *authTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if t.authorization != "" {
+		r.Header.Set("Authorization", t.authorization)
+	}
+	resp, err := t.transport.RoundTrip(r)
+	if err == nil {
+		t.status = resp.StatusCode
+	}
+	return resp, err
+}
+
+// call is a client of the HTTP API for a single gRPC call.
+type call struct {
+	*client.Client
+	transport *authTransport
+}
+
+func (s *Service) newCall(ctx context.Context) (*call, error) {
+	t := &authTransport{
+		transport: client.NewLocalTransport(s.handler),
+	}
+	if md, ok := metadata.FromContext(ctx); ok {
+		if a := md["authorization"]; len(a) > 0 {
+			t.authorization = a[0]
+		}
+	}
+	cli, err := client.New(client.Config{
+		URL:       localURL,
+		UserAgent: "internal-grpc-service",
+		Transport: t,
+	})
+	if err != nil {
+		return nil, grpc.Errorf(codes.Internal, "failed to create client: %v", err)
+	}
+	return &call{Client: cli, transport: t}, nil
+}
+
+// error converts an error of the HTTP API into a gRPC error.
+func (c *call) error(err error) error {
+	if err == nil {
+		return nil
+	}
+	code := codes.Unknown
+	switch c.transport.status {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.AlreadyExists
+	case http.StatusInternalServerError:
+		code = codes.Internal
+	}
+	return grpc.Errorf(code, "%s", err.Error())
+}
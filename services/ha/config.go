@@ -0,0 +1,114 @@
+package ha
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultConsulAddress = "127.0.0.1:8500"
+	DefaultConsulScheme  = "http"
+	DefaultKey           = "kapacitor/ha/leader"
+	DefaultSessionTTL    = 15 * time.Second
+	DefaultLockDelay     = 15 * time.Second
+	DefaultSyncInterval  = 5 * time.Second
+
+	// Consul does not accept shorter session TTLs.
+	minSessionTTL = 10 * time.Second
+)
+
+// DefaultNamespaces are the storage namespaces of the task definitions, the alert handlers and the alert topic states.
+var DefaultNamespaces = []string{"task_store", "alert_store"}
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+	// URL of the HTTP API of this server, the standby server replicates the state of the leader from it.
+	AdvertiseURL string `toml:"advertise-url"`
+
+	// Consul agent electing the leader.
+	ConsulAddress    string `toml:"consul-address"`
+	ConsulScheme     string `toml:"consul-scheme"`
+	ConsulDatacenter string `toml:"consul-datacenter"`
+	ConsulToken      string `toml:"consul-token"`
+	// Key of the leader lock in the Consul KV store, both servers of the pair must use the same key.
+	Key string `toml:"key"`
+	// The leader steps down when it could not renew its session for half of the TTL.
+	SessionTTL toml.Duration `toml:"session-ttl"`
+	// How long the lock cannot be acquired after the session of the leader expired.
+	LockDelay toml.Duration `toml:"lock-delay"`
+
+	// How often the standby server replicates the state of the leader.
+	SyncInterval toml.Duration `toml:"sync-interval"`
+	// Storage namespaces replicated from the leader.
+	Namespaces []string `toml:"namespaces"`
+
+	// Credentials of an admin user of the leader.
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	Token    string `toml:"token"`
+	// Path to CA file
+	SSLCA string `toml:"ssl-ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl-cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl-key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool `toml:"insecure-skip-verify"`
+}
+
+func NewConfig() Config {
+	return Config{
+		ConsulAddress: DefaultConsulAddress,
+		ConsulScheme:  DefaultConsulScheme,
+		Key:           DefaultKey,
+		SessionTTL:    toml.Duration(DefaultSessionTTL),
+		LockDelay:     toml.Duration(DefaultLockDelay),
+		SyncInterval:  toml.Duration(DefaultSyncInterval),
+		Namespaces:    DefaultNamespaces,
+	}
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.AdvertiseURL == "" {
+		return errors.New("must specify advertise-url")
+	}
+	u, err := url.Parse(c.AdvertiseURL)
+	if err != nil {
+		return errors.Wrapf(err, "invalid advertise-url %q", c.AdvertiseURL)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Errorf("invalid advertise-url %q, must be an http(s) URL", c.AdvertiseURL)
+	}
+	if strings.TrimSpace(c.ConsulAddress) == "" {
+		return errors.New("must specify consul-address")
+	}
+	if c.ConsulScheme != "http" && c.ConsulScheme != "https" {
+		return errors.Errorf("invalid consul-scheme %q, must be http or https", c.ConsulScheme)
+	}
+	if c.Key == "" {
+		return errors.New("must specify key")
+	}
+	if time.Duration(c.SessionTTL) < minSessionTTL {
+		return errors.Errorf("session-ttl must be at least %v", minSessionTTL)
+	}
+	if c.LockDelay < 0 {
+		return errors.New("lock-delay must not be negative")
+	}
+	if c.SyncInterval <= 0 {
+		return errors.New("sync-interval must be positive")
+	}
+	if len(c.Namespaces) == 0 {
+		return errors.New("must specify at least one namespace")
+	}
+	if c.Token != "" && (c.Username != "" || c.Password != "") {
+		return errors.New("cannot use both token and username/password")
+	}
+	return nil
+}
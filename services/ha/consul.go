@@ -0,0 +1,104 @@
+package ha
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/pkg/errors"
+)
+
+// errSessionExpired is returned when renewing a session which has expired.
+var errSessionExpired = errors.New("session expired")
+
+// elector stores the leader lock, held by the session of the leader.
+type elector interface {
+	// CreateSession creates a session which expires after the TTL unless it is renewed.
+	// The lock cannot be acquired for the lock delay after a session holding it expired.
+	CreateSession(ttl, lockDelay time.Duration) (string, error)
+	RenewSession(id string) error
+	DestroySession(id string) error
+	// Acquire acquires the lock with the session and sets its value,
+	// it succeeds if the lock is free or already held by the session.
+	Acquire(key, session string, value []byte) (bool, error)
+	Release(key, session string) error
+	// Get returns the value of the lock and the session holding it, if any.
+	Get(key string) (value []byte, session string, err error)
+}
+
+type consulElector struct {
+	client *api.Client
+}
+
+// newConsulElector returns an elector using the Consul agent.
+// Requests time out after the timeout, so that the leader notices in time when it cannot renew its session.
+func newConsulElector(c Config, timeout time.Duration) (*consulElector, error) {
+	client, err := api.NewClient(&api.Config{
+		Address:    c.ConsulAddress,
+		Scheme:     c.ConsulScheme,
+		Datacenter: c.ConsulDatacenter,
+		Token:      c.ConsulToken,
+		HttpClient: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create consul client")
+	}
+	return &consulElector{client: client}, nil
+}
+
+func (e *consulElector) CreateSession(ttl, lockDelay time.Duration) (string, error) {
+	// The session has no health checks, it only expires when it is not renewed.
+	id, _, err := e.client.Session().CreateNoChecks(&api.SessionEntry{
+		Name:      "kapacitor-ha",
+		TTL:       ttl.String(),
+		LockDelay: lockDelay,
+		Behavior:  api.SessionBehaviorRelease,
+	}, nil)
+	return id, err
+}
+
+func (e *consulElector) RenewSession(id string) error {
+	entry, _, err := e.client.Session().Renew(id, nil)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return errSessionExpired
+	}
+	return nil
+}
+
+func (e *consulElector) DestroySession(id string) error {
+	_, err := e.client.Session().Destroy(id, nil)
+	return err
+}
+
+func (e *consulElector) Acquire(key, session string, value []byte) (bool, error) {
+	ok, _, err := e.client.KV().Acquire(&api.KVPair{
+		Key:     key,
+		Value:   value,
+		Session: session,
+	}, nil)
+	return ok, err
+}
+
+func (e *consulElector) Release(key, session string) error {
+	_, _, err := e.client.KV().Release(&api.KVPair{
+		Key:     key,
+		Session: session,
+	}, nil)
+	return err
+}
+
+func (e *consulElector) Get(key string) ([]byte, string, error) {
+	pair, _, err := e.client.KV().Get(key, &api.QueryOptions{RequireConsistent: true})
+	if err != nil || pair == nil {
+		return nil, "", err
+	}
+	return pair.Value, pair.Session, nil
+}
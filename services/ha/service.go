@@ -0,0 +1,413 @@
+// Package ha runs two servers as a high availability pair.
+//
+// The servers elect the leader with a lock in the Consul KV store.
+// Only the leader runs tasks, and so sends alerts.
+// The standby server replicates the task definitions, the alert handlers and the alert topic states of the leader,
+// rejects the API requests changing them, and takes over when the leader fails.
+//
+// The leader steps down when it could not renew its session for half of the session TTL,
+// which is before Consul lets the standby server acquire the lock after the session expired.
+// Since the new leader starts its tasks with the replicated alert topic states,
+// it does not send again the notifications the previous leader sent,
+// except for the alerts which changed since the last replication.
+package ha
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/server/vars"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/services/storage"
+	"github.com/influxdata/kapacitor/tlsconfig"
+	"github.com/pkg/errors"
+)
+
+const (
+	haPath       = "/ha"
+	snapshotPath = haPath + "/snapshot"
+
+	RoleLeader  = "leader"
+	RoleStandby = "standby"
+)
+
+type Diagnostic interface {
+	Error(msg string, err error)
+	BecameLeader()
+	BecameStandby()
+	NewLeader(id, url string)
+}
+
+type Service struct {
+	config Config
+	info   vars.Infoer
+	diag   Diagnostic
+	id     string
+	routes []httpd.Route
+
+	elector elector
+	// Session of the server, empty until it is created.
+	session   string
+	lastRenew time.Time
+	lastSync  time.Time
+	// Client of the API of the leader.
+	leaderClient    *client.Client
+	leaderClientURL string
+
+	mu         sync.RWMutex
+	leader     bool
+	leaderInfo *client.HALeader
+	syncedAt   time.Time
+
+	closing chan struct{}
+	wg      sync.WaitGroup
+
+	StorageService interface {
+		Snapshot(namespace string) ([]*storage.KeyValue, error)
+		Restore(namespace string, kvs []*storage.KeyValue) error
+	}
+	HTTPDService interface {
+		AddRoutes([]httpd.Route) error
+		DelRoutes([]httpd.Route)
+	}
+	TaskStore interface {
+		Standby()
+		Activate() error
+	}
+	AlertService interface {
+		Reload() error
+	}
+}
+
+func NewService(c Config, info vars.Infoer, d Diagnostic) *Service {
+	return &Service{
+		config: c,
+		info:   info,
+		diag:   d,
+	}
+}
+
+func (s *Service) Open() error {
+	s.id = s.info.ServerID().String()
+	if s.elector == nil {
+		e, err := newConsulElector(s.config, s.interval())
+		if err != nil {
+			return err
+		}
+		s.elector = e
+	}
+
+	// The routes have no scope so only admin users may use them.
+	s.routes = []httpd.Route{
+		{
+			Method:      "GET",
+			Pattern:     haPath,
+			HandlerFunc: s.handleStatus,
+		},
+		{
+			Method:      "GET",
+			Pattern:     snapshotPath,
+			HandlerFunc: s.handleSnapshot,
+		},
+	}
+	if err := s.HTTPDService.AddRoutes(s.routes); err != nil {
+		return errors.Wrap(err, "failed to add API routes")
+	}
+
+	s.closing = make(chan struct{})
+	s.wg.Add(1)
+	go s.run()
+	return nil
+}
+
+func (s *Service) Close() error {
+	if s.HTTPDService != nil {
+		s.HTTPDService.DelRoutes(s.routes)
+	}
+	if s.closing == nil {
+		return nil
+	}
+	close(s.closing)
+	s.wg.Wait()
+	// Hand over to the standby server, the tasks are already stopped.
+	if s.IsLeader() {
+		s.stepDown()
+	}
+	if s.session != "" {
+		if err := s.elector.Release(s.config.Key, s.session); err != nil {
+			s.diag.Error("failed to release leader lock", err)
+		}
+		if err := s.elector.DestroySession(s.session); err != nil {
+			s.diag.Error("failed to destroy session", err)
+		}
+		s.session = ""
+	}
+	return nil
+}
+
+// interval is how often the session is renewed and the leader lock is acquired.
+func (s *Service) interval() time.Duration {
+	return time.Duration(s.config.SessionTTL) / 6
+}
+
+func (s *Service) run() {
+	defer s.wg.Done()
+	interval := s.interval()
+	if syncInterval := time.Duration(s.config.SyncInterval); syncInterval < interval {
+		interval = syncInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.elect(time.Now())
+		if !s.IsLeader() && time.Since(s.lastSync) >= time.Duration(s.config.SyncInterval) {
+			s.lastSync = time.Now()
+			if err := s.sync(); err != nil {
+				s.diag.Error("failed to replicate the state of the leader", err)
+			}
+		}
+		select {
+		case <-s.closing:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// elect renews the session and acquires the leader lock with it,
+// the server is the leader as long as it holds the lock.
+func (s *Service) elect(now time.Time) {
+	if s.session == "" {
+		id, err := s.elector.CreateSession(time.Duration(s.config.SessionTTL), time.Duration(s.config.LockDelay))
+		if err != nil {
+			s.diag.Error("failed to create session", err)
+			return
+		}
+		s.session, s.lastRenew = id, now
+	} else if err := s.elector.RenewSession(s.session); err != nil {
+		if err == errSessionExpired {
+			s.session = ""
+			if s.IsLeader() {
+				s.stepDown()
+			}
+			return
+		}
+		s.diag.Error("failed to renew session", err)
+		s.checkSession(now)
+		return
+	} else {
+		s.lastRenew = now
+	}
+
+	value, err := json.Marshal(client.HALeader{ID: s.id, URL: s.config.AdvertiseURL})
+	if err != nil {
+		s.diag.Error("failed to encode leader", err)
+		return
+	}
+	acquired, err := s.elector.Acquire(s.config.Key, s.session, value)
+	if err != nil {
+		s.diag.Error("failed to acquire leader lock", err)
+		s.checkSession(now)
+		return
+	}
+	if acquired {
+		if !s.IsLeader() {
+			s.takeOver()
+		}
+		return
+	}
+	if s.IsLeader() {
+		s.stepDown()
+	}
+	s.updateLeader()
+}
+
+// checkSession steps down the leader when it could not renew its session for half of the session TTL,
+// so that it stops before the session expires and the standby server takes over.
+func (s *Service) checkSession(now time.Time) {
+	if s.IsLeader() && now.Sub(s.lastRenew) >= time.Duration(s.config.SessionTTL)/2 {
+		s.stepDown()
+	}
+}
+
+// updateLeader looks up the leader holding the lock.
+func (s *Service) updateLeader() {
+	value, session, err := s.elector.Get(s.config.Key)
+	if err != nil {
+		s.diag.Error("failed to get leader", err)
+		return
+	}
+	var leader *client.HALeader
+	if session != "" {
+		leader = new(client.HALeader)
+		if err := json.Unmarshal(value, leader); err != nil {
+			s.diag.Error("failed to decode leader", err)
+			return
+		}
+	}
+	s.mu.Lock()
+	changed := leader != nil && (s.leaderInfo == nil || *s.leaderInfo != *leader)
+	s.leaderInfo = leader
+	s.mu.Unlock()
+	if changed {
+		s.diag.NewLeader(leader.ID, leader.URL)
+	}
+}
+
+// takeOver makes the server the leader.
+func (s *Service) takeOver() {
+	// Replicate the last changes of the previous leader, if it stepped down and is still reachable.
+	if err := s.sync(); err != nil {
+		s.diag.Error("failed to replicate the state of the previous leader", err)
+	}
+	if err := s.AlertService.Reload(); err != nil {
+		s.diag.Error("failed to reload alert handlers", err)
+	}
+	s.mu.Lock()
+	s.leader = true
+	s.leaderInfo = &client.HALeader{ID: s.id, URL: s.config.AdvertiseURL}
+	s.mu.Unlock()
+	s.diag.BecameLeader()
+	if err := s.TaskStore.Activate(); err != nil {
+		s.diag.Error("failed to start tasks", err)
+	}
+}
+
+// stepDown makes the server the standby.
+func (s *Service) stepDown() {
+	s.mu.Lock()
+	s.leader = false
+	s.leaderInfo = nil
+	s.mu.Unlock()
+	s.TaskStore.Standby()
+	s.diag.BecameStandby()
+}
+
+// IsLeader reports whether the server is the leader.
+func (s *Service) IsLeader() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.leader
+}
+
+// Leader reports whether the server is the leader, and the URL of the leader otherwise.
+func (s *Service) Leader() (bool, string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.leader || s.leaderInfo == nil {
+		return s.leader, ""
+	}
+	return false, s.leaderInfo.URL
+}
+
+// sync replaces the replicated namespaces with the snapshot of the leader.
+func (s *Service) sync() error {
+	s.mu.RLock()
+	leader := s.leaderInfo
+	s.mu.RUnlock()
+	if leader == nil || leader.ID == s.id {
+		return nil
+	}
+	cli, err := s.client(leader.URL)
+	if err != nil {
+		return err
+	}
+	snapshot, err := cli.HASnapshot()
+	if err != nil {
+		return err
+	}
+	for _, namespace := range s.config.Namespaces {
+		values, ok := snapshot.Namespaces[namespace]
+		if !ok {
+			return errors.Errorf("leader does not replicate namespace %q", namespace)
+		}
+		kvs := make([]*storage.KeyValue, len(values))
+		for i, v := range values {
+			kvs[i] = &storage.KeyValue{Key: v.Key, Value: v.Value}
+		}
+		if err := s.StorageService.Restore(namespace, kvs); err != nil {
+			return errors.Wrapf(err, "failed to restore namespace %q", namespace)
+		}
+	}
+	s.mu.Lock()
+	s.syncedAt = time.Now().UTC()
+	s.mu.Unlock()
+	return nil
+}
+
+// client returns the client of the API of the leader at the URL.
+func (s *Service) client(url string) (*client.Client, error) {
+	if s.leaderClient != nil && s.leaderClientURL == url {
+		return s.leaderClient, nil
+	}
+	tlsConfig, err := tlsconfig.Create(s.config.SSLCA, s.config.SSLCert, s.config.SSLKey, s.config.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	c := client.Config{
+		URL: url,
+		// Replication must not keep the standby server from renewing its session.
+		Timeout:   time.Duration(s.config.SessionTTL) / 3,
+		TLSConfig: tlsConfig,
+	}
+	if s.config.Token != "" {
+		c.Credentials = &client.Credentials{
+			Method: client.BearerAuthentication,
+			Token:  s.config.Token,
+		}
+	} else if s.config.Username != "" {
+		c.Credentials = &client.Credentials{
+			Method:   client.UserAuthentication,
+			Username: s.config.Username,
+			Password: s.config.Password,
+		}
+	}
+	cli, err := client.New(c)
+	if err != nil {
+		return nil, err
+	}
+	s.leaderClient, s.leaderClientURL = cli, url
+	return cli, nil
+}
+
+func (s *Service) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	status := client.HAStatus{
+		ID:       s.id,
+		Role:     RoleStandby,
+		Leader:   s.leaderInfo,
+		LastSync: s.syncedAt,
+	}
+	if s.leader {
+		status.Role = RoleLeader
+		status.LastSync = time.Time{}
+	}
+	s.mu.RUnlock()
+	w.Write(httpd.MarshalJSON(status, true))
+}
+
+func (s *Service) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	if !s.IsLeader() {
+		httpd.HttpError(w, "server is not the leader", true, http.StatusServiceUnavailable)
+		return
+	}
+	snapshot := client.HASnapshot{
+		Namespaces: make(map[string][]client.HAKeyValue, len(s.config.Namespaces)),
+	}
+	for _, namespace := range s.config.Namespaces {
+		kvs, err := s.StorageService.Snapshot(namespace)
+		if err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+			return
+		}
+		values := make([]client.HAKeyValue, len(kvs))
+		for i, kv := range kvs {
+			values[i] = client.HAKeyValue{Key: kv.Key, Value: kv.Value}
+		}
+		snapshot.Namespaces[namespace] = values
+	}
+	w.Write(httpd.MarshalJSON(snapshot, false))
+}
@@ -0,0 +1,439 @@
+package ha
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/server/vars"
+	"github.com/influxdata/kapacitor/services/diagnostic"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/services/httpd/httpdtest"
+	"github.com/influxdata/kapacitor/services/storage"
+	"github.com/influxdata/kapacitor/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	testSessionTTL = 300 * time.Millisecond
+	testLockDelay  = 300 * time.Millisecond
+)
+
+// fakeElector behaves like the Consul sessions and locks.
+type fakeElector struct {
+	mu       sync.Mutex
+	nextID   int
+	sessions map[string]*fakeSession
+	holder   string
+	value    []byte
+	// The lock cannot be acquired until then, after the session holding it expired.
+	delayed time.Time
+	// Sessions whose server cannot reach Consul.
+	partitioned map[string]bool
+}
+
+type fakeSession struct {
+	ttl       time.Duration
+	lockDelay time.Duration
+	renewed   time.Time
+}
+
+func newFakeElector() *fakeElector {
+	return &fakeElector{
+		sessions:    make(map[string]*fakeSession),
+		partitioned: make(map[string]bool),
+	}
+}
+
+// expire expires the sessions which were not renewed in time. Caller must have lock.
+func (e *fakeElector) expire(now time.Time) {
+	for id, s := range e.sessions {
+		if now.Sub(s.renewed) > s.ttl {
+			delete(e.sessions, id)
+			if e.holder == id {
+				e.holder = ""
+				e.delayed = now.Add(s.lockDelay)
+			}
+		}
+	}
+}
+
+func (e *fakeElector) partition(session string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.partitioned[session] = true
+}
+
+func (e *fakeElector) holderSession() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expire(time.Now())
+	return e.holder
+}
+
+func (e *fakeElector) CreateSession(ttl, lockDelay time.Duration) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.nextID++
+	id := fmt.Sprintf("session%d", e.nextID)
+	e.sessions[id] = &fakeSession{ttl: ttl, lockDelay: lockDelay, renewed: time.Now()}
+	return id, nil
+}
+
+func (e *fakeElector) RenewSession(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.partitioned[id] {
+		return errors.New("connection refused")
+	}
+	now := time.Now()
+	e.expire(now)
+	s, ok := e.sessions[id]
+	if !ok {
+		return errSessionExpired
+	}
+	s.renewed = now
+	return nil
+}
+
+func (e *fakeElector) DestroySession(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.sessions, id)
+	if e.holder == id {
+		e.holder = ""
+	}
+	return nil
+}
+
+func (e *fakeElector) Acquire(key, session string, value []byte) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.partitioned[session] {
+		return false, errors.New("connection refused")
+	}
+	now := time.Now()
+	e.expire(now)
+	if _, ok := e.sessions[session]; !ok {
+		return false, errors.New("invalid session")
+	}
+	if e.holder == session || (e.holder == "" && now.After(e.delayed)) {
+		e.holder = session
+		e.value = value
+		return true, nil
+	}
+	return false, nil
+}
+
+func (e *fakeElector) Release(key, session string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.holder == session {
+		e.holder = ""
+	}
+	return nil
+}
+
+func (e *fakeElector) Get(key string) ([]byte, string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expire(time.Now())
+	if e.holder == "" {
+		return nil, "", nil
+	}
+	return e.value, e.holder, nil
+}
+
+type memStorage struct {
+	mu         sync.Mutex
+	namespaces map[string][]*storage.KeyValue
+}
+
+func (s *memStorage) Snapshot(namespace string) ([]*storage.KeyValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.namespaces[namespace], nil
+}
+
+func (s *memStorage) Restore(namespace string, kvs []*storage.KeyValue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.namespaces[namespace] = kvs
+	return nil
+}
+
+type taskStore struct {
+	mu        sync.Mutex
+	running   bool
+	started   time.Time
+	stopped   time.Time
+	activated int
+}
+
+func (ts *taskStore) Standby() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.running {
+		ts.running = false
+		ts.stopped = time.Now()
+	}
+}
+
+func (ts *taskStore) Activate() error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.running = true
+	ts.started = time.Now()
+	ts.activated++
+	return nil
+}
+
+func (ts *taskStore) isRunning() bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.running
+}
+
+type alertService struct {
+	mu      sync.Mutex
+	reloads int
+}
+
+func (a *alertService) Reload() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reloads++
+	return nil
+}
+
+type infoer struct {
+	vars.Infoer
+	id uuid.UUID
+}
+
+func (i infoer) ServerID() uuid.UUID {
+	return i.id
+}
+
+type testServer struct {
+	*Service
+	httpd     *httpdtest.Server
+	storage   *memStorage
+	taskStore *taskStore
+	alerts    *alertService
+}
+
+func newTestServer(e elector, namespaces map[string][]*storage.KeyValue) *testServer {
+	h := httpdtest.NewServer(false)
+	c := NewConfig()
+	c.Enabled = true
+	c.AdvertiseURL = h.Server.URL
+	c.SessionTTL = toml.Duration(testSessionTTL)
+	c.LockDelay = toml.Duration(testLockDelay)
+	c.SyncInterval = toml.Duration(testSessionTTL / 6)
+	c.Namespaces = []string{"task_store"}
+	ds := diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	ds.Open()
+	s := &testServer{
+		Service:   NewService(c, infoer{id: uuid.New()}, ds.NewHAHandler()),
+		httpd:     h,
+		storage:   &memStorage{namespaces: namespaces},
+		taskStore: &taskStore{},
+		alerts:    &alertService{},
+	}
+	s.elector = e
+	s.Service.StorageService = s.storage
+	s.Service.HTTPDService = h
+	s.Service.TaskStore = s.taskStore
+	s.Service.AlertService = s.alerts
+	h.Handler.LeaderService = s.Service
+	return s
+}
+
+func (s *testServer) Close() {
+	s.Service.Close()
+	s.httpd.Close()
+}
+
+// waitFor polls the condition until it is true or the timeout elapses.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestService_Failover(t *testing.T) {
+	e := newFakeElector()
+	tasks := []*storage.KeyValue{{Key: "tasks/cpu", Value: []byte(`{"id":"cpu"}`)}}
+	a := newTestServer(e, map[string][]*storage.KeyValue{"task_store": tasks})
+	defer a.Close()
+	if err := a.Open(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "first server to become the leader", a.IsLeader)
+	waitFor(t, "first server to start tasks", a.taskStore.isRunning)
+
+	b := newTestServer(e, map[string][]*storage.KeyValue{})
+	defer b.Close()
+	if err := b.Open(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "standby server to replicate the tasks", func() bool {
+		kvs, _ := b.storage.Snapshot("task_store")
+		return reflect.DeepEqual(kvs, tasks)
+	})
+	if b.taskStore.isRunning() {
+		t.Fatal("standby server runs tasks")
+	}
+	if leader, url := b.Leader(); leader || url != a.config.AdvertiseURL {
+		t.Errorf("unexpected leader of standby server got %v %q exp false %q", leader, url, a.config.AdvertiseURL)
+	}
+
+	// The standby server rejects the requests changing the replicated state.
+	route := httpd.Route{
+		Method:     "POST",
+		Pattern:    "/replicated",
+		Replicated: true,
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		},
+	}
+	for _, s := range []*testServer{a, b} {
+		if err := s.httpd.AddRoutes([]httpd.Route{route}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := http.Post(b.httpd.Server.URL+httpd.BasePath+"/replicated", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || !bytes.Contains(body, []byte(a.config.AdvertiseURL)) {
+		t.Errorf("unexpected response of standby server got %d %s", resp.StatusCode, body)
+	}
+	resp, err = http.Post(a.httpd.Server.URL+httpd.BasePath+"/replicated", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("unexpected status code of leader got %d exp %d", resp.StatusCode, http.StatusNoContent)
+	}
+
+	// The leader loses its connection to Consul.
+	tasks = append(tasks, &storage.KeyValue{Key: "tasks/mem", Value: []byte(`{"id":"mem"}`)})
+	a.storage.Restore("task_store", tasks)
+	waitFor(t, "standby server to replicate the new task", func() bool {
+		kvs, _ := b.storage.Snapshot("task_store")
+		return reflect.DeepEqual(kvs, tasks)
+	})
+	e.partition(e.holderSession())
+	waitFor(t, "standby server to take over", b.IsLeader)
+	waitFor(t, "new leader to start tasks", b.taskStore.isRunning)
+
+	if a.IsLeader() || a.taskStore.isRunning() {
+		t.Fatal("previous leader did not step down")
+	}
+	a.taskStore.mu.Lock()
+	stopped := a.taskStore.stopped
+	a.taskStore.mu.Unlock()
+	b.taskStore.mu.Lock()
+	started := b.taskStore.started
+	b.taskStore.mu.Unlock()
+	if !stopped.Before(started) {
+		t.Errorf("new leader started tasks at %v before previous leader stopped them at %v", started, stopped)
+	}
+	b.alerts.mu.Lock()
+	reloads := b.alerts.reloads
+	b.alerts.mu.Unlock()
+	if reloads != 1 {
+		t.Errorf("unexpected alert reloads got %d exp 1", reloads)
+	}
+}
+
+func TestService_CloseHandsOver(t *testing.T) {
+	e := newFakeElector()
+	a := newTestServer(e, map[string][]*storage.KeyValue{"task_store": nil})
+	if err := a.Open(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "first server to become the leader", a.IsLeader)
+	b := newTestServer(e, map[string][]*storage.KeyValue{})
+	defer b.Close()
+	if err := b.Open(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "standby server to know the leader", func() bool {
+		_, url := b.Leader()
+		return url != ""
+	})
+
+	closed := time.Now()
+	a.Close()
+	waitFor(t, "standby server to take over", b.IsLeader)
+	// Releasing the lock does not delay the standby server.
+	if d := time.Since(closed); d >= testLockDelay {
+		t.Errorf("took over after %v, expected less than the lock delay %v", d, testLockDelay)
+	}
+	if a.taskStore.isRunning() {
+		t.Error("closed leader runs tasks")
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		update func(c *Config)
+		err    string
+	}{
+		{
+			update: func(c *Config) {},
+		},
+		{
+			update: func(c *Config) { c.AdvertiseURL = "" },
+			err:    "must specify advertise-url",
+		},
+		{
+			update: func(c *Config) { c.AdvertiseURL = "localhost:9092" },
+			err:    "must be an http(s) URL",
+		},
+		{
+			update: func(c *Config) { c.SessionTTL = toml.Duration(time.Second) },
+			err:    "session-ttl must be at least 10s",
+		},
+		{
+			update: func(c *Config) { c.Namespaces = nil },
+			err:    "must specify at least one namespace",
+		},
+		{
+			update: func(c *Config) { c.Username, c.Token = "bob", "secret" },
+			err:    "cannot use both token and username/password",
+		},
+	}
+	for i, tc := range testCases {
+		c := NewConfig()
+		c.Enabled = true
+		c.AdvertiseURL = "http://localhost:9092"
+		tc.update(&c)
+		err := c.Validate()
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("%d: unexpected error %v", i, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%d: unexpected error got %v exp %q", i, err, tc.err)
+		}
+	}
+}
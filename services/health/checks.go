@@ -0,0 +1,104 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/diagnostic"
+	"github.com/influxdata/kapacitor/services/scraper"
+	"github.com/influxdata/kapacitor/services/storage"
+)
+
+// The key read by the storage check, it does not exist.
+const (
+	healthNamespace = "health"
+	healthKey       = "health"
+)
+
+// StorageCheck checks that the storage can be read.
+func StorageCheck(storageService interface {
+	Store(namespace string) storage.Interface
+}) CheckFunc {
+	return func(ctx context.Context) client.HealthCheck {
+		err := storageService.Store(healthNamespace).View(func(tx storage.ReadOnlyTx) error {
+			_, err := tx.Get(healthKey)
+			return err
+		})
+		if err != nil && err != storage.ErrNoKeyExists {
+			return client.HealthCheck{Status: client.HealthFail, Message: err.Error()}
+		}
+		return client.HealthCheck{Status: client.HealthPass}
+	}
+}
+
+// InfluxDBCheck pings the InfluxDB clusters, a cluster that does not respond fails.
+func InfluxDBCheck(influxdb interface {
+	Ping(ctx context.Context) (map[string]string, map[string]error)
+}) CheckFunc {
+	return func(ctx context.Context) client.HealthCheck {
+		versions, errs := influxdb.Ping(ctx)
+		components := make(map[string]client.HealthCheck, len(versions)+len(errs))
+		for name, version := range versions {
+			components[name] = client.HealthCheck{Status: client.HealthPass, Message: "version " + version}
+		}
+		for name, err := range errs {
+			components[name] = client.HealthCheck{Status: client.HealthFail, Message: err.Error()}
+		}
+		return Components(components)
+	}
+}
+
+// ScraperCheck checks the last scrape of the targets of the scrapers, a target that is down warns.
+func ScraperCheck(scrapers interface {
+	Targets() []scraper.TargetStatus
+}) CheckFunc {
+	return func(ctx context.Context) client.HealthCheck {
+		targets := scrapers.Targets()
+		components := make(map[string]client.HealthCheck, len(targets))
+		for _, t := range targets {
+			hc := client.HealthCheck{Status: client.HealthPass}
+			switch t.Health {
+			case scraper.TargetDown:
+				hc.Status = client.HealthWarn
+				if t.LastError != nil {
+					hc.Message = t.LastError.Error()
+				}
+			case scraper.TargetUnknown:
+				hc.Message = "not scraped yet"
+			}
+			components[t.Scraper+" "+t.URL] = hc
+		}
+		return Components(components)
+	}
+}
+
+// LastErrorsCheck checks the last errors logged by the components, e.g. the services of alert handlers.
+// A component that logged an error within the window warns.
+func LastErrorsCheck(lastErrors func() map[string]diagnostic.LastError, components []string, window time.Duration) CheckFunc {
+	return func(ctx context.Context) client.HealthCheck {
+		errs := lastErrors()
+		now := time.Now()
+		checks := make(map[string]client.HealthCheck)
+		for _, component := range components {
+			le, ok := errs[component]
+			if !ok {
+				continue
+			}
+			msg := le.Message
+			if le.Error != "" {
+				msg += ": " + le.Error
+			}
+			hc := client.HealthCheck{
+				Status:  client.HealthPass,
+				Message: fmt.Sprintf("last error at %s: %s", le.Time.UTC().Format(time.RFC3339), msg),
+			}
+			if now.Sub(le.Time) <= window {
+				hc.Status = client.HealthWarn
+			}
+			checks[component] = hc
+		}
+		return Components(checks)
+	}
+}
@@ -0,0 +1,39 @@
+package health
+
+import (
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultTimeout     = 5 * time.Second
+	DefaultErrorWindow = 5 * time.Minute
+)
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+	// Timeout of each check, a check that does not complete in time fails.
+	Timeout toml.Duration `toml:"timeout"`
+	// Errors of alert handlers within the window make their check warn.
+	ErrorWindow toml.Duration `toml:"error-window"`
+}
+
+func NewConfig() Config {
+	return Config{
+		Enabled:     true,
+		Timeout:     toml.Duration(DefaultTimeout),
+		ErrorWindow: toml.Duration(DefaultErrorWindow),
+	}
+}
+
+func (c Config) Validate() error {
+	if c.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	if c.ErrorWindow < 0 {
+		return errors.New("error-window must not be negative")
+	}
+	return nil
+}
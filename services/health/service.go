@@ -0,0 +1,215 @@
+// Package health reports the health of the subsystems of the server, and whether it is ready to serve requests.
+//
+// The health endpoint runs the checks of the subsystems and reports their status and the status of their components.
+// The readiness endpoint suits readiness probes, e.g. of Kubernetes:
+// it succeeds once the server is open and while none of its critical checks fail.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/pkg/errors"
+)
+
+const (
+	healthPath = "/health"
+	readyPath  = "/ready"
+)
+
+// CheckFunc checks the health of a subsystem, it should return once the context is done.
+type CheckFunc func(ctx context.Context) client.HealthCheck
+
+type check struct {
+	f        CheckFunc
+	critical bool
+}
+
+type Service struct {
+	config Config
+	routes []httpd.Route
+
+	mu     sync.RWMutex
+	checks map[string]check
+	ready  bool
+
+	HTTPDService interface {
+		AddRoutes([]httpd.Route) error
+		DelRoutes([]httpd.Route)
+	}
+}
+
+func NewService(c Config) *Service {
+	return &Service{
+		config: c,
+		checks: make(map[string]check),
+	}
+}
+
+func (s *Service) Open() error {
+	s.routes = []httpd.Route{
+		{
+			Method:      "GET",
+			Pattern:     healthPath,
+			HandlerFunc: s.handleHealth,
+		},
+		{
+			// Probes do not authenticate.
+			Method:      "GET",
+			Pattern:     readyPath,
+			HandlerFunc: s.handleReady,
+			BypassAuth:  true,
+		},
+	}
+	if err := s.HTTPDService.AddRoutes(s.routes); err != nil {
+		return errors.Wrap(err, "failed to add API routes")
+	}
+	return nil
+}
+
+func (s *Service) Close() error {
+	s.SetReady(false)
+	if s.HTTPDService != nil {
+		s.HTTPDService.DelRoutes(s.routes)
+	}
+	return nil
+}
+
+// AddCheck adds the check of a subsystem, critical checks must not fail for the server to be ready.
+func (s *Service) AddCheck(name string, critical bool, f CheckFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks[name] = check{f: f, critical: critical}
+}
+
+// SetReady sets whether the server is open, the server is not ready before.
+func (s *Service) SetReady(ready bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ready = ready
+}
+
+// Health runs the checks concurrently, and returns the health of the server.
+func (s *Service) Health(ctx context.Context) client.Health {
+	s.mu.RLock()
+	checks := make(map[string]check, len(s.checks))
+	for name, c := range s.checks {
+		checks[name] = c
+	}
+	ready := s.ready
+	s.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.config.Timeout))
+	defer cancel()
+
+	h := client.Health{
+		Link:   client.Link{Relation: client.Self, Href: httpd.BasePath + healthPath},
+		Status: client.HealthPass,
+		Ready:  ready,
+		Checks: make(map[string]client.HealthCheck, len(checks)),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, c := range checks {
+		wg.Add(1)
+		go func(name string, c check) {
+			defer wg.Done()
+			hc := run(ctx, c.f)
+			hc.Critical = c.critical
+			mu.Lock()
+			defer mu.Unlock()
+			h.Checks[name] = hc
+		}(name, c)
+	}
+	wg.Wait()
+	for _, hc := range h.Checks {
+		h.Status = Worst(h.Status, hc.Status)
+		if hc.Critical && hc.Status == client.HealthFail {
+			h.Ready = false
+		}
+	}
+	return h
+}
+
+// run runs the check, it fails if the check does not complete before the context is done.
+func run(ctx context.Context, f CheckFunc) client.HealthCheck {
+	result := make(chan client.HealthCheck, 1)
+	go func() {
+		result <- f(ctx)
+	}()
+	select {
+	case hc := <-result:
+		return hc
+	case <-ctx.Done():
+		return client.HealthCheck{Status: client.HealthFail, Message: "check timed out"}
+	}
+}
+
+func (s *Service) handleHealth(w http.ResponseWriter, r *http.Request) {
+	h := s.Health(r.Context())
+	code := http.StatusOK
+	if h.Status == client.HealthFail {
+		code = http.StatusServiceUnavailable
+	}
+	w.WriteHeader(code)
+	w.Write(httpd.MarshalJSON(h, true))
+}
+
+func (s *Service) handleReady(w http.ResponseWriter, r *http.Request) {
+	h := s.Health(r.Context())
+	if h.Ready {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !s.isReady() {
+		httpd.HttpError(w, "server is not open", true, http.StatusServiceUnavailable)
+		return
+	}
+	var failed []string
+	for name, hc := range h.Checks {
+		if hc.Critical && hc.Status == client.HealthFail {
+			failed = append(failed, name)
+		}
+	}
+	sort.Strings(failed)
+	httpd.HttpError(w, fmt.Sprintf("critical checks failed: %s", strings.Join(failed, ", ")), true, http.StatusServiceUnavailable)
+}
+
+func (s *Service) isReady() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ready
+}
+
+var statusOrder = map[string]int{
+	client.HealthPass: 0,
+	client.HealthWarn: 1,
+	client.HealthFail: 2,
+}
+
+// Worst returns the worst of the statuses.
+func Worst(a, b string) string {
+	if statusOrder[b] > statusOrder[a] {
+		return b
+	}
+	return a
+}
+
+// Components returns the check of the components, its status is the worst status of the components.
+func Components(components map[string]client.HealthCheck) client.HealthCheck {
+	hc := client.HealthCheck{
+		Status:     client.HealthPass,
+		Components: components,
+	}
+	for _, c := range components {
+		hc.Status = Worst(hc.Status, c.Status)
+	}
+	return hc
+}
@@ -0,0 +1,128 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/diagnostic"
+	"github.com/influxdata/kapacitor/services/health"
+	"github.com/influxdata/kapacitor/services/scraper"
+)
+
+func status(s string) health.CheckFunc {
+	return func(ctx context.Context) client.HealthCheck {
+		return client.HealthCheck{Status: s}
+	}
+}
+
+func TestService_Health(t *testing.T) {
+	c := health.NewConfig()
+	c.Timeout = toml.Duration(10 * time.Millisecond)
+	s := health.NewService(c)
+	s.AddCheck("storage", true, status(client.HealthPass))
+	s.AddCheck("influxdb", false, status(client.HealthFail))
+	s.AddCheck("scrapers", false, status(client.HealthWarn))
+
+	h := s.Health(context.Background())
+	if h.Ready {
+		t.Error("expected server not to be ready before it is open")
+	}
+	s.SetReady(true)
+	h = s.Health(context.Background())
+	if !h.Ready || h.Status != client.HealthFail {
+		t.Errorf("unexpected health %+v", h)
+	}
+	if exp := (client.HealthCheck{Status: client.HealthPass, Critical: true}); !reflect.DeepEqual(h.Checks["storage"], exp) {
+		t.Errorf("unexpected storage check got %+v exp %+v", h.Checks["storage"], exp)
+	}
+
+	// A critical check that fails makes the server not ready.
+	s.AddCheck("storage", true, func(ctx context.Context) client.HealthCheck {
+		<-ctx.Done()
+		time.Sleep(time.Millisecond)
+		return client.HealthCheck{Status: client.HealthPass}
+	})
+	h = s.Health(context.Background())
+	if h.Ready {
+		t.Error("expected server not to be ready when a critical check fails")
+	}
+	if got := h.Checks["storage"]; got.Status != client.HealthFail || got.Message != "check timed out" {
+		t.Errorf("unexpected storage check %+v", got)
+	}
+}
+
+type influxdb struct{}
+
+func (influxdb) Ping(ctx context.Context) (map[string]string, map[string]error) {
+	return map[string]string{"default": "1.8.0"}, map[string]error{"other": errors.New("connection refused")}
+}
+
+type scrapers []scraper.TargetStatus
+
+func (s scrapers) Targets() []scraper.TargetStatus { return s }
+
+func TestChecks(t *testing.T) {
+	now := time.Now()
+	lastErrors := func() map[string]diagnostic.LastError {
+		return map[string]diagnostic.LastError{
+			"slack":   {Time: now, Message: "failed to send event", Error: "timeout"},
+			"smtp":    {Time: now.Add(-time.Hour), Message: "failed to send email"},
+			"storage": {Time: now, Message: "not an alert handler"},
+		}
+	}
+
+	testCases := []struct {
+		name string
+		f    health.CheckFunc
+		exp  client.HealthCheck
+	}{
+		{
+			name: "influxdb",
+			f:    health.InfluxDBCheck(influxdb{}),
+			exp: client.HealthCheck{
+				Status: client.HealthFail,
+				Components: map[string]client.HealthCheck{
+					"default": {Status: client.HealthPass, Message: "version 1.8.0"},
+					"other":   {Status: client.HealthFail, Message: "connection refused"},
+				},
+			},
+		},
+		{
+			name: "scrapers",
+			f: health.ScraperCheck(scrapers{
+				{Scraper: "node", URL: "http://a:9100/metrics", Health: scraper.TargetUp},
+				{Scraper: "node", URL: "http://b:9100/metrics", Health: scraper.TargetDown, LastError: errors.New("timeout")},
+				{Scraper: "json", URL: "http://c/stats", Health: scraper.TargetUnknown},
+			}),
+			exp: client.HealthCheck{
+				Status: client.HealthWarn,
+				Components: map[string]client.HealthCheck{
+					"node http://a:9100/metrics": {Status: client.HealthPass},
+					"node http://b:9100/metrics": {Status: client.HealthWarn, Message: "timeout"},
+					"json http://c/stats":        {Status: client.HealthPass, Message: "not scraped yet"},
+				},
+			},
+		},
+		{
+			name: "alert handlers",
+			f:    health.LastErrorsCheck(lastErrors, []string{"slack", "smtp", "pagerduty"}, 5*time.Minute),
+			exp: client.HealthCheck{
+				Status: client.HealthWarn,
+				Components: map[string]client.HealthCheck{
+					"slack": {Status: client.HealthWarn, Message: "last error at " + now.UTC().Format(time.RFC3339) + ": failed to send event: timeout"},
+					"smtp":  {Status: client.HealthPass, Message: "last error at " + now.Add(-time.Hour).UTC().Format(time.RFC3339) + ": failed to send email"},
+				},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		if got := tc.f(context.Background()); !reflect.DeepEqual(got, tc.exp) {
+			t.Errorf("%s: unexpected check got %+v exp %+v", tc.name, got, tc.exp)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package http_discovery
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+)
+
+// DefaultRefreshInterval is used if the refresh interval is not set.
+const DefaultRefreshInterval = time.Minute
+
+// Config is a Prometheus HTTP-SD discovery configuration
+type Config struct {
+	Enabled bool   `toml:"enabled" override:"enabled"`
+	ID      string `toml:"id" override:"id"`
+	// URL returns the target groups as JSON in the Prometheus HTTP-SD format.
+	URL             string        `toml:"url" override:"url"`
+	RefreshInterval toml.Duration `toml:"refresh-interval" override:"refresh-interval"`
+	Username        string        `toml:"username" override:"username"`
+	Password        string        `toml:"password" override:"password,redact"`
+	BearerToken     string        `toml:"bearer-token" override:"bearer-token,redact"`
+	// Path to CA file
+	SSLCA string `toml:"ssl-ca" override:"ssl-ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl-cert" override:"ssl-cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl-key" override:"ssl-key"`
+	// SSLServerName is used to verify the hostname of the URL.
+	SSLServerName string `toml:"ssl-server-name" override:"ssl-server-name"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool `toml:"insecure-skip-verify" override:"insecure-skip-verify"`
+}
+
+// Init adds default values to HTTP-SD configuration
+func (c *Config) Init() {
+	c.RefreshInterval = toml.Duration(DefaultRefreshInterval)
+}
+
+// Validate validates HTTP-SD configuration's values
+func (c Config) Validate() error {
+	if c.ID == "" {
+		return fmt.Errorf("http discovery must be given a ID")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid http discovery url %q: %v", c.URL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("http discovery url %q must use http or https", c.URL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("http discovery url %q must specify a host", c.URL)
+	}
+	if c.BearerToken != "" && (c.Username != "" || c.Password != "") {
+		return fmt.Errorf("at most one of basic auth or bearer-token can be configured")
+	}
+	if c.RefreshInterval < 0 {
+		return fmt.Errorf("http discovery refresh-interval must not be negative")
+	}
+	return nil
+}
+
+// Service return discoverer type
+func (c Config) Service() string {
+	return "http-discovery"
+}
+
+// ServiceID returns the discoverers name
+func (c Config) ServiceID() string {
+	return c.ID
+}
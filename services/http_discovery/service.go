@@ -0,0 +1,322 @@
+package http_discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor/services/scraper"
+	"github.com/influxdata/kapacitor/tlsconfig"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+)
+
+type Diagnostic scraper.Diagnostic
+
+// Service is the HTTP-SD discovery service
+type Service struct {
+	Configs []Config
+	mu      sync.Mutex
+
+	registry    scraper.Registry
+	discoverers []*discoverer
+
+	diag Diagnostic
+	open bool
+}
+
+// NewService creates a new unopened service
+func NewService(c []Config, r scraper.Registry, d Diagnostic) *Service {
+	return &Service{
+		Configs:  c,
+		registry: r,
+		diag:     d,
+	}
+}
+
+// Open starts the service
+func (s *Service) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.open {
+		return nil
+	}
+
+	s.open = true
+	if err := s.register(); err != nil {
+		return err
+	}
+
+	return s.registry.Commit()
+}
+
+// Close stops the service
+func (s *Service) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.open {
+		return nil
+	}
+
+	s.open = false
+	s.deregister()
+
+	return s.registry.Commit()
+}
+
+func (s *Service) deregister() {
+	// Stop polling and remove all the configurations in the registry
+	for _, d := range s.discoverers {
+		d.stop()
+		s.registry.RemoveDiscoverer(d)
+	}
+	s.discoverers = nil
+}
+
+func (s *Service) register() error {
+	// Add all configurations to registry and start polling the URLs
+	for _, c := range s.Configs {
+		if !c.Enabled {
+			continue
+		}
+		d, err := newDiscoverer(c, s.registry, s.diag)
+		if err != nil {
+			return fmt.Errorf("failed to create http discoverer %q: %v", c.ID, err)
+		}
+		s.registry.AddDiscoverer(d)
+		s.discoverers = append(s.discoverers, d)
+		d.start()
+	}
+	return nil
+}
+
+// Update updates configuration while running
+func (s *Service) Update(newConfigs []interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	configs := make([]Config, len(newConfigs))
+	for i, c := range newConfigs {
+		if config, ok := c.(Config); ok {
+			configs[i] = config
+		} else {
+			return fmt.Errorf("unexpected config object type, got %T exp %T", c, config)
+		}
+	}
+
+	s.deregister()
+	s.Configs = configs
+	if s.open {
+		if err := s.register(); err != nil {
+			return err
+		}
+	}
+
+	return s.registry.Commit()
+}
+
+type testOptions struct {
+	ID string `json:"id"`
+}
+
+// TestOptions returns an object that is in turn passed to Test.
+func (s *Service) TestOptions() interface{} {
+	return &testOptions{}
+}
+
+// Test a service with the provided options.
+func (s *Service) Test(options interface{}) error {
+	o, ok := options.(*testOptions)
+	if !ok {
+		return fmt.Errorf("unexpected options type %T", options)
+	}
+
+	found := -1
+	for i := range s.Configs {
+		if s.Configs[i].ID == o.ID && s.Configs[i].Enabled {
+			found = i
+		}
+	}
+	if found < 0 {
+		return fmt.Errorf("discoverer %q is not enabled or does not exist", o.ID)
+	}
+
+	d, err := newDiscoverer(s.Configs[found], s.registry, s.diag)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err = d.fetch(ctx)
+	return err
+}
+
+// discoverer periodically polls the URL and commits the registry
+// when the target groups change.
+type discoverer struct {
+	Config
+
+	client   *http.Client
+	registry scraper.Registry
+	diag     Diagnostic
+
+	mu     sync.Mutex
+	groups []*config.TargetGroup
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newDiscoverer(c Config, r scraper.Registry, d Diagnostic) (*discoverer, error) {
+	t, err := tlsconfig.Create(c.SSLCA, c.SSLCert, c.SSLKey, c.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	t.ServerName = c.SSLServerName
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		Dial: (&net.Dialer{
+			Timeout: 30 * time.Second,
+		}).Dial,
+		TLSClientConfig: t,
+	}
+	return &discoverer{
+		Config: c,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Second,
+		},
+		registry: r,
+		diag:     d,
+		groups:   []*config.TargetGroup{},
+	}, nil
+}
+
+// Prom writes the last fetched target groups as static targets into ScrapeConfig
+func (d *discoverer) Prom(c *config.ScrapeConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c.ServiceDiscoveryConfig.StaticConfigs = d.groups
+}
+
+func (d *discoverer) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.run(ctx)
+	}()
+}
+
+func (d *discoverer) stop() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+func (d *discoverer) run(ctx context.Context) {
+	interval := time.Duration(d.RefreshInterval)
+	if interval == 0 {
+		interval = DefaultRefreshInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		d.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh fetches the target groups, the previous groups are kept if the fetch fails.
+func (d *discoverer) refresh(ctx context.Context) {
+	groups, err := d.fetch(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			d.diag.With("discoverer", d.ID).Errorf("failed to fetch targets: %v", err)
+		}
+		return
+	}
+
+	d.mu.Lock()
+	changed := !reflect.DeepEqual(d.groups, groups)
+	d.groups = groups
+	d.mu.Unlock()
+
+	if changed {
+		if err := d.registry.Commit(); err != nil {
+			d.diag.With("discoverer", d.ID).Errorf("failed to update scrapers: %v", err)
+		}
+	}
+}
+
+// targetGroup is a target group in the Prometheus HTTP-SD format.
+type targetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// fetch requests the target groups from the URL.
+func (d *discoverer) fetch(ctx context.Context) ([]*config.TargetGroup, error) {
+	req, err := http.NewRequest("GET", d.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if d.Username != "" || d.Password != "" {
+		req.SetBasicAuth(d.Username, d.Password)
+	}
+	if d.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+d.BearerToken)
+	}
+	resp, err := d.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+	var tgs []targetGroup
+	if err := json.NewDecoder(resp.Body).Decode(&tgs); err != nil {
+		return nil, fmt.Errorf("failed to decode target groups: %v", err)
+	}
+
+	groups := make([]*config.TargetGroup, 0, len(tgs))
+	for i, tg := range tgs {
+		group := &config.TargetGroup{
+			Source:  d.ID + ":" + strconv.Itoa(i),
+			Targets: make([]model.LabelSet, 0, len(tg.Targets)),
+			Labels:  make(model.LabelSet, len(tg.Labels)),
+		}
+		for _, t := range tg.Targets {
+			if t == "" {
+				return nil, fmt.Errorf("empty target in target group %d", i)
+			}
+			group.Targets = append(group.Targets, model.LabelSet{
+				model.AddressLabel: model.LabelValue(t),
+			})
+		}
+		for k, v := range tg.Labels {
+			if !model.LabelName(k).IsValid() {
+				return nil, fmt.Errorf("invalid label name %q in target group %d", k, i)
+			}
+			group.Labels[model.LabelName(k)] = model.LabelValue(v)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
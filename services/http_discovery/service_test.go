@@ -0,0 +1,132 @@
+package http_discovery_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/services/http_discovery"
+	"github.com/influxdata/kapacitor/services/scraper"
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+)
+
+const targets = `[
+	{
+		"targets": ["10.0.10.2:9100", "10.0.10.3:9100"],
+		"labels": {"__meta_datacenter": "london", "job": "node"}
+	},
+	{
+		"targets": ["10.0.40.2:9100"],
+		"labels": {"env": "prod"}
+	}
+]`
+
+func TestService(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, exp := r.Header.Get("Authorization"), "Bearer secret"; got != exp {
+			t.Errorf("unexpected authorization header: got %q exp %q", got, exp)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(targets))
+	}))
+	defer ts.Close()
+
+	c := http_discovery.Config{}
+	c.Init()
+	c.Enabled = true
+	c.ID = "bridge"
+	c.URL = ts.URL + "/targets"
+	c.BearerToken = "secret"
+	c.RefreshInterval = toml.Duration(time.Hour)
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &registry{commits: make(chan struct{}, 10)}
+	s := http_discovery.NewService([]http_discovery.Config{c}, r, log.NewNopLogger())
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Wait for the commit after the targets have been fetched.
+	for i := 0; i < 2; i++ {
+		select {
+		case <-r.commits:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for commit")
+		}
+	}
+
+	sc := &config.ScrapeConfig{}
+	r.discoverer().Prom(sc)
+	groups := sc.ServiceDiscoveryConfig.StaticConfigs
+	if len(groups) != 2 {
+		t.Fatalf("unexpected number of target groups: %d", len(groups))
+	}
+	if got, exp := len(groups[0].Targets), 2; got != exp {
+		t.Errorf("unexpected number of targets: got %d exp %d", got, exp)
+	}
+	if got, exp := groups[0].Targets[1][model.AddressLabel], model.LabelValue("10.0.10.3:9100"); got != exp {
+		t.Errorf("unexpected target address: got %s exp %s", got, exp)
+	}
+	if got, exp := groups[0].Labels["__meta_datacenter"], model.LabelValue("london"); got != exp {
+		t.Errorf("unexpected label: got %s exp %s", got, exp)
+	}
+	if got, exp := groups[1].Labels["env"], model.LabelValue("prod"); got != exp {
+		t.Errorf("unexpected label: got %s exp %s", got, exp)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		c   http_discovery.Config
+		err bool
+	}{
+		{c: http_discovery.Config{ID: "a", URL: "http://localhost:8000/sd"}},
+		{c: http_discovery.Config{URL: "http://localhost:8000/sd"}, err: true},
+		{c: http_discovery.Config{ID: "a", URL: "ftp://localhost/sd"}, err: true},
+		{c: http_discovery.Config{ID: "a", URL: "http://localhost/sd", Username: "u", BearerToken: "t"}, err: true},
+	}
+	for _, tc := range testCases {
+		err := tc.c.Validate()
+		if tc.err && err == nil {
+			t.Errorf("expected error for %+v", tc.c)
+		} else if !tc.err && err != nil {
+			t.Errorf("unexpected error for %+v: %v", tc.c, err)
+		}
+	}
+}
+
+type registry struct {
+	mu          sync.Mutex
+	discoverers []scraper.Discoverer
+	commits     chan struct{}
+}
+
+func (r *registry) Commit() error {
+	r.commits <- struct{}{}
+	return nil
+}
+
+func (r *registry) AddDiscoverer(d scraper.Discoverer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.discoverers = append(r.discoverers, d)
+}
+
+func (r *registry) RemoveDiscoverer(scraper.Discoverer) {}
+func (r *registry) AddScrapers([]scraper.Config)        {}
+func (r *registry) RemoveScrapers([]scraper.Config)     {}
+func (r *registry) Pairs() []scraper.Pair               { return nil }
+
+func (r *registry) discoverer() scraper.Discoverer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.discoverers[0]
+}
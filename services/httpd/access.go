@@ -0,0 +1,239 @@
+package httpd
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// accessControl restricts the source addresses of requests and limits their rate,
+// for all requests and for the requests of each route group.
+type accessControl struct {
+	allowed []*net.IPNet
+	denied  []*net.IPNet
+	groups  []*routeGroup
+}
+
+// routeGroup is a group of routes sharing access restrictions and a rate limit.
+type routeGroup struct {
+	name    string
+	paths   []string
+	methods map[string]bool
+	allowed []*net.IPNet
+	denied  []*net.IPNet
+	limiter *rateLimiter
+}
+
+func newAccessControl(c Config) (*accessControl, error) {
+	if len(c.AllowedNetworks) == 0 && len(c.DeniedNetworks) == 0 && len(c.RouteGroups) == 0 {
+		return nil, nil
+	}
+	a := &accessControl{}
+	var err error
+	if a.allowed, err = parseNetworks(c.AllowedNetworks); err != nil {
+		return nil, err
+	}
+	if a.denied, err = parseNetworks(c.DeniedNetworks); err != nil {
+		return nil, err
+	}
+	for _, gc := range c.RouteGroups {
+		g := &routeGroup{
+			name:  gc.Name,
+			paths: gc.Paths,
+		}
+		if len(gc.Methods) > 0 {
+			g.methods = make(map[string]bool, len(gc.Methods))
+			for _, m := range gc.Methods {
+				g.methods[strings.ToUpper(m)] = true
+			}
+		}
+		if g.allowed, err = parseNetworks(gc.AllowedNetworks); err != nil {
+			return nil, err
+		}
+		if g.denied, err = parseNetworks(gc.DeniedNetworks); err != nil {
+			return nil, err
+		}
+		if gc.Rate > 0 {
+			burst := float64(gc.Burst)
+			if burst == 0 {
+				burst = math.Max(1, math.Ceil(gc.Rate))
+			}
+			g.limiter = newRateLimiter(gc.Rate, burst)
+		}
+		a.groups = append(a.groups, g)
+	}
+	return a, nil
+}
+
+// parseNetworks parses networks in CIDR notation or single addresses.
+func parseNetworks(networks []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(networks))
+	for _, n := range networks {
+		if !strings.Contains(n, "/") {
+			ip := net.ParseIP(n)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid network %q", n)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(n)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %v", n, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// permitted reports whether the address is not denied and is allowed, if any networks are allowed.
+func permitted(ip net.IP, allowed, denied []*net.IPNet) bool {
+	if contains(denied, ip) {
+		return false
+	}
+	return len(allowed) == 0 || contains(allowed, ip)
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// group returns the first route group of the request, if any.
+func (a *accessControl) group(r *http.Request) *routeGroup {
+	// Preview routes are rewritten to their API routes.
+	p := r.URL.Path
+	if strings.HasPrefix(p, BasePreviewPath) {
+		p = BasePath + strings.TrimPrefix(p, BasePreviewPath)
+	}
+	for _, g := range a.groups {
+		if g.methods != nil && !g.methods[r.Method] {
+			continue
+		}
+		for _, prefix := range g.paths {
+			if matchPathPrefix(prefix, p) {
+				return g
+			}
+		}
+	}
+	return nil
+}
+
+// matchPathPrefix reports whether the path is the prefix or one of its sub paths.
+func matchPathPrefix(prefix, p string) bool {
+	if !strings.HasPrefix(p, prefix) {
+		return false
+	}
+	return len(p) == len(prefix) || strings.HasSuffix(prefix, "/") || p[len(prefix)] == '/'
+}
+
+// check writes an error response and returns false if the request is not permitted,
+// either because of its source address or because its client exceeded the rate limit.
+func (a *accessControl) check(w http.ResponseWriter, r *http.Request, h *Handler) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		h.statMap.Add(statRequestDenied, 1)
+		accessError(w, "unknown source address", http.StatusForbidden)
+		return false
+	}
+	g := a.group(r)
+	if !permitted(ip, a.allowed, a.denied) || (g != nil && !permitted(ip, g.allowed, g.denied)) {
+		h.statMap.Add(statRequestDenied, 1)
+		accessError(w, fmt.Sprintf("access denied for %s", ip), http.StatusForbidden)
+		return false
+	}
+	if g == nil || g.limiter == nil {
+		return true
+	}
+	if ok, wait := g.limiter.allow(ip.String(), time.Now()); !ok {
+		h.statMap.Add(statRequestRateLimited, 1)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		accessError(w, fmt.Sprintf("rate limit of %s routes exceeded", g.name), http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+// accessError writes the error as JSON, since the request is rejected before reaching its route.
+func accessError(w http.ResponseWriter, err string, code int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	HttpError(w, err, false, code)
+}
+
+// rateLimiter limits the rate of the requests of each client with a token bucket.
+type rateLimiter struct {
+	// Tokens added per second and maximum number of tokens.
+	rate  float64
+	burst float64
+
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	lastCleanup time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate, burst float64) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes a token from the bucket of the client,
+// or returns how long until a token is available if the bucket is empty.
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cleanup(now)
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed.Seconds()*l.rate)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// cleanup removes the buckets which are full again, since they are the same as new buckets.
+// l.mu must be held.
+func (l *rateLimiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < time.Minute {
+		return
+	}
+	l.lastCleanup = now
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, client)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package httpd
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func Test_RateLimiter(t *testing.T) {
+	l := newRateLimiter(2, 3)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("a", now); !ok {
+			t.Fatalf("request %d of the burst was limited", i)
+		}
+	}
+	if ok, wait := l.allow("a", now); ok {
+		t.Fatal("expected request above the burst to be limited")
+	} else if wait != 500*time.Millisecond {
+		t.Errorf("unexpected wait: got %v exp %v", wait, 500*time.Millisecond)
+	}
+	// Clients have their own buckets.
+	if ok, _ := l.allow("b", now); !ok {
+		t.Error("expected request of another client to be allowed")
+	}
+	// Tokens are added at the rate.
+	now = now.Add(time.Second)
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.allow("a", now); !ok {
+			t.Fatalf("request %d after refill was limited", i)
+		}
+	}
+	if ok, _ := l.allow("a", now); ok {
+		t.Error("expected request above the refilled tokens to be limited")
+	}
+	// Full buckets are removed.
+	now = now.Add(time.Minute)
+	l.allow("c", now)
+	if len(l.buckets) != 1 {
+		t.Errorf("unexpected number of buckets: got %d exp 1", len(l.buckets))
+	}
+}
+
+func Test_ParseNetworks(t *testing.T) {
+	nets, err := parseNetworks([]string{"10.0.0.0/8", "192.168.1.5", "::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		ip  string
+		exp bool
+	}{
+		{"10.1.2.3", true},
+		{"192.168.1.5", true},
+		{"192.168.1.6", false},
+		{"::1", true},
+		{"::2", false},
+	} {
+		if got := contains(nets, net.ParseIP(tc.ip)); got != tc.exp {
+			t.Errorf("unexpected match of %s: got %v exp %v", tc.ip, got, tc.exp)
+		}
+	}
+	if _, err := parseNetworks([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("expected error for invalid network")
+	}
+}
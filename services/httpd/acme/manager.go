@@ -0,0 +1,151 @@
+// Package acme obtains and renews certificates from an ACME (RFC 8555) certificate authority,
+// such as Let's Encrypt, using the HTTP-01 challenge.
+//
+// Certificates are managed by golang.org/x/crypto/acme/autocert,
+// the Manager obtains them when it is opened instead of during the first TLS handshake.
+package acme
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const (
+	// Let's Encrypt production directory
+	LetsEncryptURL = acme.LetsEncryptURL
+
+	// Bounds of the time between attempts to obtain a certificate after a failure.
+	minRetryInterval = time.Minute
+	maxRetryInterval = time.Hour
+)
+
+type Diagnostic interface {
+	Error(msg string, err error)
+	ObtainedCertificate(domains []string, notAfter time.Time)
+}
+
+type Config struct {
+	DirectoryURL string
+	Email        string
+	Domains      []string
+	// Directory where the account key and the certificates are kept.
+	CacheDir string
+	// The certificates are renewed when they expire within RenewBefore.
+	RenewBefore time.Duration
+}
+
+// Manager obtains a certificate for each of the domains and renews it before it expires.
+// The certificates are cached so that they are not obtained again on restart.
+type Manager struct {
+	config  Config
+	diag    Diagnostic
+	manager *autocert.Manager
+	handler http.Handler
+
+	closing chan struct{}
+}
+
+func NewManager(c Config, d Diagnostic) *Manager {
+	manager := &autocert.Manager{
+		Prompt:      autocert.AcceptTOS,
+		Cache:       autocert.DirCache(c.CacheDir),
+		HostPolicy:  autocert.HostWhitelist(c.Domains...),
+		RenewBefore: c.RenewBefore,
+		Email:       c.Email,
+		Client: &acme.Client{
+			DirectoryURL: c.DirectoryURL,
+			HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+		},
+	}
+	return &Manager{
+		config:  c,
+		diag:    d,
+		manager: manager,
+		// Creating the handler enables the HTTP-01 challenge, other requests are not served.
+		handler: manager.HTTPHandler(http.NotFoundHandler()),
+	}
+}
+
+// Open starts obtaining the certificates in the background, unless they are cached.
+func (m *Manager) Open() error {
+	if err := os.MkdirAll(m.config.CacheDir, 0700); err != nil {
+		return errors.Wrap(err, "failed to create cache directory")
+	}
+	m.closing = make(chan struct{})
+	for _, domain := range m.config.Domains {
+		go m.obtain(domain, m.closing)
+	}
+	return nil
+}
+
+// Close stops retrying to obtain the certificates, pending attempts are abandoned once they time out.
+// Certificates which were obtained are still renewed by autocert.
+func (m *Manager) Close() {
+	if m.closing != nil {
+		close(m.closing)
+		m.closing = nil
+	}
+}
+
+// GetCertificate returns the certificate of the server name of the client,
+// or of the first domain if the client did not send one. It is meant for tls.Config.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if hello == nil || hello.ServerName == "" {
+		return m.manager.GetCertificate(clientHello(m.config.Domains[0]))
+	}
+	return m.manager.GetCertificate(hello)
+}
+
+// HTTPHandler serves the responses to the HTTP-01 challenges,
+// it must be reachable on port 80 of the domains.
+func (m *Manager) HTTPHandler() http.Handler {
+	return m.handler
+}
+
+// obtain obtains the certificate of the domain, retrying until it succeeds or the manager is closed.
+func (m *Manager) obtain(domain string, closing <-chan struct{}) {
+	retry := minRetryInterval
+	for {
+		cert, err := m.manager.GetCertificate(clientHello(domain))
+		select {
+		case <-closing:
+			return
+		default:
+		}
+		if err == nil {
+			if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil {
+				m.diag.ObtainedCertificate([]string{domain}, leaf.NotAfter)
+			}
+			return
+		}
+		m.diag.Error("failed to obtain certificate", errors.Wrap(err, domain))
+		timer := time.NewTimer(retry)
+		select {
+		case <-closing:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if retry *= 2; retry > maxRetryInterval {
+			retry = maxRetryInterval
+		}
+	}
+}
+
+// clientHello returns the hello of a client supporting ECDSA certificates, for the domain.
+func clientHello(domain string) *tls.ClientHelloInfo {
+	return &tls.ClientHelloInfo{
+		ServerName:        domain,
+		CipherSuites:      []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		SignatureSchemes:  []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+		SupportedCurves:   []tls.CurveID{tls.CurveP256},
+		SupportedVersions: []uint16{tls.VersionTLS13, tls.VersionTLS12},
+	}
+}
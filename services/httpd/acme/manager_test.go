@@ -0,0 +1,306 @@
+package acme_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/services/httpd/acme"
+	xacme "golang.org/x/crypto/acme"
+)
+
+// ca is a fake ACME certificate authority.
+type ca struct {
+	*httptest.Server
+	t *testing.T
+
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+
+	mu          sync.Mutex
+	nonce       int
+	nonces      map[string]bool
+	account     *ecdsa.PublicKey
+	orders      int
+	authzValid  bool
+	orderStatus string
+	certificate []byte
+	// URL of the handler answering the challenges
+	challengeURL string
+}
+
+func newCA(t *testing.T) *ca {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	c := &ca{t: t, key: key, cert: cert, nonces: make(map[string]bool)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dir", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"newNonce":   c.URL + "/nonce",
+			"newAccount": c.URL + "/account",
+			"newOrder":   c.URL + "/order",
+		})
+	})
+	mux.HandleFunc("/nonce", func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.setNonce(w)
+	})
+	mux.HandleFunc("/", c.handlePost)
+	c.Server = httptest.NewServer(mux)
+	return c
+}
+
+// setNonce sets a new nonce on the response, c.mu must be held.
+func (c *ca) setNonce(w http.ResponseWriter) {
+	c.nonce++
+	n := fmt.Sprintf("nonce%d", c.nonce)
+	c.nonces[n] = true
+	w.Header().Set("Replay-Nonce", n)
+}
+
+// verify verifies the JWS of the request and returns its payload, c.mu must be held.
+func (c *ca) verify(r *http.Request) ([]byte, error) {
+	var jws struct {
+		Protected string `json:"protected"`
+		Payload   string `json:"payload"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		return nil, err
+	}
+	h, _ := base64.RawURLEncoding.DecodeString(jws.Protected)
+	var header struct {
+		Alg   string            `json:"alg"`
+		Nonce string            `json:"nonce"`
+		URL   string            `json:"url"`
+		Kid   string            `json:"kid"`
+		JWK   map[string]string `json:"jwk"`
+	}
+	if err := json.Unmarshal(h, &header); err != nil {
+		return nil, err
+	}
+	if !c.nonces[header.Nonce] {
+		return nil, fmt.Errorf("bad nonce %q", header.Nonce)
+	}
+	delete(c.nonces, header.Nonce)
+	if header.URL != c.URL+r.URL.Path {
+		return nil, fmt.Errorf("unexpected url %q", header.URL)
+	}
+	var pub *ecdsa.PublicKey
+	if header.JWK != nil {
+		x, _ := base64.RawURLEncoding.DecodeString(header.JWK["x"])
+		y, _ := base64.RawURLEncoding.DecodeString(header.JWK["y"])
+		pub = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	} else if header.Kid == c.URL+"/account/1" && c.account != nil {
+		pub = c.account
+	} else {
+		return nil, fmt.Errorf("unknown account %q", header.Kid)
+	}
+	sig, _ := base64.RawURLEncoding.DecodeString(jws.Signature)
+	if len(sig) != 64 {
+		return nil, fmt.Errorf("invalid signature length %d", len(sig))
+	}
+	digest := sha256.Sum256([]byte(jws.Protected + "." + jws.Payload))
+	if !ecdsa.Verify(pub, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	if r.URL.Path == "/account" {
+		c.account = pub
+	}
+	return base64.RawURLEncoding.DecodeString(jws.Payload)
+}
+
+func (c *ca) handlePost(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	payload, err := c.verify(r)
+	c.setNonce(w)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"type": "urn:ietf:params:acme:error:malformed", "detail": err.Error()})
+		return
+	}
+	order := func() map[string]interface{} {
+		return map[string]interface{}{
+			"status":         c.orderStatus,
+			"authorizations": []string{c.URL + "/authz/1"},
+			"finalize":       c.URL + "/finalize/1",
+			"certificate":    c.URL + "/cert/1",
+		}
+	}
+	switch r.URL.Path {
+	case "/account":
+		w.Header().Set("Location", c.URL+"/account/1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("{}"))
+	case "/order":
+		c.orders++
+		c.authzValid = false
+		c.orderStatus = "pending"
+		w.Header().Set("Location", c.URL+"/order/1")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(order())
+	case "/order/1":
+		json.NewEncoder(w).Encode(order())
+	case "/authz/1":
+		status := "pending"
+		if c.authzValid {
+			status = "valid"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": status,
+			"challenges": []map[string]string{
+				{"type": "dns-01", "url": c.URL + "/chal/2", "token": "other"},
+				{"type": "http-01", "url": c.URL + "/chal/1", "token": "token1"},
+			},
+		})
+	case "/chal/1":
+		thumbprint, _ := xacme.JWKThumbprint(c.account)
+		req, _ := http.NewRequest("GET", c.challengeURL+"/.well-known/acme-challenge/token1", nil)
+		req.Host = "kapacitor.example.com"
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			c.t.Error(err)
+			return
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if got, exp := string(body), "token1."+thumbprint; got != exp {
+			c.t.Errorf("unexpected key authorization: got %q exp %q", got, exp)
+			return
+		}
+		c.authzValid = true
+		c.orderStatus = "ready"
+		json.NewEncoder(w).Encode(map[string]string{"type": "http-01", "url": c.URL + "/chal/1", "token": "token1", "status": "valid"})
+	case "/finalize/1":
+		var req struct {
+			CSR string `json:"csr"`
+		}
+		json.Unmarshal(payload, &req)
+		der, _ := base64.RawURLEncoding.DecodeString(req.CSR)
+		csr, err := x509.ParseCertificateRequest(der)
+		if err != nil {
+			c.t.Error(err)
+			return
+		}
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(int64(c.orders + 1)),
+			Subject:      csr.Subject,
+			DNSNames:     csr.DNSNames,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(24 * time.Hour),
+		}
+		cert, err := x509.CreateCertificate(rand.Reader, tmpl, c.cert, csr.PublicKey, c.key)
+		if err != nil {
+			c.t.Error(err)
+			return
+		}
+		c.certificate = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}),
+			pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})...)
+		c.orderStatus = "valid"
+		json.NewEncoder(w).Encode(order())
+	case "/cert/1":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(c.certificate)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+type diag struct {
+	t *testing.T
+}
+
+func (d diag) Error(msg string, err error) {
+	d.t.Error(msg, err)
+}
+
+func (d diag) ObtainedCertificate(domains []string, notAfter time.Time) {}
+
+func TestManager(t *testing.T) {
+	c := newCA(t)
+	defer c.Close()
+	dir, err := ioutil.TempDir("", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := acme.Config{
+		DirectoryURL: c.URL + "/dir",
+		Email:        "admin@example.com",
+		Domains:      []string{"kapacitor.example.com"},
+		CacheDir:     dir,
+		RenewBefore:  time.Hour,
+	}
+	m := acme.NewManager(config, diag{t: t})
+	challenges := httptest.NewServer(m.HTTPHandler())
+	defer challenges.Close()
+	c.challengeURL = challenges.URL
+	if err := m.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	var cert *x509.Certificate
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if tc, err := m.GetCertificate(nil); err == nil {
+			cert, _ = x509.ParseCertificate(tc.Certificate[0])
+			break
+		}
+	}
+	if cert == nil {
+		t.Fatal("timed out waiting for the certificate")
+	}
+	if err := cert.VerifyHostname("kapacitor.example.com"); err != nil {
+		t.Error(err)
+	}
+	m.Close()
+
+	// The cached certificate is used after a restart.
+	m = acme.NewManager(config, diag{t: t})
+	if err := m.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetCertificate(nil); err != nil {
+		t.Error(err)
+	}
+	c.mu.Lock()
+	orders := c.orders
+	c.mu.Unlock()
+	if orders != 1 {
+		t.Errorf("unexpected number of orders: got %d exp 1", orders)
+	}
+}
@@ -0,0 +1,175 @@
+package httpd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/kapacitor/auth"
+)
+
+const (
+	// Maximum size of a snapshot of a resource, larger resources are not snapshotted.
+	maxSnapshotSize = 1 << 20
+	// Timeout of a snapshot, in case the resource is a stream.
+	snapshotTimeout = 5 * time.Second
+)
+
+// AuditEvent is a mutating API request recorded by the AuditService.
+type AuditEvent struct {
+	Time       time.Time
+	User       string
+	Method     string
+	Path       string
+	Status     int
+	RemoteAddr string
+	// JSON representations of the resource before and after the request,
+	// nil if the resource did not exist or is not a single resource.
+	Before []byte
+	After  []byte
+}
+
+// auditRoute wraps the handler of a route which mutates resources with audit.
+func (h *Handler) auditRoute(r Route, inner AuthorizationHandler) AuthorizationHandler {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return inner
+	}
+	if r.NoAudit {
+		return inner
+	}
+	return h.audit(inner)
+}
+
+// audit records the request with the AuditService after serving it.
+func (h *Handler) audit(inner AuthorizationHandler) AuthorizationHandler {
+	return func(w http.ResponseWriter, r *http.Request, user auth.User) {
+		if h.AuditService == nil {
+			inner(w, r, user)
+			return
+		}
+		e := AuditEvent{
+			Time:       time.Now().UTC(),
+			User:       user.Name(),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			RemoteAddr: r.RemoteAddr,
+			Before:     h.snapshot(r.URL.Path),
+		}
+		aw := &auditWriter{ResponseWriter: w}
+		inner(aw, r, user)
+		e.Status = aw.Status()
+		if e.Status < http.StatusMultipleChoices {
+			// Created resources are returned with their link.
+			p := r.URL.Path
+			if href := linkHref(aw.body.Bytes()); href != "" {
+				p = href
+			}
+			e.After = h.snapshot(p)
+		}
+		h.AuditService.Record(e)
+	}
+}
+
+// snapshot returns the JSON representation of the resource at the path as seen by an admin user,
+// or nil if the path is not a single resource.
+func (h *Handler) snapshot(p string) []byte {
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotTimeout)
+	defer cancel()
+	r, err := http.NewRequest("GET", p, nil)
+	if err != nil {
+		return nil
+	}
+	sw := &snapshotWriter{header: make(http.Header)}
+	h.snapshotMux.ServeHTTP(sw, r.WithContext(ctx))
+	if sw.status != http.StatusOK || sw.overflow {
+		return nil
+	}
+	// Lists and other responses without a link to themselves are not single resources.
+	if linkHref(sw.body.Bytes()) != p {
+		return nil
+	}
+	return sw.body.Bytes()
+}
+
+// linkHref returns the href of the self link of a JSON resource.
+func linkHref(data []byte) string {
+	var resource struct {
+		Link struct {
+			Href string `json:"href"`
+		} `json:"link"`
+	}
+	if json.Unmarshal(data, &resource) != nil {
+		return ""
+	}
+	return resource.Link.Href
+}
+
+// auditWriter records the status and the beginning of the body of a response.
+type auditWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *auditWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if n := maxSnapshotSize - w.body.Len(); n > 0 {
+		if len(b) < n {
+			n = len(b)
+		}
+		w.body.Write(b[:n])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *auditWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// snapshotWriter buffers the response of a snapshot.
+type snapshotWriter struct {
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *snapshotWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *snapshotWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *snapshotWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.body.Len()+len(b) > maxSnapshotSize {
+		w.overflow = true
+		return len(b), nil
+	}
+	return w.body.Write(b)
+}
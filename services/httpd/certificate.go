@@ -0,0 +1,74 @@
+package httpd
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+// certificateFile serves the certificate of the HTTPS listener from files,
+// reloading it when the files change so that connections are not interrupted.
+type certificateFile struct {
+	certPath string
+	keyPath  string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	modTimes [2]time.Time
+}
+
+func newCertificateFile(certPath, keyPath string) *certificateFile {
+	return &certificateFile{
+		certPath: certPath,
+		keyPath:  keyPath,
+	}
+}
+
+// GetCertificate returns the current certificate, it is meant for tls.Config.
+func (c *certificateFile) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// Load the certificate from its files.
+// The current certificate is kept if the files are invalid.
+func (c *certificateFile) Load() error {
+	modTimes, err := c.stat()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certPath, c.keyPath)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.cert = &cert
+	c.modTimes = modTimes
+	c.mu.Unlock()
+	return nil
+}
+
+// Changed reports whether the files were modified since the certificate was loaded.
+func (c *certificateFile) Changed() (bool, error) {
+	modTimes, err := c.stat()
+	if err != nil {
+		return false, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return modTimes != c.modTimes, nil
+}
+
+func (c *certificateFile) stat() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, p := range []string{c.certPath, c.keyPath} {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = fi.ModTime()
+	}
+	return modTimes, nil
+}
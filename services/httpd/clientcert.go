@@ -0,0 +1,75 @@
+package httpd
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/influxdata/kapacitor/auth"
+	"github.com/pkg/errors"
+)
+
+// loadClientCAs loads the PEM encoded CA certificates verifying the client certificates.
+func loadClientCAs(path string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read client CA certificates")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// clientCertificate returns the verified client certificate of the request,
+// or an error if the mode requires one and the client did not present it.
+// The certificate is nil if client certificates are not used in the mode.
+func clientCertificate(r *http.Request, mode string) (*x509.Certificate, error) {
+	if !clientAuthEnabled(mode) {
+		return nil, nil
+	}
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		if mode == ClientAuthRequire {
+			return nil, errors.New("client certificate required")
+		}
+		return nil, nil
+	}
+	return r.TLS.VerifiedChains[0][0], nil
+}
+
+// certificateUser returns the user authenticated by the client certificate.
+// The user is named after the common name of the certificate subject.
+// Users known to the auth service keep their privileges,
+// and all users are granted the scopes of the roles mapped to the subject.
+func (h *Handler) certificateUser(cert *x509.Certificate) (auth.User, error) {
+	dn := cert.Subject.String()
+	name := cert.Subject.CommonName
+	if name == "" {
+		name = dn
+	}
+	roles, mapped := h.clientCertificateRoles[dn]
+	if !mapped && cert.Subject.CommonName != "" {
+		roles, mapped = h.clientCertificateRoles[cert.Subject.CommonName]
+	}
+
+	user, err := h.AuthService.User(name)
+	if err != nil {
+		if !mapped {
+			return auth.User{}, fmt.Errorf("certificate subject %q is not a user and is not mapped to any roles", dn)
+		}
+		user = auth.NewUser(name, nil, false, nil)
+	}
+	if len(roles) == 0 {
+		return user, nil
+	}
+	if h.RoleService == nil {
+		return auth.User{}, errors.New("roles are not available")
+	}
+	scopes, err := h.RoleService.RoleScopes(roles)
+	if err != nil {
+		return auth.User{}, err
+	}
+	return user.WithScopes(scopes...), nil
+}
@@ -4,14 +4,33 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/services/httpd/acme"
 	"github.com/pkg/errors"
 )
 
 const (
 	DefaultShutdownTimeout = toml.Duration(time.Second * 10)
+
+	DefaultHTTPSCertificateReloadInterval = toml.Duration(time.Minute)
+
+	DefaultACMECacheDir    = "/var/lib/kapacitor/acme"
+	DefaultACMEHTTPAddress = ":80"
+	DefaultACMERenewBefore = toml.Duration(30 * 24 * time.Hour)
+)
+
+// Client certificate authentication modes.
+const (
+	// Client certificates are not requested.
+	ClientAuthNone = "none"
+	// Client certificates are verified when presented,
+	// and authenticate the requests which have no other credentials.
+	ClientAuthOptional = "optional"
+	// Requests without a verified client certificate are rejected.
+	ClientAuthRequire = "require"
 )
 
 type Config struct {
@@ -26,11 +45,88 @@ type Config struct {
 	ShutdownTimeout  toml.Duration `toml:"shutdown-timeout"`
 	SharedSecret     string        `toml:"shared-secret"`
 
+	// How often the certificate files are checked for changes, if 0 they are only reloaded on SIGHUP.
+	HTTPSCertificateReloadInterval toml.Duration `toml:"https-certificate-reload-interval"`
+
+	// Obtain and renew the certificate from an ACME certificate authority instead of using the certificate files.
+	ACMEEnabled      bool     `toml:"acme-enabled"`
+	ACMEDirectoryURL string   `toml:"acme-directory-url"`
+	ACMEDomains      []string `toml:"acme-domains"`
+	ACMEEmail        string   `toml:"acme-email"`
+	// Directory where the account key and the certificate are kept.
+	ACMECacheDir string `toml:"acme-cache-dir"`
+	// Address answering the HTTP-01 challenges, the domains must reach it on port 80.
+	ACMEHTTPAddress string `toml:"acme-http-address"`
+	// Renew the certificate when it expires within the duration.
+	ACMERenewBefore toml.Duration `toml:"acme-renew-before"`
+
+	// Client certificate authentication of the API.
+	HTTPSClientAuth string `toml:"https-client-auth"`
+	// Client certificate authentication of the write endpoints receiving the points of InfluxDB subscriptions.
+	HTTPSSubscriptionClientAuth string `toml:"https-subscription-client-auth"`
+	// Path to the CA certificates verifying the client certificates.
+	HTTPSClientCA string `toml:"https-client-ca"`
+	// Roles granted to the users authenticated by their client certificate,
+	// by the distinguished name or the common name of the certificate subject.
+	HTTPSClientCertificateRoles map[string][]string `toml:"https-client-certificate-roles"`
+
+	// Source networks allowed to reach the API, in CIDR notation or as single addresses.
+	// All sources are allowed if empty.
+	AllowedNetworks []string `toml:"allowed-networks"`
+	// Source networks denied access to the API, they take precedence over the allowed networks.
+	DeniedNetworks []string `toml:"denied-networks"`
+	// Access restrictions and rate limits of groups of routes.
+	RouteGroups []RouteGroupConfig `toml:"route-groups"`
+
 	// Enable gzipped encoding
 	// NOTE: this is ignored in toml since it is only consumed by the tests
 	GZIP bool `toml:"-"`
 }
 
+// RouteGroupConfig restricts the access to a group of routes and limits the rate of their requests.
+// Requests belong to the first group matching their path and method.
+type RouteGroupConfig struct {
+	Name string `toml:"name"`
+	// Path prefixes of the routes in the group, e.g. "/kapacitor/v1/write".
+	Paths []string `toml:"paths"`
+	// Methods of the routes in the group, all methods if empty.
+	Methods []string `toml:"methods"`
+	// Source networks allowed and denied access to the routes, in addition to the networks of the API.
+	AllowedNetworks []string `toml:"allowed-networks"`
+	DeniedNetworks  []string `toml:"denied-networks"`
+	// Requests per second allowed from each source address, unlimited if 0.
+	Rate float64 `toml:"rate"`
+	// Requests allowed in a burst from each source address, defaults to the rate.
+	Burst int `toml:"burst"`
+}
+
+func (c RouteGroupConfig) Validate() error {
+	if c.Name == "" {
+		return errors.New("must specify name")
+	}
+	if len(c.Paths) == 0 {
+		return errors.New("must specify paths")
+	}
+	for _, p := range c.Paths {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("invalid path %q, must begin with a '/'", p)
+		}
+	}
+	if _, err := parseNetworks(c.AllowedNetworks); err != nil {
+		return errors.Wrap(err, "allowed-networks")
+	}
+	if _, err := parseNetworks(c.DeniedNetworks); err != nil {
+		return errors.Wrap(err, "denied-networks")
+	}
+	if c.Rate < 0 {
+		return errors.New("rate must not be negative")
+	}
+	if c.Burst < 0 {
+		return errors.New("burst must not be negative")
+	}
+	return nil
+}
+
 func NewConfig() Config {
 	return Config{
 		BindAddress:      ":9092",
@@ -38,6 +134,16 @@ func NewConfig() Config {
 		HttpsCertificate: "/etc/ssl/kapacitor.pem",
 		ShutdownTimeout:  DefaultShutdownTimeout,
 		GZIP:             true,
+
+		HTTPSCertificateReloadInterval: DefaultHTTPSCertificateReloadInterval,
+
+		ACMEDirectoryURL: acme.LetsEncryptURL,
+		ACMECacheDir:     DefaultACMECacheDir,
+		ACMEHTTPAddress:  DefaultACMEHTTPAddress,
+		ACMERenewBefore:  DefaultACMERenewBefore,
+
+		HTTPSClientAuth:             ClientAuthNone,
+		HTTPSSubscriptionClientAuth: ClientAuthNone,
 	}
 }
 
@@ -54,10 +160,75 @@ func (c Config) Validate() error {
 	} else if pn > 65535 || pn < 0 {
 		return fmt.Errorf("invalid http bind address port %d: out of range", pn)
 	}
+	if c.HTTPSCertificateReloadInterval < 0 {
+		return errors.New("https-certificate-reload-interval must not be negative")
+	}
+	if c.ACMEEnabled {
+		if !c.HttpsEnabled {
+			return errors.New("acme-enabled requires https-enabled")
+		}
+		if len(c.ACMEDomains) == 0 {
+			return errors.New("must specify acme-domains")
+		}
+		if c.ACMEDirectoryURL == "" {
+			return errors.New("must specify acme-directory-url")
+		}
+		if c.ACMECacheDir == "" {
+			return errors.New("must specify acme-cache-dir")
+		}
+		if _, _, err := net.SplitHostPort(c.ACMEHTTPAddress); err != nil {
+			return errors.Wrapf(err, "invalid acme-http-address %s", c.ACMEHTTPAddress)
+		}
+		if c.ACMERenewBefore <= 0 {
+			return errors.New("acme-renew-before must be positive")
+		}
+	}
+	for _, o := range []struct{ name, mode string }{
+		{"https-client-auth", c.HTTPSClientAuth},
+		{"https-subscription-client-auth", c.HTTPSSubscriptionClientAuth},
+	} {
+		switch o.mode {
+		case "", ClientAuthNone:
+			continue
+		case ClientAuthOptional, ClientAuthRequire:
+		default:
+			return fmt.Errorf("invalid %s %q, must be one of %q, %q or %q", o.name, o.mode, ClientAuthNone, ClientAuthOptional, ClientAuthRequire)
+		}
+		if !c.HttpsEnabled {
+			return fmt.Errorf("%s requires https-enabled", o.name)
+		}
+		if c.HTTPSClientCA == "" {
+			return fmt.Errorf("%s requires https-client-ca", o.name)
+		}
+	}
+	if len(c.HTTPSClientCertificateRoles) > 0 && !clientAuthEnabled(c.HTTPSClientAuth) {
+		return errors.New("https-client-certificate-roles requires https-client-auth")
+	}
+	if _, err := parseNetworks(c.AllowedNetworks); err != nil {
+		return errors.Wrap(err, "allowed-networks")
+	}
+	if _, err := parseNetworks(c.DeniedNetworks); err != nil {
+		return errors.Wrap(err, "denied-networks")
+	}
+	names := make(map[string]bool, len(c.RouteGroups))
+	for _, g := range c.RouteGroups {
+		if err := g.Validate(); err != nil {
+			return errors.Wrapf(err, "invalid route group %q", g.Name)
+		}
+		if names[g.Name] {
+			return fmt.Errorf("duplicate route group %q", g.Name)
+		}
+		names[g.Name] = true
+	}
 
 	return nil
 }
 
+// clientAuthEnabled reports whether client certificates are requested in the mode.
+func clientAuthEnabled(mode string) bool {
+	return mode != "" && mode != ClientAuthNone
+}
+
 // Determine HTTP port from BindAddress.
 func (c Config) Port() (int, error) {
 	if err := c.Validate(); err != nil {
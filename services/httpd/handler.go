@@ -8,8 +8,10 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/http/pprof"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,6 +22,7 @@ import (
 	"github.com/influxdata/influxdb/uuid"
 	"github.com/influxdata/kapacitor/auth"
 	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/lineprotocol"
 )
 
 // statistics gathered by the httpd package.
@@ -31,6 +34,9 @@ const (
 	statPointsWrittenOK           = "points_written_ok"   // Number of points written OK
 	statPointsWrittenFail         = "points_written_fail" // Number of points that failed to be written
 	statAuthFail                  = "auth_fail"           // Number of requests that failed to authenticate
+	statRequestDenied             = "req_denied"          // Number of requests denied because of their source address
+	statRequestRateLimited        = "req_rate_limited"    // Number of requests rejected by rate limits
+	statPointsWrittenBackpressure = "points_backpressure" // Number of points rejected by backpressure
 )
 
 const (
@@ -40,6 +46,8 @@ const (
 	BasePreviewPath = "/kapacitor/v1preview"
 	// Name of the special user for subscriptions
 	SubscriptionUser = "~subscriber"
+	// Prefix of API tokens, which are sent as bearer tokens.
+	APITokenPrefix = "kap_"
 )
 
 // AuthenticationMethod defines the type of authentication used.
@@ -50,6 +58,7 @@ const (
 	UserAuthentication AuthenticationMethod = iota
 	BearerAuthentication
 	SubscriptionAuthentication
+	CertificateAuthentication
 )
 
 type AuthorizationHandler func(http.ResponseWriter, *http.Request, auth.User)
@@ -61,28 +70,84 @@ type Route struct {
 	NoGzip      bool
 	NoJSON      bool
 	BypassAuth  bool
+	// NoAudit excludes the route from the audit log, e.g. for writing points.
+	NoAudit bool
+	// Scope grants access to the route to users who do not have the privilege for its API resource.
+	Scope auth.Scope
+	// AdminOnly restricts the route to admin users, privileges and scopes do not grant access to it.
+	AdminOnly bool
+	// Replicated marks the routes changing the state replicated to the standby server of a high availability pair,
+	// only the leader serves them.
+	Replicated bool
+
+	// subscription marks the routes receiving the points of InfluxDB subscriptions,
+	// they use the client certificate authentication mode of subscriptions.
+	subscription bool
 }
 
 // Handler represents an HTTP handler for the Kapacitor API server.
 type Handler struct {
 	methodMux map[string]*ServeMux
+	// snapshotMux serves the GET routes without authentication, for snapshots of audited resources.
+	snapshotMux *ServeMux
 
 	requireAuthentication bool
 	exposePprof           bool
 	sharedSecret          string
 
+	// Client certificate authentication modes of the API and of the subscriptions.
+	clientAuth             string
+	subscriptionClientAuth string
+	// Roles granted to the users authenticated by their client certificate, by certificate subject.
+	clientCertificateRoles map[string][]string
+
 	allowGzip bool
 
+	// Source address restrictions and rate limits, nil if there are none.
+	access *accessControl
+
 	Version string
 
 	AuthService auth.Interface
 
+	// TokenVerifier verifies bearer tokens which are not signed with the shared secret, when set.
+	// It returns the subject of the token, the local user the subject is explicitly mapped to, if any,
+	// and the scopes granted by the token.
+	TokenVerifier interface {
+		VerifyToken(token string) (subject, localUser string, scopes []auth.Scope, err error)
+	}
+
+	// APITokenService authenticates the bearer tokens with the APITokenPrefix, when set.
+	APITokenService interface {
+		Authenticate(token string) (auth.User, error)
+	}
+
+	// RoleService grants the scopes of roles to the users authenticated by their client certificate, when set.
+	RoleService interface {
+		RoleScopes(roles []string) ([]auth.Scope, error)
+	}
+
+	// AuditService records the mutating API requests, when set.
+	AuditService interface {
+		Record(e AuditEvent)
+	}
+
+	// LeaderService rejects the requests of replicated routes while the server is not the leader, when set.
+	LeaderService interface {
+		// Leader reports whether the server is the leader, and the URL of the leader otherwise.
+		Leader() (bool, string)
+	}
+
+	// PointsWriter writes the points, it returns a BackpressureError when it cannot accept them for now.
 	PointsWriter interface {
 		WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
 	}
 
 	DiagService interface {
 		SetLogLevelFromName(lvl string) error
+		SetComponentLogLevel(component, lvl string) error
+		SetTaskLogLevel(task, lvl string) error
+		LogLevels() (level string, components, tasks map[string]string)
 	}
 
 	diag Diagnostic
@@ -109,6 +174,7 @@ func NewHandler(
 ) *Handler {
 	h := &Handler{
 		methodMux:             make(map[string]*ServeMux),
+		snapshotMux:           NewServeMux(),
 		requireAuthentication: requireAuthentication,
 		exposePprof:           pprofEnabled,
 		sharedSecret:          sharedSecret,
@@ -136,6 +202,7 @@ func NewHandler(
 			Method:      method,
 			Pattern:     "/",
 			HandlerFunc: h.serve404,
+			NoAudit:     true,
 		}
 		h.addRawRoute(route)
 		previewRoute := Route{
@@ -143,6 +210,7 @@ func NewHandler(
 			Method:      method,
 			Pattern:     BasePreviewPath + "/",
 			HandlerFunc: h.rewritePreview,
+			NoAudit:     true,
 		}
 		h.addRawRoute(previewRoute)
 	}
@@ -162,9 +230,11 @@ func NewHandler(
 		},
 		{
 			// Data-ingest route.
-			Method:      "POST",
-			Pattern:     BasePath + "/write",
-			HandlerFunc: h.serveWrite,
+			Method:       "POST",
+			Pattern:      BasePath + "/write",
+			HandlerFunc:  h.serveWrite,
+			NoAudit:      true,
+			subscription: true,
 		},
 		{
 			// Satisfy CORS checks.
@@ -174,9 +244,11 @@ func NewHandler(
 		},
 		{
 			// Data-ingest route for /write endpoint without base path
-			Method:      "POST",
-			Pattern:     "/write",
-			HandlerFunc: h.serveWrite,
+			Method:       "POST",
+			Pattern:      "/write",
+			HandlerFunc:  h.serveWrite,
+			NoAudit:      true,
+			subscription: true,
 		},
 		{
 			// Satisfy CORS checks.
@@ -190,6 +262,12 @@ func NewHandler(
 			Pattern:     BasePath + "/:routes",
 			HandlerFunc: h.serveRoutes,
 		},
+		{
+			// Display current log levels
+			Method:      "GET",
+			Pattern:     BasePath + "/loglevel",
+			HandlerFunc: h.serveLogLevels,
+		},
 		{
 			// Change current log level
 			Method:      "POST",
@@ -291,9 +369,13 @@ func (h *Handler) addRawRoutes(routes []Route) error {
 // Add a route without prepending the BasePath
 func (h *Handler) addRawRoute(r Route) error {
 	var handler http.Handler
+	var snapshot http.HandlerFunc
 	// If it's a handler func that requires special authorization, wrap it in authentication only.
 	if hf, ok := r.HandlerFunc.(func(http.ResponseWriter, *http.Request, auth.User)); ok {
-		handler = authenticate(authorizeForward(hf), h, h.requireAuthentication)
+		handler = authenticate(h.auditRoute(r, h.leaderRoute(r, adminRoute(r, authorizeForward(hf, r.Scope)))), h, h.requireAuthentication, r.subscription)
+		snapshot = func(w http.ResponseWriter, r *http.Request) {
+			hf(w, r, auth.AdminUser)
+		}
 	}
 
 	// This is a normal handler signature so perform standard authentication/authorization.
@@ -302,11 +384,17 @@ func (h *Handler) addRawRoute(r Route) error {
 		if r.BypassAuth && h.exposePprof {
 			requireAuth = false
 		}
-		handler = authenticate(authorize(hf), h, requireAuth)
+		handler = authenticate(h.auditRoute(r, h.leaderRoute(r, adminRoute(r, authorize(hf, r.Scope)))), h, requireAuth, r.subscription)
+		snapshot = hf
 	}
 	if handler == nil {
 		return errors.New("route does not have valid handler function")
 	}
+	if r.Method == "GET" {
+		if err := h.snapshotMux.Handle(r.Pattern, snapshot); err != nil {
+			return err
+		}
+	}
 
 	// Set basic handlers for all requests
 	if !r.NoJSON {
@@ -337,6 +425,15 @@ func (h *Handler) DelRoutes(routes []Route) {
 	}
 }
 
+// routeClientAuth returns the client certificate authentication mode of the routes,
+// either the routes of subscriptions or the API routes.
+func (h *Handler) routeClientAuth(subscription bool) string {
+	if subscription {
+		return h.subscriptionClientAuth
+	}
+	return h.clientAuth
+}
+
 // Delete a route from the handler. No-op if route does not exist.
 func (h *Handler) DelRoute(r Route) {
 	r.Pattern = BasePath + r.Pattern
@@ -349,6 +446,9 @@ func (h *Handler) delRawRoute(r Route) {
 	if ok {
 		mux.Deregister(r.Pattern)
 	}
+	if r.Method == "GET" {
+		h.snapshotMux.Deregister(r.Pattern)
+	}
 }
 
 // RewritePreview rewrites the URL path from BasePreviewPath to BasePath,
@@ -356,7 +456,7 @@ func (h *Handler) delRawRoute(r Route) {
 func (h *Handler) rewritePreview(w http.ResponseWriter, r *http.Request) {
 	if strings.HasPrefix(r.URL.Path, BasePreviewPath) {
 		r.URL.Path = strings.Replace(r.URL.Path, BasePreviewPath, BasePath, 1)
-		h.ServeHTTP(w, r)
+		h.serve(w, r)
 	} else {
 		h.serve404(w, r)
 	}
@@ -365,6 +465,14 @@ func (h *Handler) rewritePreview(w http.ResponseWriter, r *http.Request) {
 // ServeHTTP responds to HTTP request to the handler.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.statMap.Add(statRequest, 1)
+	if h.access != nil && !h.access.check(w, r, h) {
+		return
+	}
+	h.serve(w, r)
+}
+
+// serve routes the request to the handler of its method and path.
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
 	method := r.Method
 	if method == "" {
 		method = "GET"
@@ -376,7 +484,14 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// serveLogLevel sets the log level of the server
+// serveLogLevels returns the log levels of the server, and of the components and tasks that override it.
+func (h *Handler) serveLogLevels(w http.ResponseWriter, r *http.Request) {
+	var levels client.LogLevels
+	levels.Level, levels.Components, levels.Tasks = h.DiagService.LogLevels()
+	w.Write(MarshalJSON(levels, true))
+}
+
+// serveLogLevel sets the log level of the server, or of a component or task
 func (h *Handler) serveLogLevel(w http.ResponseWriter, r *http.Request) {
 	var opt client.LogLevelOptions
 	dec := json.NewDecoder(r.Body)
@@ -385,7 +500,16 @@ func (h *Handler) serveLogLevel(w http.ResponseWriter, r *http.Request) {
 		HttpError(w, "invalid json: "+err.Error(), true, http.StatusBadRequest)
 		return
 	}
-	err = h.DiagService.SetLogLevelFromName(opt.Level)
+	switch {
+	case opt.Component != "" && opt.Task != "":
+		err = errors.New("cannot set the level of both a component and a task")
+	case opt.Component != "":
+		err = h.DiagService.SetComponentLogLevel(opt.Component, opt.Level)
+	case opt.Task != "":
+		err = h.DiagService.SetTaskLogLevel(opt.Task, opt.Level)
+	default:
+		err = h.DiagService.SetLogLevelFromName(opt.Level)
+	}
 	if err != nil {
 		HttpError(w, err.Error(), true, http.StatusBadRequest)
 		return
@@ -407,6 +531,20 @@ func (h *Handler) serveRoutes(w http.ResponseWriter, r *http.Request) {
 	w.Write(MarshalJSON(routes, true))
 }
 
+// BackpressureError is returned by a PointsWriter that cannot accept points for now,
+// e.g. because the queues of the tasks are full.
+type BackpressureError interface {
+	error
+	// RetryAfter returns the time after which the write should be retried.
+	RetryAfter() time.Duration
+}
+
+// LineProtocolWriter is implemented by a PointsWriter that writes parsed line protocol points,
+// which are then parsed without allocating intermediate points.
+type LineProtocolWriter interface {
+	WriteLineProtocol(database, retentionPolicy string, points *lineprotocol.Points) error
+}
+
 // serve404 returns an a formated 404 error
 func (h *Handler) serve404(w http.ResponseWriter, r *http.Request) {
 	HttpError(w, "Not Found", true, http.StatusNotFound)
@@ -467,7 +605,28 @@ func (h *Handler) serveWriteLine(w http.ResponseWriter, r *http.Request, body []
 		precision = "n"
 	}
 
-	points, err := models.ParsePointsWithPrecision(body, time.Now().UTC(), precision)
+	// Parse the points, into pooled points when the writer accepts them.
+	var (
+		write func(database, retentionPolicy string) error
+		n     int
+		err   error
+	)
+	if lw, ok := h.PointsWriter.(LineProtocolWriter); ok {
+		points := lineprotocol.Get()
+		defer lineprotocol.Put(points)
+		err = points.Parse(body, time.Now().UTC(), precision)
+		n = points.Len()
+		write = func(database, retentionPolicy string) error {
+			return lw.WriteLineProtocol(database, retentionPolicy, points)
+		}
+	} else {
+		var points []models.Point
+		points, err = models.ParsePointsWithPrecision(body, time.Now().UTC(), precision)
+		n = len(points)
+		write = func(database, retentionPolicy string) error {
+			return h.PointsWriter.WritePoints(database, retentionPolicy, models.ConsistencyLevelAll, points)
+		}
+	}
 	if err != nil {
 		if err.Error() == "EOF" {
 			w.WriteHeader(http.StatusOK)
@@ -493,22 +652,22 @@ func (h *Handler) serveWriteLine(w http.ResponseWriter, r *http.Request, body []
 	}
 
 	// Write points.
-	if err := h.PointsWriter.WritePoints(
-		database,
-		r.FormValue("rp"),
-		models.ConsistencyLevelAll,
-		points,
-	); influxdb.IsClientError(err) {
-		h.statMap.Add(statPointsWrittenFail, int64(len(points)))
+	if err := write(database, r.FormValue("rp")); influxdb.IsClientError(err) {
+		h.statMap.Add(statPointsWrittenFail, int64(n))
 		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
 		return
+	} else if bp, ok := err.(BackpressureError); ok {
+		h.statMap.Add(statPointsWrittenBackpressure, int64(n))
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(bp.RetryAfter().Seconds()))))
+		h.writeError(w, influxql.Result{Err: err}, http.StatusTooManyRequests)
+		return
 	} else if err != nil {
-		h.statMap.Add(statPointsWrittenFail, int64(len(points)))
+		h.statMap.Add(statPointsWrittenFail, int64(n))
 		h.writeError(w, influxql.Result{Err: err}, http.StatusInternalServerError)
 		return
 	}
 
-	h.statMap.Add(statPointsWrittenOK, int64(len(points)))
+	h.statMap.Add(statPointsWrittenOK, int64(n))
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -580,8 +739,16 @@ func resultError(w http.ResponseWriter, result influxql.Result, code int) {
 
 // authenticate wraps a handler and ensures that if user credentials are passed in
 // an attempt is made to authenticate that user. If authentication fails, an error is returned.
-func authenticate(inner AuthorizationHandler, h *Handler, requireAuthentication bool) http.Handler {
+// A verified client certificate authenticates the requests without other credentials.
+func authenticate(inner AuthorizationHandler, h *Handler, requireAuthentication, subscription bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cert, err := clientCertificate(r, h.routeClientAuth(subscription))
+		if err != nil {
+			h.statMap.Add(statAuthFail, 1)
+			HttpError(w, err.Error(), false, http.StatusUnauthorized)
+			return
+		}
+
 		// Return early if we are not authenticating
 		if !requireAuthentication {
 			inner(w, r, auth.AdminUser)
@@ -591,6 +758,9 @@ func authenticate(inner AuthorizationHandler, h *Handler, requireAuthentication
 		var user auth.User
 
 		creds, err := parseCredentials(r)
+		if err != nil && cert != nil {
+			creds, err = credentials{Method: CertificateAuthentication}, nil
+		}
 		if err != nil {
 			h.statMap.Add(statAuthFail, 1)
 			HttpError(w, err.Error(), false, http.StatusUnauthorized)
@@ -612,6 +782,33 @@ func authenticate(inner AuthorizationHandler, h *Handler, requireAuthentication
 				return
 			}
 		case BearerAuthentication:
+			if h.APITokenService != nil && strings.HasPrefix(creds.Token, APITokenPrefix) {
+				if user, err = h.APITokenService.Authenticate(creds.Token); err != nil {
+					h.statMap.Add(statAuthFail, 1)
+					HttpError(w, fmt.Sprintf("invalid token: %s", err.Error()), false, http.StatusUnauthorized)
+					return
+				}
+				break
+			}
+			if h.TokenVerifier != nil && !sharedSecretToken(creds.Token) {
+				subject, localUser, scopes, err := h.TokenVerifier.VerifyToken(creds.Token)
+				if err != nil {
+					h.statMap.Add(statAuthFail, 1)
+					HttpError(w, fmt.Sprintf("invalid token: %s", err.Error()), false, http.StatusUnauthorized)
+					return
+				}
+				// Tokens are never matched to local users by name,
+				// unmapped subjects are unprivileged users only granted the scopes of their token.
+				if localUser == "" {
+					user = auth.NewUser(subject, nil, false, nil)
+				} else if user, err = h.AuthService.User(localUser); err != nil {
+					h.statMap.Add(statAuthFail, 1)
+					HttpError(w, fmt.Sprintf("unknown user %q mapped to token subject", localUser), false, http.StatusUnauthorized)
+					return
+				}
+				user = user.WithScopes(scopes...)
+				break
+			}
 			keyLookupFn := func(token *jwt.Token) (interface{}, error) {
 				// Check for expected signing method.
 				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -662,6 +859,12 @@ func authenticate(inner AuthorizationHandler, h *Handler, requireAuthentication
 				HttpError(w, err.Error(), false, http.StatusUnauthorized)
 				return
 			}
+		case CertificateAuthentication:
+			if user, err = h.certificateUser(cert); err != nil {
+				h.statMap.Add(statAuthFail, 1)
+				HttpError(w, fmt.Sprintf("client certificate authentication failed: %s", err.Error()), false, http.StatusUnauthorized)
+				return
+			}
 		default:
 			HttpError(w, "unsupported authentication", false, http.StatusUnauthorized)
 		}
@@ -669,6 +872,25 @@ func authenticate(inner AuthorizationHandler, h *Handler, requireAuthentication
 	})
 }
 
+// sharedSecretToken reports whether the token is signed with an HMAC algorithm, i.e. with the shared secret.
+func sharedSecretToken(token string) bool {
+	i := strings.IndexByte(token, '.')
+	if i < 0 {
+		return false
+	}
+	b, err := jwt.DecodeSegment(token[:i])
+	if err != nil {
+		return false
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(b, &header); err != nil {
+		return false
+	}
+	return strings.HasPrefix(header.Alg, "HS")
+}
+
 // Map an HTTP method to an auth.Privilege.
 func requiredPrivilegeForHTTPMethod(method string) (auth.Privilege, error) {
 	switch m := strings.ToUpper(method); m {
@@ -691,7 +913,8 @@ type missingPrivilege interface {
 }
 
 // Check if user is authorized to perform request.
-func authorizeRequest(r *http.Request, user auth.User) error {
+// The user is authorized if it has the privilege for the API resource of the request or the scope of its route.
+func authorizeRequest(r *http.Request, user auth.User, scope auth.Scope) error {
 	// Now that we have a user authorize the request
 	rp, err := requiredPrivilegeForHTTPMethod(r.Method)
 	if err != nil {
@@ -702,6 +925,12 @@ func authorizeRequest(r *http.Request, user auth.User) error {
 		Privilege: rp,
 	}
 	err = user.AuthorizeAction(action)
+	if err != nil && scope != "" {
+		if user.AuthorizeScope(scope) == nil {
+			return nil
+		}
+		return fmt.Errorf("user %s does not have the %q scope for API endpoint %q", user.Name(), scope, r.URL.Path)
+	}
 	if err != nil {
 		if mp, ok := err.(missingPrivilege); ok {
 			return fmt.Errorf("user %s does not have \"%v\" privilege for API endpoint %q", user.Name(), mp.MissingPrivlege(), r.URL.Path)
@@ -713,9 +942,9 @@ func authorizeRequest(r *http.Request, user auth.User) error {
 }
 
 // Authorize the request and call normal inner handler.
-func authorize(inner http.HandlerFunc) AuthorizationHandler {
+func authorize(inner http.HandlerFunc, scope auth.Scope) AuthorizationHandler {
 	return func(w http.ResponseWriter, r *http.Request, user auth.User) {
-		if err := authorizeRequest(r, user); err != nil {
+		if err := authorizeRequest(r, user, scope); err != nil {
 			HttpError(w, err.Error(), false, http.StatusForbidden)
 			return
 		}
@@ -724,9 +953,9 @@ func authorize(inner http.HandlerFunc) AuthorizationHandler {
 }
 
 // Authorize the request and forward user to inner handler.
-func authorizeForward(inner AuthorizationHandler) AuthorizationHandler {
+func authorizeForward(inner AuthorizationHandler, scope auth.Scope) AuthorizationHandler {
 	return func(w http.ResponseWriter, r *http.Request, user auth.User) {
-		if err := authorizeRequest(r, user); err != nil {
+		if err := authorizeRequest(r, user, scope); err != nil {
 			HttpError(w, err.Error(), false, http.StatusForbidden)
 			return
 		}
@@ -734,6 +963,41 @@ func authorizeForward(inner AuthorizationHandler) AuthorizationHandler {
 	}
 }
 
+// adminRoute rejects the requests of admin only routes from users who are not admins.
+func adminRoute(route Route, inner AuthorizationHandler) AuthorizationHandler {
+	if !route.AdminOnly {
+		return inner
+	}
+	return func(w http.ResponseWriter, r *http.Request, user auth.User) {
+		if !user.IsAdmin() {
+			HttpError(w, fmt.Sprintf("user %s is not an admin, API endpoint %q is restricted to admin users", user.Name(), r.URL.Path), false, http.StatusForbidden)
+			return
+		}
+		inner(w, r, user)
+	}
+}
+
+// leaderRoute rejects the requests of replicated routes while the server is not the leader,
+// since the standby server replaces its replicated state with the state of the leader.
+func (h *Handler) leaderRoute(route Route, inner AuthorizationHandler) AuthorizationHandler {
+	if !route.Replicated {
+		return inner
+	}
+	return func(w http.ResponseWriter, r *http.Request, user auth.User) {
+		if h.LeaderService != nil {
+			if leader, url := h.LeaderService.Leader(); !leader {
+				msg := "server is a standby, send the request to the leader"
+				if url != "" {
+					msg += " at " + url
+				}
+				HttpError(w, msg, false, http.StatusServiceUnavailable)
+				return
+			}
+		}
+		inner(w, r, user)
+	}
+}
+
 type credentials struct {
 	Method   AuthenticationMethod
 	Username string
@@ -808,7 +1072,7 @@ func (w gzipResponseWriter) Flush() {
 // determines if the client can accept compressed responses, and encodes accordingly
 func gzipFilter(inner http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || IsWebSocket(r) {
 			inner.ServeHTTP(w, r)
 			return
 		}
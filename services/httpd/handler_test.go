@@ -1,8 +1,10 @@
 package httpd
 
 import (
+	"net/http"
 	"testing"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/influxdata/kapacitor/auth"
 )
 
@@ -103,3 +105,88 @@ func Test_RequiredPrilegeForHTTPMethod(t *testing.T) {
 		}
 	}
 }
+
+func Test_AuthorizeRequest(t *testing.T) {
+	tasksReader := auth.NewUser("reader", nil, false, nil).WithScopes(auth.TasksReadScope)
+	privileged := auth.NewUser("privileged", nil, false, map[string][]auth.Privilege{
+		"/api/tasks": {auth.AllPrivileges},
+	})
+	testCases := []struct {
+		method string
+		path   string
+		user   auth.User
+		scope  auth.Scope
+		err    string
+	}{
+		{
+			method: "GET",
+			path:   BasePath + "/tasks",
+			user:   tasksReader,
+			scope:  auth.TasksReadScope,
+		},
+		{
+			method: "POST",
+			path:   BasePath + "/tasks",
+			user:   tasksReader,
+			scope:  auth.TasksWriteScope,
+			err:    `user reader does not have the "tasks:write" scope for API endpoint "/kapacitor/v1/tasks"`,
+		},
+		{
+			method: "GET",
+			path:   BasePath + "/storage/stores",
+			user:   tasksReader,
+			err:    `user reader does not have "read" privilege for API endpoint "/kapacitor/v1/storage/stores"`,
+		},
+		{
+			method: "OPTIONS",
+			path:   BasePath + "/tasks",
+			user:   tasksReader,
+			scope:  auth.TasksWriteScope,
+		},
+		{
+			method: "DELETE",
+			path:   BasePath + "/tasks/a",
+			user:   privileged,
+			scope:  auth.TasksWriteScope,
+		},
+		{
+			method: "POST",
+			path:   BasePath + "/config/smtp",
+			user:   auth.AdminUser,
+			scope:  auth.ConfigWriteScope,
+		},
+	}
+	for _, tc := range testCases {
+		r, err := http.NewRequest(tc.method, tc.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = authorizeRequest(r, tc.user, tc.scope)
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("%s %s: unexpected error: %v", tc.method, tc.path, err)
+			}
+		} else if err == nil || err.Error() != tc.err {
+			t.Errorf("%s %s: unexpected error: got %v exp %s", tc.method, tc.path, err, tc.err)
+		}
+	}
+}
+
+func Test_SharedSecretToken(t *testing.T) {
+	claims := jwt.MapClaims{"username": "bob"}
+	hmac, err := jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sharedSecretToken(hmac) {
+		t.Error("expected HMAC token to be signed with the shared secret")
+	}
+	// The signature is not verified, only the algorithm matters.
+	rsa := jwt.EncodeSegment([]byte(`{"alg":"RS256","typ":"JWT"}`)) + ".e30.c2ln"
+	if sharedSecretToken(rsa) {
+		t.Error("expected RSA token not to be signed with the shared secret")
+	}
+	if sharedSecretToken("not a token") {
+		t.Error("expected invalid token not to be signed with the shared secret")
+	}
+}
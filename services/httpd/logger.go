@@ -1,6 +1,8 @@
 package httpd
 
 import (
+	"bufio"
+	"errors"
 	"net"
 	"net/http"
 	"strings"
@@ -29,6 +31,15 @@ func (l *responseLogger) Flush() {
 	l.w.(http.Flusher).Flush()
 }
 
+func (l *responseLogger) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := l.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	l.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
 func (l *responseLogger) Write(b []byte) (int, error) {
 	if l.status == 0 {
 		// Set status if WriteHeader has not been called
@@ -8,9 +8,13 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/influxdata/kapacitor/services/httpd/acme"
+	"github.com/pkg/errors"
 )
 
 type Diagnostic interface {
@@ -22,6 +26,8 @@ type Diagnostic interface {
 	AuthenticationEnabled(enabled bool)
 
 	ListeningOn(addr string, proto string)
+	ReloadedCertificate(path string)
+	ObtainedCertificate(domains []string, notAfter time.Time)
 
 	WriteBodyReceived(body string)
 
@@ -58,18 +64,38 @@ type Diagnostic interface {
 }
 
 type Service struct {
-	ln    net.Listener
-	addr  string
-	https bool
-	cert  string
-	key   string
-	err   chan error
+	ln       net.Listener
+	addr     string
+	hostname string
+	https    bool
+	cert     string
+	key      string
+	err      chan error
+
+	certReloadInterval time.Duration
+	certFile           *certificateFile
+
+	acmeEnabled     bool
+	acmeConfig      acme.Config
+	acmeHTTPAddress string
+	acmeManager     *acme.Manager
+	acmeServer      *http.Server
+
+	clientCA  string
+	tlsConfig *tls.Config
+
+	closing chan struct{}
+	certWG  sync.WaitGroup
 
 	externalURL string
+	// lnMu protects the listener and the external URL, which change when the service is rebound.
+	lnMu sync.RWMutex
 
 	server *http.Server
 	mu     sync.Mutex
 	wg     sync.WaitGroup
+	// replaced is closed once the listener is replaced by a listener on another address.
+	replaced chan struct{}
 
 	new             chan net.Conn
 	active          chan net.Conn
@@ -93,20 +119,26 @@ func NewService(c Config, hostname string, d Diagnostic) *Service {
 
 	localStatMap := &expvar.Map{}
 	localStatMap.Init()
-	port, _ := c.Port()
-	u := url.URL{
-		Host:   fmt.Sprintf("%s:%d", hostname, port),
-		Scheme: "http",
-	}
-	if c.HttpsEnabled {
-		u.Scheme = "https"
-	}
 	s := &Service{
-		addr:            c.BindAddress,
-		https:           c.HttpsEnabled,
-		cert:            c.HttpsCertificate,
-		key:             c.HTTPSPrivateKey,
-		externalURL:     u.String(),
+		addr:     c.BindAddress,
+		hostname: hostname,
+		https:    c.HttpsEnabled,
+		cert:     c.HttpsCertificate,
+		key:      c.HTTPSPrivateKey,
+
+		certReloadInterval: time.Duration(c.HTTPSCertificateReloadInterval),
+		acmeEnabled:        c.ACMEEnabled,
+		acmeConfig: acme.Config{
+			DirectoryURL: c.ACMEDirectoryURL,
+			Email:        c.ACMEEmail,
+			Domains:      c.ACMEDomains,
+			CacheDir:     c.ACMECacheDir,
+			RenewBefore:  time.Duration(c.ACMERenewBefore),
+		},
+		acmeHTTPAddress: c.ACMEHTTPAddress,
+		clientCA:        c.HTTPSClientCA,
+
+		externalURL:     externalURL(hostname, c.BindAddress, c.HttpsEnabled),
 		err:             make(chan error, 1),
 		shutdownTimeout: time.Duration(c.ShutdownTimeout),
 		Handler: NewHandler(
@@ -135,6 +167,11 @@ func NewService(c Config, hostname string, d Diagnostic) *Service {
 	if s.key == "" {
 		s.key = s.cert
 	}
+	s.Handler.clientAuth = c.HTTPSClientAuth
+	s.Handler.subscriptionClientAuth = c.HTTPSSubscriptionClientAuth
+	s.Handler.clientCertificateRoles = c.HTTPSClientCertificateRoles
+	// The networks were parsed when validating the config.
+	s.Handler.access, _ = newAccessControl(c)
 
 	return s
 }
@@ -148,29 +185,47 @@ func (s *Service) Open() error {
 
 	// Open listener.
 	if s.https {
-		cert, err := tls.LoadX509KeyPair(s.cert, s.key)
-		if err != nil {
-			return err
-		}
-
-		listener, err := tls.Listen("tcp", s.addr, &tls.Config{
-			Certificates: []tls.Certificate{cert},
-		})
-		if err != nil {
-			return err
+		tlsConfig := &tls.Config{}
+		if clientAuthEnabled(s.Handler.clientAuth) || clientAuthEnabled(s.Handler.subscriptionClientAuth) {
+			pool, err := loadClientCAs(s.clientCA)
+			if err != nil {
+				return err
+			}
+			tlsConfig.ClientCAs = pool
+			// Certificates are required per route unless every route requires them.
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+			if s.Handler.clientAuth == ClientAuthRequire && s.Handler.subscriptionClientAuth == ClientAuthRequire {
+				tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+			}
 		}
-
-		s.diag.ListeningOn(listener.Addr().String(), "https")
-		s.ln = listener
-	} else {
-		listener, err := net.Listen("tcp", s.addr)
-		if err != nil {
-			return err
+		s.closing = make(chan struct{})
+		if s.acmeEnabled {
+			if err := s.openACME(); err != nil {
+				return err
+			}
+			tlsConfig.GetCertificate = s.acmeManager.GetCertificate
+		} else {
+			s.certFile = newCertificateFile(s.cert, s.key)
+			if err := s.certFile.Load(); err != nil {
+				return err
+			}
+			tlsConfig.GetCertificate = s.certFile.GetCertificate
+			if s.certReloadInterval > 0 {
+				s.certWG.Add(1)
+				go s.watchCertificate(s.closing)
+			}
 		}
 
-		s.diag.ListeningOn(listener.Addr().String(), "http")
-		s.ln = listener
+		s.tlsConfig = tlsConfig
 	}
+	listener, err := s.listen(s.addr)
+	if err != nil {
+		s.closeCertificates()
+		return err
+	}
+	s.lnMu.Lock()
+	s.ln = listener
+	s.lnMu.Unlock()
 
 	// Define server
 	s.server = &http.Server{
@@ -188,16 +243,86 @@ func (s *Service) Open() error {
 	// Begin listening for requests in a separate goroutine.
 	go s.manage()
 
+	s.replaced = make(chan struct{})
 	s.wg.Add(1)
-	go s.serve()
+	go s.serve(listener, s.replaced)
 	return nil
 }
 
+// listen opens a listener on the address, with TLS if HTTPS is enabled.
+func (s *Service) listen(addr string) (net.Listener, error) {
+	if s.https {
+		listener, err := tls.Listen("tcp", addr, s.tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		s.diag.ListeningOn(listener.Addr().String(), "https")
+		return listener, nil
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s.diag.ListeningOn(listener.Addr().String(), "http")
+	return listener, nil
+}
+
+// Rebind moves the listener of the service to the address.
+// The connections already open are served until they are closed,
+// and the service keeps its listener if it fails to listen on the address.
+func (s *Service) Rebind(addr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.server == nil {
+		s.addr = addr
+		s.setExternalURL(addr)
+		return nil
+	}
+	listener, err := s.listen(addr)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", addr)
+	}
+	old, replaced := s.ln, s.replaced
+	s.lnMu.Lock()
+	s.ln = listener
+	s.lnMu.Unlock()
+	s.addr = addr
+	s.setExternalURL(addr)
+
+	s.replaced = make(chan struct{})
+	s.wg.Add(1)
+	go s.serve(listener, s.replaced)
+
+	close(replaced)
+	return old.Close()
+}
+
+func (s *Service) setExternalURL(addr string) {
+	s.lnMu.Lock()
+	defer s.lnMu.Unlock()
+	s.externalURL = externalURL(s.hostname, addr, s.https)
+}
+
+// externalURL returns the URL of the hostname on the port of the bind address.
+func externalURL(hostname, bindAddress string, https bool) string {
+	_, portStr, _ := net.SplitHostPort(bindAddress)
+	port, _ := strconv.ParseInt(portStr, 10, 64)
+	u := url.URL{
+		Host:   fmt.Sprintf("%s:%d", hostname, port),
+		Scheme: "http",
+	}
+	if https {
+		u.Scheme = "https"
+	}
+	return u.String()
+}
+
 // Close closes the underlying listener.
 func (s *Service) Close() error {
 	defer s.diag.StoppedService()
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.closeCertificates()
 	// If server is not set we were never started
 	if s.server == nil {
 		return nil
@@ -220,6 +345,92 @@ func (s *Service) Close() error {
 	return nil
 }
 
+// openACME starts obtaining the certificate from the ACME certificate authority,
+// and answering its challenges.
+func (s *Service) openACME() error {
+	s.acmeManager = acme.NewManager(s.acmeConfig, s.diag)
+	ln, err := net.Listen("tcp", s.acmeHTTPAddress)
+	if err != nil {
+		return errors.Wrap(err, "failed to listen for ACME challenges")
+	}
+	if err := s.acmeManager.Open(); err != nil {
+		ln.Close()
+		return err
+	}
+	s.diag.ListeningOn(ln.Addr().String(), "acme")
+	s.acmeServer = &http.Server{
+		Handler:  s.acmeManager.HTTPHandler(),
+		ErrorLog: s.httpServerErrorLogger,
+	}
+	s.certWG.Add(1)
+	go func() {
+		defer s.certWG.Done()
+		s.acmeServer.Serve(ln)
+	}()
+	return nil
+}
+
+// watchCertificate reloads the certificate files when they change.
+func (s *Service) watchCertificate(closing <-chan struct{}) {
+	defer s.certWG.Done()
+	ticker := time.NewTicker(s.certReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closing:
+			return
+		case <-ticker.C:
+			changed, err := s.certFile.Changed()
+			if err != nil {
+				s.diag.Error("failed to check certificate files", err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+			if err := s.certFile.Load(); err != nil {
+				s.diag.Error("failed to reload certificate, keeping the current certificate", err)
+				continue
+			}
+			s.diag.ReloadedCertificate(s.cert)
+		}
+	}
+}
+
+// closeCertificates stops reloading or renewing the certificate.
+// s.mu must be held.
+func (s *Service) closeCertificates() {
+	if s.closing == nil {
+		return
+	}
+	close(s.closing)
+	s.closing = nil
+	if s.acmeServer != nil {
+		s.acmeServer.Close()
+		s.acmeServer = nil
+	}
+	if s.acmeManager != nil {
+		s.acmeManager.Close()
+		s.acmeManager = nil
+	}
+	s.certWG.Wait()
+}
+
+// ReloadCertificate reloads the certificate files of the HTTPS listener,
+// connections are not interrupted.
+func (s *Service) ReloadCertificate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.certFile == nil {
+		return nil
+	}
+	if err := s.certFile.Load(); err != nil {
+		return err
+	}
+	s.diag.ReloadedCertificate(s.cert)
+	return nil
+}
+
 func (s *Service) Err() <-chan error {
 	return s.err
 }
@@ -302,10 +513,16 @@ func (s *Service) manage() {
 	}
 }
 
-// serve serves the handler from the listener.
-func (s *Service) serve() {
+// serve serves the handler from the listener, until it is closed.
+// Nothing is reported once the listener is replaced.
+func (s *Service) serve(ln net.Listener, replaced <-chan struct{}) {
 	defer s.wg.Done()
-	err := s.server.Serve(s.ln)
+	err := s.server.Serve(ln)
+	select {
+	case <-replaced:
+		return
+	default:
+	}
 	// The listener was closed so exit
 	// See https://github.com/golang/go/issues/4373
 	if !strings.Contains(err.Error(), "closed") {
@@ -316,6 +533,8 @@ func (s *Service) serve() {
 }
 
 func (s *Service) Addr() net.Addr {
+	s.lnMu.RLock()
+	defer s.lnMu.RUnlock()
 	if s.ln != nil {
 		return s.ln.Addr()
 	}
@@ -323,18 +542,21 @@ func (s *Service) Addr() net.Addr {
 }
 
 func (s *Service) URL() string {
-	if s.ln != nil {
-		if s.https {
-			return "https://" + s.Addr().String() + BasePath
-		}
-		return "http://" + s.Addr().String() + BasePath
+	addr := s.Addr()
+	if addr == nil {
+		return ""
+	}
+	if s.https {
+		return "https://" + addr.String() + BasePath
 	}
-	return ""
+	return "http://" + addr.String() + BasePath
 }
 
 // URL that should resolve externally to the server HTTP endpoint.
 // It is possible that the URL does not resolve correctly  if the hostname config setting is incorrect.
 func (s *Service) ExternalURL() string {
+	s.lnMu.RLock()
+	defer s.lnMu.RUnlock()
 	return s.externalURL
 }
 
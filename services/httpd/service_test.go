@@ -0,0 +1,800 @@
+package httpd_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/auth"
+	"github.com/influxdata/kapacitor/lineprotocol"
+	"github.com/influxdata/kapacitor/services/diagnostic"
+	"github.com/influxdata/kapacitor/services/httpd"
+)
+
+// newCertificate returns a certificate with the template and its key,
+// signed by the parent or self signed if the parent is nil.
+func newCertificate(t *testing.T, tmpl *x509.Certificate, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.Leaf, parent.PrivateKey.(*ecdsa.PrivateKey)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// writeCertificate writes the certificate and its key to the file.
+func writeCertificate(t *testing.T, path string, cert tls.Certificate) {
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})...)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeServerCertificate writes a self signed certificate with the serial number to the file.
+func writeServerCertificate(t *testing.T, path string, serial int64) {
+	writeCertificate(t, path, newCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+	}, nil))
+}
+
+// serial returns the serial number of the certificate served at the address.
+func serial(t *testing.T, addr string) int64 {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestService_ReloadCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kapacitor.pem")
+	writeServerCertificate(t, path, 1)
+
+	c := httpd.NewConfig()
+	c.BindAddress = "127.0.0.1:0"
+	c.HttpsEnabled = true
+	c.HttpsCertificate = path
+	c.HTTPSCertificateReloadInterval = toml.Duration(10 * time.Millisecond)
+	ds := diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	ds.Open()
+	s := httpd.NewService(c, "localhost", ds.NewHTTPDHandler())
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	addr := s.Addr().String()
+
+	if got := serial(t, addr); got != 1 {
+		t.Fatalf("unexpected serial number: got %d exp 1", got)
+	}
+
+	// Changed files are reloaded.
+	writeServerCertificate(t, path, 2)
+	// Make sure the modification time changes on file systems with a coarse resolution.
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Second))
+	var got int64
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if got = serial(t, addr); got == 2 {
+			break
+		}
+	}
+	if got != 2 {
+		t.Fatalf("unexpected serial number after change: got %d exp 2", got)
+	}
+
+	// Invalid files are ignored.
+	if err := ioutil.WriteFile(path, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReloadCertificate(); err == nil {
+		t.Error("expected error reloading an invalid certificate")
+	}
+	if got := serial(t, addr); got != 2 {
+		t.Errorf("unexpected serial number after invalid change: got %d exp 2", got)
+	}
+
+	// Certificates are reloaded on demand, e.g. on SIGHUP.
+	writeServerCertificate(t, path, 3)
+	if err := s.ReloadCertificate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := serial(t, addr); got != 3 {
+		t.Errorf("unexpected serial number after reload: got %d exp 3", got)
+	}
+}
+
+// authService knows a single user without any privileges and accepts every subscription token.
+type authService struct {
+	auth.Interface
+}
+
+func (authService) User(username string) (auth.User, error) {
+	if username != "bob" {
+		return auth.User{}, errors.New("unknown user")
+	}
+	return auth.NewUser(username, nil, false, nil), nil
+}
+
+func (authService) SubscriptionUser(token string) (auth.User, error) {
+	return auth.NewUser(httpd.SubscriptionUser, nil, false, map[string][]auth.Privilege{
+		auth.APIResource("/write"): {auth.WritePrivilege},
+	}), nil
+}
+
+// roleService grants the tasks:read scope with the viewer role.
+type roleService struct{}
+
+func (roleService) RoleScopes(roles []string) ([]auth.Scope, error) {
+	var scopes []auth.Scope
+	for _, r := range roles {
+		if r == "viewer" {
+			scopes = append(scopes, auth.TasksReadScope)
+		}
+	}
+	return scopes, nil
+}
+
+func TestService_ClientCertificates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	serverPath := filepath.Join(dir, "kapacitor.pem")
+	writeServerCertificate(t, serverPath, 1)
+	ca := newCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	caPath := filepath.Join(dir, "ca.pem")
+	writeCertificate(t, caPath, ca)
+	clientCertificate := func(serial int64, subject pkix.Name, parent *tls.Certificate) tls.Certificate {
+		return newCertificate(t, &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      subject,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}, parent)
+	}
+
+	c := httpd.NewConfig()
+	c.BindAddress = "127.0.0.1:0"
+	c.AuthEnabled = true
+	c.HttpsEnabled = true
+	c.HttpsCertificate = serverPath
+	c.HTTPSClientAuth = httpd.ClientAuthRequire
+	c.HTTPSSubscriptionClientAuth = httpd.ClientAuthOptional
+	c.HTTPSClientCA = caPath
+	c.HTTPSClientCertificateRoles = map[string][]string{
+		"CN=deploy,O=Example": {"viewer"},
+		"grafana":             {"viewer"},
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	ds := diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	ds.Open()
+	s := httpd.NewService(c, "localhost", ds.NewHTTPDHandler())
+	s.Handler.AuthService = authService{}
+	s.Handler.RoleService = roleService{}
+	if err := s.Handler.AddRoute(httpd.Route{
+		Method:  "GET",
+		Pattern: "/whoami",
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request, user auth.User) {
+			w.Write([]byte(user.Name()))
+		},
+		Scope: auth.TasksReadScope,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	base := "https://" + s.Addr().String()
+
+	deploy := clientCertificate(2, pkix.Name{CommonName: "deploy", Organization: []string{"Example"}}, &ca)
+	grafana := clientCertificate(3, pkix.Name{CommonName: "grafana"}, &ca)
+	bob := clientCertificate(4, pkix.Name{CommonName: "bob"}, &ca)
+	mallory := clientCertificate(5, pkix.Name{CommonName: "mallory"}, &ca)
+	testCases := []struct {
+		name      string
+		cert      *tls.Certificate
+		path      string
+		basicAuth []string
+		expCode   int
+		expBody   string
+	}{
+		{
+			name:    "no certificate",
+			path:    "/kapacitor/v1/whoami",
+			expCode: http.StatusUnauthorized,
+			expBody: "client certificate required",
+		},
+		{
+			name:    "mapped distinguished name",
+			cert:    &deploy,
+			path:    "/kapacitor/v1/whoami",
+			expCode: http.StatusOK,
+			expBody: "deploy",
+		},
+		{
+			name:    "mapped common name",
+			cert:    &grafana,
+			path:    "/kapacitor/v1/whoami",
+			expCode: http.StatusOK,
+			expBody: "grafana",
+		},
+		{
+			name:    "known user without roles",
+			cert:    &bob,
+			path:    "/kapacitor/v1/whoami",
+			expCode: http.StatusForbidden,
+			expBody: "user bob does not have the",
+		},
+		{
+			name:    "unknown subject",
+			cert:    &mallory,
+			path:    "/kapacitor/v1/whoami",
+			expCode: http.StatusUnauthorized,
+			expBody: "not mapped to any roles",
+		},
+		{
+			name:      "subscription without certificate",
+			path:      "/write",
+			basicAuth: []string{httpd.SubscriptionUser, "token"},
+			expCode:   http.StatusBadRequest,
+			expBody:   "database is required",
+		},
+	}
+	for _, tc := range testCases {
+		tlsConfig := &tls.Config{InsecureSkipVerify: true}
+		if tc.cert != nil {
+			tlsConfig.Certificates = []tls.Certificate{*tc.cert}
+		}
+		cli := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		method := "GET"
+		if tc.path == "/write" {
+			method = "POST"
+		}
+		req, err := http.NewRequest(method, base+tc.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.basicAuth != nil {
+			req.SetBasicAuth(tc.basicAuth[0], tc.basicAuth[1])
+		}
+		resp, err := cli.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.expCode {
+			t.Errorf("%s: unexpected status code: got %d exp %d: %s", tc.name, resp.StatusCode, tc.expCode, body)
+		}
+		if !strings.Contains(string(body), tc.expBody) {
+			t.Errorf("%s: unexpected body: got %q exp %q", tc.name, body, tc.expBody)
+		}
+	}
+
+	// Certificates which are not signed by the client CA are rejected during the handshake.
+	other := newCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "other"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	forged := clientCertificate(6, pkix.Name{CommonName: "deploy", Organization: []string{"Example"}}, &other)
+	cli := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		InsecureSkipVerify: true,
+		// Send the certificate even though the server does not accept its CA.
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &forged, nil
+		},
+	}}}
+	if resp, err := cli.Get(base + "/kapacitor/v1/whoami"); err == nil {
+		resp.Body.Close()
+		t.Error("expected handshake error with a certificate of another CA")
+	}
+}
+
+// adminAuthService also knows the admin user and the operator user with all privileges for the API.
+type adminAuthService struct {
+	authService
+}
+
+func (a adminAuthService) User(username string) (auth.User, error) {
+	switch username {
+	case "admin":
+		return auth.NewUser(username, nil, true, nil), nil
+	case "operator":
+		return auth.NewUser(username, nil, false, map[string][]auth.Privilege{
+			auth.APIResource("/"): {auth.AllPrivileges},
+		}), nil
+	}
+	return a.authService.User(username)
+}
+
+// tokenVerifier verifies the tokens it knows.
+type tokenVerifier map[string]struct {
+	subject, localUser string
+	scopes             []auth.Scope
+}
+
+func (v tokenVerifier) VerifyToken(token string) (string, string, []auth.Scope, error) {
+	t, ok := v[token]
+	if !ok {
+		return "", "", nil, errors.New("unknown token")
+	}
+	return t.subject, t.localUser, t.scopes, nil
+}
+
+func TestService_TokenVerifier(t *testing.T) {
+	c := httpd.NewConfig()
+	c.BindAddress = "127.0.0.1:0"
+	c.AuthEnabled = true
+	c.SharedSecret = "secret"
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	ds := diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	ds.Open()
+	s := httpd.NewService(c, "localhost", ds.NewHTTPDHandler())
+	s.Handler.AuthService = adminAuthService{}
+	s.Handler.TokenVerifier = tokenVerifier{
+		"named-admin":  {subject: "admin"},
+		"scoped-admin": {subject: "admin", scopes: []auth.Scope{auth.TasksReadScope}},
+		"mapped-admin": {subject: "1234", localUser: "admin"},
+		"mapped-bob":   {subject: "5678", localUser: "bob", scopes: []auth.Scope{auth.TasksReadScope}},
+		"mapped-other": {subject: "9012", localUser: "mallory"},
+	}
+	if err := s.Handler.AddRoute(httpd.Route{
+		Method:  "GET",
+		Pattern: "/whoami",
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request, user auth.User) {
+			w.Write([]byte(user.Name()))
+		},
+		Scope: auth.TasksReadScope,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	base := "http://" + s.Addr().String()
+
+	testCases := []struct {
+		name    string
+		token   string
+		method  string
+		path    string
+		expCode int
+		expBody string
+	}{
+		{
+			name:    "unmapped subject named like the admin user",
+			token:   "named-admin",
+			method:  "GET",
+			path:    "/kapacitor/v1/whoami",
+			expCode: http.StatusForbidden,
+			expBody: "user admin does not have the",
+		},
+		{
+			name:    "unmapped subject named like the admin user with scope",
+			token:   "scoped-admin",
+			method:  "GET",
+			path:    "/kapacitor/v1/whoami",
+			expCode: http.StatusOK,
+			expBody: "admin",
+		},
+		{
+			name:    "unmapped subject named like the admin user changing the log level",
+			token:   "scoped-admin",
+			method:  "POST",
+			path:    "/kapacitor/v1/loglevel",
+			expCode: http.StatusForbidden,
+			expBody: "user admin does not have",
+		},
+		{
+			name:    "subject mapped to the admin user",
+			token:   "mapped-admin",
+			method:  "GET",
+			path:    "/kapacitor/v1/whoami",
+			expCode: http.StatusOK,
+			expBody: "admin",
+		},
+		{
+			name:    "subject mapped to a user",
+			token:   "mapped-bob",
+			method:  "GET",
+			path:    "/kapacitor/v1/whoami",
+			expCode: http.StatusOK,
+			expBody: "bob",
+		},
+		{
+			name:    "subject mapped to an unknown user",
+			token:   "mapped-other",
+			method:  "GET",
+			path:    "/kapacitor/v1/whoami",
+			expCode: http.StatusUnauthorized,
+			expBody: "unknown user",
+		},
+		{
+			name:    "invalid token",
+			token:   "other",
+			method:  "GET",
+			path:    "/kapacitor/v1/whoami",
+			expCode: http.StatusUnauthorized,
+			expBody: "invalid token",
+		},
+	}
+	for _, tc := range testCases {
+		req, err := http.NewRequest(tc.method, base+tc.path, strings.NewReader(`{"level":"debug"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.expCode {
+			t.Errorf("%s: unexpected status code: got %d exp %d: %s", tc.name, resp.StatusCode, tc.expCode, body)
+		}
+		if !strings.Contains(string(body), tc.expBody) {
+			t.Errorf("%s: unexpected body: got %q exp %q", tc.name, body, tc.expBody)
+		}
+	}
+}
+
+func TestService_AdminOnlyRoute(t *testing.T) {
+	c := httpd.NewConfig()
+	c.BindAddress = "127.0.0.1:0"
+	c.AuthEnabled = true
+	c.SharedSecret = "secret"
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	ds := diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	ds.Open()
+	s := httpd.NewService(c, "localhost", ds.NewHTTPDHandler())
+	s.Handler.AuthService = adminAuthService{}
+	s.Handler.TokenVerifier = tokenVerifier{
+		"admin":    {subject: "1234", localUser: "admin"},
+		"operator": {subject: "5678", localUser: "operator"},
+		"scoped":   {subject: "9012", scopes: []auth.Scope{auth.TasksReadScope}},
+	}
+	if err := s.Handler.AddRoute(httpd.Route{
+		Method:  "GET",
+		Pattern: "/secrets",
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("secrets"))
+		},
+		Scope:     auth.TasksReadScope,
+		AdminOnly: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	base := "http://" + s.Addr().String()
+
+	testCases := []struct {
+		token   string
+		expCode int
+		expBody string
+	}{
+		{
+			token:   "admin",
+			expCode: http.StatusOK,
+			expBody: "secrets",
+		},
+		{
+			token:   "operator",
+			expCode: http.StatusForbidden,
+			expBody: "user operator is not an admin",
+		},
+		{
+			token:   "scoped",
+			expCode: http.StatusForbidden,
+			expBody: "user 9012 is not an admin",
+		},
+	}
+	for _, tc := range testCases {
+		req, err := http.NewRequest("GET", base+"/kapacitor/v1/secrets", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.token, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.expCode {
+			t.Errorf("%s: unexpected status code: got %d exp %d: %s", tc.token, resp.StatusCode, tc.expCode, body)
+		}
+		if !strings.Contains(string(body), tc.expBody) {
+			t.Errorf("%s: unexpected body: got %q exp %q", tc.token, body, tc.expBody)
+		}
+	}
+}
+
+func TestService_AccessControl(t *testing.T) {
+	c := httpd.NewConfig()
+	c.BindAddress = "127.0.0.1:0"
+	c.DeniedNetworks = []string{"10.0.0.0/8"}
+	c.RouteGroups = []httpd.RouteGroupConfig{
+		{
+			Name:            "admin",
+			Paths:           []string{"/kapacitor/v1/loglevel"},
+			AllowedNetworks: []string{"192.168.0.1"},
+		},
+		{
+			Name:    "ping",
+			Paths:   []string{"/kapacitor/v1/ping"},
+			Methods: []string{"get"},
+			Rate:    1,
+			Burst:   2,
+		},
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	ds := diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	ds.Open()
+	s := httpd.NewService(c, "localhost", ds.NewHTTPDHandler())
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	base := "http://" + s.Addr().String()
+
+	testCases := []struct {
+		name    string
+		method  string
+		path    string
+		expCode int
+	}{
+		{
+			name:    "not allowed",
+			method:  "POST",
+			path:    "/kapacitor/v1/loglevel",
+			expCode: http.StatusForbidden,
+		},
+		{
+			name:    "not allowed preview",
+			method:  "POST",
+			path:    "/kapacitor/v1preview/loglevel",
+			expCode: http.StatusForbidden,
+		},
+		{
+			name:    "burst 1",
+			method:  "GET",
+			path:    "/kapacitor/v1/ping",
+			expCode: http.StatusNoContent,
+		},
+		{
+			name:    "burst 2",
+			method:  "GET",
+			path:    "/kapacitor/v1/ping",
+			expCode: http.StatusNoContent,
+		},
+		{
+			name:    "rate limited",
+			method:  "GET",
+			path:    "/kapacitor/v1/ping",
+			expCode: http.StatusTooManyRequests,
+		},
+		{
+			name:    "other method",
+			method:  "HEAD",
+			path:    "/kapacitor/v1/ping",
+			expCode: http.StatusNoContent,
+		},
+	}
+	for _, tc := range testCases {
+		req, err := http.NewRequest(tc.method, base+tc.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.expCode {
+			t.Errorf("%s: unexpected status code: got %d exp %d", tc.name, resp.StatusCode, tc.expCode)
+		}
+		if tc.expCode == http.StatusTooManyRequests && resp.Header.Get("Retry-After") != "1" {
+			t.Errorf("%s: unexpected Retry-After header: %q", tc.name, resp.Header.Get("Retry-After"))
+		}
+	}
+}
+
+type backpressureError struct{}
+
+func (backpressureError) Error() string             { return "queues are full" }
+func (backpressureError) RetryAfter() time.Duration { return 1500 * time.Millisecond }
+
+type backpressurePointsWriter struct{}
+
+func (backpressurePointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	return backpressureError{}
+}
+
+func TestService_WriteBackpressure(t *testing.T) {
+	c := httpd.NewConfig()
+	c.BindAddress = "127.0.0.1:0"
+	ds := diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	ds.Open()
+	s := httpd.NewService(c, "localhost", ds.NewHTTPDHandler())
+	s.Handler.PointsWriter = backpressurePointsWriter{}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	resp, err := http.Post("http://"+s.Addr().String()+"/kapacitor/v1/write?db=mydb", "text/plain", strings.NewReader("cpu value=1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("unexpected status code: got %d exp %d", resp.StatusCode, http.StatusTooManyRequests)
+	}
+	if got, exp := resp.Header.Get("Retry-After"), "2"; got != exp {
+		t.Errorf("unexpected Retry-After header: got %q exp %q", got, exp)
+	}
+}
+
+type lineProtocolWriter struct {
+	backpressurePointsWriter
+	database string
+	names    []string
+}
+
+func (w *lineProtocolWriter) WriteLineProtocol(database, retentionPolicy string, points *lineprotocol.Points) error {
+	w.database = database
+	for i := 0; i < points.Len(); i++ {
+		w.names = append(w.names, points.String(points.Point(i).Name))
+	}
+	return nil
+}
+
+func TestService_WriteLineProtocol(t *testing.T) {
+	c := httpd.NewConfig()
+	c.BindAddress = "127.0.0.1:0"
+	ds := diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	ds.Open()
+	s := httpd.NewService(c, "localhost", ds.NewHTTPDHandler())
+	w := &lineProtocolWriter{}
+	s.Handler.PointsWriter = w
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	resp, err := http.Post("http://"+s.Addr().String()+"/kapacitor/v1/write?db=mydb", "text/plain", strings.NewReader("cpu value=1\nmem value=2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("unexpected status code: got %d exp %d", resp.StatusCode, http.StatusNoContent)
+	}
+	if w.database != "mydb" || !reflect.DeepEqual(w.names, []string{"cpu", "mem"}) {
+		t.Errorf("unexpected write to %q of %v", w.database, w.names)
+	}
+
+	resp, err = http.Post("http://"+s.Addr().String()+"/kapacitor/v1/write?db=mydb", "text/plain", strings.NewReader("cpu value="))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected status code for invalid point: got %d exp %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestService_Rebind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpd-rebind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certPath := filepath.Join(dir, "cert.pem")
+	writeServerCertificate(t, certPath, 1)
+
+	c := httpd.NewConfig()
+	c.BindAddress = "127.0.0.1:0"
+	c.HttpsEnabled = true
+	c.HttpsCertificate = certPath
+	ds := diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	ds.Open()
+	s := httpd.NewService(c, "localhost", ds.NewHTTPDHandler())
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	oldAddr := s.Addr().String()
+
+	// The service keeps its listener if the address is in use.
+	if err := s.Rebind(oldAddr); err == nil {
+		t.Fatal("expected error rebinding to an address in use")
+	}
+	if got := serial(t, oldAddr); got != 1 {
+		t.Errorf("unexpected serial number: got %d exp 1", got)
+	}
+
+	if err := s.Rebind("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	newAddr := s.Addr().String()
+	if newAddr == oldAddr {
+		t.Fatalf("listener not moved from %s", oldAddr)
+	}
+	if got := serial(t, newAddr); got != 1 {
+		t.Errorf("unexpected serial number: got %d exp 1", got)
+	}
+	if conn, err := tls.Dial("tcp", oldAddr, &tls.Config{InsecureSkipVerify: true}); err == nil {
+		conn.Close()
+		t.Errorf("expected the previous listener %s to be closed", oldAddr)
+	}
+	select {
+	case err := <-s.Err():
+		t.Errorf("unexpected error of the replaced listener: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
@@ -0,0 +1,139 @@
+package httpd
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+// Number of messages buffered per WebSocket client.
+// Clients that fall further behind are disconnected.
+const webSocketBufferSize = 100
+
+// IsWebSocket reports whether the request asks to upgrade the connection to a WebSocket.
+func IsWebSocket(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// Broadcaster pushes JSON messages to connected WebSocket clients.
+type Broadcaster struct {
+	mu      sync.Mutex
+	clients map[*webSocketClient]struct{}
+	closed  bool
+}
+
+type webSocketClient struct {
+	send chan []byte
+	// done is closed once the client is removed.
+	done chan struct{}
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		clients: make(map[*webSocketClient]struct{}),
+	}
+}
+
+// Serve upgrades the request to a WebSocket and sends the initial message, if any,
+// followed by each broadcast message until the client disconnects or the broadcaster is closed.
+// The initial function is called once the client is registered, so no message is missed.
+func (b *Broadcaster) Serve(w http.ResponseWriter, r *http.Request, initial func() interface{}) {
+	s := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			c := &webSocketClient{
+				send: make(chan []byte, webSocketBufferSize),
+				done: make(chan struct{}),
+			}
+			if !b.add(c) {
+				return
+			}
+			defer b.remove(c)
+
+			// Drain the incoming frames so that a closed connection is noticed.
+			go func() {
+				io.Copy(ioutil.Discard, ws)
+				b.remove(c)
+			}()
+
+			if initial != nil {
+				if v := initial(); v != nil {
+					data, err := json.Marshal(v)
+					if err != nil || websocket.Message.Send(ws, string(data)) != nil {
+						return
+					}
+				}
+			}
+			for {
+				select {
+				case data := <-c.send:
+					if err := websocket.Message.Send(ws, string(data)); err != nil {
+						return
+					}
+				case <-c.done:
+					return
+				}
+			}
+		},
+	}
+	s.ServeHTTP(w, r)
+}
+
+// Broadcast sends the value encoded as JSON to all connected clients.
+// It never blocks, clients whose buffer is full are disconnected.
+func (b *Broadcaster) Broadcast(v interface{}) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.clients) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	for c := range b.clients {
+		select {
+		case c.send <- data:
+		default:
+			b.removeLocked(c)
+		}
+	}
+	return nil
+}
+
+// Close disconnects all clients and rejects new ones.
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for c := range b.clients {
+		b.removeLocked(c)
+	}
+}
+
+func (b *Broadcaster) add(c *webSocketClient) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return false
+	}
+	b.clients[c] = struct{}{}
+	return true
+}
+
+func (b *Broadcaster) remove(c *webSocketClient) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removeLocked(c)
+}
+
+func (b *Broadcaster) removeLocked(c *webSocketClient) {
+	if _, ok := b.clients[c]; ok {
+		delete(b.clients, c)
+		close(c.done)
+	}
+}
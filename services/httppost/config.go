@@ -8,6 +8,7 @@ import (
 	"path"
 	"text/template"
 
+	"github.com/influxdata/kapacitor/alert"
 	"github.com/pkg/errors"
 )
 
@@ -89,15 +90,21 @@ func (c Config) getRowTemplate() (*template.Template, error) {
 	return getTemplate(c.RowTemplate, c.RowTemplateFile)
 }
 
+// Cached body templates, shared by the endpoints
+var (
+	bodyTemplates = alert.NewTextTemplates("body", template.FuncMap{
+		"json": func(v interface{}) string {
+			buf := bytes.Buffer{}
+			_ = json.NewEncoder(&buf).Encode(v)
+			return buf.String()
+		},
+	})
+	fileTemplates = alert.NewTextTemplates("body", nil)
+)
+
 func getTemplate(tmpl, tpath string) (*template.Template, error) {
 	if tmpl != "" {
-		t, err := template.New("body").Funcs(template.FuncMap{
-			"json": func(v interface{}) string {
-				buf := bytes.Buffer{}
-				_ = json.NewEncoder(&buf).Encode(v)
-				return buf.String()
-			},
-		}).Parse(tmpl)
+		t, err := bodyTemplates.Parse(tmpl)
 		return t, errors.Wrap(err, "failed to parse template")
 	}
 	if tpath != "" {
@@ -106,7 +113,7 @@ func getTemplate(tmpl, tpath string) (*template.Template, error) {
 			return nil, errors.Wrapf(err, "failed to read template file %q", tpath)
 		}
 
-		t, err := template.New("body").Parse(string(data))
+		t, err := fileTemplates.Parse(string(data))
 		return t, errors.Wrapf(err, "failed to parse template from file %q", tpath)
 	}
 	return nil, nil
@@ -16,6 +16,7 @@ import (
 	"context"
 	"github.com/influxdata/kapacitor/alert"
 	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/tracing"
 	"github.com/pkg/errors"
 )
 
@@ -323,13 +324,22 @@ func (h *handler) Handle(event alert.Event) {
 		req = req.WithContext(ctx)
 	}
 
-	// Execute the request
+	// Execute the request, in a span of the trace of the event if it is sampled.
+	span := tracing.Start("POST", tracing.KindClient, event.Trace)
+	defer span.Finish()
+	if span != nil {
+		span.SetAttribute("http.method", "POST")
+		span.SetAttribute("server.address", req.URL.Host)
+		req.Header.Set("traceparent", span.Context.Traceparent())
+	}
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
+		span.SetError(err)
 		h.diag.Error("failed to POST alert data", err)
 		return
 	}
 	defer resp.Body.Close()
+	span.SetAttribute("http.status_code", strconv.Itoa(resp.StatusCode))
 
 	if resp.StatusCode/100 != 2 {
 		var err error
@@ -343,6 +353,7 @@ func (h *handler) Handle(event alert.Event) {
 		} else {
 			err = errors.New("unknown error, use .captureResponse() to capture the HTTP response")
 		}
+		span.SetError(err)
 		h.diag.Error("POST returned non 2xx status code", err, keyvalue.KV("code", strconv.Itoa(resp.StatusCode)))
 	}
 
@@ -3,7 +3,9 @@ package influxdb
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
+	"path"
 	"regexp"
 	"time"
 
@@ -16,6 +18,7 @@ const (
 	// Maximum time to try and connect to InfluxDB during startup.
 	DefaultStartUpTimeout           = 5 * time.Minute
 	DefaultSubscriptionSyncInterval = 1 * time.Minute
+	DefaultHealthCheckInterval      = 10 * time.Second
 
 	DefaultSubscriptionProtocol = "http"
 )
@@ -58,6 +61,20 @@ type Config struct {
 	UDPReadBuffer            int                 `toml:"udp-read-buffer" override:"udp-read-buffer"`
 	StartUpTimeout           toml.Duration       `toml:"startup-timeout" override:"startup-timeout"`
 	SubscriptionSyncInterval toml.Duration       `toml:"subscriptions-sync-interval" override:"subscriptions-sync-interval"`
+	// Interval of the health checks of the URLs, the requests fail over to the healthy URLs.
+	HealthCheckInterval toml.Duration `toml:"health-check-interval" override:"health-check-interval"`
+	// IPs or CIDR ranges the UDP subscriptions accept packets from, any source if empty.
+	UDPAllowedSources []string `toml:"udp-allowed-sources" override:"udp-allowed-sources"`
+	// Address of a dedicated listener for the HTTP subscriptions, which always authenticates their writes
+	// with the tokens of the subscriptions. The subscriptions write to the HTTP API if empty.
+	SubscriptionHTTPBind string `toml:"subscription-http-bind" override:"subscription-http-bind"`
+	// Certificate and key of the dedicated listener, for https subscriptions.
+	SubscriptionSSLCert string `toml:"subscription-ssl-cert" override:"subscription-ssl-cert"`
+	SubscriptionSSLKey  string `toml:"subscription-ssl-key" override:"subscription-ssl-key"`
+	// Glob patterns of the measurements whose points are kept from the subscriptions, all measurements if empty.
+	SubscriptionMeasurements []string `toml:"subscription-measurements" override:"subscription-measurements"`
+	// Glob patterns of the measurements whose points are dropped from the subscriptions.
+	ExcludedSubscriptionMeasurements []string `toml:"excluded-subscription-measurements" override:"excluded-subscription-measurements"`
 }
 
 func NewConfig() Config {
@@ -77,6 +94,7 @@ func (c *Config) Init() {
 	c.StartUpTimeout = toml.Duration(DefaultStartUpTimeout)
 	c.SubscriptionProtocol = DefaultSubscriptionProtocol
 	c.SubscriptionSyncInterval = toml.Duration(DefaultSubscriptionSyncInterval)
+	c.HealthCheckInterval = toml.Duration(DefaultHealthCheckInterval)
 	c.SubscriptionMode = ClusterMode
 }
 
@@ -93,6 +111,9 @@ func (c *Config) ApplyConditionalDefaults() {
 	if c.SubscriptionSyncInterval == toml.Duration(0) {
 		c.SubscriptionSyncInterval = toml.Duration(DefaultSubscriptionSyncInterval)
 	}
+	if c.HealthCheckInterval == toml.Duration(0) {
+		c.HealthCheckInterval = toml.Duration(DefaultHealthCheckInterval)
+	}
 }
 
 var validNamePattern = regexp.MustCompile(`^[-\._\p{L}0-9]+$`)
@@ -122,6 +143,51 @@ func (c Config) Validate() error {
 	default:
 		return fmt.Errorf("invalid subscription protocol, must be one of 'udp', 'http' or 'https', got %q: %v", c.SubscriptionProtocol, c)
 	}
+	if (c.SubscriptionSSLCert == "") != (c.SubscriptionSSLKey == "") {
+		return errors.New("subscription-ssl-cert and subscription-ssl-key must be set together")
+	}
+	if c.SubscriptionSSLCert != "" && c.SubscriptionHTTPBind == "" {
+		return errors.New("subscription-ssl-cert requires subscription-http-bind")
+	}
+	if c.SubscriptionHTTPBind != "" {
+		if _, _, err := net.SplitHostPort(c.SubscriptionHTTPBind); err != nil {
+			return fmt.Errorf("invalid subscription-http-bind: %v", err)
+		}
+		switch {
+		case c.SubscriptionProtocol == "https" && c.SubscriptionSSLCert == "":
+			return errors.New("https subscriptions on subscription-http-bind require subscription-ssl-cert and subscription-ssl-key")
+		case c.SubscriptionProtocol == "http" && c.SubscriptionSSLCert != "":
+			return errors.New("subscription-protocol must be https with subscription-ssl-cert")
+		}
+	}
+	for _, subs := range []map[string][]string{c.Subscriptions, c.ExcludedSubscriptions} {
+		for db, rps := range subs {
+			if err := validatePatterns(append([]string{db}, rps...)); err != nil {
+				return fmt.Errorf("invalid subscription: %v", err)
+			}
+		}
+	}
+	if err := validatePatterns(append(c.SubscriptionMeasurements, c.ExcludedSubscriptionMeasurements...)); err != nil {
+		return fmt.Errorf("invalid subscription measurement: %v", err)
+	}
+	if len(c.SubscriptionMeasurements)+len(c.ExcludedSubscriptionMeasurements) > 0 &&
+		c.SubscriptionProtocol != "udp" && c.SubscriptionHTTPBind == "" {
+		// The points written to the HTTP API are not known to come from the subscriptions.
+		return errors.New("subscription measurements require subscription-protocol 'udp' or subscription-http-bind")
+	}
+	if _, err := udp.ParseAllowedSources(c.UDPAllowedSources); err != nil {
+		return fmt.Errorf("invalid udp-allowed-sources: %v", err)
+	}
+	return nil
+}
+
+// validatePatterns returns an error if a glob pattern is malformed.
+func validatePatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("%q: %v", p, err)
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,86 @@
+package influxdb
+
+import (
+	"path"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// matchSub reports whether the subscription is one of the subscriptions,
+// whose databases and retention policies may be glob patterns.
+func matchSub(subs map[subEntry]bool, se subEntry) bool {
+	if subs[se] {
+		return true
+	}
+	for p := range subs {
+		if p.name != se.name {
+			continue
+		}
+		if dbMatch, _ := path.Match(p.db, se.db); !dbMatch {
+			continue
+		}
+		if rpMatch, _ := path.Match(p.rp, se.rp); rpMatch {
+			return true
+		}
+	}
+	return false
+}
+
+// measurementFilter selects the points of the subscriptions by the glob patterns of their measurements.
+type measurementFilter struct {
+	// Patterns of the measurements to keep, all measurements if empty.
+	include []string
+	// Patterns of the measurements to drop.
+	exclude []string
+}
+
+func (f measurementFilter) empty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+func (f measurementFilter) match(measurement string) bool {
+	for _, p := range f.exclude {
+		if matched, _ := path.Match(p, measurement); matched {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		if matched, _ := path.Match(p, measurement); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the points whose measurements match.
+func (f measurementFilter) filter(points []models.Point) []models.Point {
+	if f.empty() {
+		return points
+	}
+	filtered := make([]models.Point, 0, len(points))
+	for _, p := range points {
+		if f.match(string(p.Name())) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// filteringWriter writes the points of the subscriptions of a cluster that match its measurement filter.
+type filteringWriter struct {
+	c *influxdbCluster
+}
+
+func (w filteringWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	w.c.filterMu.RLock()
+	f := w.c.measurementFilter
+	w.c.filterMu.RUnlock()
+	points = f.filter(points)
+	if len(points) == 0 {
+		return nil
+	}
+	return w.c.PointsWriter.WritePoints(database, retentionPolicy, consistencyLevel, points)
+}
@@ -13,6 +13,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,6 +22,8 @@ import (
 	"github.com/cenkalti/backoff"
 	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/auth"
+	"github.com/influxdata/kapacitor/client/v1"
 	"github.com/influxdata/kapacitor/influxdb"
 	"github.com/influxdata/kapacitor/keyvalue"
 	"github.com/influxdata/kapacitor/server/vars"
@@ -42,6 +45,7 @@ const (
 	// API endpoint paths
 	subscriptionsPath         = "/subscriptions"
 	subscriptionsPathAnchored = "/subscriptions/"
+	statusPath                = "/influxdb"
 )
 
 // IDer returns the current IDs of the cluster and server.
@@ -61,6 +65,9 @@ type Diagnostic interface {
 	UnlinkingSubscriptions(cluster string)
 	LinkingSubscriptions(cluster string)
 	StartedUDPListener(db string, rp string)
+	StartedSubscriptionListener(addr, protocol string)
+	URLHealthy(url string)
+	URLUnhealthy(url string, err error)
 }
 
 // Handles requests to write or read from an InfluxDB cluster
@@ -95,9 +102,9 @@ type Service struct {
 		Create(influxdb.Config) (influxdb.ClientUpdater, error)
 	}
 	AuthService interface {
-		GrantSubscriptionAccess(token, db, rp string) error
-		ListSubscriptionTokens() ([]string, error)
-		RevokeSubscriptionAccess(token string) error
+		GrantSubscriptionAccess(cluster, token, db, rp string) error
+		ListSubscriptionTokens() ([]auth.SubscriptionToken, error)
+		RevokeSubscriptionAccess(hash string) error
 	}
 	RandReader io.Reader
 	diag       Diagnostic
@@ -140,6 +147,11 @@ func (s *Service) Open() error {
 			Pattern:     subscriptionsPath,
 			HandlerFunc: s.handleSubscriptions,
 		},
+		{
+			Method:      "GET",
+			Pattern:     statusPath,
+			HandlerFunc: s.handleStatus,
+		},
 	}
 
 	if err := s.HTTPDService.AddRoutes(s.routes); err != nil {
@@ -326,6 +338,37 @@ func (s *Service) Test(options interface{}) error {
 	return nil
 }
 
+// Ping pings each cluster, it returns the versions of the clusters that responded and the errors of the others.
+func (s *Service) Ping(ctx context.Context) (map[string]string, map[string]error) {
+	s.mu.RLock()
+	clients := make(map[string]influxdb.Client, len(s.clusters))
+	for name, cluster := range s.clusters {
+		clients[name] = cluster.NewClient()
+	}
+	s.mu.RUnlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	versions := make(map[string]string)
+	errs := make(map[string]error)
+	for name, cli := range clients {
+		wg.Add(1)
+		go func(name string, cli influxdb.Client) {
+			defer wg.Done()
+			_, version, err := cli.Ping(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[name] = err
+			} else {
+				versions[name] = version
+			}
+		}(name, cli)
+	}
+	wg.Wait()
+	return versions, errs
+}
+
 // Refresh the subscriptions linking for all clusters.
 func (s *Service) handleSubscriptions(w http.ResponseWriter, r *http.Request) {
 	err := s.LinkSubscriptions()
@@ -336,6 +379,41 @@ func (s *Service) handleSubscriptions(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Status returns the status of the connections to the clusters.
+func (s *Service) Status() client.InfluxDBStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status := client.InfluxDBStatus{
+		Link:     client.Link{Relation: client.Self, Href: httpd.BasePath + statusPath},
+		Clusters: make(map[string]client.InfluxDBClusterStatus, len(s.clusters)),
+	}
+	for name, cluster := range s.clusters {
+		cs := client.InfluxDBClusterStatus{
+			Default: name == s.defaultInfluxDB,
+			URLs:    []client.InfluxDBURLStatus{},
+		}
+		for _, us := range cluster.Status() {
+			u := client.InfluxDBURLStatus{
+				URL:       us.URL,
+				Healthy:   us.Healthy,
+				LastCheck: us.LastCheck,
+				Version:   us.Version,
+			}
+			if us.Err != nil {
+				u.Error = us.Err.Error()
+			}
+			cs.Healthy = cs.Healthy || us.Healthy
+			cs.URLs = append(cs.URLs, u)
+		}
+		status.Clusters[name] = cs
+	}
+	return status
+}
+
+func (s *Service) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Write(httpd.MarshalJSON(s.Status(), true))
+}
+
 // Trigger a LinkSubscriptions event for all clusters
 func (s *Service) LinkSubscriptions() error {
 	for clusterName, cluster := range s.clusters {
@@ -356,13 +434,9 @@ func (s *Service) revokeClusterTokens() error {
 	}
 	// Check all tokens against configured clusters
 	for _, token := range tokens {
-		clusterName, _, err := splitToken(token)
-		if err != nil {
-			// Revoke invalid token
-			s.AuthService.RevokeSubscriptionAccess(token)
-		} else if _, ok := s.clusters[clusterName]; !ok {
-			// Revoke token for old non existent or disabled cluster
-			s.AuthService.RevokeSubscriptionAccess(token)
+		if _, ok := s.clusters[token.Cluster]; !ok {
+			// Revoke token for old non existent or disabled cluster, or an invalid token without a cluster
+			s.AuthService.RevokeSubscriptionAccess(token.Hash)
 		}
 	}
 	return nil
@@ -397,12 +471,26 @@ type influxdbCluster struct {
 	udpBind                  string
 	udpBuffer                int
 	udpReadBuffer            int
+	udpAllowedSources        []string
 	startupTimeout           time.Duration
 	subscriptionSyncInterval time.Duration
 	subscriptionMode         SubscriptionMode
 	disableSubs              bool
 	runningSubs              map[subEntry]bool
 	useTokens                bool
+	authEnabled              bool
+
+	// The dedicated listener of the HTTP subscriptions, nil if they write to the HTTP API.
+	subHTTPBind string
+	subSSLCert  string
+	subSSLKey   string
+	subListener *subscriptionListener
+	// filterMu protects the filter of the measurements of the points of the subscriptions.
+	filterMu          sync.RWMutex
+	measurementFilter measurementFilter
+	// tokensMu protects the tokens of the subscriptions, verified by the dedicated listener.
+	tokensMu  sync.RWMutex
+	subTokens map[string]subEntry
 
 	// ider provides an interface for getting the IDs.
 	ider IDer
@@ -412,6 +500,14 @@ type influxdbCluster struct {
 	subSyncTicker *time.Ticker
 	services      map[subEntry]openCloser
 
+	healthCheckInterval time.Duration
+	// healthDone stops the health checks.
+	healthDone chan struct{}
+	// healthMu protects the health checker of the client and the health of the URLs.
+	healthMu      sync.Mutex
+	healthChecker healthChecker
+	healthy       map[string]bool
+
 	randReader io.Reader
 
 	PointsWriter interface {
@@ -421,9 +517,9 @@ type influxdbCluster struct {
 		Create(influxdb.Config) (influxdb.ClientUpdater, error)
 	}
 	AuthService interface {
-		GrantSubscriptionAccess(token, db, rp string) error
-		ListSubscriptionTokens() ([]string, error)
-		RevokeSubscriptionAccess(token string) error
+		GrantSubscriptionAccess(cluster, token, db, rp string) error
+		ListSubscriptionTokens() ([]auth.SubscriptionToken, error)
+		RevokeSubscriptionAccess(hash string) error
 	}
 	ctxMu     sync.Mutex
 	ctx       context.Context
@@ -476,13 +572,17 @@ func newInfluxDBCluster(c Config, hostname string, ider IDer, httpPort int, useT
 		influxdbConfig:           config,
 		configSubs:               subs,
 		exConfigSubs:             exSubs,
+		measurementFilter:        measurementFilter{include: c.SubscriptionMeasurements, exclude: c.ExcludedSubscriptionMeasurements},
 		hostname:                 host,
 		httpPort:                 port,
 		udpBind:                  c.UDPBind,
 		udpBuffer:                c.UDPBuffer,
 		udpReadBuffer:            c.UDPReadBuffer,
+		udpAllowedSources:        c.UDPAllowedSources,
 		startupTimeout:           time.Duration(c.StartUpTimeout),
 		subscriptionSyncInterval: time.Duration(c.SubscriptionSyncInterval),
+		healthCheckInterval:      time.Duration(c.HealthCheckInterval),
+		healthy:                  make(map[string]bool),
 		subscriptionMode:         c.SubscriptionMode,
 		ider:                     ider,
 		subName:                  subName,
@@ -490,12 +590,26 @@ func newInfluxDBCluster(c Config, hostname string, ider IDer, httpPort int, useT
 		protocol:                 c.SubscriptionProtocol,
 		runningSubs:              make(map[subEntry]bool, len(c.Subscriptions)),
 		services:                 make(map[subEntry]openCloser, len(c.Subscriptions)),
-		// Do not use tokens for non http protocols
-		useTokens: useTokens && (c.SubscriptionProtocol == "http" || c.SubscriptionProtocol == "https"),
-		diag:      d,
+		useTokens:                subscriptionTokens(useTokens, c),
+		authEnabled:              useTokens,
+		subHTTPBind:              c.SubscriptionHTTPBind,
+		subSSLCert:               c.SubscriptionSSLCert,
+		subSSLKey:                c.SubscriptionSSLKey,
+		subTokens:                make(map[string]subEntry),
+		diag:                     d,
 	}, nil
 }
 
+// subscriptionTokens reports whether the subscriptions authenticate with tokens,
+// either to the HTTP API with authentication enabled or to the dedicated listener.
+func subscriptionTokens(authEnabled bool, c Config) bool {
+	// Do not use tokens for non http protocols
+	if c.SubscriptionProtocol != "http" && c.SubscriptionProtocol != "https" {
+		return false
+	}
+	return authEnabled || c.SubscriptionHTTPBind != ""
+}
+
 func httpConfig(c Config) (influxdb.Config, error) {
 	tlsConfig, err := tlsconfig.Create(c.SSLCA, c.SSLCert, c.SSLKey, c.InsecureSkipVerify)
 	if err != nil {
@@ -550,7 +664,12 @@ func (c *influxdbCluster) Open() error {
 		c.client = cli
 	}
 
+	if err := c.openSubListener(); err != nil {
+		return err
+	}
+
 	c.watchSubs()
+	c.watchHealth()
 
 	if err := c.linkSubscriptions(ctx, c.subName); err != nil {
 		return errors.Wrap(err, "failed to link subscription on startup")
@@ -569,12 +688,61 @@ func (c *influxdbCluster) Close() error {
 	if c.subSyncTicker != nil {
 		c.subSyncTicker.Stop()
 	}
+	c.stopHealth()
+	c.closeSubListener()
 	if c.client != nil {
 		c.client.Close()
 	}
 	return c.closeServices()
 }
 
+// openSubListener opens the dedicated listener of the HTTP subscriptions, if it is configured.
+// Must have lock to call.
+func (c *influxdbCluster) openSubListener() error {
+	if c.subHTTPBind == "" {
+		return nil
+	}
+	l, err := newSubscriptionListener(c.subHTTPBind, c.subSSLCert, c.subSSLKey, c.verifySubscription, c.diag)
+	if err != nil {
+		return err
+	}
+	l.PointsWriter = filteringWriter{c: c}
+	if err := l.Open(); err != nil {
+		return err
+	}
+	c.subListener = l
+	return nil
+}
+
+// closeSubListener closes the dedicated listener of the HTTP subscriptions.
+// Must have lock to call.
+func (c *influxdbCluster) closeSubListener() {
+	if c.subListener == nil {
+		return
+	}
+	if err := c.subListener.Close(); err != nil {
+		c.diag.Error("failed to close subscription listener", err)
+	}
+	c.subListener = nil
+}
+
+// subscriptionPort returns the port the HTTP subscriptions write to.
+// Must have lock to call.
+func (c *influxdbCluster) subscriptionPort() int {
+	if c.subListener != nil {
+		return c.subListener.Port()
+	}
+	return c.httpPort
+}
+
+// verifySubscription reports whether the token was granted to the subscription of the database and retention policy.
+func (c *influxdbCluster) verifySubscription(token, db, rp string) bool {
+	c.tokensMu.RLock()
+	defer c.tokensMu.RUnlock()
+	se, ok := c.subTokens[token]
+	return ok && se.db == db && se.rp == rp
+}
+
 // closeServices closes all running services.
 // Must have lock to call.
 func (c *influxdbCluster) closeServices() error {
@@ -649,6 +817,23 @@ func (c *influxdbCluster) Update(conf Config) error {
 		// UDP read buffer changed
 		resetServices()
 	}
+	if !reflect.DeepEqual(c.udpAllowedSources, conf.UDPAllowedSources) {
+		c.udpAllowedSources = conf.UDPAllowedSources
+		// UDP allowed sources changed
+		resetServices()
+	}
+	if c.subHTTPBind != conf.SubscriptionHTTPBind || c.subSSLCert != conf.SubscriptionSSLCert || c.subSSLKey != conf.SubscriptionSSLKey {
+		c.subHTTPBind = conf.SubscriptionHTTPBind
+		c.subSSLCert = conf.SubscriptionSSLCert
+		c.subSSLKey = conf.SubscriptionSSLKey
+		// The subscriptions are recreated with the port of the new listener during linking.
+		c.closeSubListener()
+		if c.opened {
+			if err := c.openSubListener(); err != nil {
+				return err
+			}
+		}
+	}
 
 	// If the cluster is open and either the subscription name changed or the subscriptions are now disabled,
 	// we need to unlink existing subscriptions.
@@ -675,9 +860,17 @@ func (c *influxdbCluster) Update(conf Config) error {
 		c.subscriptionSyncInterval = i
 		c.watchSubs()
 	}
+	// Check if health check interval changed.
+	if i := time.Duration(conf.HealthCheckInterval); c.healthCheckInterval != i {
+		c.healthCheckInterval = i
+		if c.opened {
+			c.watchHealth()
+		}
+	}
 
 	c.startupTimeout = time.Duration(conf.StartUpTimeout)
 	c.protocol = conf.SubscriptionProtocol
+	c.useTokens = subscriptionTokens(c.authEnabled, conf)
 	c.influxdbConfig, err = httpConfig(conf)
 	if err != nil {
 		return err
@@ -690,6 +883,9 @@ func (c *influxdbCluster) Update(conf Config) error {
 	}
 	c.configSubs = subsFromConfig(c.subName, conf.Subscriptions)
 	c.exConfigSubs = subsFromConfig(c.subName, conf.ExcludedSubscriptions)
+	c.filterMu.Lock()
+	c.measurementFilter = measurementFilter{include: conf.SubscriptionMeasurements, exclude: conf.ExcludedSubscriptionMeasurements}
+	c.filterMu.Unlock()
 
 	// Run linkSubscriptions in the background as it can take a while
 	// because of validateClientWithBackoff.
@@ -729,6 +925,93 @@ func (c *influxdbCluster) watchSubs() {
 	}
 }
 
+// healthChecker is implemented by the clients checking the health of their URLs,
+// their requests fail over to the healthy URLs.
+type healthChecker interface {
+	CheckHealth(ctx context.Context) []influxdb.URLStatus
+	Status() []influxdb.URLStatus
+}
+
+// watchHealth sets up the goroutine checking the health of the URLs of the client.
+// The caller must have the lock.
+func (c *influxdbCluster) watchHealth() {
+	c.stopHealth()
+	hc, ok := c.client.(healthChecker)
+	if !ok || c.healthCheckInterval <= 0 {
+		return
+	}
+	c.healthMu.Lock()
+	c.healthChecker = hc
+	c.healthMu.Unlock()
+
+	done := make(chan struct{})
+	c.healthDone = done
+	interval := c.healthCheckInterval
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			c.checkHealth(hc, interval)
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// stopHealth stops the health checks.
+// The caller must have the lock.
+func (c *influxdbCluster) stopHealth() {
+	if c.healthDone != nil {
+		close(c.healthDone)
+		c.healthDone = nil
+	}
+}
+
+// checkHealth checks the health of the URLs, and links the subscriptions again once a URL recovers,
+// since the subscriptions may have been lost with the server.
+func (c *influxdbCluster) checkHealth(hc healthChecker, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	statuses := hc.CheckHealth(ctx)
+
+	recovered := false
+	c.healthMu.Lock()
+	for _, s := range statuses {
+		prev, checked := c.healthy[s.URL]
+		c.healthy[s.URL] = s.Healthy
+		if checked && prev == s.Healthy {
+			continue
+		}
+		if !s.Healthy {
+			c.diag.URLUnhealthy(s.URL, s.Err)
+		} else if checked {
+			c.diag.URLHealthy(s.URL)
+			recovered = true
+		}
+	}
+	c.healthMu.Unlock()
+
+	if recovered {
+		if err := c.LinkSubscriptions(); err != nil {
+			c.diag.Error("failed to link subscriptions", err)
+		}
+	}
+}
+
+// Status returns the status of the URLs of the client, if it checks their health.
+func (c *influxdbCluster) Status() []influxdb.URLStatus {
+	c.healthMu.Lock()
+	hc := c.healthChecker
+	c.healthMu.Unlock()
+	if hc == nil {
+		return nil
+	}
+	return hc.Status()
+}
+
 func (c *influxdbCluster) NewClient() influxdb.Client {
 	return c.client
 }
@@ -795,6 +1078,13 @@ func (c *influxdbCluster) unlinkSubscriptions(subName string) error {
 			}
 		}
 	}
+	c.tokensMu.Lock()
+	for token, se := range c.subTokens {
+		if se.name == subName {
+			delete(c.subTokens, token)
+		}
+	}
+	c.tokensMu.Unlock()
 	vars.NumSubscriptionsVar.Set(c.clusterName, 0)
 	return nil
 }
@@ -999,20 +1289,24 @@ func (c *influxdbCluster) linkSubscriptions(ctx context.Context, subName string)
 					if err != nil {
 						return errors.Wrap(err, "generating token")
 					}
-					err = c.AuthService.GrantSubscriptionAccess(token, se.db, se.rp)
+					err = c.AuthService.GrantSubscriptionAccess(c.clusterName, token, se.db, se.rp)
 					if err != nil {
 						return err
 					}
+					// Accept the writes of the subscription as soon as it is created.
+					c.tokensMu.Lock()
+					c.subTokens[token] = se
+					c.tokensMu.Unlock()
 					u := url.URL{
 						Scheme: c.protocol,
 						User:   url.UserPassword(httpd.SubscriptionUser, token),
-						Host:   fmt.Sprintf("%s:%d", c.hostname, c.httpPort),
+						Host:   fmt.Sprintf("%s:%d", c.hostname, c.subscriptionPort()),
 					}
 					destination = u.String()
 				} else {
 					u := url.URL{
 						Scheme: c.protocol,
-						Host:   fmt.Sprintf("%s:%d", c.hostname, c.httpPort),
+						Host:   fmt.Sprintf("%s:%d", c.hostname, c.subscriptionPort()),
 					}
 					destination = u.String()
 				}
@@ -1045,29 +1339,33 @@ func (c *influxdbCluster) linkSubscriptions(ctx context.Context, subName string)
 	if err != nil {
 		return errors.Wrap(err, "getting existing subscription tokens")
 	}
-	// populate set of existing tokens.
+	// populate set of the hashes of existing tokens.
 	existingTokens := make(map[string]bool, len(existingSubs))
+	subTokens := make(map[string]subEntry, len(existingSubs))
 	if c.useTokens {
-		for _, si := range existingSubs {
+		for se, si := range existingSubs {
 			u, err := url.Parse(si.Destinations[0])
 			if err != nil || u.User == nil {
 				continue
 			}
 			if t, ok := u.User.Password(); ok {
-				existingTokens[t] = true
+				existingTokens[auth.HashSubscriptionToken(t)] = true
+				subTokens[t] = se
 			}
 		}
 	}
+	c.tokensMu.Lock()
+	c.subTokens = subTokens
+	c.tokensMu.Unlock()
 	// Check all tokens against existing tokens
 	for _, token := range tokens {
-		clusterName, _, err := splitToken(token)
-		// Skip invalid token or token from another cluster
-		if err != nil || clusterName != c.clusterName {
+		// Skip token from another cluster
+		if token.Cluster != c.clusterName {
 			continue
 		}
 		// If the token is not part of the existing set we need to revoke
-		if !existingTokens[token] {
-			c.AuthService.RevokeSubscriptionAccess(token)
+		if !existingTokens[token.Hash] {
+			c.AuthService.RevokeSubscriptionAccess(token.Hash)
 		}
 	}
 
@@ -1092,7 +1390,7 @@ func (c *influxdbCluster) linkSubscriptions(ctx context.Context, subName string)
 }
 
 func (c *influxdbCluster) shouldSubExist(se subEntry) bool {
-	return (len(c.configSubs) == 0 || c.configSubs[se]) && !c.exConfigSubs[se]
+	return (len(c.configSubs) == 0 || matchSub(c.configSubs, se)) && !matchSub(c.exConfigSubs, se)
 }
 
 // Determine whether a subscription has differing values from the config.
@@ -1124,7 +1422,7 @@ func (c *influxdbCluster) changedOrInvalid(se subEntry, si subInfo) bool {
 		if err != nil {
 			return true
 		}
-		if int(pn) != c.httpPort {
+		if int(pn) != c.subscriptionPort() {
 			return true
 		}
 		// Further checks for the user token
@@ -1231,10 +1529,11 @@ func (c *influxdbCluster) startUDPListener(se subEntry, port string) (*net.UDPAd
 	conf.RetentionPolicy = se.rp
 	conf.Buffer = c.udpBuffer
 	conf.ReadBuffer = c.udpReadBuffer
+	conf.AllowedSources = c.udpAllowedSources
 
 	d := c.diag.WithUDPContext(se.db, se.rp)
 	service := udp.NewService(conf, d)
-	service.PointsWriter = c.PointsWriter
+	service.PointsWriter = filteringWriter{c: c}
 	err := service.Open()
 	if err != nil {
 		return nil, err
@@ -7,14 +7,22 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/auth"
+	"github.com/influxdata/kapacitor/client/v1"
 	influxcli "github.com/influxdata/kapacitor/influxdb"
 	"github.com/influxdata/kapacitor/services/diagnostic"
 	"github.com/influxdata/kapacitor/services/httpd"
@@ -954,18 +962,30 @@ func TestService_Open_LinkSubscriptions(t *testing.T) {
 				return nil, errors.New(msg)
 			}
 		}
-		as.ListSubscriptionTokensFunc = func() ([]string, error) {
-			ts := make([]string, len(tokens))
+		// The raw tokens by their hash.
+		hashes := make(map[string]string)
+		as.ListSubscriptionTokensFunc = func() ([]auth.SubscriptionToken, error) {
+			ts := make([]auth.SubscriptionToken, len(tokens))
 			for i, token := range tokens {
-				ts[i] = base64.RawURLEncoding.EncodeToString([]byte(token))
+				hash := auth.HashSubscriptionToken(base64.RawURLEncoding.EncodeToString([]byte(token)))
+				hashes[hash] = token
+				// An invalid token has no cluster.
+				cluster := ""
+				if i := strings.IndexRune(token, ';'); i > 0 {
+					cluster = token[:i]
+				}
+				ts[i] = auth.SubscriptionToken{Cluster: cluster, Hash: hash}
 			}
 			return ts, nil
 		}
-		as.GrantSubscriptionAccessFunc = func(token, db, rp string) error {
+		as.GrantSubscriptionAccessFunc = func(cluster, token, db, rp string) error {
 			raw, err := base64.RawURLEncoding.DecodeString(token)
 			if err != nil {
 				return err
 			}
+			if !strings.HasPrefix(string(raw), cluster+";") {
+				t.Errorf("token %q granted to the wrong cluster %q", string(raw), cluster)
+			}
 			log.Println("granted token:", string(raw), db, rp)
 			grantedTokens[tokenGrant{
 				token: string(raw),
@@ -974,13 +994,13 @@ func TestService_Open_LinkSubscriptions(t *testing.T) {
 			}] = true
 			return nil
 		}
-		as.RevokeSubscriptionAccessFunc = func(token string) error {
-			raw, err := base64.RawURLEncoding.DecodeString(token)
-			if err != nil {
-				return err
+		as.RevokeSubscriptionAccessFunc = func(hash string) error {
+			raw, ok := hashes[hash]
+			if !ok {
+				return fmt.Errorf("unknown token hash %q", hash)
 			}
-			log.Println("revoked token:", string(raw))
-			revokedTokens[string(raw)] = true
+			log.Println("revoked token:", raw)
+			revokedTokens[raw] = true
 			return nil
 		}
 
@@ -1050,6 +1070,371 @@ func TestService_Open_LinkSubscriptions(t *testing.T) {
 	}
 }
 
+func TestService_Status(t *testing.T) {
+	configs := NewDefaultTestConfigs(nil)
+	configs[0].HealthCheckInterval = toml.Duration(10 * time.Millisecond)
+	s, _, cs := NewTestService(configs, "localhost", false)
+
+	var mu sync.Mutex
+	healthy := false
+	showSubs := 0
+	checked := make(chan struct{}, 100)
+	cs.CreateFunc = func(config influxcli.Config) (influxcli.ClientUpdater, error) {
+		return healthClient{
+			influxDBClient: influxDBClient{
+				QueryFunc: func(clusterName string, q influxcli.Query) (*influxcli.Response, error) {
+					if q.Command == "SHOW SUBSCRIPTIONS" {
+						mu.Lock()
+						showSubs++
+						mu.Unlock()
+					}
+					return &influxcli.Response{}, nil
+				},
+			},
+			StatusFunc: func() []influxcli.URLStatus {
+				mu.Lock()
+				defer mu.Unlock()
+				us := influxcli.URLStatus{URL: config.URLs[0], Healthy: healthy, LastCheck: time.Unix(1, 0)}
+				if healthy {
+					us.Version = "1.8.10"
+				} else {
+					us.Err = errors.New("connection refused")
+				}
+				return []influxcli.URLStatus{us}
+			},
+			checked: checked,
+		}, nil
+	}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	<-checked
+
+	exp := client.InfluxDBStatus{
+		Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/influxdb"},
+		Clusters: map[string]client.InfluxDBClusterStatus{
+			testClusterName: {
+				Default: true,
+				URLs: []client.InfluxDBURLStatus{{
+					URL:       "http://" + testClusterName,
+					LastCheck: time.Unix(1, 0),
+					Error:     "connection refused",
+				}},
+			},
+		},
+	}
+	if got := s.Status(); !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected status:\ngot\n%v\nexp\n%v", got, exp)
+	}
+
+	// The subscriptions are linked again once the URL recovers.
+	mu.Lock()
+	healthy = true
+	linked := showSubs
+	mu.Unlock()
+	timeout := time.After(5 * time.Second)
+	for {
+		mu.Lock()
+		relinked := showSubs > linked
+		mu.Unlock()
+		if relinked {
+			break
+		}
+		select {
+		case <-checked:
+		case <-timeout:
+			t.Fatal("timed out waiting for the subscriptions to be linked again")
+		}
+	}
+
+	exp.Clusters[testClusterName] = client.InfluxDBClusterStatus{
+		Default: true,
+		Healthy: true,
+		URLs: []client.InfluxDBURLStatus{{
+			URL:       "http://" + testClusterName,
+			Healthy:   true,
+			LastCheck: time.Unix(1, 0),
+			Version:   "1.8.10",
+		}},
+	}
+	if got := s.Status(); !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected status:\ngot\n%v\nexp\n%v", got, exp)
+	}
+}
+
+func TestService_SubscriptionListener(t *testing.T) {
+	configs := NewDefaultTestConfigs(nil)
+	configs[0].SubscriptionHTTPBind = "127.0.0.1:0"
+	// The dedicated listener verifies the tokens even though the HTTP API does not authenticate.
+	s, _, cs := NewTestService(configs, "127.0.0.1", false)
+	pw := &pointsWriter{}
+	s.PointsWriter = pw
+
+	var created []string
+	cs.QueryFunc = subscriptionsQueryFunc(map[string][]string{"db1": {"rpA"}}, &created)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if len(created) != 1 {
+		t.Fatalf("unexpected subscriptions %v", created)
+	}
+	destination := strings.Trim(created[0][strings.Index(created[0], "'"):], "'")
+	u, err := url.Parse(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.User == nil || u.User.Username() != httpd.SubscriptionUser {
+		t.Fatalf("unexpected subscription destination %q", destination)
+	}
+	token, _ := u.User.Password()
+
+	testCases := []struct {
+		name     string
+		user     string
+		token    string
+		rp       string
+		expCode  int
+		expWrite bool
+	}{
+		{name: "valid", user: httpd.SubscriptionUser, token: token, rp: "rpA", expCode: http.StatusNoContent, expWrite: true},
+		{name: "no credentials", rp: "rpA", expCode: http.StatusUnauthorized},
+		{name: "wrong user", user: "bob", token: token, rp: "rpA", expCode: http.StatusUnauthorized},
+		{name: "wrong token", user: httpd.SubscriptionUser, token: randomToken, rp: "rpA", expCode: http.StatusUnauthorized},
+		{name: "wrong retention policy", user: httpd.SubscriptionUser, token: token, rp: "rpB", expCode: http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		pw.points = nil
+		req, err := http.NewRequest("POST", fmt.Sprintf("http://%s/write?db=db1&rp=%s&precision=s", u.Host, tc.rp), strings.NewReader("cpu value=1 10\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.user != "" {
+			req.SetBasicAuth(tc.user, tc.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.expCode {
+			t.Errorf("%s: unexpected status code: got %d exp %d", tc.name, resp.StatusCode, tc.expCode)
+		}
+		if got := len(pw.points) == 1; got != tc.expWrite {
+			t.Errorf("%s: unexpected points written: %v", tc.name, pw.points)
+		} else if got && (pw.db != "db1" || pw.rp != "rpA" || !pw.points[0].Time().Equal(time.Unix(10, 0))) {
+			t.Errorf("%s: unexpected write to %s.%s: %v", tc.name, pw.db, pw.rp, pw.points)
+		}
+	}
+}
+
+func TestService_SubscriptionPatterns(t *testing.T) {
+	configs := NewDefaultTestConfigs(nil)
+	configs[0].Subscriptions = map[string][]string{"telegraf*": {"*"}, "app": {"autogen"}}
+	configs[0].ExcludedSubscriptions = map[string][]string{"telegraf_tmp": {"*"}}
+	configs[0].SubscriptionProtocol = "udp"
+	configs[0].SubscriptionMeasurements = []string{"cpu*", "mem"}
+	configs[0].ExcludedSubscriptionMeasurements = []string{"cpu_tmp"}
+	if err := configs[0].Validate(); err != nil {
+		t.Fatal(err)
+	}
+	s, _, cs := NewTestService(configs, "127.0.0.1", false)
+	pw := &pointsWriter{}
+	s.PointsWriter = pw
+
+	var created []string
+	cs.QueryFunc = subscriptionsQueryFunc(map[string][]string{
+		"telegraf_a":   {"autogen", "weekly"},
+		"telegraf_tmp": {"autogen"},
+		"app":          {"autogen", "weekly"},
+		"other":        {"autogen"},
+	}, &created)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var got []string
+	var destination string
+	for _, q := range created {
+		stmt, err := influxql.ParseStatement(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		create := stmt.(*influxql.CreateSubscriptionStatement)
+		got = append(got, create.Database+"."+create.RetentionPolicy)
+		if create.Database == "app" {
+			destination = create.Destinations[0]
+		}
+	}
+	sort.Strings(got)
+	if exp := []string{"app.autogen", "telegraf_a.autogen", "telegraf_a.weekly"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected subscriptions:\ngot %v\nexp %v", got, exp)
+	}
+
+	// Only the points of the matching measurements are written.
+	u, err := url.Parse(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("udp", u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("cpu value=1\nmem value=1\ndisk value=1\ncpu_tmp value=1\ncpu2 value=1\n")); err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		pw.mu.Lock()
+		points := pw.points
+		pw.mu.Unlock()
+		if points != nil {
+			var measurements []string
+			for _, p := range points {
+				measurements = append(measurements, string(p.Name()))
+			}
+			if exp := []string{"cpu", "mem", "cpu2"}; !reflect.DeepEqual(measurements, exp) {
+				t.Errorf("unexpected measurements:\ngot %v\nexp %v", measurements, exp)
+			}
+			break
+		}
+		select {
+		case <-timeout:
+			t.Fatal("timed out waiting for the points")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestService_OrphanedSubscriptions(t *testing.T) {
+	configs := NewDefaultTestConfigs(nil)
+	configs[0].Subscriptions = map[string][]string{"app": {"autogen"}}
+	s, _, cs := NewTestService(configs, "localhost", false)
+
+	staleName := "kapacitor-" + uuid.New().String()
+	existing := [][]interface{}{
+		{"app", "autogen", testSubName, []interface{}{"http://localhost:9092"}},
+		{"telegraf", "autogen", testSubName, []interface{}{"http://localhost:9092"}},
+		{"telegraf", "autogen", staleName, []interface{}{"http://localhost:9092"}},
+		{"telegraf", "autogen", "kapacitor-" + uuid.New().String(), []interface{}{"http://example.com:9092"}},
+		{"telegraf", "autogen", "chronograf", []interface{}{"http://localhost:9092"}},
+	}
+	var created []string
+	var mu sync.Mutex
+	var dropped []string
+	dbrps := subscriptionsQueryFunc(map[string][]string{"app": {"autogen"}, "telegraf": {"autogen"}}, &created)
+	cs.QueryFunc = func(clusterName string, q influxcli.Query) (*influxcli.Response, error) {
+		switch {
+		case q.Command == "SHOW SUBSCRIPTIONS":
+			result := influxcli.Result{}
+			for _, e := range existing {
+				result.Series = append(result.Series, models.Row{
+					Name:    e[0].(string),
+					Columns: []string{"retention_policy", "name", "mode", "destinations"},
+					Values:  [][]interface{}{{e[1], e[2], "ANY", e[3]}},
+				})
+			}
+			return &influxcli.Response{Results: []influxcli.Result{result}}, nil
+		case strings.HasPrefix(q.Command, "DROP SUBSCRIPTION"):
+			mu.Lock()
+			dropped = append(dropped, q.Command)
+			mu.Unlock()
+			return &influxcli.Response{}, nil
+		}
+		return dbrps(clusterName, q)
+	}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if got, exp := s.Subscriptions(), map[string][]client.DBRP{testClusterName: {{Database: "app", RetentionPolicy: "autogen"}}}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected subscriptions:\ngot %v\nexp %v", got, exp)
+	}
+
+	exp := []client.OrphanedSubscription{
+		{
+			Cluster:         testClusterName,
+			Database:        "telegraf",
+			RetentionPolicy: "autogen",
+			Name:            testSubName,
+			Destinations:    []string{"http://localhost:9092"},
+			Reason:          "the database and retention policy are not subscribed to",
+		},
+		{
+			Cluster:         testClusterName,
+			Database:        "telegraf",
+			RetentionPolicy: "autogen",
+			Name:            staleName,
+			Destinations:    []string{"http://localhost:9092"},
+			Reason:          "the subscription has a previous name of this Kapacitor",
+		},
+	}
+	orphaned, err := s.OrphanedSubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(orphaned, exp) {
+		t.Errorf("unexpected orphaned subscriptions:\ngot %+v\nexp %+v", orphaned, exp)
+	}
+
+	mu.Lock()
+	dropped = nil
+	mu.Unlock()
+	removed, err := s.RemoveOrphanedSubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(removed, exp) {
+		t.Errorf("unexpected removed subscriptions:\ngot %+v\nexp %+v", removed, exp)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	expDropped := []string{
+		fmt.Sprintf(`DROP SUBSCRIPTION "%s" ON telegraf.autogen`, testSubName),
+		fmt.Sprintf(`DROP SUBSCRIPTION "%s" ON telegraf.autogen`, staleName),
+	}
+	if !reflect.DeepEqual(dropped, expDropped) {
+		t.Errorf("unexpected dropped subscriptions:\ngot %v\nexp %v", dropped, expDropped)
+	}
+}
+
+// subscriptionsQueryFunc answers the queries of the databases and retention policies,
+// and records the subscriptions created.
+func subscriptionsQueryFunc(dbrps map[string][]string, created *[]string) func(string, influxcli.Query) (*influxcli.Response, error) {
+	return func(clusterName string, q influxcli.Query) (*influxcli.Response, error) {
+		switch {
+		case q.Command == "SHOW DATABASES":
+			var dbs [][]interface{}
+			for db := range dbrps {
+				dbs = append(dbs, []interface{}{db})
+			}
+			return &influxcli.Response{Results: []influxcli.Result{{
+				Series: []models.Row{{Values: dbs}},
+			}}}, nil
+		case strings.HasPrefix(q.Command, "SHOW RETENTION POLICIES ON"):
+			stmt, err := influxql.ParseStatement(q.Command)
+			if err != nil {
+				return nil, err
+			}
+			var rps [][]interface{}
+			for _, rp := range dbrps[stmt.(*influxql.ShowRetentionPoliciesStatement).Database] {
+				rps = append(rps, []interface{}{rp})
+			}
+			return &influxcli.Response{Results: []influxcli.Result{{
+				Series: []models.Row{{Values: rps}},
+			}}}, nil
+		case strings.HasPrefix(q.Command, "CREATE SUBSCRIPTION"):
+			*created = append(*created, q.Command)
+		}
+		return &influxcli.Response{}, nil
+	}
+}
+
 func validate(
 	t *testing.T,
 	testName string,
@@ -1184,26 +1569,26 @@ func NewTestService(configs []influxdb.Config, hostname string, useTokens bool)
 }
 
 type authService struct {
-	GrantSubscriptionAccessFunc  func(token, db, rp string) error
-	ListSubscriptionTokensFunc   func() ([]string, error)
-	RevokeSubscriptionAccessFunc func(token string) error
+	GrantSubscriptionAccessFunc  func(cluster, token, db, rp string) error
+	ListSubscriptionTokensFunc   func() ([]auth.SubscriptionToken, error)
+	RevokeSubscriptionAccessFunc func(hash string) error
 }
 
-func (a *authService) GrantSubscriptionAccess(token, db, rp string) error {
+func (a *authService) GrantSubscriptionAccess(cluster, token, db, rp string) error {
 	if a.GrantSubscriptionAccessFunc != nil {
-		return a.GrantSubscriptionAccessFunc(token, db, rp)
+		return a.GrantSubscriptionAccessFunc(cluster, token, db, rp)
 	}
 	return nil
 }
-func (a *authService) ListSubscriptionTokens() ([]string, error) {
+func (a *authService) ListSubscriptionTokens() ([]auth.SubscriptionToken, error) {
 	if a.ListSubscriptionTokensFunc != nil {
 		return a.ListSubscriptionTokensFunc()
 	}
 	return nil, nil
 }
-func (a *authService) RevokeSubscriptionAccess(token string) error {
+func (a *authService) RevokeSubscriptionAccess(hash string) error {
 	if a.RevokeSubscriptionAccessFunc != nil {
-		return a.RevokeSubscriptionAccessFunc(token)
+		return a.RevokeSubscriptionAccessFunc(hash)
 	}
 	return nil
 }
@@ -1272,6 +1657,41 @@ func (c influxDBClient) Update(config influxcli.Config) error {
 	return nil
 }
 
+type pointsWriter struct {
+	mu     sync.Mutex
+	db     string
+	rp     string
+	points []models.Point
+}
+
+func (w *pointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.db, w.rp, w.points = database, retentionPolicy, points
+	return nil
+}
+
+// healthClient is a client checking the health of its URLs.
+type healthClient struct {
+	influxDBClient
+	StatusFunc func() []influxcli.URLStatus
+	checked    chan struct{}
+}
+
+func (c healthClient) CheckHealth(ctx context.Context) []influxcli.URLStatus {
+	defer func() {
+		select {
+		case c.checked <- struct{}{}:
+		default:
+		}
+	}()
+	return c.StatusFunc()
+}
+
+func (c healthClient) Status() []influxcli.URLStatus {
+	return c.StatusFunc()
+}
+
 type logSerivce struct {
 }
 
@@ -0,0 +1,141 @@
+package influxdb
+
+import (
+	"compress/gzip"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/pkg/errors"
+)
+
+// subscriptionListener is a dedicated listener for the writes of the HTTP subscriptions of a cluster,
+// optionally with TLS, independent of the HTTP API.
+// Every write must authenticate with the token of the subscription of its database and retention policy.
+type subscriptionListener struct {
+	bind      string
+	tlsConfig *tls.Config
+
+	ln     net.Listener
+	server *http.Server
+
+	// verify reports whether the token was granted for the subscription of the database and retention policy.
+	verify func(token, db, rp string) bool
+	diag   Diagnostic
+
+	PointsWriter interface {
+		WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+	}
+}
+
+func newSubscriptionListener(bind, sslCert, sslKey string, verify func(token, db, rp string) bool, d Diagnostic) (*subscriptionListener, error) {
+	l := &subscriptionListener{
+		bind:   bind,
+		verify: verify,
+		diag:   d,
+	}
+	if sslCert != "" {
+		cert, err := tls.LoadX509KeyPair(sslCert, sslKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load the subscription certificate")
+		}
+		l.tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+	return l, nil
+}
+
+func (l *subscriptionListener) Open() error {
+	ln, err := net.Listen("tcp", l.bind)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen for subscriptions on %s", l.bind)
+	}
+	protocol := "http"
+	if l.tlsConfig != nil {
+		ln = tls.NewListener(ln, l.tlsConfig)
+		protocol = "https"
+	}
+	l.ln = ln
+	l.server = &http.Server{
+		Handler:     l,
+		ReadTimeout: time.Minute,
+	}
+	go func() {
+		if err := l.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			l.diag.Error("subscription listener failed", err)
+		}
+	}()
+	l.diag.StartedSubscriptionListener(ln.Addr().String(), protocol)
+	return nil
+}
+
+func (l *subscriptionListener) Close() error {
+	if l.server == nil {
+		return nil
+	}
+	return l.server.Close()
+}
+
+// Port returns the port the listener is bound to.
+func (l *subscriptionListener) Port() int {
+	return l.ln.Addr().(*net.TCPAddr).Port
+}
+
+// ServeHTTP serves the writes of the subscriptions, InfluxDB posts them to the /write path of the destination.
+func (l *subscriptionListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/write" {
+		httpd.HttpError(w, "not found", false, http.StatusNotFound)
+		return
+	}
+	if r.Method != "POST" {
+		httpd.HttpError(w, "method not allowed", false, http.StatusMethodNotAllowed)
+		return
+	}
+	db, rp := r.FormValue("db"), r.FormValue("rp")
+	user, token, ok := r.BasicAuth()
+	if !ok || user != httpd.SubscriptionUser || !l.verify(token, db, rp) {
+		httpd.HttpError(w, fmt.Sprintf("invalid subscription token for %s.%s", db, rp), false, http.StatusUnauthorized)
+		return
+	}
+
+	body := r.Body
+	if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		b, err := gzip.NewReader(r.Body)
+		if err != nil {
+			httpd.HttpError(w, err.Error(), false, http.StatusBadRequest)
+			return
+		}
+		defer b.Close()
+		body = b
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), false, http.StatusBadRequest)
+		return
+	}
+	precision := r.FormValue("precision")
+	if precision == "" {
+		precision = "n"
+	}
+	points, err := models.ParsePointsWithPrecision(data, time.Now().UTC(), precision)
+	if err != nil && err != io.EOF {
+		httpd.HttpError(w, err.Error(), false, http.StatusBadRequest)
+		return
+	}
+	if len(points) > 0 {
+		if err := l.PointsWriter.WritePoints(db, rp, models.ConsistencyLevelAll, points); err != nil {
+			httpd.HttpError(w, err.Error(), false, http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
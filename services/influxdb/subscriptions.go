@@ -0,0 +1,179 @@
+package influxdb
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/pkg/errors"
+)
+
+// Reasons a subscription is orphaned.
+const (
+	orphanedExcluded = "the database and retention policy are not subscribed to"
+	orphanedDisabled = "the subscriptions are disabled"
+	orphanedStale    = "the subscription has a previous name of this Kapacitor"
+)
+
+// Subscriptions returns the databases and retention policies subscribed to, by cluster.
+func (s *Service) Subscriptions() map[string][]client.DBRP {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	subs := make(map[string][]client.DBRP, len(s.clusters))
+	for name, cluster := range s.clusters {
+		subs[name] = cluster.subscriptions()
+	}
+	return subs
+}
+
+// OrphanedSubscriptions returns the subscriptions of the clusters that Kapacitor no longer uses.
+func (s *Service) OrphanedSubscriptions() ([]client.OrphanedSubscription, error) {
+	return s.orphanedSubscriptions(false)
+}
+
+// RemoveOrphanedSubscriptions drops the orphaned subscriptions from the clusters, and returns them.
+func (s *Service) RemoveOrphanedSubscriptions() ([]client.OrphanedSubscription, error) {
+	return s.orphanedSubscriptions(true)
+}
+
+func (s *Service) orphanedSubscriptions(drop bool) ([]client.OrphanedSubscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.clusters))
+	for name := range s.clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	orphaned := []client.OrphanedSubscription{}
+	for _, name := range names {
+		o, err := s.clusters[name].orphanedSubscriptions(drop)
+		orphaned = append(orphaned, o...)
+		if err != nil {
+			return orphaned, errors.Wrapf(err, "cluster %s", name)
+		}
+	}
+	return orphaned, nil
+}
+
+// subscriptions returns the databases and retention policies the cluster is subscribed to.
+func (c *influxdbCluster) subscriptions() []client.DBRP {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	dbrps := []client.DBRP{}
+	for se, running := range c.runningSubs {
+		if running && se.name == c.subName {
+			dbrps = append(dbrps, client.DBRP{Database: se.db, RetentionPolicy: se.rp})
+		}
+	}
+	sort.Slice(dbrps, func(i, j int) bool {
+		if dbrps[i].Database != dbrps[j].Database {
+			return dbrps[i].Database < dbrps[j].Database
+		}
+		return dbrps[i].RetentionPolicy < dbrps[j].RetentionPolicy
+	})
+	return dbrps
+}
+
+// orphanedSubscriptions returns the subscriptions of the cluster that Kapacitor no longer uses, dropping them if drop is true:
+// the subscriptions of this Kapacitor that are excluded or disabled,
+// and the subscriptions whose destinations are this Kapacitor but with the name of a previous cluster or server ID.
+func (c *influxdbCluster) orphanedSubscriptions(drop bool) ([]client.OrphanedSubscription, error) {
+	if drop {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+	} else {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+	}
+	if !c.opened {
+		return nil, nil
+	}
+	resp, err := c.execQuery(&influxql.ShowSubscriptionsStatement{})
+	if err != nil {
+		return nil, err
+	}
+	var orphaned []client.OrphanedSubscription
+	for _, res := range resp.Results {
+		for _, series := range res.Series {
+			for _, v := range series.Values {
+				se := subEntry{db: series.Name}
+				var destinations []string
+				for i, col := range series.Columns {
+					switch col {
+					case "retention_policy":
+						se.rp, _ = v[i].(string)
+					case "name":
+						se.name, _ = v[i].(string)
+					case "destinations":
+						ds, _ := v[i].([]interface{})
+						for _, d := range ds {
+							if d, ok := d.(string); ok {
+								destinations = append(destinations, d)
+							}
+						}
+					}
+				}
+				reason := c.orphanedReason(se, destinations)
+				if reason == "" {
+					continue
+				}
+				if drop {
+					if err := c.dropSub(se.name, se.db, se.rp); err != nil {
+						return orphaned, errors.Wrapf(err, "failed to drop subscription %s on %s.%s", se.name, se.db, se.rp)
+					}
+					if se.name == c.subName {
+						c.closeSub(se)
+					}
+				}
+				orphaned = append(orphaned, client.OrphanedSubscription{
+					Cluster:         c.clusterName,
+					Database:        se.db,
+					RetentionPolicy: se.rp,
+					Name:            se.name,
+					Destinations:    destinations,
+					Reason:          reason,
+				})
+			}
+		}
+	}
+	return orphaned, nil
+}
+
+// orphanedReason returns why the subscription is orphaned, or an empty string if it is not.
+func (c *influxdbCluster) orphanedReason(se subEntry, destinations []string) string {
+	switch {
+	case se.name == c.subName && c.disableSubs:
+		return orphanedDisabled
+	case se.name == c.subName && !c.shouldSubExist(se):
+		return orphanedExcluded
+	case se.name != c.subName && (se.name == legacySubName || strings.HasPrefix(se.name, subNamePrefix)) && c.destinedHere(destinations):
+		return orphanedStale
+	}
+	return ""
+}
+
+// destinedHere reports whether a destination is this Kapacitor.
+func (c *influxdbCluster) destinedHere(destinations []string) bool {
+	for _, d := range destinations {
+		u, err := url.Parse(d)
+		if err != nil {
+			continue
+		}
+		host, port, err := net.SplitHostPort(u.Host)
+		if err != nil || host != c.hostname {
+			continue
+		}
+		// The ports of the UDP listeners are chosen when they are created.
+		if u.Scheme == "udp" {
+			return true
+		}
+		if p, err := strconv.Atoi(port); err == nil && p == c.subscriptionPort() {
+			return true
+		}
+	}
+	return false
+}
@@ -235,6 +235,9 @@ type handler struct {
 	diag Diagnostic
 }
 
+// Cached templates, shared by the handlers
+var alertTemplates = alert.NewTextTemplates("kafka alert template", nil)
+
 func (s *Service) Handler(c HandlerConfig, ctx ...keyvalue.T) (alert.Handler, error) {
 	cluster, ok := s.Cluster(c.Cluster)
 	if !ok {
@@ -243,7 +246,7 @@ func (s *Service) Handler(c HandlerConfig, ctx ...keyvalue.T) (alert.Handler, er
 	var t *template.Template
 	if c.Template != "" {
 		var err error
-		t, err = template.New("kafka alert template").Parse(c.Template)
+		t, err = alertTemplates.Parse(c.Template)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to parse template")
 		}
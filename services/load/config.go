@@ -3,6 +3,8 @@ package load
 import (
 	"errors"
 	"path/filepath"
+
+	"github.com/influxdata/influxdb/toml"
 )
 
 const taskDir = "tasks"
@@ -12,6 +14,9 @@ const handlerDir = "handlers"
 type Config struct {
 	Enabled bool   `toml:"enabled"`
 	Dir     string `toml:"dir"`
+	// How often the directory is checked for changes.
+	// If 0 the directory is only loaded on startup and reload.
+	WatchInterval toml.Duration `toml:"watch-interval"`
 }
 
 func NewConfig() Config {
@@ -34,6 +39,10 @@ func (c Config) Validate() error {
 		return errors.New("dir must be an absolute path")
 	}
 
+	if c.WatchInterval < 0 {
+		return errors.New("watch-interval must not be negative")
+	}
+
 	return nil
 }
 
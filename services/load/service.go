@@ -8,14 +8,18 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ghodss/yaml"
 
+	"github.com/influxdata/kapacitor/auth"
 	"github.com/influxdata/kapacitor/client/v1"
 	kexpvar "github.com/influxdata/kapacitor/expvar"
 	"github.com/influxdata/kapacitor/server/vars"
+	"github.com/influxdata/kapacitor/services/httpd"
 	"github.com/influxdata/kapacitor/services/storage"
 	"github.com/pkg/errors"
 )
@@ -33,6 +37,8 @@ const (
 	loadNamespace = "load_items"
 )
 
+const statusPath = "/load/status"
+
 type Diagnostic interface {
 	Debug(msg string)
 	Error(msg string, err error)
@@ -42,6 +48,19 @@ type Diagnostic interface {
 type Service struct {
 	mu     sync.Mutex
 	config Config
+	routes []httpd.Route
+
+	// Serializes loading the directory
+	loadMu sync.Mutex
+	// Files of the directory when it was last loaded
+	files map[string]fileInfo
+	// Files that are not loaded again because they did not change
+	unchanged  map[string]bool
+	fileErrors map[string]string
+	status     client.LoadStatus
+
+	closing chan struct{}
+	wg      sync.WaitGroup
 
 	cli        *client.Client
 	statsKey   string
@@ -58,6 +77,10 @@ type Service struct {
 		Store(namespace string) storage.Interface
 		Register(name string, store storage.StoreActioner)
 	}
+	HTTPDService interface {
+		AddRoutes([]httpd.Route) error
+		DelRoutes([]httpd.Route)
+	}
 
 	diag Diagnostic
 }
@@ -83,6 +106,10 @@ func NewService(c Config, h http.Handler, d Diagnostic) (*Service, error) {
 		tasks:     map[string]bool{},
 		templates: map[string]bool{},
 		handlers:  map[string]bool{},
+		status: client.LoadStatus{
+			Dir:      c.Dir,
+			Watching: c.Enabled && c.WatchInterval > 0,
+		},
 	}
 
 	s.statsKey, s.statMap = vars.NewStatistic("load", nil)
@@ -103,129 +130,289 @@ func (s *Service) Open() error {
 	}
 	s.items = items
 	s.StorageService.Register(loadAPIName, s.items)
+
+	// Define API routes
+	s.routes = []httpd.Route{
+		{
+			Method:      "GET",
+			Pattern:     statusPath,
+			HandlerFunc: s.handleStatus,
+			Scope:       auth.TasksReadScope,
+		},
+	}
+	if err := s.HTTPDService.AddRoutes(s.routes); err != nil {
+		return errors.Wrap(err, "failed to add API routes")
+	}
+
+	if s.status.Watching {
+		s.closing = make(chan struct{})
+		s.wg.Add(1)
+		go s.watch()
+	}
 	return nil
 }
 
 func (s *Service) Close() error {
+	if s.closing != nil {
+		close(s.closing)
+		s.wg.Wait()
+	}
+	if s.HTTPDService != nil {
+		s.HTTPDService.DelRoutes(s.routes)
+	}
 	return nil
 }
 
+func (s *Service) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	status := s.status
+	s.mu.Unlock()
+	w.Write(httpd.MarshalJSON(status, true))
+}
+
+// watch loads the directory whenever its files change.
+func (s *Service) watch() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(time.Duration(s.config.WatchInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.closing:
+			return
+		case <-ticker.C:
+			s.loadMu.Lock()
+			changed := !sameFiles(s.files, s.dirFiles())
+			s.loadMu.Unlock()
+			if changed {
+				// Errors are logged and reported by the status
+				s.sync(true)
+			}
+		}
+	}
+}
+
+// fileInfo identifies a version of a file.
+type fileInfo struct {
+	modTime int64
+	size    int64
+}
+
+// dirFiles returns all files in the directory tree.
+func (s *Service) dirFiles() map[string]fileInfo {
+	files := make(map[string]fileInfo)
+	filepath.Walk(s.config.Dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			// The directory or file was removed while walking it
+			return nil
+		}
+		if !info.IsDir() {
+			files[p] = fileInfo{
+				modTime: info.ModTime().UnixNano(),
+				size:    info.Size(),
+			}
+		}
+		return nil
+	})
+	return files
+}
+
+func sameFiles(a, b map[string]fileInfo) bool {
+	if a == nil || len(a) != len(b) {
+		return false
+	}
+	for f, info := range a {
+		if other, ok := b[f]; !ok || other != info {
+			return false
+		}
+	}
+	return true
+}
+
+// walkFiles returns all files in the directory tree with one of the extensions.
+func walkFiles(dir string, exts ...string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		ext := filepath.Ext(p)
+		for _, e := range exts {
+			if ext == e {
+				files = append(files, p)
+				break
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// fileID returns the ID of the task or template defined by a file.
+func fileID(f string) string {
+	return strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))
+}
+
 // taskFiles gets a slice of all files with the .tick file extension
 // and any associated files with .json, .yml, and .yaml file extentions
-// in the configured task directory.
+// in the directory tree of the configured task directory.
 func (s *Service) taskFiles() (tickscripts []string, taskFiles []string, err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	tasksDir := s.config.tasksDir()
 
-	files, err := ioutil.ReadDir(tasksDir)
+	tickscripts, err = walkFiles(tasksDir, ".tick")
 	if err != nil {
 		return nil, nil, err
 	}
-
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		filename := file.Name()
-		switch ext := filepath.Ext(filename); ext {
-		case ".tick":
-			tickscripts = append(tickscripts, filepath.Join(tasksDir, filename))
-		case ".yml", ".json", ".yaml":
-			taskFiles = append(taskFiles, filepath.Join(tasksDir, filename))
-		default:
-			continue
-		}
+	taskFiles, err = walkFiles(tasksDir, ".yml", ".json", ".yaml")
+	if err != nil {
+		return nil, nil, err
 	}
-
 	return
 }
 
 // templateFiles gets a slice of all files with the .tick file extension
-// in the configured template directory.
+// in the directory tree of the configured template directory.
 func (s *Service) templateFiles() (tickscripts []string, err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	templatesDir := s.config.templatesDir()
-
-	files, err := ioutil.ReadDir(templatesDir)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		filename := file.Name()
-		switch ext := filepath.Ext(filename); ext {
-		case ".tick":
-			tickscripts = append(tickscripts, filepath.Join(templatesDir, filename))
-		default:
-			continue
-		}
-	}
-
-	return
+	return walkFiles(s.config.templatesDir(), ".tick")
 }
 
 // HandlerFiles gets a slice of all files with the .json, .yml, and
-// .yaml file extentions in the configured handler directory.
+// .yaml file extentions in the directory tree of the configured handler directory.
 func (s *Service) handlerFiles() ([]string, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	handlers := []string{}
-
-	handlersDir := s.config.handlersDir()
 
-	files, err := ioutil.ReadDir(handlersDir)
-	if err != nil {
-		return nil, err
-	}
+	return walkFiles(s.config.handlersDir(), ".yml", ".json", ".yaml")
+}
 
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
+// Load creates, updates and deletes tasks, templates and handlers to match the files of the directory.
+func (s *Service) Load() error {
+	return s.sync(false)
+}
 
-		filename := file.Name()
-		switch ext := filepath.Ext(filename); ext {
-		case ".yml", ".json", ".yaml":
-			handlers = append(handlers, filepath.Join(handlersDir, filename))
-		default:
-			continue
-		}
-	}
+// sync loads the directory, if onlyChanged is set files that did not change
+// since they were last loaded without error are not loaded again.
+func (s *Service) sync(onlyChanged bool) error {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
 
-	return handlers, nil
-}
+	// Record the files before loading them, so changes made while loading are loaded again.
+	files := s.dirFiles()
 
-func (s *Service) Load() error {
 	s.mu.Lock()
 	s.tasks = map[string]bool{}
 	s.templates = map[string]bool{}
 	s.handlers = map[string]bool{}
+	s.unchanged = map[string]bool{}
+	if onlyChanged {
+		for f, info := range files {
+			if prev, ok := s.files[f]; ok && prev == info && s.fileErrors[f] == "" {
+				s.unchanged[f] = true
+			}
+		}
+	}
+	s.fileErrors = map[string]string{}
 	s.mu.Unlock()
 
 	if err := s.load(); err != nil {
 		s.diag.Error("failed to load new files", err)
 		s.errorCount.Add(1)
+		s.synced(nil, err)
 		return err
 	}
 
 	if err := s.removeMissing(); err != nil {
 		s.diag.Error("failed to remove missing", err)
 		s.errorCount.Add(1)
+		s.synced(nil, err)
 		return err
 	}
 
+	s.synced(files, nil)
+	if err := s.fileError(); err != nil {
+		s.errorCount.Add(1)
+		return err
+	}
 	return nil
 }
 
+// synced records the result of loading the files of the directory.
+// The files are nil if the sync failed, so that they are loaded again.
+func (s *Service) synced(files map[string]fileInfo, err error) {
+	s.files = files
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LastSync = time.Now().UTC()
+	s.status.Error = ""
+	if err != nil {
+		s.status.Error = err.Error()
+	}
+	s.status.FileErrors = nil
+	for f, msg := range s.fileErrors {
+		if s.status.FileErrors == nil {
+			s.status.FileErrors = make(map[string]string, len(s.fileErrors))
+		}
+		if rel, err := filepath.Rel(s.config.Dir, f); err == nil {
+			f = rel
+		}
+		s.status.FileErrors[f] = msg
+	}
+}
+
+// fileError returns an error describing the files that failed to load, if any.
+func (s *Service) fileError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.fileErrors) == 0 {
+		return nil
+	}
+	files := make([]string, 0, len(s.fileErrors))
+	for f := range s.fileErrors {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	f := files[0]
+	if len(files) > 1 {
+		return fmt.Errorf("failed to load file %s: %s (and %d more files)", f, s.fileErrors[f], len(files)-1)
+	}
+	return fmt.Errorf("failed to load file %s: %s", f, s.fileErrors[f])
+}
+
+// loadFile loads the task or template defined by a file and records any error.
+// Since tasks and templates are identified by file name, the ones of files that fail
+// to load are kept as they are instead of being removed as missing.
+func (s *Service) loadFile(f, kind string, seen map[string]string, loaded map[string]bool, load func(f string) error) {
+	id := fileID(f)
+	var err error
+	if other, ok := seen[id]; ok {
+		err = fmt.Errorf("%s %s is already defined by file %s", kind, id, other)
+	} else {
+		seen[id] = f
+		s.mu.Lock()
+		unchanged := s.unchanged[f]
+		s.mu.Unlock()
+		if !unchanged {
+			s.diag.Loading(kind, f)
+			err = load(f)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.diag.Error("failed to load file "+f, err)
+		s.fileErrors[f] = err.Error()
+	}
+	loaded[id] = true
+}
+
 func (s *Service) load() error {
 	if !s.config.Enabled {
 		return nil
@@ -263,18 +450,13 @@ func (s *Service) loadTasks() error {
 		return err
 	}
 
+	seen := make(map[string]string, len(ticks)+len(templateTasks))
 	for _, f := range ticks {
-		s.diag.Loading("task", f)
-		if err := s.loadTask(f); err != nil {
-			return fmt.Errorf("failed to load file %s: %s", f, err.Error())
-		}
+		s.loadFile(f, "task", seen, s.tasks, s.loadTask)
 	}
 
 	for _, v := range templateTasks {
-		s.diag.Loading("template task", v)
-		if err := s.loadVars(v); err != nil {
-			return fmt.Errorf("failed to load file %s: %s", v, err.Error())
-		}
+		s.loadFile(v, "template task", seen, s.tasks, s.loadVars)
 	}
 
 	return nil
@@ -345,11 +527,9 @@ func (s *Service) loadTemplates() error {
 		return err
 	}
 
+	seen := make(map[string]string, len(files))
 	for _, f := range files {
-		s.diag.Loading("template", f)
-		if err := s.loadTemplate(f); err != nil {
-			return fmt.Errorf("failed to load file %s: %s", f, err.Error())
-		}
+		s.loadFile(f, "template", seen, s.templates, s.loadTemplate)
 	}
 	return nil
 }
@@ -485,7 +665,10 @@ func (s *Service) loadHandlers() error {
 	for _, f := range files {
 		s.diag.Loading("handler", f)
 		if err := s.loadHandler(f); err != nil {
-			return fmt.Errorf("failed to load file %s: %s", f, err.Error())
+			s.diag.Error("failed to load file "+f, err)
+			s.mu.Lock()
+			s.fileErrors[f] = err.Error()
+			s.mu.Unlock()
 		}
 	}
 	return nil
@@ -571,6 +754,9 @@ func (s *Service) removeTasks() error {
 		if err := s.cli.DeleteTask(l); err != nil {
 			return err
 		}
+		if err := s.items.Delete(newTaskItem(id).ID); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -589,6 +775,9 @@ func (s *Service) removeTemplates() error {
 		if err := s.cli.DeleteTemplate(l); err != nil {
 			return err
 		}
+		if err := s.items.Delete(newTemplateItem(id).ID); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -610,6 +799,9 @@ func (s *Service) removeHandlers() error {
 		if err := s.cli.DeleteTopicHandler(l); err != nil {
 			return err
 		}
+		if err := s.items.Delete(newTopicHandlerItem(pair[0], pair[1]).ID); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -0,0 +1,43 @@
+package metamonitoring
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultTopic      = "kapacitor"
+	DefaultQueueDepth = 10000
+)
+
+// The pattern of the IDs of topics.
+var validTopic = regexp.MustCompile(`^[-:\._\p{L}0-9]+$`)
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+	// Topic of the alerts of the meta-monitoring tasks.
+	Topic string `toml:"topic"`
+	// Points queued for a stream task above which the queue depth alert warns.
+	QueueDepth int64 `toml:"queue-depth"`
+}
+
+func NewConfig() Config {
+	return Config{
+		Topic:      DefaultTopic,
+		QueueDepth: DefaultQueueDepth,
+	}
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if !validTopic.MatchString(c.Topic) {
+		return errors.Errorf("invalid topic %q, it may contain only letters, numbers, '-', '.', ':' and '_'", c.Topic)
+	}
+	if c.QueueDepth <= 0 {
+		return errors.New("queue-depth must be positive")
+	}
+	return nil
+}
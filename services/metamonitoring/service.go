@@ -0,0 +1,298 @@
+// Package metamonitoring installs managed tasks alerting on the internal statistics of the server,
+// the errors of tasks and alert handlers, the points dropped and the depth of the queues of stream tasks.
+//
+// The tasks stream the statistics written to the stats database and send their alerts to a topic,
+// they are updated when the server opens and removed once meta-monitoring is disabled.
+package metamonitoring
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/influxdata/kapacitor/client/v1"
+	kexpvar "github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/server/vars"
+	"github.com/influxdata/kapacitor/services/diagnostic"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TaskPrefix is the prefix of the IDs of the meta-monitoring tasks.
+	TaskPrefix = "_meta_"
+
+	// The label identifying the meta-monitoring tasks.
+	managedByLabel = "managed-by"
+	managedByValue = "meta-monitoring"
+
+	// The statistics of the errors of alert handlers, tagged with the handler.
+	handlerStatsName = "alert_handlers"
+	handlerTag       = "handler"
+	statErrors       = "errors"
+)
+
+type task struct {
+	id     string
+	script string
+}
+
+// The tasks share the vars database, retentionPolicy, topic and queueDepth.
+var tasks = []task{
+	{
+		id: TaskPrefix + "task_errors",
+		script: `// Alert when the nodes of tasks have errors.
+var database string
+var retentionPolicy string
+var topic string
+
+stream
+    |from()
+        .database(database)
+        .retentionPolicy(retentionPolicy)
+        .measurement('nodes')
+        .groupBy('task', 'node')
+    |difference('errors')
+        .as('new_errors')
+    |alert()
+        .id('task_errors:{{ index .Tags "task" }}:{{ index .Tags "node" }}')
+        .message('{{ index .Fields "new_errors" }} errors in node {{ index .Tags "node" }} of task {{ index .Tags "task" }}')
+        .warn(lambda: "new_errors" > 0)
+        .stateChangesOnly()
+        .topic(topic)
+`,
+	},
+	{
+		id: TaskPrefix + "dropped_points",
+		script: `// Alert when tasks drop points over their limits, or writes are rejected because a stream task is behind.
+var database string
+var retentionPolicy string
+var topic string
+
+stream
+    |from()
+        .database(database)
+        .retentionPolicy(retentionPolicy)
+        .measurement('nodes')
+        .groupBy('task', 'node')
+        .where(lambda: isPresent("points_throttled") OR isPresent("points_cardinality_limited"))
+    |default()
+        .field('points_throttled', 0)
+        .field('points_cardinality_limited', 0)
+    |eval(lambda: "points_throttled" + "points_cardinality_limited")
+        .as('dropped')
+    |difference('dropped')
+        .as('new_dropped')
+    |alert()
+        .id('dropped_points:{{ index .Tags "task" }}')
+        .message('{{ index .Fields "new_dropped" }} points dropped by task {{ index .Tags "task" }}')
+        .crit(lambda: "new_dropped" > 0)
+        .stateChangesOnly()
+        .topic(topic)
+
+stream
+    |from()
+        .database(database)
+        .retentionPolicy(retentionPolicy)
+        .measurement('queue')
+        .groupBy('database', 'retention_policy')
+    |difference('rejected_points')
+        .as('new_rejected')
+    |alert()
+        .id('rejected_points:{{ index .Tags "database" }}.{{ index .Tags "retention_policy" }}')
+        .message('{{ index .Fields "new_rejected" }} points written to {{ index .Tags "database" }}.{{ index .Tags "retention_policy" }} rejected')
+        .crit(lambda: "new_rejected" > 0)
+        .stateChangesOnly()
+        .topic(topic)
+`,
+	},
+	{
+		id: TaskPrefix + "handler_errors",
+		script: `// Alert when alert handlers fail.
+var database string
+var retentionPolicy string
+var topic string
+
+stream
+    |from()
+        .database(database)
+        .retentionPolicy(retentionPolicy)
+        .measurement('` + handlerStatsName + `')
+        .groupBy('` + handlerTag + `')
+    |difference('` + statErrors + `')
+        .as('new_errors')
+    |alert()
+        .id('handler_errors:{{ index .Tags "` + handlerTag + `" }}')
+        .message('{{ index .Fields "new_errors" }} errors of the {{ index .Tags "` + handlerTag + `" }} alert handler')
+        .warn(lambda: "new_errors" > 0)
+        .stateChangesOnly()
+        .topic(topic)
+`,
+	},
+	{
+		id: TaskPrefix + "queue_depth",
+		script: `// Alert when the points queued for a stream task exceed the queue depth.
+var database string
+var retentionPolicy string
+var topic string
+var queueDepth int
+
+stream
+    |from()
+        .database(database)
+        .retentionPolicy(retentionPolicy)
+        .measurement('queue')
+        .groupBy('database', 'retention_policy')
+    |alert()
+        .id('queue_depth:{{ index .Tags "database" }}.{{ index .Tags "retention_policy" }}')
+        .message('{{ index .Fields "queued_points" }} points queued for a stream task of {{ index .Tags "database" }}.{{ index .Tags "retention_policy" }}')
+        .warn(lambda: "queued_points" > queueDepth)
+        .stateChangesOnly()
+        .topic(topic)
+`,
+	},
+}
+
+type Diagnostic interface {
+	InstalledTask(id string)
+	RemovedTask(id string)
+}
+
+type Service struct {
+	config    Config
+	diag      Diagnostic
+	cli       *client.Client
+	statsKeys []string
+
+	// Database and retention policy of the internal statistics.
+	Database        string
+	RetentionPolicy string
+	// Components of the alert handlers whose errors are published as statistics.
+	HandlerComponents []string
+	LastErrors        func() map[string]diagnostic.LastError
+}
+
+func NewService(c Config, h http.Handler, d Diagnostic) (*Service, error) {
+	cfg := client.Config{
+		URL:       "http://localhost",
+		UserAgent: "internal-meta-monitoring-service",
+	}
+	if h != nil {
+		cfg.Transport = client.NewLocalTransport(h)
+	}
+	cli, err := client.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %v", err)
+	}
+	return &Service{
+		config: c,
+		diag:   d,
+		cli:    cli,
+	}, nil
+}
+
+// Open installs the meta-monitoring tasks if it is enabled, otherwise it removes them.
+func (s *Service) Open() error {
+	installed := make(map[string]bool, len(tasks))
+	if s.config.Enabled {
+		s.publishHandlerStats()
+		for _, t := range tasks {
+			if err := s.install(t); err != nil {
+				return errors.Wrapf(err, "failed to install task %s", t.id)
+			}
+			installed[t.id] = true
+		}
+	}
+	return s.removeOthers(installed)
+}
+
+func (s *Service) Close() error {
+	for _, key := range s.statsKeys {
+		vars.DeleteStatistic(key)
+	}
+	s.statsKeys = nil
+	return nil
+}
+
+// publishHandlerStats publishes the count of the errors of each alert handler.
+func (s *Service) publishHandlerStats() {
+	for _, component := range s.HandlerComponents {
+		component := component
+		key, statMap := vars.NewStatistic(handlerStatsName, map[string]string{handlerTag: component})
+		statMap.Set(statErrors, kexpvar.NewIntFuncGauge(func() int64 {
+			return s.LastErrors()[component].Count
+		}))
+		s.statsKeys = append(s.statsKeys, key)
+	}
+}
+
+func (s *Service) taskVars() client.Vars {
+	return client.Vars{
+		"database":        {Type: client.VarString, Value: s.Database},
+		"retentionPolicy": {Type: client.VarString, Value: s.RetentionPolicy},
+		"topic":           {Type: client.VarString, Value: s.config.Topic},
+		"queueDepth":      {Type: client.VarInt, Value: s.config.QueueDepth},
+	}
+}
+
+// install creates the task, or updates and reloads it if it exists.
+func (s *Service) install(t task) error {
+	dbrps := []client.DBRP{{Database: s.Database, RetentionPolicy: s.RetentionPolicy}}
+	labels := client.Labels{managedByLabel: managedByValue}
+	taskVars := s.taskVars()
+
+	l := s.cli.TaskLink(t.id)
+	existing, _ := s.cli.Task(l, nil)
+	if existing.ID == "" {
+		if _, err := s.cli.CreateTask(client.CreateTaskOptions{
+			ID:         t.id,
+			Type:       client.StreamTask,
+			DBRPs:      dbrps,
+			TICKscript: t.script,
+			Vars:       taskVars,
+			Labels:     labels,
+			Status:     client.Enabled,
+		}); err != nil {
+			return err
+		}
+		s.diag.InstalledTask(t.id)
+		return nil
+	}
+	if _, err := s.cli.UpdateTask(l, client.UpdateTaskOptions{
+		Type:       client.StreamTask,
+		DBRPs:      dbrps,
+		TICKscript: t.script,
+		Vars:       taskVars,
+		Labels:     labels,
+	}); err != nil {
+		return err
+	}
+	// Reload the task, which may have been disabled.
+	if existing.Status == client.Enabled {
+		if _, err := s.cli.UpdateTask(l, client.UpdateTaskOptions{Status: client.Disabled}); err != nil {
+			return err
+		}
+	}
+	_, err := s.cli.UpdateTask(l, client.UpdateTaskOptions{Status: client.Enabled})
+	return err
+}
+
+// removeOthers deletes the meta-monitoring tasks that are not installed.
+func (s *Service) removeOthers(installed map[string]bool) error {
+	managed, err := s.cli.ListTasks(&client.ListTasksOptions{
+		Pattern:  TaskPrefix + "*",
+		Selector: managedByLabel + "=" + managedByValue,
+		Fields:   []string{"status"},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to list meta-monitoring tasks")
+	}
+	for _, t := range managed {
+		if installed[t.ID] {
+			continue
+		}
+		if err := s.cli.DeleteTask(s.cli.TaskLink(t.ID)); err != nil {
+			return errors.Wrapf(err, "failed to remove task %s", t.ID)
+		}
+		s.diag.RemovedTask(t.ID)
+	}
+	return nil
+}
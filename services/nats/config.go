@@ -0,0 +1,155 @@
+package nats
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultURL is the default NATS server URL.
+	DefaultURL = "nats://localhost:4222"
+
+	// DefaultReconnectWait is how long to wait before reconnecting to the server.
+	DefaultReconnectWait = 2 * time.Second
+
+	// DefaultFetchBatch is the number of messages requested from a JetStream consumer at once.
+	DefaultFetchBatch = 100
+
+	// DefaultFetchTimeout is how long a single JetStream fetch request waits for messages.
+	DefaultFetchTimeout = 5 * time.Second
+
+	// DefaultMaxRetryWait is the longest time to wait before fetching the JetStream messages again
+	// after a write failed.
+	DefaultMaxRetryWait = time.Minute
+
+	// DefaultPrecision is the precision of timestamps in received line protocol.
+	DefaultPrecision = "ns"
+)
+
+type Config struct {
+	Enabled bool   `toml:"enabled"`
+	Name    string `toml:"name"`
+	// URL of the NATS server, i.e. nats://localhost:4222 or tls://localhost:4222.
+	URL string `toml:"url"`
+
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	Token    string `toml:"token"`
+
+	// Path to CA file
+	SSLCA string `toml:"ssl-ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl-cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl-key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool `toml:"insecure-skip-verify"`
+
+	// Subjects are the core NATS subjects to subscribe to.
+	// Wildcards are supported.
+	Subjects []string `toml:"subjects"`
+	// QueueGroup is the optional queue group to join when subscribing to core NATS subjects.
+	QueueGroup string `toml:"queue-group"`
+
+	// Stream is the JetStream stream to consume from.
+	// When set, messages are consumed through a durable pull consumer
+	// and acknowledged only once they have been written.
+	Stream string `toml:"stream"`
+	// Durable is the name of the durable JetStream consumer.
+	Durable string `toml:"durable"`
+	// FilterSubject optionally restricts the JetStream consumer to a subset of the stream.
+	FilterSubject string `toml:"filter-subject"`
+	// FetchBatch is the maximum number of messages to fetch from JetStream at once.
+	FetchBatch int `toml:"fetch-batch"`
+	// FetchTimeout is how long to wait for a JetStream fetch to complete.
+	FetchTimeout toml.Duration `toml:"fetch-timeout"`
+
+	// ReconnectWait is the time to wait between reconnect attempts.
+	ReconnectWait toml.Duration `toml:"reconnect-wait"`
+	// MaxRetryWait is the longest time to wait before the JetStream messages are redelivered after a write failed.
+	// The wait starts at ReconnectWait and doubles with each consecutive failure.
+	MaxRetryWait toml.Duration `toml:"max-retry-wait"`
+
+	// Precision of the timestamps of the received line protocol.
+	Precision       string `toml:"precision"`
+	Database        string `toml:"database"`
+	RetentionPolicy string `toml:"retention-policy"`
+}
+
+func NewConfig() Config {
+	return Config{
+		URL:           DefaultURL,
+		FetchBatch:    DefaultFetchBatch,
+		FetchTimeout:  toml.Duration(DefaultFetchTimeout),
+		ReconnectWait: toml.Duration(DefaultReconnectWait),
+		MaxRetryWait:  toml.Duration(DefaultMaxRetryWait),
+		Precision:     DefaultPrecision,
+	}
+}
+
+// WithDefaults takes the given config and returns a new config with any required
+// default values set.
+func (c Config) WithDefaults() Config {
+	d := c
+	if d.URL == "" {
+		d.URL = DefaultURL
+	}
+	if d.FetchBatch <= 0 {
+		d.FetchBatch = DefaultFetchBatch
+	}
+	if d.FetchTimeout <= 0 {
+		d.FetchTimeout = toml.Duration(DefaultFetchTimeout)
+	}
+	if d.ReconnectWait <= 0 {
+		d.ReconnectWait = toml.Duration(DefaultReconnectWait)
+	}
+	if d.MaxRetryWait <= 0 {
+		d.MaxRetryWait = toml.Duration(DefaultMaxRetryWait)
+	}
+	if d.Precision == "" {
+		d.Precision = DefaultPrecision
+	}
+	if d.Durable == "" {
+		d.Durable = d.Name
+	}
+	return d
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Name == "" {
+		return errors.New("must specify a name")
+	}
+	if c.Database == "" {
+		return errors.New("must specify a database")
+	}
+	if c.URL != "" {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return errors.Wrapf(err, "invalid url %q", c.URL)
+		}
+		switch u.Scheme {
+		case "nats", "tls":
+		default:
+			return fmt.Errorf("invalid url scheme %q, must be one of nats or tls", u.Scheme)
+		}
+	}
+	if c.Stream == "" && len(c.Subjects) == 0 {
+		return errors.New("must specify at least one subject or a stream")
+	}
+	if c.Stream != "" && len(c.Subjects) != 0 {
+		return errors.New("cannot specify both subjects and a stream")
+	}
+	switch c.Precision {
+	case "", "n", "ns", "u", "ms", "s", "m", "h":
+	default:
+		return fmt.Errorf("invalid precision %q", c.Precision)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/server/vars"
+	"github.com/influxdata/kapacitor/tlsconfig"
+	natsio "github.com/nats-io/nats.go"
+)
+
+// statistics gathered by the NATS package.
+const (
+	statMessagesReceived  = "messages_rx"
+	statBytesReceived     = "bytes_rx"
+	statPointsParseFail   = "points_parse_fail"
+	statPointsTransmitted = "points_tx"
+	statTransmitFail      = "tx_fail"
+	statReconnects        = "reconnects"
+)
+
+type Diagnostic interface {
+	Error(msg string, err error, ctx ...keyvalue.T)
+	Connected(url, serverID string)
+	Subscribed(subject string)
+	ConsumerReady(stream, durable string)
+	ClosedService()
+}
+
+// Service subscribes to NATS subjects or a JetStream stream
+// and writes the received line protocol as points.
+type Service struct {
+	config Config
+
+	PointsWriter interface {
+		WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+	}
+
+	conn    *natsio.Conn
+	closing chan struct{}
+	wg      sync.WaitGroup
+
+	Diag    Diagnostic
+	statMap *expvar.Map
+	statKey string
+}
+
+func NewService(c Config, d Diagnostic) *Service {
+	return &Service{
+		config: c.WithDefaults(),
+		Diag:   d,
+	}
+}
+
+func (s *Service) Open() error {
+	if s.config.Database == "" {
+		return errors.New("database has to be specified in config")
+	}
+	if s.config.Stream == "" && len(s.config.Subjects) == 0 {
+		return errors.New("subjects or stream has to be specified in config")
+	}
+
+	tags := map[string]string{"name": s.config.Name}
+	s.statKey, s.statMap = vars.NewStatistic("nats", tags)
+
+	conn, err := s.connect()
+	if err != nil {
+		vars.DeleteStatistic(s.statKey)
+		return err
+	}
+	s.conn = conn
+	s.closing = make(chan struct{})
+
+	if s.config.Stream != "" {
+		s.wg.Add(1)
+		go s.consumeStream()
+		return nil
+	}
+	for _, subject := range s.config.Subjects {
+		// Each subscription delivers its messages in order from its own goroutine.
+		handler := func(m *natsio.Msg) { s.handle(m) }
+		if s.config.QueueGroup != "" {
+			_, err = conn.QueueSubscribe(subject, s.config.QueueGroup, handler)
+		} else {
+			_, err = conn.Subscribe(subject, handler)
+		}
+		if err != nil {
+			conn.Close()
+			vars.DeleteStatistic(s.statKey)
+			return err
+		}
+		s.Diag.Subscribed(subject)
+	}
+	return nil
+}
+
+func (s *Service) Close() error {
+	if s.closing == nil {
+		return errors.New("Service already closed")
+	}
+	close(s.closing)
+	// Closing the connection interrupts a pending fetch.
+	s.conn.Close()
+	s.wg.Wait()
+
+	vars.DeleteStatistic(s.statKey)
+	s.closing = nil
+	s.Diag.ClosedService()
+	return nil
+}
+
+// connect creates the connection to the server.
+// The connection is retried in the background if the server is unavailable,
+// and subscriptions are restored whenever the client reconnects.
+func (s *Service) connect() (*natsio.Conn, error) {
+	opts := []natsio.Option{
+		natsio.Name("kapacitor-" + s.config.Name),
+		natsio.ReconnectWait(time.Duration(s.config.ReconnectWait)),
+		natsio.MaxReconnects(-1),
+		natsio.RetryOnFailedConnect(true),
+		natsio.DisconnectErrHandler(func(_ *natsio.Conn, err error) {
+			if err != nil {
+				s.Diag.Error("lost connection to NATS server", err, keyvalue.KV("url", s.config.URL))
+			}
+		}),
+		natsio.ReconnectHandler(func(c *natsio.Conn) {
+			s.statMap.Add(statReconnects, 1)
+			s.Diag.Connected(c.ConnectedUrl(), c.ConnectedServerId())
+		}),
+		natsio.ErrorHandler(func(_ *natsio.Conn, _ *natsio.Subscription, err error) {
+			s.Diag.Error("NATS error", err)
+		}),
+	}
+	if s.config.Username != "" {
+		opts = append(opts, natsio.UserInfo(s.config.Username, s.config.Password))
+	}
+	if s.config.Token != "" {
+		opts = append(opts, natsio.Token(s.config.Token))
+	}
+	u, err := url.Parse(s.config.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "tls" || s.config.SSLCA != "" || s.config.SSLCert != "" {
+		t, err := tlsconfig.Create(s.config.SSLCA, s.config.SSLCert, s.config.SSLKey, s.config.InsecureSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, natsio.Secure(t))
+	}
+	conn, err := natsio.Connect(s.config.URL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if conn.IsConnected() {
+		s.Diag.Connected(conn.ConnectedUrl(), conn.ConnectedServerId())
+	}
+	return conn, nil
+}
+
+// consumeStream fetches messages from a durable JetStream pull consumer until the service is closed.
+func (s *Service) consumeStream() {
+	defer s.wg.Done()
+	wait := time.Duration(s.config.ReconnectWait)
+
+	var sub *natsio.Subscription
+	for sub == nil {
+		js, err := s.conn.JetStream()
+		if err == nil {
+			sub, err = js.PullSubscribe(
+				s.config.FilterSubject,
+				s.config.Durable,
+				natsio.BindStream(s.config.Stream),
+				natsio.AckExplicit(),
+				natsio.DeliverAll(),
+			)
+		}
+		if err != nil {
+			s.Diag.Error("failed to create JetStream consumer", err, keyvalue.KV("stream", s.config.Stream))
+			select {
+			case <-s.closing:
+				return
+			case <-time.After(wait):
+				continue
+			}
+		}
+	}
+	s.Diag.ConsumerReady(s.config.Stream, s.config.Durable)
+
+	// retry is how long to wait before the messages are redelivered after a write failed,
+	// it doubles with each consecutive failure so that a writer that is down is not retried in a tight loop.
+	retry := wait
+	for {
+		select {
+		case <-s.closing:
+			return
+		default:
+		}
+		msgs, err := sub.Fetch(s.config.FetchBatch, natsio.MaxWait(time.Duration(s.config.FetchTimeout)))
+		select {
+		case <-s.closing:
+			return
+		default:
+		}
+		failed := false
+		for _, m := range msgs {
+			// Only acknowledge messages once they have been handled,
+			// so that data is redelivered if the write fails or we restart.
+			// The messages after a failed write are not handled,
+			// they are redelivered in order with it.
+			if !failed && s.handle(m) {
+				if err := m.Ack(); err != nil {
+					s.Diag.Error("failed to ack message", err)
+				}
+				continue
+			}
+			failed = true
+			if err := m.Nak(); err != nil {
+				s.Diag.Error("failed to nak message", err)
+			}
+		}
+		if failed {
+			// Nothing is fetched while waiting, so the messages are only redelivered once the wait is over.
+			select {
+			case <-s.closing:
+				return
+			case <-time.After(retry):
+			}
+			if retry *= 2; retry > time.Duration(s.config.MaxRetryWait) {
+				retry = time.Duration(s.config.MaxRetryWait)
+			}
+			continue
+		}
+		if len(msgs) > 0 {
+			retry = wait
+		}
+		if err != nil && !isFetchTimeout(err) {
+			s.Diag.Error("failed to fetch messages", err, keyvalue.KV("stream", s.config.Stream))
+			select {
+			case <-s.closing:
+				return
+			case <-time.After(wait):
+			}
+		}
+	}
+}
+
+// isFetchTimeout reports whether the error only means that no messages were available before the fetch expired,
+// either locally or as signaled by the server.
+func isFetchTimeout(err error) bool {
+	return err == natsio.ErrTimeout || err == context.DeadlineExceeded || strings.Contains(err.Error(), "Request Timeout")
+}
+
+// handle parses and writes the message, returning false if the write should be retried.
+// Messages that cannot be parsed are dropped.
+func (s *Service) handle(m *natsio.Msg) bool {
+	s.statMap.Add(statMessagesReceived, 1)
+	s.statMap.Add(statBytesReceived, int64(len(m.Data)))
+
+	points, err := models.ParsePointsWithPrecision(m.Data, time.Now().UTC(), s.config.Precision)
+	if err != nil {
+		s.statMap.Add(statPointsParseFail, 1)
+		s.Diag.Error("failed to parse points", err, keyvalue.KV("subject", m.Subject))
+		return true
+	}
+	if err := s.PointsWriter.WritePoints(
+		s.config.Database,
+		s.config.RetentionPolicy,
+		models.ConsistencyLevelAll,
+		points,
+	); err != nil {
+		s.statMap.Add(statTransmitFail, 1)
+		s.Diag.Error("failed to write points to database", err, keyvalue.KV("database", s.config.Database))
+		return false
+	}
+	s.statMap.Add(statPointsTransmitted, int64(len(points)))
+	return true
+}
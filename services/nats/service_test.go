@@ -0,0 +1,387 @@
+package nats_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/services/nats"
+)
+
+func TestService_Subjects(t *testing.T) {
+	srv := newTestServer(t, func(s *testServer, op, args string) {
+		if op == "SUB" {
+			fields := strings.Fields(args)
+			body := "cpu value=1 1000000000"
+			s.send(fmt.Sprintf("MSG %s %s %d\r\n%s\r\n", fields[0], fields[1], len(body), body))
+		}
+	})
+	defer srv.Close()
+
+	c := nats.NewConfig()
+	c.Enabled = true
+	c.Name = "test"
+	c.URL = "nats://" + srv.Addr()
+	c.Subjects = []string{"metrics.cpu"}
+	c.Database = "db"
+	c.RetentionPolicy = "rp"
+
+	pw := newPointsWriter()
+	s := nats.NewService(c, diag{})
+	s.PointsWriter = pw
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	p := pw.wait(t)
+	if got, exp := p.String(), "cpu value=1 1000000000"; got != exp {
+		t.Errorf("unexpected point: got %q exp %q", got, exp)
+	}
+	if srv.sid("metrics.cpu") == "" {
+		t.Error("expected subscription to subject")
+	}
+}
+
+func TestService_Stream(t *testing.T) {
+	srv := newTestServer(t, nil)
+	srv.handler = func(s *testServer, op, args string) {
+		if op != "PUB" {
+			return
+		}
+		fields := strings.Fields(args)
+		subject := fields[0]
+		switch {
+		case subject == "$JS.API.INFO":
+			s.reply(fields[1], `{"memory":0,"storage":0,"streams":1,"consumers":0}`)
+		case subject == "$JS.API.CONSUMER.INFO.METRICS.kap":
+			s.reply(fields[1], `{"error":{"code":404,"description":"consumer not found"}}`)
+		case subject == "$JS.API.CONSUMER.DURABLE.CREATE.METRICS.kap":
+			s.reply(fields[1], `{"stream_name":"METRICS","name":"kap","config":{"durable_name":"kap","ack_policy":"explicit"}}`)
+		case subject == "$JS.API.CONSUMER.MSG.NEXT.METRICS.kap":
+			// Only answer requests that do not wait for messages,
+			// so that the following requests expire on the client.
+			if !strings.Contains(s.payload, `"no_wait":true`) {
+				return
+			}
+			if s.fetches++; s.fetches == 1 {
+				body := "cpu value=2 2000000000"
+				s.send(fmt.Sprintf("MSG metrics.cpu %s $JS.ACK.METRICS.kap.1.1.1.0.0 %d\r\n%s\r\n", s.sid(fields[1]), len(body), body))
+				s.status(fields[1], "408 Request Timeout")
+				return
+			}
+			s.status(fields[1], "404 No Messages")
+		}
+	}
+	defer srv.Close()
+
+	c := nats.NewConfig()
+	c.Enabled = true
+	c.Name = "test"
+	c.URL = "nats://" + srv.Addr()
+	c.Stream = "METRICS"
+	c.Durable = "kap"
+	c.Database = "db"
+	c.FetchTimeout = toml.Duration(100 * time.Millisecond)
+
+	pw := newPointsWriter()
+	s := nats.NewService(c, diag{})
+	s.PointsWriter = pw
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	p := pw.wait(t)
+	if got, exp := p.String(), "cpu value=2 2000000000"; got != exp {
+		t.Errorf("unexpected point: got %q exp %q", got, exp)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !srv.received("PUB $JS.ACK.METRICS.kap.1.1.1.0.0 4") {
+		if time.Now().After(deadline) {
+			t.Fatal("expected message to be acknowledged")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestService_StreamRetryBackoff(t *testing.T) {
+	acked := false
+	srv := newTestServer(t, nil)
+	srv.handler = func(s *testServer, op, args string) {
+		if op != "PUB" {
+			return
+		}
+		fields := strings.Fields(args)
+		subject := fields[0]
+		switch {
+		case subject == "$JS.API.INFO":
+			s.reply(fields[1], `{"memory":0,"storage":0,"streams":1,"consumers":0}`)
+		case subject == "$JS.API.CONSUMER.INFO.METRICS.kap":
+			s.reply(fields[1], `{"error":{"code":404,"description":"consumer not found"}}`)
+		case subject == "$JS.API.CONSUMER.DURABLE.CREATE.METRICS.kap":
+			s.reply(fields[1], `{"stream_name":"METRICS","name":"kap","config":{"durable_name":"kap","ack_policy":"explicit"}}`)
+		case strings.HasPrefix(subject, "$JS.ACK."):
+			acked = strings.HasPrefix(s.payload, "+ACK")
+		case subject == "$JS.API.CONSUMER.MSG.NEXT.METRICS.kap":
+			if !strings.Contains(s.payload, `"no_wait":true`) {
+				return
+			}
+			// The message is redelivered on every fetch until it is acknowledged.
+			if !acked {
+				body := "cpu value=3 3000000000"
+				s.send(fmt.Sprintf("MSG metrics.cpu %s $JS.ACK.METRICS.kap.1.1.1.0.0 %d\r\n%s\r\n", s.sid(fields[1]), len(body), body))
+				s.status(fields[1], "408 Request Timeout")
+				return
+			}
+			s.status(fields[1], "404 No Messages")
+		}
+	}
+	defer srv.Close()
+
+	c := nats.NewConfig()
+	c.Enabled = true
+	c.Name = "test"
+	c.URL = "nats://" + srv.Addr()
+	c.Stream = "METRICS"
+	c.Durable = "kap"
+	c.Database = "db"
+	c.FetchTimeout = toml.Duration(100 * time.Millisecond)
+	c.ReconnectWait = toml.Duration(100 * time.Millisecond)
+	c.MaxRetryWait = toml.Duration(time.Second)
+
+	pw := &failingPointsWriter{failures: 3, points: make(chan models.Point, 10)}
+	s := nats.NewService(c, diag{})
+	s.PointsWriter = pw
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	select {
+	case <-pw.points:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for point")
+	}
+	// The waits between the writes double: 100ms, 200ms and 400ms.
+	writes := pw.Writes()
+	if len(writes) != 4 {
+		t.Fatalf("unexpected number of writes got %d exp 4", len(writes))
+	}
+	for i, exp := range []time.Duration{100, 200, 400} {
+		if got := writes[i+1].Sub(writes[i]); got < exp*time.Millisecond {
+			t.Errorf("write %d retried after %v, expected at least %v", i+1, got, exp*time.Millisecond)
+		}
+	}
+}
+
+type testServer struct {
+	t       *testing.T
+	l       net.Listener
+	handler func(s *testServer, op, args string)
+
+	mu      sync.Mutex
+	conn    net.Conn
+	lines   []string
+	subs    []testSub
+	payload string
+	fetches int
+}
+
+type testSub struct {
+	subject string
+	sid     string
+}
+
+func newTestServer(t *testing.T, h func(s *testServer, op, args string)) *testServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{
+		t:       t,
+		l:       l,
+		handler: h,
+	}
+	go s.serve()
+	return s
+}
+
+func (s *testServer) Addr() string {
+	return s.l.Addr().String()
+}
+
+func (s *testServer) Close() {
+	s.l.Close()
+	s.mu.Lock()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.mu.Unlock()
+}
+
+func (s *testServer) serve() {
+	conn, err := s.l.Accept()
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+	s.send(`INFO {"server_id":"test","version":"2.9.0","proto":1,"headers":true,"max_payload":1048576}` + "\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		i := strings.Index(line, " ")
+		op, args := line, ""
+		if i > 0 {
+			op, args = line[:i], line[i+1:]
+		}
+		s.mu.Lock()
+		s.lines = append(s.lines, line)
+		s.mu.Unlock()
+		switch op {
+		case "PING":
+			s.send("PONG\r\n")
+		case "SUB":
+			fields := strings.Fields(args)
+			s.mu.Lock()
+			s.subs = append(s.subs, testSub{subject: fields[0], sid: fields[len(fields)-1]})
+			s.mu.Unlock()
+		case "PUB":
+			payload, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.payload = payload
+			s.mu.Unlock()
+		}
+		if s.handler != nil {
+			s.handler(s, op, args)
+		}
+	}
+}
+
+// sid returns the ID of the subscription matching the subject, which may have wildcards.
+func (s *testServer) sid(subject string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens := strings.Split(subject, ".")
+	for _, sub := range s.subs {
+		pattern := strings.Split(sub.subject, ".")
+		match := len(pattern) == len(tokens) || pattern[len(pattern)-1] == ">"
+		for i := 0; match && i < len(pattern) && i < len(tokens); i++ {
+			if pattern[i] == ">" {
+				break
+			}
+			match = pattern[i] == "*" || pattern[i] == tokens[i]
+		}
+		if match {
+			return sub.sid
+		}
+	}
+	return ""
+}
+
+// reply sends the body to the subscription of the reply subject.
+func (s *testServer) reply(subject, body string) {
+	s.send(fmt.Sprintf("MSG %s %s %d\r\n%s\r\n", subject, s.sid(subject), len(body), body))
+}
+
+// status sends a message without payload with a status header to the subscription of the reply subject.
+func (s *testServer) status(subject, status string) {
+	hdr := "NATS/1.0 " + status + "\r\n\r\n"
+	s.send(fmt.Sprintf("HMSG %s %s %d %d\r\n%s\r\n", subject, s.sid(subject), len(hdr), len(hdr), hdr))
+}
+
+func (s *testServer) send(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		s.t.Log(err)
+	}
+}
+
+func (s *testServer) received(line string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range s.lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+type pointsWriter struct {
+	points chan models.Point
+}
+
+func newPointsWriter() *pointsWriter {
+	return &pointsWriter{points: make(chan models.Point, 10)}
+}
+
+func (pw *pointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	for _, p := range points {
+		pw.points <- p
+	}
+	return nil
+}
+
+func (pw *pointsWriter) wait(t *testing.T) models.Point {
+	select {
+	case p := <-pw.points:
+		return p
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for point")
+	}
+	return nil
+}
+
+// failingPointsWriter fails the first writes, and records the time of every write.
+type failingPointsWriter struct {
+	mu       sync.Mutex
+	failures int
+	writes   []time.Time
+	points   chan models.Point
+}
+
+func (pw *failingPointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	pw.writes = append(pw.writes, time.Now())
+	if len(pw.writes) <= pw.failures {
+		return errors.New("writer is down")
+	}
+	for _, p := range points {
+		pw.points <- p
+	}
+	return nil
+}
+
+func (pw *failingPointsWriter) Writes() []time.Time {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	return pw.writes
+}
+
+type diag struct{}
+
+func (diag) Error(msg string, err error, ctx ...keyvalue.T) {}
+func (diag) Connected(url, serverID string)                 {}
+func (diag) Subscribed(subject string)                      {}
+func (diag) ConsumerReady(stream, durable string)           {}
+func (diag) ClosedService()                                 {}
@@ -47,14 +47,14 @@ func (s *Service) SubscriptionUser(token string) (auth.User, error) {
 	return auth.NewUser("subscription-user", nil, true, nil), nil
 }
 
-func (s *Service) GrantSubscriptionAccess(token, db, rp string) error {
+func (s *Service) GrantSubscriptionAccess(cluster, token, db, rp string) error {
 	return nil
 }
 
-func (s *Service) ListSubscriptionTokens() ([]string, error) {
+func (s *Service) ListSubscriptionTokens() ([]auth.SubscriptionToken, error) {
 	return nil, nil
 }
 
-func (s *Service) RevokeSubscriptionAccess(token string) error {
+func (s *Service) RevokeSubscriptionAccess(hash string) error {
 	return nil
 }
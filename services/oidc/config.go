@@ -0,0 +1,73 @@
+package oidc
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultScopesClaim = "scope"
+	// Minimum time between refreshes of the signing keys of the provider,
+	// when a token is signed with an unknown key.
+	DefaultKeysRefreshInterval = time.Minute
+)
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+	// URL of the OpenID Connect provider, the issuer of the tokens.
+	Issuer string `toml:"issuer"`
+	// ID of the Kapacitor client registered with the provider.
+	// Tokens must have it as their audience, unless they have one of the Audiences.
+	ClientID string `toml:"client-id"`
+	// Additional audiences accepted for access tokens.
+	Audiences []string `toml:"audiences"`
+	// Claim holding the scopes granted by the token.
+	ScopesClaim string `toml:"scopes-claim"`
+	// Scopes requested by the CLI when logging in.
+	Scopes []string `toml:"scopes"`
+	// Minimum time between refreshes of the signing keys of the provider.
+	KeysRefreshInterval toml.Duration `toml:"keys-refresh-interval"`
+	// Do not verify the certificate of the provider.
+	InsecureSkipVerify bool `toml:"insecure-skip-verify"`
+	// Local users by token subject.
+	// A token whose subject is mapped is granted the permissions of the local user in addition to its scopes,
+	// other tokens are only granted their scopes.
+	Users map[string]string `toml:"users"`
+}
+
+func NewConfig() Config {
+	return Config{
+		ScopesClaim:         DefaultScopesClaim,
+		Scopes:              []string{"openid", "profile"},
+		KeysRefreshInterval: toml.Duration(DefaultKeysRefreshInterval),
+	}
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Issuer == "" {
+		return errors.New("must specify issuer")
+	}
+	if u, err := url.Parse(c.Issuer); err != nil {
+		return errors.Wrapf(err, "invalid issuer %q", c.Issuer)
+	} else if u.Scheme != "https" && u.Scheme != "http" {
+		return errors.Errorf("invalid issuer %q, must be an http(s) URL", c.Issuer)
+	}
+	if c.ClientID == "" && len(c.Audiences) == 0 {
+		return errors.New("must specify client-id or audiences")
+	}
+	for sub, user := range c.Users {
+		if sub == "" || user == "" {
+			return errors.Errorf("invalid user mapping %q = %q, subject and user must not be empty", sub, user)
+		}
+	}
+	if c.KeysRefreshInterval < 0 {
+		return errors.New("keys-refresh-interval must not be negative")
+	}
+	return nil
+}
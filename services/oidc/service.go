@@ -0,0 +1,243 @@
+// Package oidc verifies the bearer tokens issued to API users by an OpenID Connect provider.
+//
+// Tokens are JWTs signed with the RSA keys published by the provider, they are verified by go-oidc.
+// The scopes claim of a token grants API scopes, e.g. for automation using the client credentials flow.
+// Tokens are only granted the permissions of a local user if their subject is explicitly mapped to it.
+package oidc
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/jose"
+	"github.com/coreos/go-oidc/key"
+	"github.com/coreos/go-oidc/oidc"
+	"github.com/influxdata/kapacitor/auth"
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/pkg/errors"
+)
+
+const (
+	oidcPath = "/oidc"
+)
+
+type Diagnostic interface {
+	Error(msg string, err error)
+}
+
+type Service struct {
+	config     Config
+	diag       Diagnostic
+	httpClient *http.Client
+	routes     []httpd.Route
+
+	mu sync.Mutex
+	// Signing keys of the provider.
+	keys        []key.PublicKey
+	lastRefresh time.Time
+
+	HTTPDService interface {
+		AddRoutes([]httpd.Route) error
+		DelRoutes([]httpd.Route)
+	}
+}
+
+func NewService(c Config, d Diagnostic) *Service {
+	return &Service{
+		config: c,
+		diag:   d,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: c.InsecureSkipVerify,
+				},
+			},
+		},
+	}
+}
+
+func (s *Service) Open() error {
+	// The provider settings are public so that clients can log in before authenticating.
+	s.routes = []httpd.Route{
+		{
+			Method:      "GET",
+			Pattern:     oidcPath,
+			HandlerFunc: s.handleConfig,
+			BypassAuth:  true,
+		},
+	}
+	if err := s.HTTPDService.AddRoutes(s.routes); err != nil {
+		return errors.Wrap(err, "failed to add API routes")
+	}
+	// The provider may not be reachable yet, the keys are refreshed when verifying tokens.
+	if err := s.refreshKeys(); err != nil {
+		s.diag.Error("failed to retrieve signing keys of the OpenID Connect provider", err)
+	}
+	return nil
+}
+
+func (s *Service) Close() error {
+	if s.HTTPDService != nil {
+		s.HTTPDService.DelRoutes(s.routes)
+	}
+	return nil
+}
+
+func (s *Service) handleConfig(w http.ResponseWriter, r *http.Request) {
+	scopes := s.config.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	w.Write(httpd.MarshalJSON(client.OIDCConfig{
+		Issuer:   s.config.Issuer,
+		ClientID: s.config.ClientID,
+		Scopes:   scopes,
+	}, true))
+}
+
+// VerifyToken verifies that the token was issued by the provider for Kapacitor,
+// and returns its subject, the local user the subject is mapped to, if any, and the scopes it grants.
+func (s *Service) VerifyToken(token string) (string, string, []auth.Scope, error) {
+	jwt, err := jose.ParseJWT(token)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if alg := jwt.Header[jose.HeaderKeyAlgorithm]; alg != jose.AlgRS256 {
+		return "", "", nil, fmt.Errorf("unexpected signing method: %v", alg)
+	}
+	claims, err := jwt.Claims()
+	if err != nil {
+		return "", "", nil, err
+	}
+	aud, ok := s.audience(claims)
+	if !ok {
+		return "", "", nil, errors.New("token was not issued for Kapacitor")
+	}
+	// The verifier checks the issuer, audience, expiration and signature of the token.
+	kid, _ := jwt.KeyID()
+	verifier := oidc.NewJWTVerifier(s.config.Issuer, aud, s.syncKeys, func() []key.PublicKey { return s.publicKeys(kid) })
+	if err := verifier.Verify(jwt); err != nil {
+		return "", "", nil, err
+	}
+	sub, _, err := claims.StringClaim("sub")
+	if err != nil || sub == "" {
+		return "", "", nil, errors.New("token must identify its subject")
+	}
+	return sub, s.config.Users[sub], scopes(claims[s.config.ScopesClaim]), nil
+}
+
+// audience returns the accepted audience of the claims.
+func (s *Service) audience(claims jose.Claims) (string, bool) {
+	auds, ok, err := claims.StringsClaim("aud")
+	if err != nil || !ok {
+		aud, _, _ := claims.StringClaim("aud")
+		auds = []string{aud}
+	}
+	for _, a := range auds {
+		if s.config.ClientID != "" && a == s.config.ClientID {
+			return a, true
+		}
+		for _, exp := range s.config.Audiences {
+			if a == exp {
+				return a, true
+			}
+		}
+	}
+	return "", false
+}
+
+// scopes returns the known API scopes of a scopes claim,
+// which is either a space separated string or a list of strings.
+func scopes(claim interface{}) []auth.Scope {
+	var names []string
+	switch c := claim.(type) {
+	case string:
+		names = strings.Fields(c)
+	case []interface{}:
+		for _, v := range c {
+			if str, ok := v.(string); ok {
+				names = append(names, str)
+			}
+		}
+	}
+	var scopes []auth.Scope
+	for _, name := range names {
+		if scope := auth.Scope(name); auth.ValidScope(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// publicKeys returns the key of the provider with the ID, or all its keys if it is unknown.
+func (s *Service) publicKeys(kid string) []key.PublicKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range s.keys {
+		if k.ID() == kid {
+			return []key.PublicKey{k}
+		}
+	}
+	return s.keys
+}
+
+// syncKeys refreshes the signing keys when a token is signed with an unknown key,
+// at most once per refresh interval.
+func (s *Service) syncKeys() error {
+	s.mu.Lock()
+	refresh := time.Since(s.lastRefresh) >= time.Duration(s.config.KeysRefreshInterval)
+	s.mu.Unlock()
+	if !refresh {
+		return errors.New("unknown signing key")
+	}
+	return s.refreshKeys()
+}
+
+// refreshKeys retrieves the signing keys published by the provider.
+func (s *Service) refreshKeys() error {
+	s.mu.Lock()
+	s.lastRefresh = time.Now()
+	s.mu.Unlock()
+
+	provider, err := oidc.FetchProviderConfig(s.httpClient, s.config.Issuer)
+	if err != nil {
+		return errors.Wrap(err, "failed to discover provider")
+	}
+	resp, err := s.httpClient.Get(provider.KeysEndpoint.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response code %d from %s", resp.StatusCode, provider.KeysEndpoint)
+	}
+	var jwks jose.JWKSet
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return errors.Wrapf(err, "invalid response from %s", provider.KeysEndpoint)
+	}
+	var keys []key.PublicKey
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// Only RSA keys are supported by the verifier.
+		if k.Type != "RSA" || k.Modulus == nil || k.Modulus.Sign() == 0 || k.Exponent <= 0 {
+			s.diag.Error(fmt.Sprintf("ignoring signing key %q", k.ID), fmt.Errorf("unsupported key type %q", k.Type))
+			continue
+		}
+		keys = append(keys, *key.NewPublicKey(k))
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
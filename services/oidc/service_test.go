@@ -0,0 +1,315 @@
+package oidc_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/auth"
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/diagnostic"
+	"github.com/influxdata/kapacitor/services/httpd/httpdtest"
+	"github.com/influxdata/kapacitor/services/oidc"
+)
+
+var diagService *diagnostic.Service
+
+func init() {
+	diagService = diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	diagService.Open()
+}
+
+// provider is a fake OpenID Connect provider publishing its signing keys.
+type provider struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PrivateKey
+}
+
+func newProvider(t *testing.T) *provider {
+	p := &provider{keys: make(map[string]*rsa.PrivateKey)}
+	p.addKey(t, "key1")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/auth",
+			"token_endpoint":                        p.URL + "/token",
+			"jwks_uri":                              p.URL + "/keys",
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		var keys []map[string]string
+		for kid, key := range p.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n":   jwt.EncodeSegment(key.N.Bytes()),
+				"e":   jwt.EncodeSegment(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	})
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+func (p *provider) addKey(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	p.keys[kid] = key
+	p.mu.Unlock()
+}
+
+func (p *provider) token(t *testing.T, kid string, claims jwt.MapClaims) string {
+	p.mu.Lock()
+	key := p.keys[kid]
+	p.mu.Unlock()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func newService(t *testing.T, p *provider) (*oidc.Service, *httpdtest.Server) {
+	c := oidc.NewConfig()
+	c.Enabled = true
+	c.Issuer = p.URL
+	c.ClientID = "kapacitor"
+	c.Audiences = []string{"https://kapacitor.example.com"}
+	c.KeysRefreshInterval = toml.Duration(0)
+	c.Users = map[string]string{"1234": "bob"}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	s := oidc.NewService(c, diagService.NewOIDCHandler())
+	server := httpdtest.NewServer(testing.Verbose())
+	s.HTTPDService = server
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return s, server
+}
+
+func TestService_VerifyToken(t *testing.T) {
+	p := newProvider(t)
+	defer p.Close()
+	s, server := newService(t, p)
+	defer server.Close()
+	defer s.Close()
+
+	iat := time.Now().Unix()
+	exp := time.Now().Add(time.Minute).Unix()
+	testCases := []struct {
+		name         string
+		kid          string
+		claims       jwt.MapClaims
+		expSubject   string
+		expLocalUser string
+		expScopes    []auth.Scope
+		expErr       bool
+		rotateKeysTo string
+	}{
+		{
+			name: "id token",
+			kid:  "key1",
+			claims: jwt.MapClaims{
+				"iss":                p.URL,
+				"aud":                "kapacitor",
+				"sub":                "1234",
+				"preferred_username": "bob",
+				"iat":                iat,
+				"exp":                exp,
+			},
+			expSubject:   "1234",
+			expLocalUser: "bob",
+		},
+		{
+			name: "unmapped subject named like a local user",
+			kid:  "key1",
+			claims: jwt.MapClaims{
+				"iss":                p.URL,
+				"aud":                "kapacitor",
+				"sub":                "5678",
+				"preferred_username": "admin",
+				"name":               "admin",
+				"iat":                iat,
+				"exp":                exp,
+			},
+			expSubject: "5678",
+		},
+		{
+			name: "missing subject",
+			kid:  "key1",
+			claims: jwt.MapClaims{
+				"iss":                p.URL,
+				"aud":                "kapacitor",
+				"preferred_username": "bob",
+				"iat":                iat,
+				"exp":                exp,
+			},
+			expErr: true,
+		},
+		{
+			name: "client credentials",
+			kid:  "key1",
+			claims: jwt.MapClaims{
+				"iss":   p.URL,
+				"aud":   []string{"https://kapacitor.example.com", "other"},
+				"sub":   "automation",
+				"iat":   iat,
+				"scope": "openid tasks:read alerts:ack unknown",
+				"exp":   exp,
+			},
+			expSubject: "automation",
+			expScopes:  []auth.Scope{auth.TasksReadScope, auth.AlertsAckScope},
+		},
+		{
+			name: "wrong audience",
+			kid:  "key1",
+			claims: jwt.MapClaims{
+				"iss": p.URL,
+				"aud": "other",
+				"sub": "bob",
+				"iat": iat,
+				"exp": exp,
+			},
+			expErr: true,
+		},
+		{
+			name: "wrong issuer",
+			kid:  "key1",
+			claims: jwt.MapClaims{
+				"iss": "https://evil.example.com",
+				"aud": "kapacitor",
+				"sub": "bob",
+				"iat": iat,
+				"exp": exp,
+			},
+			expErr: true,
+		},
+		{
+			name: "expired",
+			kid:  "key1",
+			claims: jwt.MapClaims{
+				"iss": p.URL,
+				"aud": "kapacitor",
+				"sub": "bob",
+				"iat": iat,
+				"exp": time.Now().Add(-time.Minute).Unix(),
+			},
+			expErr: true,
+		},
+		{
+			name: "no expiration",
+			kid:  "key1",
+			claims: jwt.MapClaims{
+				"iss": p.URL,
+				"aud": "kapacitor",
+				"sub": "bob",
+				"iat": iat,
+			},
+			expErr: true,
+		},
+		{
+			name: "rotated key",
+			kid:  "key2",
+			claims: jwt.MapClaims{
+				"iss": p.URL,
+				"aud": "kapacitor",
+				"sub": "bob",
+				"iat": iat,
+				"exp": exp,
+			},
+			expSubject:   "bob",
+			rotateKeysTo: "key2",
+		},
+	}
+	for _, tc := range testCases {
+		if tc.rotateKeysTo != "" {
+			p.addKey(t, tc.rotateKeysTo)
+		}
+		subject, localUser, scopes, err := s.VerifyToken(p.token(t, tc.kid, tc.claims))
+		if tc.expErr {
+			if err == nil {
+				t.Errorf("%s: expected error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if subject != tc.expSubject {
+			t.Errorf("%s: unexpected subject: got %q exp %q", tc.name, subject, tc.expSubject)
+		}
+		if localUser != tc.expLocalUser {
+			t.Errorf("%s: unexpected local user: got %q exp %q", tc.name, localUser, tc.expLocalUser)
+		}
+		if !reflect.DeepEqual(scopes, tc.expScopes) {
+			t.Errorf("%s: unexpected scopes: got %v exp %v", tc.name, scopes, tc.expScopes)
+		}
+	}
+
+	// Tokens signed with the shared secret are not accepted.
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": p.URL,
+		"aud": "kapacitor",
+		"sub": "bob",
+		"iat": iat,
+		"exp": exp,
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := s.VerifyToken(token); err == nil {
+		t.Error("expected error verifying an HMAC token")
+	}
+}
+
+func TestService_Config(t *testing.T) {
+	p := newProvider(t)
+	defer p.Close()
+	s, server := newService(t, p)
+	defer server.Close()
+	defer s.Close()
+
+	cli, err := client.New(client.Config{URL: server.Server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := cli.OIDCConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.OIDCConfig{
+		Issuer:   p.URL,
+		ClientID: "kapacitor",
+		Scopes:   []string{"openid", "profile"},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected config:\ngot\n%v\nexp\n%v", got, exp)
+	}
+}
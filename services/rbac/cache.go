@@ -0,0 +1,82 @@
+package rbac
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"sync"
+	"time"
+)
+
+// saltSize is the size of the random salt of the cached passwords.
+const saltSize = 16
+
+// cachedPassword is a successful password check of a user.
+// It holds a salted SHA-256 hash of the password, never the password itself,
+// and is only valid while the bcrypt hash of the user is the one the password was checked against.
+type cachedPassword struct {
+	bcrypt  []byte
+	salt    []byte
+	hash    []byte
+	expires time.Time
+}
+
+// passwordCache caches the successful password checks by user name,
+// so that bcrypt does not run on every request.
+type passwordCache struct {
+	expiration time.Duration
+
+	mu        sync.Mutex
+	passwords map[string]cachedPassword
+}
+
+func newPasswordCache(expiration time.Duration) *passwordCache {
+	return &passwordCache{
+		expiration: expiration,
+		passwords:  make(map[string]cachedPassword),
+	}
+}
+
+func saltedHash(salt []byte, password string) []byte {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(password))
+	return h.Sum(nil)
+}
+
+// Check reports whether the password of the user was checked against its bcrypt hash recently.
+func (c *passwordCache) Check(u User, password string) bool {
+	c.mu.Lock()
+	p, ok := c.passwords[u.Name]
+	c.mu.Unlock()
+	if !ok || time.Now().After(p.expires) || !bytes.Equal(p.bcrypt, u.Hash) {
+		return false
+	}
+	return subtle.ConstantTimeCompare(saltedHash(p.salt, password), p.hash) == 1
+}
+
+// Add caches the password of the user, once it matched its bcrypt hash.
+func (c *passwordCache) Add(u User, password string) {
+	if c.expiration <= 0 {
+		return
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, p := range c.passwords {
+		if now.After(p.expires) {
+			delete(c.passwords, name)
+		}
+	}
+	c.passwords[u.Name] = cachedPassword{
+		bcrypt:  u.Hash,
+		salt:    salt,
+		hash:    saltedHash(salt, password),
+		expires: now.Add(c.expiration),
+	}
+}
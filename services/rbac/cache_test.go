@@ -0,0 +1,52 @@
+package rbac
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPasswordCache(t *testing.T) {
+	c := newPasswordCache(time.Minute)
+	u := User{Name: "bob", Hash: []byte("hash of secret")}
+	if c.Check(u, "secret") {
+		t.Fatal("unexpected cached password before it was added")
+	}
+	c.Add(u, "secret")
+	if !c.Check(u, "secret") {
+		t.Error("expected the password to be cached")
+	}
+	if c.Check(u, "wrong") {
+		t.Error("unexpected match of the wrong password")
+	}
+	if c.Check(User{Name: "alice", Hash: u.Hash}, "secret") {
+		t.Error("unexpected match of another user")
+	}
+	// Changing the password invalidates the cached password.
+	if c.Check(User{Name: "bob", Hash: []byte("hash of changed")}, "secret") {
+		t.Error("unexpected match after the password changed")
+	}
+	for _, p := range c.passwords {
+		if string(p.hash) == "secret" {
+			t.Error("password cached in plaintext")
+		}
+	}
+}
+
+func TestPasswordCache_Expiration(t *testing.T) {
+	c := newPasswordCache(time.Minute)
+	u := User{Name: "bob", Hash: []byte("hash of secret")}
+	c.Add(u, "secret")
+	p := c.passwords["bob"]
+	p.expires = time.Now().Add(-time.Second)
+	c.passwords["bob"] = p
+	if c.Check(u, "secret") {
+		t.Error("unexpected match of an expired password")
+	}
+
+	// A zero expiration disables the cache.
+	c = newPasswordCache(0)
+	c.Add(u, "secret")
+	if c.Check(u, "secret") {
+		t.Error("unexpected cached password with the cache disabled")
+	}
+}
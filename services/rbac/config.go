@@ -0,0 +1,44 @@
+package rbac
+
+import (
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultCacheExpiration is how long a successful password check is cached.
+	DefaultCacheExpiration = 10 * time.Minute
+)
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+	// Credentials of an admin user created when no users exist,
+	// so that users and roles can be defined through the API.
+	AdminUsername string `toml:"admin-username"`
+	AdminPassword string `toml:"admin-password"`
+	// CacheExpiration is how long a successful password check is cached,
+	// so that the password is not checked against its bcrypt hash on every request.
+	// A zero duration disables the cache.
+	CacheExpiration toml.Duration `toml:"cache-expiration"`
+}
+
+func NewConfig() Config {
+	return Config{
+		CacheExpiration: toml.Duration(DefaultCacheExpiration),
+	}
+}
+
+func (c Config) Validate() error {
+	if c.AdminUsername != "" && c.AdminPassword == "" {
+		return errors.New("must specify admin-password with admin-username")
+	}
+	if c.AdminUsername != "" && !validName.MatchString(c.AdminUsername) {
+		return errors.Errorf("invalid admin-username %q", c.AdminUsername)
+	}
+	if c.CacheExpiration < 0 {
+		return errors.New("cache-expiration must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+package rbac
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/influxdata/kapacitor/services/storage"
+)
+
+var (
+	ErrUserExists   = errors.New("user already exists")
+	ErrNoUserExists = errors.New("no user exists")
+	ErrRoleExists   = errors.New("role already exists")
+	ErrNoRoleExists = errors.New("no role exists")
+)
+
+// Data access object for users.
+type UserDAO interface {
+	// Retrieve a user
+	Get(name string) (User, error)
+
+	// Create a user.
+	// ErrUserExists is returned if a user already exists with the same name.
+	Create(u User) error
+
+	// Replace an existing user.
+	// ErrNoUserExists is returned if the user does not exist.
+	Replace(u User) error
+
+	// Delete a user.
+	// It is not an error to delete an non-existent user.
+	Delete(name string) error
+
+	// List users matching a pattern.
+	// The pattern is shell/glob matching see https://golang.org/pkg/path/#Match
+	// Offset and limit are pagination bounds. Offset is inclusive starting at index 0.
+	// More results may exist while the number of returned items is equal to limit.
+	List(pattern string, offset, limit int) ([]User, error)
+
+	Rebuild() error
+}
+
+// Data access object for roles.
+type RoleDAO interface {
+	// Retrieve a role
+	Get(name string) (Role, error)
+
+	// Create a role.
+	// ErrRoleExists is returned if a role already exists with the same name.
+	Create(r Role) error
+
+	// Replace an existing role.
+	// ErrNoRoleExists is returned if the role does not exist.
+	Replace(r Role) error
+
+	// Delete a role.
+	// It is not an error to delete an non-existent role.
+	Delete(name string) error
+
+	// List roles matching a pattern.
+	// The pattern is shell/glob matching see https://golang.org/pkg/path/#Match
+	// Offset and limit are pagination bounds. Offset is inclusive starting at index 0.
+	// More results may exist while the number of returned items is equal to limit.
+	List(pattern string, offset, limit int) ([]Role, error)
+
+	Rebuild() error
+}
+
+// Data access object for the tokens of InfluxDB subscriptions, by the hash of the token.
+type SubscriptionDAO interface {
+	Get(hash string) (Subscription, error)
+	Put(s Subscription) error
+	Delete(hash string) error
+	List() ([]Subscription, error)
+
+	Rebuild() error
+}
+
+//--------------------------------------------------------------------
+// The following structures are stored in a database via JSON encoding.
+// Changes to the structures could break existing data.
+
+// version is the current version of the stored structures.
+const version = 1
+
+type User struct {
+	Name string `json:"name"`
+	// Bcrypt hash of the password
+	Hash  []byte   `json:"hash"`
+	Admin bool     `json:"admin"`
+	Roles []string `json:"roles"`
+}
+
+func (u User) ObjectID() string {
+	return u.Name
+}
+
+func (u User) MarshalBinary() ([]byte, error) {
+	return storage.VersionJSONEncode(version, u)
+}
+
+func (u *User) UnmarshalBinary(data []byte) error {
+	return storage.VersionJSONDecode(data, func(version int, dec *json.Decoder) error {
+		return dec.Decode(u)
+	})
+}
+
+type Role struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+func (r Role) ObjectID() string {
+	return r.Name
+}
+
+func (r Role) MarshalBinary() ([]byte, error) {
+	return storage.VersionJSONEncode(version, r)
+}
+
+func (r *Role) UnmarshalBinary(data []byte) error {
+	return storage.VersionJSONDecode(data, func(version int, dec *json.Decoder) error {
+		return dec.Decode(r)
+	})
+}
+
+type Subscription struct {
+	// Token in plaintext, it is only set by the versions storing the tokens themselves,
+	// before they are replaced by their hash.
+	Token string `json:"token,omitempty"`
+	// SHA-256 hash of the token
+	Hash string `json:"hash"`
+	// Cluster is the name of the InfluxDB cluster of the subscription.
+	Cluster         string `json:"cluster"`
+	Database        string `json:"db"`
+	RetentionPolicy string `json:"rp"`
+}
+
+func (s Subscription) ObjectID() string {
+	// The subscriptions stored before the tokens were hashed are identified by their token.
+	if s.Hash == "" {
+		return s.Token
+	}
+	return s.Hash
+}
+
+func (s Subscription) MarshalBinary() ([]byte, error) {
+	return storage.VersionJSONEncode(version, s)
+}
+
+func (s *Subscription) UnmarshalBinary(data []byte) error {
+	return storage.VersionJSONDecode(data, func(version int, dec *json.Decoder) error {
+		return dec.Decode(s)
+	})
+}
+
+// Key/Value store based implementation of the UserDAO
+type userKV struct {
+	store *storage.IndexedStore
+}
+
+func newUserKV(store storage.Interface) (*userKV, error) {
+	c := storage.DefaultIndexedStoreConfig("users", func() storage.BinaryObject {
+		return new(User)
+	})
+	istore, err := storage.NewIndexedStore(store, c)
+	if err != nil {
+		return nil, err
+	}
+	return &userKV{
+		store: istore,
+	}, nil
+}
+
+func (kv *userKV) error(err error) error {
+	if err == storage.ErrNoObjectExists {
+		return ErrNoUserExists
+	} else if err == storage.ErrObjectExists {
+		return ErrUserExists
+	}
+	return err
+}
+
+func (kv *userKV) Get(name string) (User, error) {
+	obj, err := kv.store.Get(name)
+	if err != nil {
+		return User{}, kv.error(err)
+	}
+	u, ok := obj.(*User)
+	if !ok {
+		return User{}, storage.ImpossibleTypeErr(u, obj)
+	}
+	return *u, nil
+}
+
+func (kv *userKV) Create(u User) error {
+	return kv.error(kv.store.Create(&u))
+}
+
+func (kv *userKV) Replace(u User) error {
+	return kv.error(kv.store.Replace(&u))
+}
+
+func (kv *userKV) Delete(name string) error {
+	return kv.error(kv.store.Delete(name))
+}
+
+func (kv *userKV) List(pattern string, offset, limit int) ([]User, error) {
+	objects, err := kv.store.List(storage.DefaultIDIndex, pattern, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]User, len(objects))
+	for i, object := range objects {
+		u, ok := object.(*User)
+		if !ok {
+			return nil, storage.ImpossibleTypeErr(u, object)
+		}
+		users[i] = *u
+	}
+	return users, nil
+}
+
+func (kv *userKV) Rebuild() error {
+	return kv.store.Rebuild()
+}
+
+// Key/Value store based implementation of the RoleDAO
+type roleKV struct {
+	store *storage.IndexedStore
+}
+
+func newRoleKV(store storage.Interface) (*roleKV, error) {
+	c := storage.DefaultIndexedStoreConfig("roles", func() storage.BinaryObject {
+		return new(Role)
+	})
+	istore, err := storage.NewIndexedStore(store, c)
+	if err != nil {
+		return nil, err
+	}
+	return &roleKV{
+		store: istore,
+	}, nil
+}
+
+func (kv *roleKV) error(err error) error {
+	if err == storage.ErrNoObjectExists {
+		return ErrNoRoleExists
+	} else if err == storage.ErrObjectExists {
+		return ErrRoleExists
+	}
+	return err
+}
+
+func (kv *roleKV) Get(name string) (Role, error) {
+	obj, err := kv.store.Get(name)
+	if err != nil {
+		return Role{}, kv.error(err)
+	}
+	r, ok := obj.(*Role)
+	if !ok {
+		return Role{}, storage.ImpossibleTypeErr(r, obj)
+	}
+	return *r, nil
+}
+
+func (kv *roleKV) Create(r Role) error {
+	return kv.error(kv.store.Create(&r))
+}
+
+func (kv *roleKV) Replace(r Role) error {
+	return kv.error(kv.store.Replace(&r))
+}
+
+func (kv *roleKV) Delete(name string) error {
+	return kv.error(kv.store.Delete(name))
+}
+
+func (kv *roleKV) List(pattern string, offset, limit int) ([]Role, error) {
+	objects, err := kv.store.List(storage.DefaultIDIndex, pattern, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]Role, len(objects))
+	for i, object := range objects {
+		r, ok := object.(*Role)
+		if !ok {
+			return nil, storage.ImpossibleTypeErr(r, object)
+		}
+		roles[i] = *r
+	}
+	return roles, nil
+}
+
+func (kv *roleKV) Rebuild() error {
+	return kv.store.Rebuild()
+}
+
+// Key/Value store based implementation of the SubscriptionDAO
+type subscriptionKV struct {
+	store *storage.IndexedStore
+}
+
+func newSubscriptionKV(store storage.Interface) (*subscriptionKV, error) {
+	c := storage.DefaultIndexedStoreConfig("subscriptions", func() storage.BinaryObject {
+		return new(Subscription)
+	})
+	istore, err := storage.NewIndexedStore(store, c)
+	if err != nil {
+		return nil, err
+	}
+	return &subscriptionKV{
+		store: istore,
+	}, nil
+}
+
+func (kv *subscriptionKV) Get(hash string) (Subscription, error) {
+	obj, err := kv.store.Get(hash)
+	if err != nil {
+		return Subscription{}, err
+	}
+	s, ok := obj.(*Subscription)
+	if !ok {
+		return Subscription{}, storage.ImpossibleTypeErr(s, obj)
+	}
+	return *s, nil
+}
+
+func (kv *subscriptionKV) Put(s Subscription) error {
+	return kv.store.Put(&s)
+}
+
+func (kv *subscriptionKV) Delete(hash string) error {
+	return kv.store.Delete(hash)
+}
+
+func (kv *subscriptionKV) List() ([]Subscription, error) {
+	objects, err := kv.store.List(storage.DefaultIDIndex, "", 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	subscriptions := make([]Subscription, len(objects))
+	for i, object := range objects {
+		s, ok := object.(*Subscription)
+		if !ok {
+			return nil, storage.ImpossibleTypeErr(s, object)
+		}
+		subscriptions[i] = *s
+	}
+	return subscriptions, nil
+}
+
+func (kv *subscriptionKV) Rebuild() error {
+	return kv.store.Rebuild()
+}
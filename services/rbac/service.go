@@ -0,0 +1,660 @@
+// Package rbac provides an authentication service backed by users and roles
+// stored in the storage service.
+//
+// Each role grants a set of scopes, and each user is granted the scopes of its roles.
+// Admin users are granted every privilege and are the only users allowed to manage users and roles.
+package rbac
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/kapacitor/auth"
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/services/storage"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	usersPath         = "/users"
+	usersPathAnchored = "/users/"
+	usersBasePath     = httpd.BasePath + usersPathAnchored
+	rolesPath         = "/roles"
+	rolesPathAnchored = "/roles/"
+	rolesBasePath     = httpd.BasePath + rolesPathAnchored
+
+	// Public names of the stores
+	usersAPIName         = "users"
+	rolesAPIName         = "roles"
+	subscriptionsAPIName = "subscriptions"
+	// The storage namespace for all users and roles data.
+	rbacNamespace = "rbac"
+)
+
+var validName = regexp.MustCompile(`^[-\._\p{L}0-9]+$`)
+
+type Diagnostic interface {
+	CreatedAdminUser(username string)
+}
+
+type Service struct {
+	config Config
+	diag   Diagnostic
+	routes []httpd.Route
+
+	users         UserDAO
+	roles         RoleDAO
+	subscriptions SubscriptionDAO
+
+	passwords *passwordCache
+
+	StorageService interface {
+		Store(namespace string) storage.Interface
+		Register(name string, store storage.StoreActioner)
+	}
+	HTTPDService interface {
+		AddRoutes([]httpd.Route) error
+		DelRoutes([]httpd.Route)
+	}
+}
+
+func NewService(c Config, d Diagnostic) *Service {
+	return &Service{
+		config:    c,
+		diag:      d,
+		passwords: newPasswordCache(time.Duration(c.CacheExpiration)),
+	}
+}
+
+func (s *Service) Open() error {
+	store := s.StorageService.Store(rbacNamespace)
+	users, err := newUserKV(store)
+	if err != nil {
+		return err
+	}
+	s.users = users
+	s.StorageService.Register(usersAPIName, s.users)
+
+	roles, err := newRoleKV(store)
+	if err != nil {
+		return err
+	}
+	s.roles = roles
+	s.StorageService.Register(rolesAPIName, s.roles)
+
+	subscriptions, err := newSubscriptionKV(store)
+	if err != nil {
+		return err
+	}
+	s.subscriptions = subscriptions
+	s.StorageService.Register(subscriptionsAPIName, s.subscriptions)
+	if err := s.hashSubscriptionTokens(); err != nil {
+		return errors.Wrap(err, "failed to hash subscription tokens")
+	}
+
+	if err := s.createAdminUser(); err != nil {
+		return errors.Wrap(err, "failed to create admin user")
+	}
+
+	// Define API routes, they have no scope so only admin users may manage users and roles.
+	s.routes = []httpd.Route{
+		{
+			Method:      "GET",
+			Pattern:     usersPathAnchored,
+			HandlerFunc: s.handleUser,
+		},
+		{
+			Method:      "PATCH",
+			Pattern:     usersPathAnchored,
+			HandlerFunc: s.handleUpdateUser,
+		},
+		{
+			Method:      "DELETE",
+			Pattern:     usersPathAnchored,
+			HandlerFunc: s.handleDeleteUser,
+		},
+		{
+			Method:      "GET",
+			Pattern:     usersPath,
+			HandlerFunc: s.handleListUsers,
+		},
+		{
+			Method:      "POST",
+			Pattern:     usersPath,
+			HandlerFunc: s.handleCreateUser,
+		},
+		{
+			Method:      "GET",
+			Pattern:     rolesPathAnchored,
+			HandlerFunc: s.handleRole,
+		},
+		{
+			Method:      "PATCH",
+			Pattern:     rolesPathAnchored,
+			HandlerFunc: s.handleUpdateRole,
+		},
+		{
+			Method:      "DELETE",
+			Pattern:     rolesPathAnchored,
+			HandlerFunc: s.handleDeleteRole,
+		},
+		{
+			Method:      "GET",
+			Pattern:     rolesPath,
+			HandlerFunc: s.handleListRoles,
+		},
+		{
+			Method:      "POST",
+			Pattern:     rolesPath,
+			HandlerFunc: s.handleCreateRole,
+		},
+	}
+
+	err = s.HTTPDService.AddRoutes(s.routes)
+	return errors.Wrap(err, "failed to add API routes")
+}
+
+func (s *Service) Close() error {
+	if s.HTTPDService != nil {
+		s.HTTPDService.DelRoutes(s.routes)
+	}
+	return nil
+}
+
+// createAdminUser creates the configured admin user if no users exist.
+func (s *Service) createAdminUser() error {
+	if s.config.AdminUsername == "" {
+		return nil
+	}
+	users, err := s.users.List("", 0, 1)
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		return nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(s.config.AdminPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.users.Create(User{
+		Name:  s.config.AdminUsername,
+		Hash:  hash,
+		Admin: true,
+	}); err != nil {
+		return err
+	}
+	s.diag.CreatedAdminUser(s.config.AdminUsername)
+	return nil
+}
+
+// hashSubscriptionTokens replaces the subscription tokens stored in plaintext by their hash.
+func (s *Service) hashSubscriptionTokens() error {
+	subscriptions, err := s.subscriptions.List()
+	if err != nil {
+		return err
+	}
+	for _, sub := range subscriptions {
+		if sub.Token == "" {
+			continue
+		}
+		token := sub.Token
+		sub.Token = ""
+		sub.Hash = auth.HashSubscriptionToken(token)
+		sub.Cluster = tokenCluster(token)
+		if err := s.subscriptions.Put(sub); err != nil {
+			return err
+		}
+		if err := s.subscriptions.Delete(token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tokenCluster returns the name of the cluster of a subscription token,
+// the tokens are the cluster name and random data separated by a ';', encoded in base64.
+// It returns an empty name if the token is invalid.
+func tokenCluster(token string) string {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return ""
+	}
+	i := strings.IndexRune(string(raw), ';')
+	if i <= 0 {
+		return ""
+	}
+	return string(raw[:i])
+}
+
+// Authenticate a user by its password.
+// Successful checks are cached, so that the password is only checked against its bcrypt hash once in a while.
+func (s *Service) Authenticate(username, password string) (auth.User, error) {
+	u, err := s.users.Get(username)
+	if err != nil {
+		return auth.User{}, err
+	}
+	if !s.passwords.Check(u, password) {
+		if err := bcrypt.CompareHashAndPassword(u.Hash, []byte(password)); err != nil {
+			return auth.User{}, errors.New("invalid password")
+		}
+		s.passwords.Add(u, password)
+	}
+	return s.authUser(u)
+}
+
+// Return the user with the given name.
+func (s *Service) User(username string) (auth.User, error) {
+	u, err := s.users.Get(username)
+	if err != nil {
+		return auth.User{}, err
+	}
+	return s.authUser(u)
+}
+
+// authUser returns the auth.User granted the scopes of the roles of u.
+func (s *Service) authUser(u User) (auth.User, error) {
+	scopes, err := s.RoleScopes(u.Roles)
+	if err != nil {
+		return auth.User{}, err
+	}
+	return auth.NewUser(u.Name, u.Hash, u.Admin, nil).WithScopes(scopes...), nil
+}
+
+// RoleScopes returns the scopes granted by the roles.
+// Roles that no longer exist grant no scopes.
+func (s *Service) RoleScopes(roles []string) ([]auth.Scope, error) {
+	var scopes []auth.Scope
+	for _, name := range roles {
+		r, err := s.roles.Get(name)
+		if err == ErrNoRoleExists {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, scope := range r.Scopes {
+			scopes = append(scopes, auth.Scope(scope))
+		}
+	}
+	return scopes, nil
+}
+
+// Return a user allowed to write points, if the token was granted to an InfluxDB subscription.
+func (s *Service) SubscriptionUser(token string) (auth.User, error) {
+	if _, err := s.subscriptions.Get(auth.HashSubscriptionToken(token)); err != nil {
+		return auth.User{}, errors.New("unknown subscription token")
+	}
+	return auth.NewUser(httpd.SubscriptionUser, nil, false, map[string][]auth.Privilege{
+		auth.APIResource("/write"): {auth.WritePrivilege},
+	}), nil
+}
+
+// GrantSubscriptionAccess stores the hash of the token, the token itself is not stored.
+func (s *Service) GrantSubscriptionAccess(cluster, token, db, rp string) error {
+	return s.subscriptions.Put(Subscription{
+		Hash:            auth.HashSubscriptionToken(token),
+		Cluster:         cluster,
+		Database:        db,
+		RetentionPolicy: rp,
+	})
+}
+
+func (s *Service) ListSubscriptionTokens() ([]auth.SubscriptionToken, error) {
+	subscriptions, err := s.subscriptions.List()
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]auth.SubscriptionToken, len(subscriptions))
+	for i, sub := range subscriptions {
+		tokens[i] = auth.SubscriptionToken{Cluster: sub.Cluster, Hash: sub.Hash}
+	}
+	return tokens, nil
+}
+
+func (s *Service) RevokeSubscriptionAccess(hash string) error {
+	return s.subscriptions.Delete(hash)
+}
+
+func (s *Service) userLink(name string) client.Link {
+	return client.Link{Relation: client.Self, Href: path.Join(httpd.BasePath, usersPath, name)}
+}
+
+func (s *Service) roleLink(name string) client.Link {
+	return client.Link{Relation: client.Self, Href: path.Join(httpd.BasePath, rolesPath, name)}
+}
+
+func (s *Service) convertUser(u User) (client.User, error) {
+	au, err := s.authUser(u)
+	if err != nil {
+		return client.User{}, err
+	}
+	roles := u.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	scopes := make([]string, 0, len(au.Scopes()))
+	for _, scope := range au.Scopes() {
+		scopes = append(scopes, string(scope))
+	}
+	return client.User{
+		Link:   s.userLink(u.Name),
+		Name:   u.Name,
+		Admin:  u.Admin,
+		Roles:  roles,
+		Scopes: scopes,
+	}, nil
+}
+
+func (s *Service) convertRole(r Role) client.Role {
+	scopes := r.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return client.Role{
+		Link:   s.roleLink(r.Name),
+		Name:   r.Name,
+		Scopes: scopes,
+	}
+}
+
+// validateRoles returns an error if any of the roles does not exist.
+func (s *Service) validateRoles(roles []string) error {
+	for _, name := range roles {
+		if _, err := s.roles.Get(name); err == ErrNoRoleExists {
+			return fmt.Errorf("unknown role %q", name)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !auth.ValidScope(auth.Scope(scope)) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	return nil
+}
+
+func nameFromPath(p, base string) (string, error) {
+	if len(p) <= len(base) {
+		return "", errors.New("must specify name on path")
+	}
+	return p[len(base):], nil
+}
+
+// listOptions parses the pattern, offset and limit query parameters.
+func listOptions(r *http.Request) (pattern string, offset, limit int, err error) {
+	pattern = r.URL.Query().Get("pattern")
+	offset, limit = 0, 100
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		offset, err = strconv.Atoi(offsetStr)
+		if err != nil {
+			return "", 0, 0, fmt.Errorf("invalid offset parameter %q must be an integer: %s", offsetStr, err)
+		}
+	}
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			return "", 0, 0, fmt.Errorf("invalid limit parameter %q must be an integer: %s", limitStr, err)
+		}
+	}
+	return pattern, offset, limit, nil
+}
+
+func (s *Service) handleUser(w http.ResponseWriter, r *http.Request) {
+	name, err := nameFromPath(r.URL.Path, usersBasePath)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	u, err := s.users.Get(name)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	cu, err := s.convertUser(u)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.Write(httpd.MarshalJSON(cu, true))
+}
+
+func (s *Service) handleListUsers(w http.ResponseWriter, r *http.Request) {
+	pattern, offset, limit, err := listOptions(r)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	users, err := s.users.List(pattern, offset, limit)
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to list users with pattern %q: %s", pattern, err), true, http.StatusBadRequest)
+		return
+	}
+	type response struct {
+		Users []client.User `json:"users"`
+	}
+	res := response{Users: make([]client.User, len(users))}
+	for i, u := range users {
+		res.Users[i], err = s.convertUser(u)
+		if err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Write(httpd.MarshalJSON(res, true))
+}
+
+func (s *Service) handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	opt := client.CreateUserOptions{}
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		httpd.HttpError(w, "invalid JSON: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if !validName.MatchString(opt.Name) {
+		httpd.HttpError(w, fmt.Sprintf("user name must contain only letters, numbers, '-', '.' and '_'. %q", opt.Name), true, http.StatusBadRequest)
+		return
+	}
+	if opt.Password == "" {
+		httpd.HttpError(w, "must provide a password", true, http.StatusBadRequest)
+		return
+	}
+	if err := s.validateRoles(opt.Roles); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(opt.Password), bcrypt.DefaultCost)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	u := User{
+		Name:  opt.Name,
+		Hash:  hash,
+		Admin: opt.Admin,
+		Roles: opt.Roles,
+	}
+	if err := s.users.Create(u); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	cu, err := s.convertUser(u)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.Write(httpd.MarshalJSON(cu, true))
+}
+
+func (s *Service) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
+	name, err := nameFromPath(r.URL.Path, usersBasePath)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	opt := client.UpdateUserOptions{}
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		httpd.HttpError(w, "invalid JSON: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	u, err := s.users.Get(name)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	if opt.Password != "" {
+		u.Hash, err = bcrypt.GenerateFromPassword([]byte(opt.Password), bcrypt.DefaultCost)
+		if err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+			return
+		}
+	}
+	if opt.Admin != nil {
+		u.Admin = *opt.Admin
+	}
+	if opt.Roles != nil {
+		if err := s.validateRoles(opt.Roles); err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+			return
+		}
+		u.Roles = opt.Roles
+	}
+	if err := s.users.Replace(u); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	cu, err := s.convertUser(u)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.Write(httpd.MarshalJSON(cu, true))
+}
+
+func (s *Service) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	name, err := nameFromPath(r.URL.Path, usersBasePath)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if err := s.users.Delete(name); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) handleRole(w http.ResponseWriter, r *http.Request) {
+	name, err := nameFromPath(r.URL.Path, rolesBasePath)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	role, err := s.roles.Get(name)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	w.Write(httpd.MarshalJSON(s.convertRole(role), true))
+}
+
+func (s *Service) handleListRoles(w http.ResponseWriter, r *http.Request) {
+	pattern, offset, limit, err := listOptions(r)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	roles, err := s.roles.List(pattern, offset, limit)
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to list roles with pattern %q: %s", pattern, err), true, http.StatusBadRequest)
+		return
+	}
+	type response struct {
+		Roles []client.Role `json:"roles"`
+	}
+	res := response{Roles: make([]client.Role, len(roles))}
+	for i, role := range roles {
+		res.Roles[i] = s.convertRole(role)
+	}
+	w.Write(httpd.MarshalJSON(res, true))
+}
+
+func (s *Service) handleCreateRole(w http.ResponseWriter, r *http.Request) {
+	opt := client.CreateRoleOptions{}
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		httpd.HttpError(w, "invalid JSON: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if !validName.MatchString(opt.Name) {
+		httpd.HttpError(w, fmt.Sprintf("role name must contain only letters, numbers, '-', '.' and '_'. %q", opt.Name), true, http.StatusBadRequest)
+		return
+	}
+	if err := validateScopes(opt.Scopes); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	role := Role{
+		Name:   opt.Name,
+		Scopes: opt.Scopes,
+	}
+	if err := s.roles.Create(role); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	w.Write(httpd.MarshalJSON(s.convertRole(role), true))
+}
+
+func (s *Service) handleUpdateRole(w http.ResponseWriter, r *http.Request) {
+	name, err := nameFromPath(r.URL.Path, rolesBasePath)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	opt := client.UpdateRoleOptions{}
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		httpd.HttpError(w, "invalid JSON: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	role, err := s.roles.Get(name)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	if opt.Scopes != nil {
+		if err := validateScopes(opt.Scopes); err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+			return
+		}
+		role.Scopes = opt.Scopes
+	}
+	if err := s.roles.Replace(role); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.Write(httpd.MarshalJSON(s.convertRole(role), true))
+}
+
+func (s *Service) handleDeleteRole(w http.ResponseWriter, r *http.Request) {
+	name, err := nameFromPath(r.URL.Path, rolesBasePath)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if err := s.roles.Delete(name); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
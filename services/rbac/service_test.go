@@ -0,0 +1,282 @@
+package rbac_test
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/kapacitor/auth"
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/diagnostic"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/services/httpd/httpdtest"
+	"github.com/influxdata/kapacitor/services/rbac"
+	"github.com/influxdata/kapacitor/services/storage"
+	"github.com/influxdata/kapacitor/services/storage/storagetest"
+)
+
+var diagService *diagnostic.Service
+
+func init() {
+	diagService = diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	diagService.Open()
+}
+
+func OpenNewService(c rbac.Config) (*rbac.Service, *httpdtest.Server, *client.Client) {
+	return openNewService(c, storagetest.New())
+}
+
+func openNewService(c rbac.Config, storageService storageService) (*rbac.Service, *httpdtest.Server, *client.Client) {
+	service := rbac.NewService(c, diagService.NewRBACHandler())
+	service.StorageService = storageService
+	server := httpdtest.NewServer(testing.Verbose())
+	service.HTTPDService = server
+	if err := service.Open(); err != nil {
+		panic(err)
+	}
+	cli, err := client.New(client.Config{URL: server.Server.URL})
+	if err != nil {
+		panic(err)
+	}
+	return service, server, cli
+}
+
+func TestService_AdminUser(t *testing.T) {
+	c := rbac.NewConfig()
+	c.Enabled = true
+	c.AdminUsername = "admin"
+	c.AdminPassword = "secret"
+	service, server, _ := OpenNewService(c)
+	defer server.Close()
+	defer service.Close()
+
+	user, err := service.Authenticate("admin", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.IsAdmin() {
+		t.Error("expected admin user")
+	}
+	if _, err := service.Authenticate("admin", "wrong"); err == nil {
+		t.Error("expected error authenticating with the wrong password")
+	}
+	if _, err := service.Authenticate("bob", "secret"); err == nil {
+		t.Error("expected error authenticating an unknown user")
+	}
+}
+
+func TestService_UsersAndRoles(t *testing.T) {
+	service, server, cli := OpenNewService(rbac.NewConfig())
+	defer server.Close()
+	defer service.Close()
+
+	if _, err := cli.CreateRole(client.CreateRoleOptions{
+		Name:   "operator",
+		Scopes: []string{"tasks:read", "alerts:ack"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.CreateRole(client.CreateRoleOptions{
+		Name:   "invalid",
+		Scopes: []string{"tasks:everything"},
+	}); err == nil {
+		t.Error("expected error creating a role with an unknown scope")
+	}
+	if _, err := cli.CreateUser(client.CreateUserOptions{
+		Name:     "bob",
+		Password: "secret",
+		Roles:    []string{"unknown"},
+	}); err == nil {
+		t.Error("expected error creating a user with an unknown role")
+	}
+
+	u, err := cli.CreateUser(client.CreateUserOptions{
+		Name:     "bob",
+		Password: "secret",
+		Roles:    []string{"operator"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.User{
+		Link:   client.Link{Relation: client.Self, Href: "/kapacitor/v1/users/bob"},
+		Name:   "bob",
+		Roles:  []string{"operator"},
+		Scopes: []string{"tasks:read", "alerts:ack"},
+	}
+	if !reflect.DeepEqual(u, exp) {
+		t.Errorf("unexpected user:\ngot\n%v\nexp\n%v", u, exp)
+	}
+
+	user, err := service.Authenticate("bob", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := user.AuthorizeScope(auth.TasksReadScope); err != nil {
+		t.Error(err)
+	}
+	if err := user.AuthorizeScope(auth.TasksWriteScope); err == nil {
+		t.Error("expected bob not to have the tasks:write scope")
+	}
+
+	// Granting a scope to the role grants it to its users.
+	if _, err := cli.UpdateRole(cli.RoleLink("operator"), client.UpdateRoleOptions{
+		Scopes: []string{"tasks:read", "tasks:write"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	user, err = service.User("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := user.Scopes(), []auth.Scope{auth.TasksReadScope, auth.TasksWriteScope}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected scopes: got %v exp %v", got, exp)
+	}
+
+	admin := true
+	u, err = cli.UpdateUser(cli.UserLink("bob"), client.UpdateUserOptions{
+		Password: "changed",
+		Admin:    &admin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.Admin || !reflect.DeepEqual(u.Roles, []string{"operator"}) {
+		t.Errorf("unexpected user after update: %v", u)
+	}
+	if _, err := service.Authenticate("bob", "secret"); err == nil {
+		t.Error("expected error authenticating with the old password")
+	}
+	if _, err := service.Authenticate("bob", "changed"); err != nil {
+		t.Error(err)
+	}
+
+	// Deleting the role removes its scopes from the users.
+	if err := cli.DeleteRole(cli.RoleLink("operator")); err != nil {
+		t.Fatal(err)
+	}
+	roles, err := cli.ListRoles(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roles) != 0 {
+		t.Errorf("expected no roles, got %v", roles)
+	}
+	u, err = cli.User(cli.UserLink("bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(u.Scopes) != 0 {
+		t.Errorf("expected no scopes, got %v", u.Scopes)
+	}
+
+	if err := cli.DeleteUser(cli.UserLink("bob")); err != nil {
+		t.Fatal(err)
+	}
+	users, err := cli.ListUsers(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 0 {
+		t.Errorf("expected no users, got %v", users)
+	}
+}
+
+func TestService_SubscriptionUser(t *testing.T) {
+	service, server, _ := OpenNewService(rbac.NewConfig())
+	defer server.Close()
+	defer service.Close()
+
+	if _, err := service.SubscriptionUser("token"); err == nil {
+		t.Error("expected error for an unknown token")
+	}
+	if err := service.GrantSubscriptionAccess("cluster", "token", "db", "rp"); err != nil {
+		t.Fatal(err)
+	}
+	user, err := service.SubscriptionUser("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Name() != httpd.SubscriptionUser {
+		t.Errorf("unexpected user name %q", user.Name())
+	}
+	if err := user.AuthorizeAction(auth.Action{Resource: auth.APIResource("/write"), Privilege: auth.WritePrivilege}); err != nil {
+		t.Error(err)
+	}
+	if err := user.AuthorizeAction(auth.Action{Resource: auth.APIResource("/tasks"), Privilege: auth.ReadPrivilege}); err == nil {
+		t.Error("expected subscription user not to read tasks")
+	}
+	// Only the hash of the token is stored.
+	tokens, err := service.ListSubscriptionTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := auth.HashSubscriptionToken("token")
+	if exp := []auth.SubscriptionToken{{Cluster: "cluster", Hash: hash}}; !reflect.DeepEqual(tokens, exp) {
+		t.Errorf("unexpected tokens: got %v exp %v", tokens, exp)
+	}
+	if _, err := service.SubscriptionUser(hash); err == nil {
+		t.Error("expected error authenticating with the hash of the token")
+	}
+	if err := service.RevokeSubscriptionAccess(hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.SubscriptionUser("token"); err == nil {
+		t.Error("expected error for a revoked token")
+	}
+}
+
+func TestService_HashSubscriptionTokens(t *testing.T) {
+	store := storage.NewMemStore("rbac")
+	subscriptions, err := storage.NewIndexedStore(store, storage.DefaultIndexedStoreConfig("subscriptions", func() storage.BinaryObject {
+		return new(rbac.Subscription)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The tokens were stored in plaintext by the earlier versions.
+	token := base64.RawURLEncoding.EncodeToString([]byte("cluster;random data"))
+	if err := subscriptions.Put(&rbac.Subscription{Token: token, Database: "db", RetentionPolicy: "rp"}); err != nil {
+		t.Fatal(err)
+	}
+
+	service, server, _ := openNewService(rbac.NewConfig(), sharedStorage{TestStore: storagetest.New(), store: store})
+	defer server.Close()
+	defer service.Close()
+
+	tokens, err := service.ListSubscriptionTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []auth.SubscriptionToken{{Cluster: "cluster", Hash: auth.HashSubscriptionToken(token)}}; !reflect.DeepEqual(tokens, exp) {
+		t.Errorf("unexpected tokens: got %v exp %v", tokens, exp)
+	}
+	if _, err := service.SubscriptionUser(token); err != nil {
+		t.Error(err)
+	}
+	objects, err := subscriptions.List(storage.DefaultIDIndex, "", 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range objects {
+		if sub := o.(*rbac.Subscription); sub.Token != "" {
+			t.Errorf("token still stored in plaintext: %v", sub)
+		}
+	}
+}
+
+type storageService interface {
+	Store(namespace string) storage.Interface
+	Register(name string, store storage.StoreActioner)
+}
+
+// sharedStorage returns the same store for every namespace.
+type sharedStorage struct {
+	storagetest.TestStore
+	store storage.Interface
+}
+
+func (s sharedStorage) Store(namespace string) storage.Interface {
+	return s.store
+}
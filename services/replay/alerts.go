@@ -0,0 +1,237 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor/alert"
+	kclient "github.com/influxdata/kapacitor/client/v1"
+	alertservice "github.com/influxdata/kapacitor/services/alert"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	recordAlertsPath = recordingsPath + "/alerts"
+	replayAlertsPath = replaysPath + "/alerts"
+)
+
+// recordedAlertEvent is the representation of an alert event in a recording,
+// stored as one JSON object per line.
+type recordedAlertEvent struct {
+	Topic         string           `json:"topic"`
+	State         alert.EventState `json:"state"`
+	PreviousState alert.EventState `json:"previous-state"`
+	Data          alert.EventData  `json:"data"`
+	NoExternal    bool             `json:"no-external"`
+}
+
+func (e recordedAlertEvent) event() alert.Event {
+	return alert.Event{
+		Topic:      e.Topic,
+		State:      e.State,
+		Data:       e.Data,
+		NoExternal: e.NoExternal,
+	}.WithPreviousState(e.PreviousState)
+}
+
+// alertRecorder is an anonymous topic handler writing the events of the topic to a recording.
+type alertRecorder struct {
+	mu  sync.Mutex
+	w   io.WriteCloser
+	enc *json.Encoder
+	err error
+}
+
+func (r *alertRecorder) Handle(event alert.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(recordedAlertEvent{
+		Topic:         event.Topic,
+		State:         event.State,
+		PreviousState: event.PreviousState(),
+		Data:          event.Data,
+		NoExternal:    event.NoExternal,
+	})
+}
+
+// Close closes the recording, returning the first error encountered while writing events.
+func (r *alertRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := r.w.Close()
+	if r.err != nil {
+		return r.err
+	}
+	return err
+}
+
+func (s *Service) handleRecordAlerts(w http.ResponseWriter, r *http.Request) {
+	var opt kclient.RecordAlertsOptions
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if opt.ID == "" {
+		opt.ID = uuid.New().String()
+	}
+	if !validID.MatchString(opt.ID) {
+		httpd.HttpError(w, fmt.Sprintf("recording ID must contain only letters, numbers, '-', '.' and '_'. %q", opt.ID), true, http.StatusBadRequest)
+		return
+	}
+	if opt.Topic == "" {
+		httpd.HttpError(w, "must specify the topic to record", true, http.StatusBadRequest)
+		return
+	}
+	if !opt.Stop.After(time.Now()) {
+		httpd.HttpError(w, fmt.Sprintf("stop time %v is not in the future", opt.Stop), true, http.StatusBadRequest)
+		return
+	}
+	dataUrl := s.dataURLFromID(opt.ID, alertEXT)
+
+	recording := Recording{
+		ID:      opt.ID,
+		DataURL: dataUrl.String(),
+		Type:    AlertRecording,
+		Date:    time.Now(),
+		Status:  Running,
+	}
+	if err := s.recordings.Create(recording); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+
+	ds, _ := s.parseDataSourceURL(dataUrl.String())
+	f, err := ds.StreamWriter()
+	if err != nil {
+		s.updateRecordingResult(recording, ds, err)
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	rec := &alertRecorder{w: f, enc: json.NewEncoder(f)}
+	// Register the handler before responding so that no events are missed once the recording is created.
+	s.AlertService.RegisterAnonHandler(opt.Topic, rec)
+
+	s.wg.Add(1)
+	go func(recording Recording) {
+		defer s.wg.Done()
+		timer := time.NewTimer(time.Until(opt.Stop))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-s.closing:
+		}
+		// Deregistering waits for the handler to process all events it has received.
+		s.AlertService.DeregisterAnonHandler(opt.Topic, rec)
+		s.updateRecordingResult(recording, ds, rec.Close())
+	}(recording)
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(httpd.MarshalJSON(convertRecording(recording), true))
+}
+
+type handlerCloser interface {
+	Close()
+}
+
+func (s *Service) handleReplayAlerts(w http.ResponseWriter, r *http.Request) {
+	var opt kclient.ReplayAlertsOptions
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	recording, err := s.recordings.Get(opt.Recording)
+	if err != nil {
+		httpd.HttpError(w, "recording not found: "+err.Error(), true, http.StatusNotFound)
+		return
+	}
+	if recording.Type != AlertRecording {
+		httpd.HttpError(w, fmt.Sprintf("recording %s does not contain alert events", recording.ID), true, http.StatusBadRequest)
+		return
+	}
+	if recording.Status != Finished {
+		httpd.HttpError(w, fmt.Sprintf("recording %s is not finished", recording.ID), true, http.StatusBadRequest)
+		return
+	}
+	if len(opt.Handlers) == 0 {
+		httpd.HttpError(w, "must specify at least one handler", true, http.StatusBadRequest)
+		return
+	}
+
+	res := kclient.AlertsReplay{
+		Recording: recording.ID,
+		Handlers:  make([]string, len(opt.Handlers)),
+	}
+	handlers := make([]alert.Handler, 0, len(opt.Handlers))
+	defer func() {
+		for _, h := range handlers {
+			if c, ok := h.(handlerCloser); ok {
+				c.Close()
+			}
+		}
+	}()
+	for i, o := range opt.Handlers {
+		id := o.ID
+		if id == "" {
+			id = fmt.Sprintf("handler%d", i)
+		}
+		h, err := s.AlertService.NewHandler(alertservice.HandlerSpec{
+			ID:      id,
+			Topic:   "replay:" + recording.ID,
+			Kind:    o.Kind,
+			Options: o.Options,
+			Match:   o.Match,
+		})
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid handler %s: %v", id, err), true, http.StatusBadRequest)
+			return
+		}
+		handlers = append(handlers, h)
+		res.Handlers[i] = id
+	}
+
+	res.Events, err = s.replayAlertEvents(recording, handlers)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.Write(httpd.MarshalJSON(res, true))
+}
+
+// replayAlertEvents sends each event of the recording to the handlers in order,
+// returning the number of events replayed.
+func (s *Service) replayAlertEvents(recording Recording, handlers []alert.Handler) (int, error) {
+	ds, err := s.parseDataSourceURL(recording.DataURL)
+	if err != nil {
+		return 0, errors.Wrap(err, "load data source")
+	}
+	f, err := ds.StreamReader()
+	if err != nil {
+		return 0, errors.Wrap(err, "data source open")
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	count := 0
+	for {
+		var e recordedAlertEvent
+		if err := dec.Decode(&e); err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return count, errors.Wrapf(err, "failed to read event %d", count+1)
+		}
+		event := e.event()
+		for _, h := range handlers {
+			h.Handle(event)
+		}
+		count++
+	}
+}
@@ -2,21 +2,89 @@ package replay
 
 import (
 	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/objectstore"
 )
 
 type Config struct {
 	Dir string `toml:"dir"`
+	// StorageURL is the object storage location of new recordings, e.g. s3://bucket/recordings.
+	// Recordings are still written to and cached in Dir.
+	// If empty recordings are only stored in Dir.
+	StorageURL string `toml:"storage-url"`
+	// Retention is how long recordings are kept, 0 keeps them forever.
+	Retention toml.Duration `toml:"retention"`
+	// How often to delete expired recordings.
+	RetentionCheckInterval toml.Duration `toml:"retention-check-interval"`
+	// RingBuffers configures always-on recordings of the live stream.
+	RingBuffers []RingBufferConfig `toml:"ring-buffer"`
+}
+
+// RingBufferConfig keeps the most recent stream data for a dbrp on disk,
+// so that it can be saved as a recording after the fact.
+type RingBufferConfig struct {
+	Database        string `toml:"db"`
+	RetentionPolicy string `toml:"rp"`
+	// How much of the stream to keep.
+	Retention toml.Duration `toml:"retention"`
+}
+
+func (c RingBufferConfig) Validate() error {
+	if c.Database == "" {
+		return fmt.Errorf("must specify db")
+	}
+	if c.RetentionPolicy == "" {
+		return fmt.Errorf("must specify rp")
+	}
+	if c.Retention <= 0 {
+		return fmt.Errorf("retention must be positive, got %v", time.Duration(c.Retention))
+	}
+	return nil
 }
 
 func (c Config) Validate() error {
 	if c.Dir == "" {
 		return fmt.Errorf("must specify dir")
 	}
+	if c.StorageURL != "" {
+		u, err := url.Parse(c.StorageURL)
+		if err != nil {
+			return fmt.Errorf("invalid storage-url: %v", err)
+		}
+		if !remoteSchemes[u.Scheme] {
+			return fmt.Errorf("invalid storage-url %q: scheme must be one of s3, gs or az", c.StorageURL)
+		}
+		o := objectURL(*u, "recording")
+		if err := objectstore.Validate(o.String()); err != nil {
+			return fmt.Errorf("invalid storage-url: %v", err)
+		}
+	}
+	if c.Retention < 0 {
+		return fmt.Errorf("retention must not be negative, got %v", time.Duration(c.Retention))
+	}
+	if c.Retention > 0 && c.RetentionCheckInterval <= 0 {
+		return fmt.Errorf("retention-check-interval must be positive, got %v", time.Duration(c.RetentionCheckInterval))
+	}
+	dbrps := make(map[string]bool, len(c.RingBuffers))
+	for _, rb := range c.RingBuffers {
+		if err := rb.Validate(); err != nil {
+			return fmt.Errorf("invalid ring-buffer: %v", err)
+		}
+		dbrp := rb.Database + "." + rb.RetentionPolicy
+		if dbrps[dbrp] {
+			return fmt.Errorf("duplicate ring-buffer for %q", dbrp)
+		}
+		dbrps[dbrp] = true
+	}
 	return nil
 }
 
 func NewConfig() Config {
 	return Config{
-		Dir: "./replay",
+		Dir:                    "./replay",
+		RetentionCheckInterval: toml.Duration(time.Hour),
 	}
 }
@@ -66,11 +66,12 @@ type RecordingType int
 const (
 	StreamRecording RecordingType = iota
 	BatchRecording
+	AlertRecording
 )
 
 type Recording struct {
 	ID string
-	// URL for stored Recording data. Currently only file:// is supported.
+	// URL for stored Recording data, either file:// or an s3://, gs:// or az:// object.
 	DataURL  string
 	Type     RecordingType
 	Size     int64
@@ -225,6 +226,7 @@ type Replay struct {
 	TaskID        string
 	RecordingTime bool
 	Clock         Clock
+	Speed         float64
 	Date          time.Time
 	Error         string
 	Status        Status
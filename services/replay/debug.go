@@ -0,0 +1,487 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor"
+	kclient "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/clock"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	debugPath         = "/debug"
+	debugPathAnchored = "/debug/"
+
+	// Prefix of the task masters of debug sessions,
+	// it cannot be part of a replay ID so the names never collide.
+	debugTaskMasterPrefix = "debug:"
+
+	// Interval between checks of whether a task has processed a step.
+	settleInterval = 5 * time.Millisecond
+	// Maximum time to wait for a task to process a step.
+	settleTimeout = time.Second
+)
+
+// A debugSession replays a recording to a task one point or batch at a time.
+// Sessions are only kept in memory.
+type debugSession struct {
+	id          string
+	taskID      string
+	recordingID string
+
+	clk *clock.StepClock
+
+	mu sync.Mutex
+	// Task master executing the task, nil once the session finished
+	tm          *kapacitor.TaskMaster
+	breakpoints []string
+	// Node whose output paused the session
+	breakpoint string
+	running    bool
+	finished   bool
+	err        error
+	nodes      map[string]kapacitor.NodeExecutionStats
+
+	// pausing is signaled to stop a running session
+	pausing chan struct{}
+	// stopped is closed once the session is no longer running
+	stopped chan struct{}
+	// done is closed once the replay finished
+	done chan struct{}
+}
+
+func (s *Service) debugSessionFromPath(p string) (*debugSession, error) {
+	id := strings.TrimPrefix(p, httpd.BasePath+debugPathAnchored)
+	if id == "" || id == p {
+		return nil, errors.New("must specify debug session id on path")
+	}
+	s.debugMu.Lock()
+	defer s.debugMu.Unlock()
+	sess, ok := s.debugSessions[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown debug session %s", id)
+	}
+	return sess, nil
+}
+
+func debugSessionLink(id string) kclient.Link {
+	return kclient.Link{Relation: kclient.Self, Href: path.Join(httpd.BasePath, debugPath, id)}
+}
+
+func (s *Service) handleCreateDebugSession(w http.ResponseWriter, r *http.Request) {
+	var opt kclient.CreateDebugSessionOptions
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if opt.ID == "" {
+		opt.ID = uuid.New().String()
+	}
+	if !validID.MatchString(opt.ID) {
+		httpd.HttpError(w, fmt.Sprintf("debug session ID must contain only letters, numbers, '-', '.' and '_'. %q", opt.ID), true, http.StatusBadRequest)
+		return
+	}
+	t, err := s.TaskStore.Load(opt.Task)
+	if err != nil {
+		httpd.HttpError(w, "task load: "+err.Error(), true, http.StatusNotFound)
+		return
+	}
+	recording, err := s.recordings.Get(opt.Recording)
+	if err != nil {
+		httpd.HttpError(w, "recording not found: "+err.Error(), true, http.StatusNotFound)
+		return
+	}
+	if err := checkBreakpoints(t, opt.Breakpoints); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	sess := &debugSession{
+		id:          opt.ID,
+		taskID:      t.ID,
+		recordingID: recording.ID,
+		clk:         clock.Step(time.Now()),
+		breakpoints: opt.Breakpoints,
+		pausing:     make(chan struct{}, 1),
+		stopped:     make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	close(sess.stopped)
+	runReplay, err := s.replayRecording(t, recording, sess.clk, true)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+
+	s.debugMu.Lock()
+	if _, ok := s.debugSessions[sess.id]; ok {
+		s.debugMu.Unlock()
+		httpd.HttpError(w, fmt.Sprintf("debug session %s already exists", sess.id), true, http.StatusBadRequest)
+		return
+	}
+	s.debugSessions[sess.id] = sess
+	s.debugMu.Unlock()
+
+	started := make(chan struct{})
+	go func() {
+		replay := &Replay{ID: debugTaskMasterPrefix + sess.id}
+		err := s.doReplay(replay, t, func(tm *kapacitor.TaskMaster) error {
+			sess.mu.Lock()
+			sess.tm = tm
+			sess.mu.Unlock()
+			close(started)
+			err := runReplay(tm)
+			sess.settle()
+			return err
+		})
+		sess.finish(err)
+	}()
+	// Wait for the task to start so the session can be inspected right away.
+	select {
+	case <-started:
+	case <-sess.done:
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(httpd.MarshalJSON(sess.convert(), true))
+}
+
+// checkBreakpoints returns an error if a breakpoint is not a node of the task.
+func checkBreakpoints(t *kapacitor.Task, breakpoints []string) error {
+	nodes := make(map[string]bool)
+	t.Pipeline.Walk(func(n pipeline.Node) error {
+		nodes[n.Name()] = true
+		return nil
+	})
+	for _, b := range breakpoints {
+		if !nodes[b] {
+			return fmt.Errorf("breakpoint %s is not a node of task %s", b, t.ID)
+		}
+	}
+	return nil
+}
+
+func (s *Service) handleDebugSession(w http.ResponseWriter, r *http.Request) {
+	sess, err := s.debugSessionFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	w.Write(httpd.MarshalJSON(sess.convert(), true))
+}
+
+func (s *Service) handleListDebugSessions(w http.ResponseWriter, r *http.Request) {
+	s.debugMu.Lock()
+	sessions := make([]*debugSession, 0, len(s.debugSessions))
+	for _, sess := range s.debugSessions {
+		sessions = append(sessions, sess)
+	}
+	s.debugMu.Unlock()
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].id < sessions[j].id })
+
+	type response struct {
+		Sessions []kclient.DebugSession `json:"sessions"`
+	}
+	res := response{Sessions: make([]kclient.DebugSession, len(sessions))}
+	for i, sess := range sessions {
+		res.Sessions[i] = sess.convert()
+	}
+	w.Write(httpd.MarshalJSON(res, true))
+}
+
+func (s *Service) handleUpdateDebugSession(w http.ResponseWriter, r *http.Request) {
+	sess, err := s.debugSessionFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	var opt kclient.UpdateDebugSessionOptions
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if opt.Breakpoints != nil {
+		t, err := s.TaskStore.Load(sess.taskID)
+		if err != nil {
+			httpd.HttpError(w, "task load: "+err.Error(), true, http.StatusNotFound)
+			return
+		}
+		if err := checkBreakpoints(t, opt.Breakpoints); err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+			return
+		}
+		sess.mu.Lock()
+		sess.breakpoints = opt.Breakpoints
+		sess.mu.Unlock()
+	}
+
+	switch opt.Action {
+	case "":
+	case kclient.DebugStep:
+		count := opt.Count
+		if count == 0 {
+			count = 1
+		}
+		if count < 0 {
+			httpd.HttpError(w, fmt.Sprintf("invalid step count %d must be positive", count), true, http.StatusBadRequest)
+			return
+		}
+		stopped, err := sess.run(count)
+		if err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+			return
+		}
+		<-stopped
+	case kclient.DebugContinue:
+		if _, err := sess.run(-1); err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+			return
+		}
+	case kclient.DebugPause:
+		sess.pause()
+	default:
+		httpd.HttpError(w, fmt.Sprintf("invalid action %q", opt.Action), true, http.StatusBadRequest)
+		return
+	}
+	w.Write(httpd.MarshalJSON(sess.convert(), true))
+}
+
+func (s *Service) handleDeleteDebugSession(w http.ResponseWriter, r *http.Request) {
+	sess, err := s.debugSessionFromPath(r.URL.Path)
+	if err == nil {
+		s.debugMu.Lock()
+		delete(s.debugSessions, sess.id)
+		s.debugMu.Unlock()
+		sess.close()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// closeDebugSessions lets all debug sessions replay the rest of their recordings so their tasks stop.
+func (s *Service) closeDebugSessions() {
+	s.debugMu.Lock()
+	defer s.debugMu.Unlock()
+	for id, sess := range s.debugSessions {
+		sess.close()
+		delete(s.debugSessions, id)
+	}
+}
+
+// run replays up to count points or batches, or all remaining ones if count is negative,
+// pausing early if a breakpoint node emits data.
+// The returned channel is closed once the session is paused again.
+func (sess *debugSession) run(count int64) (<-chan struct{}, error) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.finished {
+		return nil, errors.New("debug session finished")
+	}
+	if sess.running {
+		return nil, errors.New("debug session is running, pause it first")
+	}
+	sess.running = true
+	sess.breakpoint = ""
+	sess.stopped = make(chan struct{})
+	// Discard a pause requested while the session was not running.
+	select {
+	case <-sess.pausing:
+	default:
+	}
+	stopped := sess.stopped
+	breakpoints := len(sess.breakpoints) > 0
+	go func() {
+		defer func() {
+			sess.mu.Lock()
+			sess.running = false
+			close(sess.stopped)
+			sess.mu.Unlock()
+		}()
+		if count < 0 && !breakpoints {
+			sess.runUntilPaused()
+			return
+		}
+		for i := int64(0); count < 0 || i < count; i++ {
+			select {
+			case <-sess.pausing:
+				return
+			default:
+			}
+			if sess.step() {
+				return
+			}
+		}
+	}()
+	return stopped, nil
+}
+
+// step replays a single point or batch, returning whether the session should stop running.
+func (sess *debugSession) step() bool {
+	before := sess.emitted()
+	steps, _ := sess.clk.Steps()
+	sess.clk.Step(1)
+	sess.clk.Wait(steps + 1)
+	select {
+	case <-sess.done:
+		return true
+	default:
+	}
+	sess.settle()
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	for _, b := range sess.breakpoints {
+		if sess.nodes[b].Emitted > before[b] {
+			sess.breakpoint = b
+			return true
+		}
+	}
+	return false
+}
+
+// runUntilPaused replays all points or batches until the session is paused or finished.
+func (sess *debugSession) runUntilPaused() {
+	sess.clk.Run()
+	select {
+	case <-sess.pausing:
+		sess.clk.Pause()
+		sess.settle()
+	case <-sess.done:
+	}
+}
+
+func (sess *debugSession) pause() {
+	sess.mu.Lock()
+	stopped := sess.stopped
+	sess.mu.Unlock()
+	select {
+	case sess.pausing <- struct{}{}:
+	default:
+	}
+	<-stopped
+}
+
+func (sess *debugSession) close() {
+	sess.pause()
+	sess.clk.Close()
+}
+
+// emitted returns the number of messages emitted by each breakpoint node.
+func (sess *debugSession) emitted() map[string]int64 {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	emitted := make(map[string]int64, len(sess.breakpoints))
+	for _, b := range sess.breakpoints {
+		emitted[b] = sess.nodes[b].Emitted
+	}
+	return emitted
+}
+
+// settle waits for the task to process the replayed data and records the statistics of its nodes.
+// The task has processed the data once no messages are queued and its statistics no longer change.
+func (sess *debugSession) settle() {
+	sess.mu.Lock()
+	tm := sess.tm
+	sess.mu.Unlock()
+	if tm == nil {
+		return
+	}
+	var last map[string]kapacitor.NodeExecutionStats
+	for start := time.Now(); time.Since(start) < settleTimeout; time.Sleep(settleInterval) {
+		nodes, ok := tm.NodeExecutionStats(sess.taskID)
+		if !ok {
+			break
+		}
+		if settled(last, nodes) {
+			break
+		}
+		last = nodes
+	}
+	if last != nil {
+		sess.mu.Lock()
+		sess.nodes = last
+		sess.mu.Unlock()
+	}
+}
+
+func settled(last, nodes map[string]kapacitor.NodeExecutionStats) bool {
+	if last == nil || len(last) != len(nodes) {
+		return false
+	}
+	for name, n := range nodes {
+		if n.QueueDepth != 0 || n != last[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func (sess *debugSession) finish(err error) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.tm = nil
+	sess.finished = true
+	sess.err = err
+	close(sess.done)
+	// Release any step waiting for data that will never come.
+	sess.clk.Close()
+}
+
+func (sess *debugSession) convert() kclient.DebugSession {
+	steps, now := sess.clk.Steps()
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	s := kclient.DebugSession{
+		Link:        debugSessionLink(sess.id),
+		ID:          sess.id,
+		Task:        sess.taskID,
+		Recording:   sess.recordingID,
+		Breakpoints: sess.breakpoints,
+		Breakpoint:  sess.breakpoint,
+		Status:      kclient.Running,
+		Paused:      !sess.running,
+		Steps:       steps,
+		NodeStats:   make(map[string]kclient.NodeStats, len(sess.nodes)),
+	}
+	if steps > 0 {
+		s.Elapsed = kclient.Duration(now.Sub(sess.clk.Zero()))
+	}
+	if s.Breakpoints == nil {
+		s.Breakpoints = []string{}
+	}
+	if sess.finished {
+		s.Status = kclient.Finished
+		s.Paused = false
+		if sess.err != nil {
+			s.Status = kclient.Failed
+			s.Error = sess.err.Error()
+		}
+	}
+	for name, n := range sess.nodes {
+		s.NodeStats[name] = kclient.NodeStats{
+			Collected:  n.Collected,
+			Emitted:    n.Emitted,
+			Errors:     n.Errors,
+			QueueDepth: n.QueueDepth,
+			Buffered:   n.Buffered,
+			Latency: kclient.LatencyStats{
+				Count: n.Latency.Count,
+				P50:   kclient.Duration(n.Latency.P50),
+				P90:   kclient.Duration(n.Latency.P90),
+				P99:   kclient.Duration(n.Latency.P99),
+				Max:   kclient.Duration(n.Latency.Max),
+			},
+		}
+	}
+	return s
+}
@@ -0,0 +1,359 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor"
+	"github.com/influxdata/kapacitor/alert"
+	kclient "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/clock"
+	"github.com/influxdata/kapacitor/influxdb"
+	alertservice "github.com/influxdata/kapacitor/services/alert"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/uuid"
+	"github.com/pkg/errors"
+)
+
+const replayDiffPath = replaysPath + "/diff"
+
+// outputRecorder captures the alert events and InfluxDB writes of a replayed task
+// in place of sending them on, so that the outputs of two replays can be compared.
+type outputRecorder struct {
+	// Prefix of the anonymous topics of the task's alert nodes.
+	anonPrefix string
+	// Maximum number of the most recent events and points kept, 0 keeps all.
+	limit int
+	// keepFirst keeps the first events and points within the limit instead of the most recent.
+	keepFirst bool
+
+	mu     sync.Mutex
+	events []alert.Event
+	points []recordedPoint
+	// Total number of events and points, including those no longer kept.
+	eventCount int64
+	pointCount int64
+}
+
+type recordedPoint struct {
+	Database        string
+	RetentionPolicy string
+	influxdb.Point
+}
+
+// setup replaces the alert and InfluxDB services of the task master with recording ones.
+func (r *outputRecorder) setup(tm *kapacitor.TaskMaster, task *kapacitor.Task) {
+	r.anonPrefix = tm.ID() + ":" + task.ID + ":"
+	tm.AlertService = recordingAlertService{alertService: tm.AlertService, r: r}
+	tm.InfluxDBService = recordingInfluxDBService{r: r}
+}
+
+// source returns the name identifying where an event came from independent of the task ID,
+// either the alert node name or the topic name.
+func (r *outputRecorder) source(topic string) string {
+	if strings.HasPrefix(topic, r.anonPrefix) {
+		return topic[len(r.anonPrefix):]
+	}
+	return "topic:" + topic
+}
+
+type alertService interface {
+	alertservice.AnonHandlerRegistrar
+	alertservice.Events
+	alertservice.TopicPersister
+	alertservice.InhibitorLookup
+}
+
+type recordingAlertService struct {
+	alertService
+	r *outputRecorder
+}
+
+// Collect records the event without handling it.
+func (s recordingAlertService) Collect(event alert.Event) error {
+	s.r.mu.Lock()
+	if s.r.limit == 0 || len(s.r.events) < s.r.limit || !s.r.keepFirst {
+		s.r.events = append(s.r.events, event)
+	}
+	if s.r.limit > 0 && len(s.r.events) > s.r.limit {
+		s.r.events = s.r.events[1:]
+	}
+	s.r.eventCount++
+	s.r.mu.Unlock()
+	return nil
+}
+
+type recordingInfluxDBService struct {
+	r *outputRecorder
+}
+
+func (s recordingInfluxDBService) NewNamedClient(name string) (influxdb.Client, error) {
+	return recordingInfluxDBClient{r: s.r}, nil
+}
+
+type recordingInfluxDBClient struct {
+	r *outputRecorder
+}
+
+func (recordingInfluxDBClient) Ping(ctx context.Context) (time.Duration, string, error) {
+	return 0, "", nil
+}
+
+// Write records the points without writing them.
+func (c recordingInfluxDBClient) Write(bp influxdb.BatchPoints) error {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	for _, p := range bp.Points() {
+		c.r.pointCount++
+		if c.r.limit > 0 && len(c.r.points) >= c.r.limit && c.r.keepFirst {
+			continue
+		}
+		c.r.points = append(c.r.points, recordedPoint{
+			Database:        bp.Database(),
+			RetentionPolicy: bp.RetentionPolicy(),
+			Point:           p,
+		})
+		if c.r.limit > 0 && len(c.r.points) > c.r.limit {
+			c.r.points = c.r.points[1:]
+		}
+	}
+	return nil
+}
+
+func (recordingInfluxDBClient) Query(q influxdb.Query) (*influxdb.Response, error) {
+	return &influxdb.Response{}, nil
+}
+
+func (s *Service) handleReplayDiff(w http.ResponseWriter, req *http.Request) {
+	var opt kclient.ReplayDiffOptions
+	dec := json.NewDecoder(req.Body)
+	err := dec.Decode(&opt)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if opt.Base == opt.Task {
+		httpd.HttpError(w, fmt.Sprintf("must diff two different tasks, got %q twice", opt.Task), true, http.StatusBadRequest)
+		return
+	}
+	base, err := s.TaskStore.Load(opt.Base)
+	if err != nil {
+		httpd.HttpError(w, "base task load: "+err.Error(), true, http.StatusNotFound)
+		return
+	}
+	task, err := s.TaskStore.Load(opt.Task)
+	if err != nil {
+		httpd.HttpError(w, "task load: "+err.Error(), true, http.StatusNotFound)
+		return
+	}
+	if base.Type != task.Type {
+		httpd.HttpError(w, fmt.Sprintf("cannot diff a %v task against a %v task", task.Type, base.Type), true, http.StatusBadRequest)
+		return
+	}
+	recording, err := s.recordings.Get(opt.Recording)
+	if err != nil {
+		httpd.HttpError(w, "recording not found: "+err.Error(), true, http.StatusNotFound)
+		return
+	}
+
+	id := uuid.New().String()
+	var baseOut, taskOut outputRecorder
+	errs := make(chan error, 2)
+	run := func(t *kapacitor.Task, name string, out *outputRecorder) {
+		runReplay, err := s.replayRecording(t, recording, clock.Fast(), opt.RecordingTime)
+		if err == nil {
+			setup := func(tm *kapacitor.TaskMaster) { out.setup(tm, t) }
+			_, err = s.replayTask("diff:"+id+":"+name, t, setup, runReplay)
+		}
+		errs <- errors.Wrapf(err, "replaying task %s", t.ID)
+	}
+	go run(base, "base", &baseOut)
+	go run(task, "task", &taskOut)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	diff := kclient.ReplayDiff{
+		Recording:   opt.Recording,
+		Base:        opt.Base,
+		Task:        opt.Task,
+		BaseEvents:  len(baseOut.events),
+		TaskEvents:  len(taskOut.events),
+		BaseOutputs: len(baseOut.points),
+		TaskOutputs: len(taskOut.points),
+		Events:      diffEvents(&baseOut, &taskOut),
+		Outputs:     diffOutputs(baseOut.points, taskOut.points),
+	}
+	diff.Equal = len(diff.Events) == 0 && len(diff.Outputs) == 0
+	w.Write(httpd.MarshalJSON(diff, true))
+}
+
+type eventKey struct {
+	source string
+	id     string
+	time   int64
+}
+
+// diffEvents compares the events by source, ID and time, reporting any that are missing on either side or differ in level.
+func diffEvents(base, task *outputRecorder) []kclient.EventDiff {
+	index := func(r *outputRecorder) map[eventKey]alert.EventState {
+		m := make(map[eventKey]alert.EventState, len(r.events))
+		for _, e := range r.events {
+			m[eventKey{source: r.source(e.Topic), id: e.State.ID, time: e.State.Time.UnixNano()}] = e.State
+		}
+		return m
+	}
+	baseEvents, taskEvents := index(base), index(task)
+	keys := make([]eventKey, 0, len(baseEvents))
+	for k := range baseEvents {
+		keys = append(keys, k)
+	}
+	for k := range taskEvents {
+		if _, ok := baseEvents[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].time != keys[j].time {
+			return keys[i].time < keys[j].time
+		}
+		if keys[i].source != keys[j].source {
+			return keys[i].source < keys[j].source
+		}
+		return keys[i].id < keys[j].id
+	})
+
+	var diffs []kclient.EventDiff
+	for _, k := range keys {
+		b, inBase := baseEvents[k]
+		t, inTask := taskEvents[k]
+		if inBase && inTask && b.Level == t.Level {
+			continue
+		}
+		d := kclient.EventDiff{
+			Source: k.source,
+			ID:     k.id,
+			Time:   time.Unix(0, k.time).UTC(),
+		}
+		if inBase {
+			d.Base = convertEventState(b)
+		}
+		if inTask {
+			d.Task = convertEventState(t)
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+func convertEventState(s alert.EventState) *kclient.EventState {
+	return &kclient.EventState{
+		Message:  s.Message,
+		Details:  s.Details,
+		Time:     s.Time,
+		Duration: kclient.Duration(s.Duration),
+		Level:    s.Level.String(),
+	}
+}
+
+type outputKey struct {
+	database        string
+	retentionPolicy string
+	name            string
+	tags            string
+	time            int64
+}
+
+func newOutputKey(p recordedPoint) outputKey {
+	return outputKey{
+		database:        p.Database,
+		retentionPolicy: p.RetentionPolicy,
+		name:            p.Name,
+		tags:            tagsKey(p.Tags),
+		time:            p.Time.UnixNano(),
+	}
+}
+
+// tagsKey returns the tags as sorted key=value pairs.
+func tagsKey(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// diffOutputs compares the written points by series and time, reporting any that are missing on either side or differ in fields.
+func diffOutputs(base, task []recordedPoint) []kclient.OutputDiff {
+	index := func(points []recordedPoint) map[outputKey]recordedPoint {
+		m := make(map[outputKey]recordedPoint, len(points))
+		for _, p := range points {
+			m[newOutputKey(p)] = p
+		}
+		return m
+	}
+	baseOutputs, taskOutputs := index(base), index(task)
+	keys := make([]outputKey, 0, len(baseOutputs))
+	for k := range baseOutputs {
+		keys = append(keys, k)
+	}
+	for k := range taskOutputs {
+		if _, ok := baseOutputs[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch {
+		case a.time != b.time:
+			return a.time < b.time
+		case a.database != b.database:
+			return a.database < b.database
+		case a.retentionPolicy != b.retentionPolicy:
+			return a.retentionPolicy < b.retentionPolicy
+		case a.name != b.name:
+			return a.name < b.name
+		default:
+			return a.tags < b.tags
+		}
+	})
+
+	var diffs []kclient.OutputDiff
+	for _, k := range keys {
+		b, inBase := baseOutputs[k]
+		t, inTask := taskOutputs[k]
+		if inBase && inTask && reflect.DeepEqual(b.Fields, t.Fields) {
+			continue
+		}
+		p := b
+		if !inBase {
+			p = t
+		}
+		d := kclient.OutputDiff{
+			Database:        p.Database,
+			RetentionPolicy: p.RetentionPolicy,
+			Measurement:     p.Name,
+			Tags:            p.Tags,
+			Time:            p.Time.UTC(),
+		}
+		if inBase {
+			d.Base = b.Fields
+		}
+		if inTask {
+			d.Task = t.Fields
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
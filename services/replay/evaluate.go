@@ -0,0 +1,184 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/influxdata/kapacitor"
+	kclient "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/clock"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/tick/ast"
+	"github.com/influxdata/kapacitor/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	evaluatePath = replaysPath + "/evaluate"
+
+	// Prefix of the task masters of evaluations,
+	// it cannot be part of a replay ID so the names never collide.
+	evaluateTaskMasterPrefix = "evaluate:"
+
+	defaultEvaluateLimit = 100
+	maxEvaluateLimit     = 10000
+)
+
+// handleEvaluate replays a recording to a TICKscript that is not defined as a task,
+// returning the output of the nodes without children, the alert events and the points written to InfluxDB.
+func (s *Service) handleEvaluate(w http.ResponseWriter, req *http.Request) {
+	var opt kclient.EvaluateOptions
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&opt); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	limit := opt.Limit
+	if limit == 0 {
+		limit = defaultEvaluateLimit
+	}
+	if limit < 0 || limit > maxEvaluateLimit {
+		httpd.HttpError(w, fmt.Sprintf("invalid limit %d must be between 1 and %d", opt.Limit, maxEvaluateLimit), true, http.StatusBadRequest)
+		return
+	}
+	recording, err := s.recordings.Get(opt.Recording)
+	if err != nil {
+		httpd.HttpError(w, "recording not found: "+err.Error(), true, http.StatusNotFound)
+		return
+	}
+	var tt kapacitor.TaskType
+	switch recording.Type {
+	case StreamRecording:
+		tt = kapacitor.StreamTask
+	case BatchRecording:
+		tt = kapacitor.BatchTask
+	default:
+		httpd.HttpError(w, fmt.Sprintf("recording %s contains alert events, it can only be replayed to alert handlers", recording.ID), true, http.StatusBadRequest)
+		return
+	}
+	dbrps, err := evaluateDBRPs(opt)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	id := uuid.New().String()
+	task, err := s.TaskMaster.NewTask(id, opt.Script, tt, dbrps, 0, nil)
+	if err != nil {
+		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	runReplay, err := s.replayRecording(task, recording, clock.Fast(), opt.RecordingTime)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	e, err := s.evaluate(task, limit, runReplay)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	e.Recording = opt.Recording
+	w.Write(httpd.MarshalJSON(e, true))
+}
+
+// evaluate runs the replay to a task in a new isolated task master,
+// returning the first limit rows of the output of the nodes without children,
+// alert events and points written to InfluxDB.
+func (s *Service) evaluate(task *kapacitor.Task, limit int, runReplay func(tm *kapacitor.TaskMaster) error) (kclient.Evaluation, error) {
+	leaves := task.Pipeline.LinkLeafNoOps()
+	out := outputRecorder{limit: limit, keepFirst: true}
+	setup := func(tm *kapacitor.TaskMaster) { out.setup(tm, task) }
+	taps := make([]*kapacitor.Tap, len(leaves))
+	stats, err := s.replayTask(evaluateTaskMasterPrefix+task.ID, task, setup, func(tm *kapacitor.TaskMaster) error {
+		// The taps buffer as many rows as they receive, so they never drop rows.
+		for i, n := range leaves {
+			tap, err := tm.Tap(task.ID, n.Name(), limit)
+			if err != nil {
+				return errors.Wrapf(err, "failed to tap node %s", n.Name())
+			}
+			taps[i] = tap
+		}
+		return runReplay(tm)
+	})
+	if err != nil {
+		return kclient.Evaluation{}, err
+	}
+
+	e := kclient.Evaluation{
+		Outputs:    make([]kclient.NodeOutput, len(leaves)),
+		Events:     make([]kclient.EvaluatedEvent, len(out.events)),
+		Writes:     make([]kclient.WrittenPoint, len(out.points)),
+		EventCount: out.eventCount,
+		WriteCount: out.pointCount,
+		ExecutionStats: kclient.ExecutionStats{
+			TaskStats: stats.TaskStats,
+			NodeStats: stats.NodeStats,
+		},
+	}
+	for i, n := range leaves {
+		taps[i].Close()
+		rows := []kclient.Row{}
+		for row := range taps[i].Rows {
+			rows = append(rows, kclient.Row{
+				Name:    row.Name,
+				Tags:    row.Tags,
+				Columns: row.Columns,
+				Values:  row.Values,
+			})
+		}
+		e.Outputs[i] = kclient.NodeOutput{Node: n.Name(), Rows: rows}
+	}
+	for i, ev := range out.events {
+		e.Events[i] = kclient.EvaluatedEvent{
+			Source: out.source(ev.Topic),
+			ID:     ev.State.ID,
+			State:  *convertEventState(ev.State),
+		}
+	}
+	for i, p := range out.points {
+		e.Writes[i] = kclient.WrittenPoint{
+			Database:        p.Database,
+			RetentionPolicy: p.RetentionPolicy,
+			Measurement:     p.Name,
+			Tags:            p.Tags,
+			Time:            p.Time.UTC(),
+			Fields:          p.Fields,
+		}
+	}
+	return e, nil
+}
+
+// evaluateDBRPs returns the databases and retention policies of the dbrp statements of the script,
+// or of the options if it has none.
+func evaluateDBRPs(opt kclient.EvaluateOptions) ([]kapacitor.DBRP, error) {
+	p, err := ast.Parse(opt.Script)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TICKscript: %v", err)
+	}
+	var dbrps []kapacitor.DBRP
+	if pn, ok := p.(*ast.ProgramNode); ok {
+		for _, n := range pn.Nodes {
+			if d, ok := n.(*ast.DBRPNode); ok {
+				dbrps = append(dbrps, kapacitor.DBRP{
+					Database:        d.DB.Reference,
+					RetentionPolicy: d.RP.Reference,
+				})
+			}
+		}
+	}
+	if len(dbrps) > 0 && len(opt.DBRPs) > 0 {
+		return nil, errors.New("cannot specify dbrp both implicitly and explicitly")
+	}
+	for _, d := range opt.DBRPs {
+		dbrps = append(dbrps, kapacitor.DBRP{
+			Database:        d.Database,
+			RetentionPolicy: d.RetentionPolicy,
+		})
+	}
+	if len(dbrps) == 0 {
+		return nil, errors.New("must specify dbrp")
+	}
+	return dbrps, nil
+}
@@ -0,0 +1,499 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/kapacitor"
+	kclient "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	recordImportPath = recordingsPath + "/import"
+
+	csvFormat          = "csv"
+	annotatedCSVFormat = "annotated-csv"
+)
+
+// Columns of annotated CSV that are neither tags nor fields.
+var annotatedReservedColumns = map[string]bool{
+	"":             true,
+	"result":       true,
+	"table":        true,
+	"_start":       true,
+	"_stop":        true,
+	"_time":        true,
+	"_value":       true,
+	"_field":       true,
+	"_measurement": true,
+}
+
+func (s *Service) handleRecordImport(w http.ResponseWriter, r *http.Request) {
+	var opt kclient.RecordImportOptions
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&opt)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if opt.ID == "" {
+		opt.ID = uuid.New().String()
+	}
+	if !validID.MatchString(opt.ID) {
+		httpd.HttpError(w, fmt.Sprintf("recording ID must contain only letters, numbers, '-', '.' and '_'. %q", opt.ID), true, http.StatusBadRequest)
+		return
+	}
+	if opt.Format == "" {
+		opt.Format = detectCSVFormat(opt.Data)
+	}
+
+	var points []edge.PointMessage
+	switch opt.Format {
+	case csvFormat:
+		points, err = parseImportCSV(opt)
+	case annotatedCSVFormat:
+		points, err = parseImportAnnotatedCSV(opt)
+	default:
+		err = fmt.Errorf("unknown format %q, expected %q or %q", opt.Format, csvFormat, annotatedCSVFormat)
+	}
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	var dataUrl url.URL
+	var typ RecordingType
+	switch opt.Type {
+	case kclient.StreamTask:
+		if opt.Database == "" || opt.RetentionPolicy == "" {
+			httpd.HttpError(w, "must provide db and rp of a stream recording", true, http.StatusBadRequest)
+			return
+		}
+		dataUrl = s.dataURLFromID(opt.ID, streamEXT)
+		typ = StreamRecording
+	case kclient.BatchTask:
+		dataUrl = s.dataURLFromID(opt.ID, batchEXT)
+		typ = BatchRecording
+	default:
+		httpd.HttpError(w, fmt.Sprintf("invalid recording type %v", opt.Type), true, http.StatusBadRequest)
+		return
+	}
+
+	recording := Recording{
+		ID:      opt.ID,
+		DataURL: dataUrl.String(),
+		Type:    typ,
+		Date:    time.Now(),
+		Status:  Running,
+	}
+	err = s.recordings.Create(recording)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+
+	ds, _ := s.parseDataSourceURL(dataUrl.String())
+	if typ == StreamRecording {
+		err = s.saveImportedStream(ds, points)
+	} else {
+		err = s.saveImportedBatches(ds, points)
+	}
+	s.updateRecordingResult(recording, ds, err)
+	recording, err = s.recordings.Get(opt.ID)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(httpd.MarshalJSON(convertRecording(recording), true))
+}
+
+// detectCSVFormat returns the annotated CSV format if the data starts with an annotation.
+func detectCSVFormat(data string) string {
+	if strings.HasPrefix(strings.TrimSpace(data), "#") {
+		return annotatedCSVFormat
+	}
+	return csvFormat
+}
+
+// saveImportedStream writes the points ordered by time as a stream recording.
+func (s *Service) saveImportedStream(ds DataSource, points []edge.PointMessage) error {
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Time().Before(points[j].Time())
+	})
+	sw, err := ds.StreamWriter()
+	if err != nil {
+		return err
+	}
+	for _, p := range points {
+		if err := kapacitor.WritePointForRecording(sw, p, precision); err != nil {
+			sw.Close()
+			return err
+		}
+	}
+	return sw.Close()
+}
+
+// saveImportedBatches writes the points as a batch recording with one batch per series,
+// as if they were the result of a single query.
+func (s *Service) saveImportedBatches(ds DataSource, points []edge.PointMessage) error {
+	var groups []models.GroupID
+	series := make(map[models.GroupID][]edge.PointMessage)
+	for _, p := range points {
+		id := p.GroupID()
+		if _, ok := series[id]; !ok {
+			groups = append(groups, id)
+		}
+		series[id] = append(series[id], p)
+	}
+
+	archiver, err := ds.BatchArchiver()
+	if err != nil {
+		return err
+	}
+	w, err := archiver.Archive(0)
+	if err != nil {
+		archiver.Close()
+		return err
+	}
+	for _, id := range groups {
+		ps := series[id]
+		sort.SliceStable(ps, func(i, j int) bool {
+			return ps[i].Time().Before(ps[j].Time())
+		})
+		bps := make([]edge.BatchPointMessage, len(ps))
+		for i, p := range ps {
+			bps[i] = edge.NewBatchPointMessage(p.Fields(), p.Tags(), p.Time())
+		}
+		b := edge.NewBufferedBatchMessage(
+			edge.NewBeginBatchMessage(ps[0].Name(), ps[0].Tags(), false, ps[len(ps)-1].Time(), len(bps)),
+			bps,
+			edge.NewEndBatchMessage(),
+		)
+		if err := kapacitor.WriteBatchForRecording(w, b); err != nil {
+			archiver.Close()
+			return err
+		}
+	}
+	return archiver.Close()
+}
+
+// parseImportCSV converts a CSV with a header row into points.
+// Each row is a point, columns other than the time, measurement and tag columns are fields.
+func parseImportCSV(opt kclient.RecordImportOptions) ([]edge.PointMessage, error) {
+	r := csv.NewReader(strings.NewReader(opt.Data))
+	r.FieldsPerRecord = 0
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid csv")
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	timeColumn := opt.TimeColumn
+	if timeColumn == "" {
+		timeColumn = "time"
+	}
+	measurementColumn := opt.MeasurementColumn
+	if measurementColumn == "" && opt.Measurement == "" {
+		measurementColumn = "name"
+	}
+	tagColumns := make(map[string]bool, len(opt.TagColumns))
+	for _, c := range opt.TagColumns {
+		tagColumns[c] = true
+	}
+	header := rows[0]
+	hasTime := false
+	for _, column := range header {
+		if column == timeColumn {
+			hasTime = true
+		}
+	}
+	if !hasTime {
+		return nil, fmt.Errorf("missing time column %q", timeColumn)
+	}
+
+	points := make([]edge.PointMessage, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		var t time.Time
+		name := opt.Measurement
+		tags := make(models.Tags, len(tagColumns))
+		fields := make(models.Fields, len(row))
+		for j, value := range row {
+			column := header[j]
+			switch {
+			case value == "":
+				continue
+			case column == timeColumn:
+				t, err = parseImportTime(value, opt.TimeFormat)
+				if err != nil {
+					return nil, fmt.Errorf("invalid time on line %d: %v", i+2, err)
+				}
+			case column == measurementColumn:
+				name = value
+			case tagColumns[column]:
+				tags[column] = value
+			default:
+				fields[column] = csvFieldValue(value)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if name == "" {
+			return nil, fmt.Errorf("missing measurement on line %d", i+2)
+		}
+		if t.IsZero() {
+			return nil, fmt.Errorf("missing time on line %d", i+2)
+		}
+		points = append(points, newImportedPoint(opt, name, tags, fields, t))
+	}
+	return points, nil
+}
+
+// parseImportTime parses either a time in the given layout, RFC3339 by default,
+// or an integer timestamp in nanoseconds.
+func parseImportTime(value, layout string) (time.Time, error) {
+	if layout == "" {
+		if ns, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return time.Unix(0, ns).UTC(), nil
+		}
+		layout = time.RFC3339Nano
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// csvFieldValue converts a CSV value into an integer, float, boolean or string field.
+func csvFieldValue(v string) interface{} {
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	switch strings.ToLower(v) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return v
+}
+
+type annotatedKey struct {
+	name string
+	tags models.GroupID
+	time int64
+}
+
+// parseImportAnnotatedCSV converts the tables of an InfluxDB 2.x annotated CSV into points.
+// Rows with a _field and _value column are merged into one point per series and time.
+// Otherwise, as for pivoted results, grouped columns are tags and all other columns are fields.
+func parseImportAnnotatedCSV(opt kclient.RecordImportOptions) ([]edge.PointMessage, error) {
+	var points []edge.PointMessage
+	index := make(map[annotatedKey]int)
+
+	// Tables are separated by empty lines, each with its own annotations and header.
+	scanner := bufio.NewScanner(strings.NewReader(opt.Data))
+	scanner.Buffer(nil, 1024*1024)
+	var table bytes.Buffer
+	line := 0
+	start := 1
+	flush := func() error {
+		if table.Len() == 0 {
+			return nil
+		}
+		err := parseAnnotatedTable(opt, table.Bytes(), start, &points, index)
+		table.Reset()
+		return err
+	}
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			start = line + 1
+			continue
+		}
+		table.WriteString(text)
+		table.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+func parseAnnotatedTable(opt kclient.RecordImportOptions, data []byte, start int, points *[]edge.PointMessage, index map[annotatedKey]int) error {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	var datatypes, groups, defaults, header []string
+	for i := 0; ; i++ {
+		row, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "invalid annotated csv")
+		}
+		line := start + i
+		if header == nil {
+			switch row[0] {
+			case "#datatype":
+				datatypes = row
+			case "#group":
+				groups = row
+			case "#default":
+				defaults = row
+			default:
+				if strings.HasPrefix(row[0], "#") {
+					// Ignore unknown annotations
+					continue
+				}
+				header = row
+			}
+			continue
+		}
+		if len(row) != len(header) {
+			return fmt.Errorf("line %d: expected %d columns got %d", line, len(header), len(row))
+		}
+
+		var (
+			t          time.Time
+			name       = opt.Measurement
+			fieldName  string
+			fieldValue interface{}
+			hasValue   bool
+			tags       = make(models.Tags)
+			fields     = make(models.Fields)
+		)
+		for j, value := range row {
+			if value == "" && j < len(defaults) {
+				value = defaults[j]
+			}
+			if value == "" {
+				continue
+			}
+			column := header[j]
+			datatype := ""
+			if j < len(datatypes) {
+				datatype = datatypes[j]
+			}
+			switch column {
+			case "_time":
+				t, err = time.Parse(time.RFC3339Nano, value)
+				if err != nil {
+					return fmt.Errorf("line %d: invalid _time: %v", line, err)
+				}
+			case "_measurement":
+				name = value
+			case "_field":
+				fieldName = value
+			case "_value":
+				fieldValue, err = annotatedValue(value, datatype)
+				if err != nil {
+					return fmt.Errorf("line %d: invalid _value: %v", line, err)
+				}
+				hasValue = true
+			default:
+				if annotatedReservedColumns[column] {
+					continue
+				}
+				if j < len(groups) && groups[j] == "true" {
+					tags[column] = value
+					continue
+				}
+				v, err := annotatedValue(value, datatype)
+				if err != nil {
+					return fmt.Errorf("line %d: invalid %s: %v", line, column, err)
+				}
+				fields[column] = v
+			}
+		}
+		if fieldName != "" && hasValue {
+			fields[fieldName] = fieldValue
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if name == "" {
+			return fmt.Errorf("line %d: missing _measurement", line)
+		}
+		if t.IsZero() {
+			return fmt.Errorf("line %d: missing _time", line)
+		}
+		t = t.UTC()
+		key := annotatedKey{
+			name: name,
+			tags: models.ToGroupID(name, tags, models.Dimensions{TagNames: models.SortedKeys(tags)}),
+			time: t.UnixNano(),
+		}
+		if idx, ok := index[key]; ok {
+			p := (*points)[idx]
+			merged := p.Fields().Copy()
+			for k, v := range fields {
+				merged[k] = v
+			}
+			p.SetFields(merged)
+			continue
+		}
+		index[key] = len(*points)
+		*points = append(*points, newImportedPoint(opt, name, tags, fields, t))
+	}
+}
+
+// annotatedValue converts a value according to its annotated CSV datatype.
+func annotatedValue(value, datatype string) (interface{}, error) {
+	switch datatype {
+	case "long":
+		return strconv.ParseInt(value, 10, 64)
+	case "unsignedLong":
+		u, err := strconv.ParseUint(value, 10, 63)
+		return int64(u), err
+	case "double":
+		return strconv.ParseFloat(value, 64)
+	case "boolean":
+		return strconv.ParseBool(value)
+	case "string":
+		return value, nil
+	default:
+		return csvFieldValue(value), nil
+	}
+}
+
+func newImportedPoint(opt kclient.RecordImportOptions, name string, tags models.Tags, fields models.Fields, t time.Time) edge.PointMessage {
+	return edge.NewPointMessage(
+		name,
+		opt.Database,
+		opt.RetentionPolicy,
+		models.Dimensions{TagNames: models.SortedKeys(tags)},
+		fields,
+		tags,
+		t,
+	)
+}
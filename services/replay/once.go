@@ -0,0 +1,66 @@
+package replay
+
+import (
+	"io"
+	"io/ioutil"
+	"time"
+
+	dbmodels "github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor"
+	kclient "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/clock"
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/pkg/errors"
+)
+
+// RunOnce runs a stream TICKscript as the task with the ID against the points of the line protocol read from r,
+// as if they were written to the first dbrp of the task at their timestamps.
+// It returns the first maxEvaluateLimit rows of the output of the nodes without children,
+// alert events and points written to InfluxDB, the same way as an evaluation.
+func (s *Service) RunOnce(id, script string, dbrps []kclient.DBRP, r io.Reader, precision string) (kclient.Evaluation, error) {
+	taskDBRPs, err := evaluateDBRPs(kclient.EvaluateOptions{Script: script, DBRPs: dbrps})
+	if err != nil {
+		return kclient.Evaluation{}, err
+	}
+	task, err := s.TaskMaster.NewTask(id, script, kapacitor.StreamTask, taskDBRPs, 0, nil)
+	if err != nil {
+		return kclient.Evaluation{}, errors.Wrap(err, "invalid TICKscript")
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return kclient.Evaluation{}, errors.Wrap(err, "failed to read line protocol")
+	}
+	mps, err := dbmodels.ParsePointsWithPrecision(data, time.Now(), precision)
+	if err != nil {
+		return kclient.Evaluation{}, errors.Wrap(err, "invalid line protocol")
+	}
+	return s.evaluate(task, maxEvaluateLimit, replayPoints(task, mps))
+}
+
+// replayPoints returns a function that replays the points to a stream task at their timestamps,
+// as if they were written to its first dbrp.
+func replayPoints(task *kapacitor.Task, mps []dbmodels.Point) func(tm *kapacitor.TaskMaster) error {
+	return func(tm *kapacitor.TaskMaster) error {
+		points := make(chan edge.PointMessage, len(mps))
+		for _, mp := range mps {
+			points <- edge.NewPointMessage(
+				mp.Name(),
+				task.DBRPs[0].Database,
+				task.DBRPs[0].RetentionPolicy,
+				models.Dimensions{},
+				models.Fields(mp.Fields()),
+				models.Tags(mp.Tags().Map()),
+				mp.Time().UTC(),
+			)
+		}
+		close(points)
+
+		stream, err := tm.Stream(task.ID)
+		if err != nil {
+			return errors.Wrap(err, "stream start")
+		}
+		return <-kapacitor.ReplayStreamFromChan(clock.Fast(), points, stream, true)
+	}
+}
@@ -0,0 +1,352 @@
+package replay
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor"
+	kclient "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	recordRingBufferPath = recordingsPath + "/ring-buffer"
+
+	// Directory within the replay dir where ring buffer segments are kept.
+	ringBufferDir = "ring-buffers"
+	// Name prefix of the task master forks feeding the ring buffers.
+	// The ':' cannot appear in a recording ID so the names never collide.
+	ringBufferForkPrefix = "ring-buffer:"
+
+	// Number of segments a ring buffer is split into over its retention.
+	ringBufferSegments = 10
+	minSegmentDuration = time.Second
+)
+
+// ringSegment is a single gzipped stream recording file of a ring buffer.
+type ringSegment struct {
+	path  string
+	start time.Time
+	end   time.Time
+}
+
+// ringBuffer records the live stream of a single dbrp into rotating segment files,
+// deleting segments once they fall outside of the retention.
+type ringBuffer struct {
+	db        string
+	rp        string
+	dir       string
+	retention time.Duration
+	segment   time.Duration
+
+	e  edge.StatsEdge
+	wg sync.WaitGroup
+
+	mu       sync.Mutex
+	segments []ringSegment
+	current  *ringSegment
+	w        io.WriteCloser
+	// Number of snapshots reading the segment files, no segments are deleted while non zero.
+	reading int
+
+	diag Diagnostic
+}
+
+func newRingBuffer(c RingBufferConfig, saveDir string, d Diagnostic) (*ringBuffer, error) {
+	retention := time.Duration(c.Retention)
+	segment := retention / ringBufferSegments
+	if segment < minSegmentDuration {
+		segment = minSegmentDuration
+	}
+	rb := &ringBuffer{
+		db:        c.Database,
+		rp:        c.RetentionPolicy,
+		dir:       filepath.Join(saveDir, ringBufferDir, c.Database+"."+c.RetentionPolicy),
+		retention: retention,
+		segment:   segment,
+		diag:      d,
+	}
+	if err := os.MkdirAll(rb.dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := rb.loadSegments(); err != nil {
+		return nil, err
+	}
+	return rb, nil
+}
+
+func (rb *ringBuffer) name() string {
+	return rb.db + "." + rb.rp
+}
+
+// loadSegments finds the segments left behind by a previous run.
+func (rb *ringBuffer) loadSegments() error {
+	files, err := ioutil.ReadDir(rb.dir)
+	if err != nil {
+		return errors.Wrap(err, "loading ring buffer segments")
+	}
+	for _, info := range files {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, streamEXT) {
+			continue
+		}
+		nano, err := strconv.ParseInt(strings.TrimSuffix(name, streamEXT), 10, 64)
+		if err != nil {
+			rb.diag.Error("unknown file in ring buffer dir", fmt.Errorf("%s is not a ring buffer segment", name))
+			continue
+		}
+		rb.segments = append(rb.segments, ringSegment{
+			path:  filepath.Join(rb.dir, name),
+			start: time.Unix(0, nano),
+			end:   info.ModTime(),
+		})
+	}
+	sort.Slice(rb.segments, func(i, j int) bool {
+		return rb.segments[i].start.Before(rb.segments[j].start)
+	})
+	rb.mu.Lock()
+	rb.expire(time.Now())
+	rb.mu.Unlock()
+	return nil
+}
+
+// open starts recording the stream of the dbrp.
+func (rb *ringBuffer) open(tm interface {
+	NewFork(name string, dbrps []kapacitor.DBRP, measurements []string) (edge.StatsEdge, error)
+}) error {
+	dbrps := []kapacitor.DBRP{{Database: rb.db, RetentionPolicy: rb.rp}}
+	e, err := tm.NewFork(ringBufferForkPrefix+rb.name(), dbrps, []string{""})
+	if err != nil {
+		return err
+	}
+	rb.e = e
+	rb.wg.Add(1)
+	go func() {
+		defer rb.wg.Done()
+		rb.run()
+	}()
+	return nil
+}
+
+func (rb *ringBuffer) run() {
+	for m, ok := rb.e.Emit(); ok; m, ok = rb.e.Emit() {
+		p, isPoint := m.(edge.PointMessage)
+		if !isPoint {
+			// Skip messages that are not points
+			continue
+		}
+		if err := rb.write(p, time.Now()); err != nil {
+			rb.diag.Error("failed to write point to ring buffer", err, keyvalue.KV("ring_buffer", rb.name()))
+		}
+	}
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.rotate(time.Now())
+}
+
+// wait blocks until the ring buffer has stopped, after its fork has been deleted.
+func (rb *ringBuffer) wait() {
+	rb.wg.Wait()
+}
+
+func (rb *ringBuffer) write(p edge.PointMessage, now time.Time) error {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if rb.current != nil && now.Sub(rb.current.start) >= rb.segment {
+		rb.rotate(now)
+		rb.expire(now)
+	}
+	if rb.current == nil {
+		s := ringSegment{
+			path:  filepath.Join(rb.dir, strconv.FormatInt(now.UnixNano(), 10)+streamEXT),
+			start: now,
+		}
+		w, err := fileSource(s.path).StreamWriter()
+		if err != nil {
+			return err
+		}
+		rb.current = &s
+		rb.w = w
+	}
+	return kapacitor.WritePointForRecording(rb.w, p, precision)
+}
+
+// rotate closes the current segment, if any.
+// Must hold the lock.
+func (rb *ringBuffer) rotate(now time.Time) {
+	if rb.current == nil {
+		return
+	}
+	if err := rb.w.Close(); err != nil {
+		rb.diag.Error("failed to close ring buffer segment", err, keyvalue.KV("ring_buffer", rb.name()))
+	}
+	rb.current.end = now
+	rb.segments = append(rb.segments, *rb.current)
+	rb.current = nil
+	rb.w = nil
+}
+
+// expire deletes all closed segments that ended before the retention.
+// Must hold the lock.
+func (rb *ringBuffer) expire(now time.Time) {
+	if rb.reading > 0 {
+		return
+	}
+	cutoff := now.Add(-rb.retention)
+	i := 0
+	for ; i < len(rb.segments) && rb.segments[i].end.Before(cutoff); i++ {
+		if err := os.Remove(rb.segments[i].path); err != nil && !os.IsNotExist(err) {
+			rb.diag.Error("failed to remove ring buffer segment", err, keyvalue.KV("ring_buffer", rb.name()))
+		}
+	}
+	rb.segments = rb.segments[i:]
+}
+
+// snapshot writes the retained data that ended within duration of now to w.
+// A zero duration writes all retained data.
+// The segments are gzip members so they are concatenated as is.
+func (rb *ringBuffer) snapshot(w io.Writer, duration time.Duration, now time.Time) (int, error) {
+	rb.mu.Lock()
+	rb.rotate(now)
+	rb.expire(now)
+	var segments []ringSegment
+	for _, s := range rb.segments {
+		if duration > 0 && s.end.Before(now.Add(-duration)) {
+			continue
+		}
+		segments = append(segments, s)
+	}
+	rb.reading++
+	rb.mu.Unlock()
+
+	defer func() {
+		rb.mu.Lock()
+		rb.reading--
+		rb.mu.Unlock()
+	}()
+
+	for _, s := range segments {
+		if err := copySegment(w, s.path); err != nil {
+			return 0, errors.Wrapf(err, "copying ring buffer segment %s", s.path)
+		}
+	}
+	return len(segments), nil
+}
+
+func copySegment(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func (s *Service) openRingBuffers() error {
+	for _, c := range s.ringBufferConfigs {
+		rb, err := newRingBuffer(c, s.saveDir, s.diag)
+		if err != nil {
+			return errors.Wrapf(err, "creating ring buffer for %s.%s", c.Database, c.RetentionPolicy)
+		}
+		if err := rb.open(s.TaskMaster); err != nil {
+			return errors.Wrapf(err, "opening ring buffer for %s", rb.name())
+		}
+		s.ringBuffers[rb.name()] = rb
+	}
+	return nil
+}
+
+func (s *Service) closeRingBuffers() {
+	for name, rb := range s.ringBuffers {
+		s.TaskMaster.DelFork(ringBufferForkPrefix + name)
+		rb.wait()
+		delete(s.ringBuffers, name)
+	}
+}
+
+func (s *Service) handleRecordRingBuffer(w http.ResponseWriter, r *http.Request) {
+	var opt kclient.RecordRingBufferOptions
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&opt)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if opt.ID == "" {
+		opt.ID = uuid.New().String()
+	}
+	if !validID.MatchString(opt.ID) {
+		httpd.HttpError(w, fmt.Sprintf("recording ID must contain only letters, numbers, '-', '.' and '_'. %q", opt.ID), true, http.StatusBadRequest)
+		return
+	}
+	if opt.Duration < 0 {
+		httpd.HttpError(w, fmt.Sprintf("duration must not be negative, got %v", time.Duration(opt.Duration)), true, http.StatusBadRequest)
+		return
+	}
+	rb, ok := s.ringBuffers[opt.Database+"."+opt.RetentionPolicy]
+	if !ok {
+		httpd.HttpError(w, fmt.Sprintf("no ring buffer configured for %s.%s", opt.Database, opt.RetentionPolicy), true, http.StatusNotFound)
+		return
+	}
+	dataUrl := s.dataURLFromID(opt.ID, streamEXT)
+
+	recording := Recording{
+		ID:      opt.ID,
+		DataURL: dataUrl.String(),
+		Type:    StreamRecording,
+		Date:    time.Now(),
+		Status:  Running,
+	}
+	err = s.recordings.Create(recording)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+
+	ds, _ := s.parseDataSourceURL(dataUrl.String())
+	err = s.doRecordRingBuffer(rb, localPath(ds), time.Duration(opt.Duration))
+	s.updateRecordingResult(recording, ds, err)
+	recording, err = s.recordings.Get(opt.ID)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(httpd.MarshalJSON(convertRecording(recording), true))
+}
+
+// Save the data retained by a ring buffer as a stream recording.
+func (s *Service) doRecordRingBuffer(rb *ringBuffer, path string, duration time.Duration) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create recording file: %s", err)
+	}
+	defer f.Close()
+	n, err := rb.snapshot(f, duration, time.Now())
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// Write an empty gzip member so the recording can still be read.
+		gz := gzip.NewWriter(f)
+		return gz.Close()
+	}
+	return nil
+}
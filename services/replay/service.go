@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
@@ -15,24 +16,30 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/kapacitor"
+	"github.com/influxdata/kapacitor/alert"
+	"github.com/influxdata/kapacitor/auth"
 	kclient "github.com/influxdata/kapacitor/client/v1"
 	"github.com/influxdata/kapacitor/clock"
 	"github.com/influxdata/kapacitor/edge"
 	"github.com/influxdata/kapacitor/influxdb"
 	"github.com/influxdata/kapacitor/keyvalue"
 	"github.com/influxdata/kapacitor/models"
+	alertservice "github.com/influxdata/kapacitor/services/alert"
 	"github.com/influxdata/kapacitor/services/httpd"
 	"github.com/influxdata/kapacitor/services/storage"
+	"github.com/influxdata/kapacitor/tick"
 	"github.com/influxdata/kapacitor/uuid"
 	"github.com/pkg/errors"
 )
 
 const streamEXT = ".srpl"
 const batchEXT = ".brpl"
+const alertEXT = ".arpl"
 
 const precision = "n"
 
@@ -59,6 +66,11 @@ type Diagnostic interface {
 // Handles recording, starting, and waiting on replays
 type Service struct {
 	saveDir string
+	// Object storage location of new recordings, nil if stored only in saveDir.
+	storageURL *url.URL
+
+	retention              time.Duration
+	retentionCheckInterval time.Duration
 
 	recordings RecordingDAO
 	replays    ReplayDAO
@@ -71,6 +83,7 @@ type Service struct {
 	}
 	TaskStore interface {
 		Load(id string) (*kapacitor.Task, error)
+		Compile(id, templateID, tickscript string, vars kclient.Vars, dbrps []kclient.DBRP) (*kapacitor.Task, error)
 	}
 	HTTPDService interface {
 		AddRoutes([]httpd.Route) error
@@ -84,22 +97,51 @@ type Service struct {
 		Set(*kapacitor.TaskMaster)
 		Delete(*kapacitor.TaskMaster)
 	}
+	AlertService interface {
+		RegisterAnonHandler(topic string, h alert.Handler)
+		DeregisterAnonHandler(topic string, h alert.Handler)
+		NewHandler(spec alertservice.HandlerSpec) (alert.Handler, error)
+	}
 	TaskMaster interface {
 		NewFork(name string, dbrps []kapacitor.DBRP, measurements []string) (edge.StatsEdge, error)
 		DelFork(name string)
 		New(name string) *kapacitor.TaskMaster
 		Stream(name string) (kapacitor.StreamCollector, error)
+		NewTask(id, script string, tt kapacitor.TaskType, dbrps []kapacitor.DBRP, snapshotInterval time.Duration, vars map[string]tick.Var) (*kapacitor.Task, error)
 	}
 
+	debugMu       sync.Mutex
+	debugSessions map[string]*debugSession
+
+	shadowMu sync.Mutex
+	shadows  map[string]*shadow
+
+	ringBufferConfigs []RingBufferConfig
+	ringBuffers       map[string]*ringBuffer
+
+	closing chan struct{}
+	wg      sync.WaitGroup
+
 	diag Diagnostic
 }
 
 // Create a new replay master.
 func NewService(conf Config, d Diagnostic) *Service {
-	return &Service{
-		saveDir: conf.Dir,
-		diag:    d,
+	s := &Service{
+		saveDir:                conf.Dir,
+		retention:              time.Duration(conf.Retention),
+		retentionCheckInterval: time.Duration(conf.RetentionCheckInterval),
+		debugSessions:          make(map[string]*debugSession),
+		shadows:                make(map[string]*shadow),
+		ringBufferConfigs:      conf.RingBuffers,
+		ringBuffers:            make(map[string]*ringBuffer),
+		diag:                   d,
+	}
+	if conf.StorageURL != "" {
+		// The URL has already been validated.
+		s.storageURL, _ = url.Parse(conf.StorageURL)
 	}
+	return s
 }
 
 const (
@@ -141,17 +183,29 @@ func (s *Service) Open() error {
 	s.markFailedRecordings()
 	s.markFailedReplays()
 
+	if err := s.openRingBuffers(); err != nil {
+		return err
+	}
+
+	s.closing = make(chan struct{})
+	if s.retention > 0 {
+		s.wg.Add(1)
+		go s.runRetention(s.retention, s.retentionCheckInterval)
+	}
+
 	// Setup routes
 	s.routes = []httpd.Route{
 		{
 			Method:      "GET",
 			Pattern:     recordingsPathAnchored,
 			HandlerFunc: s.handleRecording,
+			Scope:       auth.ReplaysReadScope,
 		},
 		{
 			Method:      "DELETE",
 			Pattern:     recordingsPathAnchored,
 			HandlerFunc: s.handleDeleteRecording,
+			Scope:       auth.ReplaysWriteScope,
 		},
 		{
 			Method:      "OPTIONS",
@@ -162,31 +216,55 @@ func (s *Service) Open() error {
 			Method:      "GET",
 			Pattern:     recordingsPath,
 			HandlerFunc: s.handleListRecordings,
+			Scope:       auth.ReplaysReadScope,
 		},
 		{
 			Method:      "POST",
 			Pattern:     recordStreamPath,
 			HandlerFunc: s.handleRecordStream,
+			Scope:       auth.ReplaysWriteScope,
 		},
 		{
 			Method:      "POST",
 			Pattern:     recordBatchPath,
 			HandlerFunc: s.handleRecordBatch,
+			Scope:       auth.ReplaysWriteScope,
 		},
 		{
 			Method:      "POST",
 			Pattern:     recordQueryPath,
 			HandlerFunc: s.handleRecordQuery,
+			Scope:       auth.ReplaysWriteScope,
+		},
+		{
+			Method:      "POST",
+			Pattern:     recordRingBufferPath,
+			HandlerFunc: s.handleRecordRingBuffer,
+			Scope:       auth.ReplaysWriteScope,
+		},
+		{
+			Method:      "POST",
+			Pattern:     recordImportPath,
+			HandlerFunc: s.handleRecordImport,
+			Scope:       auth.ReplaysWriteScope,
+		},
+		{
+			Method:      "POST",
+			Pattern:     recordAlertsPath,
+			HandlerFunc: s.handleRecordAlerts,
+			Scope:       auth.ReplaysWriteScope,
 		},
 		{
 			Method:      "GET",
 			Pattern:     replaysPathAnchored,
 			HandlerFunc: s.handleReplay,
+			Scope:       auth.ReplaysReadScope,
 		},
 		{
 			Method:      "DELETE",
 			Pattern:     replaysPathAnchored,
 			HandlerFunc: s.handleDeleteReplay,
+			Scope:       auth.ReplaysWriteScope,
 		},
 		{
 			Method:      "OPTIONS",
@@ -197,21 +275,119 @@ func (s *Service) Open() error {
 			Method:      "GET",
 			Pattern:     replaysPath,
 			HandlerFunc: s.handleListReplays,
+			Scope:       auth.ReplaysReadScope,
 		},
 		{
 			Method:      "POST",
 			Pattern:     replaysPath,
 			HandlerFunc: s.handleCreateReplay,
+			Scope:       auth.ReplaysWriteScope,
 		},
 		{
 			Method:      "POST",
 			Pattern:     replayBatchPath,
 			HandlerFunc: s.handleReplayBatch,
+			Scope:       auth.ReplaysWriteScope,
 		},
 		{
 			Method:      "POST",
 			Pattern:     replayQueryPath,
 			HandlerFunc: s.handleReplayQuery,
+			Scope:       auth.ReplaysWriteScope,
+		},
+		{
+			Method:      "POST",
+			Pattern:     replayDiffPath,
+			HandlerFunc: s.handleReplayDiff,
+			Scope:       auth.ReplaysWriteScope,
+		},
+		{
+			Method:      "POST",
+			Pattern:     replayAlertsPath,
+			HandlerFunc: s.handleReplayAlerts,
+			Scope:       auth.ReplaysWriteScope,
+		},
+		{
+			Method:      "POST",
+			Pattern:     evaluatePath,
+			HandlerFunc: s.handleEvaluate,
+			Scope:       auth.ReplaysWriteScope,
+		},
+		{
+			Method:      "POST",
+			Pattern:     taskTestPath,
+			HandlerFunc: s.handleTaskTest,
+			Scope:       auth.ReplaysWriteScope,
+		},
+		{
+			Method:      "GET",
+			Pattern:     shadowsPathAnchored,
+			HandlerFunc: s.handleShadow,
+			Scope:       auth.ReplaysReadScope,
+		},
+		{
+			Method:      "PATCH",
+			Pattern:     shadowsPathAnchored,
+			HandlerFunc: s.handleUpdateShadow,
+			Scope:       auth.ReplaysWriteScope,
+		},
+		{
+			Method:      "DELETE",
+			Pattern:     shadowsPathAnchored,
+			HandlerFunc: s.handleDeleteShadow,
+			Scope:       auth.ReplaysWriteScope,
+		},
+		{
+			Method:      "OPTIONS",
+			Pattern:     shadowsPathAnchored,
+			HandlerFunc: httpd.ServeOptions,
+		},
+		{
+			Method:      "GET",
+			Pattern:     shadowsPath,
+			HandlerFunc: s.handleListShadows,
+			Scope:       auth.ReplaysReadScope,
+		},
+		{
+			Method:      "POST",
+			Pattern:     shadowsPath,
+			HandlerFunc: s.handleCreateShadow,
+			Scope:       auth.ReplaysWriteScope,
+		},
+		{
+			Method:      "GET",
+			Pattern:     debugPathAnchored,
+			HandlerFunc: s.handleDebugSession,
+			Scope:       auth.ReplaysReadScope,
+		},
+		{
+			Method:      "PATCH",
+			Pattern:     debugPathAnchored,
+			HandlerFunc: s.handleUpdateDebugSession,
+			Scope:       auth.ReplaysWriteScope,
+		},
+		{
+			Method:      "DELETE",
+			Pattern:     debugPathAnchored,
+			HandlerFunc: s.handleDeleteDebugSession,
+			Scope:       auth.ReplaysWriteScope,
+		},
+		{
+			Method:      "OPTIONS",
+			Pattern:     debugPathAnchored,
+			HandlerFunc: httpd.ServeOptions,
+		},
+		{
+			Method:      "GET",
+			Pattern:     debugPath,
+			HandlerFunc: s.handleListDebugSessions,
+			Scope:       auth.ReplaysReadScope,
+		},
+		{
+			Method:      "POST",
+			Pattern:     debugPath,
+			HandlerFunc: s.handleCreateDebugSession,
+			Scope:       auth.ReplaysWriteScope,
 		},
 	}
 
@@ -247,6 +423,8 @@ func (s *Service) syncRecordingMetadata() error {
 			typ = StreamRecording
 		case batchEXT:
 			typ = BatchRecording
+		case alertEXT:
+			typ = AlertRecording
 		default:
 			s.diag.Error("unknown file type in replay dir", fmt.Errorf("%s has unknown file type", name))
 			continue
@@ -341,6 +519,13 @@ func (s *Service) markFailedReplays() {
 
 func (s *Service) Close() error {
 	s.HTTPDService.DelRoutes(s.routes)
+	s.closeDebugSessions()
+	s.closeShadows()
+	s.closeRingBuffers()
+	if s.closing != nil {
+		close(s.closing)
+		s.wg.Wait()
+	}
 	return nil
 }
 
@@ -364,6 +549,8 @@ func convertRecording(recording Recording) kclient.Recording {
 		typ = kclient.StreamTask
 	case BatchRecording:
 		typ = kclient.BatchTask
+	case AlertRecording:
+		typ = kclient.AlertEvents
 	}
 	var status kclient.Status
 	switch recording.Status {
@@ -427,6 +614,7 @@ func convertReplay(replay Replay) kclient.Replay {
 		Task:           replay.TaskID,
 		RecordingTime:  replay.RecordingTime,
 		Clock:          clk,
+		Speed:          replay.Speed,
 		Date:           replay.Date,
 		Error:          replay.Error,
 		Status:         status,
@@ -499,6 +687,8 @@ func (s *Service) handleListRecordings(w http.ResponseWriter, r *http.Request) {
 					value = kclient.StreamTask
 				case BatchRecording:
 					value = kclient.BatchTask
+				case AlertRecording:
+					value = kclient.AlertEvents
 				}
 			case "size":
 				value = recording.Size
@@ -566,18 +756,7 @@ func (s *Service) handleDeleteRecording(w http.ResponseWriter, r *http.Request)
 		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
 		return
 	}
-	err = s.recordings.Delete(rid)
-	if err != nil {
-		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
-		return
-	}
-	ds, err := parseDataSourceURL(recording.DataURL)
-	if err != nil {
-		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
-		return
-	}
-
-	err = ds.Remove()
+	err = s.deleteRecording(recording)
 	if err != nil {
 		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
 		return
@@ -587,6 +766,9 @@ func (s *Service) handleDeleteRecording(w http.ResponseWriter, r *http.Request)
 }
 
 func (s *Service) dataURLFromID(id, ext string) url.URL {
+	if s.storageURL != nil {
+		return objectURL(*s.storageURL, id+ext)
+	}
 	return url.URL{
 		Scheme: "file",
 		Path:   filepath.ToSlash(filepath.Join(s.saveDir, id+ext)),
@@ -630,7 +812,7 @@ func (s *Service) handleRecordStream(w http.ResponseWriter, r *http.Request) {
 
 	// Spawn routine to perform actual recording.
 	go func(recording Recording) {
-		ds, _ := parseDataSourceURL(dataUrl.String())
+		ds, _ := s.parseDataSourceURL(dataUrl.String())
 		err := s.doRecordStream(opt.ID, ds, opt.Stop, t.DBRPs, t.Measurements())
 		s.updateRecordingResult(recording, ds, err)
 	}(recording)
@@ -684,7 +866,7 @@ func (s *Service) handleRecordBatch(w http.ResponseWriter, req *http.Request) {
 	}
 
 	go func(recording Recording) {
-		ds, _ := parseDataSourceURL(dataUrl.String())
+		ds, _ := s.parseDataSourceURL(dataUrl.String())
 		err := s.doRecordBatch(ds, t, opt.Start, opt.Stop)
 		s.updateRecordingResult(recording, ds, err)
 	}(recording)
@@ -737,7 +919,7 @@ func (s *Service) handleRecordQuery(w http.ResponseWriter, req *http.Request) {
 	}
 
 	go func(recording Recording) {
-		ds, _ := parseDataSourceURL(dataUrl.String())
+		ds, _ := s.parseDataSourceURL(dataUrl.String())
 		err := s.doRecordQuery(ds, opt.Query, typ, opt.Cluster)
 		s.updateRecordingResult(recording, ds, err)
 	}(recording)
@@ -752,6 +934,12 @@ func (s *Service) updateRecordingResult(recording Recording, ds DataSource, err
 		recording.Status = Failed
 		recording.Error = err.Error()
 	}
+	if o, ok := ds.(objectSource); ok && err == nil {
+		if err := o.upload(); err != nil {
+			recording.Status = Failed
+			recording.Error = err.Error()
+		}
+	}
 	recording.Date = time.Now()
 	recording.Progress = 1.0
 	recording.Size, err = ds.Size()
@@ -818,6 +1006,7 @@ var allReplayFields = []string{
 	"task",
 	"recording-time",
 	"clock",
+	"speed",
 	"date",
 	"error",
 	"status",
@@ -884,6 +1073,8 @@ func (s *Service) handleListReplays(w http.ResponseWriter, r *http.Request) {
 				case Real:
 					value = kclient.Real
 				}
+			case "speed":
+				value = replay.Speed
 			case "date":
 				value = replay.Date
 			case "error":
@@ -940,18 +1131,14 @@ func (s *Service) handleCreateReplay(w http.ResponseWriter, req *http.Request) {
 		httpd.HttpError(w, "recording not found: "+err.Error(), true, http.StatusNotFound)
 		return
 	}
+	if recording.Type == AlertRecording {
+		httpd.HttpError(w, fmt.Sprintf("recording %s contains alert events, it can only be replayed to alert handlers", recording.ID), true, http.StatusBadRequest)
+		return
+	}
 
-	var clk clock.Clock
-	var clockType Clock
-	switch opt.Clock {
-	case kclient.Real:
-		clk = clock.Wall()
-		clockType = Real
-	case kclient.Fast:
-		clk = clock.Fast()
-		clockType = Fast
-	default:
-		httpd.HttpError(w, fmt.Sprintf("invalid clock type %v", opt.Clock), true, http.StatusBadRequest)
+	clk, clockType, err := replayClock(opt.Clock, opt.Speed)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
 		return
 	}
 
@@ -962,6 +1149,7 @@ func (s *Service) handleCreateReplay(w http.ResponseWriter, req *http.Request) {
 		TaskID:         opt.Task,
 		RecordingTime:  opt.RecordingTime,
 		Clock:          clockType,
+		Speed:          opt.Speed,
 		Date:           time.Now(),
 		Status:         Running,
 		ExecutionStats: ExecutionStats{},
@@ -1001,17 +1189,9 @@ func (s *Service) handleReplayBatch(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	var clk clock.Clock
-	var clockType Clock
-	switch opt.Clock {
-	case kclient.Real:
-		clk = clock.Wall()
-		clockType = Real
-	case kclient.Fast:
-		clk = clock.Fast()
-		clockType = Fast
-	default:
-		httpd.HttpError(w, fmt.Sprintf("invalid clock type %v", opt.Clock), true, http.StatusBadRequest)
+	clk, clockType, err := replayClock(opt.Clock, opt.Speed)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
 		return
 	}
 	if t.Type == kapacitor.StreamTask {
@@ -1025,6 +1205,7 @@ func (s *Service) handleReplayBatch(w http.ResponseWriter, req *http.Request) {
 		TaskID:        opt.Task,
 		RecordingTime: opt.RecordingTime,
 		Clock:         clockType,
+		Speed:         opt.Speed,
 		Date:          time.Now(),
 		Status:        Running,
 	}
@@ -1069,17 +1250,9 @@ func (r *Service) handleReplayQuery(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	var clk clock.Clock
-	var clockType Clock
-	switch opt.Clock {
-	case kclient.Real:
-		clk = clock.Wall()
-		clockType = Real
-	case kclient.Fast:
-		clk = clock.Fast()
-		clockType = Fast
-	default:
-		httpd.HttpError(w, fmt.Sprintf("invalid clock type %v", opt.Clock), true, http.StatusBadRequest)
+	clk, clockType, err := replayClock(opt.Clock, opt.Speed)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
 		return
 	}
 
@@ -1088,6 +1261,7 @@ func (r *Service) handleReplayQuery(w http.ResponseWriter, req *http.Request) {
 		TaskID:        opt.Task,
 		RecordingTime: opt.RecordingTime,
 		Clock:         clockType,
+		Speed:         opt.Speed,
 		Date:          time.Now(),
 		Status:        Running,
 	}
@@ -1106,12 +1280,46 @@ func (r *Service) handleReplayQuery(w http.ResponseWriter, req *http.Request) {
 	w.Write(httpd.MarshalJSON(convertReplay(replay), true))
 }
 
+// replayClock returns the clock to replay with.
+// The real clock runs speed times faster than realtime, a zero speed is realtime.
+func replayClock(c kclient.Clock, speed float64) (clock.Clock, Clock, error) {
+	if speed < 0 || math.IsNaN(speed) || math.IsInf(speed, 0) {
+		return nil, 0, fmt.Errorf("invalid speed %v, must be a positive number", speed)
+	}
+	switch c {
+	case kclient.Real:
+		if speed != 0 && speed != 1 {
+			return clock.Scaled(speed), Real, nil
+		}
+		return clock.Wall(), Real, nil
+	case kclient.Fast:
+		if speed != 0 {
+			return nil, 0, errors.New("speed can only be set for the real clock")
+		}
+		return clock.Fast(), Fast, nil
+	default:
+		return nil, 0, fmt.Errorf("invalid clock type %v", c)
+	}
+}
+
 func (r *Service) doReplayFromRecording(replay *Replay, task *kapacitor.Task, recording Recording, clk clock.Clock, recTime bool) error {
-	dataSource, err := parseDataSourceURL(recording.DataURL)
+	runReplay, err := r.replayRecording(task, recording, clk, recTime)
 	if err != nil {
-		return errors.Wrap(err, "load data source")
+		return err
 	}
-	runReplay := func(tm *kapacitor.TaskMaster) error {
+	return r.doReplay(replay, task, runReplay)
+}
+
+// replayRecording returns a function that replays the data of a recording to a task.
+func (r *Service) replayRecording(task *kapacitor.Task, recording Recording, clk clock.Clock, recTime bool) (func(tm *kapacitor.TaskMaster) error, error) {
+	if recording.Type == AlertRecording {
+		return nil, fmt.Errorf("recording %s contains alert events, it can only be replayed to alert handlers", recording.ID)
+	}
+	dataSource, err := r.parseDataSourceURL(recording.DataURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "load data source")
+	}
+	return func(tm *kapacitor.TaskMaster) error {
 		var replayC <-chan error
 		switch task.Type {
 		case kapacitor.StreamTask:
@@ -1133,9 +1341,7 @@ func (r *Service) doReplayFromRecording(replay *Replay, task *kapacitor.Task, re
 			replayC = kapacitor.ReplayBatchFromIO(clk, fs, collectors, recTime)
 		}
 		return <-replayC
-	}
-	return r.doReplay(replay, task, runReplay)
-
+	}, nil
 }
 
 func (r *Service) doLiveBatchReplay(replay *Replay, task *kapacitor.Task, clk clock.Clock, recTime bool, start, stop time.Time) error {
@@ -1200,8 +1406,25 @@ func (r *Service) doLiveQueryReplay(replay *Replay, task *kapacitor.Task, clk cl
 }
 
 func (r *Service) doReplay(replay *Replay, task *kapacitor.Task, runReplay func(tm *kapacitor.TaskMaster) error) error {
+	stats, err := r.replayTask(replay.ID, task, nil, runReplay)
+	if err != nil {
+		return err
+	}
+
+	// Set stats on replay
+	replay.ExecutionStats.TaskStats = stats.TaskStats
+	replay.ExecutionStats.NodeStats = stats.NodeStats
+	return nil
+}
+
+// replayTask runs the replay of a task in a new isolated task master with the given ID.
+// If not nil, setup is called with the task master before the task is started.
+func (r *Service) replayTask(id string, task *kapacitor.Task, setup func(tm *kapacitor.TaskMaster), runReplay func(tm *kapacitor.TaskMaster) error) (kapacitor.ExecutionStats, error) {
 	// Create new isolated task master
-	tm := r.TaskMaster.New(replay.ID)
+	tm := r.TaskMaster.New(id)
+	if setup != nil {
+		setup(tm)
+	}
 	r.TaskMasterLookup.Set(tm)
 	defer r.TaskMasterLookup.Delete(tm)
 
@@ -1209,7 +1432,7 @@ func (r *Service) doReplay(replay *Replay, task *kapacitor.Task, runReplay func(
 	defer tm.Close()
 	et, err := tm.StartTask(task)
 	if err != nil {
-		return errors.Wrap(err, "task start")
+		return kapacitor.ExecutionStats{}, errors.Wrap(err, "task start")
 	}
 
 	// This will force the task to stop or do nothing if it already stopped.
@@ -1223,17 +1446,13 @@ func (r *Service) doReplay(replay *Replay, task *kapacitor.Task, runReplay func(
 	// Run the replay
 	err = runReplay(tm)
 	if err != nil {
-		return errors.Wrap(err, "running replay")
+		return kapacitor.ExecutionStats{}, errors.Wrap(err, "running replay")
 	}
 	stats, err := tm.ExecutionStats(task.ID)
 	if err != nil {
-		return errors.Wrap(err, "getting executing stats replay")
+		return kapacitor.ExecutionStats{}, errors.Wrap(err, "getting executing stats replay")
 	}
 
-	// Set stats on replay
-	replay.ExecutionStats.TaskStats = stats.TaskStats
-	replay.ExecutionStats.NodeStats = stats.NodeStats
-
 	// Drain tm so the task can finish
 	tm.Drain()
 
@@ -1243,15 +1462,15 @@ func (r *Service) doReplay(replay *Replay, task *kapacitor.Task, runReplay func(
 	// Check for error on task
 	err = et.Wait()
 	if err != nil {
-		return errors.Wrap(err, "task run")
+		return stats, errors.Wrap(err, "task run")
 	}
 
 	// Call close explicitly to check for error
 	err = tm.Close()
 	if err != nil {
-		return errors.Wrap(err, "task master close")
+		return stats, errors.Wrap(err, "task master close")
 	}
-	return nil
+	return stats, nil
 }
 
 // wrap gzipped writer and underlying file
@@ -1653,7 +1872,7 @@ type DataSource interface {
 
 type fileSource string
 
-func parseDataSourceURL(rawurl string) (DataSource, error) {
+func (s *Service) parseDataSourceURL(rawurl string) (DataSource, error) {
 	u, err := url.Parse(rawurl)
 	if err != nil {
 		return nil, err
@@ -1661,6 +1880,8 @@ func parseDataSourceURL(rawurl string) (DataSource, error) {
 	switch u.Scheme {
 	case "file":
 		return fileSource(getFilePathFromUrl(u)), nil
+	case "s3", "gs", "az":
+		return objectSource{url: rawurl, cache: fileSource(s.cachePath(u))}, nil
 	default:
 		return nil, fmt.Errorf("unsupported data source scheme %s", u.Scheme)
 	}
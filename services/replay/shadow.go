@@ -0,0 +1,300 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor"
+	kclient "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	shadowsPath         = "/shadows"
+	shadowsPathAnchored = "/shadows/"
+
+	// Prefix of the task masters and forks of shadows,
+	// it cannot be part of a replay ID so the names never collide.
+	shadowPrefix = "shadow:"
+
+	// Number of the most recent alert events and points kept in the report of a shadow.
+	shadowReportLimit = 1000
+)
+
+// A shadow runs a task against a mirrored copy of the live stream for a limited time.
+// Alert events and InfluxDB writes of the task are only reported, never delivered.
+// Shadows are only kept in memory.
+type shadow struct {
+	id     string
+	taskID string
+	start  time.Time
+	stop   time.Time
+
+	out outputRecorder
+
+	mu       sync.Mutex
+	points   int64
+	finished bool
+	err      error
+
+	// stopping is closed to stop mirroring the stream
+	stopping chan struct{}
+	stopOnce sync.Once
+	// done is closed once the task stopped
+	done chan struct{}
+}
+
+func (s *Service) shadowFromPath(p string) (*shadow, error) {
+	id := strings.TrimPrefix(p, httpd.BasePath+shadowsPathAnchored)
+	if id == "" || id == p {
+		return nil, errors.New("must specify shadow id on path")
+	}
+	s.shadowMu.Lock()
+	defer s.shadowMu.Unlock()
+	sh, ok := s.shadows[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown shadow %s", id)
+	}
+	return sh, nil
+}
+
+func shadowLink(id string) kclient.Link {
+	return kclient.Link{Relation: kclient.Self, Href: path.Join(httpd.BasePath, shadowsPath, id)}
+}
+
+func (s *Service) handleCreateShadow(w http.ResponseWriter, r *http.Request) {
+	var opt kclient.CreateShadowOptions
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if opt.ID == "" {
+		opt.ID = uuid.New().String()
+	}
+	if !validID.MatchString(opt.ID) {
+		httpd.HttpError(w, fmt.Sprintf("shadow ID must contain only letters, numbers, '-', '.' and '_'. %q", opt.ID), true, http.StatusBadRequest)
+		return
+	}
+	if opt.Duration <= 0 {
+		httpd.HttpError(w, fmt.Sprintf("duration must be positive, got %v", time.Duration(opt.Duration)), true, http.StatusBadRequest)
+		return
+	}
+	t, err := s.TaskStore.Load(opt.Task)
+	if err != nil {
+		httpd.HttpError(w, "task load: "+err.Error(), true, http.StatusNotFound)
+		return
+	}
+	if t.Type != kapacitor.StreamTask {
+		httpd.HttpError(w, fmt.Sprintf("only stream tasks can be shadowed, %s is a %v task", t.ID, t.Type), true, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	sh := &shadow{
+		id:       opt.ID,
+		taskID:   t.ID,
+		start:    now,
+		stop:     now.Add(time.Duration(opt.Duration)),
+		out:      outputRecorder{limit: shadowReportLimit},
+		stopping: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	s.shadowMu.Lock()
+	if _, ok := s.shadows[sh.id]; ok {
+		s.shadowMu.Unlock()
+		httpd.HttpError(w, fmt.Sprintf("shadow %s already exists", sh.id), true, http.StatusBadRequest)
+		return
+	}
+	s.shadows[sh.id] = sh
+	s.shadowMu.Unlock()
+
+	started := make(chan struct{})
+	go func() {
+		setup := func(tm *kapacitor.TaskMaster) { sh.out.setup(tm, t) }
+		_, err := s.replayTask(shadowPrefix+sh.id, t, setup, func(tm *kapacitor.TaskMaster) error {
+			return s.runShadow(sh, t, tm, started)
+		})
+		sh.finish(err)
+	}()
+	// Wait for the fork so that no points are missed after the shadow is created.
+	select {
+	case <-started:
+	case <-sh.done:
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(httpd.MarshalJSON(sh.convert(), true))
+}
+
+// runShadow forwards the live stream of the task's dbrps to the shadow task master until the shadow is stopped.
+func (s *Service) runShadow(sh *shadow, t *kapacitor.Task, tm *kapacitor.TaskMaster, started chan<- struct{}) error {
+	stream, err := tm.Stream(sh.id)
+	if err != nil {
+		close(started)
+		return errors.Wrap(err, "stream start")
+	}
+	defer stream.Close()
+	forkName := shadowPrefix + sh.id
+	e, err := s.TaskMaster.NewFork(forkName, t.DBRPs, t.Measurements())
+	if err != nil {
+		close(started)
+		return err
+	}
+	close(started)
+
+	timer := time.AfterFunc(time.Until(sh.stop), sh.halt)
+	defer timer.Stop()
+	go func() {
+		<-sh.stopping
+		e.Abort()
+		s.TaskMaster.DelFork(forkName)
+	}()
+	// Make sure the fork is removed if the task fails.
+	defer sh.halt()
+
+	for m, ok := e.Emit(); ok; m, ok = e.Emit() {
+		p, isPoint := m.(edge.PointMessage)
+		if !isPoint {
+			continue
+		}
+		if err := stream.CollectPoint(p); err != nil {
+			return err
+		}
+		sh.mu.Lock()
+		sh.points++
+		sh.mu.Unlock()
+	}
+	return nil
+}
+
+func (s *Service) handleShadow(w http.ResponseWriter, r *http.Request) {
+	sh, err := s.shadowFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	w.Write(httpd.MarshalJSON(sh.convert(), true))
+}
+
+func (s *Service) handleListShadows(w http.ResponseWriter, r *http.Request) {
+	s.shadowMu.Lock()
+	shadows := make([]*shadow, 0, len(s.shadows))
+	for _, sh := range s.shadows {
+		shadows = append(shadows, sh)
+	}
+	s.shadowMu.Unlock()
+	sort.Slice(shadows, func(i, j int) bool { return shadows[i].id < shadows[j].id })
+
+	type response struct {
+		Shadows []kclient.Shadow `json:"shadows"`
+	}
+	res := response{Shadows: make([]kclient.Shadow, len(shadows))}
+	for i, sh := range shadows {
+		res.Shadows[i] = sh.convert()
+	}
+	w.Write(httpd.MarshalJSON(res, true))
+}
+
+func (s *Service) handleUpdateShadow(w http.ResponseWriter, r *http.Request) {
+	sh, err := s.shadowFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	var opt kclient.UpdateShadowOptions
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	switch opt.Action {
+	case "":
+	case kclient.ShadowStop:
+		sh.halt()
+		<-sh.done
+	default:
+		httpd.HttpError(w, fmt.Sprintf("invalid action %q", opt.Action), true, http.StatusBadRequest)
+		return
+	}
+	w.Write(httpd.MarshalJSON(sh.convert(), true))
+}
+
+func (s *Service) handleDeleteShadow(w http.ResponseWriter, r *http.Request) {
+	sh, err := s.shadowFromPath(r.URL.Path)
+	if err == nil {
+		s.shadowMu.Lock()
+		delete(s.shadows, sh.id)
+		s.shadowMu.Unlock()
+		sh.halt()
+		<-sh.done
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// closeShadows stops all shadows and waits for their tasks to stop.
+func (s *Service) closeShadows() {
+	s.shadowMu.Lock()
+	defer s.shadowMu.Unlock()
+	for id, sh := range s.shadows {
+		sh.halt()
+		<-sh.done
+		delete(s.shadows, id)
+	}
+}
+
+// halt stops mirroring the stream to the shadow, letting its task finish.
+func (sh *shadow) halt() {
+	sh.stopOnce.Do(func() { close(sh.stopping) })
+}
+
+func (sh *shadow) finish(err error) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.finished = true
+	sh.err = err
+	close(sh.done)
+}
+
+func (sh *shadow) convert() kclient.Shadow {
+	sh.mu.Lock()
+	report := kclient.Shadow{
+		Link:   shadowLink(sh.id),
+		ID:     sh.id,
+		Task:   sh.taskID,
+		Start:  sh.start,
+		Stop:   sh.stop,
+		Status: kclient.Running,
+		Points: sh.points,
+	}
+	if sh.finished {
+		report.Status = kclient.Finished
+		if sh.err != nil {
+			report.Status = kclient.Failed
+			report.Error = sh.err.Error()
+		}
+	}
+	sh.mu.Unlock()
+
+	sh.out.mu.Lock()
+	defer sh.out.mu.Unlock()
+	report.EventCount = sh.out.eventCount
+	report.OutputCount = sh.out.pointCount
+	report.Events = make([]kclient.ShadowEvent, len(sh.out.events))
+	for i, e := range sh.out.events {
+		report.Events[i] = kclient.ShadowEvent{
+			Source:     sh.out.source(e.Topic),
+			ID:         e.State.ID,
+			EventState: *convertEventState(e.State),
+		}
+	}
+	return report
+}
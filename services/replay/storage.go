@@ -0,0 +1,170 @@
+package replay
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/objectstore"
+	"github.com/pkg/errors"
+)
+
+// remoteSchemes are the data URL schemes of recordings kept in object storage.
+var remoteSchemes = map[string]bool{
+	"s3": true,
+	"gs": true,
+	"az": true,
+}
+
+// objectURL returns the URL of the named object under the base URL, keeping its query.
+func objectURL(base url.URL, name string) url.URL {
+	base.Path = path.Join("/", base.Path, name)
+	return base
+}
+
+// objectSource is a recording kept in object storage.
+// The recording is written to and read from a local cache file,
+// which is uploaded once the recording has finished and downloaded on first read.
+type objectSource struct {
+	url   string
+	cache fileSource
+}
+
+func (s objectSource) Size() (int64, error) {
+	return s.cache.Size()
+}
+
+func (s objectSource) Remove() error {
+	if err := objectstore.Delete(context.Background(), s.url); err != nil {
+		return err
+	}
+	err := s.cache.Remove()
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s objectSource) StreamWriter() (io.WriteCloser, error) {
+	return s.cache.StreamWriter()
+}
+
+func (s objectSource) StreamReader() (io.ReadCloser, error) {
+	if err := s.fetch(); err != nil {
+		return nil, err
+	}
+	return s.cache.StreamReader()
+}
+
+func (s objectSource) BatchArchiver() (BatchArchiver, error) {
+	return s.cache.BatchArchiver()
+}
+
+func (s objectSource) BatchReaders() ([]io.ReadCloser, error) {
+	if err := s.fetch(); err != nil {
+		return nil, err
+	}
+	return s.cache.BatchReaders()
+}
+
+// upload copies the cached recording to object storage.
+func (s objectSource) upload() error {
+	data, err := ioutil.ReadFile(string(s.cache))
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(objectstore.Put(context.Background(), s.url, data, "application/octet-stream"), "uploading recording")
+}
+
+// fetch downloads the recording into the cache unless it is already cached.
+func (s objectSource) fetch() error {
+	if _, err := os.Stat(string(s.cache)); err == nil {
+		return nil
+	}
+	data, err := objectstore.Get(context.Background(), s.url)
+	if err != nil {
+		return errors.Wrap(err, "downloading recording")
+	}
+	// Write to a temporary file first so a failed download is not mistaken for a cached recording.
+	tmp := string(s.cache) + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, string(s.cache))
+}
+
+// localPath returns the path of the local file backing the data source.
+func localPath(ds DataSource) string {
+	switch ds := ds.(type) {
+	case objectSource:
+		return string(ds.cache)
+	case fileSource:
+		return string(ds)
+	default:
+		return ""
+	}
+}
+
+// runRetention periodically deletes recordings older than the retention.
+func (s *Service) runRetention(retention, interval time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.deleteExpiredRecordings(time.Now().Add(-retention))
+		select {
+		case <-s.closing:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deleteExpiredRecordings deletes all finished or failed recordings from before the cutoff.
+func (s *Service) deleteExpiredRecordings(cutoff time.Time) {
+	offset := 0
+	limit := 100
+	for {
+		recordings, err := s.recordings.List("", offset, limit)
+		if err != nil {
+			s.diag.Error("failed to list recordings", err)
+			return
+		}
+		for _, recording := range recordings {
+			if recording.Status == Running || !recording.Date.Before(cutoff) {
+				offset++
+				continue
+			}
+			if err := s.deleteRecording(recording); err != nil {
+				s.diag.Error("failed to delete expired recording", err, keyvalue.KV("recording_id", recording.ID))
+				offset++
+			}
+		}
+		if len(recordings) != limit {
+			return
+		}
+	}
+}
+
+// deleteRecording deletes the recording and its data.
+func (s *Service) deleteRecording(recording Recording) error {
+	if err := s.recordings.Delete(recording.ID); err != nil {
+		return err
+	}
+	ds, err := s.parseDataSourceURL(recording.DataURL)
+	if err != nil {
+		return err
+	}
+	return ds.Remove()
+}
+
+// cachePath returns the path in the save dir of the recording data stored at the URL.
+func (s *Service) cachePath(u *url.URL) string {
+	return filepath.Join(s.saveDir, path.Base(u.Path))
+}
@@ -0,0 +1,255 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	dbmodels "github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor"
+	kclient "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+)
+
+const (
+	taskTestPath = replaysPath + "/test"
+
+	// ID of the tasks of the tests of TICKscripts.
+	testTaskID = "test"
+)
+
+// handleTaskTest runs a test case of a task, template or TICKscript against its input points,
+// and compares the alert events and the points written to InfluxDB with the expected ones.
+func (s *Service) handleTaskTest(w http.ResponseWriter, req *http.Request) {
+	var test kclient.TaskTest
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&test); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	task, err := s.testTask(test)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if task.Type != kapacitor.StreamTask {
+		httpd.HttpError(w, "only stream tasks can be tested", true, http.StatusBadRequest)
+		return
+	}
+	if len(task.DBRPs) == 0 {
+		httpd.HttpError(w, "must specify dbrp", true, http.StatusBadRequest)
+		return
+	}
+	mps, err := testPoints(test)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	e, err := s.evaluate(task, maxEvaluateLimit, replayPoints(task, mps))
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	r := kclient.TaskTestResult{
+		Name:   test.Name,
+		Alerts: e.Events,
+		Points: e.Writes,
+	}
+	if e.EventCount > int64(len(e.Events)) || e.WriteCount > int64(len(e.Writes)) {
+		r.Failures = append(r.Failures, fmt.Sprintf("the test produced more than %d alert events or points", maxEvaluateLimit))
+	} else {
+		r.Failures = checkTaskTest(test, e)
+	}
+	r.Passed = len(r.Failures) == 0
+	w.Write(httpd.MarshalJSON(r, true))
+}
+
+// testTask creates the task of a test, which is not defined.
+func (s *Service) testTask(test kclient.TaskTest) (*kapacitor.Task, error) {
+	n := 0
+	for _, v := range []string{test.Task, test.Template, test.TICKscript} {
+		if v != "" {
+			n++
+		}
+	}
+	if n != 1 {
+		return nil, fmt.Errorf("must specify exactly one of task, template or script")
+	}
+	switch {
+	case test.Task != "":
+		if len(test.Vars) > 0 || len(test.DBRPs) > 0 {
+			return nil, fmt.Errorf("cannot specify vars or dbrps of a defined task")
+		}
+		task, err := s.TaskStore.Load(test.Task)
+		if err != nil {
+			return nil, fmt.Errorf("task load: %v", err)
+		}
+		return task, nil
+	case test.Template != "":
+		return s.TaskStore.Compile(test.Template, test.Template, "", test.Vars, test.DBRPs)
+	default:
+		return s.TaskStore.Compile(testTaskID, "", test.TICKscript, test.Vars, test.DBRPs)
+	}
+}
+
+// testPoints parses the input points of a test, ordered by time.
+func testPoints(test kclient.TaskTest) ([]dbmodels.Point, error) {
+	start := testStart(test)
+	mps := make([]dbmodels.Point, len(test.Points))
+	for i, p := range test.Points {
+		points, err := dbmodels.ParsePointsWithPrecision([]byte(p.Line), start.Add(time.Duration(p.At)), "n")
+		if err != nil {
+			return nil, fmt.Errorf("invalid point %d: %v", i, err)
+		}
+		if len(points) != 1 {
+			return nil, fmt.Errorf("invalid point %d: must be a single line", i)
+		}
+		mps[i] = points[0]
+	}
+	sort.SliceStable(mps, func(i, j int) bool {
+		return mps[i].Time().Before(mps[j].Time())
+	})
+	return mps, nil
+}
+
+// testStart returns the mocked time at which the test starts.
+func testStart(test kclient.TaskTest) time.Time {
+	if test.Time.IsZero() {
+		return time.Unix(0, 0).UTC()
+	}
+	return test.Time
+}
+
+// checkTaskTest returns the differences between the expected and the actual results of a test.
+func checkTaskTest(test kclient.TaskTest, e kclient.Evaluation) []string {
+	var failures []string
+	fail := func(format string, args ...interface{}) {
+		failures = append(failures, fmt.Sprintf(format, args...))
+	}
+	start := testStart(test)
+	at := func(t time.Time) string {
+		return t.Sub(start).String()
+	}
+
+	if exp := test.Expect.Alerts; exp != nil {
+		if len(exp) != len(e.Events) {
+			fail("expected %d alert events, got %d", len(exp), len(e.Events))
+		}
+		for i := 0; i < len(exp) || i < len(e.Events); i++ {
+			if i >= len(e.Events) {
+				fail("alert event %d: missing %s", i, formatExpectedAlert(exp[i]))
+				continue
+			}
+			got := e.Events[i]
+			if i >= len(exp) {
+				fail("alert event %d: unexpected id=%s level=%s at=%s", i, got.ID, got.State.Level, at(got.State.Time))
+				continue
+			}
+			if exp[i].ID != "" && exp[i].ID != got.ID {
+				fail("alert event %d: expected id %q, got %q", i, exp[i].ID, got.ID)
+			}
+			if exp[i].Level != "" && !strings.EqualFold(exp[i].Level, got.State.Level) {
+				fail("alert event %d: expected level %s, got %s", i, exp[i].Level, got.State.Level)
+			}
+			if exp[i].Message != "" && exp[i].Message != got.State.Message {
+				fail("alert event %d: expected message %q, got %q", i, exp[i].Message, got.State.Message)
+			}
+			if exp[i].At != nil && start.Add(time.Duration(*exp[i].At)) != got.State.Time {
+				fail("alert event %d: expected at %s, got %s", i, time.Duration(*exp[i].At), at(got.State.Time))
+			}
+		}
+	}
+
+	if exp := test.Expect.Points; exp != nil {
+		if len(exp) != len(e.Writes) {
+			fail("expected %d points, got %d", len(exp), len(e.Writes))
+		}
+		for i := 0; i < len(exp) || i < len(e.Writes); i++ {
+			if i >= len(e.Writes) {
+				fail("point %d: missing measurement=%s tags=%v fields=%v", i, exp[i].Measurement, exp[i].Tags, exp[i].Fields)
+				continue
+			}
+			got := e.Writes[i]
+			if i >= len(exp) {
+				fail("point %d: unexpected measurement=%s tags=%v fields=%v at=%s", i, got.Measurement, got.Tags, got.Fields, at(got.Time))
+				continue
+			}
+			if exp[i].Database != "" && exp[i].Database != got.Database {
+				fail("point %d: expected db %q, got %q", i, exp[i].Database, got.Database)
+			}
+			if exp[i].RetentionPolicy != "" && exp[i].RetentionPolicy != got.RetentionPolicy {
+				fail("point %d: expected rp %q, got %q", i, exp[i].RetentionPolicy, got.RetentionPolicy)
+			}
+			if exp[i].Measurement != "" && exp[i].Measurement != got.Measurement {
+				fail("point %d: expected measurement %q, got %q", i, exp[i].Measurement, got.Measurement)
+			}
+			if exp[i].Tags != nil && !reflect.DeepEqual(exp[i].Tags, got.Tags) {
+				fail("point %d: expected tags %v, got %v", i, exp[i].Tags, got.Tags)
+			}
+			if exp[i].Fields != nil && !fieldsEqual(exp[i].Fields, got.Fields) {
+				fail("point %d: expected fields %v, got %v", i, exp[i].Fields, got.Fields)
+			}
+			if exp[i].At != nil && start.Add(time.Duration(*exp[i].At)) != got.Time {
+				fail("point %d: expected at %s, got %s", i, time.Duration(*exp[i].At), at(got.Time))
+			}
+		}
+	}
+	return failures
+}
+
+func formatExpectedAlert(a kclient.ExpectedAlert) string {
+	s := fmt.Sprintf("id=%s level=%s", a.ID, a.Level)
+	if a.At != nil {
+		s += " at=" + time.Duration(*a.At).String()
+	}
+	return s
+}
+
+// fieldsEqual reports whether the fields have the same names and values,
+// numbers are equal if they have the same value whatever their type,
+// since the expected ones are decoded from JSON.
+func fieldsEqual(exp, got map[string]interface{}) bool {
+	if len(exp) != len(got) {
+		return false
+	}
+	for k, e := range exp {
+		g, ok := got[k]
+		if !ok {
+			return false
+		}
+		ef, eNum := toFloat(e)
+		gf, gNum := toFloat(g)
+		if eNum || gNum {
+			if !eNum || !gNum || ef != gf {
+				return false
+			}
+			continue
+		}
+		if !reflect.DeepEqual(e, g) {
+			return false
+		}
+	}
+	return true
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
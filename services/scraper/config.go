@@ -58,6 +58,32 @@ type Config struct {
 
 	// Blacklist is a list of hosts to ignore and not scrape
 	Blacklist []string `toml:"blacklist" override:"blacklist"`
+
+	// RelabelConfigs are applied in order to the labels of the discovered targets.
+	RelabelConfigs []RelabelConfig `toml:"relabel" override:"relabel"`
+
+	// MetricAllow are regular expressions of the metric names to keep, all metrics are kept if empty.
+	MetricAllow []string `toml:"metric-allow" override:"metric-allow"`
+	// MetricDeny are regular expressions of the metric names to drop.
+	MetricDeny []string `toml:"metric-deny" override:"metric-deny"`
+	// LabelAllow are label=regex pairs, metrics are only kept if all of the labels match.
+	LabelAllow []string `toml:"label-allow" override:"label-allow"`
+	// LabelDeny are label=regex pairs, metrics are dropped if any of the labels match.
+	LabelDeny []string `toml:"label-deny" override:"label-deny"`
+	// MetricRelabelConfigs are applied in order to the scraped metrics after the allow and deny lists.
+	MetricRelabelConfigs []RelabelConfig `toml:"metric-relabel" override:"metric-relabel"`
+
+	// URLs are the JSON endpoints to scrape when the type is json.
+	URLs []string `toml:"urls" override:"urls"`
+	// Measurement is the name of the points created from JSON documents.
+	Measurement string `toml:"measurement" override:"measurement"`
+	// Root is a JSONPath selecting the objects in the JSON document that become points.
+	// Field and tag paths are relative to each selected object.
+	Root string `toml:"root" override:"root"`
+	// Fields maps field names to the JSONPath of their value.
+	Fields map[string]string `toml:"fields" override:"fields"`
+	// Tags maps tag names to the JSONPath of their value.
+	Tags map[string]string `toml:"tags" override:"tags"`
 }
 
 // Init adds default values to Config scraper
@@ -80,13 +106,69 @@ func (c *Config) Validate() error {
 	if c.RetentionPolicy == "" {
 		return fmt.Errorf("scraper config must be given an rp")
 	}
-	if c.Type != "prometheus" {
+	switch c.Type {
+	case "prometheus":
+		if _, err := promRelabelConfigs(c.RelabelConfigs); err != nil {
+			return err
+		}
+		if _, err := c.metricRelabelConfigs(); err != nil {
+			return err
+		}
+	case "json":
+		return c.validateJSON()
+	default:
 		return fmt.Errorf("Unknown scraper type")
 	}
 
 	return nil
 }
 
+func (c *Config) validateJSON() error {
+	if len(c.URLs) == 0 {
+		return fmt.Errorf("json scraper config must be given at least one url")
+	}
+	for _, u := range c.URLs {
+		if _, err := url.Parse(u); err != nil {
+			return fmt.Errorf("invalid url %q: %v", u, err)
+		}
+	}
+	if c.Measurement == "" {
+		return fmt.Errorf("json scraper config must be given a measurement")
+	}
+	if len(c.Fields) == 0 {
+		return fmt.Errorf("json scraper config must be given at least one field")
+	}
+	_, err := c.jsonMapping()
+	return err
+}
+
+// jsonMapping compiles the JSONPath expressions of a json scraper.
+func (c *Config) jsonMapping() (*jsonMapping, error) {
+	m := &jsonMapping{
+		fields: make(map[string]jsonPath, len(c.Fields)),
+		tags:   make(map[string]jsonPath, len(c.Tags)),
+	}
+	root := c.Root
+	if root == "" {
+		root = "$"
+	}
+	var err error
+	if m.root, err = parseJSONPath(root); err != nil {
+		return nil, err
+	}
+	for name, p := range c.Fields {
+		if m.fields[name], err = parseJSONPath(p); err != nil {
+			return nil, err
+		}
+	}
+	for name, p := range c.Tags {
+		if m.tags[name], err = parseJSONPath(p); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
 // Prom generates the prometheus configuration for the scraper
 func (c *Config) Prom() *config.ScrapeConfig {
 	sc := &config.ScrapeConfig{
@@ -114,6 +196,9 @@ func (c *Config) Prom() *config.ScrapeConfig {
 			},
 		},
 	}
+	// Relabel configs are checked when the configuration is validated.
+	sc.RelabelConfigs, _ = promRelabelConfigs(c.RelabelConfigs)
+	sc.MetricRelabelConfigs, _ = c.metricRelabelConfigs()
 	return sc
 }
 
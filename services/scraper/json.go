@@ -0,0 +1,260 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/tlsconfig"
+)
+
+// jsonMapping is the compiled mapping from a JSON document to points.
+type jsonMapping struct {
+	root   jsonPath
+	fields map[string]jsonPath
+	tags   map[string]jsonPath
+}
+
+// points creates a set of fields and tags for each object selected by the root path.
+// Objects without any fields are skipped.
+func (m *jsonMapping) points(doc interface{}) []jsonPoint {
+	var points []jsonPoint
+	for _, obj := range m.root.Find(doc) {
+		p := jsonPoint{
+			fields: make(models.Fields, len(m.fields)),
+			tags:   make(models.Tags, len(m.tags)),
+		}
+		for name, path := range m.fields {
+			v, ok := path.First(obj)
+			if !ok {
+				continue
+			}
+			switch v := v.(type) {
+			case float64, bool, string:
+				p.fields[name] = v
+			}
+		}
+		if len(p.fields) == 0 {
+			continue
+		}
+		for name, path := range m.tags {
+			v, ok := path.First(obj)
+			if !ok || v == nil {
+				continue
+			}
+			switch v.(type) {
+			case map[string]interface{}, []interface{}:
+				continue
+			}
+			p.tags[name] = fmt.Sprint(v)
+		}
+		points = append(points, p)
+	}
+	return points
+}
+
+type jsonPoint struct {
+	fields models.Fields
+	tags   models.Tags
+}
+
+// jsonScraper periodically requests JSON documents and converts them into points.
+type jsonScraper struct {
+	c       Config
+	mapping *jsonMapping
+	client  *http.Client
+	write   func(edge.PointMessage) error
+	diag    Diagnostic
+
+	closing chan struct{}
+	wg      sync.WaitGroup
+
+	mu sync.Mutex
+	// Status of the last scrape of each URL.
+	status map[string]TargetStatus
+}
+
+func newJSONScraper(c Config, write func(edge.PointMessage) error, d Diagnostic) (*jsonScraper, error) {
+	m, err := c.jsonMapping()
+	if err != nil {
+		return nil, err
+	}
+	t, err := tlsconfig.Create(c.SSLCA, c.SSLCert, c.SSLKey, c.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	t.ServerName = c.SSLServerName
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		Dial: (&net.Dialer{
+			Timeout: 30 * time.Second,
+		}).Dial,
+		TLSClientConfig: t,
+	}
+	if c.ProxyURL != nil {
+		transport.Proxy = http.ProxyURL(c.ProxyURL)
+	}
+	return &jsonScraper{
+		c:       c,
+		mapping: m,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   time.Duration(c.ScrapeTimeout),
+		},
+		write:   write,
+		diag:    d.With("scraper", c.Name),
+		closing: make(chan struct{}),
+		status:  make(map[string]TargetStatus),
+	}, nil
+}
+
+func (s *jsonScraper) Open() {
+	s.wg.Add(1)
+	go s.run()
+}
+
+func (s *jsonScraper) Close() {
+	close(s.closing)
+	s.wg.Wait()
+}
+
+func (s *jsonScraper) run() {
+	defer s.wg.Done()
+	interval := time.Duration(s.c.ScrapeInterval)
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.scrapeAll()
+		select {
+		case <-s.closing:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *jsonScraper) scrapeAll() {
+	for _, u := range s.c.URLs {
+		if s.blacklisted(u) {
+			continue
+		}
+		now := time.Now()
+		err := s.scrape(u)
+		if err != nil {
+			s.diag.Errorf("failed to scrape %s: %v", u, err)
+		}
+		s.setStatus(u, now, err)
+	}
+}
+
+func (s *jsonScraper) setStatus(u string, now time.Time, err error) {
+	status := TargetStatus{
+		Scraper:    s.c.Name,
+		URL:        u,
+		Health:     TargetUp,
+		LastError:  err,
+		LastScrape: now,
+	}
+	if err != nil {
+		status.Health = TargetDown
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status[u] = status
+}
+
+// targets returns the status of the URLs of the scraper, those not scraped yet have an unknown health.
+func (s *jsonScraper) targets() []TargetStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var targets []TargetStatus
+	for _, u := range s.c.URLs {
+		if s.blacklisted(u) {
+			continue
+		}
+		status, ok := s.status[u]
+		if !ok {
+			status = TargetStatus{Scraper: s.c.Name, URL: u, Health: TargetUnknown}
+		}
+		targets = append(targets, status)
+	}
+	return targets
+}
+
+func (s *jsonScraper) blacklisted(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	for _, listed := range s.c.Blacklist {
+		if u.Host == listed {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *jsonScraper) scrape(target string) error {
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return err
+	}
+	if len(s.c.Params) > 0 {
+		q := req.URL.Query()
+		for k, vs := range s.c.Params {
+			for _, v := range vs {
+				q.Add(k, v)
+			}
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+	req.Header.Set("Accept", "application/json")
+	if s.c.Username != "" || s.c.Password != "" {
+		req.SetBasicAuth(s.c.Username, s.c.Password)
+	}
+	if s.c.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.c.BearerToken)
+	}
+
+	now := time.Now().UTC()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	var doc interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("failed to decode JSON response: %v", err)
+	}
+
+	for _, p := range s.mapping.points(doc) {
+		if _, ok := p.tags["instance"]; !ok {
+			p.tags["instance"] = req.URL.Host
+		}
+		err := s.write(edge.NewPointMessage(
+			s.c.Measurement,
+			s.c.Database,
+			s.c.RetentionPolicy,
+			models.Dimensions{},
+			p.fields,
+			p.tags,
+			now,
+		))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
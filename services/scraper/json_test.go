@@ -0,0 +1,115 @@
+package scraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/models"
+	plog "github.com/prometheus/common/log"
+)
+
+func TestJSONScraper_Scrape(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"device": {"name": "ups1", "status": {"load": 42.5, "online": true}},
+			"ports": [
+				{"id": 1, "counters": {"rx": 100, "tx": 200}},
+				{"id": 2, "counters": {"rx": 300, "tx": 400}},
+				{"id": 3}
+			]
+		}`))
+	}))
+	defer ts.Close()
+
+	testCases := []struct {
+		name string
+		c    Config
+		exp  []models.Fields
+		tags []models.Tags
+	}{
+		{
+			name: "root",
+			c: Config{
+				Fields: map[string]string{
+					"load":   "$.device.status.load",
+					"online": "$['device']['status']['online']",
+				},
+				Tags: map[string]string{
+					"device": "$.device.name",
+				},
+			},
+			exp:  []models.Fields{{"load": 42.5, "online": true}},
+			tags: []models.Tags{{"device": "ups1"}},
+		},
+		{
+			name: "wildcard",
+			c: Config{
+				Root: "$.ports[*]",
+				Fields: map[string]string{
+					"rx": "$.counters.rx",
+					"tx": "$.counters.tx",
+				},
+				Tags: map[string]string{
+					"port": "$.id",
+				},
+			},
+			exp: []models.Fields{
+				{"rx": 100.0, "tx": 200.0},
+				{"rx": 300.0, "tx": 400.0},
+			},
+			tags: []models.Tags{{"port": "1"}, {"port": "2"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.c.Init()
+			tc.c.Name = "test"
+			tc.c.Type = "json"
+			tc.c.Database = "db"
+			tc.c.RetentionPolicy = "rp"
+			tc.c.Measurement = "appliance"
+			tc.c.URLs = []string{ts.URL}
+			if err := tc.c.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			var got []edge.PointMessage
+			write := func(p edge.PointMessage) error {
+				got = append(got, p)
+				return nil
+			}
+			js, err := newJSONScraper(tc.c, write, plog.Base())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := js.scrape(ts.URL); err != nil {
+				t.Fatal(err)
+			}
+			sort.Slice(got, func(i, j int) bool {
+				return got[i].Tags()["port"] < got[j].Tags()["port"]
+			})
+			if len(got) != len(tc.exp) {
+				t.Fatalf("unexpected number of points: got %d exp %d", len(got), len(tc.exp))
+			}
+			for i, p := range got {
+				if p.Name() != "appliance" || p.Database() != "db" || p.RetentionPolicy() != "rp" {
+					t.Errorf("unexpected point %d: %v", i, p)
+				}
+				if !reflect.DeepEqual(p.Fields(), tc.exp[i]) {
+					t.Errorf("unexpected fields %d: got %v exp %v", i, p.Fields(), tc.exp[i])
+				}
+				tags := p.Tags()
+				if tags["instance"] == "" {
+					t.Errorf("expected instance tag on point %d", i)
+				}
+				delete(tags, "instance")
+				if !reflect.DeepEqual(tags, tc.tags[i]) {
+					t.Errorf("unexpected tags %d: got %v exp %v", i, tags, tc.tags[i])
+				}
+			}
+		})
+	}
+}
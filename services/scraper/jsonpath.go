@@ -0,0 +1,125 @@
+package scraper
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a compiled JSONPath expression.
+// Only the subset of JSONPath needed to address values in a document is supported:
+// the root $, child access by .name or ['name'], array indexes [n] and the wildcard * or [*].
+type jsonPath []pathStep
+
+type pathStep struct {
+	// key is the object member to select, unused for index and wildcard steps.
+	key string
+	// index is the array element to select, negative values count from the end.
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+func parseJSONPath(p string) (jsonPath, error) {
+	s := strings.TrimSpace(p)
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("invalid JSONPath %q: must start with $", p)
+	}
+	s = s[1:]
+	var path jsonPath
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			name := s[:end]
+			s = s[end:]
+			switch name {
+			case "":
+				return nil, fmt.Errorf("invalid JSONPath %q: empty member name", p)
+			case "*":
+				path = append(path, pathStep{wildcard: true})
+			default:
+				path = append(path, pathStep{key: name})
+			}
+		case '[':
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q: missing ]", p)
+			}
+			sel := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			switch {
+			case sel == "*":
+				path = append(path, pathStep{wildcard: true})
+			case len(sel) >= 2 && (sel[0] == '\'' || sel[0] == '"') && sel[len(sel)-1] == sel[0]:
+				path = append(path, pathStep{key: sel[1 : len(sel)-1]})
+			default:
+				i, err := strconv.Atoi(sel)
+				if err != nil {
+					return nil, fmt.Errorf("invalid JSONPath %q: invalid index %q", p, sel)
+				}
+				path = append(path, pathStep{index: i, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %q: unexpected character %q", p, s[0])
+		}
+	}
+	return path, nil
+}
+
+// Find returns all values in the decoded JSON document v selected by the path.
+func (p jsonPath) Find(v interface{}) []interface{} {
+	values := []interface{}{v}
+	for _, step := range p {
+		var next []interface{}
+		for _, v := range values {
+			next = step.apply(v, next)
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		values = next
+	}
+	return values
+}
+
+// First returns the first value selected by the path.
+func (p jsonPath) First(v interface{}) (interface{}, bool) {
+	values := p.Find(v)
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+func (s pathStep) apply(v interface{}, values []interface{}) []interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if s.wildcard {
+			for _, e := range v {
+				values = append(values, e)
+			}
+		} else if !s.isIndex {
+			if e, ok := v[s.key]; ok {
+				values = append(values, e)
+			}
+		}
+	case []interface{}:
+		if s.wildcard {
+			values = append(values, v...)
+		} else if s.isIndex {
+			i := s.index
+			if i < 0 {
+				i += len(v)
+			}
+			if i >= 0 && i < len(v) {
+				values = append(values, v[i])
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,197 @@
+package scraper
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+)
+
+// relabelTarget matches valid target labels of the replace action, which may reference capture groups.
+var relabelTarget = regexp.MustCompile(`^(?:(?:[a-zA-Z_]|\$(?:\{\w+\}|\w+))+\w*)+$`)
+
+// RelabelConfig is a Prometheus style relabeling rule.
+// Rules are applied in order to the labels of each discovered target,
+// labels starting with __ are removed after relabeling and the remaining
+// labels become tags of the scraped points.
+type RelabelConfig struct {
+	// SourceLabels are concatenated with the separator and matched against the regex.
+	SourceLabels []string `toml:"source-labels" json:"source-labels" mapstructure:"source-labels"`
+	// Separator between the concatenated source labels, defaults to ;
+	Separator string `toml:"separator" json:"separator" mapstructure:"separator"`
+	// Regex matched against the source labels, defaults to (.*)
+	Regex string `toml:"regex" json:"regex" mapstructure:"regex"`
+	// Modulus of the hash of the source labels for the hashmod action.
+	Modulus uint64 `toml:"modulus" json:"modulus" mapstructure:"modulus"`
+	// TargetLabel is the label written by the replace and hashmod actions.
+	TargetLabel string `toml:"target-label" json:"target-label" mapstructure:"target-label"`
+	// Replacement is the value written by the replace action, defaults to $1
+	Replacement string `toml:"replacement" json:"replacement" mapstructure:"replacement"`
+	// Action is one of replace, keep, drop, hashmod, labelmap, labeldrop or labelkeep, defaults to replace.
+	Action string `toml:"action" json:"action" mapstructure:"action"`
+}
+
+// Prom returns the prometheus relabel configuration with the defaults applied.
+func (r RelabelConfig) Prom() (*config.RelabelConfig, error) {
+	rc := &config.RelabelConfig{
+		Separator:   config.DefaultRelabelConfig.Separator,
+		Regex:       config.DefaultRelabelConfig.Regex,
+		Modulus:     r.Modulus,
+		TargetLabel: r.TargetLabel,
+		Replacement: config.DefaultRelabelConfig.Replacement,
+		Action:      config.DefaultRelabelConfig.Action,
+	}
+	for _, l := range r.SourceLabels {
+		if !model.LabelName(l).IsValid() {
+			return nil, fmt.Errorf("invalid source label %q", l)
+		}
+		rc.SourceLabels = append(rc.SourceLabels, model.LabelName(l))
+	}
+	if r.Separator != "" {
+		rc.Separator = r.Separator
+	}
+	if r.Regex != "" {
+		re, err := config.NewRegexp(r.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %v", r.Regex, err)
+		}
+		rc.Regex = re
+	}
+	if r.Replacement != "" {
+		rc.Replacement = r.Replacement
+	}
+	if r.Action != "" {
+		rc.Action = config.RelabelAction(r.Action)
+	}
+
+	// The job label encodes the database, retention policy and name of the scraper.
+	if rc.TargetLabel == model.JobLabel {
+		return nil, fmt.Errorf("the %s label cannot be relabeled", model.JobLabel)
+	}
+
+	switch rc.Action {
+	case config.RelabelReplace:
+		if !relabelTarget.MatchString(rc.TargetLabel) {
+			return nil, fmt.Errorf("%q is invalid target-label for %s action", rc.TargetLabel, rc.Action)
+		}
+	case config.RelabelHashMod:
+		if rc.Modulus == 0 {
+			return nil, fmt.Errorf("relabel configuration for hashmod requires non-zero modulus")
+		}
+		if !model.LabelName(rc.TargetLabel).IsValid() {
+			return nil, fmt.Errorf("%q is invalid target-label for %s action", rc.TargetLabel, rc.Action)
+		}
+	case config.RelabelKeep, config.RelabelDrop:
+		if len(rc.SourceLabels) == 0 {
+			return nil, fmt.Errorf("relabel configuration for %s action requires source-labels", rc.Action)
+		}
+	case config.RelabelLabelMap:
+	case config.RelabelLabelDrop, config.RelabelLabelKeep:
+		if len(r.SourceLabels) > 0 || r.TargetLabel != "" || r.Modulus != 0 || r.Separator != "" || r.Replacement != "" {
+			return nil, fmt.Errorf("%s action requires only regex, and no other fields", rc.Action)
+		}
+	default:
+		return nil, fmt.Errorf("unknown relabel action %q", r.Action)
+	}
+	return rc, nil
+}
+
+// promRelabelConfigs converts the relabeling rules into their prometheus configuration.
+func promRelabelConfigs(rs []RelabelConfig) ([]*config.RelabelConfig, error) {
+	if len(rs) == 0 {
+		return nil, nil
+	}
+	rcs := make([]*config.RelabelConfig, len(rs))
+	for i, r := range rs {
+		rc, err := r.Prom()
+		if err != nil {
+			return nil, fmt.Errorf("relabel %d: %v", i, err)
+		}
+		rcs[i] = rc
+	}
+	return rcs, nil
+}
+
+// metricRelabelConfigs returns the relabeling rules applied to the scraped metrics.
+// The allow and deny lists are converted into keep and drop rules preceding the metric relabel configs.
+func (c *Config) metricRelabelConfigs() ([]*config.RelabelConfig, error) {
+	var rcs []*config.RelabelConfig
+	filter := func(label model.LabelName, regex string, action config.RelabelAction) error {
+		re, err := config.NewRegexp(regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %v", regex, err)
+		}
+		rcs = append(rcs, &config.RelabelConfig{
+			SourceLabels: model.LabelNames{label},
+			Separator:    config.DefaultRelabelConfig.Separator,
+			Regex:        re,
+			Replacement:  config.DefaultRelabelConfig.Replacement,
+			Action:       action,
+		})
+		return nil
+	}
+	for _, re := range append(c.MetricAllow, c.MetricDeny...) {
+		if _, err := regexp.Compile(re); err != nil {
+			return nil, fmt.Errorf("invalid metric name regex %q: %v", re, err)
+		}
+	}
+	// Metric names are kept if they match any of the expressions.
+	if len(c.MetricAllow) > 0 {
+		if err := filter(model.MetricNameLabel, alternate(c.MetricAllow), config.RelabelKeep); err != nil {
+			return nil, fmt.Errorf("metric-allow: %v", err)
+		}
+	}
+	if len(c.MetricDeny) > 0 {
+		if err := filter(model.MetricNameLabel, alternate(c.MetricDeny), config.RelabelDrop); err != nil {
+			return nil, fmt.Errorf("metric-deny: %v", err)
+		}
+	}
+	for _, pair := range c.LabelAllow {
+		label, regex, err := parseLabelPair(pair)
+		if err == nil {
+			err = filter(label, regex, config.RelabelKeep)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("label-allow: %v", err)
+		}
+	}
+	for _, pair := range c.LabelDeny {
+		label, regex, err := parseLabelPair(pair)
+		if err == nil {
+			err = filter(label, regex, config.RelabelDrop)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("label-deny: %v", err)
+		}
+	}
+	mrcs, err := promRelabelConfigs(c.MetricRelabelConfigs)
+	if err != nil {
+		return nil, fmt.Errorf("metric %v", err)
+	}
+	return append(rcs, mrcs...), nil
+}
+
+// alternate combines the regular expressions so that any of them match.
+func alternate(regexes []string) string {
+	groups := make([]string, len(regexes))
+	// Each expression is grouped so alternation does not change its meaning.
+	for i, re := range regexes {
+		groups[i] = "(?:" + re + ")"
+	}
+	return strings.Join(groups, "|")
+}
+
+// parseLabelPair parses a label=regex pair.
+func parseLabelPair(pair string) (model.LabelName, string, error) {
+	parts := strings.SplitN(pair, "=", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid label pair %q, must be label=regex", pair)
+	}
+	label := model.LabelName(parts[0])
+	if !label.IsValid() {
+		return "", "", fmt.Errorf("invalid label name %q", parts[0])
+	}
+	return label, parts[1], nil
+}
@@ -0,0 +1,124 @@
+package scraper
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/relabel"
+)
+
+func TestRelabelConfigs(t *testing.T) {
+	rcs, err := promRelabelConfigs([]RelabelConfig{
+		{
+			SourceLabels: []string{"__meta_env"},
+			Regex:        "prod.*",
+			Action:       "keep",
+		},
+		{
+			SourceLabels: []string{"__meta_node"},
+			TargetLabel:  "host",
+		},
+		{
+			Regex:       "__meta_label_(.+)",
+			Replacement: "label_$1",
+			Action:      "labelmap",
+		},
+		{
+			Regex:  "label_secret",
+			Action: "labeldrop",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := model.LabelSet{
+		model.AddressLabel:    "10.0.0.1:9100",
+		"__meta_env":          "production",
+		"__meta_node":         "node1",
+		"__meta_label_team":   "db",
+		"__meta_label_secret": "x",
+	}
+	got := relabel.Process(target, rcs...)
+	exp := model.LabelSet{
+		model.AddressLabel:    "10.0.0.1:9100",
+		"__meta_env":          "production",
+		"__meta_node":         "node1",
+		"__meta_label_team":   "db",
+		"__meta_label_secret": "x",
+		"host":                "node1",
+		"label_team":          "db",
+	}
+	if !got.Equal(exp) {
+		t.Errorf("unexpected labels:\ngot %v\nexp %v", got, exp)
+	}
+
+	target["__meta_env"] = "staging"
+	if got := relabel.Process(target, rcs...); got != nil {
+		t.Errorf("expected target to be dropped, got %v", got)
+	}
+}
+
+func TestRelabelConfig_Invalid(t *testing.T) {
+	testCases := []RelabelConfig{
+		{Action: "unknown"},
+		{Regex: "(", TargetLabel: "a"},
+		{SourceLabels: []string{"a"}, Action: "keep", Regex: "["},
+		{Action: "keep"},
+		{Action: "hashmod", TargetLabel: "shard"},
+		{Action: "labeldrop", Regex: "a", TargetLabel: "b"},
+		{SourceLabels: []string{"a"}, TargetLabel: "job"},
+		{SourceLabels: []string{"a"}, TargetLabel: "1invalid"},
+	}
+	for _, tc := range testCases {
+		if _, err := tc.Prom(); err == nil {
+			t.Errorf("expected error for %+v", tc)
+		}
+	}
+}
+
+func TestConfig_MetricRelabelConfigs(t *testing.T) {
+	c := &Config{
+		MetricAllow: []string{"node_cpu.*", "node_memory_.*"},
+		MetricDeny:  []string{"node_cpu_guest.*"},
+		LabelAllow:  []string{"mode=user|system|idle"},
+		LabelDeny:   []string{"cpu=cpu1[0-9]"},
+		MetricRelabelConfigs: []RelabelConfig{
+			{
+				SourceLabels: []string{"mode"},
+				TargetLabel:  "cpu_mode",
+			},
+		},
+	}
+	rcs, err := c.metricRelabelConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		metric model.LabelSet
+		kept   bool
+	}{
+		{metric: model.LabelSet{"__name__": "node_cpu", "cpu": "cpu0", "mode": "user"}, kept: true},
+		{metric: model.LabelSet{"__name__": "node_cpu", "cpu": "cpu12", "mode": "user"}},
+		{metric: model.LabelSet{"__name__": "node_cpu", "cpu": "cpu0", "mode": "iowait"}},
+		{metric: model.LabelSet{"__name__": "node_cpu_guest_seconds", "cpu": "cpu0", "mode": "user"}},
+		{metric: model.LabelSet{"__name__": "node_disk_io", "mode": "user"}},
+		{metric: model.LabelSet{"__name__": "node_memory_free", "mode": "idle"}, kept: true},
+	}
+	for _, tc := range testCases {
+		got := relabel.Process(tc.metric.Clone(), rcs...)
+		if kept := got != nil; kept != tc.kept {
+			t.Errorf("unexpected result for %v: got kept %t exp %t", tc.metric, kept, tc.kept)
+			continue
+		}
+		if got != nil && got["cpu_mode"] != tc.metric["mode"] {
+			t.Errorf("expected cpu_mode label to be set, got %v", got)
+		}
+	}
+
+	c.LabelDeny = []string{"invalid"}
+	if _, err := c.metricRelabelConfigs(); err == nil {
+		t.Error("expected error for invalid label pair")
+	}
+}
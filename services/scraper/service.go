@@ -5,6 +5,7 @@ import (
 	"math"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/influxdata/kapacitor/edge"
 	"github.com/influxdata/kapacitor/models"
@@ -42,15 +43,36 @@ type Service struct {
 
 	discoverers []Discoverer
 
+	// jsonScrapers are the running scrapers of type json keyed by name
+	jsonScrapers map[string]*jsonScraper
+
 	// TargetManager represents a scraping/discovery manager
 	mgr interface {
 		ApplyConfig(cfg *config.Config) error
 		Stop()
 		Start()
 		Wait()
+		Targets() []*retrieval.Target
 	}
 }
 
+// Health of a target.
+const (
+	TargetUnknown = string(retrieval.HealthUnknown)
+	TargetUp      = string(retrieval.HealthGood)
+	TargetDown    = string(retrieval.HealthBad)
+)
+
+// TargetStatus is the status of the last scrape of a target.
+type TargetStatus struct {
+	Scraper string
+	URL     string
+	// Health is up or down after the first scrape of the target, and unknown before.
+	Health     string
+	LastError  error
+	LastScrape time.Time
+}
+
 // NewService creates a new scraper service
 func NewService(c []Config, d Diagnostic) *Service {
 	s := &Service{
@@ -74,6 +96,7 @@ func (s *Service) Open() error {
 	s.closing = make(chan struct{})
 
 	go s.scrape()
+	s.startJSONScrapers()
 
 	s.open = true
 	return nil
@@ -90,6 +113,7 @@ func (s *Service) Close() error {
 
 	s.open = false
 	close(s.closing)
+	s.stopJSONScrapers()
 
 	s.wg.Wait()
 
@@ -233,6 +257,8 @@ func (s *Service) Update(newConfigs []interface{}) error {
 
 	s.storeConfigs(configs)
 	if s.open {
+		s.stopJSONScrapers()
+		s.startJSONScrapers()
 		pairs := s.pairs()
 		conf := s.prom(pairs)
 		select {
@@ -244,6 +270,55 @@ func (s *Service) Update(newConfigs []interface{}) error {
 	return nil
 }
 
+// Targets returns the status of the targets of the running scrapers.
+func (s *Service) Targets() []TargetStatus {
+	var targets []TargetStatus
+	for _, t := range s.mgr.Targets() {
+		targets = append(targets, TargetStatus{
+			Scraper:    string(t.Labels()[model.JobLabel]),
+			URL:        t.URL().String(),
+			Health:     string(t.Health()),
+			LastError:  t.LastError(),
+			LastScrape: t.LastScrape(),
+		})
+	}
+	s.mu.Lock()
+	for _, js := range s.jsonScrapers {
+		targets = append(targets, js.targets()...)
+	}
+	s.mu.Unlock()
+	return targets
+}
+
+// startJSONScrapers starts all enabled json scrapers, assumes service is locked
+func (s *Service) startJSONScrapers() {
+	s.jsonScrapers = make(map[string]*jsonScraper)
+	for _, c := range s.loadConfigs() {
+		if !c.Enabled || c.Type != "json" {
+			continue
+		}
+		js, err := newJSONScraper(c, s.writeJSONPoint, s.diag)
+		if err != nil {
+			s.diag.With("scraper", c.Name).Errorf("failed to create json scraper: %v", err)
+			continue
+		}
+		js.Open()
+		s.jsonScrapers[c.Name] = js
+	}
+}
+
+// stopJSONScrapers stops all running json scrapers, assumes service is locked
+func (s *Service) stopJSONScrapers() {
+	for name, js := range s.jsonScrapers {
+		js.Close()
+		delete(s.jsonScrapers, name)
+	}
+}
+
+func (s *Service) writeJSONPoint(p edge.PointMessage) error {
+	return s.PointsWriter.WriteKapacitorPoint(p)
+}
+
 // prom assumes service is locked
 func (s *Service) prom(pairs []Pair) *config.Config {
 	conf := &config.Config{
@@ -341,7 +416,8 @@ func (s *Service) RemoveScrapers(scrapers []Config) {
 func (s *Service) pairs() []Pair {
 	pairs := []Pair{}
 	for _, scr := range s.loadConfigs() {
-		if !scr.Enabled {
+		// JSON scrapers do not use discoverers
+		if !scr.Enabled || scr.Type == "json" {
 			continue
 		}
 		for _, d := range s.discoverers {
@@ -183,8 +183,11 @@ type handler struct {
 	sourceTmpl *text.Template
 }
 
+// Cached templates, shared by the handlers
+var sourceTemplates = alert.NewTextTemplates("source", nil)
+
 func (s *Service) Handler(c HandlerConfig, ctx ...keyvalue.T) (alert.Handler, error) {
-	srcTmpl, err := text.New("source").Parse(c.Source)
+	srcTmpl, err := sourceTemplates.Parse(c.Source)
 	if err != nil {
 		return nil, err
 	}
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/influxdata/kapacitor/auth"
 	client "github.com/influxdata/kapacitor/client/v1"
 	"github.com/influxdata/kapacitor/services/httpd"
 	"github.com/pkg/errors"
@@ -53,16 +54,19 @@ func (s *Service) Open() error {
 			Method:      "GET",
 			Pattern:     testPath,
 			HandlerFunc: s.handleListTests,
+			Scope:       auth.ConfigReadScope,
 		},
 		{
 			Method:      "GET",
 			Pattern:     testPathAnchored,
 			HandlerFunc: s.handleTestOptions,
+			Scope:       auth.ConfigReadScope,
 		},
 		{
 			Method:      "POST",
 			Pattern:     testPathAnchored,
 			HandlerFunc: s.handleTest,
+			Scope:       auth.ConfigWriteScope,
 		},
 	}
 
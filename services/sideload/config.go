@@ -0,0 +1,42 @@
+package sideload
+
+import (
+	"errors"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+)
+
+const (
+	DefaultRefreshInterval = time.Minute
+	DefaultTimeout         = 30 * time.Second
+)
+
+type Config struct {
+	// How often the remote sources are fetched again.
+	RefreshInterval toml.Duration `toml:"refresh-interval"`
+	// Timeout of fetching a remote source.
+	Timeout toml.Duration `toml:"timeout"`
+	// Whether the archive of a remote source must match the SHA-256 checksum in the <url>.sha256 object.
+	VerifyChecksum bool `toml:"verify-checksum"`
+	// Path of the PEM encoded public key the archive of a remote source must be signed with,
+	// the signature is the <url>.sig object.
+	PublicKey string `toml:"public-key"`
+}
+
+func NewConfig() Config {
+	return Config{
+		RefreshInterval: toml.Duration(DefaultRefreshInterval),
+		Timeout:         toml.Duration(DefaultTimeout),
+	}
+}
+
+func (c Config) Validate() error {
+	if c.RefreshInterval <= 0 {
+		return errors.New("refresh-interval must be positive")
+	}
+	if c.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	return nil
+}
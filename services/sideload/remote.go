@@ -0,0 +1,199 @@
+package sideload
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/influxdata/kapacitor/objectstore"
+	"github.com/pkg/errors"
+)
+
+// Suffixes of the objects next to the archive of a remote source.
+const (
+	checksumSuffix  = ".sha256"
+	signatureSuffix = ".sig"
+)
+
+// isRemote reports whether the scheme is of a remote source,
+// an HTTP endpoint or an object of a bucket serving the archive of an override tree.
+func isRemote(scheme string) bool {
+	switch scheme {
+	case "http", "https", "s3", "gs", "az":
+		return true
+	}
+	return false
+}
+
+// fetch returns the content and entity tag of the URL,
+// or objectstore.ErrNotModified if the entity tag is still etag.
+func fetch(ctx context.Context, u *url.URL, etag string) ([]byte, string, error) {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return objectstore.GetIfNoneMatch(ctx, u.String(), etag)
+	}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, "", objectstore.ErrNotModified
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, "", fmt.Errorf("unexpected response code %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+	return data, resp.Header.Get("ETag"), nil
+}
+
+// companion returns the URL of the object next to the archive with the suffix.
+func companion(u *url.URL, suffix string) *url.URL {
+	c := *u
+	c.Path += suffix
+	c.RawPath = ""
+	return &c
+}
+
+// verifyChecksum checks the data against the SHA-256 checksum in the format of sha256sum.
+func verifyChecksum(data, checksum []byte) error {
+	fields := strings.Fields(string(checksum))
+	if len(fields) == 0 {
+		return errors.New("empty checksum")
+	}
+	exp, err := hex.DecodeString(fields[0])
+	if err != nil {
+		return errors.Wrap(err, "invalid checksum")
+	}
+	sum := sha256.Sum256(data)
+	if !bytes.Equal(sum[:], exp) {
+		return fmt.Errorf("checksum mismatch, got %x exp %x", sum, exp)
+	}
+	return nil
+}
+
+// loadPublicKey reads the PEM encoded RSA, ECDSA or Ed25519 public key of the file.
+func loadPublicKey(p string) (crypto.PublicKey, error) {
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read public key %q", p)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in public key %q", p)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse public key %q", p)
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T in %q", key, p)
+	}
+}
+
+// verifySignature checks the signature of the data,
+// a SHA-256 PKCS #1 v1.5 or ECDSA signature, or an Ed25519 signature of the data, raw or base64 encoded.
+func verifySignature(key crypto.PublicKey, data, sig []byte) error {
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err == nil {
+		sig = decoded
+	}
+	sum := sha256.Sum256(data)
+	valid := false
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig) == nil
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(k, sum[:], sig)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(k, data, sig)
+	}
+	if !valid {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// readArchive reads the values files of the tar archive, optionally gzip compressed, by their relative paths.
+func readArchive(data []byte) (map[string]map[string]interface{}, error) {
+	contents, err := readArchiveFiles(data)
+	if err != nil {
+		return nil, err
+	}
+	cache := make(map[string]map[string]interface{}, len(contents))
+	for rel, content := range contents {
+		values, err := parseValues(rel, content)
+		if err != nil {
+			return nil, err
+		}
+		cache[rel] = values
+	}
+	return cache, nil
+}
+
+// readArchiveFiles reads the contents of the regular files of the tar archive, optionally gzip compressed,
+// by their relative paths.
+func readArchiveFiles(data []byte) (map[string][]byte, error) {
+	var r io.Reader = bytes.NewReader(data)
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read gzip archive")
+		}
+		defer gz.Close()
+		r = gz
+	}
+	contents := make(map[string][]byte)
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read tar archive")
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		rel := path.Clean(strings.TrimPrefix(h.Name, "./"))
+		// The files must be within the tree.
+		if rel == "." || strings.HasPrefix(rel, "../") || rel == ".." || path.IsAbs(rel) {
+			return nil, fmt.Errorf("invalid path %q in archive", h.Name)
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %q from archive", h.Name)
+		}
+		contents[rel] = content
+	}
+	return contents, nil
+}
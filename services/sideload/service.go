@@ -1,24 +1,35 @@
 package sideload
 
 import (
+	"context"
+	"crypto"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/ghodss/yaml"
+	"github.com/influxdata/kapacitor/auth"
+	client "github.com/influxdata/kapacitor/client/v1"
 	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/objectstore"
 	"github.com/influxdata/kapacitor/services/httpd"
 	"github.com/pkg/errors"
 )
 
 const (
-	reloadPath = "/sideload/reload"
-	basePath   = httpd.BasePath + reloadPath
+	reloadPath  = "/sideload/reload"
+	sourcesPath = "/sideload/sources"
+	basePath    = httpd.BasePath + reloadPath
 )
 
 type Diagnostic interface {
@@ -28,9 +39,15 @@ type Diagnostic interface {
 }
 
 type Service struct {
+	config Config
 	diag   Diagnostic
 	routes []httpd.Route
 
+	// publicKey verifies the signatures of the remote sources if set.
+	publicKey crypto.PublicKey
+	closing   chan struct{}
+	wg        sync.WaitGroup
+
 	mu      sync.Mutex
 	sources map[string]*source
 
@@ -40,31 +57,90 @@ type Service struct {
 	}
 }
 
-func NewService(d Diagnostic) *Service {
+func NewService(c Config, d Diagnostic) *Service {
 	return &Service{
+		config:  c,
 		diag:    d,
 		sources: make(map[string]*source),
 	}
 }
 
 func (s *Service) Open() error {
+	if s.config.PublicKey != "" {
+		key, err := loadPublicKey(s.config.PublicKey)
+		if err != nil {
+			return err
+		}
+		s.publicKey = key
+	}
+
 	// Define API routes
 	s.routes = []httpd.Route{
 		{
 			Method:      "POST",
 			Pattern:     reloadPath,
 			HandlerFunc: s.handleReload,
+			Scope:       auth.TasksWriteScope,
+		},
+		{
+			Method:      "GET",
+			Pattern:     sourcesPath,
+			HandlerFunc: s.handleSources,
+			Scope:       auth.TasksReadScope,
 		},
 	}
 
-	err := s.HTTPDService.AddRoutes(s.routes)
-	return errors.Wrap(err, "failed to add API routes")
+	if err := s.HTTPDService.AddRoutes(s.routes); err != nil {
+		return errors.Wrap(err, "failed to add API routes")
+	}
+
+	s.closing = make(chan struct{})
+	s.wg.Add(1)
+	go s.refresh()
+	return nil
 }
+
 func (s *Service) Close() error {
+	if s.closing != nil {
+		close(s.closing)
+		s.wg.Wait()
+	}
 	s.HTTPDService.DelRoutes(s.routes)
 	return nil
 }
 
+// refresh fetches the remote sources again on every refresh interval.
+// Sources that fail to update keep their previous values.
+func (s *Service) refresh() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(time.Duration(s.config.RefreshInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.closing:
+			return
+		case <-ticker.C:
+			for _, src := range s.remoteSources() {
+				if err := src.updateCache(); err != nil {
+					s.diag.Error("failed to refresh sideload source", err)
+				}
+			}
+		}
+	}
+}
+
+func (s *Service) remoteSources() []*source {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sources []*source
+	for _, src := range s.sources {
+		if src.url != nil {
+			sources = append(sources, src)
+		}
+	}
+	return sources
+}
+
 func (s *Service) handleReload(w http.ResponseWriter, r *http.Request) {
 	err := s.Reload()
 	if err != nil {
@@ -74,55 +150,103 @@ func (s *Service) handleReload(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (s *Service) handleSources(w http.ResponseWriter, r *http.Request) {
+	w.Write(httpd.MarshalJSON(s.Sources(), true))
+}
+
 func (s *Service) Reload() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	for dir, src := range s.sources {
+	for key, src := range s.sources {
 		if err := src.updateCache(); err != nil {
-			return errors.Wrapf(err, "failed to update source %q", dir)
+			return errors.Wrapf(err, "failed to update source %q", key)
 		}
 	}
 	return nil
 }
 
-func (s *Service) Source(srcURL string) (Source, error) {
+// Sources returns the sources with the nodes using them, and their files read again without applying them,
+// so that the effects of editing a file are known before reloading it.
+func (s *Service) Sources() client.SideloadSources {
+	s.mu.Lock()
+	sources := make([]*source, 0, len(s.sources))
+	for _, src := range s.sources {
+		sources = append(sources, src)
+	}
+	s.mu.Unlock()
+
+	ss := client.SideloadSources{
+		Link:    client.Link{Relation: client.Self, Href: httpd.BasePath + sourcesPath},
+		Sources: make([]client.SideloadSource, 0, len(sources)),
+	}
+	for _, src := range sources {
+		ss.Sources = append(ss.Sources, src.status())
+	}
+	sort.Slice(ss.Sources, func(i, j int) bool {
+		return ss.Sources[i].Source < ss.Sources[j].Source
+	})
+	return ss
+}
+
+// Reference is the use of a source by a sideload node of a task.
+type Reference struct {
+	Task string
+	Node string
+	// Order templates of the node.
+	Order []string
+	// Keys of the fields and tags loaded by the node.
+	Keys []string
+}
+
+// Source returns the source of the URL, either a file:// directory of values files
+// or the tar archive of such a directory served by an http(s):// endpoint or an s3://, gs:// or az:// object.
+// The source is used by the reference until it is closed.
+func (s *Service) Source(srcURL string, ref Reference) (Source, error) {
 	u, err := url.Parse(srcURL)
 	if err != nil {
 		return nil, err
 	}
-	if u.Scheme != "file" {
-		return nil, fmt.Errorf("unsupported source scheme %q, must be 'file'", u.Scheme)
-	}
-	if !filepath.IsAbs(u.Path) {
-		return nil, fmt.Errorf("sideload source path must be absolute %q", u.Path)
+	var key string
+	src := &source{s: s, refs: make(map[*handle]Reference)}
+	switch {
+	case u.Scheme == "file":
+		if !filepath.IsAbs(u.Path) {
+			return nil, fmt.Errorf("sideload source path must be absolute %q", u.Path)
+		}
+		src.dir = filepath.Clean(u.Path)
+		key = src.dir
+	case isRemote(u.Scheme):
+		src.url = u
+		key = u.String()
+	default:
+		return nil, fmt.Errorf("unsupported source scheme %q, must be one of 'file', 'http', 'https', 's3', 'gs' or 'az'", u.Scheme)
 	}
-	dir := filepath.Clean(u.Path)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	src, ok := s.sources[dir]
-	if !ok {
-		src = &source{
-			s:   s,
-			dir: dir,
-		}
+	if existing, ok := s.sources[key]; ok {
+		src = existing
+	} else {
+		src.key = key
 		err := src.updateCache()
 		if err != nil {
 			return nil, err
 		}
-		s.sources[dir] = src
+		s.sources[key] = src
 	}
-	src.referenceCount++
+	h := &handle{source: src}
+	src.refs[h] = ref
 
-	return src, nil
+	return h, nil
 }
 
-func (s *Service) removeSource(src *source) {
+func (s *Service) removeSource(h *handle) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	src.referenceCount--
-	if src.referenceCount == 0 {
-		delete(s.sources, src.dir)
+	src := h.source
+	delete(src.refs, h)
+	if len(src.refs) == 0 {
+		delete(s.sources, src.key)
 	}
 }
 
@@ -132,21 +256,242 @@ type Source interface {
 }
 
 type source struct {
-	s              *Service
-	dir            string
-	mu             sync.RWMutex
-	cache          map[string]map[string]interface{}
-	referenceCount int
+	s *Service
+	// key of the source in the sources of the service, its directory or URL.
+	key string
+	// dir is the directory of a local source.
+	dir string
+	// url is the URL of the archive of a remote source.
+	url *url.URL
+
+	mu    sync.RWMutex
+	cache map[string]map[string]interface{}
+	// matches are the files of the cache matching the wildcard paths looked up, sorted,
+	// guarded by matchMu while the cache is read and reset when it is replaced.
+	matchMu sync.Mutex
+	matches map[string][]string
+	// refs are the references of the open handles of the source, guarded by the mutex of the service.
+	refs map[*handle]Reference
+
+	// The entity tag and checksum of the archive of a remote source last applied,
+	// guarded by updateMu.
+	updateMu sync.Mutex
+	etag     string
+	sum      [sha256.Size]byte
+}
+
+// handle is the source used by a reference.
+type handle struct {
+	*source
+	once sync.Once
+}
+
+func (h *handle) Close() {
+	h.once.Do(func() {
+		h.s.removeSource(h)
+	})
+}
+
+// status reports the references of the source and its files as they are now.
+func (s *source) status() client.SideloadSource {
+	s.s.mu.Lock()
+	refs := make([]Reference, 0, len(s.refs))
+	for _, ref := range s.refs {
+		refs = append(refs, ref)
+	}
+	s.s.mu.Unlock()
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Task != refs[j].Task {
+			return refs[i].Task < refs[j].Task
+		}
+		return refs[i].Node < refs[j].Node
+	})
+
+	ss := client.SideloadSource{
+		Source:     s.key,
+		References: make([]client.SideloadReference, len(refs)),
+		Files:      []client.SideloadFile{},
+	}
+	if s.url == nil {
+		ss.Source = "file://" + filepath.ToSlash(s.dir)
+	}
+	globs := make([][]string, len(refs))
+	for i, ref := range refs {
+		ss.References[i] = client.SideloadReference{
+			Task:  ref.Task,
+			Node:  ref.Node,
+			Order: ref.Order,
+			Keys:  sortedKeys(ref.Keys),
+		}
+		globs[i] = make([]string, len(ref.Order))
+		for j, o := range ref.Order {
+			globs[i][j] = orderGlob(o)
+		}
+	}
+
+	files, err := s.readFiles()
+	if err != nil {
+		ss.Error = err.Error()
+		return ss
+	}
+	for _, f := range files {
+		cf := client.SideloadFile{
+			Path:       f.path,
+			Keys:       []string{},
+			References: []client.SideloadReference{},
+		}
+		if f.err != nil {
+			cf.Error = f.err.Error()
+		}
+		for k := range f.values {
+			cf.Keys = append(cf.Keys, k)
+		}
+		sort.Strings(cf.Keys)
+		for i, ref := range refs {
+			if !matchesAny(globs[i], filepath.ToSlash(f.path)) {
+				continue
+			}
+			keys := []string{}
+			for _, k := range ss.References[i].Keys {
+				if _, ok := f.values[k]; ok {
+					keys = append(keys, k)
+				}
+			}
+			cf.References = append(cf.References, client.SideloadReference{
+				Task: ref.Task,
+				Node: ref.Node,
+				Keys: keys,
+			})
+		}
+		ss.Files = append(ss.Files, cf)
+	}
+	return ss
+}
+
+// valuesFile is a values file of a source read without being applied.
+type valuesFile struct {
+	path   string
+	values map[string]interface{}
+	err    error
 }
 
-func (s *source) Close() {
-	s.s.removeSource(s)
+// readFiles reads the values files of the source, sorted by path.
+// Errors of a file are reported with the file, the returned error fails the whole source.
+func (s *source) readFiles() ([]valuesFile, error) {
+	var files []valuesFile
+	if s.url != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.s.config.Timeout))
+		defer cancel()
+		data, _, err := fetch(ctx, s.url, "")
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch sideload source %q", s.key)
+		}
+		if err := s.verify(ctx, data); err != nil {
+			return nil, err
+		}
+		contents, err := readArchiveFiles(data)
+		if err != nil {
+			return nil, err
+		}
+		for rel, content := range contents {
+			values, err := parseValues(rel, content)
+			files = append(files, valuesFile{path: rel, values: values, err: err})
+		}
+	} else {
+		err := filepath.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(s.dir, path)
+			if err != nil {
+				return err
+			}
+			// Files with a relative path starting with '.' fail a reload like files that fail to parse.
+			if len(rel) == 0 || rel[0] == '.' {
+				files = append(files, valuesFile{path: rel, err: errors.New("invalid relative path")})
+				return nil
+			}
+			values, err := readValues(path)
+			files = append(files, valuesFile{path: rel, values: values, err: err})
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read sideload source %q", s.dir)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+	return files, nil
+}
+
+// orderGlob returns the pattern of the paths an order template evaluates to,
+// each template action matches any characters but the path separator.
+func orderGlob(order string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(order, "{{")
+		if i < 0 {
+			break
+		}
+		j := strings.Index(order[i:], "}}")
+		if j < 0 {
+			break
+		}
+		b.WriteString(order[:i])
+		b.WriteByte('*')
+		order = order[i+j+2:]
+	}
+	b.WriteString(order)
+	return b.String()
+}
+
+// QuoteMeta escapes the wildcard characters of the text so that a path of an order matches it literally.
+func QuoteMeta(text string) string {
+	if !hasMeta(text) {
+		return text
+	}
+	var b strings.Builder
+	for _, r := range text {
+		switch r {
+		case '*', '?', '[', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func hasMeta(p string) bool {
+	return strings.ContainsAny(p, `*?[\`)
+}
+
+func matchesAny(globs []string, p string) bool {
+	for _, g := range globs {
+		if ok, _ := path.Match(g, path.Clean(p)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(keys []string) []string {
+	sorted := append([]string{}, keys...)
+	sort.Strings(sorted)
+	return sorted
 }
 
 func (s *source) updateCache() error {
+	if s.url != nil {
+		return s.updateRemote()
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.cache = make(map[string]map[string]interface{})
+	s.matches = nil
 	err := filepath.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -174,6 +519,68 @@ func (s *source) updateCache() error {
 	return errors.Wrapf(err, "failed to update sideload cache for source %q", s.dir)
 }
 
+// updateRemote fetches the archive of the remote source, unless its entity tag is unchanged,
+// verifies its checksum and signature if configured and replaces the values with its files.
+func (s *source) updateRemote() error {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.s.config.Timeout))
+	defer cancel()
+
+	data, etag, err := fetch(ctx, s.url, s.etag)
+	if err == objectstore.ErrNotModified {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to fetch sideload source %q", s.key)
+	}
+	sum := sha256.Sum256(data)
+	if sum == s.sum {
+		s.etag = etag
+		return nil
+	}
+	if err := s.verify(ctx, data); err != nil {
+		return err
+	}
+	cache, err := readArchive(data)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update sideload cache for source %q", s.key)
+	}
+
+	s.mu.Lock()
+	s.cache = cache
+	s.matches = nil
+	s.mu.Unlock()
+	s.etag = etag
+	s.sum = sum
+	return nil
+}
+
+// verify checks the checksum and signature of the archive of the remote source if configured.
+func (s *source) verify(ctx context.Context, data []byte) error {
+	if s.s.config.VerifyChecksum {
+		checksum, _, err := fetch(ctx, companion(s.url, checksumSuffix), "")
+		if err != nil {
+			return errors.Wrapf(err, "failed to fetch checksum of sideload source %q", s.key)
+		}
+		if err := verifyChecksum(data, checksum); err != nil {
+			return errors.Wrapf(err, "failed to verify sideload source %q", s.key)
+		}
+	}
+	if s.s.publicKey != nil {
+		sig, _, err := fetch(ctx, companion(s.url, signatureSuffix), "")
+		if err != nil {
+			return errors.Wrapf(err, "failed to fetch signature of sideload source %q", s.key)
+		}
+		if err := verifySignature(s.s.publicKey, data, sig); err != nil {
+			return errors.Wrapf(err, "failed to verify sideload source %q", s.key)
+		}
+	}
+	return nil
+}
+
+// Lookup returns the value of the key in the first file of the order with the key.
+// A path of the order with wildcards, as in path.Match, checks the files matching it in lexical order.
 func (s *source) Lookup(order []string, key string) (value interface{}) {
 	key = filepath.Clean(key)
 
@@ -181,20 +588,46 @@ func (s *source) Lookup(order []string, key string) (value interface{}) {
 	defer s.mu.RUnlock()
 
 	for _, o := range order {
-		values, ok := s.cache[o]
-		if !ok {
+		if values, ok := s.cache[o]; ok {
+			if v, ok := values[key]; ok {
+				return v
+			}
 			continue
 		}
-		v, ok := values[key]
-		if !ok {
+		if !hasMeta(o) {
 			continue
 		}
-		value = v
-		break
+		for _, p := range s.match(o) {
+			if v, ok := s.cache[p][key]; ok {
+				return v
+			}
+		}
 	}
 	return
 }
 
+// match returns the files of the cache matching the pattern, sorted.
+// The read lock of the cache must be held.
+func (s *source) match(pattern string) []string {
+	s.matchMu.Lock()
+	defer s.matchMu.Unlock()
+	if files, ok := s.matches[pattern]; ok {
+		return files
+	}
+	var files []string
+	for p := range s.cache {
+		if ok, _ := path.Match(pattern, filepath.ToSlash(p)); ok {
+			files = append(files, p)
+		}
+	}
+	sort.Strings(files)
+	if s.matches == nil {
+		s.matches = make(map[string][]string)
+	}
+	s.matches[pattern] = files
+	return files
+}
+
 func readValues(p string) (map[string]interface{}, error) {
 	f, err := os.Open(p)
 	if err != nil {
@@ -205,7 +638,12 @@ func readValues(p string) (map[string]interface{}, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to read values file %q", p)
 	}
+	return parseValues(p, data)
+}
 
+// parseValues unmarshals the values of the file according to its extension,
+// files that are neither YAML nor JSON have no values.
+func parseValues(p string, data []byte) (map[string]interface{}, error) {
 	values := make(map[string]interface{})
 	ext := filepath.Ext(p)
 	switch ext {
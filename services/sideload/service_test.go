@@ -1,17 +1,33 @@
 package sideload_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
 	"github.com/influxdata/kapacitor/services/sideload"
 )
 
 func NewService() *sideload.Service {
-	s := sideload.NewService(nil)
+	s := sideload.NewService(sideload.NewConfig(), nil)
 	return s
 }
 
@@ -22,7 +38,7 @@ func TestService_Source_Lookup(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	src, err := s.Source(fmt.Sprintf("file://%s/testdata/src0", wd))
+	src, err := s.Source(fmt.Sprintf("file://%s/testdata/src0", wd), sideload.Reference{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -78,3 +94,330 @@ func TestService_Source_Lookup(t *testing.T) {
 		})
 	}
 }
+
+// archive returns the gzipped tar archive of the files.
+func archive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestService_Source_Remote(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "sideload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyPath := filepath.Join(dir, "sideload.pub")
+	if err := ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var data, sig []byte
+	etag := ""
+	fetches := 0
+	publish := func(files map[string]string, version string) {
+		mu.Lock()
+		defer mu.Unlock()
+		data = archive(t, files)
+		sig = []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data)))
+		etag = `"` + version + `"`
+	}
+	publish(map[string]string{
+		"default.yml":     "key0: 0.0\nkey1: one\n",
+		"host/hostA.json": `{"key0": 5.0}`,
+	}, "v1")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/tree.tar.gz":
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			fetches++
+			w.Header().Set("ETag", etag)
+			w.Write(data)
+		case "/tree.tar.gz.sha256":
+			fmt.Fprintf(w, "%x  tree.tar.gz\n", sha256.Sum256(data))
+		case "/tree.tar.gz.sig":
+			w.Write(sig)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	c := sideload.NewConfig()
+	c.VerifyChecksum = true
+	c.PublicKey = keyPath
+	s := sideload.NewService(c, nil)
+	s.HTTPDService = httpdService{}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	src, err := s.Source(ts.URL+"/tree.tar.gz", sideload.Reference{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	order := []string{"host/hostA.json", "default.yml"}
+	if got := src.Lookup(order, "key0"); got != 5.0 {
+		t.Errorf("unexpected key0 %v", got)
+	}
+
+	// The archive is not fetched again while its entity tag is unchanged.
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if fetches != 1 {
+		t.Errorf("unexpected fetches of unchanged archive %d", fetches)
+	}
+	mu.Unlock()
+
+	publish(map[string]string{
+		"default.yml":     "key0: 0.0\nkey1: two\n",
+		"host/hostA.json": `{"key0": 6.0}`,
+	}, "v2")
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := src.Lookup(order, "key0"); got != 6.0 {
+		t.Errorf("unexpected key0 after update %v", got)
+	}
+
+	// Archives with invalid signatures are not applied.
+	publish(map[string]string{"default.yml": "key1: three\n"}, "v3")
+	mu.Lock()
+	sig = []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte("other"))))
+	mu.Unlock()
+	if err := s.Reload(); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+		t.Errorf("expected invalid signature error, got %v", err)
+	}
+	if got := src.Lookup(order, "key1"); got != "two" {
+		t.Errorf("unexpected key1 after invalid update %v", got)
+	}
+}
+
+func TestService_Source_LookupWildcard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sideload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"default.yml":            "key0: 0.0\nkey1: default\nkey2: default\n",
+		"dc/east/a.yml":          "key1: east-a\n",
+		"dc/east/b.yml":          "key1: east-b\nkey2: east-b\n",
+		"dc/west/a.yml":          "key1: west-a\n",
+		"host/server*.yml":       "key0: 9.0\n",
+		"host/serverA.yml":       "key0: 5.0\n",
+		"dc/east/notes/skip.yml": "key2: nested\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewService()
+	src, err := s.Source("file://"+dir, sideload.Reference{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	testCases := []struct {
+		order []string
+		key   string
+		want  interface{}
+	}{
+		{
+			order: []string{"dc/east/*.yml", "default.yml"},
+			key:   "key1",
+			want:  "east-a",
+		},
+		{
+			// Keys missing from the first matching files fall back to the next ones.
+			order: []string{"dc/east/*.yml", "default.yml"},
+			key:   "key2",
+			want:  "east-b",
+		},
+		{
+			order: []string{"dc/west/*.yml", "default.yml"},
+			key:   "key2",
+			want:  "default",
+		},
+		{
+			order: []string{"dc/*/b.yml", "default.yml"},
+			key:   "key1",
+			want:  "east-b",
+		},
+		{
+			order: []string{"dc/north/*.yml", "default.yml"},
+			key:   "key1",
+			want:  "default",
+		},
+		{
+			order: []string{"host/server?.yml"},
+			key:   "key0",
+			want:  9.0,
+		},
+		{
+			// Quoted wildcards match literally.
+			order: []string{"host/" + sideload.QuoteMeta("server*") + ".yml"},
+			key:   "key0",
+			want:  9.0,
+		},
+		{
+			order: []string{"host/" + sideload.QuoteMeta("serverB") + ".yml", "default.yml"},
+			key:   "key0",
+			want:  0.0,
+		},
+	}
+	for _, tc := range testCases {
+		if got := src.Lookup(tc.order, tc.key); !cmp.Equal(got, tc.want) {
+			t.Errorf("unexpected %s value for order %v: got %v want %v", tc.key, tc.order, got, tc.want)
+		}
+	}
+}
+
+func TestService_Sources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sideload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("default.yml", "key0: 0.0\nkey1: one\n")
+	write("host/hostA.yml", "key0: 5.0\n")
+
+	s := NewService()
+	src1, err := s.Source("file://"+dir, sideload.Reference{
+		Task:  "task1",
+		Node:  "sideload2",
+		Order: []string{"host/{{.host}}.yml", "default.yml"},
+		Keys:  []string{"key0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src1.Close()
+	src2, err := s.Source("file://"+dir, sideload.Reference{
+		Task:  "task2",
+		Node:  "sideload3",
+		Order: []string{"default.yml"},
+		Keys:  []string{"key1", "key0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An invalid edit is reported before it fails a reload.
+	write("host/hostB.yml", "key0: [\n")
+
+	exp := client.SideloadSources{
+		Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/sideload/sources"},
+		Sources: []client.SideloadSource{{
+			Source: "file://" + filepath.ToSlash(dir),
+			References: []client.SideloadReference{
+				{Task: "task1", Node: "sideload2", Order: []string{"host/{{.host}}.yml", "default.yml"}, Keys: []string{"key0"}},
+				{Task: "task2", Node: "sideload3", Order: []string{"default.yml"}, Keys: []string{"key0", "key1"}},
+			},
+			Files: []client.SideloadFile{
+				{
+					Path: "default.yml",
+					Keys: []string{"key0", "key1"},
+					References: []client.SideloadReference{
+						{Task: "task1", Node: "sideload2", Keys: []string{"key0"}},
+						{Task: "task2", Node: "sideload3", Keys: []string{"key0", "key1"}},
+					},
+				},
+				{
+					Path: "host/hostA.yml",
+					Keys: []string{"key0"},
+					References: []client.SideloadReference{
+						{Task: "task1", Node: "sideload2", Keys: []string{"key0"}},
+					},
+				},
+				{
+					Path: "host/hostB.yml",
+					Keys: []string{},
+					References: []client.SideloadReference{
+						{Task: "task1", Node: "sideload2", Keys: []string{}},
+					},
+				},
+			},
+		}},
+	}
+	got := s.Sources()
+	errMsg := got.Sources[0].Files[2].Error
+	if !strings.Contains(errMsg, "failed to unmarshal yaml values") {
+		t.Errorf("expected error of host/hostB.yml, got %q", errMsg)
+	}
+	got.Sources[0].Files[2].Error = ""
+	if !cmp.Equal(exp, got) {
+		t.Errorf("unexpected sources -want/+got\n%s", cmp.Diff(exp, got))
+	}
+	if err := s.Reload(); err == nil {
+		t.Error("expected reload error")
+	}
+
+	// The source is no longer referenced by a closed node.
+	src2.Close()
+	if got := s.Sources().Sources[0].References; len(got) != 1 || got[0].Task != "task1" {
+		t.Errorf("unexpected references after close %v", got)
+	}
+}
+
+func TestService_Source_UnsupportedScheme(t *testing.T) {
+	s := NewService()
+	if _, err := s.Source("ftp://example.com/tree.tar.gz", sideload.Reference{}); err == nil {
+		t.Error("expected error for unsupported scheme")
+	}
+}
+
+type httpdService struct{}
+
+func (httpdService) AddRoutes([]httpd.Route) error { return nil }
+func (httpdService) DelRoutes([]httpd.Route)       {}
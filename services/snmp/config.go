@@ -0,0 +1,218 @@
+package snmp
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultInterval is how often the agents are polled.
+	DefaultInterval = time.Minute
+
+	// DefaultTimeout is how long to wait for a response from an agent.
+	DefaultTimeout = 5 * time.Second
+
+	// DefaultVersion is the SNMP version used to poll agents.
+	DefaultVersion = "2c"
+
+	// DefaultCommunity is the community used for v1 and v2c agents.
+	DefaultCommunity = "public"
+
+	// DefaultMaxRepetitions is the number of rows requested at once when walking tables.
+	DefaultMaxRepetitions = 10
+
+	// DefaultMeasurement is the measurement of points created from scalar fields.
+	DefaultMeasurement = "snmp"
+
+	// DefaultIndexTag is the tag holding the row index of points created from tables.
+	DefaultIndexTag = "index"
+)
+
+type Config struct {
+	Enabled bool   `toml:"enabled"`
+	Name    string `toml:"name"`
+	// Agents are the host:port addresses of the agents to poll.
+	// If the port is omitted 161 is used.
+	Agents []string `toml:"agents"`
+	// Version is the SNMP version, one of 1, 2c or 3.
+	Version   string `toml:"version"`
+	Community string `toml:"community"`
+	Retries   int    `toml:"retries"`
+	// Timeout of a single request to an agent.
+	Timeout toml.Duration `toml:"timeout"`
+	// MaxRepetitions is the number of rows requested at once when walking tables.
+	MaxRepetitions int `toml:"max-repetitions"`
+
+	// SNMPv3 security parameters.
+	Username string `toml:"username"`
+	// SecurityLevel is one of noAuthNoPriv, authNoPriv or authPriv.
+	SecurityLevel string `toml:"security-level"`
+	// AuthProtocol is one of MD5 or SHA.
+	AuthProtocol string `toml:"auth-protocol"`
+	AuthPassword string `toml:"auth-password"`
+	// PrivProtocol is one of DES or AES.
+	PrivProtocol string `toml:"priv-protocol"`
+	PrivPassword string `toml:"priv-password"`
+	ContextName  string `toml:"context-name"`
+
+	// Interval is how often the agents are polled.
+	Interval toml.Duration `toml:"interval"`
+
+	// MIBDirs are directories containing MIB files used to resolve object names.
+	// Common objects from SNMPv2-MIB, IF-MIB and HOST-RESOURCES-MIB are always known.
+	// The fields or tables with objects that cannot be resolved are not polled.
+	MIBDirs []string `toml:"mib-dirs"`
+
+	// Measurement is the name of the points created from the scalar fields.
+	Measurement     string `toml:"measurement"`
+	Database        string `toml:"database"`
+	RetentionPolicy string `toml:"retention-policy"`
+
+	// Fields are the scalar objects requested from each agent.
+	// They are written together as a single point per agent.
+	Fields []FieldConfig `toml:"field"`
+	// Tables are the tables walked on each agent.
+	Tables []TableConfig `toml:"table"`
+}
+
+// FieldConfig maps an SNMP object to a field or tag.
+type FieldConfig struct {
+	// Name of the field or tag, defaults to the object name.
+	Name string `toml:"name"`
+	// OID is either a numeric OID or an object name,
+	// i.e. 1.3.6.1.2.1.1.3.0 or SNMPv2-MIB::sysUpTime.0.
+	OID string `toml:"oid"`
+	// IsTag stores the value as a tag instead of a field.
+	IsTag bool `toml:"is-tag"`
+}
+
+// TableConfig describes a table to walk, each row becomes a point.
+type TableConfig struct {
+	// Measurement is the name of the points created from the table rows.
+	Measurement string `toml:"measurement"`
+	// IndexTag is the tag holding the row index.
+	IndexTag string `toml:"index-tag"`
+	// Fields are the columns of the table to walk.
+	Fields []FieldConfig `toml:"field"`
+}
+
+func NewConfig() Config {
+	return Config{
+		Version:        DefaultVersion,
+		Community:      DefaultCommunity,
+		Timeout:        toml.Duration(DefaultTimeout),
+		MaxRepetitions: DefaultMaxRepetitions,
+		Interval:       toml.Duration(DefaultInterval),
+		Measurement:    DefaultMeasurement,
+	}
+}
+
+// WithDefaults takes the given config and returns a new config with any required
+// default values set.
+func (c Config) WithDefaults() Config {
+	d := c
+	if d.Version == "" {
+		d.Version = DefaultVersion
+	}
+	if d.Timeout <= 0 {
+		d.Timeout = toml.Duration(DefaultTimeout)
+	}
+	if d.MaxRepetitions <= 0 {
+		d.MaxRepetitions = DefaultMaxRepetitions
+	}
+	if d.Interval <= 0 {
+		d.Interval = toml.Duration(DefaultInterval)
+	}
+	if d.Measurement == "" {
+		d.Measurement = DefaultMeasurement
+	}
+	d.Tables = make([]TableConfig, len(c.Tables))
+	for i, t := range c.Tables {
+		if t.IndexTag == "" {
+			t.IndexTag = DefaultIndexTag
+		}
+		d.Tables[i] = t
+	}
+	return d
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Name == "" {
+		return errors.New("must specify a name")
+	}
+	if len(c.Agents) == 0 {
+		return errors.New("must specify at least one agent")
+	}
+	for _, a := range c.Agents {
+		if _, _, err := net.SplitHostPort(agentAddr(a)); err != nil {
+			return errors.Wrapf(err, "invalid agent address %q", a)
+		}
+	}
+	if c.Database == "" {
+		return errors.New("must specify a database")
+	}
+	switch c.Version {
+	case "", "1", "2c":
+	case "3":
+		if c.Username == "" {
+			return errors.New("must specify a username for SNMPv3")
+		}
+		switch c.SecurityLevel {
+		case "", "noAuthNoPriv", "authNoPriv", "authPriv":
+		default:
+			return fmt.Errorf("invalid security-level %q", c.SecurityLevel)
+		}
+		switch c.AuthProtocol {
+		case "", "MD5", "SHA":
+		default:
+			return fmt.Errorf("invalid auth-protocol %q", c.AuthProtocol)
+		}
+		switch c.PrivProtocol {
+		case "", "DES", "AES":
+		default:
+			return fmt.Errorf("invalid priv-protocol %q", c.PrivProtocol)
+		}
+	default:
+		return fmt.Errorf("invalid version %q, must be one of 1, 2c or 3", c.Version)
+	}
+	if len(c.Fields) == 0 && len(c.Tables) == 0 {
+		return errors.New("must specify at least one field or table")
+	}
+	if c.Version == "1" && len(c.Tables) > 0 {
+		return errors.New("tables require SNMP version 2c or 3")
+	}
+	for _, f := range c.Fields {
+		if f.OID == "" {
+			return errors.New("must specify an oid for each field")
+		}
+	}
+	for _, t := range c.Tables {
+		if t.Measurement == "" {
+			return errors.New("must specify a measurement for each table")
+		}
+		if len(t.Fields) == 0 {
+			return fmt.Errorf("must specify at least one field for table %q", t.Measurement)
+		}
+		for _, f := range t.Fields {
+			if f.OID == "" {
+				return fmt.Errorf("must specify an oid for each field of table %q", t.Measurement)
+			}
+		}
+	}
+	return nil
+}
+
+// agentAddr adds the default SNMP port to addresses without one.
+func agentAddr(a string) string {
+	if _, _, err := net.SplitHostPort(a); err != nil {
+		return net.JoinHostPort(a, "161")
+	}
+	return a
+}
@@ -0,0 +1,333 @@
+package snmp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// baseMIB contains the well known object names so that common
+// objects can be resolved without loading any MIB files.
+var baseMIB = map[string]string{
+	"iso":          "1",
+	"org":          "1.3",
+	"dod":          "1.3.6",
+	"internet":     "1.3.6.1",
+	"directory":    "1.3.6.1.1",
+	"mgmt":         "1.3.6.1.2",
+	"mib-2":        "1.3.6.1.2.1",
+	"experimental": "1.3.6.1.3",
+	"private":      "1.3.6.1.4",
+	"enterprises":  "1.3.6.1.4.1",
+	"security":     "1.3.6.1.5",
+	"snmpV2":       "1.3.6.1.6",
+	"snmpModules":  "1.3.6.1.6.3",
+
+	// SNMPv2-MIB
+	"system":      "1.3.6.1.2.1.1",
+	"sysDescr":    "1.3.6.1.2.1.1.1",
+	"sysObjectID": "1.3.6.1.2.1.1.2",
+	"sysUpTime":   "1.3.6.1.2.1.1.3",
+	"sysContact":  "1.3.6.1.2.1.1.4",
+	"sysName":     "1.3.6.1.2.1.1.5",
+	"sysLocation": "1.3.6.1.2.1.1.6",
+	"sysServices": "1.3.6.1.2.1.1.7",
+
+	// IF-MIB
+	"interfaces":        "1.3.6.1.2.1.2",
+	"ifNumber":          "1.3.6.1.2.1.2.1",
+	"ifTable":           "1.3.6.1.2.1.2.2",
+	"ifEntry":           "1.3.6.1.2.1.2.2.1",
+	"ifIndex":           "1.3.6.1.2.1.2.2.1.1",
+	"ifDescr":           "1.3.6.1.2.1.2.2.1.2",
+	"ifType":            "1.3.6.1.2.1.2.2.1.3",
+	"ifMtu":             "1.3.6.1.2.1.2.2.1.4",
+	"ifSpeed":           "1.3.6.1.2.1.2.2.1.5",
+	"ifPhysAddress":     "1.3.6.1.2.1.2.2.1.6",
+	"ifAdminStatus":     "1.3.6.1.2.1.2.2.1.7",
+	"ifOperStatus":      "1.3.6.1.2.1.2.2.1.8",
+	"ifLastChange":      "1.3.6.1.2.1.2.2.1.9",
+	"ifInOctets":        "1.3.6.1.2.1.2.2.1.10",
+	"ifInUcastPkts":     "1.3.6.1.2.1.2.2.1.11",
+	"ifInDiscards":      "1.3.6.1.2.1.2.2.1.13",
+	"ifInErrors":        "1.3.6.1.2.1.2.2.1.14",
+	"ifOutOctets":       "1.3.6.1.2.1.2.2.1.16",
+	"ifOutUcastPkts":    "1.3.6.1.2.1.2.2.1.17",
+	"ifOutDiscards":     "1.3.6.1.2.1.2.2.1.19",
+	"ifOutErrors":       "1.3.6.1.2.1.2.2.1.20",
+	"ifMIB":             "1.3.6.1.2.1.31",
+	"ifXTable":          "1.3.6.1.2.1.31.1.1",
+	"ifXEntry":          "1.3.6.1.2.1.31.1.1.1",
+	"ifName":            "1.3.6.1.2.1.31.1.1.1.1",
+	"ifHCInOctets":      "1.3.6.1.2.1.31.1.1.1.6",
+	"ifHCInUcastPkts":   "1.3.6.1.2.1.31.1.1.1.7",
+	"ifHCOutOctets":     "1.3.6.1.2.1.31.1.1.1.10",
+	"ifHCOutUcastPkts":  "1.3.6.1.2.1.31.1.1.1.11",
+	"ifHighSpeed":       "1.3.6.1.2.1.31.1.1.1.15",
+	"ifAlias":           "1.3.6.1.2.1.31.1.1.1.18",
+	"hrSystemUptime":    "1.3.6.1.2.1.25.1.1",
+	"hrSystemProcesses": "1.3.6.1.2.1.25.1.6",
+	"hrStorageTable":    "1.3.6.1.2.1.25.2.3",
+	"hrStorageDescr":    "1.3.6.1.2.1.25.2.3.1.3",
+	"hrStorageSize":     "1.3.6.1.2.1.25.2.3.1.5",
+	"hrStorageUsed":     "1.3.6.1.2.1.25.2.3.1.6",
+	"hrProcessorLoad":   "1.3.6.1.2.1.25.3.3.1.2",
+}
+
+// definingMacros are the ASN.1 macros that assign an OID to a name.
+var definingMacros = map[string]bool{
+	"OBJECT":             true,
+	"OBJECT-TYPE":        true,
+	"OBJECT-IDENTITY":    true,
+	"MODULE-IDENTITY":    true,
+	"NOTIFICATION-TYPE":  true,
+	"OBJECT-GROUP":       true,
+	"NOTIFICATION-GROUP": true,
+	"MODULE-COMPLIANCE":  true,
+	"AGENT-CAPABILITIES": true,
+}
+
+// MIB resolves object names to numeric OIDs and back.
+type MIB struct {
+	oids  map[string]string
+	names map[string]string
+}
+
+// NewMIB returns a MIB containing the well known objects
+// and all objects defined in the MIB files found in dirs.
+// The MIB files that cannot be read are skipped, the MIB of the others
+// is returned along with an error listing them.
+func NewMIB(dirs []string) (*MIB, error) {
+	m := &MIB{
+		oids:  make(map[string]string, len(baseMIB)),
+		names: make(map[string]string, len(baseMIB)),
+	}
+	for name, oid := range baseMIB {
+		m.add(name, oid)
+	}
+	var defs []mibDef
+	var failures []string
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			failures = append(failures, errors.Wrapf(err, "failed to read MIB directory %q", dir).Error())
+			continue
+		}
+		for _, fi := range files {
+			if fi.IsDir() {
+				continue
+			}
+			path := filepath.Join(dir, fi.Name())
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				failures = append(failures, errors.Wrapf(err, "failed to read MIB file %q", path).Error())
+				continue
+			}
+			defs = append(defs, parseMIB(string(data))...)
+		}
+	}
+	m.resolve(defs)
+	if len(failures) > 0 {
+		return m, errors.New(strings.Join(failures, "; "))
+	}
+	return m, nil
+}
+
+func (m *MIB) add(name, oid string) {
+	m.oids[name] = oid
+	if _, ok := m.names[oid]; !ok {
+		m.names[oid] = name
+	}
+}
+
+// resolve assigns OIDs to definitions once their parents are known.
+// Definitions whose parents never resolve are dropped.
+func (m *MIB) resolve(defs []mibDef) {
+	for len(defs) > 0 {
+		var unresolved []mibDef
+		for _, d := range defs {
+			parent, ok := m.oids[d.parent]
+			if !ok {
+				unresolved = append(unresolved, d)
+				continue
+			}
+			m.add(d.name, parent+"."+strings.Join(d.subIDs, "."))
+		}
+		if len(unresolved) == len(defs) {
+			return
+		}
+		defs = unresolved
+	}
+}
+
+// OID resolves an object name to its numeric OID.
+// Names may be qualified with their module and have an instance suffix,
+// i.e. IF-MIB::ifInOctets.1. Numeric OIDs are returned as is.
+func (m *MIB) OID(name string) (string, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(name), ".")
+	if i := strings.Index(s, "::"); i >= 0 {
+		s = s[i+2:]
+	}
+	if s == "" {
+		return "", fmt.Errorf("invalid object name %q", name)
+	}
+	if isNumericOID(s) {
+		return s, nil
+	}
+	object, suffix := s, ""
+	if i := strings.Index(s, "."); i >= 0 {
+		object, suffix = s[:i], s[i:]
+	}
+	oid, ok := m.oids[object]
+	if !ok {
+		return "", fmt.Errorf("unknown object name %q", name)
+	}
+	if suffix != "" && !isNumericOID(suffix[1:]) {
+		return "", fmt.Errorf("invalid instance suffix in %q", name)
+	}
+	return oid + suffix, nil
+}
+
+// Name returns the object name of the OID if it is known.
+func (m *MIB) Name(oid string) (string, bool) {
+	name, ok := m.names[strings.TrimPrefix(oid, ".")]
+	return name, ok
+}
+
+func isNumericOID(s string) bool {
+	for _, part := range strings.Split(s, ".") {
+		if _, err := strconv.ParseUint(part, 10, 32); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// mibDef is a single OID assignment read from a MIB file.
+type mibDef struct {
+	name   string
+	parent string
+	subIDs []string
+}
+
+// parseMIB extracts the OID assignments from the text of a MIB module.
+// Only the value assignments are understood, all other syntax is skipped.
+func parseMIB(text string) []mibDef {
+	tokens := tokenizeMIB(text)
+	var defs []mibDef
+	pending := ""
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case i+1 < len(tokens) && isIdentifier(t) && definingMacros[tokens[i+1]]:
+			pending = t
+		case t == "::=" && pending != "" && i+1 < len(tokens) && tokens[i+1] == "{":
+			var parts []string
+			j := i + 2
+			for ; j < len(tokens) && tokens[j] != "}"; j++ {
+				parts = append(parts, tokens[j])
+			}
+			i = j
+			if d, ok := newMIBDef(pending, parts); ok {
+				defs = append(defs, d)
+			}
+			pending = ""
+		}
+	}
+	return defs
+}
+
+// newMIBDef creates a definition from the components of an OID value,
+// i.e. { ifEntry 10 } or { iso org(3) dod(6) 1 }.
+func newMIBDef(name string, parts []string) (mibDef, bool) {
+	if len(parts) < 2 {
+		return mibDef{}, false
+	}
+	d := mibDef{
+		name:   name,
+		parent: parts[0],
+	}
+	if i := strings.Index(d.parent, "("); i > 0 {
+		// The first component may itself be a numbered name like iso(1).
+		d.parent = d.parent[:i]
+	}
+	for _, p := range parts[1:] {
+		if i := strings.Index(p, "("); i >= 0 && strings.HasSuffix(p, ")") {
+			p = p[i+1 : len(p)-1]
+		}
+		if _, err := strconv.ParseUint(p, 10, 32); err != nil {
+			return mibDef{}, false
+		}
+		d.subIDs = append(d.subIDs, p)
+	}
+	return d, true
+}
+
+func isIdentifier(t string) bool {
+	if t == "" || !unicode.IsLower(rune(t[0])) {
+		return false
+	}
+	for _, r := range t {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenizeMIB splits MIB text into tokens, dropping comments and quoted strings.
+// Numbered names such as org(3) are kept as a single token.
+func tokenizeMIB(text string) []string {
+	var tokens []string
+	cur := strings.Builder{}
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '-' && i+1 < len(text) && text[i+1] == '-':
+			// Comments run to the end of the line.
+			flush()
+			end := strings.IndexByte(text[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end
+		case c == '"':
+			// Quoted strings may span lines and are never needed.
+			flush()
+			end := strings.IndexByte(text[i+1:], '"')
+			if end < 0 {
+				return tokens
+			}
+			i += end + 1
+		case c == '{' || c == '}':
+			flush()
+			tokens = append(tokens, string(c))
+		case c == '(' && cur.Len() > 0:
+			// Keep numbered names together, i.e. org(3).
+			end := strings.IndexByte(text[i:], ')')
+			if end < 0 {
+				flush()
+				continue
+			}
+			cur.WriteString(text[i : i+end+1])
+			i += end
+		case unicode.IsSpace(rune(c)) || c == ',' || c == '(' || c == ')':
+			flush()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	flush()
+	return tokens
+}
@@ -0,0 +1,131 @@
+package snmp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testMIB = `
+ACME-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    MODULE-IDENTITY, OBJECT-TYPE, Counter32, enterprises
+        FROM SNMPv2-SMI;
+
+acmeMIB MODULE-IDENTITY
+    LAST-UPDATED "201801010000Z"
+    ORGANIZATION "ACME"
+    CONTACT-INFO "support@acme.example -- not a comment"
+    DESCRIPTION
+        "The MIB module for ACME devices.
+         acmeFake OBJECT-TYPE ::= { acme 99 }"
+    ::= { enterprises 99999 }
+
+acmeObjects OBJECT IDENTIFIER ::= { acmeMIB 1 }
+
+-- acmeCommented OBJECT IDENTIFIER ::= { acmeMIB 2 }
+
+acmeTemperature OBJECT-TYPE
+    SYNTAX      Integer32
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Temperature in degrees."
+    ::= { acmeObjects 1 }
+
+acmeFanTable OBJECT-TYPE
+    SYNTAX      SEQUENCE OF AcmeFanEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "Fans."
+    ::= { acmeObjects 2 }
+
+AcmeFanEntry ::= SEQUENCE { acmeFanSpeed Counter32 }
+
+acmeFanEntry OBJECT-TYPE
+    SYNTAX      AcmeFanEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "A fan."
+    INDEX       { acmeFanIndex }
+    ::= { acmeFanTable 1 }
+
+acmeFanSpeed OBJECT-TYPE
+    SYNTAX      Counter32
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Fan speed."
+    ::= { acmeFanEntry 2 }
+
+acmeLegacy OBJECT IDENTIFIER ::= { iso org(3) dod(6) internet(1) private(4) 1 99999 2 }
+
+END
+`
+
+func TestMIB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snmp-mib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "ACME-MIB.txt"), []byte(testMIB), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewMIB([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name string
+		oid  string
+	}{
+		{name: "SNMPv2-MIB::sysUpTime.0", oid: "1.3.6.1.2.1.1.3.0"},
+		{name: "ifHCInOctets", oid: "1.3.6.1.2.1.31.1.1.1.6"},
+		{name: ".1.3.6.1.2.1.1.5.0", oid: "1.3.6.1.2.1.1.5.0"},
+		{name: "ACME-MIB::acmeTemperature.0", oid: "1.3.6.1.4.1.99999.1.1.0"},
+		{name: "acmeFanSpeed", oid: "1.3.6.1.4.1.99999.1.2.1.2"},
+		{name: "acmeLegacy", oid: "1.3.6.1.4.1.99999.2"},
+	}
+	for _, tc := range testCases {
+		oid, err := m.OID(tc.name)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if oid != tc.oid {
+			t.Errorf("%s: unexpected oid: got %s exp %s", tc.name, oid, tc.oid)
+		}
+	}
+
+	for _, name := range []string{"acmeFake", "acmeCommented", "acmeTemperature.x"} {
+		if _, err := m.OID(name); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if name, ok := m.Name("1.3.6.1.4.1.99999.1.2.1.2"); !ok || name != "acmeFanSpeed" {
+		t.Errorf("unexpected name: got %q exp %q", name, "acmeFanSpeed")
+	}
+}
+
+func TestMIB_MissingDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snmp-mib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "ACME-MIB.txt"), []byte(testMIB), 0600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+	m, err := NewMIB([]string{missing, dir})
+	if err == nil || !strings.HasPrefix(err.Error(), `failed to read MIB directory "`+missing+`"`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if oid, err := m.OID("acmeFanSpeed"); err != nil || oid != "1.3.6.1.4.1.99999.1.2.1.2" {
+		t.Errorf("unexpected oid of the readable directory: got %s, %v", oid, err)
+	}
+}
@@ -0,0 +1,368 @@
+package snmp
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/server/vars"
+	"github.com/k-sone/snmpgo"
+	"github.com/pkg/errors"
+)
+
+// statistics gathered by the SNMP poller.
+const (
+	statPolls             = "polls"
+	statPollFail          = "poll_fail"
+	statPointsTransmitted = "points_tx"
+	statTransmitFail      = "tx_fail"
+)
+
+type Diagnostic interface {
+	Error(msg string, err error, ctx ...keyvalue.T)
+	StartedPolling(agents []string)
+	ClosedService()
+}
+
+// Service periodically polls SNMP agents and writes the results as points.
+type Service struct {
+	config Config
+	mib    *MIB
+
+	// resolved OIDs of the configured fields and table columns,
+	// the fields and tables that failed to resolve are not polled
+	fields []resolvedField
+	tables []resolvedTable
+
+	wg      sync.WaitGroup
+	closing chan struct{}
+
+	PointsWriter interface {
+		WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+	}
+
+	diag    Diagnostic
+	statMap *expvar.Map
+	statKey string
+}
+
+type resolvedField struct {
+	FieldConfig
+	oid *snmpgo.Oid
+}
+
+type resolvedTable struct {
+	TableConfig
+	columns []resolvedField
+}
+
+func NewService(c Config, d Diagnostic) *Service {
+	return &Service{
+		config: c.WithDefaults(),
+		diag:   d,
+	}
+}
+
+func (s *Service) Open() error {
+	// A MIB that failed to load only disables the polls of the objects it defines.
+	mib, err := NewMIB(s.config.MIBDirs)
+	if err != nil {
+		s.diag.Error("failed to load MIB files", err)
+	}
+	s.mib = mib
+	s.fields, err = s.resolveFields(s.config.Fields)
+	if err != nil {
+		s.diag.Error("failed to resolve fields, not polling them", err, keyvalue.KV("measurement", s.config.Measurement))
+		s.fields = nil
+	}
+	s.tables = s.tables[:0]
+	for _, t := range s.config.Tables {
+		columns, err := s.resolveFields(t.Fields)
+		if err != nil {
+			s.diag.Error("failed to resolve table, not polling it", err, keyvalue.KV("measurement", t.Measurement))
+			continue
+		}
+		s.tables = append(s.tables, resolvedTable{TableConfig: t, columns: columns})
+	}
+	if len(s.fields) == 0 && len(s.tables) == 0 {
+		s.diag.Error("no fields or tables to poll", errors.New("all fields and tables failed to resolve"))
+		return nil
+	}
+
+	s.statKey, s.statMap = vars.NewStatistic("snmp", map[string]string{"name": s.config.Name})
+	s.closing = make(chan struct{})
+	s.wg.Add(1)
+	go s.run()
+	s.diag.StartedPolling(s.config.Agents)
+	return nil
+}
+
+func (s *Service) Close() error {
+	if s.closing == nil {
+		return nil
+	}
+	close(s.closing)
+	s.wg.Wait()
+	s.closing = nil
+	vars.DeleteStatistic(s.statKey)
+	s.diag.ClosedService()
+	return nil
+}
+
+// resolveFields resolves the object names of the fields into OIDs
+// and defaults the field names to the object names.
+func (s *Service) resolveFields(fields []FieldConfig) ([]resolvedField, error) {
+	resolved := make([]resolvedField, len(fields))
+	for i, f := range fields {
+		oid, err := s.mib.OID(f.OID)
+		if err != nil {
+			return nil, err
+		}
+		o, err := snmpgo.NewOid(oid)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid oid %q", f.OID)
+		}
+		if f.Name == "" {
+			f.Name = s.objectName(f.OID, oid)
+		}
+		resolved[i] = resolvedField{FieldConfig: f, oid: o}
+	}
+	return resolved, nil
+}
+
+// objectName returns the name of an object without module or instance suffix.
+func (s *Service) objectName(name, oid string) string {
+	if n, ok := s.mib.Name(oid); ok {
+		return n
+	}
+	if i := strings.Index(name, "::"); i >= 0 {
+		name = name[i+2:]
+	}
+	if isNumericOID(strings.TrimPrefix(name, ".")) {
+		return strings.TrimPrefix(name, ".")
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+func (s *Service) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(time.Duration(s.config.Interval))
+	defer ticker.Stop()
+	for {
+		s.pollAll()
+		select {
+		case <-s.closing:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) pollAll() {
+	var wg sync.WaitGroup
+	for _, agent := range s.config.Agents {
+		wg.Add(1)
+		go func(agent string) {
+			defer wg.Done()
+			s.statMap.Add(statPolls, 1)
+			points, err := s.poll(agent)
+			if err != nil {
+				s.statMap.Add(statPollFail, 1)
+				s.diag.Error("failed to poll agent", err, keyvalue.KV("agent", agent))
+			}
+			if len(points) == 0 {
+				return
+			}
+			if err := s.PointsWriter.WritePoints(
+				s.config.Database,
+				s.config.RetentionPolicy,
+				models.ConsistencyLevelAll,
+				points,
+			); err != nil {
+				s.statMap.Add(statTransmitFail, 1)
+				s.diag.Error("failed to write points to database", err, keyvalue.KV("database", s.config.Database))
+				return
+			}
+			s.statMap.Add(statPointsTransmitted, int64(len(points)))
+		}(agent)
+	}
+	wg.Wait()
+}
+
+func (s *Service) newClient(agent string) (*snmpgo.SNMP, error) {
+	args := snmpgo.SNMPArguments{
+		Address:     agentAddr(agent),
+		Timeout:     time.Duration(s.config.Timeout),
+		Retries:     uint(s.config.Retries),
+		Community:   s.config.Community,
+		UserName:    s.config.Username,
+		ContextName: s.config.ContextName,
+	}
+	switch s.config.Version {
+	case "1":
+		args.Version = snmpgo.V1
+	case "3":
+		args.Version = snmpgo.V3
+		switch s.config.SecurityLevel {
+		case "authNoPriv":
+			args.SecurityLevel = snmpgo.AuthNoPriv
+		case "authPriv":
+			args.SecurityLevel = snmpgo.AuthPriv
+		default:
+			args.SecurityLevel = snmpgo.NoAuthNoPriv
+		}
+		args.AuthProtocol = snmpgo.AuthProtocol(s.config.AuthProtocol)
+		args.AuthPassword = s.config.AuthPassword
+		args.PrivProtocol = snmpgo.PrivProtocol(s.config.PrivProtocol)
+		args.PrivPassword = s.config.PrivPassword
+	default:
+		args.Version = snmpgo.V2c
+	}
+	return snmpgo.NewSNMP(args)
+}
+
+// poll requests all fields and walks all tables of the agent.
+func (s *Service) poll(agent string) ([]models.Point, error) {
+	client, err := s.newClient(agent)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid SNMP configuration")
+	}
+	if err := client.Open(); err != nil {
+		return nil, errors.Wrap(err, "failed to connect to agent")
+	}
+	defer client.Close()
+
+	now := time.Now().UTC()
+	var points []models.Point
+	if len(s.fields) > 0 {
+		p, err := s.pollFields(client, agent, now)
+		if err != nil {
+			return points, err
+		}
+		if p != nil {
+			points = append(points, p)
+		}
+	}
+	for _, t := range s.tables {
+		rows, err := s.walkTable(client, agent, t, now)
+		if err != nil {
+			return points, errors.Wrapf(err, "failed to walk table %q", t.Measurement)
+		}
+		points = append(points, rows...)
+	}
+	return points, nil
+}
+
+func (s *Service) pollFields(client *snmpgo.SNMP, agent string, now time.Time) (models.Point, error) {
+	oids := make(snmpgo.Oids, len(s.fields))
+	for i, f := range s.fields {
+		oids[i] = f.oid
+	}
+	pdu, err := client.GetRequest(oids)
+	if err != nil {
+		return nil, err
+	}
+	if pdu.ErrorStatus() != snmpgo.NoError {
+		return nil, fmt.Errorf("agent returned error %s at index %d", pdu.ErrorStatus(), pdu.ErrorIndex())
+	}
+	tags := models.Tags{}
+	tags.SetString("agent", agent)
+	fields := make(models.Fields, len(s.fields))
+	vbs := pdu.VarBinds()
+	for _, f := range s.fields {
+		vb := vbs.MatchOid(f.oid)
+		if vb == nil {
+			continue
+		}
+		setValue(f.FieldConfig, vb.Variable, &tags, fields)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return models.NewPoint(s.config.Measurement, tags, fields, now)
+}
+
+func (s *Service) walkTable(client *snmpgo.SNMP, agent string, t resolvedTable, now time.Time) ([]models.Point, error) {
+	oids := make(snmpgo.Oids, len(t.columns))
+	for i, c := range t.columns {
+		oids[i] = c.oid
+	}
+	pdu, err := client.GetBulkWalk(oids, 0, s.config.MaxRepetitions)
+	if err != nil {
+		return nil, err
+	}
+	if pdu.ErrorStatus() != snmpgo.NoError {
+		return nil, fmt.Errorf("agent returned error %s at index %d", pdu.ErrorStatus(), pdu.ErrorIndex())
+	}
+
+	type row struct {
+		tags   models.Tags
+		fields models.Fields
+	}
+	rows := make(map[string]*row)
+	var indexes []string
+	vbs := pdu.VarBinds()
+	for _, c := range t.columns {
+		prefix := c.oid.String() + "."
+		for _, vb := range vbs.MatchBaseOids(c.oid) {
+			index := strings.TrimPrefix(vb.Oid.String(), prefix)
+			r, ok := rows[index]
+			if !ok {
+				r = &row{fields: make(models.Fields)}
+				r.tags.SetString("agent", agent)
+				r.tags.SetString(t.IndexTag, index)
+				rows[index] = r
+				indexes = append(indexes, index)
+			}
+			setValue(c.FieldConfig, vb.Variable, &r.tags, r.fields)
+		}
+	}
+
+	points := make([]models.Point, 0, len(rows))
+	for _, index := range indexes {
+		r := rows[index]
+		if len(r.fields) == 0 {
+			continue
+		}
+		p, err := models.NewPoint(t.Measurement, r.tags, r.fields, now)
+		if err != nil {
+			return points, err
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// setValue converts the SNMP variable and stores it as either a tag or a field.
+func setValue(f FieldConfig, v snmpgo.Variable, tags *models.Tags, fields models.Fields) {
+	var value interface{}
+	switch v := v.(type) {
+	case *snmpgo.NoSucheObject, *snmpgo.NoSucheInstance, *snmpgo.EndOfMibView, *snmpgo.Null:
+		return
+	case *snmpgo.Integer, *snmpgo.Counter32, *snmpgo.Gauge32, *snmpgo.TimeTicks, *snmpgo.Counter64:
+		i, err := v.BigInt()
+		if err != nil {
+			return
+		}
+		if i.IsInt64() {
+			value = i.Int64()
+		} else {
+			value, _ = new(big.Float).SetInt(i).Float64()
+		}
+	default:
+		value = v.String()
+	}
+	if f.IsTag {
+		tags.SetString(f.Name, fmt.Sprint(value))
+		return
+	}
+	fields[f.Name] = value
+}
@@ -0,0 +1,282 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/services/diagnostic"
+	"github.com/k-sone/snmpgo"
+)
+
+// testAgent is an SNMP v2c agent serving fixed objects.
+type testAgent struct {
+	conn    net.PacketConn
+	oids    snmpgo.Oids
+	objects map[string]snmpgo.Variable
+
+	mu       sync.Mutex
+	requests []snmpgo.PduType
+}
+
+// message is an SNMP v1 or v2c message.
+type message struct {
+	Version   int
+	Community []byte
+	Pdu       asn1.RawValue
+}
+
+func newTestAgent(t *testing.T, objects map[string]snmpgo.Variable) *testAgent {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &testAgent{
+		conn:    conn,
+		objects: objects,
+	}
+	for oid := range objects {
+		o, err := snmpgo.NewOid(oid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.oids = append(a.oids, o)
+	}
+	sort.Slice(a.oids, func(i, j int) bool { return a.oids[i].Compare(a.oids[j]) < 0 })
+	go a.serve()
+	return a
+}
+
+func (a *testAgent) Addr() string {
+	return a.conn.LocalAddr().String()
+}
+
+func (a *testAgent) Close() {
+	a.conn.Close()
+}
+
+// Requests returns the types of the requests received.
+func (a *testAgent) Requests() []snmpgo.PduType {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]snmpgo.PduType(nil), a.requests...)
+}
+
+func (a *testAgent) serve() {
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var msg message
+		if _, err := asn1.Unmarshal(buf[:n], &msg); err != nil {
+			continue
+		}
+		req := snmpgo.NewPdu(snmpgo.V2c, snmpgo.GetRequest)
+		if _, err := req.Unmarshal(msg.Pdu.FullBytes); err != nil {
+			continue
+		}
+		a.mu.Lock()
+		a.requests = append(a.requests, req.PduType())
+		a.mu.Unlock()
+
+		resp := snmpgo.NewPdu(snmpgo.V2c, snmpgo.GetResponse)
+		resp.SetRequestId(req.RequestId())
+		switch req.PduType() {
+		case snmpgo.GetRequest:
+			for _, vb := range req.VarBinds() {
+				if v, ok := a.objects[vb.Oid.String()]; ok {
+					resp.AppendVarBind(vb.Oid, v)
+				} else {
+					resp.AppendVarBind(vb.Oid, snmpgo.NewNoSucheObject())
+				}
+			}
+		case snmpgo.GetBulkRequest:
+			// The error status and index of a bulk request are its non repeaters and max repetitions.
+			oids := make(snmpgo.Oids, len(req.VarBinds()))
+			for i, vb := range req.VarBinds() {
+				oids[i] = vb.Oid
+			}
+			for r := 0; r < req.ErrorIndex(); r++ {
+				for i, oid := range oids {
+					next := a.next(oid)
+					if next == nil {
+						resp.AppendVarBind(oid, snmpgo.NewEndOfMibView())
+						continue
+					}
+					resp.AppendVarBind(next, a.objects[next.String()])
+					oids[i] = next
+				}
+			}
+		}
+		pdu, err := resp.Marshal()
+		if err != nil {
+			continue
+		}
+		msg.Pdu = asn1.RawValue{FullBytes: pdu}
+		data, err := asn1.Marshal(msg)
+		if err != nil {
+			continue
+		}
+		a.conn.WriteTo(data, addr)
+	}
+}
+
+// next returns the first OID after the OID.
+func (a *testAgent) next(oid *snmpgo.Oid) *snmpgo.Oid {
+	for _, o := range a.oids {
+		if o.Compare(oid) > 0 {
+			return o
+		}
+	}
+	return nil
+}
+
+type pointsWriter struct {
+	mu     sync.Mutex
+	points []string
+	// written is signaled after each write.
+	written chan struct{}
+}
+
+func newPointsWriter() *pointsWriter {
+	return &pointsWriter{written: make(chan struct{}, 1)}
+}
+
+// WritePoints records the points without their timestamps.
+func (w *pointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	w.mu.Lock()
+	for _, p := range points {
+		line := p.String()
+		w.points = append(w.points, database+" "+line[:strings.LastIndex(line, " ")])
+	}
+	w.mu.Unlock()
+	select {
+	case w.written <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (w *pointsWriter) Points(t *testing.T) []string {
+	select {
+	case <-w.written:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for points")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.points
+}
+
+var testObjects = map[string]snmpgo.Variable{
+	"1.3.6.1.2.1.1.3.0":         snmpgo.NewTimeTicks(4200),
+	"1.3.6.1.2.1.1.5.0":         snmpgo.NewOctetString([]byte("router1")),
+	"1.3.6.1.2.1.2.2.1.2.1":     snmpgo.NewOctetString([]byte("eth0")),
+	"1.3.6.1.2.1.2.2.1.2.2":     snmpgo.NewOctetString([]byte("eth1")),
+	"1.3.6.1.2.1.2.2.1.10.1":    snmpgo.NewCounter32(100),
+	"1.3.6.1.2.1.2.2.1.10.2":    snmpgo.NewCounter32(200),
+	"1.3.6.1.2.1.31.1.1.1.6.1":  snmpgo.NewCounter64(1 << 40),
+	"1.3.6.1.4.1.99999.1.2.1.2": snmpgo.NewCounter32(3000),
+}
+
+func newTestService(agent string) (*Service, *pointsWriter) {
+	c := NewConfig()
+	c.Enabled = true
+	c.Name = "test"
+	c.Agents = []string{agent}
+	c.Database = "snmp"
+	c.Retries = 0
+	c.Timeout = toml.Duration(time.Second)
+	c.Interval = toml.Duration(time.Hour)
+	c.MaxRepetitions = 2
+	c.Fields = []FieldConfig{
+		{OID: "SNMPv2-MIB::sysUpTime.0", Name: "uptime"},
+		{OID: "sysName.0", IsTag: true},
+	}
+	c.Tables = []TableConfig{{
+		Measurement: "interfaces",
+		Fields: []FieldConfig{
+			{OID: "IF-MIB::ifDescr", Name: "name", IsTag: true},
+			{OID: "IF-MIB::ifInOctets"},
+		},
+	}}
+	ds := diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	ds.Open()
+	s := NewService(c, ds.NewSNMPHandler())
+	w := newPointsWriter()
+	s.PointsWriter = w
+	return s, w
+}
+
+func TestService_Poll(t *testing.T) {
+	a := newTestAgent(t, testObjects)
+	defer a.Close()
+	s, w := newTestService(a.Addr())
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	exp := []string{
+		"snmp snmp,agent=" + a.Addr() + ",sysName=router1 uptime=4200i",
+		"snmp interfaces,agent=" + a.Addr() + ",index=1,name=eth0 ifInOctets=100i",
+		"snmp interfaces,agent=" + a.Addr() + ",index=2,name=eth1 ifInOctets=200i",
+	}
+	if got := w.Points(t); !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected points:\ngot %v\nexp %v", got, exp)
+	}
+}
+
+func TestService_PollBadMIBDir(t *testing.T) {
+	a := newTestAgent(t, testObjects)
+	defer a.Close()
+	s, w := newTestService(a.Addr())
+	s.config.MIBDirs = []string{"/does/not/exist"}
+	s.config.Tables = append(s.config.Tables, TableConfig{
+		Measurement: "fans",
+		IndexTag:    "fan",
+		Fields:      []FieldConfig{{OID: "ACME-MIB::acmeFanSpeed"}},
+	})
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Only the table of the objects of the missing MIB is not polled.
+	exp := []string{
+		"snmp snmp,agent=" + a.Addr() + ",sysName=router1 uptime=4200i",
+		"snmp interfaces,agent=" + a.Addr() + ",index=1,name=eth0 ifInOctets=100i",
+		"snmp interfaces,agent=" + a.Addr() + ",index=2,name=eth1 ifInOctets=200i",
+	}
+	if got := w.Points(t); !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected points:\ngot %v\nexp %v", got, exp)
+	}
+}
+
+func TestService_PollNothingResolved(t *testing.T) {
+	a := newTestAgent(t, testObjects)
+	defer a.Close()
+	s, _ := newTestService(a.Addr())
+	s.config.MIBDirs = []string{"/does/not/exist"}
+	s.config.Fields = []FieldConfig{{OID: "ACME-MIB::acmeTemperature.0"}}
+	s.config.Tables = nil
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	time.Sleep(100 * time.Millisecond)
+	if got := a.Requests(); len(got) != 0 {
+		t.Errorf("unexpected requests to the agent: %v", got)
+	}
+}
@@ -216,10 +216,13 @@ type handler struct {
 }
 
 // Handler creates a handler from the config.
+// Cached templates, shared by the handlers
+var dataTemplates = alert.NewTextTemplates("data", nil)
+
 func (s *Service) Handler(c HandlerConfig, ctx ...keyvalue.T) (alert.Handler, error) {
 	// Compile data value templates
 	for i, d := range c.DataList {
-		tmpl, err := text.New("data").Parse(d.Value)
+		tmpl, err := dataTemplates.Parse(d.Value)
 		if err != nil {
 			return nil, err
 		}
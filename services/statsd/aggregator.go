@@ -0,0 +1,193 @@
+package statsd
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// aggregator accumulates metrics between flushes.
+// It is not safe for concurrent use.
+type aggregator struct {
+	percentiles    []float64
+	deleteGauges   bool
+	deleteCounters bool
+
+	counters map[string]*counter
+	gauges   map[string]*gauge
+	timers   map[string]*timer
+	sets     map[string]*set
+}
+
+type series struct {
+	name string
+	tags map[string]string
+	// updated is true if the series received metrics since the last flush.
+	updated bool
+}
+
+type counter struct {
+	series
+	value float64
+}
+
+type gauge struct {
+	series
+	value float64
+}
+
+type timer struct {
+	series
+	values []float64
+	// count of the samples, accounting for the sample rate
+	count float64
+}
+
+type set struct {
+	series
+	values map[string]bool
+}
+
+func newAggregator(c Config) *aggregator {
+	return &aggregator{
+		percentiles:    c.Percentiles,
+		deleteGauges:   c.DeleteGauges,
+		deleteCounters: c.DeleteCounters,
+		counters:       make(map[string]*counter),
+		gauges:         make(map[string]*gauge),
+		timers:         make(map[string]*timer),
+		sets:           make(map[string]*set),
+	}
+}
+
+func (a *aggregator) add(m metric) {
+	key := m.key()
+	s := series{name: m.name, tags: m.tags, updated: true}
+	switch m.mtype {
+	case typeCounter:
+		c, ok := a.counters[key]
+		if !ok {
+			c = &counter{series: s}
+			a.counters[key] = c
+		}
+		c.updated = true
+		c.value += m.value / m.sampleRate
+	case typeGauge:
+		g, ok := a.gauges[key]
+		if !ok {
+			g = &gauge{series: s}
+			a.gauges[key] = g
+		}
+		g.updated = true
+		if m.relative {
+			g.value += m.value
+		} else {
+			g.value = m.value
+		}
+	case typeTimer, typeHistogram, typeDistribution:
+		t, ok := a.timers[key]
+		if !ok {
+			t = &timer{series: s}
+			a.timers[key] = t
+		}
+		t.updated = true
+		t.values = append(t.values, m.value)
+		t.count += 1 / m.sampleRate
+	case typeSet:
+		st, ok := a.sets[key]
+		if !ok {
+			st = &set{series: s, values: make(map[string]bool)}
+			a.sets[key] = st
+		}
+		st.updated = true
+		st.values[m.setValue] = true
+	}
+}
+
+// flush returns the aggregated points and resets the aggregator for the next interval.
+func (a *aggregator) flush(now time.Time) []models.Point {
+	var points []models.Point
+	appendPoint := func(s series, mtype string, fields models.Fields) {
+		tags := models.NewTags(s.tags)
+		tags.SetString("metric_type", mtype)
+		p, err := models.NewPoint(s.name, tags, fields, now)
+		if err != nil {
+			// Invalid names or tags cannot be written, drop them.
+			return
+		}
+		points = append(points, p)
+	}
+
+	for key, c := range a.counters {
+		if !c.updated && a.deleteCounters {
+			delete(a.counters, key)
+			continue
+		}
+		appendPoint(c.series, "counter", models.Fields{"value": c.value})
+		c.value = 0
+		c.updated = false
+	}
+	for key, g := range a.gauges {
+		if !g.updated && a.deleteGauges {
+			delete(a.gauges, key)
+			continue
+		}
+		appendPoint(g.series, "gauge", models.Fields{"value": g.value})
+		g.updated = false
+	}
+	for _, t := range a.timers {
+		appendPoint(t.series, "timing", t.fields(a.percentiles))
+	}
+	a.timers = make(map[string]*timer)
+	for _, s := range a.sets {
+		appendPoint(s.series, "set", models.Fields{"value": int64(len(s.values))})
+	}
+	a.sets = make(map[string]*set)
+	return points
+}
+
+func (t *timer) fields(percentiles []float64) models.Fields {
+	values := t.values
+	sort.Float64s(values)
+	n := float64(len(values))
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / n
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	fields := models.Fields{
+		"count":  t.count,
+		"sum":    sum,
+		"mean":   mean,
+		"lower":  values[0],
+		"upper":  values[len(values)-1],
+		"stddev": math.Sqrt(variance / n),
+		"median": percentile(values, 50),
+	}
+	for _, p := range percentiles {
+		fields[percentileName(p)] = percentile(values, p)
+	}
+	return fields
+}
+
+// percentile returns the nearest rank percentile of the sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// percentileName returns the field name of a percentile, i.e. p90 or p99_9.
+func percentileName(p float64) string {
+	return "p" + strings.Replace(strconv.FormatFloat(p, 'f', -1, 64), ".", "_", -1)
+}
@@ -0,0 +1,112 @@
+package statsd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultBindAddress is the default address to listen on.
+	DefaultBindAddress = ":8125"
+
+	// DefaultProtocol is the default network protocol.
+	DefaultProtocol = "udp"
+
+	// DefaultFlushInterval is how often aggregated metrics are written as points.
+	DefaultFlushInterval = 10 * time.Second
+
+	// DefaultBuffer is the number of packets to buffer when reading off the socket.
+	DefaultBuffer = 1000
+
+	// DefaultMaxTCPConnections is the maximum number of concurrent TCP connections.
+	DefaultMaxTCPConnections = 250
+)
+
+// DefaultPercentiles are the percentiles computed for timers.
+var DefaultPercentiles = []float64{90}
+
+type Config struct {
+	Enabled     bool   `toml:"enabled"`
+	BindAddress string `toml:"bind-address"`
+	// Protocol is either udp or tcp.
+	Protocol   string `toml:"protocol"`
+	ReadBuffer int    `toml:"read-buffer"`
+	Buffer     int    `toml:"buffer"`
+	// MaxTCPConnections limits the number of concurrent TCP connections.
+	MaxTCPConnections int `toml:"max-tcp-connections"`
+
+	// FlushInterval is how often aggregated metrics are written as points.
+	FlushInterval toml.Duration `toml:"flush-interval"`
+	// Percentiles computed for timers and histograms.
+	Percentiles []float64 `toml:"percentiles"`
+	// DeleteGauges drops gauges that have not been updated since the last flush.
+	// Otherwise the last value is written on every flush.
+	DeleteGauges bool `toml:"delete-gauges"`
+	// DeleteCounters drops counters that have not been updated since the last flush.
+	// Otherwise a zero value is written on every flush.
+	DeleteCounters bool `toml:"delete-counters"`
+	// DatadogExtensions enables parsing of dogstatsd tags, i.e. metric:1|c|#host:a,env:prod.
+	DatadogExtensions bool `toml:"datadog-extensions"`
+
+	Database        string `toml:"database"`
+	RetentionPolicy string `toml:"retention-policy"`
+}
+
+func NewConfig() Config {
+	return Config{
+		BindAddress:       DefaultBindAddress,
+		Protocol:          DefaultProtocol,
+		Buffer:            DefaultBuffer,
+		MaxTCPConnections: DefaultMaxTCPConnections,
+		FlushInterval:     toml.Duration(DefaultFlushInterval),
+		Percentiles:       DefaultPercentiles,
+		DeleteGauges:      true,
+		DeleteCounters:    true,
+		DatadogExtensions: true,
+	}
+}
+
+// WithDefaults takes the given config and returns a new config with any required
+// default values set.
+func (c Config) WithDefaults() Config {
+	d := c
+	if d.BindAddress == "" {
+		d.BindAddress = DefaultBindAddress
+	}
+	if d.Protocol == "" {
+		d.Protocol = DefaultProtocol
+	}
+	if d.Buffer <= 0 {
+		d.Buffer = DefaultBuffer
+	}
+	if d.MaxTCPConnections <= 0 {
+		d.MaxTCPConnections = DefaultMaxTCPConnections
+	}
+	if d.FlushInterval <= 0 {
+		d.FlushInterval = toml.Duration(DefaultFlushInterval)
+	}
+	return d
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	switch c.Protocol {
+	case "", "udp", "tcp":
+	default:
+		return fmt.Errorf("invalid protocol %q, must be one of udp or tcp", c.Protocol)
+	}
+	if c.Database == "" {
+		return errors.New("must specify a database")
+	}
+	for _, p := range c.Percentiles {
+		if p <= 0 || p >= 100 {
+			return fmt.Errorf("invalid percentile %v, must be between 0 and 100", p)
+		}
+	}
+	return nil
+}
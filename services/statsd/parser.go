@@ -0,0 +1,127 @@
+package statsd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// metric types as sent on the wire.
+const (
+	typeCounter      = "c"
+	typeGauge        = "g"
+	typeTimer        = "ms"
+	typeHistogram    = "h"
+	typeDistribution = "d"
+	typeSet          = "s"
+)
+
+// metric is a single parsed statsd sample.
+type metric struct {
+	name  string
+	tags  map[string]string
+	mtype string
+	// value of counters, gauges and timers
+	value float64
+	// setValue is the member of a set
+	setValue string
+	// relative is true for gauges updated with a sign, i.e. +3 or -3
+	relative bool
+	// sampleRate of counters and timers
+	sampleRate float64
+}
+
+// key identifies the series the metric is aggregated into.
+func (m metric) key() string {
+	if len(m.tags) == 0 {
+		return m.name
+	}
+	keys := make([]string, 0, len(m.tags))
+	for k := range m.tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b := strings.Builder{}
+	b.WriteString(m.name)
+	for _, k := range keys {
+		b.WriteByte(',')
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(m.tags[k])
+	}
+	return b.String()
+}
+
+// isDatadogOnly reports whether the line is a dogstatsd event or service check,
+// those are not metrics and are ignored.
+func isDatadogOnly(line string) bool {
+	return strings.HasPrefix(line, "_e{") || strings.HasPrefix(line, "_sc|")
+}
+
+// parseLine parses a statsd line of the form
+// <name>:<value>|<type>[|@<sample rate>][|#<tag>:<value>,...].
+// Dogstatsd tags are only parsed when datadog is true.
+func parseLine(line string, datadog bool) (metric, error) {
+	m := metric{sampleRate: 1}
+	i := strings.LastIndex(line, ":")
+	// Dogstatsd tags may contain colons, so find the value separator before the first pipe.
+	if p := strings.Index(line, "|"); p > 0 {
+		i = strings.LastIndex(line[:p], ":")
+	}
+	if i <= 0 {
+		return m, fmt.Errorf("invalid line %q: missing value", line)
+	}
+	m.name = line[:i]
+	parts := strings.Split(line[i+1:], "|")
+	if len(parts) < 2 {
+		return m, fmt.Errorf("invalid line %q: missing type", line)
+	}
+	m.mtype = parts[1]
+	for _, p := range parts[2:] {
+		switch {
+		case strings.HasPrefix(p, "@"):
+			rate, err := strconv.ParseFloat(p[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return m, fmt.Errorf("invalid line %q: invalid sample rate %q", line, p[1:])
+			}
+			m.sampleRate = rate
+		case strings.HasPrefix(p, "#") && datadog:
+			m.tags = parseTags(p[1:])
+		}
+	}
+
+	value := parts[0]
+	switch m.mtype {
+	case typeSet:
+		m.setValue = value
+		return m, nil
+	case typeGauge:
+		m.relative = strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-")
+	case typeCounter, typeTimer, typeHistogram, typeDistribution:
+	default:
+		return m, fmt.Errorf("invalid line %q: unknown metric type %q", line, m.mtype)
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return m, fmt.Errorf("invalid line %q: invalid value %q", line, value)
+	}
+	m.value = v
+	return m, nil
+}
+
+// parseTags parses dogstatsd tags, tags without a value are given the value true.
+func parseTags(s string) map[string]string {
+	tags := make(map[string]string)
+	for _, t := range strings.Split(s, ",") {
+		if t == "" {
+			continue
+		}
+		if i := strings.Index(t, ":"); i > 0 {
+			tags[t[:i]] = t[i+1:]
+		} else {
+			tags[t] = "true"
+		}
+	}
+	return tags
+}
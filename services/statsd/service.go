@@ -0,0 +1,299 @@
+package statsd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/server/vars"
+)
+
+const (
+	UDPPacketSize = 65536
+)
+
+// statistics gathered by the statsd package.
+const (
+	statMetricsReceived   = "metrics_rx"
+	statBytesReceived     = "bytes_rx"
+	statParseFail         = "parse_fail"
+	statReadFail          = "read_fail"
+	statTCPConnections    = "tcp_connections"
+	statTCPRejected       = "tcp_rejected"
+	statPointsTransmitted = "points_tx"
+	statTransmitFail      = "tx_fail"
+)
+
+type Diagnostic interface {
+	Error(msg string, err error, ctx ...keyvalue.T)
+	StartedListening(protocol, addr string)
+	ClosedService()
+}
+
+// Service listens for statsd metrics, aggregates them
+// and writes the aggregates as points on every flush interval.
+type Service struct {
+	config Config
+
+	udpConn  *net.UDPConn
+	listener net.Listener
+	addr     net.Addr
+
+	wg      sync.WaitGroup
+	done    chan struct{}
+	packets chan []byte
+
+	connMu sync.Mutex
+	conns  map[net.Conn]bool
+
+	agg *aggregator
+
+	PointsWriter interface {
+		WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+	}
+
+	Diag    Diagnostic
+	statMap *expvar.Map
+	statKey string
+}
+
+func NewService(c Config, diag Diagnostic) *Service {
+	d := c.WithDefaults()
+	return &Service{
+		config: d,
+		agg:    newAggregator(d),
+		conns:  make(map[net.Conn]bool),
+		Diag:   diag,
+	}
+}
+
+func (s *Service) Open() (err error) {
+	if s.config.Database == "" {
+		return errors.New("database has to be specified in config")
+	}
+
+	switch s.config.Protocol {
+	case "tcp":
+		s.listener, err = net.Listen("tcp", s.config.BindAddress)
+		if err != nil {
+			s.Diag.Error("failed to set up TCP listener at address", err, keyvalue.KV("address", s.config.BindAddress))
+			return err
+		}
+		s.addr = s.listener.Addr()
+	default:
+		addr, err := net.ResolveUDPAddr("udp", s.config.BindAddress)
+		if err != nil {
+			s.Diag.Error("failed to resolve UDP address", err, keyvalue.KV("bind_address", s.config.BindAddress))
+			return err
+		}
+		s.udpConn, err = net.ListenUDP("udp", addr)
+		if err != nil {
+			s.Diag.Error("failed to set up UDP listener at address", err, keyvalue.KV("address", addr.String()))
+			return err
+		}
+		if s.config.ReadBuffer != 0 {
+			if err := s.udpConn.SetReadBuffer(s.config.ReadBuffer); err != nil {
+				s.Diag.Error("failed to set UDP read buffer", err, keyvalue.KV("read_buffer", fmt.Sprintf("%v", s.config.ReadBuffer)))
+				s.udpConn.Close()
+				return err
+			}
+		}
+		s.addr = s.udpConn.LocalAddr()
+	}
+
+	tags := map[string]string{"bind": s.addr.String(), "protocol": s.config.Protocol}
+	s.statKey, s.statMap = vars.NewStatistic("statsd", tags)
+
+	s.Diag.StartedListening(s.config.Protocol, s.addr.String())
+
+	s.done = make(chan struct{})
+	s.packets = make(chan []byte, s.config.Buffer)
+	s.wg.Add(2)
+	if s.udpConn != nil {
+		go s.serveUDP()
+	} else {
+		go s.serveTCP()
+	}
+	go s.process()
+	return nil
+}
+
+func (s *Service) Close() error {
+	if s.done == nil {
+		return errors.New("Service already closed")
+	}
+	close(s.done)
+	if s.udpConn != nil {
+		s.udpConn.Close()
+	}
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	s.connMu.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.connMu.Unlock()
+	s.wg.Wait()
+
+	vars.DeleteStatistic(s.statKey)
+
+	// Release all remaining resources.
+	s.done = nil
+	s.udpConn = nil
+	s.listener = nil
+	s.packets = nil
+
+	s.Diag.ClosedService()
+	return nil
+}
+
+// Addr returns the address the service is listening on.
+func (s *Service) Addr() net.Addr {
+	return s.addr
+}
+
+func (s *Service) serveUDP() {
+	defer s.wg.Done()
+	buf := make([]byte, UDPPacketSize)
+	for {
+		n, _, err := s.udpConn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			s.statMap.Add(statReadFail, 1)
+			s.Diag.Error("failed to read UDP message", err)
+			continue
+		}
+		s.statMap.Add(statBytesReceived, int64(n))
+		p := make([]byte, n)
+		copy(p, buf[:n])
+		if !s.enqueue(p) {
+			return
+		}
+	}
+}
+
+func (s *Service) serveTCP() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			s.statMap.Add(statReadFail, 1)
+			s.Diag.Error("failed to accept TCP connection", err)
+			continue
+		}
+		s.connMu.Lock()
+		if len(s.conns) >= s.config.MaxTCPConnections {
+			s.connMu.Unlock()
+			s.statMap.Add(statTCPRejected, 1)
+			conn.Close()
+			continue
+		}
+		s.conns[conn] = true
+		s.connMu.Unlock()
+		s.statMap.Add(statTCPConnections, 1)
+
+		s.wg.Add(1)
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Service) handleConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.connMu.Lock()
+		delete(s.conns, conn)
+		s.connMu.Unlock()
+		s.statMap.Add(statTCPConnections, -1)
+		conn.Close()
+	}()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		s.statMap.Add(statBytesReceived, int64(len(line)+1))
+		p := make([]byte, len(line))
+		copy(p, line)
+		if !s.enqueue(p) {
+			return
+		}
+	}
+}
+
+// enqueue queues the packet for processing, it returns false if the service is closing.
+func (s *Service) enqueue(p []byte) bool {
+	select {
+	case s.packets <- p:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+// process parses and aggregates packets and writes the aggregates on every flush interval.
+func (s *Service) process() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(time.Duration(s.config.FlushInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case p := <-s.packets:
+			s.parsePacket(p)
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
+
+func (s *Service) parsePacket(p []byte) {
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		l := strings.TrimSpace(string(line))
+		if l == "" || isDatadogOnly(l) {
+			continue
+		}
+		m, err := parseLine(l, s.config.DatadogExtensions)
+		if err != nil {
+			s.statMap.Add(statParseFail, 1)
+			s.Diag.Error("failed to parse metric", err)
+			continue
+		}
+		s.statMap.Add(statMetricsReceived, 1)
+		s.agg.add(m)
+	}
+}
+
+func (s *Service) flush() {
+	points := s.agg.flush(time.Now().UTC())
+	if len(points) == 0 {
+		return
+	}
+	if err := s.PointsWriter.WritePoints(
+		s.config.Database,
+		s.config.RetentionPolicy,
+		models.ConsistencyLevelAll,
+		points,
+	); err != nil {
+		s.Diag.Error("failed to write points to database", err, keyvalue.KV("database", s.config.Database))
+		s.statMap.Add(statTransmitFail, 1)
+		return
+	}
+	s.statMap.Add(statPointsTransmitted, int64(len(points)))
+}
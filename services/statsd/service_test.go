@@ -0,0 +1,94 @@
+package statsd_test
+
+import (
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/services/statsd"
+)
+
+func TestService(t *testing.T) {
+	for _, protocol := range []string{"udp", "tcp"} {
+		t.Run(protocol, func(t *testing.T) {
+			c := statsd.NewConfig()
+			c.Enabled = true
+			c.BindAddress = "127.0.0.1:0"
+			c.Protocol = protocol
+			c.Database = "db"
+			c.FlushInterval = toml.Duration(100 * time.Millisecond)
+
+			pw := &pointsWriter{points: make(chan []models.Point, 10)}
+			s := statsd.NewService(c, diag{})
+			s.PointsWriter = pw
+			if err := s.Open(); err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			conn, err := net.Dial(protocol, s.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			msg := "requests:1|c|#host:a\n" +
+				"requests:2|c|@0.5|#host:a\n" +
+				"temp:20|g\n" +
+				"temp:+5|g\n" +
+				"latency:10|ms\n" +
+				"latency:30|ms\n" +
+				"users:alice|s\n" +
+				"users:bob|s\n" +
+				"users:alice|s\n" +
+				"_e{5,4}:title|text\n"
+			if _, err := conn.Write([]byte(msg)); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			deadline := time.After(5 * time.Second)
+			for len(got) < 4 {
+				select {
+				case points := <-pw.points:
+					for _, p := range points {
+						p.SetTime(time.Unix(0, 0))
+						got = append(got, p.String())
+					}
+				case <-deadline:
+					t.Fatalf("timed out waiting for points, got %v", got)
+				}
+			}
+			sort.Strings(got)
+			exp := []string{
+				"latency,metric_type=timing count=2,lower=10,mean=20,median=10,p90=30,stddev=10,sum=40,upper=30 0",
+				"requests,host=a,metric_type=counter value=5 0",
+				"temp,metric_type=gauge value=25 0",
+				"users,metric_type=set value=2i 0",
+			}
+			for i := range exp {
+				if got[i] != exp[i] {
+					t.Errorf("unexpected point %d:\ngot %s\nexp %s", i, got[i], exp[i])
+				}
+			}
+		})
+	}
+}
+
+type pointsWriter struct {
+	points chan []models.Point
+}
+
+func (pw *pointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	pw.points <- points
+	return nil
+}
+
+type diag struct{}
+
+func (diag) Error(msg string, err error, ctx ...keyvalue.T) {}
+func (diag) StartedListening(protocol, addr string)         {}
+func (diag) ClosedService()                                 {}
@@ -17,6 +17,7 @@ const (
 	storagePath            = "/storage"
 	storagePathAnchored    = storagePath + "/"
 	backupPath             = storagePath + "/backup"
+	rekeyPath              = storagePath + "/rekey"
 	storesPath             = storagePath + "/stores"
 	storesPathAnchored     = storesPath + "/"
 	storesBasePath         = httpd.BasePath + storesPath
@@ -29,6 +30,10 @@ type APIServer struct {
 	routes    []httpd.Route
 	diag      Diagnostic
 
+	Rekeyer interface {
+		Rekey() (id uint32, count int, err error)
+	}
+
 	HTTPDService interface {
 		AddRoutes([]httpd.Route) error
 		DelRoutes([]httpd.Route)
@@ -55,6 +60,11 @@ func (s *APIServer) Open() error {
 			Pattern:     storagePathAnchored,
 			HandlerFunc: s.handleStoreAction,
 		},
+		{
+			Method:      "POST",
+			Pattern:     rekeyPath,
+			HandlerFunc: s.handleRekey,
+		},
 	}
 	err := s.HTTPDService.AddRoutes(s.routes)
 	if err != nil {
@@ -69,6 +79,10 @@ func (s *APIServer) Close() error {
 }
 
 func (s *APIServer) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if s.DB == nil {
+		httpd.HttpError(w, "backup is only supported by the bolt storage backend, back up the external database instead", true, http.StatusNotImplemented)
+		return
+	}
 	err := s.DB.View(func(tx *bolt.Tx) error {
 		w.Header().Set("Content-Type", "application/octet-stream")
 		w.Header().Set("Content-Disposition", `attachment; filename="kapacitor.db"`)
@@ -130,3 +144,19 @@ func (s *APIServer) handleStoreAction(w http.ResponseWriter, r *http.Request) {
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (s *APIServer) handleRekey(w http.ResponseWriter, r *http.Request) {
+	id, count, err := s.Rekeyer.Rekey()
+	if err == ErrEncryptionDisabled {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to rotate data key: %v", err), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(client.StorageRekeyResult{
+		KeyID:  id,
+		Values: count,
+	}, true))
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"github.com/boltdb/bolt"
+)
+
+// Backend stores the namespaces of the storage service,
+// either in a local BoltDB file or in an external database shared by stateless servers.
+type Backend interface {
+	// Store returns the store of the namespace.
+	Store(namespace string) Interface
+	// Update runs f in a single read-write transaction spanning all namespaces.
+	// If f returns a nil error the transaction is committed, otherwise the error is returned.
+	Update(f func(tx BackendTx) error) error
+	Close() error
+}
+
+// BackendTx is a read-write transaction spanning all namespaces of a backend.
+type BackendTx interface {
+	// Namespace returns the operations on the namespace within the transaction.
+	Namespace(name string) ReadWriteOperator
+}
+
+// ReadWriteOperator provides an interface for performing read and write operations.
+type ReadWriteOperator interface {
+	ReadOperator
+	WriteOperator
+}
+
+// boltBackend stores each namespace in a bucket of a BoltDB file.
+type boltBackend struct {
+	db *bolt.DB
+}
+
+func (b *boltBackend) Store(namespace string) Interface {
+	return NewBolt(b.db, namespace)
+}
+
+func (b *boltBackend) Update(f func(tx BackendTx) error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return f(boltBackendTx{db: b.db, tx: tx})
+	})
+}
+
+func (b *boltBackend) Close() error {
+	return b.db.Close()
+}
+
+type boltBackendTx struct {
+	db *bolt.DB
+	tx *bolt.Tx
+}
+
+func (t boltBackendTx) Namespace(name string) ReadWriteOperator {
+	return &boltTx{b: NewBolt(t.db, name), tx: t.tx}
+}
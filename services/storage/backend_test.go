@@ -0,0 +1,179 @@
+package storage_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/kapacitor/services/diagnostic"
+	"github.com/influxdata/kapacitor/services/httpd/httpdtest"
+	"github.com/influxdata/kapacitor/services/storage"
+	"github.com/influxdata/kapacitor/services/storage/etcdtest"
+)
+
+func init() {
+	stores["etcd"] = newEtcdService
+	// The postgres backend is tested against a database given by a connection string,
+	// e.g. postgres://postgres@localhost/kapacitor?sslmode=disable
+	if cs := os.Getenv("KAPACITOR_TEST_POSTGRES"); cs != "" {
+		stores["postgres"] = func() (storeCloser, error) {
+			c := storage.NewConfig()
+			c.Backend = storage.PostgresBackend
+			c.Postgres.ConnectionString = cs
+			// Use a new table for each store.
+			c.Postgres.Table = fmt.Sprintf("kapacitor_test_%d", nextTable())
+			return newBackendService(c, nil)
+		}
+	}
+}
+
+var (
+	tableMu sync.Mutex
+	table   int
+)
+
+func nextTable() int {
+	tableMu.Lock()
+	defer tableMu.Unlock()
+	table++
+	return table
+}
+
+type backendService struct {
+	*storage.Service
+	httpd *httpdtest.Server
+	etcd  *etcdtest.Server
+}
+
+func (s backendService) Close() {
+	s.Service.Close()
+	s.httpd.Close()
+	if s.etcd != nil {
+		s.etcd.Close()
+	}
+}
+
+func newBackendService(c storage.Config, etcd *etcdtest.Server) (backendService, error) {
+	ds := diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	ds.Open()
+	s := storage.NewService(c, ds.NewStorageHandler())
+	h := httpdtest.NewServer(false)
+	s.HTTPDService = h
+	if err := s.Open(); err != nil {
+		h.Close()
+		return backendService{}, err
+	}
+	return backendService{Service: s, httpd: h, etcd: etcd}, nil
+}
+
+func newEtcdService() (storeCloser, error) {
+	etcd := etcdtest.NewServer()
+	c := storage.NewConfig()
+	c.Backend = storage.EtcdBackend
+	c.Etcd.Endpoints = []string{etcd.URL()}
+	s, err := newBackendService(c, etcd)
+	if err != nil {
+		etcd.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func TestStorage_Etcd_ConcurrentServers(t *testing.T) {
+	etcd := etcdtest.NewServer()
+	defer etcd.Close()
+	c := storage.NewConfig()
+	c.Backend = storage.EtcdBackend
+	c.Etcd.Endpoints = []string{etcd.URL()}
+	// Servers sharing the etcd cluster.
+	var servers []storage.Interface
+	for i := 0; i < 2; i++ {
+		s, err := newBackendService(c, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		servers = append(servers, s.Store("counter"))
+	}
+
+	const writers, increments = 4, 10
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(s storage.Interface) {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				if err := s.Update(func(tx storage.Tx) error {
+					count := 0
+					if kv, err := tx.Get("count"); err == nil {
+						count, _ = strconv.Atoi(string(kv.Value))
+					} else if err != storage.ErrNoKeyExists {
+						return err
+					}
+					return tx.Put("count", []byte(strconv.Itoa(count+1)))
+				}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(servers[i%len(servers)])
+	}
+	wg.Wait()
+
+	if got, exp := string(getValue(t, servers[0], "count")), strconv.Itoa(writers*increments); got != exp {
+		t.Errorf("unexpected count got %s exp %s", got, exp)
+	}
+}
+
+func TestService_EtcdBackendEncryption(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "storage-etcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	keyFile := tmpDir + "/key"
+	if err := writeKey(keyFile); err != nil {
+		t.Fatal(err)
+	}
+	etcd := etcdtest.NewServer()
+	defer etcd.Close()
+	c := storage.NewConfig()
+	c.Backend = storage.EtcdBackend
+	c.Etcd.Endpoints = []string{etcd.URL()}
+	c.EncryptionKeyFile = keyFile
+
+	s, err := newBackendService(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := s.EncryptedStore("handlers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte(`{"password":"secret"}`)
+	if err := store.Update(func(tx storage.Tx) error {
+		return tx.Put("handler", secret)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Rekey(); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// Another server sharing the etcd cluster reads the values.
+	s, err = newBackendService(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	store, err = s.EncryptedStore("handlers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := getValue(t, store, "handler"); string(got) != string(secret) {
+		t.Errorf("unexpected value got %q exp %q", got, secret)
+	}
+}
@@ -1,21 +1,135 @@
 package storage
 
-import "fmt"
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+)
+
+const (
+	BoltBackend     = "bolt"
+	EtcdBackend     = "etcd"
+	PostgresBackend = "postgres"
+
+	DefaultEtcdPrefix    = "kapacitor"
+	DefaultEtcdTimeout   = 5 * time.Second
+	DefaultPostgresTable = "kapacitor_storage"
+)
+
+var validTable = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
 
 type Config struct {
+	// Backend storing the data, one of bolt, etcd or postgres.
+	Backend string `toml:"backend"`
 	// Path to a boltdb database file.
 	BoltDBPath string `toml:"boltdb"`
+	// Path to a file holding the base64 encoded key which encrypts the data key of the sensitive values,
+	// e.g. handler credentials and overridden secrets.
+	EncryptionKeyFile string `toml:"encryption-key-file"`
+	// Command printing the base64 encoded key on its output, e.g. a KMS client, instead of the file.
+	EncryptionKeyCommand []string `toml:"encryption-key-command"`
+
+	Etcd     EtcdConfig     `toml:"etcd"`
+	Postgres PostgresConfig `toml:"postgres"`
+}
+
+type EtcdConfig struct {
+	// URLs of the etcd members, the storage uses the JSON gateway of the v3 API.
+	Endpoints []string `toml:"endpoints"`
+	// Prefix of the keys of the data.
+	Prefix string `toml:"prefix"`
+	// Timeout of the requests to etcd.
+	Timeout toml.Duration `toml:"timeout"`
+
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	// Path to CA file
+	SSLCA string `toml:"ssl-ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl-cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl-key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool `toml:"insecure-skip-verify"`
+}
+
+type PostgresConfig struct {
+	// Connection string of the database, as a URL or as key=value pairs.
+	ConnectionString string `toml:"connection-string"`
+	// Table storing the data, it is created if it does not exist.
+	Table string `toml:"table"`
 }
 
 func NewConfig() Config {
 	return Config{
+		Backend:    BoltBackend,
 		BoltDBPath: "./kapacitor.db",
+		Etcd: EtcdConfig{
+			Prefix:  DefaultEtcdPrefix,
+			Timeout: toml.Duration(DefaultEtcdTimeout),
+		},
+		Postgres: PostgresConfig{
+			Table: DefaultPostgresTable,
+		},
 	}
 }
 
 func (c Config) Validate() error {
-	if c.BoltDBPath == "" {
-		return fmt.Errorf("must specify storage 'boltdb' path")
+	switch c.Backend {
+	case BoltBackend, "":
+		if c.BoltDBPath == "" {
+			return fmt.Errorf("must specify storage 'boltdb' path")
+		}
+	case EtcdBackend:
+		if err := c.Etcd.Validate(); err != nil {
+			return err
+		}
+	case PostgresBackend:
+		if err := c.Postgres.Validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid storage backend %q, must be one of %q, %q or %q", c.Backend, BoltBackend, EtcdBackend, PostgresBackend)
+	}
+	if c.EncryptionKeyFile != "" && len(c.EncryptionKeyCommand) > 0 {
+		return fmt.Errorf("must specify only one of 'encryption-key-file' and 'encryption-key-command'")
+	}
+	return nil
+}
+
+// EncryptionEnabled reports whether the sensitive values are encrypted.
+func (c Config) EncryptionEnabled() bool {
+	return c.EncryptionKeyFile != "" || len(c.EncryptionKeyCommand) > 0
+}
+
+func (c EtcdConfig) Validate() error {
+	if len(c.Endpoints) == 0 {
+		return fmt.Errorf("must specify at least one etcd endpoint")
+	}
+	for _, e := range c.Endpoints {
+		u, err := url.Parse(e)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid etcd endpoint %q, must be an http(s) URL", e)
+		}
+	}
+	if c.Prefix == "" {
+		return fmt.Errorf("must specify etcd prefix")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("etcd timeout must be positive")
+	}
+	return nil
+}
+
+func (c PostgresConfig) Validate() error {
+	if c.ConnectionString == "" {
+		return fmt.Errorf("must specify postgres connection-string")
+	}
+	if !validTable.MatchString(c.Table) {
+		return fmt.Errorf("invalid postgres table %q, must be an unquoted SQL identifier", c.Table)
 	}
 	return nil
 }
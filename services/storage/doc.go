@@ -7,7 +7,8 @@ Typically objects are serialized and stored as the value.
 As a result, updates to a single field of an object can incur the cost to retrieve the entire object and store it again.
 In most cases this is acceptable since modifications are rare and object size is small.
 
-A BoltDB backed implementation is also provided.
+A BoltDB backed implementation is also provided,
+as well as etcd and PostgreSQL backed implementations storing the data outside of the local filesystem.
 
 */
 package storage
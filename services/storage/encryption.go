@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrEncryptionDisabled is returned when rotating the data key while encryption is disabled.
+var ErrEncryptionDisabled = errors.New("storage encryption is not enabled")
+
+// Size in bytes of the key encryption key and of the data keys, for AES-256.
+const keySize = 32
+
+// encryptedPrefix marks encrypted values.
+// Plain values are JSON documents, which never begin with a zero byte.
+var encryptedPrefix = []byte("\x00enc")
+
+// dataKeyAD is the additional data authenticating the encrypted data key.
+var dataKeyAD = []byte("kapacitor data key")
+
+// loadKeyEncryptionKey loads the base64 encoded key encryption key from the file,
+// or from the output of the command, e.g. a KMS client.
+func loadKeyEncryptionKey(file string, command []string) ([]byte, error) {
+	var data []byte
+	var err error
+	if file != "" {
+		data, err = ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read encryption key file")
+		}
+	} else {
+		data, err = exec.Command(command[0], command[1:]...).Output()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to run encryption key command")
+		}
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid encryption key, must be base64 encoded")
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("invalid encryption key, must be %d bytes, got %d", keySize, len(key))
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the plaintext with a random nonce, which is prepended to the ciphertext.
+func seal(aead cipher.AEAD, plaintext, ad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, ad), nil
+}
+
+// unseal decrypts the ciphertext prepended with its nonce.
+func unseal(aead cipher.AEAD, ciphertext, ad []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, ad)
+}
+
+// wrapKey encrypts the data key with the key encryption key.
+func wrapKey(kek, key []byte) ([]byte, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	return seal(aead, key, dataKeyAD)
+}
+
+// unwrapKey decrypts the data key with the key encryption key.
+func unwrapKey(kek, wrapped []byte) ([]byte, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	return unseal(aead, wrapped, dataKeyAD)
+}
+
+// newDataKey returns a random data key.
+func newDataKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// keyring holds the data keys encrypting the values of encrypted stores.
+// Previous data keys are kept for the reads which started before a rotation.
+type keyring struct {
+	mu      sync.RWMutex
+	keys    map[uint32]cipher.AEAD
+	current uint32
+}
+
+func newKeyring() *keyring {
+	return &keyring{keys: make(map[uint32]cipher.AEAD)}
+}
+
+// add adds the data key with the ID to the keyring.
+func (k *keyring) add(id uint32, key []byte) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	k.keys[id] = aead
+	k.mu.Unlock()
+	return nil
+}
+
+// use makes the data key with the ID encrypt new values, and returns the ID of the previous key.
+func (k *keyring) use(id uint32) uint32 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	prev := k.current
+	k.current = id
+	return prev
+}
+
+// encrypt encrypts the value with the current data key.
+// The additional data binds the value to its key in the store.
+func (k *keyring) encrypt(value, ad []byte) ([]byte, error) {
+	k.mu.RLock()
+	id, aead := k.current, k.keys[k.current]
+	k.mu.RUnlock()
+	header := make([]byte, len(encryptedPrefix)+4)
+	copy(header, encryptedPrefix)
+	binary.BigEndian.PutUint32(header[len(encryptedPrefix):], id)
+	sealed, err := seal(aead, value, ad)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, sealed...), nil
+}
+
+// decrypt decrypts the value with the data key it was encrypted with.
+// Values which are not encrypted are returned as they are.
+func (k *keyring) decrypt(value, ad []byte) ([]byte, error) {
+	if !encrypted(value) {
+		return value, nil
+	}
+	if len(value) < len(encryptedPrefix)+4 {
+		return nil, errors.New("invalid encrypted value")
+	}
+	id := binary.BigEndian.Uint32(value[len(encryptedPrefix):])
+	k.mu.RLock()
+	aead, ok := k.keys[id]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown data key %d", id)
+	}
+	return unseal(aead, value[len(encryptedPrefix)+4:], ad)
+}
+
+func encrypted(value []byte) bool {
+	return bytes.HasPrefix(value, encryptedPrefix)
+}
+
+// valueAD returns the additional data of the value of the key in the namespace.
+func valueAD(namespace, key string) []byte {
+	return []byte(namespace + "\x00" + key)
+}
+
+// Encrypted implements Interface, encrypting the values of the underlying store.
+// Values stored before encryption was enabled are read as they are.
+type Encrypted struct {
+	store     Interface
+	namespace string
+	keyring   *keyring
+}
+
+func newEncrypted(store Interface, namespace string, k *keyring) *Encrypted {
+	return &Encrypted{
+		store:     store,
+		namespace: namespace,
+		keyring:   k,
+	}
+}
+
+func (e *Encrypted) View(f func(tx ReadOnlyTx) error) error {
+	return e.store.View(func(tx ReadOnlyTx) error {
+		return f(&encryptedReadOnlyTx{ReadOnlyTx: tx, e: e})
+	})
+}
+
+func (e *Encrypted) Update(f func(tx Tx) error) error {
+	return e.store.Update(func(tx Tx) error {
+		return f(&encryptedTx{Tx: tx, e: e})
+	})
+}
+
+func (e *Encrypted) get(tx ReadOperator, key string) (*KeyValue, error) {
+	kv, err := tx.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if kv.Value, err = e.keyring.decrypt(kv.Value, valueAD(e.namespace, kv.Key)); err != nil {
+		return nil, errors.Wrapf(err, "failed to decrypt %q", key)
+	}
+	return kv, nil
+}
+
+func (e *Encrypted) list(tx ReadOperator, prefix string) ([]*KeyValue, error) {
+	kvs, err := tx.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range kvs {
+		if kv.Value, err = e.keyring.decrypt(kv.Value, valueAD(e.namespace, kv.Key)); err != nil {
+			return nil, errors.Wrapf(err, "failed to decrypt %q", kv.Key)
+		}
+	}
+	return kvs, nil
+}
+
+type encryptedReadOnlyTx struct {
+	ReadOnlyTx
+	e *Encrypted
+}
+
+func (t *encryptedReadOnlyTx) Get(key string) (*KeyValue, error) {
+	return t.e.get(t.ReadOnlyTx, key)
+}
+
+func (t *encryptedReadOnlyTx) List(prefix string) ([]*KeyValue, error) {
+	return t.e.list(t.ReadOnlyTx, prefix)
+}
+
+type encryptedTx struct {
+	Tx
+	e *Encrypted
+}
+
+func (t *encryptedTx) Get(key string) (*KeyValue, error) {
+	return t.e.get(t.Tx, key)
+}
+
+func (t *encryptedTx) List(prefix string) ([]*KeyValue, error) {
+	return t.e.list(t.Tx, prefix)
+}
+
+func (t *encryptedTx) Put(key string, value []byte) error {
+	v, err := t.e.keyring.encrypt(value, valueAD(t.e.namespace, key))
+	if err != nil {
+		return errors.Wrapf(err, "failed to encrypt %q", key)
+	}
+	return t.Tx.Put(key, v)
+}
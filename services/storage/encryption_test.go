@@ -0,0 +1,244 @@
+package storage_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/influxdata/kapacitor/services/diagnostic"
+	"github.com/influxdata/kapacitor/services/httpd/httpdtest"
+	"github.com/influxdata/kapacitor/services/storage"
+)
+
+type encryptedService struct {
+	*storage.Service
+	httpd *httpdtest.Server
+	dir   string
+}
+
+func (s encryptedService) Store(name string) storage.Interface {
+	store, err := s.EncryptedStore(name)
+	if err != nil {
+		panic(err)
+	}
+	return store
+}
+
+func (s encryptedService) Close() {
+	s.Service.Close()
+	s.httpd.Close()
+	os.RemoveAll(s.dir)
+}
+
+func newEncryptedService() (storeCloser, error) {
+	tmpDir, err := ioutil.TempDir("", "storage-encrypted")
+	if err != nil {
+		return nil, err
+	}
+	keyFile := filepath.Join(tmpDir, "key")
+	if err := writeKey(keyFile); err != nil {
+		return nil, err
+	}
+	s, h, err := openService(filepath.Join(tmpDir, "kapacitor.db"), keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return encryptedService{Service: s, httpd: h, dir: tmpDir}, nil
+}
+
+// writeKey writes a new random key encryption key to the file.
+func writeKey(path string) error {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600)
+}
+
+func openService(dbPath, keyFile string) (*storage.Service, *httpdtest.Server, error) {
+	c := storage.NewConfig()
+	c.BoltDBPath = dbPath
+	c.EncryptionKeyFile = keyFile
+	ds := diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	ds.Open()
+	s := storage.NewService(c, ds.NewStorageHandler())
+	h := httpdtest.NewServer(false)
+	s.HTTPDService = h
+	if err := s.Open(); err != nil {
+		h.Close()
+		return nil, nil, err
+	}
+	return s, h, nil
+}
+
+func rawValue(t *testing.T, dbPath, bucket, key string) []byte {
+	db, err := bolt.Open(dbPath, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var value []byte
+	db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(bucket)); b != nil {
+			value = append(value, b.Get([]byte(key))...)
+		}
+		return nil
+	})
+	return value
+}
+
+func getValue(t *testing.T, s storage.Interface, key string) []byte {
+	var value []byte
+	if err := s.View(func(tx storage.ReadOnlyTx) error {
+		kv, err := tx.Get(key)
+		if err != nil {
+			return err
+		}
+		value = kv.Value
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestService_Encryption(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "storage-encryption")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	dbPath := filepath.Join(tmpDir, "kapacitor.db")
+	keyFile := filepath.Join(tmpDir, "key")
+	if err := writeKey(keyFile); err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte(`{"password":"secret"}`)
+
+	// Store a plain value before enabling encryption.
+	s, h, err := openService(dbPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Store("handlers").Update(func(tx storage.Tx) error {
+		return tx.Put("plain", secret)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Rekey(); err != storage.ErrEncryptionDisabled {
+		t.Fatalf("unexpected rekey error got %v exp %v", err, storage.ErrEncryptionDisabled)
+	}
+	s.Close()
+	h.Close()
+
+	s, h, err = openService(dbPath, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := s.EncryptedStore("handlers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Update(func(tx storage.Tx) error {
+		return tx.Put("new", secret)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"plain", "new"} {
+		if got := getValue(t, store, key); !bytes.Equal(got, secret) {
+			t.Errorf("unexpected value of %q got %q exp %q", key, got, secret)
+		}
+	}
+	// Rotate the key encryption key and the data key.
+	if err := writeKey(keyFile); err != nil {
+		t.Fatal(err)
+	}
+	id, count, err := s.Rekey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 2 || count != 2 {
+		t.Errorf("unexpected rekey result got key %d count %d exp key 2 count 2", id, count)
+	}
+	if got := getValue(t, store, "plain"); !bytes.Equal(got, secret) {
+		t.Errorf("unexpected value after rekey got %q exp %q", got, secret)
+	}
+	s.Close()
+	h.Close()
+
+	for _, key := range []string{"plain", "new"} {
+		if raw := rawValue(t, dbPath, "handlers", key); bytes.Contains(raw, []byte("secret")) {
+			t.Errorf("value of %q is stored in plain text: %q", key, raw)
+		}
+	}
+
+	// Reopen with the rotated key.
+	s, h, err = openService(dbPath, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err = s.EncryptedStore("handlers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := getValue(t, store, "new"); !bytes.Equal(got, secret) {
+		t.Errorf("unexpected value after reopen got %q exp %q", got, secret)
+	}
+	s.Close()
+	h.Close()
+
+	// Opening with another key or without a key fails.
+	wrongKey := filepath.Join(tmpDir, "wrong")
+	if err := writeKey(wrongKey); err != nil {
+		t.Fatal(err)
+	}
+	for _, kf := range []string{wrongKey, ""} {
+		if s, h, err := openService(dbPath, kf); err == nil {
+			s.Close()
+			h.Close()
+			t.Errorf("expected error opening with key file %q", kf)
+		}
+	}
+}
+
+func TestService_SnapshotRestore(t *testing.T) {
+	db, err := newEncryptedService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	s := db.(encryptedService)
+
+	store := s.Store("handlers")
+	if err := store.Update(func(tx storage.Tx) error {
+		if err := tx.Put("stale", []byte("stale")); err != nil {
+			return err
+		}
+		return tx.Put("kept", []byte("old"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Restore("handlers", []*storage.KeyValue{
+		{Key: "kept", Value: []byte("new")},
+		{Key: "added", Value: []byte("added")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	kvs, err := s.Snapshot("handlers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []*storage.KeyValue{
+		{Key: "added", Value: []byte("added")},
+		{Key: "kept", Value: []byte("new")},
+	}
+	if !reflect.DeepEqual(kvs, exp) {
+		t.Errorf("unexpected snapshot got %v exp %v", kvs, exp)
+	}
+}
@@ -0,0 +1,612 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor/tlsconfig"
+	"github.com/pkg/errors"
+)
+
+// errEtcdConflict is returned when committing a transaction after another transaction changed the data.
+var errEtcdConflict = errors.New("etcd transaction conflicts with a concurrent transaction")
+
+const (
+	// Conflicting transactions are run again up to this many times.
+	etcdMaxAttempts = 10
+	// Maximum delay before running a conflicting transaction again the first time.
+	etcdRetryDelay = 5 * time.Millisecond
+)
+
+// gRPC status code of invalid or expired auth tokens.
+const etcdCodeUnauthenticated = 16
+
+// etcdBackend stores all namespaces under a key prefix in etcd, using the JSON gateway of the v3 API.
+//
+// Transactions read a snapshot of the data at the revision they began,
+// and buffer their writes until they are committed in a single etcd transaction.
+// Every write transaction also updates a lock key, and only commits if the lock key was not updated since it began,
+// so that write transactions are serializable like the write transactions of BoltDB.
+// Write transactions of the same server are run one at a time,
+// so only the transactions conflicting with the ones of other servers are run again by Update.
+type etcdBackend struct {
+	client  *etcdClient
+	prefix  string
+	lockKey string
+	writeMu sync.Mutex
+}
+
+func newEtcdBackend(c EtcdConfig) (*etcdBackend, error) {
+	client, err := newEtcdClient(c)
+	if err != nil {
+		return nil, err
+	}
+	b := &etcdBackend{
+		client:  client,
+		prefix:  c.Prefix + "/data/",
+		lockKey: c.Prefix + "/lock",
+	}
+	// Check the connection.
+	if _, err := client.Range(etcdRangeRequest{Key: []byte(b.lockKey)}); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *etcdBackend) Store(namespace string) Interface {
+	return &Etcd{
+		b:      b,
+		prefix: b.namespacePrefix(namespace),
+	}
+}
+
+// namespacePrefix returns the prefix of the keys of the namespace,
+// the namespace is escaped so that it is not a prefix of other namespaces.
+func (b *etcdBackend) namespacePrefix(namespace string) string {
+	return b.prefix + url.PathEscape(namespace) + "/"
+}
+
+func (b *etcdBackend) Update(f func(tx BackendTx) error) error {
+	return b.retry(func() error {
+		tx, err := b.begin(true)
+		if err != nil {
+			return err
+		}
+		defer tx.rollback()
+		if err := f(etcdBackendTx{tx}); err != nil {
+			return err
+		}
+		return tx.commit()
+	})
+}
+
+func (b *etcdBackend) Close() error {
+	return nil
+}
+
+// retry runs f again while it fails with a conflict,
+// after a random delay growing with the attempts so that conflicting servers do not keep conflicting.
+func (b *etcdBackend) retry(f func() error) error {
+	var err error
+	for i := 0; i < etcdMaxAttempts; i++ {
+		if err = f(); err != errEtcdConflict {
+			return err
+		}
+		time.Sleep(time.Duration(rand.Int63n(int64(etcdRetryDelay) << uint(i))))
+	}
+	return err
+}
+
+func (b *etcdBackend) begin(write bool) (*etcdTx, error) {
+	if write {
+		b.writeMu.Lock()
+	}
+	resp, err := b.client.Range(etcdRangeRequest{Key: []byte(b.lockKey)})
+	if err != nil {
+		if write {
+			b.writeMu.Unlock()
+		}
+		return nil, err
+	}
+	tx := &etcdTx{
+		b:        b,
+		revision: resp.Header.Revision,
+		write:    write,
+	}
+	if len(resp.KVs) > 0 {
+		tx.lockRevision = resp.KVs[0].ModRevision
+	}
+	if write {
+		tx.writes = make(map[string]*etcdWrite)
+	}
+	return tx, nil
+}
+
+type etcdBackendTx struct {
+	tx *etcdTx
+}
+
+func (t etcdBackendTx) Namespace(name string) ReadWriteOperator {
+	return &etcdNamespaceTx{tx: t.tx, prefix: t.tx.b.namespacePrefix(name)}
+}
+
+// Etcd implementation of Store
+type Etcd struct {
+	b      *etcdBackend
+	prefix string
+}
+
+func (e *Etcd) View(f func(tx ReadOnlyTx) error) error {
+	return DoView(e, f)
+}
+
+func (e *Etcd) Update(f func(tx Tx) error) error {
+	return e.b.retry(func() error {
+		return DoUpdate(e, f)
+	})
+}
+
+func (e *Etcd) BeginTx() (Tx, error) {
+	return e.newTx(true)
+}
+
+func (e *Etcd) BeginReadOnlyTx() (ReadOnlyTx, error) {
+	return e.newTx(false)
+}
+
+func (e *Etcd) newTx(write bool) (*etcdNamespaceTx, error) {
+	tx, err := e.b.begin(write)
+	if err != nil {
+		return nil, err
+	}
+	return &etcdNamespaceTx{tx: tx, prefix: e.prefix}, nil
+}
+
+type etcdWrite struct {
+	value  []byte
+	delete bool
+}
+
+// etcdTx reads the data at a revision and buffers the writes.
+type etcdTx struct {
+	b *etcdBackend
+	// Revision of the snapshot of the data the transaction reads.
+	revision etcdInt
+	// Revision of the last change of the lock key.
+	lockRevision etcdInt
+	write        bool
+	// Buffered writes by key.
+	writes map[string]*etcdWrite
+	done   bool
+}
+
+func (t *etcdTx) get(key string) ([]byte, bool, error) {
+	if w, ok := t.writes[key]; ok {
+		return w.value, !w.delete, nil
+	}
+	resp, err := t.b.client.Range(etcdRangeRequest{
+		Key:      []byte(key),
+		Revision: t.revision,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(resp.KVs) == 0 {
+		return nil, false, nil
+	}
+	return resp.KVs[0].Value, true, nil
+}
+
+func (t *etcdTx) list(prefix string) ([]*KeyValue, error) {
+	resp, err := t.b.client.Range(etcdRangeRequest{
+		Key:      []byte(prefix),
+		RangeEnd: prefixEnd([]byte(prefix)),
+		Revision: t.revision,
+	})
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string][]byte, len(resp.KVs))
+	for _, kv := range resp.KVs {
+		values[string(kv.Key)] = kv.Value
+	}
+	for key, w := range t.writes {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if w.delete {
+			delete(values, key)
+		} else {
+			values[key] = w.value
+		}
+	}
+	kvs := make([]*KeyValue, 0, len(values))
+	for key, value := range values {
+		kvs = append(kvs, &KeyValue{Key: key, Value: value})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs, nil
+}
+
+func (t *etcdTx) put(key string, value []byte) error {
+	if !t.write {
+		return errors.New("cannot write in a read only transaction")
+	}
+	t.writes[key] = &etcdWrite{value: value}
+	return nil
+}
+
+func (t *etcdTx) delete(key string) error {
+	if !t.write {
+		return errors.New("cannot write in a read only transaction")
+	}
+	t.writes[key] = &etcdWrite{delete: true}
+	return nil
+}
+
+func (t *etcdTx) commit() error {
+	if t.done {
+		return errors.New("transaction already committed or rolled back")
+	}
+	defer t.rollback()
+	if len(t.writes) == 0 {
+		return nil
+	}
+	req := etcdTxnRequest{
+		Compare: []etcdCompare{{
+			Key:         []byte(t.b.lockKey),
+			Target:      "MOD",
+			Result:      "EQUAL",
+			ModRevision: t.lockRevision,
+		}},
+	}
+	keys := make([]string, 0, len(t.writes))
+	for key := range t.writes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		w := t.writes[key]
+		if w.delete {
+			req.Success = append(req.Success, etcdRequestOp{RequestDeleteRange: &etcdDeleteRangeRequest{Key: []byte(key)}})
+		} else {
+			req.Success = append(req.Success, etcdRequestOp{RequestPut: &etcdPutRequest{Key: []byte(key), Value: w.value}})
+		}
+	}
+	req.Success = append(req.Success, etcdRequestOp{RequestPut: &etcdPutRequest{Key: []byte(t.b.lockKey), Value: []byte{}}})
+	resp, err := t.b.client.Txn(req)
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return errEtcdConflict
+	}
+	return nil
+}
+
+// rollback discards the buffered writes, and lets the next write transaction begin.
+func (t *etcdTx) rollback() {
+	if t.done {
+		return
+	}
+	t.done = true
+	if t.write {
+		t.b.writeMu.Unlock()
+	}
+}
+
+// etcdNamespaceTx implements the Tx interface for the keys of a namespace.
+type etcdNamespaceTx struct {
+	tx     *etcdTx
+	prefix string
+}
+
+func (t *etcdNamespaceTx) Get(key string) (*KeyValue, error) {
+	value, ok, err := t.tx.get(t.prefix + key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNoKeyExists
+	}
+	return &KeyValue{
+		Key:   key,
+		Value: value,
+	}, nil
+}
+
+func (t *etcdNamespaceTx) Exists(key string) (bool, error) {
+	_, ok, err := t.tx.get(t.prefix + key)
+	return ok, err
+}
+
+func (t *etcdNamespaceTx) List(prefix string) ([]*KeyValue, error) {
+	kvs, err := t.tx.list(t.prefix + prefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range kvs {
+		kv.Key = strings.TrimPrefix(kv.Key, t.prefix)
+	}
+	return kvs, nil
+}
+
+func (t *etcdNamespaceTx) Put(key string, value []byte) error {
+	return t.tx.put(t.prefix+key, value)
+}
+
+func (t *etcdNamespaceTx) Delete(key string) error {
+	return t.tx.delete(t.prefix + key)
+}
+
+func (t *etcdNamespaceTx) Commit() error {
+	return t.tx.commit()
+}
+
+func (t *etcdNamespaceTx) Rollback() error {
+	t.tx.rollback()
+	return nil
+}
+
+// prefixEnd returns the end of the range of the keys with the prefix.
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// The prefix is all 0xff bytes, the range ends with the last key.
+	return []byte{0}
+}
+
+// etcdInt is an int64 encoded as a JSON string by the gateway.
+type etcdInt int64
+
+func (i etcdInt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(i), 10))
+}
+
+func (i *etcdInt) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = etcdInt(v)
+	return nil
+}
+
+type etcdResponseHeader struct {
+	Revision etcdInt `json:"revision"`
+}
+
+type etcdKeyValue struct {
+	Key         []byte  `json:"key"`
+	Value       []byte  `json:"value"`
+	ModRevision etcdInt `json:"mod_revision"`
+}
+
+type etcdRangeRequest struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end,omitempty"`
+	// Revision of the snapshot to read, the latest revision if zero.
+	Revision etcdInt `json:"revision,omitempty"`
+}
+
+type etcdRangeResponse struct {
+	Header etcdResponseHeader `json:"header"`
+	KVs    []etcdKeyValue     `json:"kvs"`
+}
+
+type etcdCompare struct {
+	Key         []byte  `json:"key"`
+	Target      string  `json:"target"`
+	Result      string  `json:"result"`
+	ModRevision etcdInt `json:"mod_revision"`
+}
+
+type etcdPutRequest struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type etcdDeleteRangeRequest struct {
+	Key []byte `json:"key"`
+}
+
+type etcdRequestOp struct {
+	RequestPut         *etcdPutRequest         `json:"request_put,omitempty"`
+	RequestDeleteRange *etcdDeleteRangeRequest `json:"request_delete_range,omitempty"`
+}
+
+type etcdTxnRequest struct {
+	Compare []etcdCompare   `json:"compare"`
+	Success []etcdRequestOp `json:"success"`
+}
+
+type etcdTxnResponse struct {
+	Header    etcdResponseHeader `json:"header"`
+	Succeeded bool               `json:"succeeded"`
+}
+
+type etcdError struct {
+	Code    int    `json:"code"`
+	Err     string `json:"error"`
+	Message string `json:"message"`
+}
+
+// etcdClient is a client of the JSON gateway of the etcd v3 API.
+//
+// The official client is not used since it requires versions of gRPC and protobuf
+// which are incompatible with the ones vendored for other dependencies,
+// while the storage only needs the range, txn and authenticate calls.
+// The gateway maps these calls to the v3 gRPC API, encoding the messages as JSON with the original field names:
+// bytes are standard base64, int64 values are strings, and fields with default values are omitted,
+// e.g. succeeded is missing from the responses of failed transactions.
+// Errors are gRPC statuses with a code and a message.
+// The encoding is checked against the one of the etcd 3.5 gateway by the tests.
+type etcdClient struct {
+	endpoints  []string
+	username   string
+	password   string
+	httpClient *http.Client
+
+	mu sync.Mutex
+	// Index of the endpoint of the last successful request.
+	current int
+	token   string
+}
+
+func newEtcdClient(c EtcdConfig) (*etcdClient, error) {
+	tlsConfig, err := tlsconfig.Create(c.SSLCA, c.SSLCert, c.SSLKey, c.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]string, len(c.Endpoints))
+	for i, e := range c.Endpoints {
+		endpoints[i] = strings.TrimSuffix(e, "/")
+	}
+	return &etcdClient{
+		endpoints: endpoints,
+		username:  c.Username,
+		password:  c.Password,
+		httpClient: &http.Client{
+			Timeout: time.Duration(c.Timeout),
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}, nil
+}
+
+func (c *etcdClient) Range(req etcdRangeRequest) (*etcdRangeResponse, error) {
+	resp := new(etcdRangeResponse)
+	if err := c.call("/v3/kv/range", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *etcdClient) Txn(req etcdTxnRequest) (*etcdTxnResponse, error) {
+	resp := new(etcdTxnResponse)
+	if err := c.call("/v3/kv/txn", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// call sends the request to the current endpoint, or to the other endpoints if it cannot reach it.
+// It authenticates again if the auth token expired.
+func (c *etcdClient) call(path string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	authenticated := false
+	for {
+		token, err := c.authToken(false)
+		if err != nil {
+			return err
+		}
+		err = c.post(path, token, body, resp)
+		if e, ok := err.(*etcdError); ok && e.Code == etcdCodeUnauthenticated && c.username != "" && !authenticated {
+			if _, err := c.authToken(true); err != nil {
+				return err
+			}
+			authenticated = true
+			continue
+		}
+		return err
+	}
+}
+
+// authToken returns the auth token, authenticating if there is none or if renew is true.
+func (c *etcdClient) authToken(renew bool) (string, error) {
+	if c.username == "" {
+		return "", nil
+	}
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+	if token != "" && !renew {
+		return token, nil
+	}
+	body, err := json.Marshal(map[string]string{
+		"name":     c.username,
+		"password": c.password,
+	})
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := c.post("/v3/auth/authenticate", "", body, &resp); err != nil {
+		return "", errors.Wrap(err, "failed to authenticate with etcd")
+	}
+	c.mu.Lock()
+	c.token = resp.Token
+	c.mu.Unlock()
+	return resp.Token, nil
+}
+
+func (c *etcdClient) post(path, token string, body []byte, resp interface{}) error {
+	c.mu.Lock()
+	start := c.current
+	c.mu.Unlock()
+	var lastErr error
+	for i := 0; i < len(c.endpoints); i++ {
+		n := (start + i) % len(c.endpoints)
+		req, err := http.NewRequest("POST", c.endpoints[n]+path, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		r, err := c.httpClient.Do(req)
+		if err != nil {
+			// Try the next endpoint.
+			lastErr = err
+			continue
+		}
+		c.mu.Lock()
+		c.current = n
+		c.mu.Unlock()
+		data, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return err
+		}
+		if r.StatusCode != http.StatusOK {
+			e := new(etcdError)
+			if err := json.Unmarshal(data, e); err != nil || (e.Err == "" && e.Message == "") {
+				return fmt.Errorf("etcd request failed: code %d: body: %s", r.StatusCode, data)
+			}
+			return e
+		}
+		return json.Unmarshal(data, resp)
+	}
+	return errors.Wrap(lastErr, "failed to reach etcd")
+}
+
+func (e *etcdError) Error() string {
+	if e.Message != "" {
+		return "etcd: " + e.Message
+	}
+	return "etcd: " + e.Err
+}
@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// The bodies of the responses below are the encoding of the etcd v3.5 gateway, grpc-gateway v1 with OrigName,
+// and the bodies of the requests decode to the expected etcdserverpb requests with it:
+// bytes are standard base64, int64 values are strings and fields with default values are omitted.
+
+// etcdExchange is an expected request to the gateway and its response.
+type etcdExchange struct {
+	path string
+	auth string
+	req  string
+	code int
+	resp string
+}
+
+// etcdGateway replays exchanges with the gateway in order.
+type etcdGateway struct {
+	*httptest.Server
+	t *testing.T
+
+	mu        sync.Mutex
+	exchanges []etcdExchange
+}
+
+func newEtcdGateway(t *testing.T, exchanges ...etcdExchange) *etcdGateway {
+	g := &etcdGateway{t: t, exchanges: exchanges}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		body, _ := ioutil.ReadAll(r.Body)
+		if len(g.exchanges) == 0 {
+			t.Errorf("unexpected request %s %s", r.URL.Path, body)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		e := g.exchanges[0]
+		g.exchanges = g.exchanges[1:]
+		if r.URL.Path != e.path {
+			t.Errorf("unexpected path: got %s exp %s", r.URL.Path, e.path)
+		}
+		if got := r.Header.Get("Authorization"); got != e.auth {
+			t.Errorf("%s: unexpected authorization: got %q exp %q", e.path, got, e.auth)
+		}
+		if string(body) != e.req {
+			t.Errorf("%s: unexpected request:\ngot %s\nexp %s", e.path, body, e.req)
+		}
+		if e.code == 0 {
+			e.code = http.StatusOK
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(e.code)
+		w.Write([]byte(e.resp))
+	}))
+	return g
+}
+
+func (g *etcdGateway) done() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.exchanges) > 0 {
+		g.t.Errorf("%d exchanges were not requested, first %s %s", len(g.exchanges), g.exchanges[0].path, g.exchanges[0].req)
+	}
+}
+
+const etcdLockRange = `{"key":"a2FwYWNpdG9yL2xvY2s="}`
+
+func TestEtcdBackend_GatewayEncoding(t *testing.T) {
+	g := newEtcdGateway(t,
+		// Connection check, the lock key does not exist yet.
+		etcdExchange{
+			path: "/v3/kv/range",
+			req:  etcdLockRange,
+			resp: `{"header":{"cluster_id":"1","member_id":"2","revision":"3","raft_term":"2"}}`,
+		},
+		// Beginning a transaction reads the revision of the data and of the lock key.
+		etcdExchange{
+			path: "/v3/kv/range",
+			req:  etcdLockRange,
+			resp: `{"header":{"cluster_id":"14841639068965178418","member_id":"10276657743932975437","revision":"7","raft_term":"2"},` +
+				`"kvs":[{"key":"a2FwYWNpdG9yL2xvY2s=","create_revision":"2","mod_revision":"3","version":"2"}],"count":"1"}`,
+		},
+		// Listing reads the snapshot of the revision of the transaction.
+		etcdExchange{
+			path: "/v3/kv/range",
+			req:  `{"key":"a2FwYWNpdG9yL2RhdGEvdGFza3Mv","range_end":"a2FwYWNpdG9yL2RhdGEvdGFza3Mw","revision":"7"}`,
+			resp: `{"header":{"cluster_id":"14841639068965178418","member_id":"10276657743932975437","revision":"9","raft_term":"2"},` +
+				`"kvs":[{"key":"a2FwYWNpdG9yL2RhdGEvdGFza3MvdDE=","create_revision":"5","mod_revision":"7","version":"2","value":"AP92"},` +
+				`{"key":"a2FwYWNpdG9yL2RhdGEvdGFza3Mva/8=","create_revision":"6","mod_revision":"6","version":"1"}],"count":"2"}`,
+		},
+		// The writes are committed if the lock key did not change, a failed comparison omits succeeded.
+		etcdExchange{
+			path: "/v3/kv/txn",
+			req: `{"compare":[{"key":"a2FwYWNpdG9yL2xvY2s=","target":"MOD","result":"EQUAL","mod_revision":"3"}],"success":[` +
+				`{"request_delete_range":{"key":"a2FwYWNpdG9yL2RhdGEvdGFza3MvZA=="}},` +
+				`{"request_put":{"key":"a2FwYWNpdG9yL2RhdGEvdGFza3Mva/8=","value":"AP92"}},` +
+				`{"request_put":{"key":"a2FwYWNpdG9yL2xvY2s=","value":""}}]}`,
+			resp: `{"header":{"cluster_id":"1","member_id":"2","revision":"8","raft_term":"2"},"responses":[{"response_range":{"header":{"revision":"8"}}}]}`,
+		},
+		etcdExchange{
+			path: "/v3/kv/range",
+			req:  etcdLockRange,
+			resp: `{"header":{"cluster_id":"1","member_id":"2","revision":"8","raft_term":"2"},` +
+				`"kvs":[{"key":"a2FwYWNpdG9yL2xvY2s=","create_revision":"2","mod_revision":"8","version":"3"}],"count":"1"}`,
+		},
+		etcdExchange{
+			path: "/v3/kv/txn",
+			req: `{"compare":[{"key":"a2FwYWNpdG9yL2xvY2s=","target":"MOD","result":"EQUAL","mod_revision":"8"}],"success":[` +
+				`{"request_put":{"key":"a2FwYWNpdG9yL2RhdGEvdGFza3MvdDE=","value":"dmFsdWU="}},` +
+				`{"request_put":{"key":"a2FwYWNpdG9yL2xvY2s=","value":""}}]}`,
+			resp: `{"header":{"cluster_id":"1","member_id":"2","revision":"9","raft_term":"2"},"succeeded":true,"responses":[{"response_put":{"header":{"revision":"9"}}}]}`,
+		},
+	)
+	defer g.Close()
+
+	b, err := newEtcdBackend(EtcdConfig{Endpoints: []string{g.URL}, Prefix: "kapacitor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := b.begin(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.revision != 7 || tx.lockRevision != 3 {
+		t.Errorf("unexpected revisions: got %d and %d exp 7 and 3", tx.revision, tx.lockRevision)
+	}
+	kvs, err := tx.list("kapacitor/data/tasks/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []*KeyValue{
+		{Key: "kapacitor/data/tasks/k\xff"},
+		{Key: "kapacitor/data/tasks/t1", Value: []byte{0, 0xff, 'v'}},
+	}
+	if len(kvs) != len(exp) {
+		t.Fatalf("unexpected number of key values: got %d exp %d", len(kvs), len(exp))
+	}
+	for i := range exp {
+		if !reflect.DeepEqual(kvs[i], exp[i]) {
+			t.Errorf("unexpected key value %d: got %q=%q exp %q=%q", i, kvs[i].Key, kvs[i].Value, exp[i].Key, exp[i].Value)
+		}
+	}
+	tx.put("kapacitor/data/tasks/k\xff", []byte{0, 0xff, 'v'})
+	tx.delete("kapacitor/data/tasks/d")
+	if err := tx.commit(); err != errEtcdConflict {
+		t.Errorf("unexpected commit error: got %v exp %v", err, errEtcdConflict)
+	}
+
+	tx, err = b.begin(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.put("kapacitor/data/tasks/t1", []byte("value"))
+	if err := tx.commit(); err != nil {
+		t.Errorf("unexpected commit error: %v", err)
+	}
+	g.done()
+}
+
+func TestEtcdClient_GatewayErrors(t *testing.T) {
+	g := newEtcdGateway(t,
+		etcdExchange{
+			path: "/v3/auth/authenticate",
+			req:  `{"name":"kapacitor","password":"secret"}`,
+			resp: `{"header":{"cluster_id":"1","member_id":"2","revision":"3","raft_term":"2"},"token":"token1.10"}`,
+		},
+		// Expired auth tokens are renewed once.
+		etcdExchange{
+			path: "/v3/kv/range",
+			auth: "token1.10",
+			req:  etcdLockRange,
+			code: http.StatusUnauthorized,
+			resp: `{"error":"etcdserver: invalid auth token","code":16,"message":"etcdserver: invalid auth token"}`,
+		},
+		etcdExchange{
+			path: "/v3/auth/authenticate",
+			req:  `{"name":"kapacitor","password":"secret"}`,
+			resp: `{"header":{"cluster_id":"1","member_id":"2","revision":"3","raft_term":"2"},"token":"token2.11"}`,
+		},
+		etcdExchange{
+			path: "/v3/kv/range",
+			auth: "token2.11",
+			req:  etcdLockRange,
+			resp: `{"header":{"cluster_id":"1","member_id":"2","revision":"3","raft_term":"2"}}`,
+		},
+		// Reading a compacted revision.
+		etcdExchange{
+			path: "/v3/kv/range",
+			auth: "token2.11",
+			req:  `{"key":"a2FwYWNpdG9yL2xvY2s=","revision":"2"}`,
+			code: http.StatusBadRequest,
+			resp: `{"error":"etcdserver: mvcc: required revision has been compacted","code":11,"message":"etcdserver: mvcc: required revision has been compacted"}`,
+		},
+		// Errors of grpc-gateway v2 have no error field.
+		etcdExchange{
+			path: "/v3/kv/range",
+			auth: "token2.11",
+			req:  etcdLockRange,
+			code: http.StatusServiceUnavailable,
+			resp: `{"code":14,"message":"etcdserver: request timed out","details":[]}`,
+		},
+		// Errors of proxies are not JSON.
+		etcdExchange{
+			path: "/v3/kv/range",
+			auth: "token2.11",
+			req:  etcdLockRange,
+			code: http.StatusBadGateway,
+			resp: `<html>Bad Gateway</html>`,
+		},
+		// Invalid credentials are not retried.
+		etcdExchange{
+			path: "/v3/kv/range",
+			auth: "token2.11",
+			req:  etcdLockRange,
+			code: http.StatusUnauthorized,
+			resp: `{"error":"etcdserver: invalid auth token","code":16,"message":"etcdserver: invalid auth token"}`,
+		},
+		etcdExchange{
+			path: "/v3/auth/authenticate",
+			req:  `{"name":"kapacitor","password":"secret"}`,
+			code: http.StatusBadRequest,
+			resp: `{"error":"etcdserver: authentication failed, invalid user ID or password","code":3,"message":"etcdserver: authentication failed, invalid user ID or password"}`,
+		},
+	)
+	defer g.Close()
+
+	c, err := newEtcdClient(EtcdConfig{Endpoints: []string{g.URL}, Username: "kapacitor", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Range(etcdRangeRequest{Key: []byte("kapacitor/lock")}); err != nil {
+		t.Errorf("unexpected error renewing the auth token: %v", err)
+	}
+	testCases := []struct {
+		req etcdRangeRequest
+		err string
+	}{
+		{
+			req: etcdRangeRequest{Key: []byte("kapacitor/lock"), Revision: 2},
+			err: "etcd: etcdserver: mvcc: required revision has been compacted",
+		},
+		{
+			req: etcdRangeRequest{Key: []byte("kapacitor/lock")},
+			err: "etcd: etcdserver: request timed out",
+		},
+		{
+			req: etcdRangeRequest{Key: []byte("kapacitor/lock")},
+			err: "etcd request failed: code 502: body: <html>Bad Gateway</html>",
+		},
+		{
+			req: etcdRangeRequest{Key: []byte("kapacitor/lock")},
+			err: "failed to authenticate with etcd: etcd: etcdserver: authentication failed, invalid user ID or password",
+		},
+	}
+	for _, tc := range testCases {
+		_, err := c.Range(tc.req)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+			t.Errorf("unexpected error: got %v exp %s", err, tc.err)
+		}
+	}
+	g.done()
+}
@@ -0,0 +1,214 @@
+// Package etcdtest provides a fake of the JSON gateway of the etcd v3 KV API.
+package etcdtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Server struct {
+	ts *httptest.Server
+
+	mu       sync.Mutex
+	revision int64
+	// Versions of each key in revision order.
+	versions map[string][]version
+	// Number of failed transactions.
+	conflicts int
+}
+
+type version struct {
+	revision int64
+	value    []byte
+	deleted  bool
+}
+
+func NewServer() *Server {
+	s := &Server{
+		revision: 1,
+		versions: make(map[string][]version),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3/kv/range", s.handleRange)
+	mux.HandleFunc("/v3/kv/txn", s.handleTxn)
+	s.ts = httptest.NewServer(mux)
+	return s
+}
+
+func (s *Server) URL() string {
+	return s.ts.URL
+}
+
+func (s *Server) Close() {
+	s.ts.Close()
+}
+
+// Conflicts returns the number of transactions whose comparisons failed.
+func (s *Server) Conflicts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conflicts
+}
+
+type int64String int64
+
+func (i int64String) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(i), 10))
+}
+
+func (i *int64String) UnmarshalJSON(data []byte) error {
+	v, err := strconv.ParseInt(strings.Trim(string(data), `"`), 10, 64)
+	*i = int64String(v)
+	return err
+}
+
+type header struct {
+	Revision int64String `json:"revision"`
+}
+
+type keyValue struct {
+	Key         []byte      `json:"key"`
+	Value       []byte      `json:"value,omitempty"`
+	ModRevision int64String `json:"mod_revision"`
+}
+
+type rangeRequest struct {
+	Key      []byte      `json:"key"`
+	RangeEnd []byte      `json:"range_end"`
+	Revision int64String `json:"revision"`
+}
+
+type rangeResponse struct {
+	Header header     `json:"header"`
+	KVs    []keyValue `json:"kvs,omitempty"`
+}
+
+type compare struct {
+	Key         []byte      `json:"key"`
+	Target      string      `json:"target"`
+	Result      string      `json:"result"`
+	ModRevision int64String `json:"mod_revision"`
+}
+
+type requestOp struct {
+	RequestPut *struct {
+		Key   []byte `json:"key"`
+		Value []byte `json:"value"`
+	} `json:"request_put"`
+	RequestDeleteRange *struct {
+		Key []byte `json:"key"`
+	} `json:"request_delete_range"`
+}
+
+type txnRequest struct {
+	Compare []compare   `json:"compare"`
+	Success []requestOp `json:"success"`
+	Failure []requestOp `json:"failure"`
+}
+
+type txnResponse struct {
+	Header    header `json:"header"`
+	Succeeded bool   `json:"succeeded,omitempty"`
+}
+
+// latest returns the version of the key at the revision. Caller must have lock.
+func (s *Server) latest(key string, revision int64) (version, bool) {
+	versions := s.versions[key]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].revision <= revision {
+			return versions[i], !versions[i].deleted
+		}
+	}
+	return version{}, false
+}
+
+func (s *Server) handleRange(w http.ResponseWriter, r *http.Request) {
+	var req rangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	revision := int64(req.Revision)
+	if revision == 0 {
+		revision = s.revision
+	}
+	resp := rangeResponse{Header: header{Revision: int64String(s.revision)}}
+	var keys []string
+	for key := range s.versions {
+		k := []byte(key)
+		if len(req.RangeEnd) == 0 {
+			if bytes.Equal(k, req.Key) {
+				keys = append(keys, key)
+			}
+		} else if bytes.Compare(k, req.Key) >= 0 && (bytes.Equal(req.RangeEnd, []byte{0}) || bytes.Compare(k, req.RangeEnd) < 0) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if v, ok := s.latest(key, revision); ok {
+			resp.KVs = append(resp.KVs, keyValue{
+				Key:         []byte(key),
+				Value:       v.value,
+				ModRevision: int64String(v.revision),
+			})
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) handleTxn(w http.ResponseWriter, r *http.Request) {
+	var req txnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	succeeded := true
+	for _, c := range req.Compare {
+		if c.Target != "MOD" || c.Result != "EQUAL" {
+			http.Error(w, "unsupported comparison", http.StatusBadRequest)
+			return
+		}
+		var modRevision int64
+		if v, ok := s.latest(string(c.Key), s.revision); ok {
+			modRevision = v.revision
+		}
+		if modRevision != int64(c.ModRevision) {
+			succeeded = false
+		}
+	}
+	ops := req.Success
+	if !succeeded {
+		s.conflicts++
+		ops = req.Failure
+	}
+	if len(ops) > 0 {
+		s.revision++
+		for _, op := range ops {
+			switch {
+			case op.RequestPut != nil:
+				key := string(op.RequestPut.Key)
+				s.versions[key] = append(s.versions[key], version{revision: s.revision, value: op.RequestPut.Value})
+			case op.RequestDeleteRange != nil:
+				key := string(op.RequestDeleteRange.Key)
+				if _, ok := s.latest(key, s.revision); ok {
+					s.versions[key] = append(s.versions[key], version{revision: s.revision, deleted: true})
+				}
+			}
+		}
+	}
+	json.NewEncoder(w).Encode(txnResponse{
+		Header:    header{Revision: int64String(s.revision)},
+		Succeeded: succeeded,
+	})
+}
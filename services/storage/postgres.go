@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	// Register the postgres driver.
+	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// postgresBackend stores all namespaces in a table of a PostgreSQL database.
+// Write transactions hold a transaction level advisory lock, so that they are serialized
+// like the write transactions of BoltDB, also between servers sharing the database.
+type postgresBackend struct {
+	db    *sql.DB
+	table string
+}
+
+func newPostgresBackend(c PostgresConfig) (*postgresBackend, error) {
+	db, err := sql.Open("postgres", c.ConnectionString)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	_, err = db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	namespace TEXT NOT NULL,
+	key TEXT NOT NULL,
+	value BYTEA NOT NULL,
+	PRIMARY KEY (namespace, key)
+)`, c.Table))
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrapf(err, "failed to create table %q", c.Table)
+	}
+	return &postgresBackend{
+		db:    db,
+		table: c.Table,
+	}, nil
+}
+
+func (b *postgresBackend) Store(namespace string) Interface {
+	return &Postgres{
+		b:         b,
+		namespace: namespace,
+	}
+}
+
+func (b *postgresBackend) Update(f func(tx BackendTx) error) error {
+	tx, err := b.begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := f(postgresBackendTx{b: b, tx: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (b *postgresBackend) Close() error {
+	return b.db.Close()
+}
+
+func (b *postgresBackend) begin(write bool) (*sql.Tx, error) {
+	if !write {
+		// Read only transactions see a snapshot of the data.
+		return b.db.BeginTx(context.Background(), &sql.TxOptions{
+			Isolation: sql.LevelRepeatableRead,
+			ReadOnly:  true,
+		})
+	}
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, b.table); err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(err, "failed to lock storage")
+	}
+	return tx, nil
+}
+
+type postgresBackendTx struct {
+	b  *postgresBackend
+	tx *sql.Tx
+}
+
+func (t postgresBackendTx) Namespace(name string) ReadWriteOperator {
+	return &postgresTx{b: t.b, tx: t.tx, namespace: name}
+}
+
+// Postgres implementation of Store
+type Postgres struct {
+	b         *postgresBackend
+	namespace string
+}
+
+func (p *Postgres) View(f func(tx ReadOnlyTx) error) error {
+	return DoView(p, f)
+}
+
+func (p *Postgres) Update(f func(tx Tx) error) error {
+	return DoUpdate(p, f)
+}
+
+func (p *Postgres) BeginTx() (Tx, error) {
+	return p.newTx(true)
+}
+
+func (p *Postgres) BeginReadOnlyTx() (ReadOnlyTx, error) {
+	return p.newTx(false)
+}
+
+func (p *Postgres) newTx(write bool) (*postgresTx, error) {
+	tx, err := p.b.begin(write)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresTx{
+		b:         p.b,
+		tx:        tx,
+		namespace: p.namespace,
+	}, nil
+}
+
+// postgresTx wraps an underlying sql.Tx type to implement the Tx interface for a namespace.
+type postgresTx struct {
+	b         *postgresBackend
+	tx        *sql.Tx
+	namespace string
+}
+
+func (t *postgresTx) Get(key string) (*KeyValue, error) {
+	var value []byte
+	err := t.tx.QueryRow(
+		fmt.Sprintf(`SELECT value FROM %s WHERE namespace = $1 AND key = $2`, t.b.table),
+		t.namespace, key,
+	).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoKeyExists
+	} else if err != nil {
+		return nil, err
+	}
+	return &KeyValue{
+		Key:   key,
+		Value: value,
+	}, nil
+}
+
+func (t *postgresTx) Exists(key string) (bool, error) {
+	var exists bool
+	err := t.tx.QueryRow(
+		fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE namespace = $1 AND key = $2)`, t.b.table),
+		t.namespace, key,
+	).Scan(&exists)
+	return exists, err
+}
+
+func (t *postgresTx) List(prefix string) ([]*KeyValue, error) {
+	// Order the keys by their bytes like BoltDB.
+	rows, err := t.tx.Query(
+		fmt.Sprintf(`SELECT key, value FROM %s WHERE namespace = $1 AND left(key, length($2::text)) = $2::text ORDER BY key COLLATE "C"`, t.b.table),
+		t.namespace, prefix,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var kvs []*KeyValue
+	for rows.Next() {
+		kv := new(KeyValue)
+		if err := rows.Scan(&kv.Key, &kv.Value); err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs, rows.Err()
+}
+
+func (t *postgresTx) Put(key string, value []byte) error {
+	if value == nil {
+		// Store an empty value like BoltDB, instead of NULL.
+		value = []byte{}
+	}
+	_, err := t.tx.Exec(
+		fmt.Sprintf(`INSERT INTO %s (namespace, key, value) VALUES ($1, $2, $3) ON CONFLICT (namespace, key) DO UPDATE SET value = EXCLUDED.value`, t.b.table),
+		t.namespace, key, value,
+	)
+	return err
+}
+
+func (t *postgresTx) Delete(key string) error {
+	_, err := t.tx.Exec(
+		fmt.Sprintf(`DELETE FROM %s WHERE namespace = $1 AND key = $2`, t.b.table),
+		t.namespace, key,
+	)
+	return err
+}
+
+func (t *postgresTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *postgresTx) Rollback() error {
+	// Rolling back a committed transaction has no effect.
+	if err := t.tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		return err
+	}
+	return nil
+}
@@ -1,9 +1,12 @@
 package storage
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path"
 	"sync"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/influxdata/kapacitor/services/httpd"
@@ -12,15 +15,26 @@ import (
 
 type Diagnostic interface {
 	Error(msg string, err error)
+	EncryptedValues(namespace string, count int)
+	RotatedDataKey(id uint32, count int)
 }
 
 type Service struct {
-	dbpath string
+	config Config
 
+	backend Backend
+	// boltdb is the BoltDB file of the bolt backend, it is nil with external backends.
 	boltdb *bolt.DB
 	stores map[string]Interface
 	mu     sync.Mutex
 
+	encryptionEnabled    bool
+	encryptionKeyFile    string
+	encryptionKeyCommand []string
+	// keyring holds the data keys, it is nil if encryption is disabled.
+	keyring *keyring
+	dataKey dataKey
+
 	registrar StoreActionerRegistrar
 	apiServer *APIServer
 
@@ -36,33 +50,50 @@ type Service struct {
 
 func NewService(conf Config, d Diagnostic) *Service {
 	return &Service{
-		dbpath: conf.BoltDBPath,
+		config: conf,
 		diag:   d,
 		stores: make(map[string]Interface),
+
+		encryptionEnabled:    conf.EncryptionEnabled(),
+		encryptionKeyFile:    conf.EncryptionKeyFile,
+		encryptionKeyCommand: conf.EncryptionKeyCommand,
 	}
 }
 
 const (
 	versionsNamespace = "versions"
+
+	// The bucket holding the encrypted data key.
+	encryptionBucket = "encryption"
+	dataKeyKey       = "data_key"
 )
 
+// dataKey is the stored data key, encrypted with the key encryption key.
+type dataKey struct {
+	ID  uint32 `json:"id"`
+	Key []byte `json:"key"`
+	// Namespaces whose values are encrypted.
+	Namespaces []string  `json:"namespaces"`
+	Rotated    time.Time `json:"rotated"`
+}
+
 func (s *Service) Open() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	err := os.MkdirAll(path.Dir(s.dbpath), 0755)
-	if err != nil {
-		return errors.Wrapf(err, "mkdir dirs %q", s.dbpath)
+	if err := s.openBackend(); err != nil {
+		return err
 	}
-	db, err := bolt.Open(s.dbpath, 0600, nil)
-	if err != nil {
-		return errors.Wrapf(err, "open boltdb @ %q", s.dbpath)
+
+	if err := s.openEncryption(); err != nil {
+		s.backend.Close()
+		return err
 	}
-	s.boltdb = db
 
 	s.registrar = NewStorageResitrar()
 	s.apiServer = &APIServer{
 		DB:           s.boltdb,
 		Registrar:    s.registrar,
+		Rekeyer:      s,
 		HTTPDService: s.HTTPDService,
 		diag:         s.diag,
 	}
@@ -84,8 +115,38 @@ func (s *Service) Close() error {
 			return err
 		}
 	}
-	if s.boltdb != nil {
-		return s.boltdb.Close()
+	if s.backend != nil {
+		return s.backend.Close()
+	}
+	return nil
+}
+
+func (s *Service) openBackend() error {
+	switch s.config.Backend {
+	case EtcdBackend:
+		b, err := newEtcdBackend(s.config.Etcd)
+		if err != nil {
+			return errors.Wrap(err, "open etcd backend")
+		}
+		s.backend = b
+	case PostgresBackend:
+		b, err := newPostgresBackend(s.config.Postgres)
+		if err != nil {
+			return errors.Wrap(err, "open postgres backend")
+		}
+		s.backend = b
+	default:
+		dbpath := s.config.BoltDBPath
+		err := os.MkdirAll(path.Dir(dbpath), 0755)
+		if err != nil {
+			return errors.Wrapf(err, "mkdir dirs %q", dbpath)
+		}
+		db, err := bolt.Open(dbpath, 0600, nil)
+		if err != nil {
+			return errors.Wrapf(err, "open boltdb @ %q", dbpath)
+		}
+		s.boltdb = db
+		s.backend = &boltBackend{db: db}
 	}
 	return nil
 }
@@ -102,12 +163,292 @@ func (s *Service) store(name string) Interface {
 	if store, ok := s.stores[name]; ok {
 		return store
 	} else {
-		store = NewBolt(s.boltdb, name)
+		store = s.backend.Store(name)
 		s.stores[name] = store
 		return store
 	}
 }
 
+// EncryptedStore returns a namespaced store whose values are encrypted at rest,
+// if encryption is enabled. Otherwise it returns the same store as Store.
+// The existing values of the namespace are encrypted the first time it is requested.
+func (s *Service) EncryptedStore(name string) (Interface, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keyring == nil {
+		return s.store(name), nil
+	}
+	if store, ok := s.stores[name]; ok {
+		if _, ok := store.(*Encrypted); !ok {
+			return nil, fmt.Errorf("namespace %q is not encrypted", name)
+		}
+		return store, nil
+	}
+	if !s.encryptedNamespace(name) {
+		if err := s.encryptNamespace(name); err != nil {
+			return nil, errors.Wrapf(err, "failed to encrypt %q", name)
+		}
+	}
+	store := newEncrypted(s.backend.Store(name), name, s.keyring)
+	s.stores[name] = store
+	return store, nil
+}
+
+func (s *Service) encryptedNamespace(name string) bool {
+	for _, ns := range s.dataKey.Namespaces {
+		if ns == name {
+			return true
+		}
+	}
+	return false
+}
+
+// openEncryption loads the data key, creating it the first time encryption is enabled.
+func (s *Service) openEncryption() error {
+	stored, err := s.loadDataKey()
+	if err != nil {
+		return err
+	}
+	if !s.encryptionEnabled {
+		if stored != nil {
+			return errors.New("storage is encrypted, must configure 'encryption-key-file' or 'encryption-key-command'")
+		}
+		return nil
+	}
+	kek, err := loadKeyEncryptionKey(s.encryptionKeyFile, s.encryptionKeyCommand)
+	if err != nil {
+		return err
+	}
+	k := newKeyring()
+	if stored == nil {
+		key, err := newDataKey()
+		if err != nil {
+			return err
+		}
+		wrapped, err := wrapKey(kek, key)
+		if err != nil {
+			return err
+		}
+		stored = &dataKey{ID: 1, Key: wrapped, Rotated: time.Now().UTC()}
+		if err := s.backend.Update(func(tx BackendTx) error {
+			return putDataKey(tx, *stored)
+		}); err != nil {
+			return errors.Wrap(err, "failed to store data key")
+		}
+		if err := k.add(stored.ID, key); err != nil {
+			return err
+		}
+	} else {
+		key, err := unwrapKey(kek, stored.Key)
+		if err != nil {
+			return errors.Wrap(err, "failed to decrypt data key, the encryption key is not the key which encrypted the storage")
+		}
+		if err := k.add(stored.ID, key); err != nil {
+			return err
+		}
+	}
+	k.use(stored.ID)
+	s.keyring = k
+	s.dataKey = *stored
+	return nil
+}
+
+func (s *Service) loadDataKey() (*dataKey, error) {
+	var stored *dataKey
+	err := s.backend.Store(encryptionBucket).View(func(tx ReadOnlyTx) error {
+		kv, err := tx.Get(dataKeyKey)
+		if err == ErrNoKeyExists {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		stored = new(dataKey)
+		return json.Unmarshal(kv.Value, stored)
+	})
+	return stored, errors.Wrap(err, "failed to load data key")
+}
+
+func putDataKey(tx BackendTx, k dataKey) error {
+	data, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	return tx.Namespace(encryptionBucket).Put(dataKeyKey, data)
+}
+
+// encryptNamespace encrypts the existing values of the namespace with the current data key,
+// and records that the namespace is encrypted.
+// s.mu must be held.
+func (s *Service) encryptNamespace(name string) error {
+	stored := s.dataKey
+	stored.Namespaces = append(append([]string(nil), s.dataKey.Namespaces...), name)
+	var count int
+	err := s.backend.Update(func(tx BackendTx) error {
+		var err error
+		if count, err = s.reencrypt(tx, name, false); err != nil {
+			return err
+		}
+		return putDataKey(tx, stored)
+	})
+	if err != nil {
+		return err
+	}
+	s.dataKey = stored
+	if count > 0 {
+		s.diag.EncryptedValues(name, count)
+	}
+	return nil
+}
+
+// reencrypt encrypts the values of the namespace with the current data key,
+// and returns the number of values it encrypted.
+// Values which are already encrypted are only encrypted again if all is true.
+func (s *Service) reencrypt(tx BackendTx, name string, all bool) (int, error) {
+	ns := tx.Namespace(name)
+	list, err := ns.List("")
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, kv := range list {
+		if encrypted(kv.Value) && !all {
+			continue
+		}
+		value, err := s.keyring.decrypt(kv.Value, valueAD(name, kv.Key))
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to decrypt %q", kv.Key)
+		}
+		value, err = s.keyring.encrypt(value, valueAD(name, kv.Key))
+		if err != nil {
+			return 0, err
+		}
+		if err := ns.Put(kv.Key, value); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Rekey replaces the data key with a new key, and encrypts all the encrypted values again with it.
+// The new data key is encrypted with the key encryption key loaded again from its file or command,
+// so that it can be rotated too.
+// It returns the ID of the new data key and the number of values it encrypted.
+func (s *Service) Rekey() (uint32, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keyring == nil {
+		return 0, 0, ErrEncryptionDisabled
+	}
+	kek, err := loadKeyEncryptionKey(s.encryptionKeyFile, s.encryptionKeyCommand)
+	if err != nil {
+		return 0, 0, err
+	}
+	key, err := newDataKey()
+	if err != nil {
+		return 0, 0, err
+	}
+	wrapped, err := wrapKey(kek, key)
+	if err != nil {
+		return 0, 0, err
+	}
+	stored := s.dataKey
+	stored.ID++
+	stored.Key = wrapped
+	stored.Rotated = time.Now().UTC()
+	if err := s.keyring.add(stored.ID, key); err != nil {
+		return 0, 0, err
+	}
+
+	var count int
+	var prev uint32
+	var switched bool
+	err = s.backend.Update(func(tx BackendTx) error {
+		// Writes to encrypted stores happen in transactions serialized with this one,
+		// so they use the new data key from now on.
+		// Backends retrying conflicting transactions may run this function again.
+		if !switched {
+			prev, switched = s.keyring.use(stored.ID), true
+		}
+		count = 0
+		for _, name := range stored.Namespaces {
+			n, err := s.reencrypt(tx, name, true)
+			if err != nil {
+				return errors.Wrapf(err, "failed to encrypt %q", name)
+			}
+			count += n
+		}
+		return putDataKey(tx, stored)
+	})
+	if err != nil {
+		if switched {
+			s.keyring.use(prev)
+		}
+		return 0, 0, err
+	}
+	s.dataKey = stored
+	s.diag.RotatedDataKey(stored.ID, count)
+	return stored.ID, count, nil
+}
+
+// namespaceStore returns the store of the namespace, decrypting its values if they are encrypted.
+// Caller must have lock.
+func (s *Service) namespaceStore(name string) Interface {
+	if store, ok := s.stores[name]; ok {
+		return store
+	}
+	if s.keyring != nil && s.encryptedNamespace(name) {
+		store := newEncrypted(s.backend.Store(name), name, s.keyring)
+		s.stores[name] = store
+		return store
+	}
+	return s.store(name)
+}
+
+// Snapshot returns all the values of the namespace, decrypted if they are encrypted.
+func (s *Service) Snapshot(name string) ([]*KeyValue, error) {
+	s.mu.Lock()
+	store := s.namespaceStore(name)
+	s.mu.Unlock()
+	var kvs []*KeyValue
+	err := store.View(func(tx ReadOnlyTx) error {
+		var err error
+		kvs, err = tx.List("")
+		return err
+	})
+	return kvs, err
+}
+
+// Restore replaces all the values of the namespace with the values of a snapshot.
+func (s *Service) Restore(name string, kvs []*KeyValue) error {
+	s.mu.Lock()
+	store := s.namespaceStore(name)
+	s.mu.Unlock()
+	keep := make(map[string]bool, len(kvs))
+	for _, kv := range kvs {
+		keep[kv.Key] = true
+	}
+	return store.Update(func(tx Tx) error {
+		existing, err := tx.List("")
+		if err != nil {
+			return err
+		}
+		for _, kv := range existing {
+			if !keep[kv.Key] {
+				if err := tx.Delete(kv.Key); err != nil {
+					return err
+				}
+			}
+		}
+		for _, kv := range kvs {
+			if err := tx.Put(kv.Key, kv.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (s *Service) Versions() Versions {
 	return s.versions
 }
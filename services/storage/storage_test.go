@@ -21,8 +21,9 @@ type createStoreCloser func() (storeCloser, error)
 // stores is a map of all storage implementations,
 // each test will be run against the stores found in this map.
 var stores = map[string]createStoreCloser{
-	"bolt": newBolt,
-	"mem":  newMemStore,
+	"bolt":      newBolt,
+	"mem":       newMemStore,
+	"encrypted": newEncryptedService,
 }
 
 type storeCloser interface {
@@ -18,6 +18,10 @@ func (s TestStore) Store(name string) storage.Interface {
 	return storage.NewMemStore(name)
 }
 
+func (s TestStore) EncryptedStore(name string) (storage.Interface, error) {
+	return storage.NewMemStore(name), nil
+}
+
 func (s TestStore) Versions() storage.Versions {
 	return s.versions
 }
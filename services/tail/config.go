@@ -0,0 +1,117 @@
+package tail
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultPollInterval is how often files are checked for new lines and rotation.
+	DefaultPollInterval = time.Second
+
+	// DefaultFormat is the default line format.
+	DefaultFormat = "grok"
+
+	// DefaultMaxLineSize is the longest line read, longer lines are truncated.
+	DefaultMaxLineSize = 64 * 1024
+)
+
+type Config struct {
+	Enabled bool   `toml:"enabled"`
+	Name    string `toml:"name"`
+	// Files are glob patterns of the files to tail, i.e. /var/log/nginx/*.log.
+	Files []string `toml:"files"`
+	// FromBeginning reads files that exist at startup from the beginning instead of the end.
+	// Files created later are always read from the beginning.
+	FromBeginning bool `toml:"from-beginning"`
+	// PollInterval is how often files are checked for new lines and rotation.
+	PollInterval toml.Duration `toml:"poll-interval"`
+	MaxLineSize  int           `toml:"max-line-size"`
+
+	// Format of the lines, one of grok, regex or json.
+	Format string `toml:"format"`
+	// Pattern is the grok or regex pattern used to parse lines.
+	// Named captures become fields or tags.
+	Pattern string `toml:"pattern"`
+	// CustomPatterns are additional grok patterns, one per line as NAME PATTERN.
+	CustomPatterns string `toml:"custom-patterns"`
+
+	// Measurement is the name of the points.
+	Measurement string `toml:"measurement"`
+	// TagKeys are the captures or JSON keys stored as tags instead of fields.
+	TagKeys []string `toml:"tag-keys"`
+	// TimeKey is the capture or JSON key holding the timestamp.
+	// If empty the time the line was read is used.
+	TimeKey string `toml:"time-key"`
+	// TimeFormat is either unix, unix_ms, unix_us, unix_ns or a Go time layout.
+	// Defaults to RFC3339.
+	TimeFormat string `toml:"time-format"`
+	// PathTag is the tag holding the path of the file, empty to disable.
+	PathTag string `toml:"path-tag"`
+
+	Database        string `toml:"database"`
+	RetentionPolicy string `toml:"retention-policy"`
+}
+
+func NewConfig() Config {
+	return Config{
+		PollInterval: toml.Duration(DefaultPollInterval),
+		MaxLineSize:  DefaultMaxLineSize,
+		Format:       DefaultFormat,
+		PathTag:      "path",
+	}
+}
+
+// WithDefaults takes the given config and returns a new config with any required
+// default values set.
+func (c Config) WithDefaults() Config {
+	d := c
+	if d.PollInterval <= 0 {
+		d.PollInterval = toml.Duration(DefaultPollInterval)
+	}
+	if d.MaxLineSize <= 0 {
+		d.MaxLineSize = DefaultMaxLineSize
+	}
+	if d.Format == "" {
+		d.Format = DefaultFormat
+	}
+	if d.Measurement == "" {
+		d.Measurement = d.Name
+	}
+	return d
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Name == "" {
+		return errors.New("must specify a name")
+	}
+	if len(c.Files) == 0 {
+		return errors.New("must specify at least one file")
+	}
+	for _, f := range c.Files {
+		if _, err := filepath.Match(f, ""); err != nil {
+			return errors.Wrapf(err, "invalid file pattern %q", f)
+		}
+	}
+	if c.Database == "" {
+		return errors.New("must specify a database")
+	}
+	switch c.Format {
+	case "", "grok", "regex":
+		if c.Pattern == "" {
+			return fmt.Errorf("must specify a pattern for format %q", c.Format)
+		}
+	case "json":
+	default:
+		return fmt.Errorf("invalid format %q, must be one of grok, regex or json", c.Format)
+	}
+	_, err := newParser(c.WithDefaults())
+	return err
+}
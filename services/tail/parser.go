@@ -0,0 +1,187 @@
+package tail
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/pkg/errors"
+)
+
+// parser converts lines into points.
+type parser struct {
+	format      string
+	re          *regexp.Regexp
+	types       map[string]string
+	measurement string
+	tagKeys     map[string]bool
+	timeKey     string
+	timeFormat  string
+}
+
+func newParser(c Config) (*parser, error) {
+	p := &parser{
+		format:      c.Format,
+		types:       make(map[string]string),
+		measurement: c.Measurement,
+		tagKeys:     make(map[string]bool, len(c.TagKeys)),
+		timeKey:     c.TimeKey,
+		timeFormat:  c.TimeFormat,
+	}
+	for _, k := range c.TagKeys {
+		p.tagKeys[k] = true
+	}
+	var err error
+	switch c.Format {
+	case "grok":
+		p.re, p.types, err = compileGrok(c.Pattern, c.CustomPatterns)
+	case "regex":
+		p.re, err = regexp.Compile(c.Pattern)
+		if err != nil {
+			err = errors.Wrapf(err, "invalid regex pattern %q", c.Pattern)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for k, t := range p.types {
+		if t == "tag" {
+			p.tagKeys[k] = true
+		}
+	}
+	return p, nil
+}
+
+// errNoMatch is returned for lines that do not match the pattern.
+var errNoMatch = errors.New("line does not match pattern")
+
+// parse converts the line into a point.
+// Lines without any fields are skipped by returning a nil point.
+func (p *parser) parse(line string, now time.Time, tags map[string]string) (models.Point, error) {
+	values, err := p.values(line)
+	if err != nil {
+		return nil, err
+	}
+
+	t := now
+	fields := make(models.Fields, len(values))
+	pointTags := make(map[string]string, len(tags)+len(p.tagKeys))
+	for k, v := range tags {
+		pointTags[k] = v
+	}
+	for k, v := range values {
+		switch {
+		case k == p.timeKey:
+			t, err = p.parseTime(v)
+			if err != nil {
+				return nil, err
+			}
+		case p.tagKeys[k]:
+			if s := fmt.Sprint(v); s != "" {
+				pointTags[k] = s
+			}
+		default:
+			if fv := p.fieldValue(k, v); fv != nil {
+				fields[k] = fv
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return models.NewPoint(p.measurement, models.NewTags(pointTags), fields, t)
+}
+
+// values extracts the named values from the line.
+func (p *parser) values(line string) (map[string]interface{}, error) {
+	if p.format == "json" {
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			return nil, errors.Wrap(err, "invalid JSON")
+		}
+		return obj, nil
+	}
+	m := p.re.FindStringSubmatch(line)
+	if m == nil {
+		return nil, errNoMatch
+	}
+	values := make(map[string]interface{})
+	for i, name := range p.re.SubexpNames() {
+		if name == "" || m[i] == "" {
+			continue
+		}
+		values[name] = m[i]
+	}
+	return values, nil
+}
+
+// fieldValue converts captured strings using their grok type,
+// untyped captures are stored as numbers if they can be parsed as such.
+func (p *parser) fieldValue(k string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		switch p.types[k] {
+		case "int":
+			if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return i
+			}
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return int64(f)
+			}
+			return nil
+		case "float":
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f
+			}
+			return nil
+		case "string":
+			return v
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+		return v
+	case float64, bool:
+		return v
+	case nil:
+		return nil
+	default:
+		// Nested JSON objects and arrays are not supported as field values.
+		return nil
+	}
+}
+
+func (p *parser) parseTime(v interface{}) (time.Time, error) {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return time.Time{}, fmt.Errorf("invalid time value %v", v)
+	}
+	var unit time.Duration
+	switch p.timeFormat {
+	case "unix":
+		unit = time.Second
+	case "unix_ms":
+		unit = time.Millisecond
+	case "unix_us":
+		unit = time.Microsecond
+	case "unix_ns":
+		unit = time.Nanosecond
+	case "":
+		return time.Parse(time.RFC3339Nano, s)
+	default:
+		return time.Parse(p.timeFormat, s)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time value %q", s)
+	}
+	return time.Unix(0, int64(f*float64(unit))).UTC(), nil
+}
@@ -0,0 +1,310 @@
+package tail
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/server/vars"
+)
+
+// statistics gathered by the tail service.
+const (
+	statFilesTailed       = "files_tailed"
+	statLinesRead         = "lines_rx"
+	statLinesParseFail    = "lines_parse_fail"
+	statRotations         = "rotations"
+	statPointsTransmitted = "points_tx"
+	statTransmitFail      = "tx_fail"
+)
+
+type Diagnostic interface {
+	Error(msg string, err error, ctx ...keyvalue.T)
+	TailingFile(path string)
+	StoppedTailingFile(path string)
+	ClosedService()
+}
+
+// Service tails files matching glob patterns and parses each line into a point.
+// Files are polled for new data, rotation and truncation.
+type Service struct {
+	config Config
+	parser *parser
+
+	files map[string]*tailedFile
+
+	wg      sync.WaitGroup
+	closing chan struct{}
+
+	PointsWriter interface {
+		WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+	}
+
+	diag    Diagnostic
+	statMap *expvar.Map
+	statKey string
+}
+
+type tailedFile struct {
+	path string
+	f    *os.File
+	info os.FileInfo
+	// partial holds data read after the last newline.
+	partial []byte
+	// discarding is true while skipping the rest of a line longer than the max line size.
+	discarding bool
+}
+
+func NewService(c Config, d Diagnostic) *Service {
+	return &Service{
+		config: c.WithDefaults(),
+		files:  make(map[string]*tailedFile),
+		diag:   d,
+	}
+}
+
+func (s *Service) Open() error {
+	p, err := newParser(s.config)
+	if err != nil {
+		return err
+	}
+	s.parser = p
+	s.statKey, s.statMap = vars.NewStatistic("tail", map[string]string{"name": s.config.Name})
+	s.closing = make(chan struct{})
+
+	// Files that exist at startup are read from the end unless configured otherwise.
+	s.discover(!s.config.FromBeginning)
+
+	s.wg.Add(1)
+	go s.run()
+	return nil
+}
+
+func (s *Service) Close() error {
+	if s.closing == nil {
+		return nil
+	}
+	close(s.closing)
+	s.wg.Wait()
+	s.closing = nil
+	for path, tf := range s.files {
+		tf.f.Close()
+		delete(s.files, path)
+	}
+	vars.DeleteStatistic(s.statKey)
+	s.diag.ClosedService()
+	return nil
+}
+
+func (s *Service) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(time.Duration(s.config.PollInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.closing:
+			return
+		case <-ticker.C:
+			s.poll()
+		}
+	}
+}
+
+// poll discovers new files and reads new lines from all tailed files.
+func (s *Service) poll() {
+	s.discover(false)
+	paths := make([]string, 0, len(s.files))
+	for path := range s.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		s.read(s.files[path])
+		s.checkRotation(s.files[path])
+	}
+}
+
+// discover starts tailing any new files matching the configured patterns.
+func (s *Service) discover(fromEnd bool) {
+	for _, pattern := range s.config.Files {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			s.diag.Error("invalid file pattern", err, keyvalue.KV("pattern", pattern))
+			continue
+		}
+		for _, path := range matches {
+			if _, ok := s.files[path]; ok {
+				continue
+			}
+			tf, err := s.open(path, fromEnd)
+			if err != nil {
+				s.diag.Error("failed to open file", err, keyvalue.KV("path", path))
+				continue
+			}
+			if tf != nil {
+				s.files[path] = tf
+			}
+		}
+	}
+}
+
+func (s *Service) open(path string, fromEnd bool) (*tailedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, nil
+	}
+	if fromEnd {
+		if _, err := f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	s.statMap.Add(statFilesTailed, 1)
+	s.diag.TailingFile(path)
+	return &tailedFile{path: path, f: f, info: info}, nil
+}
+
+// checkRotation reopens files that have been replaced and rewinds truncated files.
+// Removed files are no longer tailed.
+func (s *Service) checkRotation(tf *tailedFile) {
+	info, err := os.Stat(tf.path)
+	if err != nil {
+		tf.f.Close()
+		delete(s.files, tf.path)
+		s.statMap.Add(statFilesTailed, -1)
+		s.diag.StoppedTailingFile(tf.path)
+		return
+	}
+	if !os.SameFile(tf.info, info) {
+		// The file was rotated, all remaining data of the old file has been read.
+		s.statMap.Add(statRotations, 1)
+		tf.f.Close()
+		delete(s.files, tf.path)
+		s.statMap.Add(statFilesTailed, -1)
+		ntf, err := s.open(tf.path, false)
+		if err != nil {
+			s.diag.Error("failed to open rotated file", err, keyvalue.KV("path", tf.path))
+			return
+		}
+		if ntf != nil {
+			s.files[tf.path] = ntf
+			s.read(ntf)
+		}
+		return
+	}
+	offset, err := tf.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return
+	}
+	if info.Size() < offset {
+		// The file was truncated in place, start over.
+		s.statMap.Add(statRotations, 1)
+		if _, err := tf.f.Seek(0, io.SeekStart); err != nil {
+			s.diag.Error("failed to rewind truncated file", err, keyvalue.KV("path", tf.path))
+			return
+		}
+		tf.partial = nil
+		tf.discarding = false
+		s.read(tf)
+	}
+}
+
+// read reads all complete lines available in the file and writes the parsed points.
+func (s *Service) read(tf *tailedFile) {
+	buf := make([]byte, 32*1024)
+	var points []models.Point
+	now := time.Now().UTC()
+	var tags map[string]string
+	if s.config.PathTag != "" {
+		tags = map[string]string{s.config.PathTag: tf.path}
+	}
+	for {
+		n, err := tf.f.Read(buf)
+		data := buf[:n]
+		for len(data) > 0 {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				if !tf.discarding {
+					tf.partial = append(tf.partial, data...)
+					if len(tf.partial) > s.config.MaxLineSize {
+						points = s.appendLine(points, tf.partial[:s.config.MaxLineSize], now, tags)
+						tf.partial = nil
+						tf.discarding = true
+					}
+				}
+				break
+			}
+			if tf.discarding {
+				tf.discarding = false
+			} else {
+				line := append(tf.partial, data[:i]...)
+				if len(line) > s.config.MaxLineSize {
+					line = line[:s.config.MaxLineSize]
+				}
+				points = s.appendLine(points, line, now, tags)
+			}
+			tf.partial = nil
+			data = data[i+1:]
+		}
+		if err != nil || n == 0 {
+			if err != nil && err != io.EOF {
+				s.diag.Error("failed to read file", err, keyvalue.KV("path", tf.path))
+			}
+			break
+		}
+	}
+	s.write(points)
+}
+
+func (s *Service) appendLine(points []models.Point, line []byte, now time.Time, tags map[string]string) []models.Point {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) == 0 {
+		return points
+	}
+	s.statMap.Add(statLinesRead, 1)
+	p, err := s.parser.parse(string(line), now, tags)
+	if err != nil {
+		s.statMap.Add(statLinesParseFail, 1)
+		if err != errNoMatch {
+			s.diag.Error("failed to parse line", err)
+		}
+		return points
+	}
+	if p == nil {
+		return points
+	}
+	return append(points, p)
+}
+
+func (s *Service) write(points []models.Point) {
+	if len(points) == 0 {
+		return
+	}
+	if err := s.PointsWriter.WritePoints(
+		s.config.Database,
+		s.config.RetentionPolicy,
+		models.ConsistencyLevelAll,
+		points,
+	); err != nil {
+		s.statMap.Add(statTransmitFail, 1)
+		s.diag.Error("failed to write points to database", err, keyvalue.KV("database", s.config.Database))
+		return
+	}
+	s.statMap.Add(statPointsTransmitted, int64(len(points)))
+}
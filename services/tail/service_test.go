@@ -0,0 +1,110 @@
+package tail_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/services/tail"
+)
+
+func TestService(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kapacitor-tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	if err := ioutil.WriteFile(path, []byte("old GET 500 1.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := tail.NewConfig()
+	c.Enabled = true
+	c.Name = "requests"
+	c.Files = []string{filepath.Join(dir, "*.log")}
+	c.PollInterval = toml.Duration(10 * time.Millisecond)
+	c.Pattern = `%{WORD:host:tag} %{WORD:verb:tag} %{INT:status:int} %{NUMBER:duration:float}`
+	c.PathTag = ""
+	c.Database = "db"
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	pw := &pointsWriter{points: make(chan []models.Point, 10)}
+	s := tail.NewService(c, diag{})
+	s.PointsWriter = pw
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	appendLines(t, path, "a GET 200 0.5\nb POST 4")
+	expectPoints(t, pw, "requests,host=a,verb=GET duration=0.5,status=200i")
+	appendLines(t, path, "04 1.5\nnot a match\n")
+	expectPoints(t, pw, "requests,host=b,verb=POST duration=1.5,status=404i")
+
+	// Rotate the file, the new file must be read from the beginning.
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendLines(t, path, "c PUT 201 2\n")
+	expectPoints(t, pw, "requests,host=c,verb=PUT duration=2,status=201i")
+
+	// Truncate the file in place, truncation is detected when the file shrinks.
+	if err := ioutil.WriteFile(path, []byte("d GET 2 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectPoints(t, pw, "requests,host=d,verb=GET duration=3,status=2i")
+}
+
+func appendLines(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectPoints(t *testing.T, pw *pointsWriter, exp ...string) {
+	t.Helper()
+	select {
+	case points := <-pw.points:
+		if len(points) != len(exp) {
+			t.Fatalf("unexpected number of points: got %d exp %d", len(points), len(exp))
+		}
+		for i, p := range points {
+			// Ignore the timestamp, it is the time the line was read.
+			if got := p.String(); got[:len(got)-20] != exp[i] {
+				t.Errorf("unexpected point %d:\ngot %s\nexp %s", i, got, exp[i])
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for points")
+	}
+}
+
+type pointsWriter struct {
+	points chan []models.Point
+}
+
+func (pw *pointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	pw.points <- points
+	return nil
+}
+
+type diag struct{}
+
+func (diag) Error(msg string, err error, ctx ...keyvalue.T) {}
+func (diag) TailingFile(path string)                        {}
+func (diag) StoppedTailingFile(path string)                 {}
+func (diag) ClosedService()                                 {}
@@ -0,0 +1,250 @@
+package task_store
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/server/vars"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/pkg/errors"
+)
+
+// bulkAction applies a change to a single task.
+// It returns a function that reverts the change, nil if nothing was changed.
+type bulkAction func(task Task) (undo func() error, err error)
+
+func (ts *Service) handleBulkTasks(w http.ResponseWriter, r *http.Request) {
+	opts := client.BulkTasksOptions{}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&opts); err != nil {
+		httpd.HttpError(w, "invalid JSON", true, http.StatusBadRequest)
+		return
+	}
+
+	var action bulkAction
+	switch opts.Action {
+	case client.BulkEnable:
+		action = ts.bulkEnable
+	case client.BulkDisable:
+		action = ts.bulkDisable
+	case client.BulkReload:
+		action = ts.bulkReload
+	case client.BulkDelete:
+		action = ts.bulkDelete
+	default:
+		httpd.HttpError(w, fmt.Sprintf("invalid action %q, must be one of enable, disable, reload or delete", opts.Action), true, http.StatusBadRequest)
+		return
+	}
+	if len(opts.Patterns) == 0 && opts.Selector == "" {
+		httpd.HttpError(w, "must specify at least one pattern or a selector", true, http.StatusBadRequest)
+		return
+	}
+	sel, err := parseSelector(opts.Selector)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	patterns := opts.Patterns
+	if len(patterns) == 0 {
+		patterns = []string{"*"}
+	}
+	tasks, err := ts.matchTasks(patterns, sel)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	result := client.BulkTasksResult{
+		Action: opts.Action,
+		Tasks:  make([]string, len(tasks)),
+	}
+	for i, task := range tasks {
+		result.Tasks[i] = task.ID
+	}
+
+	// Check that every task can be started before changing any of them.
+	if opts.Action == client.BulkEnable || opts.Action == client.BulkReload {
+		for _, task := range tasks {
+			if _, err := ts.newKapacitorTask(task); err != nil {
+				result.Errors = append(result.Errors, client.BulkTaskError{ID: task.ID, Error: err.Error()})
+			}
+		}
+	}
+
+	if len(result.Errors) == 0 {
+		undos := make([]func() error, 0, len(tasks))
+		for _, task := range tasks {
+			undo, err := action(task)
+			if err != nil {
+				result.Errors = append(result.Errors, client.BulkTaskError{ID: task.ID, Error: err.Error()})
+				break
+			}
+			undos = append(undos, undo)
+		}
+		if len(result.Errors) > 0 {
+			// Rollback in reverse order
+			for i := len(undos) - 1; i >= 0; i-- {
+				if undos[i] == nil {
+					continue
+				}
+				if err := undos[i](); err != nil {
+					ts.diag.Error("error rolling back bulk task change", err, keyvalue.KV("task", tasks[i].ID))
+				}
+			}
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		w.WriteHeader(http.StatusConflict)
+		w.Write(httpd.MarshalJSON(result, true))
+		return
+	}
+
+	if opts.Action == client.BulkDelete {
+		// Snapshots and versions are only deleted once no rollback is possible.
+		for _, task := range tasks {
+			ts.snapshots.Delete(task.ID)
+			if err := ts.versions.Delete(task.ID); err != nil {
+				ts.diag.Error("failed to delete task versions", err, keyvalue.KV("task", task.ID))
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(result, true))
+}
+
+// matchTasks returns all tasks matching any of the patterns and the selector,
+// each task at most once.
+func (ts *Service) matchTasks(patterns []string, sel selector) ([]Task, error) {
+	var matched []Task
+	seen := make(map[string]bool)
+	limit := 100
+	for _, pattern := range patterns {
+		offset := 0
+		for {
+			tasks, err := ts.tasks.List(pattern, offset, limit)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to list tasks with pattern %q", pattern)
+			}
+			for _, task := range tasks {
+				if !seen[task.ID] && sel.Matches(task.Labels) {
+					seen[task.ID] = true
+					matched = append(matched, task)
+				}
+			}
+			if len(tasks) != limit {
+				break
+			}
+			offset += limit
+		}
+	}
+	return matched, nil
+}
+
+func (ts *Service) bulkEnable(task Task) (func() error, error) {
+	if task.Status == Enabled {
+		return nil, nil
+	}
+	return ts.enableTask(task)
+}
+
+func (ts *Service) bulkDisable(task Task) (func() error, error) {
+	if task.Status == Disabled {
+		return nil, nil
+	}
+	updated := task
+	updated.Status = Disabled
+	updated.Modified = time.Now()
+	if err := ts.tasks.Replace(updated); err != nil {
+		return nil, err
+	}
+	vars.NumEnabledTasksVar.Add(-1)
+	ts.stopTask(task.ID)
+	return func() error {
+		if err := ts.tasks.Replace(task); err != nil {
+			return err
+		}
+		vars.NumEnabledTasksVar.Add(1)
+		return ts.startTask(task)
+	}, nil
+}
+
+// bulkReload restarts the task, disabled tasks are enabled.
+func (ts *Service) bulkReload(task Task) (func() error, error) {
+	if task.Status == Disabled {
+		return ts.enableTask(task)
+	}
+	ts.stopTask(task.ID)
+	if err := ts.startTask(task); err != nil {
+		return nil, err
+	}
+	return func() error {
+		ts.stopTask(task.ID)
+		return ts.startTask(task)
+	}, nil
+}
+
+func (ts *Service) bulkDelete(task Task) (func() error, error) {
+	if task.TemplateID != "" {
+		if err := ts.templates.DisassociateTask(task.TemplateID, task.ID); err != nil {
+			return nil, errors.Wrap(err, "failed to disassociate task from template")
+		}
+	}
+	if err := ts.tasks.Delete(task.ID); err != nil {
+		if task.TemplateID != "" {
+			ts.templates.AssociateTask(task.TemplateID, task.ID)
+		}
+		return nil, err
+	}
+	vars.NumTasksVar.Add(-1)
+	if task.Status == Enabled {
+		vars.NumEnabledTasksVar.Add(-1)
+		ts.TaskMasterLookup.Main().DeleteTask(task.ID)
+		ts.setPaused(task.ID, false)
+	}
+	return func() error {
+		if err := ts.tasks.Create(task); err != nil {
+			return err
+		}
+		vars.NumTasksVar.Add(1)
+		if task.TemplateID != "" {
+			if err := ts.templates.AssociateTask(task.TemplateID, task.ID); err != nil {
+				return err
+			}
+		}
+		if task.Status == Enabled {
+			vars.NumEnabledTasksVar.Add(1)
+			return ts.startTask(task)
+		}
+		return nil
+	}, nil
+}
+
+// enableTask enables and starts a disabled task.
+func (ts *Service) enableTask(task Task) (func() error, error) {
+	updated := task
+	updated.Status = Enabled
+	now := time.Now()
+	updated.Modified = now
+	updated.LastEnabled = now
+	if err := ts.tasks.Replace(updated); err != nil {
+		return nil, err
+	}
+	if err := ts.startTask(updated); err != nil {
+		if rerr := ts.tasks.Replace(task); rerr != nil {
+			ts.diag.Error("error rolling back bulk task change", rerr, keyvalue.KV("task", task.ID))
+		}
+		return nil, err
+	}
+	vars.NumEnabledTasksVar.Add(1)
+	return func() error {
+		ts.stopTask(task.ID)
+		vars.NumEnabledTasksVar.Add(-1)
+		return ts.tasks.Replace(task)
+	}, nil
+}
@@ -0,0 +1,64 @@
+package task_store
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+)
+
+const (
+	cardinalityPath = "cardinality"
+
+	defaultCardinalityTopK = 10
+)
+
+// handleTaskCardinality serves the live cardinality of the measurements read by a stream task,
+// with the k tags and tag values of each measurement with the most series.
+func (ts *Service) handleTaskCardinality(w http.ResponseWriter, r *http.Request, id string) {
+	if _, err := ts.tasks.Get(id); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+
+	k := defaultCardinalityTopK
+	if s := r.URL.Query().Get("k"); s != "" {
+		var err error
+		k, err = strconv.Atoi(s)
+		if err != nil || k <= 0 {
+			httpd.HttpError(w, fmt.Sprintf("invalid k %q must be a positive integer", s), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	c := client.TaskCardinality{
+		Link:         client.Link{Relation: client.Self, Href: path.Join(httpd.BasePath, tasksPath, id, cardinalityPath)},
+		ID:           id,
+		Measurements: []client.MeasurementCardinality{},
+	}
+	measurements, tracked := ts.TaskMasterLookup.Main().Cardinality(id, k)
+	c.Tracked = tracked
+	for _, m := range measurements {
+		cm := client.MeasurementCardinality{
+			Measurement: m.Measurement,
+			Series:      m.Series,
+			MaxSeries:   m.MaxSeries,
+			Dropped:     m.Dropped,
+			Sampled:     m.Sampled,
+			TopTags:     make([]client.TagCardinality, len(m.TopTags)),
+			TopValues:   make([]client.TagValueCardinality, len(m.TopValues)),
+		}
+		for i, t := range m.TopTags {
+			cm.TopTags[i] = client.TagCardinality{Tag: t.Tag, Values: t.Values}
+		}
+		for i, v := range m.TopValues {
+			cm.TopValues[i] = client.TagValueCardinality{Tag: v.Tag, Value: v.Value, Series: v.Series}
+		}
+		c.Measurements = append(c.Measurements, cm)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(c, true))
+}
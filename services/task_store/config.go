@@ -1,24 +1,156 @@
 package task_store
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor"
 )
 
 type Config struct {
 	// Deprecated, only needed to find old db and migrate
 	Dir              string        `toml:"dir"`
 	SnapshotInterval toml.Duration `toml:"snapshot-interval"`
+	// Whether to save the node states of the tasks, e.g. partially filled windows,
+	// when the server shuts down so that the tasks resume from them on the next start.
+	SnapshotOnShutdown bool `toml:"snapshot-on-shutdown"`
+	// Number of versions of each task definition to keep, 0 keeps all versions.
+	MaxVersions int `toml:"max-versions"`
+	// Default limits of tasks that do not set their own, 0 means no limit.
+	MaxPointsPerSecond int64 `toml:"max-points-per-second"`
+	MaxGroups          int64 `toml:"max-groups"`
+	MaxBufferedPoints  int64 `toml:"max-buffered-points"`
+	// Number of points queued for a stream task above which writes to the databases
+	// and retention policies of the task are rejected, 0 disables backpressure.
+	QueueThreshold int64 `toml:"queue-threshold"`
+	// Time after which the writers should retry rejected writes.
+	BackpressureRetryAfter toml.Duration `toml:"backpressure-retry-after"`
+	// Whether to merge the identical branches of tasks and move filters ahead of other nodes.
+	OptimizePipelines bool `toml:"optimize-pipelines"`
+	// Maximum delay of the batch queries of each query node,
+	// spreading the queries of the tasks scheduled at the same time.
+	BatchQueryJitter toml.Duration `toml:"batch-query-jitter"`
+	// Maximum number of concurrent batch queries of each InfluxDB cluster, 0 means no limit.
+	MaxConcurrentQueries int `toml:"max-concurrent-queries"`
+
+	Cardinality CardinalityConfig `toml:"cardinality"`
+}
+
+// CardinalityConfig limits the live series of each measurement read by a stream task.
+type CardinalityConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Maximum number of live series of a measurement, 0 means no limit.
+	MaxSeries int64 `toml:"max-series"`
+	// Action for the points of new series above the limit, one of drop, sample or error.
+	Action string `toml:"action"`
+	// One of every sample-every points above the limit is kept with the sample action.
+	SampleEvery int64 `toml:"sample-every"`
+	// Time after which a series without points is no longer live.
+	SeriesTTL toml.Duration `toml:"series-ttl"`
+	// Limits of specific measurements.
+	Measurements []MeasurementCardinalityConfig `toml:"measurement"`
+}
+
+type MeasurementCardinalityConfig struct {
+	Name      string `toml:"name"`
+	MaxSeries int64  `toml:"max-series"`
+}
+
+func (c CardinalityConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.MaxSeries < 0 {
+		return errors.New("max-series must not be negative")
+	}
+	switch c.Action {
+	case kapacitor.CardinalityDrop, kapacitor.CardinalityError:
+	case kapacitor.CardinalitySample:
+		if c.SampleEvery <= 0 {
+			return errors.New("sample-every must be positive")
+		}
+	default:
+		return fmt.Errorf("invalid action %q, must be one of %q, %q or %q", c.Action, kapacitor.CardinalityDrop, kapacitor.CardinalitySample, kapacitor.CardinalityError)
+	}
+	if c.SeriesTTL <= 0 {
+		return errors.New("series-ttl must be positive")
+	}
+	names := make(map[string]bool, len(c.Measurements))
+	for _, m := range c.Measurements {
+		if m.Name == "" {
+			return errors.New("must specify measurement name")
+		}
+		if names[m.Name] {
+			return fmt.Errorf("duplicate measurement %q", m.Name)
+		}
+		names[m.Name] = true
+		if m.MaxSeries < 0 {
+			return fmt.Errorf("max-series of measurement %q must not be negative", m.Name)
+		}
+	}
+	return nil
+}
+
+// Limits returns the cardinality limits of the stream tasks.
+func (c CardinalityConfig) Limits() kapacitor.CardinalityLimits {
+	l := kapacitor.CardinalityLimits{
+		Enabled:      c.Enabled,
+		MaxSeries:    c.MaxSeries,
+		Measurements: make(map[string]int64, len(c.Measurements)),
+		Action:       c.Action,
+		SampleEvery:  c.SampleEvery,
+		SeriesTTL:    time.Duration(c.SeriesTTL),
+	}
+	for _, m := range c.Measurements {
+		l.Measurements[m.Name] = m.MaxSeries
+	}
+	return l
 }
 
 func NewConfig() Config {
 	return Config{
-		Dir:              "./tasks",
-		SnapshotInterval: toml.Duration(time.Minute),
+		Dir:                    "./tasks",
+		SnapshotInterval:       toml.Duration(time.Minute),
+		MaxVersions:            10,
+		BackpressureRetryAfter: toml.Duration(time.Second),
+		Cardinality: CardinalityConfig{
+			MaxSeries:   10000,
+			Action:      kapacitor.CardinalityDrop,
+			SampleEvery: 100,
+			SeriesTTL:   toml.Duration(10 * time.Minute),
+		},
 	}
 }
 
 func (c Config) Validate() error {
+	if c.MaxVersions < 0 {
+		return errors.New("max-versions must not be negative")
+	}
+	if c.MaxPointsPerSecond < 0 {
+		return errors.New("max-points-per-second must not be negative")
+	}
+	if c.MaxGroups < 0 {
+		return errors.New("max-groups must not be negative")
+	}
+	if c.MaxBufferedPoints < 0 {
+		return errors.New("max-buffered-points must not be negative")
+	}
+	if c.QueueThreshold < 0 {
+		return errors.New("queue-threshold must not be negative")
+	}
+	if c.QueueThreshold > 0 && c.BackpressureRetryAfter <= 0 {
+		return errors.New("backpressure-retry-after must be positive")
+	}
+	if c.BatchQueryJitter < 0 {
+		return errors.New("batch-query-jitter must not be negative")
+	}
+	if c.MaxConcurrentQueries < 0 {
+		return errors.New("max-concurrent-queries must not be negative")
+	}
+	if err := c.Cardinality.Validate(); err != nil {
+		return fmt.Errorf("cardinality: %v", err)
+	}
 	return nil
 }
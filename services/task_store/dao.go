@@ -17,6 +17,7 @@ var (
 	ErrTemplateExists   = errors.New("template already exists")
 	ErrNoTemplateExists = errors.New("no template exists")
 	ErrNoSnapshotExists = errors.New("no snapshot exists")
+	ErrNoVersionExists  = errors.New("no task version exists")
 )
 
 // Data access object for Task data.
@@ -90,6 +91,25 @@ type SnapshotDAO interface {
 	Exists(id string) (bool, error)
 }
 
+// Data access object for the version history of task definitions.
+type VersionDAO interface {
+	// Retrieve a version of a task.
+	// ErrNoVersionExists is returned if the version does not exist.
+	Get(taskID string, version int) (TaskVersion, error)
+	// Retrieve the most recent version of a task.
+	// ErrNoVersionExists is returned if the task has no versions.
+	Latest(taskID string) (TaskVersion, error)
+	// Save a new version of a task, numbered one more than the most recent version.
+	// Once a task has more than max versions the oldest are deleted, unless max is 0.
+	Add(v TaskVersion, max int) (TaskVersion, error)
+	// List all versions of a task, oldest first.
+	List(taskID string) ([]TaskVersion, error)
+	// Move all versions of a task to a new task ID.
+	Move(oldID, newID string) error
+	// Delete all versions of a task.
+	Delete(taskID string) error
+}
+
 //--------------------------------------------------------------------
 // The following structures are stored in a database via gob encoding.
 // Changes to the structures could break existing data.
@@ -127,6 +147,18 @@ type Task struct {
 	TemplateID string
 	// Set of vars for a templated task
 	Vars map[string]Var
+	// Arbitrary key/value pairs used to select tasks
+	Labels map[string]string
+	// Times the task is active when enabled, always if nil
+	Schedule *Schedule
+	// Resource limits of the task, zero values use the default limits
+	Limits Limits
+	// Policy for restarting the task after errors, never restarted if nil
+	Restart *RestartPolicy
+	// IDs of the batch tasks whose output this batch task queries
+	DependsOn []string
+	// Stream tasks with a higher priority receive points first
+	Priority int
 	// Last error the task had either while defining or executing.
 	Error string
 	// Status of the task
@@ -141,6 +173,24 @@ type Task struct {
 
 type rawTask Task
 
+// TaskVersion is the definition of a task at some point in time.
+type TaskVersion struct {
+	TaskID  string
+	Version int
+	// The task type (stream|batch).
+	Type TaskType
+	// The DBs and RPs the task is allowed to access.
+	DBRPs []DBRP
+	// The TICKscript for the task.
+	TICKscript string
+	// ID of task template
+	TemplateID string
+	// Set of vars for a templated task
+	Vars map[string]Var
+	// The time the version was created
+	Created time.Time
+}
+
 func (t Task) ObjectID() string {
 	return t.ID
 }
@@ -170,6 +220,8 @@ type Template struct {
 	Created time.Time
 	// The time the task was last modified
 	Modified time.Time
+	// Constraints on the values of the template vars, by var name.
+	Schema map[string]VarSchema
 }
 
 type DBRP struct {
@@ -177,8 +229,15 @@ type DBRP struct {
 	RetentionPolicy string
 }
 
+type Limits struct {
+	MaxPointsPerSecond int64
+	MaxGroups          int64
+	MaxBufferedPoints  int64
+}
+
 type Snapshot struct {
 	NodeSnapshots map[string][]byte
+	Checkpoint    string
 }
 
 // Key/Value store based implementation of the TaskDAO
@@ -552,6 +611,166 @@ func (d *snapshotKV) Get(id string) (snap *Snapshot, err error) {
 	return
 }
 
+const (
+	versionDataPrefix = "/versions/data/"
+)
+
+// Key/Value implementation of VersionDAO
+type versionKV struct {
+	store storage.Interface
+}
+
+func newVersionKV(store storage.Interface) *versionKV {
+	return &versionKV{
+		store: store,
+	}
+}
+
+func (d *versionKV) encodeVersion(v TaskVersion) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := enc.Encode(v)
+	return buf.Bytes(), err
+}
+
+func (d *versionKV) decodeVersion(data []byte) (TaskVersion, error) {
+	var v TaskVersion
+	dec := gob.NewDecoder(bytes.NewReader(data))
+	err := dec.Decode(&v)
+	return v, err
+}
+
+func (d *versionKV) versionPrefix(taskID string) string {
+	return versionDataPrefix + taskID + "/"
+}
+
+// Versions are zero padded so that keys sort in version order.
+func (d *versionKV) versionDataKey(taskID string, version int) string {
+	return fmt.Sprintf("%s%010d", d.versionPrefix(taskID), version)
+}
+
+func (d *versionKV) list(tx storage.ReadOperator, taskID string) ([]TaskVersion, error) {
+	kvs, err := tx.List(d.versionPrefix(taskID))
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]TaskVersion, len(kvs))
+	for i, kv := range kvs {
+		versions[i], err = d.decodeVersion(kv.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return versions, nil
+}
+
+func (d *versionKV) Get(taskID string, version int) (v TaskVersion, err error) {
+	err = d.store.View(func(tx storage.ReadOnlyTx) error {
+		data, err := tx.Get(d.versionDataKey(taskID, version))
+		if err != nil {
+			if err == storage.ErrNoKeyExists {
+				return ErrNoVersionExists
+			}
+			return err
+		}
+		v, err = d.decodeVersion(data.Value)
+		return err
+	})
+	return
+}
+
+func (d *versionKV) Latest(taskID string) (v TaskVersion, err error) {
+	err = d.store.View(func(tx storage.ReadOnlyTx) error {
+		versions, err := d.list(tx, taskID)
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			return ErrNoVersionExists
+		}
+		v = versions[len(versions)-1]
+		return nil
+	})
+	return
+}
+
+func (d *versionKV) Add(v TaskVersion, max int) (TaskVersion, error) {
+	err := d.store.Update(func(tx storage.Tx) error {
+		versions, err := d.list(tx, v.TaskID)
+		if err != nil {
+			return err
+		}
+		v.Version = 1
+		if len(versions) > 0 {
+			v.Version = versions[len(versions)-1].Version + 1
+		}
+		data, err := d.encodeVersion(v)
+		if err != nil {
+			return err
+		}
+		if err := tx.Put(d.versionDataKey(v.TaskID, v.Version), data); err != nil {
+			return err
+		}
+		if max > 0 {
+			versions = append(versions, v)
+			for len(versions) > max {
+				if err := tx.Delete(d.versionDataKey(v.TaskID, versions[0].Version)); err != nil {
+					return err
+				}
+				versions = versions[1:]
+			}
+		}
+		return nil
+	})
+	return v, err
+}
+
+func (d *versionKV) List(taskID string) (versions []TaskVersion, err error) {
+	err = d.store.View(func(tx storage.ReadOnlyTx) error {
+		versions, err = d.list(tx, taskID)
+		return err
+	})
+	return
+}
+
+func (d *versionKV) Move(oldID, newID string) error {
+	return d.store.Update(func(tx storage.Tx) error {
+		versions, err := d.list(tx, oldID)
+		if err != nil {
+			return err
+		}
+		for _, v := range versions {
+			if err := tx.Delete(d.versionDataKey(oldID, v.Version)); err != nil {
+				return err
+			}
+			v.TaskID = newID
+			data, err := d.encodeVersion(v)
+			if err != nil {
+				return err
+			}
+			if err := tx.Put(d.versionDataKey(newID, v.Version), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (d *versionKV) Delete(taskID string) error {
+	return d.store.Update(func(tx storage.Tx) error {
+		kvs, err := tx.List(d.versionPrefix(taskID))
+		if err != nil {
+			return err
+		}
+		for _, kv := range kvs {
+			if err := tx.Delete(kv.Key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 type VarType int
 
 const (
@@ -0,0 +1,55 @@
+package task_store
+
+import (
+	"fmt"
+)
+
+// validateDependsOn checks that a task only depends on existing batch tasks
+// and that the dependencies do not form a cycle.
+func (ts *Service) validateDependsOn(task Task) error {
+	if len(task.DependsOn) == 0 {
+		return nil
+	}
+	if task.Type != BatchTask {
+		return fmt.Errorf("only batch tasks can depend on other tasks")
+	}
+	for _, id := range task.DependsOn {
+		if id == task.ID {
+			return fmt.Errorf("task cannot depend on itself")
+		}
+		dep, err := ts.tasks.Get(id)
+		if err == ErrNoTaskExists {
+			return fmt.Errorf("task depends on unknown task %q", id)
+		} else if err != nil {
+			return err
+		}
+		if dep.Type != BatchTask {
+			return fmt.Errorf("task depends on %q which is not a batch task", id)
+		}
+	}
+
+	// Walk the dependencies looking for the task itself
+	visited := make(map[string]bool)
+	queue := append([]string(nil), task.DependsOn...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		dep, err := ts.tasks.Get(id)
+		if err == ErrNoTaskExists {
+			continue
+		} else if err != nil {
+			return err
+		}
+		for _, next := range dep.DependsOn {
+			if next == task.ID {
+				return fmt.Errorf("task %q depends on task %q, which would create a cycle", id, task.ID)
+			}
+			queue = append(queue, next)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package task_store
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var validLabelKey = regexp.MustCompile(`^[\p{L}_][-\._\p{L}0-9]*$`)
+
+// Tags set on the statistics of every node, labels cannot use them as keys.
+var reservedLabelKeys = map[string]bool{
+	"task": true,
+	"node": true,
+	"type": true,
+	"kind": true,
+}
+
+func validateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if !validLabelKey.MatchString(k) {
+			return fmt.Errorf("label key must start with a letter or '_' and contain only letters, numbers, '-', '.' and '_'. %q", k)
+		}
+		if reservedLabelKeys[k] {
+			return fmt.Errorf("label key %q is reserved", k)
+		}
+		if strings.ContainsAny(v, ",=!") {
+			return fmt.Errorf("label value must not contain ',', '=' or '!'. %q", v)
+		}
+	}
+	return nil
+}
+
+type requirement struct {
+	key    string
+	value  string
+	negate bool
+	// exists is true if only the presence of the key is checked.
+	exists bool
+}
+
+func (r requirement) matches(labels map[string]string) bool {
+	v, ok := labels[r.key]
+	if r.exists {
+		return ok != r.negate
+	}
+	return (ok && v == r.value) != r.negate
+}
+
+// selector filters tasks by their labels.
+// An empty selector matches all tasks.
+type selector []requirement
+
+// parseSelector parses a comma separated list of requirements
+// of the form key=value, key!=value, key or !key.
+func parseSelector(s string) (selector, error) {
+	var sel selector
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var r requirement
+		switch {
+		case strings.Contains(part, "!="):
+			kv := strings.SplitN(part, "!=", 2)
+			r = requirement{key: strings.TrimSpace(kv[0]), value: strings.TrimSpace(kv[1]), negate: true}
+		case strings.Contains(part, "="):
+			kv := strings.SplitN(part, "=", 2)
+			r = requirement{key: strings.TrimSpace(kv[0]), value: strings.TrimSpace(kv[1])}
+		case strings.HasPrefix(part, "!"):
+			r = requirement{key: strings.TrimSpace(part[1:]), negate: true, exists: true}
+		default:
+			r = requirement{key: part, exists: true}
+		}
+		if !validLabelKey.MatchString(r.key) {
+			return nil, fmt.Errorf("invalid selector %q: invalid label key %q", s, r.key)
+		}
+		if strings.ContainsAny(r.value, "=!") {
+			return nil, fmt.Errorf("invalid selector %q: invalid label value %q", s, r.value)
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+func (s selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package task_store
+
+import (
+	"testing"
+)
+
+func TestSelector_Matches(t *testing.T) {
+	labels := map[string]string{
+		"team": "db",
+		"env":  "prod",
+	}
+	tt := []struct {
+		selector string
+		exp      bool
+	}{
+		{selector: "", exp: true},
+		{selector: "team=db", exp: true},
+		{selector: "team=web", exp: false},
+		{selector: "team=db,env=prod", exp: true},
+		{selector: "team=db, env!=prod", exp: false},
+		{selector: "env!=dev", exp: true},
+		{selector: "owner!=bob", exp: true},
+		{selector: "team", exp: true},
+		{selector: "owner", exp: false},
+		{selector: "!owner", exp: true},
+		{selector: "!team", exp: false},
+	}
+	for _, tc := range tt {
+		sel, err := parseSelector(tc.selector)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.selector, err)
+		}
+		if got := sel.Matches(labels); got != tc.exp {
+			t.Errorf("%q: unexpected match: got %v exp %v", tc.selector, got, tc.exp)
+		}
+	}
+}
+
+func TestParseSelector_Invalid(t *testing.T) {
+	for _, s := range []string{
+		"=db",
+		"team==db",
+		"te am=db",
+		"!",
+	} {
+		if _, err := parseSelector(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	if err := validateLabels(map[string]string{"team": "db", "cost-center": "42"}); err != nil {
+		t.Error(err)
+	}
+	for _, labels := range []map[string]string{
+		{"task": "x"},
+		{"1team": "db"},
+		{"team": "a,b"},
+	} {
+		if err := validateLabels(labels); err == nil {
+			t.Errorf("%v: expected error", labels)
+		}
+	}
+}
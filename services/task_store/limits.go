@@ -0,0 +1,43 @@
+package task_store
+
+import (
+	"errors"
+
+	"github.com/influxdata/kapacitor"
+	"github.com/influxdata/kapacitor/client/v1"
+)
+
+func convertToServiceLimits(cl client.TaskLimits) (Limits, error) {
+	if cl.MaxPointsPerSecond < 0 || cl.MaxGroups < 0 || cl.MaxBufferedPoints < 0 {
+		return Limits{}, errors.New("task limits must not be negative")
+	}
+	return Limits{
+		MaxPointsPerSecond: cl.MaxPointsPerSecond,
+		MaxGroups:          cl.MaxGroups,
+		MaxBufferedPoints:  cl.MaxBufferedPoints,
+	}, nil
+}
+
+func convertToClientLimits(l Limits) client.TaskLimits {
+	return client.TaskLimits{
+		MaxPointsPerSecond: l.MaxPointsPerSecond,
+		MaxGroups:          l.MaxGroups,
+		MaxBufferedPoints:  l.MaxBufferedPoints,
+	}
+}
+
+// taskLimits returns the limits enforced for a task,
+// the default limits are used for the limits the task does not set.
+func (ts *Service) taskLimits(l Limits) kapacitor.TaskLimits {
+	limit := func(v, def int64) int64 {
+		if v == 0 {
+			return def
+		}
+		return v
+	}
+	return kapacitor.TaskLimits{
+		MaxPointsPerSecond: limit(l.MaxPointsPerSecond, ts.defaultLimits.MaxPointsPerSecond),
+		MaxGroups:          limit(l.MaxGroups, ts.defaultLimits.MaxGroups),
+		MaxBufferedPoints:  limit(l.MaxBufferedPoints, ts.defaultLimits.MaxBufferedPoints),
+	}
+}
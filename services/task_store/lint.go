@@ -0,0 +1,223 @@
+package task_store
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/tick/ast"
+)
+
+// Rules of the lint findings.
+const (
+	lintUnusedVar    = "unused-var"
+	lintDeprecated   = "deprecated"
+	lintMissingQuiet = "missing-quiet"
+)
+
+// deprecatedProperties maps the deprecated properties to the message of their finding.
+var deprecatedProperties = map[string]string{
+	"pagerDuty": "pagerDuty uses the deprecated PagerDuty v1 events API, use pagerDuty2",
+	"opsGenie":  "opsGenie uses the deprecated OpsGenie v1 API, use opsGenie2",
+	"hipChat":   "hipChat is deprecated since HipChat has been discontinued, use another handler such as slack",
+}
+
+// influxqlMethods are the chain methods creating InfluxQL nodes,
+// whose output only has the fields named by their As property.
+var influxqlMethods = map[string]bool{
+	"count":              true,
+	"distinct":           true,
+	"mean":               true,
+	"median":             true,
+	"mode":               true,
+	"spread":             true,
+	"sum":                true,
+	"first":              true,
+	"last":               true,
+	"min":                true,
+	"max":                true,
+	"percentile":         true,
+	"top":                true,
+	"bottom":             true,
+	"stddev":             true,
+	"elapsed":            true,
+	"difference":         true,
+	"movingAverage":      true,
+	"holtWinters":        true,
+	"holtWintersWithFit": true,
+	"cumulativeSum":      true,
+}
+
+func (ts *Service) handleLintTICKscript(w http.ResponseWriter, r *http.Request) {
+	opts := client.LintOptions{}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&opts); err != nil {
+		httpd.HttpError(w, "invalid JSON", true, http.StatusBadRequest)
+		return
+	}
+	l := lintTICKscript(opts.TICKscript)
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(l, true))
+}
+
+// lintTICKscript formats the TICKscript in its canonical form and reports likely mistakes.
+// Unlike validation the TICKscript is only parsed, so it does not need to compile.
+func lintTICKscript(script string) client.TICKscriptLint {
+	l := client.TICKscriptLint{}
+	pn, err := newProgramNodeFromTickscript(script)
+	if err != nil {
+		l.Errors = append(l.Errors, newValidationMessage(err))
+		return l
+	}
+	l.Formatted = ast.Format(pn)
+	l.Changed = l.Formatted != script
+
+	add := func(rule string, n ast.Node, msg string) {
+		l.Findings = append(l.Findings, client.LintFinding{
+			Rule:    rule,
+			Message: msg,
+			Line:    n.Line(),
+			Char:    n.Char(),
+		})
+	}
+
+	// Check the declarations, and whether the data of each var is raw stream data.
+	var declared []*ast.IdentifierNode
+	raw := make(map[string]bool)
+	for _, n := range pn.Nodes {
+		switch node := n.(type) {
+		case *ast.DeclarationNode:
+			declared = append(declared, node.Left)
+			raw[node.Left.Ident] = lintChain(node.Right, raw, add)
+		case *ast.TypeDeclarationNode:
+			declared = append(declared, node.Node)
+		default:
+			lintChain(n, raw, add)
+		}
+	}
+
+	used := make(map[string]bool)
+	walkTICKscript(pn, func(n ast.Node) {
+		switch node := n.(type) {
+		case *ast.IdentifierNode:
+			used[node.Ident] = true
+		case *ast.FunctionNode:
+			if msg, ok := deprecatedProperties[node.Func]; ok && node.Type == ast.PropertyFunc {
+				add(lintDeprecated, node, msg)
+			}
+		}
+	})
+	for _, ident := range declared {
+		if !used[ident.Ident] {
+			add(lintUnusedVar, ident, fmt.Sprintf("var %s is declared but never used", ident.Ident))
+		}
+	}
+
+	sort.SliceStable(l.Findings, func(i, j int) bool {
+		a, b := l.Findings[i], l.Findings[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Char < b.Char
+	})
+	return l
+}
+
+// lintChain checks the nodes of a chain expression and reports whether its data is raw stream data,
+// whose fields are unknown since any point may lack any field.
+// The raw map holds the same for the vars declared so far.
+func lintChain(n ast.Node, raw map[string]bool, add func(rule string, n ast.Node, msg string)) bool {
+	source, calls := flattenChain(n)
+	isRaw := false
+	if ident, ok := source.(*ast.IdentifierNode); ok {
+		isRaw = ident.Ident == "stream" || raw[ident.Ident]
+	}
+
+	// An eval of raw data logs an error for every point missing a field it references.
+	var (
+		eval        *ast.FunctionNode
+		quiet, keep bool
+	)
+	endEval := func() {
+		if eval == nil {
+			return
+		}
+		if !quiet {
+			add(lintMissingQuiet, eval, "eval of raw stream data logs an error for every point missing a referenced field, add .quiet() unless the errors are expected to be rare")
+		}
+		if !keep {
+			// Only the fields of the expressions are kept.
+			isRaw = false
+		}
+		eval = nil
+	}
+	for _, fn := range calls {
+		switch fn.Type {
+		case ast.ChainFunc:
+			endEval()
+			switch {
+			case influxqlMethods[fn.Func], fn.Func == "default":
+				isRaw = false
+			case fn.Func == "eval" && isRaw:
+				eval, quiet, keep = fn, false, false
+			}
+		case ast.PropertyFunc:
+			switch fn.Func {
+			case "quiet":
+				quiet = true
+			case "keep":
+				keep = true
+			}
+		}
+	}
+	endEval()
+	return isRaw
+}
+
+// flattenChain returns the source of a chain expression and its functions in order.
+func flattenChain(n ast.Node) (ast.Node, []*ast.FunctionNode) {
+	c, ok := n.(*ast.ChainNode)
+	if !ok {
+		return n, nil
+	}
+	source, calls := flattenChain(c.Left)
+	if fn, ok := c.Right.(*ast.FunctionNode); ok {
+		calls = append(calls, fn)
+	}
+	return source, calls
+}
+
+// walkTICKscript calls f for every node of the TICKscript except the identifiers being declared.
+// Unlike ast.Walk it descends into chains and lists.
+func walkTICKscript(n ast.Node, f func(ast.Node)) {
+	f(n)
+	switch node := n.(type) {
+	case *ast.ProgramNode:
+		for _, c := range node.Nodes {
+			walkTICKscript(c, f)
+		}
+	case *ast.DeclarationNode:
+		walkTICKscript(node.Right, f)
+	case *ast.ChainNode:
+		walkTICKscript(node.Left, f)
+		walkTICKscript(node.Right, f)
+	case *ast.FunctionNode:
+		for _, a := range node.Args {
+			walkTICKscript(a, f)
+		}
+	case *ast.ListNode:
+		for _, c := range node.Nodes {
+			walkTICKscript(c, f)
+		}
+	case *ast.LambdaNode:
+		walkTICKscript(node.Expression, f)
+	case *ast.BinaryNode:
+		walkTICKscript(node.Left, f)
+		walkTICKscript(node.Right, f)
+	case *ast.UnaryNode:
+		walkTICKscript(node.Node, f)
+	}
+}
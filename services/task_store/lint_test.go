@@ -0,0 +1,121 @@
+package task_store
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/kapacitor/client/v1"
+)
+
+func TestLintTICKscript(t *testing.T) {
+	tt := []struct {
+		script string
+		exp    []client.LintFinding
+	}{
+		{
+			script: `var unused = 5m
+
+var period = 1m
+
+var every duration
+
+stream
+    |from()
+    |window()
+        .period(period)
+        .every(every)
+`,
+			exp: []client.LintFinding{{
+				Rule:    "unused-var",
+				Message: "var unused is declared but never used",
+				Line:    1,
+				Char:    5,
+			}},
+		},
+		{
+			// Vars referenced by lambdas and lists are used
+			script: `var crit = 10
+
+var tags = ['host', 'cpu']
+
+batch
+    |query('SELECT mean(usage) FROM cpu')
+    |groupBy(tags)
+    |alert()
+        .crit(lambda: "mean" > crit)
+        .pagerDuty()
+        .hipChat()
+`,
+			exp: []client.LintFinding{
+				{
+					Rule:    "deprecated",
+					Message: "pagerDuty uses the deprecated PagerDuty v1 events API, use pagerDuty2",
+					Line:    10,
+					Char:    10,
+				},
+				{
+					Rule:    "deprecated",
+					Message: "hipChat is deprecated since HipChat has been discontinued, use another handler such as slack",
+					Line:    11,
+					Char:    10,
+				},
+			},
+		},
+		{
+			script: `var data = stream
+    |from()
+
+data
+    |eval(lambda: "a" + "b")
+        .as('c')
+    |eval(lambda: "c" * 2)
+        .as('d')
+`,
+			exp: []client.LintFinding{{
+				Rule:    "missing-quiet",
+				Message: "eval of raw stream data logs an error for every point missing a referenced field, add .quiet() unless the errors are expected to be rare",
+				Line:    5,
+				Char:    6,
+			}},
+		},
+		{
+			// Evals that are quiet or follow an aggregation are not noisy
+			script: `stream
+    |from()
+    |eval(lambda: "a" + "b")
+        .as('c')
+        .keep()
+        .quiet()
+    |window()
+    |mean('c')
+    |eval(lambda: "mean" * 2)
+        .as('d')
+`,
+		},
+	}
+	for i, tc := range tt {
+		l := lintTICKscript(tc.script)
+		if len(l.Errors) > 0 {
+			t.Fatalf("%d: unexpected errors %v", i, l.Errors)
+		}
+		if !reflect.DeepEqual(l.Findings, tc.exp) {
+			t.Errorf("%d: unexpected findings\ngot\n%+v\nexp\n%+v", i, l.Findings, tc.exp)
+		}
+		if l.Changed {
+			t.Errorf("%d: the script is already formatted, got\n%s", i, l.Formatted)
+		}
+	}
+}
+
+func TestLintTICKscript_Format(t *testing.T) {
+	l := lintTICKscript("stream|from().measurement('cpu')")
+	exp := "stream\n    |from()\n        .measurement('cpu')\n"
+	if !l.Changed || l.Formatted != exp {
+		t.Errorf("unexpected formatting changed: %v\n%s", l.Changed, l.Formatted)
+	}
+
+	l = lintTICKscript("stream|from(")
+	if len(l.Errors) != 1 || l.Errors[0].Line != 1 || l.Formatted != "" {
+		t.Errorf("unexpected lint of an invalid TICKscript %+v", l)
+	}
+}
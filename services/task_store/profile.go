@@ -0,0 +1,106 @@
+package task_store
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/influxdata/kapacitor"
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+)
+
+const (
+	profilePath = "profile"
+
+	defaultProfileDuration = 10 * time.Second
+	maxProfileDuration     = time.Minute
+
+	// Type of the values of the samples of CPU profiles, in nanoseconds.
+	cpuSampleType = "cpu"
+)
+
+// handleTaskProfile serves a CPU profile of an executing task collected over the duration parameter,
+// as the CPU time of each node, or in the pprof or folded format for flame graphs.
+func (ts *Service) handleTaskProfile(w http.ResponseWriter, r *http.Request, id string) {
+	if _, err := ts.tasks.Get(id); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+
+	duration := defaultProfileDuration
+	if d := r.URL.Query().Get("duration"); d != "" {
+		var err error
+		duration, err = time.ParseDuration(d)
+		if err != nil || duration <= 0 || duration > maxProfileDuration {
+			httpd.HttpError(w, fmt.Sprintf("invalid duration %q must be a positive duration of at most %v", d, maxProfileDuration), true, http.StatusBadRequest)
+			return
+		}
+	}
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = "json"
+	case "json", "pprof", "folded":
+	default:
+		httpd.HttpError(w, fmt.Sprintf("invalid format parameter %q", format), true, http.StatusBadRequest)
+		return
+	}
+
+	tm := ts.TaskMasterLookup.Main()
+	if !tm.IsExecuting(id) {
+		if format != "json" {
+			httpd.HttpError(w, fmt.Sprintf("task %s is not executing", id), true, http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(httpd.MarshalJSON(ts.taskProfile(id, duration), true))
+		return
+	}
+
+	p, err := tm.ProfileTask(r.Context(), id, duration)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if err == kapacitor.ErrProfiling {
+			code = http.StatusConflict
+		}
+		httpd.HttpError(w, err.Error(), true, code)
+		return
+	}
+
+	switch format {
+	case "pprof":
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+".pprof"))
+		w.WriteHeader(http.StatusOK)
+		p.Write(w)
+	case "folded":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		p.WriteFolded(w, cpuSampleType, kapacitor.ProfileNodeLabel)
+	default:
+		tp := ts.taskProfile(id, duration)
+		tp.Executing = true
+		total := p.Value(cpuSampleType)
+		tp.CPU = client.Duration(total)
+		for node, cpu := range p.ValueByLabel(cpuSampleType, kapacitor.ProfileNodeLabel) {
+			np := client.NodeProfile{CPU: client.Duration(cpu)}
+			if total > 0 {
+				np.Percent = 100 * float64(cpu) / float64(total)
+			}
+			tp.Nodes[node] = np
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(httpd.MarshalJSON(tp, true))
+	}
+}
+
+func (ts *Service) taskProfile(id string, duration time.Duration) client.TaskProfile {
+	return client.TaskProfile{
+		Link:     client.Link{Relation: client.Self, Href: path.Join(httpd.BasePath, tasksPath, id, profilePath)},
+		ID:       id,
+		Duration: client.Duration(duration),
+		Nodes:    map[string]client.NodeProfile{},
+	}
+}
@@ -0,0 +1,215 @@
+package task_store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/kapacitor/alert"
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/keyvalue"
+)
+
+const (
+	defaultRestartBackoff    = time.Second
+	defaultRestartMaxBackoff = 5 * time.Minute
+)
+
+// RestartPolicy restarts an enabled task after it stops with an error.
+type RestartPolicy struct {
+	// Number of consecutive restarts before giving up
+	MaxRestarts int
+	// Delay before the first restart, doubled for each consecutive restart
+	Backoff time.Duration
+	// Maximum delay before a restart.
+	// Once a task runs this long without error its restarts are no longer consecutive.
+	MaxBackoff time.Duration
+	// Topic of the alert sent when giving up, no alert is sent if empty
+	Topic string
+}
+
+func (p *RestartPolicy) Validate() error {
+	if p.MaxRestarts <= 0 {
+		return fmt.Errorf("max-restarts must be positive")
+	}
+	if p.Backoff <= 0 {
+		return fmt.Errorf("backoff must be positive")
+	}
+	if p.MaxBackoff < p.Backoff {
+		return fmt.Errorf("max-backoff must not be less than backoff")
+	}
+	return nil
+}
+
+// delay returns the delay before restarting a task that already restarted the given number of times.
+func (p *RestartPolicy) delay(restarts int) time.Duration {
+	d := p.Backoff
+	for i := 0; i < restarts && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+func convertToServiceRestartPolicy(cp *client.TaskRestartPolicy) (*RestartPolicy, error) {
+	if cp == nil || *cp == (client.TaskRestartPolicy{}) {
+		return nil, nil
+	}
+	p := &RestartPolicy{
+		MaxRestarts: cp.MaxRestarts,
+		Backoff:     time.Duration(cp.Backoff),
+		MaxBackoff:  time.Duration(cp.MaxBackoff),
+		Topic:       cp.Topic,
+	}
+	if p.Backoff == 0 {
+		p.Backoff = defaultRestartBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = defaultRestartMaxBackoff
+		if p.MaxBackoff < p.Backoff {
+			p.MaxBackoff = p.Backoff
+		}
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func convertToClientRestartPolicy(p *RestartPolicy) *client.TaskRestartPolicy {
+	if p == nil {
+		return nil
+	}
+	return &client.TaskRestartPolicy{
+		MaxRestarts: p.MaxRestarts,
+		Backoff:     client.Duration(p.Backoff),
+		MaxBackoff:  client.Duration(p.MaxBackoff),
+		Topic:       p.Topic,
+	}
+}
+
+// restartState tracks the consecutive restarts of a task.
+type restartState struct {
+	restarts int
+	timer    *time.Timer
+}
+
+// restartCount returns the number of consecutive restarts of a task.
+func (ts *Service) restartCount(id string) int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if s, ok := ts.restarts[id]; ok {
+		return s.restarts
+	}
+	return 0
+}
+
+// cancelRestart stops any pending restart of a task and forgets its restarts.
+func (ts *Service) cancelRestart(id string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if s, ok := ts.restarts[id]; ok {
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+		delete(ts.restarts, id)
+	}
+}
+
+// taskFailed schedules a restart of a task that stopped with an error after running for the given duration,
+// according to the restart policy of the task.
+func (ts *Service) taskFailed(id string, ran time.Duration, taskErr error) {
+	task, err := ts.tasks.Get(id)
+	if err != nil {
+		ts.diag.Error("failed to get task to restart", err, keyvalue.KV("task", id))
+		return
+	}
+	p := task.Restart
+	if p == nil || task.Status != Enabled {
+		return
+	}
+
+	ts.mu.Lock()
+	s, ok := ts.restarts[id]
+	if !ok {
+		s = &restartState{}
+		ts.restarts[id] = s
+	}
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if ran >= p.MaxBackoff {
+		s.restarts = 0
+	}
+	if s.restarts >= p.MaxRestarts {
+		restarts := s.restarts
+		ts.mu.Unlock()
+		ts.diag.GaveUpRestartingTask(id, restarts)
+		ts.alertGaveUp(task, restarts, taskErr)
+		return
+	}
+	delay := p.delay(s.restarts)
+	s.restarts++
+	attempt := s.restarts
+	s.timer = time.AfterFunc(delay, func() {
+		ts.restartTask(id)
+	})
+	ts.mu.Unlock()
+	ts.diag.RestartingTask(id, attempt, delay)
+}
+
+// restartTask starts a task that stopped with an error if it is still enabled.
+func (ts *Service) restartTask(id string) {
+	select {
+	case <-ts.closing:
+		return
+	default:
+	}
+	task, err := ts.tasks.Get(id)
+	if err != nil {
+		ts.diag.Error("failed to get task to restart", err, keyvalue.KV("task", id))
+		return
+	}
+	if task.Status != Enabled || ts.TaskMasterLookup.Main().IsExecuting(id) {
+		return
+	}
+	if err := ts.startTask(task); err != nil {
+		ts.diag.Error("failed to restart task", err, keyvalue.KV("task", id))
+		ts.taskFailed(id, 0, err)
+	}
+}
+
+// stopRestarts stops all pending restarts.
+func (ts *Service) stopRestarts() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for _, s := range ts.restarts {
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+	}
+}
+
+// alertGaveUp sends a critical alert to the topic of the restart policy of the task.
+func (ts *Service) alertGaveUp(task Task, restarts int, taskErr error) {
+	if task.Restart.Topic == "" || ts.AlertService == nil {
+		return
+	}
+	event := alert.Event{
+		Topic: task.Restart.Topic,
+		State: alert.EventState{
+			ID:      task.ID,
+			Message: fmt.Sprintf("task %s stopped with an error and was not restarted after %d restarts: %v", task.ID, restarts, taskErr),
+			Time:    time.Now().UTC(),
+			Level:   alert.Critical,
+		},
+		Data: alert.EventData{
+			TaskName: task.ID,
+		},
+	}
+	if err := ts.AlertService.Collect(event); err != nil {
+		ts.diag.Error("failed to send alert for task that was not restarted", err, keyvalue.KV("task", task.ID))
+	}
+}
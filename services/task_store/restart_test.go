@@ -0,0 +1,51 @@
+package task_store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/client/v1"
+)
+
+func TestRestartPolicy_Delay(t *testing.T) {
+	p := &RestartPolicy{
+		MaxRestarts: 10,
+		Backoff:     time.Second,
+		MaxBackoff:  5 * time.Second,
+	}
+	for restarts, exp := range []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		5 * time.Second,
+		5 * time.Second,
+	} {
+		if got := p.delay(restarts); got != exp {
+			t.Errorf("%d: unexpected delay got %v exp %v", restarts, got, exp)
+		}
+	}
+}
+
+func TestConvertToServiceRestartPolicy(t *testing.T) {
+	p, err := convertToServiceRestartPolicy(&client.TaskRestartPolicy{MaxRestarts: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := (RestartPolicy{MaxRestarts: 3, Backoff: defaultRestartBackoff, MaxBackoff: defaultRestartMaxBackoff}); *p != exp {
+		t.Errorf("unexpected defaults got %+v exp %+v", *p, exp)
+	}
+
+	if p, err := convertToServiceRestartPolicy(&client.TaskRestartPolicy{}); err != nil || p != nil {
+		t.Errorf("expected empty policy to remove the policy, got %+v %v", p, err)
+	}
+
+	for _, cp := range []client.TaskRestartPolicy{
+		{Backoff: client.Duration(time.Second)},
+		{MaxRestarts: 1, Backoff: client.Duration(-time.Second)},
+		{MaxRestarts: 1, Backoff: client.Duration(time.Minute), MaxBackoff: client.Duration(time.Second)},
+	} {
+		if _, err := convertToServiceRestartPolicy(&cp); err == nil {
+			t.Errorf("%+v: expected error", cp)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package task_store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gorhill/cronexpr"
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/keyvalue"
+)
+
+// How often the schedules of the tasks are checked.
+const scheduleCheckInterval = 10 * time.Second
+
+// Schedule restricts the times during which an enabled task is active.
+type Schedule struct {
+	// Cron expression matching the minutes the task is active
+	Cron string
+	// Daily windows the task is active
+	Windows []TimeWindow
+	// IANA time zone name, UTC if empty
+	Timezone string
+}
+
+type TimeWindow struct {
+	// Abbreviated week days the window starts on, every day if empty
+	Days []string
+	// Time of day of the form 15:04
+	Start string
+	Stop  string
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseTimeOfDay returns the offset from midnight of a time of the form 15:04.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, must be of the form 15:04", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (w TimeWindow) Validate() error {
+	for _, d := range w.Days {
+		if _, ok := weekdays[d]; !ok {
+			return fmt.Errorf("invalid day %q, must be one of sun, mon, tue, wed, thu, fri or sat", d)
+		}
+	}
+	if _, err := parseTimeOfDay(w.Start); err != nil {
+		return err
+	}
+	if _, err := parseTimeOfDay(w.Stop); err != nil {
+		return err
+	}
+	return nil
+}
+
+// contains reports whether the window contains t, t must be in the time zone of the schedule.
+func (w TimeWindow) contains(t time.Time) bool {
+	start, _ := parseTimeOfDay(w.Start)
+	stop, _ := parseTimeOfDay(w.Stop)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	tod := t.Sub(midnight)
+	if start < stop {
+		return tod >= start && tod < stop && w.onDay(t.Weekday())
+	}
+	// The window crosses midnight
+	return tod >= start && w.onDay(t.Weekday()) ||
+		tod < stop && w.onDay(midnight.AddDate(0, 0, -1).Weekday())
+}
+
+func (w TimeWindow) onDay(d time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, day := range w.Days {
+		if weekdays[day] == d {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schedule) Validate() error {
+	if s.Cron == "" && len(s.Windows) == 0 {
+		return fmt.Errorf("schedule must have a cron expression or at least one window")
+	}
+	if s.Cron != "" {
+		if _, err := cronexpr.Parse(s.Cron); err != nil {
+			return fmt.Errorf("invalid cron expression %q: %v", s.Cron, err)
+		}
+	}
+	for _, w := range s.Windows {
+		if err := w.Validate(); err != nil {
+			return err
+		}
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q: %v", s.Timezone, err)
+	}
+	return nil
+}
+
+// Active reports whether a task with the schedule is active at time t.
+// A nil schedule is always active.
+func (s *Schedule) Active(t time.Time) (bool, error) {
+	if s == nil {
+		return true, nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return false, err
+	}
+	t = t.In(loc)
+	if s.Cron != "" {
+		expr, err := cronexpr.Parse(s.Cron)
+		if err != nil {
+			return false, err
+		}
+		minute := t.Truncate(time.Minute)
+		if expr.Next(minute.Add(-time.Nanosecond)).Equal(minute) {
+			return true, nil
+		}
+	}
+	for _, w := range s.Windows {
+		if w.contains(t) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func convertToServiceSchedule(cs *client.TaskSchedule) (*Schedule, error) {
+	if cs == nil || cs.Cron == "" && len(cs.Windows) == 0 && cs.Timezone == "" {
+		return nil, nil
+	}
+	s := &Schedule{
+		Cron:     cs.Cron,
+		Timezone: cs.Timezone,
+	}
+	for _, w := range cs.Windows {
+		days := make([]string, len(w.Days))
+		for i, d := range w.Days {
+			days[i] = strings.ToLower(d)
+		}
+		s.Windows = append(s.Windows, TimeWindow{
+			Days:  days,
+			Start: w.Start,
+			Stop:  w.Stop,
+		})
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func convertToClientSchedule(s *Schedule) *client.TaskSchedule {
+	if s == nil {
+		return nil
+	}
+	cs := &client.TaskSchedule{
+		Cron:     s.Cron,
+		Timezone: s.Timezone,
+	}
+	for _, w := range s.Windows {
+		cs.Windows = append(cs.Windows, client.TimeWindow{
+			Days:  w.Days,
+			Start: w.Start,
+			Stop:  w.Stop,
+		})
+	}
+	return cs
+}
+
+func (ts *Service) isPaused(id string) bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.paused[id]
+}
+
+func (ts *Service) setPaused(id string, paused bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if paused {
+		ts.paused[id] = true
+	} else {
+		delete(ts.paused, id)
+	}
+}
+
+// pauseTask stops a running task until its schedule is active again.
+func (ts *Service) pauseTask(id string) {
+	ts.TaskMasterLookup.Main().StopTask(id)
+	ts.setPaused(id, true)
+	ts.diag.PausedTask(id)
+}
+
+// applySchedule pauses or resumes an enabled task according to its schedule.
+func (ts *Service) applySchedule(task Task, now time.Time) {
+	active, err := task.Schedule.Active(now)
+	if err != nil {
+		ts.diag.Error("invalid task schedule", err, keyvalue.KV("task", task.ID))
+		return
+	}
+	paused := ts.isPaused(task.ID)
+	switch {
+	case active && paused:
+		if err := ts.startTask(task); err != nil {
+			ts.diag.Error("failed to resume task", err, keyvalue.KV("task", task.ID))
+			return
+		}
+		ts.diag.ResumedTask(task.ID)
+	case !active && !paused && ts.TaskMasterLookup.Main().IsExecuting(task.ID):
+		ts.pauseTask(task.ID)
+	}
+}
+
+// runSchedules periodically pauses and resumes tasks according to their schedules.
+func (ts *Service) runSchedules() {
+	defer ts.wg.Done()
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ts.closing:
+			return
+		case now := <-ticker.C:
+			if ts.isStandby() {
+				continue
+			}
+			tasks, err := ts.matchTasks([]string{"*"}, nil)
+			if err != nil {
+				ts.diag.Error("failed to list tasks to check their schedules", err)
+				continue
+			}
+			for _, task := range tasks {
+				if task.Status == Enabled && (task.Schedule != nil || ts.isPaused(task.ID)) {
+					ts.applySchedule(task, now)
+				}
+			}
+		}
+	}
+}
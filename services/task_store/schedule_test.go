@@ -0,0 +1,107 @@
+package task_store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Active(t *testing.T) {
+	// 2017-01-02 is a Monday
+	monday := func(hour, minute int) time.Time {
+		return time.Date(2017, 1, 2, hour, minute, 0, 0, time.UTC)
+	}
+	tt := []struct {
+		name     string
+		schedule *Schedule
+		t        time.Time
+		exp      bool
+	}{
+		{
+			name: "nil schedule",
+			t:    monday(12, 0),
+			exp:  true,
+		},
+		{
+			name:     "within window",
+			schedule: &Schedule{Windows: []TimeWindow{{Start: "09:00", Stop: "17:00"}}},
+			t:        monday(9, 0),
+			exp:      true,
+		},
+		{
+			name:     "window stop is exclusive",
+			schedule: &Schedule{Windows: []TimeWindow{{Start: "09:00", Stop: "17:00"}}},
+			t:        monday(17, 0),
+			exp:      false,
+		},
+		{
+			name:     "other day",
+			schedule: &Schedule{Windows: []TimeWindow{{Days: []string{"tue"}, Start: "09:00", Stop: "17:00"}}},
+			t:        monday(12, 0),
+			exp:      false,
+		},
+		{
+			name:     "across midnight before midnight",
+			schedule: &Schedule{Windows: []TimeWindow{{Days: []string{"mon"}, Start: "22:00", Stop: "06:00"}}},
+			t:        monday(23, 0),
+			exp:      true,
+		},
+		{
+			name:     "across midnight after midnight",
+			schedule: &Schedule{Windows: []TimeWindow{{Days: []string{"mon"}, Start: "22:00", Stop: "06:00"}}},
+			t:        monday(23, 0).Add(2 * time.Hour),
+			exp:      true,
+		},
+		{
+			name:     "across midnight started the day before",
+			schedule: &Schedule{Windows: []TimeWindow{{Days: []string{"mon"}, Start: "22:00", Stop: "06:00"}}},
+			t:        monday(1, 0),
+			exp:      false,
+		},
+		{
+			name:     "timezone",
+			schedule: &Schedule{Windows: []TimeWindow{{Start: "09:00", Stop: "17:00"}}, Timezone: "Etc/GMT-10"},
+			t:        monday(0, 0),
+			exp:      true,
+		},
+		{
+			name:     "cron match",
+			schedule: &Schedule{Cron: "* 0-5 * * 1-5"},
+			t:        monday(5, 59).Add(30 * time.Second),
+			exp:      true,
+		},
+		{
+			name:     "cron no match",
+			schedule: &Schedule{Cron: "* 0-5 * * 1-5"},
+			t:        monday(6, 0),
+			exp:      false,
+		},
+	}
+	for _, tc := range tt {
+		if tc.schedule != nil {
+			if err := tc.schedule.Validate(); err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+		}
+		got, err := tc.schedule.Active(tc.t)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.exp {
+			t.Errorf("%s: unexpected active got %v exp %v", tc.name, got, tc.exp)
+		}
+	}
+}
+
+func TestSchedule_Validate(t *testing.T) {
+	for _, s := range []Schedule{
+		{},
+		{Cron: "not a cron"},
+		{Windows: []TimeWindow{{Start: "9am", Stop: "17:00"}}},
+		{Windows: []TimeWindow{{Days: []string{"monday"}, Start: "09:00", Stop: "17:00"}}},
+		{Cron: "* * * * *", Timezone: "Nowhere/Unknown"},
+	} {
+		if err := s.Validate(); err == nil {
+			t.Errorf("%+v: expected error", s)
+		}
+	}
+}
@@ -10,10 +10,14 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/influxdata/kapacitor"
+	"github.com/influxdata/kapacitor/alert"
+	"github.com/influxdata/kapacitor/auth"
 	"github.com/influxdata/kapacitor/client/v1"
 	"github.com/influxdata/kapacitor/keyvalue"
 	"github.com/influxdata/kapacitor/server/vars"
@@ -28,6 +32,9 @@ import (
 const (
 	tasksPath         = "/tasks"
 	tasksPathAnchored = "/tasks/"
+	tasksBulkPath     = "/tasks/bulk"
+	tasksValidatePath = "/tasks/validate"
+	tasksLintPath     = "/tasks/lint"
 
 	templatesPath         = "/templates"
 	templatesPathAnchored = "/templates/"
@@ -39,6 +46,12 @@ type Diagnostic interface {
 
 	FinishedTask(taskID string)
 
+	PausedTask(taskID string)
+	ResumedTask(taskID string)
+
+	RestartingTask(taskID string, attempt int, delay time.Duration)
+	GaveUpRestartingTask(taskID string, restarts int)
+
 	Error(msg string, err error, ctx ...keyvalue.T)
 
 	Debug(msg string)
@@ -52,9 +65,23 @@ type Service struct {
 	tasks            TaskDAO
 	templates        TemplateDAO
 	snapshots        SnapshotDAO
+	versions         VersionDAO
 	routes           []httpd.Route
 	snapshotInterval time.Duration
-	StorageService   interface {
+	maxVersions      int
+	defaultLimits    Limits
+
+	// Enabled tasks that are not running because they are outside of their schedule.
+	mu      sync.Mutex
+	paused  map[string]bool
+	closing chan struct{}
+	wg      sync.WaitGroup
+	// Consecutive restarts of tasks that stopped with an error.
+	restarts map[string]*restartState
+	// Tasks are not started while the server is the standby of a high availability pair.
+	standby bool
+
+	StorageService interface {
 		Store(namespace string) storage.Interface
 		Register(name string, store storage.StoreActioner)
 	}
@@ -68,6 +95,18 @@ type Service struct {
 		Set(*kapacitor.TaskMaster)
 		Delete(*kapacitor.TaskMaster)
 	}
+	AlertService interface {
+		Collect(event alert.Event) error
+		Watch(f func(alert.Event)) (cancel func())
+	}
+	// LogWatcher streams the log messages with the tags, it is used to watch the errors of the tasks.
+	LogWatcher interface {
+		Watch(tags map[string]string, f func(map[string]interface{})) (cancel func())
+	}
+	// ClusterService places the batch tasks on the nodes of a cluster, it is nil without a cluster.
+	ClusterService interface {
+		OwnsTask(id string) bool
+	}
 
 	diag Diagnostic
 }
@@ -81,8 +120,14 @@ type taskStore struct {
 func NewService(conf Config, d Diagnostic) *Service {
 	return &Service{
 		snapshotInterval: time.Duration(conf.SnapshotInterval),
+		maxVersions:      conf.MaxVersions,
 		diag:             d,
 		oldDBDir:         conf.Dir,
+		defaultLimits: Limits{
+			MaxPointsPerSecond: conf.MaxPointsPerSecond,
+			MaxGroups:          conf.MaxGroups,
+			MaxBufferedPoints:  conf.MaxBufferedPoints,
+		},
 	}
 }
 
@@ -104,6 +149,10 @@ func (ts *Service) Open() error {
 	ts.StorageService.Register(tasksAPIName, ts.tasks)
 	ts.templates = newTemplateKV(store)
 	ts.snapshots = newSnapshotKV(store)
+	ts.versions = newVersionKV(store)
+	ts.paused = make(map[string]bool)
+	ts.restarts = make(map[string]*restartState)
+	ts.closing = make(chan struct{})
 
 	// Perform migration to new storage service.
 	if err := ts.migrate(); err != nil {
@@ -116,11 +165,14 @@ func (ts *Service) Open() error {
 			Method:      "GET",
 			Pattern:     tasksPathAnchored,
 			HandlerFunc: ts.handleTask,
+			Scope:       auth.TasksReadScope,
 		},
 		{
 			Method:      "DELETE",
 			Pattern:     tasksPathAnchored,
 			HandlerFunc: ts.handleDeleteTask,
+			Scope:       auth.TasksWriteScope,
+			Replicated:  true,
 		},
 		{
 			// Satisfy CORS checks.
@@ -132,26 +184,60 @@ func (ts *Service) Open() error {
 			Method:      "PATCH",
 			Pattern:     tasksPathAnchored,
 			HandlerFunc: ts.handleUpdateTask,
+			Scope:       auth.TasksWriteScope,
+			Replicated:  true,
 		},
 		{
 			Method:      "GET",
 			Pattern:     tasksPath,
 			HandlerFunc: ts.handleListTasks,
+			Scope:       auth.TasksReadScope,
 		},
 		{
 			Method:      "POST",
 			Pattern:     tasksPath,
 			HandlerFunc: ts.handleCreateTask,
+			Scope:       auth.TasksWriteScope,
+			Replicated:  true,
+		},
+		{
+			Method:      "POST",
+			Pattern:     tasksBulkPath,
+			HandlerFunc: ts.handleBulkTasks,
+			Scope:       auth.TasksWriteScope,
+			Replicated:  true,
+		},
+		{
+			Method:      "POST",
+			Pattern:     tasksValidatePath,
+			HandlerFunc: ts.handleValidateTask,
+			Scope:       auth.TasksWriteScope,
+		},
+		{
+			Method:      "POST",
+			Pattern:     tasksLintPath,
+			HandlerFunc: ts.handleLintTICKscript,
+			Scope:       auth.TasksReadScope,
+		},
+		{
+			Method:      "POST",
+			Pattern:     tasksPathAnchored,
+			HandlerFunc: ts.handleRollbackTask,
+			Scope:       auth.TasksWriteScope,
+			Replicated:  true,
 		},
 		{
 			Method:      "GET",
 			Pattern:     templatesPathAnchored,
 			HandlerFunc: ts.handleTemplate,
+			Scope:       auth.TasksReadScope,
 		},
 		{
 			Method:      "DELETE",
 			Pattern:     templatesPathAnchored,
 			HandlerFunc: ts.handleDeleteTemplate,
+			Scope:       auth.TasksWriteScope,
+			Replicated:  true,
 		},
 		{
 			// Satisfy CORS checks.
@@ -163,16 +249,21 @@ func (ts *Service) Open() error {
 			Method:      "PATCH",
 			Pattern:     templatesPathAnchored,
 			HandlerFunc: ts.handleUpdateTemplate,
+			Scope:       auth.TasksWriteScope,
+			Replicated:  true,
 		},
 		{
 			Method:      "GET",
 			Pattern:     templatesPath,
 			HandlerFunc: ts.handleListTemplates,
+			Scope:       auth.TasksReadScope,
 		},
 		{
 			Method:      "POST",
 			Pattern:     templatesPath,
 			HandlerFunc: ts.handleCreateTemplate,
+			Scope:       auth.TasksWriteScope,
+			Replicated:  true,
 		},
 	}
 
@@ -181,6 +272,19 @@ func (ts *Service) Open() error {
 		return err
 	}
 
+	if err := ts.startEnabledTasks(); err != nil {
+		return err
+	}
+
+	ts.wg.Add(1)
+	go ts.runSchedules()
+
+	return nil
+}
+
+// startEnabledTasks starts all enabled tasks, unless the service is on standby.
+func (ts *Service) startEnabledTasks() error {
+	standby := ts.isStandby()
 	numTasks := int64(0)
 	numEnabledTasks := int64(0)
 
@@ -197,6 +301,9 @@ func (ts *Service) Open() error {
 			numTasks++
 			if task.Status == Enabled {
 				numEnabledTasks++
+				if standby {
+					continue
+				}
 				ts.diag.StartingTask(task.ID)
 				err = ts.startTask(task)
 				if err != nil {
@@ -219,6 +326,32 @@ func (ts *Service) Open() error {
 	return nil
 }
 
+// Standby stops all running tasks and keeps tasks from starting until Activate is called.
+// The standby server of a high availability pair keeps the replicated tasks without running them.
+func (ts *Service) Standby() {
+	ts.stopRestarts()
+	ts.mu.Lock()
+	ts.standby = true
+	ts.paused = make(map[string]bool)
+	ts.restarts = make(map[string]*restartState)
+	ts.mu.Unlock()
+	ts.TaskMasterLookup.Main().StopTasks()
+}
+
+// Activate starts the enabled tasks, after the server became the leader of a high availability pair.
+func (ts *Service) Activate() error {
+	ts.mu.Lock()
+	ts.standby = false
+	ts.mu.Unlock()
+	return ts.startEnabledTasks()
+}
+
+func (ts *Service) isStandby() bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.standby
+}
+
 // Migrate data from previous task.db to new storage service.
 // This process will return any errors and stop the TaskStore from opening
 // thus stopping the entire Kapacitor startup.
@@ -376,6 +509,11 @@ func (ts *Service) migrate() error {
 
 func (ts *Service) Close() error {
 	ts.HTTPDService.DelRoutes(ts.routes)
+	if ts.closing != nil {
+		close(ts.closing)
+		ts.wg.Wait()
+		ts.stopRestarts()
+	}
 	return nil
 }
 
@@ -387,9 +525,56 @@ func (ts *Service) Load(id string) (*kapacitor.Task, error) {
 	return ts.newKapacitorTask(t)
 }
 
+// Compile creates the task of a TICKscript, or of a template if templateID is not empty,
+// with the vars and dbrps the same way as when defining it, without defining it.
+// The dbrps default to the dbrp statements of the TICKscript.
+func (ts *Service) Compile(id, templateID, tickscript string, vars client.Vars, dbrps []client.DBRP) (*kapacitor.Task, error) {
+	if templateID != "" {
+		template, err := ts.templates.Get(templateID)
+		if err != nil {
+			return nil, fmt.Errorf("unknown template %s: %v", templateID, err)
+		}
+		tickscript = template.TICKscript
+	}
+	pn, err := newProgramNodeFromTickscript(tickscript)
+	if err != nil {
+		return nil, err
+	}
+	task := Task{
+		ID:         id,
+		TICKscript: tickscript,
+	}
+	switch taskTypeFromProgram(pn) {
+	case client.StreamTask:
+		task.Type = StreamTask
+	case client.BatchTask:
+		task.Type = BatchTask
+	default:
+		return nil, fmt.Errorf("invalid task type, TICKscript must use exactly one of stream or batch")
+	}
+	if implicit := dbrpsFromProgram(pn); len(implicit) > 0 {
+		if len(dbrps) > 0 {
+			return nil, fmt.Errorf("cannot specify dbrp in both implicitly and explicitly")
+		}
+		dbrps = implicit
+	}
+	for _, dbrp := range dbrps {
+		task.DBRPs = append(task.DBRPs, DBRP{
+			Database:        dbrp.Database,
+			RetentionPolicy: dbrp.RetentionPolicy,
+		})
+	}
+	task.Vars, err = ts.convertToServiceVars(vars)
+	if err != nil {
+		return nil, err
+	}
+	return ts.newKapacitorTask(task)
+}
+
 func (ts *Service) SaveSnapshot(id string, snapshot *kapacitor.TaskSnapshot) error {
 	s := &Snapshot{
 		NodeSnapshots: snapshot.NodeSnapshots,
+		Checkpoint:    snapshot.Checkpoint,
 	}
 	return ts.snapshots.Put(id, s)
 }
@@ -410,6 +595,7 @@ func (ts *Service) LoadSnapshot(id string) (*kapacitor.TaskSnapshot, error) {
 	}
 	s := &kapacitor.TaskSnapshot{
 		NodeSnapshots: snapshot.NodeSnapshots,
+		Checkpoint:    snapshot.Checkpoint,
 	}
 	return s, nil
 }
@@ -432,6 +618,31 @@ func (ts *Service) handleTask(w http.ResponseWriter, r *http.Request) {
 		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
 		return
 	}
+	if i := strings.IndexRune(id, '/'); i != -1 {
+		switch id[i+1:] {
+		case statsPath:
+			ts.handleTaskStats(w, r, id[:i])
+			return
+		case dotPath:
+			ts.handleTaskDot(w, r, id[:i])
+			return
+		case cardinalityPath:
+			ts.handleTaskCardinality(w, r, id[:i])
+			return
+		case profilePath:
+			ts.handleTaskProfile(w, r, id[:i])
+			return
+		case watchPath:
+			ts.handleTaskWatch(w, r, id[:i])
+			return
+		}
+		if p := id[i+1:]; strings.HasPrefix(p, tapPath+"/") {
+			ts.handleTaskTap(w, r, id[:i], strings.TrimPrefix(p, tapPath+"/"))
+			return
+		}
+		ts.handleTaskVersions(w, r, id[:i], id[i+1:])
+		return
+	}
 
 	raw, err := ts.tasks.Get(id)
 	if err != nil {
@@ -499,6 +710,10 @@ var allTaskFields = []string{
 	"modified",
 	"last-enabled",
 	"vars",
+	"labels",
+	"schedule",
+	"paused",
+	"limits",
 }
 
 const tasksBasePathAnchored = httpd.BasePath + tasksPathAnchored
@@ -569,7 +784,13 @@ func (ts *Service) handleListTasks(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	rawTasks, err := ts.tasks.List(pattern, int(offset), int(limit))
+	sel, err := parseSelector(r.URL.Query().Get("selector"))
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	rawTasks, err := ts.listTasks(pattern, sel, int(offset), int(limit))
 	if err != nil {
 		httpd.HttpError(w, fmt.Sprintf("failed to list tasks with pattern %q: %s", pattern, err), true, http.StatusBadRequest)
 		return
@@ -660,6 +881,22 @@ func (ts *Service) handleListTasks(w http.ResponseWriter, r *http.Request) {
 					break
 				}
 				value = vars
+			case "labels":
+				value = client.Labels(task.Labels)
+			case "schedule":
+				value = convertToClientSchedule(task.Schedule)
+			case "paused":
+				value = task.Status == Enabled && ts.isPaused(task.ID)
+			case "limits":
+				value = convertToClientLimits(task.Limits)
+			case "priority":
+				value = task.Priority
+			case "restart":
+				value = convertToClientRestartPolicy(task.Restart)
+			case "restarts":
+				value = ts.restartCount(task.ID)
+			case "depends-on":
+				value = task.DependsOn
 			default:
 				httpd.HttpError(w, fmt.Sprintf("unsupported field %q", field), true, http.StatusBadRequest)
 				return
@@ -675,6 +912,41 @@ func (ts *Service) handleListTasks(w http.ResponseWriter, r *http.Request) {
 	w.Write(httpd.MarshalJSON(response{tasks}, true))
 }
 
+// listTasks lists the tasks matching both the pattern and the selector.
+// Offset and limit apply to the matching tasks.
+func (ts *Service) listTasks(pattern string, sel selector, offset, limit int) ([]Task, error) {
+	if len(sel) == 0 {
+		return ts.tasks.List(pattern, offset, limit)
+	}
+	var matched []Task
+	pageOffset := 0
+	pageLimit := 100
+	for len(matched) < limit {
+		tasks, err := ts.tasks.List(pattern, pageOffset, pageLimit)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			if !sel.Matches(task.Labels) {
+				continue
+			}
+			if offset > 0 {
+				offset--
+				continue
+			}
+			matched = append(matched, task)
+			if len(matched) == limit {
+				break
+			}
+		}
+		if len(tasks) != pageLimit {
+			break
+		}
+		pageOffset += pageLimit
+	}
+	return matched, nil
+}
+
 var validTaskID = regexp.MustCompile(`^[-\._\p{L}0-9]+$`)
 
 func (ts *Service) handleCreateTask(w http.ResponseWriter, r *http.Request) {
@@ -767,6 +1039,48 @@ func (ts *Service) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
 		return
 	}
+
+	// Set labels
+	if err := validateLabels(task.Labels); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if len(task.Labels) > 0 {
+		newTask.Labels = task.Labels
+	}
+
+	// Set schedule
+	newTask.Schedule, err = convertToServiceSchedule(task.Schedule)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	// Set limits
+	if task.Limits != nil {
+		newTask.Limits, err = convertToServiceLimits(*task.Limits)
+		if err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Set priority
+	if task.Priority != nil {
+		newTask.Priority = *task.Priority
+	}
+
+	// Set restart policy
+	newTask.Restart, err = convertToServiceRestartPolicy(task.Restart)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	// Set dependencies
+	if len(task.DependsOn) > 0 {
+		newTask.DependsOn = task.DependsOn
+	}
 	// Check for parity between tickscript and dbrp
 
 	pn, err := newProgramNodeFromTickscript(newTask.TICKscript)
@@ -791,6 +1105,14 @@ func (ts *Service) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
 		return
 	}
+	if err := ts.validateDependsOn(newTask); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if err := ts.checkTemplateVars(newTask); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
 
 	now := time.Now()
 	newTask.Created = now
@@ -827,6 +1149,7 @@ func (ts *Service) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
 		return
 	}
+	ts.saveVersion(newTask)
 
 	// Count new task
 	vars.NumTasksVar.Add(1)
@@ -983,6 +1306,58 @@ func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Set labels
+	if task.Labels != nil {
+		if err := validateLabels(task.Labels); err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+			return
+		}
+		updated.Labels = nil
+		if len(task.Labels) > 0 {
+			updated.Labels = task.Labels
+		}
+	}
+
+	// Set schedule
+	if task.Schedule != nil {
+		updated.Schedule, err = convertToServiceSchedule(task.Schedule)
+		if err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Set limits
+	if task.Limits != nil {
+		updated.Limits, err = convertToServiceLimits(*task.Limits)
+		if err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Set priority
+	if task.Priority != nil {
+		updated.Priority = *task.Priority
+	}
+
+	// Set restart policy
+	if task.Restart != nil {
+		updated.Restart, err = convertToServiceRestartPolicy(task.Restart)
+		if err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Set dependencies
+	if task.DependsOn != nil {
+		updated.DependsOn = nil
+		if len(task.DependsOn) > 0 {
+			updated.DependsOn = task.DependsOn
+		}
+	}
+
 	// set task type from tickscript
 	switch tt := taskTypeFromProgram(pn); tt {
 	case client.StreamTask:
@@ -1000,6 +1375,14 @@ func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
 		return
 	}
+	if err := ts.validateDependsOn(updated); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if err := ts.checkTemplateVars(updated); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
 
 	now := time.Now()
 	updated.Modified = now
@@ -1007,7 +1390,17 @@ func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		updated.LastEnabled = now
 	}
 
+	// Keep the original definition in the history of tasks created without one.
+	ts.saveVersion(original)
 	if original.ID != updated.ID {
+		if err := ts.versions.Move(original.ID, updated.ID); err != nil {
+			ts.diag.Error(
+				"failed to move task versions during ID change",
+				err,
+				keyvalue.KV("oldID", original.ID),
+				keyvalue.KV("newID", updated.ID),
+			)
+		}
 		// Task ID changed delete and re-create.
 		if err := ts.tasks.Create(updated); err != nil {
 			httpd.HttpError(w, fmt.Sprintf("failed to create new task during ID change: %s", err.Error()), true, http.StatusInternalServerError)
@@ -1036,6 +1429,8 @@ func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	ts.saveVersion(updated)
+
 	if statusChanged {
 		// Enable/Disable task
 		switch updated.Status {
@@ -1050,6 +1445,14 @@ func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 			vars.NumEnabledTasksVar.Add(-1)
 			ts.stopTask(original.ID)
 		}
+	} else if updated.Status == Enabled && original.ID == updated.ID {
+		if task.Schedule != nil {
+			// Pause or resume the task right away
+			ts.applySchedule(updated, now)
+		}
+		if updated.Priority != original.Priority {
+			ts.TaskMasterLookup.Main().SetPriority(updated.ID, updated.Priority)
+		}
 	}
 
 	t, err := ts.convertTask(updated, "formatted", "attributes", ts.TaskMasterLookup.Main())
@@ -1136,9 +1539,17 @@ func (ts *Service) convertTask(t Task, scriptFormat, dotView string, tm *kapacit
 		DBRPs:          dbrps,
 		TICKscript:     script,
 		Vars:           vars,
+		Labels:         client.Labels(t.Labels),
+		Schedule:       convertToClientSchedule(t.Schedule),
+		Limits:         convertToClientLimits(t.Limits),
+		Priority:       t.Priority,
+		Restart:        convertToClientRestartPolicy(t.Restart),
+		Restarts:       ts.restartCount(t.ID),
+		DependsOn:      t.DependsOn,
 		Status:         status,
 		Dot:            dot,
 		Executing:      executing,
+		Paused:         t.Status == Enabled && ts.isPaused(t.ID),
 		ExecutionStats: stats,
 		Created:        t.Created,
 		Modified:       t.Modified,
@@ -1414,6 +1825,10 @@ func (ts *Service) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
 func (ts *Service) deleteTask(id string) error {
 	// Delete associated snapshot
 	ts.snapshots.Delete(id)
+	// Delete version history
+	if err := ts.versions.Delete(id); err != nil {
+		ts.diag.Error("failed to delete task versions", err, keyvalue.KV("task", id))
+	}
 
 	// Delete task object
 	task, err := ts.tasks.Get(id)
@@ -1433,6 +1848,8 @@ func (ts *Service) deleteTask(id string) error {
 	if task.Status == Enabled {
 		vars.NumEnabledTasksVar.Add(-1)
 		ts.TaskMasterLookup.Main().DeleteTask(id)
+		ts.setPaused(id, false)
+		ts.cancelRestart(id)
 	}
 	return ts.tasks.Delete(id)
 }
@@ -1480,6 +1897,7 @@ func (ts *Service) convertTemplate(t Template, scriptFormat string) (client.Temp
 		Created:    t.Created,
 		Modified:   t.Modified,
 		Vars:       vars,
+		Schema:     convertToClientSchema(t.Schema),
 	}, nil
 }
 
@@ -1704,12 +2122,19 @@ func (ts *Service) handleCreateTemplate(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Validate template
-	_, err = ts.templateTask(newTemplate)
+	kt, err := ts.templateTask(newTemplate)
 	if err != nil {
 		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
 		return
 	}
 
+	// Set schema
+	newTemplate.Schema, err = ts.convertToServiceSchema(template.Schema, kt.Vars())
+	if err != nil {
+		httpd.HttpError(w, "invalid schema: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
 	now := time.Now()
 	newTemplate.Created = now
 	newTemplate.Modified = now
@@ -1772,12 +2197,23 @@ func (ts *Service) handleUpdateTemplate(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Validate template
-	_, err = ts.templateTask(updated)
+	kt, err := ts.templateTask(updated)
 	if err != nil {
 		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
 		return
 	}
 
+	// Set schema, the existing schema must still match the vars of an updated TICKscript
+	schema := convertToClientSchema(original.Schema)
+	if template.Schema != nil {
+		schema = template.Schema
+	}
+	updated.Schema, err = ts.convertToServiceSchema(schema, kt.Vars())
+	if err != nil {
+		httpd.HttpError(w, "invalid schema: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
 	// Get associated tasks
 	taskIds, err := ts.templates.ListAssociatedTasks(original.ID)
 	if err != nil {
@@ -1785,6 +2221,22 @@ func (ts *Service) handleUpdateTemplate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// The vars of all associated tasks must match the schema
+	for _, taskId := range taskIds {
+		task, err := ts.tasks.Get(taskId)
+		if err == ErrNoTaskExists {
+			continue
+		}
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("error retrieving associated task %s: %s", taskId, err), true, http.StatusInternalServerError)
+			return
+		}
+		if err := checkSchema(updated.Schema, task.Vars); err != nil {
+			httpd.HttpError(w, fmt.Sprintf("associated task %s does not match the schema: %s", taskId, err), true, http.StatusBadRequest)
+			return
+		}
+	}
+
 	// Save updated template
 	now := time.Now()
 	updated.Modified = now
@@ -1959,13 +2411,21 @@ func (ts *Service) newKapacitorTask(task Task) (*kapacitor.Task, error) {
 	if err != nil {
 		return nil, err
 	}
-	return ts.TaskMasterLookup.Main().NewTask(task.ID,
+	t, err := ts.TaskMasterLookup.Main().NewTask(task.ID,
 		task.TICKscript,
 		tt,
 		dbrps,
 		ts.snapshotInterval,
 		vars,
 	)
+	if err != nil {
+		return nil, err
+	}
+	t.Labels = task.Labels
+	t.Limits = ts.taskLimits(task.Limits)
+	t.Priority = task.Priority
+	t.DependsOn = task.DependsOn
+	return t, nil
 }
 
 func (ts *Service) templateTask(template Template) (*kapacitor.Template, error) {
@@ -1987,10 +2447,29 @@ func (ts *Service) templateTask(template Template) (*kapacitor.Template, error)
 }
 
 func (ts *Service) startTask(task Task) error {
+	if ts.isStandby() {
+		// The task is started once the server is the leader.
+		return nil
+	}
+	if task.Type == BatchTask && ts.ClusterService != nil && !ts.ClusterService.OwnsTask(task.ID) {
+		// The batch task runs on another node of the cluster.
+		return nil
+	}
 	t, err := ts.newKapacitorTask(task)
 	if err != nil {
 		return err
 	}
+	active, err := task.Schedule.Active(time.Now())
+	if err != nil {
+		return err
+	}
+	if !active {
+		// The task is started once its schedule is active.
+		ts.setPaused(task.ID, true)
+		return nil
+	}
+	ts.setPaused(task.ID, false)
+
 	// Starting task, remove last error
 	ts.saveLastError(t.ID, "")
 
@@ -2012,6 +2491,7 @@ func (ts *Service) startTask(task Task) error {
 		}
 	}
 
+	started := time.Now()
 	go func() {
 		// Wait for task to finish
 		err := et.Wait()
@@ -2023,10 +2503,10 @@ func (ts *Service) startTask(task Task) error {
 
 			ts.diag.Error("task finished with error", err, keyvalue.KV("task", et.Task.ID))
 			// Save last error from task.
-			err = ts.saveLastError(t.ID, err.Error())
-			if err != nil {
+			if err := ts.saveLastError(t.ID, err.Error()); err != nil {
 				ts.diag.Error("failed to save last error for task", err, keyvalue.KV("task", et.Task.ID))
 			}
+			ts.taskFailed(t.ID, time.Since(started), err)
 		}
 	}()
 	return nil
@@ -2034,6 +2514,8 @@ func (ts *Service) startTask(task Task) error {
 
 func (ts *Service) stopTask(id string) {
 	ts.TaskMasterLookup.Main().StopTask(id)
+	ts.setPaused(id, false)
+	ts.cancelRestart(id)
 }
 
 // Save last error from task.
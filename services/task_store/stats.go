@@ -0,0 +1,109 @@
+package task_store
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/influxdata/kapacitor"
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+)
+
+const (
+	statsPath = "stats"
+	dotPath   = "dot"
+
+	defaultDotInterval = time.Second
+	maxDotInterval     = time.Minute
+)
+
+// handleTaskStats serves the execution statistics of each node of a task.
+func (ts *Service) handleTaskStats(w http.ResponseWriter, r *http.Request, id string) {
+	if _, err := ts.tasks.Get(id); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(ts.taskStats(id), true))
+}
+
+// taskStats returns the execution statistics of each node of a task.
+func (ts *Service) taskStats(id string) client.TaskStats {
+	tm := ts.TaskMasterLookup.Main()
+	stats := client.TaskStats{
+		Link:      client.Link{Relation: client.Self, Href: path.Join(httpd.BasePath, tasksPath, id, statsPath)},
+		ID:        id,
+		NodeStats: map[string]client.NodeStats{},
+	}
+	nodes, executing := tm.NodeExecutionStats(id)
+	stats.Executing = executing
+	if executing {
+		if s, err := tm.ExecutionStats(id); err == nil {
+			stats.TaskStats = s.TaskStats
+		}
+	}
+	for name, n := range nodes {
+		stats.NodeStats[name] = convertNodeStats(n)
+	}
+	return stats
+}
+
+// handleTaskDot serves the DOT graph of an executing task with the rates of its edges,
+// measured over the interval parameter.
+func (ts *Service) handleTaskDot(w http.ResponseWriter, r *http.Request, id string) {
+	if _, err := ts.tasks.Get(id); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+
+	interval := defaultDotInterval
+	if i := r.URL.Query().Get("interval"); i != "" {
+		var err error
+		interval, err = time.ParseDuration(i)
+		if err != nil || interval <= 0 || interval > maxDotInterval {
+			httpd.HttpError(w, fmt.Sprintf("invalid interval %q must be a positive duration of at most %v", i, maxDotInterval), true, http.StatusBadRequest)
+			return
+		}
+	}
+	dotView := r.URL.Query().Get("dot-view")
+	switch dotView {
+	case "":
+		dotView = "attributes"
+	case "attributes":
+	case "labels":
+	default:
+		httpd.HttpError(w, fmt.Sprintf("invalid dot-view parameter %q", dotView), true, http.StatusBadRequest)
+		return
+	}
+
+	dot := client.TaskDot{
+		Link:     client.Link{Relation: client.Self, Href: path.Join(httpd.BasePath, tasksPath, id, dotPath)},
+		ID:       id,
+		Interval: client.Duration(interval),
+		Dot:      ts.TaskMasterLookup.Main().ExecutingLiveDot(id, dotView == "labels", interval),
+	}
+	dot.Executing = dot.Dot != ""
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(dot, true))
+}
+
+func convertNodeStats(n kapacitor.NodeExecutionStats) client.NodeStats {
+	return client.NodeStats{
+		Collected:  n.Collected,
+		Emitted:    n.Emitted,
+		Errors:     n.Errors,
+		QueueDepth: n.QueueDepth,
+		Buffered:   n.Buffered,
+		Memory:     n.Memory,
+		Latency: client.LatencyStats{
+			Count: n.Latency.Count,
+			P50:   client.Duration(n.Latency.P50),
+			P90:   client.Duration(n.Latency.P90),
+			P99:   client.Duration(n.Latency.P99),
+			Max:   client.Duration(n.Latency.Max),
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package task_store
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/kapacitor/services/httpd"
+)
+
+const (
+	tapPath = "tap"
+
+	defaultTapCount   = 10
+	maxTapCount       = 10000
+	defaultTapTimeout = time.Minute
+)
+
+// handleTaskTap streams the next points or batches emitted by a node of a task as JSON rows, one per line.
+// The stream ends once count rows were sent, the timeout elapsed or the task stopped.
+func (ts *Service) handleTaskTap(w http.ResponseWriter, r *http.Request, id, nodeName string) {
+	if _, err := ts.tasks.Get(id); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+
+	count := defaultTapCount
+	if c := r.URL.Query().Get("count"); c != "" {
+		var err error
+		count, err = strconv.Atoi(c)
+		if err != nil || count <= 0 || count > maxTapCount {
+			httpd.HttpError(w, fmt.Sprintf("invalid count %q must be an integer between 1 and %d", c, maxTapCount), true, http.StatusBadRequest)
+			return
+		}
+	}
+	timeout := defaultTapTimeout
+	if t := r.URL.Query().Get("timeout"); t != "" {
+		var err error
+		timeout, err = time.ParseDuration(t)
+		if err != nil || timeout <= 0 {
+			httpd.HttpError(w, fmt.Sprintf("invalid timeout %q must be a positive duration", t), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	tap, err := ts.TaskMasterLookup.Main().Tap(id, nodeName, count)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	defer tap.Close()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	w.Header().Add("Transfer-Encoding", "chunked")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case row, ok := <-tap.Rows:
+			if !ok {
+				return
+			}
+			if err := enc.Encode(row); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-timer.C:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
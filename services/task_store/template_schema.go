@@ -0,0 +1,219 @@
+package task_store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/tick"
+	"github.com/influxdata/kapacitor/tick/ast"
+)
+
+// VarSchema constrains the values of a template var,
+// in addition to the type and default value declared by the TICKscript.
+type VarSchema struct {
+	// Bounds of int and float vars.
+	// Flags are used instead of pointers since gob does not preserve pointers to zero values.
+	Min    float64
+	HasMin bool
+	Max    float64
+	HasMax bool
+	// Allowed values of int, float and string vars, any value if empty
+	Enum []Var
+}
+
+// number returns the value of an int or float var as a float.
+func (v Var) number() (float64, bool) {
+	switch v.Type {
+	case VarInt:
+		return float64(v.IntValue), true
+	case VarFloat:
+		return v.FloatValue, true
+	}
+	return 0, false
+}
+
+// display returns the value of an int, float or string var as it is shown in errors.
+func (v Var) display() string {
+	switch v.Type {
+	case VarInt:
+		return fmt.Sprint(v.IntValue)
+	case VarFloat:
+		return fmt.Sprint(v.FloatValue)
+	case VarString:
+		return fmt.Sprintf("%q", v.StringValue)
+	}
+	return fmt.Sprintf("<%v>", v.Type)
+}
+
+func (v Var) equal(o Var) bool {
+	if n, ok := v.number(); ok {
+		m, ok := o.number()
+		return ok && n == m
+	}
+	return v.Type == VarString && o.Type == VarString && v.StringValue == o.StringValue
+}
+
+// check returns an error if the value of the var is not allowed by the schema.
+func (s VarSchema) check(name string, v Var) error {
+	if n, ok := v.number(); ok {
+		if s.HasMin && n < s.Min {
+			return fmt.Errorf("invalid value for var %q: %s is less than the minimum %v", name, v.display(), s.Min)
+		}
+		if s.HasMax && n > s.Max {
+			return fmt.Errorf("invalid value for var %q: %s is greater than the maximum %v", name, v.display(), s.Max)
+		}
+	}
+	if len(s.Enum) == 0 {
+		return nil
+	}
+	allowed := make([]string, len(s.Enum))
+	for i, e := range s.Enum {
+		if v.equal(e) {
+			return nil
+		}
+		allowed[i] = e.display()
+	}
+	return fmt.Errorf("invalid value for var %q: %s is not one of %s", name, v.display(), strings.Join(allowed, ", "))
+}
+
+// convertToServiceSchema validates the schema against the vars declared by the template.
+func (ts *Service) convertToServiceSchema(cs map[string]client.VarSchema, tvars map[string]tick.Var) (map[string]VarSchema, error) {
+	if len(cs) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(cs))
+	for name := range cs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	schema := make(map[string]VarSchema, len(cs))
+	for _, name := range names {
+		c := cs[name]
+		tv, ok := tvars[name]
+		if !ok {
+			return nil, fmt.Errorf("schema for var %q which is not declared by the template", name)
+		}
+		numeric := tv.Type == ast.TInt || tv.Type == ast.TFloat
+		if (c.Min != nil || c.Max != nil) && !numeric {
+			return nil, fmt.Errorf("schema for var %q: min and max only apply to int and float vars, not %v", name, tv.Type)
+		}
+		if c.Min != nil && c.Max != nil && *c.Min > *c.Max {
+			return nil, fmt.Errorf("schema for var %q: min %v is greater than max %v", name, *c.Min, *c.Max)
+		}
+		if len(c.Enum) > 0 && !numeric && tv.Type != ast.TString {
+			return nil, fmt.Errorf("schema for var %q: enum only applies to int, float and string vars, not %v", name, tv.Type)
+		}
+		s := VarSchema{}
+		if c.Min != nil {
+			s.Min, s.HasMin = *c.Min, true
+		}
+		if c.Max != nil {
+			s.Max, s.HasMax = *c.Max, true
+		}
+		for _, e := range c.Enum {
+			v, err := enumVar(e, tv.Type)
+			if err != nil {
+				return nil, fmt.Errorf("schema for var %q: %v", name, err)
+			}
+			s.Enum = append(s.Enum, v)
+		}
+		// The default value must be valid
+		if tv.Value != nil {
+			cv, err := ts.convertToClientVarFromTick(tv)
+			if err != nil {
+				return nil, err
+			}
+			v, err := ts.convertToServiceVar(cv)
+			if err != nil {
+				return nil, err
+			}
+			if err := s.check(name, v); err != nil {
+				return nil, fmt.Errorf("default value does not match the schema: %v", err)
+			}
+		}
+		schema[name] = s
+	}
+	return schema, nil
+}
+
+// enumVar converts an enum value decoded from JSON to a var of the given type.
+func enumVar(value interface{}, typ ast.ValueType) (Var, error) {
+	switch typ {
+	case ast.TInt:
+		if f, ok := value.(float64); ok && f == float64(int64(f)) {
+			return Var{Type: VarInt, IntValue: int64(f)}, nil
+		}
+	case ast.TFloat:
+		if f, ok := value.(float64); ok {
+			return Var{Type: VarFloat, FloatValue: f}, nil
+		}
+	case ast.TString:
+		if s, ok := value.(string); ok {
+			return Var{Type: VarString, StringValue: s}, nil
+		}
+	}
+	return Var{}, fmt.Errorf("enum value %v is not of type %v", value, typ)
+}
+
+func convertToClientSchema(schema map[string]VarSchema) map[string]client.VarSchema {
+	if len(schema) == 0 {
+		return nil
+	}
+	cs := make(map[string]client.VarSchema, len(schema))
+	for name, s := range schema {
+		c := client.VarSchema{}
+		if s.HasMin {
+			min := s.Min
+			c.Min = &min
+		}
+		if s.HasMax {
+			max := s.Max
+			c.Max = &max
+		}
+		for _, e := range s.Enum {
+			switch e.Type {
+			case VarInt:
+				c.Enum = append(c.Enum, e.IntValue)
+			case VarFloat:
+				c.Enum = append(c.Enum, e.FloatValue)
+			case VarString:
+				c.Enum = append(c.Enum, e.StringValue)
+			}
+		}
+		cs[name] = c
+	}
+	return cs
+}
+
+// checkTemplateVars returns an error if the vars of a templated task are not allowed by the schema of its template.
+func (ts *Service) checkTemplateVars(task Task) error {
+	if task.TemplateID == "" {
+		return nil
+	}
+	template, err := ts.templates.Get(task.TemplateID)
+	if err != nil {
+		return err
+	}
+	return checkSchema(template.Schema, task.Vars)
+}
+
+func checkSchema(schema map[string]VarSchema, vars map[string]Var) error {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s, ok := schema[name]
+		if !ok {
+			continue
+		}
+		if err := s.check(name, vars[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package task_store
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/tick"
+	"github.com/influxdata/kapacitor/tick/ast"
+)
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func TestVarSchema_Check(t *testing.T) {
+	s := VarSchema{
+		HasMin: true,
+		Max:    100,
+		HasMax: true,
+	}
+	if err := s.check("warn", Var{Type: VarInt, IntValue: 50}); err != nil {
+		t.Error(err)
+	}
+	if err := s.check("warn", Var{Type: VarFloat, FloatValue: 100.5}); err == nil {
+		t.Error("expected error")
+	} else if exp, got := `invalid value for var "warn": 100.5 is greater than the maximum 100`, err.Error(); got != exp {
+		t.Errorf("unexpected error got %q exp %q", got, exp)
+	}
+	if err := s.check("warn", Var{Type: VarInt, IntValue: -1}); err == nil {
+		t.Error("expected error")
+	} else if exp, got := `invalid value for var "warn": -1 is less than the minimum 0`, err.Error(); got != exp {
+		t.Errorf("unexpected error got %q exp %q", got, exp)
+	}
+
+	s = VarSchema{
+		Enum: []Var{
+			{Type: VarString, StringValue: "usage_idle"},
+			{Type: VarString, StringValue: "usage_user"},
+		},
+	}
+	if err := s.check("field", Var{Type: VarString, StringValue: "usage_user"}); err != nil {
+		t.Error(err)
+	}
+	if err := s.check("field", Var{Type: VarString, StringValue: "usage_system"}); err == nil {
+		t.Error("expected error")
+	} else if exp, got := `invalid value for var "field": "usage_system" is not one of "usage_idle", "usage_user"`, err.Error(); got != exp {
+		t.Errorf("unexpected error got %q exp %q", got, exp)
+	}
+}
+
+func TestConvertToServiceSchema(t *testing.T) {
+	ts := &Service{}
+	tvars := map[string]tick.Var{
+		"warn":  {Type: ast.TFloat, Value: 80.0},
+		"count": {Type: ast.TInt},
+		"field": {Type: ast.TString, Value: "usage_idle"},
+		"every": {Type: ast.TDuration},
+	}
+	schema, err := ts.convertToServiceSchema(map[string]client.VarSchema{
+		"warn":  {Min: float(0), Max: float(100)},
+		"count": {Enum: []interface{}{1.0, 2.0}},
+		"field": {Enum: []interface{}{"usage_idle", "usage_user"}},
+	}, tvars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := (Var{Type: VarInt, IntValue: 2}); !reflect.DeepEqual(schema["count"].Enum[1], exp) {
+		t.Errorf("unexpected enum value got %+v exp %+v", schema["count"].Enum[1], exp)
+	}
+
+	for _, tc := range []struct {
+		schema map[string]client.VarSchema
+		err    string
+	}{
+		{
+			schema: map[string]client.VarSchema{"crit": {Min: float(0)}},
+			err:    `schema for var "crit" which is not declared by the template`,
+		},
+		{
+			schema: map[string]client.VarSchema{"every": {Min: float(0)}},
+			err:    `schema for var "every": min and max only apply to int and float vars, not duration`,
+		},
+		{
+			schema: map[string]client.VarSchema{"warn": {Min: float(10), Max: float(1)}},
+			err:    `schema for var "warn": min 10 is greater than max 1`,
+		},
+		{
+			schema: map[string]client.VarSchema{"count": {Enum: []interface{}{1.5}}},
+			err:    `schema for var "count": enum value 1.5 is not of type int`,
+		},
+		{
+			schema: map[string]client.VarSchema{"warn": {Max: float(50)}},
+			err:    `default value does not match the schema: invalid value for var "warn": 80 is greater than the maximum 50`,
+		},
+	} {
+		_, err := ts.convertToServiceSchema(tc.schema, tvars)
+		if err == nil {
+			t.Errorf("%v: expected error", tc.schema)
+		} else if err.Error() != tc.err {
+			t.Errorf("unexpected error got %q exp %q", err.Error(), tc.err)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package task_store
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/influxdata/kapacitor"
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/tick/ast"
+)
+
+// Default name of the DOT graph of a validated task.
+const validateTaskID = "validate"
+
+// Errors from the parser and evaluator report their position in this form.
+var errorPosition = regexp.MustCompile(`line (\d+) char (\d+)`)
+
+// newValidationMessage creates a message from an error,
+// extracting the position of the error from its text if present.
+func newValidationMessage(err error) client.ValidationMessage {
+	m := client.ValidationMessage{Message: err.Error()}
+	if match := errorPosition.FindStringSubmatch(m.Message); match != nil {
+		m.Line, _ = strconv.Atoi(match[1])
+		m.Char, _ = strconv.Atoi(match[2])
+	}
+	return m
+}
+
+func (ts *Service) handleValidateTask(w http.ResponseWriter, r *http.Request) {
+	opts := client.ValidateTaskOptions{}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&opts); err != nil {
+		httpd.HttpError(w, "invalid JSON", true, http.StatusBadRequest)
+		return
+	}
+	if opts.ID != "" && !validTaskID.MatchString(opts.ID) {
+		httpd.HttpError(w, fmt.Sprintf("task ID must contain only letters, numbers, '-', '.' and '_'. %q", opts.ID), true, http.StatusBadRequest)
+		return
+	}
+
+	v := ts.validateTask(opts)
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(v, true))
+}
+
+// validateTask compiles the task the same way it would be when created
+// and reports all problems found.
+func (ts *Service) validateTask(opts client.ValidateTaskOptions) client.TaskValidation {
+	v := client.TaskValidation{
+		Type:  opts.Type,
+		DBRPs: opts.DBRPs,
+	}
+	if v.DBRPs == nil {
+		v.DBRPs = []client.DBRP{}
+	}
+	fail := func(err error) client.TaskValidation {
+		v.Errors = append(v.Errors, newValidationMessage(err))
+		return v
+	}
+
+	if opts.TICKscript == "" {
+		return fail(fmt.Errorf("must provide TICKscript"))
+	}
+	pn, err := newProgramNodeFromTickscript(opts.TICKscript)
+	if err != nil {
+		return fail(err)
+	}
+
+	tt := taskTypeFromProgram(pn)
+	if tt == client.InvalidTask {
+		return fail(fmt.Errorf("invalid task type, TICKscript must use exactly one of stream or batch"))
+	}
+	if opts.Type != client.InvalidTask && opts.Type != tt {
+		return fail(fmt.Errorf("task type %v does not match the TICKscript, which defines a %v task", opts.Type, tt))
+	}
+	v.Type = tt
+
+	if dbrps := dbrpsFromProgram(pn); len(dbrps) > 0 {
+		if len(opts.DBRPs) > 0 {
+			return fail(fmt.Errorf("cannot specify dbrp in both implicitly and explicitly"))
+		}
+		v.DBRPs = dbrps
+	}
+	if len(v.DBRPs) == 0 {
+		return fail(fmt.Errorf("must specify dbrp"))
+	}
+
+	task := Task{
+		ID:         opts.ID,
+		TICKscript: opts.TICKscript,
+		DBRPs:      make([]DBRP, len(v.DBRPs)),
+	}
+	if task.ID == "" {
+		task.ID = validateTaskID
+	}
+	for i, dbrp := range v.DBRPs {
+		task.DBRPs[i] = DBRP{
+			Database:        dbrp.Database,
+			RetentionPolicy: dbrp.RetentionPolicy,
+		}
+	}
+	switch tt {
+	case client.StreamTask:
+		task.Type = StreamTask
+	case client.BatchTask:
+		task.Type = BatchTask
+	}
+	task.Vars, err = ts.convertToServiceVars(opts.Vars)
+	if err != nil {
+		return fail(err)
+	}
+
+	kt, err := ts.newKapacitorTask(task)
+	if err != nil {
+		return fail(err)
+	}
+
+	// The queries of batch tasks are only parsed once the task starts.
+	err = kt.Pipeline.Walk(func(n pipeline.Node) error {
+		q, ok := n.(*pipeline.QueryNode)
+		if !ok {
+			return nil
+		}
+		query, err := kapacitor.NewQuery(q.QueryStr)
+		if err != nil {
+			v.Errors = append(v.Errors, client.ValidationMessage{Message: err.Error(), Node: n.Name()})
+			return nil
+		}
+		dbrps, err := query.DBRPs()
+		if err != nil {
+			v.Errors = append(v.Errors, client.ValidationMessage{Message: err.Error(), Node: n.Name()})
+			return nil
+		}
+		for _, dbrp := range dbrps {
+			if !containsDBRP(v.DBRPs, dbrp.Database, dbrp.RetentionPolicy) {
+				v.Warnings = append(v.Warnings, client.ValidationMessage{
+					Message: fmt.Sprintf("query reads from %q.%q which is not one of the task dbrps", dbrp.Database, dbrp.RetentionPolicy),
+					Node:    n.Name(),
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fail(err)
+	}
+	if len(v.Errors) > 0 {
+		return v
+	}
+
+	v.Warnings = append(v.Warnings, fromWarnings(kt.Pipeline, v.DBRPs)...)
+	v.Warnings = append(v.Warnings, fieldWarnings(kt.Pipeline)...)
+	v.Valid = true
+	v.Dot = string(kt.Dot())
+	return v
+}
+
+// containsDBRP reports whether the database and retention policy are in dbrps.
+// An empty retention policy matches any retention policy of the database.
+func containsDBRP(dbrps []client.DBRP, db, rp string) bool {
+	for _, dbrp := range dbrps {
+		if dbrp.Database == db && (rp == "" || dbrp.RetentionPolicy == rp) {
+			return true
+		}
+	}
+	return false
+}
+
+// fromWarnings warns about from nodes that can never receive data
+// because they select a database that is not one of the task dbrps.
+func fromWarnings(p *pipeline.Pipeline, dbrps []client.DBRP) []client.ValidationMessage {
+	var warnings []client.ValidationMessage
+	p.Walk(func(n pipeline.Node) error {
+		f, ok := n.(*pipeline.FromNode)
+		if !ok || f.Database == "" {
+			return nil
+		}
+		if !containsDBRP(dbrps, f.Database, f.RetentionPolicy) {
+			warnings = append(warnings, client.ValidationMessage{
+				Message: fmt.Sprintf("from selects database %q which is not one of the task dbrps, it will never receive data", f.Database),
+				Node:    n.Name(),
+			})
+		}
+		return nil
+	})
+	return warnings
+}
+
+// fieldSet is the set of fields known to exist on the data of a node.
+// A nil fieldSet means the fields are unknown.
+type fieldSet map[string]bool
+
+func (s fieldSet) with(fields ...string) fieldSet {
+	if s == nil {
+		return nil
+	}
+	n := make(fieldSet, len(s)+len(fields))
+	for f := range s {
+		n[f] = true
+	}
+	for _, f := range fields {
+		n[f] = true
+	}
+	return n
+}
+
+func newFieldSet(fields ...string) fieldSet {
+	return fieldSet{}.with(fields...)
+}
+
+// fieldWarnings warns about lambda expressions that reference fields
+// that cannot exist because an earlier node replaced the set of fields,
+// e.g. after a mean the only field is the one named by its As property.
+// References to tags cannot be told apart from fields,
+// so these are warnings and not errors.
+func fieldWarnings(p *pipeline.Pipeline) []client.ValidationMessage {
+	var warnings []client.ValidationMessage
+	known := make(map[pipeline.ID]fieldSet)
+	check := func(n pipeline.Node, fields fieldSet, l *ast.LambdaNode) {
+		if fields == nil || l == nil {
+			return
+		}
+		refs := ast.FindReferenceVariables(l)
+		sort.Strings(refs)
+		for _, ref := range refs {
+			if !fields[ref] {
+				warnings = append(warnings, client.ValidationMessage{
+					Message: fmt.Sprintf("unknown field %q, it is not produced by the parent nodes unless it is a tag", ref),
+					Line:    l.Line(),
+					Char:    l.Char(),
+					Node:    n.Name(),
+				})
+			}
+		}
+	}
+
+	p.Walk(func(n pipeline.Node) error {
+		var parent fieldSet
+		if parents := n.Parents(); len(parents) == 1 {
+			parent = known[parents[0].ID()]
+		}
+		switch node := n.(type) {
+		case *pipeline.InfluxQLNode:
+			switch node.Method {
+			case "top", "bottom":
+				// Selected fields and tags are also kept.
+			default:
+				known[n.ID()] = newFieldSet(node.As)
+			}
+		case *pipeline.EvalNode:
+			fields := parent
+			for i, l := range node.Lambdas {
+				check(n, fields, l)
+				if i < len(node.AsList) {
+					fields = fields.with(node.AsList[i])
+				}
+			}
+			switch {
+			case !node.KeepFlag:
+				known[n.ID()] = newFieldSet(node.AsList...)
+			case len(node.KeepList) > 0:
+				known[n.ID()] = newFieldSet(node.KeepList...)
+			default:
+				known[n.ID()] = parent.with(node.AsList...)
+			}
+		case *pipeline.WhereNode:
+			check(n, parent, node.Lambda)
+			known[n.ID()] = parent
+		case *pipeline.AlertNode:
+			for _, l := range []*ast.LambdaNode{node.Info, node.Warn, node.Crit, node.InfoReset, node.WarnReset, node.CritReset} {
+				check(n, parent, l)
+			}
+			known[n.ID()] = parent
+		case *pipeline.WindowNode,
+			*pipeline.GroupByNode,
+			*pipeline.LogNode,
+			*pipeline.HTTPOutNode,
+			*pipeline.ShiftNode,
+			*pipeline.SampleNode,
+			*pipeline.BarrierNode:
+			known[n.ID()] = parent
+		}
+		return nil
+	})
+	return warnings
+}
@@ -0,0 +1,92 @@
+package task_store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/tick/stateful"
+)
+
+type deadman struct{}
+
+func (deadman) Interval() time.Duration { return 0 }
+func (deadman) Threshold() float64      { return 0 }
+func (deadman) Id() string              { return "" }
+func (deadman) Message() string         { return "" }
+func (deadman) Global() bool            { return false }
+
+func TestFieldWarnings(t *testing.T) {
+	tt := []struct {
+		script string
+		exp    []client.ValidationMessage
+	}{
+		{
+			// Fields of the raw data are unknown
+			script: `stream
+    |from()
+    |where(lambda: "value" > 0)
+    |alert()
+        .crit(lambda: "other" > 0)
+`,
+		},
+		{
+			script: `stream
+    |from()
+    |window()
+    |mean('value')
+    |where(lambda: "value" > 0 AND "mean" > 0)
+`,
+			exp: []client.ValidationMessage{{
+				Message: `unknown field "value", it is not produced by the parent nodes unless it is a tag`,
+				Line:    5,
+				Char:    12,
+				Node:    "where4",
+			}},
+		},
+		{
+			// Eval can reference the results of its earlier expressions
+			script: `stream
+    |from()
+    |window()
+    |sum('value')
+        .as('total')
+    |eval(lambda: "total" * 2.0, lambda: "double" + 1.0)
+        .as('double', 'plus')
+    |alert()
+        .warn(lambda: "plus" > 1.0)
+        .crit(lambda: "total" > 1.0)
+`,
+			exp: []client.ValidationMessage{{
+				Message: `unknown field "total", it is not produced by the parent nodes unless it is a tag`,
+				Line:    10,
+				Char:    15,
+				Node:    "alert5",
+			}},
+		},
+		{
+			// Kept fields remain known
+			script: `stream
+    |from()
+    |window()
+    |count('value')
+    |eval(lambda: "count" * 2.0)
+        .as('double')
+        .keep()
+    |alert()
+        .crit(lambda: "count" > "double")
+`,
+		},
+	}
+	for i, tc := range tt {
+		p, err := pipeline.CreatePipeline(tc.script, pipeline.StreamEdge, stateful.NewScope(), deadman{}, nil)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if got := fieldWarnings(p); !reflect.DeepEqual(got, tc.exp) {
+			t.Errorf("%d: unexpected warnings\ngot\n%+v\nexp\n%+v", i, got, tc.exp)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package task_store
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/services/httpd"
+)
+
+const (
+	versionsPath = "versions"
+	rollbackPath = "rollback"
+)
+
+// saveVersion records the definition of the task as a new version,
+// unless it is the same as the most recent version.
+func (ts *Service) saveVersion(task Task) {
+	latest, err := ts.versions.Latest(task.ID)
+	if err != nil && err != ErrNoVersionExists {
+		ts.diag.Error("failed to retrieve latest task version", err, keyvalue.KV("task", task.ID))
+		return
+	}
+	if err == nil && sameDefinition(latest, task) {
+		return
+	}
+	v := TaskVersion{
+		TaskID:     task.ID,
+		Type:       task.Type,
+		DBRPs:      task.DBRPs,
+		TICKscript: task.TICKscript,
+		TemplateID: task.TemplateID,
+		Vars:       task.Vars,
+		Created:    time.Now(),
+	}
+	if _, err := ts.versions.Add(v, ts.maxVersions); err != nil {
+		ts.diag.Error("failed to save task version", err, keyvalue.KV("task", task.ID))
+	}
+}
+
+func sameDefinition(v TaskVersion, t Task) bool {
+	return v.Type == t.Type &&
+		v.TICKscript == t.TICKscript &&
+		v.TemplateID == t.TemplateID &&
+		(len(v.DBRPs) == 0 && len(t.DBRPs) == 0 || reflect.DeepEqual(v.DBRPs, t.DBRPs)) &&
+		(len(v.Vars) == 0 && len(t.Vars) == 0 || reflect.DeepEqual(v.Vars, t.Vars))
+}
+
+func (ts *Service) taskVersionLink(id string, version int) client.Link {
+	return client.Link{Relation: client.Self, Href: path.Join(httpd.BasePath, tasksPath, id, versionsPath, strconv.Itoa(version))}
+}
+
+func (ts *Service) convertVersion(v TaskVersion) (client.TaskVersion, error) {
+	var typ client.TaskType
+	switch v.Type {
+	case StreamTask:
+		typ = client.StreamTask
+	case BatchTask:
+		typ = client.BatchTask
+	default:
+		return client.TaskVersion{}, fmt.Errorf("invalid task type %v", v.Type)
+	}
+
+	dbrps := make([]client.DBRP, len(v.DBRPs))
+	for i, dbrp := range v.DBRPs {
+		dbrps[i] = client.DBRP{
+			Database:        dbrp.Database,
+			RetentionPolicy: dbrp.RetentionPolicy,
+		}
+	}
+
+	vars, err := ts.convertToClientVars(v.Vars)
+	if err != nil {
+		return client.TaskVersion{}, err
+	}
+
+	return client.TaskVersion{
+		Link:       ts.taskVersionLink(v.TaskID, v.Version),
+		Version:    v.Version,
+		TemplateID: v.TemplateID,
+		Type:       typ,
+		DBRPs:      dbrps,
+		TICKscript: v.TICKscript,
+		Vars:       vars,
+		Created:    v.Created,
+	}, nil
+}
+
+// handleTaskVersions serves the versions of a task,
+// p is the path following the task ID.
+func (ts *Service) handleTaskVersions(w http.ResponseWriter, r *http.Request, id, p string) {
+	parts := strings.Split(p, "/")
+	if parts[0] != versionsPath || len(parts) > 2 {
+		httpd.HttpError(w, fmt.Sprintf("unknown path %q for task %s", p, id), true, http.StatusNotFound)
+		return
+	}
+
+	if _, err := ts.tasks.Get(id); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 2 {
+		version, err := strconv.Atoi(parts[1])
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid version %q must be an integer", parts[1]), true, http.StatusBadRequest)
+			return
+		}
+		v, err := ts.versions.Get(id, version)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("task %s has no version %d", id, version), true, http.StatusNotFound)
+			return
+		}
+		cv, err := ts.convertVersion(v)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid task version stored in db: %s", err.Error()), true, http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(httpd.MarshalJSON(cv, true))
+		return
+	}
+
+	versions, err := ts.versions.List(id)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	type response struct {
+		Versions []client.TaskVersion `json:"versions"`
+	}
+	res := response{Versions: make([]client.TaskVersion, len(versions))}
+	for i, v := range versions {
+		res.Versions[i], err = ts.convertVersion(v)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid task version stored in db: %s", err.Error()), true, http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(res, true))
+}
+
+func (ts *Service) handleRollbackTask(w http.ResponseWriter, r *http.Request) {
+	p, err := ts.taskIDFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	id := path.Dir(p)
+	if path.Base(p) != rollbackPath || id == "." {
+		httpd.HttpError(w, fmt.Sprintf("unknown path %q", r.URL.Path), true, http.StatusNotFound)
+		return
+	}
+
+	opts := client.RollbackTaskOptions{}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&opts); err != nil {
+		httpd.HttpError(w, "invalid JSON", true, http.StatusBadRequest)
+		return
+	}
+
+	original, err := ts.tasks.Get(id)
+	if err != nil {
+		httpd.HttpError(w, "task does not exist, cannot rollback", true, http.StatusNotFound)
+		return
+	}
+	v, err := ts.versions.Get(id, opts.Version)
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("task %s has no version %d", id, opts.Version), true, http.StatusNotFound)
+		return
+	}
+
+	updated := original
+	updated.Type = v.Type
+	updated.DBRPs = v.DBRPs
+	updated.TICKscript = v.TICKscript
+	updated.TemplateID = v.TemplateID
+	updated.Vars = v.Vars
+	if v.TemplateID != "" {
+		// The script of templated tasks always comes from the template.
+		template, err := ts.templates.Get(v.TemplateID)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("unknown template %s of version %d: err: %s", v.TemplateID, v.Version, err), true, http.StatusBadRequest)
+			return
+		}
+		updated.Type = template.Type
+		updated.TICKscript = template.TICKscript
+	}
+
+	// Validate task
+	if _, err := ts.newKapacitorTask(updated); err != nil {
+		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	if original.TemplateID != updated.TemplateID {
+		if original.TemplateID != "" {
+			if err := ts.templates.DisassociateTask(original.TemplateID, id); err != nil {
+				httpd.HttpError(w, fmt.Sprintf("failed to disassociate task with template: %s", err), true, http.StatusBadRequest)
+				return
+			}
+		}
+		if updated.TemplateID != "" {
+			if err := ts.templates.AssociateTask(updated.TemplateID, id); err != nil {
+				httpd.HttpError(w, fmt.Sprintf("failed to associate task with template: %s", err), true, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	updated.Modified = time.Now()
+	if err := ts.tasks.Replace(updated); err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to replace task definition: %s", err.Error()), true, http.StatusInternalServerError)
+		return
+	}
+	ts.saveVersion(original)
+	ts.saveVersion(updated)
+
+	if updated.Status == Enabled {
+		// Reload the task with the restored definition
+		ts.stopTask(id)
+		if err := ts.startTask(updated); err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	t, err := ts.convertTask(updated, "formatted", "attributes", ts.TaskMasterLookup.Main())
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(t, true))
+}
@@ -0,0 +1,172 @@
+package task_store
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/kapacitor"
+	"github.com/influxdata/kapacitor/alert"
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+)
+
+const (
+	watchPath = "watch"
+
+	defaultWatchInterval = 10 * time.Second
+	minWatchInterval     = time.Second
+
+	// Number of errors and alert events buffered for a slow client,
+	// the next ones are dropped until the client catches up.
+	watchBufferSize = 1000
+)
+
+// handleTaskWatch streams the live feed of a task, the errors of its nodes, the alert events it emits
+// and its execution statistics at each interval, until the client disconnects.
+// The entries are JSON objects one per line, or server-sent events if the client accepts text/event-stream.
+func (ts *Service) handleTaskWatch(w http.ResponseWriter, r *http.Request, id string) {
+	if _, err := ts.tasks.Get(id); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+
+	interval := defaultWatchInterval
+	if i := r.URL.Query().Get("interval"); i != "" {
+		var err error
+		interval, err = time.ParseDuration(i)
+		if err != nil || interval < minWatchInterval {
+			httpd.HttpError(w, fmt.Sprintf("invalid interval %q must be a duration of at least %v", i, minWatchInterval), true, http.StatusBadRequest)
+			return
+		}
+	}
+	sse := r.Header.Get("Accept") == "text/event-stream"
+
+	events := make(chan client.TaskWatchEvent, watchBufferSize)
+	send := func(e client.TaskWatchEvent) {
+		select {
+		case events <- e:
+		default:
+		}
+	}
+	if ts.LogWatcher != nil {
+		cancel := ts.LogWatcher.Watch(map[string]string{
+			"task_master": kapacitor.MainTaskMaster,
+			"task":        id,
+			"lvl":         "error",
+		}, func(m map[string]interface{}) {
+			send(nodeErrorEvent(m))
+		})
+		defer cancel()
+	}
+	if ts.AlertService != nil {
+		cancel := ts.AlertService.Watch(func(e alert.Event) {
+			if e.Data.TaskName != id {
+				return
+			}
+			send(client.TaskWatchEvent{
+				Type: client.TaskWatchAlert,
+				Time: time.Now().UTC(),
+				Alert: &client.TaskAlert{
+					Topic: e.Topic,
+					ID:    e.State.ID,
+					State: client.EventState{
+						Message:  e.State.Message,
+						Details:  e.State.Details,
+						Time:     e.State.Time,
+						Duration: client.Duration(e.State.Duration),
+						Level:    e.State.Level.String(),
+					},
+				},
+			})
+		})
+		defer cancel()
+	}
+
+	if sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Header().Add("Transfer-Encoding", "chunked")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	write := func(e client.TaskWatchEvent) error {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if sse {
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+		} else {
+			_, err = fmt.Fprintf(w, "%s\n", data)
+		}
+		if err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+	writeStats := func() error {
+		stats := ts.taskStats(id)
+		return write(client.TaskWatchEvent{
+			Type:  client.TaskWatchStats,
+			Time:  time.Now().UTC(),
+			Stats: &stats,
+		})
+	}
+
+	// The feed starts with the statistics so the client shows the state of the task right away.
+	if err := writeStats(); err != nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case e := <-events:
+			if err := write(e); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := writeStats(); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// nodeErrorEvent returns the entry of the live feed of an error logged by a node.
+func nodeErrorEvent(m map[string]interface{}) client.TaskWatchEvent {
+	e := client.TaskWatchEvent{
+		Type:  client.TaskWatchError,
+		Time:  time.Now().UTC(),
+		Error: &client.NodeError{},
+	}
+	for k, v := range m {
+		s, _ := v.(string)
+		switch k {
+		case "ts":
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				e.Time = t.UTC()
+			}
+		case "node":
+			e.Error.Node = s
+		case "msg":
+			e.Error.Message = s
+		case "err":
+			e.Error.Error = s
+		case "lvl", "task", "task_master", "service":
+		default:
+			if e.Error.Fields == nil {
+				e.Error.Fields = make(map[string]interface{})
+			}
+			e.Error.Fields[k] = v
+		}
+	}
+	return e
+}
@@ -0,0 +1,133 @@
+package tokens
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/influxdata/kapacitor/services/storage"
+)
+
+var (
+	ErrTokenExists   = errors.New("token already exists")
+	ErrNoTokenExists = errors.New("no token exists")
+)
+
+// Data access object for API tokens.
+type TokenDAO interface {
+	// Retrieve a token
+	Get(id string) (Token, error)
+
+	// Create a token.
+	// ErrTokenExists is returned if a token already exists with the same ID.
+	Create(t Token) error
+
+	// Delete a token.
+	// It is not an error to delete an non-existent token.
+	Delete(id string) error
+
+	// List all tokens.
+	List() ([]Token, error)
+
+	Rebuild() error
+}
+
+//--------------------------------------------------------------------
+// The following structures are stored in a database via JSON encoding.
+// Changes to the structures could break existing data.
+
+// version is the current version of the stored structures.
+const version = 1
+
+type Token struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	// SHA-256 hash of the secret token
+	Hash    []byte    `json:"hash"`
+	Owner   string    `json:"owner"`
+	Scopes  []string  `json:"scopes"`
+	Created time.Time `json:"created"`
+	// Zero if the token never expires
+	Expires time.Time `json:"expires"`
+}
+
+func (t Token) ObjectID() string {
+	return t.ID
+}
+
+func (t Token) MarshalBinary() ([]byte, error) {
+	return storage.VersionJSONEncode(version, t)
+}
+
+func (t *Token) UnmarshalBinary(data []byte) error {
+	return storage.VersionJSONDecode(data, func(version int, dec *json.Decoder) error {
+		return dec.Decode(t)
+	})
+}
+
+// Key/Value store based implementation of the TokenDAO
+type tokenKV struct {
+	store *storage.IndexedStore
+}
+
+func newTokenKV(store storage.Interface) (*tokenKV, error) {
+	c := storage.DefaultIndexedStoreConfig("tokens", func() storage.BinaryObject {
+		return new(Token)
+	})
+	istore, err := storage.NewIndexedStore(store, c)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenKV{
+		store: istore,
+	}, nil
+}
+
+func (kv *tokenKV) error(err error) error {
+	if err == storage.ErrNoObjectExists {
+		return ErrNoTokenExists
+	} else if err == storage.ErrObjectExists {
+		return ErrTokenExists
+	}
+	return err
+}
+
+func (kv *tokenKV) Get(id string) (Token, error) {
+	obj, err := kv.store.Get(id)
+	if err != nil {
+		return Token{}, kv.error(err)
+	}
+	t, ok := obj.(*Token)
+	if !ok {
+		return Token{}, storage.ImpossibleTypeErr(t, obj)
+	}
+	return *t, nil
+}
+
+func (kv *tokenKV) Create(t Token) error {
+	return kv.error(kv.store.Create(&t))
+}
+
+func (kv *tokenKV) Delete(id string) error {
+	return kv.error(kv.store.Delete(id))
+}
+
+func (kv *tokenKV) List() ([]Token, error) {
+	objects, err := kv.store.List(storage.DefaultIDIndex, "", 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]Token, len(objects))
+	for i, object := range objects {
+		t, ok := object.(*Token)
+		if !ok {
+			return nil, storage.ImpossibleTypeErr(t, object)
+		}
+		tokens[i] = *t
+	}
+	return tokens, nil
+}
+
+func (kv *tokenKV) Rebuild() error {
+	return kv.store.Rebuild()
+}
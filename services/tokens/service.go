@@ -0,0 +1,301 @@
+// Package tokens provides API tokens, which authenticate automation without the credentials of a user.
+//
+// Each token grants a set of scopes and may expire. Only the SHA-256 hash of a token is stored,
+// the secret token is returned once when it is created.
+// Only admin users are allowed to manage tokens,
+// and a token is only valid while the user who created it exists and is an admin.
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/kapacitor/auth"
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/services/storage"
+	"github.com/pkg/errors"
+)
+
+const (
+	tokensPath         = "/tokens"
+	tokensPathAnchored = "/tokens/"
+	tokensBasePath     = httpd.BasePath + tokensPathAnchored
+
+	// Public name of the store
+	tokensAPIName = "tokens"
+	// The storage namespace for all token data.
+	tokensNamespace = "tokens"
+
+	// Number of random bytes of the ID and of the secret of a token.
+	idSize     = 8
+	secretSize = 32
+)
+
+type Service struct {
+	routes []httpd.Route
+	tokens TokenDAO
+
+	StorageService interface {
+		Store(namespace string) storage.Interface
+		Register(name string, store storage.StoreActioner)
+	}
+	AuthService interface {
+		User(username string) (auth.User, error)
+	}
+	HTTPDService interface {
+		AddRoutes([]httpd.Route) error
+		DelRoutes([]httpd.Route)
+	}
+}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+func (s *Service) Open() error {
+	tokens, err := newTokenKV(s.StorageService.Store(tokensNamespace))
+	if err != nil {
+		return err
+	}
+	s.tokens = tokens
+	s.StorageService.Register(tokensAPIName, s.tokens)
+
+	// Define API routes, they are restricted to admin users
+	// since the privileges of a user on the tokens resource must not allow it to grant tokens.
+	s.routes = []httpd.Route{
+		{
+			Method:      "GET",
+			Pattern:     tokensPathAnchored,
+			HandlerFunc: s.handleToken,
+			AdminOnly:   true,
+		},
+		{
+			Method:      "DELETE",
+			Pattern:     tokensPathAnchored,
+			HandlerFunc: s.handleRevokeToken,
+			AdminOnly:   true,
+		},
+		{
+			Method:      "GET",
+			Pattern:     tokensPath,
+			HandlerFunc: s.handleListTokens,
+			AdminOnly:   true,
+		},
+		{
+			Method:      "POST",
+			Pattern:     tokensPath,
+			HandlerFunc: s.handleCreateToken,
+			AdminOnly:   true,
+		},
+	}
+
+	err = s.HTTPDService.AddRoutes(s.routes)
+	return errors.Wrap(err, "failed to add API routes")
+}
+
+func (s *Service) Close() error {
+	if s.HTTPDService != nil {
+		s.HTTPDService.DelRoutes(s.routes)
+	}
+	return nil
+}
+
+// Authenticate returns a user granted the scopes of the token,
+// the user has the name of the owner of the token but none of its privileges.
+func (s *Service) Authenticate(token string) (auth.User, error) {
+	id, err := tokenID(token)
+	if err != nil {
+		return auth.User{}, err
+	}
+	t, err := s.tokens.Get(id)
+	if err == ErrNoTokenExists {
+		return auth.User{}, errors.New("unknown token")
+	} else if err != nil {
+		return auth.User{}, err
+	}
+	if hash := sha256.Sum256([]byte(token)); subtle.ConstantTimeCompare(hash[:], t.Hash) != 1 {
+		return auth.User{}, errors.New("unknown token")
+	}
+	if !t.Expires.IsZero() && !time.Now().Before(t.Expires) {
+		return auth.User{}, errors.New("token is expired")
+	}
+	// The owner is looked up every time, so deleting the owner or revoking its admin privileges disables its tokens.
+	owner, err := s.AuthService.User(t.Owner)
+	if err != nil {
+		return auth.User{}, fmt.Errorf("owner %q of the token does not exist", t.Owner)
+	}
+	if !owner.IsAdmin() {
+		return auth.User{}, fmt.Errorf("owner %q of the token is no longer an admin", t.Owner)
+	}
+	scopes := make([]auth.Scope, len(t.Scopes))
+	for i, scope := range t.Scopes {
+		scopes[i] = auth.Scope(scope)
+	}
+	return auth.NewUser(t.Owner, nil, false, nil).WithScopes(scopes...), nil
+}
+
+// newToken returns a new random token and its ID.
+// Tokens have the form <prefix><id>_<secret>.
+func newToken() (id, token string, err error) {
+	b := make([]byte, idSize+secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	id = hex.EncodeToString(b[:idSize])
+	token = httpd.APITokenPrefix + id + "_" + base64.RawURLEncoding.EncodeToString(b[idSize:])
+	return id, token, nil
+}
+
+// tokenID returns the ID of a token.
+func tokenID(token string) (string, error) {
+	if !strings.HasPrefix(token, httpd.APITokenPrefix) {
+		return "", errors.New("not an API token")
+	}
+	token = token[len(httpd.APITokenPrefix):]
+	i := strings.IndexByte(token, '_')
+	if i != 2*idSize {
+		return "", errors.New("malformed API token")
+	}
+	return token[:i], nil
+}
+
+func (s *Service) tokenLink(id string) client.Link {
+	return client.Link{Relation: client.Self, Href: path.Join(httpd.BasePath, tokensPath, id)}
+}
+
+func (s *Service) convertToken(t Token) client.APIToken {
+	scopes := t.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return client.APIToken{
+		Link:        s.tokenLink(t.ID),
+		ID:          t.ID,
+		Description: t.Description,
+		Owner:       t.Owner,
+		Scopes:      scopes,
+		Created:     t.Created,
+		Expires:     t.Expires,
+	}
+}
+
+func idFromPath(p string) (string, error) {
+	if len(p) <= len(tokensBasePath) {
+		return "", errors.New("must specify token id on path")
+	}
+	return p[len(tokensBasePath):], nil
+}
+
+func (s *Service) handleToken(w http.ResponseWriter, r *http.Request) {
+	id, err := idFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	t, err := s.tokens.Get(id)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	w.Write(httpd.MarshalJSON(s.convertToken(t), true))
+}
+
+func (s *Service) handleListTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := s.tokens.List()
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to list tokens: %s", err), true, http.StatusInternalServerError)
+		return
+	}
+	// Tokens have random IDs, list the newest last.
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].Created.Before(tokens[j].Created)
+	})
+	type response struct {
+		Tokens []client.APIToken `json:"tokens"`
+	}
+	res := response{Tokens: make([]client.APIToken, len(tokens))}
+	for i, t := range tokens {
+		res.Tokens[i] = s.convertToken(t)
+	}
+	w.Write(httpd.MarshalJSON(res, true))
+}
+
+func (s *Service) handleCreateToken(w http.ResponseWriter, r *http.Request, user auth.User) {
+	opt := client.CreateAPITokenOptions{}
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		httpd.HttpError(w, "invalid JSON: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if len(opt.Scopes) == 0 {
+		httpd.HttpError(w, "must grant at least one scope", true, http.StatusBadRequest)
+		return
+	}
+	for _, scope := range opt.Scopes {
+		if !auth.ValidScope(auth.Scope(scope)) {
+			httpd.HttpError(w, fmt.Sprintf("unknown scope %q", scope), true, http.StatusBadRequest)
+			return
+		}
+		// A token cannot grant more than its owner has.
+		if err := user.AuthorizeScope(auth.Scope(scope)); err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusForbidden)
+			return
+		}
+	}
+	if opt.Duration < 0 {
+		httpd.HttpError(w, "duration must not be negative", true, http.StatusBadRequest)
+		return
+	}
+
+	id, token, err := newToken()
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	hash := sha256.Sum256([]byte(token))
+	t := Token{
+		ID:          id,
+		Description: opt.Description,
+		Hash:        hash[:],
+		Owner:       user.Name(),
+		Scopes:      opt.Scopes,
+		Created:     time.Now().UTC(),
+	}
+	if opt.Duration > 0 {
+		t.Expires = t.Created.Add(time.Duration(opt.Duration))
+	}
+	if err := s.tokens.Create(t); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	ct := s.convertToken(t)
+	ct.Token = token
+	w.Write(httpd.MarshalJSON(ct, true))
+}
+
+func (s *Service) handleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	id, err := idFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if _, err := s.tokens.Get(id); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	if err := s.tokens.Delete(id); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
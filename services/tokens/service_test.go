@@ -0,0 +1,176 @@
+package tokens_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/auth"
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd/httpdtest"
+	"github.com/influxdata/kapacitor/services/storage/storagetest"
+	"github.com/influxdata/kapacitor/services/tokens"
+)
+
+// authService has the users by name.
+type authService struct {
+	mu    sync.Mutex
+	users map[string]auth.User
+}
+
+func (s *authService) User(username string) (auth.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[username]
+	if !ok {
+		return auth.User{}, fmt.Errorf("user %q does not exist", username)
+	}
+	return u, nil
+}
+
+func (s *authService) SetUser(u auth.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[u.Name()] = u
+}
+
+func (s *authService) DeleteUser(username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.users, username)
+}
+
+// OpenNewService opens the service with the admin user making the requests of the client.
+func OpenNewService() (*tokens.Service, *authService, *httpdtest.Server, *client.Client) {
+	users := &authService{users: map[string]auth.User{auth.AdminUser.Name(): auth.AdminUser}}
+	service := tokens.NewService()
+	service.StorageService = storagetest.New()
+	service.AuthService = users
+	server := httpdtest.NewServer(testing.Verbose())
+	service.HTTPDService = server
+	if err := service.Open(); err != nil {
+		panic(err)
+	}
+	cli, err := client.New(client.Config{URL: server.Server.URL})
+	if err != nil {
+		panic(err)
+	}
+	return service, users, server, cli
+}
+
+func TestService_Tokens(t *testing.T) {
+	service, _, server, cli := OpenNewService()
+	defer server.Close()
+	defer service.Close()
+
+	if _, err := cli.CreateAPIToken(client.CreateAPITokenOptions{
+		Scopes: []string{"tasks:everything"},
+	}); err == nil {
+		t.Error("expected error creating a token with an unknown scope")
+	}
+	if _, err := cli.CreateAPIToken(client.CreateAPITokenOptions{}); err == nil {
+		t.Error("expected error creating a token without scopes")
+	}
+
+	token, err := cli.CreateAPIToken(client.CreateAPITokenOptions{
+		Description: "ci",
+		Scopes:      []string{"tasks:read", "tasks:write"},
+		Duration:    client.Duration(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Token == "" {
+		t.Fatal("expected the secret token on creation")
+	}
+	if got, exp := token.Expires.Sub(token.Created), time.Hour; got != exp {
+		t.Errorf("unexpected expiration: got %v exp %v", got, exp)
+	}
+
+	user, err := service.Authenticate(token.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.IsAdmin() {
+		t.Error("expected token user not to be an admin")
+	}
+	if got, exp := user.Scopes(), []auth.Scope{auth.TasksReadScope, auth.TasksWriteScope}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected scopes: got %v exp %v", got, exp)
+	}
+	if _, err := service.Authenticate(token.Token + "x"); err == nil {
+		t.Error("expected error authenticating with a wrong secret")
+	}
+
+	got, err := cli.APIToken(token.Link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Token != "" {
+		t.Error("expected the secret token not to be returned after creation")
+	}
+	if got.Description != "ci" || got.ID != token.ID {
+		t.Errorf("unexpected token %v", got)
+	}
+
+	expired, err := cli.CreateAPIToken(client.CreateAPITokenOptions{
+		Scopes:   []string{"alerts:read"},
+		Duration: client.Duration(time.Nanosecond),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Authenticate(expired.Token); err == nil {
+		t.Error("expected error authenticating with an expired token")
+	}
+
+	list, err := cli.ListAPITokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != token.ID || list[1].ID != expired.ID {
+		t.Errorf("unexpected tokens %v", list)
+	}
+
+	if err := cli.RevokeAPIToken(token.Link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Authenticate(token.Token); err == nil {
+		t.Error("expected error authenticating with a revoked token")
+	}
+	if err := cli.RevokeAPIToken(token.Link); err == nil {
+		t.Error("expected error revoking an unknown token")
+	}
+}
+
+func TestService_TokenOwner(t *testing.T) {
+	service, users, server, cli := OpenNewService()
+	defer server.Close()
+	defer service.Close()
+
+	token, err := cli.CreateAPIToken(client.CreateAPITokenOptions{
+		Scopes: []string{"tasks:read"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Authenticate(token.Token); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := auth.AdminUser.Name()
+	users.SetUser(auth.NewUser(owner, nil, false, nil))
+	if _, err := service.Authenticate(token.Token); err == nil || !strings.Contains(err.Error(), "is no longer an admin") {
+		t.Errorf("unexpected error authenticating with the token of a user who is no longer an admin: %v", err)
+	}
+	users.DeleteUser(owner)
+	if _, err := service.Authenticate(token.Token); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("unexpected error authenticating with the token of a deleted user: %v", err)
+	}
+	users.SetUser(auth.AdminUser)
+	if _, err := service.Authenticate(token.Token); err != nil {
+		t.Errorf("unexpected error authenticating with the token of an admin again: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Default OTLP/HTTP traces endpoint of a local OpenTelemetry collector.
+	DefaultURL           = "http://localhost:4318/v1/traces"
+	DefaultServiceName   = "kapacitor"
+	DefaultSampleRate    = 0.01
+	DefaultBatchSize     = 512
+	DefaultFlushInterval = 5 * time.Second
+	DefaultTimeout       = 10 * time.Second
+)
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+	// URL of the OTLP/HTTP traces endpoint the spans are exported to.
+	URL string `toml:"url"`
+	// Name of the service of the exported spans.
+	ServiceName string `toml:"service-name"`
+	// Fraction of the ingested points and queried batches that are traced, between 0 and 1.
+	SampleRate float64 `toml:"sample-rate"`
+	// Headers of the export requests, e.g. for authentication.
+	Headers map[string]string `toml:"headers"`
+	// Maximum number of spans of an export request.
+	BatchSize int `toml:"batch-size"`
+	// How often the spans are exported.
+	FlushInterval toml.Duration `toml:"flush-interval"`
+	// Timeout of an export request.
+	Timeout toml.Duration `toml:"timeout"`
+	// Do not verify the certificate of the URL.
+	InsecureSkipVerify bool `toml:"insecure-skip-verify"`
+}
+
+func NewConfig() Config {
+	return Config{
+		URL:           DefaultURL,
+		ServiceName:   DefaultServiceName,
+		SampleRate:    DefaultSampleRate,
+		BatchSize:     DefaultBatchSize,
+		FlushInterval: toml.Duration(DefaultFlushInterval),
+		Timeout:       toml.Duration(DefaultTimeout),
+	}
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return errors.Wrapf(err, "invalid url %q", c.URL)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("invalid url %q, must be an http(s) URL", c.URL)
+	}
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		return errors.Errorf("invalid sample-rate %v, must be between 0 and 1", c.SampleRate)
+	}
+	if c.BatchSize <= 0 {
+		return errors.New("batch-size must be positive")
+	}
+	if c.FlushInterval <= 0 {
+		return errors.New("flush-interval must be positive")
+	}
+	if c.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	ktracing "github.com/influxdata/kapacitor/tracing"
+)
+
+// The request exported by the JSON marshaler of the OpenTelemetry collector (pdata v1.31.0) for the spans of TestExportRequest_Encoding.
+const otlpExportRequest = `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"kapacitor"}}]},` +
+	`"scopeSpans":[{"scope":{"name":"github.com/influxdata/kapacitor"},"spans":[` +
+	`{"traceId":"0102030405060708090a0b0c0d0e0f10","spanId":"a1a2a3a4a5a6a7a8","parentSpanId":"","name":"ingest","kind":5,` +
+	`"startTimeUnixNano":"1700000000123456789","endTimeUnixNano":"1700000000124456789","status":{}},` +
+	`{"traceId":"0102030405060708090a0b0c0d0e0f10","spanId":"b1b2b3b4b5b6b7b8","parentSpanId":"a1a2a3a4a5a6a7a8","name":"alert2","kind":1,` +
+	`"startTimeUnixNano":"1700000000123556789","endTimeUnixNano":"1700000000124356789",` +
+	`"attributes":[{"key":"kapacitor.task","value":{"stringValue":"cpu_alert"}}],"status":{"message":"failed","code":2}}]}]}]}`
+
+func TestExportRequest_Encoding(t *testing.T) {
+	start := time.Unix(1700000000, 123456789)
+	root := &ktracing.Span{
+		Name: "ingest",
+		Kind: ktracing.KindConsumer,
+		Context: ktracing.SpanContext{
+			TraceID: ktracing.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+			SpanID:  ktracing.SpanID{0xa1, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7, 0xa8},
+		},
+		Start: start,
+		End:   start.Add(time.Millisecond),
+	}
+	child := &ktracing.Span{
+		Name: "alert2",
+		Kind: ktracing.KindInternal,
+		Context: ktracing.SpanContext{
+			TraceID: root.Context.TraceID,
+			SpanID:  ktracing.SpanID{0xb1, 0xb2, 0xb3, 0xb4, 0xb5, 0xb6, 0xb7, 0xb8},
+		},
+		Parent:     root.Context.SpanID,
+		Start:      start.Add(100 * time.Microsecond),
+		End:        start.Add(900 * time.Microsecond),
+		Attributes: []ktracing.Attribute{{Key: "kapacitor.task", Value: "cpu_alert"}},
+		Error:      "failed",
+	}
+	data, err := json.Marshal(newExportRequest("kapacitor", []*ktracing.Span{root, child}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != otlpExportRequest {
+		t.Errorf("unexpected export request:\ngot %s\nexp %s", got, otlpExportRequest)
+	}
+}
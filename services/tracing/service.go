@@ -0,0 +1,242 @@
+// Package tracing exports the sampled spans of the flow of points and batches through tasks
+// to an OpenTelemetry collector, with the JSON encoding of the OTLP/HTTP protocol.
+package tracing
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ktracing "github.com/influxdata/kapacitor/tracing"
+	"github.com/pkg/errors"
+)
+
+// Number of batches of spans buffered for export, spans are dropped when the buffer is full.
+const bufferedBatches = 4
+
+type Diagnostic interface {
+	Error(msg string, err error)
+}
+
+type Service struct {
+	config     Config
+	diag       Diagnostic
+	httpClient *http.Client
+
+	spans   chan *ktracing.Span
+	dropped int64
+
+	closing chan struct{}
+	wg      sync.WaitGroup
+}
+
+func NewService(c Config, d Diagnostic) *Service {
+	return &Service{
+		config: c,
+		diag:   d,
+		httpClient: &http.Client{
+			Timeout: time.Duration(c.Timeout),
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: c.InsecureSkipVerify,
+				},
+			},
+		},
+	}
+}
+
+func (s *Service) Open() error {
+	s.spans = make(chan *ktracing.Span, bufferedBatches*s.config.BatchSize)
+	s.closing = make(chan struct{})
+	s.wg.Add(1)
+	go s.run()
+	ktracing.SetTracer(ktracing.NewTracer(s.config.SampleRate, s))
+	return nil
+}
+
+// Close stops recording spans and exports the buffered spans.
+func (s *Service) Close() error {
+	ktracing.SetTracer(nil)
+	if s.closing != nil {
+		close(s.closing)
+		s.wg.Wait()
+	}
+	return nil
+}
+
+// Export queues the span for export, it is dropped if the buffer is full.
+func (s *Service) Export(span *ktracing.Span) {
+	select {
+	case s.spans <- span:
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+}
+
+func (s *Service) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(time.Duration(s.config.FlushInterval))
+	defer ticker.Stop()
+	batch := make([]*ktracing.Span, 0, s.config.BatchSize)
+	flush := func() {
+		if dropped := atomic.SwapInt64(&s.dropped, 0); dropped > 0 {
+			s.diag.Error("dropped spans", fmt.Errorf("export buffer full, dropped %d spans", dropped))
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.export(batch); err != nil {
+			s.diag.Error("failed to export spans", err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case span := <-s.spans:
+			batch = append(batch, span)
+			if len(batch) == s.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-s.closing:
+			for {
+				select {
+				case span := <-s.spans:
+					batch = append(batch, span)
+					if len(batch) == s.config.BatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// export posts the spans to the OTLP/HTTP endpoint.
+func (s *Service) export(spans []*ktracing.Span) error {
+	data, err := json.Marshal(newExportRequest(s.config.ServiceName, spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.config.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.config.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("unexpected status code %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// The messages of the OTLP trace service, with their JSON encoding.
+// The fields are encoded like the JSON marshaler of the OpenTelemetry collector:
+// IDs are hex, enums are integers, 64 bit integers are strings,
+// and the parent span ID and the status of spans are always present.
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []attribute `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type span struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	ParentSpanID      string      `json:"parentSpanId"`
+	Name              string      `json:"name"`
+	Kind              int         `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []attribute `json:"attributes,omitempty"`
+	Status            status      `json:"status"`
+}
+
+type attribute struct {
+	Key   string         `json:"key"`
+	Value attributeValue `json:"value"`
+}
+
+type attributeValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type status struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code,omitempty"`
+}
+
+// Code of the status of a span that failed.
+const statusCodeError = 2
+
+func newExportRequest(serviceName string, spans []*ktracing.Span) exportRequest {
+	ss := make([]span, len(spans))
+	for i, s := range spans {
+		ss[i] = span{
+			TraceID:           s.Context.TraceID.String(),
+			SpanID:            s.Context.SpanID.String(),
+			Name:              s.Name,
+			Kind:              int(s.Kind),
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+		}
+		if s.Parent != (ktracing.SpanID{}) {
+			ss[i].ParentSpanID = s.Parent.String()
+		}
+		for _, a := range s.Attributes {
+			ss[i].Attributes = append(ss[i].Attributes, attribute{Key: a.Key, Value: attributeValue{StringValue: a.Value}})
+		}
+		if s.Error != "" {
+			ss[i].Status = status{Code: statusCodeError, Message: s.Error}
+		}
+	}
+	return exportRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource: resource{
+				Attributes: []attribute{{Key: "service.name", Value: attributeValue{StringValue: serviceName}}},
+			},
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: "github.com/influxdata/kapacitor"},
+				Spans: ss,
+			}},
+		}},
+	}
+}
@@ -0,0 +1,137 @@
+package tracing_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/services/diagnostic"
+	"github.com/influxdata/kapacitor/services/tracing"
+	ktracing "github.com/influxdata/kapacitor/tracing"
+)
+
+var diagService *diagnostic.Service
+
+func init() {
+	diagService = diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	diagService.Open()
+}
+
+type exportedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Kind         int    `json:"kind"`
+	Attributes   []struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+		} `json:"value"`
+	} `json:"attributes"`
+	Status *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+type exportRequest struct {
+	ResourceSpans []struct {
+		ScopeSpans []struct {
+			Spans []exportedSpan `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+func TestService_Export(t *testing.T) {
+	var mu sync.Mutex
+	var spans []exportedSpan
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, exp := r.Header.Get("Authorization"), "Bearer token"; got != exp {
+			t.Errorf("unexpected authorization header got %q exp %q", got, exp)
+		}
+		var req exportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	c := tracing.NewConfig()
+	c.Enabled = true
+	c.URL = ts.URL
+	c.SampleRate = 1
+	c.BatchSize = 1
+	c.Headers = map[string]string{"Authorization": "Bearer token"}
+	s := tracing.NewService(c, diagService.NewTracingHandler())
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	root := ktracing.Start("ingest", ktracing.KindConsumer, ktracing.SpanContext{})
+	child := ktracing.Start("alert2", ktracing.KindInternal, root.SpanContext())
+	child.SetAttribute("kapacitor.task", "test")
+	child.SetError(errors.New("failed"))
+	child.Finish()
+	root.Finish()
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ktracing.Start("ingest", ktracing.KindConsumer, ktracing.SpanContext{}) != nil {
+		t.Error("expected no spans after the service is closed")
+	}
+
+	if len(spans) != 2 {
+		t.Fatalf("unexpected number of exported spans got %d exp 2", len(spans))
+	}
+	gotChild, gotRoot := spans[0], spans[1]
+	if gotRoot.Name != "ingest" || gotRoot.Kind != int(ktracing.KindConsumer) || gotRoot.ParentSpanID != "" || gotRoot.Status == nil || gotRoot.Status.Code != 0 {
+		t.Errorf("unexpected root span %+v", gotRoot)
+	}
+	if gotRoot.TraceID != root.Context.TraceID.String() || gotRoot.SpanID != root.Context.SpanID.String() {
+		t.Errorf("unexpected ids of root span %+v", gotRoot)
+	}
+	if gotChild.Name != "alert2" || gotChild.TraceID != gotRoot.TraceID || gotChild.ParentSpanID != gotRoot.SpanID {
+		t.Errorf("unexpected child span %+v", gotChild)
+	}
+	if len(gotChild.Attributes) != 1 || gotChild.Attributes[0].Key != "kapacitor.task" || gotChild.Attributes[0].Value.StringValue != "test" {
+		t.Errorf("unexpected attributes of child span %+v", gotChild.Attributes)
+	}
+	if gotChild.Status == nil || gotChild.Status.Code != 2 || gotChild.Status.Message != "failed" {
+		t.Errorf("unexpected status of child span %+v", gotChild.Status)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		c     func(c *tracing.Config)
+		valid bool
+	}{
+		{name: "default", c: func(c *tracing.Config) {}, valid: true},
+		{name: "disabled", c: func(c *tracing.Config) { c.Enabled = false; c.URL = "" }, valid: true},
+		{name: "url", c: func(c *tracing.Config) { c.URL = "localhost:4318" }},
+		{name: "sample-rate", c: func(c *tracing.Config) { c.SampleRate = 2 }},
+		{name: "batch-size", c: func(c *tracing.Config) { c.BatchSize = 0 }},
+		{name: "flush-interval", c: func(c *tracing.Config) { c.FlushInterval = toml.Duration(0) }},
+	}
+	for _, tc := range testCases {
+		c := tracing.NewConfig()
+		c.Enabled = true
+		tc.c(&c)
+		if err := c.Validate(); (err == nil) != tc.valid {
+			t.Errorf("%s: unexpected validation error %v", tc.name, err)
+		}
+	}
+}
@@ -1,13 +1,20 @@
 package udf
 
 import (
-	"errors"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/command"
+	"github.com/influxdata/kapacitor/udf/python"
+	"github.com/pkg/errors"
 )
 
+// Scheme of gRPC addresses of unix domain sockets.
+const unixScheme = "unix://"
+
 type Config struct {
 	Functions map[string]FunctionConfig `toml:"functions"`
 }
@@ -19,10 +26,29 @@ type FunctionConfig struct {
 	// Config for connecting to domain socket
 	Socket string `toml:"socket"`
 
+	// Config for connecting to an agent serving the UDF gRPC service,
+	// either host:port or unix:///path/to/socket
+	GRPC string `toml:"grpc"`
+
 	// Config for creating process
 	Prog string            `toml:"prog"`
 	Args []string          `toml:"args"`
 	Env  map[string]string `toml:"env"`
+
+	// Config for running a Python handler with the agent bundled with Kapacitor
+	Python PythonConfig `toml:"python"`
+
+	// Config for limiting the resources of the process
+	MaxMemory    toml.Size     `toml:"max-memory"`
+	MaxCPUTime   toml.Duration `toml:"max-cpu-time"`
+	MaxOpenFiles int64         `toml:"max-open-files"`
+	Cgroup       string        `toml:"cgroup"`
+	CPUQuota     float64       `toml:"cpu-quota"`
+
+	// Config for restarting the UDF when it fails while a task is running
+	RestartAttempts  int           `toml:"restart-attempts"`
+	RestartDelay     toml.Duration `toml:"restart-delay"`
+	SnapshotInterval toml.Duration `toml:"snapshot-interval"`
 }
 
 func NewConfig() Config {
@@ -43,13 +69,108 @@ func (c FunctionConfig) Validate() error {
 	if time.Duration(c.Timeout) <= time.Millisecond {
 		return fmt.Errorf("timeout is too small: %s", c.Timeout)
 	}
+	if c.RestartAttempts < 0 {
+		return errors.New("restart-attempts must not be negative")
+	}
+	if c.RestartDelay < 0 {
+		return errors.New("restart-delay must not be negative")
+	}
+	if c.SnapshotInterval < 0 {
+		return errors.New("snapshot-interval must not be negative")
+	}
+	limits := c.Limits()
+	if err := limits.Validate(); err != nil {
+		return err
+	}
 	// We have socket config ensure the process config is empty
+	if c.Socket != "" || c.GRPC != "" {
+		if !limits.IsZero() {
+			return errors.New("resource limits are only supported for process UDFs")
+		}
+		if !c.Python.IsZero() {
+			return errors.New("python config is only supported for process UDFs")
+		}
+	}
 	if c.Socket != "" {
 		if c.Prog != "" || len(c.Args) != 0 || len(c.Env) != 0 {
 			return errors.New("both socket and process config provided")
 		}
+		if c.GRPC != "" {
+			return errors.New("both socket and grpc config provided")
+		}
+	} else if c.GRPC != "" {
+		if c.Prog != "" || len(c.Args) != 0 || len(c.Env) != 0 {
+			return errors.New("both grpc and process config provided")
+		}
+		if strings.HasPrefix(c.GRPC, unixScheme) {
+			if c.GRPC == unixScheme {
+				return errors.New("grpc unix socket path must not be empty")
+			}
+		} else if _, _, err := net.SplitHostPort(c.GRPC); err != nil {
+			return fmt.Errorf("invalid grpc address %q: %v", c.GRPC, err)
+		}
+	} else if !c.Python.IsZero() {
+		if c.Prog != "" || len(c.Args) != 0 {
+			return errors.New("both python and prog config provided")
+		}
+		if err := c.Python.Validate(); err != nil {
+			return errors.Wrap(err, "python")
+		}
 	} else if c.Prog == "" {
-		return errors.New("must set either prog or socket")
+		return errors.New("must set either prog, python, socket or grpc")
+	}
+	return nil
+}
+
+// Limits returns the resource limits of the process.
+func (c FunctionConfig) Limits() command.Limits {
+	return command.Limits{
+		Memory:    int64(c.MaxMemory),
+		CPUTime:   time.Duration(c.MaxCPUTime),
+		OpenFiles: c.MaxOpenFiles,
+		Cgroup:    c.Cgroup,
+		CPUQuota:  c.CPUQuota,
+	}
+}
+
+// PythonConfig is the config of a UDF implemented as a Python handler.
+// The handler runs with the agent bundled with Kapacitor, optionally in a virtualenv.
+type PythonConfig struct {
+	// Path of the script defining the handler.
+	Script string `toml:"script" json:"script"`
+	// Name of the handler class, required if the script defines more than one handler.
+	Handler string `toml:"handler" json:"handler"`
+	// Interpreter used to create the virtualenv, or to run the script if there is no virtualenv.
+	Interpreter string `toml:"interpreter" json:"interpreter"`
+	// Path of the virtualenv the handler runs in, created if it does not exist.
+	Virtualenv string `toml:"virtualenv" json:"virtualenv"`
+	// Path of a pip requirements file installed into the virtualenv.
+	Requirements string `toml:"requirements" json:"requirements"`
+}
+
+func (c PythonConfig) IsZero() bool {
+	return c == PythonConfig{}
+}
+
+func (c PythonConfig) Validate() error {
+	if c.Script == "" {
+		return errors.New("must set script")
 	}
 	return nil
 }
+
+// spec returns how to run the handler,
+// the requirements are installed into the virtualenv in venvDir if no virtualenv is set.
+func (c PythonConfig) spec(venvDir string) python.Spec {
+	venv := c.Virtualenv
+	if venv == "" && c.Requirements != "" {
+		venv = venvDir
+	}
+	return python.Spec{
+		Script:       c.Script,
+		Handler:      c.Handler,
+		Interpreter:  c.Interpreter,
+		Virtualenv:   venv,
+		Requirements: c.Requirements,
+	}
+}
@@ -0,0 +1,89 @@
+package udf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/kapacitor/services/udf"
+)
+
+func TestConfig_ParsePython(t *testing.T) {
+	var c udf.Config
+	if _, err := toml.Decode(`
+[functions.movingAvg]
+  timeout = "10s"
+  [functions.movingAvg.python]
+    script = "/etc/kapacitor/udf/moving_avg.py"
+    handler = "AvgHandler"
+    requirements = "/etc/kapacitor/udf/requirements.txt"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	fc := c.Functions["movingAvg"]
+	if got, exp := time.Duration(fc.Timeout), 10*time.Second; got != exp {
+		t.Errorf("unexpected timeout: got %v exp %v", got, exp)
+	}
+	exp := udf.PythonConfig{
+		Script:       "/etc/kapacitor/udf/moving_avg.py",
+		Handler:      "AvgHandler",
+		Requirements: "/etc/kapacitor/udf/requirements.txt",
+	}
+	if fc.Python != exp {
+		t.Errorf("unexpected python config:\ngot %+v\nexp %+v", fc.Python, exp)
+	}
+}
+
+func TestFunctionConfig_ValidatePython(t *testing.T) {
+	testCases := []struct {
+		name string
+		c    udf.FunctionConfig
+		err  string
+	}{
+		{
+			name: "missing script",
+			c: udf.FunctionConfig{
+				Python: udf.PythonConfig{Virtualenv: "/venv"},
+			},
+			err: "python: must set script",
+		},
+		{
+			name: "python and prog",
+			c: udf.FunctionConfig{
+				Prog:   "/usr/bin/python3",
+				Python: udf.PythonConfig{Script: "udf.py"},
+			},
+			err: "both python and prog config provided",
+		},
+		{
+			name: "python and socket",
+			c: udf.FunctionConfig{
+				Socket: "/tmp/udf.sock",
+				Python: udf.PythonConfig{Script: "udf.py"},
+			},
+			err: "python config is only supported for process UDFs",
+		},
+		{
+			name: "python with env",
+			c: udf.FunctionConfig{
+				Env:    map[string]string{"PYTHONPATH": "/lib"},
+				Python: udf.PythonConfig{Script: "udf.py"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc.c.Timeout.UnmarshalText([]byte("10s"))
+		err := tc.c.Validate()
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+			}
+		} else if err == nil || err.Error() != tc.err {
+			t.Errorf("%s: unexpected error: got %v exp %s", tc.name, err, tc.err)
+		}
+	}
+}
@@ -1,34 +1,81 @@
 package udf
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/influxdata/kapacitor"
+	"github.com/influxdata/kapacitor/auth"
 	"github.com/influxdata/kapacitor/command"
+	"github.com/influxdata/kapacitor/services/httpd"
 	"github.com/influxdata/kapacitor/udf"
+	"github.com/influxdata/kapacitor/udf/python"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+const (
+	udfsPath      = "/udfs/"
+	upgradeSuffix = "/upgrade"
 )
 
 type Diagnostic interface {
 	LoadedUDFInfo(udf string)
+	UpgradedUDF(udf string)
 
 	WithUDFContext() udf.Diagnostic
 }
 
 type Service struct {
-	configs map[string]FunctionConfig
-	infos   map[string]udf.Info
-	diag    Diagnostic
-	mu      sync.RWMutex
+	infos map[string]udf.Info
+	diag  Diagnostic
+	mu    sync.RWMutex
+
+	// Configs may change when a UDF is upgraded.
+	configMu sync.RWMutex
+	configs  map[string]FunctionConfig
+	// Closed and replaced when a UDF is upgraded.
+	upgrades map[string]chan struct{}
+
+	// Serializes upgrades.
+	upgradeMu sync.Mutex
+
+	// Connections to gRPC agents by address, shared by all uses of a UDF.
+	connMu sync.Mutex
+	conns  map[string]*grpc.ClientConn
+
+	routes []httpd.Route
+
+	// Directory of the bundled Python agent and the virtualenvs of Python UDFs.
+	Dir string
+
+	HTTPDService interface {
+		AddRoutes([]httpd.Route) error
+		DelRoutes([]httpd.Route)
+	}
 }
 
 func NewService(c Config, d Diagnostic) *Service {
+	configs := make(map[string]FunctionConfig, len(c.Functions))
+	upgrades := make(map[string]chan struct{}, len(c.Functions))
+	for name, fc := range c.Functions {
+		configs[name] = fc
+		upgrades[name] = make(chan struct{})
+	}
 	return &Service{
-		configs: c.Functions,
-		infos:   make(map[string]udf.Info),
-		diag:    d,
+		configs:  configs,
+		upgrades: upgrades,
+		infos:    make(map[string]udf.Info),
+		conns:    make(map[string]*grpc.ClientConn),
+		diag:     d,
 	}
 }
 
@@ -39,10 +86,32 @@ func (s *Service) Open() error {
 			return err
 		}
 	}
+	if s.HTTPDService != nil {
+		s.routes = []httpd.Route{
+			{
+				Method:      "POST",
+				Pattern:     udfsPath,
+				HandlerFunc: s.handleUpgrade,
+				Scope:       auth.TasksWriteScope,
+			},
+		}
+		if err := s.HTTPDService.AddRoutes(s.routes); err != nil {
+			return errors.Wrap(err, "failed to add API routes")
+		}
+	}
 	return nil
 }
 
 func (s *Service) Close() error {
+	if s.HTTPDService != nil {
+		s.HTTPDService.DelRoutes(s.routes)
+	}
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	for addr, conn := range s.conns {
+		conn.Close()
+		delete(s.conns, addr)
+	}
 	return nil
 }
 
@@ -68,11 +137,24 @@ func (s *Service) Create(
 	d udf.Diagnostic,
 	abortCallback func(),
 ) (udf.Interface, error) {
-	conf, ok := s.configs[name]
+	conf, ok := s.config(name)
 	if !ok {
 		return nil, fmt.Errorf("no such UDF %s", name)
 	}
-	if conf.Socket != "" {
+	if conf.GRPC != "" {
+		// Create gRPC UDF
+		conn, err := s.grpcConn(name, conf)
+		if err != nil {
+			return nil, err
+		}
+		return kapacitor.NewUDFSocket(
+			taskID, nodeID,
+			kapacitor.NewGRPCConn(conn, taskID, nodeID),
+			d,
+			time.Duration(conf.Timeout),
+			abortCallback,
+		), nil
+	} else if conf.Socket != "" {
 		// Create socket UDF
 		return kapacitor.NewUDFSocket(
 			taskID, nodeID,
@@ -88,9 +170,18 @@ func (s *Service) Create(
 			env = append(env, fmt.Sprintf("%s=%s", k, v))
 		}
 		cmdSpec := command.Spec{
-			Prog: conf.Prog,
-			Args: conf.Args,
-			Env:  env,
+			Prog:   conf.Prog,
+			Args:   conf.Args,
+			Env:    env,
+			Limits: conf.Limits(),
+		}
+		if !conf.Python.IsZero() {
+			pythonPath, ok := conf.Env["PYTHONPATH"]
+			if !ok {
+				pythonPath = os.Getenv("PYTHONPATH")
+			}
+			cmdSpec.Prog, cmdSpec.Args, pythonPath = python.Command(s.pythonDir(), s.pythonSpec(name, conf), pythonPath)
+			cmdSpec.Env = append(cmdSpec.Env, "PYTHONPATH="+pythonPath)
 		}
 		return kapacitor.NewUDFProcess(
 			taskID, nodeID,
@@ -103,9 +194,147 @@ func (s *Service) Create(
 	}
 }
 
+// RestartPolicy returns how the UDF is restarted when it fails.
+func (s *Service) RestartPolicy(name string) udf.RestartPolicy {
+	conf, _ := s.config(name)
+	return udf.RestartPolicy{
+		Attempts:         conf.RestartAttempts,
+		Delay:            time.Duration(conf.RestartDelay),
+		SnapshotInterval: time.Duration(conf.SnapshotInterval),
+	}
+}
+
+func (s *Service) config(name string) (FunctionConfig, bool) {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	conf, ok := s.configs[name]
+	return conf, ok
+}
+
+// Upgrades returns a channel that is closed when the UDF is upgraded.
+// Tasks using the UDF switch to the upgraded UDF once the channel is closed.
+func (s *Service) Upgrades(name string) <-chan struct{} {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.upgrades[name]
+}
+
+// Upgrade replaces how the UDF is run or connected to and signals the running tasks to switch to it.
+// The upgraded UDF is checked by loading its info before any task is switched.
+func (s *Service) Upgrade(name string, u UpgradeConfig) error {
+	s.upgradeMu.Lock()
+	defer s.upgradeMu.Unlock()
+
+	old, ok := s.config(name)
+	if !ok {
+		return fmt.Errorf("no such UDF %s", name)
+	}
+	conf := u.apply(old)
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+
+	s.configMu.Lock()
+	s.configs[name] = conf
+	s.configMu.Unlock()
+	if err := s.Refresh(name); err != nil {
+		s.configMu.Lock()
+		s.configs[name] = old
+		s.configMu.Unlock()
+		return err
+	}
+
+	s.configMu.Lock()
+	close(s.upgrades[name])
+	s.upgrades[name] = make(chan struct{})
+	s.configMu.Unlock()
+	s.diag.UpgradedUDF(name)
+	return nil
+}
+
+// UpgradeConfig is the new connection config of an upgraded UDF.
+// If none of Prog, Socket, GRPC and Python is set the UDF is restarted with its current config,
+// e.g. to use a replaced binary or to install changed requirements of a Python UDF.
+type UpgradeConfig struct {
+	Prog   string            `json:"prog"`
+	Args   []string          `json:"args"`
+	Env    map[string]string `json:"env"`
+	Socket string            `json:"socket"`
+	GRPC   string            `json:"grpc"`
+	Python *PythonConfig     `json:"python"`
+}
+
+func (u UpgradeConfig) apply(c FunctionConfig) FunctionConfig {
+	if u.Prog == "" && u.Socket == "" && u.GRPC == "" && u.Python == nil {
+		return c
+	}
+	c.Prog = u.Prog
+	c.Args = u.Args
+	c.Env = u.Env
+	c.Socket = u.Socket
+	c.GRPC = u.GRPC
+	c.Python = PythonConfig{}
+	if u.Python != nil {
+		c.Python = *u.Python
+	}
+	return c
+}
+
+// handleUpgrade handles POST /udfs/<name>/upgrade.
+func (s *Service) handleUpgrade(w http.ResponseWriter, r *http.Request) {
+	p := strings.TrimPrefix(r.URL.Path, httpd.BasePath+udfsPath)
+	if !strings.HasSuffix(p, upgradeSuffix) {
+		httpd.HttpError(w, "unknown UDF action", true, http.StatusNotFound)
+		return
+	}
+	name := path.Clean(strings.TrimSuffix(p, upgradeSuffix))
+	if _, ok := s.config(name); !ok {
+		httpd.HttpError(w, fmt.Sprintf("no such UDF %s", name), true, http.StatusNotFound)
+		return
+	}
+	u := UpgradeConfig{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			httpd.HttpError(w, "invalid JSON: "+err.Error(), true, http.StatusBadRequest)
+			return
+		}
+	}
+	if err := s.Upgrade(name, u); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// grpcConn returns the connection to the gRPC agent of the UDF, dialing it on first use.
+func (s *Service) grpcConn(name string, conf FunctionConfig) (*grpc.ClientConn, error) {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	if conn, ok := s.conns[conf.GRPC]; ok {
+		return conn, nil
+	}
+	target := conf.GRPC
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if strings.HasPrefix(target, unixScheme) {
+		target = strings.TrimPrefix(target, unixScheme)
+		opts = append(opts, grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout("unix", addr, timeout)
+		}))
+	}
+	conn, err := grpc.Dial(target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to UDF %s: %v", name, err)
+	}
+	s.conns[conf.GRPC] = conn
+	return conn, nil
+}
+
 func (s *Service) Refresh(name string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if err := s.preparePython(name); err != nil {
+		return fmt.Errorf("failed to prepare python UDF %q: %v", name, err)
+	}
 	info, err := s.loadUDFInfo(name)
 	if err != nil {
 		return fmt.Errorf("failed to load process info for %q: %v", name, err)
@@ -115,6 +344,26 @@ func (s *Service) Refresh(name string) error {
 	return nil
 }
 
+func (s *Service) pythonDir() string {
+	return filepath.Join(s.Dir, "python")
+}
+
+func (s *Service) pythonSpec(name string, conf FunctionConfig) python.Spec {
+	return conf.Python.spec(filepath.Join(s.Dir, "venvs", name))
+}
+
+// preparePython installs the bundled agent and the virtualenv of a Python UDF.
+func (s *Service) preparePython(name string) error {
+	conf, _ := s.config(name)
+	if conf.Python.IsZero() {
+		return nil
+	}
+	if err := python.Install(s.pythonDir()); err != nil {
+		return errors.Wrap(err, "failed to install agent")
+	}
+	return python.Prepare(s.pythonSpec(name, conf))
+}
+
 func (s *Service) loadUDFInfo(name string) (udf.Info, error) {
 	// loadUDFInfo creates a UDF connection outside the context of a task or node
 	// because it only makes the Info request and never makes an Init request.
@@ -1,5 +1,11 @@
 package udp
 
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
 const (
 	// The number of packets to buffer when reading off the socket.
 	// A buffer of this size will be allocated for each instance of a UDP service.
@@ -14,6 +20,39 @@ type Config struct {
 
 	Database        string `toml:"database"`
 	RetentionPolicy string `toml:"retention-policy"`
+
+	// IPs or CIDR ranges the packets are accepted from, packets from any source are accepted if empty.
+	AllowedSources []string `toml:"allowed-sources"`
+}
+
+func (c Config) Validate() error {
+	_, err := ParseAllowedSources(c.AllowedSources)
+	return err
+}
+
+// ParseAllowedSources parses the IPs or CIDR ranges of the allowed sources of packets.
+func ParseAllowedSources(sources []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(sources))
+	for _, s := range sources {
+		if strings.Contains(s, "/") {
+			_, n, err := net.ParseCIDR(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowed source %q: %v", s, err)
+			}
+			nets = append(nets, n)
+			continue
+		}
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid allowed source %q, must be an IP or a CIDR range", s)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return nets, nil
 }
 
 // WithDefaults takes the given config and returns a new config with any required
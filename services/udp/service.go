@@ -6,10 +6,12 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/kapacitor/expvar"
 	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/lineprotocol"
 	"github.com/influxdata/kapacitor/server/vars"
 )
 
@@ -25,8 +27,20 @@ const (
 	statReadFail          = "read_fail"
 	statPointsTransmitted = "points_tx"
 	statTransmitFail      = "tx_fail"
+	statBackpressure      = "backpressure"
+	statSourceRejected    = "source_rejected"
 )
 
+// backpressureError is returned by the PointsWriter while it cannot accept points.
+type backpressureError interface {
+	RetryAfter() time.Duration
+}
+
+// lineProtocolWriter is implemented by a PointsWriter that writes parsed line protocol points.
+type lineProtocolWriter interface {
+	WriteLineProtocol(database, retentionPolicy string, points *lineprotocol.Points) error
+}
+
 type Diagnostic interface {
 	Error(msg string, err error, ctx ...keyvalue.T)
 	StartedListening(addr string)
@@ -46,6 +60,8 @@ type Service struct {
 	packets chan []byte
 
 	config Config
+	// allowed are the sources the packets are accepted from, any source if empty.
+	allowed []*net.IPNet
 
 	PointsWriter interface {
 		WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
@@ -73,6 +89,10 @@ func (s *Service) Open() (err error) {
 	if s.config.Database == "" {
 		return errors.New("database has to be specified in config")
 	}
+	s.allowed, err = ParseAllowedSources(s.config.AllowedSources)
+	if err != nil {
+		return err
+	}
 
 	s.addr, err = net.ResolveUDPAddr("udp", s.config.BindAddress)
 	if err != nil {
@@ -129,7 +149,7 @@ func (s *Service) serve() {
 			// Keep processing.
 		}
 
-		n, _, err := s.conn.ReadFromUDP(buf)
+		n, addr, err := s.conn.ReadFromUDP(buf)
 		if err != nil {
 			if !strings.Contains(err.Error(), "use of closed network connection") {
 				s.statMap.Add(statReadFail, 1)
@@ -137,6 +157,10 @@ func (s *Service) serve() {
 			}
 			continue
 		}
+		if !s.allowedSource(addr.IP) {
+			s.statMap.Add(statSourceRejected, 1)
+			continue
+		}
 		s.statMap.Add(statBytesReceived, int64(n))
 		p := make([]byte, n)
 		copy(p, buf[:n])
@@ -144,30 +168,87 @@ func (s *Service) serve() {
 	}
 }
 
+// allowedSource reports whether packets are accepted from the IP.
+func (s *Service) allowedSource(ip net.IP) bool {
+	if len(s.allowed) == 0 {
+		return true
+	}
+	for _, n := range s.allowed {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Service) processPackets() {
 	defer s.wg.Done()
 
+	// Parse into reused points when the writer accepts them.
+	lw, lineProtocol := s.PointsWriter.(lineProtocolWriter)
+	var lp *lineprotocol.Points
+	if lineProtocol {
+		lp = lineprotocol.Get()
+		defer lineprotocol.Put(lp)
+	}
+
 	for p := range s.packets {
-		points, err := models.ParsePoints(p)
+		var (
+			write func() error
+			n     int
+			err   error
+		)
+		if lineProtocol {
+			err = lp.Parse(p, time.Now().UTC(), "n")
+			n = lp.Len()
+			write = func() error {
+				return lw.WriteLineProtocol(s.config.Database, s.config.RetentionPolicy, lp)
+			}
+		} else {
+			var points []models.Point
+			points, err = models.ParsePoints(p)
+			n = len(points)
+			write = func() error {
+				return s.PointsWriter.WritePoints(
+					s.config.Database,
+					s.config.RetentionPolicy,
+					models.ConsistencyLevelAll,
+					points,
+				)
+			}
+		}
 		if err != nil {
 			s.statMap.Add(statPointsParseFail, 1)
 			s.Diag.Error("failed to parse points", err)
 			continue
 		}
 
-		if err := s.PointsWriter.WritePoints(
-			s.config.Database,
-			s.config.RetentionPolicy,
-			models.ConsistencyLevelAll,
-			points,
-		); err == nil {
-			s.statMap.Add(statPointsTransmitted, int64(len(points)))
+		if err := s.writePoints(write); err == nil {
+			s.statMap.Add(statPointsTransmitted, int64(n))
 		} else {
 			s.Diag.Error("failed to write points to database", err, keyvalue.KV("database", s.config.Database))
 			s.statMap.Add(statTransmitFail, 1)
 		}
 
-		s.statMap.Add(statPointsReceived, int64(len(points)))
+		s.statMap.Add(statPointsReceived, int64(n))
+	}
+}
+
+// writePoints writes the points with write, retrying while the PointsWriter signals backpressure.
+// Reading packets pauses meanwhile, once the packet buffer is full.
+func (s *Service) writePoints(write func() error) error {
+	for {
+		err := write()
+		bp, ok := err.(backpressureError)
+		if !ok {
+			return err
+		}
+		s.statMap.Add(statBackpressure, 1)
+		select {
+		case <-s.done:
+			return err
+		case <-time.After(bp.RetryAfter()):
+		}
 	}
 }
 
@@ -0,0 +1,99 @@
+package udp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/keyvalue"
+)
+
+type diag struct{}
+
+func (diag) Error(msg string, err error, ctx ...keyvalue.T) {}
+func (diag) StartedListening(addr string)                   {}
+func (diag) ClosedService()                                 {}
+
+type pointsWriter chan []models.Point
+
+func (w pointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	w <- points
+	return nil
+}
+
+func TestService_AllowedSources(t *testing.T) {
+	testCases := []struct {
+		allowed  []string
+		accepted bool
+	}{
+		{allowed: nil, accepted: true},
+		{allowed: []string{"127.0.0.1"}, accepted: true},
+		{allowed: []string{"10.0.0.0/8", "127.0.0.0/8"}, accepted: true},
+		{allowed: []string{"10.0.0.0/8"}, accepted: false},
+		{allowed: []string{"::1"}, accepted: false},
+	}
+	for _, tc := range testCases {
+		s := NewService(Config{
+			BindAddress:    "127.0.0.1:0",
+			Database:       "db",
+			AllowedSources: tc.allowed,
+		}, diag{})
+		written := make(pointsWriter, 1)
+		s.PointsWriter = written
+		if err := s.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		conn, err := net.DialUDP("udp", nil, s.Addr())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Write([]byte("cpu value=1\n")); err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+
+		if tc.accepted {
+			select {
+			case points := <-written:
+				if len(points) != 1 {
+					t.Errorf("%v: unexpected points %v", tc.allowed, points)
+				}
+			case <-time.After(5 * time.Second):
+				t.Errorf("%v: timed out waiting for the points", tc.allowed)
+			}
+		} else {
+			timeout := time.After(5 * time.Second)
+			for s.statMap.Get(statSourceRejected) == nil {
+				select {
+				case <-timeout:
+					t.Fatalf("%v: timed out waiting for the packet to be rejected", tc.allowed)
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+			select {
+			case points := <-written:
+				t.Errorf("%v: unexpected points written %v", tc.allowed, points)
+			default:
+			}
+		}
+		s.Close()
+	}
+}
+
+func TestParseAllowedSources(t *testing.T) {
+	nets, err := ParseAllowedSources([]string{"10.1.2.3", "192.168.0.0/16", "fe80::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"10.1.2.3/32", "192.168.0.0/16", "fe80::1/128"}
+	for i, n := range nets {
+		if n.String() != exp[i] {
+			t.Errorf("unexpected source %d: got %s exp %s", i, n, exp[i])
+		}
+	}
+	if _, err := ParseAllowedSources([]string{"example.com"}); err == nil {
+		t.Error("expected error for a host name")
+	}
+}
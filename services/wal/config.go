@@ -0,0 +1,63 @@
+package wal
+
+import (
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultDir           = "./wal"
+	DefaultFsyncInterval = time.Second
+	DefaultSegmentSize   = 10 * 1024 * 1024
+	DefaultMaxSize       = 100 * 1024 * 1024
+	DefaultMaxAge        = 10 * time.Minute
+)
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+	// Directory of the log segment files.
+	Dir string `toml:"dir"`
+	// How often the log is synced to disk, 0 syncs every write.
+	// Points received since the last sync may be lost on a crash.
+	FsyncInterval toml.Duration `toml:"fsync-interval"`
+	// Size in bytes of a segment file after which a new segment is started.
+	SegmentSize int64 `toml:"segment-size"`
+	// Size in bytes of all segments above which the oldest segments are deleted.
+	MaxSize int64 `toml:"max-size"`
+	// Age of the last write to a segment after which it is deleted.
+	MaxAge toml.Duration `toml:"max-age"`
+}
+
+func NewConfig() Config {
+	return Config{
+		Dir:           DefaultDir,
+		FsyncInterval: toml.Duration(DefaultFsyncInterval),
+		SegmentSize:   DefaultSegmentSize,
+		MaxSize:       DefaultMaxSize,
+		MaxAge:        toml.Duration(DefaultMaxAge),
+	}
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Dir == "" {
+		return errors.New("must specify dir")
+	}
+	if c.FsyncInterval < 0 {
+		return errors.New("fsync-interval must not be negative")
+	}
+	if c.SegmentSize <= 0 {
+		return errors.New("segment-size must be positive")
+	}
+	if c.MaxSize < c.SegmentSize {
+		return errors.New("max-size must not be less than segment-size")
+	}
+	if c.MaxAge <= 0 {
+		return errors.New("max-age must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,449 @@
+// Package wal logs the stream points written to the server, so that they are replayed into the tasks after a crash.
+//
+// The log is a sequence of segment files named by the time they were started.
+// Each record holds the points of a single write prefixed by its length and checksum,
+// so that a record torn by a crash is detected when the log is replayed.
+// The oldest segments are deleted once the log exceeds its maximum size or the segments their maximum age.
+// A clean shutdown deletes the log, since all of its points have been written to the tasks.
+//
+// The position of the last record written to the tasks is the checkpoint saved in the snapshots of the tasks,
+// so after a crash each task is only replayed the records after the checkpoint of the snapshot it was restored from.
+// Only the points written to the tasks of this node are logged, points forwarded to other nodes of a cluster are not.
+package wal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	segmentExt = ".wal"
+	// Size of the length and checksum preceding the payload of a record.
+	headerSize = 8
+	// Records larger than this are considered corrupt.
+	maxRecordSize = 1 << 30
+	// How often expired segments are deleted when every write is synced.
+	expireInterval = time.Second
+)
+
+var ErrClosed = errors.New("write-ahead log is closed")
+
+type Diagnostic interface {
+	Error(msg string, err error)
+	Replayed(segments, points int)
+}
+
+type segment struct {
+	path string
+	// Start time of the segment in nanoseconds, from its name.
+	start int64
+	size  int64
+	// Time of the last write to the segment.
+	modified time.Time
+}
+
+type Service struct {
+	config Config
+	diag   Diagnostic
+
+	mu sync.Mutex
+	// All segments oldest first, the last one is being written.
+	segments []*segment
+	current  *os.File
+	dirty    bool
+	// Start time of the last segment, in nanoseconds.
+	lastStart int64
+	// Segments left behind by a crash that have not been replayed yet.
+	replay []*segment
+	closed bool
+	// Position of the last record written to the tasks.
+	written string
+
+	// writeMu orders the writes to the tasks as the records of the log,
+	// so that the points of all the records up to the written position have been written to the tasks.
+	writeMu sync.Mutex
+
+	// ready is closed once the log has been replayed, writes wait for it so that the points stay in order.
+	ready     chan struct{}
+	readyOnce sync.Once
+	closing   chan struct{}
+	wg        sync.WaitGroup
+
+	// PointsWriter writes the points to the tasks.
+	PointsWriter interface {
+		WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+	}
+	// TaskMaster replays the points of the records to the tasks which were restored from a snapshot before them.
+	TaskMaster interface {
+		ReplayPoints(checkpoint, database, retentionPolicy string, points []models.Point) error
+	}
+}
+
+func NewService(c Config, d Diagnostic) *Service {
+	return &Service{
+		config:  c,
+		diag:    d,
+		ready:   make(chan struct{}),
+		closing: make(chan struct{}),
+	}
+}
+
+func (s *Service) Open() error {
+	if err := os.MkdirAll(s.config.Dir, 0755); err != nil {
+		return errors.Wrap(err, "failed to create write-ahead log dir")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadSegments(); err != nil {
+		return err
+	}
+	s.replay = append([]*segment(nil), s.segments...)
+	if err := s.startSegment(time.Now()); err != nil {
+		return err
+	}
+
+	interval := time.Duration(s.config.FsyncInterval)
+	if interval == 0 {
+		interval = expireInterval
+	}
+	s.wg.Add(1)
+	go s.run(interval)
+	return nil
+}
+
+func (s *Service) Close() error {
+	close(s.closing)
+	s.wg.Wait()
+	s.setReady()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.current == nil {
+		return nil
+	}
+	if err := s.current.Close(); err != nil {
+		return err
+	}
+	if len(s.replay) > 0 {
+		// Keep the segments of the crash since they have not been replayed.
+		return nil
+	}
+	// All points have been written to the tasks.
+	for _, seg := range s.segments {
+		if err := os.Remove(seg.path); err != nil {
+			s.diag.Error("failed to delete segment", err)
+		}
+	}
+	s.segments = nil
+	return nil
+}
+
+// loadSegments finds the segments left behind by a previous run. Caller must have lock.
+func (s *Service) loadSegments() error {
+	files, err := ioutil.ReadDir(s.config.Dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to load write-ahead log segments")
+	}
+	for _, info := range files {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		start, err := strconv.ParseInt(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			s.diag.Error("unknown file in write-ahead log dir", fmt.Errorf("%s is not a segment", name))
+			continue
+		}
+		if start > s.lastStart {
+			s.lastStart = start
+		}
+		s.segments = append(s.segments, &segment{
+			path:     filepath.Join(s.config.Dir, name),
+			start:    start,
+			size:     info.Size(),
+			modified: info.ModTime(),
+		})
+	}
+	sort.Slice(s.segments, func(i, j int) bool {
+		return s.segments[i].path < s.segments[j].path
+	})
+	return nil
+}
+
+// startSegment closes the current segment and starts a new one. Caller must have lock.
+func (s *Service) startSegment(now time.Time) error {
+	if s.current != nil {
+		if err := s.current.Sync(); err != nil {
+			return err
+		}
+		if err := s.current.Close(); err != nil {
+			return err
+		}
+		s.current = nil
+		s.dirty = false
+	}
+	// Pad the name so that the segments sort by time, the names must be unique.
+	start := now.UnixNano()
+	if start <= s.lastStart {
+		start = s.lastStart + 1
+	}
+	s.lastStart = start
+	path := filepath.Join(s.config.Dir, fmt.Sprintf("%020d%s", start, segmentExt))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to create write-ahead log segment")
+	}
+	s.current = f
+	s.segments = append(s.segments, &segment{
+		path:     path,
+		start:    start,
+		modified: now,
+	})
+	return nil
+}
+
+func (s *Service) run(interval time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.closing:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.dirty {
+				if err := s.current.Sync(); err != nil {
+					s.diag.Error("failed to sync segment", err)
+				}
+				s.dirty = false
+			}
+			s.truncate(time.Now())
+			s.mu.Unlock()
+		}
+	}
+}
+
+// truncate deletes the oldest segments while the log is too large or they are too old.
+// Caller must have lock.
+func (s *Service) truncate(now time.Time) {
+	if len(s.replay) > 0 {
+		// Keep the segments of the crash until they have been replayed.
+		return
+	}
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+	// The last segment is being written.
+	for len(s.segments) > 1 {
+		seg := s.segments[0]
+		if total <= s.config.MaxSize && now.Sub(seg.modified) < time.Duration(s.config.MaxAge) {
+			break
+		}
+		if err := os.Remove(seg.path); err != nil {
+			s.diag.Error("failed to delete segment", err)
+			break
+		}
+		total -= seg.size
+		s.segments = s.segments[1:]
+	}
+}
+
+// WritePoints logs the points and writes them to the tasks.
+// Writes wait until the log of a crash has been replayed.
+func (s *Service) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	select {
+	case <-s.ready:
+	case <-s.closing:
+		return ErrClosed
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	position, err := s.log(database, retentionPolicy, points)
+	if err != nil {
+		if err == ErrClosed {
+			return err
+		}
+		// Still write the points, they are only lost on a crash.
+		s.diag.Error("failed to log points", err)
+	}
+	if err := s.PointsWriter.WritePoints(database, retentionPolicy, consistencyLevel, points); err != nil {
+		return err
+	}
+	if position != "" {
+		s.setWritten(position)
+	}
+	return nil
+}
+
+// Checkpoint returns the position of the last record whose points were written to the tasks,
+// empty if none were written.
+func (s *Service) Checkpoint() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.written
+}
+
+func (s *Service) setWritten(position string) {
+	s.mu.Lock()
+	s.written = position
+	s.mu.Unlock()
+}
+
+// position returns the position of the end of a record of the segment,
+// positions sort in the order of the records, also across the logs of restarts.
+func position(seg *segment, offset int64) string {
+	return fmt.Sprintf("%020d:%020d", seg.start, offset)
+}
+
+// log appends the record of the points to the log and returns its position.
+func (s *Service) log(database, retentionPolicy string, points []models.Point) (string, error) {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, headerSize))
+	buf.WriteString(database)
+	buf.WriteByte('\n')
+	buf.WriteString(retentionPolicy)
+	buf.WriteByte('\n')
+	for _, p := range points {
+		buf.WriteString(p.String())
+		buf.WriteByte('\n')
+	}
+	record := buf.Bytes()
+	payload := record[headerSize:]
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return "", ErrClosed
+	}
+	now := time.Now()
+	n, err := s.current.Write(record)
+	seg := s.segments[len(s.segments)-1]
+	seg.size += int64(n)
+	seg.modified = now
+	if err != nil {
+		return "", err
+	}
+	pos := position(seg, seg.size)
+	if s.config.FsyncInterval == 0 {
+		if err := s.current.Sync(); err != nil {
+			return "", err
+		}
+	} else {
+		s.dirty = true
+	}
+	if seg.size >= s.config.SegmentSize {
+		if err := s.startSegment(now); err != nil {
+			return "", err
+		}
+		s.truncate(now)
+	}
+	return pos, nil
+}
+
+// Replay writes the points of the log left behind by a crash to the tasks,
+// skipping for each task the records before the checkpoint of the snapshot it was restored from.
+// It must be called once the tasks have been started.
+func (s *Service) Replay() {
+	defer s.setReady()
+	s.mu.Lock()
+	segments := s.replay
+	s.mu.Unlock()
+
+	points := 0
+	for _, seg := range segments {
+		n, err := s.replaySegment(seg)
+		points += n
+		if err != nil {
+			s.diag.Error(fmt.Sprintf("failed to replay segment %s", filepath.Base(seg.path)), err)
+		}
+	}
+
+	s.mu.Lock()
+	s.replay = nil
+	s.mu.Unlock()
+	if len(segments) > 0 {
+		s.diag.Replayed(len(segments), points)
+	}
+}
+
+func (s *Service) setReady() {
+	s.readyOnce.Do(func() {
+		close(s.ready)
+	})
+}
+
+// replaySegment writes the points of the records of the segment to the tasks,
+// stopping at the first torn or corrupt record.
+func (s *Service) replaySegment(seg *segment) (int, error) {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	header := make([]byte, headerSize)
+	count := 0
+	var offset int64
+	for {
+		if _, err := io.ReadFull(r, header); err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return count, errors.Wrap(err, "failed to read record")
+		}
+		size := binary.BigEndian.Uint32(header[0:4])
+		if size > maxRecordSize {
+			return count, fmt.Errorf("corrupt record of %d bytes", size)
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return count, errors.Wrap(err, "failed to read record")
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+			return count, errors.New("corrupt record, checksum mismatch")
+		}
+		database, retentionPolicy, points, err := parseRecord(payload)
+		if err != nil {
+			return count, err
+		}
+		offset += headerSize + int64(size)
+		pos := position(seg, offset)
+		if err := s.TaskMaster.ReplayPoints(pos, database, retentionPolicy, points); err != nil {
+			return count, errors.Wrap(err, "failed to write points")
+		}
+		// The tasks have now processed the record, or their snapshots include it.
+		s.setWritten(pos)
+		count += len(points)
+	}
+}
+
+func parseRecord(payload []byte) (string, string, []models.Point, error) {
+	parts := bytes.SplitN(payload, []byte{'\n'}, 3)
+	if len(parts) != 3 {
+		return "", "", nil, errors.New("invalid record")
+	}
+	points, err := models.ParsePointsWithPrecision(parts[2], time.Now(), "n")
+	if err != nil {
+		return "", "", nil, errors.Wrap(err, "invalid points in record")
+	}
+	return string(parts[0]), string(parts[1]), points, nil
+}
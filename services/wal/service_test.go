@@ -0,0 +1,293 @@
+package wal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/services/diagnostic"
+)
+
+type write struct {
+	database        string
+	retentionPolicy string
+	points          []string
+}
+
+type pointsWriter struct {
+	mu         sync.Mutex
+	writes     []write
+	checkpoint string
+}
+
+func (w *pointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	wr := write{database: database, retentionPolicy: retentionPolicy}
+	for _, p := range points {
+		wr.points = append(wr.points, p.String())
+	}
+	w.writes = append(w.writes, wr)
+	return nil
+}
+
+// ReplayPoints records the replayed points as writes, skipping the records up to the checkpoint of the writer.
+func (w *pointsWriter) ReplayPoints(checkpoint, database, retentionPolicy string, points []models.Point) error {
+	w.mu.Lock()
+	skip := checkpoint <= w.checkpoint
+	w.mu.Unlock()
+	if skip {
+		return nil
+	}
+	return w.WritePoints(database, retentionPolicy, models.ConsistencyLevelAny, points)
+}
+
+func (w *pointsWriter) Writes() []write {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writes
+}
+
+func newTestConfig(t *testing.T) Config {
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewConfig()
+	c.Enabled = true
+	c.Dir = dir
+	c.FsyncInterval = 0
+	return c
+}
+
+func openService(t *testing.T, c Config) (*Service, *pointsWriter) {
+	ds := diagnostic.NewService(diagnostic.NewConfig(), ioutil.Discard, ioutil.Discard)
+	ds.Open()
+	s := NewService(c, ds.NewWALHandler())
+	w := new(pointsWriter)
+	s.PointsWriter = w
+	s.TaskMaster = w
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return s, w
+}
+
+// crash stops the service without deleting the log.
+func crash(s *Service) {
+	close(s.closing)
+	s.wg.Wait()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.current.Close()
+}
+
+func writePoints(t *testing.T, s *Service, database, retentionPolicy string, lines string) {
+	points, err := models.ParsePointsString(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WritePoints(database, retentionPolicy, models.ConsistencyLevelAny, points); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestService_ReplayAfterCrash(t *testing.T) {
+	c := newTestConfig(t)
+	defer os.RemoveAll(c.Dir)
+
+	s, w := openService(t, c)
+	s.Replay()
+	writePoints(t, s, "db0", "rp0", "cpu,host=a value=1 1000000000\ncpu,host=b value=2 1000000000")
+	writePoints(t, s, "db1", "autogen", "mem value=3i 2000000000")
+	if got := len(w.Writes()); got != 2 {
+		t.Fatalf("unexpected number of writes got %d exp 2", got)
+	}
+	crash(s)
+
+	s, w = openService(t, c)
+	defer s.Close()
+	if got := len(w.Writes()); got != 0 {
+		t.Fatalf("unexpected writes before replay: %d", got)
+	}
+	s.Replay()
+	exp := []write{
+		{database: "db0", retentionPolicy: "rp0", points: []string{"cpu,host=a value=1 1000000000", "cpu,host=b value=2 1000000000"}},
+		{database: "db1", retentionPolicy: "autogen", points: []string{"mem value=3i 2000000000"}},
+	}
+	if got := w.Writes(); !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected replayed writes:\ngot %v\nexp %v", got, exp)
+	}
+}
+
+func TestService_ReplayAfterCheckpoint(t *testing.T) {
+	c := newTestConfig(t)
+	defer os.RemoveAll(c.Dir)
+
+	s, _ := openService(t, c)
+	s.Replay()
+	if got := s.Checkpoint(); got != "" {
+		t.Fatalf("unexpected checkpoint before writes: %q", got)
+	}
+	writePoints(t, s, "db0", "rp0", "cpu value=1 1000000000")
+	checkpoint := s.Checkpoint()
+	writePoints(t, s, "db0", "rp0", "cpu value=2 2000000000")
+	if got := s.Checkpoint(); got <= checkpoint {
+		t.Fatalf("checkpoint did not advance got %q after %q", got, checkpoint)
+	}
+	crash(s)
+
+	// A later log sorts after the checkpoints of the earlier one.
+	s, w := openService(t, c)
+	defer s.Close()
+	w.checkpoint = checkpoint
+	s.Replay()
+	exp := []write{
+		{database: "db0", retentionPolicy: "rp0", points: []string{"cpu value=2 2000000000"}},
+	}
+	if got := w.Writes(); !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected replayed writes:\ngot %v\nexp %v", got, exp)
+	}
+	replayed := s.Checkpoint()
+	writePoints(t, s, "db0", "rp0", "cpu value=3 3000000000")
+	if got := s.Checkpoint(); got <= replayed {
+		t.Errorf("checkpoint of the new log got %q not after the replayed %q", got, replayed)
+	}
+}
+
+func TestService_ReplayTornRecord(t *testing.T) {
+	c := newTestConfig(t)
+	defer os.RemoveAll(c.Dir)
+
+	s, _ := openService(t, c)
+	s.Replay()
+	writePoints(t, s, "db0", "rp0", "cpu value=1 1000000000")
+	writePoints(t, s, "db0", "rp0", "cpu value=2 2000000000")
+	crash(s)
+
+	// Cut the last record short, like a crash in the middle of a write.
+	files := segmentFiles(t, c.Dir)
+	if len(files) != 1 {
+		t.Fatalf("unexpected segments %v", files)
+	}
+	info, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(files[0], info.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+
+	s, w := openService(t, c)
+	defer s.Close()
+	s.Replay()
+	exp := []write{
+		{database: "db0", retentionPolicy: "rp0", points: []string{"cpu value=1 1000000000"}},
+	}
+	if got := w.Writes(); !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected replayed writes:\ngot %v\nexp %v", got, exp)
+	}
+}
+
+func TestService_CloseDeletesLog(t *testing.T) {
+	c := newTestConfig(t)
+	defer os.RemoveAll(c.Dir)
+
+	s, _ := openService(t, c)
+	s.Replay()
+	writePoints(t, s, "db0", "rp0", "cpu value=1 1000000000")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if files := segmentFiles(t, c.Dir); len(files) != 0 {
+		t.Errorf("unexpected segments after close %v", files)
+	}
+	if err := s.WritePoints("db0", "rp0", models.ConsistencyLevelAny, nil); err != ErrClosed {
+		t.Errorf("unexpected error writing to closed log got %v exp %v", err, ErrClosed)
+	}
+}
+
+func TestService_CloseKeepsLogNotReplayed(t *testing.T) {
+	c := newTestConfig(t)
+	defer os.RemoveAll(c.Dir)
+
+	s, _ := openService(t, c)
+	s.Replay()
+	writePoints(t, s, "db0", "rp0", "cpu value=1 1000000000")
+	crash(s)
+
+	// The server failed to start before the log was replayed.
+	s, _ = openService(t, c)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, w := openService(t, c)
+	defer s.Close()
+	s.Replay()
+	if got := len(w.Writes()); got != 1 {
+		t.Errorf("unexpected number of replayed writes got %d exp 1", got)
+	}
+}
+
+func TestService_TruncateBySize(t *testing.T) {
+	c := newTestConfig(t)
+	defer os.RemoveAll(c.Dir)
+	c.SegmentSize = 100
+	c.MaxSize = 300
+
+	s, _ := openService(t, c)
+	defer s.Close()
+	s.Replay()
+	for i := 0; i < 50; i++ {
+		writePoints(t, s, "db0", "rp0", "cpu,host=serverA value=1 1000000000")
+	}
+	var total int64
+	for _, f := range segmentFiles(t, c.Dir) {
+		info, err := os.Stat(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += info.Size()
+	}
+	if total > c.MaxSize {
+		t.Errorf("log exceeds max size got %d exp at most %d", total, c.MaxSize)
+	}
+}
+
+func TestService_TruncateByAge(t *testing.T) {
+	c := newTestConfig(t)
+	defer os.RemoveAll(c.Dir)
+	c.SegmentSize = 10
+
+	s, _ := openService(t, c)
+	defer s.Close()
+	s.Replay()
+	writePoints(t, s, "db0", "rp0", "cpu value=1 1000000000")
+	writePoints(t, s, "db0", "rp0", "cpu value=2 2000000000")
+	if got := len(segmentFiles(t, c.Dir)); got != 3 {
+		t.Fatalf("unexpected number of segments got %d exp 3", got)
+	}
+
+	s.mu.Lock()
+	s.truncate(time.Now().Add(time.Duration(c.MaxAge)))
+	s.mu.Unlock()
+	// The segment being written is kept.
+	if got := len(segmentFiles(t, c.Dir)); got != 1 {
+		t.Errorf("unexpected number of segments got %d exp 1", got)
+	}
+}
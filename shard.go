@@ -0,0 +1,61 @@
+package kapacitor
+
+import (
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/timer"
+)
+
+// nodeShard is a worker of a node that processes a subset of its groups.
+type nodeShard struct {
+	// Index of the shard, from zero to the number of shards of the node.
+	index int
+	// Timer of the messages processed by the shard.
+	timer timer.Timer
+	// Edges the shard forwards its messages to.
+	outs []edge.StatsEdge
+}
+
+// shardGroupFunc creates the receiver of a new group processed by the shard.
+type shardGroupFunc func(s nodeShard, group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error)
+
+// shardReceiver creates the groups of a shard of a node.
+type shardReceiver struct {
+	n        *node
+	shard    nodeShard
+	newGroup shardGroupFunc
+}
+
+func (r shardReceiver) NewGroup(group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error) {
+	return r.newGroup(r.shard, group, first)
+}
+
+func (r shardReceiver) MaxGroups() int64 {
+	return r.n.MaxGroups()
+}
+
+// newGroupedConsumer returns the consumer of the groups of the node.
+// When shards is greater than one, the groups are processed in parallel by that many shards,
+// so newGroup and the receivers it returns must be safe to use by concurrent shards.
+func (n *node) newGroupedConsumer(shards int64, newGroup shardGroupFunc) edge.GroupedConsumer {
+	if shards <= 1 {
+		return edge.NewGroupedConsumer(n.ins[0], shardReceiver{
+			n:        n,
+			shard:    nodeShard{timer: n.timer, outs: n.outs},
+			newGroup: newGroup,
+		})
+	}
+	receivers := make([]edge.GroupedReceiver, shards)
+	for i := range receivers {
+		receivers[i] = shardReceiver{
+			n: n,
+			shard: nodeShard{
+				index: i,
+				// Timers are not safe for concurrent use.
+				timer: n.et.tm.TimingService.NewTimer(n.timerSetter),
+				outs:  edge.NewShardEdges(n.outs),
+			},
+			newGroup: newGroup,
+		}
+	}
+	return edge.NewShardedGroupedConsumer(n.ins[0], receivers, defaultEdgeBufferSize)
+}
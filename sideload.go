@@ -35,7 +35,19 @@ func newSideloadNode(et *ExecutingTask, n *pipeline.SideloadNode, d NodeDiagnost
 		order:      make([]string, len(n.OrderList)),
 		orderTmpls: make([]orderTmpl, len(n.OrderList)),
 	}
-	src, err := et.tm.SideloadService.Source(n.Source)
+	keys := make([]string, 0, len(n.Fields)+len(n.Tags))
+	for k := range n.Fields {
+		keys = append(keys, k)
+	}
+	for k := range n.Tags {
+		keys = append(keys, k)
+	}
+	src, err := et.tm.SideloadService.Source(n.Source, sideload.Reference{
+		Task:  et.Task.ID,
+		Node:  n.Name(),
+		Order: n.OrderList,
+		Keys:  keys,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -87,13 +99,33 @@ func newOrderTmpl(text string, bp *bufpool.Pool) (orderTmpl, error) {
 func (t orderTmpl) Path(tags models.Tags) (string, error) {
 	buf := t.bufferPool.Get()
 	defer t.bufferPool.Put(buf)
-	err := t.tmpl.Execute(buf, tags)
+	err := t.tmpl.Execute(buf, quoteTags(tags))
 	if err != nil {
 		return "", err
 	}
 	return buf.String(), nil
 }
 
+// quoteTags escapes the wildcard characters of the tag values,
+// so that only the wildcards of the order templates match several files.
+func quoteTags(tags models.Tags) models.Tags {
+	var quoted models.Tags
+	for k, v := range tags {
+		q := sideload.QuoteMeta(v)
+		if q == v {
+			continue
+		}
+		if quoted == nil {
+			quoted = tags.Copy()
+		}
+		quoted[k] = q
+	}
+	if quoted == nil {
+		return tags
+	}
+	return quoted
+}
+
 func (n *SideloadNode) doSideload(p edge.FieldsTagsTimeSetter) {
 	for i, o := range n.orderTmpls {
 		p, err := o.Path(p.Tags())
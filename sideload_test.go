@@ -0,0 +1,41 @@
+package kapacitor
+
+import (
+	"testing"
+
+	"github.com/influxdata/kapacitor/bufpool"
+	"github.com/influxdata/kapacitor/models"
+)
+
+func TestOrderTmpl_Path(t *testing.T) {
+	o, err := newOrderTmpl("dc/{{.dc}}/*.yml", bufpool.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		tags models.Tags
+		exp  string
+	}{
+		{
+			tags: models.Tags{"dc": "east"},
+			exp:  "dc/east/*.yml",
+		},
+		{
+			// Wildcards of the tag values are quoted.
+			tags: models.Tags{"dc": "east*"},
+			exp:  `dc/east\*/*.yml`,
+		},
+	}
+	for _, tc := range testCases {
+		got, err := o.Path(tc.tags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.exp {
+			t.Errorf("unexpected path for tags %v: got %q exp %q", tc.tags, got, tc.exp)
+		}
+	}
+	if got := testCases[1].tags["dc"]; got != "east*" {
+		t.Errorf("tags of the point were modified %q", got)
+	}
+}
@@ -0,0 +1,141 @@
+package kapacitor
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"sync"
+
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/tick/stateful"
+)
+
+// groupState is the state of a group kept in the snapshots of a node.
+type groupState interface {
+	// snapshot returns the state of the group, nil if the group has no state.
+	snapshot() ([]byte, error)
+	restore(snapshot []byte) error
+}
+
+// groupStates tracks the states of the groups of a node, so that they are kept in the snapshots of the node.
+type groupStates struct {
+	// mu must be held while a group handles a message.
+	// Groups processed by concurrent shards of a node hold it for reading,
+	// a snapshot holds it for writing.
+	mu     sync.RWMutex
+	groups map[models.GroupID]groupState
+	// Snapshots of the groups that have not been created yet.
+	restored map[models.GroupID][]byte
+}
+
+func newGroupStates() *groupStates {
+	return &groupStates{
+		groups:   make(map[models.GroupID]groupState),
+		restored: make(map[models.GroupID][]byte),
+	}
+}
+
+// add tracks the state of a new group, restoring it if the group is in the snapshot.
+func (g *groupStates) add(id models.GroupID, s groupState) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	snapshot, ok := g.restored[id]
+	delete(g.restored, id)
+	g.groups[id] = s
+	if ok {
+		if err := s.restore(snapshot); err != nil {
+			return fmt.Errorf("failed to restore state of group %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+func (g *groupStates) delete(id models.GroupID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.groups, id)
+}
+
+// snapshot returns the states of all groups, nil if no group has state.
+func (g *groupStates) snapshot() ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	snapshots := make(map[models.GroupID][]byte, len(g.groups)+len(g.restored))
+	for id, snapshot := range g.restored {
+		snapshots[id] = snapshot
+	}
+	for id, s := range g.groups {
+		snapshot, err := s.snapshot()
+		if err != nil {
+			return nil, err
+		}
+		if snapshot != nil {
+			snapshots[id] = snapshot
+		}
+	}
+	if len(snapshots) == 0 {
+		return nil, nil
+	}
+	return encodeState(snapshots)
+}
+
+// restore keeps the states of the groups in the snapshot until the groups are created.
+func (g *groupStates) restore(snapshot []byte) error {
+	if len(snapshot) == 0 {
+		return nil
+	}
+	var snapshots map[models.GroupID][]byte
+	if err := decodeState(snapshot, &snapshots); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.restored = snapshots
+	return nil
+}
+
+func encodeState(state interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(state); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeState(data []byte, state interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(state)
+}
+
+// snapshotExpressions returns the state of the stateful expressions, nil if none of them has state.
+func snapshotExpressions(expressions []stateful.Expression) ([]byte, error) {
+	states := make([][]byte, len(expressions))
+	empty := true
+	for i, expr := range expressions {
+		state, err := expr.Snapshot()
+		if err != nil {
+			return nil, err
+		}
+		states[i] = state
+		empty = empty && state == nil
+	}
+	if empty {
+		return nil, nil
+	}
+	return encodeState(states)
+}
+
+func restoreExpressions(expressions []stateful.Expression, snapshot []byte) error {
+	var states [][]byte
+	if err := decodeState(snapshot, &states); err != nil {
+		return err
+	}
+	for i, state := range states {
+		if i >= len(expressions) || state == nil {
+			continue
+		}
+		if err := expressions[i].Restore(state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/models"
 	"github.com/influxdata/kapacitor/pipeline"
 	"github.com/influxdata/kapacitor/tick/ast"
 	"github.com/influxdata/kapacitor/tick/stateful"
@@ -13,14 +14,24 @@ import (
 type stateTracker interface {
 	track(t time.Time, inState bool) interface{}
 	reset()
+	// snapshot returns the state of the tracker, nil if it is not in the state.
+	snapshot() ([]byte, error)
+	restore(snapshot []byte) error
 }
 
 type stateTrackingGroup struct {
-	n *StateTrackingNode
+	n  *StateTrackingNode
+	id models.GroupID
 	stateful.Expression
 	tracker stateTracker
 }
 
+// stateTrackingState is the state of a group kept in the snapshots of the node.
+type stateTrackingState struct {
+	Expression []byte
+	Tracker    []byte
+}
+
 type StateTrackingNode struct {
 	node
 	as string
@@ -29,9 +40,13 @@ type StateTrackingNode struct {
 	scopePool stateful.ScopePool
 
 	newTracker func() stateTracker
+	groups     *groupStates
 }
 
-func (n *StateTrackingNode) runStateTracking(_ []byte) error {
+func (n *StateTrackingNode) runStateTracking(snapshot []byte) error {
+	if err := n.groups.restore(snapshot); err != nil {
+		n.diag.Error("failed to restore snapshot", err)
+	}
 	consumer := edge.NewGroupedConsumer(
 		n.ins[0],
 		n,
@@ -43,24 +58,68 @@ func (n *StateTrackingNode) runStateTracking(_ []byte) error {
 func (n *StateTrackingNode) NewGroup(group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error) {
 	return edge.NewReceiverFromForwardReceiverWithStats(
 		n.outs,
-		edge.NewTimedForwardReceiver(n.timer, n.newGroup()),
+		edge.NewTimedForwardReceiver(n.timer, n.newGroup(group.ID)),
 	), nil
 }
 
-func (n *StateTrackingNode) newGroup() *stateTrackingGroup {
+func (n *StateTrackingNode) newGroup(id models.GroupID) *stateTrackingGroup {
 	// Create a new tracking group
 	g := &stateTrackingGroup{
-		n: n,
+		n:  n,
+		id: id,
 	}
 
 	g.Expression = n.expr.CopyReset()
 
 	g.tracker = n.newTracker()
+	if err := n.groups.add(id, g); err != nil {
+		n.diag.Error("failed to restore snapshot", err)
+	}
 	return g
 }
 
+func (n *StateTrackingNode) snapshot() ([]byte, error) {
+	return n.groups.snapshot()
+}
+
+func (g *stateTrackingGroup) snapshot() ([]byte, error) {
+	expr, err := g.Expression.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	tracker, err := g.tracker.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	if expr == nil && tracker == nil {
+		return nil, nil
+	}
+	return encodeState(stateTrackingState{
+		Expression: expr,
+		Tracker:    tracker,
+	})
+}
+
+func (g *stateTrackingGroup) restore(snapshot []byte) error {
+	var s stateTrackingState
+	if err := decodeState(snapshot, &s); err != nil {
+		return err
+	}
+	if s.Expression != nil {
+		if err := g.Expression.Restore(s.Expression); err != nil {
+			return err
+		}
+	}
+	if s.Tracker != nil {
+		return g.tracker.restore(s.Tracker)
+	}
+	return nil
+}
+
 func (g *stateTrackingGroup) BeginBatch(begin edge.BeginBatchMessage) (edge.Message, error) {
+	g.n.groups.mu.Lock()
 	g.tracker.reset()
+	g.n.groups.mu.Unlock()
 	return begin, nil
 }
 
@@ -89,6 +148,8 @@ func (g *stateTrackingGroup) Point(p edge.PointMessage) (edge.Message, error) {
 }
 
 func (g *stateTrackingGroup) track(p edge.FieldsTagsTimeSetter) error {
+	g.n.groups.mu.Lock()
+	defer g.n.groups.mu.Unlock()
 	pass, err := EvalPredicate(g.Expression, g.n.scopePool, p)
 	if err != nil {
 		return err
@@ -104,6 +165,7 @@ func (g *stateTrackingGroup) Barrier(b edge.BarrierMessage) (edge.Message, error
 	return b, nil
 }
 func (g *stateTrackingGroup) DeleteGroup(d edge.DeleteGroupMessage) (edge.Message, error) {
+	g.n.groups.delete(g.id)
 	return d, nil
 }
 func (g *stateTrackingGroup) Done() {}
@@ -118,6 +180,17 @@ func (sdt *stateDurationTracker) reset() {
 	sdt.startTime = time.Time{}
 }
 
+func (sdt *stateDurationTracker) snapshot() ([]byte, error) {
+	if sdt.startTime.IsZero() {
+		return nil, nil
+	}
+	return encodeState(sdt.startTime)
+}
+
+func (sdt *stateDurationTracker) restore(snapshot []byte) error {
+	return decodeState(snapshot, &sdt.startTime)
+}
+
 func (sdt *stateDurationTracker) track(t time.Time, inState bool) interface{} {
 	if !inState {
 		sdt.startTime = time.Time{}
@@ -145,6 +218,7 @@ func newStateDurationNode(et *ExecutingTask, sd *pipeline.StateDurationNode, d N
 		newTracker: func() stateTracker { return &stateDurationTracker{sd: sd} },
 		expr:       expr,
 		scopePool:  stateful.NewScopePool(ast.FindReferenceVariables(sd.Lambda.Expression)),
+		groups:     newGroupStates(),
 	}
 	n.node.runF = n.runStateTracking
 	return n, nil
@@ -158,6 +232,17 @@ func (sct *stateCountTracker) reset() {
 	sct.count = 0
 }
 
+func (sct *stateCountTracker) snapshot() ([]byte, error) {
+	if sct.count == 0 {
+		return nil, nil
+	}
+	return encodeState(sct.count)
+}
+
+func (sct *stateCountTracker) restore(snapshot []byte) error {
+	return decodeState(snapshot, &sct.count)
+}
+
 func (sct *stateCountTracker) track(t time.Time, inState bool) interface{} {
 	if !inState {
 		sct.count = 0
@@ -183,6 +268,7 @@ func newStateCountNode(et *ExecutingTask, sc *pipeline.StateCountNode, d NodeDia
 		newTracker: func() stateTracker { return &stateCountTracker{} },
 		expr:       expr,
 		scopePool:  stateful.NewScopePool(ast.FindReferenceVariables(sc.Lambda.Expression)),
+		groups:     newGroupStates(),
 	}
 	n.node.runF = n.runStateTracking
 	return n, nil
@@ -3,14 +3,24 @@ package kapacitor
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/influxdata/kapacitor/edge"
+	kexpvar "github.com/influxdata/kapacitor/expvar"
 	"github.com/influxdata/kapacitor/models"
 	"github.com/influxdata/kapacitor/pipeline"
 	"github.com/influxdata/kapacitor/tick/ast"
 	"github.com/influxdata/kapacitor/tick/stateful"
 )
 
+const (
+	statPointsThrottled = "points_throttled"
+	statPointsLimited   = "points_cardinality_limited"
+
+	// Minimum time between logging throttled points
+	throttledLogInterval = time.Minute
+)
+
 type StreamNode struct {
 	node
 	s *pipeline.StreamNode
@@ -27,7 +37,42 @@ func newStreamNode(et *ExecutingTask, n *pipeline.StreamNode, d NodeDiagnostic)
 }
 
 func (n *StreamNode) runSourceStream([]byte) error {
+	limit := newRateLimit(n.et.Task.Limits.MaxPointsPerSecond)
+	throttled := &kexpvar.Int{}
+	if limit.max > 0 {
+		n.statMap.Set(statPointsThrottled, throttled)
+	}
+	limited := &kexpvar.Int{}
+	if n.et.cardinality != nil {
+		n.statMap.Set(statPointsLimited, limited)
+	}
+	var lastLog, lastLimitedLog time.Time
+	var lastDropped, lastLimited int64
 	for m, ok := n.ins[0].Emit(); ok; m, ok = n.ins[0].Emit() {
+		if m.Type() == edge.Point && !limit.allow() {
+			throttled.Add(1)
+			if now := time.Now(); now.Sub(lastLog) >= throttledLogInterval {
+				n.diag.PointsThrottled(limit.max, limit.dropped-lastDropped)
+				lastLog = now
+				lastDropped = limit.dropped
+			}
+			continue
+		}
+		if p, ok := m.(edge.PointMessage); ok {
+			admit, err := n.et.cardinality.admit(p)
+			if err != nil {
+				return err
+			}
+			if !admit {
+				limited.Add(1)
+				if now := time.Now(); now.Sub(lastLimitedLog) >= throttledLogInterval {
+					n.diag.PointsCardinalityLimited(limited.IntValue() - lastLimited)
+					lastLimitedLog = now
+					lastLimited = limited.IntValue()
+				}
+				continue
+			}
+		}
 		for _, child := range n.outs {
 			err := child.Collect(m)
 			if err != nil {
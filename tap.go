@@ -0,0 +1,154 @@
+package kapacitor
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/models"
+)
+
+// A Tap receives copies of the points or batches emitted by a node of an executing task.
+// Tapping a node never blocks the task, rows are dropped if they are not read fast enough.
+type Tap struct {
+	// Rows receives a row for each point or batch emitted by the node.
+	// It is closed once the count of the tap is reached or the task stops.
+	Rows <-chan *models.Row
+
+	rows      chan *models.Row
+	remaining int
+	buffer    edge.BatchBuffer
+	inBatch   bool
+
+	e      *Edge
+	mu     sync.Mutex
+	closed bool
+}
+
+func newTap(e *Edge, count int) *Tap {
+	rows := make(chan *models.Row, count)
+	return &Tap{
+		Rows:      rows,
+		rows:      rows,
+		remaining: count,
+		e:         e,
+	}
+}
+
+// collect is called from the goroutine of the tapped node for each message it emits.
+func (t *Tap) collect(m edge.Message) {
+	var row *models.Row
+	switch m := m.(type) {
+	case edge.PointMessage:
+		row = m.ToRow()
+	case edge.BufferedBatchMessage:
+		row = m.ToRow()
+	case edge.BeginBatchMessage:
+		t.inBatch = true
+		t.buffer.BeginBatch(m)
+	case edge.BatchPointMessage:
+		if t.inBatch {
+			t.buffer.BatchPoint(m)
+		}
+	case edge.EndBatchMessage:
+		// Batches already in progress when the tap was added are skipped.
+		if t.inBatch {
+			t.inBatch = false
+			row = t.buffer.BufferedBatchMessage(m).ToRow()
+		}
+	}
+	if row == nil {
+		return
+	}
+	t.mu.Lock()
+	if !t.closed {
+		select {
+		case t.rows <- row:
+		default:
+		}
+		t.remaining--
+	}
+	done := t.remaining == 0
+	t.mu.Unlock()
+	if done {
+		t.Close()
+	}
+}
+
+// Close removes the tap from the node and closes its Rows channel.
+func (t *Tap) Close() {
+	t.e.removeTap(t)
+	t.closeRows()
+}
+
+func (t *Tap) closeRows() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.closed {
+		t.closed = true
+		close(t.rows)
+	}
+}
+
+// Tap adds a tap to the output of the named node of the task, which receives the next count points or batches.
+func (et *ExecutingTask) Tap(nodeName string, count int) (*Tap, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("tap count must be positive, got %d", count)
+	}
+	for _, n := range et.nodes {
+		if n.Name() == nodeName {
+			return n.tap(count)
+		}
+	}
+	return nil, fmt.Errorf("unknown node %s", nodeName)
+}
+
+var errNoOutput = errors.New("node has no output, tap one of its parents instead")
+
+// tap adds a tap to the output of the node.
+func (n *node) tap(count int) (*Tap, error) {
+	if len(n.outs) == 0 {
+		return nil, errNoOutput
+	}
+	// All children receive the same messages, so tapping the first edge suffices.
+	e, ok := n.outs[0].(*Edge)
+	if !ok {
+		return nil, fmt.Errorf("cannot tap node %s", n.Name())
+	}
+	return e.addTap(count)
+}
+
+func (e *Edge) addTap(count int) (*Tap, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil, ErrAborted
+	}
+	t := newTap(e, count)
+	taps, _ := e.taps.Load().([]*Tap)
+	e.taps.Store(append(taps[:len(taps):len(taps)], t))
+	return t, nil
+}
+
+func (e *Edge) removeTap(t *Tap) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	taps, _ := e.taps.Load().([]*Tap)
+	remaining := make([]*Tap, 0, len(taps))
+	for _, tap := range taps {
+		if tap != t {
+			remaining = append(remaining, tap)
+		}
+	}
+	e.taps.Store(remaining)
+}
+
+// closeTaps closes all taps of the edge, e.mu must be held.
+func (e *Edge) closeTaps() {
+	taps, _ := e.taps.Load().([]*Tap)
+	e.taps.Store([]*Tap(nil))
+	for _, t := range taps {
+		t.closeRows()
+	}
+}
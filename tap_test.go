@@ -0,0 +1,82 @@
+package kapacitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
+)
+
+func TestTap_Batch(t *testing.T) {
+	e := &Edge{
+		StatsEdge: edge.NewStatsEdge(edge.NewChannelEdge(pipeline.BatchEdge, 100)),
+		diag:      taskMasterDiagnostic{},
+	}
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	tags := models.Tags{"host": "A"}
+	batch := func(values ...float64) {
+		e.Collect(edge.NewBeginBatchMessage("cpu", tags, false, now, len(values)))
+		for i, v := range values {
+			e.Collect(edge.NewBatchPointMessage(models.Fields{"value": v}, tags, now.Add(time.Duration(i)*time.Second)))
+		}
+		e.Collect(edge.NewEndBatchMessage())
+	}
+
+	// A batch in progress when the tap is added is skipped.
+	e.Collect(edge.NewBeginBatchMessage("cpu", tags, false, now, 1))
+	tap, err := e.addTap(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Collect(edge.NewBatchPointMessage(models.Fields{"value": 0.0}, tags, now))
+	e.Collect(edge.NewEndBatchMessage())
+
+	batch(1, 2)
+	batch(3)
+	batch(4)
+
+	var rows []*models.Row
+	for r := range tap.Rows {
+		rows = append(rows, r)
+	}
+	if got, exp := len(rows), 2; got != exp {
+		t.Fatalf("unexpected number of rows got %d exp %d", got, exp)
+	}
+	if got, exp := len(rows[0].Values), 2; got != exp {
+		t.Errorf("unexpected number of values in first batch got %d exp %d", got, exp)
+	}
+	if got, exp := rows[1].Values[0][1], 3.0; got != exp {
+		t.Errorf("unexpected value in second batch got %v exp %v", got, exp)
+	}
+	if taps, _ := e.taps.Load().([]*Tap); len(taps) != 0 {
+		t.Errorf("expected tap to be removed once its count is reached, got %d taps", len(taps))
+	}
+}
+
+func TestTap_CloseEdge(t *testing.T) {
+	e := &Edge{
+		StatsEdge: edge.NewStatsEdge(edge.NewChannelEdge(pipeline.StreamEdge, 100)),
+		diag:      taskMasterDiagnostic{},
+	}
+	tap, err := e.addTap(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Collect(edge.NewPointMessage("cpu", "db", "rp", models.Dimensions{}, models.Fields{"value": 1.0}, nil, time.Now()))
+	e.Close()
+
+	count := 0
+	for range tap.Rows {
+		count++
+	}
+	if count != 1 {
+		t.Errorf("unexpected number of rows got %d exp 1", count)
+	}
+	// Closing the tap after the edge is safe.
+	tap.Close()
+	if _, err := e.addTap(1); err != ErrAborted {
+		t.Errorf("unexpected error tapping closed edge got %v exp %v", err, ErrAborted)
+	}
+}
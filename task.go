@@ -79,6 +79,14 @@ type Task struct {
 	Type             TaskType
 	DBRPs            []DBRP
 	SnapshotInterval time.Duration
+	// Labels are added as tags to the statistics of the task.
+	Labels map[string]string
+	Limits TaskLimits
+	// Points are sent to stream tasks with a higher priority first.
+	Priority int
+	// IDs of the batch tasks whose output is queried by this batch task.
+	// Each query waits until these tasks completed their intervals up to the query time.
+	DependsOn []string
 }
 
 func (t *Task) Dot() []byte {
@@ -115,6 +123,14 @@ type ExecutingTask struct {
 	stopping chan struct{}
 	wg       sync.WaitGroup
 	diag     TaskDiagnostic
+	// Points buffered by all nodes of the task
+	buffered *bufferLimit
+	// Memory held by all nodes of the task
+	memory *memoryAccount
+	// Live series of the measurements read by a stream task, nil if not tracked.
+	cardinality *cardinalityGovernor
+	// Checkpoint of the snapshot the task was restored from, empty if it was not restored.
+	checkpoint string
 
 	// Mutex for throughput var
 	tmu        sync.RWMutex
@@ -130,6 +146,13 @@ func NewExecutingTask(tm *TaskMaster, t *Task) (*ExecutingTask, error) {
 		outputs: make(map[string]Output),
 		lookup:  make(map[pipeline.ID]Node),
 		diag:    d,
+		buffered: &bufferLimit{
+			max: t.Limits.MaxBufferedPoints,
+		},
+		memory: &memoryAccount{},
+	}
+	if tm.CardinalityLimits.Enabled && t.Type == StreamTask {
+		et.cardinality = newCardinalityGovernor(tm.CardinalityLimits)
 	}
 	err := et.link()
 	if err != nil {
@@ -210,6 +233,9 @@ func (et *ExecutingTask) start(ins []edge.StatsEdge, snapshot *TaskSnapshot) err
 		validSnapshot = err == nil
 	}
 
+	if validSnapshot {
+		et.checkpoint = snapshot.Checkpoint
+	}
 	err := et.walk(func(n Node) error {
 		if validSnapshot {
 			n.start(snapshot.NodeSnapshots[n.Name()])
@@ -349,6 +375,8 @@ func (et *ExecutingTask) ExecutionStats() (ExecutionStats, error) {
 
 	// Fill the task stats
 	executionStats.TaskStats["throughput"] = et.getThroughput()
+	executionStats.TaskStats["buffered_points"] = et.buffered.buffered()
+	executionStats.TaskStats[statMemoryBytes] = et.memoryBytes()
 
 	// Fill the nodes stats
 	err := et.walk(func(node Node) error {
@@ -370,9 +398,52 @@ func (et *ExecutingTask) ExecutionStats() (ExecutionStats, error) {
 	return executionStats, nil
 }
 
+// memoryBytes estimates the memory held by the nodes of the task and the messages queued for them.
+func (et *ExecutingTask) memoryBytes() int64 {
+	var bytes int64
+	for _, n := range et.nodes {
+		bytes += n.memoryBytes()
+	}
+	return bytes
+}
+
 // Return a graphviz .dot formatted byte array.
 // Label edges with relavant execution information.
 func (et *ExecutingTask) EDot(labels bool) []byte {
+	return et.edot(labels, nil)
+}
+
+// edgeRates are the rates of the edges of a task in messages per second, by parent and child node name.
+type edgeRates map[string]map[string]float64
+
+// LiveDot returns the DOT graph of the task with the edges annotated with their rate
+// over the interval and the number of errors of the node they lead to.
+// LiveDot blocks for the interval while it measures the rates.
+func (et *ExecutingTask) LiveDot(labels bool, interval time.Duration) []byte {
+	counts := func() map[string]map[string]int64 {
+		c := make(map[string]map[string]int64, len(et.nodes))
+		for _, n := range et.nodes {
+			c[n.Name()] = n.childCounts()
+		}
+		return c
+	}
+	start := time.Now()
+	before := counts()
+	time.Sleep(interval)
+	after := counts()
+	elapsed := time.Since(start).Seconds()
+
+	rates := make(edgeRates, len(after))
+	for parent, children := range after {
+		rates[parent] = make(map[string]float64, len(children))
+		for child, count := range children {
+			rates[parent][child] = float64(count-before[parent][child]) / elapsed
+		}
+	}
+	return et.edot(labels, rates)
+}
+
+func (et *ExecutingTask) edot(labels bool, rates edgeRates) []byte {
 
 	var buf bytes.Buffer
 
@@ -403,7 +474,7 @@ func (et *ExecutingTask) EDot(labels bool) []byte {
 	buf.WriteString("];\n")
 
 	_ = et.walk(func(n Node) error {
-		n.edot(&buf, labels)
+		n.edot(&buf, labels, rates)
 		return nil
 	})
 	buf.Write([]byte("}"))
@@ -455,6 +526,8 @@ func (et *ExecutingTask) createNode(p pipeline.Node, d NodeDiagnostic) (n Node,
 		n, err = newBatchNode(et, t, d)
 	case *pipeline.QueryNode:
 		n, err = newQueryNode(et, t, d)
+	case *pipeline.FileNode:
+		n, err = newFileNode(et, t, d)
 	case *pipeline.WindowNode:
 		n, err = newWindowNode(et, t, d)
 	case *pipeline.HTTPOutNode:
@@ -463,6 +536,16 @@ func (et *ExecutingTask) createNode(p pipeline.Node, d NodeDiagnostic) (n Node,
 		n, err = newHTTPPostNode(et, t, d)
 	case *pipeline.InfluxDBOutNode:
 		n, err = newInfluxDBOutNode(et, t, d)
+	case *pipeline.ExecNode:
+		n, err = newExecNode(et, t, d)
+	case *pipeline.WasmNode:
+		n, err = newWasmNode(et, t, d)
+	case *pipeline.InlineNode:
+		n, err = newInlineNode(et, t, d)
+	case *pipeline.OnnxNode:
+		n, err = newOnnxNode(et, t, d)
+	case *pipeline.ObjectStoreOutNode:
+		n, err = newObjectStoreOutNode(et, t, d)
 	case *pipeline.KapacitorLoopbackNode:
 		n, err = newKapacitorLoopbackNode(et, t, d)
 	case *pipeline.AlertNode:
@@ -528,11 +611,16 @@ func (et *ExecutingTask) createNode(p pipeline.Node, d NodeDiagnostic) (n Node,
 
 type TaskSnapshot struct {
 	NodeSnapshots map[string][]byte
+	// Checkpoint is the position of the write-ahead log of the points written to the task master
+	// when the snapshot was taken, only the later points are replayed into the task after a crash.
+	Checkpoint string
 }
 
 func (et *ExecutingTask) Snapshot() (*TaskSnapshot, error) {
 	snapshot := &TaskSnapshot{
 		NodeSnapshots: make(map[string][]byte),
+		// The checkpoint is taken first, points written while the nodes are snapshotted are replayed.
+		Checkpoint: et.tm.checkpoint(),
 	}
 	err := et.walk(func(n Node) error {
 		data, err := n.snapshot()
@@ -570,8 +658,9 @@ func (et *ExecutingTask) runSnapshotter() {
 			for _, data := range snapshot.NodeSnapshots {
 				size += len(data)
 			}
-			// Only save the snapshot if it has content
-			if size > 0 {
+			// Only save the snapshot if it has content,
+			// or a checkpoint so that the points processed by a stateless task are not replayed into it.
+			if size > 0 || snapshot.Checkpoint != "" {
 				err = et.tm.TaskStore.SaveSnapshot(et.Task.ID, snapshot)
 				if err != nil {
 					et.diag.Error("failed to save task snapshot", err)
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,11 +15,13 @@ import (
 	"github.com/influxdata/kapacitor/expvar"
 	"github.com/influxdata/kapacitor/influxdb"
 	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/lineprotocol"
 	"github.com/influxdata/kapacitor/models"
 	"github.com/influxdata/kapacitor/pipeline"
 	"github.com/influxdata/kapacitor/server/vars"
 	alertservice "github.com/influxdata/kapacitor/services/alert"
 	"github.com/influxdata/kapacitor/services/alerta"
+	"github.com/influxdata/kapacitor/services/amqp"
 	ec2 "github.com/influxdata/kapacitor/services/ec2/client"
 	"github.com/influxdata/kapacitor/services/hipchat"
 	"github.com/influxdata/kapacitor/services/httpd"
@@ -42,6 +45,7 @@ import (
 	"github.com/influxdata/kapacitor/tick"
 	"github.com/influxdata/kapacitor/tick/stateful"
 	"github.com/influxdata/kapacitor/timer"
+	"github.com/influxdata/kapacitor/tracing"
 	"github.com/influxdata/kapacitor/udf"
 )
 
@@ -69,6 +73,9 @@ type Diagnostic interface {
 	StoppedTask(id string)
 	StoppedTaskWithError(id string, err error)
 
+	SavedTaskSnapshot(id string)
+	FailedToSaveTaskSnapshot(id string, err error)
+
 	TaskMasterDot(d string)
 }
 
@@ -76,6 +83,8 @@ type UDFService interface {
 	List() []string
 	Info(name string) (udf.Info, bool)
 	Create(name, taskID, nodeID string, d udf.Diagnostic, abortCallback func()) (udf.Interface, error)
+	RestartPolicy(name string) udf.RestartPolicy
+	Upgrades(name string) <-chan struct{}
 }
 
 var ErrTaskMasterClosed = errors.New("TaskMaster is closed")
@@ -170,6 +179,9 @@ type TaskMaster struct {
 	KafkaService interface {
 		Handler(kafka.HandlerConfig, ...keyvalue.T) (alert.Handler, error)
 	}
+	AMQPService interface {
+		Handler(amqp.HandlerConfig, ...keyvalue.T) (alert.Handler, error)
+	}
 	AlertaService interface {
 		DefaultHandlerConfig() alerta.HandlerConfig
 		Handler(alerta.HandlerConfig, ...keyvalue.T) (alert.Handler, error)
@@ -194,13 +206,35 @@ type TaskMaster struct {
 	}
 
 	SideloadService interface {
-		Source(dir string) (sideload.Source, error)
+		Source(srcURL string, ref sideload.Reference) (sideload.Source, error)
 	}
 
 	Commander command.Commander
 
+	// WAL logs the points written to the task master, its position is saved in the snapshots of the tasks.
+	// It is not shared with the task masters created by New.
+	WAL interface {
+		Checkpoint() string
+	}
+
 	DefaultRetentionPolicy string
 
+	// Number of points queued for a stream task above which writes to the
+	// databases and retention policies of the task are rejected, 0 disables backpressure.
+	QueueThreshold int64
+	// Time after which rejected writes should be retried.
+	BackpressureRetryAfter time.Duration
+	// Limits of the live series of the measurements read by the stream tasks.
+	CardinalityLimits CardinalityLimits
+	// Whether to optimize the pipelines of new tasks, see pipeline.Optimize.
+	OptimizePipelines bool
+	// Maximum delay of the batch queries of a query node, spreading the queries of the tasks scheduled at the same time.
+	BatchQueryJitter time.Duration
+	// Maximum number of concurrent batch queries of each InfluxDB cluster, 0 means no limit.
+	MaxConcurrentQueries int
+	// Scheduler of the batch queries, shared with the task masters created by New.
+	queries *queryScheduler
+
 	// Incoming streams
 	writePointsIn StreamCollector
 	writesClosed  bool
@@ -210,11 +244,22 @@ type TaskMaster struct {
 	// We are mapping from (db, rp, measurement) to map of task ids to their edges
 	// The outer map (from dbrp&measurement) is for fast access on forkPoint
 	// While the inner map is for handling fork deletions better (see taskToForkKeys)
-	forks map[forkKey]map[string]edge.Edge
+	forks map[forkKey]map[string]edge.StatsEdge
+
+	// Task ids of each fork key ordered by descending priority,
+	// so that points are sent to higher priority tasks first.
+	forkOrder map[forkKey][]string
+
+	// Priority of each task with a fork
+	forkPriorities map[string]int
 
 	// Stats for number of points each fork has received
 	forkStats map[forkKey]*expvar.Int
 
+	// Queue stats of each database and retention policy
+	queueStats   map[dbrpKey]*queueStats
+	queueStatsMu sync.Mutex
+
 	// Task to fork keys is map to help in deletes, in deletes
 	// we have only the task id, and they are called after the task is deleted from TaskMaster.tasks
 	taskToForkKeys map[string][]forkKey
@@ -225,6 +270,9 @@ type TaskMaster struct {
 	// Executing tasks
 	tasks map[string]*ExecutingTask
 
+	// Batch intervals completed by the executing tasks
+	progress *intervalProgress
+
 	// DeleteHooks for tasks
 	deleteHooks map[string][]deleteHook
 
@@ -246,11 +294,15 @@ type forkKey struct {
 func NewTaskMaster(id string, info vars.Infoer, d Diagnostic) *TaskMaster {
 	return &TaskMaster{
 		id:             id,
-		forks:          make(map[forkKey]map[string]edge.Edge),
+		forks:          make(map[forkKey]map[string]edge.StatsEdge),
+		forkOrder:      make(map[forkKey][]string),
+		forkPriorities: make(map[string]int),
 		forkStats:      make(map[forkKey]*expvar.Int),
+		queueStats:     make(map[dbrpKey]*queueStats),
 		taskToForkKeys: make(map[string][]forkKey),
 		batches:        make(map[string][]BatchCollector),
 		tasks:          make(map[string]*ExecutingTask),
+		progress:       newIntervalProgress(),
 		deleteHooks:    make(map[string][]deleteHook),
 		ServerInfo:     info,
 		diag:           d.WithTaskMasterContext(id),
@@ -264,6 +316,13 @@ func NewTaskMaster(id string, info vars.Infoer, d Diagnostic) *TaskMaster {
 func (tm *TaskMaster) New(id string) *TaskMaster {
 	n := NewTaskMaster(id, tm.ServerInfo, tm.diag)
 	n.DefaultRetentionPolicy = tm.DefaultRetentionPolicy
+	n.QueueThreshold = tm.QueueThreshold
+	n.BackpressureRetryAfter = tm.BackpressureRetryAfter
+	n.CardinalityLimits = tm.CardinalityLimits
+	n.OptimizePipelines = tm.OptimizePipelines
+	n.BatchQueryJitter = tm.BatchQueryJitter
+	n.MaxConcurrentQueries = tm.MaxConcurrentQueries
+	n.queries = tm.queries
 	n.HTTPDService = tm.HTTPDService
 	n.TaskStore = tm.TaskStore
 	n.DeadmanService = tm.DeadmanService
@@ -284,6 +343,7 @@ func (tm *TaskMaster) New(id string) *TaskMaster {
 	n.SNMPTrapService = tm.SNMPTrapService
 	n.HipChatService = tm.HipChatService
 	n.AlertaService = tm.AlertaService
+	n.AMQPService = tm.AMQPService
 	n.SensuService = tm.SensuService
 	n.TalkService = tm.TalkService
 	n.TimingService = tm.TimingService
@@ -305,6 +365,9 @@ func (tm *TaskMaster) Open() (err error) {
 	}
 	tm.closed = false
 	tm.drained = false
+	if tm.queries == nil {
+		tm.queries = newQueryScheduler(tm.BatchQueryJitter, tm.MaxConcurrentQueries)
+	}
 	tm.writePointsIn, err = tm.stream("write_points")
 	if err != nil {
 		tm.closed = true
@@ -322,6 +385,30 @@ func (tm *TaskMaster) StopTasks() {
 	}
 }
 
+// HandoffTasks stops all tasks and saves the snapshots of their node states,
+// e.g. partially filled windows, so that the tasks resume from them when they are started again.
+// The task master must be drained first, so that the states include all received data.
+func (tm *TaskMaster) HandoffTasks() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	for id, et := range tm.tasks {
+		if err := tm.stopTask(id); err != nil {
+			// The node states of a failed task are not saved.
+			continue
+		}
+		snapshot, err := et.Snapshot()
+		if err != nil {
+			tm.diag.FailedToSaveTaskSnapshot(id, err)
+			continue
+		}
+		if err := tm.TaskStore.SaveSnapshot(id, snapshot); err != nil {
+			tm.diag.FailedToSaveTaskSnapshot(id, err)
+			continue
+		}
+		tm.diag.SavedTaskSnapshot(id)
+	}
+}
+
 func (tm *TaskMaster) Close() error {
 	tm.mu.Lock()
 	closed := tm.closed
@@ -409,6 +496,9 @@ func (tm *TaskMaster) NewTask(
 	if err != nil {
 		return nil, err
 	}
+	if tm.OptimizePipelines {
+		pipeline.Optimize(p)
+	}
 	// A task will always have a stream or batch node.
 	// If it doesn't have anything more then the task does nothing with the data.
 	if p.Len() <= 1 {
@@ -490,7 +580,7 @@ func (tm *TaskMaster) StartTask(t *Task) (*ExecutingTask, error) {
 	var ins []edge.StatsEdge
 	switch et.Task.Type {
 	case StreamTask:
-		e, err := tm.newFork(et.Task.ID, et.Task.DBRPs, et.Task.Measurements())
+		e, err := tm.newFork(et.Task.ID, et.Task.DBRPs, et.Task.Measurements(), et.Task.Priority)
 		if err != nil {
 			return nil, err
 		}
@@ -523,6 +613,9 @@ func (tm *TaskMaster) StartTask(t *Task) (*ExecutingTask, error) {
 	}
 
 	tm.tasks[et.Task.ID] = et
+	if len(t.DependsOn) > 0 {
+		tm.progress.setDependsOn(t.ID, t.DependsOn)
+	}
 	tm.diag.StartedTask(t.ID)
 	tm.diag.TaskMasterDot(string(t.Dot()))
 
@@ -564,6 +657,8 @@ func (tm *TaskMaster) stopTask(id string) (err error) {
 		}
 
 		err = et.stop()
+		// The queries have stopped, forget their progress
+		tm.progress.delete(id)
 		if err != nil {
 			tm.diag.StoppedTaskWithError(id, err)
 		} else {
@@ -606,6 +701,41 @@ func (tm *TaskMaster) ExecutionStats(id string) (ExecutionStats, error) {
 	return task.ExecutionStats()
 }
 
+// Tap adds a tap to the output of a node of an executing task.
+func (tm *TaskMaster) Tap(id, nodeName string, count int) (*Tap, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	task, executing := tm.tasks[id]
+	if !executing {
+		return nil, fmt.Errorf("task %s is not executing", id)
+	}
+	return task.Tap(nodeName, count)
+}
+
+// NodeExecutionStats returns the statistics of each node of a task, and whether the task is executing.
+func (tm *TaskMaster) NodeExecutionStats(id string) (map[string]NodeExecutionStats, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	task, executing := tm.tasks[id]
+	if !executing {
+		return nil, false
+	}
+	return task.NodeExecutionStats(), true
+}
+
+// Cardinality returns the live cardinality of the measurements read by a stream task,
+// with at most k top tags and tag values of each measurement.
+// It reports false if the task is not executing or its cardinality is not tracked.
+func (tm *TaskMaster) Cardinality(id string, k int) ([]MeasurementCardinality, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	et, executing := tm.tasks[id]
+	if !executing || et.cardinality == nil {
+		return nil, false
+	}
+	return et.cardinality.cardinality(k), true
+}
+
 func (tm *TaskMaster) ExecutingDot(id string, labels bool) string {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
@@ -616,6 +746,18 @@ func (tm *TaskMaster) ExecutingDot(id string, labels bool) string {
 	return ""
 }
 
+// ExecutingLiveDot returns the DOT graph of an executing task with the rates of its edges measured over the interval,
+// or an empty string if the task is not executing.
+func (tm *TaskMaster) ExecutingLiveDot(id string, labels bool, interval time.Duration) string {
+	tm.mu.RLock()
+	et, executing := tm.tasks[id]
+	tm.mu.RUnlock()
+	if executing {
+		return string(et.LiveDot(labels, interval))
+	}
+	return ""
+}
+
 func (tm *TaskMaster) Stream(name string) (StreamCollector, error) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
@@ -677,6 +819,11 @@ func (tm *TaskMaster) runForking(in StreamEdge) {
 }
 
 func (tm *TaskMaster) forkPoint(p edge.PointMessage) {
+	tm.forkPointTo(p, nil)
+}
+
+// forkPointTo forks the point to the tasks included, or to all tasks if include is nil.
+func (tm *TaskMaster) forkPointTo(p edge.PointMessage, include func(id string) bool) {
 	tm.mu.RLock()
 	locked := true
 	defer func() {
@@ -700,13 +847,21 @@ func (tm *TaskMaster) forkPoint(p edge.PointMessage) {
 		Measurement:     "",
 	}
 
-	// Merge the results to the forks map
-	for _, edge := range tm.forks[key] {
-		_ = edge.Collect(p)
-	}
-
-	for _, edge := range tm.forks[emptyMeasurementKey] {
-		_ = edge.Collect(p)
+	// Merge the results to the forks map,
+	// both sets of forks are ordered by priority so merge them in order.
+	ids, emptyIDs := tm.forkOrder[key], tm.forkOrder[emptyMeasurementKey]
+	for len(ids) > 0 || len(emptyIDs) > 0 {
+		if len(emptyIDs) == 0 || len(ids) > 0 && tm.forkPriorities[ids[0]] >= tm.forkPriorities[emptyIDs[0]] {
+			if include == nil || include(ids[0]) {
+				_ = tm.forks[key][ids[0]].Collect(p)
+			}
+			ids = ids[1:]
+		} else {
+			if include == nil || include(emptyIDs[0]) {
+				_ = tm.forks[emptyMeasurementKey][emptyIDs[0]].Collect(p)
+			}
+			emptyIDs = emptyIDs[1:]
+		}
 	}
 
 	c, ok := tm.forkStats[key]
@@ -747,6 +902,10 @@ func (tm *TaskMaster) WritePoints(database, retentionPolicy string, consistencyL
 	if retentionPolicy == "" {
 		retentionPolicy = tm.DefaultRetentionPolicy
 	}
+	if err := tm.checkBackpressure(database, retentionPolicy, len(points)); err != nil {
+		return err
+	}
+	tm.recordWrite(database, retentionPolicy, len(points))
 	for _, mp := range points {
 		p := edge.NewPointMessage(
 			mp.Name(),
@@ -757,7 +916,90 @@ func (tm *TaskMaster) WritePoints(database, retentionPolicy string, consistencyL
 			models.Tags(mp.Tags().Map()),
 			mp.Time(),
 		)
+		span := tracePoint(p)
+		err := tm.writePointsIn.CollectPoint(p)
+		span.Finish()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReplayPoints writes the points of the record of the write-ahead log at the checkpoint
+// to the stream tasks which were not restored from a snapshot taken after it, the other tasks already processed them.
+// The points are forked to the tasks directly, so they must be replayed before other points are written.
+func (tm *TaskMaster) ReplayPoints(checkpoint, database, retentionPolicy string, points []imodels.Point) error {
+	if retentionPolicy == "" {
+		retentionPolicy = tm.DefaultRetentionPolicy
+	}
+	tm.mu.RLock()
+	replay := make(map[string]bool, len(tm.tasks))
+	for id, et := range tm.tasks {
+		replay[id] = et.checkpoint < checkpoint
+	}
+	tm.mu.RUnlock()
+	include := func(id string) bool { return replay[id] }
+	for _, mp := range points {
+		tm.forkPointTo(edge.NewPointMessage(
+			mp.Name(),
+			database,
+			retentionPolicy,
+			models.Dimensions{},
+			models.Fields(mp.Fields()),
+			models.Tags(mp.Tags().Map()),
+			mp.Time(),
+		), include)
+	}
+	return nil
+}
+
+// checkpoint returns the position of the write-ahead log, empty if there is none.
+func (tm *TaskMaster) checkpoint() string {
+	if tm.WAL == nil {
+		return ""
+	}
+	return tm.WAL.Checkpoint()
+}
+
+// WriteLineProtocol writes the parsed line protocol points,
+// without the intermediate InfluxDB points of WritePoints.
+func (tm *TaskMaster) WriteLineProtocol(database, retentionPolicy string, points *lineprotocol.Points) error {
+	tm.writesMu.RLock()
+	defer tm.writesMu.RUnlock()
+	if tm.writesClosed {
+		return ErrTaskMasterClosed
+	}
+	if retentionPolicy == "" {
+		retentionPolicy = tm.DefaultRetentionPolicy
+	}
+	if err := tm.checkBackpressure(database, retentionPolicy, points.Len()); err != nil {
+		return err
+	}
+	tm.recordWrite(database, retentionPolicy, points.Len())
+	for i := 0; i < points.Len(); i++ {
+		lp := points.Point(i)
+		tags := make(models.Tags, len(lp.Tags))
+		for _, t := range lp.Tags {
+			tags[points.String(t.Key)] = points.String(t.Value)
+		}
+		fields := make(models.Fields, len(lp.Fields))
+		for j := range lp.Fields {
+			f := &lp.Fields[j]
+			fields[points.String(f.Key)] = f.Value()
+		}
+		p := edge.NewPointMessage(
+			points.String(lp.Name),
+			database,
+			retentionPolicy,
+			models.Dimensions{},
+			fields,
+			tags,
+			lp.Time,
+		)
+		span := tracePoint(p)
 		err := tm.writePointsIn.CollectPoint(p)
+		span.Finish()
 		if err != nil {
 			return err
 		}
@@ -765,6 +1007,19 @@ func (tm *TaskMaster) WritePoints(database, retentionPolicy string, consistencyL
 	return nil
 }
 
+// tracePoint starts the trace of an ingested point if it is sampled,
+// the span lasts until the point is queued for the tasks.
+func tracePoint(p edge.PointMessage) *tracing.Span {
+	span := tracing.Start("ingest", tracing.KindConsumer, tracing.SpanContext{})
+	if span != nil {
+		span.SetAttribute("kapacitor.database", p.Database())
+		span.SetAttribute("kapacitor.retention_policy", p.RetentionPolicy())
+		span.SetAttribute("kapacitor.measurement", p.Name())
+		edge.SetTraceContext(p, span.Context)
+	}
+	return span
+}
+
 func (tm *TaskMaster) WriteKapacitorPoint(p edge.PointMessage) error {
 	tm.writesMu.RLock()
 	defer tm.writesMu.RUnlock()
@@ -779,7 +1034,7 @@ func (tm *TaskMaster) WriteKapacitorPoint(p edge.PointMessage) error {
 func (tm *TaskMaster) NewFork(taskName string, dbrps []DBRP, measurements []string) (edge.StatsEdge, error) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
-	return tm.newFork(taskName, dbrps, measurements)
+	return tm.newFork(taskName, dbrps, measurements, 0)
 }
 
 func forkKeys(dbrps []DBRP, measurements []string) []forkKey {
@@ -801,13 +1056,14 @@ func forkKeys(dbrps []DBRP, measurements []string) []forkKey {
 }
 
 // internal newFork, must have acquired lock before calling.
-func (tm *TaskMaster) newFork(taskName string, dbrps []DBRP, measurements []string) (edge.StatsEdge, error) {
+func (tm *TaskMaster) newFork(taskName string, dbrps []DBRP, measurements []string, priority int) (edge.StatsEdge, error) {
 	if tm.closed {
 		return nil, ErrTaskMasterClosed
 	}
 
 	d := tm.diag.WithEdgeContext(taskName, "stream", "stream0")
 	e := newEdge(taskName, "stream", "stream0", pipeline.StreamEdge, defaultEdgeBufferSize, d)
+	tm.forkPriorities[taskName] = priority
 
 	for _, key := range forkKeys(dbrps, measurements) {
 		tm.taskToForkKeys[taskName] = append(tm.taskToForkKeys[taskName], key)
@@ -815,7 +1071,7 @@ func (tm *TaskMaster) newFork(taskName string, dbrps []DBRP, measurements []stri
 		// Add the task to the tasksMap if it doesn't exists
 		tasksMap, ok := tm.forks[key]
 		if !ok {
-			tasksMap = make(map[string]edge.Edge, 0)
+			tasksMap = make(map[string]edge.StatsEdge, 0)
 		}
 
 		// Add the edge to task map
@@ -823,11 +1079,25 @@ func (tm *TaskMaster) newFork(taskName string, dbrps []DBRP, measurements []stri
 
 		// update the task map in the forks
 		tm.forks[key] = tasksMap
+		tm.orderForks(key)
 	}
 
 	return e, nil
 }
 
+// SetPriority changes the priority of the fork of a running stream task.
+func (tm *TaskMaster) SetPriority(id string, priority int) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if _, ok := tm.forkPriorities[id]; !ok {
+		return
+	}
+	tm.forkPriorities[id] = priority
+	for _, key := range tm.taskToForkKeys[id] {
+		tm.orderForks(key)
+	}
+}
+
 func (tm *TaskMaster) DelFork(id string) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
@@ -856,11 +1126,31 @@ func (tm *TaskMaster) delFork(id string) {
 
 			// remove the task in fork map
 			delete(tm.forks[key], id)
+			tm.orderForks(key)
 		}
 	}
 
 	// remove mapping from task id to it's keys
 	delete(tm.taskToForkKeys, id)
+	delete(tm.forkPriorities, id)
+}
+
+// internal orderForks function, must have lock to call.
+// Orders the tasks of a fork key by descending priority,
+// tasks with the same priority are ordered by id.
+func (tm *TaskMaster) orderForks(key forkKey) {
+	ids := make([]string, 0, len(tm.forks[key]))
+	for id := range tm.forks[key] {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		pi, pj := tm.forkPriorities[ids[i]], tm.forkPriorities[ids[j]]
+		if pi != pj {
+			return pi > pj
+		}
+		return ids[i] < ids[j]
+	})
+	tm.forkOrder[key] = ids
 }
 
 func (tm *TaskMaster) SnapshotTask(id string) (*TaskSnapshot, error) {
@@ -0,0 +1,147 @@
+package kapacitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/models"
+)
+
+type taskMasterDiagnostic struct {
+	Diagnostic
+}
+
+func (d taskMasterDiagnostic) WithTaskMasterContext(string) Diagnostic { return d }
+func (d taskMasterDiagnostic) WithEdgeContext(task, parent, child string) EdgeDiagnostic {
+	return d
+}
+func (taskMasterDiagnostic) ClosingEdge(collected, emitted int64) {}
+
+func TestTaskMaster_ForkPriority(t *testing.T) {
+	tm := NewTaskMaster("testForkPriority", nil, taskMasterDiagnostic{})
+	tm.closed = false
+
+	dbrps := []DBRP{{Database: "db", RetentionPolicy: "rp"}}
+	forks := []struct {
+		id          string
+		measurement string
+		priority    int
+	}{
+		{id: "low", measurement: "cpu", priority: 0},
+		{id: "all", measurement: "", priority: 5},
+		{id: "high", measurement: "cpu", priority: 10},
+		{id: "other", measurement: "cpu", priority: 0},
+	}
+	edges := make(map[string]edge.StatsEdge)
+	for _, f := range forks {
+		e, err := tm.newFork(f.id, dbrps, []string{f.measurement}, f.priority)
+		if err != nil {
+			t.Fatal(err)
+		}
+		edges[f.id] = e
+	}
+
+	cpu := forkKey{Database: "db", RetentionPolicy: "rp", Measurement: "cpu"}
+	if got, exp := tm.forkOrder[cpu], []string{"high", "low", "other"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected fork order got %v exp %v", got, exp)
+	}
+
+	tm.forkPoint(edge.NewPointMessage("cpu", "db", "rp", models.Dimensions{}, models.Fields{"value": 1.0}, nil, time.Time{}))
+	for id, e := range edges {
+		if got := e.Collected(); got != 1 {
+			t.Errorf("%s: unexpected collected points got %d exp 1", id, got)
+		}
+	}
+
+	tm.SetPriority("other", 20)
+	if got, exp := tm.forkOrder[cpu], []string{"other", "high", "low"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected fork order after priority change got %v exp %v", got, exp)
+	}
+
+	tm.delFork("high")
+	if got, exp := tm.forkOrder[cpu], []string{"other", "low"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected fork order after delete got %v exp %v", got, exp)
+	}
+	if _, ok := tm.forkPriorities["high"]; ok {
+		t.Error("expected priority of deleted fork to be removed")
+	}
+}
+
+func TestTaskMaster_Backpressure(t *testing.T) {
+	tm := NewTaskMaster("testBackpressure", nil, taskMasterDiagnostic{})
+	tm.closed = false
+	tm.QueueThreshold = 2
+	tm.BackpressureRetryAfter = time.Second
+
+	e, err := tm.newFork("task", []DBRP{{Database: "db", RetentionPolicy: "rp"}}, []string{""}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := edge.NewPointMessage("cpu", "db", "rp", models.Dimensions{}, models.Fields{"value": 1.0}, nil, time.Time{})
+	for i := 0; i < 2; i++ {
+		if err := tm.checkBackpressure("db", "rp", 1); err != nil {
+			t.Fatalf("unexpected backpressure with %d queued points: %v", i, err)
+		}
+		if err := e.Collect(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = tm.checkBackpressure("db", "rp", 3)
+	bp, ok := err.(BackpressureError)
+	if !ok {
+		t.Fatalf("expected backpressure error, got %v", err)
+	}
+	if bp.Queued != 2 || bp.RetryAfter() != time.Second {
+		t.Errorf("unexpected backpressure error %+v", bp)
+	}
+	if err := tm.checkBackpressure("other", "rp", 1); err != nil {
+		t.Errorf("unexpected backpressure of other database: %v", err)
+	}
+	stats := tm.getQueueStats("db", "rp")
+	if got := stats.rejectedPoints.IntValue(); got != 3 {
+		t.Errorf("unexpected rejected points got %d exp 3", got)
+	}
+
+	// The task reads a point from its queue.
+	if _, ok := e.Emit(); !ok {
+		t.Fatal("expected point")
+	}
+	if err := tm.checkBackpressure("db", "rp", 1); err != nil {
+		t.Errorf("unexpected backpressure after point was read: %v", err)
+	}
+}
+
+func TestTaskMaster_DBRPUsage(t *testing.T) {
+	tm := NewTaskMaster("testDBRPUsage", nil, taskMasterDiagnostic{})
+	tm.closed = false
+
+	cpu := DBRP{Database: "db", RetentionPolicy: "rp"}
+	idle := DBRP{Database: "idle", RetentionPolicy: "rp"}
+	tm.tasks["b"] = &ExecutingTask{Task: &Task{ID: "b", Type: StreamTask, DBRPs: []DBRP{cpu, idle}}}
+	tm.tasks["a"] = &ExecutingTask{Task: &Task{ID: "a", Type: StreamTask, DBRPs: []DBRP{cpu}}}
+	tm.tasks["batch"] = &ExecutingTask{Task: &Task{ID: "batch", Type: BatchTask, DBRPs: []DBRP{cpu}}}
+
+	tm.recordWrite("db", "rp", 3)
+	tm.recordWrite("db", "rp", 2)
+	tm.recordWrite("orphan", "rp", 1)
+
+	usage := tm.DBRPUsage()
+	if got := len(usage); got != 3 {
+		t.Fatalf("unexpected usage %v", usage)
+	}
+	u := usage[cpu]
+	if u.PointsReceived != 5 || u.LastWrite.IsZero() || !reflect.DeepEqual(u.Tasks, []string{"a", "b"}) {
+		t.Errorf("unexpected usage of %v: %+v", cpu, u)
+	}
+	u = usage[idle]
+	if u.PointsReceived != 0 || !u.LastWrite.IsZero() || !reflect.DeepEqual(u.Tasks, []string{"b"}) {
+		t.Errorf("unexpected usage of %v: %+v", idle, u)
+	}
+	u = usage[DBRP{Database: "orphan", RetentionPolicy: "rp"}]
+	if u.PointsReceived != 1 || len(u.Tasks) != 0 {
+		t.Errorf("unexpected usage of orphan: %+v", u)
+	}
+}
@@ -0,0 +1,55 @@
+package kapacitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime/pprof"
+	"time"
+
+	"github.com/influxdata/kapacitor/profile"
+)
+
+// Labels of the goroutines of the nodes of tasks, to attribute CPU profile samples to them.
+const (
+	ProfileTaskLabel = "task"
+	ProfileNodeLabel = "node"
+)
+
+// ErrProfiling is returned when a CPU profile is already being collected,
+// since a process can only collect one at a time.
+var ErrProfiling = errors.New("a CPU profile is already being collected")
+
+// ProfileTask collects a CPU profile of the process for the duration,
+// and returns the samples of the nodes of the task.
+// The nodes are identified by the node label of the samples.
+func (tm *TaskMaster) ProfileTask(ctx context.Context, id string, d time.Duration) (*profile.Profile, error) {
+	if !tm.IsExecuting(id) {
+		return nil, fmt.Errorf("task %s is not executing", id)
+	}
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return nil, ErrProfiling
+	}
+	t := time.NewTimer(d)
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+		t.Stop()
+	}
+	pprof.StopCPUProfile()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	p, err := profile.Parse(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return p.Filter(ProfileTaskLabel, id), nil
+}
+
+// profileLabels returns the labels of the goroutine of the node.
+func (n *node) profileLabels() pprof.LabelSet {
+	return pprof.Labels(ProfileTaskLabel, n.et.Task.ID, ProfileNodeLabel, n.Name())
+}
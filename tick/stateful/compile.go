@@ -0,0 +1,86 @@
+package stateful
+
+import (
+	"github.com/influxdata/kapacitor/tick/ast"
+)
+
+// createOperandEvaluator creates the evaluator of an operand or argument of an expression,
+// folding it to a constant if it only has literals.
+// The root of an expression is not folded, so that it still reports the type it was written with.
+func createOperandEvaluator(n ast.Node) (NodeEvaluator, error) {
+	e, err := createNodeEvaluator(n)
+	if err != nil {
+		return nil, err
+	}
+	return foldConstant(n, e), nil
+}
+
+// foldConstant returns the value of an expression of constants as a constant node,
+// so that it is computed once when the expression is created instead of on every evaluation.
+// The evaluator is returned as is if the expression is not constant or fails to evaluate,
+// so that it fails on evaluation as before.
+func foldConstant(n ast.Node, e NodeEvaluator) (folded NodeEvaluator) {
+	if !isConstantNode(n) {
+		return e
+	}
+	// Integer division by zero panics
+	defer func() {
+		if r := recover(); r != nil {
+			folded = e
+		}
+	}()
+	typ, err := e.Type(nil)
+	if err != nil {
+		return e
+	}
+	scope := NewScope()
+	state := ExecutionState{}
+	switch typ {
+	case ast.TFloat:
+		if v, err := e.EvalFloat(scope, state); err == nil {
+			return &EvalFloatNode{Float64: v}
+		}
+	case ast.TInt:
+		if v, err := e.EvalInt(scope, state); err == nil {
+			return &EvalIntNode{Int64: v}
+		}
+	case ast.TBool:
+		if v, err := e.EvalBool(scope, state); err == nil {
+			return &EvalBoolNode{Node: &ast.BoolNode{Bool: v}}
+		}
+	case ast.TString:
+		if v, err := e.EvalString(scope, state); err == nil {
+			return &EvalStringNode{Node: &ast.StringNode{Literal: v}}
+		}
+	case ast.TDuration:
+		if v, err := e.EvalDuration(scope, state); err == nil {
+			return &EvalDurationNode{Duration: v}
+		}
+	}
+	return e
+}
+
+// isConstantNode reports whether the expression only has literals.
+func isConstantNode(n ast.Node) bool {
+	switch node := n.(type) {
+	case *ast.NumberNode, *ast.StringNode, *ast.BoolNode, *ast.DurationNode, *ast.RegexNode:
+		return true
+	case *ast.UnaryNode:
+		return isConstantNode(node.Node)
+	case *ast.BinaryNode:
+		return isConstantNode(node.Left) && isConstantNode(node.Right)
+	}
+	return false
+}
+
+// isPure reports whether evaluating the expression has no side effects,
+// i.e. it only calls builtin functions without state.
+// A pure expression can be evaluated again to recover from a failed evaluation.
+func isPure(n ast.Node) bool {
+	for _, f := range ast.FindFunctionCalls(n) {
+		if !IsStateless(f) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,231 @@
+package stateful
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/tick/ast"
+)
+
+func TestCreateOperandEvaluator_FoldConstant(t *testing.T) {
+	testCases := []struct {
+		name string
+		node ast.Node
+		exp  NodeEvaluator
+	}{
+		{
+			name: "float",
+			node: &ast.BinaryNode{
+				Operator: ast.TokenMult,
+				Left:     &ast.NumberNode{IsFloat: true, Float64: 2.5},
+				Right:    &ast.NumberNode{IsFloat: true, Float64: 4},
+			},
+			exp: &EvalFloatNode{Float64: 10},
+		},
+		{
+			name: "nested int",
+			node: &ast.BinaryNode{
+				Operator: ast.TokenPlus,
+				Left:     &ast.NumberNode{IsInt: true, Int64: 1},
+				Right: &ast.UnaryNode{
+					Operator: ast.TokenMinus,
+					Node:     &ast.NumberNode{IsInt: true, Int64: 3},
+				},
+			},
+			exp: &EvalIntNode{Int64: -2},
+		},
+		{
+			name: "bool",
+			node: &ast.UnaryNode{
+				Operator: ast.TokenNot,
+				Node:     &ast.BoolNode{Bool: false},
+			},
+			exp: &EvalBoolNode{Node: &ast.BoolNode{Bool: true}},
+		},
+		{
+			name: "string",
+			node: &ast.BinaryNode{
+				Operator: ast.TokenPlus,
+				Left:     &ast.StringNode{Literal: "cpu"},
+				Right:    &ast.StringNode{Literal: "_total"},
+			},
+			exp: &EvalStringNode{Node: &ast.StringNode{Literal: "cpu_total"}},
+		},
+		{
+			name: "duration",
+			node: &ast.BinaryNode{
+				Operator: ast.TokenMult,
+				Left:     &ast.DurationNode{Dur: time.Minute},
+				Right:    &ast.NumberNode{IsInt: true, Int64: 5},
+			},
+			exp: &EvalDurationNode{Duration: 5 * time.Minute},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := createOperandEvaluator(tc.node)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, exp := e, tc.exp; !evaluatorsEqual(got, exp) {
+				t.Errorf("unexpected evaluator got %#v exp %#v", got, exp)
+			}
+		})
+	}
+}
+
+func TestCreateOperandEvaluator_NotFolded(t *testing.T) {
+	testCases := []struct {
+		name string
+		node ast.Node
+	}{
+		{
+			name: "reference",
+			node: &ast.BinaryNode{
+				Operator: ast.TokenMult,
+				Left:     &ast.ReferenceNode{Reference: "value"},
+				Right:    &ast.NumberNode{IsFloat: true, Float64: 4},
+			},
+		},
+		{
+			name: "division by zero",
+			node: &ast.BinaryNode{
+				Operator: ast.TokenDiv,
+				Left:     &ast.NumberNode{IsInt: true, Int64: 1},
+				Right:    &ast.NumberNode{IsInt: true, Int64: 0},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := createOperandEvaluator(tc.node)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := e.(*EvalBinaryNode); !ok {
+				t.Errorf("expected the expression not to be folded, got %T", e)
+			}
+		})
+	}
+}
+
+func TestEvalBinaryNode_Pure(t *testing.T) {
+	testCases := []struct {
+		name string
+		node *ast.BinaryNode
+		pure bool
+	}{
+		{
+			name: "reference",
+			node: &ast.BinaryNode{
+				Operator: ast.TokenGreater,
+				Left:     &ast.ReferenceNode{Reference: "value"},
+				Right:    &ast.NumberNode{IsFloat: true, Float64: 4},
+			},
+			pure: true,
+		},
+		{
+			name: "stateless function",
+			node: &ast.BinaryNode{
+				Operator: ast.TokenGreater,
+				Left: &ast.FunctionNode{
+					Func: "abs",
+					Args: []ast.Node{&ast.ReferenceNode{Reference: "value"}},
+				},
+				Right: &ast.NumberNode{IsFloat: true, Float64: 4},
+			},
+			pure: true,
+		},
+		{
+			name: "stateful function",
+			node: &ast.BinaryNode{
+				Operator: ast.TokenGreater,
+				Left: &ast.FunctionNode{
+					Func: "sigma",
+					Args: []ast.Node{&ast.ReferenceNode{Reference: "value"}},
+				},
+				Right: &ast.NumberNode{IsFloat: true, Float64: 4},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := NewEvalBinaryNode(tc.node)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, exp := e.pure, tc.pure; got != exp {
+				t.Errorf("unexpected pure got %t exp %t", got, exp)
+			}
+		})
+	}
+}
+
+func TestEvalBinaryNode_EvalBool_TypeChanges(t *testing.T) {
+	e, err := NewEvalBinaryNode(&ast.BinaryNode{
+		Operator: ast.TokenGreater,
+		Left:     &ast.ReferenceNode{Reference: "value"},
+		Right: &ast.BinaryNode{
+			Operator: ast.TokenMult,
+			Left:     &ast.NumberNode{IsInt: true, Int64: 5},
+			Right:    &ast.NumberNode{IsInt: true, Int64: 2},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := CreateExecutionState()
+	testCases := []struct {
+		value interface{}
+		exp   bool
+		err   string
+	}{
+		{value: 11.0, exp: true},
+		{value: 9.0, exp: false},
+		{value: int64(11), exp: true},
+		{value: int64(10), exp: false},
+		{value: "11", err: "mismatched type to binary operator. got string > int. see bool(), int(), float(), string(), duration()"},
+		{value: 12.5, exp: true},
+		{value: nil, err: `name "value" is undefined. Names in scope: `},
+	}
+	for i, tc := range testCases {
+		scope := NewScope()
+		if tc.value != nil {
+			scope.Set("value", tc.value)
+		}
+		got, err := e.EvalBool(scope, state)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%d: unexpected error got %v exp %s", i, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if got != tc.exp {
+			t.Errorf("%d: unexpected result for %v got %t exp %t", i, tc.value, got, tc.exp)
+		}
+	}
+}
+
+func evaluatorsEqual(a, b NodeEvaluator) bool {
+	switch a := a.(type) {
+	case *EvalFloatNode:
+		b, ok := b.(*EvalFloatNode)
+		return ok && a.Float64 == b.Float64
+	case *EvalIntNode:
+		b, ok := b.(*EvalIntNode)
+		return ok && a.Int64 == b.Int64
+	case *EvalBoolNode:
+		b, ok := b.(*EvalBoolNode)
+		return ok && a.Node.Bool == b.Node.Bool
+	case *EvalStringNode:
+		b, ok := b.(*EvalStringNode)
+		return ok && a.Node.Literal == b.Node.Literal
+	case *EvalDurationNode:
+		b, ok := b.(*EvalDurationNode)
+		return ok && a.Duration == b.Duration
+	}
+	return false
+}
@@ -77,6 +77,11 @@ type EvalBinaryNode struct {
 	// Constant return type
 	// If InvalidType then this node is dynamic.
 	constReturnType ast.ValueType
+
+	// Whether the node can be evaluated again without side effects,
+	// so that a dynamic node can use the evaluation function of the types of its previous evaluation
+	// and only determine the types of its sides when it fails.
+	pure bool
 }
 
 func NewEvalBinaryNode(node *ast.BinaryNode) (*EvalBinaryNode, error) {
@@ -86,14 +91,15 @@ func NewEvalBinaryNode(node *ast.BinaryNode) (*EvalBinaryNode, error) {
 	b := &EvalBinaryNode{
 		operator:        node.Operator,
 		constReturnType: getConstantNodeType(node),
+		pure:            isPure(node),
 	}
 
-	leftSideEvaluator, err := createNodeEvaluator(node.Left)
+	leftSideEvaluator, err := createOperandEvaluator(node.Left)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to handle left node: %v", err)
 	}
 
-	rightSideEvaluator, err := createNodeEvaluator(node.Right)
+	rightSideEvaluator, err := createOperandEvaluator(node.Right)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to handle right node: %v", err)
 	}
@@ -194,7 +200,13 @@ func (e *EvalBinaryNode) EvalBool(scope *Scope, executionState ExecutionState) (
 	var result resultContainer
 	var err *ErrSide
 	if e.leftEvaluator.IsDynamic() || e.rightEvaluator.IsDynamic() {
-		result, err = e.evaluateDynamicNode(scope, executionState, e.leftEvaluator, e.rightEvaluator)
+		if e.pure {
+			// The types of the sides rarely change, try the evaluation function of the previous types
+			result, err = e.eval(scope, executionState)
+		}
+		if !e.pure || err != nil {
+			result, err = e.evaluateDynamicNode(scope, executionState, e.leftEvaluator, e.rightEvaluator)
+		}
 	} else {
 		result, err = e.eval(scope, executionState)
 	}
@@ -22,7 +22,7 @@ func NewEvalFunctionNode(funcNode *ast.FunctionNode) (*EvalFunctionNode, error)
 
 	evalFuncNode.argsEvaluators = make([]NodeEvaluator, 0, len(funcNode.Args))
 	for i, argNode := range funcNode.Args {
-		argEvaluator, err := createNodeEvaluator(argNode)
+		argEvaluator, err := createOperandEvaluator(argNode)
 		if err != nil {
 			return nil, fmt.Errorf("Failed to handle %v argument: %v", i+1, err)
 		}
@@ -19,7 +19,7 @@ func NewEvalUnaryNode(unaryNode *ast.UnaryNode) (*EvalUnaryNode, error) {
 		return nil, fmt.Errorf("Invalid unary operator: %q", unaryNode.Operator)
 	}
 
-	nodeEvaluator, err := createNodeEvaluator(unaryNode.Node)
+	nodeEvaluator, err := createOperandEvaluator(unaryNode.Node)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to handle node: %v", err)
 	}
@@ -1,5 +1,11 @@
 package stateful
 
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
 // ExecutionState is auxiliary struct for data/context that needs to be passed
 // to evaluation functions
 type ExecutionState struct {
@@ -18,3 +24,63 @@ func (ea ExecutionState) ResetAll() {
 		f.Reset()
 	}
 }
+
+// stateFunc is a function with state, e.g. sigma, whose state is kept in snapshots.
+type stateFunc interface {
+	Func
+	// snapshot returns the state of the function, nil if it has not been called since it was reset.
+	snapshot() ([]byte, error)
+	restore(snapshot []byte) error
+}
+
+// Snapshot returns the state of the stateful functions, nil if none of them has state.
+func (ea ExecutionState) Snapshot() ([]byte, error) {
+	states := make(map[string][]byte)
+	for name, f := range ea.Funcs {
+		sf, ok := f.(stateFunc)
+		if !ok {
+			continue
+		}
+		state, err := sf.snapshot()
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot function %s: %v", name, err)
+		}
+		if state != nil {
+			states[name] = state
+		}
+	}
+	if len(states) == 0 {
+		return nil, nil
+	}
+	return encodeState(states)
+}
+
+// Restore sets the state of the stateful functions from a snapshot.
+func (ea ExecutionState) Restore(snapshot []byte) error {
+	var states map[string][]byte
+	if err := decodeState(snapshot, &states); err != nil {
+		return err
+	}
+	for name, state := range states {
+		sf, ok := ea.Funcs[name].(stateFunc)
+		if !ok {
+			return fmt.Errorf("unknown stateful function %s", name)
+		}
+		if err := sf.restore(state); err != nil {
+			return fmt.Errorf("failed to restore function %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func encodeState(state interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(state); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeState(data []byte, state interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(state)
+}
@@ -24,6 +24,12 @@ type Expression interface {
 
 	// Return a copy of the expression but with a Reset state.
 	CopyReset() Expression
+
+	// Snapshot returns the state of the stateful functions of the expression,
+	// nil if the functions have no state.
+	Snapshot() ([]byte, error)
+	// Restore sets the state of the stateful functions from a snapshot.
+	Restore(snapshot []byte) error
 }
 
 type expression struct {
@@ -60,6 +66,14 @@ func (se *expression) Reset() {
 	se.executionState.ResetAll()
 }
 
+func (se *expression) Snapshot() ([]byte, error) {
+	return se.executionState.Snapshot()
+}
+
+func (se *expression) Restore(snapshot []byte) error {
+	return se.executionState.Restore(snapshot)
+}
+
 func (se *expression) Type(scope ReadOnlyScope) (ast.ValueType, error) {
 	return se.nodeEvaluator.Type(scope)
 }
@@ -237,6 +237,13 @@ func init() {
 	builtinFuncs = NewFunctions()
 }
 
+// IsStateless reports whether the function of the name is a builtin function
+// whose results do not depend on its previous calls.
+func IsStateless(name string) bool {
+	_, ok := statelessFuncs[name]
+	return ok
+}
+
 // Return set of built-in Funcs
 func NewFunctions() Funcs {
 	funcs := make(Funcs, len(statelessFuncs)+3)
@@ -1067,6 +1074,26 @@ func (c *count) Reset() {
 	c.n = 0
 }
 
+type countState struct {
+	N int64
+}
+
+func (c *count) snapshot() ([]byte, error) {
+	if c.n == 0 {
+		return nil, nil
+	}
+	return encodeState(countState{N: c.n})
+}
+
+func (c *count) restore(snapshot []byte) error {
+	var state countState
+	if err := decodeState(snapshot, &state); err != nil {
+		return err
+	}
+	c.n = state.N
+	return nil
+}
+
 // Counts the number of values processed.
 func (c *count) Call(args ...interface{}) (v interface{}, err error) {
 	c.n++
@@ -1099,6 +1126,37 @@ func (s *sigma) Reset() {
 	s.n = 0
 }
 
+type sigmaState struct {
+	Mean     float64
+	Variance float64
+	M2       float64
+	N        float64
+}
+
+func (s *sigma) snapshot() ([]byte, error) {
+	if s.n == 0 {
+		return nil, nil
+	}
+	return encodeState(sigmaState{
+		Mean:     s.mean,
+		Variance: s.variance,
+		M2:       s.m2,
+		N:        s.n,
+	})
+}
+
+func (s *sigma) restore(snapshot []byte) error {
+	var state sigmaState
+	if err := decodeState(snapshot, &state); err != nil {
+		return err
+	}
+	s.mean = state.Mean
+	s.variance = state.Variance
+	s.m2 = state.M2
+	s.n = state.N
+	return nil
+}
+
 // Computes the number of standard devaitions a given value is from the running mean.
 func (s *sigma) Call(args ...interface{}) (interface{}, error) {
 	if len(args) != 1 {
@@ -1143,6 +1201,32 @@ func (s *spread) Reset() {
 	s.max = math.Inf(-1)
 }
 
+type spreadState struct {
+	Min float64
+	Max float64
+}
+
+func (s *spread) snapshot() ([]byte, error) {
+	if s.min > s.max {
+		// No values have been processed.
+		return nil, nil
+	}
+	return encodeState(spreadState{
+		Min: s.min,
+		Max: s.max,
+	})
+}
+
+func (s *spread) restore(snapshot []byte) error {
+	var state spreadState
+	if err := decodeState(snapshot, &state); err != nil {
+		return err
+	}
+	s.min = state.Min
+	s.max = state.Max
+	return nil
+}
+
 // Computes the running range of all values
 func (s *spread) Call(args ...interface{}) (interface{}, error) {
 	if len(args) != 1 {
@@ -913,3 +913,42 @@ func Test_StatelessFuncs(t *testing.T) {
 	}
 
 }
+
+func Test_StatefulFuncs_SnapshotRestore(t *testing.T) {
+	values := []float64{1, 4, 2, 8, 5}
+	for _, name := range []string{"count", "sigma", "spread"} {
+		// One execution state is snapshotted halfway and restored into another.
+		state := CreateExecutionState()
+		restored := CreateExecutionState()
+		if snapshot, err := restored.Snapshot(); err != nil {
+			t.Fatal(err)
+		} else if snapshot != nil {
+			t.Errorf("%s: unexpected snapshot of reset functions: %v", name, snapshot)
+		}
+		for i, v := range values {
+			if i == len(values)/2 {
+				snapshot, err := state.Snapshot()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := restored.Restore(snapshot); err != nil {
+					t.Fatal(err)
+				}
+			}
+			exp, err := state.Funcs[name].Call(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i < len(values)/2 {
+				continue
+			}
+			got, err := restored.Funcs[name].Call(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != exp {
+				t.Errorf("%s: unexpected result of value %d got %v exp %v", name, i, got, exp)
+			}
+		}
+	}
+}
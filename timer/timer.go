@@ -25,10 +25,17 @@ type timerState int
 // A variable that is settable.
 // The use of this interface allows for control
 // on how the averaged timed value accessed.
+// If the Setter also implements Observer it is notified of each timed value.
 type Setter interface {
 	Set(int64)
 }
 
+// An Observer is notified of the duration of each timed event,
+// instead of only their moving average.
+type Observer interface {
+	Observe(time.Duration)
+}
+
 const (
 	Stopped timerState = iota
 	Started
@@ -98,6 +105,9 @@ func (t *timer) Stop() {
 		return
 	}
 	t.current += time.Now().Sub(t.start)
+	if o, ok := t.avgVar.(Observer); ok {
+		o.Observe(t.current)
+	}
 	// Use float64 precision when performing movavg calculations.
 	avg := t.avg.update(float64(t.current))
 	t.current = 0
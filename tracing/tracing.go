@@ -0,0 +1,198 @@
+// Package tracing records sampled spans of the flow of points and batches through tasks,
+// from their ingestion through the nodes of tasks to the delivery of the alerts they trigger.
+//
+// Spans are recorded by the tracer set with SetTracer, no spans are recorded without a tracer.
+// The trace of a point is started when it is ingested, if it is sampled,
+// and its context is carried by the point to the nodes it goes through.
+// Only the contexts of sampled traces are carried, so a valid context is always sampled.
+package tracing
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type TraceID [16]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+type SpanID [8]byte
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanContext identifies a span and its trace.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// IsValid reports whether the context identifies a span.
+func (c SpanContext) IsValid() bool {
+	return c.TraceID != TraceID{} && c.SpanID != SpanID{}
+}
+
+// Traceparent returns the context in the format of the W3C traceparent HTTP header.
+func (c SpanContext) Traceparent() string {
+	return "00-" + c.TraceID.String() + "-" + c.SpanID.String() + "-01"
+}
+
+// ParseTraceparent parses the context of a W3C traceparent HTTP header.
+// It reports false if the header is invalid or its trace is not sampled.
+func ParseTraceparent(s string) (SpanContext, bool) {
+	var c SpanContext
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return c, false
+	}
+	if _, err := hex.Decode(c.TraceID[:], []byte(parts[1])); err != nil {
+		return c, false
+	}
+	if _, err := hex.Decode(c.SpanID[:], []byte(parts[2])); err != nil {
+		return c, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || flags[0]&1 == 0 {
+		return c, false
+	}
+	return c, c.IsValid()
+}
+
+// Kind is the role of a span in a trace, with the values of OpenTelemetry.
+type Kind int
+
+const (
+	KindInternal Kind = iota + 1
+	KindServer
+	KindClient
+	KindProducer
+	KindConsumer
+)
+
+// Attribute is a string attribute of a span.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// Span is an operation of a trace.
+// The methods of a nil span do nothing, so that spans that are not sampled need no checks.
+type Span struct {
+	Name       string
+	Kind       Kind
+	Context    SpanContext
+	Parent     SpanID
+	Start      time.Time
+	End        time.Time
+	Attributes []Attribute
+	// Error of the operation, empty if it succeeded.
+	Error string
+
+	tracer *Tracer
+}
+
+// SpanContext returns the context of the span, or an invalid context for a nil span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.Context
+}
+
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.Attributes = append(s.Attributes, Attribute{Key: key, Value: value})
+}
+
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.Error = err.Error()
+}
+
+// Finish ends the span and exports it.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.End = time.Now()
+	s.tracer.exporter.Export(s)
+}
+
+// Exporter exports the finished spans.
+// Export must not block, since it is called from the goroutines of the traced operations.
+type Exporter interface {
+	Export(s *Span)
+}
+
+// Tracer starts spans, sampling new traces.
+type Tracer struct {
+	sampleRate float64
+	exporter   Exporter
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewTracer returns a tracer sampling the rate of new traces, between 0 and 1,
+// and exporting their spans to the exporter.
+func NewTracer(sampleRate float64, e Exporter) *Tracer {
+	return &Tracer{
+		sampleRate: sampleRate,
+		exporter:   e,
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Start starts a span, child of the parent span if it is valid,
+// otherwise a new trace if it is sampled.
+// It returns nil if the span is not sampled.
+func (t *Tracer) Start(name string, kind Kind, parent SpanContext) *Span {
+	s := &Span{
+		Name:   name,
+		Kind:   kind,
+		Start:  time.Now(),
+		tracer: t,
+	}
+	t.mu.Lock()
+	if parent.IsValid() {
+		s.Context.TraceID = parent.TraceID
+		s.Parent = parent.SpanID
+	} else {
+		if t.rand.Float64() >= t.sampleRate {
+			t.mu.Unlock()
+			return nil
+		}
+		t.rand.Read(s.Context.TraceID[:])
+	}
+	t.rand.Read(s.Context.SpanID[:])
+	t.mu.Unlock()
+	return s
+}
+
+var tracer atomic.Value // *Tracer
+
+// SetTracer sets the tracer recording spans, or stops recording spans if it is nil.
+func SetTracer(t *Tracer) {
+	tracer.Store(t)
+}
+
+// Start starts a span with the tracer set with SetTracer.
+// It returns nil if there is no tracer or the span is not sampled.
+func Start(name string, kind Kind, parent SpanContext) *Span {
+	t, _ := tracer.Load().(*Tracer)
+	if t == nil {
+		return nil
+	}
+	return t.Start(name, kind, parent)
+}
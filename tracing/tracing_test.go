@@ -0,0 +1,124 @@
+package tracing_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/kapacitor/tracing"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	spans []*tracing.Span
+}
+
+func (r *recorder) Export(s *tracing.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+func TestTracer_Start(t *testing.T) {
+	r := new(recorder)
+	tracer := tracing.NewTracer(1, r)
+
+	root := tracer.Start("ingest", tracing.KindConsumer, tracing.SpanContext{})
+	if root == nil {
+		t.Fatal("expected root span to be sampled")
+	}
+	if !root.Context.IsValid() {
+		t.Fatal("expected valid span context")
+	}
+	child := tracer.Start("eval2", tracing.KindInternal, root.Context)
+	child.SetAttribute("kapacitor.node", "eval2")
+	child.SetError(errors.New("failed"))
+	child.Finish()
+	root.Finish()
+
+	if child.Context.TraceID != root.Context.TraceID {
+		t.Error("expected child span in the trace of its parent")
+	}
+	if child.Parent != root.Context.SpanID {
+		t.Error("unexpected parent of child span")
+	}
+	if child.Context.SpanID == root.Context.SpanID {
+		t.Error("expected a new span ID for the child span")
+	}
+	if len(r.spans) != 2 || r.spans[0] != child || r.spans[1] != root {
+		t.Fatalf("unexpected exported spans %v", r.spans)
+	}
+	if got, exp := child.Attributes, []tracing.Attribute{{Key: "kapacitor.node", Value: "eval2"}}; len(got) != 1 || got[0] != exp[0] {
+		t.Errorf("unexpected attributes got %v exp %v", got, exp)
+	}
+	if child.Error != "failed" {
+		t.Errorf("unexpected error %q", child.Error)
+	}
+	if child.End.Before(child.Start) {
+		t.Error("expected span to end after its start")
+	}
+}
+
+func TestTracer_Start_NotSampled(t *testing.T) {
+	r := new(recorder)
+	tracer := tracing.NewTracer(0, r)
+	if s := tracer.Start("ingest", tracing.KindConsumer, tracing.SpanContext{}); s != nil {
+		t.Fatal("expected trace not to be sampled")
+	}
+	// The methods of spans that are not sampled do nothing.
+	var s *tracing.Span
+	s.SetAttribute("key", "value")
+	s.SetError(errors.New("failed"))
+	s.Finish()
+	if s.SpanContext().IsValid() {
+		t.Error("expected invalid context of a nil span")
+	}
+
+	// The children of sampled spans are always sampled.
+	parent := tracing.NewTracer(1, r).Start("ingest", tracing.KindConsumer, tracing.SpanContext{})
+	if s := tracer.Start("eval2", tracing.KindInternal, parent.Context); s == nil {
+		t.Error("expected the child of a sampled span to be sampled")
+	}
+}
+
+func TestStart_NoTracer(t *testing.T) {
+	tracing.SetTracer(nil)
+	if s := tracing.Start("ingest", tracing.KindConsumer, tracing.SpanContext{}); s != nil {
+		t.Error("expected no span without a tracer")
+	}
+	r := new(recorder)
+	tracing.SetTracer(tracing.NewTracer(1, r))
+	defer tracing.SetTracer(nil)
+	tracing.Start("ingest", tracing.KindConsumer, tracing.SpanContext{}).Finish()
+	if len(r.spans) != 1 {
+		t.Errorf("unexpected exported spans %v", r.spans)
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	s := tracing.NewTracer(1, new(recorder)).Start("ingest", tracing.KindConsumer, tracing.SpanContext{})
+	header := s.Context.Traceparent()
+	if got, exp := len(header), 55; got != exp {
+		t.Fatalf("unexpected length of traceparent %q got %d exp %d", header, got, exp)
+	}
+	c, ok := tracing.ParseTraceparent(header)
+	if !ok {
+		t.Fatalf("failed to parse traceparent %q", header)
+	}
+	if c != s.Context {
+		t.Errorf("unexpected context got %v exp %v", c, s.Context)
+	}
+
+	for _, header := range []string{
+		"",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00",
+		"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b71692033-01",
+		"ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319x-b7ad6b7169203331-01",
+	} {
+		if _, ok := tracing.ParseTraceparent(header); ok {
+			t.Errorf("expected invalid or unsampled traceparent %q", header)
+		}
+	}
+}
@@ -11,48 +11,108 @@ import (
 	"github.com/cenkalti/backoff"
 	"github.com/influxdata/kapacitor/command"
 	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/expvar"
 	"github.com/influxdata/kapacitor/pipeline"
 	"github.com/influxdata/kapacitor/udf"
 	"github.com/influxdata/kapacitor/udf/agent"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	statsUDFRestarts = "restarts"
 )
 
 // User defined function
 type UDFNode struct {
 	node
-	u       *pipeline.UDFNode
+	u      *pipeline.UDFNode
+	policy udf.RestartPolicy
+
+	mu       sync.Mutex
+	run      *udfRun
+	stopped  bool
+	stopping chan struct{}
+
+	// The message read from the input that the failed UDF did not receive.
+	pending edge.Message
+	// Whether the failed UDF received the beginning of a batch but not its end.
+	inBatch   bool
+	skipBatch bool
+
+	snapshotMu   sync.Mutex
+	lastSnapshot []byte
+
+	restarts *expvar.Int
+}
+
+// udfRun is a run of the UDF between restarts.
+type udfRun struct {
 	udf     udf.Interface
 	aborted chan struct{}
-
-	wg      sync.WaitGroup
-	mu      sync.Mutex
-	stopped bool
+	// Closed when the UDF is upgraded.
+	upgraded <-chan struct{}
+	// Whether the UDF is open and may be aborted.
+	opened bool
+	// Whether the UDF is initialized and may be snapshotted.
+	ready bool
+	// Group for waiting on the writes to the UDF.
+	wg sync.WaitGroup
 }
 
+// writeResult is the reason the writes to a UDF stopped.
+type writeResult int
+
+const (
+	writeInputDone writeResult = iota
+	writeAborted
+	writeUpgraded
+)
+
 // Create a new UDFNode that sends incoming data to child udf
 func newUDFNode(et *ExecutingTask, n *pipeline.UDFNode, d NodeDiagnostic) (*UDFNode, error) {
 	un := &UDFNode{
-		node:    node{Node: n, et: et, diag: d},
-		u:       n,
-		aborted: make(chan struct{}),
+		node:     node{Node: n, et: et, diag: d},
+		u:        n,
+		policy:   et.tm.UDFService.RestartPolicy(n.UDFName),
+		stopping: make(chan struct{}),
 	}
 	// Create the UDF
-	f, err := et.tm.UDFService.Create(
-		n.UDFName,
-		et.Task.ID,
-		n.Name(),
-		d,
-		un.abortedCallback,
-	)
+	r, err := un.newRun()
 	if err != nil {
 		return nil, err
 	}
-	un.udf = f
+	un.run = r
 	un.node.runF = un.runUDF
 	un.node.stopF = un.stopUDF
 	return un, nil
 }
 
+func (n *UDFNode) newRun() (*udfRun, error) {
+	r := &udfRun{
+		aborted:  make(chan struct{}),
+		upgraded: n.et.tm.UDFService.Upgrades(n.u.UDFName),
+	}
+	f, err := n.et.tm.UDFService.Create(
+		n.u.UDFName,
+		n.et.Task.ID,
+		n.u.Name(),
+		n.diag,
+		func() {
+			close(r.aborted)
+			// wait till we are done writing
+			r.wg.Wait()
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	r.udf = f
+	return r, nil
+}
+
 var errNodeAborted = errors.New("node aborted")
 
 func (n *UDFNode) stopUDF() {
@@ -60,13 +120,18 @@ func (n *UDFNode) stopUDF() {
 	defer n.mu.Unlock()
 	if !n.stopped {
 		n.stopped = true
-		if n.udf != nil {
-			n.udf.Abort(errNodeAborted)
+		close(n.stopping)
+		// A UDF that is not open yet is aborted once it is.
+		if n.run != nil && n.run.opened {
+			n.run.udf.Abort(errNodeAborted)
 		}
 	}
 }
 
 func (n *UDFNode) runUDF(snapshot []byte) (err error) {
+	n.restarts = &expvar.Int{}
+	n.statMap.Set(statsUDFRestarts, n.restarts)
+
 	defer func() {
 		n.mu.Lock()
 		defer n.mu.Unlock()
@@ -77,21 +142,100 @@ func (n *UDFNode) runUDF(snapshot []byte) (err error) {
 		n.stopped = true
 	}()
 
-	if err := n.udf.Open(); err != nil {
-		return err
+	n.setLastSnapshot(snapshot)
+	if n.policy.SnapshotInterval > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go n.runSnapshots(done)
 	}
-	if err := n.udf.Init(n.u.Options); err != nil {
-		return err
+
+	// Read the input in a goroutine so that writing to the UDF can stop without a message.
+	msgs := make(chan edge.Message)
+	readDone := make(chan struct{})
+	defer close(readDone)
+	go func() {
+		defer close(msgs)
+		for m, ok := n.ins[0].Emit(); ok; m, ok = n.ins[0].Emit() {
+			select {
+			case msgs <- m:
+			case <-readDone:
+				return
+			}
+		}
+	}()
+
+	r := n.run
+	attempts := 0
+	for {
+		res, err := n.runOnce(r, n.getLastSnapshot(), msgs)
+		if res != writeUpgraded || err != nil {
+			if err == nil || res == writeInputDone || attempts >= n.policy.Attempts {
+				return err
+			}
+			n.mu.Lock()
+			stopped := n.stopped
+			n.mu.Unlock()
+			if stopped {
+				return err
+			}
+
+			attempts++
+			n.diag.Error("UDF failed, restarting", err)
+			n.restarts.Add(1)
+			select {
+			case <-time.After(n.policy.Delay):
+			case <-n.stopping:
+				return err
+			}
+		}
+		r, err = n.newRun()
+		if err != nil {
+			return err
+		}
+		n.mu.Lock()
+		if n.stopped {
+			n.mu.Unlock()
+			return errNodeAborted
+		}
+		n.run = r
+		// Drop the rest of a batch the failed UDF received in part.
+		n.skipBatch = n.inBatch
+		n.mu.Unlock()
+	}
+}
+
+// runOnce runs the UDF until the input is closed, the UDF fails or it is upgraded.
+// An upgraded UDF is snapshotted and closed cleanly so that the next run continues where it stopped.
+func (n *UDFNode) runOnce(r *udfRun, snapshot []byte, msgs <-chan edge.Message) (res writeResult, err error) {
+	if err := r.udf.Open(); err != nil {
+		return writeAborted, err
+	}
+	n.mu.Lock()
+	r.opened = true
+	stopped := n.stopped
+	n.mu.Unlock()
+	if stopped {
+		r.udf.Abort(errNodeAborted)
+		r.udf.Close()
+		return writeAborted, errNodeAborted
+	}
+	if err := r.udf.Init(n.u.Options); err != nil {
+		r.udf.Abort(err)
+		return writeAborted, err
 	}
 	if snapshot != nil {
-		if err := n.udf.Restore(snapshot); err != nil {
-			return err
+		if err := r.udf.Restore(snapshot); err != nil {
+			r.udf.Abort(err)
+			return writeAborted, err
 		}
 	}
+	n.mu.Lock()
+	r.ready = true
+	n.mu.Unlock()
 
 	forwardErr := make(chan error, 1)
 	go func() {
-		out := n.udf.Out()
+		out := r.udf.Out()
 		for m := range out {
 			if err := edge.Forward(n.outs, m); err != nil {
 				forwardErr <- err
@@ -103,41 +247,134 @@ func (n *UDFNode) runUDF(snapshot []byte) (err error) {
 
 	// The abort callback needs to know when we are done writing
 	// so we wrap in a wait group.
-	n.wg.Add(1)
+	r.wg.Add(1)
 	go func() {
-		defer n.wg.Done()
-		in := n.udf.In()
-		for m, ok := n.ins[0].Emit(); ok; m, ok = n.ins[0].Emit() {
-			n.timer.Start()
-			select {
-			case in <- m:
-			case <-n.aborted:
-				return
-			}
-			n.timer.Stop()
-		}
+		defer r.wg.Done()
+		res = n.write(r, msgs)
 	}()
 
 	// wait till we are done writing
-	n.wg.Wait()
+	r.wg.Wait()
+
+	if res == writeUpgraded {
+		// The snapshot is taken after the UDF processed all data written to it.
+		if snapshot, err := r.udf.Snapshot(); err != nil {
+			n.diag.Error("failed to snapshot UDF before upgrading it, using the last snapshot", err)
+		} else {
+			n.setLastSnapshot(snapshot)
+		}
+	}
 
 	// Close the udf
-	if err := n.udf.Close(); err != nil {
-		return err
+	if err := r.udf.Close(); err != nil {
+		return res, err
 	}
 
 	// Wait/Return any error from the forwarding goroutine
-	return <-forwardErr
+	return res, <-forwardErr
 }
 
-func (n *UDFNode) abortedCallback() {
-	close(n.aborted)
-	// wait till we are done writing
-	n.wg.Wait()
+// write writes the input to the UDF until the input is closed, the UDF is aborted
+// or the UDF is upgraded outside of a batch.
+func (n *UDFNode) write(r *udfRun, msgs <-chan edge.Message) writeResult {
+	in := r.udf.In()
+	for {
+		m := n.pending
+		n.pending = nil
+		if m == nil {
+			upgraded := r.upgraded
+			if n.inBatch {
+				// Wait for the end of the batch
+				upgraded = nil
+			}
+			var ok bool
+			select {
+			case m, ok = <-msgs:
+				if !ok {
+					return writeInputDone
+				}
+			case <-upgraded:
+				return writeUpgraded
+			case <-r.aborted:
+				return writeAborted
+			}
+		}
+		if n.skipBatch {
+			switch m.Type() {
+			case edge.BeginBatch, edge.BufferedBatch:
+				n.skipBatch = false
+			case edge.BatchPoint, edge.EndBatch:
+				continue
+			}
+		}
+		n.timer.Start()
+		select {
+		case in <- m:
+		case <-r.aborted:
+			n.timer.Stop()
+			n.pending = m
+			return writeAborted
+		}
+		n.timer.Stop()
+		switch m.Type() {
+		case edge.BeginBatch:
+			n.inBatch = true
+		case edge.EndBatch:
+			n.inBatch = false
+		}
+	}
+}
+
+// runSnapshots periodically snapshots the UDF so a restarted UDF does not lose its state.
+func (n *UDFNode) runSnapshots(done <-chan struct{}) {
+	ticker := time.NewTicker(n.policy.SnapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := n.snapshot(); err != nil {
+				n.diag.Error("failed to snapshot UDF", err)
+			}
+		case <-done:
+			return
+		}
+	}
 }
 
 func (n *UDFNode) snapshot() ([]byte, error) {
-	return n.udf.Snapshot()
+	n.mu.Lock()
+	r := n.run
+	ready := r.ready
+	stopped := n.stopped
+	n.mu.Unlock()
+	if !ready {
+		if n.policy.Attempts > 0 || stopped {
+			// The UDF is restarting or has stopped, keep the last snapshot.
+			return n.getLastSnapshot(), nil
+		}
+		return nil, errors.New("UDF is not initialized")
+	}
+	snapshot, err := r.udf.Snapshot()
+	if err != nil {
+		if n.policy.Attempts > 0 {
+			return n.getLastSnapshot(), nil
+		}
+		return nil, err
+	}
+	n.setLastSnapshot(snapshot)
+	return snapshot, nil
+}
+
+func (n *UDFNode) getLastSnapshot() []byte {
+	n.snapshotMu.Lock()
+	defer n.snapshotMu.Unlock()
+	return n.lastSnapshot
+}
+
+func (n *UDFNode) setLastSnapshot(snapshot []byte) {
+	n.snapshotMu.Lock()
+	defer n.snapshotMu.Unlock()
+	n.lastSnapshot = snapshot
 }
 
 // UDFProcess wraps an external process and sends and receives data
@@ -409,3 +646,118 @@ func (s *socket) In() io.WriteCloser {
 func (s *socket) Out() io.Reader {
 	return s.conn
 }
+
+type grpcSocket struct {
+	client agent.UDFClient
+	md     metadata.MD
+
+	cancel context.CancelFunc
+	in     *io.PipeWriter
+	out    *io.PipeReader
+	wg     sync.WaitGroup
+}
+
+// NewGRPCConn returns a Socket that exchanges the UDF messages over a call of the UDF gRPC service.
+// Calls share the connection, the task and node names are sent as the call metadata.
+func NewGRPCConn(conn *grpc.ClientConn, taskName, nodeName string) Socket {
+	return &grpcSocket{
+		client: agent.NewUDFClient(conn),
+		md:     metadata.Pairs("task", taskName, "node", nodeName),
+	}
+}
+
+func (s *grpcSocket) Open() error {
+	ctx, cancel := context.WithCancel(metadata.NewContext(context.Background(), s.md))
+	// Start the call, retrying like a socket connection while the agent is unavailable.
+	b := backoff.NewExponentialBackOff()
+	b.MaxElapsedTime = time.Minute * 5
+
+	var stream agent.UDF_ProcessClient
+	err := backoff.Retry(func() error {
+		st, err := s.client.Process(ctx)
+		if err != nil {
+			return err
+		}
+		stream = st
+		return nil
+	},
+		b,
+	)
+	if err != nil {
+		cancel()
+		return err
+	}
+	s.cancel = cancel
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	s.in = inW
+	s.out = outR
+
+	s.wg.Add(2)
+	go func() {
+		defer s.wg.Done()
+		// Unblock the UDF server if the stream failed.
+		inR.CloseWithError(sendRequests(stream, inR))
+	}()
+	go func() {
+		defer s.wg.Done()
+		outW.CloseWithError(recvResponses(stream, outW))
+	}()
+	return nil
+}
+
+// Close aborts the call if it has not finished and waits for the stream to close.
+func (s *grpcSocket) Close() error {
+	if s.cancel != nil {
+		s.cancel()
+		s.in.Close()
+		s.out.Close()
+		s.wg.Wait()
+	}
+	return nil
+}
+
+func (s *grpcSocket) In() io.WriteCloser {
+	return s.in
+}
+
+func (s *grpcSocket) Out() io.Reader {
+	return s.out
+}
+
+// sendRequests sends the requests read from r on the stream,
+// closing the sending side of the stream once r is closed.
+func sendRequests(stream agent.UDF_ProcessClient, r io.Reader) error {
+	in := bufio.NewReader(r)
+	var buf []byte
+	for {
+		req := new(agent.Request)
+		err := agent.ReadMessage(&buf, in, req)
+		if err == io.EOF {
+			return stream.CloseSend()
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(req); err != nil {
+			return err
+		}
+	}
+}
+
+// recvResponses writes the responses received on the stream to w until the stream is closed.
+func recvResponses(stream agent.UDF_ProcessClient, w io.Writer) error {
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := agent.WriteMessage(res, w); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"bufio"
+	"io"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// GRPCServer serves the UDF gRPC service.
+// Each call of Process is handled by a new Agent communicating over the call's stream,
+// so a single process can serve any number of tasks over one connection.
+//
+// Register the server on a grpc.Server with RegisterUDFServer.
+type GRPCServer struct {
+	newHandler func(a *Agent, md metadata.MD) Handler
+}
+
+// Create a new GRPCServer.
+// The newHandler function creates the Handler for the Agent of each call,
+// md contains the "task" and "node" IDs sent by Kapacitor.
+func NewGRPCServer(newHandler func(a *Agent, md metadata.MD) Handler) *GRPCServer {
+	return &GRPCServer{
+		newHandler: newHandler,
+	}
+}
+
+// Process runs an Agent for the call until Kapacitor closes its side of the stream
+// and the Handler has stopped.
+func (s *GRPCServer) Process(stream UDF_ProcessServer) error {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	a := New(inR, outW)
+	md, _ := metadata.FromContext(stream.Context())
+	a.Handler = s.newHandler(a, md)
+
+	go func() {
+		inW.CloseWithError(recvRequests(stream, inW))
+	}()
+	sendErrC := make(chan error, 1)
+	go func() {
+		err := sendResponses(stream, outR)
+		// Unblock the agent if the stream failed.
+		outR.CloseWithError(err)
+		sendErrC <- err
+	}()
+
+	if err := a.Start(); err != nil {
+		inR.Close()
+		outW.Close()
+		<-sendErrC
+		return err
+	}
+	err := a.Wait()
+	if sendErr := <-sendErrC; err == nil {
+		err = sendErr
+	}
+	return err
+}
+
+// recvRequests writes the requests received on the stream to w until the stream is closed.
+func recvRequests(stream UDF_ProcessServer, w io.Writer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := WriteMessage(req, w); err != nil {
+			return err
+		}
+	}
+}
+
+// sendResponses sends the responses read from r on the stream until r is closed.
+func sendResponses(stream UDF_ProcessServer, r io.Reader) error {
+	in := bufio.NewReader(r)
+	var buf []byte
+	for {
+		res := new(Response)
+		err := ReadMessage(&buf, in, res)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+	}
+}
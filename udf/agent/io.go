@@ -8,7 +8,7 @@ import (
 	"github.com/golang/protobuf/proto"
 )
 
-//go:generate protoc --go_out=./ --python_out=./py/kapacitor/udf/ udf.proto
+//go:generate protoc --go_out=plugins=grpc:./ --python_out=./py/kapacitor/udf/ udf.proto
 
 // Interface for reading messages
 // If you have an io.Reader
@@ -1,12 +1,13 @@
 # Kapacitor UDF Agent implementation in Python
 #
+# Supports Python 2.7 and Python 3.
+#
 # Requires protobuf v3
 #   pip install protobuf==3.0.0b2
 
 import sys
-import udf_pb2
+from kapacitor.udf import udf_pb2
 from threading import Lock, Thread
-from Queue import Queue
 import io
 import traceback
 import socket
@@ -15,6 +16,9 @@ import os
 import logging
 logger = logging.getLogger()
 
+# Version of the protocol implemented by the agent.
+PROTOCOL_VERSION = 1
+
 
 # The Agent calls the appropriate methods on the Handler as requests are read off STDIN.
 #
@@ -48,7 +52,12 @@ class Handler(object):
 #
 # The Agent requires a Handler object in order to fulfill requests.
 class Agent(object):
-    def __init__(self, _in=sys.stdin, out=sys.stdout,handler=None):
+    def __init__(self, _in=None, out=None, handler=None):
+        # Python 3 exposes the binary streams as the buffer of STDIN and STDOUT.
+        if _in is None:
+            _in = getattr(sys.stdin, 'buffer', sys.stdin)
+        if out is None:
+            out = getattr(sys.stdout, 'buffer', sys.stdout)
         self._in = _in
         self._out = out
         self._thread = None
@@ -152,10 +161,10 @@ def encodeUvarint(writer, value):
     bits = value & varintMask
     value >>= shiftSize
     while value:
-        writer.write(chr(varintMoreMask|bits))
+        writer.write(bytearray([varintMoreMask|bits]))
         bits = value & varintMask
         value >>= shiftSize
-    return writer.write(chr(bits))
+    return writer.write(bytearray([bits]))
 
 # Decode an unsigned varint, max of 32 bits
 def decodeUvarint32(reader):
@@ -186,7 +195,7 @@ class Server(object):
         try:
             while True:
                 conn, addr = self._listener.accept()
-                conn = conn.makefile()
+                conn = conn.makefile('rwb')
                 thread = Thread(target=self._accepter.accept, args=(conn,addr))
                 thread.start()
         except:
@@ -35,6 +35,11 @@ import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
 
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
 var _ = fmt.Errorf
@@ -1235,80 +1240,187 @@ func init() {
 	proto.RegisterEnum("agent.ValueType", ValueType_name, ValueType_value)
 }
 
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for UDF service
+
+type UDFClient interface {
+	Process(ctx context.Context, opts ...grpc.CallOption) (UDF_ProcessClient, error)
+}
+
+type uDFClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewUDFClient(cc *grpc.ClientConn) UDFClient {
+	return &uDFClient{cc}
+}
+
+func (c *uDFClient) Process(ctx context.Context, opts ...grpc.CallOption) (UDF_ProcessClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_UDF_serviceDesc.Streams[0], c.cc, "/agent.UDF/Process", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &uDFProcessClient{stream}
+	return x, nil
+}
+
+type UDF_ProcessClient interface {
+	Send(*Request) error
+	Recv() (*Response, error)
+	grpc.ClientStream
+}
+
+type uDFProcessClient struct {
+	grpc.ClientStream
+}
+
+func (x *uDFProcessClient) Send(m *Request) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *uDFProcessClient) Recv() (*Response, error) {
+	m := new(Response)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for UDF service
+
+type UDFServer interface {
+	Process(UDF_ProcessServer) error
+}
+
+func RegisterUDFServer(s *grpc.Server, srv UDFServer) {
+	s.RegisterService(&_UDF_serviceDesc, srv)
+}
+
+func _UDF_Process_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(UDFServer).Process(&uDFProcessServer{stream})
+}
+
+type UDF_ProcessServer interface {
+	Send(*Response) error
+	Recv() (*Request, error)
+	grpc.ServerStream
+}
+
+type uDFProcessServer struct {
+	grpc.ServerStream
+}
+
+func (x *uDFProcessServer) Send(m *Response) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *uDFProcessServer) Recv() (*Request, error) {
+	m := new(Request)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _UDF_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "agent.UDF",
+	HandlerType: (*UDFServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Process",
+			Handler:       _UDF_Process_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "udf.proto",
+}
+
 func init() { proto.RegisterFile("udf.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 1151 bytes of a gzipped FileDescriptorProto
+	// 1185 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xc4, 0x57, 0xdd, 0x72, 0xdb, 0xc4,
-	0x17, 0xaf, 0x22, 0xcb, 0x96, 0x8e, 0x9d, 0x58, 0xde, 0xe6, 0x9f, 0xea, 0x1f, 0x3a, 0x99, 0x20,
-	0x68, 0x9b, 0x84, 0x62, 0xc0, 0xc0, 0x50, 0x3a, 0x05, 0x26, 0xc6, 0x86, 0x78, 0x68, 0xe3, 0x8e,
-	0xe2, 0xf6, 0x5e, 0x8e, 0x36, 0xae, 0x26, 0x8e, 0x64, 0x24, 0x39, 0x60, 0xae, 0x78, 0x1c, 0x1e,
-	0x80, 0x87, 0xe0, 0x82, 0x27, 0x61, 0x86, 0x77, 0x60, 0xbf, 0xb4, 0x5a, 0xd9, 0x86, 0x4c, 0x99,
-	0xce, 0x70, 0xa7, 0x3d, 0xe7, 0x77, 0xbe, 0xcf, 0x9e, 0xb3, 0x02, 0x6b, 0x1e, 0x5c, 0xb4, 0x67,
-	0x49, 0x9c, 0xc5, 0xc8, 0xf0, 0x27, 0x38, 0xca, 0xdc, 0x4d, 0xa8, 0x0f, 0xa2, 0x8b, 0xd8, 0xc3,
-	0xdf, 0xcf, 0x71, 0x9a, 0xb9, 0x7f, 0x6a, 0xd0, 0xe0, 0xe7, 0x74, 0x16, 0x47, 0x29, 0x46, 0xf7,
-	0xc0, 0xf8, 0xc1, 0x8f, 0xb2, 0xd4, 0xd1, 0xf6, 0xb5, 0x83, 0xad, 0x4e, 0xb3, 0xcd, 0xc4, 0xda,
-	0xfd, 0x60, 0x82, 0x47, 0x8b, 0x19, 0xf6, 0x38, 0x17, 0xbd, 0x07, 0x26, 0x51, 0x7b, 0x1d, 0x06,
-	0x38, 0x75, 0x36, 0xd6, 0x23, 0x25, 0x00, 0x3d, 0x86, 0x5a, 0x3c, 0xcb, 0x42, 0xa2, 0xdf, 0xd1,
-	0xf7, 0xf5, 0x83, 0x7a, 0x67, 0x5f, 0x60, 0x55, 0xcb, 0xed, 0x21, 0x87, 0xf4, 0xa3, 0x2c, 0x59,
-	0x78, 0xb9, 0xc0, 0xee, 0x33, 0x68, 0xa8, 0x0c, 0x64, 0x83, 0x7e, 0x89, 0x17, 0xcc, 0x3b, 0xcb,
-	0xa3, 0x9f, 0xe8, 0x01, 0x18, 0xd7, 0xfe, 0x74, 0x8e, 0x99, 0x1f, 0xf5, 0x4e, 0x4b, 0xe8, 0xe6,
-	0x52, 0xcc, 0x02, 0xe7, 0x3f, 0xde, 0x78, 0xa4, 0xb9, 0x5f, 0x02, 0x14, 0x0c, 0xf4, 0x21, 0x00,
-	0x63, 0x51, 0x7f, 0x69, 0xc4, 0x3a, 0x89, 0xc3, 0x16, 0xf2, 0x2f, 0x73, 0x86, 0xa7, 0x60, 0xdc,
-	0x0b, 0x9a, 0xbe, 0x30, 0x13, 0xe9, 0x23, 0xb6, 0x65, 0x64, 0x1a, 0x8b, 0x6c, 0xb3, 0x64, 0x5d,
-	0x86, 0x81, 0x76, 0xa0, 0x9a, 0xf9, 0xe9, 0xe5, 0xa0, 0xc7, 0xbc, 0xb4, 0x3c, 0x71, 0xa2, 0xf4,
-	0x28, 0x0e, 0x30, 0xa1, 0xeb, 0x9c, 0xce, 0x4f, 0xee, 0x09, 0x54, 0xb9, 0x0a, 0x84, 0xa0, 0x12,
-	0xf9, 0x57, 0x58, 0x44, 0xcc, 0xbe, 0xd1, 0x11, 0x54, 0x99, 0x4f, 0x34, 0xf7, 0xd4, 0x2a, 0x2a,
-	0x59, 0x65, 0x9e, 0x7b, 0x02, 0xe1, 0xfe, 0xa1, 0x41, 0x5d, 0xa1, 0xa3, 0x77, 0xa1, 0x92, 0x91,
-	0x50, 0x44, 0x7d, 0x57, 0xa3, 0x65, 0x5c, 0xb4, 0x07, 0xd6, 0x38, 0x8e, 0xa7, 0x2f, 0x65, 0x62,
-	0xcd, 0x93, 0x5b, 0x5e, 0x41, 0x42, 0x77, 0xc1, 0x0c, 0xa3, 0x8c, 0xb3, 0xa9, 0xe7, 0x3a, 0x61,
-	0x4b, 0x0a, 0x72, 0xa1, 0x1e, 0xc4, 0xf3, 0xf1, 0x14, 0x73, 0x40, 0x85, 0x00, 0x34, 0x02, 0x50,
-	0x89, 0x14, 0x93, 0x66, 0x49, 0x18, 0x4d, 0x38, 0xc6, 0xa0, 0xe1, 0x51, 0x8c, 0x42, 0x44, 0xf7,
-	0x61, 0x33, 0x98, 0x27, 0xbe, 0x74, 0xde, 0xa9, 0x0a, 0x53, 0x65, 0x72, 0xb7, 0x26, 0x5a, 0x80,
-	0x94, 0xb7, 0xc1, 0xcb, 0x23, 0xba, 0xd9, 0x81, 0x5a, 0x3a, 0x3f, 0x3f, 0xc7, 0x29, 0xef, 0x67,
-	0xd3, 0xcb, 0x8f, 0x68, 0x1b, 0x0c, 0x9c, 0x24, 0x71, 0x22, 0xea, 0xc1, 0x0f, 0x6e, 0x0b, 0x9a,
-	0x67, 0x91, 0x3f, 0x4b, 0x5f, 0xc5, 0x79, 0x89, 0xdd, 0x36, 0xd8, 0x05, 0x49, 0xa8, 0xdd, 0x05,
-	0x33, 0x15, 0x34, 0xa6, 0xb7, 0xe1, 0xc9, 0xb3, 0xfb, 0x10, 0xb6, 0x08, 0x2e, 0x8b, 0x13, 0x9c,
-	0x37, 0xc9, 0x3f, 0xa1, 0x8f, 0xa1, 0x29, 0xd1, 0xff, 0xd2, 0xe7, 0xfb, 0x60, 0x7f, 0x87, 0xf1,
-	0xcc, 0x9f, 0x86, 0xd7, 0xd2, 0x24, 0x69, 0x9a, 0x2c, 0x14, 0x4d, 0xa3, 0x7b, 0xec, 0xdb, 0x7d,
-	0x00, 0x2d, 0x05, 0x27, 0x8c, 0xad, 0x03, 0xde, 0x83, 0xcd, 0x3e, 0xd5, 0x2c, 0x41, 0xd2, 0xae,
-	0xa6, 0xda, 0xfd, 0x5d, 0x03, 0xe8, 0xe2, 0x49, 0x18, 0x75, 0xfd, 0xec, 0xfc, 0xd5, 0xda, 0x3e,
-	0x25, 0x82, 0x93, 0x24, 0x9e, 0xcf, 0x72, 0x87, 0xd9, 0x01, 0x7d, 0x40, 0x6c, 0xfa, 0x93, 0x7c,
-	0x16, 0xbc, 0x25, 0x3a, 0xb0, 0x50, 0xd5, 0x1e, 0x11, 0x2e, 0x1f, 0x03, 0x0c, 0x48, 0x55, 0xa7,
-	0xe1, 0x4f, 0xbc, 0x8f, 0x88, 0x93, 0xf4, 0x9b, 0x5e, 0x9c, 0xf1, 0xe2, 0x94, 0x1a, 0x34, 0x58,
-	0x92, 0xc4, 0x69, 0xf7, 0x33, 0xb0, 0xa4, 0xf8, 0x9a, 0x61, 0xb1, 0xad, 0x0e, 0x0b, 0x4b, 0x9d,
-	0x0c, 0xbf, 0x54, 0xc1, 0x78, 0x1e, 0x93, 0x16, 0x5e, 0x97, 0x13, 0x19, 0xdd, 0x86, 0x12, 0x1d,
-	0xa9, 0x6b, 0xe0, 0x67, 0xfe, 0xd8, 0x4f, 0xb1, 0xb8, 0xbd, 0xf2, 0x8c, 0x0e, 0xa0, 0x99, 0xe0,
-	0x8c, 0xc4, 0x45, 0x7a, 0xf4, 0x79, 0x3c, 0x0d, 0xcf, 0x17, 0xcc, 0x7b, 0xcb, 0x5b, 0x26, 0x17,
-	0x39, 0x32, 0xd4, 0x1c, 0xed, 0x01, 0x04, 0xc4, 0x6e, 0x94, 0xb2, 0xd9, 0x52, 0x25, 0x99, 0xb2,
-	0x3c, 0x85, 0x42, 0x26, 0x00, 0xcf, 0x61, 0x8d, 0xe5, 0x70, 0x47, 0xe4, 0x90, 0xf9, 0xbf, 0x92,
-	0xbe, 0x2e, 0x34, 0x2e, 0x42, 0x3c, 0x0d, 0xd2, 0x1e, 0xbb, 0x7e, 0x8e, 0xc9, 0x64, 0xf6, 0x4a,
-	0x32, 0xdf, 0x28, 0x00, 0x2e, 0x5b, 0x92, 0x41, 0x9f, 0x83, 0xc5, 0xcf, 0x83, 0x28, 0x73, 0xac,
-	0x52, 0xe1, 0x54, 0x05, 0x84, 0xcb, 0xa5, 0x0b, 0x74, 0x61, 0xfe, 0x8c, 0xdd, 0x6c, 0x07, 0xfe,
-	0xd6, 0x3c, 0x07, 0x94, 0xcc, 0x73, 0x12, 0x7a, 0x02, 0xc0, 0xcf, 0x5d, 0x32, 0x81, 0x9c, 0x06,
-	0xd3, 0x70, 0x77, 0x8d, 0x06, 0xca, 0xe6, 0xf2, 0x0a, 0x5e, 0xe9, 0x95, 0xfa, 0x1b, 0xe9, 0x95,
-	0xdd, 0xaf, 0xa0, 0xb5, 0x92, 0xb0, 0x9b, 0x14, 0x68, 0xaa, 0x82, 0x27, 0xb0, 0x55, 0x4e, 0xd8,
-	0x4d, 0xd2, 0xfa, 0x5a, 0xf3, 0x4a, 0xc2, 0x5e, 0xcb, 0xff, 0x2f, 0xa0, 0xb9, 0x94, 0xaf, 0x9b,
-	0xc4, 0x4d, 0xf5, 0xaa, 0xfc, 0xa6, 0x81, 0xd9, 0x8f, 0x82, 0xd7, 0xbd, 0xf7, 0xf4, 0x5e, 0x5d,
-	0xf9, 0x3f, 0xf2, 0x7d, 0xe1, 0xb1, 0x6f, 0xf4, 0xbe, 0xe8, 0xe3, 0x0a, 0x2b, 0xe9, 0xff, 0xf3,
-	0x37, 0x84, 0x50, 0xbe, 0xd2, 0xca, 0x6f, 0xfc, 0xd6, 0xff, 0xac, 0x43, 0x2d, 0x1f, 0x9a, 0x07,
-	0x50, 0x09, 0xc9, 0xab, 0x80, 0x09, 0x16, 0x3b, 0x55, 0x79, 0x2d, 0x91, 0xc5, 0xc3, 0x10, 0x1c,
-	0x19, 0x66, 0xe2, 0xc5, 0x51, 0x20, 0xe5, 0xc3, 0x80, 0x23, 0xc3, 0x0c, 0x11, 0xc7, 0x2e, 0xf3,
-	0xa1, 0xcb, 0x02, 0xaf, 0x77, 0xee, 0x08, 0xf8, 0xf2, 0xd0, 0xa6, 0x0b, 0x56, 0x62, 0xd1, 0x27,
-	0xca, 0xd2, 0xa8, 0x30, 0xb9, 0xfc, 0x92, 0x2f, 0x2d, 0x28, 0xba, 0x78, 0x73, 0x24, 0xfa, 0x08,
-	0x6a, 0x09, 0x5f, 0x27, 0x2c, 0x41, 0xf5, 0xce, 0xff, 0x84, 0x50, 0x79, 0x25, 0x11, 0x99, 0x1c,
-	0x87, 0x0e, 0xc1, 0x18, 0xd3, 0xd1, 0xeb, 0xd8, 0xa5, 0xe7, 0x53, 0x31, 0x8e, 0x09, 0x98, 0x23,
-	0xc8, 0xd3, 0xc1, 0x98, 0xd1, 0xcb, 0xe6, 0xb4, 0x18, 0xb4, 0xa1, 0x5e, 0x40, 0x8a, 0x62, 0x4c,
-	0xf4, 0x0e, 0xe8, 0x38, 0x0a, 0x1c, 0xc4, 0x30, 0xcd, 0xa5, 0x8a, 0x12, 0x18, 0xe5, 0x76, 0x2d,
-	0xa8, 0x5d, 0x91, 0x95, 0x46, 0x98, 0xee, 0xaf, 0x3a, 0x98, 0x72, 0xd5, 0x1c, 0x96, 0x6a, 0x70,
-	0x7b, 0xcd, 0x3b, 0x51, 0x16, 0xe1, 0xb0, 0x54, 0x84, 0xdb, 0xa5, 0x22, 0xa8, 0x50, 0x52, 0x85,
-	0x47, 0xab, 0x55, 0x70, 0x56, 0xab, 0x20, 0x85, 0x94, 0x32, 0x7c, 0xba, 0x52, 0x86, 0x3b, 0x2b,
-	0x65, 0x90, 0x72, 0x45, 0x1d, 0x3a, 0xcb, 0x75, 0xd8, 0x59, 0xae, 0x83, 0x14, 0x92, 0x85, 0x78,
-	0x98, 0x6f, 0xd9, 0x2a, 0x93, 0xd8, 0xce, 0x33, 0xa7, 0xae, 0x62, 0x9a, 0x65, 0x06, 0xfa, 0xcf,
-	0xcb, 0x76, 0xf4, 0x36, 0x99, 0x01, 0xe2, 0xa9, 0x8f, 0x00, 0xaa, 0x67, 0x23, 0xaf, 0x7f, 0xfc,
-	0xcc, 0xbe, 0x85, 0x2c, 0x30, 0xba, 0xc7, 0xa3, 0xaf, 0x4f, 0x6c, 0xed, 0xa8, 0x07, 0x96, 0x7c,
-	0x57, 0x22, 0x13, 0x2a, 0xdd, 0xe1, 0xf0, 0x29, 0x41, 0xd4, 0x40, 0x1f, 0x9c, 0x8e, 0x6c, 0x8d,
-	0x8a, 0xf5, 0x86, 0x2f, 0xba, 0x4f, 0xfb, 0xf6, 0x86, 0x50, 0x31, 0x38, 0xfd, 0xd6, 0xd6, 0x51,
-	0x03, 0xcc, 0xde, 0x0b, 0xef, 0x78, 0x34, 0x18, 0x9e, 0xda, 0x95, 0x71, 0x95, 0xfd, 0xbf, 0x7c,
-	0xfc, 0x57, 0x00, 0x00, 0x00, 0xff, 0xff, 0x71, 0xb7, 0xac, 0x48, 0xcc, 0x0c, 0x00, 0x00,
+	0x17, 0xb7, 0x22, 0x7f, 0x48, 0xc7, 0x4e, 0xac, 0x6c, 0xf3, 0x6f, 0xf5, 0x37, 0x9d, 0x8c, 0x11,
+	0x6d, 0xe3, 0x84, 0x62, 0x8a, 0xa1, 0xd3, 0xd2, 0x29, 0x65, 0x62, 0xec, 0x62, 0x0f, 0x6d, 0x92,
+	0xd9, 0x38, 0xbd, 0x97, 0xa3, 0x8d, 0xab, 0x89, 0x23, 0x19, 0x69, 0x1d, 0x30, 0x57, 0x3c, 0x0e,
+	0x0f, 0xc0, 0x43, 0x70, 0xc1, 0x93, 0x30, 0xc3, 0x3b, 0x30, 0xfb, 0x21, 0x69, 0x65, 0x1b, 0x32,
+	0x65, 0x3a, 0xc3, 0x9d, 0xf6, 0x9c, 0xdf, 0xf9, 0xd8, 0xf3, 0xb9, 0x02, 0x73, 0xee, 0x5d, 0xb4,
+	0x67, 0x51, 0x48, 0x43, 0x54, 0x72, 0x27, 0x24, 0xa0, 0xce, 0x26, 0x54, 0x87, 0xc1, 0x45, 0x88,
+	0xc9, 0xf7, 0x73, 0x12, 0x53, 0xe7, 0x4f, 0x0d, 0x6a, 0xe2, 0x1c, 0xcf, 0xc2, 0x20, 0x26, 0xe8,
+	0x3e, 0x94, 0x7e, 0x70, 0x03, 0x1a, 0xdb, 0x5a, 0x53, 0x6b, 0x6d, 0x75, 0xea, 0x6d, 0x2e, 0xd6,
+	0xee, 0x7b, 0x13, 0x32, 0x5a, 0xcc, 0x08, 0x16, 0x5c, 0xf4, 0x31, 0x18, 0xb3, 0x28, 0xbc, 0xf6,
+	0x3d, 0x12, 0xdb, 0x1b, 0xeb, 0x91, 0x29, 0x00, 0x3d, 0x83, 0x4a, 0x38, 0xa3, 0x7e, 0x18, 0xc4,
+	0xb6, 0xde, 0xd4, 0x5b, 0xd5, 0x4e, 0x53, 0x62, 0x55, 0xcb, 0xed, 0x63, 0x01, 0xe9, 0x07, 0x34,
+	0x5a, 0xe0, 0x44, 0xa0, 0xf1, 0x1a, 0x6a, 0x2a, 0x03, 0x59, 0xa0, 0x5f, 0x92, 0x05, 0xf7, 0xce,
+	0xc4, 0xec, 0x13, 0xed, 0x41, 0xe9, 0xda, 0x9d, 0xce, 0x09, 0xf7, 0xa3, 0xda, 0xd9, 0x96, 0xba,
+	0x85, 0x14, 0xb7, 0x20, 0xf8, 0xcf, 0x36, 0x9e, 0x6a, 0xce, 0x0b, 0x80, 0x8c, 0x81, 0x1e, 0x01,
+	0x70, 0x16, 0xf3, 0x97, 0xdd, 0x58, 0x6f, 0x6d, 0x75, 0x2c, 0x29, 0xff, 0x26, 0x61, 0x60, 0x05,
+	0xe3, 0x5c, 0xb0, 0xf0, 0xf9, 0x54, 0x86, 0x0f, 0xed, 0x65, 0x37, 0xd3, 0xf8, 0xcd, 0x36, 0x73,
+	0xd6, 0xd3, 0x6b, 0xa0, 0xdb, 0x50, 0xa6, 0x6e, 0x7c, 0x39, 0xec, 0x71, 0x2f, 0x4d, 0x2c, 0x4f,
+	0x8c, 0x1e, 0x84, 0x1e, 0x19, 0xf6, 0x6c, 0x5d, 0xd0, 0xc5, 0xc9, 0x19, 0x40, 0x59, 0xa8, 0x40,
+	0x08, 0x8a, 0x81, 0x7b, 0x45, 0xe4, 0x8d, 0xf9, 0x37, 0x3a, 0x80, 0x32, 0xf7, 0x89, 0xc5, 0x9e,
+	0x59, 0x45, 0x39, 0xab, 0xdc, 0x73, 0x2c, 0x11, 0xce, 0x1f, 0x1a, 0x54, 0x15, 0x3a, 0xba, 0x07,
+	0x45, 0xba, 0x98, 0x11, 0x99, 0xdf, 0xd5, 0xdb, 0x72, 0x2e, 0xda, 0x05, 0x73, 0x1c, 0x86, 0xd3,
+	0x37, 0x69, 0x60, 0x8d, 0x41, 0x01, 0x67, 0x24, 0x74, 0x17, 0x0c, 0x3f, 0xa0, 0x82, 0xcd, 0x3c,
+	0xd7, 0x07, 0x05, 0x9c, 0x52, 0x90, 0x03, 0x55, 0x2f, 0x9c, 0x8f, 0xa7, 0x44, 0x00, 0x8a, 0x4d,
+	0xad, 0xa5, 0x0d, 0x0a, 0x58, 0x25, 0x32, 0x4c, 0x4c, 0x23, 0x3f, 0x98, 0x08, 0x4c, 0x89, 0x5d,
+	0x8f, 0x61, 0x14, 0x22, 0x7a, 0x00, 0x9b, 0xde, 0x3c, 0x72, 0x53, 0xe7, 0xed, 0xb2, 0x34, 0x95,
+	0x27, 0x77, 0x2b, 0xb2, 0x04, 0x9c, 0x17, 0x50, 0x13, 0xe9, 0x91, 0xd5, 0x6c, 0x43, 0x25, 0x9e,
+	0x9f, 0x9f, 0x93, 0x58, 0xd4, 0xb3, 0x81, 0x93, 0x23, 0xda, 0x81, 0x12, 0x89, 0xa2, 0x30, 0x92,
+	0xf9, 0x10, 0x07, 0x67, 0x1b, 0xea, 0xa7, 0x81, 0x3b, 0x8b, 0xdf, 0x86, 0x49, 0x8a, 0x9d, 0x36,
+	0x58, 0x19, 0x49, 0xaa, 0x6d, 0x80, 0x11, 0x4b, 0x1a, 0xd7, 0x5b, 0xc3, 0xe9, 0xd9, 0x79, 0x08,
+	0x5b, 0x98, 0xc4, 0x34, 0x8c, 0x48, 0x52, 0x24, 0xff, 0x84, 0x3e, 0x84, 0x7a, 0x8a, 0xfe, 0x97,
+	0x3e, 0x3f, 0x00, 0xeb, 0x3b, 0x42, 0x66, 0xee, 0xd4, 0xbf, 0x4e, 0x4d, 0x22, 0x28, 0x52, 0x5f,
+	0x16, 0x8d, 0x8e, 0xf9, 0xb7, 0xb3, 0x07, 0xdb, 0x0a, 0x4e, 0x1a, 0x5b, 0x07, 0xbc, 0x0f, 0x9b,
+	0x7d, 0xa6, 0x39, 0x05, 0xa5, 0x76, 0x35, 0xd5, 0xee, 0xef, 0x1a, 0x40, 0x97, 0x4c, 0xfc, 0xa0,
+	0xeb, 0xd2, 0xf3, 0xb7, 0x6b, 0xeb, 0x74, 0x07, 0x4a, 0x93, 0x28, 0x9c, 0xcf, 0x12, 0x87, 0xf9,
+	0x01, 0x7d, 0x0a, 0x45, 0xea, 0x4e, 0x92, 0x59, 0xf0, 0x81, 0xac, 0xc0, 0x4c, 0x55, 0x7b, 0xe4,
+	0x4e, 0xe4, 0x18, 0xe0, 0x40, 0xa6, 0x3a, 0xf6, 0x7f, 0x12, 0x75, 0xa4, 0x63, 0xfe, 0xcd, 0x1a,
+	0x67, 0xbc, 0x38, 0x72, 0xaf, 0x44, 0xe5, 0x18, 0x58, 0x9e, 0x1a, 0x4f, 0xc0, 0x4c, 0xc5, 0xd7,
+	0x0c, 0x8b, 0x1d, 0x75, 0x58, 0x98, 0xea, 0x64, 0xf8, 0xa5, 0x0c, 0xa5, 0x93, 0xd0, 0x0f, 0xd6,
+	0x06, 0x2f, 0xbd, 0xdd, 0x86, 0x72, 0xbb, 0x06, 0x18, 0x9e, 0x4b, 0xdd, 0xb1, 0x1b, 0x13, 0xd9,
+	0xbd, 0xe9, 0x19, 0xb5, 0xa0, 0x1e, 0x11, 0x4a, 0x02, 0x56, 0xa3, 0x27, 0xe1, 0xd4, 0x3f, 0x5f,
+	0x70, 0xef, 0x4d, 0xbc, 0x4c, 0xce, 0x62, 0x54, 0x52, 0x63, 0xb4, 0x0b, 0xe0, 0xf9, 0x57, 0x24,
+	0x88, 0xf9, 0x6c, 0x29, 0x37, 0xf5, 0x96, 0x89, 0x15, 0x0a, 0x3a, 0x90, 0x31, 0xac, 0xf0, 0x18,
+	0xde, 0x96, 0x31, 0xe4, 0xfe, 0xaf, 0x84, 0xaf, 0x0b, 0xb5, 0x0b, 0x9f, 0x4c, 0xbd, 0xb8, 0xc7,
+	0xdb, 0xcf, 0x36, 0xb8, 0xcc, 0x6e, 0x4e, 0xe6, 0xa5, 0x02, 0x10, 0xb2, 0x39, 0x19, 0xf4, 0x25,
+	0x98, 0xe2, 0x3c, 0x0c, 0xa8, 0x6d, 0xe6, 0x12, 0xa7, 0x2a, 0x18, 0x06, 0x54, 0x48, 0x67, 0xe8,
+	0xcc, 0xfc, 0x29, 0xef, 0x6c, 0x1b, 0xfe, 0xd6, 0xbc, 0x00, 0xe4, 0xcc, 0x0b, 0x12, 0x7a, 0x0e,
+	0x20, 0xce, 0xdd, 0x30, 0x9c, 0xda, 0x35, 0xae, 0xe1, 0xee, 0x1a, 0x0d, 0x8c, 0x2d, 0xe4, 0x15,
+	0xbc, 0x52, 0x2b, 0xd5, 0xf7, 0x52, 0x2b, 0x8d, 0xaf, 0x61, 0x7b, 0x25, 0x60, 0x37, 0x29, 0xd0,
+	0x54, 0x05, 0xcf, 0x61, 0x2b, 0x1f, 0xb0, 0x9b, 0xa4, 0xf5, 0xb5, 0xe6, 0x95, 0x80, 0xbd, 0x93,
+	0xff, 0x5f, 0x41, 0x7d, 0x29, 0x5e, 0x37, 0x89, 0x1b, 0x6a, 0xab, 0xfc, 0xa6, 0x81, 0xd1, 0x0f,
+	0xbc, 0x77, 0xed, 0x7b, 0xd6, 0x57, 0x57, 0xee, 0x8f, 0x62, 0x5f, 0x60, 0xfe, 0x8d, 0x3e, 0x91,
+	0x75, 0x5c, 0xe4, 0x29, 0xfd, 0x7f, 0xf2, 0x86, 0x90, 0xca, 0x57, 0x4a, 0xf9, 0xbd, 0x77, 0xfd,
+	0xcf, 0x3a, 0x54, 0x92, 0xa1, 0xd9, 0x82, 0xa2, 0x1f, 0x5c, 0x84, 0x5c, 0x30, 0xdb, 0xa9, 0xca,
+	0x6b, 0x69, 0x50, 0xc0, 0x1c, 0x21, 0x90, 0x3e, 0xb5, 0x37, 0x96, 0x90, 0x3e, 0xcd, 0x21, 0x7d,
+	0x8a, 0x9e, 0x80, 0x79, 0x99, 0x0c, 0x5d, 0x7e, 0xf1, 0x6a, 0xe7, 0x8e, 0x84, 0x2f, 0x0f, 0x6d,
+	0xb6, 0x60, 0x53, 0x2c, 0xfa, 0x42, 0x59, 0x1a, 0xc5, 0xa6, 0xa6, 0x34, 0xf9, 0xd2, 0x82, 0x62,
+	0x8b, 0x37, 0x41, 0xa2, 0xcf, 0xa0, 0x12, 0x89, 0x75, 0xc2, 0x03, 0x54, 0xed, 0xfc, 0x4f, 0x0a,
+	0xe5, 0x57, 0xd2, 0xa0, 0x80, 0x13, 0x1c, 0xda, 0x87, 0xd2, 0x98, 0x8d, 0x5e, 0xdb, 0xca, 0x3d,
+	0x9f, 0xb2, 0x71, 0x3c, 0x28, 0x60, 0x81, 0x40, 0xf7, 0xa0, 0x34, 0x63, 0xcd, 0x66, 0x6f, 0x73,
+	0x68, 0x4d, 0x6d, 0x40, 0x86, 0xe2, 0x4c, 0xf4, 0x11, 0xe8, 0x24, 0xf0, 0x6c, 0xc4, 0x31, 0xf5,
+	0xa5, 0x8c, 0x0e, 0x0a, 0x98, 0x71, 0xbb, 0x26, 0x54, 0xae, 0x48, 0x1c, 0xbb, 0x13, 0xe2, 0xfc,
+	0xaa, 0x83, 0x91, 0xae, 0x9a, 0xfd, 0x5c, 0x0e, 0x6e, 0xad, 0x79, 0x27, 0xa6, 0x49, 0xd8, 0xcf,
+	0x25, 0xe1, 0x56, 0x2e, 0x09, 0x2a, 0xd4, 0xa7, 0xe8, 0xe9, 0x6a, 0x16, 0xec, 0xd5, 0x2c, 0xa4,
+	0x42, 0x4a, 0x1a, 0x1e, 0xaf, 0xa4, 0xe1, 0xce, 0x4a, 0x1a, 0x52, 0xb9, 0x2c, 0x0f, 0x9d, 0xe5,
+	0x3c, 0xdc, 0x5e, 0xce, 0x43, 0x2a, 0x94, 0x26, 0xe2, 0x61, 0xb2, 0x65, 0xcb, 0x5c, 0x62, 0x27,
+	0x89, 0x9c, 0xba, 0x8a, 0x59, 0x94, 0x39, 0xe8, 0x3f, 0x4f, 0xdb, 0xc1, 0x87, 0x60, 0x24, 0x4f,
+	0x7d, 0x04, 0x50, 0x3e, 0x1d, 0xe1, 0xfe, 0xe1, 0x6b, 0xab, 0x80, 0x4c, 0x28, 0x75, 0x0f, 0x47,
+	0xdf, 0x0c, 0x2c, 0xed, 0xa0, 0x07, 0x66, 0xfa, 0xae, 0x44, 0x06, 0x14, 0xbb, 0xc7, 0xc7, 0xaf,
+	0xac, 0x02, 0xaa, 0x80, 0x3e, 0x3c, 0x1a, 0x59, 0x1a, 0x13, 0xeb, 0x1d, 0x9f, 0x75, 0x5f, 0xf5,
+	0xad, 0x0d, 0xa9, 0x62, 0x78, 0xf4, 0xad, 0xa5, 0xa3, 0x1a, 0x18, 0xbd, 0x33, 0x7c, 0x38, 0x1a,
+	0x1e, 0x1f, 0x59, 0xc5, 0xce, 0x63, 0xd0, 0xcf, 0x7a, 0x2f, 0x51, 0x1b, 0x2a, 0x27, 0x51, 0xc8,
+	0xdf, 0x41, 0x5b, 0x69, 0x30, 0x79, 0x35, 0x37, 0xea, 0x59, 0x70, 0x79, 0x94, 0x5a, 0xda, 0x23,
+	0x6d, 0x5c, 0xe6, 0xbf, 0x3d, 0x9f, 0xff, 0x35, 0x00, 0x38, 0xce, 0xbc, 0xba, 0x03, 0x0d, 0x00,
+	0x00,
 }
@@ -0,0 +1,12 @@
+// Code generated by gen.go. DO NOT EDIT.
+
+package python
+
+// Files of the bundled agent and runner by their path relative to the install directory.
+var files = map[string]string{
+	"kapacitor/__init__.py":     "",
+	"kapacitor/udf/__init__.py": "VERSION = \"\"\n",
+	"kapacitor/udf/agent.py":    "# Kapacitor UDF Agent implementation in Python\n#\n# Supports Python 2.7 and Python 3.\n#\n# Requires protobuf v3\n#   pip install protobuf==3.0.0b2\n\nimport sys\nfrom kapacitor.udf import udf_pb2\nfrom threading import Lock, Thread\nimport io\nimport traceback\nimport socket\nimport os\n\nimport logging\nlogger = logging.getLogger()\n\n# Version of the protocol implemented by the agent.\nPROTOCOL_VERSION = 1\n\n\n# The Agent calls the appropriate methods on the Handler as requests are read off STDIN.\n#\n# Throwing an exception will cause the Agent to stop and an ErrorResponse to be sent.\n# Some *Response objects (like SnapshotResponse) allow for returning their own error within the object itself.\n# These types of errors will not stop the Agent and Kapacitor will deal with them appropriately.\n#\n# The Handler is called from a single thread, meaning methods will not be called concurrently.\n#\n# To write Points/Batches back to the Agent/Kapacitor use the Agent.write_response method, which is thread safe.\nclass Handler(object):\n    def info(self):\n        pass\n    def init(self, init_req):\n        pass\n    def snapshot(self):\n        pass\n    def restore(self, restore_req):\n        pass\n    def begin_batch(self, begin_req):\n        pass\n    def point(self):\n        pass\n    def end_batch(self, end_req):\n        pass\n\n\n# Python implementation of a Kapacitor UDF agent.\n# This agent is responsible for reading and writing\n# messages over STDIN and STDOUT.\n#\n# The Agent requires a Handler object in order to fulfill requests.\nclass Agent(object):\n    def __init__(self, _in=None, out=None, handler=None):\n        # Python 3 exposes the binary streams as the buffer of STDIN and STDOUT.\n        if _in is None:\n            _in = getattr(sys.stdin, 'buffer', sys.stdin)\n        if out is None:\n            out = getattr(sys.stdout, 'buffer', sys.stdout)\n        self._in = _in\n        self._out = out\n        self._thread = None\n        self.handler = handler\n        self._write_lock = Lock()\n\n    # Start the agent.\n    # This method returns immediately\n    def start(self):\n        self._thread = Thread(target=self._read_loop)\n        self._thread.start()\n\n    # Wait for the Agent to terminate.\n    # The Agent will terminate if STDIN is closed or an error occurs\n    def wait(self):\n        self._thread.join()\n        self._in.close()\n        self._out.close()\n\n    # Write a response to STDOUT.\n    # This method is thread safe.\n    def write_response(self, response, flush=False):\n        if response is None:\n            raise Exception(\"cannot write None response\")\n\n        # Serialize message\n        self._write_lock.acquire()\n        try:\n            data = response.SerializeToString()\n            # Write message len\n            encodeUvarint(self._out, len(data))\n            # Write message\n            self._out.write(data)\n            if flush:\n                self._out.flush()\n        finally:\n            self._write_lock.release()\n\n    # Read requests off stdin\n    def _read_loop(self):\n        request = udf_pb2.Request()\n        while True:\n            msg = 'unknown'\n            try:\n                size = decodeUvarint32(self._in)\n                data = self._in.read(size)\n\n                request.ParseFromString(data)\n\n                # use parsed message\n                msg = request.WhichOneof(\"message\")\n                if msg == \"info\":\n                    response = self.handler.info()\n                    self.write_response(response, flush=True)\n                elif msg == \"init\":\n                    response = self.handler.init(request.init)\n                    self.write_response(response, flush=True)\n                elif msg == \"keepalive\":\n                    response = udf_pb2.Response()\n                    response.keepalive.time = request.keepalive.time\n                    self.write_response(response, flush=True)\n                elif msg == \"snapshot\":\n                    response = self.handler.snapshot()\n                    self.write_response(response, flush=True)\n                elif msg == \"restore\":\n                    response = self.handler.restore(request.restore)\n                    self.write_response(response, flush=True)\n                elif msg == \"begin\":\n                    self.handler.begin_batch(request.begin)\n                elif msg == \"point\":\n                    self.handler.point(request.point)\n                elif msg == \"end\":\n                    self.handler.end_batch(request.end)\n                else:\n                    logger.error(\"received unhandled request %s\", msg)\n            except EOF:\n                break\n            except Exception as e:\n                traceback.print_exc()\n                error = \"error processing request of type %s: %s\" % (msg, e)\n                logger.error(error)\n                response = udf_pb2.Response()\n                response.error.error = error\n                self.write_response(response)\n                break\n\n# Indicates the end of a file/stream has been reached.\nclass EOF(Exception):\n    pass\n\n# Varint encode decode values\nmask32uint = (1 << 32) - 1\nbyteSize = 8\nshiftSize = byteSize - 1\nvarintMoreMask = 2**shiftSize\nvarintMask = varintMoreMask - 1\n\n\n# Encode an unsigned varint\ndef encodeUvarint(writer, value):\n    bits = value & varintMask\n    value >>= shiftSize\n    while value:\n        writer.write(bytearray([varintMoreMask|bits]))\n        bits = value & varintMask\n        value >>= shiftSize\n    return writer.write(bytearray([bits]))\n\n# Decode an unsigned varint, max of 32 bits\ndef decodeUvarint32(reader):\n    result = 0\n    shift = 0\n    while True:\n        byte = reader.read(1)\n        if len(byte) == 0:\n            raise EOF\n        b = ord(byte)\n        result |= ((b & varintMask) << shift)\n        if not (b & varintMoreMask):\n            result &= mask32uint\n            return result\n        shift += shiftSize\n        if shift >= 32:\n            raise Exception(\"too many bytes when decoding varint, larger than 32bit uint\")\n\nclass Server(object):\n    def __init__(self, socket_path, accepter):\n        self._socket_path = socket_path\n        self._listener = socket.socket(socket.AF_UNIX, socket.SOCK_STREAM )\n        self._listener.bind(socket_path)\n        self._accepter = accepter\n\n    def serve(self):\n        self._listener.listen(5)\n        try:\n            while True:\n                conn, addr = self._listener.accept()\n                conn = conn.makefile('rwb')\n                thread = Thread(target=self._accepter.accept, args=(conn,addr))\n                thread.start()\n        except:\n            self.stop()\n\n    def stop(self):\n        self._listener.close()\n        try:\n            os.remove(self._socket_path)\n        except:\n            pass\n\n",
+	"kapacitor/udf/udf_pb2.py":  "# Generated by the protocol buffer compiler.  DO NOT EDIT!\n# source: udf.proto\n\nimport sys\n_b=sys.version_info[0]<3 and (lambda x:x) or (lambda x:x.encode('latin1'))\nfrom google.protobuf.internal import enum_type_wrapper\nfrom google.protobuf import descriptor as _descriptor\nfrom google.protobuf import message as _message\nfrom google.protobuf import reflection as _reflection\nfrom google.protobuf import symbol_database as _symbol_database\nfrom google.protobuf import descriptor_pb2\n# @@protoc_insertion_point(imports)\n\n_sym_db = _symbol_database.Default()\n\n\n\n\nDESCRIPTOR = _descriptor.FileDescriptor(\n  name='udf.proto',\n  package='agent',\n  syntax='proto3',\n  serialized_pb=_b('\\n\\tudf.proto\\x12\\x05\\x61gent\\\"\\r\\n\\x0bInfoRequest\\\"\\xc7\\x01\\n\\x0cInfoResponse\\x12\\x1e\\n\\x05wants\\x18\\x01 \\x01(\\x0e\\x32\\x0f.agent.EdgeType\\x12!\\n\\x08provides\\x18\\x02 \\x01(\\x0e\\x32\\x0f.agent.EdgeType\\x12\\x31\\n\\x07options\\x18\\x03 \\x03(\\x0b\\x32 .agent.InfoResponse.OptionsEntry\\x1a\\x41\\n\\x0cOptionsEntry\\x12\\x0b\\n\\x03key\\x18\\x01 \\x01(\\t\\x12 \\n\\x05value\\x18\\x02 \\x01(\\x0b\\x32\\x11.agent.OptionInfo:\\x02\\x38\\x01\\\"2\\n\\nOptionInfo\\x12$\\n\\nvalueTypes\\x18\\x01 \\x03(\\x0e\\x32\\x10.agent.ValueType\\\"M\\n\\x0bInitRequest\\x12\\x1e\\n\\x07options\\x18\\x01 \\x03(\\x0b\\x32\\r.agent.Option\\x12\\x0e\\n\\x06taskID\\x18\\x02 \\x01(\\t\\x12\\x0e\\n\\x06nodeID\\x18\\x03 \\x01(\\t\\\":\\n\\x06Option\\x12\\x0c\\n\\x04name\\x18\\x01 \\x01(\\t\\x12\\\"\\n\\x06values\\x18\\x02 \\x03(\\x0b\\x32\\x12.agent.OptionValue\\\"\\xa6\\x01\\n\\x0bOptionValue\\x12\\x1e\\n\\x04type\\x18\\x01 \\x01(\\x0e\\x32\\x10.agent.ValueType\\x12\\x13\\n\\tboolValue\\x18\\x02 \\x01(\\x08H\\x00\\x12\\x12\\n\\x08intValue\\x18\\x03 \\x01(\\x03H\\x00\\x12\\x15\\n\\x0b\\x64oubleValue\\x18\\x04 \\x01(\\x01H\\x00\\x12\\x15\\n\\x0bstringValue\\x18\\x05 \\x01(\\tH\\x00\\x12\\x17\\n\\rdurationValue\\x18\\x06 \\x01(\\x03H\\x00\\x42\\x07\\n\\x05value\\\".\\n\\x0cInitResponse\\x12\\x0f\\n\\x07success\\x18\\x01 \\x01(\\x08\\x12\\r\\n\\x05\\x65rror\\x18\\x02 \\x01(\\t\\\"\\x11\\n\\x0fSnapshotRequest\\\"$\\n\\x10SnapshotResponse\\x12\\x10\\n\\x08snapshot\\x18\\x01 \\x01(\\x0c\\\"\\\"\\n\\x0eRestoreRequest\\x12\\x10\\n\\x08snapshot\\x18\\x01 \\x01(\\x0c\\\"1\\n\\x0fRestoreResponse\\x12\\x0f\\n\\x07success\\x18\\x01 \\x01(\\x08\\x12\\r\\n\\x05\\x65rror\\x18\\x02 \\x01(\\t\\\" \\n\\x10KeepaliveRequest\\x12\\x0c\\n\\x04time\\x18\\x01 \\x01(\\x03\\\"!\\n\\x11KeepaliveResponse\\x12\\x0c\\n\\x04time\\x18\\x01 \\x01(\\x03\\\"\\x1e\\n\\rErrorResponse\\x12\\r\\n\\x05\\x65rror\\x18\\x01 \\x01(\\t\\\"\\x9f\\x01\\n\\nBeginBatch\\x12\\x0c\\n\\x04name\\x18\\x01 \\x01(\\t\\x12\\r\\n\\x05group\\x18\\x02 \\x01(\\t\\x12)\\n\\x04tags\\x18\\x03 \\x03(\\x0b\\x32\\x1b.agent.BeginBatch.TagsEntry\\x12\\x0c\\n\\x04size\\x18\\x04 \\x01(\\x03\\x12\\x0e\\n\\x06\\x62yName\\x18\\x05 \\x01(\\x08\\x1a+\\n\\tTagsEntry\\x12\\x0b\\n\\x03key\\x18\\x01 \\x01(\\t\\x12\\r\\n\\x05value\\x18\\x02 \\x01(\\t:\\x02\\x38\\x01\\\"\\xf1\\x04\\n\\x05Point\\x12\\x0c\\n\\x04time\\x18\\x01 \\x01(\\x03\\x12\\x0c\\n\\x04name\\x18\\x02 \\x01(\\t\\x12\\x10\\n\\x08\\x64\\x61tabase\\x18\\x03 \\x01(\\t\\x12\\x17\\n\\x0fretentionPolicy\\x18\\x04 \\x01(\\t\\x12\\r\\n\\x05group\\x18\\x05 \\x01(\\t\\x12\\x12\\n\\ndimensions\\x18\\x06 \\x03(\\t\\x12$\\n\\x04tags\\x18\\x07 \\x03(\\x0b\\x32\\x16.agent.Point.TagsEntry\\x12\\x34\\n\\x0c\\x66ieldsDouble\\x18\\x08 \\x03(\\x0b\\x32\\x1e.agent.Point.FieldsDoubleEntry\\x12.\\n\\tfieldsInt\\x18\\t \\x03(\\x0b\\x32\\x1b.agent.Point.FieldsIntEntry\\x12\\x34\\n\\x0c\\x66ieldsString\\x18\\n \\x03(\\x0b\\x32\\x1e.agent.Point.FieldsStringEntry\\x12\\x30\\n\\nfieldsBool\\x18\\x0c \\x03(\\x0b\\x32\\x1c.agent.Point.FieldsBoolEntry\\x12\\x0e\\n\\x06\\x62yName\\x18\\x0b \\x01(\\x08\\x1a+\\n\\tTagsEntry\\x12\\x0b\\n\\x03key\\x18\\x01 \\x01(\\t\\x12\\r\\n\\x05value\\x18\\x02 \\x01(\\t:\\x02\\x38\\x01\\x1a\\x33\\n\\x11\\x46ieldsDoubleEntry\\x12\\x0b\\n\\x03key\\x18\\x01 \\x01(\\t\\x12\\r\\n\\x05value\\x18\\x02 \\x01(\\x01:\\x02\\x38\\x01\\x1a\\x30\\n\\x0e\\x46ieldsIntEntry\\x12\\x0b\\n\\x03key\\x18\\x01 \\x01(\\t\\x12\\r\\n\\x05value\\x18\\x02 \\x01(\\x03:\\x02\\x38\\x01\\x1a\\x33\\n\\x11\\x46ieldsStringEntry\\x12\\x0b\\n\\x03key\\x18\\x01 \\x01(\\t\\x12\\r\\n\\x05value\\x18\\x02 \\x01(\\t:\\x02\\x38\\x01\\x1a\\x31\\n\\x0f\\x46ieldsBoolEntry\\x12\\x0b\\n\\x03key\\x18\\x01 \\x01(\\t\\x12\\r\\n\\x05value\\x18\\x02 \\x01(\\x08:\\x02\\x38\\x01\\\"\\x9b\\x01\\n\\x08\\x45ndBatch\\x12\\x0c\\n\\x04name\\x18\\x01 \\x01(\\t\\x12\\r\\n\\x05group\\x18\\x02 \\x01(\\t\\x12\\x0c\\n\\x04tmax\\x18\\x03 \\x01(\\x03\\x12\\'\\n\\x04tags\\x18\\x04 \\x03(\\x0b\\x32\\x19.agent.EndBatch.TagsEntry\\x12\\x0e\\n\\x06\\x62yName\\x18\\x05 \\x01(\\x08\\x1a+\\n\\tTagsEntry\\x12\\x0b\\n\\x03key\\x18\\x01 \\x01(\\t\\x12\\r\\n\\x05value\\x18\\x02 \\x01(\\t:\\x02\\x38\\x01\\\"\\xc3\\x02\\n\\x07Request\\x12\\\"\\n\\x04info\\x18\\x01 \\x01(\\x0b\\x32\\x12.agent.InfoRequestH\\x00\\x12\\\"\\n\\x04init\\x18\\x02 \\x01(\\x0b\\x32\\x12.agent.InitRequestH\\x00\\x12,\\n\\tkeepalive\\x18\\x03 \\x01(\\x0b\\x32\\x17.agent.KeepaliveRequestH\\x00\\x12*\\n\\x08snapshot\\x18\\x04 \\x01(\\x0b\\x32\\x16.agent.SnapshotRequestH\\x00\\x12(\\n\\x07restore\\x18\\x05 \\x01(\\x0b\\x32\\x15.agent.RestoreRequestH\\x00\\x12\\\"\\n\\x05\\x62\\x65gin\\x18\\x10 \\x01(\\x0b\\x32\\x11.agent.BeginBatchH\\x00\\x12\\x1d\\n\\x05point\\x18\\x11 \\x01(\\x0b\\x32\\x0c.agent.PointH\\x00\\x12\\x1e\\n\\x03\\x65nd\\x18\\x12 \\x01(\\x0b\\x32\\x0f.agent.EndBatchH\\x00\\x42\\t\\n\\x07message\\\"\\xf0\\x02\\n\\x08Response\\x12#\\n\\x04info\\x18\\x01 \\x01(\\x0b\\x32\\x13.agent.InfoResponseH\\x00\\x12#\\n\\x04init\\x18\\x02 \\x01(\\x0b\\x32\\x13.agent.InitResponseH\\x00\\x12-\\n\\tkeepalive\\x18\\x03 \\x01(\\x0b\\x32\\x18.agent.KeepaliveResponseH\\x00\\x12+\\n\\x08snapshot\\x18\\x04 \\x01(\\x0b\\x32\\x17.agent.SnapshotResponseH\\x00\\x12)\\n\\x07restore\\x18\\x05 \\x01(\\x0b\\x32\\x16.agent.RestoreResponseH\\x00\\x12%\\n\\x05\\x65rror\\x18\\x06 \\x01(\\x0b\\x32\\x14.agent.ErrorResponseH\\x00\\x12\\\"\\n\\x05\\x62\\x65gin\\x18\\x10 \\x01(\\x0b\\x32\\x11.agent.BeginBatchH\\x00\\x12\\x1d\\n\\x05point\\x18\\x11 \\x01(\\x0b\\x32\\x0c.agent.PointH\\x00\\x12\\x1e\\n\\x03\\x65nd\\x18\\x12 \\x01(\\x0b\\x32\\x0f.agent.EndBatchH\\x00\\x42\\t\\n\\x07message*!\\n\\x08\\x45\\x64geType\\x12\\n\\n\\x06STREAM\\x10\\x00\\x12\\t\\n\\x05\\x42\\x41TCH\\x10\\x01*D\\n\\tValueType\\x12\\x08\\n\\x04\\x42OOL\\x10\\x00\\x12\\x07\\n\\x03INT\\x10\\x01\\x12\\n\\n\\x06\\x44OUBLE\\x10\\x02\\x12\\n\\n\\x06STRING\\x10\\x03\\x12\\x0c\\n\\x08\\x44URATION\\x10\\x04\\x62\\x06proto3')\n)\n\n_EDGETYPE = _descriptor.EnumDescriptor(\n  name='EdgeType',\n  full_name='agent.EdgeType',\n  filename=None,\n  file=DESCRIPTOR,\n  values=[\n    _descriptor.EnumValueDescriptor(\n      name='STREAM', index=0, number=0,\n      options=None,\n      type=None),\n    _descriptor.EnumValueDescriptor(\n      name='BATCH', index=1, number=1,\n      options=None,\n      type=None),\n  ],\n  containing_type=None,\n  options=None,\n  serialized_start=2535,\n  serialized_end=2568,\n)\n_sym_db.RegisterEnumDescriptor(_EDGETYPE)\n\nEdgeType = enum_type_wrapper.EnumTypeWrapper(_EDGETYPE)\n_VALUETYPE = _descriptor.EnumDescriptor(\n  name='ValueType',\n  full_name='agent.ValueType',\n  filename=None,\n  file=DESCRIPTOR,\n  values=[\n    _descriptor.EnumValueDescriptor(\n      name='BOOL', index=0, number=0,\n      options=None,\n      type=None),\n    _descriptor.EnumValueDescriptor(\n      name='INT', index=1, number=1,\n      options=None,\n      type=None),\n    _descriptor.EnumValueDescriptor(\n      name='DOUBLE', index=2, number=2,\n      options=None,\n      type=None),\n    _descriptor.EnumValueDescriptor(\n      name='STRING', index=3, number=3,\n      options=None,\n      type=None),\n    _descriptor.EnumValueDescriptor(\n      name='DURATION', index=4, number=4,\n      options=None,\n      type=None),\n  ],\n  containing_type=None,\n  options=None,\n  serialized_start=2570,\n  serialized_end=2638,\n)\n_sym_db.RegisterEnumDescriptor(_VALUETYPE)\n\nValueType = enum_type_wrapper.EnumTypeWrapper(_VALUETYPE)\nSTREAM = 0\nBATCH = 1\nBOOL = 0\nINT = 1\nDOUBLE = 2\nSTRING = 3\nDURATION = 4\n\n\n\n_INFOREQUEST = _descriptor.Descriptor(\n  name='InfoRequest',\n  full_name='agent.InfoRequest',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=20,\n  serialized_end=33,\n)\n\n\n_INFORESPONSE_OPTIONSENTRY = _descriptor.Descriptor(\n  name='OptionsEntry',\n  full_name='agent.InfoResponse.OptionsEntry',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='key', full_name='agent.InfoResponse.OptionsEntry.key', index=0,\n      number=1, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='value', full_name='agent.InfoResponse.OptionsEntry.value', index=1,\n      number=2, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=_descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001')),\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=170,\n  serialized_end=235,\n)\n\n_INFORESPONSE = _descriptor.Descriptor(\n  name='InfoResponse',\n  full_name='agent.InfoResponse',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='wants', full_name='agent.InfoResponse.wants', index=0,\n      number=1, type=14, cpp_type=8, label=1,\n      has_default_value=False, default_value=0,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='provides', full_name='agent.InfoResponse.provides', index=1,\n      number=2, type=14, cpp_type=8, label=1,\n      has_default_value=False, default_value=0,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='options', full_name='agent.InfoResponse.options', index=2,\n      number=3, type=11, cpp_type=10, label=3,\n      has_default_value=False, default_value=[],\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[_INFORESPONSE_OPTIONSENTRY, ],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=36,\n  serialized_end=235,\n)\n\n\n_OPTIONINFO = _descriptor.Descriptor(\n  name='OptionInfo',\n  full_name='agent.OptionInfo',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='valueTypes', full_name='agent.OptionInfo.valueTypes', index=0,\n      number=1, type=14, cpp_type=8, label=3,\n      has_default_value=False, default_value=[],\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=237,\n  serialized_end=287,\n)\n\n\n_INITREQUEST = _descriptor.Descriptor(\n  name='InitRequest',\n  full_name='agent.InitRequest',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='options', full_name='agent.InitRequest.options', index=0,\n      number=1, type=11, cpp_type=10, label=3,\n      has_default_value=False, default_value=[],\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='taskID', full_name='agent.InitRequest.taskID', index=1,\n      number=2, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='nodeID', full_name='agent.InitRequest.nodeID', index=2,\n      number=3, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=289,\n  serialized_end=366,\n)\n\n\n_OPTION = _descriptor.Descriptor(\n  name='Option',\n  full_name='agent.Option',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='name', full_name='agent.Option.name', index=0,\n      number=1, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='values', full_name='agent.Option.values', index=1,\n      number=2, type=11, cpp_type=10, label=3,\n      has_default_value=False, default_value=[],\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=368,\n  serialized_end=426,\n)\n\n\n_OPTIONVALUE = _descriptor.Descriptor(\n  name='OptionValue',\n  full_name='agent.OptionValue',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='type', full_name='agent.OptionValue.type', index=0,\n      number=1, type=14, cpp_type=8, label=1,\n      has_default_value=False, default_value=0,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='boolValue', full_name='agent.OptionValue.boolValue', index=1,\n      number=2, type=8, cpp_type=7, label=1,\n      has_default_value=False, default_value=False,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='intValue', full_name='agent.OptionValue.intValue', index=2,\n      number=3, type=3, cpp_type=2, label=1,\n      has_default_value=False, default_value=0,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='doubleValue', full_name='agent.OptionValue.doubleValue', index=3,\n      number=4, type=1, cpp_type=5, label=1,\n      has_default_value=False, default_value=float(0),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='stringValue', full_name='agent.OptionValue.stringValue', index=4,\n      number=5, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='durationValue', full_name='agent.OptionValue.durationValue', index=5,\n      number=6, type=3, cpp_type=2, label=1,\n      has_default_value=False, default_value=0,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n    _descriptor.OneofDescriptor(\n      name='value', full_name='agent.OptionValue.value',\n      index=0, containing_type=None, fields=[]),\n  ],\n  serialized_start=429,\n  serialized_end=595,\n)\n\n\n_INITRESPONSE = _descriptor.Descriptor(\n  name='InitResponse',\n  full_name='agent.InitResponse',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='success', full_name='agent.InitResponse.success', index=0,\n      number=1, type=8, cpp_type=7, label=1,\n      has_default_value=False, default_value=False,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='error', full_name='agent.InitResponse.error', index=1,\n      number=2, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=597,\n  serialized_end=643,\n)\n\n\n_SNAPSHOTREQUEST = _descriptor.Descriptor(\n  name='SnapshotRequest',\n  full_name='agent.SnapshotRequest',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=645,\n  serialized_end=662,\n)\n\n\n_SNAPSHOTRESPONSE = _descriptor.Descriptor(\n  name='SnapshotResponse',\n  full_name='agent.SnapshotResponse',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='snapshot', full_name='agent.SnapshotResponse.snapshot', index=0,\n      number=1, type=12, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\"),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=664,\n  serialized_end=700,\n)\n\n\n_RESTOREREQUEST = _descriptor.Descriptor(\n  name='RestoreRequest',\n  full_name='agent.RestoreRequest',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='snapshot', full_name='agent.RestoreRequest.snapshot', index=0,\n      number=1, type=12, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\"),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=702,\n  serialized_end=736,\n)\n\n\n_RESTORERESPONSE = _descriptor.Descriptor(\n  name='RestoreResponse',\n  full_name='agent.RestoreResponse',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='success', full_name='agent.RestoreResponse.success', index=0,\n      number=1, type=8, cpp_type=7, label=1,\n      has_default_value=False, default_value=False,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='error', full_name='agent.RestoreResponse.error', index=1,\n      number=2, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=738,\n  serialized_end=787,\n)\n\n\n_KEEPALIVEREQUEST = _descriptor.Descriptor(\n  name='KeepaliveRequest',\n  full_name='agent.KeepaliveRequest',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='time', full_name='agent.KeepaliveRequest.time', index=0,\n      number=1, type=3, cpp_type=2, label=1,\n      has_default_value=False, default_value=0,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=789,\n  serialized_end=821,\n)\n\n\n_KEEPALIVERESPONSE = _descriptor.Descriptor(\n  name='KeepaliveResponse',\n  full_name='agent.KeepaliveResponse',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='time', full_name='agent.KeepaliveResponse.time', index=0,\n      number=1, type=3, cpp_type=2, label=1,\n      has_default_value=False, default_value=0,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=823,\n  serialized_end=856,\n)\n\n\n_ERRORRESPONSE = _descriptor.Descriptor(\n  name='ErrorResponse',\n  full_name='agent.ErrorResponse',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='error', full_name='agent.ErrorResponse.error', index=0,\n      number=1, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=858,\n  serialized_end=888,\n)\n\n\n_BEGINBATCH_TAGSENTRY = _descriptor.Descriptor(\n  name='TagsEntry',\n  full_name='agent.BeginBatch.TagsEntry',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='key', full_name='agent.BeginBatch.TagsEntry.key', index=0,\n      number=1, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='value', full_name='agent.BeginBatch.TagsEntry.value', index=1,\n      number=2, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=_descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001')),\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=1007,\n  serialized_end=1050,\n)\n\n_BEGINBATCH = _descriptor.Descriptor(\n  name='BeginBatch',\n  full_name='agent.BeginBatch',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='name', full_name='agent.BeginBatch.name', index=0,\n      number=1, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='group', full_name='agent.BeginBatch.group', index=1,\n      number=2, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='tags', full_name='agent.BeginBatch.tags', index=2,\n      number=3, type=11, cpp_type=10, label=3,\n      has_default_value=False, default_value=[],\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='size', full_name='agent.BeginBatch.size', index=3,\n      number=4, type=3, cpp_type=2, label=1,\n      has_default_value=False, default_value=0,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='byName', full_name='agent.BeginBatch.byName', index=4,\n      number=5, type=8, cpp_type=7, label=1,\n      has_default_value=False, default_value=False,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[_BEGINBATCH_TAGSENTRY, ],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=891,\n  serialized_end=1050,\n)\n\n\n_POINT_TAGSENTRY = _descriptor.Descriptor(\n  name='TagsEntry',\n  full_name='agent.Point.TagsEntry',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='key', full_name='agent.Point.TagsEntry.key', index=0,\n      number=1, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='value', full_name='agent.Point.TagsEntry.value', index=1,\n      number=2, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=_descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001')),\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=1007,\n  serialized_end=1050,\n)\n\n_POINT_FIELDSDOUBLEENTRY = _descriptor.Descriptor(\n  name='FieldsDoubleEntry',\n  full_name='agent.Point.FieldsDoubleEntry',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='key', full_name='agent.Point.FieldsDoubleEntry.key', index=0,\n      number=1, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='value', full_name='agent.Point.FieldsDoubleEntry.value', index=1,\n      number=2, type=1, cpp_type=5, label=1,\n      has_default_value=False, default_value=float(0),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=_descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001')),\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=1473,\n  serialized_end=1524,\n)\n\n_POINT_FIELDSINTENTRY = _descriptor.Descriptor(\n  name='FieldsIntEntry',\n  full_name='agent.Point.FieldsIntEntry',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='key', full_name='agent.Point.FieldsIntEntry.key', index=0,\n      number=1, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='value', full_name='agent.Point.FieldsIntEntry.value', index=1,\n      number=2, type=3, cpp_type=2, label=1,\n      has_default_value=False, default_value=0,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=_descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001')),\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=1526,\n  serialized_end=1574,\n)\n\n_POINT_FIELDSSTRINGENTRY = _descriptor.Descriptor(\n  name='FieldsStringEntry',\n  full_name='agent.Point.FieldsStringEntry',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='key', full_name='agent.Point.FieldsStringEntry.key', index=0,\n      number=1, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='value', full_name='agent.Point.FieldsStringEntry.value', index=1,\n      number=2, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=_descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001')),\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=1576,\n  serialized_end=1627,\n)\n\n_POINT_FIELDSBOOLENTRY = _descriptor.Descriptor(\n  name='FieldsBoolEntry',\n  full_name='agent.Point.FieldsBoolEntry',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='key', full_name='agent.Point.FieldsBoolEntry.key', index=0,\n      number=1, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='value', full_name='agent.Point.FieldsBoolEntry.value', index=1,\n      number=2, type=8, cpp_type=7, label=1,\n      has_default_value=False, default_value=False,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=_descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001')),\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=1629,\n  serialized_end=1678,\n)\n\n_POINT = _descriptor.Descriptor(\n  name='Point',\n  full_name='agent.Point',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='time', full_name='agent.Point.time', index=0,\n      number=1, type=3, cpp_type=2, label=1,\n      has_default_value=False, default_value=0,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='name', full_name='agent.Point.name', index=1,\n      number=2, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='database', full_name='agent.Point.database', index=2,\n      number=3, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='retentionPolicy', full_name='agent.Point.retentionPolicy', index=3,\n      number=4, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='group', full_name='agent.Point.group', index=4,\n      number=5, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='dimensions', full_name='agent.Point.dimensions', index=5,\n      number=6, type=9, cpp_type=9, label=3,\n      has_default_value=False, default_value=[],\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='tags', full_name='agent.Point.tags', index=6,\n      number=7, type=11, cpp_type=10, label=3,\n      has_default_value=False, default_value=[],\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='fieldsDouble', full_name='agent.Point.fieldsDouble', index=7,\n      number=8, type=11, cpp_type=10, label=3,\n      has_default_value=False, default_value=[],\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='fieldsInt', full_name='agent.Point.fieldsInt', index=8,\n      number=9, type=11, cpp_type=10, label=3,\n      has_default_value=False, default_value=[],\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='fieldsString', full_name='agent.Point.fieldsString', index=9,\n      number=10, type=11, cpp_type=10, label=3,\n      has_default_value=False, default_value=[],\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='fieldsBool', full_name='agent.Point.fieldsBool', index=10,\n      number=12, type=11, cpp_type=10, label=3,\n      has_default_value=False, default_value=[],\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='byName', full_name='agent.Point.byName', index=11,\n      number=11, type=8, cpp_type=7, label=1,\n      has_default_value=False, default_value=False,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[_POINT_TAGSENTRY, _POINT_FIELDSDOUBLEENTRY, _POINT_FIELDSINTENTRY, _POINT_FIELDSSTRINGENTRY, _POINT_FIELDSBOOLENTRY, ],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=1053,\n  serialized_end=1678,\n)\n\n\n_ENDBATCH_TAGSENTRY = _descriptor.Descriptor(\n  name='TagsEntry',\n  full_name='agent.EndBatch.TagsEntry',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='key', full_name='agent.EndBatch.TagsEntry.key', index=0,\n      number=1, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='value', full_name='agent.EndBatch.TagsEntry.value', index=1,\n      number=2, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=_descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001')),\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=1007,\n  serialized_end=1050,\n)\n\n_ENDBATCH = _descriptor.Descriptor(\n  name='EndBatch',\n  full_name='agent.EndBatch',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='name', full_name='agent.EndBatch.name', index=0,\n      number=1, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='group', full_name='agent.EndBatch.group', index=1,\n      number=2, type=9, cpp_type=9, label=1,\n      has_default_value=False, default_value=_b(\"\").decode('utf-8'),\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='tmax', full_name='agent.EndBatch.tmax', index=2,\n      number=3, type=3, cpp_type=2, label=1,\n      has_default_value=False, default_value=0,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='tags', full_name='agent.EndBatch.tags', index=3,\n      number=4, type=11, cpp_type=10, label=3,\n      has_default_value=False, default_value=[],\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='byName', full_name='agent.EndBatch.byName', index=4,\n      number=5, type=8, cpp_type=7, label=1,\n      has_default_value=False, default_value=False,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[_ENDBATCH_TAGSENTRY, ],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n  ],\n  serialized_start=1681,\n  serialized_end=1836,\n)\n\n\n_REQUEST = _descriptor.Descriptor(\n  name='Request',\n  full_name='agent.Request',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='info', full_name='agent.Request.info', index=0,\n      number=1, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='init', full_name='agent.Request.init', index=1,\n      number=2, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='keepalive', full_name='agent.Request.keepalive', index=2,\n      number=3, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='snapshot', full_name='agent.Request.snapshot', index=3,\n      number=4, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='restore', full_name='agent.Request.restore', index=4,\n      number=5, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='begin', full_name='agent.Request.begin', index=5,\n      number=16, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='point', full_name='agent.Request.point', index=6,\n      number=17, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='end', full_name='agent.Request.end', index=7,\n      number=18, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n    _descriptor.OneofDescriptor(\n      name='message', full_name='agent.Request.message',\n      index=0, containing_type=None, fields=[]),\n  ],\n  serialized_start=1839,\n  serialized_end=2162,\n)\n\n\n_RESPONSE = _descriptor.Descriptor(\n  name='Response',\n  full_name='agent.Response',\n  filename=None,\n  file=DESCRIPTOR,\n  containing_type=None,\n  fields=[\n    _descriptor.FieldDescriptor(\n      name='info', full_name='agent.Response.info', index=0,\n      number=1, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='init', full_name='agent.Response.init', index=1,\n      number=2, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='keepalive', full_name='agent.Response.keepalive', index=2,\n      number=3, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='snapshot', full_name='agent.Response.snapshot', index=3,\n      number=4, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='restore', full_name='agent.Response.restore', index=4,\n      number=5, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='error', full_name='agent.Response.error', index=5,\n      number=6, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='begin', full_name='agent.Response.begin', index=6,\n      number=16, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='point', full_name='agent.Response.point', index=7,\n      number=17, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n    _descriptor.FieldDescriptor(\n      name='end', full_name='agent.Response.end', index=8,\n      number=18, type=11, cpp_type=10, label=1,\n      has_default_value=False, default_value=None,\n      message_type=None, enum_type=None, containing_type=None,\n      is_extension=False, extension_scope=None,\n      options=None),\n  ],\n  extensions=[\n  ],\n  nested_types=[],\n  enum_types=[\n  ],\n  options=None,\n  is_extendable=False,\n  syntax='proto3',\n  extension_ranges=[],\n  oneofs=[\n    _descriptor.OneofDescriptor(\n      name='message', full_name='agent.Response.message',\n      index=0, containing_type=None, fields=[]),\n  ],\n  serialized_start=2165,\n  serialized_end=2533,\n)\n\n_INFORESPONSE_OPTIONSENTRY.fields_by_name['value'].message_type = _OPTIONINFO\n_INFORESPONSE_OPTIONSENTRY.containing_type = _INFORESPONSE\n_INFORESPONSE.fields_by_name['wants'].enum_type = _EDGETYPE\n_INFORESPONSE.fields_by_name['provides'].enum_type = _EDGETYPE\n_INFORESPONSE.fields_by_name['options'].message_type = _INFORESPONSE_OPTIONSENTRY\n_OPTIONINFO.fields_by_name['valueTypes'].enum_type = _VALUETYPE\n_INITREQUEST.fields_by_name['options'].message_type = _OPTION\n_OPTION.fields_by_name['values'].message_type = _OPTIONVALUE\n_OPTIONVALUE.fields_by_name['type'].enum_type = _VALUETYPE\n_OPTIONVALUE.oneofs_by_name['value'].fields.append(\n  _OPTIONVALUE.fields_by_name['boolValue'])\n_OPTIONVALUE.fields_by_name['boolValue'].containing_oneof = _OPTIONVALUE.oneofs_by_name['value']\n_OPTIONVALUE.oneofs_by_name['value'].fields.append(\n  _OPTIONVALUE.fields_by_name['intValue'])\n_OPTIONVALUE.fields_by_name['intValue'].containing_oneof = _OPTIONVALUE.oneofs_by_name['value']\n_OPTIONVALUE.oneofs_by_name['value'].fields.append(\n  _OPTIONVALUE.fields_by_name['doubleValue'])\n_OPTIONVALUE.fields_by_name['doubleValue'].containing_oneof = _OPTIONVALUE.oneofs_by_name['value']\n_OPTIONVALUE.oneofs_by_name['value'].fields.append(\n  _OPTIONVALUE.fields_by_name['stringValue'])\n_OPTIONVALUE.fields_by_name['stringValue'].containing_oneof = _OPTIONVALUE.oneofs_by_name['value']\n_OPTIONVALUE.oneofs_by_name['value'].fields.append(\n  _OPTIONVALUE.fields_by_name['durationValue'])\n_OPTIONVALUE.fields_by_name['durationValue'].containing_oneof = _OPTIONVALUE.oneofs_by_name['value']\n_BEGINBATCH_TAGSENTRY.containing_type = _BEGINBATCH\n_BEGINBATCH.fields_by_name['tags'].message_type = _BEGINBATCH_TAGSENTRY\n_POINT_TAGSENTRY.containing_type = _POINT\n_POINT_FIELDSDOUBLEENTRY.containing_type = _POINT\n_POINT_FIELDSINTENTRY.containing_type = _POINT\n_POINT_FIELDSSTRINGENTRY.containing_type = _POINT\n_POINT_FIELDSBOOLENTRY.containing_type = _POINT\n_POINT.fields_by_name['tags'].message_type = _POINT_TAGSENTRY\n_POINT.fields_by_name['fieldsDouble'].message_type = _POINT_FIELDSDOUBLEENTRY\n_POINT.fields_by_name['fieldsInt'].message_type = _POINT_FIELDSINTENTRY\n_POINT.fields_by_name['fieldsString'].message_type = _POINT_FIELDSSTRINGENTRY\n_POINT.fields_by_name['fieldsBool'].message_type = _POINT_FIELDSBOOLENTRY\n_ENDBATCH_TAGSENTRY.containing_type = _ENDBATCH\n_ENDBATCH.fields_by_name['tags'].message_type = _ENDBATCH_TAGSENTRY\n_REQUEST.fields_by_name['info'].message_type = _INFOREQUEST\n_REQUEST.fields_by_name['init'].message_type = _INITREQUEST\n_REQUEST.fields_by_name['keepalive'].message_type = _KEEPALIVEREQUEST\n_REQUEST.fields_by_name['snapshot'].message_type = _SNAPSHOTREQUEST\n_REQUEST.fields_by_name['restore'].message_type = _RESTOREREQUEST\n_REQUEST.fields_by_name['begin'].message_type = _BEGINBATCH\n_REQUEST.fields_by_name['point'].message_type = _POINT\n_REQUEST.fields_by_name['end'].message_type = _ENDBATCH\n_REQUEST.oneofs_by_name['message'].fields.append(\n  _REQUEST.fields_by_name['info'])\n_REQUEST.fields_by_name['info'].containing_oneof = _REQUEST.oneofs_by_name['message']\n_REQUEST.oneofs_by_name['message'].fields.append(\n  _REQUEST.fields_by_name['init'])\n_REQUEST.fields_by_name['init'].containing_oneof = _REQUEST.oneofs_by_name['message']\n_REQUEST.oneofs_by_name['message'].fields.append(\n  _REQUEST.fields_by_name['keepalive'])\n_REQUEST.fields_by_name['keepalive'].containing_oneof = _REQUEST.oneofs_by_name['message']\n_REQUEST.oneofs_by_name['message'].fields.append(\n  _REQUEST.fields_by_name['snapshot'])\n_REQUEST.fields_by_name['snapshot'].containing_oneof = _REQUEST.oneofs_by_name['message']\n_REQUEST.oneofs_by_name['message'].fields.append(\n  _REQUEST.fields_by_name['restore'])\n_REQUEST.fields_by_name['restore'].containing_oneof = _REQUEST.oneofs_by_name['message']\n_REQUEST.oneofs_by_name['message'].fields.append(\n  _REQUEST.fields_by_name['begin'])\n_REQUEST.fields_by_name['begin'].containing_oneof = _REQUEST.oneofs_by_name['message']\n_REQUEST.oneofs_by_name['message'].fields.append(\n  _REQUEST.fields_by_name['point'])\n_REQUEST.fields_by_name['point'].containing_oneof = _REQUEST.oneofs_by_name['message']\n_REQUEST.oneofs_by_name['message'].fields.append(\n  _REQUEST.fields_by_name['end'])\n_REQUEST.fields_by_name['end'].containing_oneof = _REQUEST.oneofs_by_name['message']\n_RESPONSE.fields_by_name['info'].message_type = _INFORESPONSE\n_RESPONSE.fields_by_name['init'].message_type = _INITRESPONSE\n_RESPONSE.fields_by_name['keepalive'].message_type = _KEEPALIVERESPONSE\n_RESPONSE.fields_by_name['snapshot'].message_type = _SNAPSHOTRESPONSE\n_RESPONSE.fields_by_name['restore'].message_type = _RESTORERESPONSE\n_RESPONSE.fields_by_name['error'].message_type = _ERRORRESPONSE\n_RESPONSE.fields_by_name['begin'].message_type = _BEGINBATCH\n_RESPONSE.fields_by_name['point'].message_type = _POINT\n_RESPONSE.fields_by_name['end'].message_type = _ENDBATCH\n_RESPONSE.oneofs_by_name['message'].fields.append(\n  _RESPONSE.fields_by_name['info'])\n_RESPONSE.fields_by_name['info'].containing_oneof = _RESPONSE.oneofs_by_name['message']\n_RESPONSE.oneofs_by_name['message'].fields.append(\n  _RESPONSE.fields_by_name['init'])\n_RESPONSE.fields_by_name['init'].containing_oneof = _RESPONSE.oneofs_by_name['message']\n_RESPONSE.oneofs_by_name['message'].fields.append(\n  _RESPONSE.fields_by_name['keepalive'])\n_RESPONSE.fields_by_name['keepalive'].containing_oneof = _RESPONSE.oneofs_by_name['message']\n_RESPONSE.oneofs_by_name['message'].fields.append(\n  _RESPONSE.fields_by_name['snapshot'])\n_RESPONSE.fields_by_name['snapshot'].containing_oneof = _RESPONSE.oneofs_by_name['message']\n_RESPONSE.oneofs_by_name['message'].fields.append(\n  _RESPONSE.fields_by_name['restore'])\n_RESPONSE.fields_by_name['restore'].containing_oneof = _RESPONSE.oneofs_by_name['message']\n_RESPONSE.oneofs_by_name['message'].fields.append(\n  _RESPONSE.fields_by_name['error'])\n_RESPONSE.fields_by_name['error'].containing_oneof = _RESPONSE.oneofs_by_name['message']\n_RESPONSE.oneofs_by_name['message'].fields.append(\n  _RESPONSE.fields_by_name['begin'])\n_RESPONSE.fields_by_name['begin'].containing_oneof = _RESPONSE.oneofs_by_name['message']\n_RESPONSE.oneofs_by_name['message'].fields.append(\n  _RESPONSE.fields_by_name['point'])\n_RESPONSE.fields_by_name['point'].containing_oneof = _RESPONSE.oneofs_by_name['message']\n_RESPONSE.oneofs_by_name['message'].fields.append(\n  _RESPONSE.fields_by_name['end'])\n_RESPONSE.fields_by_name['end'].containing_oneof = _RESPONSE.oneofs_by_name['message']\nDESCRIPTOR.message_types_by_name['InfoRequest'] = _INFOREQUEST\nDESCRIPTOR.message_types_by_name['InfoResponse'] = _INFORESPONSE\nDESCRIPTOR.message_types_by_name['OptionInfo'] = _OPTIONINFO\nDESCRIPTOR.message_types_by_name['InitRequest'] = _INITREQUEST\nDESCRIPTOR.message_types_by_name['Option'] = _OPTION\nDESCRIPTOR.message_types_by_name['OptionValue'] = _OPTIONVALUE\nDESCRIPTOR.message_types_by_name['InitResponse'] = _INITRESPONSE\nDESCRIPTOR.message_types_by_name['SnapshotRequest'] = _SNAPSHOTREQUEST\nDESCRIPTOR.message_types_by_name['SnapshotResponse'] = _SNAPSHOTRESPONSE\nDESCRIPTOR.message_types_by_name['RestoreRequest'] = _RESTOREREQUEST\nDESCRIPTOR.message_types_by_name['RestoreResponse'] = _RESTORERESPONSE\nDESCRIPTOR.message_types_by_name['KeepaliveRequest'] = _KEEPALIVEREQUEST\nDESCRIPTOR.message_types_by_name['KeepaliveResponse'] = _KEEPALIVERESPONSE\nDESCRIPTOR.message_types_by_name['ErrorResponse'] = _ERRORRESPONSE\nDESCRIPTOR.message_types_by_name['BeginBatch'] = _BEGINBATCH\nDESCRIPTOR.message_types_by_name['Point'] = _POINT\nDESCRIPTOR.message_types_by_name['EndBatch'] = _ENDBATCH\nDESCRIPTOR.message_types_by_name['Request'] = _REQUEST\nDESCRIPTOR.message_types_by_name['Response'] = _RESPONSE\nDESCRIPTOR.enum_types_by_name['EdgeType'] = _EDGETYPE\nDESCRIPTOR.enum_types_by_name['ValueType'] = _VALUETYPE\n_sym_db.RegisterFileDescriptor(DESCRIPTOR)\n\nInfoRequest = _reflection.GeneratedProtocolMessageType('InfoRequest', (_message.Message,), dict(\n  DESCRIPTOR = _INFOREQUEST,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.InfoRequest)\n  ))\n_sym_db.RegisterMessage(InfoRequest)\n\nInfoResponse = _reflection.GeneratedProtocolMessageType('InfoResponse', (_message.Message,), dict(\n\n  OptionsEntry = _reflection.GeneratedProtocolMessageType('OptionsEntry', (_message.Message,), dict(\n    DESCRIPTOR = _INFORESPONSE_OPTIONSENTRY,\n    __module__ = 'udf_pb2'\n    # @@protoc_insertion_point(class_scope:agent.InfoResponse.OptionsEntry)\n    ))\n  ,\n  DESCRIPTOR = _INFORESPONSE,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.InfoResponse)\n  ))\n_sym_db.RegisterMessage(InfoResponse)\n_sym_db.RegisterMessage(InfoResponse.OptionsEntry)\n\nOptionInfo = _reflection.GeneratedProtocolMessageType('OptionInfo', (_message.Message,), dict(\n  DESCRIPTOR = _OPTIONINFO,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.OptionInfo)\n  ))\n_sym_db.RegisterMessage(OptionInfo)\n\nInitRequest = _reflection.GeneratedProtocolMessageType('InitRequest', (_message.Message,), dict(\n  DESCRIPTOR = _INITREQUEST,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.InitRequest)\n  ))\n_sym_db.RegisterMessage(InitRequest)\n\nOption = _reflection.GeneratedProtocolMessageType('Option', (_message.Message,), dict(\n  DESCRIPTOR = _OPTION,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.Option)\n  ))\n_sym_db.RegisterMessage(Option)\n\nOptionValue = _reflection.GeneratedProtocolMessageType('OptionValue', (_message.Message,), dict(\n  DESCRIPTOR = _OPTIONVALUE,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.OptionValue)\n  ))\n_sym_db.RegisterMessage(OptionValue)\n\nInitResponse = _reflection.GeneratedProtocolMessageType('InitResponse', (_message.Message,), dict(\n  DESCRIPTOR = _INITRESPONSE,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.InitResponse)\n  ))\n_sym_db.RegisterMessage(InitResponse)\n\nSnapshotRequest = _reflection.GeneratedProtocolMessageType('SnapshotRequest', (_message.Message,), dict(\n  DESCRIPTOR = _SNAPSHOTREQUEST,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.SnapshotRequest)\n  ))\n_sym_db.RegisterMessage(SnapshotRequest)\n\nSnapshotResponse = _reflection.GeneratedProtocolMessageType('SnapshotResponse', (_message.Message,), dict(\n  DESCRIPTOR = _SNAPSHOTRESPONSE,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.SnapshotResponse)\n  ))\n_sym_db.RegisterMessage(SnapshotResponse)\n\nRestoreRequest = _reflection.GeneratedProtocolMessageType('RestoreRequest', (_message.Message,), dict(\n  DESCRIPTOR = _RESTOREREQUEST,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.RestoreRequest)\n  ))\n_sym_db.RegisterMessage(RestoreRequest)\n\nRestoreResponse = _reflection.GeneratedProtocolMessageType('RestoreResponse', (_message.Message,), dict(\n  DESCRIPTOR = _RESTORERESPONSE,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.RestoreResponse)\n  ))\n_sym_db.RegisterMessage(RestoreResponse)\n\nKeepaliveRequest = _reflection.GeneratedProtocolMessageType('KeepaliveRequest', (_message.Message,), dict(\n  DESCRIPTOR = _KEEPALIVEREQUEST,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.KeepaliveRequest)\n  ))\n_sym_db.RegisterMessage(KeepaliveRequest)\n\nKeepaliveResponse = _reflection.GeneratedProtocolMessageType('KeepaliveResponse', (_message.Message,), dict(\n  DESCRIPTOR = _KEEPALIVERESPONSE,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.KeepaliveResponse)\n  ))\n_sym_db.RegisterMessage(KeepaliveResponse)\n\nErrorResponse = _reflection.GeneratedProtocolMessageType('ErrorResponse', (_message.Message,), dict(\n  DESCRIPTOR = _ERRORRESPONSE,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.ErrorResponse)\n  ))\n_sym_db.RegisterMessage(ErrorResponse)\n\nBeginBatch = _reflection.GeneratedProtocolMessageType('BeginBatch', (_message.Message,), dict(\n\n  TagsEntry = _reflection.GeneratedProtocolMessageType('TagsEntry', (_message.Message,), dict(\n    DESCRIPTOR = _BEGINBATCH_TAGSENTRY,\n    __module__ = 'udf_pb2'\n    # @@protoc_insertion_point(class_scope:agent.BeginBatch.TagsEntry)\n    ))\n  ,\n  DESCRIPTOR = _BEGINBATCH,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.BeginBatch)\n  ))\n_sym_db.RegisterMessage(BeginBatch)\n_sym_db.RegisterMessage(BeginBatch.TagsEntry)\n\nPoint = _reflection.GeneratedProtocolMessageType('Point', (_message.Message,), dict(\n\n  TagsEntry = _reflection.GeneratedProtocolMessageType('TagsEntry', (_message.Message,), dict(\n    DESCRIPTOR = _POINT_TAGSENTRY,\n    __module__ = 'udf_pb2'\n    # @@protoc_insertion_point(class_scope:agent.Point.TagsEntry)\n    ))\n  ,\n\n  FieldsDoubleEntry = _reflection.GeneratedProtocolMessageType('FieldsDoubleEntry', (_message.Message,), dict(\n    DESCRIPTOR = _POINT_FIELDSDOUBLEENTRY,\n    __module__ = 'udf_pb2'\n    # @@protoc_insertion_point(class_scope:agent.Point.FieldsDoubleEntry)\n    ))\n  ,\n\n  FieldsIntEntry = _reflection.GeneratedProtocolMessageType('FieldsIntEntry', (_message.Message,), dict(\n    DESCRIPTOR = _POINT_FIELDSINTENTRY,\n    __module__ = 'udf_pb2'\n    # @@protoc_insertion_point(class_scope:agent.Point.FieldsIntEntry)\n    ))\n  ,\n\n  FieldsStringEntry = _reflection.GeneratedProtocolMessageType('FieldsStringEntry', (_message.Message,), dict(\n    DESCRIPTOR = _POINT_FIELDSSTRINGENTRY,\n    __module__ = 'udf_pb2'\n    # @@protoc_insertion_point(class_scope:agent.Point.FieldsStringEntry)\n    ))\n  ,\n\n  FieldsBoolEntry = _reflection.GeneratedProtocolMessageType('FieldsBoolEntry', (_message.Message,), dict(\n    DESCRIPTOR = _POINT_FIELDSBOOLENTRY,\n    __module__ = 'udf_pb2'\n    # @@protoc_insertion_point(class_scope:agent.Point.FieldsBoolEntry)\n    ))\n  ,\n  DESCRIPTOR = _POINT,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.Point)\n  ))\n_sym_db.RegisterMessage(Point)\n_sym_db.RegisterMessage(Point.TagsEntry)\n_sym_db.RegisterMessage(Point.FieldsDoubleEntry)\n_sym_db.RegisterMessage(Point.FieldsIntEntry)\n_sym_db.RegisterMessage(Point.FieldsStringEntry)\n_sym_db.RegisterMessage(Point.FieldsBoolEntry)\n\nEndBatch = _reflection.GeneratedProtocolMessageType('EndBatch', (_message.Message,), dict(\n\n  TagsEntry = _reflection.GeneratedProtocolMessageType('TagsEntry', (_message.Message,), dict(\n    DESCRIPTOR = _ENDBATCH_TAGSENTRY,\n    __module__ = 'udf_pb2'\n    # @@protoc_insertion_point(class_scope:agent.EndBatch.TagsEntry)\n    ))\n  ,\n  DESCRIPTOR = _ENDBATCH,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.EndBatch)\n  ))\n_sym_db.RegisterMessage(EndBatch)\n_sym_db.RegisterMessage(EndBatch.TagsEntry)\n\nRequest = _reflection.GeneratedProtocolMessageType('Request', (_message.Message,), dict(\n  DESCRIPTOR = _REQUEST,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.Request)\n  ))\n_sym_db.RegisterMessage(Request)\n\nResponse = _reflection.GeneratedProtocolMessageType('Response', (_message.Message,), dict(\n  DESCRIPTOR = _RESPONSE,\n  __module__ = 'udf_pb2'\n  # @@protoc_insertion_point(class_scope:agent.Response)\n  ))\n_sym_db.RegisterMessage(Response)\n\n\n_INFORESPONSE_OPTIONSENTRY.has_options = True\n_INFORESPONSE_OPTIONSENTRY._options = _descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001'))\n_BEGINBATCH_TAGSENTRY.has_options = True\n_BEGINBATCH_TAGSENTRY._options = _descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001'))\n_POINT_TAGSENTRY.has_options = True\n_POINT_TAGSENTRY._options = _descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001'))\n_POINT_FIELDSDOUBLEENTRY.has_options = True\n_POINT_FIELDSDOUBLEENTRY._options = _descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001'))\n_POINT_FIELDSINTENTRY.has_options = True\n_POINT_FIELDSINTENTRY._options = _descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001'))\n_POINT_FIELDSSTRINGENTRY.has_options = True\n_POINT_FIELDSSTRINGENTRY._options = _descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001'))\n_POINT_FIELDSBOOLENTRY.has_options = True\n_POINT_FIELDSBOOLENTRY._options = _descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001'))\n_ENDBATCH_TAGSENTRY.has_options = True\n_ENDBATCH_TAGSENTRY._options = _descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\\001'))\n# @@protoc_insertion_point(module_scope)\n",
+	"kapacitor_udf_runner.py":   "# Runs a Python UDF handler with the Kapacitor UDF agent bundled with kapacitord.\n#\n# The script defines a subclass of kapacitor.udf.agent.Handler whose constructor takes the agent,\n# the runner creates the agent and the handler and serves the requests of Kapacitor over STDIN and STDOUT.\n#\n# A script may declare the versions of the UDF protocol it supports with a PROTOCOL_VERSIONS list,\n# the highest version supported by Kapacitor, the agent and the script is used.\n#\n# Usage:\n#   python -u kapacitor_udf_runner.py --protocol-versions 1 [--handler NAME] SCRIPT\n\nimport argparse\nimport inspect\nimport logging\nimport os\nimport sys\n\nlogging.basicConfig(level=logging.INFO, format='%(asctime)s %(levelname)s:%(name)s: %(message)s')\nlogger = logging.getLogger('kapacitor_udf_runner')\n\n# Exit code of errors setting up the UDF.\nEXIT_SETUP = 2\n\n\ndef fail(msg, *args):\n    logger.error(msg, *args)\n    sys.exit(EXIT_SETUP)\n\n\ndef load_script(path):\n    name = os.path.splitext(os.path.basename(path))[0]\n    sys.path.insert(0, os.path.dirname(os.path.abspath(path)))\n    try:\n        import importlib.util\n    except ImportError:\n        import imp\n        return imp.load_source(name, path)\n    spec = importlib.util.spec_from_file_location(name, path)\n    module = importlib.util.module_from_spec(spec)\n    sys.modules[name] = module\n    spec.loader.exec_module(module)\n    return module\n\n\ndef negotiate(kapacitor_versions, agent_version, script_versions):\n    common = [v for v in kapacitor_versions if v <= agent_version]\n    if script_versions is not None:\n        common = [v for v in common if v in script_versions]\n    if not common:\n        return None\n    return max(common)\n\n\ndef find_handler(module, base, name):\n    if name:\n        handler = getattr(module, name, None)\n        if handler is None:\n            fail(\"script does not define handler %s\", name)\n        return handler\n    handlers = [\n        cls for _, cls in inspect.getmembers(module, inspect.isclass)\n        if issubclass(cls, base) and cls is not base and cls.__module__ == module.__name__\n    ]\n    if len(handlers) != 1:\n        fail(\"script must define exactly one Handler subclass, found %d, set the handler to choose one\", len(handlers))\n    return handlers[0]\n\n\ndef main():\n    parser = argparse.ArgumentParser(description='Run a Kapacitor UDF handler.')\n    parser.add_argument('--protocol-versions', required=True,\n                        help='comma separated versions of the UDF protocol supported by Kapacitor')\n    parser.add_argument('--handler', default='', help='name of the Handler subclass to run')\n    parser.add_argument('script', help='path of the script defining the handler')\n    args = parser.parse_args()\n\n    try:\n        import google.protobuf\n    except ImportError:\n        fail(\"the protobuf package is not installed for %s, add it to the requirements of the UDF\", sys.executable)\n    from kapacitor.udf import agent\n\n    # Anything the script prints must not corrupt the responses written to STDOUT.\n    out = getattr(sys.stdout, 'buffer', sys.stdout)\n    sys.stdout = sys.stderr\n\n    module = load_script(args.script)\n\n    kapacitor_versions = [int(v) for v in args.protocol_versions.split(',')]\n    script_versions = getattr(module, 'PROTOCOL_VERSIONS', None)\n    version = negotiate(kapacitor_versions, agent.PROTOCOL_VERSION, script_versions)\n    if version is None:\n        fail(\"no common UDF protocol version, kapacitor supports %s, the agent %d and the script %s\",\n             kapacitor_versions, agent.PROTOCOL_VERSION, script_versions)\n    logger.info(\"using UDF protocol version %d\", version)\n\n    handler = find_handler(module, agent.Handler, args.handler)\n    a = agent.Agent(out=out)\n    a.handler = handler(a)\n\n    a.start()\n    a.wait()\n\n\nif __name__ == '__main__':\n    main()\n",
+}
@@ -0,0 +1,55 @@
+//go:build ignore
+// +build ignore
+
+// Generates files.gen.go containing the bundled agent and runner.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// Source path of the bundled files by their path relative to the install directory.
+var sources = map[string]string{
+	"kapacitor/__init__.py":     "../agent/py/kapacitor/__init__.py",
+	"kapacitor/udf/__init__.py": "../agent/py/kapacitor/udf/__init__.py",
+	"kapacitor/udf/agent.py":    "../agent/py/kapacitor/udf/agent.py",
+	"kapacitor/udf/udf_pb2.py":  "../agent/py/kapacitor/udf/udf_pb2.py",
+	"kapacitor_udf_runner.py":   "runner.py",
+}
+
+func main() {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen.go. DO NOT EDIT.\n\n")
+	buf.WriteString("package python\n\n")
+	buf.WriteString("// Files of the bundled agent and runner by their path relative to the install directory.\n")
+	buf.WriteString("var files = map[string]string{\n")
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.FromSlash(sources[name]))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(&buf, "%q: %q,\n", path.Clean(name), data)
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("files.gen.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,167 @@
+// Package python runs Python UDFs with the UDF agent bundled with Kapacitor.
+//
+// A Python UDF only implements a handler, the bundled runner script creates the agent,
+// negotiates the version of the UDF protocol and serves the requests of Kapacitor.
+// The UDF optionally runs in a virtualenv managed by Kapacitor, into which
+// the dependencies of the agent and the requirements of the UDF are installed.
+package python
+
+//go:generate go run gen.go
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Version of the UDF protocol implemented by Kapacitor.
+	ProtocolVersion = 1
+
+	// Default interpreter used to create virtualenvs and to run UDFs without one.
+	DefaultInterpreter = "python3"
+
+	// Name of the bundled script running the handler of a UDF.
+	runnerFile = "kapacitor_udf_runner.py"
+
+	// Dependency of the bundled agent installed into every virtualenv.
+	agentRequirement = "protobuf>=3.0.0,<4"
+
+	// File recording the digest of the requirements installed into a virtualenv.
+	installedFile = ".kapacitor-requirements"
+)
+
+// Spec describes how to run a Python UDF.
+type Spec struct {
+	// Path of the script defining the handler.
+	Script string
+	// Name of the handler class, if empty the script must define exactly one handler.
+	Handler string
+	// Interpreter used to create the virtualenv, or to run the script if there is no virtualenv.
+	Interpreter string
+	// Path of the virtualenv the UDF runs in, it is created if it does not exist.
+	Virtualenv string
+	// Path of a pip requirements file installed into the virtualenv.
+	Requirements string
+}
+
+func (s Spec) interpreter() string {
+	if s.Interpreter == "" {
+		return DefaultInterpreter
+	}
+	return s.Interpreter
+}
+
+// python returns the interpreter the UDF runs with.
+func (s Spec) python() string {
+	if s.Virtualenv == "" {
+		return s.interpreter()
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(s.Virtualenv, "Scripts", "python.exe")
+	}
+	return filepath.Join(s.Virtualenv, "bin", "python")
+}
+
+// Install writes the bundled agent and runner into dir, replacing any previous versions.
+func Install(dir string) error {
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prepare creates the virtualenv of the spec if it does not exist
+// and installs the requirements into it when they changed since they were last installed.
+func Prepare(s Spec) error {
+	if s.Virtualenv == "" {
+		return nil
+	}
+	if _, err := os.Stat(s.python()); os.IsNotExist(err) {
+		if err := run(s.interpreter(), "-m", "venv", s.Virtualenv); err != nil {
+			return fmt.Errorf("failed to create virtualenv %s: %v", s.Virtualenv, err)
+		}
+	} else if err != nil {
+		return err
+	}
+
+	digest, err := requirementsDigest(s.Requirements)
+	if err != nil {
+		return err
+	}
+	installed := filepath.Join(s.Virtualenv, installedFile)
+	if b, err := ioutil.ReadFile(installed); err == nil && string(b) == digest {
+		return nil
+	}
+	args := []string{"-m", "pip", "install", "--disable-pip-version-check", agentRequirement}
+	if s.Requirements != "" {
+		args = append(args, "-r", s.Requirements)
+	}
+	if err := run(s.python(), args...); err != nil {
+		return fmt.Errorf("failed to install requirements into virtualenv %s: %v", s.Virtualenv, err)
+	}
+	return ioutil.WriteFile(installed, []byte(digest), 0644)
+}
+
+// requirementsDigest returns a digest of everything installed into a virtualenv.
+func requirementsDigest(requirements string) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(agentRequirement + "\n"))
+	if requirements != "" {
+		b, err := ioutil.ReadFile(requirements)
+		if err != nil {
+			return "", err
+		}
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func run(prog string, args ...string) error {
+	var out bytes.Buffer
+	cmd := exec.Command(prog, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return fmt.Errorf("%v: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// Command returns the program and arguments running the UDF with the agent installed in dir,
+// and the PYTHONPATH the agent is found with given the current PYTHONPATH.
+func Command(dir string, s Spec, pythonPath string) (prog string, args []string, path string) {
+	args = []string{
+		// The protocol requires STDIN and STDOUT to be unbuffered.
+		"-u",
+		filepath.Join(dir, runnerFile),
+		"--protocol-versions", strconv.Itoa(ProtocolVersion),
+	}
+	if s.Handler != "" {
+		args = append(args, "--handler", s.Handler)
+	}
+	args = append(args, s.Script)
+
+	path = dir
+	if pythonPath != "" {
+		path += string(os.PathListSeparator) + pythonPath
+	}
+	return s.python(), args, path
+}
@@ -0,0 +1,154 @@
+package python
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/kapacitor/udf/agent"
+)
+
+const testHandler = `
+from kapacitor.udf.agent import Handler
+from kapacitor.udf import udf_pb2
+
+class MirrorHandler(Handler):
+    def __init__(self, agent):
+        self._agent = agent
+
+    def info(self):
+        response = udf_pb2.Response()
+        response.info.wants = udf_pb2.BATCH
+        response.info.provides = udf_pb2.STREAM
+        return response
+`
+
+func TestCommand(t *testing.T) {
+	s := Spec{
+		Script:     "/udfs/mirror.py",
+		Handler:    "MirrorHandler",
+		Virtualenv: "/venvs/mirror",
+	}
+	prog, args, path := Command("/agent", s, "/lib")
+	if exp := "/venvs/mirror/bin/python"; prog != exp {
+		t.Errorf("unexpected prog: got %s exp %s", prog, exp)
+	}
+	expArgs := []string{"-u", "/agent/kapacitor_udf_runner.py", "--protocol-versions", "1", "--handler", "MirrorHandler", "/udfs/mirror.py"}
+	if !reflect.DeepEqual(args, expArgs) {
+		t.Errorf("unexpected args:\ngot %v\nexp %v", args, expArgs)
+	}
+	if exp := "/agent:/lib"; path != exp {
+		t.Errorf("unexpected PYTHONPATH: got %s exp %s", path, exp)
+	}
+
+	prog, _, path = Command("/agent", Spec{Script: "/udfs/mirror.py"}, "")
+	if prog != DefaultInterpreter {
+		t.Errorf("unexpected prog: got %s exp %s", prog, DefaultInterpreter)
+	}
+	if exp := "/agent"; path != exp {
+		t.Errorf("unexpected PYTHONPATH: got %s exp %s", path, exp)
+	}
+}
+
+func TestInstall(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kapacitor_python_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := Install(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{runnerFile, "kapacitor/udf/agent.py", "kapacitor/udf/udf_pb2.py"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("bundled file %s was not installed: %v", name, err)
+		}
+	}
+}
+
+// startRunner runs the handler script with the bundled agent using the python3 interpreter.
+func startRunner(t *testing.T, script string) (*exec.Cmd, string) {
+	if _, err := exec.LookPath(DefaultInterpreter); err != nil {
+		t.Skip("python3 is not installed")
+	}
+	dir, err := ioutil.TempDir("", "kapacitor_python_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Install(dir); err != nil {
+		t.Fatal(err)
+	}
+	scriptPath := filepath.Join(dir, "handler.py")
+	if err := ioutil.WriteFile(scriptPath, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prog, args, path := Command(dir, Spec{Script: scriptPath}, "")
+	cmd := exec.Command(prog, args...)
+	cmd.Env = append(os.Environ(), "PYTHONPATH="+path)
+	return cmd, dir
+}
+
+func hasProtobuf() bool {
+	return exec.Command(DefaultInterpreter, "-c", "import google.protobuf").Run() == nil
+}
+
+func TestRunner_MissingProtobuf(t *testing.T) {
+	cmd, dir := startRunner(t, testHandler)
+	defer os.RemoveAll(dir)
+	if hasProtobuf() {
+		t.Skip("protobuf is installed")
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatal("expected runner to fail")
+	}
+	if exp := "the protobuf package is not installed"; !strings.Contains(string(out), exp) {
+		t.Errorf("unexpected output, expected it to contain %q:\n%s", exp, out)
+	}
+}
+
+func TestRunner_Info(t *testing.T) {
+	cmd, dir := startRunner(t, testHandler)
+	defer os.RemoveAll(dir)
+	if !hasProtobuf() {
+		t.Skip("protobuf is not installed")
+	}
+
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+	defer in.Close()
+
+	req := &agent.Request{Message: &agent.Request_Info{Info: &agent.InfoRequest{}}}
+	if err := agent.WriteMessage(req, in); err != nil {
+		t.Fatal(err)
+	}
+	var buf []byte
+	res := &agent.Response{}
+	if err := agent.ReadMessage(&buf, bufio.NewReader(out), res); err != nil {
+		t.Fatal(err)
+	}
+	info := res.GetInfo()
+	if info == nil {
+		t.Fatalf("unexpected response %v", res)
+	}
+	if info.Wants != agent.EdgeType_BATCH || info.Provides != agent.EdgeType_STREAM {
+		t.Errorf("unexpected info %v", info)
+	}
+}
@@ -0,0 +1,106 @@
+# Runs a Python UDF handler with the Kapacitor UDF agent bundled with kapacitord.
+#
+# The script defines a subclass of kapacitor.udf.agent.Handler whose constructor takes the agent,
+# the runner creates the agent and the handler and serves the requests of Kapacitor over STDIN and STDOUT.
+#
+# A script may declare the versions of the UDF protocol it supports with a PROTOCOL_VERSIONS list,
+# the highest version supported by Kapacitor, the agent and the script is used.
+#
+# Usage:
+#   python -u kapacitor_udf_runner.py --protocol-versions 1 [--handler NAME] SCRIPT
+
+import argparse
+import inspect
+import logging
+import os
+import sys
+
+logging.basicConfig(level=logging.INFO, format='%(asctime)s %(levelname)s:%(name)s: %(message)s')
+logger = logging.getLogger('kapacitor_udf_runner')
+
+# Exit code of errors setting up the UDF.
+EXIT_SETUP = 2
+
+
+def fail(msg, *args):
+    logger.error(msg, *args)
+    sys.exit(EXIT_SETUP)
+
+
+def load_script(path):
+    name = os.path.splitext(os.path.basename(path))[0]
+    sys.path.insert(0, os.path.dirname(os.path.abspath(path)))
+    try:
+        import importlib.util
+    except ImportError:
+        import imp
+        return imp.load_source(name, path)
+    spec = importlib.util.spec_from_file_location(name, path)
+    module = importlib.util.module_from_spec(spec)
+    sys.modules[name] = module
+    spec.loader.exec_module(module)
+    return module
+
+
+def negotiate(kapacitor_versions, agent_version, script_versions):
+    common = [v for v in kapacitor_versions if v <= agent_version]
+    if script_versions is not None:
+        common = [v for v in common if v in script_versions]
+    if not common:
+        return None
+    return max(common)
+
+
+def find_handler(module, base, name):
+    if name:
+        handler = getattr(module, name, None)
+        if handler is None:
+            fail("script does not define handler %s", name)
+        return handler
+    handlers = [
+        cls for _, cls in inspect.getmembers(module, inspect.isclass)
+        if issubclass(cls, base) and cls is not base and cls.__module__ == module.__name__
+    ]
+    if len(handlers) != 1:
+        fail("script must define exactly one Handler subclass, found %d, set the handler to choose one", len(handlers))
+    return handlers[0]
+
+
+def main():
+    parser = argparse.ArgumentParser(description='Run a Kapacitor UDF handler.')
+    parser.add_argument('--protocol-versions', required=True,
+                        help='comma separated versions of the UDF protocol supported by Kapacitor')
+    parser.add_argument('--handler', default='', help='name of the Handler subclass to run')
+    parser.add_argument('script', help='path of the script defining the handler')
+    args = parser.parse_args()
+
+    try:
+        import google.protobuf
+    except ImportError:
+        fail("the protobuf package is not installed for %s, add it to the requirements of the UDF", sys.executable)
+    from kapacitor.udf import agent
+
+    # Anything the script prints must not corrupt the responses written to STDOUT.
+    out = getattr(sys.stdout, 'buffer', sys.stdout)
+    sys.stdout = sys.stderr
+
+    module = load_script(args.script)
+
+    kapacitor_versions = [int(v) for v in args.protocol_versions.split(',')]
+    script_versions = getattr(module, 'PROTOCOL_VERSIONS', None)
+    version = negotiate(kapacitor_versions, agent.PROTOCOL_VERSION, script_versions)
+    if version is None:
+        fail("no common UDF protocol version, kapacitor supports %s, the agent %d and the script %s",
+             kapacitor_versions, agent.PROTOCOL_VERSION, script_versions)
+    logger.info("using UDF protocol version %d", version)
+
+    handler = find_handler(module, agent.Handler, args.handler)
+    a = agent.Agent(out=out)
+    a.handler = handler(a)
+
+    a.start()
+    a.wait()
+
+
+if __name__ == '__main__':
+    main()
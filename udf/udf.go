@@ -1,6 +1,8 @@
 package udf
 
 import (
+	"time"
+
 	"github.com/influxdata/kapacitor/edge"
 	"github.com/influxdata/kapacitor/udf/agent"
 )
@@ -19,3 +21,15 @@ type Interface interface {
 	In() chan<- edge.Message
 	Out() <-chan edge.Message
 }
+
+// RestartPolicy controls how a UDF that failed while a task is running is restarted.
+type RestartPolicy struct {
+	// Maximum number of times the UDF is restarted while the task runs.
+	// If zero the task fails with the UDF.
+	Attempts int
+	// How long to wait before restarting the UDF.
+	Delay time.Duration
+	// How often to snapshot the state of the UDF, a restarted UDF is restored from the last snapshot.
+	// If zero only the snapshots of the task are used.
+	SnapshotInterval time.Duration
+}
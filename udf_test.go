@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"io/ioutil"
+	"net"
 	"reflect"
 	"testing"
 	"time"
@@ -16,6 +17,8 @@ import (
 	"github.com/influxdata/kapacitor/udf"
 	"github.com/influxdata/kapacitor/udf/agent"
 	udf_test "github.com/influxdata/kapacitor/udf/test"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 var diagService *diagnostic.Service
@@ -138,6 +141,93 @@ func testUDF_WritePoint(u udf.Interface, uio *udf_test.IO, t *testing.T) {
 	}
 }
 
+func TestUDFGRPC_WritePoint(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mdC := make(chan metadata.MD, 1)
+	srv := grpc.NewServer()
+	agent.RegisterUDFServer(srv, agent.NewGRPCServer(func(a *agent.Agent, md metadata.MD) agent.Handler {
+		mdC <- md
+		return &echoHandler{agent: a}
+	}))
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	d := kapacitorDiag.WithNodeContext("WritePoint")
+	u := kapacitor.NewUDFSocket("WritePoint", "testNode", kapacitor.NewGRPCConn(conn, "WritePoint", "testNode"), d, 0, nil)
+	if err := u.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Init(nil); err != nil {
+		t.Fatal(err)
+	}
+	md := <-mdC
+	if got, exp := md["task"], []string{"WritePoint"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected task metadata got %v exp %v", got, exp)
+	}
+	if got, exp := md["node"], []string{"testNode"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected node metadata got %v exp %v", got, exp)
+	}
+
+	p := edge.NewPointMessage(
+		"test",
+		"db",
+		"rp",
+		models.Dimensions{},
+		models.Fields{"f1": 1.0, "f2": 2.0},
+		models.Tags{"t1": "v1", "t2": "v2"},
+		time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC),
+	)
+	u.In() <- p
+	rp := <-u.Out()
+	if !reflect.DeepEqual(rp, p) {
+		t.Errorf("unexpected returned point got: %v exp %v", rp, p)
+	}
+
+	if err := u.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+// echoHandler is an agent handler that sends every point back to Kapacitor.
+type echoHandler struct {
+	agent *agent.Agent
+}
+
+func (h *echoHandler) Info() (*agent.InfoResponse, error) {
+	return &agent.InfoResponse{Wants: agent.EdgeType_STREAM, Provides: agent.EdgeType_STREAM}, nil
+}
+func (h *echoHandler) Init(*agent.InitRequest) (*agent.InitResponse, error) {
+	return &agent.InitResponse{Success: true}, nil
+}
+func (h *echoHandler) Snapshot() (*agent.SnapshotResponse, error) {
+	return &agent.SnapshotResponse{}, nil
+}
+func (h *echoHandler) Restore(*agent.RestoreRequest) (*agent.RestoreResponse, error) {
+	return &agent.RestoreResponse{Success: true}, nil
+}
+func (h *echoHandler) BeginBatch(*agent.BeginBatch) error { return nil }
+func (h *echoHandler) Point(p *agent.Point) error {
+	h.agent.Responses <- &agent.Response{
+		Message: &agent.Response_Point{
+			Point: p,
+		},
+	}
+	return nil
+}
+func (h *echoHandler) EndBatch(*agent.EndBatch) error { return nil }
+func (h *echoHandler) Stop() {
+	close(h.agent.Responses)
+}
+
 func TestUDFSocket_WriteBatch(t *testing.T) {
 	u, uio := newUDFSocket("WriteBatch")
 	testUDF_WriteBatch(u, uio, t)
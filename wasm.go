@@ -0,0 +1,204 @@
+package kapacitor
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/wasm"
+	"github.com/pkg/errors"
+)
+
+const (
+	statsWasmErrors = "wasm_errors"
+)
+
+type WasmNode struct {
+	node
+	w *pipeline.WasmNode
+	f *wasm.Func
+
+	args   []uint64
+	errors *expvar.Int
+}
+
+// Create a new WasmNode which transforms each point with a WebAssembly function.
+func newWasmNode(et *ExecutingTask, n *pipeline.WasmNode, d NodeDiagnostic) (*WasmNode, error) {
+	m, err := n.Compile()
+	if err != nil {
+		return nil, err
+	}
+	typ := m.Exports()[n.Function]
+	inst, err := m.Instantiate(wasm.Config{
+		MaxMemoryPages:  uint32(n.MaxMemoryPages),
+		MaxInstructions: n.MaxInstructions,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to instantiate wasm module")
+	}
+	f, err := inst.Func(n.Function)
+	if err != nil {
+		return nil, err
+	}
+	wn := &WasmNode{
+		node: node{Node: n, et: et, diag: d},
+		w:    n,
+		f:    f,
+		args: make([]uint64, len(typ.Params)),
+	}
+	wn.node.runF = wn.runWasm
+	return wn, nil
+}
+
+func (n *WasmNode) runWasm([]byte) error {
+	n.errors = &expvar.Int{}
+	n.statMap.Set(statsWasmErrors, n.errors)
+
+	consumer := edge.NewGroupedConsumer(
+		n.ins[0],
+		n,
+	)
+	n.statMap.Set(statCardinalityGauge, consumer.CardinalityVar())
+
+	return consumer.Consume()
+}
+
+func (n *WasmNode) NewGroup(group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error) {
+	return edge.NewReceiverFromForwardReceiverWithStats(
+		n.outs,
+		edge.NewTimedForwardReceiver(n.timer, n),
+	), nil
+}
+
+// call calls the function with the fields of p and sets its results as fields of p.
+func (n *WasmNode) call(p edge.FieldsTagsTimeSetter) error {
+	fields := p.Fields()
+	typ := n.f.Type()
+	for i, name := range n.w.FieldsList {
+		v, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("field %q does not exist", name)
+		}
+		arg, err := wasmArg(typ.Params[i], v)
+		if err != nil {
+			return errors.Wrapf(err, "field %q", name)
+		}
+		n.args[i] = arg
+	}
+	results, err := n.f.Call(n.args...)
+	if err != nil {
+		return err
+	}
+	newFields := make(models.Fields, len(fields)+len(results))
+	for f, v := range fields {
+		newFields[f] = v
+	}
+	for i, r := range results {
+		newFields[n.w.AsList[i]] = wasmResult(typ.Results[i], r)
+	}
+	p.SetFields(newFields)
+	return nil
+}
+
+// wasmArg converts a field value into the bits of an argument of type t.
+func wasmArg(t wasm.ValueType, v interface{}) (uint64, error) {
+	switch t {
+	case wasm.I32, wasm.I64:
+		var i int64
+		switch v := v.(type) {
+		case int64:
+			i = v
+		case bool:
+			if v {
+				i = 1
+			}
+		default:
+			return 0, fmt.Errorf("cannot convert %T to %v", v, t)
+		}
+		if t == wasm.I32 {
+			if i < math.MinInt32 || i > math.MaxInt32 {
+				return 0, fmt.Errorf("value %d overflows %v", i, t)
+			}
+			return uint64(uint32(int32(i))), nil
+		}
+		return uint64(i), nil
+	case wasm.F32, wasm.F64:
+		var f float64
+		switch v := v.(type) {
+		case float64:
+			f = v
+		case int64:
+			f = float64(v)
+		default:
+			return 0, fmt.Errorf("cannot convert %T to %v", v, t)
+		}
+		if t == wasm.F32 {
+			return uint64(math.Float32bits(float32(f))), nil
+		}
+		return math.Float64bits(f), nil
+	}
+	return 0, fmt.Errorf("unsupported type %v", t)
+}
+
+// wasmResult converts the bits of a result of type t into a field value.
+func wasmResult(t wasm.ValueType, r uint64) interface{} {
+	switch t {
+	case wasm.I32:
+		return int64(int32(r))
+	case wasm.I64:
+		return int64(r)
+	case wasm.F32:
+		return float64(math.Float32frombits(uint32(r)))
+	default:
+		return math.Float64frombits(r)
+	}
+}
+
+func (n *WasmNode) doCall(p edge.FieldsTagsTimeSetter) bool {
+	if err := n.call(p); err != nil {
+		n.errors.Add(1)
+		if !n.w.QuietFlag {
+			n.diag.Error("error calling wasm function", err)
+		}
+		// Skip bad point
+		return false
+	}
+	return true
+}
+
+func (n *WasmNode) BeginBatch(begin edge.BeginBatchMessage) (edge.Message, error) {
+	begin = begin.ShallowCopy()
+	begin.SetSizeHint(0)
+	return begin, nil
+}
+
+func (n *WasmNode) BatchPoint(bp edge.BatchPointMessage) (edge.Message, error) {
+	bp = bp.ShallowCopy()
+	if n.doCall(bp) {
+		return bp, nil
+	}
+	return nil, nil
+}
+
+func (n *WasmNode) EndBatch(end edge.EndBatchMessage) (edge.Message, error) {
+	return end, nil
+}
+
+func (n *WasmNode) Point(p edge.PointMessage) (edge.Message, error) {
+	p = p.ShallowCopy()
+	if n.doCall(p) {
+		return p, nil
+	}
+	return nil, nil
+}
+
+func (n *WasmNode) Barrier(b edge.BarrierMessage) (edge.Message, error) {
+	return b, nil
+}
+func (n *WasmNode) DeleteGroup(d edge.DeleteGroupMessage) (edge.Message, error) {
+	return d, nil
+}
+func (n *WasmNode) Done() {}
@@ -0,0 +1,292 @@
+package wasm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Opcodes of the instructions, instructions with the 0xfc prefix are stored as 0xfc00 | sub opcode.
+const (
+	opUnreachable  = 0x00
+	opNop          = 0x01
+	opBlock        = 0x02
+	opLoop         = 0x03
+	opIf           = 0x04
+	opElse         = 0x05
+	opEnd          = 0x0b
+	opBr           = 0x0c
+	opBrIf         = 0x0d
+	opBrTable      = 0x0e
+	opReturn       = 0x0f
+	opCall         = 0x10
+	opCallIndirect = 0x11
+
+	opDrop       = 0x1a
+	opSelect     = 0x1b
+	opSelectType = 0x1c
+
+	opLocalGet  = 0x20
+	opLocalSet  = 0x21
+	opLocalTee  = 0x22
+	opGlobalGet = 0x23
+	opGlobalSet = 0x24
+
+	opI32Load    = 0x28
+	opI64Load    = 0x29
+	opF32Load    = 0x2a
+	opF64Load    = 0x2b
+	opI32Load8S  = 0x2c
+	opI32Load8U  = 0x2d
+	opI32Load16S = 0x2e
+	opI32Load16U = 0x2f
+	opI64Load8S  = 0x30
+	opI64Load8U  = 0x31
+	opI64Load16S = 0x32
+	opI64Load16U = 0x33
+	opI64Load32S = 0x34
+	opI64Load32U = 0x35
+	opI32Store   = 0x36
+	opI64Store   = 0x37
+	opF32Store   = 0x38
+	opF64Store   = 0x39
+	opI32Store8  = 0x3a
+	opI32Store16 = 0x3b
+	opI64Store8  = 0x3c
+	opI64Store16 = 0x3d
+	opI64Store32 = 0x3e
+	opMemorySize = 0x3f
+	opMemoryGrow = 0x40
+
+	opI32Const = 0x41
+	opI64Const = 0x42
+	opF32Const = 0x43
+	opF64Const = 0x44
+
+	opI32Eqz = 0x45
+	opI32GeU = 0x4f
+	opI64Eqz = 0x50
+	opI64GeU = 0x5a
+	opF32Eq  = 0x5b
+	opF64Ge  = 0x66
+
+	opI32Clz      = 0x67
+	opI64Rotr     = 0x8a
+	opF32Abs      = 0x8b
+	opF64Copysign = 0xa6
+
+	opI32WrapI64        = 0xa7
+	opF64ReinterpretI64 = 0xbf
+	opI32Extend8S       = 0xc0
+	opI64Extend32S      = 0xc4
+
+	opPrefix        = 0xfc
+	opTruncSatFirst = opPrefix<<8 | 0
+	opTruncSatLast  = opPrefix<<8 | 7
+	opMemoryInit    = opPrefix<<8 | 8
+	opDataDrop      = opPrefix<<8 | 9
+	opMemoryCopy    = opPrefix<<8 | 10
+	opMemoryFill    = opPrefix<<8 | 11
+)
+
+// instr is a decoded instruction.
+type instr struct {
+	op uint16
+	// imm is the immediate of the instruction:
+	// an index, a label depth, the bits of a constant, a memory offset or the block type of a block, loop or if.
+	imm uint64
+
+	// Number of parameters and results of a block, loop or if.
+	params  int
+	results int
+	// Index of the end instruction of a block, loop or if and of the else instruction of an if,
+	// -1 if the if has no else.
+	end int
+	els int
+
+	// Label depths of br_table, the last one is the default.
+	labels []uint32
+}
+
+// code decodes the instructions of a function body matching the ends of blocks.
+func (r *reader) code(m *Module) ([]instr, error) {
+	var code []instr
+	var blocks []int
+	for {
+		in, err := r.instr(m)
+		if err != nil {
+			return nil, err
+		}
+		pc := len(code)
+		switch in.op {
+		case opBlock, opLoop, opIf:
+			in.els = -1
+			blocks = append(blocks, pc)
+		case opElse:
+			if len(blocks) == 0 || code[blocks[len(blocks)-1]].op != opIf || code[blocks[len(blocks)-1]].els != -1 {
+				return nil, errors.New("else without if")
+			}
+			code[blocks[len(blocks)-1]].els = pc
+		case opEnd:
+			if len(blocks) == 0 {
+				code = append(code, in)
+				if r.len() != 0 {
+					return nil, errors.New("instructions after the end of the function")
+				}
+				return code, nil
+			}
+			code[blocks[len(blocks)-1]].end = pc
+			blocks = blocks[:len(blocks)-1]
+		}
+		code = append(code, in)
+	}
+}
+
+// Block types are encoded as the empty block type, a value type or typeIndexFlag | the index of a function type.
+const (
+	emptyBlockType = 0x40
+	typeIndexFlag  = 1 << 32
+)
+
+func (r *reader) blockType(m *Module) (params, results int, typ uint64, err error) {
+	if r.pos >= len(r.b) {
+		return 0, 0, 0, errUnexpectedEnd
+	}
+	switch b := r.b[r.pos]; {
+	case b == emptyBlockType:
+		r.pos++
+		return 0, 0, emptyBlockType, nil
+	case ValueType(b) == I32 || ValueType(b) == I64 || ValueType(b) == F32 || ValueType(b) == F64:
+		r.pos++
+		return 0, 1, uint64(b), nil
+	}
+	idx, err := r.sleb(33)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if m == nil || idx < 0 || idx >= int64(len(m.types)) {
+		return 0, 0, 0, fmt.Errorf("invalid block type index %d", idx)
+	}
+	t := m.types[idx]
+	return len(t.Params), len(t.Results), typeIndexFlag | uint64(idx), nil
+}
+
+// blockFuncType returns the signature of the block type of a block, loop or if.
+func (m *Module) blockFuncType(in instr) FuncType {
+	switch {
+	case in.imm == emptyBlockType:
+		return FuncType{}
+	case in.imm&typeIndexFlag != 0:
+		return m.types[in.imm&^typeIndexFlag]
+	}
+	return FuncType{Results: []ValueType{ValueType(in.imm)}}
+}
+
+// instr decodes a single instruction.
+func (r *reader) instr(m *Module) (instr, error) {
+	b, err := r.byte()
+	if err != nil {
+		return instr{}, err
+	}
+	in := instr{op: uint16(b)}
+	switch {
+	case b == opBlock || b == opLoop || b == opIf:
+		in.params, in.results, in.imm, err = r.blockType(m)
+	case b == opBr || b == opBrIf || b == opCall || b == opLocalGet || b == opLocalSet ||
+		b == opLocalTee || b == opGlobalGet || b == opGlobalSet:
+		var v uint32
+		v, err = r.u32()
+		in.imm = uint64(v)
+	case b == opBrTable:
+		in.labels, err = r.u32s()
+		if err == nil {
+			var def uint32
+			def, err = r.u32()
+			in.labels = append(in.labels, def)
+		}
+	case b == opCallIndirect:
+		var typ uint32
+		typ, err = r.u32()
+		in.imm = uint64(typ)
+		if err == nil {
+			err = r.zero()
+		}
+	case b == opSelectType:
+		_, err = r.valueTypes()
+		in.op = opSelect
+	case b >= opI32Load && b <= opI64Store32:
+		// The alignment is only a hint.
+		if _, err = r.u32(); err == nil {
+			var offset uint32
+			offset, err = r.u32()
+			in.imm = uint64(offset)
+		}
+	case b == opMemorySize || b == opMemoryGrow:
+		err = r.zero()
+	case b == opI32Const:
+		var v int64
+		v, err = r.sleb(32)
+		in.imm = uint64(uint32(int32(v)))
+	case b == opI64Const:
+		var v int64
+		v, err = r.sleb(64)
+		in.imm = uint64(v)
+	case b == opF32Const:
+		var v float32
+		v, err = r.f32()
+		in.imm = uint64(math.Float32bits(v))
+	case b == opF64Const:
+		var v float64
+		v, err = r.f64()
+		in.imm = math.Float64bits(v)
+	case b == opPrefix:
+		var sub uint32
+		sub, err = r.u32()
+		if err != nil {
+			break
+		}
+		in.op = opPrefix<<8 | uint16(sub)
+		switch in.op {
+		case opMemoryInit:
+			var idx uint32
+			idx, err = r.u32()
+			in.imm = uint64(idx)
+			if err == nil {
+				err = r.zero()
+			}
+		case opDataDrop:
+			var idx uint32
+			idx, err = r.u32()
+			in.imm = uint64(idx)
+		case opMemoryCopy:
+			if err = r.zero(); err == nil {
+				err = r.zero()
+			}
+		case opMemoryFill:
+			err = r.zero()
+		default:
+			if in.op < opTruncSatFirst || in.op > opTruncSatLast {
+				err = fmt.Errorf("unsupported instruction 0xfc %d", sub)
+			}
+		}
+	case b == opUnreachable || b == opNop || b == opElse || b == opEnd || b == opReturn ||
+		b == opDrop || b == opSelect ||
+		b >= opI32Eqz && b <= opI64Extend32S:
+		// No immediates
+	default:
+		err = fmt.Errorf("unsupported instruction 0x%x", b)
+	}
+	return in, err
+}
+
+// zero reads the reserved zero byte of memory and table instructions.
+func (r *reader) zero() error {
+	b, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if b != 0 {
+		return fmt.Errorf("expected zero byte, got 0x%x", b)
+	}
+	return nil
+}
@@ -0,0 +1,898 @@
+package wasm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"runtime"
+)
+
+// Maximum depth of nested calls, deeper calls trap.
+const maxCallDepth = 1000
+
+// Limits of an instance if the config does not set them.
+const (
+	// DefaultMaxMemoryPages is the maximum number of 64KiB pages of memory, i.e. 1MiB.
+	DefaultMaxMemoryPages = 16
+	// DefaultMaxTableElements is the maximum number of elements of the table.
+	DefaultMaxTableElements = 65536
+	// DefaultMaxInstructions is the maximum number of instructions executed by a call.
+	DefaultMaxInstructions = 1000000
+)
+
+// Config limits the resources an instance may use.
+// The resources are always limited, the defaults apply to the limits left zero.
+type Config struct {
+	// Maximum number of 64KiB pages of memory.
+	// The maximum declared by the module applies if it is lower.
+	// If zero DefaultMaxMemoryPages applies.
+	MaxMemoryPages uint32
+	// Maximum number of elements of the table.
+	// If zero DefaultMaxTableElements applies.
+	MaxTableElements uint32
+	// Maximum number of instructions executed by a call of an exported function.
+	// If zero DefaultMaxInstructions applies.
+	MaxInstructions int64
+}
+
+// withDefaults returns the config with the defaults of the limits left zero.
+func (c Config) withDefaults() Config {
+	if c.MaxMemoryPages == 0 {
+		c.MaxMemoryPages = DefaultMaxMemoryPages
+	}
+	if c.MaxTableElements == 0 {
+		c.MaxTableElements = DefaultMaxTableElements
+	}
+	if c.MaxInstructions == 0 {
+		c.MaxInstructions = DefaultMaxInstructions
+	}
+	return c
+}
+
+// Trap is the error returned when the execution of a module traps.
+type Trap struct {
+	Reason string
+}
+
+func (t *Trap) Error() string {
+	return "wasm trap: " + t.Reason
+}
+
+func trap(format string, args ...interface{}) {
+	panic(&Trap{Reason: fmt.Sprintf(format, args...)})
+}
+
+// Instance is an instantiated module with its own memory, globals and table.
+// An Instance is not safe for concurrent use.
+type Instance struct {
+	m        *Module
+	config   Config
+	memory   []byte
+	maxPages uint32
+	globals  []uint64
+	table    []int64
+	dropped  []bool
+
+	fuel  int64
+	depth int
+}
+
+// Instantiate creates a new instance of the module, running its start function if any.
+func (m *Module) Instantiate(c Config) (inst *Instance, err error) {
+	c = c.withDefaults()
+	i := &Instance{
+		m:       m,
+		config:  c,
+		globals: make([]uint64, len(m.globals)),
+		dropped: make([]bool, len(m.data)),
+	}
+	if m.memory != nil {
+		if m.memory.min > c.MaxMemoryPages {
+			return nil, fmt.Errorf("module requires %d memory pages, the limit is %d", m.memory.min, c.MaxMemoryPages)
+		}
+		i.memory = make([]byte, int(m.memory.min)*pageSize)
+		i.maxPages = c.MaxMemoryPages
+		if m.memory.hasMax && m.memory.max < i.maxPages {
+			i.maxPages = m.memory.max
+		}
+	}
+	for g := range m.globals {
+		i.globals[g] = i.constValue(m.globals[g].init)
+	}
+	if m.table != nil {
+		if m.table.min > c.MaxTableElements {
+			return nil, fmt.Errorf("module requires %d table elements, the limit is %d", m.table.min, c.MaxTableElements)
+		}
+		i.table = make([]int64, m.table.min)
+		for t := range i.table {
+			i.table[t] = -1
+		}
+	}
+	for s, e := range m.elems {
+		offset := uint64(uint32(i.constValue(e.offset)))
+		if offset+uint64(len(e.funcs)) > uint64(len(i.table)) {
+			return nil, fmt.Errorf("element segment %d does not fit in the table", s)
+		}
+		for j, f := range e.funcs {
+			i.table[offset+uint64(j)] = int64(f)
+		}
+	}
+	for s, d := range m.data {
+		if d.passive {
+			continue
+		}
+		offset := uint64(uint32(i.constValue(d.offset)))
+		if offset+uint64(len(d.data)) > uint64(len(i.memory)) {
+			return nil, fmt.Errorf("data segment %d does not fit in memory", s)
+		}
+		copy(i.memory[offset:], d.data)
+	}
+	if m.start != nil {
+		if _, err := i.invoke(*m.start, nil); err != nil {
+			return nil, err
+		}
+	}
+	return i, nil
+}
+
+func (i *Instance) constValue(code []instr) uint64 {
+	in := code[0]
+	if in.op == opGlobalGet {
+		return i.globals[in.imm]
+	}
+	return in.imm
+}
+
+// Memory returns the memory of the instance.
+func (i *Instance) Memory() []byte {
+	return i.memory
+}
+
+// Func is an exported function of an instance.
+type Func struct {
+	inst  *Instance
+	index uint32
+	typ   FuncType
+}
+
+// Func returns the exported function with the given name.
+func (i *Instance) Func(name string) (*Func, error) {
+	e, ok := i.m.exports[name]
+	if !ok || e.kind != exportFunc {
+		return nil, fmt.Errorf("module does not export a function named %q", name)
+	}
+	return &Func{
+		inst:  i,
+		index: e.index,
+		typ:   i.m.types[i.m.funcs[e.index].typ],
+	}, nil
+}
+
+// Type returns the signature of the function.
+func (f *Func) Type() FuncType {
+	return f.typ
+}
+
+// Call calls the function with the raw bits of its arguments and returns the raw bits of its results.
+// Values of type i32 and f32 use the low 32 bits.
+// A *Trap is returned if the execution traps.
+func (f *Func) Call(args ...uint64) ([]uint64, error) {
+	if len(args) != len(f.typ.Params) {
+		return nil, fmt.Errorf("function expects %d arguments, got %d", len(f.typ.Params), len(args))
+	}
+	return f.inst.invoke(f.index, args)
+}
+
+func (i *Instance) invoke(index uint32, args []uint64) (results []uint64, err error) {
+	i.fuel = i.config.MaxInstructions
+	i.depth = 0
+	defer func() {
+		if r := recover(); r != nil {
+			switch r := r.(type) {
+			case *Trap:
+				err = r
+			case runtime.Error:
+				// A bug of the interpreter, the code of the modules is validated when they are compiled.
+				err = &Trap{Reason: r.Error()}
+			default:
+				panic(r)
+			}
+		}
+	}()
+	return i.call(index, args), nil
+}
+
+func (i *Instance) call(index uint32, args []uint64) []uint64 {
+	f := &i.m.funcs[index]
+	if f.imported {
+		trap("call to imported function %s", f.name)
+	}
+	i.depth++
+	if i.depth > maxCallDepth {
+		trap("call stack exhausted")
+	}
+	typ := i.m.types[f.typ]
+	locals := make([]uint64, len(typ.Params)+len(f.locals))
+	copy(locals, args)
+	results := i.exec(f, typ, locals)
+	i.depth--
+	return results
+}
+
+type label struct {
+	// Height of the stack when the block was entered, excluding its parameters.
+	height int
+	// Number of values kept when branching to the label.
+	arity int
+	// Instruction to continue at when branching to the label.
+	cont int
+	loop bool
+}
+
+func (i *Instance) exec(f *function, typ FuncType, locals []uint64) []uint64 {
+	code := f.code
+	stack := make([]uint64, 0, 16)
+	var labels []label
+
+	pop := func() uint64 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v
+	}
+	ret := func() []uint64 {
+		results := make([]uint64, len(typ.Results))
+		copy(results, stack[len(stack)-len(results):])
+		return results
+	}
+	// branch returns false if the branch targets the function body, i.e. returns.
+	pc := 0
+	branch := func(depth int) bool {
+		if depth >= len(labels) {
+			return false
+		}
+		l := labels[len(labels)-1-depth]
+		copy(stack[l.height:], stack[len(stack)-l.arity:])
+		stack = stack[:l.height+l.arity]
+		if l.loop {
+			labels = labels[:len(labels)-depth]
+		} else {
+			labels = labels[:len(labels)-1-depth]
+		}
+		pc = l.cont
+		return true
+	}
+
+	for {
+		i.fuel--
+		if i.fuel < 0 {
+			trap("instruction limit of %d exceeded", i.config.MaxInstructions)
+		}
+		in := &code[pc]
+		pc++
+		switch op := in.op; {
+		case op == opUnreachable:
+			trap("unreachable executed")
+		case op == opNop:
+		case op == opBlock:
+			labels = append(labels, label{height: len(stack) - in.params, arity: in.results, cont: in.end + 1})
+		case op == opLoop:
+			labels = append(labels, label{height: len(stack) - in.params, arity: in.params, cont: pc, loop: true})
+		case op == opIf:
+			c := uint32(pop())
+			l := label{height: len(stack) - in.params, arity: in.results, cont: in.end + 1}
+			if c != 0 {
+				labels = append(labels, l)
+			} else if in.els >= 0 {
+				labels = append(labels, l)
+				pc = in.els + 1
+			} else {
+				pc = in.end + 1
+			}
+		case op == opElse:
+			// The end of the then branch, skip the else branch.
+			pc = labels[len(labels)-1].cont
+			labels = labels[:len(labels)-1]
+		case op == opEnd:
+			if len(labels) == 0 {
+				return ret()
+			}
+			labels = labels[:len(labels)-1]
+		case op == opBr:
+			if !branch(int(in.imm)) {
+				return ret()
+			}
+		case op == opBrIf:
+			if uint32(pop()) != 0 && !branch(int(in.imm)) {
+				return ret()
+			}
+		case op == opBrTable:
+			idx := uint64(uint32(pop()))
+			if idx >= uint64(len(in.labels)-1) {
+				idx = uint64(len(in.labels) - 1)
+			}
+			if !branch(int(in.labels[idx])) {
+				return ret()
+			}
+		case op == opReturn:
+			return ret()
+		case op == opCall:
+			stack = i.callFromStack(uint32(in.imm), stack)
+		case op == opCallIndirect:
+			elem := uint64(uint32(pop()))
+			if elem >= uint64(len(i.table)) {
+				trap("undefined table element %d", elem)
+			}
+			index := i.table[elem]
+			if index < 0 {
+				trap("uninitialized table element %d", elem)
+			}
+			if !i.m.types[i.m.funcs[index].typ].equal(i.m.types[in.imm]) {
+				trap("indirect call signature mismatch")
+			}
+			stack = i.callFromStack(uint32(index), stack)
+		case op == opDrop:
+			stack = stack[:len(stack)-1]
+		case op == opSelect:
+			c := uint32(pop())
+			b := pop()
+			if c == 0 {
+				stack[len(stack)-1] = b
+			}
+		case op == opLocalGet:
+			stack = append(stack, locals[in.imm])
+		case op == opLocalSet:
+			locals[in.imm] = pop()
+		case op == opLocalTee:
+			locals[in.imm] = stack[len(stack)-1]
+		case op == opGlobalGet:
+			stack = append(stack, i.globals[in.imm])
+		case op == opGlobalSet:
+			i.globals[in.imm] = pop()
+		case op >= opI32Load && op <= opI64Load32U:
+			addr := uint64(uint32(pop())) + in.imm
+			stack = append(stack, i.load(op, addr))
+		case op >= opI32Store && op <= opI64Store32:
+			v := pop()
+			addr := uint64(uint32(pop())) + in.imm
+			i.store(op, addr, v)
+		case op == opMemorySize:
+			stack = append(stack, uint64(len(i.memory)/pageSize))
+		case op == opMemoryGrow:
+			n := uint32(pop())
+			stack = append(stack, uint64(uint32(i.grow(n))))
+		case op == opI32Const || op == opI64Const || op == opF32Const || op == opF64Const:
+			stack = append(stack, in.imm)
+		case op == opI32Eqz || op == opI64Eqz ||
+			op >= opI32Clz && op <= opI32Clz+2 ||
+			op >= 0x79 && op <= 0x7b ||
+			op >= 0x8b && op <= 0x91 ||
+			op >= 0x99 && op <= 0x9f ||
+			op >= opI32WrapI64 && op <= opI64Extend32S ||
+			op >= opTruncSatFirst && op <= opTruncSatLast:
+			stack[len(stack)-1] = unary(op, stack[len(stack)-1])
+		case op > opI32Eqz && op <= opF64Copysign:
+			b := pop()
+			stack[len(stack)-1] = binaryOp(op, stack[len(stack)-1], b)
+		case op == opMemoryInit:
+			n := uint64(uint32(pop()))
+			src := uint64(uint32(pop()))
+			dst := uint64(uint32(pop()))
+			var data []byte
+			if !i.dropped[in.imm] {
+				data = i.m.data[in.imm].data
+			}
+			if src+n > uint64(len(data)) || dst+n > uint64(len(i.memory)) {
+				trap("out of bounds memory access")
+			}
+			copy(i.memory[dst:], data[src:src+n])
+		case op == opDataDrop:
+			i.dropped[in.imm] = true
+		case op == opMemoryCopy:
+			n := uint64(uint32(pop()))
+			src := uint64(uint32(pop()))
+			dst := uint64(uint32(pop()))
+			if src+n > uint64(len(i.memory)) || dst+n > uint64(len(i.memory)) {
+				trap("out of bounds memory access")
+			}
+			copy(i.memory[dst:dst+n], i.memory[src:src+n])
+		case op == opMemoryFill:
+			n := uint64(uint32(pop()))
+			v := byte(pop())
+			dst := uint64(uint32(pop()))
+			if dst+n > uint64(len(i.memory)) {
+				trap("out of bounds memory access")
+			}
+			for j := dst; j < dst+n; j++ {
+				i.memory[j] = v
+			}
+		default:
+			trap("unsupported instruction 0x%x", op)
+		}
+	}
+}
+
+func (i *Instance) callFromStack(index uint32, stack []uint64) []uint64 {
+	n := len(i.m.types[i.m.funcs[index].typ].Params)
+	args := make([]uint64, n)
+	copy(args, stack[len(stack)-n:])
+	stack = stack[:len(stack)-n]
+	return append(stack, i.call(index, args)...)
+}
+
+func (i *Instance) grow(n uint32) int32 {
+	old := uint32(len(i.memory) / pageSize)
+	if uint64(old)+uint64(n) > uint64(i.maxPages) {
+		return -1
+	}
+	i.memory = append(i.memory, make([]byte, int(n)*pageSize)...)
+	return int32(old)
+}
+
+func (i *Instance) bytes(addr, size uint64) []byte {
+	if addr+size > uint64(len(i.memory)) {
+		trap("out of bounds memory access")
+	}
+	return i.memory[addr : addr+size]
+}
+
+func (i *Instance) load(op uint16, addr uint64) uint64 {
+	switch op {
+	case opI32Load, opF32Load:
+		return uint64(binary.LittleEndian.Uint32(i.bytes(addr, 4)))
+	case opI64Load, opF64Load:
+		return binary.LittleEndian.Uint64(i.bytes(addr, 8))
+	case opI32Load8S:
+		return uint64(uint32(int32(int8(i.bytes(addr, 1)[0]))))
+	case opI32Load8U, opI64Load8U:
+		return uint64(i.bytes(addr, 1)[0])
+	case opI32Load16S:
+		return uint64(uint32(int32(int16(binary.LittleEndian.Uint16(i.bytes(addr, 2))))))
+	case opI32Load16U, opI64Load16U:
+		return uint64(binary.LittleEndian.Uint16(i.bytes(addr, 2)))
+	case opI64Load8S:
+		return uint64(int64(int8(i.bytes(addr, 1)[0])))
+	case opI64Load16S:
+		return uint64(int64(int16(binary.LittleEndian.Uint16(i.bytes(addr, 2)))))
+	case opI64Load32S:
+		return uint64(int64(int32(binary.LittleEndian.Uint32(i.bytes(addr, 4)))))
+	case opI64Load32U:
+		return uint64(binary.LittleEndian.Uint32(i.bytes(addr, 4)))
+	}
+	panic(errors.New("unreachable"))
+}
+
+func (i *Instance) store(op uint16, addr uint64, v uint64) {
+	switch op {
+	case opI32Store, opF32Store, opI64Store32:
+		binary.LittleEndian.PutUint32(i.bytes(addr, 4), uint32(v))
+	case opI64Store, opF64Store:
+		binary.LittleEndian.PutUint64(i.bytes(addr, 8), v)
+	case opI32Store8, opI64Store8:
+		i.bytes(addr, 1)[0] = byte(v)
+	case opI32Store16, opI64Store16:
+		binary.LittleEndian.PutUint16(i.bytes(addr, 2), uint16(v))
+	}
+}
+
+func b2u(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func f32(v uint64) float32 { return math.Float32frombits(uint32(v)) }
+func f64(v uint64) float64 { return math.Float64frombits(v) }
+func u32(f float32) uint64 { return uint64(math.Float32bits(f)) }
+func u64(f float64) uint64 { return math.Float64bits(f) }
+
+// unary executes the instructions with one operand.
+func unary(op uint16, v uint64) uint64 {
+	switch op {
+	case opI32Eqz:
+		return b2u(uint32(v) == 0)
+	case opI64Eqz:
+		return b2u(v == 0)
+	case 0x67: // i32.clz
+		return uint64(bits.LeadingZeros32(uint32(v)))
+	case 0x68: // i32.ctz
+		return uint64(bits.TrailingZeros32(uint32(v)))
+	case 0x69: // i32.popcnt
+		return uint64(bits.OnesCount32(uint32(v)))
+	case 0x79: // i64.clz
+		return uint64(bits.LeadingZeros64(v))
+	case 0x7a: // i64.ctz
+		return uint64(bits.TrailingZeros64(v))
+	case 0x7b: // i64.popcnt
+		return uint64(bits.OnesCount64(v))
+	case 0x8b: // f32.abs
+		return v &^ (1 << 31)
+	case 0x8c: // f32.neg
+		return uint64(uint32(v) ^ (1 << 31))
+	case 0x8d: // f32.ceil
+		return u32(float32(math.Ceil(float64(f32(v)))))
+	case 0x8e: // f32.floor
+		return u32(float32(math.Floor(float64(f32(v)))))
+	case 0x8f: // f32.trunc
+		return u32(float32(math.Trunc(float64(f32(v)))))
+	case 0x90: // f32.nearest
+		return u32(float32(math.RoundToEven(float64(f32(v)))))
+	case 0x91: // f32.sqrt
+		return u32(float32(math.Sqrt(float64(f32(v)))))
+	case 0x99: // f64.abs
+		return v &^ (1 << 63)
+	case 0x9a: // f64.neg
+		return v ^ (1 << 63)
+	case 0x9b: // f64.ceil
+		return u64(math.Ceil(f64(v)))
+	case 0x9c: // f64.floor
+		return u64(math.Floor(f64(v)))
+	case 0x9d: // f64.trunc
+		return u64(math.Trunc(f64(v)))
+	case 0x9e: // f64.nearest
+		return u64(math.RoundToEven(f64(v)))
+	case 0x9f: // f64.sqrt
+		return u64(math.Sqrt(f64(v)))
+	case opI32WrapI64:
+		return uint64(uint32(v))
+	case 0xa8: // i32.trunc_f32_s
+		return uint64(uint32(int32(truncS(float64(f32(v)), 32))))
+	case 0xa9: // i32.trunc_f32_u
+		return uint64(uint32(truncU(float64(f32(v)), 32)))
+	case 0xaa: // i32.trunc_f64_s
+		return uint64(uint32(int32(truncS(f64(v), 32))))
+	case 0xab: // i32.trunc_f64_u
+		return uint64(uint32(truncU(f64(v), 32)))
+	case 0xac: // i64.extend_i32_s
+		return uint64(int64(int32(v)))
+	case 0xad: // i64.extend_i32_u
+		return uint64(uint32(v))
+	case 0xae: // i64.trunc_f32_s
+		return uint64(truncS(float64(f32(v)), 64))
+	case 0xaf: // i64.trunc_f32_u
+		return truncU(float64(f32(v)), 64)
+	case 0xb0: // i64.trunc_f64_s
+		return uint64(truncS(f64(v), 64))
+	case 0xb1: // i64.trunc_f64_u
+		return truncU(f64(v), 64)
+	case 0xb2: // f32.convert_i32_s
+		return u32(float32(int32(v)))
+	case 0xb3: // f32.convert_i32_u
+		return u32(float32(uint32(v)))
+	case 0xb4: // f32.convert_i64_s
+		return u32(float32(int64(v)))
+	case 0xb5: // f32.convert_i64_u
+		return u32(float32(v))
+	case 0xb6: // f32.demote_f64
+		return u32(float32(f64(v)))
+	case 0xb7: // f64.convert_i32_s
+		return u64(float64(int32(v)))
+	case 0xb8: // f64.convert_i32_u
+		return u64(float64(uint32(v)))
+	case 0xb9: // f64.convert_i64_s
+		return u64(float64(int64(v)))
+	case 0xba: // f64.convert_i64_u
+		return u64(float64(v))
+	case 0xbb: // f64.promote_f32
+		return u64(float64(f32(v)))
+	case 0xbc, 0xbe: // i32.reinterpret_f32, f32.reinterpret_i32
+		return uint64(uint32(v))
+	case 0xbd, opF64ReinterpretI64: // i64.reinterpret_f64, f64.reinterpret_i64
+		return v
+	case opI32Extend8S:
+		return uint64(uint32(int32(int8(v))))
+	case 0xc1: // i32.extend16_s
+		return uint64(uint32(int32(int16(v))))
+	case 0xc2: // i64.extend8_s
+		return uint64(int64(int8(v)))
+	case 0xc3: // i64.extend16_s
+		return uint64(int64(int16(v)))
+	case opI64Extend32S:
+		return uint64(int64(int32(v)))
+	case opTruncSatFirst: // i32.trunc_sat_f32_s
+		return uint64(uint32(int32(truncSatS(float64(f32(v)), 32))))
+	case opTruncSatFirst + 1: // i32.trunc_sat_f32_u
+		return uint64(uint32(truncSatU(float64(f32(v)), 32)))
+	case opTruncSatFirst + 2: // i32.trunc_sat_f64_s
+		return uint64(uint32(int32(truncSatS(f64(v), 32))))
+	case opTruncSatFirst + 3: // i32.trunc_sat_f64_u
+		return uint64(uint32(truncSatU(f64(v), 32)))
+	case opTruncSatFirst + 4: // i64.trunc_sat_f32_s
+		return uint64(truncSatS(float64(f32(v)), 64))
+	case opTruncSatFirst + 5: // i64.trunc_sat_f32_u
+		return truncSatU(float64(f32(v)), 64)
+	case opTruncSatFirst + 6: // i64.trunc_sat_f64_s
+		return uint64(truncSatS(f64(v), 64))
+	case opTruncSatLast: // i64.trunc_sat_f64_u
+		return truncSatU(f64(v), 64)
+	}
+	trap("unsupported instruction 0x%x", op)
+	return 0
+}
+
+// truncS truncates f to a signed integer of the given size, trapping if it is out of range.
+func truncS(f float64, size uint) int64 {
+	if math.IsNaN(f) {
+		trap("invalid conversion to integer")
+	}
+	t := math.Trunc(f)
+	min := -math.Ldexp(1, int(size)-1)
+	if t < min || t >= -min {
+		trap("integer overflow")
+	}
+	return int64(t)
+}
+
+// truncU truncates f to an unsigned integer of the given size, trapping if it is out of range.
+func truncU(f float64, size uint) uint64 {
+	if math.IsNaN(f) {
+		trap("invalid conversion to integer")
+	}
+	t := math.Trunc(f)
+	if t <= -1 || t >= math.Ldexp(1, int(size)) {
+		trap("integer overflow")
+	}
+	return uint64(t)
+}
+
+func truncSatS(f float64, size uint) int64 {
+	switch {
+	case math.IsNaN(f):
+		return 0
+	case f < -math.Ldexp(1, int(size)-1):
+		return -1 << (size - 1)
+	case f >= math.Ldexp(1, int(size)-1):
+		return 1<<(size-1) - 1
+	}
+	return int64(f)
+}
+
+func truncSatU(f float64, size uint) uint64 {
+	switch {
+	case math.IsNaN(f) || f <= -1:
+		return 0
+	case f >= math.Ldexp(1, int(size)):
+		return math.MaxUint64 >> (64 - size)
+	}
+	return uint64(f)
+}
+
+// binaryOp executes the instructions with two operands.
+func binaryOp(op uint16, a, b uint64) uint64 {
+	switch {
+	case op <= opI32GeU:
+		return i32Binary(op, uint32(a), uint32(b))
+	case op <= opI64GeU:
+		return i64Binary(op, a, b)
+	case op <= 0x60:
+		return f32Binary(op, f32(a), f32(b))
+	case op <= opF64Ge:
+		return f64Binary(op, f64(a), f64(b))
+	case op <= 0x78:
+		return i32Binary(op, uint32(a), uint32(b))
+	case op <= opI64Rotr:
+		return i64Binary(op, a, b)
+	case op <= 0x98:
+		return f32Binary(op, f32(a), f32(b))
+	default:
+		return f64Binary(op, f64(a), f64(b))
+	}
+}
+
+func i32Binary(op uint16, a, b uint32) uint64 {
+	switch op {
+	case 0x46:
+		return b2u(a == b)
+	case 0x47:
+		return b2u(a != b)
+	case 0x48:
+		return b2u(int32(a) < int32(b))
+	case 0x49:
+		return b2u(a < b)
+	case 0x4a:
+		return b2u(int32(a) > int32(b))
+	case 0x4b:
+		return b2u(a > b)
+	case 0x4c:
+		return b2u(int32(a) <= int32(b))
+	case 0x4d:
+		return b2u(a <= b)
+	case 0x4e:
+		return b2u(int32(a) >= int32(b))
+	case opI32GeU:
+		return b2u(a >= b)
+	case 0x6a:
+		return uint64(a + b)
+	case 0x6b:
+		return uint64(a - b)
+	case 0x6c:
+		return uint64(a * b)
+	case 0x6d: // div_s
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		if int32(a) == math.MinInt32 && int32(b) == -1 {
+			trap("integer overflow")
+		}
+		return uint64(uint32(int32(a) / int32(b)))
+	case 0x6e: // div_u
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		return uint64(a / b)
+	case 0x6f: // rem_s
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		if int32(b) == -1 {
+			return 0
+		}
+		return uint64(uint32(int32(a) % int32(b)))
+	case 0x70: // rem_u
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		return uint64(a % b)
+	case 0x71:
+		return uint64(a & b)
+	case 0x72:
+		return uint64(a | b)
+	case 0x73:
+		return uint64(a ^ b)
+	case 0x74:
+		return uint64(a << (b % 32))
+	case 0x75:
+		return uint64(uint32(int32(a) >> (b % 32)))
+	case 0x76:
+		return uint64(a >> (b % 32))
+	case 0x77:
+		return uint64(bits.RotateLeft32(a, int(b%32)))
+	case 0x78:
+		return uint64(bits.RotateLeft32(a, -int(b%32)))
+	}
+	trap("unsupported instruction 0x%x", op)
+	return 0
+}
+
+func i64Binary(op uint16, a, b uint64) uint64 {
+	switch op {
+	case 0x51:
+		return b2u(a == b)
+	case 0x52:
+		return b2u(a != b)
+	case 0x53:
+		return b2u(int64(a) < int64(b))
+	case 0x54:
+		return b2u(a < b)
+	case 0x55:
+		return b2u(int64(a) > int64(b))
+	case 0x56:
+		return b2u(a > b)
+	case 0x57:
+		return b2u(int64(a) <= int64(b))
+	case 0x58:
+		return b2u(a <= b)
+	case 0x59:
+		return b2u(int64(a) >= int64(b))
+	case opI64GeU:
+		return b2u(a >= b)
+	case 0x7c:
+		return a + b
+	case 0x7d:
+		return a - b
+	case 0x7e:
+		return a * b
+	case 0x7f: // div_s
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		if int64(a) == math.MinInt64 && int64(b) == -1 {
+			trap("integer overflow")
+		}
+		return uint64(int64(a) / int64(b))
+	case 0x80: // div_u
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		return a / b
+	case 0x81: // rem_s
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		if int64(b) == -1 {
+			return 0
+		}
+		return uint64(int64(a) % int64(b))
+	case 0x82: // rem_u
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		return a % b
+	case 0x83:
+		return a & b
+	case 0x84:
+		return a | b
+	case 0x85:
+		return a ^ b
+	case 0x86:
+		return a << (b % 64)
+	case 0x87:
+		return uint64(int64(a) >> (b % 64))
+	case 0x88:
+		return a >> (b % 64)
+	case 0x89:
+		return bits.RotateLeft64(a, int(b%64))
+	case opI64Rotr:
+		return bits.RotateLeft64(a, -int(b%64))
+	}
+	trap("unsupported instruction 0x%x", op)
+	return 0
+}
+
+func f32Binary(op uint16, a, b float32) uint64 {
+	switch op {
+	case opF32Eq:
+		return b2u(a == b)
+	case 0x5c:
+		return b2u(a != b)
+	case 0x5d:
+		return b2u(a < b)
+	case 0x5e:
+		return b2u(a > b)
+	case 0x5f:
+		return b2u(a <= b)
+	case 0x60:
+		return b2u(a >= b)
+	case 0x92:
+		return u32(a + b)
+	case 0x93:
+		return u32(a - b)
+	case 0x94:
+		return u32(a * b)
+	case 0x95:
+		return u32(a / b)
+	case 0x96:
+		return u32(float32(math.Min(float64(a), float64(b))))
+	case 0x97:
+		return u32(float32(math.Max(float64(a), float64(b))))
+	case 0x98:
+		return u32(float32(math.Copysign(float64(a), float64(b))))
+	}
+	trap("unsupported instruction 0x%x", op)
+	return 0
+}
+
+func f64Binary(op uint16, a, b float64) uint64 {
+	switch op {
+	case 0x61:
+		return b2u(a == b)
+	case 0x62:
+		return b2u(a != b)
+	case 0x63:
+		return b2u(a < b)
+	case 0x64:
+		return b2u(a > b)
+	case 0x65:
+		return b2u(a <= b)
+	case opF64Ge:
+		return b2u(a >= b)
+	case 0xa0:
+		return u64(a + b)
+	case 0xa1:
+		return u64(a - b)
+	case 0xa2:
+		return u64(a * b)
+	case 0xa3:
+		return u64(a / b)
+	case 0xa4:
+		return u64(math.Min(a, b))
+	case 0xa5:
+		return u64(math.Max(a, b))
+	case opF64Copysign:
+		return u64(math.Copysign(a, b))
+	}
+	trap("unsupported instruction 0x%x", op)
+	return 0
+}
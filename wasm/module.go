@@ -0,0 +1,819 @@
+// Package wasm implements an interpreter of WebAssembly modules.
+//
+// It is used to run user defined transformations inside kapacitord.
+// The interpreter is sandboxed: modules have no access to the host,
+// imported functions trap when they are called, memory is limited to a maximum number of pages
+// and the number of instructions executed by a call is limited.
+// Modules are validated when they are compiled, including the types of the instructions of their functions.
+//
+// The WebAssembly 1.0 instruction set is supported along with the
+// sign extension, non-trapping float to int conversion, multi-value and bulk memory extensions.
+package wasm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ValueType is the type of a WebAssembly value.
+type ValueType byte
+
+const (
+	I32 ValueType = 0x7f
+	I64 ValueType = 0x7e
+	F32 ValueType = 0x7d
+	F64 ValueType = 0x7c
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case I32:
+		return "i32"
+	case I64:
+		return "i64"
+	case F32:
+		return "f32"
+	case F64:
+		return "f64"
+	default:
+		return fmt.Sprintf("unknown(0x%x)", byte(t))
+	}
+}
+
+// FuncType is the signature of a function.
+type FuncType struct {
+	Params  []ValueType
+	Results []ValueType
+}
+
+func (t FuncType) equal(o FuncType) bool {
+	return bytes.Equal(valueTypeBytes(t.Params), valueTypeBytes(o.Params)) &&
+		bytes.Equal(valueTypeBytes(t.Results), valueTypeBytes(o.Results))
+}
+
+func valueTypeBytes(ts []ValueType) []byte {
+	b := make([]byte, len(ts))
+	for i, t := range ts {
+		b[i] = byte(t)
+	}
+	return b
+}
+
+const (
+	exportFunc   = 0x00
+	exportTable  = 0x01
+	exportMemory = 0x02
+	exportGlobal = 0x03
+)
+
+type limits struct {
+	min uint32
+	max uint32
+	// hasMax is false if the limits have no maximum.
+	hasMax bool
+}
+
+type global struct {
+	typ     ValueType
+	mutable bool
+	init    []instr
+}
+
+type export struct {
+	kind  byte
+	index uint32
+}
+
+type dataSegment struct {
+	// passive segments are only copied to memory by memory.init.
+	passive bool
+	offset  []instr
+	data    []byte
+}
+
+type elemSegment struct {
+	offset []instr
+	funcs  []uint32
+}
+
+type function struct {
+	typ    uint32
+	locals []ValueType
+	code   []instr
+	// imported functions have no code and trap when called.
+	imported bool
+	name     string
+}
+
+// Module is a decoded WebAssembly module.
+// A Module is immutable and can be instantiated any number of times.
+type Module struct {
+	types   []FuncType
+	funcs   []function
+	table   *limits
+	memory  *limits
+	globals []global
+	exports map[string]export
+	start   *uint32
+	elems   []elemSegment
+	data    []dataSegment
+}
+
+const magic = "\x00asm"
+
+// Compile decodes a binary WebAssembly module.
+func Compile(b []byte) (*Module, error) {
+	if len(b) < 8 || string(b[:4]) != magic {
+		return nil, errors.New("not a WebAssembly module")
+	}
+	if v := binary.LittleEndian.Uint32(b[4:8]); v != 1 {
+		return nil, fmt.Errorf("unsupported WebAssembly version %d", v)
+	}
+	m := &Module{
+		exports: make(map[string]export),
+	}
+	r := &reader{b: b, pos: 8}
+	var funcTypes []uint32
+	for r.len() > 0 {
+		id, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		content, err := r.bytes(int(size))
+		if err != nil {
+			return nil, err
+		}
+		sr := &reader{b: content}
+		switch id {
+		case 0:
+			// Custom sections are ignored
+			continue
+		case 1:
+			err = m.decodeTypes(sr)
+		case 2:
+			err = m.decodeImports(sr)
+		case 3:
+			funcTypes, err = sr.u32s()
+		case 4:
+			err = m.decodeTable(sr)
+		case 5:
+			err = m.decodeMemory(sr)
+		case 6:
+			err = m.decodeGlobals(sr)
+		case 7:
+			err = m.decodeExports(sr)
+		case 8:
+			var start uint32
+			start, err = sr.u32()
+			m.start = &start
+		case 9:
+			err = m.decodeElems(sr)
+		case 10:
+			err = m.decodeCode(sr, funcTypes)
+		case 11:
+			err = m.decodeData(sr)
+		case 12:
+			// The data count is only needed by single pass validators.
+			_, err = sr.u32()
+		default:
+			err = fmt.Errorf("unknown section id %d", id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("section %d: %v", id, err)
+		}
+		if sr.len() != 0 {
+			return nil, fmt.Errorf("section %d: %d unexpected trailing bytes", id, sr.len())
+		}
+	}
+	if err := m.validate(funcTypes); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Module) decodeTypes(r *reader) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		form, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if form != 0x60 {
+			return fmt.Errorf("invalid function type form 0x%x", form)
+		}
+		params, err := r.valueTypes()
+		if err != nil {
+			return err
+		}
+		results, err := r.valueTypes()
+		if err != nil {
+			return err
+		}
+		m.types = append(m.types, FuncType{Params: params, Results: results})
+	}
+	return nil
+}
+
+func (m *Module) decodeImports(r *reader) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		module, err := r.name()
+		if err != nil {
+			return err
+		}
+		name, err := r.name()
+		if err != nil {
+			return err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if kind != exportFunc {
+			return fmt.Errorf("import %s.%s: only functions can be imported", module, name)
+		}
+		typ, err := r.u32()
+		if err != nil {
+			return err
+		}
+		m.funcs = append(m.funcs, function{
+			typ:      typ,
+			imported: true,
+			name:     module + "." + name,
+		})
+	}
+	return nil
+}
+
+func (m *Module) decodeTable(r *reader) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	if n > 1 {
+		return errors.New("only one table is supported")
+	}
+	if n == 0 {
+		return nil
+	}
+	typ, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if typ != 0x70 {
+		return fmt.Errorf("unsupported table element type 0x%x", typ)
+	}
+	l, err := r.limits()
+	if err != nil {
+		return err
+	}
+	m.table = &l
+	return nil
+}
+
+func (m *Module) decodeMemory(r *reader) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	if n > 1 {
+		return errors.New("only one memory is supported")
+	}
+	if n == 0 {
+		return nil
+	}
+	l, err := r.limits()
+	if err != nil {
+		return err
+	}
+	if l.min > maxPages || (l.hasMax && l.max > maxPages) {
+		return errors.New("memory limits exceed 4GiB")
+	}
+	m.memory = &l
+	return nil
+}
+
+func (m *Module) decodeGlobals(r *reader) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		typ, err := r.valueType()
+		if err != nil {
+			return err
+		}
+		mut, err := r.byte()
+		if err != nil {
+			return err
+		}
+		init, err := r.constExpr()
+		if err != nil {
+			return err
+		}
+		m.globals = append(m.globals, global{typ: typ, mutable: mut == 1, init: init})
+	}
+	return nil
+}
+
+func (m *Module) decodeExports(r *reader) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		name, err := r.name()
+		if err != nil {
+			return err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return err
+		}
+		index, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if _, ok := m.exports[name]; ok {
+			return fmt.Errorf("duplicate export %q", name)
+		}
+		m.exports[name] = export{kind: kind, index: index}
+	}
+	return nil
+}
+
+func (m *Module) decodeElems(r *reader) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		flags, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if flags != 0 {
+			return fmt.Errorf("unsupported element segment kind %d", flags)
+		}
+		offset, err := r.constExpr()
+		if err != nil {
+			return err
+		}
+		funcs, err := r.u32s()
+		if err != nil {
+			return err
+		}
+		m.elems = append(m.elems, elemSegment{offset: offset, funcs: funcs})
+	}
+	return nil
+}
+
+func (m *Module) decodeCode(r *reader, funcTypes []uint32) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	if int(n) != len(funcTypes) {
+		return fmt.Errorf("%d function bodies for %d functions", n, len(funcTypes))
+	}
+	for i := uint32(0); i < n; i++ {
+		size, err := r.u32()
+		if err != nil {
+			return err
+		}
+		body, err := r.bytes(int(size))
+		if err != nil {
+			return err
+		}
+		br := &reader{b: body}
+		groups, err := br.u32()
+		if err != nil {
+			return err
+		}
+		var locals []ValueType
+		for g := uint32(0); g < groups; g++ {
+			count, err := br.u32()
+			if err != nil {
+				return err
+			}
+			typ, err := br.valueType()
+			if err != nil {
+				return err
+			}
+			if uint64(len(locals))+uint64(count) > maxLocals {
+				return fmt.Errorf("function %d: too many locals", i)
+			}
+			for c := uint32(0); c < count; c++ {
+				locals = append(locals, typ)
+			}
+		}
+		code, err := br.code(m)
+		if err != nil {
+			return fmt.Errorf("function %d: %v", i, err)
+		}
+		m.funcs = append(m.funcs, function{
+			typ:    funcTypes[i],
+			locals: locals,
+			code:   code,
+		})
+	}
+	return nil
+}
+
+func (m *Module) decodeData(r *reader) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		flags, err := r.u32()
+		if err != nil {
+			return err
+		}
+		var seg dataSegment
+		switch flags {
+		case 0:
+			seg.offset, err = r.constExpr()
+		case 1:
+			seg.passive = true
+		case 2:
+			var mem uint32
+			mem, err = r.u32()
+			if err == nil && mem != 0 {
+				err = fmt.Errorf("invalid memory index %d", mem)
+			}
+			if err == nil {
+				seg.offset, err = r.constExpr()
+			}
+		default:
+			err = fmt.Errorf("unsupported data segment kind %d", flags)
+		}
+		if err != nil {
+			return err
+		}
+		size, err := r.u32()
+		if err != nil {
+			return err
+		}
+		seg.data, err = r.bytes(int(size))
+		if err != nil {
+			return err
+		}
+		m.data = append(m.data, seg)
+	}
+	return nil
+}
+
+// validate checks the indexes used by the module and type checks its function bodies and constant expressions.
+func (m *Module) validate(funcTypes []uint32) error {
+	if len(m.funcs)-countImported(m.funcs) != len(funcTypes) {
+		return errors.New("missing code section")
+	}
+	for i, f := range m.funcs {
+		if int(f.typ) >= len(m.types) {
+			return fmt.Errorf("function %d: invalid type index %d", i, f.typ)
+		}
+		for _, in := range f.code {
+			switch in.op {
+			case opCall:
+				if in.imm >= uint64(len(m.funcs)) {
+					return fmt.Errorf("function %d: invalid function index %d", i, in.imm)
+				}
+			case opCallIndirect:
+				if in.imm >= uint64(len(m.types)) {
+					return fmt.Errorf("function %d: invalid type index %d", i, in.imm)
+				}
+				if m.table == nil {
+					return fmt.Errorf("function %d: call_indirect without a table", i)
+				}
+			case opGlobalGet, opGlobalSet:
+				if in.imm >= uint64(len(m.globals)) {
+					return fmt.Errorf("function %d: invalid global index %d", i, in.imm)
+				}
+				if in.op == opGlobalSet && !m.globals[in.imm].mutable {
+					return fmt.Errorf("function %d: global %d is immutable", i, in.imm)
+				}
+			case opLocalGet, opLocalSet, opLocalTee:
+				if in.imm >= uint64(len(m.types[f.typ].Params)+len(f.locals)) {
+					return fmt.Errorf("function %d: invalid local index %d", i, in.imm)
+				}
+			}
+			if in.op >= opI32Load && in.op <= opMemoryGrow || in.op == opMemoryInit || in.op == opMemoryCopy || in.op == opMemoryFill {
+				if m.memory == nil {
+					return fmt.Errorf("function %d: memory instruction without a memory", i)
+				}
+			}
+			if in.op == opMemoryInit || in.op == opDataDrop {
+				if in.imm >= uint64(len(m.data)) {
+					return fmt.Errorf("function %d: invalid data index %d", i, in.imm)
+				}
+			}
+		}
+	}
+	for i := range m.funcs {
+		if m.funcs[i].imported {
+			continue
+		}
+		if err := m.validateFunc(&m.funcs[i]); err != nil {
+			return fmt.Errorf("function %d: %v", i, err)
+		}
+	}
+	for i, g := range m.globals {
+		typ, err := m.constType(g.init, i)
+		if err != nil {
+			return fmt.Errorf("global %d: %v", i, err)
+		}
+		if typ != g.typ {
+			return fmt.Errorf("global %d: type mismatch: expected %v, got %v", i, g.typ, typ)
+		}
+	}
+	offsets := make([][]instr, 0, len(m.elems)+len(m.data))
+	for _, e := range m.elems {
+		offsets = append(offsets, e.offset)
+	}
+	for _, d := range m.data {
+		if !d.passive {
+			offsets = append(offsets, d.offset)
+		}
+	}
+	for _, offset := range offsets {
+		typ, err := m.constType(offset, len(m.globals))
+		if err != nil {
+			return fmt.Errorf("segment offset: %v", err)
+		}
+		if typ != I32 {
+			return fmt.Errorf("segment offset: type mismatch: expected i32, got %v", typ)
+		}
+	}
+	for name, e := range m.exports {
+		switch e.kind {
+		case exportFunc:
+			if int(e.index) >= len(m.funcs) {
+				return fmt.Errorf("export %q: invalid function index %d", name, e.index)
+			}
+		case exportGlobal:
+			if int(e.index) >= len(m.globals) {
+				return fmt.Errorf("export %q: invalid global index %d", name, e.index)
+			}
+		}
+	}
+	if m.start != nil && int(*m.start) >= len(m.funcs) {
+		return fmt.Errorf("invalid start function index %d", *m.start)
+	}
+	for i, e := range m.elems {
+		if m.table == nil {
+			return errors.New("element segment without a table")
+		}
+		for _, f := range e.funcs {
+			if int(f) >= len(m.funcs) {
+				return fmt.Errorf("element segment %d: invalid function index %d", i, f)
+			}
+		}
+	}
+	if len(m.data) > 0 && m.memory == nil {
+		return errors.New("data segment without a memory")
+	}
+	return nil
+}
+
+func countImported(funcs []function) int {
+	n := 0
+	for _, f := range funcs {
+		if f.imported {
+			n++
+		}
+	}
+	return n
+}
+
+// Exports returns the names and signatures of the exported functions.
+func (m *Module) Exports() map[string]FuncType {
+	funcs := make(map[string]FuncType)
+	for name, e := range m.exports {
+		if e.kind == exportFunc {
+			funcs[name] = m.types[m.funcs[e.index].typ]
+		}
+	}
+	return funcs
+}
+
+const (
+	pageSize  = 64 * 1024
+	maxPages  = 65536
+	maxLocals = 50000
+)
+
+// reader decodes the primitive values of the binary format.
+type reader struct {
+	b   []byte
+	pos int
+}
+
+var errUnexpectedEnd = errors.New("unexpected end of data")
+
+func (r *reader) len() int {
+	return len(r.b) - r.pos
+}
+
+func (r *reader) byte() (byte, error) {
+	if r.pos >= len(r.b) {
+		return 0, errUnexpectedEnd
+	}
+	b := r.b[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || n > r.len() {
+		return nil, errUnexpectedEnd
+	}
+	b := r.b[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *reader) uleb(bits uint) (uint64, error) {
+	var v uint64
+	var shift uint
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		if shift >= bits {
+			return 0, errors.New("integer representation too long")
+		}
+		v |= uint64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if bits < 64 && v>>bits != 0 {
+		return 0, errors.New("integer too large")
+	}
+	return v, nil
+}
+
+func (r *reader) sleb(bits uint) (int64, error) {
+	var v int64
+	var shift uint
+	var b byte
+	var err error
+	for {
+		b, err = r.byte()
+		if err != nil {
+			return 0, err
+		}
+		if shift >= bits {
+			return 0, errors.New("integer representation too long")
+		}
+		v |= int64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if shift < 64 && b&0x40 != 0 {
+		v |= -1 << shift
+	}
+	return v, nil
+}
+
+func (r *reader) u32() (uint32, error) {
+	v, err := r.uleb(32)
+	return uint32(v), err
+}
+
+func (r *reader) u32s() ([]uint32, error) {
+	n, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	if int(n) > r.len() {
+		return nil, errUnexpectedEnd
+	}
+	vs := make([]uint32, n)
+	for i := range vs {
+		if vs[i], err = r.u32(); err != nil {
+			return nil, err
+		}
+	}
+	return vs, nil
+}
+
+func (r *reader) name() (string, error) {
+	n, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(int(n))
+	return string(b), err
+}
+
+func (r *reader) valueType() (ValueType, error) {
+	b, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	switch t := ValueType(b); t {
+	case I32, I64, F32, F64:
+		return t, nil
+	default:
+		return 0, fmt.Errorf("unsupported value type 0x%x", b)
+	}
+}
+
+func (r *reader) valueTypes() ([]ValueType, error) {
+	n, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	if int(n) > r.len() {
+		return nil, errUnexpectedEnd
+	}
+	ts := make([]ValueType, n)
+	for i := range ts {
+		if ts[i], err = r.valueType(); err != nil {
+			return nil, err
+		}
+	}
+	return ts, nil
+}
+
+func (r *reader) limits() (limits, error) {
+	flag, err := r.byte()
+	if err != nil {
+		return limits{}, err
+	}
+	var l limits
+	if l.min, err = r.u32(); err != nil {
+		return l, err
+	}
+	switch flag {
+	case 0:
+	case 1:
+		l.hasMax = true
+		if l.max, err = r.u32(); err != nil {
+			return l, err
+		}
+		if l.max < l.min {
+			return l, errors.New("maximum is less than minimum")
+		}
+	default:
+		return l, fmt.Errorf("invalid limits flag 0x%x", flag)
+	}
+	return l, nil
+}
+
+func (r *reader) f32() (float32, error) {
+	b, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+}
+
+func (r *reader) f64() (float64, error) {
+	b, err := r.bytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+}
+
+// constExpr decodes a constant expression used to initialize globals and segment offsets.
+func (r *reader) constExpr() ([]instr, error) {
+	var code []instr
+	for {
+		in, err := r.instr(nil)
+		if err != nil {
+			return nil, err
+		}
+		switch in.op {
+		case opI32Const, opI64Const, opF32Const, opF64Const, opGlobalGet:
+			code = append(code, in)
+		case opEnd:
+			if len(code) != 1 {
+				return nil, errors.New("constant expression must contain exactly one instruction")
+			}
+			return code, nil
+		default:
+			return nil, fmt.Errorf("instruction 0x%x is not allowed in a constant expression", in.op)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package wasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// unknownType is the type of the operands of unreachable code, it matches any type.
+const unknownType ValueType = 0
+
+// ctrlFrame is a block, loop, if or else, or the body of the function, being validated.
+type ctrlFrame struct {
+	op      uint16
+	params  []ValueType
+	results []ValueType
+	// Height of the operand stack when the block was entered.
+	height int
+	// unreachable is true after an unconditional branch, the rest of the block is not executed.
+	unreachable bool
+}
+
+// labelTypes returns the types of the operands of a branch to the block.
+func (f *ctrlFrame) labelTypes() []ValueType {
+	if f.op == opLoop {
+		return f.params
+	}
+	return f.results
+}
+
+// validator type checks the instructions of a function body,
+// following the validation algorithm of the WebAssembly specification.
+type validator struct {
+	m      *Module
+	locals []ValueType
+	vals   []ValueType
+	ctrls  []ctrlFrame
+}
+
+func (v *validator) push(t ValueType) {
+	v.vals = append(v.vals, t)
+}
+
+func (v *validator) pushAll(ts []ValueType) {
+	v.vals = append(v.vals, ts...)
+}
+
+func (v *validator) pop() (ValueType, error) {
+	f := &v.ctrls[len(v.ctrls)-1]
+	if len(v.vals) == f.height {
+		if f.unreachable {
+			return unknownType, nil
+		}
+		return 0, errors.New("operand stack underflow")
+	}
+	t := v.vals[len(v.vals)-1]
+	v.vals = v.vals[:len(v.vals)-1]
+	return t, nil
+}
+
+func (v *validator) popExpect(expect ValueType) (ValueType, error) {
+	t, err := v.pop()
+	if err != nil {
+		return 0, err
+	}
+	if t != expect && t != unknownType && expect != unknownType {
+		return 0, fmt.Errorf("type mismatch: expected %v, got %v", expect, t)
+	}
+	if t == unknownType {
+		return expect, nil
+	}
+	return t, nil
+}
+
+func (v *validator) popAll(ts []ValueType) error {
+	for i := len(ts) - 1; i >= 0; i-- {
+		if _, err := v.popExpect(ts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *validator) pushCtrl(op uint16, params, results []ValueType) {
+	v.ctrls = append(v.ctrls, ctrlFrame{
+		op:      op,
+		params:  params,
+		results: results,
+		height:  len(v.vals),
+	})
+	v.pushAll(params)
+}
+
+func (v *validator) popCtrl() (ctrlFrame, error) {
+	f := v.ctrls[len(v.ctrls)-1]
+	if err := v.popAll(f.results); err != nil {
+		return f, err
+	}
+	if len(v.vals) != f.height {
+		return f, fmt.Errorf("%d values left on the operand stack at the end of the block", len(v.vals)-f.height)
+	}
+	v.ctrls = v.ctrls[:len(v.ctrls)-1]
+	return f, nil
+}
+
+func (v *validator) unreachable() {
+	f := &v.ctrls[len(v.ctrls)-1]
+	v.vals = v.vals[:f.height]
+	f.unreachable = true
+}
+
+// label returns the block targeted by a branch of the depth.
+func (v *validator) label(depth uint64) (*ctrlFrame, error) {
+	if depth >= uint64(len(v.ctrls)) {
+		return nil, fmt.Errorf("invalid label depth %d", depth)
+	}
+	return &v.ctrls[len(v.ctrls)-1-int(depth)], nil
+}
+
+// unaryOp pops an operand of type in and pushes a result of type out.
+func (v *validator) unaryOp(in, out ValueType) error {
+	if _, err := v.popExpect(in); err != nil {
+		return err
+	}
+	v.push(out)
+	return nil
+}
+
+// binaryOp pops two operands of type in and pushes a result of type out.
+func (v *validator) binaryOp(in, out ValueType) error {
+	if err := v.popAll([]ValueType{in, in}); err != nil {
+		return err
+	}
+	v.push(out)
+	return nil
+}
+
+// conversions are the operand and result types of the conversion instructions from i32.wrap_i64.
+var conversions = [...][2]ValueType{
+	{I64, I32}, {F32, I32}, {F32, I32}, {F64, I32}, {F64, I32},
+	{I32, I64}, {I32, I64}, {F32, I64}, {F32, I64}, {F64, I64}, {F64, I64},
+	{I32, F32}, {I32, F32}, {I64, F32}, {I64, F32}, {F64, F32},
+	{I32, F64}, {I32, F64}, {I64, F64}, {I64, F64}, {F32, F64},
+	{F32, I32}, {F64, I64}, {I32, F32}, {I64, F64},
+	{I32, I32}, {I32, I32}, {I64, I64}, {I64, I64}, {I64, I64},
+}
+
+// truncSats are the operand and result types of the non-trapping float to int conversions.
+var truncSats = [...][2]ValueType{
+	{F32, I32}, {F32, I32}, {F64, I32}, {F64, I32},
+	{F32, I64}, {F32, I64}, {F64, I64}, {F64, I64},
+}
+
+// validateFunc type checks the body of the function.
+func (m *Module) validateFunc(f *function) error {
+	typ := m.types[f.typ]
+	v := &validator{
+		m:      m,
+		locals: append(append([]ValueType(nil), typ.Params...), f.locals...),
+	}
+	v.ctrls = append(v.ctrls, ctrlFrame{op: opBlock, results: typ.Results})
+	for pc, in := range f.code {
+		if len(v.ctrls) == 0 {
+			return errors.New("instructions after the end of the function")
+		}
+		if err := v.instr(in); err != nil {
+			return fmt.Errorf("instruction %d (0x%x): %v", pc, in.op, err)
+		}
+	}
+	if len(v.ctrls) != 0 {
+		return errors.New("missing end of the function")
+	}
+	return nil
+}
+
+func (v *validator) instr(in instr) error {
+	switch op := in.op; {
+	case op == opUnreachable:
+		v.unreachable()
+	case op == opNop:
+	case op == opBlock || op == opLoop || op == opIf:
+		if op == opIf {
+			if _, err := v.popExpect(I32); err != nil {
+				return err
+			}
+		}
+		t := v.m.blockFuncType(in)
+		if err := v.popAll(t.Params); err != nil {
+			return err
+		}
+		v.pushCtrl(op, t.Params, t.Results)
+	case op == opElse:
+		f, err := v.popCtrl()
+		if err != nil {
+			return err
+		}
+		if f.op != opIf {
+			return errors.New("else without if")
+		}
+		v.pushCtrl(opElse, f.params, f.results)
+	case op == opEnd:
+		f, err := v.popCtrl()
+		if err != nil {
+			return err
+		}
+		if f.op == opIf && !bytes.Equal(valueTypeBytes(f.params), valueTypeBytes(f.results)) {
+			return errors.New("if without else must return its parameters")
+		}
+		v.pushAll(f.results)
+	case op == opBr:
+		l, err := v.label(in.imm)
+		if err != nil {
+			return err
+		}
+		if err := v.popAll(l.labelTypes()); err != nil {
+			return err
+		}
+		v.unreachable()
+	case op == opBrIf:
+		if _, err := v.popExpect(I32); err != nil {
+			return err
+		}
+		l, err := v.label(in.imm)
+		if err != nil {
+			return err
+		}
+		types := l.labelTypes()
+		if err := v.popAll(types); err != nil {
+			return err
+		}
+		v.pushAll(types)
+	case op == opBrTable:
+		if _, err := v.popExpect(I32); err != nil {
+			return err
+		}
+		def, err := v.label(uint64(in.labels[len(in.labels)-1]))
+		if err != nil {
+			return err
+		}
+		arity := len(def.labelTypes())
+		for _, depth := range in.labels[:len(in.labels)-1] {
+			l, err := v.label(uint64(depth))
+			if err != nil {
+				return err
+			}
+			types := l.labelTypes()
+			if len(types) != arity {
+				return errors.New("br_table labels have different arities")
+			}
+			// The operands are checked against the types of each label.
+			vals := append([]ValueType(nil), v.vals...)
+			if err := v.popAll(types); err != nil {
+				return err
+			}
+			v.vals = vals
+		}
+		if err := v.popAll(def.labelTypes()); err != nil {
+			return err
+		}
+		v.unreachable()
+	case op == opReturn:
+		if err := v.popAll(v.ctrls[0].results); err != nil {
+			return err
+		}
+		v.unreachable()
+	case op == opCall || op == opCallIndirect:
+		var t FuncType
+		if op == opCall {
+			t = v.m.types[v.m.funcs[in.imm].typ]
+		} else {
+			if _, err := v.popExpect(I32); err != nil {
+				return err
+			}
+			t = v.m.types[in.imm]
+		}
+		if err := v.popAll(t.Params); err != nil {
+			return err
+		}
+		v.pushAll(t.Results)
+	case op == opDrop:
+		if _, err := v.pop(); err != nil {
+			return err
+		}
+	case op == opSelect:
+		if _, err := v.popExpect(I32); err != nil {
+			return err
+		}
+		t1, err := v.pop()
+		if err != nil {
+			return err
+		}
+		t2, err := v.popExpect(t1)
+		if err != nil {
+			return err
+		}
+		v.push(t2)
+	case op == opLocalGet:
+		v.push(v.locals[in.imm])
+	case op == opLocalSet:
+		if _, err := v.popExpect(v.locals[in.imm]); err != nil {
+			return err
+		}
+	case op == opLocalTee:
+		return v.unaryOp(v.locals[in.imm], v.locals[in.imm])
+	case op == opGlobalGet:
+		v.push(v.m.globals[in.imm].typ)
+	case op == opGlobalSet:
+		if _, err := v.popExpect(v.m.globals[in.imm].typ); err != nil {
+			return err
+		}
+	case op >= opI32Load && op <= opI64Load32U:
+		return v.unaryOp(I32, memoryType(op))
+	case op >= opI32Store && op <= opI64Store32:
+		return v.popAll([]ValueType{I32, memoryType(op)})
+	case op == opMemorySize:
+		v.push(I32)
+	case op == opMemoryGrow:
+		return v.unaryOp(I32, I32)
+	case op == opI32Const:
+		v.push(I32)
+	case op == opI64Const:
+		v.push(I64)
+	case op == opF32Const:
+		v.push(F32)
+	case op == opF64Const:
+		v.push(F64)
+	case op == opI32Eqz:
+		return v.unaryOp(I32, I32)
+	case op > opI32Eqz && op <= opI32GeU:
+		return v.binaryOp(I32, I32)
+	case op == opI64Eqz:
+		return v.unaryOp(I64, I32)
+	case op <= opI64GeU:
+		return v.binaryOp(I64, I32)
+	case op <= 0x60:
+		return v.binaryOp(F32, I32)
+	case op <= opF64Ge:
+		return v.binaryOp(F64, I32)
+	case op <= 0x69:
+		return v.unaryOp(I32, I32)
+	case op <= 0x78:
+		return v.binaryOp(I32, I32)
+	case op <= 0x7b:
+		return v.unaryOp(I64, I64)
+	case op <= opI64Rotr:
+		return v.binaryOp(I64, I64)
+	case op <= 0x91:
+		return v.unaryOp(F32, F32)
+	case op <= 0x98:
+		return v.binaryOp(F32, F32)
+	case op <= 0x9f:
+		return v.unaryOp(F64, F64)
+	case op <= opF64Copysign:
+		return v.binaryOp(F64, F64)
+	case op <= opI64Extend32S:
+		c := conversions[op-opI32WrapI64]
+		return v.unaryOp(c[0], c[1])
+	case op >= opTruncSatFirst && op <= opTruncSatLast:
+		c := truncSats[op-opTruncSatFirst]
+		return v.unaryOp(c[0], c[1])
+	case op == opMemoryInit || op == opMemoryCopy || op == opMemoryFill:
+		return v.popAll([]ValueType{I32, I32, I32})
+	case op == opDataDrop:
+	default:
+		return errors.New("unsupported instruction")
+	}
+	return nil
+}
+
+// memoryType returns the type of the value loaded or stored by a memory instruction.
+func memoryType(op uint16) ValueType {
+	switch op {
+	case opI32Load, opI32Load8S, opI32Load8U, opI32Load16S, opI32Load16U, opI32Store, opI32Store8, opI32Store16:
+		return I32
+	case opF32Load, opF32Store:
+		return F32
+	case opF64Load, opF64Store:
+		return F64
+	}
+	return I64
+}
+
+// constType returns the type of a constant expression,
+// a global.get may only refer to the immutable globals before the index.
+func (m *Module) constType(code []instr, globals int) (ValueType, error) {
+	switch in := code[0]; in.op {
+	case opI32Const:
+		return I32, nil
+	case opI64Const:
+		return I64, nil
+	case opF32Const:
+		return F32, nil
+	case opF64Const:
+		return F64, nil
+	default:
+		if in.imm >= uint64(globals) {
+			return 0, fmt.Errorf("invalid global index %d", in.imm)
+		}
+		if m.globals[in.imm].mutable {
+			return 0, fmt.Errorf("global %d is mutable", in.imm)
+		}
+		return m.globals[in.imm].typ, nil
+	}
+}
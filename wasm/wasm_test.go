@@ -0,0 +1,332 @@
+package wasm
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// vec encodes a vector of already encoded items.
+func vec(items ...[]byte) []byte {
+	b := []byte{byte(len(items))}
+	for _, i := range items {
+		b = append(b, i...)
+	}
+	return b
+}
+
+func section(id byte, items ...[]byte) []byte {
+	content := vec(items...)
+	return append([]byte{id, byte(len(content))}, content...)
+}
+
+func funcType(params, results []byte) []byte {
+	b := append([]byte{0x60, byte(len(params))}, params...)
+	b = append(b, byte(len(results)))
+	return append(b, results...)
+}
+
+func exportFn(name string, idx byte) []byte {
+	return append(append([]byte{byte(len(name))}, name...), exportFunc, idx)
+}
+
+func importFn(module, name string, typ byte) []byte {
+	b := append([]byte{byte(len(module))}, module...)
+	b = append(append(b, byte(len(name))), name...)
+	return append(b, exportFunc, typ)
+}
+
+func body(locals []byte, code ...byte) []byte {
+	b := append(locals, code...)
+	return append([]byte{byte(len(b))}, b...)
+}
+
+var noLocals = []byte{0}
+
+// testModule builds a module exporting the test functions.
+func testModule() []byte {
+	i32, i64 := byte(I32), byte(I64)
+	b := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	b = append(b, section(1,
+		funcType([]byte{i32, i32}, []byte{i32}), // 0
+		funcType([]byte{i64}, []byte{i64}),      // 1
+		funcType(nil, nil),                      // 2
+		funcType([]byte{i32}, []byte{i32}),      // 3
+	)...)
+	// Function 0 is an import.
+	b = append(b, section(2, importFn("env", "log", 2))...)
+	// Functions 1 to 7.
+	b = append(b, section(3, []byte{0}, []byte{1}, []byte{2}, []byte{0}, []byte{3}, []byte{2}, []byte{2})...)
+	b = append(b, section(5, []byte{0x01, 1, 2})...)
+	b = append(b, section(7,
+		exportFn("add", 1),
+		exportFn("fact", 2),
+		exportFn("spin", 3),
+		exportFn("div", 4),
+		exportFn("mem", 5),
+		exportFn("recurse", 6),
+		exportFn("log", 7),
+	)...)
+	b = append(b, section(10,
+		// add: local.get 0 local.get 1 i32.add
+		body(noLocals, 0x20, 0, 0x20, 1, 0x6a, 0x0b),
+		// fact: iterative factorial
+		body([]byte{1, 1, i64},
+			0x42, 1, 0x21, 1, // i64.const 1 local.set 1
+			0x02, 0x40, 0x03, 0x40, // block loop
+			0x20, 0, 0x50, 0x0d, 1, // local.get 0 i64.eqz br_if 1
+			0x20, 1, 0x20, 0, 0x7e, 0x21, 1, // local.get 1 local.get 0 i64.mul local.set 1
+			0x20, 0, 0x42, 1, 0x7d, 0x21, 0, // local.get 0 i64.const 1 i64.sub local.set 0
+			0x0c, 0, 0x0b, 0x0b, // br 0 end end
+			0x20, 1, 0x0b),
+		// spin: loop br 0 end
+		body(noLocals, 0x03, 0x40, 0x0c, 0, 0x0b, 0x0b),
+		// div: i32.div_s
+		body(noLocals, 0x20, 0, 0x20, 1, 0x6d, 0x0b),
+		// mem: grow by the argument, store 7 at the last byte and return the previous size
+		body(noLocals,
+			0x20, 0, 0x40, 0, // local.get 0 memory.grow
+			0x3f, 0, 0x41, 0x80, 0x80, 0x04, 0x6c, 0x41, 1, 0x6b, // memory.size i32.const 65536 i32.mul i32.const 1 i32.sub
+			0x41, 7, 0x3a, 0, 0, // i32.const 7 i32.store8
+			0x0b),
+		// recurse: call 6
+		body(noLocals, 0x10, 6, 0x0b),
+		// log: call the import
+		body(noLocals, 0x10, 0, 0x0b),
+	)...)
+	return b
+}
+
+func instantiate(t *testing.T, c Config) *Instance {
+	m, err := Compile(testModule())
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, err := m.Instantiate(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func call(t *testing.T, i *Instance, name string, args ...uint64) ([]uint64, error) {
+	f, err := i.Func(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.Call(args...)
+}
+
+func TestCompile_Exports(t *testing.T) {
+	m, err := Compile(testModule())
+	if err != nil {
+		t.Fatal(err)
+	}
+	exports := m.Exports()
+	if got, exp := exports["add"], (FuncType{Params: []ValueType{I32, I32}, Results: []ValueType{I32}}); !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected add type: got %v exp %v", got, exp)
+	}
+	if got, exp := len(exports), 7; got != exp {
+		t.Errorf("unexpected number of exports: got %d exp %d", got, exp)
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	b := testModule()
+	for _, tc := range []struct {
+		name string
+		b    []byte
+	}{
+		{name: "magic", b: []byte("\x00asm\x02\x00\x00\x00")},
+		{name: "truncated", b: b[:len(b)-3]},
+	} {
+		if _, err := Compile(tc.b); err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
+
+// funcModule builds a module with a single function.
+func funcModule(params, results, locals []byte, code ...byte) []byte {
+	b := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	b = append(b, section(1, funcType(params, results))...)
+	b = append(b, section(3, []byte{0})...)
+	return append(b, section(10, body(locals, code...))...)
+}
+
+func TestCompile_Validate(t *testing.T) {
+	i32, i64 := byte(I32), byte(I64)
+	for _, tc := range []struct {
+		name string
+		b    []byte
+		err  string
+	}{
+		{
+			name: "unreachable operands",
+			// unreachable i32.add
+			b: funcModule(nil, []byte{i32}, noLocals, 0x00, 0x6a, 0x0b),
+		},
+		{
+			name: "if else",
+			// i32.const 1 if i32 i32.const 2 else i32.const 3 end
+			b: funcModule(nil, []byte{i32}, noLocals, 0x41, 1, 0x04, i32, 0x41, 2, 0x05, 0x41, 3, 0x0b, 0x0b),
+		},
+		{
+			name: "stack underflow",
+			// i32.add
+			b:   funcModule(nil, []byte{i32}, noLocals, 0x6a, 0x0b),
+			err: "operand stack underflow",
+		},
+		{
+			name: "missing result",
+			b:    funcModule(nil, []byte{i32}, noLocals, 0x0b),
+			err:  "operand stack underflow",
+		},
+		{
+			name: "type mismatch",
+			// local.get 0 i32.eqz
+			b:   funcModule([]byte{i64}, []byte{i32}, noLocals, 0x20, 0, 0x45, 0x0b),
+			err: "type mismatch: expected i32, got i64",
+		},
+		{
+			name: "values left",
+			// i32.const 1
+			b:   funcModule(nil, nil, noLocals, 0x41, 1, 0x0b),
+			err: "1 values left on the operand stack",
+		},
+		{
+			name: "invalid label",
+			// br 1
+			b:   funcModule(nil, nil, noLocals, 0x0c, 1, 0x0b),
+			err: "invalid label depth 1",
+		},
+		{
+			name: "if without else",
+			// i32.const 1 if i32 i32.const 2 end
+			b:   funcModule(nil, []byte{i32}, noLocals, 0x41, 1, 0x04, i32, 0x41, 2, 0x0b, 0x0b),
+			err: "if without else",
+		},
+		{
+			name: "global type",
+			// A global of type i32 initialized with i64.const 1.
+			b:   append(funcModule(nil, nil, noLocals, 0x0b), section(6, []byte{i32, 0, 0x42, 1, 0x0b})...),
+			err: "global 0: type mismatch",
+		},
+	} {
+		_, err := Compile(tc.b)
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: unexpected error: got %v exp %q", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestInstantiate_DefaultLimits(t *testing.T) {
+	b := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	// A memory with a minimum of 17 pages.
+	b = append(b, section(5, []byte{0x00, 17})...)
+	m, err := Compile(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Instantiate(Config{}); err == nil || !strings.Contains(err.Error(), "the limit is 16") {
+		t.Errorf("unexpected error: got %v", err)
+	}
+
+	i := instantiate(t, Config{})
+	if _, err := call(t, i, "spin"); err == nil || !strings.Contains(err.Error(), "instruction limit of 1000000 exceeded") {
+		t.Errorf("unexpected error: got %v", err)
+	}
+}
+
+func TestInstantiate_TableLimit(t *testing.T) {
+	b := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	// A table of funcref with a minimum of 2^32-1 elements.
+	b = append(b, section(4, []byte{0x70, 0x00, 0xff, 0xff, 0xff, 0xff, 0x0f})...)
+	m, err := Compile(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Instantiate(Config{}); err == nil || !strings.Contains(err.Error(), "the limit is 65536") {
+		t.Errorf("unexpected error: got %v", err)
+	}
+	if _, err := m.Instantiate(Config{MaxTableElements: 1000}); err == nil || !strings.Contains(err.Error(), "the limit is 1000") {
+		t.Errorf("unexpected error: got %v", err)
+	}
+}
+
+func TestCall(t *testing.T) {
+	i := instantiate(t, Config{MaxMemoryPages: 2})
+	res, err := call(t, i, "add", 40, uint64(uint32(math.MaxUint32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := res, []uint64{39}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected add result: got %v exp %v", got, exp)
+	}
+	res, err = call(t, i, "fact", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := res, []uint64{2432902008176640000}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected fact result: got %v exp %v", got, exp)
+	}
+}
+
+func TestCall_Memory(t *testing.T) {
+	i := instantiate(t, Config{MaxMemoryPages: 2})
+	res, err := call(t, i, "mem", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := res, []uint64{1}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected previous size: got %v exp %v", got, exp)
+	}
+	if got, exp := len(i.Memory()), 2*pageSize; got != exp {
+		t.Errorf("unexpected memory size: got %d exp %d", got, exp)
+	}
+	if got := i.Memory()[2*pageSize-1]; got != 7 {
+		t.Errorf("unexpected stored byte: got %d exp 7", got)
+	}
+	// Growing beyond the limit fails and returns -1.
+	res, err = call(t, i, "mem", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := res, []uint64{uint64(math.MaxUint32)}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected grow result: got %v exp %v", got, exp)
+	}
+}
+
+func TestCall_Traps(t *testing.T) {
+	i := instantiate(t, Config{MaxInstructions: 10000})
+	for _, tc := range []struct {
+		name string
+		args []uint64
+		err  string
+	}{
+		{name: "spin", err: "instruction limit of 10000 exceeded"},
+		{name: "div", args: []uint64{1, 0}, err: "integer divide by zero"},
+		{name: "div", args: []uint64{uint64(uint32(math.MaxUint32/2 + 1)), uint64(uint32(math.MaxUint32))}, err: "integer overflow"},
+		{name: "recurse", err: "call stack exhausted"},
+		{name: "log", err: "call to imported function env.log"},
+		{name: "add", args: []uint64{1}, err: "function expects 2 arguments, got 1"},
+	} {
+		_, err := call(t, i, tc.name, tc.args...)
+		if err == nil {
+			t.Errorf("%s: expected error", tc.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: unexpected error: got %q exp %q", tc.name, err, tc.err)
+		}
+	}
+	// The instance is still usable after a trap.
+	if _, err := call(t, i, "add", 1, 2); err != nil {
+		t.Fatal(err)
+	}
+}
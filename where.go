@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/models"
 	"github.com/influxdata/kapacitor/pipeline"
 	"github.com/influxdata/kapacitor/tick/ast"
 	"github.com/influxdata/kapacitor/tick/stateful"
@@ -17,13 +18,15 @@ type WhereNode struct {
 
 	expression stateful.Expression
 	scopePool  stateful.ScopePool
+	groups     *groupStates
 }
 
 // Create a new WhereNode which filters down the batch or stream by a condition
 func newWhereNode(et *ExecutingTask, n *pipeline.WhereNode, d NodeDiagnostic) (wn *WhereNode, err error) {
 	wn = &WhereNode{
-		node: node{Node: n, et: et, diag: d},
-		w:    n,
+		node:   node{Node: n, et: et, diag: d},
+		w:      n,
+		groups: newGroupStates(),
 	}
 
 	expr, err := stateful.NewExpression(n.Lambda.Expression)
@@ -41,6 +44,9 @@ func newWhereNode(et *ExecutingTask, n *pipeline.WhereNode, d NodeDiagnostic) (w
 }
 
 func (n *WhereNode) runWhere(snapshot []byte) error {
+	if err := n.groups.restore(snapshot); err != nil {
+		n.diag.Error("failed to restore snapshot", err)
+	}
 	consumer := edge.NewGroupedConsumer(
 		n.ins[0],
 		n,
@@ -53,19 +59,29 @@ func (n *WhereNode) runWhere(snapshot []byte) error {
 func (n *WhereNode) NewGroup(group edge.GroupInfo, first edge.PointMeta) (edge.Receiver, error) {
 	return edge.NewReceiverFromForwardReceiverWithStats(
 		n.outs,
-		edge.NewTimedForwardReceiver(n.timer, n.newGroup()),
+		edge.NewTimedForwardReceiver(n.timer, n.newGroup(group.ID)),
 	), nil
 }
 
-func (n *WhereNode) newGroup() *whereGroup {
-	return &whereGroup{
+func (n *WhereNode) newGroup(id models.GroupID) *whereGroup {
+	g := &whereGroup{
 		n:    n,
+		id:   id,
 		expr: n.expression.CopyReset(),
 	}
+	if err := n.groups.add(id, g); err != nil {
+		n.diag.Error("failed to restore snapshot", err)
+	}
+	return g
+}
+
+func (n *WhereNode) snapshot() ([]byte, error) {
+	return n.groups.snapshot()
 }
 
 type whereGroup struct {
 	n    *WhereNode
+	id   models.GroupID
 	expr stateful.Expression
 }
 
@@ -88,7 +104,9 @@ func (g *whereGroup) Point(p edge.PointMessage) (edge.Message, error) {
 }
 
 func (g *whereGroup) doWhere(p edge.FieldsTagsTimeGetterMessage) (edge.Message, error) {
+	g.n.groups.mu.Lock()
 	pass, err := EvalPredicate(g.expr, g.n.scopePool, p)
+	g.n.groups.mu.Unlock()
 	if err != nil {
 		g.n.diag.Error("error while evaluating expression", err)
 		return nil, nil
@@ -99,10 +117,19 @@ func (g *whereGroup) doWhere(p edge.FieldsTagsTimeGetterMessage) (edge.Message,
 	return nil, nil
 }
 
+func (g *whereGroup) snapshot() ([]byte, error) {
+	return g.expr.Snapshot()
+}
+
+func (g *whereGroup) restore(snapshot []byte) error {
+	return g.expr.Restore(snapshot)
+}
+
 func (g *whereGroup) Barrier(b edge.BarrierMessage) (edge.Message, error) {
 	return b, nil
 }
 func (g *whereGroup) DeleteGroup(d edge.DeleteGroupMessage) (edge.Message, error) {
+	g.n.groups.delete(g.id)
 	return d, nil
 }
 func (g *whereGroup) Done() {}
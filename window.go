@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/influxdata/kapacitor/edge"
+	"github.com/influxdata/kapacitor/expvar"
 	"github.com/influxdata/kapacitor/models"
 	"github.com/influxdata/kapacitor/pipeline"
 )
@@ -13,6 +14,42 @@ import (
 type WindowNode struct {
 	node
 	w *pipeline.WindowNode
+
+	groups *groupStates
+}
+
+// window buffers the points of a group.
+type window interface {
+	edge.ForwardReceiver
+	groupState
+}
+
+// windowState is the state of a window kept in the snapshots of the node.
+type windowState struct {
+	// NextEmitTime is the time a window by time emits next.
+	NextEmitTime time.Time
+	// Count is the number of points a window by count received and NextEmit the count it emits next.
+	Count    int
+	NextEmit int
+	Points   []windowPoint
+}
+
+type windowPoint struct {
+	Fields models.Fields
+	Tags   models.Tags
+	Time   time.Time
+}
+
+func windowPoints(points []edge.BatchPointMessage) []windowPoint {
+	wps := make([]windowPoint, len(points))
+	for i, p := range points {
+		wps[i] = windowPoint{
+			Fields: p.Fields(),
+			Tags:   p.Tags(),
+			Time:   p.Time(),
+		}
+	}
+	return wps
 }
 
 // Create a new  WindowNode, which windows data for a period of time and emits the window.
@@ -21,16 +58,21 @@ func newWindowNode(et *ExecutingTask, n *pipeline.WindowNode, d NodeDiagnostic)
 		return nil, errors.New("window node must have either a non zero period or non zero period count")
 	}
 	wn := &WindowNode{
-		w:    n,
-		node: node{Node: n, et: et, diag: d},
+		w:      n,
+		node:   node{Node: n, et: et, diag: d},
+		groups: newGroupStates(),
 	}
 	wn.node.runF = wn.runWindow
 	return wn, nil
 }
 
-func (n *WindowNode) runWindow([]byte) (err error) {
+func (n *WindowNode) runWindow(snapshot []byte) (err error) {
+	if err := n.groups.restore(snapshot); err != nil {
+		n.diag.Error("failed to restore snapshot", err)
+	}
 	consumer := edge.NewGroupedConsumer(n.ins[0], n)
 	n.statMap.Set(statCardinalityGauge, consumer.CardinalityVar())
+	n.statMap.Set(statMemoryBytes, expvar.NewIntFuncGauge(n.memoryBytes))
 	err = consumer.Consume()
 	return
 }
@@ -40,17 +82,48 @@ func (n *WindowNode) NewGroup(group edge.GroupInfo, first edge.PointMeta) (edge.
 	if err != nil {
 		return nil, err
 	}
+	if err := n.groups.add(group.ID, r); err != nil {
+		n.diag.Error("failed to restore snapshot", err)
+	}
 	return edge.NewReceiverFromForwardReceiverWithStats(
 		n.outs,
-		edge.NewTimedForwardReceiver(n.timer, r),
+		edge.NewTimedForwardReceiver(n.timer, lockedWindow{window: r, n: n, id: group.ID}),
 	), nil
 }
 
+func (n *WindowNode) snapshot() ([]byte, error) {
+	return n.groups.snapshot()
+}
+
+// lockedWindow serializes the messages of a window with the snapshots of the node.
+type lockedWindow struct {
+	window
+	n  *WindowNode
+	id models.GroupID
+}
+
+func (w lockedWindow) Point(p edge.PointMessage) (edge.Message, error) {
+	w.n.groups.mu.Lock()
+	defer w.n.groups.mu.Unlock()
+	return w.window.Point(p)
+}
+
+func (w lockedWindow) Barrier(b edge.BarrierMessage) (edge.Message, error) {
+	w.n.groups.mu.Lock()
+	defer w.n.groups.mu.Unlock()
+	return w.window.Barrier(b)
+}
+
+func (w lockedWindow) DeleteGroup(d edge.DeleteGroupMessage) (edge.Message, error) {
+	w.n.groups.delete(w.id)
+	return w.window.DeleteGroup(d)
+}
+
 func (n *WindowNode) DeleteGroup(group models.GroupID) {
 	// Nothing to do
 }
 
-func (n *WindowNode) newWindow(group edge.GroupInfo, first edge.PointMeta) (edge.ForwardReceiver, error) {
+func (n *WindowNode) newWindow(group edge.GroupInfo, first edge.PointMeta) (window, error) {
 	switch {
 	case n.w.Period != 0:
 		return newWindowByTime(
@@ -61,6 +134,8 @@ func (n *WindowNode) newWindow(group edge.GroupInfo, first edge.PointMeta) (edge
 			n.w.Every,
 			n.w.AlignFlag,
 			n.w.FillPeriodFlag,
+			n.buffered,
+			n.memory,
 			n.diag,
 		), nil
 	case n.w.PeriodCount != 0:
@@ -70,6 +145,8 @@ func (n *WindowNode) newWindow(group edge.GroupInfo, first edge.PointMeta) (edge
 			int(n.w.PeriodCount),
 			int(n.w.EveryCount),
 			n.w.FillPeriodFlag,
+			n.buffered,
+			n.memory,
 			n.diag,
 		), nil
 	default:
@@ -91,6 +168,9 @@ type windowByTime struct {
 	period time.Duration
 	every  time.Duration
 
+	limit  *bufferLimit
+	memory *bufferMemory
+
 	diag NodeDiagnostic
 }
 
@@ -102,6 +182,8 @@ func newWindowByTime(
 	every time.Duration,
 	align,
 	fillPeriod bool,
+	limit *bufferLimit,
+	memory *memoryAccount,
 	d NodeDiagnostic,
 
 ) *windowByTime {
@@ -133,6 +215,8 @@ func newWindowByTime(
 		fillPeriod: fillPeriod,
 		period:     period,
 		every:      every,
+		limit:      limit,
+		memory:     &bufferMemory{account: memory},
 		diag:       d,
 	}
 }
@@ -152,7 +236,7 @@ func (w *windowByTime) Barrier(b edge.BarrierMessage) (msg edge.Message, err err
 		if !b.Time().Before(w.nextEmit) {
 			// purge old points
 			oldest := b.Time().Add(-1 * w.period)
-			w.buf.purge(oldest, false)
+			w.purge(oldest, false)
 
 			// get current batch
 			msg = w.batch(b.Time())
@@ -165,7 +249,7 @@ func (w *windowByTime) Barrier(b edge.BarrierMessage) (msg edge.Message, err err
 		if !b.Time().Before(w.nextEmit) {
 			// purge old points
 			oldest := w.nextEmit.Add(-1 * w.period)
-			w.buf.purge(oldest, true)
+			w.purge(oldest, true)
 
 			// get current batch
 			msg = w.batch(w.nextEmit)
@@ -181,19 +265,43 @@ func (w *windowByTime) Barrier(b edge.BarrierMessage) (msg edge.Message, err err
 	return
 }
 func (w *windowByTime) DeleteGroup(d edge.DeleteGroupMessage) (edge.Message, error) {
+	w.memory.set(0)
 	return d, nil
 }
 func (w *windowByTime) Done() {}
 
+func (w *windowByTime) snapshot() ([]byte, error) {
+	return encodeState(windowState{
+		NextEmitTime: w.nextEmit,
+		Points:       windowPoints(w.buf.points()),
+	})
+}
+
+func (w *windowByTime) restore(snapshot []byte) error {
+	var s windowState
+	if err := decodeState(snapshot, &s); err != nil {
+		return err
+	}
+	w.nextEmit = s.NextEmitTime
+	for _, p := range s.Points {
+		if err := w.insert(edge.NewPointMessage(w.name, "", "", w.group.Dimensions, p.Fields, p.Tags, p.Time)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (w *windowByTime) Point(p edge.PointMessage) (msg edge.Message, err error) {
 	if w.every == 0 {
 		// Insert point before.
-		w.buf.insert(p)
+		if err := w.insert(p); err != nil {
+			return nil, err
+		}
 		// Since we are emitting every point we can use a right aligned window (oldest, now]
 		if !p.Time().Before(w.nextEmit) {
 			// purge old points
 			oldest := p.Time().Add(-1 * w.period)
-			w.buf.purge(oldest, false)
+			w.purge(oldest, false)
 
 			// get current batch
 			msg = w.batch(p.Time())
@@ -206,7 +314,7 @@ func (w *windowByTime) Point(p edge.PointMessage) (msg edge.Message, err error)
 		if !p.Time().Before(w.nextEmit) {
 			// purge old points
 			oldest := w.nextEmit.Add(-1 * w.period)
-			w.buf.purge(oldest, true)
+			w.purge(oldest, true)
 
 			// get current batch
 			msg = w.batch(w.nextEmit)
@@ -219,11 +327,32 @@ func (w *windowByTime) Point(p edge.PointMessage) (msg edge.Message, err error)
 			}
 		}
 		// Insert point after.
-		w.buf.insert(p)
+		if err := w.insert(p); err != nil {
+			return nil, err
+		}
 	}
 	return
 }
 
+// insert adds the point to the buffer, counting it against the buffer limit and the memory of the task.
+func (w *windowByTime) insert(p edge.PointMessage) error {
+	if err := w.limit.add(1); err != nil {
+		return err
+	}
+	w.buf.insert(p)
+	w.memory.observe(p)
+	w.memory.set(w.buf.size)
+	return nil
+}
+
+// purge removes expired points from the buffer, releasing them from the buffer limit and the memory of the task.
+func (w *windowByTime) purge(oldest time.Time, inclusive bool) {
+	size := w.buf.size
+	w.buf.purge(oldest, inclusive)
+	w.limit.add(w.buf.size - size)
+	w.memory.set(w.buf.size)
+}
+
 // batch returns the current window buffer as a batch message.
 // TODO(nathanielc): A possible optimization could be to not buffer the data at all if we know that we do not have overlapping windows.
 func (w *windowByTime) batch(tmax time.Time) edge.BufferedBatchMessage {
@@ -370,6 +499,9 @@ type windowByCount struct {
 	size     int
 	count    int
 
+	limit  *bufferLimit
+	memory *bufferMemory
+
 	diag NodeDiagnostic
 }
 
@@ -379,6 +511,8 @@ func newWindowByCount(
 	period,
 	every int,
 	fillPeriod bool,
+	limit *bufferLimit,
+	memory *memoryAccount,
 	d NodeDiagnostic,
 ) *windowByCount {
 	// Determine the first nextEmit index
@@ -393,6 +527,8 @@ func newWindowByCount(
 		period:   period,
 		every:    every,
 		nextEmit: nextEmit,
+		limit:    limit,
+		memory:   &bufferMemory{account: memory},
 		diag:     d,
 	}
 }
@@ -410,18 +546,61 @@ func (w *windowByCount) Barrier(b edge.BarrierMessage) (edge.Message, error) {
 	return b, nil
 }
 func (w *windowByCount) DeleteGroup(d edge.DeleteGroupMessage) (edge.Message, error) {
+	w.memory.set(0)
 	return d, nil
 }
 func (w *windowByCount) Done() {}
 
+func (w *windowByCount) snapshot() ([]byte, error) {
+	return encodeState(windowState{
+		Count:    w.count,
+		NextEmit: w.nextEmit,
+		Points:   windowPoints(w.points()),
+	})
+}
+
+func (w *windowByCount) restore(snapshot []byte) error {
+	var s windowState
+	if err := decodeState(snapshot, &s); err != nil {
+		return err
+	}
+	points := s.Points
+	if len(points) > w.period {
+		// Keep the latest points if the period has been shortened.
+		points = points[len(points)-w.period:]
+	}
+	for _, p := range points {
+		if err := w.limit.add(1); err != nil {
+			return err
+		}
+		bp := edge.NewBatchPointMessage(p.Fields, p.Tags, p.Time)
+		w.buf[w.stop] = bp
+		w.stop = (w.stop + 1) % w.period
+		w.size++
+		w.memory.observe(bp)
+	}
+	w.memory.set(w.size)
+	w.count = s.Count
+	w.nextEmit = s.NextEmit
+	return nil
+}
+
 func (w *windowByCount) Point(p edge.PointMessage) (msg edge.Message, err error) {
-	w.buf[w.stop] = edge.BatchPointFromPoint(p)
+	if w.size < w.period {
+		if err := w.limit.add(1); err != nil {
+			return nil, err
+		}
+	}
+	bp := edge.BatchPointFromPoint(p)
+	w.buf[w.stop] = bp
 	w.stop = (w.stop + 1) % w.period
 	if w.size == w.period {
 		w.start = (w.start + 1) % w.period
 	} else {
 		w.size++
 	}
+	w.memory.observe(bp)
+	w.memory.set(w.size)
 	w.count++
 	//Check if its time to emit
 	if w.count == w.nextEmit {
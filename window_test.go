@@ -24,6 +24,8 @@ func (d *windowNodeDiagnostic) StartingBatchQuery(q string)
 func (d *windowNodeDiagnostic) LogBatchData(level, prefix string, batch edge.BufferedBatchMessage) {}
 func (d *windowNodeDiagnostic) LogPointData(level, prefix string, point edge.PointMessage)         {}
 func (d *windowNodeDiagnostic) UDFLog(s string)                                                    {}
+func (d *windowNodeDiagnostic) PointsThrottled(limit, dropped int64)                               {}
+func (d *windowNodeDiagnostic) PointsCardinalityLimited(dropped int64)                             {}
 
 func TestWindowBufferByTime(t *testing.T) {
 	assert := assert.New(t)
@@ -143,6 +145,8 @@ func TestWindowBufferByCount(t *testing.T) {
 			tc.period,
 			tc.every,
 			tc.fillPeriod,
+			nil,
+			nil,
 			newWindowNodeDiagnostic(),
 		)
 
@@ -211,3 +215,40 @@ func TestWindowBufferByCount(t *testing.T) {
 		}
 	}
 }
+
+func TestWindowByCount_SnapshotRestore(t *testing.T) {
+	w := newWindowByCount("test", edge.GroupInfo{}, 5, 2, false, nil, nil, newWindowNodeDiagnostic())
+	restored := newWindowByCount("test", edge.GroupInfo{}, 5, 2, false, nil, nil, newWindowNodeDiagnostic())
+	for i := 1; i <= 10; i++ {
+		if i == 7 {
+			snapshot, err := w.snapshot()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := restored.restore(snapshot); err != nil {
+				t.Fatal(err)
+			}
+		}
+		p := edge.NewPointMessage(
+			"name", "db", "rp",
+			models.Dimensions{},
+			models.Fields{"value": int64(i)},
+			nil,
+			time.Unix(int64(i), 0).UTC(),
+		)
+		exp, err := w.Point(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < 7 {
+			continue
+		}
+		got, err := restored.Point(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !assert.Equal(t, exp, got, "point %d", i) {
+			break
+		}
+	}
+}